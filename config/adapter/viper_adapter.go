@@ -161,6 +161,12 @@ func processPDFEnv(v *viper.Viper, cfg *config.Config) {
 	if mineruURL := v.GetString("PDF_PARSE_MINERU_URL"); mineruURL != "" {
 		cfg.PDF.Parse.Mineru.URL = mineruURL
 	}
+	if doclingURL := v.GetString("PDF_PARSE_DOCLING_URL"); doclingURL != "" {
+		cfg.PDF.Parse.Docling.URL = doclingURL
+	}
+	if markerURL := v.GetString("PDF_PARSE_MARKER_URL"); markerURL != "" {
+		cfg.PDF.Parse.Marker.URL = markerURL
+	}
 }
 
 // processDifyEnv 处理Dify相关环境变量
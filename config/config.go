@@ -89,16 +89,185 @@ type NavConfig struct {
 	Website WebsiteConfig `json:"website" yaml:"website"`
 }
 
+// ReadingAnalyticsConfig 阅读统计配置
+type ReadingAnalyticsConfig struct {
+	SessionIdleTimeout int `json:"session-idle-timeout" yaml:"session-idle-timeout"` // 阅读会话空闲超时，超过该间隔的心跳开启新会话 单位：秒
+	RollupLookbackDays int `json:"rollup-lookback-days" yaml:"rollup-lookback-days"` // 每日汇总任务回溯重算的天数（含当天）
+	MaxRangeDays       int `json:"max-range-days" yaml:"max-range-days"`             // 统计接口允许查询的最大天数
+}
+
+// EventTrackerConfig 事件采集配置
+type EventTrackerConfig struct {
+	Enabled             bool   `json:"enabled" yaml:"enabled"`                               // 是否开启事件采集，关闭时上报接口直接返回成功
+	SignSecret          string `json:"sign-secret" yaml:"sign-secret"`                       // 上报签名密钥(HmacSHA256)，开启采集时必填，不设默认值
+	SignMaxSkew         int    `json:"sign-max-skew" yaml:"sign-max-skew"`                   // 上报时间戳允许的最大偏差，0表示不校验 单位：秒
+	MaxEventsPerRequest int    `json:"max-events-per-request" yaml:"max-events-per-request"` // 单次上报最多接收的事件数
+	Sink                string `json:"sink" yaml:"sink"`                                     // 事件落地方式：db 写入事件表，mq 转发到消息队列
+	BufferSize          int    `json:"buffer-size" yaml:"buffer-size"`                       // 写缓冲队列长度，队列满时丢弃新事件
+	BatchSize           int    `json:"batch-size" yaml:"batch-size"`                         // 单批写入的事件数
+	FlushInterval       int    `json:"flush-interval" yaml:"flush-interval"`                 // 缓冲区定时刷新间隔 单位：毫秒
+	RetentionDays       int    `json:"retention-days" yaml:"retention-days"`                 // 事件保留天数，0表示不清理
+	RetentionDeleteSize int    `json:"retention-delete-size" yaml:"retention-delete-size"`   // 清理任务每批删除的条数
+	Topic               string `json:"topic" yaml:"topic"`                                   // sink为mq时的消息主题
+	Tag                 string `json:"tag" yaml:"tag"`                                       // sink为mq时的消息标签
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	Enabled             bool     `json:"enabled" yaml:"enabled"`                             // 是否开启审计日志，关闭时业务代码和中间件的记录均为空操作
+	CaptureHTTP         bool     `json:"capture-http" yaml:"capture-http"`                   // 是否由中间件自动记录写请求（POST、PUT、PATCH、DELETE）
+	ExcludePaths        []string `json:"exclude-paths" yaml:"exclude-paths"`                 // 中间件不记录的接口路径前缀
+	AlwaysPaths         []string `json:"always-paths" yaml:"always-paths"`                   // 中间件不区分请求方法都记录的接口路径前缀，如管理后台接口
+	RetentionDays       int      `json:"retention-days" yaml:"retention-days"`               // 审计日志保留天数，0表示不清理
+	RetentionDeleteSize int      `json:"retention-delete-size" yaml:"retention-delete-size"` // 清理任务每批删除的条数
+	MaxExportRows       int      `json:"max-export-rows" yaml:"max-export-rows"`             // 单次导出的最大条数
+}
+
+// TakeoutConfig 用户数据导出配置
+type TakeoutConfig struct {
+	Enabled         bool `json:"enabled" yaml:"enabled"`                   // 是否开启用户数据导出
+	LinkExpiry      int  `json:"link-expiry" yaml:"link-expiry"`           // 导出文件的下载有效期，不应超过临时存储桶的生命周期 单位：秒
+	RequestInterval int  `json:"request-interval" yaml:"request-interval"` // 同一用户两次申请导出的最小间隔 单位：秒
+	BatchSize       int  `json:"batch-size" yaml:"batch-size"`             // 导出任务每次执行处理的申请数
+	RunningTimeout  int  `json:"running-timeout" yaml:"running-timeout"`   // 导出中的申请超过该时长视为中断，重新排队 单位：秒
+	MaxAttempts     int  `json:"max-attempts" yaml:"max-attempts"`         // 单个申请最多尝试导出的次数
+}
+
+// AccountDeletionConfig 账号注销配置
+type AccountDeletionConfig struct {
+	Enabled        bool `json:"enabled" yaml:"enabled"`                 // 是否开启用户自助注销
+	GracePeriod    int  `json:"grace-period" yaml:"grace-period"`       // 申请注销后的冷静期，期间可撤销 单位：秒
+	BatchSize      int  `json:"batch-size" yaml:"batch-size"`           // 注销任务每次执行处理的申请数
+	RunningTimeout int  `json:"running-timeout" yaml:"running-timeout"` // 执行中的注销超过该时长视为中断，从未完成的模块继续执行 单位：秒
+	MaxAttempts    int  `json:"max-attempts" yaml:"max-attempts"`       // 单个注销最多自动尝试的次数，超过后需管理员重试
+}
+
+// TrashConfig 回收站配置
+type TrashConfig struct {
+	Enabled        bool `json:"enabled" yaml:"enabled"`                   // 是否开启回收站，关闭后删除的数据不再记录，无法恢复
+	RetentionDays  int  `json:"retention-days" yaml:"retention-days"`     // 回收站条目保留天数，超过后彻底删除并释放存储，0表示不清理
+	PurgeBatchSize int  `json:"purge-batch-size" yaml:"purge-batch-size"` // 清理任务每次执行彻底删除的条目数
+}
+
+// MailConfig 邮件发送配置
+type MailConfig struct {
+	Driver   string `json:"driver" yaml:"driver"`       // 发送方式：smtp 真实发送，file 写入本地文件，log 仅输出日志
+	Host     string `json:"host" yaml:"host"`           // SMTP服务器地址
+	Port     int    `json:"port" yaml:"port"`           // SMTP服务器端口，465使用隐式TLS，其他端口在支持时使用STARTTLS
+	Username string `json:"username" yaml:"username"`   // SMTP用户名
+	Password string `json:"password" yaml:"password"`   // SMTP密码
+	From     string `json:"from" yaml:"from"`           // 发件人地址
+	FromName string `json:"from-name" yaml:"from-name"` // 发件人名称
+	FileDir  string `json:"file-dir" yaml:"file-dir"`   // driver为file时邮件的写入目录
+	Timeout  int    `json:"timeout" yaml:"timeout"`     // 发送超时 单位：秒
+}
+
+// AccountConfig 邮箱账号生命周期配置（邮箱验证、找回密码、修改密码）
+type AccountConfig struct {
+	RequireEmailVerification bool   `json:"require-email-verification" yaml:"require-email-verification"` // 是否要求邮箱验证后才能登录
+	TokenSecret              string `json:"token-secret" yaml:"token-secret"`                             // 一次性令牌签名密钥
+	VerifyTokenTTL           int    `json:"verify-token-ttl" yaml:"verify-token-ttl"`                     // 邮箱验证令牌有效期 单位：秒
+	ResetTokenTTL            int    `json:"reset-token-ttl" yaml:"reset-token-ttl"`                       // 重置密码令牌有效期 单位：秒
+	SendInterval             int    `json:"send-interval" yaml:"send-interval"`                           // 同一邮箱两次发送邮件的最小间隔 单位：秒
+	VerifyURL                string `json:"verify-url" yaml:"verify-url"`                                 // 邮件中的验证链接地址，令牌以token参数拼接
+	ResetURL                 string `json:"reset-url" yaml:"reset-url"`                                   // 邮件中的重置密码链接地址，令牌以token参数拼接
+	PasswordMinLength        int    `json:"password-min-length" yaml:"password-min-length"`               // 密码最小长度
+}
+
+// SessionConfig 登录会话与登录历史配置
+type SessionConfig struct {
+	LastSeenInterval     int  `json:"last-seen-interval" yaml:"last-seen-interval"`         // 会话最后活跃时间的最小更新间隔 单位：秒
+	HistoryRetentionDays int  `json:"history-retention-days" yaml:"history-retention-days"` // 登录历史保留天数，<=0表示不清理
+	HistoryDeleteSize    int  `json:"history-delete-size" yaml:"history-delete-size"`       // 清理任务每批删除的条数
+	NotifyNewDevice      bool `json:"notify-new-device" yaml:"notify-new-device"`           // 新设备登录时是否发送邮件提醒
+}
+
+// MFAConfig 两步验证（TOTP）配置
+type MFAConfig struct {
+	Issuer              string `json:"issuer" yaml:"issuer"`                               // 认证器App中显示的签发方名称
+	SecretKey           string `json:"secret-key" yaml:"secret-key"`                       // 加密存储TOTP密钥的key，为空时使用JWT密钥
+	ChallengeTTL        int    `json:"challenge-ttl" yaml:"challenge-ttl"`                 // 密码验证通过后完成两步验证的有效期 单位：秒
+	MaxAttempts         int64  `json:"max-attempts" yaml:"max-attempts"`                   // 每个用户每分钟最多校验验证码的次数
+	RecoveryCodeCount   int    `json:"recovery-code-count" yaml:"recovery-code-count"`     // 恢复码个数
+	RememberDeviceDays  int    `json:"remember-device-days" yaml:"remember-device-days"`   // 记住此设备的天数，<=0表示不支持
+	TrustedDeviceCookie string `json:"trusted-device-cookie" yaml:"trusted-device-cookie"` // 记住设备的cookie名称，%s为appId
+	VerifyURL           string `json:"verify-url" yaml:"verify-url"`                       // 外部身份登录需要两步验证时跳转的前端页面
+}
+
+// ExternalProviderConfig 外部身份提供方配置
+type ExternalProviderConfig struct {
+	Name         string   `json:"name" yaml:"name"`                 // 提供方标识，用于路由 /api/oauth2/external/:provider，需唯一
+	Type         string   `json:"type" yaml:"type"`                 // 提供方类型：oidc、google、github、orcid
+	DisplayName  string   `json:"display_name" yaml:"display_name"` // 登录页展示名称
+	Issuer       string   `json:"issuer" yaml:"issuer"`             // OIDC Issuer，用于自动发现；google、orcid类型可留空
+	ClientID     string   `json:"client_id" yaml:"client_id"`       // Client ID
+	ClientSecret string   `json:"client_secret" yaml:"client_secret"`
+	Scopes       []string `json:"scopes" yaml:"scopes"`               // 授权范围，oidc类型会自动补充openid
+	RedirectPath string   `json:"redirect_path" yaml:"redirect_path"` // 回调路径，为空时使用 /api/oauth2/external/<name>/callback
+	TrustEmail   bool     `json:"trust_email" yaml:"trust_email"`     // 是否信任提供方返回的邮箱（即使未声明已验证），用于按邮箱自动关联已有账号
+	Sandbox      bool     `json:"sandbox" yaml:"sandbox"`             // orcid类型是否使用沙箱环境
+}
+
+// AuthorizationServerConfig 面向第三方应用（浏览器插件、Obsidian插件、桌面应用）的OAuth2授权服务器配置
+type AuthorizationServerConfig struct {
+	Issuer             string              `json:"issuer" yaml:"issuer"`                             // 对外服务地址，用于 .well-known 元数据与令牌的 iss
+	ConsentURL         string              `json:"consent_url" yaml:"consent_url"`                   // 前端授权确认页面，/api/oauth2/authorize 校验通过后携带原始参数跳转
+	AccessTokenExpiry  int                 `json:"access_token_expiry" yaml:"access_token_expiry"`   // 第三方访问令牌有效期 单位：秒
+	RefreshTokenExpiry int                 `json:"refresh_token_expiry" yaml:"refresh_token_expiry"` // 第三方刷新令牌有效期 单位：秒
+	Scopes             []OAuth2ScopeConfig `json:"scopes" yaml:"scopes"`                             // 可授予第三方应用的作用域
+}
+
+// OAuth2ScopeConfig 第三方应用作用域，第三方令牌只能访问其作用域声明的接口
+type OAuth2ScopeConfig struct {
+	Name        string   `json:"name" yaml:"name"`               // 作用域名称，如 notes:read
+	Description string   `json:"description" yaml:"description"` // 授权确认页展示的说明
+	Paths       []string `json:"paths" yaml:"paths"`             // 允许访问的接口路径前缀
+	Methods     []string `json:"methods" yaml:"methods"`         // 允许的HTTP方法，为空表示不限制
+}
+
+// RateLimitConfig 接口限流配置，按接口路径或gRPC方法匹配策略，按会员类型区分额度
+type RateLimitConfig struct {
+	Enabled      bool                    `json:"enabled" yaml:"enabled"`
+	Store        string                  `json:"store" yaml:"store"`                   // 计数存储：redis 多实例共享计数，memory 仅在当前进程内计数；Redis不可用时使用memory
+	KeyPrefix    string                  `json:"key-prefix" yaml:"key-prefix"`         // 限流键前缀
+	APIKeyHeader string                  `json:"api-key-header" yaml:"api-key-header"` // 按API Key限流时读取的请求头，gRPC读取同名metadata
+	APIKeys      []string                `json:"api-keys" yaml:"api-keys"`             // 已发放的API Key，只有列表中的Key单独计数，其余请求按IP限流
+	TierCacheTTL int                     `json:"tier-cache-ttl" yaml:"tier-cache-ttl"` // 用户会员类型的本地缓存时间 单位：秒
+	Policies     []RateLimitPolicyConfig `json:"policies" yaml:"policies"`             // 限流策略，按顺序匹配，请求只受第一条命中策略的限制
+}
+
+// RateLimitPolicyConfig 限流策略
+type RateLimitPolicyConfig struct {
+	Name        string           `json:"name" yaml:"name"`                 // 策略名称，作为限流键的一部分，需唯一
+	Paths       []string         `json:"paths" yaml:"paths"`               // HTTP接口路径前缀
+	Methods     []string         `json:"methods" yaml:"methods"`           // HTTP方法，为空表示不限制
+	GRPCMethods []string         `json:"grpc-methods" yaml:"grpc-methods"` // gRPC方法，按前缀匹配，如 /doc.DocService/
+	Type        string           `json:"type" yaml:"type"`                 // 限流算法：counter、sliding_window、token_bucket、leaky_bucket，memory存储统一按固定窗口计数
+	KeyBy       string           `json:"key-by" yaml:"key-by"`             // 限流维度：user、api_key、ip，取不到用户或API Key时按IP限流
+	TimeUnit    string           `json:"time-unit" yaml:"time-unit"`       // 额度的时间单位：s、m、h、d
+	Limit       int64            `json:"limit" yaml:"limit"`               // 默认额度，匿名请求和未单独配置的会员类型使用
+	Tiers       map[string]int64 `json:"tiers" yaml:"tiers"`               // 各会员类型的额度，如 free: 10、pro: 60，负数表示不限流
+	Cost        int64            `json:"cost" yaml:"cost"`                 // 每次请求消耗的额度，默认1
+}
+
 // Config holds all configuration for our application
 type Config struct {
 	Server struct {
 		Port    int    `json:"port" yaml:"port"`
 		Host    string `json:"host" yaml:"host"`
 		Timeout int    `json:"timeout" yaml:"timeout"` // in seconds
-		GRPC    struct {
-			Port int    `json:"port" yaml:"port"`
-			Host string `json:"host" yaml:"host"`
+		// 可信代理的IP或CIDR，只采用这些代理转发的 X-Forwarded-For，为空时不信任任何代理，直接使用连接地址
+		TrustedProxies []string `json:"trustedProxies" yaml:"trustedProxies"`
+		GRPC           struct {
+			Port          int      `json:"port" yaml:"port"`
+			Host          string   `json:"host" yaml:"host"`
+			PublicMethods []string `json:"publicMethods" yaml:"publicMethods"` // 无需认证的gRPC方法，按前缀匹配，如 /user.UserService/Register
 		} `json:"grpc" yaml:"grpc"`
+		Health struct {
+			CheckTimeout   int `json:"checkTimeout" yaml:"checkTimeout"`     // 单个依赖检查超时 单位：秒
+			ReadinessDelay int `json:"readinessDelay" yaml:"readinessDelay"` // 关闭时标记未就绪后、停止接收请求前的等待时间，供负载均衡摘除流量 单位：秒
+			DrainTimeout   int `json:"drainTimeout" yaml:"drainTimeout"`     // 等待进行中的HTTP、SSE、gRPC请求结束的最长时间，超时后强制结束 单位：秒
+		} `json:"health" yaml:"health"`
 	} `json:"server" yaml:"server"`
 
 	Logging struct {
@@ -112,9 +281,16 @@ type Config struct {
 
 	Tracing struct {
 		Enabled     bool    `json:"enabled" yaml:"enabled"`
+		Provider    string  `json:"provider" yaml:"provider"` // jaeger/opentelemetry，默认jaeger
 		ServiceName string  `json:"serviceName" yaml:"serviceName"`
 		JaegerURL   string  `json:"jaegerUrl" yaml:"jaegerUrl"`
 		SampleRate  float64 `json:"sampleRate" yaml:"sampleRate"`
+		OTLP        struct {
+			Endpoint       string            `json:"endpoint" yaml:"endpoint"`             // OTLP collector gRPC 地址，如 otel-collector:4317
+			Insecure       bool              `json:"insecure" yaml:"insecure"`             // 是否使用明文连接
+			Headers        map[string]string `json:"headers" yaml:"headers"`               // 请求头，如鉴权 token
+			MetricInterval int               `json:"metricInterval" yaml:"metricInterval"` // 指标上报间隔 单位：秒
+		} `json:"otlp" yaml:"otlp"`
 	} `json:"tracing" yaml:"tracing"`
 
 	Metrics struct {
@@ -314,8 +490,15 @@ type Config struct {
 			RedirectPath    string   `json:"redirect_path" yaml:"redirect_path"` // Google OAuth2 Redirect Path (e.g., /api/oauth2/google/callback)
 			Scopes          []string `json:"scopes" yaml:"scopes"`
 			LoginSuccessURL string   `json:"login_success_url" yaml:"login_success_url"` // Google OAuth2 Login Success URL
-		} `json:"googleOAuth2" yaml:"googleOAuth2"`
-		ResourceProtection struct {
+		} `json:"googleOAuth2" yaml:"googleOAuth2"` // 兼容旧配置，未在external.providers中声明google时据此生成google提供方
+		External struct {
+			LoginSuccessURL string                   `json:"login_success_url" yaml:"login_success_url"` // 外部登录成功后的跳转地址，为空时使用googleOAuth2.login_success_url
+			LinkSuccessURL  string                   `json:"link_success_url" yaml:"link_success_url"`   // 关联外部账号成功后的跳转地址
+			StateTTL        int                      `json:"state_ttl" yaml:"state_ttl"`                 // 登录state有效期 单位：秒
+			Providers       []ExternalProviderConfig `json:"providers" yaml:"providers"`                 // 外部身份提供方列表
+		} `json:"external" yaml:"external"`
+		AuthorizationServer AuthorizationServerConfig `json:"authorization_server" yaml:"authorization_server"` // 第三方应用授权服务器
+		ResourceProtection  struct {
 			PublicPaths []string `json:"publicPaths" yaml:"publicPaths"` // 公开资源路径前缀，不需要验证
 			AdminPaths  []string `json:"adminPaths" yaml:"adminPaths"`   // 管理员资源路径前缀，需要管理员角色
 			AdminRoles  []string `json:"adminRoles" yaml:"adminRoles"`   // 管理员角色
@@ -402,6 +585,7 @@ type Config struct {
 			HandlerVersion             string   `json:"handler-version" yaml:"handler-version"`
 			SummaryHandlerSplitStrings []string `json:"summary-handler-split-strings" yaml:"summary-handler-split-strings"`
 		} `json:"summary-paper" yaml:"summary-paper"`
+		StructuredSummary StructuredSummaryConfig `json:"structured-summary" yaml:"structured-summary"`
 	} `json:"copilot" yaml:"copilot"`
 	Membership MembershipConfig `json:"membership" yaml:"membership"`
 
@@ -415,6 +599,11 @@ type Config struct {
 			CheckoutSuccessURL string `json:"checkoutSuccessURL" yaml:"checkoutSuccessURL"`
 			CheckoutCancelURL  string `json:"checkoutCancelURL" yaml:"checkoutCancelURL"`
 		} `json:"stripe" yaml:"stripe"`
+		// 进程内模拟支付渠道，用于测试和本地联调，生产环境不要开启
+		Mock struct {
+			IsEnable      bool   `json:"isEnable" yaml:"isEnable"`
+			WebhookSecret string `json:"webhookSecret" yaml:"webhookSecret"` // Webhook签名密钥
+		} `json:"mock" yaml:"mock"`
 	} `json:"pay" yaml:"pay"`
 
 	// 调度器配置
@@ -430,6 +619,51 @@ type Config struct {
 				Key    string `json:"key" yaml:"key"`
 				Expiry int    `json:"expiry" yaml:"expiry"`
 			} `json:"credit-pay-confirm-expired-job" yaml:"credit-pay-confirm-expired-job"`
+			ReadingDailyRollupJob struct {
+				Spec   string `json:"spec" yaml:"spec"`
+				Key    string `json:"key" yaml:"key"`
+				Expiry int    `json:"expiry" yaml:"expiry"`
+			} `json:"reading-daily-rollup-job" yaml:"reading-daily-rollup-job"`
+			TrackingEventRetentionJob struct {
+				Spec   string `json:"spec" yaml:"spec"`
+				Key    string `json:"key" yaml:"key"`
+				Expiry int    `json:"expiry" yaml:"expiry"`
+			} `json:"tracking-event-retention-job" yaml:"tracking-event-retention-job"`
+			LoginHistoryRetentionJob struct {
+				Spec   string `json:"spec" yaml:"spec"`
+				Key    string `json:"key" yaml:"key"`
+				Expiry int    `json:"expiry" yaml:"expiry"`
+			} `json:"login-history-retention-job" yaml:"login-history-retention-job"`
+			PdfThumbRenderJob struct {
+				Spec   string `json:"spec" yaml:"spec"`
+				Key    string `json:"key" yaml:"key"`
+				Expiry int    `json:"expiry" yaml:"expiry"`
+			} `json:"pdf-thumb-render-job" yaml:"pdf-thumb-render-job"`
+			CreditLedgerReconcileJob struct {
+				Spec   string `json:"spec" yaml:"spec"`
+				Key    string `json:"key" yaml:"key"`
+				Expiry int    `json:"expiry" yaml:"expiry"`
+			} `json:"credit-ledger-reconcile-job" yaml:"credit-ledger-reconcile-job"`
+			AuditLogRetentionJob struct {
+				Spec   string `json:"spec" yaml:"spec"`
+				Key    string `json:"key" yaml:"key"`
+				Expiry int    `json:"expiry" yaml:"expiry"`
+			} `json:"audit-log-retention-job" yaml:"audit-log-retention-job"`
+			TakeoutExportJob struct {
+				Spec   string `json:"spec" yaml:"spec"`
+				Key    string `json:"key" yaml:"key"`
+				Expiry int    `json:"expiry" yaml:"expiry"`
+			} `json:"takeout-export-job" yaml:"takeout-export-job"`
+			AccountDeletionJob struct {
+				Spec   string `json:"spec" yaml:"spec"`
+				Key    string `json:"key" yaml:"key"`
+				Expiry int    `json:"expiry" yaml:"expiry"`
+			} `json:"account-deletion-job" yaml:"account-deletion-job"`
+			TrashPurgeJob struct {
+				Spec   string `json:"spec" yaml:"spec"`
+				Key    string `json:"key" yaml:"key"`
+				Expiry int    `json:"expiry" yaml:"expiry"`
+			} `json:"trash-purge-job" yaml:"trash-purge-job"`
 		} `json:"jobs" yaml:"jobs"`
 	} `json:"scheduler" yaml:"scheduler"`
 
@@ -442,6 +676,39 @@ type Config struct {
 	// 网站配置
 	Nav NavConfig `json:"nav" yaml:"nav"`
 
+	// 阅读统计配置
+	ReadingAnalytics ReadingAnalyticsConfig `json:"reading-analytics" yaml:"reading-analytics"`
+
+	// 事件采集配置
+	EventTracker EventTrackerConfig `json:"event-tracker" yaml:"event-tracker"`
+
+	// 审计日志配置
+	Audit AuditConfig `json:"audit" yaml:"audit"`
+
+	// 用户数据导出配置
+	Takeout TakeoutConfig `json:"takeout" yaml:"takeout"`
+
+	// 账号注销配置
+	AccountDeletion AccountDeletionConfig `json:"account-deletion" yaml:"account-deletion"`
+
+	// 回收站配置
+	Trash TrashConfig `json:"trash" yaml:"trash"`
+
+	// 邮件发送配置
+	Mail MailConfig `json:"mail" yaml:"mail"`
+
+	// 邮箱账号生命周期配置
+	Account AccountConfig `json:"account" yaml:"account"`
+
+	// 登录会话配置
+	Session SessionConfig `json:"session" yaml:"session"`
+
+	// 两步验证配置
+	MFA MFAConfig `json:"mfa" yaml:"mfa"`
+
+	// 接口限流配置
+	RateLimit RateLimitConfig `json:"rate-limit" yaml:"rate-limit"`
+
 	// 调试相关配置
 	Debug struct {
 		// 是否启用请求日志记录
@@ -573,7 +840,11 @@ type PDFConfig struct {
 	} `json:"download" yaml:"download"`
 
 	Parse struct {
-		Grobid struct {
+		Engine   string            `json:"engine" yaml:"engine"`     // 解析引擎 mineru/grobid/docling/marker/native，没有规则命中时使用，默认mineru
+		Compare  bool              `json:"compare" yaml:"compare"`   // 并行运行全部候选解析器，选取质量分最高的结果
+		MinScore float64           `json:"minScore" yaml:"minScore"` // 顺序解析时结果低于该质量分则继续尝试后续解析器
+		Rules    []ParseRuleConfig `json:"rules" yaml:"rules"`       // 解析器选择规则，按顺序匹配
+		Grobid   struct {
 			URL              string `json:"url" yaml:"url"`                           // Grobid服务地址
 			HeaderDocument   string `json:"headerDocument" yaml:"headerDocument"`     // Grobid头部文档解析地址
 			FulltextDocument string `json:"fulltextDocument" yaml:"fulltextDocument"` // Grobid全文文档解析地址
@@ -589,7 +860,92 @@ type PDFConfig struct {
 			Timeout     int    `json:"timeout" yaml:"timeout"`         // MinerU超时 单位：分钟
 			MaxPage     int    `json:"maxPage" yaml:"maxPage"`         // MinerU解析的最大页数
 		} `json:"mineru" yaml:"mineru"`
+		Docling struct {
+			URL        string `json:"url" yaml:"url"`               // docling-serve服务地址
+			ConvertURL string `json:"convertURL" yaml:"convertURL"` // Docling文件转换地址，默认/v1/convert/file
+			Timeout    int    `json:"timeout" yaml:"timeout"`       // Docling超时 单位：分钟
+		} `json:"docling" yaml:"docling"`
+		Marker struct {
+			URL        string `json:"url" yaml:"url"`               // Marker服务地址
+			ConvertURL string `json:"convertURL" yaml:"convertURL"` // Marker文件转换地址，默认/marker/upload
+			Timeout    int    `json:"timeout" yaml:"timeout"`       // Marker超时 单位：分钟
+		} `json:"marker" yaml:"marker"`
+		Native struct {
+			Fallback bool `json:"fallback" yaml:"fallback"` // 主引擎未配置或解析失败时是否回退到内置解析器
+			MaxPage  int  `json:"maxPage" yaml:"maxPage"`   // 内置解析器解析的最大页数
+		} `json:"native" yaml:"native"`
+		Arxiv struct {
+			Enabled    bool    `json:"enabled" yaml:"enabled"`       // arXiv论文是否优先下载LaTeX源码解析
+			SourceURL  string  `json:"sourceURL" yaml:"sourceURL"`   // e-print下载地址前缀，默认https://arxiv.org/e-print/
+			Timeout    int     `json:"timeout" yaml:"timeout"`       // 下载超时 单位：秒
+			MaxSizeMB  int     `json:"maxSizeMB" yaml:"maxSizeMB"`   // e-print大小上限 单位：MB
+			MinAligned float64 `json:"minAligned" yaml:"minAligned"` // 与PDF对齐的块占比(0-1)低于该值时放弃源码解析结果
+		} `json:"arxiv" yaml:"arxiv"`
 	} `json:"parse" yaml:"parse"`
+
+	Thumb struct {
+		Concurrency   int `json:"concurrency" yaml:"concurrency"`     // 同时渲染的PDF数量
+		BatchSize     int `json:"batchSize" yaml:"batchSize"`         // 每次任务处理的PDF数量上限
+		LookbackHours int `json:"lookbackHours" yaml:"lookbackHours"` // 只处理最近多少小时内上传的PDF
+		CoverWidth    int `json:"coverWidth" yaml:"coverWidth"`       // 封面缩略图宽度(像素)
+		PageWidth     int `json:"pageWidth" yaml:"pageWidth"`         // 页面缩略图宽度(像素)
+		MaxPages      int `json:"maxPages" yaml:"maxPages"`           // 最多生成多少页的页面缩略图
+		FigureWidth   int `json:"figureWidth" yaml:"figureWidth"`     // 裁剪图表时页面的渲染宽度(像素)
+		JpegQuality   int `json:"jpegQuality" yaml:"jpegQuality"`     // 缩略图JPEG质量(1-100)
+		CacheMaxAge   int `json:"cacheMaxAge" yaml:"cacheMaxAge"`     // 图片响应的浏览器缓存时间 单位：秒
+	} `json:"thumb" yaml:"thumb"`
+
+	// 非PDF文档（EPUB、DOCX、网页、Markdown）导入配置
+	Document struct {
+		MaxSizeMB    int `json:"maxSizeMB" yaml:"maxSizeMB"`       // 上传文档大小上限 单位：MB
+		FetchTimeout int `json:"fetchTimeout" yaml:"fetchTimeout"` // 抓取网页的超时时间 单位：秒
+	} `json:"document" yaml:"document"`
+}
+
+// ParseRuleConfig 解析器选择规则，条件为空表示不限制
+type ParseRuleConfig struct {
+	Parsers   []string `json:"parsers" yaml:"parsers"`     // 命中规则时按顺序尝试的解析器
+	Languages []string `json:"languages" yaml:"languages"` // 文档语言，如 en/zh
+	MinPages  int      `json:"minPages" yaml:"minPages"`   // 最小页数
+	MaxPages  int      `json:"maxPages" yaml:"maxPages"`   // 最大页数
+	Tiers     []string `json:"tiers" yaml:"tiers"`         // 会员等级 free/pro
+	Require   []string `json:"require" yaml:"require"`     // 解析器必须具备的能力 header/fulltext/figures/formulas/markdown
+}
+
+// StructuredSummaryConfig 论文结构化总结配置
+type StructuredSummaryConfig struct {
+	HandlerFrom     string                  `json:"handler-from" yaml:"handler-from"`         // 总结来源标识，写入PdfSummary.SourceFrom
+	HandlerVersion  string                  `json:"handler-version" yaml:"handler-version"`   // 总结版本，升级后旧缓存失效
+	MaxInputChars   int                     `json:"max-input-chars" yaml:"max-input-chars"`   // 提交给LLM的正文最大字符数
+	DefaultTemplate string                  `json:"default-template" yaml:"default-template"` // 默认模板key
+	Templates       []SummaryTemplateConfig `json:"templates" yaml:"templates"`               // 总结模板列表
+}
+
+// SummaryTemplateConfig 论文总结模板
+type SummaryTemplateConfig struct {
+	Key      string                         `json:"key" yaml:"key"`
+	Name     string                         `json:"name" yaml:"name"`
+	Sections []SummaryTemplateSectionConfig `json:"sections" yaml:"sections"`
+}
+
+// SummaryTemplateSectionConfig 论文总结模板中的一个小节
+type SummaryTemplateSectionConfig struct {
+	Key    string `json:"key" yaml:"key"`       // 小节key，如 problem、method
+	Title  string `json:"title" yaml:"title"`   // 小节标题
+	Prompt string `json:"prompt" yaml:"prompt"` // 小节提示词
+}
+
+// GetTemplate 根据key获取总结模板，key为空时返回默认模板
+func (c *StructuredSummaryConfig) GetTemplate(key string) *SummaryTemplateConfig {
+	if key == "" {
+		key = c.DefaultTemplate
+	}
+	for i := range c.Templates {
+		if c.Templates[i].Key == key {
+			return &c.Templates[i]
+		}
+	}
+	return nil
 }
 
 type PersonalConfig struct {
@@ -733,6 +1089,12 @@ func GlobalConfig() *Config {
 	config.Redis.MinRetryBackoff = 8
 	config.Redis.MaxRetryBackoff = 512
 
+	// RateLimit defaults
+	config.RateLimit.Store = "redis"
+	config.RateLimit.KeyPrefix = "ratelimit"
+	config.RateLimit.APIKeyHeader = "X-API-Key"
+	config.RateLimit.TierCacheTTL = 60
+
 	// Cache defaults
 	config.Cache.Type = "redis"    // 默认使用 redis，本地版可改为 memory
 	config.Cache.Expiration = 1800 // 默认 30 分钟
@@ -806,6 +1168,101 @@ func GlobalConfig() *Config {
 	config.PDF.Download.TempDownloadDirectory = "temp/download"
 	config.PDF.Download.MineruImageDirectory = "images"
 
+	// 论文结构化总结默认值
+	config.Copilot.StructuredSummary.HandlerFrom = "structured"
+	config.Copilot.StructuredSummary.HandlerVersion = "0.0.1"
+	config.Copilot.StructuredSummary.MaxInputChars = 60000
+	config.Copilot.StructuredSummary.DefaultTemplate = "default"
+	config.Copilot.StructuredSummary.Templates = []SummaryTemplateConfig{
+		{
+			Key:  "default",
+			Name: "Default",
+			Sections: []SummaryTemplateSectionConfig{
+				{Key: "problem", Title: "Problem", Prompt: "What problem does the paper address and why does it matter?"},
+				{Key: "method", Title: "Method", Prompt: "What is the proposed method or approach?"},
+				{Key: "results", Title: "Results", Prompt: "What are the main experimental results and findings?"},
+				{Key: "limitations", Title: "Limitations", Prompt: "What are the limitations or open issues?"},
+				{Key: "key_figures", Title: "Key Figures", Prompt: "Which figures or tables are the most important and what do they show?"},
+			},
+		},
+	}
+
+	// 阅读统计默认值
+	config.ReadingAnalytics.SessionIdleTimeout = 300
+	config.ReadingAnalytics.RollupLookbackDays = 2
+	config.ReadingAnalytics.MaxRangeDays = 366
+
+	// 事件采集默认值
+	config.EventTracker.Enabled = false
+	config.EventTracker.MaxEventsPerRequest = 200
+	config.EventTracker.Sink = "db"
+	config.EventTracker.BufferSize = 10000
+	config.EventTracker.BatchSize = 200
+	config.EventTracker.FlushInterval = 2000
+	config.EventTracker.RetentionDays = 90
+	config.EventTracker.RetentionDeleteSize = 5000
+
+	// 审计日志默认值
+	config.Audit.Enabled = true
+	config.Audit.CaptureHTTP = true
+	config.Audit.AlwaysPaths = []string{"/api/admin"}
+	config.Audit.RetentionDays = 365
+	config.Audit.RetentionDeleteSize = 5000
+	config.Audit.MaxExportRows = 50000
+
+	// 用户数据导出默认值
+	config.Takeout.Enabled = true
+	config.Takeout.LinkExpiry = 86400
+	config.Takeout.RequestInterval = 86400
+	config.Takeout.BatchSize = 1
+	config.Takeout.RunningTimeout = 3600
+	config.Takeout.MaxAttempts = 3
+
+	// 账号注销默认值
+	config.AccountDeletion.Enabled = true
+	config.AccountDeletion.GracePeriod = 1209600
+	config.AccountDeletion.BatchSize = 5
+	config.AccountDeletion.RunningTimeout = 3600
+	config.AccountDeletion.MaxAttempts = 5
+
+	// 回收站默认值
+	config.Trash.Enabled = true
+	config.Trash.RetentionDays = 30
+	config.Trash.PurgeBatchSize = 200
+
+	// 邮件默认值
+	config.Mail.Driver = "log"
+	config.Mail.Port = 465
+	config.Mail.Timeout = 10
+
+	// 邮箱账号默认值
+	config.Account.RequireEmailVerification = false
+	config.Account.VerifyTokenTTL = 24 * 3600
+	config.Account.ResetTokenTTL = 1800
+	config.Account.SendInterval = 60
+	config.Account.PasswordMinLength = 8
+
+	// 登录会话默认值
+	config.Session.LastSeenInterval = 300
+	config.Session.HistoryRetentionDays = 180
+	config.Session.HistoryDeleteSize = 5000
+	config.Session.NotifyNewDevice = true
+
+	// 两步验证默认值
+	config.MFA.Issuer = "odoc"
+	config.MFA.ChallengeTTL = 300
+	config.MFA.MaxAttempts = 5
+	config.MFA.RecoveryCodeCount = 10
+	config.MFA.RememberDeviceDays = 30
+	config.MFA.TrustedDeviceCookie = "mfa_trusted_%s"
+
+	// 外部身份登录默认值
+	config.OAuth2.External.StateTTL = 600
+
+	// 第三方应用授权服务器默认值
+	config.OAuth2.AuthorizationServer.AccessTokenExpiry = 3600
+	config.OAuth2.AuthorizationServer.RefreshTokenExpiry = 2592000
+
 	//设置RocketMQ配置默认值
 	config.RocketMQ.Client.LogLevel = "ERROR"
 	config.RocketMQ.Client.RequestTimeout = 30000
@@ -53,6 +53,11 @@ type AiPermission struct {
 			CreditCost int64  `json:"creditCost" yaml:"creditCost"`
 		} `json:"models" yaml:"models"`
 	} `json:"copilot" yaml:"copilot"`
+	PaperSummary struct {
+		IsEnable   bool  `json:"isEnable" yaml:"isEnable"`
+		IsFree     bool  `json:"isFree" yaml:"isFree"`
+		CreditCost int64 `json:"creditCost" yaml:"creditCost"`
+	} `json:"paperSummary" yaml:"paperSummary"`
 }
 
 // Translate 翻译权限配置
@@ -74,6 +74,7 @@ const (
 	Membership_Status_CreditService_Ai_Copilot_NotEnabled      = 4401 // 积分服务-AI辅读-未开启
 	Membership_Status_CreditService_Ai_Copilot_ModelNotFound   = 4402 // 积分服务-AI辅读-模型未找到
 	Membership_Status_CreditService_Ai_Copilot_ModelNotEnabled = 4403 // 积分服务-AI辅读-模型未开启
+	Membership_Status_CreditService_Ai_PaperSummaryNotEnabled  = 4411 // 积分服务-AI论文总结-未开启
 
 	Membership_Status_CreditService_Translate_OcrNotEnabled         = 4501 // 积分服务-翻译-OCR未开启
 	Membership_Status_CreditService_Translate_WordNotEnabled        = 4502 // 积分服务-翻译-划词翻译未开启
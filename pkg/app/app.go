@@ -27,6 +27,7 @@ import (
 	"github.com/yb2020/odoc/internal/database"
 	"github.com/yb2020/odoc/internal/i18n"
 	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/health"
 	pkgi18n "github.com/yb2020/odoc/pkg/i18n"
 	"github.com/yb2020/odoc/pkg/logging"
 	"github.com/yb2020/odoc/pkg/metrics"
@@ -58,6 +59,8 @@ type App struct {
 	Localizer       pkgi18n.Localizer
 	Scheduler       *scheduler.Scheduler // 定时调度器
 	ErrorReporter   middleware.ErrorReporter
+	Health          *health.Registry // 依赖健康检查和就绪状态
+	Drainer         *health.Drainer  // 关闭时排空进行中的请求
 
 	// gRPC服务器相关
 	GRPCServer *grpc.Server // gRPC服务器
@@ -161,9 +164,8 @@ func WithGRPCPort(port int) Option {
 func NewApp(options ...Option) (*App, error) {
 	// 创建默认应用程序
 	app := &App{
-		Config:          config.GetConfig(),
-		ShutdownTimeout: 5 * time.Second,
-		GRPCAddr:        fmt.Sprintf("%s:%d", config.GetConfig().Server.GRPC.Host, config.GetConfig().Server.GRPC.Port), // 从配置文件读取GRPC地址
+		Config:   config.GetConfig(),
+		GRPCAddr: fmt.Sprintf("%s:%d", config.GetConfig().Server.GRPC.Host, config.GetConfig().Server.GRPC.Port), // 从配置文件读取GRPC地址
 	}
 
 	// 应用选项
@@ -171,6 +173,14 @@ func NewApp(options ...Option) (*App, error) {
 		option(app)
 	}
 
+	// 关闭超时即请求排空时间：选项优先，其次是配置，默认5秒
+	if app.ShutdownTimeout <= 0 {
+		app.ShutdownTimeout = 5 * time.Second
+		if drainTimeout := app.Config.Server.Health.DrainTimeout; drainTimeout > 0 {
+			app.ShutdownTimeout = time.Duration(drainTimeout) * time.Second
+		}
+	}
+
 	// 初始化日志
 	fmt.Println("Initializing logging reporter")
 
@@ -237,18 +247,26 @@ func NewApp(options ...Option) (*App, error) {
 	// 初始化跟踪器（如果未提供）
 	if app.Tracer == nil {
 		if app.Config.Tracing.Enabled {
-			tracer, closer, err := tracing.InitTracer(
-				app.Config.Tracing.ServiceName,
-				app.Config.Tracing.JaegerURL,
-				app.Config.Tracing.SampleRate,
-				app.Logger,
-			)
+			tracingConfig := app.Config.Tracing
+			endpoint := tracingConfig.JaegerURL
+			if tracingConfig.Provider != "" && tracingConfig.Provider != tracing.ProviderJaeger {
+				endpoint = tracingConfig.OTLP.Endpoint
+			}
+			provider, err := tracing.NewTracerProvider(tracing.Options{
+				Provider:       tracingConfig.Provider,
+				ServiceName:    tracingConfig.ServiceName,
+				Endpoint:       endpoint,
+				SampleRate:     tracingConfig.SampleRate,
+				Insecure:       tracingConfig.OTLP.Insecure,
+				Headers:        tracingConfig.OTLP.Headers,
+				MetricInterval: time.Duration(tracingConfig.OTLP.MetricInterval) * time.Second,
+			}, app.Logger)
 			if err != nil {
-				app.Logger.Error("msg", "Failed to initialize tracer", "error", err.Error())
+				app.Logger.Error("msg", "Failed to initialize tracer", "provider", tracingConfig.Provider, "error", err.Error())
 				app.Tracer = opentracing.NoopTracer{}
 			} else {
-				app.Tracer = tracer
-				app.TracerCloser = closer
+				app.Tracer = provider.GetTracer()
+				app.TracerCloser = provider
 			}
 		} else {
 			app.Tracer = opentracing.NoopTracer{}
@@ -277,6 +295,13 @@ func NewApp(options ...Option) (*App, error) {
 func (a *App) Setup() error {
 	a.Logger.Info("msg", "Setting up application", "port", a.Config.Server.Port, "host", a.Config.Server.Host)
 
+	if a.Health == nil {
+		a.Health = health.NewRegistry(time.Duration(a.Config.Server.Health.CheckTimeout) * time.Second)
+	}
+	if a.Drainer == nil {
+		a.Drainer = health.NewDrainer()
+	}
+
 	// 初始化数据库连接（如果配置了数据库且尚未提供连接）
 	if a.DB == nil && a.Config.Database.Enabled {
 		a.Logger.Info("msg", "初始化数据库连接", "type", a.Config.Database.Type)
@@ -290,6 +315,12 @@ func (a *App) Setup() error {
 		a.DB = db
 		a.Logger.Info("msg", "数据库连接成功", "type", a.Config.Database.Type)
 	}
+	if a.DB != nil {
+		if err := a.DB.Use(tracing.NewGormPlugin(a.Tracer)); err != nil {
+			a.Logger.Warn("msg", "注册数据库跟踪插件失败", "error", err.Error())
+		}
+		a.Health.Register("database", health.PingDB(a.DB))
+	}
 
 	// 初始化 Redis 客户端（如果配置了 Redis 且尚未提供连接）
 	if a.RedisClient == nil && a.Config.Redis.Enabled {
@@ -320,6 +351,10 @@ func (a *App) Setup() error {
 		} else {
 			// 获取初始化后的全局Redis客户端
 			a.RedisClient = database.GetRedisClient()
+			if wrapper, ok := a.RedisClient.(*database.RedisClientWrapper); ok {
+				wrapper.AddHook(tracing.NewRedisHook(a.Tracer))
+			}
+			a.Health.Register("redis", health.PingRedis(a.RedisClient))
 			a.Logger.Info("msg", "Redis 客户端初始化成功", "host", a.Config.Redis.Host, "port", a.Config.Redis.Port)
 		}
 	}
@@ -373,6 +408,7 @@ func (a *App) Setup() error {
 			// 	),
 			// )
 			a.Scheduler.Start()
+			a.Health.Register("scheduler", a.Scheduler)
 			a.Logger.Info("msg", "定时调度器初始化成功")
 		}
 	}
@@ -392,6 +428,9 @@ func (a *App) Setup() error {
 		a.Logger.Info("msg", "注册模块", "name", module.Name())
 	}
 
+	// 注册各模块依赖的外部服务健康检查
+	services.RegisterAllModuleHealthChecks(a.Health)
+
 	httpHandler := transport.NewHTTPHandler(a.Logger, a.Tracer, a.MetricsHandler, a.SeaHandlers...)
 
 	// 获取底层的 gin.Engine
@@ -401,6 +440,24 @@ func (a *App) Setup() error {
 		return fmt.Errorf("failed to get gin.Engine instance")
 	}
 
+	// 只信任配置的代理转发的 X-Forwarded-For，否则客户端可以伪造来源IP绕过按IP限流
+	if err := a.GinEngine.SetTrustedProxies(a.Config.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	// 探针不计入进行中的请求，需在排空中间件之前注册
+	a.Health.RegisterRoutes(a.GinEngine)
+	a.GinEngine.Use(a.Drainer.GinMiddleware())
+
+	// 审计中间件需在模块路由之前注册为全局中间件
+	a.GinEngine.Use(middleware.AuditMiddleware(a.Config.Audit))
+
+	// 接口限流需在模块路由之前注册为全局中间件
+	rateLimitMiddleware := services.GetRateLimitMiddleware()
+	if rateLimitMiddleware != nil {
+		a.GinEngine.Use(rateLimitMiddleware.GinMiddleware())
+	}
+
 	// 注册所有模块的路由
 	services.RegisterAllModuleRoutes(a.GinEngine)
 
@@ -462,12 +519,35 @@ func (a *App) Setup() error {
 	a.Logger.Info("msg", "配置GRPC服务器地址", "addr", a.GRPCAddr)
 
 	// 创建额外的 gRPC 拦截器
+	// 顺序：排空统计 -> 链路追踪 -> 访问日志 -> 错误本地化 -> 认证 -> 限流，日志记录的是本地化后的最终状态码
 	extraUnaryInterceptors := []grpc.UnaryServerInterceptor{
+		a.Drainer.UnaryServerInterceptor(),
 		grpc_opentracing.UnaryServerInterceptor(grpc_opentracing.WithTracer(a.Tracer)),
+		middleware.GRPCLoggingUnaryInterceptor(a.Logger),
+		middleware.GRPCErrorUnaryInterceptor(a.Logger, a.Localizer),
+	}
+	extraStreamInterceptors := []grpc.StreamServerInterceptor{
+		a.Drainer.StreamServerInterceptor(),
+		grpc_opentracing.StreamServerInterceptor(grpc_opentracing.WithTracer(a.Tracer)),
+		middleware.GRPCLoggingStreamInterceptor(a.Logger),
+		middleware.GRPCErrorStreamInterceptor(a.Logger, a.Localizer),
+	}
+	if authMiddleware := services.GetAuthMiddleware(); authMiddleware != nil {
+		extraUnaryInterceptors = append(extraUnaryInterceptors, authMiddleware.GRPCUnaryInterceptor())
+		extraStreamInterceptors = append(extraStreamInterceptors, authMiddleware.GRPCStreamInterceptor())
+	}
+	if rateLimitMiddleware != nil {
+		extraUnaryInterceptors = append(extraUnaryInterceptors, rateLimitMiddleware.GRPCUnaryInterceptor())
+		extraStreamInterceptors = append(extraStreamInterceptors, rateLimitMiddleware.GRPCStreamInterceptor())
+	}
+
+	// panic 拦截器依赖错误报告器
+	if a.ErrorReporter == nil {
+		a.ErrorReporter = middleware.NewDefaultErrorReporter(a.Logger, a.Tracer)
 	}
 
 	// 使用我们的统一错误处理机制创建 gRPC 服务器选项
-	serverOptions := middleware.CreateGRPCServerOptions(a.ErrorReporter, extraUnaryInterceptors, nil)
+	serverOptions := middleware.CreateGRPCServerOptions(a.ErrorReporter, extraUnaryInterceptors, extraStreamInterceptors)
 
 	// 创建 gRPC 服务器
 	a.GRPCServer = grpc.NewServer(serverOptions...)
@@ -531,6 +611,7 @@ func (a *App) Run() error {
 		}()
 	}
 
+	a.Health.SetReady()
 	a.Logger.Info("msg", "Service started")
 
 	// 等待信号或错误
@@ -543,26 +624,62 @@ func (a *App) Run() error {
 		a.Logger.Error("msg", "Service error", "err", err)
 	}
 
-	// 优雅关闭 HTTP 服务器
+	// 先标记未就绪，等待负载均衡摘除流量后再停止接收请求
+	a.Health.SetDraining()
+	if delay := time.Duration(a.Config.Server.Health.ReadinessDelay) * time.Second; delay > 0 {
+		a.Logger.Info("msg", "Readiness set to false, waiting before draining", "delay", delay)
+		time.Sleep(delay)
+	}
+
+	if shutdownErr := a.drain(); shutdownErr != nil && err == nil {
+		err = shutdownErr
+	}
+
+	a.Logger.Info("msg", "Service stopped", "err", err)
+	return err
+}
+
+// drain 停止接收新请求，等待进行中的 HTTP、SSE、gRPC 请求结束；超过 ShutdownTimeout 后取消剩余请求并强制关闭
+func (a *App) drain() error {
 	ctx, cancel := context.WithTimeout(context.Background(), a.ShutdownTimeout)
 	defer cancel()
 
-	a.Logger.Info("msg", "Shutting down HTTP server")
-	if shutdownErr := a.Server.Shutdown(ctx); shutdownErr != nil {
-		a.Logger.Error("msg", "HTTP server shutdown error", "err", shutdownErr)
-		if err == nil {
-			err = shutdownErr
-		}
-	}
+	a.Logger.Info("msg", "Shutting down HTTP server", "inflight", a.Drainer.InFlight(), "timeout", a.ShutdownTimeout)
+	httpDone := make(chan error, 1)
+	go func() {
+		httpDone <- a.Server.Shutdown(ctx)
+	}()
 
-	// 如果有gRPC服务器，优雅关闭
+	var grpcDone chan struct{}
 	if a.GRPCServer != nil {
 		a.Logger.Info("msg", "Shutting down gRPC server")
-		// 优雅停止gRPC服务器
-		a.GRPCServer.GracefulStop()
+		grpcDone = make(chan struct{})
+		go func() {
+			a.GRPCServer.GracefulStop()
+			close(grpcDone)
+		}()
 	}
 
-	a.Logger.Info("msg", "Service stopped", "err", err)
+	if waitErr := a.Drainer.Wait(ctx); waitErr != nil {
+		// SSE 等长连接不会自行结束，取消其上下文让处理函数尽快返回
+		a.Logger.Warn("msg", "Drain timed out, aborting in-flight requests", "inflight", a.Drainer.InFlight())
+		a.Drainer.Abort()
+	}
+
+	err := <-httpDone
+	if err != nil {
+		a.Logger.Error("msg", "HTTP server shutdown error", "err", err)
+		a.Server.Close()
+	}
+
+	if grpcDone != nil {
+		select {
+		case <-grpcDone:
+		case <-ctx.Done():
+			a.GRPCServer.Stop()
+			<-grpcDone
+		}
+	}
 	return err
 }
 
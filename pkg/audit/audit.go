@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+)
+
+// 审计日志通过全局 Recorder 落地；审计模块未初始化时 Record 为空操作，调用方无需判断
+
+// 操作类型
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionRefund = "refund"
+	ActionHTTP   = "http" // 中间件自动采集的写请求
+)
+
+// 记录来源
+const (
+	SourceService = "service" // 业务代码显式记录
+	SourceHTTP    = "http"    // HTTP 中间件自动采集
+)
+
+// Entry 一条审计日志
+type Entry struct {
+	ActorId    string        // 操作人用户ID，为空时从上下文中获取
+	Action     string        // 操作类型
+	EntityType string        // 目标实体类型，如 doc、glossary、membership、order
+	EntityId   string        // 目标实体ID
+	Before     any           // 变更前的数据，可为空
+	After      any           // 变更后的数据，可为空
+	Changes    []FieldChange // 字段级差异，为空且 Before/After 都存在时自动计算
+	Source     string        // 记录来源，为空时为 service
+	Method     string        // HTTP 方法
+	Path       string        // 路由路径
+	StatusCode int           // 响应状态码
+	ClientIp   string        // 客户端IP，为空时从上下文中获取
+	UserAgent  string        // User-Agent，为空时从上下文中获取
+	RequestId  string        // 请求ID，为空时从上下文中获取
+	OccurredAt time.Time     // 发生时间，为空时取当前时间
+}
+
+// Recorder 审计日志落地接口，由审计模块实现
+type Recorder interface {
+	Record(ctx context.Context, entry *Entry) error
+}
+
+var (
+	recorderMu sync.RWMutex
+	recorder   Recorder
+)
+
+// SetRecorder 设置全局审计日志落地实现，传 nil 表示关闭
+func SetRecorder(r Recorder) {
+	recorderMu.Lock()
+	defer recorderMu.Unlock()
+	recorder = r
+}
+
+func currentRecorder() Recorder {
+	recorderMu.RLock()
+	defer recorderMu.RUnlock()
+	return recorder
+}
+
+// Enabled 是否已设置审计日志落地实现
+func Enabled() bool {
+	return currentRecorder() != nil
+}
+
+// Record 记录一条审计日志，操作人、IP、User-Agent 和请求ID 未填写时从上下文中补全
+// 审计日志写入失败不应影响业务，调用方通常忽略返回的错误
+func Record(ctx context.Context, entry *Entry) error {
+	r := currentRecorder()
+	if r == nil || entry == nil {
+		return nil
+	}
+	fill(ctx, entry)
+	return r.Record(ctx, entry)
+}
+
+// fill 用上下文中的信息补全未填写的字段
+func fill(ctx context.Context, entry *Entry) {
+	if entry.ActorId == "" {
+		entry.ActorId, _ = userContext.GetUserID(ctx)
+	}
+	if info, ok := RequestInfoFromContext(ctx); ok {
+		if entry.ClientIp == "" {
+			entry.ClientIp = info.ClientIp
+		}
+		if entry.UserAgent == "" {
+			entry.UserAgent = info.UserAgent
+		}
+		if entry.RequestId == "" {
+			entry.RequestId = info.RequestId
+		}
+	}
+	if entry.Source == "" {
+		entry.Source = SourceService
+	}
+	if entry.OccurredAt.IsZero() {
+		entry.OccurredAt = time.Now()
+	}
+	if len(entry.Changes) == 0 && entry.Before != nil && entry.After != nil {
+		entry.Changes = Diff(entry.Before, entry.After)
+	}
+}
+
+// RequestInfo 请求来源信息，由 HTTP 中间件写入上下文
+type RequestInfo struct {
+	ClientIp  string
+	UserAgent string
+	RequestId string
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo 将请求来源信息写入上下文
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext 从上下文中获取请求来源信息
+func RequestInfoFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info, ok
+}
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+)
+
+type testRecorder struct {
+	entries []*Entry
+}
+
+func (r *testRecorder) Record(ctx context.Context, entry *Entry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func TestRecordWithoutRecorder(t *testing.T) {
+	SetRecorder(nil)
+	if Enabled() {
+		t.Fatalf("Enabled() = true, want false")
+	}
+	if err := Record(context.Background(), &Entry{Action: ActionDelete}); err != nil {
+		t.Fatalf("Record err = %v", err)
+	}
+}
+
+func TestRecordFillsFromContext(t *testing.T) {
+	recorder := &testRecorder{}
+	SetRecorder(recorder)
+	defer SetRecorder(nil)
+
+	ctx := userContext.NewUserContext().SetUserID("u-1").ToContext(context.Background())
+	ctx = WithRequestInfo(ctx, RequestInfo{ClientIp: "10.0.0.1", UserAgent: "ua", RequestId: "req-1"})
+	type glossary struct {
+		Text  string `json:"text"`
+		Match bool   `json:"match"`
+	}
+	err := Record(ctx, &Entry{
+		Action:     ActionUpdate,
+		EntityType: "glossary",
+		EntityId:   "g-1",
+		Before:     glossary{Text: "a", Match: true},
+		After:      glossary{Text: "b", Match: true},
+	})
+	if err != nil {
+		t.Fatalf("Record err = %v", err)
+	}
+	if len(recorder.entries) != 1 {
+		t.Fatalf("recorded %d entries, want 1", len(recorder.entries))
+	}
+	entry := recorder.entries[0]
+	if entry.ActorId != "u-1" || entry.ClientIp != "10.0.0.1" || entry.UserAgent != "ua" || entry.RequestId != "req-1" {
+		t.Fatalf("entry not filled from context: %+v", entry)
+	}
+	if entry.Source != SourceService || entry.OccurredAt.IsZero() {
+		t.Fatalf("entry defaults not set: source=%q occurredAt=%v", entry.Source, entry.OccurredAt)
+	}
+	if len(entry.Changes) != 1 || entry.Changes[0].Field != "text" || entry.Changes[0].Before != "a" || entry.Changes[0].After != "b" {
+		t.Fatalf("changes = %+v, want only text a -> b", entry.Changes)
+	}
+}
+
+func TestRecordKeepsExplicitActor(t *testing.T) {
+	recorder := &testRecorder{}
+	SetRecorder(recorder)
+	defer SetRecorder(nil)
+
+	ctx := userContext.NewUserContext().SetUserID("u-1").ToContext(context.Background())
+	_ = Record(ctx, &Entry{ActorId: "admin", Action: ActionRefund})
+	if got := recorder.entries[0].ActorId; got != "admin" {
+		t.Fatalf("ActorId = %q, want admin", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := map[string]any{"name": "a", "count": 1, "password": "old", "removed": true}
+	after := map[string]any{"name": "a", "count": 2, "password": "new", "added": "x"}
+	changes := Diff(before, after)
+
+	want := []FieldChange{
+		{Field: "added", After: "x"},
+		{Field: "count", Before: float64(1), After: float64(2)},
+		{Field: "password", Before: redactedValue, After: redactedValue},
+		{Field: "removed", Before: true},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("changes[%d] = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestDiffScalar(t *testing.T) {
+	if changes := Diff(1, 1); changes != nil {
+		t.Fatalf("Diff(1, 1) = %+v, want nil", changes)
+	}
+	changes := Diff("a", "b")
+	if len(changes) != 1 || changes[0].Field != "" || changes[0].Before != "a" || changes[0].After != "b" {
+		t.Fatalf("Diff(a, b) = %+v", changes)
+	}
+}
+
+func TestSnapshotRedactsSensitiveFields(t *testing.T) {
+	got := Snapshot(map[string]any{"name": "a", "accessToken": "t"})
+	want := `{"accessToken":"***","name":"a"}`
+	if got != want {
+		t.Fatalf("Snapshot = %s, want %s", got, want)
+	}
+	if got := Snapshot(nil); got != "" {
+		t.Fatalf("Snapshot(nil) = %q, want empty", got)
+	}
+}
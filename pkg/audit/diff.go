@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// redactedValue 敏感字段在差异中的替代值
+const redactedValue = "***"
+
+// sensitiveKeywords 字段名包含这些关键字时不记录原值
+var sensitiveKeywords = []string{"password", "secret", "token", "salt"}
+
+// FieldChange 单个字段的变更
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// Diff 比较变更前后的数据，返回按字段名排序的差异
+// 数据按 JSON 序列化后逐个顶层字段比较；不是对象时整体比较，字段名为空
+func Diff(before any, after any) []FieldChange {
+	beforeValue, beforeOk := toJSONValue(before)
+	afterValue, afterOk := toJSONValue(after)
+	if !beforeOk || !afterOk {
+		return nil
+	}
+
+	beforeMap, beforeIsMap := beforeValue.(map[string]any)
+	afterMap, afterIsMap := afterValue.(map[string]any)
+	if !beforeIsMap || !afterIsMap {
+		if reflect.DeepEqual(beforeValue, afterValue) {
+			return nil
+		}
+		return []FieldChange{{Before: beforeValue, After: afterValue}}
+	}
+
+	fields := make(map[string]struct{}, len(beforeMap)+len(afterMap))
+	for field := range beforeMap {
+		fields[field] = struct{}{}
+	}
+	for field := range afterMap {
+		fields[field] = struct{}{}
+	}
+
+	var changes []FieldChange
+	for field := range fields {
+		oldValue, newValue := beforeMap[field], afterMap[field]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		change := FieldChange{Field: field, Before: oldValue, After: newValue}
+		if IsSensitiveField(field) {
+			change.Before, change.After = redactedValue, redactedValue
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// Snapshot 将数据序列化为 JSON 快照，敏感字段替换为掩码，数据为空或无法序列化时返回空串
+func Snapshot(value any) string {
+	jsonValue, ok := toJSONValue(value)
+	if !ok || jsonValue == nil {
+		return ""
+	}
+	if object, isMap := jsonValue.(map[string]any); isMap {
+		for field := range object {
+			if IsSensitiveField(field) {
+				object[field] = redactedValue
+			}
+		}
+	}
+	data, err := json.Marshal(jsonValue)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// IsSensitiveField 判断字段名是否属于敏感字段
+func IsSensitiveField(field string) bool {
+	lower := strings.ToLower(field)
+	for _, keyword := range sensitiveKeywords {
+		if strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// toJSONValue 将数据按 JSON 序列化再反序列化为通用结构，便于比较
+func toJSONValue(value any) (any, bool) {
+	if value == nil {
+		return nil, true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var result any
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, false
+	}
+	return result, true
+}
@@ -28,6 +28,12 @@ type Cache interface {
 	// SetNotBizPrefix 将值存入缓存，不带业务前缀
 	SetNotBizPrefix(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 
+	// SetNX 键不存在时才存入值，返回是否存入，并发调用时只有一个调用方能存入
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)
+
+	// GetAndDelete 原子地获取并删除值，并发调用时只有一个调用方能取到值
+	GetAndDelete(ctx context.Context, key string, dest interface{}) (bool, error)
+
 	// Delete 从缓存中删除值
 	Delete(ctx context.Context, key string) error
 
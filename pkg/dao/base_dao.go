@@ -638,6 +638,23 @@ func (d *GormBaseDAO[T]) DeleteByIds(ctx context.Context, ids []string) error {
 	return nil
 }
 
+// RestoreByIds 恢复逻辑删除的数据
+func (d *GormBaseDAO[T]) RestoreByIds(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return errors.New("ids cannot be empty")
+	}
+
+	updates := handlerBeforeUpdate(ctx, false)
+	updates["is_deleted"] = false
+	result := d.getDBFromContext(ctx).Model(new(T)).Where("id in ?", ids).Updates(updates)
+	if result.Error != nil {
+		d.logger.Error("msg", "恢复逻辑删除数据失败", "ids", ids, "error", result.Error.Error())
+		return result.Error
+	}
+
+	return nil
+}
+
 // 处理BeforeDelete, context中需要包含用户信息
 func handlerBeforeUpdate(ctx context.Context, isDeleted bool) map[string]interface{} {
 	// 创建更新映射
@@ -675,6 +692,36 @@ func (d *GormBaseDAO[T]) RemoveById(ctx context.Context, id string) error {
 	return nil
 }
 
+// RemoveByUserId 物理删除指定用户的全部数据（包括已逻辑删除的），仅适用于包含 user_id 字段的表，返回删除的行数
+func (d *GormBaseDAO[T]) RemoveByUserId(ctx context.Context, userId string) (int64, error) {
+	if userId == "" {
+		return 0, errors.New("userId cannot be empty")
+	}
+
+	var entity T
+	result := d.getDBFromContext(ctx).Where("user_id = ?", userId).Delete(&entity)
+	if result.Error != nil {
+		d.logger.Error("msg", "按用户物理删除数据失败", "userId", userId, "error", result.Error.Error())
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// RemoveByCreatorId 物理删除指定用户创建的全部数据（包括已逻辑删除的），用于没有 user_id 字段、按创建人区分归属的表，返回删除的行数
+func (d *GormBaseDAO[T]) RemoveByCreatorId(ctx context.Context, creatorId string) (int64, error) {
+	if creatorId == "" {
+		return 0, errors.New("creatorId cannot be empty")
+	}
+
+	var entity T
+	result := d.getDBFromContext(ctx).Where("creator_id = ?", creatorId).Delete(&entity)
+	if result.Error != nil {
+		d.logger.Error("msg", "按创建人物理删除数据失败", "creatorId", creatorId, "error", result.Error.Error())
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
 // 获取数据库字段名，考虑 GORM 标签
 func getDBFieldName(field reflect.StructField) string {
 	// 尝试从 GORM 标签获取字段名
@@ -0,0 +1,60 @@
+// Package daotest 为服务层测试提供基于 SQLite 临时文件的数据库
+package daotest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yb2020/odoc/pkg/logging"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// NewDB 创建测试数据库并迁移给定的模型，测试结束后自动关闭
+// 使用 WAL 模式的临时文件而不是内存数据库，事务外的查询不会被事务持有的连接阻塞
+func NewDB(t testing.TB, models ...any) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_journal_mode=WAL&_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+		NamingStrategy: schema.NamingStrategy{
+			SingularTable: true,
+		},
+		SkipDefaultTransaction: true,
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("parse model: %v", err)
+		}
+		// SQLite 驱动只把声明为 timestamp/datetime/date 的列解析为时间，PostgreSQL 的 timestamptz 改用 datetime
+		for _, field := range stmt.Schema.Fields {
+			if field.DataType == "timestamptz" {
+				field.DataType = "datetime"
+			}
+		}
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// NewLogger 创建只输出错误日志的测试日志记录器
+func NewLogger() logging.Logger {
+	return logging.NewLogger("error", logging.LogFormatLogfmt)
+}
@@ -0,0 +1,141 @@
+package docformat
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// anchorContextLength 锚点前后保存的上下文字数
+const anchorContextLength = 32
+
+// ErrInvalidAnchor 锚点位置越界或选中的文本为空
+var ErrInvalidAnchor = errors.New("invalid text anchor")
+
+// Anchor 文本锚点，对应 W3C Web Annotation 的 TextQuoteSelector 与 TextPositionSelector。
+//
+// Start、End 是在文档 Markdown 中按 Unicode 字符计算的偏移；文档重新转换后偏移可能失效，
+// 此时根据 Exact 及其前后文重新定位。
+type Anchor struct {
+	Exact  string `json:"exact"`
+	Prefix string `json:"prefix"`
+	Suffix string `json:"suffix"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+}
+
+// NewAnchor 根据选中范围创建锚点
+func NewAnchor(text string, start, end int) (Anchor, error) {
+	runes := []rune(text)
+	if start < 0 || end > len(runes) || start >= end {
+		return Anchor{}, ErrInvalidAnchor
+	}
+	prefixStart := start - anchorContextLength
+	if prefixStart < 0 {
+		prefixStart = 0
+	}
+	suffixEnd := end + anchorContextLength
+	if suffixEnd > len(runes) {
+		suffixEnd = len(runes)
+	}
+	return Anchor{
+		Exact:  string(runes[start:end]),
+		Prefix: string(runes[prefixStart:start]),
+		Suffix: string(runes[end:suffixEnd]),
+		Start:  start,
+		End:    end,
+	}, nil
+}
+
+// Resolve 在文本中定位锚点，返回选中范围的字符偏移。
+//
+// 依次尝试：原位置的文本与 Exact 一致；Exact 的全部出现位置中前后文最匹配、离原位置最近的一处；
+// 忽略空白差异后的匹配。都失败时 ok 为 false。
+func Resolve(text string, anchor Anchor) (start, end int, ok bool) {
+	if anchor.Exact == "" {
+		return 0, 0, false
+	}
+	runes := []rune(text)
+	exactLen := utf8.RuneCountInString(anchor.Exact)
+	if anchor.Start >= 0 && anchor.End <= len(runes) && anchor.End-anchor.Start == exactLen &&
+		string(runes[anchor.Start:anchor.End]) == anchor.Exact {
+		return anchor.Start, anchor.End, true
+	}
+
+	bestScore, bestDistance := -1, 0
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], anchor.Exact)
+		if i < 0 {
+			break
+		}
+		byteStart := offset + i
+		s := utf8.RuneCountInString(text[:byteStart])
+		score := contextScore(runes, s, s+exactLen, anchor)
+		distance := abs(s - anchor.Start)
+		if score > bestScore || (score == bestScore && distance < bestDistance) {
+			start, end, bestScore, bestDistance = s, s+exactLen, score, distance
+		}
+		_, size := utf8.DecodeRuneInString(text[byteStart:])
+		offset = byteStart + size
+	}
+	if bestScore >= 0 {
+		return start, end, true
+	}
+	return resolveIgnoringSpace(runes, anchor)
+}
+
+// contextScore 前缀从后往前、后缀从前往后与文本相同的字数之和
+func contextScore(runes []rune, start, end int, anchor Anchor) int {
+	score := 0
+	prefix := []rune(anchor.Prefix)
+	for i := 1; i <= len(prefix) && start-i >= 0; i++ {
+		if prefix[len(prefix)-i] != runes[start-i] {
+			break
+		}
+		score++
+	}
+	suffix := []rune(anchor.Suffix)
+	for i := 0; i < len(suffix) && end+i < len(runes); i++ {
+		if suffix[i] != runes[end+i] {
+			break
+		}
+		score++
+	}
+	return score
+}
+
+// resolveIgnoringSpace 去掉空白后匹配，兼容重新转换后换行和缩进的变化
+func resolveIgnoringSpace(runes []rune, anchor Anchor) (int, int, bool) {
+	var compact []rune
+	var positions []int // compact 中每个字符在原文中的位置
+	for i, r := range runes {
+		if !unicode.IsSpace(r) {
+			compact = append(compact, r)
+			positions = append(positions, i)
+		}
+	}
+	var exact []rune
+	for _, r := range anchor.Exact {
+		if !unicode.IsSpace(r) {
+			exact = append(exact, r)
+		}
+	}
+	if len(exact) == 0 {
+		return 0, 0, false
+	}
+	i := strings.Index(string(compact), string(exact))
+	if i < 0 {
+		return 0, 0, false
+	}
+	first := utf8.RuneCountInString(string(compact)[:i])
+	last := first + len(exact) - 1
+	return positions[first], positions[last] + 1, true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
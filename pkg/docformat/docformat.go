@@ -0,0 +1,185 @@
+// Package docformat 识别 EPUB、DOCX、HTML、Markdown 等非PDF文档，并转换为统一的 Markdown 表示。
+//
+// 转换后的 Markdown 通过 docparser.FromMarkdown 生成与PDF解析一致的元数据和全文段落，
+// 阅读、检索和翻译均基于这份 Markdown；标注使用文本锚点定位（见 anchor.go）。
+package docformat
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yb2020/odoc/pkg/docparser"
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+)
+
+// Format 文档格式
+type Format string
+
+const (
+	FormatPDF      Format = "pdf"
+	FormatEPUB     Format = "epub"
+	FormatDOCX     Format = "docx"
+	FormatHTML     Format = "html"
+	FormatMarkdown Format = "markdown"
+)
+
+// ErrUnsupported 无法识别或不支持的文档格式
+var ErrUnsupported = errors.New("unsupported document format")
+
+// Normalize 规范化格式名称，历史数据为空时视为PDF
+func Normalize(format string) Format {
+	f := Format(strings.ToLower(strings.TrimSpace(format)))
+	if f == "" {
+		return FormatPDF
+	}
+	return f
+}
+
+// Extension 保存源文件时使用的扩展名
+func (f Format) Extension() string {
+	switch f {
+	case FormatMarkdown:
+		return ".md"
+	case FormatHTML:
+		return ".html"
+	case "":
+		return ""
+	default:
+		return "." + string(f)
+	}
+}
+
+// Document 转换后的文档
+type Document struct {
+	Format   Format
+	Title    string
+	Authors  []string
+	Language string
+	Markdown string
+}
+
+// Parse 由 Markdown 生成元数据和全文段落，转换时已知的标题和作者优先
+func (d *Document) Parse() (*pb.DocumentMetadata, *pb.FullDocument) {
+	metadata, fullDocument := docparser.FromMarkdown(d.Markdown)
+	if d.Title != "" && (metadata.Title == nil || metadata.Title.Text == "") {
+		metadata.Title = &pb.Title{Text: d.Title}
+	}
+	if len(d.Authors) > 0 && len(metadata.Authors) == 0 {
+		for _, name := range d.Authors {
+			metadata.Authors = append(metadata.Authors, &pb.Author{FullName: name})
+		}
+	}
+	return metadata, fullDocument
+}
+
+// EstimatePages 按字数估算页数，用于会员额度和解析规则
+func (d *Document) EstimatePages() int {
+	const charsPerPage = 3000
+	pages := (utf8.RuneCountInString(d.Markdown) + charsPerPage - 1) / charsPerPage
+	if pages < 1 {
+		return 1
+	}
+	return pages
+}
+
+// Detect 根据文件内容和文件名识别文档格式，无法识别时返回空
+func Detect(fileName string, content []byte) Format {
+	if bytes.HasPrefix(content, []byte("%PDF-")) {
+		return FormatPDF
+	}
+	if bytes.HasPrefix(content, []byte("PK\x03\x04")) {
+		return detectZip(content)
+	}
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".pdf":
+		return FormatPDF
+	case ".md", ".markdown", ".txt":
+		return FormatMarkdown
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML
+	}
+	if looksLikeHTML(content) {
+		return FormatHTML
+	}
+	if utf8.Valid(content) && len(bytes.TrimSpace(content)) > 0 {
+		return FormatMarkdown
+	}
+	return ""
+}
+
+// detectZip 根据压缩包中的条目区分 EPUB 和 DOCX
+func detectZip(content []byte) Format {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return ""
+	}
+	for _, f := range reader.File {
+		switch f.Name {
+		case "mimetype":
+			data, err := readZipFile(f)
+			if err == nil && strings.TrimSpace(string(data)) == "application/epub+zip" {
+				return FormatEPUB
+			}
+		case "word/document.xml":
+			return FormatDOCX
+		case "META-INF/container.xml":
+			return FormatEPUB
+		}
+	}
+	return ""
+}
+
+// looksLikeHTML 判断文本内容是否为HTML页面
+func looksLikeHTML(content []byte) bool {
+	head := content
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	head = bytes.ToLower(bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))))
+	return bytes.HasPrefix(head, []byte("<!doctype html")) || bytes.HasPrefix(head, []byte("<html")) ||
+		(bytes.HasPrefix(head, []byte("<?xml")) && bytes.Contains(head, []byte("<html")))
+}
+
+// Convert 将文档转换为 Markdown，PDF 不在此处理
+func Convert(format Format, content []byte) (*Document, error) {
+	var (
+		doc *Document
+		err error
+	)
+	switch format {
+	case FormatEPUB:
+		doc, err = ConvertEPUB(content)
+	case FormatDOCX:
+		doc, err = ConvertDOCX(content)
+	case FormatHTML:
+		doc, err = ConvertHTML(content, "")
+	case FormatMarkdown:
+		doc, err = ConvertMarkdown(content)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(doc.Markdown) == "" {
+		return nil, errors.New("document has no readable text")
+	}
+	return doc, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(rc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,250 @@
+package docformat
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// buildZip 按顺序写入压缩包条目
+func buildZip(t *testing.T, entries [][2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e[0])
+		if err != nil {
+			t.Fatalf("创建压缩包条目失败: %v", err)
+		}
+		w.Write([]byte(e[1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("写入压缩包失败: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func testEPUB(t *testing.T) []byte {
+	return buildZip(t, [][2]string{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", `<?xml version="1.0"?>
+<container xmlns="urn:oasis:names:tc:opendocument:xmlns:container" version="1.0">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`},
+		{"OEBPS/content.opf", `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" xmlns:dc="http://purl.org/dc/elements/1.1/" version="3.0">
+  <metadata>
+    <dc:title>Deep Reading</dc:title>
+    <dc:creator>Alice Zhang</dc:creator>
+    <dc:creator>Bob Li</dc:creator>
+    <dc:language>en</dc:language>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="c2" href="text/chapter%202.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c1" href="text/chapter1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+  </manifest>
+  <spine><itemref idref="nav"/><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`},
+		{"OEBPS/nav.xhtml", `<html><body><nav><ol><li>Chapter 1</li></ol></nav></body></html>`},
+		{"OEBPS/text/chapter1.xhtml", `<html xmlns="http://www.w3.org/1999/xhtml"><body>
+<h1>1 Introduction</h1><p>Reading is <em>fun</em>.</p><img src="../images/a.png" alt="cover"/></body></html>`},
+		{"OEBPS/text/chapter 2.xhtml", `<html><body><h1>2 Method</h1><ul><li>first</li><li>second<ul><li>nested</li></ul></li></ul></body></html>`},
+	})
+}
+
+func testDOCX(t *testing.T) []byte {
+	return buildZip(t, [][2]string{
+		{"[Content_Types].xml", `<Types/>`},
+		{"word/styles.xml", `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:style w:styleId="1"><w:name w:val="heading 1"/></w:style>
+  <w:style w:styleId="Heading2"><w:name w:val="heading 2"/></w:style>
+</w:styles>`},
+		{"docProps/core.xml", `<cp:coreProperties xmlns:cp="c" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <dc:title>Word Manuscript</dc:title><dc:creator>Carol Wang</dc:creator></cp:coreProperties>`},
+		{"word/document.xml", `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="1"/></w:pPr><w:r><w:t>Introduction</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Plain </w:t></w:r><w:r><w:t>text.</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>item one</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>item two</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Details</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>A</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>B</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>1</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>2</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+<w:p><w:r><w:drawing/></w:r></w:p>
+<w:p><w:r><w:t>Figure 1: A drawing.</w:t></w:r></w:p>
+</w:body></w:document>`},
+	})
+}
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name    string
+		file    string
+		content []byte
+		want    Format
+	}{
+		{"pdf", "a.bin", []byte("%PDF-1.7"), FormatPDF},
+		{"epub", "book", testEPUB(t), FormatEPUB},
+		{"docx", "paper.docx", testDOCX(t), FormatDOCX},
+		{"html按内容", "page", []byte("<!DOCTYPE html><html></html>"), FormatHTML},
+		{"markdown按扩展名", "notes.md", []byte("# Notes"), FormatMarkdown},
+		{"其他压缩包", "a.zip", buildZip(t, [][2]string{{"a.txt", "x"}}), ""},
+		{"二进制", "a.bin", []byte{0xff, 0xfe, 0x00}, ""},
+	}
+	for _, c := range cases {
+		if got := Detect(c.file, c.content); got != c.want {
+			t.Errorf("%s: 期望 %q, 实际 %q", c.name, c.want, got)
+		}
+	}
+	if Normalize("") != FormatPDF || Normalize(" EPUB ") != FormatEPUB {
+		t.Errorf("格式规范化错误")
+	}
+}
+
+func TestConvertEPUB(t *testing.T) {
+	doc, err := ConvertEPUB(testEPUB(t))
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	want := "# Deep Reading\n\n# 1 Introduction\n\nReading is *fun*.\n\n![cover]()\n\n# 2 Method\n\n- first\n- second\n  - nested\n"
+	if doc.Markdown != want {
+		t.Errorf("Markdown 错误:\n%s", doc.Markdown)
+	}
+	if doc.Title != "Deep Reading" || len(doc.Authors) != 2 || doc.Language != "en" {
+		t.Errorf("文档属性错误: %+v", doc)
+	}
+	metadata, _ := doc.Parse()
+	if metadata.Title.GetText() != "Deep Reading" || len(metadata.Authors) != 2 {
+		t.Errorf("元数据错误: %v", metadata)
+	}
+}
+
+func TestConvertDOCX(t *testing.T) {
+	doc, err := ConvertDOCX(testDOCX(t))
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	want := "# Word Manuscript\n\n# Introduction\n\nPlain text.\n\n- item one\n  - item two\n\n## Details\n\n" +
+		"| A | B |\n| --- | --- |\n| 1 | 2 |\n\n![]()\n\nFigure 1: A drawing.\n"
+	if doc.Markdown != want {
+		t.Errorf("Markdown 错误:\n%s", doc.Markdown)
+	}
+	if doc.Title != "Word Manuscript" || len(doc.Authors) != 1 || doc.Authors[0] != "Carol Wang" {
+		t.Errorf("文档属性错误: %+v", doc)
+	}
+	metadata, _ := doc.Parse()
+	paired := false
+	for _, figure := range metadata.FiguresAndTables {
+		paired = paired || figure.RefIdx == "Figure 1"
+	}
+	if !paired {
+		t.Errorf("图片应与图注配对: %v", metadata.FiguresAndTables)
+	}
+}
+
+func TestConvertHTML(t *testing.T) {
+	page := `<!DOCTYPE html><html lang="en"><head><title>Layout Parsing | Blog</title>
+<meta name="citation_author" content="Alice Zhang"></head><body>
+<nav><a href="/">Home</a></nav>
+<article>
+  <h1>Layout Parsing</h1>
+  <p>Documents have <strong>structure</strong>, see <a href="/docs/intro">the intro</a> and <a href="#fn1">[1]</a>.</p>
+  <h2>Tables</h2>
+  <table><caption>Table 1: Results</caption><tr><th>Model</th><th>F1</th></tr><tr><td>Ours</td><td>0.9</td></tr></table>
+  <p>` + strings.Repeat("Long body text. ", 20) + `</p>
+  <script>alert(1)</script>
+</article>
+<footer>Copyright</footer></body></html>`
+	doc, err := ConvertHTML([]byte(page), "https://example.com/posts/1")
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if doc.Title != "Layout Parsing" || doc.Language != "en" || len(doc.Authors) != 1 {
+		t.Errorf("文档属性错误: %+v", doc)
+	}
+	for _, want := range []string{
+		"# Layout Parsing\n\nDocuments have **structure**, see [the intro](https://example.com/docs/intro) and [1].",
+		"## Tables\n\nTable 1: Results\n\n| Model | F1 |\n| --- | --- |\n| Ours | 0.9 |",
+	} {
+		if !strings.Contains(doc.Markdown, want) {
+			t.Errorf("Markdown 缺少 %q:\n%s", want, doc.Markdown)
+		}
+	}
+	for _, noise := range []string{"Home", "Copyright", "alert"} {
+		if strings.Contains(doc.Markdown, noise) {
+			t.Errorf("Markdown 不应包含 %q", noise)
+		}
+	}
+}
+
+func TestConvertMarkdown(t *testing.T) {
+	doc, err := Convert(FormatMarkdown, []byte("---\ntitle: \"Notes\"\nauthors:\n  - Alice\n  - Bob\nlang: zh\n---\n\nBody text.\n"))
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if doc.Markdown != "# Notes\n\nBody text.\n" || doc.Language != "zh" || strings.Join(doc.Authors, ",") != "Alice,Bob" {
+		t.Errorf("转换结果错误: %+v", doc)
+	}
+	if _, err := Convert(FormatPDF, nil); err != ErrUnsupported {
+		t.Errorf("PDF 应返回 ErrUnsupported, 实际 %v", err)
+	}
+}
+
+func TestAnchor(t *testing.T) {
+	text := "The cat sat. The cat ran. 猫在跑。"
+	anchor, err := NewAnchor(text, 17, 20)
+	if err != nil || anchor.Exact != "cat" || anchor.Prefix != "The cat sat. The " || anchor.Suffix != " ran. 猫在跑。" {
+		t.Fatalf("创建锚点错误: %+v %v", anchor, err)
+	}
+	if _, err := NewAnchor(text, 5, 5); err != ErrInvalidAnchor {
+		t.Errorf("空选区应返回 ErrInvalidAnchor")
+	}
+
+	// 原位置不变
+	if s, e, ok := Resolve(text, anchor); !ok || s != 17 || e != 20 {
+		t.Errorf("原位置定位错误: %d %d %v", s, e, ok)
+	}
+	// 文档前面插入内容后根据上下文选择第二个 cat
+	edited := "Intro. " + text
+	if s, e, ok := Resolve(edited, anchor); !ok || s != 24 || e != 27 {
+		t.Errorf("重新定位错误: %d %d %v", s, e, ok)
+	}
+	// 多字节字符按字符计算偏移
+	zh, _ := NewAnchor(text, 26, 28)
+	if s, _, ok := Resolve("前言。"+text, zh); !ok || s != 29 || zh.Exact != "猫在" {
+		t.Errorf("中文定位错误: %d %v %q", s, ok, zh.Exact)
+	}
+	// 换行变化
+	wrapped := Anchor{Exact: "cat sat. The cat", Start: 100, End: 116}
+	if s, e, ok := Resolve("The cat sat.\nThe  cat ran.", wrapped); !ok || s != 4 || e != 21 {
+		t.Errorf("忽略空白定位错误: %d %d %v", s, e, ok)
+	}
+	if _, _, ok := Resolve(text, Anchor{Exact: "dog"}); ok {
+		t.Errorf("不存在的文本不应定位成功")
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	markdown := "# Title\n\nFirst paragraph here.\n\n- item one\n- item two\n"
+	first, _ := NewAnchor(markdown, 9, 14)
+	list, _ := NewAnchor(markdown, 34, 53)
+	overlap, _ := NewAnchor(markdown, 10, 20)
+	annotations := []Annotation{
+		{Anchor: list, Color: "#ff0"},
+		{Anchor: first, Note: "key term"},
+		{Anchor: overlap, Note: "overlapping"},
+		{Anchor: Anchor{Exact: "missing text"}, Note: "lost"},
+	}
+	out, unresolved := ExportMarkdown(markdown, annotations)
+	if unresolved != 1 {
+		t.Errorf("未定位数量错误: %d", unresolved)
+	}
+	want := "# Title\n\n<mark>First</mark>[^1] paragraph here.\n\n" +
+		"- <mark style=\"background-color: #ff0\">item one</mark>\n- <mark style=\"background-color: #ff0\">item two</mark>\n\n" +
+		"[^1]: key term\n\n---\n\n> missing text\n\nlost\n\n> irst parag\n\noverlapping\n"
+	if out != want {
+		t.Errorf("导出结果错误:\n%s", out)
+	}
+}
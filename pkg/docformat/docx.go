@@ -0,0 +1,306 @@
+package docformat
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// docxBlock 文档中的一个段落或表格
+type docxBlock struct {
+	text    string
+	heading int // 标题层级，0 表示正文；Title 样式为 1
+	list    int // 列表层级加一，0 表示不是列表
+	table   [][]string
+	image   bool // 只包含图片的段落
+}
+
+// docxCore docProps/core.xml 中的文档属性
+type docxCore struct {
+	Title    string `xml:"title"`
+	Creator  string `xml:"creator"`
+	Language string `xml:"language"`
+}
+
+// docxStyles word/styles.xml，用于将样式ID映射为样式名称
+type docxStyles struct {
+	Styles []struct {
+		ID   string `xml:"styleId,attr"`
+		Name struct {
+			Val string `xml:"val,attr"`
+		} `xml:"name"`
+	} `xml:"style"`
+}
+
+// ConvertDOCX 将 Word 文档转换为 Markdown，标题样式转换为标题，编号段落转换为列表
+func ConvertDOCX(content []byte) (*Document, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("open docx: %w", err)
+	}
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		files[f.Name] = f
+	}
+	main, ok := files["word/document.xml"]
+	if !ok {
+		return nil, fmt.Errorf("docx document part not found")
+	}
+
+	styles := map[string]string{}
+	if f, ok := files["word/styles.xml"]; ok {
+		if data, err := readZipFile(f); err == nil {
+			var s docxStyles
+			if xml.Unmarshal(data, &s) == nil {
+				for _, style := range s.Styles {
+					styles[style.ID] = strings.ToLower(style.Name.Val)
+				}
+			}
+		}
+	}
+
+	data, err := readZipFile(main)
+	if err != nil {
+		return nil, fmt.Errorf("read docx document: %w", err)
+	}
+	blocks, err := parseDocxBody(data, styles)
+	if err != nil {
+		return nil, fmt.Errorf("decode docx document: %w", err)
+	}
+
+	doc := &Document{Format: FormatDOCX}
+	if f, ok := files["docProps/core.xml"]; ok {
+		if data, err := readZipFile(f); err == nil {
+			var core docxCore
+			if xml.Unmarshal(data, &core) == nil {
+				doc.Title = normalizeSpace(core.Title)
+				doc.Language = strings.TrimSpace(core.Language)
+				for _, name := range strings.Split(core.Creator, ";") {
+					if name = normalizeSpace(name); name != "" {
+						doc.Authors = append(doc.Authors, name)
+					}
+				}
+			}
+		}
+	}
+	// 没有文档属性时使用 Title 样式或第一个标题
+	for _, b := range blocks {
+		if doc.Title == "" && b.heading > 0 {
+			doc.Title = b.text
+			break
+		}
+	}
+	doc.Markdown = withTitle(doc.Title, renderDocxBlocks(blocks))
+	return doc, nil
+}
+
+// parseDocxBody 顺序读取 word/document.xml 中的段落和表格
+func parseDocxBody(data []byte, styles map[string]string) ([]docxBlock, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var (
+		blocks    []docxBlock
+		para      *docxBlock
+		text      strings.Builder
+		inText    bool
+		tableRows [][]string // 当前最外层表格
+		row       []string
+		cell      []string
+		tableDeep int
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tbl":
+				tableDeep++
+				if tableDeep == 1 {
+					tableRows = nil
+				}
+			case "tr":
+				if tableDeep == 1 {
+					row = nil
+				}
+			case "tc":
+				if tableDeep == 1 {
+					cell = nil
+				}
+			case "p":
+				para = &docxBlock{}
+				text.Reset()
+			case "pStyle":
+				if para != nil {
+					para.heading = docxHeadingLevel(styles[docxAttr(t, "val")], docxAttr(t, "val"))
+				}
+			case "ilvl":
+				if para != nil {
+					level, _ := strconv.Atoi(docxAttr(t, "val"))
+					para.list = level + 1
+				}
+			case "numPr":
+				if para != nil && para.list == 0 {
+					para.list = 1
+				}
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteString(" ")
+			case "br", "cr":
+				text.WriteString(" ")
+			case "drawing", "pict":
+				if para != nil {
+					para.image = true
+				}
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if para == nil {
+					continue
+				}
+				para.text = normalizeSpace(text.String())
+				if tableDeep > 0 {
+					if para.text != "" {
+						cell = append(cell, para.text)
+					}
+				} else if para.text != "" || para.image {
+					if para.text != "" {
+						para.image = false
+					}
+					blocks = append(blocks, *para)
+				}
+				para = nil
+			case "tc":
+				if tableDeep == 1 {
+					row = append(row, strings.Join(cell, " "))
+				}
+			case "tr":
+				if tableDeep == 1 && len(row) > 0 {
+					tableRows = append(tableRows, row)
+				}
+			case "tbl":
+				tableDeep--
+				if tableDeep == 0 && len(tableRows) > 0 {
+					blocks = append(blocks, docxBlock{table: tableRows})
+				}
+			}
+		}
+	}
+	return blocks, nil
+}
+
+// docxHeadingLevel 根据样式名称判断标题层级，兼容本地化样式ID（如 "1"、"2"）
+func docxHeadingLevel(name, id string) int {
+	if name == "" {
+		name = strings.ToLower(id)
+	}
+	name = strings.ReplaceAll(name, " ", "")
+	switch {
+	case name == "title":
+		return 1
+	case strings.HasPrefix(name, "heading"):
+		level, err := strconv.Atoi(strings.TrimPrefix(name, "heading"))
+		if err != nil || level < 1 {
+			return 0
+		}
+		// Title 占用一级标题
+		if level+1 > 6 {
+			return 6
+		}
+		return level + 1
+	}
+	return 0
+}
+
+func docxAttr(t xml.StartElement, local string) string {
+	for _, attr := range t.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// renderDocxBlocks 输出 Markdown；文档没有 Title 样式时标题整体提升一级
+func renderDocxBlocks(blocks []docxBlock) string {
+	shift := 1
+	for _, b := range blocks {
+		if b.heading == 1 {
+			shift = 0
+			break
+		}
+	}
+	var out []string
+	var list []string
+	flushList := func() {
+		if len(list) > 0 {
+			out = append(out, strings.Join(list, "\n"))
+			list = nil
+		}
+	}
+	for _, b := range blocks {
+		switch {
+		case b.list > 0 && b.heading == 0:
+			list = append(list, strings.Repeat("  ", b.list-1)+"- "+b.text)
+			continue
+		case b.table != nil:
+			flushList()
+			out = append(out, renderTable(b.table))
+		case b.heading > 0:
+			flushList()
+			level := b.heading - shift
+			if level < 1 {
+				level = 1
+			}
+			out = append(out, strings.Repeat("#", level)+" "+b.text)
+		case b.image:
+			flushList()
+			out = append(out, "![]()")
+		default:
+			flushList()
+			out = append(out, b.text)
+		}
+	}
+	flushList()
+	return strings.Join(out, "\n\n")
+}
+
+// renderTable 输出 Markdown 表格，第一行作为表头
+func renderTable(rows [][]string) string {
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	var sb strings.Builder
+	for i, row := range rows {
+		cells := make([]string, width)
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = strings.ReplaceAll(row[j], "|", `\|`)
+			}
+		}
+		sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
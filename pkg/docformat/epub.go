@@ -0,0 +1,159 @@
+package docformat
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// epubContainer META-INF/container.xml，指向OPF包文件
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// epubPackage OPF包文件中的元数据、清单和阅读顺序
+type epubPackage struct {
+	Metadata struct {
+		Titles    []string `xml:"title"`
+		Creators  []string `xml:"creator"`
+		Languages []string `xml:"language"`
+	} `xml:"metadata"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef  string `xml:"idref,attr"`
+		Linear string `xml:"linear,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// ConvertEPUB 按书脊顺序将 EPUB 各章节转换为 Markdown
+func ConvertEPUB(content []byte) (*Document, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("open epub: %w", err)
+	}
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		files[f.Name] = f
+	}
+
+	opfPath, err := epubRootfile(reader.File, files)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readZipFile(files[opfPath])
+	if err != nil {
+		return nil, fmt.Errorf("read epub package: %w", err)
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("decode epub package: %w", err)
+	}
+
+	items := make(map[string]int, len(pkg.Manifest))
+	for i, item := range pkg.Manifest {
+		items[item.ID] = i
+	}
+	baseDir := path.Dir(opfPath)
+	var chapters []string
+	for _, ref := range pkg.Spine {
+		i, ok := items[ref.IDRef]
+		if !ok || ref.Linear == "no" {
+			continue
+		}
+		item := pkg.Manifest[i]
+		// EPUB3 的导航文档只是目录，不计入正文
+		if strings.Contains(item.Properties, "nav") || !isXHTML(item.MediaType) {
+			continue
+		}
+		href, err := url.PathUnescape(item.Href)
+		if err != nil {
+			href = item.Href
+		}
+		f, ok := files[path.Join(baseDir, href)]
+		if !ok {
+			continue
+		}
+		chapter, err := readZipFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("read epub chapter %s: %w", item.Href, err)
+		}
+		markdown, err := xhtmlToMarkdown(chapter)
+		if err != nil {
+			return nil, fmt.Errorf("convert epub chapter %s: %w", item.Href, err)
+		}
+		if markdown != "" {
+			chapters = append(chapters, markdown)
+		}
+	}
+
+	doc := &Document{Format: FormatEPUB}
+	if len(pkg.Metadata.Titles) > 0 {
+		doc.Title = normalizeSpace(pkg.Metadata.Titles[0])
+	}
+	for _, creator := range pkg.Metadata.Creators {
+		if name := normalizeSpace(creator); name != "" {
+			doc.Authors = append(doc.Authors, name)
+		}
+	}
+	if len(pkg.Metadata.Languages) > 0 {
+		doc.Language = strings.TrimSpace(pkg.Metadata.Languages[0])
+	}
+	doc.Markdown = withTitle(doc.Title, strings.Join(chapters, "\n\n"))
+	return doc, nil
+}
+
+// epubRootfile 读取OPF包文件路径
+func epubRootfile(entries []*zip.File, files map[string]*zip.File) (string, error) {
+	if f, ok := files["META-INF/container.xml"]; ok {
+		data, err := readZipFile(f)
+		if err != nil {
+			return "", fmt.Errorf("read epub container: %w", err)
+		}
+		var container epubContainer
+		if err := xml.Unmarshal(data, &container); err != nil {
+			return "", fmt.Errorf("decode epub container: %w", err)
+		}
+		for _, rootfile := range container.Rootfiles {
+			if _, ok := files[rootfile.FullPath]; ok {
+				return rootfile.FullPath, nil
+			}
+		}
+	}
+	// 容器文件缺失时查找第一个OPF文件
+	for _, f := range entries {
+		if strings.HasSuffix(strings.ToLower(f.Name), ".opf") {
+			return f.Name, nil
+		}
+	}
+	return "", fmt.Errorf("epub package file not found")
+}
+
+func isXHTML(mediaType string) bool {
+	return mediaType == "application/xhtml+xml" || mediaType == "text/html"
+}
+
+// xhtmlToMarkdown 将章节正文转换为 Markdown，章节内的图片只保留占位
+func xhtmlToMarkdown(content []byte) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	doc.Find("script, style, nav").Remove()
+	w := &markdownWriter{skipImages: true}
+	w.blocks(doc.Find("body"))
+	return w.String(), nil
+}
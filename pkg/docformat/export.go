@@ -0,0 +1,108 @@
+package docformat
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Annotation 导出时写回文档的标注
+type Annotation struct {
+	Anchor Anchor
+	Color  string // 高亮颜色，为空时使用 <mark> 默认样式
+	Note   string // 批注内容，导出为脚注
+}
+
+// ExportMarkdown 将标注写回 Markdown：高亮文本用 <mark> 包裹，批注作为脚注附在文末。
+//
+// 无法定位或与其他标注重叠的标注不做高亮，其批注连同原文引用列在文末；返回未能定位的标注数量。
+func ExportMarkdown(markdown string, annotations []Annotation) (string, int) {
+	type placed struct {
+		Annotation
+		start, end int
+	}
+	var resolved []placed
+	var detached []Annotation
+	unresolved := 0
+	for _, a := range annotations {
+		start, end, ok := Resolve(markdown, a.Anchor)
+		if !ok {
+			unresolved++
+			detached = append(detached, a)
+			continue
+		}
+		resolved = append(resolved, placed{Annotation: a, start: start, end: end})
+	}
+	sort.SliceStable(resolved, func(i, j int) bool {
+		return resolved[i].start < resolved[j].start
+	})
+
+	runes := []rune(markdown)
+	var sb strings.Builder
+	var footnotes []string
+	cursor := 0
+	for _, p := range resolved {
+		if p.start < cursor {
+			detached = append(detached, p.Annotation)
+			continue
+		}
+		sb.WriteString(string(runes[cursor:p.start]))
+		sb.WriteString(highlight(string(runes[p.start:p.end]), p.Color))
+		if note := strings.TrimSpace(p.Note); note != "" {
+			footnotes = append(footnotes, note)
+			fmt.Fprintf(&sb, "[^%d]", len(footnotes))
+		}
+		cursor = p.end
+	}
+	sb.WriteString(string(runes[cursor:]))
+
+	out := strings.TrimRight(sb.String(), "\n")
+	if len(footnotes) > 0 {
+		out += "\n"
+		for i, note := range footnotes {
+			out += fmt.Sprintf("\n[^%d]: %s", i+1, indentContinuation(note))
+		}
+	}
+	if len(detached) > 0 {
+		out += "\n\n---\n"
+		for _, a := range detached {
+			out += "\n> " + strings.ReplaceAll(strings.TrimSpace(a.Anchor.Exact), "\n", "\n> ") + "\n"
+			if note := strings.TrimSpace(a.Note); note != "" {
+				out += "\n" + note + "\n"
+			}
+		}
+	}
+	return strings.TrimRight(out, "\n") + "\n", unresolved
+}
+
+var blockMarkerRe = regexp.MustCompile(`^(#{1,6} +|(> *)+|[-*+] +|\d+[.)] +)`)
+
+// highlight 逐行包裹高亮，避免 <mark> 跨越 Markdown 的块边界
+func highlight(text, color string) string {
+	open := "<mark>"
+	if color != "" {
+		open = fmt.Sprintf(`<mark style="background-color: %s">`, html.EscapeString(color))
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		lead := line[:strings.Index(line, trimmed)]
+		// 标题、引用、列表等行首标记保留在高亮之外
+		if marker := blockMarkerRe.FindString(trimmed); marker != "" && marker != trimmed {
+			lead += marker
+			trimmed = trimmed[len(marker):]
+		}
+		lines[i] = lead + open + trimmed + "</mark>" + line[len(lead)+len(trimmed):]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentContinuation 多行脚注的后续行缩进，保持在同一个脚注内
+func indentContinuation(note string) string {
+	return strings.ReplaceAll(note, "\n", "\n    ")
+}
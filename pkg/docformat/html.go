@@ -0,0 +1,382 @@
+package docformat
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// 网页中与正文无关的元素
+const htmlNoiseSelector = "script, style, noscript, template, iframe, form, button, input, select, textarea, svg, canvas, " +
+	"nav, header, footer, aside, [role=navigation], [role=banner], [role=contentinfo], [aria-hidden=true], " +
+	".nav, .navbar, .menu, .sidebar, .footer, .header, .comments, .comment, .share, .social, .advertisement, .ads"
+
+// 常见的正文容器，按优先级排列
+var htmlArticleSelectors = []string{
+	"article", "main", "[role=main]", "[itemprop=articleBody]",
+	".article-content", ".post-content", ".entry-content", ".article-body", "#content", ".content",
+}
+
+// 正文容器的最少字数，低于该值时按段落密度查找正文
+const minArticleChars = 200
+
+var htmlSpaceRe = regexp.MustCompile(`\s+`)
+
+// ConvertHTML 提取网页正文并转换为 Markdown，baseURL 用于补全相对链接和图片地址
+func ConvertHTML(content []byte, baseURL string) (*Document, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse html: %w", err)
+	}
+	base, _ := url.Parse(baseURL)
+	title := htmlTitle(doc)
+	authors := htmlAuthors(doc)
+	language, _ := doc.Find("html").Attr("lang")
+
+	doc.Find(htmlNoiseSelector).Remove()
+	article := extractArticle(doc)
+	// 正文中与页面标题相同的一级标题由 Markdown 标题代替
+	article.Find("h1").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if normalizeSpace(s.Text()) == title {
+			s.Remove()
+			return false
+		}
+		return true
+	})
+
+	w := &markdownWriter{base: base}
+	w.blocks(article)
+	return &Document{
+		Format:   FormatHTML,
+		Title:    title,
+		Authors:  authors,
+		Language: strings.TrimSpace(language),
+		Markdown: withTitle(title, w.String()),
+	}, nil
+}
+
+// htmlTitle 依次使用学术元数据、Open Graph、第一个 h1 和 <title> 作为标题
+func htmlTitle(doc *goquery.Document) string {
+	for _, selector := range []string{`meta[name="citation_title"]`, `meta[property="og:title"]`, `meta[name="twitter:title"]`} {
+		if title, ok := doc.Find(selector).First().Attr("content"); ok && strings.TrimSpace(title) != "" {
+			return normalizeSpace(title)
+		}
+	}
+	if title := normalizeSpace(doc.Find("h1").First().Text()); title != "" {
+		return title
+	}
+	return normalizeSpace(doc.Find("title").First().Text())
+}
+
+// htmlAuthors 从页面元数据中读取作者
+func htmlAuthors(doc *goquery.Document) []string {
+	var authors []string
+	seen := map[string]bool{}
+	doc.Find(`meta[name="citation_author"], meta[name="author"], meta[property="article:author"]`).Each(func(i int, s *goquery.Selection) {
+		name := normalizeSpace(s.AttrOr("content", ""))
+		if name == "" || seen[name] || strings.HasPrefix(name, "http") {
+			return
+		}
+		seen[name] = true
+		authors = append(authors, name)
+	})
+	return authors
+}
+
+// extractArticle 查找网页正文：优先使用语义化容器，否则选择段落文字最多的元素
+func extractArticle(doc *goquery.Document) *goquery.Selection {
+	for _, selector := range htmlArticleSelectors {
+		var best *goquery.Selection
+		bestLen := 0
+		doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+			if n := utf8.RuneCountInString(normalizeSpace(s.Text())); n > bestLen {
+				best, bestLen = s, n
+			}
+		})
+		if best != nil && bestLen >= minArticleChars {
+			return best
+		}
+	}
+
+	// 每个段落的文字计入其父元素，得分最高的父元素即为正文
+	scores := map[any]int{}
+	var best *goquery.Selection
+	bestScore := 0
+	doc.Find("p").Each(func(i int, p *goquery.Selection) {
+		parent := p.Parent()
+		if parent.Length() == 0 {
+			return
+		}
+		node := parent.Get(0)
+		scores[node] += utf8.RuneCountInString(normalizeSpace(p.Text()))
+		if scores[node] > bestScore {
+			best, bestScore = parent, scores[node]
+		}
+	})
+	if best != nil && bestScore >= minArticleChars {
+		return best
+	}
+	return doc.Find("body")
+}
+
+// withTitle 正文没有以该标题开头时在开头补充一级标题
+func withTitle(title, markdown string) string {
+	markdown = strings.TrimSpace(markdown)
+	if title == "" || strings.HasPrefix(markdown, "# "+title+"\n") {
+		return markdown + "\n"
+	}
+	return "# " + title + "\n\n" + markdown + "\n"
+}
+
+func normalizeSpace(s string) string {
+	return strings.TrimSpace(htmlSpaceRe.ReplaceAllString(s, " "))
+}
+
+// markdownWriter 将HTML元素逐块写为 Markdown
+type markdownWriter struct {
+	base       *url.URL
+	skipImages bool // 图片不在正文中可用时只保留占位
+	out        []string
+	pending    strings.Builder // 尚未结束的行内内容
+}
+
+func (w *markdownWriter) String() string {
+	w.flush()
+	return strings.Join(w.out, "\n\n")
+}
+
+// block 写入一个完整的块
+func (w *markdownWriter) block(text string) {
+	w.flush()
+	if strings.TrimSpace(text) != "" {
+		w.out = append(w.out, text)
+	}
+}
+
+// flush 将累积的行内内容作为一个段落写入
+func (w *markdownWriter) flush() {
+	text := strings.TrimSpace(w.pending.String())
+	w.pending.Reset()
+	if text != "" {
+		w.out = append(w.out, text)
+	}
+}
+
+// blocks 写入元素的全部子节点
+func (w *markdownWriter) blocks(sel *goquery.Selection) {
+	sel.Contents().Each(func(i int, s *goquery.Selection) {
+		w.node(s)
+	})
+}
+
+func (w *markdownWriter) node(s *goquery.Selection) {
+	name := goquery.NodeName(s)
+	switch name {
+	case "#text":
+		w.pending.WriteString(htmlSpaceRe.ReplaceAllString(s.Text(), " "))
+	case "#comment", "head", "script", "style":
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		if text := w.inline(s); text != "" {
+			w.block(strings.Repeat("#", int(name[1]-'0')) + " " + text)
+		}
+	case "p":
+		w.block(w.inline(s))
+	case "ul", "ol":
+		w.block(w.list(s, 0))
+	case "pre":
+		w.block("```\n" + strings.TrimRight(s.Text(), "\n") + "\n```")
+	case "blockquote":
+		inner := &markdownWriter{base: w.base, skipImages: w.skipImages}
+		inner.blocks(s)
+		if text := inner.String(); text != "" {
+			w.block("> " + strings.ReplaceAll(text, "\n", "\n> "))
+		}
+	case "table":
+		w.block(w.table(s))
+	case "hr":
+		w.block("---")
+	case "img":
+		w.block(w.image(s))
+	case "figcaption", "caption", "dt", "dd":
+		w.block(w.inline(s))
+	case "math":
+		if tex := mathTeX(s); tex != "" {
+			w.block("$$\n" + tex + "\n$$")
+		}
+	default:
+		if isBlockContainer(s) {
+			w.flush()
+			w.blocks(s)
+			w.flush()
+			return
+		}
+		if isBlockElement(name) {
+			w.block(w.inline(s))
+			return
+		}
+		w.inlineNode(&w.pending, s)
+	}
+}
+
+// inline 将元素内容转换为行内 Markdown
+func (w *markdownWriter) inline(sel *goquery.Selection) string {
+	var sb strings.Builder
+	sel.Contents().Each(func(i int, s *goquery.Selection) {
+		w.inlineNode(&sb, s)
+	})
+	return normalizeSpace(sb.String())
+}
+
+// inlineNode 写入单个行内节点，保留节点前后的空白
+func (w *markdownWriter) inlineNode(sb *strings.Builder, s *goquery.Selection) {
+	switch name := goquery.NodeName(s); name {
+	case "#text":
+		sb.WriteString(htmlSpaceRe.ReplaceAllString(s.Text(), " "))
+	case "br":
+		sb.WriteString(" ")
+	case "strong", "b":
+		wrapInline(sb, s.Text(), w.inline(s), "**")
+	case "em", "i":
+		wrapInline(sb, s.Text(), w.inline(s), "*")
+	case "code":
+		if text := s.Text(); text != "" {
+			sb.WriteString("`" + text + "`")
+		}
+	case "a":
+		text := w.inline(s)
+		href := w.resolve(s.AttrOr("href", ""))
+		if text == "" || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+			wrapInline(sb, s.Text(), text, "")
+		} else {
+			wrapInline(sb, s.Text(), "["+text+"]("+href+")", "")
+		}
+	case "img":
+		sb.WriteString(w.image(s))
+	case "math":
+		if tex := mathTeX(s); tex != "" {
+			sb.WriteString("$" + tex + "$")
+		}
+	case "#comment", "script", "style":
+	default:
+		s.Contents().Each(func(i int, child *goquery.Selection) {
+			w.inlineNode(sb, child)
+		})
+	}
+}
+
+// wrapInline 用标记包裹行内文本，原文首尾的空白放在标记之外
+func wrapInline(sb *strings.Builder, raw, text, marker string) {
+	if text == "" {
+		return
+	}
+	if strings.TrimLeftFunc(raw, unicode.IsSpace) != raw {
+		sb.WriteString(" ")
+	}
+	sb.WriteString(marker + text + marker)
+	if strings.TrimRightFunc(raw, unicode.IsSpace) != raw {
+		sb.WriteString(" ")
+	}
+}
+
+// list 转换列表，嵌套列表按层级缩进
+func (w *markdownWriter) list(sel *goquery.Selection, depth int) string {
+	ordered := goquery.NodeName(sel) == "ol"
+	indent := strings.Repeat("  ", depth)
+	var lines []string
+	n := 0
+	sel.ChildrenFiltered("li").Each(func(i int, li *goquery.Selection) {
+		n++
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", n)
+		}
+		nested := li.ChildrenFiltered("ul, ol")
+		item := li.Clone()
+		item.ChildrenFiltered("ul, ol").Remove()
+		lines = append(lines, indent+marker+w.inline(item))
+		nested.Each(func(j int, child *goquery.Selection) {
+			lines = append(lines, w.list(child, depth+1))
+		})
+	})
+	return strings.Join(lines, "\n")
+}
+
+// table 转换为 Markdown 表格，表格标题作为单独的段落
+func (w *markdownWriter) table(sel *goquery.Selection) string {
+	var rows [][]string
+	sel.Find("tr").Each(func(i int, tr *goquery.Selection) {
+		var cells []string
+		tr.ChildrenFiltered("th, td").Each(func(j int, cell *goquery.Selection) {
+			cells = append(cells, w.inline(cell))
+		})
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+	})
+	if len(rows) == 0 {
+		return ""
+	}
+	if caption := w.inline(sel.ChildrenFiltered("caption")); caption != "" {
+		return caption + "\n\n" + renderTable(rows)
+	}
+	return renderTable(rows)
+}
+
+func (w *markdownWriter) image(s *goquery.Selection) string {
+	src := s.AttrOr("src", s.AttrOr("data-src", ""))
+	if w.skipImages {
+		src = ""
+	}
+	return "![" + normalizeSpace(s.AttrOr("alt", "")) + "](" + w.resolve(src) + ")"
+}
+
+// resolve 将相对地址补全为绝对地址
+func (w *markdownWriter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || w.base == nil || strings.HasPrefix(ref, "#") {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return w.base.ResolveReference(u).String()
+}
+
+// mathTeX 读取 MathML 中的 TeX 注解
+func mathTeX(s *goquery.Selection) string {
+	if tex := strings.TrimSpace(s.Find(`annotation[encoding="application/x-tex"]`).Text()); tex != "" {
+		return tex
+	}
+	return strings.TrimSpace(s.AttrOr("alttext", ""))
+}
+
+var blockElements = map[string]bool{
+	"div": true, "section": true, "article": true, "main": true, "body": true, "html": true,
+	"figure": true, "header": true, "footer": true, "dl": true, "center": true, "li": true,
+}
+
+func isBlockElement(name string) bool {
+	return blockElements[name]
+}
+
+// isBlockContainer 元素是否包含块级子元素，需要逐块转换
+func isBlockContainer(s *goquery.Selection) bool {
+	if !isBlockElement(goquery.NodeName(s)) {
+		return false
+	}
+	return s.Children().FilterFunction(func(i int, child *goquery.Selection) bool {
+		switch name := goquery.NodeName(child); name {
+		case "p", "h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol", "pre", "blockquote", "table", "hr", "img", "figcaption", "math":
+			return true
+		default:
+			return isBlockElement(name)
+		}
+	}).Length() > 0
+}
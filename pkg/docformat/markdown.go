@@ -0,0 +1,77 @@
+package docformat
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ConvertMarkdown 读取 Markdown 文档，YAML 头信息中的 title、author(s)、lang 作为文档属性并从正文中移除
+func ConvertMarkdown(content []byte) (*Document, error) {
+	if !utf8.Valid(content) {
+		return nil, fmt.Errorf("markdown is not valid utf-8")
+	}
+	text := strings.TrimPrefix(string(content), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	doc := &Document{Format: FormatMarkdown}
+
+	if strings.HasPrefix(text, "---\n") {
+		if end := strings.Index(text[4:], "\n---"); end >= 0 {
+			frontMatter := text[4 : 4+end]
+			body := text[4+end+4:]
+			if i := strings.IndexByte(body, '\n'); i >= 0 {
+				body = body[i+1:]
+			} else {
+				body = ""
+			}
+			parseFrontMatter(frontMatter, doc)
+			text = body
+		}
+	}
+	doc.Markdown = withTitle(doc.Title, text)
+	return doc, nil
+}
+
+// parseFrontMatter 读取简单的 key: value 头信息，作者支持逗号分隔和 YAML 列表
+func parseFrontMatter(frontMatter string, doc *Document) {
+	var listKey string
+	for _, line := range strings.Split(frontMatter, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "- ") && listKey != "" {
+			if listKey == "author" || listKey == "authors" {
+				if name := unquote(strings.TrimPrefix(trimmed, "- ")); name != "" {
+					doc.Authors = append(doc.Authors, name)
+				}
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = unquote(value)
+		listKey = ""
+		if value == "" {
+			listKey = key
+			continue
+		}
+		switch key {
+		case "title":
+			doc.Title = value
+		case "author", "authors":
+			value = strings.Trim(value, "[]")
+			for _, name := range strings.Split(value, ",") {
+				if name = unquote(name); name != "" {
+					doc.Authors = append(doc.Authors, name)
+				}
+			}
+		case "lang", "language":
+			doc.Language = value
+		}
+	}
+}
+
+func unquote(s string) string {
+	return strings.Trim(strings.TrimSpace(s), `"'`)
+}
@@ -0,0 +1,384 @@
+// Package conformance 解析器一致性测试工具
+//
+// 每个解析后端在 testdata 下维护一组录制的夹具：夹具描述输入文档、录制的后端响应和期望结果。
+// Run 对每个夹具执行解析，校验所有解析器都必须满足的结构约束以及夹具中的期望，
+// 新增解析后端时只需录制夹具并调用 Run。
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yb2020/odoc/pkg/docparser"
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+)
+
+// 夹具文件后缀
+const fixtureSuffix = ".fixture.json"
+
+// Fixture 一个录制的解析夹具
+type Fixture struct {
+	Name      string      `json:"name"`
+	Input     string      `json:"input"`    // 输入文档，相对于夹具文件；为空时使用占位内容
+	Recorded  string      `json:"recorded"` // 录制的后端响应，相对于夹具文件
+	Lang      string      `json:"lang"`
+	PageCount int         `json:"pageCount"`
+	Tier      string      `json:"tier"`
+	Expect    Expectation `json:"expect"`
+
+	dir string
+}
+
+// Expectation 夹具的期望结果，零值表示不校验
+type Expectation struct {
+	Error          bool     `json:"error"`          // 期望解析失败
+	Title          string   `json:"title"`          // 标题，忽略大小写比较
+	Authors        []string `json:"authors"`        // 作者全名，需全部出现
+	MinParagraphs  int      `json:"minParagraphs"`  // 最少正文段落数
+	MinReferences  int      `json:"minReferences"`  // 最少参考文献数
+	MinMarkers     int      `json:"minMarkers"`     // 最少参考文献引用标记数
+	MinFigures     int      `json:"minFigures"`     // 最少图表数
+	MinFormulas    int      `json:"minFormulas"`    // 最少公式数
+	Sections       []string `json:"sections"`       // 目录中需出现的章节标题
+	AbstractPrefix string   `json:"abstractPrefix"` // 摘要开头
+	MinScore       float64  `json:"minScore"`       // 最低质量分
+}
+
+// LoadFixtures 读取目录下的全部夹具，按名称排序
+func LoadFixtures(dir string) ([]*Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+fixtureSuffix))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	fixtures := make([]*Fixture, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fixture := &Fixture{}
+		if err := json.Unmarshal(data, fixture); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if fixture.Name == "" {
+			fixture.Name = strings.TrimSuffix(filepath.Base(path), fixtureSuffix)
+		}
+		fixture.dir = filepath.Dir(path)
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
+
+// Content 输入文档内容
+func (f *Fixture) Content() ([]byte, error) {
+	if f.Input == "" {
+		return []byte("%PDF-1.4\n%conformance placeholder\n"), nil
+	}
+	return os.ReadFile(filepath.Join(f.dir, f.Input))
+}
+
+// RecordedResponse 录制的后端响应
+func (f *Fixture) RecordedResponse() ([]byte, error) {
+	if f.Recorded == "" {
+		return nil, fmt.Errorf("fixture %s has no recorded response", f.Name)
+	}
+	return os.ReadFile(filepath.Join(f.dir, f.Recorded))
+}
+
+// Request 夹具对应的解析请求
+func (f *Fixture) Request() (*docparser.Request, error) {
+	content, err := f.Content()
+	if err != nil {
+		return nil, err
+	}
+	return &docparser.Request{
+		Content:    content,
+		FileSHA256: "conformance-" + f.Name,
+		Lang:       f.Lang,
+		PageCount:  f.PageCount,
+		Tier:       f.Tier,
+	}, nil
+}
+
+// Replay 回放录制响应的后端服务
+type Replay struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	response []byte
+	requests []*http.Request
+}
+
+// NewReplay 启动回放服务，测试结束时自动关闭
+func NewReplay(t testing.TB) *Replay {
+	t.Helper()
+	r := &Replay{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := req.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		response := r.response
+		r.mu.Unlock()
+		if response == nil {
+			http.Error(w, "no recorded response", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(response)
+	}))
+	t.Cleanup(r.Server.Close)
+	return r
+}
+
+// Use 设置后续请求回放的响应
+func (r *Replay) Use(response []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.response = response
+}
+
+// LastRequest 最近一次收到的请求
+func (r *Replay) LastRequest() *http.Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.requests) == 0 {
+		return nil
+	}
+	return r.requests[len(r.requests)-1]
+}
+
+// Run 对每个夹具执行解析并校验结果；prepare 在解析前调用，用于设置回放响应，可为 nil
+func Run(t *testing.T, p docparser.Parser, fixtures []*Fixture, prepare func(t *testing.T, f *Fixture)) {
+	t.Helper()
+	if len(fixtures) == 0 {
+		t.Fatalf("解析器 %s 没有夹具", p.Name())
+	}
+	if !p.Available() {
+		t.Fatalf("解析器 %s 不可用", p.Name())
+	}
+	for _, fixture := range fixtures {
+		fixture := fixture
+		t.Run(fixture.Name, func(t *testing.T) {
+			if prepare != nil {
+				prepare(t, fixture)
+			}
+			req, err := fixture.Request()
+			if err != nil {
+				t.Fatalf("读取夹具输入失败: %v", err)
+			}
+			result, err := p.Parse(context.Background(), req)
+			if fixture.Expect.Error {
+				if err == nil {
+					t.Fatalf("期望解析失败，实际成功")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			if result == nil {
+				t.Fatalf("解析结果为空")
+			}
+			CheckInvariants(t, p.Capabilities(), result)
+			CheckExpectation(t, fixture.Expect, result, req.PageCount)
+		})
+	}
+}
+
+// CheckInvariants 校验所有解析器结果都必须满足的结构约束
+func CheckInvariants(t testing.TB, caps docparser.Capability, result *docparser.Result) {
+	t.Helper()
+	if caps.Has(docparser.CapHeader) && result.Metadata == nil {
+		t.Errorf("声明了 header 能力但元数据为空")
+	}
+	if caps.Has(docparser.CapFulltext) && (result.FullDocument == nil || len(result.FullDocument.Paragraphs) == 0) {
+		t.Errorf("声明了 fulltext 能力但没有段落")
+	}
+	if caps.Has(docparser.CapMarkdown) && strings.TrimSpace(result.Markdown) == "" {
+		t.Errorf("声明了 markdown 能力但 Markdown 为空")
+	}
+
+	refIdx := make(map[string]bool)
+	if result.Metadata != nil {
+		for _, ref := range result.Metadata.References {
+			if ref.RefIdx == "" {
+				t.Errorf("参考文献缺少 RefIdx: %q", ref.ContentText)
+			}
+			if refIdx[ref.RefIdx] {
+				t.Errorf("参考文献 RefIdx 重复: %s", ref.RefIdx)
+			}
+			refIdx[ref.RefIdx] = true
+			checkBBox(t, "参考文献 "+ref.RefIdx, ref.Bbox)
+		}
+		for _, marker := range result.Metadata.ReferenceMarkers {
+			if !refIdx[marker.RefIdx] {
+				t.Errorf("引用标记指向不存在的参考文献: %s", marker.RefIdx)
+			}
+			checkBBox(t, "引用标记 "+marker.RefIdx, marker.Bbox)
+		}
+		checkCatalogue(t, result.Metadata.Catalogue, 0)
+	}
+
+	if result.FullDocument == nil {
+		return
+	}
+	for i, p := range result.FullDocument.Paragraphs {
+		if p.Order != int32(i+1) {
+			t.Errorf("段落 %d 的 Order 为 %d，应从1开始连续递增", i, p.Order)
+		}
+		switch p.Type {
+		case pb.ParagraphType_TEXT:
+			if p.Text == nil || strings.TrimSpace(p.Text.Text) == "" {
+				t.Errorf("段落 %d 是文本段落但没有文本", i)
+			} else {
+				checkBBox(t, fmt.Sprintf("段落 %d", i), p.Text.Bbox)
+			}
+		case pb.ParagraphType_IMAGE, pb.ParagraphType_TABLE:
+			if p.FigureTable == nil {
+				t.Errorf("段落 %d 是图表段落但没有图表", i)
+			}
+		case pb.ParagraphType_FORMULA:
+			if p.Formula == nil {
+				t.Errorf("段落 %d 是公式段落但没有公式", i)
+			}
+		default:
+			t.Errorf("段落 %d 类型未知: %v", i, p.Type)
+		}
+		for _, ref := range p.References {
+			if !refIdx[ref.Target] {
+				t.Errorf("段落 %d 的引用指向不存在的参考文献: %s", i, ref.Target)
+			}
+		}
+	}
+}
+
+// checkCatalogue 目录层级必须逐级加深
+func checkCatalogue(t testing.TB, items []*pb.CatalogueItem, parentLevel int) {
+	t.Helper()
+	for _, item := range items {
+		if strings.TrimSpace(item.Title) == "" {
+			t.Errorf("目录条目标题为空")
+		}
+		level := 0
+		fmt.Sscanf(item.Level, "%d", &level)
+		if level <= parentLevel {
+			t.Errorf("目录条目 %q 的层级 %q 应大于上级层级 %d", item.Title, item.Level, parentLevel)
+		}
+		checkCatalogue(t, item.Child, level)
+	}
+}
+
+func checkBBox(t testing.TB, what string, bbox *pb.BBox) {
+	t.Helper()
+	if bbox == nil {
+		return
+	}
+	if bbox.X0 > bbox.X1 || bbox.Y0 > bbox.Y1 {
+		t.Errorf("%s 的坐标无效: (%v,%v)-(%v,%v)", what, bbox.X0, bbox.Y0, bbox.X1, bbox.Y1)
+	}
+	if bbox.PageNumber < 1 {
+		t.Errorf("%s 的页码无效: %d", what, bbox.PageNumber)
+	}
+}
+
+// CheckExpectation 校验夹具中的期望结果
+func CheckExpectation(t testing.TB, expect Expectation, result *docparser.Result, pageCount int) {
+	t.Helper()
+	metadata := result.Metadata
+	if metadata == nil {
+		metadata = &pb.DocumentMetadata{}
+	}
+	if expect.Title != "" {
+		title := ""
+		if metadata.Title != nil {
+			title = metadata.Title.Text
+		}
+		if !strings.EqualFold(strings.TrimSpace(title), expect.Title) {
+			t.Errorf("标题: 期望 %q, 实际 %q", expect.Title, title)
+		}
+	}
+	authors := make(map[string]bool)
+	for _, a := range metadata.Authors {
+		authors[a.FullName] = true
+	}
+	for _, name := range expect.Authors {
+		if !authors[name] {
+			t.Errorf("缺少作者 %q", name)
+		}
+	}
+	if expect.AbstractPrefix != "" && (metadata.Abstract == nil || !strings.HasPrefix(metadata.Abstract.Text, expect.AbstractPrefix)) {
+		t.Errorf("摘要应以 %q 开头", expect.AbstractPrefix)
+	}
+	sections := make(map[string]bool)
+	collectSections(metadata.Catalogue, sections)
+	for _, s := range expect.Sections {
+		if !sections[s] {
+			t.Errorf("目录中缺少章节 %q", s)
+		}
+	}
+
+	textParagraphs := 0
+	if result.FullDocument != nil {
+		for _, p := range result.FullDocument.Paragraphs {
+			if p.Type == pb.ParagraphType_TEXT {
+				textParagraphs++
+			}
+		}
+	}
+	checkMin(t, "正文段落", textParagraphs, expect.MinParagraphs)
+	checkMin(t, "参考文献", len(metadata.References), expect.MinReferences)
+	checkMin(t, "引用标记", len(metadata.ReferenceMarkers), expect.MinMarkers)
+	checkMin(t, "图表", len(metadata.FiguresAndTables), expect.MinFigures)
+	checkMin(t, "公式", len(metadata.Formulas), expect.MinFormulas)
+
+	if expect.MinScore > 0 {
+		if score := docparser.Score(result, pageCount); score < expect.MinScore {
+			t.Errorf("质量分: 期望不低于 %.3f, 实际 %.3f", expect.MinScore, score)
+		}
+	}
+}
+
+func collectSections(items []*pb.CatalogueItem, sections map[string]bool) {
+	for _, item := range items {
+		sections[item.Title] = true
+		collectSections(item.Child, sections)
+	}
+}
+
+func checkMin(t testing.TB, what string, actual, min int) {
+	t.Helper()
+	if min > 0 && actual < min {
+		t.Errorf("%s数量: 期望不少于 %d, 实际 %d", what, min, actual)
+	}
+}
+
+// ReadAll 读取请求中上传的文件内容，用于校验后端收到的文档
+func ReadAll(req *http.Request, field string) ([]byte, error) {
+	if req == nil || req.MultipartForm == nil {
+		return nil, fmt.Errorf("not a multipart request")
+	}
+	files := req.MultipartForm.File[field]
+	if len(files) == 0 {
+		return nil, fmt.Errorf("field %s not found", field)
+	}
+	f, err := files[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
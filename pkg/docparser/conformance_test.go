@@ -0,0 +1,50 @@
+package docparser_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/yb2020/odoc/pkg/docparser"
+	"github.com/yb2020/odoc/pkg/docparser/conformance"
+	"github.com/yb2020/odoc/pkg/http_client"
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+// runRecorded 使用录制的后端响应对 HTTP 解析器执行一致性测试
+func runRecorded(t *testing.T, dir, field string, newParser func(client http_client.HttpClient, url string) docparser.Parser) {
+	fixtures, err := conformance.LoadFixtures(dir)
+	if err != nil {
+		t.Fatalf("读取夹具失败: %v", err)
+	}
+	replay := conformance.NewReplay(t)
+	client := http_client.NewHttpClient(logging.NewLogger("error", "json"))
+	parser := newParser(client, replay.URL)
+	conformance.Run(t, parser, fixtures, func(t *testing.T, f *conformance.Fixture) {
+		response, err := f.RecordedResponse()
+		if err != nil {
+			t.Fatalf("读取录制响应失败: %v", err)
+		}
+		replay.Use(response)
+		t.Cleanup(func() {
+			// 后端必须收到完整的文档内容
+			content, _ := f.Content()
+			uploaded, err := conformance.ReadAll(replay.LastRequest(), field)
+			if err != nil || !bytes.Equal(uploaded, content) {
+				t.Errorf("后端收到的文档与输入不一致: %v", err)
+			}
+		})
+	})
+}
+
+func TestDoclingConformance(t *testing.T) {
+	runRecorded(t, "testdata/docling", "files", func(client http_client.HttpClient, url string) docparser.Parser {
+		return docparser.NewDoclingParser(client, url, "", time.Minute)
+	})
+}
+
+func TestMarkerConformance(t *testing.T) {
+	runRecorded(t, "testdata/marker", "file", func(client http_client.HttpClient, url string) docparser.Parser {
+		return docparser.NewMarkerParser(client, url, "", time.Minute)
+	})
+}
@@ -0,0 +1,107 @@
+package docparser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yb2020/odoc/pkg/http_client"
+)
+
+// ParserDocling Docling 解析器名称
+const ParserDocling = "docling"
+
+// Docling 服务默认的文件转换接口
+const defaultDoclingConvertPath = "/v1/convert/file"
+
+// DoclingParser 调用 docling-serve 将文档转换为 Markdown 后再解析
+type DoclingParser struct {
+	httpClient  http_client.HttpClient
+	url         string
+	convertPath string
+	timeout     time.Duration
+}
+
+// NewDoclingParser 创建 Docling 解析器，url 为空时解析器不可用
+func NewDoclingParser(httpClient http_client.HttpClient, url, convertPath string, timeout time.Duration) *DoclingParser {
+	if convertPath == "" {
+		convertPath = defaultDoclingConvertPath
+	}
+	return &DoclingParser{
+		httpClient:  httpClient,
+		url:         strings.TrimRight(url, "/"),
+		convertPath: convertPath,
+		timeout:     timeout,
+	}
+}
+
+// doclingResponse docling-serve 文件转换接口的响应
+type doclingResponse struct {
+	Document struct {
+		Filename  string `json:"filename"`
+		MdContent string `json:"md_content"`
+	} `json:"document"`
+	Status string `json:"status"`
+	Errors []struct {
+		ErrorMessage string `json:"error_message"`
+	} `json:"errors"`
+}
+
+func (p *DoclingParser) Name() string {
+	return ParserDocling
+}
+
+func (p *DoclingParser) Capabilities() Capability {
+	return CapHeader | CapFulltext | CapFigures | CapFormulas | CapMarkdown
+}
+
+func (p *DoclingParser) Available() bool {
+	return p.url != ""
+}
+
+func (p *DoclingParser) Parse(ctx context.Context, req *Request) (*Result, error) {
+	formData := map[string]string{
+		"to_formats":            "md",
+		"image_export_mode":     "placeholder",
+		"do_formula_enrichment": "true",
+	}
+	body, err := p.httpClient.PostMultipartFormWithFileInput(p.url+p.convertPath, formData,
+		map[string][]byte{"files": req.Content},
+		map[string]string{"files": fileName(req)},
+		map[string]string{"Accept": "application/json"},
+		p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	var resp doclingResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decode docling response: %w", err)
+	}
+	if resp.Status != "success" && resp.Status != "partial_success" {
+		message := resp.Status
+		if len(resp.Errors) > 0 {
+			message = resp.Errors[0].ErrorMessage
+		}
+		return nil, fmt.Errorf("docling convert failed: %s", message)
+	}
+	return markdownResult(resp.Document.MdContent)
+}
+
+// markdownResult 由 Markdown 全文生成解析结果
+func markdownResult(markdown string) (*Result, error) {
+	if strings.TrimSpace(markdown) == "" {
+		return nil, fmt.Errorf("empty markdown")
+	}
+	metadata, fullDocument := FromMarkdown(markdown)
+	return &Result{Metadata: metadata, FullDocument: fullDocument, Markdown: markdown}, nil
+}
+
+// fileName 上传时使用的文件名
+func fileName(req *Request) string {
+	if req.FileSHA256 != "" {
+		return req.FileSHA256 + ".pdf"
+	}
+	return "document.pdf"
+}
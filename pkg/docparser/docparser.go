@@ -0,0 +1,110 @@
+// Package docparser 定义文档解析器的统一接口，以及按语言、页数、会员等级选择解析器的注册表
+//
+// GROBID、MinerU、Docling、Marker 和内置解析器都实现 Parser 接口，
+// 新增解析后端只需注册到 Registry 并配置选择规则，无需修改调用方。
+package docparser
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+)
+
+// Capability 解析器能力标记，可按位组合
+type Capability uint32
+
+const (
+	// CapHeader 能解析标题、作者、摘要等头部信息
+	CapHeader Capability = 1 << iota
+	// CapFulltext 能解析全文段落和章节目录
+	CapFulltext
+	// CapFigures 能识别图表及其位置
+	CapFigures
+	// CapFormulas 能识别公式
+	CapFormulas
+	// CapMarkdown 能输出 Markdown 全文
+	CapMarkdown
+)
+
+var capabilityNames = []struct {
+	cap  Capability
+	name string
+}{
+	{CapHeader, "header"},
+	{CapFulltext, "fulltext"},
+	{CapFigures, "figures"},
+	{CapFormulas, "formulas"},
+	{CapMarkdown, "markdown"},
+}
+
+// Has 是否具备全部指定能力
+func (c Capability) Has(required Capability) bool {
+	return c&required == required
+}
+
+// String 能力名称列表，如 "header|fulltext"
+func (c Capability) String() string {
+	var names []string
+	for _, n := range capabilityNames {
+		if c.Has(n.cap) {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// ParseCapabilities 解析能力名称列表，忽略未知名称
+func ParseCapabilities(names []string) Capability {
+	var c Capability
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, n := range capabilityNames {
+			if n.name == name {
+				c |= n.cap
+			}
+		}
+	}
+	return c
+}
+
+var (
+	// ErrNoParser 没有满足条件且可用的解析器
+	ErrNoParser = errors.New("docparser: no available parser")
+	// ErrDuplicateParser 同名解析器重复注册
+	ErrDuplicateParser = errors.New("docparser: parser already registered")
+)
+
+// Request 解析请求
+type Request struct {
+	Content    []byte // 文档内容
+	FileSHA256 string // 文档哈希，解析结果中回填
+	Lang       string // 文档语言，如 en/zh，未知时为空
+	PageCount  int    // 文档页数，未知时为0
+	Tier       string // 上传者会员等级，如 free/pro，未知时为空
+	ArxivId    string // arXiv 编号，已知时可优先解析 LaTeX 源码
+}
+
+// Result 解析结果，各解析器按能力填充对应字段
+type Result struct {
+	Parser       string                     // 产出结果的解析器名称
+	Metadata     *pb.DocumentMetadata       // 头部信息、目录、参考文献、图表
+	FullDocument *pb.FullDocument           // 全文段落
+	PageBlocks   []*pb.PageBlockData        // 页面块，用于全文翻译
+	ImageRecords map[string]*pb.ImageRecord // 图表切图记录
+	Markdown     string                     // Markdown 全文
+	Score        float64                    // 结果质量分，由 Score 计算
+}
+
+// Parser 文档解析器
+type Parser interface {
+	// Name 解析器名称，在注册表中唯一
+	Name() string
+	// Capabilities 解析器具备的能力
+	Capabilities() Capability
+	// Available 解析器当前是否可用，如外部服务地址是否已配置
+	Available() bool
+	// Parse 解析文档
+	Parse(ctx context.Context, req *Request) (*Result, error)
+}
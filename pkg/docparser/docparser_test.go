@@ -0,0 +1,291 @@
+package docparser
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+)
+
+// fakeParser 按预设结果返回的解析器
+type fakeParser struct {
+	name      string
+	caps      Capability
+	available bool
+	result    *Result
+	err       error
+	calls     int
+}
+
+func (p *fakeParser) Name() string             { return p.name }
+func (p *fakeParser) Capabilities() Capability { return p.caps }
+func (p *fakeParser) Available() bool          { return p.available }
+
+func (p *fakeParser) Parse(ctx context.Context, req *Request) (*Result, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	copied := *p.result
+	return &copied, nil
+}
+
+// richResult 头部、目录、参考文献都齐全的结果
+func richResult() *Result {
+	var refs []*pb.Reference
+	for i := 0; i < 10; i++ {
+		refs = append(refs, &pb.Reference{RefIdx: string(rune('a' + i))})
+	}
+	return &Result{
+		Metadata: &pb.DocumentMetadata{
+			Title:      &pb.Title{Text: "A Paper"},
+			Authors:    []*pb.Author{{FullName: "Alice Zhang"}},
+			Abstract:   &pb.Abstract{Text: strings.Repeat("abstract ", 10)},
+			Catalogue:  []*pb.CatalogueItem{{Title: "1"}, {Title: "2"}, {Title: "3"}},
+			References: refs,
+		},
+		FullDocument: &pb.FullDocument{Paragraphs: []*pb.Paragraph{
+			{Type: pb.ParagraphType_TEXT, Text: &pb.Text{Text: strings.Repeat("x", 2000)}},
+		}},
+	}
+}
+
+// poorResult 只有少量正文的结果
+func poorResult() *Result {
+	return &Result{FullDocument: &pb.FullDocument{Paragraphs: []*pb.Paragraph{
+		{Type: pb.ParagraphType_TEXT, Text: &pb.Text{Text: "short text"}},
+	}}}
+}
+
+func newTestRegistry(t *testing.T, parsers ...Parser) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	for _, p := range parsers {
+		if err := r.Register(p); err != nil {
+			t.Fatalf("注册解析器失败: %v", err)
+		}
+	}
+	return r
+}
+
+func names(parsers []Parser) string {
+	var result []string
+	for _, p := range parsers {
+		result = append(result, p.Name())
+	}
+	return strings.Join(result, ",")
+}
+
+func TestCapability(t *testing.T) {
+	caps := ParseCapabilities([]string{"header", " Fulltext ", "unknown"})
+	if caps != CapHeader|CapFulltext {
+		t.Fatalf("解析能力错误: %v", caps)
+	}
+	if !caps.Has(CapHeader) || caps.Has(CapHeader|CapFigures) {
+		t.Errorf("Has 判断错误")
+	}
+	if caps.String() != "header|fulltext" {
+		t.Errorf("能力名称错误: %s", caps.String())
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	r := newTestRegistry(t, &fakeParser{name: "grobid"})
+	if err := r.Register(&fakeParser{name: "GROBID"}); !errors.Is(err, ErrDuplicateParser) {
+		t.Fatalf("重复注册应返回 ErrDuplicateParser, 实际 %v", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	grobid := &fakeParser{name: "grobid", caps: CapHeader | CapFulltext, available: true}
+	mineru := &fakeParser{name: "mineru", caps: CapHeader | CapFulltext | CapFigures | CapFormulas, available: true}
+	marker := &fakeParser{name: "marker", caps: CapFulltext | CapMarkdown, available: false}
+	native := &fakeParser{name: "native", caps: CapHeader | CapFulltext | CapFigures, available: true}
+	r := newTestRegistry(t, grobid, mineru, marker, native)
+	r.SetRules([]Rule{
+		{Parsers: []string{"marker"}, Tiers: []string{"pro"}},
+		{Parsers: []string{"mineru", "grobid"}, Tiers: []string{"pro"}, MaxPages: 50},
+		{Parsers: []string{"grobid"}, Languages: []string{"en"}, MaxPages: 30},
+		{Parsers: []string{"native"}, MinPages: 200},
+	}, []string{"mineru", "native"})
+
+	cases := []struct {
+		name    string
+		req     Request
+		require Capability
+		want    string
+	}{
+		// marker 不可用，继续匹配下一条规则
+		{"会员短文档", Request{Tier: "PRO", PageCount: 10}, 0, "mineru,grobid"},
+		{"英文短文档", Request{Lang: "en", PageCount: 12}, 0, "grobid"},
+		{"要求图表能力时跳过不满足的规则", Request{Lang: "en", PageCount: 12}, CapFigures, "mineru,native"},
+		{"页数未知不匹配页数规则", Request{Lang: "en"}, 0, "mineru,native"},
+		{"超长文档", Request{Lang: "zh", PageCount: 300}, 0, "native"},
+		{"默认", Request{Lang: "zh", PageCount: 20}, 0, "mineru,native"},
+	}
+	for _, c := range cases {
+		if got := names(r.Select(&c.req, c.require)); got != c.want {
+			t.Errorf("%s: 期望 %s, 实际 %s", c.name, c.want, got)
+		}
+	}
+}
+
+func TestRunSequential(t *testing.T) {
+	failing := &fakeParser{name: "grobid", available: true, err: errors.New("timeout")}
+	poor := &fakeParser{name: "native", available: true, result: poorResult()}
+	rich := &fakeParser{name: "mineru", available: true, result: richResult()}
+	r := newTestRegistry(t, failing, poor, rich)
+	r.SetRules(nil, []string{"grobid", "native", "mineru"})
+
+	// 失败后继续，低于最低分时继续尝试并取最高分
+	result, err := r.Run(context.Background(), &Request{PageCount: 1, FileSHA256: "sha"}, RunOptions{MinScore: 0.5})
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if result.Parser != "mineru" || result.Metadata.FileSHA256 != "sha" {
+		t.Errorf("应选择 mineru 并回填哈希, 实际 %s %q", result.Parser, result.Metadata.FileSHA256)
+	}
+
+	// 不设置最低分时使用第一个成功的结果
+	rich.calls = 0
+	result, err = r.Run(context.Background(), &Request{PageCount: 1}, RunOptions{})
+	if err != nil || result.Parser != "native" || rich.calls != 0 {
+		t.Errorf("应使用第一个成功的 native, 实际 %v %v, mineru 调用 %d 次", result, err, rich.calls)
+	}
+}
+
+func TestRunCompare(t *testing.T) {
+	poor := &fakeParser{name: "native", available: true, result: poorResult()}
+	rich := &fakeParser{name: "mineru", available: true, result: richResult()}
+	failing := &fakeParser{name: "grobid", available: true, err: errors.New("bad gateway")}
+	r := newTestRegistry(t, poor, rich, failing)
+	r.SetRules(nil, []string{"native", "grobid", "mineru"})
+
+	result, err := r.Run(context.Background(), &Request{PageCount: 1}, RunOptions{Compare: true})
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if result.Parser != "mineru" || poor.calls != 1 || rich.calls != 1 || failing.calls != 1 {
+		t.Errorf("比较模式应运行全部解析器并选择 mineru, 实际 %s", result.Parser)
+	}
+	if result.Score <= Score(poorResult(), 1) {
+		t.Errorf("选中结果的质量分 %.3f 应高于其他结果", result.Score)
+	}
+}
+
+func TestRunFallback(t *testing.T) {
+	failing := &fakeParser{name: "mineru", available: true, err: errors.New("connection refused")}
+	native := &fakeParser{name: "native", available: true, result: poorResult()}
+	r := newTestRegistry(t, failing, native)
+	r.SetRules(nil, []string{"mineru"})
+
+	result, err := r.Run(context.Background(), &Request{}, RunOptions{Fallback: []string{"native"}})
+	if err != nil || result.Parser != "native" {
+		t.Fatalf("应回退到 native, 实际 %v %v", result, err)
+	}
+
+	_, err = r.Run(context.Background(), &Request{}, RunOptions{})
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("没有回退时应返回解析错误, 实际 %v", err)
+	}
+
+	empty := newTestRegistry(t)
+	if _, err := empty.Run(context.Background(), &Request{}, RunOptions{}); !errors.Is(err, ErrNoParser) {
+		t.Errorf("没有解析器时应返回 ErrNoParser, 实际 %v", err)
+	}
+}
+
+func TestScore(t *testing.T) {
+	if score := Score(richResult(), 1); score < 0.8 {
+		t.Errorf("完整结果的质量分过低: %.3f", score)
+	}
+	if score := Score(poorResult(), 10); score > 0.05 {
+		t.Errorf("少量正文的质量分过高: %.3f", score)
+	}
+	garbled := richResult()
+	garbled.FullDocument.Paragraphs[0].Text.Text = strings.Repeat("�", 2000)
+	if score := Score(garbled, 1); score != 0 {
+		t.Errorf("乱码结果的质量分应为0: %.3f", score)
+	}
+	if Score(nil, 1) != 0 {
+		t.Errorf("空结果的质量分应为0")
+	}
+}
+
+func TestFromMarkdown(t *testing.T) {
+	markdown := `# Deep Layout Parsing
+
+Alice Zhang, Bob Li
+
+## Abstract
+
+We parse documents.
+
+## 1 Introduction
+
+Layout matters [1]. See the [survey](https://example.com) and [2-3].
+
+$$
+E = mc^2
+$$
+
+## 2 Method
+
+### 2.1 Encoder
+
+The encoder is *simple*.
+
+![](figure.png)
+
+Figure 1: The encoder.
+
+## References
+
+1. Smith, J. Layout. 2020.
+2. Doe, A. Parsing. 2021.
+3. Roe, B. Tables,
+   continued on a new line. 2022.
+`
+	metadata, document := FromMarkdown(markdown)
+	if metadata.Title == nil || metadata.Title.Text != "Deep Layout Parsing" {
+		t.Fatalf("标题错误: %v", metadata.Title)
+	}
+	if len(metadata.Authors) != 2 || metadata.Authors[1].Surname != "Li" {
+		t.Errorf("作者错误: %v", metadata.Authors)
+	}
+	if metadata.Abstract == nil || metadata.Abstract.Text != "We parse documents." {
+		t.Errorf("摘要错误: %v", metadata.Abstract)
+	}
+	if len(metadata.Catalogue) != 3 || len(metadata.Catalogue[1].Child) != 1 || metadata.Catalogue[1].Child[0].Level != "2" {
+		t.Errorf("目录层级错误: %v", metadata.Catalogue)
+	}
+	if len(metadata.References) != 3 || metadata.References[2].PublishDate != "2022" ||
+		!strings.Contains(metadata.References[2].ContentText, "continued on a new line") {
+		t.Errorf("参考文献错误: %v", metadata.References)
+	}
+	if len(metadata.ReferenceMarkers) != 3 {
+		t.Errorf("引用标记数量错误: %d", len(metadata.ReferenceMarkers))
+	}
+	if len(metadata.FiguresAndTables) != 1 || metadata.FiguresAndTables[0].RefIdx != "Figure 1" {
+		t.Errorf("图表错误: %v", metadata.FiguresAndTables)
+	}
+	if len(metadata.Formulas) != 1 || metadata.Formulas[0].RefContent != "E = mc^2" {
+		t.Errorf("公式错误: %v", metadata.Formulas)
+	}
+	var texts []string
+	for _, p := range document.Paragraphs {
+		if p.Text != nil {
+			texts = append(texts, p.Text.Text)
+		}
+	}
+	want := []string{
+		"We parse documents.",
+		"Layout matters [1]. See the survey and [2-3].",
+		"The encoder is simple.",
+	}
+	if strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("正文段落错误: %q", texts)
+	}
+}
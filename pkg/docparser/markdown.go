@@ -0,0 +1,456 @@
+package docparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+)
+
+var (
+	mdHeadingRe    = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	mdImageRe      = regexp.MustCompile(`!\[([^\]]*)\]\(([^)]*)\)`)
+	mdLinkRe       = regexp.MustCompile(`\[((?:[^\[\]]|\[[^\]]*\])+)\]\([^)]*\)`)
+	mdEmphasisRe   = regexp.MustCompile(`(\*\*|__|\*|_|` + "`" + `)([^*_` + "`" + `]+)(\*\*|__|\*|_|` + "`" + `)`)
+	mdTagRe        = regexp.MustCompile(`</?(sup|sub|span|br|b|i|em|strong)[^>]*>`)
+	mdListItemRe   = regexp.MustCompile(`^\s*(?:[-*+]|\d{1,4}[.)])\s+`)
+	mdRefLabelRe   = regexp.MustCompile(`^\s*(?:[-*+]\s+)?\[(\d{1,4})\]\s*`)
+	mdNumLabelRe   = regexp.MustCompile(`^\s*(\d{1,4})[.)]\s+`)
+	mdYearRe       = regexp.MustCompile(`\b(19\d{2}|20\d{2})[a-z]?\b`)
+	mdCaptionRe    = regexp.MustCompile(`^(?i:(figure|fig\.|table|tab\.))\s*(\d+)`)
+	mdCitationRe   = regexp.MustCompile(`\[(\d{1,4}(?:\s*[,–-]\s*\d{1,4})*)\]`)
+	mdAbstractRe   = regexp.MustCompile(`(?i)^(abstract|摘\s*要)\s*[.:：—-]?\s*`)
+	mdReferencesRe = regexp.MustCompile(`(?i)^(\d+\.?\s*)?(references|bibliography|参考文献)$`)
+	mdAckRe        = regexp.MustCompile(`(?i)^(\d+\.?\s*)?(acknowledg(e)?ments?|致\s*谢)$`)
+	mdAuthorMarkRe = regexp.MustCompile(`[\d*†‡§¶,]+$`)
+	mdAuthorSepRe  = regexp.MustCompile(`\s*(?:,|;|\band\b|&)\s*`)
+	mdCommaRe      = regexp.MustCompile(`\s*,\s*`)
+	mdNumbersRe    = regexp.MustCompile(`^\d{1,4}(?:\s*[,–-]\s*\d{1,4})*$`)
+
+	// Docling 以占位符模式导出图片时输出的标记
+	mdImagePlaceholder = "<!-- image -->"
+
+	// 反斜杠转义的 Markdown 符号
+	mdUnescaper = strings.NewReplacer(`\[`, "[", `\]`, "]", `\_`, "_", `\*`, "*", `\#`, "#", `\|`, "|")
+)
+
+// mdBlock Markdown 中以空行分隔的块
+type mdBlock struct {
+	level int // 标题层级，非标题为0
+	text  string
+	lines []string
+	math  bool
+	table bool
+}
+
+// FromMarkdown 将 Markdown 全文转换为文档元数据和全文段落
+//
+// 适用于只输出 Markdown 的解析后端：一级标题作为论文标题，其余标题构成目录，
+// Abstract 与参考文献章节分别抽取为摘要和参考文献，图片和表格按标题生成图表。
+// Markdown 不含版面坐标，结果中的 Bbox 均为空。
+func FromMarkdown(markdown string) (*pb.DocumentMetadata, *pb.FullDocument) {
+	blocks := splitMarkdown(markdown)
+	metadata := &pb.DocumentMetadata{}
+	var paragraphs []*pb.Paragraph
+	var headings []*mdBlock
+	var frontMatter []string
+	var referenceLines []string
+	var abstract, acknowledgment []string
+	var section string
+	seenSection := false
+	inReferences, inAbstract, inAck := false, false, false
+
+	for i, b := range blocks {
+		if b.level > 0 {
+			abstractHeading := mdAbstractRe.MatchString(b.text) && strings.TrimSpace(mdAbstractRe.ReplaceAllString(b.text, "")) == ""
+			switch {
+			case metadata.Title == nil && !seenSection && b.level <= 2 && !abstractHeading:
+				metadata.Title = &pb.Title{Text: b.text}
+				continue
+			case abstractHeading:
+				inAbstract, inReferences, inAck = true, false, false
+				seenSection = true
+				continue
+			}
+			seenSection = true
+			inAbstract = false
+			inReferences = mdReferencesRe.MatchString(b.text)
+			inAck = mdAckRe.MatchString(b.text)
+			section = b.text
+			headings = append(headings, b)
+			continue
+		}
+
+		switch {
+		case inReferences:
+			referenceLines = append(referenceLines, b.lines...)
+			continue
+		case inAbstract:
+			abstract = append(abstract, b.text)
+		case !seenSection && mdAbstractRe.MatchString(b.text):
+			// 正文中以 "Abstract." 开头的摘要段落
+			abstract = append(abstract, mdAbstractRe.ReplaceAllString(b.text, ""))
+			seenSection = true
+			continue
+		case !seenSection && metadata.Title != nil:
+			frontMatter = append(frontMatter, b.text)
+			continue
+		case inAck:
+			acknowledgment = append(acknowledgment, b.text)
+		}
+
+		if p := mdFigureParagraph(blocks, i, section); p != nil {
+			paragraphs = append(paragraphs, p)
+			continue
+		}
+		if b.text == "" || (mdCaptionRe.MatchString(b.text) && mdHasFigureNeighbour(blocks, i)) {
+			// 图表标题已随图表生成
+			continue
+		}
+		p := &pb.Paragraph{Type: pb.ParagraphType_TEXT, SectionTitle: section, SectionId: section}
+		if b.math {
+			p.Type = pb.ParagraphType_FORMULA
+			p.Formula = &pb.Formula{RefContent: b.text, Id: fmt.Sprintf("formula-%d", len(paragraphs)+1), SectionTitle: section, SectionId: section}
+		} else {
+			p.Text = &pb.Text{Text: b.text}
+		}
+		paragraphs = append(paragraphs, p)
+	}
+
+	metadata.Authors = mdAuthors(frontMatter)
+	if len(abstract) > 0 {
+		metadata.Abstract = &pb.Abstract{Text: strings.Join(abstract, "\n")}
+	}
+	if len(acknowledgment) > 0 {
+		metadata.Acknowledgment = &pb.Acknowledgment{Text: strings.Join(acknowledgment, "\n")}
+	}
+	metadata.Catalogue = mdCatalogue(headings)
+	var labels map[string]string
+	metadata.References, labels = mdReferences(referenceLines)
+
+	for i, p := range paragraphs {
+		p.Order = int32(i + 1)
+		switch {
+		case p.FigureTable != nil:
+			metadata.FiguresAndTables = append(metadata.FiguresAndTables, p.FigureTable)
+		case p.Formula != nil:
+			metadata.Formulas = append(metadata.Formulas, p.Formula)
+		case p.Text != nil:
+			p.References = mdCitations(p.Text.Text, labels)
+			for _, ref := range p.References {
+				metadata.ReferenceMarkers = append(metadata.ReferenceMarkers, &pb.RefMarker{RefIdx: ref.Target, RefContent: ref.Text})
+			}
+		}
+	}
+	return metadata, &pb.FullDocument{Paragraphs: paragraphs}
+}
+
+// splitMarkdown 按空行切分块，代码块和公式块保持完整
+func splitMarkdown(markdown string) []*mdBlock {
+	var blocks []*mdBlock
+	var current []string
+	fence := ""
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		b := &mdBlock{lines: current}
+		joined := strings.TrimSpace(strings.Join(current, "\n"))
+		switch {
+		case strings.HasPrefix(joined, "$$"):
+			b.math = true
+			b.text = strings.TrimSpace(strings.Trim(joined, "$"))
+		case strings.HasPrefix(joined, "|"):
+			b.table = true
+			b.text = joined
+		default:
+			b.text = cleanInline(joinLines(current))
+		}
+		blocks = append(blocks, b)
+		current = nil
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			current = append(current, line)
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+				flush()
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || (strings.HasPrefix(trimmed, "$$") && !(len(trimmed) > 4 && strings.HasSuffix(trimmed, "$$"))) {
+			flush()
+			fence = trimmed[:2]
+			if strings.HasPrefix(trimmed, "```") {
+				fence = "```"
+			}
+			current = append(current, line)
+			continue
+		}
+		if m := mdHeadingRe.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			blocks = append(blocks, &mdBlock{level: len(m[1]), text: cleanInline(m[2]), lines: []string{line}})
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if trimmed == mdImagePlaceholder {
+			flush()
+			blocks = append(blocks, &mdBlock{text: "![]()", lines: []string{line}})
+			continue
+		}
+		// 图片单独成块，便于与相邻的图表标题配对
+		if mdImageRe.MatchString(trimmed) && strings.TrimSpace(mdImageRe.ReplaceAllString(trimmed, "")) == "" {
+			flush()
+			current = append(current, line)
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+	return blocks
+}
+
+// joinLines 合并段落内的换行，处理英文连字符断行
+func joinLines(lines []string) string {
+	var sb strings.Builder
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		text := sb.String()
+		switch {
+		case sb.Len() == 0:
+		case strings.HasSuffix(text, "-") && len(text) > 1 && text[len(text)-2] >= 'a' && text[len(text)-2] <= 'z':
+			s := strings.TrimSuffix(text, "-")
+			sb.Reset()
+			sb.WriteString(s)
+		default:
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+// cleanInline 去掉行内 Markdown 标记，保留图片和引用编号
+func cleanInline(text string) string {
+	if mdImageRe.MatchString(text) {
+		return strings.TrimSpace(text)
+	}
+	text = mdTagRe.ReplaceAllString(mdUnescaper.Replace(text), "")
+	text = mdLinkRe.ReplaceAllStringFunc(text, func(s string) string {
+		// 链接形式的引用，如 "[[1]](#page-5-0)"、"[1](#bib1)"
+		label := mdLinkRe.FindStringSubmatch(s)[1]
+		if mdNumbersRe.MatchString(label) {
+			return "[" + label + "]"
+		}
+		return label
+	})
+	for i := 0; i < 2; i++ {
+		text = mdEmphasisRe.ReplaceAllString(text, "$2")
+	}
+	return strings.TrimSpace(text)
+}
+
+// mdFigureParagraph 图片或表格块生成图表段落，标题取相邻的 "Figure N"/"Table N" 块
+func mdFigureParagraph(blocks []*mdBlock, i int, section string) *pb.Paragraph {
+	b := blocks[i]
+	isImage := mdImageRe.MatchString(b.text)
+	if !isImage && !b.table {
+		return nil
+	}
+	caption := ""
+	for _, j := range []int{i + 1, i - 1} {
+		if j >= 0 && j < len(blocks) && blocks[j].level == 0 && mdCaptionRe.MatchString(blocks[j].text) {
+			caption = blocks[j].text
+			break
+		}
+	}
+	if caption == "" && isImage {
+		caption = strings.TrimSpace(mdImageRe.FindStringSubmatch(b.text)[1])
+	}
+	figureTable := &pb.FigureTable{
+		RefContent:   caption,
+		Type:         "figure",
+		SectionTitle: section,
+		SectionId:    section,
+	}
+	paragraph := &pb.Paragraph{Type: pb.ParagraphType_IMAGE, SectionTitle: section, SectionId: section, FigureTable: figureTable}
+	if b.table {
+		figureTable.Type = "table"
+		paragraph.Type = pb.ParagraphType_TABLE
+	}
+	if m := mdCaptionRe.FindStringSubmatch(caption); m != nil {
+		label := "Figure "
+		if strings.HasPrefix(strings.ToLower(m[1]), "tab") {
+			label = "Table "
+		}
+		figureTable.RefIdx = label + m[2]
+	}
+	figureTable.Id = fmt.Sprintf("%s-%d", figureTable.Type, i+1)
+	return paragraph
+}
+
+// mdHasFigureNeighbour 标题块是否紧邻图片或表格
+func mdHasFigureNeighbour(blocks []*mdBlock, i int) bool {
+	for _, j := range []int{i - 1, i + 1} {
+		if j >= 0 && j < len(blocks) && (blocks[j].table || mdImageRe.MatchString(blocks[j].text)) {
+			return true
+		}
+	}
+	return false
+}
+
+// mdAuthors 从标题与第一个章节之间的文字中抽取作者
+func mdAuthors(frontMatter []string) []*pb.Author {
+	var authors []*pb.Author
+	for _, text := range frontMatter {
+		if len([]rune(text)) > 300 || strings.Contains(text, "@") {
+			continue
+		}
+		for _, part := range mdAuthorSepRe.Split(text, -1) {
+			name := strings.TrimSpace(mdAuthorMarkRe.ReplaceAllString(strings.TrimSpace(part), ""))
+			fields := strings.Fields(name)
+			if len(fields) < 2 || len(fields) > 4 || !capitalized(fields) {
+				continue
+			}
+			authors = append(authors, &pb.Author{
+				FullName:  name,
+				GivenName: strings.Join(fields[:len(fields)-1], " "),
+				Surname:   fields[len(fields)-1],
+			})
+		}
+		if len(authors) > 0 {
+			// 作者通常在标题后的第一段，后续为单位信息
+			break
+		}
+	}
+	return authors
+}
+
+func capitalized(fields []string) bool {
+	for _, f := range fields {
+		r := []rune(f)[0]
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// mdCatalogue 按标题层级构建目录树
+func mdCatalogue(headings []*mdBlock) []*pb.CatalogueItem {
+	if len(headings) == 0 {
+		return []*pb.CatalogueItem{}
+	}
+	minLevel := headings[0].level
+	for _, h := range headings {
+		if h.level < minLevel {
+			minLevel = h.level
+		}
+	}
+	var roots []*pb.CatalogueItem
+	var stack []*pb.CatalogueItem
+	var levels []int
+	for i, h := range headings {
+		level := h.level - minLevel + 1
+		item := &pb.CatalogueItem{
+			Title:      h.text,
+			TitleOrder: h.text,
+			Level:      strconv.Itoa(level),
+			Order:      int32(i + 1),
+			Child:      []*pb.CatalogueItem{},
+		}
+		for len(levels) > 0 && levels[len(levels)-1] >= level {
+			stack, levels = stack[:len(stack)-1], levels[:len(levels)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, item)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Child = append(parent.Child, item)
+		}
+		stack, levels = append(stack, item), append(levels, level)
+	}
+	return roots
+}
+
+// mdReferences 将参考文献章节切分为条目，返回条目和编号到 RefIdx 的映射
+func mdReferences(lines []string) ([]*pb.Reference, map[string]string) {
+	var entries []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if mdListItemRe.MatchString(trimmed) || mdRefLabelRe.MatchString(trimmed) || len(entries) == 0 {
+			entries = append(entries, trimmed)
+			continue
+		}
+		entries[len(entries)-1] += " " + trimmed
+	}
+	references := make([]*pb.Reference, 0, len(entries))
+	labels := make(map[string]string)
+	for _, entry := range entries {
+		ref := &pb.Reference{RefIdx: fmt.Sprintf("b%d", len(references))}
+		text := entry
+		if m := mdRefLabelRe.FindStringSubmatch(text); m != nil {
+			labels[m[1]] = ref.RefIdx
+			text = text[len(m[0]):]
+		} else if m := mdNumLabelRe.FindStringSubmatch(text); m != nil {
+			labels[m[1]] = ref.RefIdx
+			text = text[len(m[0]):]
+		} else {
+			text = mdListItemRe.ReplaceAllString(text, "")
+		}
+		ref.ContentText = cleanInline(text)
+		if years := mdYearRe.FindAllStringSubmatch(text, -1); len(years) > 0 {
+			ref.PublishDate = years[len(years)-1][1]
+		}
+		references = append(references, ref)
+	}
+	return references, labels
+}
+
+// mdCitations 段落中指向已知参考文献的数字引用
+func mdCitations(text string, labels map[string]string) []*pb.RefInfo {
+	if len(labels) == 0 {
+		return nil
+	}
+	var refs []*pb.RefInfo
+	for _, m := range mdCitationRe.FindAllStringSubmatch(text, -1) {
+		for _, part := range mdCommaRe.Split(m[1], -1) {
+			for _, number := range expandRange(part) {
+				if refIdx, ok := labels[number]; ok {
+					refs = append(refs, &pb.RefInfo{Text: m[0], Target: refIdx})
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// expandRange 展开 "4-6" 形式的编号范围
+func expandRange(part string) []string {
+	bounds := strings.FieldsFunc(part, func(r rune) bool { return r == '-' || r == '–' })
+	if len(bounds) == 2 {
+		from, err1 := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		to, err2 := strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err1 == nil && err2 == nil && to >= from && to-from <= 50 {
+			var numbers []string
+			for n := from; n <= to; n++ {
+				numbers = append(numbers, strconv.Itoa(n))
+			}
+			return numbers
+		}
+	}
+	return []string{strings.TrimSpace(part)}
+}
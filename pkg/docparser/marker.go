@@ -0,0 +1,78 @@
+package docparser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yb2020/odoc/pkg/http_client"
+)
+
+// ParserMarker Marker 解析器名称
+const ParserMarker = "marker"
+
+// Marker 服务默认的文件上传转换接口
+const defaultMarkerConvertPath = "/marker/upload"
+
+// MarkerParser 调用 Marker 服务将文档转换为 Markdown 后再解析
+type MarkerParser struct {
+	httpClient  http_client.HttpClient
+	url         string
+	convertPath string
+	timeout     time.Duration
+}
+
+// NewMarkerParser 创建 Marker 解析器，url 为空时解析器不可用
+func NewMarkerParser(httpClient http_client.HttpClient, url, convertPath string, timeout time.Duration) *MarkerParser {
+	if convertPath == "" {
+		convertPath = defaultMarkerConvertPath
+	}
+	return &MarkerParser{
+		httpClient:  httpClient,
+		url:         strings.TrimRight(url, "/"),
+		convertPath: convertPath,
+		timeout:     timeout,
+	}
+}
+
+// markerResponse Marker 服务转换接口的响应
+type markerResponse struct {
+	Format  string `json:"format"`
+	Output  string `json:"output"`
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+}
+
+func (p *MarkerParser) Name() string {
+	return ParserMarker
+}
+
+func (p *MarkerParser) Capabilities() Capability {
+	return CapHeader | CapFulltext | CapFigures | CapFormulas | CapMarkdown
+}
+
+func (p *MarkerParser) Available() bool {
+	return p.url != ""
+}
+
+func (p *MarkerParser) Parse(ctx context.Context, req *Request) (*Result, error) {
+	body, err := p.httpClient.PostMultipartFormWithFileInput(p.url+p.convertPath,
+		map[string]string{"output_format": "markdown"},
+		map[string][]byte{"file": req.Content},
+		map[string]string{"file": fileName(req)},
+		map[string]string{"Accept": "application/json"},
+		p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	var resp markerResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decode marker response: %w", err)
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("marker convert failed: %s", resp.Error)
+	}
+	return markdownResult(resp.Output)
+}
@@ -0,0 +1,108 @@
+package docparser
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+)
+
+// 每页正文的期望字符数，用于估算全文覆盖率
+const expectedCharsPerPage = 2000
+
+// 各项质量指标的权重，合计为1
+const (
+	weightTitle      = 0.15
+	weightAuthors    = 0.10
+	weightAbstract   = 0.10
+	weightText       = 0.25
+	weightCatalogue  = 0.10
+	weightReferences = 0.15
+	weightMarkers    = 0.05
+	weightFigures    = 0.05
+	weightPageBlocks = 0.05
+)
+
+// Score 计算解析结果的质量分，范围 [0, 1]
+//
+// 分数综合头部信息完整度、全文覆盖率、目录、参考文献和图表，
+// 并按乱码比例扣分；pageCount 未知时全文覆盖率只看是否有正文。
+func Score(result *Result, pageCount int) float64 {
+	if result == nil {
+		return 0
+	}
+	var score float64
+	metadata := result.Metadata
+	if metadata != nil {
+		if metadata.Title != nil && strings.TrimSpace(metadata.Title.Text) != "" {
+			score += weightTitle
+		}
+		score += weightAuthors * saturate(len(metadata.Authors), 1)
+		if metadata.Abstract != nil && len([]rune(strings.TrimSpace(metadata.Abstract.Text))) >= 50 {
+			score += weightAbstract
+		}
+		score += weightCatalogue * saturate(len(metadata.Catalogue), 3)
+		score += weightReferences * saturate(len(metadata.References), 10)
+		if len(metadata.ReferenceMarkers) > 0 {
+			score += weightMarkers
+		}
+		if len(metadata.FiguresAndTables) > 0 {
+			score += weightFigures
+		}
+	}
+	if len(result.PageBlocks) > 0 {
+		score += weightPageBlocks
+	}
+
+	text := documentText(result)
+	chars := len([]rune(text))
+	if pageCount > 0 {
+		score += weightText * math.Min(float64(chars)/float64(pageCount*expectedCharsPerPage), 1)
+	} else if chars > 0 {
+		score += weightText
+	}
+	// 乱码比例超过一半时分数归零
+	score *= math.Max(0, 1-2*garbageRatio(text))
+	return math.Round(score*1000) / 1000
+}
+
+// saturate 数量达到 full 时记满分
+func saturate(n, full int) float64 {
+	return math.Min(float64(n)/float64(full), 1)
+}
+
+// documentText 结果中的正文文本，没有段落时使用 Markdown
+func documentText(result *Result) string {
+	var sb strings.Builder
+	if result.FullDocument != nil {
+		for _, p := range result.FullDocument.Paragraphs {
+			if p.Type == pb.ParagraphType_TEXT && p.Text != nil {
+				sb.WriteString(p.Text.Text)
+				sb.WriteByte('\n')
+			}
+		}
+	}
+	if sb.Len() == 0 {
+		return result.Markdown
+	}
+	return sb.String()
+}
+
+// garbageRatio 文本中替换符、私用区和控制字符的比例，用于识别字体编码错误导致的乱码
+func garbageRatio(text string) float64 {
+	total, garbage := 0, 0
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if r == unicode.ReplacementChar || unicode.Is(unicode.Co, r) || unicode.IsControl(r) {
+			garbage++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(garbage) / float64(total)
+}
@@ -0,0 +1,261 @@
+package docparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Rule 解析器选择规则，条件为空表示不限制
+type Rule struct {
+	Parsers   []string   // 命中规则时按顺序尝试的解析器
+	Languages []string   // 文档语言
+	MinPages  int        // 最小页数
+	MaxPages  int        // 最大页数，0表示不限制
+	Tiers     []string   // 会员等级
+	Require   Capability // 解析器必须具备的能力
+}
+
+// Match 请求是否满足规则的条件；页数或语言未知时不满足带对应条件的规则
+func (r Rule) Match(req *Request) bool {
+	if len(r.Languages) > 0 && !containsFold(r.Languages, req.Lang) {
+		return false
+	}
+	if len(r.Tiers) > 0 && !containsFold(r.Tiers, req.Tier) {
+		return false
+	}
+	if r.MinPages > 0 && (req.PageCount <= 0 || req.PageCount < r.MinPages) {
+		return false
+	}
+	if r.MaxPages > 0 && (req.PageCount <= 0 || req.PageCount > r.MaxPages) {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// RunOptions 解析执行选项
+type RunOptions struct {
+	Require  Capability // 候选解析器必须具备的能力
+	Compare  bool       // 并行运行全部候选解析器，选取质量分最高的结果
+	MinScore float64    // 顺序执行时结果低于该分数则继续尝试后续解析器
+	Fallback []string   // 候选解析器全部失败后依次尝试的解析器
+}
+
+// Registry 解析器注册表
+type Registry struct {
+	mu       sync.RWMutex
+	parsers  map[string]Parser
+	order    []string
+	rules    []Rule
+	defaults []string
+}
+
+// NewRegistry 创建解析器注册表
+func NewRegistry() *Registry {
+	return &Registry{parsers: make(map[string]Parser)}
+}
+
+// Register 注册解析器，名称不区分大小写
+func (r *Registry) Register(p Parser) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := strings.ToLower(p.Name())
+	if _, ok := r.parsers[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateParser, name)
+	}
+	r.parsers[name] = p
+	r.order = append(r.order, name)
+	return nil
+}
+
+// Get 按名称获取解析器
+func (r *Registry) Get(name string) (Parser, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.parsers[strings.ToLower(strings.TrimSpace(name))]
+	return p, ok
+}
+
+// Parsers 按注册顺序返回全部解析器
+func (r *Registry) Parsers() []Parser {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Parser, 0, len(r.order))
+	for _, name := range r.order {
+		result = append(result, r.parsers[name])
+	}
+	return result
+}
+
+// SetRules 设置选择规则；没有规则命中时使用 defaults 中的解析器
+func (r *Registry) SetRules(rules []Rule, defaults []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = rules
+	r.defaults = defaults
+}
+
+// Select 按规则选择候选解析器，返回已注册、可用且具备所需能力的解析器
+func (r *Registry) Select(req *Request, require Capability) []Parser {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, rule := range r.rules {
+		if !rule.Match(req) {
+			continue
+		}
+		if candidates := r.resolve(rule.Parsers, require|rule.Require); len(candidates) > 0 {
+			return candidates
+		}
+	}
+	return r.resolve(r.defaults, require)
+}
+
+// resolve 将名称列表解析为可用的解析器，去重并保持顺序
+func (r *Registry) resolve(names []string, require Capability) []Parser {
+	var result []Parser
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		p, ok := r.parsers[name]
+		if !ok || seen[name] || !p.Available() || !p.Capabilities().Has(require) {
+			continue
+		}
+		seen[name] = true
+		result = append(result, p)
+	}
+	return result
+}
+
+// Run 选择解析器并执行解析
+//
+// 顺序模式下依次尝试候选解析器，结果达到 MinScore 即返回，否则返回质量分最高的结果；
+// 比较模式下并行运行全部候选解析器并返回质量分最高的结果。
+// 候选解析器全部失败时依次尝试 Fallback 中的解析器。
+func (r *Registry) Run(ctx context.Context, req *Request, opts RunOptions) (*Result, error) {
+	candidates := r.Select(req, opts.Require)
+	var best *Result
+	var errs []error
+	if opts.Compare {
+		best, errs = runAll(ctx, candidates, req)
+	} else {
+		best, errs = runSequential(ctx, candidates, req, opts.MinScore)
+	}
+	if best != nil {
+		return best, nil
+	}
+
+	r.mu.RLock()
+	fallback := r.resolve(opts.Fallback, opts.Require)
+	r.mu.RUnlock()
+	for _, p := range fallback {
+		if containsParser(candidates, p) {
+			continue
+		}
+		result, err := runParser(ctx, p, req)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return result, nil
+	}
+	if len(errs) == 0 {
+		return nil, ErrNoParser
+	}
+	return nil, errors.Join(errs...)
+}
+
+func runSequential(ctx context.Context, candidates []Parser, req *Request, minScore float64) (*Result, []error) {
+	var best *Result
+	var errs []error
+	for _, p := range candidates {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		result, err := runParser(ctx, p, req)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if best == nil || result.Score > best.Score {
+			best = result
+		}
+		if result.Score >= minScore {
+			break
+		}
+	}
+	return best, errs
+}
+
+func runAll(ctx context.Context, candidates []Parser, req *Request) (*Result, []error) {
+	results := make([]*Result, len(candidates))
+	errs := make([]error, len(candidates))
+	var wg sync.WaitGroup
+	for i, p := range candidates {
+		wg.Add(1)
+		go func(i int, p Parser) {
+			defer wg.Done()
+			results[i], errs[i] = runParser(ctx, p, req)
+		}(i, p)
+	}
+	wg.Wait()
+
+	var best *Result
+	var failed []error
+	for i := range candidates {
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+			continue
+		}
+		// 分数相同时保留规则中靠前的解析器
+		if best == nil || results[i].Score > best.Score {
+			best = results[i]
+		}
+	}
+	return best, failed
+}
+
+// runParser 执行单个解析器并计算结果质量分
+func runParser(ctx context.Context, p Parser, req *Request) (result *Result, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			result, err = nil, fmt.Errorf("%s: panic: %v", p.Name(), rec)
+		}
+	}()
+	result, err = p.Parse(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Name(), err)
+	}
+	if result == nil {
+		return nil, fmt.Errorf("%s: empty result", p.Name())
+	}
+	result.Parser = p.Name()
+	if result.Metadata != nil && result.Metadata.FileSHA256 == "" {
+		result.Metadata.FileSHA256 = req.FileSHA256
+	}
+	result.Score = Score(result, req.PageCount)
+	return result, nil
+}
+
+func containsParser(parsers []Parser, p Parser) bool {
+	for _, c := range parsers {
+		if c == p {
+			return true
+		}
+	}
+	return false
+}
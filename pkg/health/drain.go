@@ -0,0 +1,110 @@
+package health
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+)
+
+// Drainer 统计进行中的 HTTP（含 SSE）和 gRPC 请求，关闭时等待它们结束
+type Drainer struct {
+	mu       sync.Mutex
+	inflight int
+	idle     chan struct{} // Wait 期间请求数归零时关闭
+
+	abortCtx context.Context
+	abort    context.CancelFunc
+}
+
+// NewDrainer 创建请求排空器
+func NewDrainer() *Drainer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Drainer{abortCtx: ctx, abort: cancel}
+}
+
+// InFlight 进行中的请求数
+func (d *Drainer) InFlight() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inflight
+}
+
+// Wait 等待所有进行中的请求结束，ctx 结束时返回 ctx 的错误
+func (d *Drainer) Wait(ctx context.Context) error {
+	d.mu.Lock()
+	if d.inflight == 0 {
+		d.mu.Unlock()
+		return nil
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Abort 取消所有进行中请求的上下文，SSE 等长连接据此结束
+func (d *Drainer) Abort() {
+	d.abort()
+}
+
+// track 登记一个请求，返回的上下文在 Abort 时取消；done 必须在请求结束时调用
+func (d *Drainer) track(ctx context.Context) (context.Context, func()) {
+	d.mu.Lock()
+	d.inflight++
+	d.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(d.abortCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		d.mu.Lock()
+		d.inflight--
+		if d.inflight == 0 && d.idle != nil {
+			close(d.idle)
+			d.idle = nil
+		}
+		d.mu.Unlock()
+	}
+}
+
+// GinMiddleware 统计 HTTP 请求；应在业务路由注册前添加，探针路由不需要统计
+func (d *Drainer) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, done := d.track(c.Request.Context())
+		defer done()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// UnaryServerInterceptor 统计 gRPC 一元请求
+func (d *Drainer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, done := d.track(ctx)
+		defer done()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 统计 gRPC 流式请求
+func (d *Drainer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, done := d.track(stream.Context())
+		defer done()
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
@@ -0,0 +1,34 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 探针路由
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// RegisterRoutes 注册存活和就绪探针
+func (r *Registry) RegisterRoutes(engine *gin.Engine) {
+	engine.GET(LivenessPath, r.Liveness)
+	engine.GET(ReadinessPath, r.Readiness)
+}
+
+// Liveness 进程能处理请求即为存活；关闭过程中同样返回成功，避免排空期间被重启
+func (r *Registry) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": StatusUp, "state": r.State()})
+}
+
+// Readiness 返回各依赖的状态和耗时，未就绪或任一依赖不可用时返回 503
+func (r *Registry) Readiness(c *gin.Context) {
+	report := r.Check(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
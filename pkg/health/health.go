@@ -0,0 +1,203 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Checker 依赖检查，返回 nil 表示依赖可用
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc 函数形式的依赖检查
+type CheckerFunc func(ctx context.Context) error
+
+// Check 实现 Checker
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// 依赖状态
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// 服务状态
+const (
+	StateStarting = "starting" // 启动中，尚未接收流量
+	StateReady    = "ready"    // 可以接收流量
+	StateDraining = "draining" // 关闭中，等待进行中的请求结束
+)
+
+// 单个依赖检查的默认超时时间
+const DefaultCheckTimeout = 2 * time.Second
+
+// Result 单个依赖的检查结果
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report 就绪检查报告
+type Report struct {
+	Status string   `json:"status"`
+	State  string   `json:"state"`
+	Checks []Result `json:"checks"`
+}
+
+// Ready 服务处于就绪状态且所有依赖可用
+func (r Report) Ready() bool {
+	return r.Status == StatusUp
+}
+
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// Registry 依赖检查注册表，同时维护服务的就绪状态
+type Registry struct {
+	mu       sync.RWMutex
+	checkers []namedChecker
+	state    atomic.Value
+	timeout  time.Duration
+}
+
+// NewRegistry 创建注册表，timeout 为单个依赖检查的超时时间，<=0 时使用默认值
+func NewRegistry(timeout time.Duration) *Registry {
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+	r := &Registry{timeout: timeout}
+	r.state.Store(StateStarting)
+	return r
+}
+
+// Register 注册依赖检查，名称重复时覆盖之前的检查
+func (r *Registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.checkers {
+		if r.checkers[i].name == name {
+			r.checkers[i].checker = checker
+			return
+		}
+	}
+	r.checkers = append(r.checkers, namedChecker{name: name, checker: checker})
+}
+
+// SetReady 标记服务可以接收流量
+func (r *Registry) SetReady() {
+	r.state.Store(StateReady)
+}
+
+// SetDraining 标记服务关闭中，之后就绪检查始终失败
+func (r *Registry) SetDraining() {
+	r.state.Store(StateDraining)
+}
+
+// State 当前服务状态
+func (r *Registry) State() string {
+	return r.state.Load().(string)
+}
+
+// Check 并发执行所有依赖检查；服务未就绪时不执行检查
+func (r *Registry) Check(ctx context.Context) Report {
+	state := r.State()
+	report := Report{Status: StatusDown, State: state, Checks: []Result{}}
+	if state != StateReady {
+		return report
+	}
+
+	r.mu.RLock()
+	checkers := make([]namedChecker, len(r.checkers))
+	copy(checkers, r.checkers)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checkers))
+	var wg sync.WaitGroup
+	for i, c := range checkers {
+		wg.Add(1)
+		go func(i int, c namedChecker) {
+			defer wg.Done()
+			results[i] = r.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+	report.Status = StatusUp
+	for _, result := range results {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	report.Checks = results
+	return report
+}
+
+// run 执行单个检查；超时或 panic 都视为依赖不可用，不响应 ctx 的检查不会拖住整个报告
+func (r *Registry) run(ctx context.Context, c namedChecker) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				errCh <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		errCh <- c.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{
+		Name:      c.name,
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// PingDB 检查数据库连接
+func PingDB(db *gorm.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// PingRedis 检查 Redis 连接
+func PingRedis(client interface {
+	Ping(ctx context.Context) *redis.StatusCmd
+}) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+}
@@ -0,0 +1,175 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegistryState(t *testing.T) {
+	r := NewRegistry(0)
+	r.Register("ok", CheckerFunc(func(ctx context.Context) error { return nil }))
+
+	if report := r.Check(context.Background()); report.Ready() || report.State != StateStarting {
+		t.Fatalf("starting report = %+v, want not ready", report)
+	}
+	r.SetReady()
+	if report := r.Check(context.Background()); !report.Ready() || len(report.Checks) != 1 {
+		t.Fatalf("ready report = %+v, want ready with one check", report)
+	}
+	r.SetDraining()
+	report := r.Check(context.Background())
+	if report.Ready() || report.State != StateDraining || len(report.Checks) != 0 {
+		t.Fatalf("draining report = %+v, want not ready without checks", report)
+	}
+}
+
+func TestRegistryCheckResults(t *testing.T) {
+	r := NewRegistry(50 * time.Millisecond)
+	r.Register("redis", CheckerFunc(func(ctx context.Context) error { return nil }))
+	r.Register("database", CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") }))
+	r.Register("s3", CheckerFunc(func(ctx context.Context) error {
+		// 不响应 ctx 的检查也不应拖住报告
+		time.Sleep(time.Second)
+		return nil
+	}))
+	r.Register("scheduler", CheckerFunc(func(ctx context.Context) error { panic("boom") }))
+	// 重复名称覆盖之前的检查
+	r.Register("redis", CheckerFunc(func(ctx context.Context) error { return nil }))
+	r.SetReady()
+
+	start := time.Now()
+	report := r.Check(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Check took %v, want bounded by timeout", elapsed)
+	}
+	if report.Ready() {
+		t.Fatalf("report ready with failing checks")
+	}
+
+	want := map[string]string{
+		"database":  StatusDown,
+		"redis":     StatusUp,
+		"s3":        StatusDown,
+		"scheduler": StatusDown,
+	}
+	if len(report.Checks) != len(want) {
+		t.Fatalf("checks = %+v, want %d", report.Checks, len(want))
+	}
+	for i, result := range report.Checks {
+		if i > 0 && report.Checks[i-1].Name > result.Name {
+			t.Errorf("checks not sorted by name: %+v", report.Checks)
+		}
+		if result.Status != want[result.Name] {
+			t.Errorf("%s status = %s, want %s (error %q)", result.Name, result.Status, want[result.Name], result.Error)
+		}
+		if result.Status == StatusDown && result.Error == "" {
+			t.Errorf("%s is down without error", result.Name)
+		}
+	}
+}
+
+func TestDrainerWait(t *testing.T) {
+	d := NewDrainer()
+	if err := d.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait with no requests = %v", err)
+	}
+
+	_, done := d.track(context.Background())
+	if d.InFlight() != 1 {
+		t.Fatalf("InFlight = %d, want 1", d.InFlight())
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		done()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := d.Wait(ctx); err != nil {
+		t.Fatalf("Wait = %v, want nil after request finished", err)
+	}
+	if d.InFlight() != 0 {
+		t.Fatalf("InFlight = %d, want 0", d.InFlight())
+	}
+}
+
+func TestDrainerAbort(t *testing.T) {
+	d := NewDrainer()
+	reqCtx, done := d.track(context.Background())
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait = %v, want deadline exceeded", err)
+	}
+
+	d.Abort()
+	select {
+	case <-reqCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("request context not cancelled by Abort")
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := NewRegistry(0)
+	healthy := true
+	r.Register("database", CheckerFunc(func(ctx context.Context) error {
+		if !healthy {
+			return errors.New("down")
+		}
+		return nil
+	}))
+	d := NewDrainer()
+
+	engine := gin.New()
+	r.RegisterRoutes(engine)
+	engine.Use(d.GinMiddleware())
+	engine.GET("/api/ping", func(c *gin.Context) {
+		if d.InFlight() != 1 {
+			t.Errorf("InFlight inside handler = %d, want 1", d.InFlight())
+		}
+		c.Status(http.StatusOK)
+	})
+
+	get := func(path string) (int, Report) {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var report Report
+		_ = json.Unmarshal(w.Body.Bytes(), &report)
+		return w.Code, report
+	}
+
+	if code, _ := get(LivenessPath); code != http.StatusOK {
+		t.Errorf("liveness = %d, want 200", code)
+	}
+	if code, _ := get(ReadinessPath); code != http.StatusServiceUnavailable {
+		t.Errorf("readiness before ready = %d, want 503", code)
+	}
+	r.SetReady()
+	if code, report := get(ReadinessPath); code != http.StatusOK || len(report.Checks) != 1 {
+		t.Errorf("readiness = %d %+v, want 200 with one check", code, report)
+	}
+	healthy = false
+	if code, report := get(ReadinessPath); code != http.StatusServiceUnavailable || report.Checks[0].Status != StatusDown {
+		t.Errorf("readiness with failing dependency = %d %+v, want 503", code, report)
+	}
+	r.SetDraining()
+	if code, _ := get(LivenessPath); code != http.StatusOK {
+		t.Errorf("liveness while draining = %d, want 200", code)
+	}
+	if code, _ := get("/api/ping"); code != http.StatusOK {
+		t.Errorf("api = %d, want 200", code)
+	}
+	if d.InFlight() != 0 {
+		t.Errorf("InFlight after request = %d, want 0", d.InFlight())
+	}
+}
@@ -0,0 +1,113 @@
+package http_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// 抓取用户提供的URL时允许的最大重定向次数
+const publicClientMaxRedirects = 5
+
+var (
+	// ErrNonPublicAddress 目标地址不是公网地址
+	ErrNonPublicAddress = errors.New("destination is not a public address")
+	// ErrBodyTooLarge 响应体超过大小上限
+	ErrBodyTooLarge = errors.New("response body too large")
+)
+
+// 运营商级NAT等不属于 netip 私有地址判断范围的非公网地址段
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// NewPublicClient 创建只允许访问公网地址的HTTP客户端，用于抓取用户提供的URL。
+// 检查在建立连接时针对实际连接的IP进行，每次重定向都会重新检查，
+// 因此无法通过解析到内网的域名、DNS重绑定或重定向访问回环、内网和链路本地地址
+func NewPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !IsPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrNonPublicAddress, address)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// 不使用环境变量中的代理，否则连接检查的是代理地址而不是目标地址
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= publicClientMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", publicClientMaxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: redirect to %s", ErrNonPublicAddress, req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// IsPublicAddr 判断地址是否为公网地址
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// FetchLimited 发送GET请求并读取响应内容，响应体超过 maxSize 时返回 ErrBodyTooLarge，不会读取超出部分
+func FetchLimited(ctx context.Context, client *http.Client, url string, headers map[string]string, maxSize int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送GET请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP请求返回非200状态码: %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, ErrBodyTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if int64(len(body)) > maxSize {
+		return nil, ErrBodyTooLarge
+	}
+	return body, nil
+}
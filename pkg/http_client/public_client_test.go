@@ -0,0 +1,87 @@
+package http_client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "127.0.0.1", want: false},
+		{addr: "::1", want: false},
+		{addr: "10.1.2.3", want: false},
+		{addr: "172.16.0.1", want: false},
+		{addr: "192.168.1.1", want: false},
+		{addr: "169.254.169.254", want: false},
+		{addr: "fe80::1", want: false},
+		{addr: "fd00::1", want: false},
+		{addr: "0.0.0.0", want: false},
+		{addr: "100.64.0.1", want: false},
+		{addr: "::ffff:127.0.0.1", want: false},
+		{addr: "::ffff:169.254.169.254", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := IsPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("IsPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublicClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	// 直接访问和经由域名访问都会在建立连接时被拒绝
+	urls := []string{server.URL, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)}
+	client := NewPublicClient(5 * time.Second)
+	for _, url := range urls {
+		_, err := FetchLimited(context.Background(), client, url, nil, 1024)
+		if !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("FetchLimited(%s) err = %v, want ErrNonPublicAddress", url, err)
+		}
+	}
+}
+
+func TestFetchLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 不声明长度，只能在读取时限制
+		w.Header().Set("Transfer-Encoding", "chunked")
+		w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		wantErr error
+	}{
+		{name: "within limit", maxSize: 100},
+		{name: "over limit", maxSize: 99, wantErr: ErrBodyTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := FetchLimited(context.Background(), server.Client(), server.URL, nil, tt.maxSize)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && len(body) != 100 {
+				t.Fatalf("body length = %d, want 100", len(body))
+			}
+		})
+	}
+}
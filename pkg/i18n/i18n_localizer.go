@@ -16,6 +16,8 @@ type Localizer interface {
 	Localize(messageID string, c *gin.Context) string
 	// LocalizeWithData 使用数据本地化消息
 	LocalizeWithData(messageID string, data map[string]interface{}, c *gin.Context) string
+	// LocalizeWithLanguage 使用指定语言和数据本地化消息，用于脱离请求上下文的场景（如发送邮件）
+	LocalizeWithLanguage(messageID string, data map[string]interface{}, lang string) string
 	// GetDefaultLanguage 获取默认语言
 	GetDefaultLanguage() string
 	// GetSupportedLanguages 获取支持的语言列表
@@ -87,6 +89,14 @@ func (l *I18nLocalizer) LocalizeWithData(messageID string, data map[string]inter
 	return l.localizeWithLocale(messageID, data, lang)
 }
 
+// LocalizeWithLanguage 使用指定语言和数据本地化消息，语言为空时使用默认语言
+func (l *I18nLocalizer) LocalizeWithLanguage(messageID string, data map[string]interface{}, lang string) string {
+	if lang == "" {
+		lang = l.defaultLanguage
+	}
+	return l.localizeWithLocale(messageID, data, lang)
+}
+
 func (l *I18nLocalizer) GetLanguage(c *gin.Context) string {
 	return l.getLanguage(c)
 }
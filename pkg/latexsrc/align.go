@@ -0,0 +1,667 @@
+package latexsrc
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yb2020/odoc/pkg/pdftext"
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	anchorWords   = 6    // 锚点词组的最大长度
+	minAnchor     = 3    // 锚点词组的最小长度，过短容易误匹配
+	searchWindow  = 4000 // 顺序查找时向后搜索的词数
+	headingWindow = 3000
+	floatWindow   = 20000 // 图表会浮动到前后若干页，标题在更大范围内查找
+	maxGapLines   = 15    // 段落之间的行数超过该值时不按间隙估算公式位置
+)
+
+var (
+	// 正文中的方括号引用，如 "[3]"、"[1, 4–6]"、"[SJL20]"
+	bracketRe = regexp.MustCompile(`\[([^\[\]]{1,120})\]`)
+	// 行尾的公式编号，如 "(3)"、"(2a)"
+	eqNumberRe = regexp.MustCompile(`\(([0-9A-Z]+(?:\.[0-9]+)?[a-z]?)\)\s*$`)
+	// 正文中的图表引用，如 "Figure 3"、"Fig. 2"、"Table 1"
+	figureMarkerRe = regexp.MustCompile(`\b(Figure|Fig\.|Table|Tab\.)\s*~?(\d+)`)
+)
+
+// AlignStats 对齐结果统计
+type AlignStats struct {
+	Blocks  int // 参与对齐的块数
+	Aligned int // 找到PDF位置的块数
+}
+
+// pdfLine PDF中的一行及其所在页
+type pdfLine struct {
+	page *pdftext.Page
+	line *pdftext.Line
+}
+
+// aligner 将源码中的文本按词序与PDF文本层匹配
+type aligner struct {
+	paper    *Paper
+	metadata *pb.DocumentMetadata
+	lines    []pdfLine
+	words    []string // PDF文本层按阅读顺序的规范化词
+	wordLine []int    // 每个词所在的行
+	wordEnd  []int    // 每个词结束的行，连字符断行合并的词跨两行
+	body     []bool   // 行已匹配到正文、标题或图表标题
+	cursor   int
+
+	markers    map[string]bool
+	figureRefs map[string]bool
+	stats      AlignStats
+	equations  []pendingEquation
+	floats     []pendingFloat
+	lastEnd    int
+}
+
+// pendingEquation 等待按前后段落位置估算坐标的公式
+type pendingEquation struct {
+	formula *pb.Formula
+	numbers []string
+	prevEnd int // 前一个已对齐块的最后一个词，-1 表示没有
+	next    int // 后一个已对齐块的第一个词，-1 表示没有
+}
+
+// pendingFloat 等待估算区域的图表
+type pendingFloat struct {
+	figure  *pb.FigureTable
+	table   bool
+	caption []int // 标题所在行
+}
+
+// Align 将 ToDocument 生成的结果与PDF页面对齐，回填段落、标题、公式、图表和引用标记的坐标
+func (p *Paper) Align(metadata *pb.DocumentMetadata, pages []*pdftext.Page) AlignStats {
+	a := &aligner{
+		paper:      p,
+		metadata:   metadata,
+		markers:    make(map[string]bool),
+		figureRefs: make(map[string]bool),
+		lastEnd:    -1,
+	}
+	metadata.Pages = metadata.Pages[:0]
+	for _, page := range pages {
+		metadata.Pages = append(metadata.Pages, &pb.PageInfo{
+			PageNumber: int32(page.Number),
+			Width:      page.Width,
+			Height:     page.Height,
+		})
+	}
+	a.index(pages)
+	if len(a.words) == 0 || p.paragraphs == nil {
+		return a.stats
+	}
+	for _, ft := range metadata.FiguresAndTables {
+		if ft.RefIdx != "" {
+			a.figureRefs[ft.RefIdx] = true
+		}
+	}
+	a.front()
+	for _, b := range p.Blocks {
+		ref := p.paragraphs[b]
+		if ref == nil {
+			continue
+		}
+		a.stats.Blocks++
+		switch b.Kind {
+		case BlockHeading:
+			a.heading(b, ref.item)
+		case BlockText:
+			a.text(b, ref.paragraph)
+		case BlockEquation:
+			a.equations = append(a.equations, pendingEquation{formula: ref.paragraph.Formula, numbers: b.Numbers, prevEnd: a.lastEnd, next: -1})
+		case BlockFigure, BlockTable:
+			a.caption(b, ref.paragraph)
+		}
+	}
+	a.placeEquations()
+	a.placeFloats()
+	return a.stats
+}
+
+// index 建立PDF文本层的词序索引，合并行尾连字符断开的单词
+func (a *aligner) index(pages []*pdftext.Page) {
+	for _, page := range pages {
+		hyphen := false
+		for i := range page.Lines {
+			line := &page.Lines[i]
+			a.lines = append(a.lines, pdfLine{page: page, line: line})
+			lineIdx := len(a.lines) - 1
+			words := normWords(line.Text)
+			if hyphen && len(words) > 0 && startsLower(line.Text) && len(a.words) > 0 {
+				a.words[len(a.words)-1] += words[0]
+				a.wordEnd[len(a.wordEnd)-1] = lineIdx
+				words = words[1:]
+			}
+			for _, w := range words {
+				a.words = append(a.words, w)
+				a.wordLine = append(a.wordLine, lineIdx)
+				a.wordEnd = append(a.wordEnd, lineIdx)
+			}
+			text := strings.TrimSpace(line.Text)
+			hyphen = len(text) > 1 && strings.HasSuffix(text, "-") && unicode.IsLetter(rune(text[len(text)-2]))
+		}
+	}
+	a.body = make([]bool, len(a.lines))
+}
+
+// front 对齐标题和摘要，只在前两页中查找
+func (a *aligner) front() {
+	limit := 0
+	for limit < len(a.words) && a.lines[a.wordLine[limit]].page.Number <= 2 {
+		limit++
+	}
+	if title := a.metadata.Title; title != nil {
+		if start, end, ok := a.locate(title.Text, 0, limit, false); ok {
+			title.Bbox = a.rangeBBox(start, end)
+			a.cursor = end + 1
+		}
+	}
+	if abstract := a.metadata.Abstract; abstract != nil {
+		if start, end, ok := a.locate(abstract.Text, a.cursor, limit, false); ok {
+			abstract.Bbox = a.rangeBBox(start, end)
+			a.cursor = end + 1
+		}
+	}
+}
+
+func (a *aligner) heading(b *Block, item *pb.CatalogueItem) {
+	words := normWords(b.SectionTitle())
+	if len(words) == 0 {
+		return
+	}
+	to := min(len(a.words), a.cursor+headingWindow)
+	pos := a.find(words, a.cursor, to, true)
+	if pos < 0 && b.Number != "" {
+		// 部分模板不显示章节编号
+		words = normWords(b.Text)
+		pos = a.find(words, a.cursor, to, true)
+	}
+	if pos < 0 {
+		return
+	}
+	end := pos + len(words) - 1
+	item.Bbox = a.rangeBBox(pos, end)
+	a.aligned(pos, end)
+}
+
+func (a *aligner) text(b *Block, paragraph *pb.Paragraph) {
+	start, end, ok := a.locate(b.Text, a.cursor, min(len(a.words), a.cursor+searchWindow), false)
+	if !ok {
+		// 顺序查找失败时在剩余全文中查找，跳过被浮动体打乱的部分
+		start, end, ok = a.locate(b.Text, a.cursor, len(a.words), false)
+	}
+	if !ok {
+		return
+	}
+	paragraph.Text.Bbox = a.rangeBBox(start, end)
+	a.aligned(start, end)
+	lines := a.rangeLines(start, end)
+	a.citations(paragraph.References, lines)
+	a.figureMarkers(lines)
+}
+
+// aligned 记录已对齐的范围，推进顺序查找的位置
+func (a *aligner) aligned(start, end int) {
+	a.stats.Aligned++
+	for i := range a.equations {
+		if a.equations[i].next < 0 {
+			a.equations[i].next = start
+		}
+	}
+	a.cursor = end + 1
+	a.lastEnd = end
+}
+
+func (a *aligner) caption(b *Block, paragraph *pb.Paragraph) {
+	from := max(0, a.cursor-floatWindow/2)
+	start, end, ok := a.locate(b.Text, from, min(len(a.words), a.cursor+floatWindow), false)
+	if !ok {
+		return
+	}
+	a.stats.Aligned++
+	figure := paragraph.FigureTable
+	figure.RefBbox = a.rangeBBox(start, end)
+	lines := a.rangeLines(start, end)
+	for _, li := range lines {
+		a.body[li] = true
+	}
+	a.citations(paragraph.References, lines)
+	a.floats = append(a.floats, pendingFloat{figure: figure, table: b.Kind == BlockTable, caption: lines})
+}
+
+// locate 在 [from, to) 中查找文本，返回首尾词的位置
+//
+// 行内公式在PDF中的文字与源码无法对应，文本按公式切分为若干片段，
+// 用开头片段的前几个词定位起点、结尾片段的后几个词定位终点。
+func (a *aligner) locate(text string, from, to int, lineStart bool) (int, int, bool) {
+	segments := mathFreeSegments(text)
+	total := 0
+	for _, seg := range segments {
+		total += len(seg)
+	}
+	if total < minAnchor || from >= to {
+		return 0, 0, false
+	}
+	start := -1
+	for i, tried := 0, 0; i < len(segments) && tried < 3 && start < 0; i++ {
+		seg := segments[i]
+		if len(seg) < minAnchor {
+			continue
+		}
+		tried++
+		start = a.find(seg[:min(anchorWords, len(seg))], from, to, lineStart)
+	}
+	if start < 0 {
+		return 0, 0, false
+	}
+	limit := min(len(a.words), start+total*2+50)
+	end := -1
+	for i, tried := len(segments)-1, 0; i >= 0 && tried < 3 && end < 0; i-- {
+		seg := segments[i]
+		if len(seg) < minAnchor {
+			continue
+		}
+		tried++
+		k := min(anchorWords, len(seg))
+		if pos := a.find(seg[len(seg)-k:], start, limit, false); pos >= 0 {
+			end = pos + k - 1
+		}
+	}
+	if end < 0 {
+		end = min(len(a.words)-1, start+total-1)
+	}
+	return start, end, true
+}
+
+// find 查找连续词组的第一次出现，lineStart 要求词组从行首开始
+func (a *aligner) find(seq []string, from, to int, lineStart bool) int {
+	if len(seq) == 0 {
+		return -1
+	}
+	to = min(to, len(a.words)-len(seq)+1)
+	for i := max(from, 0); i < to; i++ {
+		if a.words[i] != seq[0] {
+			continue
+		}
+		if lineStart && i > 0 && a.wordLine[i-1] == a.wordLine[i] {
+			continue
+		}
+		matched := true
+		for k := 1; k < len(seq); k++ {
+			if a.words[i+k] != seq[k] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
+
+// rangeLines 词范围覆盖的行
+func (a *aligner) rangeLines(start, end int) []int {
+	var lines []int
+	for li := a.wordLine[start]; li <= a.wordEnd[end]; li++ {
+		lines = append(lines, li)
+	}
+	return lines
+}
+
+// rangeBBox 词范围在起始页、起始栏内的外接矩形
+func (a *aligner) rangeBBox(start, end int) *pb.BBox {
+	first := a.lines[a.wordLine[start]]
+	rect := first.line.Rect
+	for _, li := range a.rangeLines(start, end) {
+		a.body[li] = true
+		l := a.lines[li]
+		if l.page == first.page && l.line.Column == first.line.Column {
+			rect = rect.Union(l.line.Rect)
+		}
+	}
+	return toBBox(rect, first.page)
+}
+
+// citations 在段落所在的行中查找引用标记，生成参考文献引用标记并回填段落引用的坐标
+func (a *aligner) citations(refs []*pb.RefInfo, lines []int) {
+	if len(refs) == 0 {
+		return
+	}
+	targets := make(map[string]*BibItem)
+	marks := make(map[string]string) // 编号或 alpha 标签 -> RefIdx
+	for _, ref := range refs {
+		idx, err := strconv.Atoi(strings.TrimPrefix(ref.Target, "b"))
+		if err != nil || idx < 0 || idx >= len(a.paper.Bibliography) {
+			continue
+		}
+		item := a.paper.Bibliography[idx]
+		targets[ref.Target] = item
+		marks[item.mark(idx)] = ref.Target
+	}
+	patterns := make(map[string]*regexp.Regexp)
+	if a.paper.AuthorYear {
+		for target, item := range targets {
+			if re := authorYearRe(item); re != nil {
+				patterns[target] = re
+			}
+		}
+	}
+	found := make(map[string]*pb.BBox)
+	for _, li := range lines {
+		l := a.lines[li]
+		if a.paper.AuthorYear {
+			for target, re := range patterns {
+				for _, loc := range re.FindAllStringIndex(l.line.Text, -1) {
+					a.addMarker(li, loc[0], loc[1], target, found)
+				}
+			}
+			continue
+		}
+		for _, loc := range bracketRe.FindAllStringSubmatchIndex(l.line.Text, -1) {
+			for _, m := range expandMarks(l.line.Text[loc[2]:loc[3]]) {
+				if target, ok := marks[m]; ok {
+					a.addMarker(li, loc[0], loc[1], target, found)
+				}
+			}
+		}
+	}
+	for _, ref := range refs {
+		if bbox, ok := found[ref.Target]; ok {
+			ref.Bbox = bbox
+		}
+	}
+}
+
+func (a *aligner) addMarker(li, start, end int, target string, found map[string]*pb.BBox) {
+	l := a.lines[li]
+	key := strconv.Itoa(li) + ":" + strconv.Itoa(start) + ":" + target
+	if a.markers[key] {
+		return
+	}
+	a.markers[key] = true
+	bbox := toBBox(estimateRange(*l.line, start, end), l.page)
+	a.metadata.ReferenceMarkers = append(a.metadata.ReferenceMarkers, &pb.RefMarker{
+		RefIdx:     target,
+		Bbox:       bbox,
+		RefContent: l.line.Text[start:end],
+	})
+	if _, ok := found[target]; !ok {
+		found[target] = bbox
+	}
+}
+
+// authorYearRe 作者-年份引用的匹配规则：第一作者姓氏后不远处出现年份
+func authorYearRe(item *BibItem) *regexp.Regexp {
+	surname := strings.Fields(item.Short)
+	if len(surname) == 0 || item.Year == "" {
+		return nil
+	}
+	return regexp.MustCompile(`\b` + regexp.QuoteMeta(surname[0]) + `\b[^()\[\]]{0,60}?` + regexp.QuoteMeta(item.Year))
+}
+
+// expandMarks 展开方括号中的编号列表和范围，如 "1, 4–6" -> 1 4 5 6
+func expandMarks(s string) []string {
+	var marks []string
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		part = strings.TrimSpace(part)
+		bounds := strings.FieldsFunc(part, func(r rune) bool { return r == '-' || r == '–' })
+		if len(bounds) == 2 {
+			from, err1 := strconv.Atoi(strings.TrimSpace(bounds[0]))
+			to, err2 := strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err1 == nil && err2 == nil && to >= from && to-from <= 50 {
+				for n := from; n <= to; n++ {
+					marks = append(marks, strconv.Itoa(n))
+				}
+				continue
+			}
+		}
+		if part != "" {
+			marks = append(marks, part)
+		}
+	}
+	return marks
+}
+
+// figureMarkers 段落中的图表引用标记
+func (a *aligner) figureMarkers(lines []int) {
+	for _, li := range lines {
+		l := a.lines[li]
+		for _, loc := range figureMarkerRe.FindAllStringSubmatchIndex(l.line.Text, -1) {
+			refIdx := "Figure " + l.line.Text[loc[4]:loc[5]]
+			if strings.HasPrefix(l.line.Text[loc[2]:loc[3]], "Tab") {
+				refIdx = "Table " + l.line.Text[loc[4]:loc[5]]
+			}
+			if !a.figureRefs[refIdx] {
+				continue
+			}
+			a.metadata.FigureAndTableMarkers = append(a.metadata.FigureAndTableMarkers, &pb.RefMarker{
+				RefIdx:     refIdx,
+				RefContent: l.line.Text[loc[4]:loc[5]],
+				Bbox:       toBBox(estimateRange(*l.line, loc[0], loc[1]), l.page),
+			})
+		}
+	}
+}
+
+// placeEquations 公式位于前后两个已对齐段落之间的行中，有编号时以编号所在行为中心
+func (a *aligner) placeEquations() {
+	gaps := make(map[[2]int]int)
+	for _, eq := range a.equations {
+		gaps[[2]int{eq.prevEnd, eq.next}]++
+	}
+	for _, eq := range a.equations {
+		lo, hi := 0, len(a.lines)-1
+		if eq.prevEnd >= 0 {
+			lo = a.wordEnd[eq.prevEnd] + 1
+		}
+		if eq.next >= 0 {
+			hi = a.wordLine[eq.next] - 1
+		}
+		if lo > hi {
+			continue
+		}
+		var lines []int
+		if len(eq.numbers) > 0 {
+			lines = a.numberedLines(lo, hi, eq.numbers)
+		} else if gaps[[2]int{eq.prevEnd, eq.next}] == 1 && hi-lo < maxGapLines {
+			// 间隙中只有这一个公式时整个间隙都是公式
+			for li := lo; li <= hi; li++ {
+				if a.lines[li].page == a.lines[lo].page {
+					lines = append(lines, li)
+				}
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		first := a.lines[lines[0]]
+		rect := first.line.Rect
+		for _, li := range lines[1:] {
+			rect = rect.Union(a.lines[li].line.Rect)
+		}
+		eq.formula.Bbox = toBBox(rect, first.page)
+		a.stats.Aligned++
+	}
+}
+
+// numberedLines 以公式编号所在行为中心，向上下扩展到行距明显变大或遇到其他公式编号为止
+func (a *aligner) numberedLines(lo, hi int, numbers []string) []int {
+	own := make(map[string]bool, len(numbers))
+	for _, n := range numbers {
+		own[n] = true
+	}
+	anchor, last := -1, -1
+	for li := lo; li <= hi; li++ {
+		if m := eqNumberRe.FindStringSubmatch(a.lines[li].line.Text); m != nil && own[m[1]] {
+			if anchor < 0 {
+				anchor = li
+			}
+			last = li
+		}
+	}
+	if anchor < 0 {
+		return nil
+	}
+	belongs := func(li, neighbour int) bool {
+		l, n := a.lines[li], a.lines[neighbour]
+		if l.page != n.page || a.body[li] {
+			return false
+		}
+		if m := eqNumberRe.FindStringSubmatch(l.line.Text); m != nil && !own[m[1]] {
+			return false
+		}
+		gap := math.Max(l.line.Rect.Y0-n.line.Rect.Y1, n.line.Rect.Y0-l.line.Rect.Y1)
+		return gap < 1.5*math.Max(n.line.Rect.Height(), 10)
+	}
+	from, to := anchor, last
+	for from > lo && belongs(from-1, from) {
+		from--
+	}
+	for to < hi && belongs(to+1, to) {
+		to++
+	}
+	var lines []int
+	for li := from; li <= to; li++ {
+		if a.lines[li].page == a.lines[anchor].page {
+			lines = append(lines, li)
+		}
+	}
+	return lines
+}
+
+// placeFloats 估算图表区域：图在标题上方、表在标题下方，直到同栏内最近的正文行
+func (a *aligner) placeFloats() {
+	for _, f := range a.floats {
+		captionLine := a.lines[f.caption[0]]
+		page := captionLine.page
+		caption := captionLine.line.Rect
+		for _, li := range f.caption {
+			if a.lines[li].page == page {
+				caption = caption.Union(a.lines[li].line.Rect)
+			}
+		}
+		x0, x1 := caption.X0, caption.X1
+		top, bottom := page.Height, 0.0
+		for _, l := range page.Lines {
+			if l.Column == captionLine.line.Column {
+				x0, x1 = math.Min(x0, l.Rect.X0), math.Max(x1, l.Rect.X1)
+			}
+			top, bottom = math.Min(top, l.Rect.Y0), math.Max(bottom, l.Rect.Y1)
+		}
+		region := pdftext.Rect{X0: x0, X1: x1}
+		for li, l := range a.lines {
+			if l.page != page || !a.body[li] || l.line.Rect.OverlapX(region) <= 0 || containsInt(f.caption, li) {
+				continue
+			}
+			if l.line.Rect.Y1 <= caption.Y0 {
+				top = math.Max(top, l.line.Rect.Y1)
+			}
+			if l.line.Rect.Y0 >= caption.Y1 {
+				bottom = math.Min(bottom, l.line.Rect.Y0)
+			}
+		}
+		region.Y0, region.Y1 = top, caption.Y0
+		if f.table && bottom-caption.Y1 > 10 {
+			region.Y0, region.Y1 = caption.Y1, bottom
+		}
+		if region.Height() < 10 {
+			continue
+		}
+		f.figure.Bbox = toBBox(region, page)
+	}
+}
+
+func containsInt(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// mathFreeSegments 按行内公式切分文本，返回每个片段的规范化词
+func mathFreeSegments(text string) [][]string {
+	var segments [][]string
+	last := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] != '$' {
+			continue
+		}
+		if words := normWords(text[last:i]); len(words) > 0 {
+			segments = append(segments, words)
+		}
+		i = closingDollar(text, i+1)
+		last = i + 1
+	}
+	if last < len(text) {
+		if words := normWords(text[last:]); len(words) > 0 {
+			segments = append(segments, words)
+		}
+	}
+	return segments
+}
+
+// normWords 规范化为小写的字母数字词，去掉重音，连字等兼容字符按 NFKD 分解
+func normWords(s string) []string {
+	var words []string
+	var cur strings.Builder
+	for _, r := range norm.NFKD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cur.WriteRune(unicode.ToLower(r))
+		case cur.Len() > 0:
+			words = append(words, cur.String())
+			cur.Reset()
+		}
+	}
+	if cur.Len() > 0 {
+		words = append(words, cur.String())
+	}
+	return words
+}
+
+func startsLower(s string) bool {
+	r, _ := utf8.DecodeRuneInString(strings.TrimSpace(s))
+	return unicode.IsLower(r)
+}
+
+// estimateRange 按字符位置估算行内一段文字的范围
+func estimateRange(line pdftext.Line, start, end int) pdftext.Rect {
+	total := utf8.RuneCountInString(line.Text)
+	if total == 0 {
+		return line.Rect
+	}
+	from := utf8.RuneCountInString(line.Text[:start])
+	to := utf8.RuneCountInString(line.Text[:end])
+	width := line.Rect.Width()
+	return pdftext.Rect{
+		X0: line.Rect.X0 + width*float64(from)/float64(total),
+		Y0: line.Rect.Y0,
+		X1: math.Min(line.Rect.X1, line.Rect.X0+width*float64(to)/float64(total)),
+		Y1: line.Rect.Y1,
+	}
+}
+
+// toBBox 将 pdftext 的矩形转换为解析结果的 BBox
+func toBBox(rect pdftext.Rect, page *pdftext.Page) *pb.BBox {
+	return &pb.BBox{
+		X0:           rect.X0,
+		Y0:           rect.Y0,
+		X1:           rect.X1,
+		Y1:           rect.Y1,
+		OriginHeight: page.Height,
+		OriginWidth:  page.Width,
+		PageNumber:   int32(page.Number),
+	}
+}
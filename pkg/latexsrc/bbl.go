@@ -0,0 +1,220 @@
+package latexsrc
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	bibYearRe     = regexp.MustCompile(`\b(19\d{2}|20\d{2})[a-z]?\b`)
+	bibArxivRe    = regexp.MustCompile(`(?i)(?:arxiv[:\s]*(?:preprint\s*)?(?:arxiv:)?|arxiv\.org/abs/)(\d{4}\.\d{4,5})`)
+	bibQuotedRe   = regexp.MustCompile(`"(.{8,}?)[,.]?"`)
+	natbibLabelRe = regexp.MustCompile(`^(.*?)\s*\(([^()]*)\)(.*)$`)
+	bibInitialsRe = regexp.MustCompile(`^(?:[A-Z][a-z]?\.\s*-?)+$`)
+	blxFieldRe    = regexp.MustCompile(`\\field\{(\w+)\}\{`)
+	blxFamilyRe   = regexp.MustCompile(`family=\{([^}]*)\}`)
+	blxGivenRe    = regexp.MustCompile(`given=\{([^}]*)\}`)
+	blxEprintRe   = regexp.MustCompile(`\\verb\{eprint\}\s*\\verb\s+(\S+)`)
+)
+
+// BibItem 参考文献条目
+type BibItem struct {
+	Key     string   // \bibitem 的引用键
+	Label   string   // \bibitem 的可选标签，如 natbib 的 "Smith et~al.(2020)"、alpha 样式的 "SJL20"
+	Text    string   // 条目全文
+	Title   string   // 标题
+	Authors []string // 作者
+	Year    string   // 年份，可能带 a/b 后缀
+	Short   string   // 作者-年份引用中的作者简称，如 "Smith et al."
+	ArxivId string
+}
+
+// parseBibliography 解析正文中的 thebibliography 环境，没有时解析与主文件同名的 .bbl
+func (p *parser) parseBibliography(main string, body string) []*BibItem {
+	if i := strings.Index(body, `\begin{thebibliography}`); i >= 0 {
+		start := i + len(`\begin{thebibliography}`)
+		end, _ := findEnd(body, start, "thebibliography")
+		return p.parseBibitems(body[start:end])
+	}
+	bbl := p.src.bblFile(main)
+	if bbl == "" {
+		return nil
+	}
+	content := StripComments(string(p.src.Files[bbl]))
+	if strings.Contains(content, `\entry{`) {
+		return p.parseBiblatex(content)
+	}
+	if i := strings.Index(content, `\begin{thebibliography}`); i >= 0 {
+		start := i + len(`\begin{thebibliography}`)
+		end, _ := findEnd(content, start, "thebibliography")
+		content = content[start:end]
+	}
+	return p.parseBibitems(content)
+}
+
+// bblFile 与主文件同名的 .bbl，没有时取任意一个 .bbl
+func (s *Source) bblFile(main string) string {
+	name := strings.TrimSuffix(main, path.Ext(main)) + ".bbl"
+	if _, ok := s.Files[name]; ok {
+		return name
+	}
+	var found string
+	for file := range s.Files {
+		if strings.EqualFold(path.Ext(file), ".bbl") && (found == "" || file < found) {
+			found = file
+		}
+	}
+	return found
+}
+
+// parseBibitems 按 \bibitem[label]{key} 切分条目
+func (p *parser) parseBibitems(s string) []*BibItem {
+	var items []*BibItem
+	parts := strings.Split(s, `\bibitem`)
+	for _, part := range parts[1:] {
+		item := &BibItem{}
+		i := 0
+		if label, next, ok := readOptional(part, i); ok {
+			item.Label, _ = p.convert(label)
+			i = next
+		}
+		key, next, ok := readGroup(part, i)
+		if !ok {
+			continue
+		}
+		item.Key = strings.TrimSpace(key)
+		segments := strings.Split(part[next:], `\newblock`)
+		texts := make([]string, 0, len(segments))
+		for _, seg := range segments {
+			if text, _ := p.convert(seg); text != "" {
+				texts = append(texts, text)
+			}
+		}
+		item.Text = strings.Join(texts, " ")
+		if len(texts) >= 2 {
+			item.Authors = splitBibAuthors(texts[0])
+			item.Title = strings.TrimRight(texts[1], " .,")
+		} else if m := bibQuotedRe.FindStringSubmatchIndex(item.Text); m != nil {
+			item.Title = item.Text[m[2]:m[3]]
+			item.Authors = splitBibAuthors(item.Text[:m[0]])
+		}
+		if m := natbibLabelRe.FindStringSubmatch(item.Label); m != nil {
+			item.Short, item.Year = strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+		}
+		if item.Year == "" {
+			item.Year = bibYearRe.FindString(item.Text)
+		}
+		if m := bibArxivRe.FindStringSubmatch(item.Text); m != nil {
+			item.ArxivId = m[1]
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// parseBiblatex 解析 biblatex 生成的 .bbl，条目以 \entry{key}{type}{} ... \endentry 表示
+func (p *parser) parseBiblatex(s string) []*BibItem {
+	var items []*BibItem
+	for _, part := range strings.Split(s, `\entry{`)[1:] {
+		end := strings.IndexByte(part, '}')
+		if end < 0 {
+			continue
+		}
+		if e := strings.Index(part, `\endentry`); e >= 0 {
+			part = part[:e]
+		}
+		item := &BibItem{Key: strings.TrimSpace(part[:end])}
+		fields := make(map[string]string)
+		for _, loc := range blxFieldRe.FindAllStringSubmatchIndex(part, -1) {
+			if value, _, ok := readGroup(part, loc[1]-1); ok {
+				fields[part[loc[2]:loc[3]]], _ = p.convert(value)
+			}
+		}
+		if i := strings.Index(part, `\name{author}`); i >= 0 {
+			names := part[i:]
+			if k := strings.Index(names[1:], `\name{`); k >= 0 {
+				names = names[:k+1]
+			}
+			if k := strings.Index(names, `\list{`); k >= 0 {
+				names = names[:k]
+			}
+			for _, person := range strings.Split(names, "{{hash=")[1:] {
+				family := blxFamilyRe.FindStringSubmatch(person)
+				if family == nil {
+					continue
+				}
+				name, _ := p.convert(family[1])
+				if given := blxGivenRe.FindStringSubmatch(person); given != nil {
+					g, _ := p.convert(given[1])
+					name = g + " " + name
+				}
+				item.Authors = append(item.Authors, name)
+			}
+		}
+		item.Title = fields["title"]
+		item.Year = fields["year"]
+		if item.Year == "" && len(fields["date"]) >= 4 {
+			item.Year = fields["date"][:4]
+		}
+		if m := blxEprintRe.FindStringSubmatch(part); m != nil && strings.EqualFold(fields["eprinttype"], "arxiv") {
+			item.ArxivId = m[1]
+		}
+		item.Short = shortAuthors(item.Authors)
+		venue := fields["journaltitle"]
+		if venue == "" {
+			venue = fields["booktitle"]
+		}
+		var parts []string
+		for _, s := range []string{strings.Join(item.Authors, ", "), item.Title, venue, item.Year} {
+			if s != "" {
+				parts = append(parts, strings.TrimRight(s, "."))
+			}
+		}
+		item.Text = strings.Join(parts, ". ")
+		if item.Text != "" {
+			item.Text += "."
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// shortAuthors 作者-年份引用中的作者简称
+func shortAuthors(authors []string) string {
+	surname := func(name string) string {
+		fields := strings.Fields(name)
+		if len(fields) == 0 {
+			return ""
+		}
+		return fields[len(fields)-1]
+	}
+	switch len(authors) {
+	case 0:
+		return ""
+	case 1:
+		return surname(authors[0])
+	case 2:
+		return surname(authors[0]) + " and " + surname(authors[1])
+	default:
+		return surname(authors[0]) + " et al."
+	}
+}
+
+// splitBibAuthors 拆分条目中的作者列表，"Smith, J." 形式的缩写并入姓氏
+func splitBibAuthors(s string) []string {
+	s = strings.Trim(strings.TrimSpace(bibYearRe.ReplaceAllString(s, "")), " ,.()")
+	var names []string
+	for _, part := range authorNameRe.Split(s, -1) {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.EqualFold(strings.TrimSuffix(part, "."), "et al") {
+			continue
+		}
+		if bibInitialsRe.MatchString(part) && len(names) > 0 && !strings.Contains(names[len(names)-1], " ") {
+			names[len(names)-1] = part + " " + names[len(names)-1]
+			continue
+		}
+		names = append(names, strings.TrimSuffix(part, " et al."))
+	}
+	return names
+}
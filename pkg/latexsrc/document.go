@@ -0,0 +1,195 @@
+package latexsrc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+)
+
+// paragraphRef 块对应的解析结果对象，Align 据此回填坐标
+type paragraphRef struct {
+	paragraph *pb.Paragraph
+	item      *pb.CatalogueItem
+}
+
+// RefIdx 参考文献下标对应的 RefIdx，与其他解析器一致为 "b0"、"b1"…
+func RefIdx(i int) string {
+	return "b" + strconv.Itoa(i)
+}
+
+// SectionTitle 标题块在目录和段落中使用的标题，带编号时为 "2.1 Method"
+func (b *Block) SectionTitle() string {
+	if b == nil {
+		return ""
+	}
+	if b.Number == "" {
+		return b.Text
+	}
+	return b.Number + " " + b.Text
+}
+
+// RefIdx 图表在解析结果中的 RefIdx，如 "Figure 3"、"Table 1"
+func (b *Block) RefIdx() string {
+	if b.Number == "" {
+		return ""
+	}
+	if b.Kind == BlockTable {
+		return "Table " + b.Number
+	}
+	return "Figure " + b.Number
+}
+
+// ToDocument 转换为解析结果的元数据和全文段落，坐标为空，由 Align 回填
+func (p *Paper) ToDocument() (*pb.DocumentMetadata, *pb.FullDocument) {
+	metadata := &pb.DocumentMetadata{}
+	if p.Title != "" {
+		metadata.Title = &pb.Title{Text: p.Title}
+	}
+	for _, name := range p.Authors {
+		fields := strings.Fields(name)
+		author := &pb.Author{FullName: name, Surname: fields[len(fields)-1]}
+		if len(fields) > 1 {
+			author.GivenName = strings.Join(fields[:len(fields)-1], " ")
+		}
+		metadata.Authors = append(metadata.Authors, author)
+	}
+	if p.Abstract != "" {
+		metadata.Abstract = &pb.Abstract{Text: p.Abstract}
+	}
+	metadata.References = p.references()
+
+	p.paragraphs = make(map[*Block]*paragraphRef)
+	var paragraphs []*pb.Paragraph
+	var headings []*Block
+	figureCount := map[string]int{}
+	for _, b := range p.Blocks {
+		section := b.Section.SectionTitle()
+		paragraph := &pb.Paragraph{SectionTitle: section, SectionId: section, Order: int32(len(paragraphs) + 1)}
+		switch b.Kind {
+		case BlockHeading:
+			headings = append(headings, b)
+			continue
+		case BlockText:
+			paragraph.Type = pb.ParagraphType_TEXT
+			paragraph.Text = &pb.Text{Text: b.Text}
+			paragraph.References = p.refInfos(b)
+		case BlockEquation:
+			formula := &pb.Formula{
+				RefContent:   b.Latex,
+				RefIdx:       equationRefIdx(b.Numbers),
+				Id:           fmt.Sprintf("formula-%d", len(metadata.Formulas)+1),
+				SectionId:    section,
+				SectionTitle: section,
+			}
+			paragraph.Type = pb.ParagraphType_FORMULA
+			paragraph.Formula = formula
+			metadata.Formulas = append(metadata.Formulas, formula)
+		case BlockFigure, BlockTable:
+			figureType, paragraphType := "figure", pb.ParagraphType_IMAGE
+			if b.Kind == BlockTable {
+				figureType, paragraphType = "table", pb.ParagraphType_TABLE
+			}
+			figureCount[figureType]++
+			caption := b.Text
+			if ref := b.RefIdx(); ref != "" {
+				caption = ref + ": " + caption
+			}
+			figureTable := &pb.FigureTable{
+				RefContent:   caption,
+				RefIdx:       b.RefIdx(),
+				Type:         figureType,
+				Id:           fmt.Sprintf("%s-%d", figureType, figureCount[figureType]),
+				SectionId:    section,
+				SectionTitle: section,
+			}
+			paragraph.Type = paragraphType
+			paragraph.FigureTable = figureTable
+			paragraph.References = p.refInfos(b)
+			metadata.FiguresAndTables = append(metadata.FiguresAndTables, figureTable)
+		}
+		p.paragraphs[b] = &paragraphRef{paragraph: paragraph}
+		paragraphs = append(paragraphs, paragraph)
+	}
+	metadata.Catalogue = p.catalogue(headings)
+	return metadata, &pb.FullDocument{Paragraphs: paragraphs}
+}
+
+// equationRefIdx 公式编号，如 "(3)"，对齐环境中多个编号时为 "(3)-(5)"
+func equationRefIdx(numbers []string) string {
+	switch len(numbers) {
+	case 0:
+		return ""
+	case 1:
+		return "(" + numbers[0] + ")"
+	default:
+		return "(" + numbers[0] + ")-(" + numbers[len(numbers)-1] + ")"
+	}
+}
+
+// refInfos 段落中的引用，每个引用键一条
+func (p *Paper) refInfos(b *Block) []*pb.RefInfo {
+	var refs []*pb.RefInfo
+	for _, c := range b.Citations {
+		for _, key := range c.Keys {
+			refs = append(refs, &pb.RefInfo{Text: c.Text, Target: RefIdx(p.keys[key])})
+		}
+	}
+	return refs
+}
+
+func (p *Paper) references() []*pb.Reference {
+	references := make([]*pb.Reference, 0, len(p.Bibliography))
+	for i, item := range p.Bibliography {
+		ref := &pb.Reference{
+			RefIdx:      RefIdx(i),
+			Title:       item.Title,
+			PublishDate: item.Year,
+			ContentText: item.Text,
+			ArxivId:     item.ArxivId,
+		}
+		for _, name := range item.Authors {
+			fields := strings.Fields(name)
+			if len(fields) == 0 {
+				continue
+			}
+			author := &pb.Author{FullName: name, Surname: fields[len(fields)-1]}
+			if len(fields) > 1 {
+				author.GivenName = strings.Join(fields[:len(fields)-1], " ")
+			}
+			ref.Authors = append(ref.Authors, author)
+		}
+		references = append(references, ref)
+	}
+	return references
+}
+
+// catalogue 按标题层级构建目录树
+func (p *Paper) catalogue(headings []*Block) []*pb.CatalogueItem {
+	roots := []*pb.CatalogueItem{}
+	var stack []*pb.CatalogueItem
+	var levels []int
+	for i, h := range headings {
+		item := &pb.CatalogueItem{
+			Title:          h.SectionTitle(),
+			FormattedTitle: h.SectionTitle(),
+			TitleOrder:     h.Number,
+			Level:          strconv.Itoa(h.Level),
+			Order:          int32(i + 1),
+			Child:          []*pb.CatalogueItem{},
+		}
+		p.paragraphs[h] = &paragraphRef{item: item}
+		for len(levels) > 0 && levels[len(levels)-1] >= h.Level {
+			stack, levels = stack[:len(stack)-1], levels[:len(levels)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, item)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Child = append(parent.Child, item)
+		}
+		stack, levels = append(stack, item), append(levels, h.Level)
+	}
+	return roots
+}
@@ -0,0 +1,431 @@
+package latexsrc
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yb2020/odoc/pkg/pdftext"
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+)
+
+const mainTex = `\documentclass{article}
+\usepackage{amsmath}
+\newcommand{\R}{\mathbb{R}}
+\newcommand{\norm}[1]{\left\| #1 \right\|}
+\newcommand{\method}{SparseNet}
+\title{Sparse Networks for Fast Inference}
+\author{Alice Zhang$^1$ \and Bob Li$^{2}$\thanks{Corresponding author.} \\ University}
+\begin{document}
+\maketitle
+\begin{abstract}
+We propose \method{}, a sparse network. % 注释会被去掉
+\end{abstract}
+\input{sections/intro}
+\section{Method}\label{sec:method}
+Given $x \in \R^n$ we minimise the loss in Eq.~\eqref{eq:loss}:
+\begin{equation}
+  L(x) = \norm{x}^2 \label{eq:loss}
+\end{equation}
+The update rules follow directly from \cite{smith2020,lee2019}.
+\begin{align}
+  a &= b + c \\
+  d &= e \nonumber \\
+  f &= g \label{eq:last}
+\end{align}
+\begin{figure}[t]
+  \centering
+  \includegraphics[width=\linewidth]{figs/arch.pdf}
+  \caption{Overview of the \method{} architecture.}
+  \label{fig:arch}
+\end{figure}
+As shown in Figure~\ref{fig:arch} and Section~\ref{sec:method}, it works.
+\subsection*{Details}
+Unnumbered subsection text with~\cite{missing} citation.
+\appendix
+\section{Proofs}
+Proof text in the appendix.
+\bibliographystyle{plain}
+\bibliography{refs}
+\end{document}
+`
+
+const introTex = `\section{Introduction}
+Deep networks are slow~\cite{smith2020}.
+
+Second paragraph of the introduction.
+`
+
+const refsBbl = `\begin{thebibliography}{2}
+\bibitem{smith2020}
+J.~Smith and K.~Jones.
+\newblock Fast networks.
+\newblock In \emph{Proc. ICML}, 2020.
+\newblock arXiv:2001.01234.
+
+\bibitem{lee2019}
+M.~Lee.
+\newblock Sparse training.
+\newblock 2019.
+\end{thebibliography}
+`
+
+func testSource() *Source {
+	return &Source{Files: map[string][]byte{
+		"paper.tex":          []byte(mainTex),
+		"sections/intro.tex": []byte(introTex),
+		"paper.bbl":          []byte(refsBbl),
+		"figs/arch.pdf":      []byte("%PDF-1.4"),
+	}}
+}
+
+func mustParse(t *testing.T, src *Source) *Paper {
+	t.Helper()
+	paper, err := Parse(src)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	return paper
+}
+
+func blocksOf(paper *Paper, kind BlockKind) []*Block {
+	var result []*Block
+	for _, b := range paper.Blocks {
+		if b.Kind == kind {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	src, err := Extract(tarGz(t, map[string]string{"./paper.tex": mainTex, "../evil.tex": "x", "sections/intro.tex": introTex}))
+	if err != nil {
+		t.Fatalf("解压失败: %v", err)
+	}
+	if _, ok := src.Files["paper.tex"]; !ok {
+		t.Errorf("路径应去掉前导 ./，实际 %v", src.TexFiles())
+	}
+	if _, ok := src.Files["../evil.tex"]; ok {
+		t.Error("跳出根目录的路径应被忽略")
+	}
+	if main, err := src.MainFile(); err != nil || main != "paper.tex" {
+		t.Errorf("主文件应为 paper.tex，实际 %q %v", main, err)
+	}
+
+	// 单个 gzip 压缩的 tex 文件
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(mainTex))
+	zw.Close()
+	if src, err := Extract(buf.Bytes()); err != nil || len(src.Files) != 1 {
+		t.Errorf("单文件 e-print 解压失败: %v", err)
+	}
+
+	if _, err := Extract([]byte("%PDF-1.5 ...")); !errors.Is(err, ErrNoSource) {
+		t.Errorf("只有PDF时应返回 ErrNoSource，实际 %v", err)
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	cases := map[string]string{
+		"a % comment\nb":                       "a b",
+		`50\% off`:                             `50\% off`,
+		"a %\n\nb":                             "a \n\nb",
+		`x\iffalse hidden \fi y`:               `x y`,
+		`x\iffalse \ifx a \fi b\fi y`:          `x y`,
+		"p\\begin{comment}drop\\end{comment}q": "pq",
+	}
+	for in, want := range cases {
+		if got := StripComments(in); got != want {
+			t.Errorf("StripComments(%q) = %q，期望 %q", in, got, want)
+		}
+	}
+}
+
+func TestParseStructure(t *testing.T) {
+	paper := mustParse(t, testSource())
+
+	if paper.Title != "Sparse Networks for Fast Inference" {
+		t.Errorf("标题错误: %q", paper.Title)
+	}
+	if strings.Join(paper.Authors, "|") != "Alice Zhang|Bob Li" {
+		t.Errorf("作者错误: %q", paper.Authors)
+	}
+	if paper.Abstract != "We propose SparseNet, a sparse network." {
+		t.Errorf("摘要错误: %q", paper.Abstract)
+	}
+
+	var headings []string
+	for _, h := range blocksOf(paper, BlockHeading) {
+		headings = append(headings, h.SectionTitle())
+	}
+	want := "1 Introduction|2 Method|Details|A Proofs"
+	if strings.Join(headings, "|") != want {
+		t.Errorf("章节应为 %q，实际 %q", want, strings.Join(headings, "|"))
+	}
+
+	texts := blocksOf(paper, BlockText)
+	if texts[0].Text != "Deep networks are slow [1]." || texts[1].Text != "Second paragraph of the introduction." {
+		t.Errorf("引言段落错误: %q / %q", texts[0].Text, texts[1].Text)
+	}
+	if texts[0].Section == nil || texts[0].Section.Text != "Introduction" {
+		t.Error("段落应归属到 Introduction 章节")
+	}
+	if got := texts[2].Text; got != `Given $x \in \mathbb{R}^n$ we minimise the loss in Eq. (1):` {
+		t.Errorf("宏展开或公式引用错误: %q", got)
+	}
+	found := false
+	for _, b := range texts {
+		if b.Text == "As shown in Figure 1 and Section 2, it works." {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("图和章节的交叉引用应替换为编号")
+	}
+}
+
+func TestParseEquations(t *testing.T) {
+	paper := mustParse(t, testSource())
+	equations := blocksOf(paper, BlockEquation)
+	if len(equations) != 2 {
+		t.Fatalf("应有2个行间公式，实际 %d", len(equations))
+	}
+	if equations[0].Latex != `L(x) = \left\| x \right\|^2` || strings.Join(equations[0].Numbers, ",") != "1" {
+		t.Errorf("equation 环境解析错误: %q %v", equations[0].Latex, equations[0].Numbers)
+	}
+	align := equations[1]
+	if strings.Join(align.Numbers, ",") != "2,3" {
+		t.Errorf("\\nonumber 的行不应编号，实际 %v", align.Numbers)
+	}
+	if align.Latex != `\begin{aligned}a &= b + c \\ d &= e \\ f &= g\end{aligned}` {
+		t.Errorf("align 应转换为 aligned: %q", align.Latex)
+	}
+}
+
+func TestParseCitationsAndFigures(t *testing.T) {
+	paper := mustParse(t, testSource())
+	if len(paper.Bibliography) != 2 {
+		t.Fatalf("应有2条参考文献，实际 %d", len(paper.Bibliography))
+	}
+	smith := paper.Bibliography[0]
+	if smith.Title != "Fast networks" || smith.Year != "2020" || smith.ArxivId != "2001.01234" {
+		t.Errorf("参考文献解析错误: %+v", smith)
+	}
+	if strings.Join(smith.Authors, "|") != "J. Smith|K. Jones" {
+		t.Errorf("参考文献作者错误: %q", smith.Authors)
+	}
+
+	var cited *Block
+	for _, b := range blocksOf(paper, BlockText) {
+		if strings.Contains(b.Text, "update rules") {
+			cited = b
+		}
+	}
+	if cited == nil || cited.Text != "The update rules follow directly from [1, 2]." {
+		t.Fatalf("多键引用排版错误: %+v", cited)
+	}
+	if len(cited.Citations) != 1 || strings.Join(cited.Citations[0].Keys, ",") != "smith2020,lee2019" {
+		t.Errorf("引用记录错误: %+v", cited.Citations)
+	}
+
+	figures := blocksOf(paper, BlockFigure)
+	if len(figures) != 1 {
+		t.Fatalf("应有1个图，实际 %d", len(figures))
+	}
+	fig := figures[0]
+	if fig.Number != "1" || fig.Text != "Overview of the SparseNet architecture." || strings.Join(fig.Files, ",") != "figs/arch.pdf" {
+		t.Errorf("图解析错误: %+v", fig)
+	}
+}
+
+func TestAuthorYearCitations(t *testing.T) {
+	src := &Source{Files: map[string][]byte{
+		"main.tex": []byte(`\documentclass{article}
+\usepackage{natbib}
+\begin{document}
+\section{Related}
+Prior work \citep{smith2020} and \citet{lee2019} studied this.
+\bibliography{main}
+\end{document}`),
+		"main.bbl": []byte(`\begin{thebibliography}{2}
+\providecommand{\natexlab}[1]{#1}
+\bibitem[{Smith et~al.(2020{\natexlab{a}})Smith, Jones, and Wu}]{smith2020}
+John Smith, Kate Jones, and Wei Wu.
+\newblock Fast networks.
+\newblock 2020{\natexlab{a}}.
+
+\bibitem[{Lee(2019)}]{lee2019}
+Min Lee.
+\newblock Sparse training.
+\newblock 2019.
+\end{thebibliography}`),
+	}}
+	paper := mustParse(t, src)
+	if !paper.AuthorYear {
+		t.Fatal("natbib 带作者-年份标签时应识别为作者-年份样式")
+	}
+	text := blocksOf(paper, BlockText)[0].Text
+	if text != "Prior work (Smith et al., 2020a) and Lee (2019) studied this." {
+		t.Errorf("作者-年份引用排版错误: %q", text)
+	}
+}
+
+func TestBiblatex(t *testing.T) {
+	p := &parser{src: &Source{}, macros: map[string]macro{}, bib: map[string]int{}}
+	items := p.parseBiblatex(`\refsection{0}
+\entry{vaswani2017}{inproceedings}{}
+  \name{author}{2}{}{%
+    {{hash=1}{family={Vaswani},familyi={V\bibinitperiod},given={Ashish},giveni={A\bibinitperiod}}}%
+    {{hash=2}{family={Shazeer},familyi={S\bibinitperiod},given={Noam},giveni={N\bibinitperiod}}}%
+  }
+  \field{title}{Attention is all you need}
+  \field{booktitle}{NeurIPS}
+  \field{year}{2017}
+  \verb{eprint}
+  \verb 1706.03762
+  \endverb
+  \field{eprinttype}{arXiv}
+\endentry`)
+	if len(items) != 1 {
+		t.Fatalf("应解析出1条 biblatex 条目，实际 %d", len(items))
+	}
+	item := items[0]
+	if item.Key != "vaswani2017" || item.Title != "Attention is all you need" || item.Year != "2017" || item.ArxivId != "1706.03762" {
+		t.Errorf("biblatex 条目解析错误: %+v", item)
+	}
+	if strings.Join(item.Authors, "|") != "Ashish Vaswani|Noam Shazeer" || item.Short != "Vaswani and Shazeer" {
+		t.Errorf("biblatex 作者解析错误: %q %q", item.Authors, item.Short)
+	}
+}
+
+func TestToDocument(t *testing.T) {
+	paper := mustParse(t, testSource())
+	metadata, full := paper.ToDocument()
+
+	if len(metadata.References) != 2 || metadata.References[0].RefIdx != "b0" || metadata.References[0].ArxivId != "2001.01234" {
+		t.Errorf("参考文献转换错误: %v", metadata.References)
+	}
+	if len(metadata.Catalogue) != 3 || metadata.Catalogue[1].Title != "2 Method" || len(metadata.Catalogue[1].Child) != 1 {
+		t.Errorf("目录树错误: %v", metadata.Catalogue)
+	}
+	if len(metadata.Formulas) != 2 || metadata.Formulas[0].RefIdx != "(1)" || metadata.Formulas[1].RefIdx != "(2)-(3)" {
+		t.Errorf("公式转换错误: %v", metadata.Formulas)
+	}
+	if metadata.Formulas[0].SectionTitle != "2 Method" {
+		t.Errorf("公式所属章节错误: %q", metadata.Formulas[0].SectionTitle)
+	}
+	if len(metadata.FiguresAndTables) != 1 || metadata.FiguresAndTables[0].RefIdx != "Figure 1" ||
+		metadata.FiguresAndTables[0].RefContent != "Figure 1: Overview of the SparseNet architecture." {
+		t.Errorf("图表转换错误: %v", metadata.FiguresAndTables)
+	}
+	var refs []*pb.RefInfo
+	for _, p := range full.Paragraphs {
+		refs = append(refs, p.References...)
+	}
+	if len(refs) != 3 || refs[0].Target != "b0" || refs[0].Text != "[1]" || refs[2].Target != "b1" {
+		t.Errorf("段落引用错误: %v", refs)
+	}
+}
+
+// textLine 构造一行PDF文本，按字符数估算宽度
+func textLine(text string, y float64) pdftext.Line {
+	return pdftext.Line{Text: text, Rect: pdftext.Rect{X0: 72, Y0: y, X1: 72 + float64(len(text))*5, Y1: y + 10}}
+}
+
+func TestAlign(t *testing.T) {
+	paper := mustParse(t, testSource())
+	metadata, full := paper.ToDocument()
+
+	page1 := &pdftext.Page{Number: 1, Width: 612, Height: 792, Lines: []pdftext.Line{
+		textLine("Sparse Networks for Fast Inference", 60),
+		textLine("Alice Zhang Bob Li", 80),
+		textLine("We propose SparseNet, a sparse network.", 110),
+		textLine("1 Introduction", 140),
+		textLine("Deep networks are slow [1].", 160),
+		textLine("Second paragraph of the intro-", 180),
+		textLine("duction.", 192),
+		textLine("2 Method", 220),
+		textLine("Given x ∈ Rn we minimise the loss in Eq. (1):", 240),
+		textLine("L(x) = ||x||2 (1)", 260),
+		textLine("The update rules follow directly from [1, 2].", 290),
+		textLine("a = b + c (2)", 310),
+		textLine("d = e", 322),
+		textLine("f = g (3)", 334),
+	}}
+	page2 := &pdftext.Page{Number: 2, Width: 612, Height: 792, Lines: []pdftext.Line{
+		textLine("encoder decoder", 80),
+		textLine("Figure 1: Overview of the SparseNet architecture.", 300),
+		textLine("As shown in Figure 1 and Section 2, it works.", 330),
+	}}
+	stats := paper.Align(metadata, []*pdftext.Page{page1, page2})
+	if stats.Aligned == 0 {
+		t.Fatal("没有对齐任何块")
+	}
+	if metadata.Title.Bbox == nil || metadata.Title.Bbox.Y0 != 60 {
+		t.Errorf("标题坐标错误: %v", metadata.Title.Bbox)
+	}
+	if item := metadata.Catalogue[1]; item.Bbox == nil || item.Bbox.Y0 != 220 {
+		t.Errorf("章节标题坐标错误: %v", item.Bbox)
+	}
+	second := full.Paragraphs[1].Text
+	if second.Bbox == nil || second.Bbox.Y0 != 180 || second.Bbox.Y1 != 202 {
+		t.Errorf("跨行段落坐标错误（应合并连字符断行）: %v", second.Bbox)
+	}
+	if f := metadata.Formulas[0].Bbox; f == nil || f.Y0 != 260 || f.Y1 != 270 {
+		t.Errorf("公式(1)坐标错误: %v", f)
+	}
+	if f := metadata.Formulas[1].Bbox; f == nil || f.Y0 != 310 || f.Y1 != 344 {
+		t.Errorf("对齐环境公式坐标应覆盖三行: %v", f)
+	}
+	figure := metadata.FiguresAndTables[0]
+	if figure.RefBbox == nil || figure.RefBbox.PageNumber != 2 || figure.Bbox == nil || figure.Bbox.Y1 != 300 {
+		t.Errorf("图的标题或区域坐标错误: %v / %v", figure.RefBbox, figure.Bbox)
+	}
+
+	targets := map[string]int{}
+	for _, m := range metadata.ReferenceMarkers {
+		targets[m.RefIdx]++
+		if m.Bbox == nil {
+			t.Errorf("引用标记缺少坐标: %v", m)
+		}
+	}
+	if targets["b0"] != 2 || targets["b1"] != 1 {
+		t.Errorf("引用标记错误: %v", targets)
+	}
+	if full.Paragraphs[0].References[0].Bbox == nil {
+		t.Error("段落引用应回填坐标")
+	}
+	if len(metadata.FigureAndTableMarkers) != 1 || metadata.FigureAndTableMarkers[0].RefIdx != "Figure 1" {
+		t.Errorf("图表引用标记错误: %v", metadata.FigureAndTableMarkers)
+	}
+	if len(metadata.Pages) != 2 {
+		t.Errorf("页面信息错误: %v", metadata.Pages)
+	}
+}
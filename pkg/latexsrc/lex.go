@@ -0,0 +1,389 @@
+package latexsrc
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 宏展开的嵌套深度和展开后文本的长度上限，防止递归定义的宏无限展开
+const (
+	maxMacroDepth  = 8
+	maxExpandedLen = 8 << 20
+)
+
+// macro 用户通过 \newcommand、\def、\DeclareMathOperator 定义的宏
+type macro struct {
+	nargs      int
+	optDefault *string // 第一个参数为可选参数时的默认值
+	body       string
+}
+
+// readCommand 读取 s[i] 处反斜杠开始的命令名，返回命令名和命令之后的位置
+func readCommand(s string, i int) (string, int) {
+	j := i + 1
+	if j >= len(s) {
+		return "", j
+	}
+	if !isLetter(s[j]) {
+		return s[j : j+1], j + 1
+	}
+	for j < len(s) && isLetter(s[j]) {
+		j++
+	}
+	return s[i+1 : j], j
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '@'
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// matchBrace 返回与 s[i] 处左括号匹配的右括号位置，跳过转义的括号；不匹配时返回 -1
+func matchBrace(s string, i int, open, close byte) int {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// readGroup 读取必选参数：花括号内的内容，或单个命令、单个字符
+func readGroup(s string, i int) (string, int, bool) {
+	i = skipSpace(s, i)
+	if i >= len(s) {
+		return "", i, false
+	}
+	switch s[i] {
+	case '{':
+		end := matchBrace(s, i, '{', '}')
+		if end < 0 {
+			return s[i+1:], len(s), true
+		}
+		return s[i+1 : end], end + 1, true
+	case '\\':
+		_, next := readCommand(s, i)
+		return s[i:next], next, true
+	case '}', '[', ']':
+		return "", i, false
+	default:
+		return s[i : i+1], i + 1, true
+	}
+}
+
+// readOptional 读取方括号可选参数，方括号内的花括号组不参与匹配
+func readOptional(s string, i int) (string, int, bool) {
+	j := skipSpace(s, i)
+	if j >= len(s) || s[j] != '[' {
+		return "", i, false
+	}
+	depth := 0
+	for k := j + 1; k < len(s); k++ {
+		switch s[k] {
+		case '\\':
+			k++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ']':
+			if depth == 0 {
+				return s[j+1 : k], k + 1, true
+			}
+		}
+	}
+	return "", i, false
+}
+
+// skipArgs 跳过命令的可选参数和 n 个必选参数
+func skipArgs(s string, i int, n int) int {
+	for {
+		_, next, ok := readOptional(s, i)
+		if !ok {
+			break
+		}
+		i = next
+	}
+	for k := 0; k < n; k++ {
+		_, next, ok := readGroup(s, i)
+		if !ok {
+			break
+		}
+		i = next
+	}
+	return i
+}
+
+// findEnd 在 s[i:] 中查找与已读取的 \begin{env} 匹配的 \end{env}，返回环境内容的结束位置和 \end{env} 之后的位置
+func findEnd(s string, i int, env string) (int, int) {
+	begin, end := `\begin{`+env+`}`, `\end{`+env+`}`
+	depth := 1
+	for j := i; j < len(s); {
+		b := strings.Index(s[j:], begin)
+		e := strings.Index(s[j:], end)
+		if e < 0 {
+			return len(s), len(s)
+		}
+		if b >= 0 && b < e {
+			depth++
+			j += b + len(begin)
+			continue
+		}
+		depth--
+		if depth == 0 {
+			return j + e, j + e + len(end)
+		}
+		j += e + len(end)
+	}
+	return len(s), len(s)
+}
+
+// splitTopLevel 按顶层（不在花括号和环境中）的分隔符切分，用于切分对齐环境的行
+func splitTopLevel(s string, sep string) []string {
+	var parts []string
+	depth, envDepth, last := 0, 0, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '{':
+			depth++
+		case s[i] == '}':
+			depth--
+		case strings.HasPrefix(s[i:], `\begin{`):
+			envDepth++
+			i += len(`\begin`)
+		case strings.HasPrefix(s[i:], `\end{`):
+			envDepth--
+			i += len(`\end`)
+		case depth == 0 && envDepth == 0 && strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[last:i])
+			i += len(sep) - 1
+			last = i + 1
+		case s[i] == '\\':
+			i++
+		}
+	}
+	return append(parts, s[last:])
+}
+
+var (
+	newCommandRe = regexp.MustCompile(`^\\(?:re)?(?:new|provide)command\*?$`)
+	defParamRe   = regexp.MustCompile(`^(#\d)*$`)
+)
+
+// collectMacros 收集文本中的宏定义，返回去掉定义后的文本
+func (p *parser) collectMacros(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			i++
+			continue
+		}
+		name, j := readCommand(s, i)
+		if j < len(s) && s[j] == '*' && (name == "newcommand" || name == "renewcommand" || name == "providecommand" || name == "DeclareMathOperator") {
+			name += "*"
+			j++
+		}
+		var next int
+		switch {
+		case newCommandRe.MatchString(`\` + name):
+			next = p.defineNewCommand(s, j, name == "providecommand" || name == "providecommand*")
+		case name == "def" || name == "gdef" || name == "edef":
+			next = p.defineDef(s, j)
+		case name == "DeclareMathOperator" || name == "DeclareMathOperator*":
+			next = p.defineOperator(s, j, strings.HasSuffix(name, "*"))
+		}
+		if next > j {
+			i = next
+			continue
+		}
+		sb.WriteString(s[i:j])
+		i = j
+	}
+	return sb.String()
+}
+
+// defineNewCommand \newcommand{\name}[n][default]{body}
+func (p *parser) defineNewCommand(s string, i int, provide bool) int {
+	name, i, ok := readGroup(s, i)
+	if !ok {
+		return 0
+	}
+	name = strings.TrimSpace(name)
+	if !strings.HasPrefix(name, `\`) {
+		return 0
+	}
+	m := macro{}
+	if n, next, ok := readOptional(s, i); ok {
+		m.nargs, _ = strconv.Atoi(strings.TrimSpace(n))
+		i = next
+		if def, next, ok := readOptional(s, i); ok {
+			m.optDefault = &def
+			i = next
+		}
+	}
+	body, next, ok := readGroup(s, i)
+	if !ok {
+		return 0
+	}
+	m.body = body
+	if _, exists := p.macros[name[1:]]; !(provide && exists) {
+		p.defineMacro(name[1:], m)
+	}
+	return next
+}
+
+// defineDef \def\name#1#2{body}，只支持连续编号的简单参数
+func (p *parser) defineDef(s string, i int) int {
+	i = skipSpace(s, i)
+	if i >= len(s) || s[i] != '\\' {
+		return 0
+	}
+	name, j := readCommand(s, i)
+	k := strings.IndexByte(s[j:], '{')
+	if k < 0 || !defParamRe.MatchString(s[j:j+k]) {
+		return 0
+	}
+	body, next, ok := readGroup(s, j+k)
+	if !ok {
+		return 0
+	}
+	p.defineMacro(name, macro{nargs: k / 2, body: body})
+	return next
+}
+
+// defineOperator \DeclareMathOperator{\name}{text}
+func (p *parser) defineOperator(s string, i int, star bool) int {
+	name, i, ok := readGroup(s, i)
+	if !ok || !strings.HasPrefix(strings.TrimSpace(name), `\`) {
+		return 0
+	}
+	text, next, ok := readGroup(s, i)
+	if !ok {
+		return 0
+	}
+	op := `\operatorname`
+	if star {
+		op += "*"
+	}
+	p.defineMacro(strings.TrimSpace(name)[1:], macro{body: op + "{" + text + "}"})
+	return next
+}
+
+// structuralCommands 解析依赖的命令，重定义后只影响排版样式，不参与展开
+var structuralCommands = map[string]bool{
+	"section": true, "subsection": true, "subsubsection": true, "paragraph": true, "chapter": true,
+	"cite": true, "citep": true, "citet": true, "ref": true, "eqref": true, "label": true,
+	"caption": true, "begin": true, "end": true, "item": true, "title": true, "author": true,
+	"includegraphics": true, "bibitem": true, "footnote": true, "appendix": true,
+}
+
+func (p *parser) defineMacro(name string, m macro) {
+	if structuralCommands[name] {
+		return
+	}
+	// 包含宏定义的宏通常是模板的内部实现，展开后没有意义
+	if strings.Contains(m.body, `\def`) || strings.Contains(m.body, `\newcommand`) || strings.Contains(m.body, `\renewcommand`) {
+		return
+	}
+	p.macros[name] = m
+}
+
+// expandMacros 展开用户定义的宏
+func (p *parser) expandMacros(s string) string {
+	if len(p.macros) == 0 {
+		return s
+	}
+	for depth := 0; depth < maxMacroDepth; depth++ {
+		expanded, changed := p.expandOnce(s)
+		if !changed || len(expanded) > maxExpandedLen {
+			return s
+		}
+		s = expanded
+	}
+	return s
+}
+
+func (p *parser) expandOnce(s string) (string, bool) {
+	var sb strings.Builder
+	changed := false
+	for i := 0; i < len(s); {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			i++
+			continue
+		}
+		name, j := readCommand(s, i)
+		m, ok := p.macros[name]
+		if !ok {
+			sb.WriteString(s[i:j])
+			i = j
+			continue
+		}
+		args := make([]string, 0, m.nargs)
+		k := j
+		if m.optDefault != nil && m.nargs > 0 {
+			if opt, next, ok := readOptional(s, k); ok {
+				args, k = append(args, opt), next
+			} else {
+				args = append(args, *m.optDefault)
+			}
+		}
+		for len(args) < m.nargs {
+			arg, next, ok := readGroup(s, k)
+			if !ok {
+				break
+			}
+			args, k = append(args, arg), next
+		}
+		body := m.body
+		for n := len(args); n >= 1; n-- {
+			body = strings.ReplaceAll(body, "#"+strconv.Itoa(n), args[n-1])
+		}
+		// 无参数宏后的空格在 TeX 中被吞掉
+		if m.nargs == 0 {
+			k = skipInlineSpace(s, k)
+		}
+		// 宏体以命令结尾而后面紧跟字母时，补空格避免拼成另一个命令
+		if k < len(s) && isLetter(s[k]) && endsWithControlWord(body) {
+			body += " "
+		}
+		sb.WriteString(body)
+		changed = true
+		i = k
+	}
+	return sb.String(), changed
+}
+
+// skipInlineSpace 跳过同一行内的空白，不跨越段落分隔
+func skipInlineSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// endsWithControlWord 文本是否以 \name 形式的命令结尾
+func endsWithControlWord(s string) bool {
+	i := len(s)
+	for i > 0 && isLetter(s[i-1]) {
+		i--
+	}
+	return i < len(s) && i > 0 && s[i-1] == '\\'
+}
@@ -0,0 +1,697 @@
+package latexsrc
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BlockKind 正文块类型
+type BlockKind int
+
+const (
+	// BlockText 文本段落
+	BlockText BlockKind = iota
+	// BlockHeading 章节标题
+	BlockHeading
+	// BlockEquation 行间公式
+	BlockEquation
+	// BlockFigure 图
+	BlockFigure
+	// BlockTable 表
+	BlockTable
+)
+
+// Citation 正文中的一处 \cite
+type Citation struct {
+	Keys []string // 能在参考文献中找到的引用键
+	Text string   // 按引用样式排版后的文字，如 "[3, 5]"、"(Smith et al., 2020)"
+}
+
+// Block 正文中按阅读顺序排列的一个块
+type Block struct {
+	Kind      BlockKind
+	Text      string     // 段落、标题或图表标题的纯文本，行内公式保留为 $...$
+	Citations []Citation // 段落和图表标题中的引用
+	Level     int        // 标题层级，从1开始
+	Number    string     // 标题、图表的编号，如 "2.1"、"3"；不编号时为空
+	Latex     string     // 行间公式的 LaTeX，不含 $$ 和 \label
+	Numbers   []string   // 行间公式的编号，对齐环境中每个编号行一个
+	Files     []string   // 图表引用的图片文件
+	Section   *Block     // 所属章节标题，正文开头的块为空
+
+	raw string
+}
+
+// Paper 从 LaTeX 源码解析出的论文
+type Paper struct {
+	Title        string
+	Authors      []string
+	Abstract     string
+	Blocks       []*Block
+	Bibliography []*BibItem
+	AuthorYear   bool // 参考文献为作者-年份引用样式
+
+	keys map[string]int // 引用键 -> 参考文献下标
+	// ToDocument 生成的段落和目录项，Align 按块回填坐标
+	paragraphs map[*Block]*paragraphRef
+}
+
+// target \label 指向的对象
+type target struct {
+	kind   string // section/equation/figure/table/algorithm/theorem
+	name   string // 定理类环境的名称，如 Theorem
+	number string
+}
+
+// theorem \newtheorem 定义的定理类环境
+type theorem struct {
+	title   string
+	counter string
+	starred bool
+}
+
+// parser 解析状态
+type parser struct {
+	src        *Source
+	macros     map[string]macro
+	labels     map[string]target
+	theorems   map[string]theorem
+	bib        map[string]int // 引用键 -> 参考文献下标
+	items      []*BibItem
+	authorYear bool
+
+	blocks      []*Block
+	buf         strings.Builder
+	section     *Block
+	target      target
+	hasChapters bool
+	appendix    bool
+	sections    [5]int
+	counters    map[string]int
+}
+
+var (
+	labelRe       = regexp.MustCompile(`\\label\s*\{([^}]*)\}`)
+	tagRe         = regexp.MustCompile(`\\tag\*?\s*\{([^}]*)\}`)
+	noNumberRe    = regexp.MustCompile(`\\(nonumber|notag)\b`)
+	graphicsRe    = regexp.MustCompile(`\\includegraphics\*?\s*(?:\[[^\]]*\])?\s*\{([^}]+)\}`)
+	captionRe     = regexp.MustCompile(`\\caption(\*?)`)
+	newTheoremRe  = regexp.MustCompile(`\\newtheorem(\*?)\s*\{([^}]+)\}\s*(?:\[([^\]]+)\])?\s*\{([^}]*)\}\s*(?:\[([^\]]+)\])?`)
+	subfloatEnvRe = regexp.MustCompile(`\\begin\{(subfigure|subtable|minipage)\}`)
+	natbibRe      = regexp.MustCompile(`\\usepackage\s*(?:\[([^\]]*)\])?\s*\{[^}]*\bnatbib\b[^}]*\}`)
+	biblatexRe    = regexp.MustCompile(`\\usepackage\s*(?:\[([^\]]*)\])?\s*\{biblatex\}`)
+	citeStyleRe   = regexp.MustCompile(`\\setcitestyle\s*\{([^}]*)\}`)
+	authorSepRe   = regexp.MustCompile(`\\(and|AND|And)\b`)
+	authorNameRe  = regexp.MustCompile(`\s*(?:,\s*and\s+|\s+and\s+|,|;|&)\s*`)
+	authorMarkRe  = regexp.MustCompile(`[\d*†‡§¶♯♮⋆∗#,]+$`)
+)
+
+// 行间公式环境
+var mathEnvs = map[string]bool{
+	"equation": true, "align": true, "flalign": true, "alignat": true, "xalignat": true,
+	"gather": true, "multline": true, "eqnarray": true, "displaymath": true, "dmath": true,
+}
+
+// 内容不参与正文的环境
+var skipEnvs = map[string]bool{
+	"abstract": true, "thebibliography": true, "verbatim": true, "lstlisting": true, "minted": true,
+	"tikzpicture": true, "picture": true, "comment": true, "tabular": true, "tabularx": true,
+	"filecontents": true, "titlepage": true, "CCSXML": true,
+}
+
+// 开始时带有尺寸等参数的环境，参数不是正文
+var envArgs = map[string]int{
+	"minipage": 1, "multicols": 1, "wrapfigure": 2, "wraptable": 2, "adjustbox": 1, "subfigure": 1, "subtable": 1,
+}
+
+// Parse 解析 e-print 源码
+func Parse(src *Source) (*Paper, error) {
+	main, err := src.MainFile()
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{
+		src:      src,
+		macros:   make(map[string]macro),
+		labels:   make(map[string]target),
+		theorems: make(map[string]theorem),
+		bib:      make(map[string]int),
+		counters: make(map[string]int),
+	}
+	full := p.collectMacros(src.Expand(main))
+	full = p.expandMacros(full)
+	preamble := full
+	if i := strings.Index(full, `\begin{document}`); i >= 0 {
+		preamble = full[:i]
+	}
+	body := documentBody(full)
+
+	p.collectTheorems(full)
+	p.items = p.parseBibliography(main, body)
+	for i, item := range p.items {
+		if _, ok := p.bib[item.Key]; !ok {
+			p.bib[item.Key] = i
+		}
+	}
+	p.authorYear = citeAuthorYear(preamble, p.items)
+	p.hasChapters = strings.Contains(body, `\chapter`)
+
+	paper := &Paper{
+		Bibliography: p.items,
+		AuthorYear:   p.authorYear,
+		keys:         p.bib,
+	}
+	p.walk(body)
+	p.flush()
+
+	// 标签在全文遍历后才完整，引用和文本在最后统一转换
+	if title, ok := firstCommandArg(full, "title", "icmltitle"); ok {
+		paper.Title, _ = p.convert(title)
+	}
+	paper.Authors = p.authors(full)
+	if abstract, ok := p.abstract(body); ok {
+		paper.Abstract, _ = p.convert(abstract)
+	}
+	for _, b := range p.blocks {
+		if b.Kind == BlockEquation {
+			continue
+		}
+		b.Text, b.Citations = p.convert(b.raw)
+	}
+	for _, b := range p.blocks {
+		if b.Kind == BlockText && strings.TrimSpace(b.Text) == "" {
+			continue
+		}
+		paper.Blocks = append(paper.Blocks, b)
+	}
+	return paper, nil
+}
+
+// collectTheorems 收集 \newtheorem 定义的定理类环境
+func (p *parser) collectTheorems(s string) {
+	for _, m := range newTheoremRe.FindAllStringSubmatch(s, -1) {
+		counter := m[2]
+		if m[3] != "" {
+			counter = m[3]
+		}
+		p.theorems[m[2]] = theorem{title: strings.TrimSpace(m[4]), counter: counter, starred: m[1] == "*"}
+	}
+}
+
+// citeAuthorYear 判断引用样式是否为作者-年份
+func citeAuthorYear(preamble string, items []*BibItem) bool {
+	if m := biblatexRe.FindStringSubmatch(preamble); m != nil {
+		opts := strings.ToLower(m[1])
+		return strings.Contains(opts, "authoryear") || strings.Contains(opts, "apa") || strings.Contains(opts, "authortitle")
+	}
+	m := natbibRe.FindStringSubmatch(preamble)
+	if m == nil {
+		return false
+	}
+	opts := m[1]
+	if s := citeStyleRe.FindStringSubmatch(preamble); s != nil {
+		opts += "," + s[1]
+	}
+	if strings.Contains(opts, "numbers") || strings.Contains(opts, "super") {
+		return false
+	}
+	if strings.Contains(opts, "authoryear") {
+		return true
+	}
+	labelled := 0
+	for _, item := range items {
+		if item.Short != "" && item.Year != "" {
+			labelled++
+		}
+	}
+	return len(items) > 0 && labelled*2 > len(items)
+}
+
+// walk 按阅读顺序遍历正文，切分为标题、段落、公式和图表
+func (p *parser) walk(s string) {
+	for i := 0; i < len(s); {
+		switch c := s[i]; c {
+		case '\\':
+			i = p.command(s, i)
+		case '{':
+			end := matchBrace(s, i, '{', '}')
+			if end < 0 {
+				end = len(s) - 1
+			}
+			p.buf.WriteString(s[i : end+1])
+			i = end + 1
+		case '$':
+			i = p.dollar(s, i)
+		case '\n':
+			j := skipInlineSpace(s, i+1)
+			if j < len(s) && (s[j] == '\n' || s[j] == '\r') {
+				p.flush()
+				i = skipSpace(s, j)
+				continue
+			}
+			p.buf.WriteByte(' ')
+			i++
+		default:
+			p.buf.WriteByte(c)
+			i++
+		}
+	}
+}
+
+// dollar 处理 $...$ 行内公式和 $$...$$ 行间公式
+func (p *parser) dollar(s string, i int) int {
+	if strings.HasPrefix(s[i:], "$$") {
+		end := strings.Index(s[i+2:], "$$")
+		if end < 0 {
+			return len(s)
+		}
+		p.flush()
+		p.equation("displaymath", s[i+2:i+2+end])
+		return i + 2 + end + 2
+	}
+	end := closingDollar(s, i+1)
+	p.buf.WriteString(s[i : end+1])
+	return end + 1
+}
+
+// closingDollar 查找行内公式的结束 $，跳过转义的 \$
+func closingDollar(s string, i int) int {
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '$':
+			return j
+		}
+	}
+	return len(s) - 1
+}
+
+// command 处理正文中的命令，返回命令之后的位置
+func (p *parser) command(s string, i int) int {
+	name, j := readCommand(s, i)
+	switch name {
+	case "part", "chapter", "section", "subsection", "subsubsection":
+		return p.heading(s, j, name)
+	case "paragraph", "subparagraph":
+		// 段落标题是段首的粗体文字，不进入目录
+		p.flush()
+		j = skipStar(s, j)
+		_, j, _ = readOptional(s, j)
+		title, next, _ := readGroup(s, j)
+		p.buf.WriteString(title + " ")
+		return next
+	case "begin":
+		env, k, ok := readGroup(s, j)
+		if !ok {
+			return j
+		}
+		env = strings.TrimSpace(env)
+		end, next := findEnd(s, k, env)
+		p.environment(env, s[k:end])
+		return next
+	case "[":
+		end := strings.Index(s[j:], `\]`)
+		if end < 0 {
+			return len(s)
+		}
+		p.flush()
+		p.equation("displaymath", s[j:j+end])
+		return j + end + 2
+	case "item":
+		p.flush()
+		if label, next, ok := readOptional(s, j); ok {
+			p.buf.WriteString(label + " ")
+			return next
+		}
+		return j
+	case "par":
+		p.flush()
+		return j
+	case "appendix":
+		p.flush()
+		p.appendix = true
+		p.sections[1] = 0
+		return j
+	case "label":
+		key, next, ok := readGroup(s, j)
+		if ok {
+			p.labels[strings.TrimSpace(key)] = p.target
+		}
+		return next
+	case "bibliography", "printbibliography", "bibliographystyle":
+		p.flush()
+		if name == "printbibliography" {
+			return skipArgs(s, j, 0)
+		}
+		return skipArgs(s, j, 1)
+	}
+	p.buf.WriteString(s[i:j])
+	return j
+}
+
+func skipStar(s string, i int) int {
+	if i < len(s) && s[i] == '*' {
+		return i + 1
+	}
+	return i
+}
+
+// heading 章节标题，按层级编号；附录中的一级标题用字母编号
+func (p *parser) heading(s string, j int, name string) int {
+	star := j < len(s) && s[j] == '*'
+	j = skipStar(s, j)
+	_, j, _ = readOptional(s, j)
+	title, next, ok := readGroup(s, j)
+	if !ok {
+		return j
+	}
+	p.flush()
+	if name == "part" {
+		return next
+	}
+	level := map[string]int{"chapter": 1, "section": 1, "subsection": 2, "subsubsection": 3}[name]
+	if p.hasChapters && name != "chapter" {
+		level++
+	}
+	b := &Block{Kind: BlockHeading, Level: level, raw: title}
+	if !star {
+		p.sections[level]++
+		for k := level + 1; k < len(p.sections); k++ {
+			p.sections[k] = 0
+		}
+		parts := make([]string, 0, level)
+		for k := 1; k <= level; k++ {
+			n := strconv.Itoa(p.sections[k])
+			if k == 1 && p.appendix {
+				n = appendixNumber(p.sections[k])
+			}
+			parts = append(parts, n)
+		}
+		b.Number = strings.Join(parts, ".")
+		p.target = target{kind: "section", number: b.Number}
+	}
+	p.blocks = append(p.blocks, b)
+	p.section = b
+	return next
+}
+
+// appendixNumber 附录编号 A、B、…、Z、AA
+func appendixNumber(n int) string {
+	if n <= 0 {
+		return "0"
+	}
+	var sb []byte
+	for n > 0 {
+		n--
+		sb = append([]byte{byte('A' + n%26)}, sb...)
+		n /= 26
+	}
+	return string(sb)
+}
+
+// environment 处理 \begin{env}...\end{env}
+func (p *parser) environment(env string, content string) {
+	base := strings.TrimSuffix(env, "*")
+	if n, ok := envArgs[base]; ok {
+		content = content[skipArgs(content, 0, n):]
+	}
+	switch {
+	case mathEnvs[base]:
+		p.flush()
+		p.equation(env, content)
+	case base == "figure" || base == "wrapfigure" || base == "SCfigure":
+		p.flush()
+		p.float(BlockFigure, "figure", content)
+	case base == "table" || base == "wraptable":
+		p.flush()
+		p.float(BlockTable, "table", content)
+	case base == "algorithm":
+		p.flush()
+		p.float(BlockFigure, "algorithm", content)
+	case skipEnvs[base]:
+	case base == "proof":
+		p.flush()
+		p.buf.WriteString("Proof. ")
+		p.walk(content)
+		p.flush()
+	default:
+		p.flush()
+		if th, ok := p.theorems[env]; ok {
+			p.theoremHead(th, &content)
+			previous := p.target
+			p.walk(content)
+			p.flush()
+			p.target = previous
+			return
+		}
+		p.walk(content)
+		p.flush()
+	}
+}
+
+// theoremHead 写入定理类环境的标题，如 "Theorem 2 (Name)."，并设置 \label 的指向
+func (p *parser) theoremHead(th theorem, content *string) {
+	head := th.title
+	if !th.starred {
+		p.counters[th.counter]++
+		number := strconv.Itoa(p.counters[th.counter])
+		head += " " + number
+		p.target = target{kind: "theorem", name: th.title, number: number}
+	}
+	if name, next, ok := readOptional(*content, 0); ok {
+		head += " (" + name + ")"
+		*content = (*content)[next:]
+	}
+	p.buf.WriteString(head + ". ")
+}
+
+// equation 行间公式，对齐环境按行编号
+func (p *parser) equation(env string, content string) {
+	base := strings.TrimSuffix(env, "*")
+	numbered := env == base && base != "displaymath"
+	b := &Block{Kind: BlockEquation, Section: p.section}
+
+	var rows []string
+	switch base {
+	case "align", "flalign", "alignat", "xalignat", "gather", "eqnarray":
+		rows = splitTopLevel(content, `\\`)
+		if len(rows) > 1 && strings.TrimSpace(rows[len(rows)-1]) == "" {
+			rows = rows[:len(rows)-1]
+		}
+	default:
+		rows = []string{content}
+	}
+	cleaned := make([]string, 0, len(rows))
+	for _, row := range rows {
+		number := ""
+		if m := tagRe.FindStringSubmatch(row); m != nil {
+			number = strings.TrimSpace(m[1])
+		} else if numbered && !noNumberRe.MatchString(row) {
+			p.counters["equation"]++
+			number = strconv.Itoa(p.counters["equation"])
+		}
+		if number != "" {
+			b.Numbers = append(b.Numbers, number)
+		}
+		for _, m := range labelRe.FindAllStringSubmatch(row, -1) {
+			p.labels[strings.TrimSpace(m[1])] = target{kind: "equation", number: number}
+		}
+		row = labelRe.ReplaceAllString(row, "")
+		row = tagRe.ReplaceAllString(row, "")
+		row = noNumberRe.ReplaceAllString(row, "")
+		if base == "eqnarray" {
+			if parts := strings.SplitN(row, "&", 3); len(parts) == 3 {
+				row = parts[0] + "&" + parts[1] + parts[2]
+			}
+		}
+		cleaned = append(cleaned, strings.TrimSpace(row))
+	}
+
+	switch base {
+	case "align", "flalign", "eqnarray":
+		b.Latex = `\begin{aligned}` + strings.Join(cleaned, ` \\ `) + `\end{aligned}`
+	case "alignat", "xalignat":
+		cols, next, _ := readGroup(cleaned[0], 0)
+		cleaned[0] = strings.TrimSpace(cleaned[0][next:])
+		b.Latex = `\begin{alignedat}{` + cols + `}` + strings.Join(cleaned, ` \\ `) + `\end{alignedat}`
+	case "gather":
+		b.Latex = `\begin{gathered}` + strings.Join(cleaned, ` \\ `) + `\end{gathered}`
+	case "multline":
+		b.Latex = `\begin{gathered}` + cleaned[0] + `\end{gathered}`
+	default:
+		b.Latex = cleaned[0]
+	}
+	if strings.TrimSpace(b.Latex) == "" {
+		return
+	}
+	p.blocks = append(p.blocks, b)
+}
+
+// float 图表环境：每个 \caption 对应一个图表，子图的标题不单独编号
+func (p *parser) float(kind BlockKind, counter string, content string) {
+	outer := removeSubfloats(content)
+	locs := captionRe.FindAllStringSubmatchIndex(outer, -1)
+	files := graphicsRe.FindAllStringSubmatchIndex(outer, -1)
+	start := 0
+	for n, loc := range locs {
+		_, j, _ := readOptional(outer, loc[1])
+		caption, end, ok := readGroup(outer, j)
+		if !ok {
+			continue
+		}
+		b := &Block{Kind: kind, Section: p.section, raw: caption}
+		if loc[3] == loc[2] {
+			p.counters[counter]++
+			b.Number = strconv.Itoa(p.counters[counter])
+		}
+		// 标签和图片按位置归属到相邻的标题，最后一个标题收下剩余内容
+		segmentEnd := len(outer)
+		if n+1 < len(locs) {
+			segmentEnd = max(locs[n+1][0], end)
+		}
+		for _, m := range labelRe.FindAllStringSubmatch(outer[start:segmentEnd], -1) {
+			p.labels[strings.TrimSpace(m[1])] = target{kind: counter, number: b.Number}
+		}
+		for _, f := range files {
+			if f[0] >= start && f[0] < segmentEnd {
+				b.Files = append(b.Files, strings.TrimSpace(outer[f[2]:f[3]]))
+			}
+		}
+		start = segmentEnd
+		// 算法只参与编号，不作为图表输出
+		if counter != "algorithm" {
+			p.blocks = append(p.blocks, b)
+		}
+	}
+	// 子图中的标签指向所在的图
+	if len(locs) > 0 {
+		number := strconv.Itoa(p.counters[counter])
+		for _, m := range labelRe.FindAllStringSubmatch(content, -1) {
+			if _, ok := p.labels[strings.TrimSpace(m[1])]; !ok {
+				p.labels[strings.TrimSpace(m[1])] = target{kind: counter, number: number}
+			}
+		}
+	}
+}
+
+// removeSubfloats 将 subfigure 等子环境替换为其中的图片，避免子图标题被当作图表标题
+func removeSubfloats(s string) string {
+	for {
+		loc := subfloatEnvRe.FindStringSubmatchIndex(s)
+		if loc == nil {
+			return s
+		}
+		env := s[loc[2]:loc[3]]
+		end, next := findEnd(s, loc[1], env)
+		inner := s[loc[1]:end]
+		var keep strings.Builder
+		for _, m := range graphicsRe.FindAllString(inner, -1) {
+			keep.WriteString(m)
+		}
+		// minipage 中的独立标题是并排的两个图表，保留
+		if env == "minipage" && captionRe.MatchString(inner) {
+			keep.Reset()
+			keep.WriteString(inner)
+		}
+		s = s[:loc[0]] + keep.String() + s[next:]
+	}
+}
+
+// flush 结束当前文本段落
+func (p *parser) flush() {
+	raw := strings.TrimSpace(p.buf.String())
+	p.buf.Reset()
+	if raw == "" {
+		return
+	}
+	p.blocks = append(p.blocks, &Block{Kind: BlockText, Section: p.section, raw: raw})
+}
+
+// abstract 摘要：abstract 环境或 \abstract{...}
+func (p *parser) abstract(body string) (string, bool) {
+	if i := strings.Index(body, `\begin{abstract}`); i >= 0 {
+		start := i + len(`\begin{abstract}`)
+		end, _ := findEnd(body, start, "abstract")
+		return body[start:end], true
+	}
+	return firstCommandArg(body, "abstract")
+}
+
+// firstCommandArg 第一个指定命令的必选参数
+func firstCommandArg(s string, names ...string) (string, bool) {
+	for _, name := range names {
+		if args := commandArgs(s, name); len(args) > 0 {
+			return args[0], true
+		}
+	}
+	return "", false
+}
+
+// commandArgs 全部指定命令的第一个必选参数
+func commandArgs(s string, name string) []string {
+	var args []string
+	needle := `\` + name
+	for i := 0; ; {
+		k := strings.Index(s[i:], needle)
+		if k < 0 {
+			return args
+		}
+		j := i + k + len(needle)
+		if j < len(s) && isLetter(s[j]) {
+			i = j
+			continue
+		}
+		_, j, _ = readOptional(s, j)
+		if arg, next, ok := readGroup(s, j); ok && next > j {
+			args = append(args, arg)
+			i = next
+			continue
+		}
+		i = j
+	}
+}
+
+// authors 从 \author 等命令中抽取作者姓名，去掉单位、邮箱和脚注标记
+func (p *parser) authors(s string) []string {
+	var raws []string
+	for _, name := range []string{"author", "icmlauthor", "IEEEauthorblockN"} {
+		raws = append(raws, commandArgs(s, name)...)
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, raw := range raws {
+		for _, part := range authorSepRe.Split(raw, -1) {
+			// 换行之后通常是单位和邮箱
+			if lines := splitTopLevel(part, `\\`); len(lines) > 0 && strings.TrimSpace(lines[0]) != "" {
+				part = lines[0]
+			}
+			text, _ := p.convert(part)
+			text = stripMath(text)
+			for _, name := range authorNameRe.Split(text, -1) {
+				name = strings.TrimSpace(authorMarkRe.ReplaceAllString(strings.TrimSpace(name), ""))
+				fields := strings.Fields(name)
+				if len(fields) == 0 || len(fields) > 5 || strings.Contains(name, "@") || seen[name] {
+					continue
+				}
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// stripMath 去掉文本中的行内公式
+func stripMath(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '$' {
+			i = closingDollar(s, i+1)
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
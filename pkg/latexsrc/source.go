@@ -0,0 +1,297 @@
+// Package latexsrc 解析 arXiv e-print 中的 LaTeX 源码，并与PDF页面文本对齐
+//
+// arXiv 为大部分论文保留了作者上传的 LaTeX 源码。与从PDF版面反推结构相比，
+// 源码中的章节、\label/\ref、\cite 与 .bbl 条目、公式的 LaTeX 原文都是精确的。
+// 本包把源码解析为 Paper，转换为解析结果的 DocumentMetadata 与 FullDocument，
+// 再通过文本匹配将段落、标题、公式和引用标记定位到PDF页面坐标上。
+package latexsrc
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 解压后源码的大小上限，避免异常压缩包占满内存
+const (
+	maxFileSize  = 32 << 20
+	maxTotalSize = 256 << 20
+	maxIncludes  = 16 // \input 嵌套深度上限
+)
+
+var (
+	// ErrNoSource e-print 中没有 LaTeX 源码，如作者只提交了PDF
+	ErrNoSource = errors.New("latexsrc: e-print has no latex source")
+	// ErrNoMainFile 找不到包含 \documentclass 的主文件
+	ErrNoMainFile = errors.New("latexsrc: main tex file not found")
+)
+
+var (
+	documentClassRe = regexp.MustCompile(`\\documentclass\s*(\[[^\]]*\])?\s*\{`)
+	inputRe         = regexp.MustCompile(`\\(input|include|subfile)\s*\{([^}]+)\}|\\input\s+([^\s{}\\]+)`)
+)
+
+// Source e-print 解压后的文件集合
+type Source struct {
+	Files map[string][]byte // 相对路径 -> 文件内容
+}
+
+// Extract 解压 arXiv e-print，支持 gzip 压缩的 tar 包、单个 gzip 压缩的 tex 文件以及未压缩的 tar/tex
+func Extract(data []byte) (*Source, error) {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		unpacked, err := io.ReadAll(io.LimitReader(zr, maxTotalSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(unpacked) > maxTotalSize {
+			return nil, errors.New("latexsrc: e-print too large")
+		}
+		data = unpacked
+	}
+	if isTar(data) {
+		return extractTar(data)
+	}
+	if bytes.HasPrefix(data, []byte("%PDF")) || !documentClassRe.Match(data) {
+		return nil, ErrNoSource
+	}
+	return &Source{Files: map[string][]byte{"main.tex": data}}, nil
+}
+
+// isTar 检查 ustar 魔数
+func isTar(data []byte) bool {
+	return len(data) > 262 && bytes.HasPrefix(data[257:], []byte("ustar"))
+}
+
+func extractTar(data []byte) (*Source, error) {
+	src := &Source{Files: make(map[string][]byte)}
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || header.Size > maxFileSize {
+			continue
+		}
+		name := cleanPath(header.Name)
+		if name == "" {
+			continue
+		}
+		content, err := io.ReadAll(io.LimitReader(tr, maxFileSize))
+		if err != nil {
+			return nil, err
+		}
+		src.Files[name] = content
+	}
+	if len(src.TexFiles()) == 0 {
+		return nil, ErrNoSource
+	}
+	return src, nil
+}
+
+// cleanPath 统一为不带前导 "./" 的相对路径，拒绝跳出根目录的路径
+func cleanPath(name string) string {
+	name = path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimPrefix(name, "/")
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return ""
+	}
+	return name
+}
+
+// TexFiles 按路径排序的 .tex 文件列表
+func (s *Source) TexFiles() []string {
+	var names []string
+	for name := range s.Files {
+		if strings.EqualFold(path.Ext(name), ".tex") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MainFile 主文件：包含 \documentclass 和 \begin{document} 的 tex 文件，多个候选时取内容最长的
+func (s *Source) MainFile() (string, error) {
+	var main string
+	for _, name := range s.TexFiles() {
+		content := StripComments(string(s.Files[name]))
+		if !documentClassRe.MatchString(content) || !strings.Contains(content, `\begin{document}`) {
+			continue
+		}
+		if main == "" || len(s.Files[name]) > len(s.Files[main]) {
+			main = name
+		}
+	}
+	if main == "" {
+		return "", ErrNoMainFile
+	}
+	return main, nil
+}
+
+// Lookup 按 LaTeX 的规则查找文件：原名，或补上扩展名
+func (s *Source) Lookup(name string, exts ...string) (string, bool) {
+	name = cleanPath(name)
+	if name == "" {
+		return "", false
+	}
+	if _, ok := s.Files[name]; ok && path.Ext(name) != "" {
+		return name, true
+	}
+	for _, ext := range exts {
+		if _, ok := s.Files[name+ext]; ok {
+			return name + ext, true
+		}
+	}
+	if _, ok := s.Files[name]; ok {
+		return name, true
+	}
+	return "", false
+}
+
+// Expand 读取主文件，去掉注释并递归展开 \input、\include 引入的文件
+func (s *Source) Expand(main string) string {
+	return s.expand(main, 0, map[string]bool{})
+}
+
+func (s *Source) expand(name string, depth int, visiting map[string]bool) string {
+	if depth > maxIncludes || visiting[name] {
+		return ""
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	content := StripComments(string(s.Files[name]))
+	return inputRe.ReplaceAllStringFunc(content, func(match string) string {
+		m := inputRe.FindStringSubmatch(match)
+		target := strings.TrimSpace(m[2])
+		if target == "" {
+			target = m[3]
+		}
+		file, ok := s.Lookup(target, ".tex")
+		if !ok {
+			// 引入的是宏包等外部文件时保持原样
+			if m[1] == "include" || m[1] == "subfile" {
+				return ""
+			}
+			return match
+		}
+		text := s.expand(file, depth+1, visiting)
+		if m[1] == "subfile" {
+			text = documentBody(text)
+		}
+		return "\n" + text + "\n"
+	})
+}
+
+// StripComments 去掉 % 注释、comment 环境和 \iffalse 块
+//
+// 与 TeX 一致，行尾注释连同换行和下一行的行首空白一起去掉。
+func StripComments(text string) string {
+	var sb strings.Builder
+	sb.Grow(len(text))
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '\\' && i+1 < len(text) {
+			sb.WriteByte(c)
+			sb.WriteByte(text[i+1])
+			i++
+			continue
+		}
+		if c != '%' {
+			sb.WriteByte(c)
+			continue
+		}
+		end := strings.IndexByte(text[i:], '\n')
+		if end < 0 {
+			break
+		}
+		i += end
+		// 空行是段落分隔，不能被注释吞掉
+		j := i + 1
+		for j < len(text) && (text[j] == ' ' || text[j] == '\t') {
+			j++
+		}
+		if j < len(text) && text[j] == '\n' {
+			sb.WriteByte('\n')
+		}
+		i = j - 1
+	}
+	text = removeEnvironment(sb.String(), "comment")
+	return removeIfFalse(text)
+}
+
+// removeEnvironment 删除指定环境及其内容
+func removeEnvironment(text string, env string) string {
+	begin, end := `\begin{`+env+`}`, `\end{`+env+`}`
+	for {
+		i := strings.Index(text, begin)
+		if i < 0 {
+			return text
+		}
+		j := strings.Index(text[i:], end)
+		if j < 0 {
+			return text[:i]
+		}
+		text = text[:i] + text[i+j+len(end):]
+	}
+}
+
+var ifFalseRe = regexp.MustCompile(`\\(iffalse|if0)\b|\\fi\b|\\if[a-zA-Z@]*\b`)
+
+// removeIfFalse 删除 \iffalse ... \fi 块，正确处理其中嵌套的条件
+func removeIfFalse(text string) string {
+	if !strings.Contains(text, `\iffalse`) && !strings.Contains(text, `\if0`) {
+		return text
+	}
+	var sb strings.Builder
+	last, depth, start := 0, 0, 0
+	for _, loc := range ifFalseRe.FindAllStringSubmatchIndex(text, -1) {
+		token := text[loc[0]:loc[1]]
+		switch {
+		case depth == 0 && loc[2] >= 0:
+			sb.WriteString(text[last:loc[0]])
+			depth, start = 1, loc[0]
+		case depth > 0 && token == `\fi`:
+			depth--
+			if depth == 0 {
+				last = loc[1]
+			}
+		case depth > 0:
+			depth++
+		}
+	}
+	if depth > 0 {
+		sb.WriteString(text[last:start])
+		return sb.String()
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// documentBody \begin{document} 与 \end{document} 之间的正文，没有 document 环境时返回原文
+func documentBody(text string) string {
+	i := strings.Index(text, `\begin{document}`)
+	if i < 0 {
+		return text
+	}
+	body := text[i+len(`\begin{document}`):]
+	if j := strings.Index(body, `\end{document}`); j >= 0 {
+		body = body[:j]
+	}
+	return body
+}
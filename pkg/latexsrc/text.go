@@ -0,0 +1,401 @@
+package latexsrc
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var spaceRe = regexp.MustCompile(`\s+`)
+
+// 引用命令
+var citeCommands = map[string]bool{
+	"cite": true, "citep": true, "citet": true, "citealp": true, "citealt": true, "citeauthor": true,
+	"citeyear": true, "citeyearpar": true, "citenum": true, "parencite": true, "textcite": true,
+	"autocite": true, "footcite": true, "smartcite": true, "supercite": true, "Cite": true, "Citep": true,
+	"Citet": true, "Citealp": true, "Citeauthor": true, "Parencite": true, "Textcite": true, "Autocite": true,
+	"nocite": true,
+}
+
+// 交叉引用命令
+var refCommands = map[string]bool{
+	"ref": true, "eqref": true, "autoref": true, "cref": true, "Cref": true, "pageref": true,
+	"nameref": true, "vref": true, "Autoref": true,
+}
+
+// 参数不属于正文的命令及其必选参数个数，可选参数一并跳过
+var dropCommands = map[string]int{
+	"label": 1, "footnote": 1, "footnotetext": 1, "thanks": 1, "vspace": 1, "hspace": 1, "index": 1,
+	"marginpar": 1, "todo": 1, "includegraphics": 1, "color": 1, "textcolor": 1, "colorbox": 1,
+	"fcolorbox": 2, "definecolor": 3, "setlength": 2, "addtolength": 2, "setcounter": 2, "addtocounter": 2,
+	"bibliographystyle": 1, "bibliography": 1, "pagestyle": 1, "thispagestyle": 1, "title": 1,
+	"author": 1, "date": 1, "affiliation": 1, "address": 1, "email": 1, "institute": 1, "inst": 1,
+	"icmltitle": 1, "icmltitlerunning": 1, "icmlauthor": 2, "icmlaffiliation": 2, "icmlcorrespondingauthor": 2,
+	"icmlkeywords": 1, "keywords": 1, "ccsdesc": 1, "setcopyright": 1, "acmYear": 1, "acmDOI": 1,
+	"acmISBN": 1, "acmPrice": 1, "acmConference": 1, "acmBooktitle": 1, "hypersetup": 1, "captionsetup": 1,
+	"graphicspath": 1, "usepackage": 1, "newtheorem": 2, "theoremstyle": 1, "raisebox": 1, "resizebox": 2,
+	"scalebox": 1, "rule": 2, "phantom": 1, "hphantom": 1, "vphantom": 1, "input": 1, "orcid": 1,
+	"IEEEauthorrefmark": 1, "IEEEauthorblockA": 1, "abstract": 1, "footnotemark": 0, "caption": 1,
+	"renewcommand": 2, "newcommand": 2, "newenvironment": 3, "renewenvironment": 3, "end": 1,
+	"bibitem": 1, "runningtitle": 1, "runningauthor": 1, "shorttitle": 1, "shortauthors": 1,
+}
+
+// 直接替换为文字的命令
+var symbolCommands = map[string]string{
+	"ldots": "...", "dots": "...", "textellipsis": "...", "LaTeX": "LaTeX", "TeX": "TeX", "BibTeX": "BibTeX",
+	"textendash": "–", "textemdash": "—", "textbullet": "•", "S": "§", "P": "¶", "dag": "†", "ddag": "‡",
+	"copyright": "©", "textregistered": "®", "texttrademark": "™", "textasciitilde": "~",
+	"textbackslash": `\`, "textless": "<", "textgreater": ">", "textbar": "|", "textquoteleft": "'",
+	"textquoteright": "'", "textquotedblleft": `"`, "textquotedblright": `"`, "ss": "ß", "o": "ø", "O": "Ø",
+	"ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ", "aa": "å", "AA": "Å", "l": "ł", "L": "Ł", "i": "ı", "j": "ȷ",
+	"newline": " ", "linebreak": " ", "quad": " ", "qquad": " ", "enspace": " ", "enskip": " ", "space": " ",
+	"textdegree": "°", "textpm": "±", "texttimes": "×", "euro": "€", "pounds": "£", "textasciicircum": "^",
+}
+
+// 重音命令对应的组合字符
+var accentCommands = map[string]rune{
+	"'": '́', "`": '̀', "^": '̂', `"`: '̈', "~": '̃', "=": '̄', ".": '̇',
+	"c": '̧', "v": '̌', "u": '̆', "H": '̋', "k": '̨', "r": '̊', "d": '̣',
+	"b": '̱',
+}
+
+// converter 将 LaTeX 文本转换为纯文本
+type converter struct {
+	p     *parser
+	sb    strings.Builder
+	cites []Citation
+}
+
+// convert 将段落、标题等 LaTeX 片段转换为纯文本，返回文本和其中的引用
+func (p *parser) convert(raw string) (string, []Citation) {
+	c := &converter{p: p}
+	c.run(raw)
+	return strings.TrimSpace(spaceRe.ReplaceAllString(norm.NFC.String(c.sb.String()), " ")), c.cites
+}
+
+func (c *converter) run(s string) {
+	for i := 0; i < len(s); {
+		ch := s[i]
+		switch {
+		case ch == '\\':
+			i = c.command(s, i)
+			continue
+		case ch == '$':
+			end := closingDollar(s, i+1)
+			if strings.HasPrefix(s[i:], "$$") {
+				if k := strings.Index(s[i+2:], "$$"); k >= 0 {
+					c.math(s[i+2 : i+2+k])
+					i += k + 4
+					continue
+				}
+			}
+			c.math(s[i+1 : end])
+			i = end + 1
+			continue
+		case ch == '{' || ch == '}':
+		case ch == '~':
+			c.sb.WriteByte(' ')
+		case strings.HasPrefix(s[i:], "``") || strings.HasPrefix(s[i:], "''"):
+			c.sb.WriteByte('"')
+			i += 2
+			continue
+		case ch == '`':
+			c.sb.WriteByte('\'')
+		case strings.HasPrefix(s[i:], "---"):
+			c.sb.WriteString("—")
+			i += 3
+			continue
+		case strings.HasPrefix(s[i:], "--"):
+			c.sb.WriteString("–")
+			i += 2
+			continue
+		case ch == '&' || ch == '\n' || ch == '\t' || ch == '\r':
+			c.sb.WriteByte(' ')
+		default:
+			c.sb.WriteByte(ch)
+		}
+		i++
+	}
+}
+
+// math 行内公式保留 LaTeX 原文
+func (c *converter) math(s string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return
+	}
+	c.sb.WriteString("$" + s + "$")
+}
+
+// command 转换一个命令，返回命令之后的位置
+func (c *converter) command(s string, i int) int {
+	name, j := readCommand(s, i)
+	switch {
+	case citeCommands[name]:
+		j = skipStar(s, j)
+		var opts []string
+		for {
+			opt, next, ok := readOptional(s, j)
+			if !ok {
+				break
+			}
+			opts, j = append(opts, opt), next
+		}
+		keys, next, _ := readGroup(s, j)
+		c.cite(name, opts, keys)
+		return next
+	case refCommands[name]:
+		j = skipStar(s, j)
+		keys, next, _ := readGroup(s, j)
+		c.sb.WriteString(c.ref(name, keys))
+		return next
+	case name == "url":
+		arg, next, _ := readGroup(s, j)
+		c.sb.WriteString(arg)
+		return next
+	case name == "href":
+		_, next, _ := readGroup(s, j)
+		arg, next, _ := readGroup(s, next)
+		c.run(arg)
+		return next
+	case name == "verb":
+		if j >= len(s) {
+			return j
+		}
+		end := strings.IndexByte(s[j+1:], s[j])
+		if end < 0 {
+			return len(s)
+		}
+		c.sb.WriteString(s[j+1 : j+1+end])
+		return j + end + 2
+	case name == "ensuremath":
+		arg, next, _ := readGroup(s, j)
+		c.math(arg)
+		return next
+	case name == "(":
+		end := strings.Index(s[j:], `\)`)
+		if end < 0 {
+			return len(s)
+		}
+		c.math(s[j : j+end])
+		return j + end + 2
+	case name == "[":
+		end := strings.Index(s[j:], `\]`)
+		if end < 0 {
+			return len(s)
+		}
+		c.math(s[j : j+end])
+		return j + end + 2
+	case name == "begin":
+		env, next, _ := readGroup(s, j)
+		env = strings.TrimSpace(env)
+		base := strings.TrimSuffix(env, "*")
+		if skipEnvs[base] || mathEnvs[base] {
+			end, after := findEnd(s, next, env)
+			if mathEnvs[base] {
+				c.math(s[next:end])
+			}
+			return after
+		}
+		if n, ok := envArgs[base]; ok {
+			return skipArgs(s, next, n)
+		}
+		return next
+	case name == "item":
+		c.sb.WriteByte(' ')
+		if label, next, ok := readOptional(s, j); ok {
+			c.run(label)
+			c.sb.WriteByte(' ')
+			return next
+		}
+		return j
+	case name == `\`:
+		c.sb.WriteByte(' ')
+		if _, next, ok := readOptional(s, j); ok {
+			return next
+		}
+		return j
+	}
+	if mark, ok := accentCommands[name]; ok {
+		arg, next, ok := readGroup(s, j)
+		if !ok {
+			return j
+		}
+		// 无点的 \i、\j 加重音后就是普通字母
+		switch strings.TrimSpace(arg) {
+		case `\i`, `{\i}`:
+			arg = "i"
+		case `\j`, `{\j}`:
+			arg = "j"
+		}
+		c.sb.WriteString(arg)
+		c.sb.WriteRune(mark)
+		return next
+	}
+	if sym, ok := symbolCommands[name]; ok {
+		c.sb.WriteString(sym)
+		if isLetter(name[0]) {
+			// 控制词后的空格被 TeX 吞掉，{} 常用来保留空格
+			if strings.HasPrefix(s[j:], "{}") {
+				return j + 2
+			}
+		}
+		return j
+	}
+	if n, ok := dropCommands[name]; ok {
+		return skipArgs(s, skipStar(s, j), n)
+	}
+	if len(name) == 1 && !isLetter(name[0]) {
+		switch name {
+		case "%", "&", "$", "#", "_", "{", "}":
+			c.sb.WriteString(name)
+		case ",", ";", ":", " ", "!":
+			if name != "!" {
+				c.sb.WriteByte(' ')
+			}
+		}
+		return j
+	}
+	// 其余命令（\emph、\textbf 等）只去掉命令本身，参数作为正文保留
+	j = skipStar(s, j)
+	for {
+		_, next, ok := readOptional(s, j)
+		if !ok || next == j {
+			return j
+		}
+		j = next
+	}
+}
+
+// cite 按引用样式排版 \cite，记录引用的参考文献
+func (c *converter) cite(name string, opts []string, keys string) {
+	if name == "nocite" {
+		return
+	}
+	var items []*BibItem
+	var numbers []string
+	var valid []string
+	for _, key := range strings.Split(keys, ",") {
+		key = strings.TrimSpace(key)
+		idx, ok := c.p.bib[key]
+		if !ok {
+			continue
+		}
+		item := c.p.items[idx]
+		items = append(items, item)
+		numbers = append(numbers, item.mark(idx))
+		valid = append(valid, key)
+	}
+	var pre, post string
+	switch len(opts) {
+	case 1:
+		post, _ = c.p.convert(opts[0])
+	case 2:
+		pre, _ = c.p.convert(opts[0])
+		post, _ = c.p.convert(opts[1])
+	}
+	if len(items) == 0 {
+		c.sb.WriteString("[?]")
+		return
+	}
+
+	text := ""
+	lower := strings.ToLower(name)
+	authorYear := c.p.authorYear && name != "citenum"
+	for _, item := range items {
+		if item.Short == "" || item.Year == "" {
+			authorYear = false
+		}
+	}
+	switch {
+	case lower == "citeauthor":
+		text = joinItems(items, func(it *BibItem) string { return it.Short }, ", ")
+	case lower == "citeyear":
+		text = joinItems(items, func(it *BibItem) string { return it.Year }, ", ")
+	case lower == "citeyearpar":
+		text = "(" + joinItems(items, func(it *BibItem) string { return it.Year }, ", ") + ")"
+	case !authorYear:
+		text = "[" + withNotes(pre, strings.Join(numbers, ", "), post) + "]"
+	case lower == "citep" || lower == "parencite" || lower == "autocite":
+		text = "(" + withNotes(pre, joinItems(items, func(it *BibItem) string { return it.Short + ", " + it.Year }, "; "), post) + ")"
+	case lower == "citealp":
+		text = joinItems(items, func(it *BibItem) string { return it.Short + ", " + it.Year }, "; ")
+	case lower == "citealt":
+		text = joinItems(items, func(it *BibItem) string { return it.Short + " " + it.Year }, "; ")
+	default:
+		text = joinItems(items, func(it *BibItem) string { return it.Short + " (" + it.Year + ")" }, "; ")
+	}
+	if name[0] >= 'A' && name[0] <= 'Z' && text != "" {
+		text = strings.ToUpper(text[:1]) + text[1:]
+	}
+	c.sb.WriteString(text)
+	c.cites = append(c.cites, Citation{Keys: valid, Text: text})
+}
+
+func joinItems(items []*BibItem, f func(*BibItem) string, sep string) string {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		parts = append(parts, f(item))
+	}
+	return strings.Join(parts, sep)
+}
+
+func withNotes(pre, body, post string) string {
+	if pre != "" {
+		body = pre + " " + body
+	}
+	if post != "" {
+		body += ", " + post
+	}
+	return body
+}
+
+// ref 交叉引用替换为编号，找不到标签时与 LaTeX 一样输出 ??
+func (c *converter) ref(name string, keys string) string {
+	var parts []string
+	for _, key := range strings.Split(keys, ",") {
+		t, ok := c.p.labels[strings.TrimSpace(key)]
+		if !ok || t.number == "" {
+			parts = append(parts, "??")
+			continue
+		}
+		switch name {
+		case "eqref":
+			parts = append(parts, "("+t.number+")")
+		case "autoref", "Autoref", "cref", "Cref":
+			parts = append(parts, t.display())
+		default:
+			parts = append(parts, t.number)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// display \autoref、\cref 输出的带类型名称的引用，如 "Figure 3"、"Equation (2)"
+func (t target) display() string {
+	switch t.kind {
+	case "section":
+		return "Section " + t.number
+	case "equation":
+		return "Equation (" + t.number + ")"
+	case "figure":
+		return "Figure " + t.number
+	case "table":
+		return "Table " + t.number
+	case "algorithm":
+		return "Algorithm " + t.number
+	case "theorem":
+		return t.name + " " + t.number
+	}
+	return t.number
+}
+
+// mark 参考文献在正文中的标记：作者-年份标签以外的都是编号
+func (item *BibItem) mark(idx int) string {
+	if item.Label != "" && item.Short == "" {
+		return item.Label
+	}
+	return strconv.Itoa(idx + 1)
+}
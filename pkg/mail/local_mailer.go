@@ -0,0 +1,75 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/idgen"
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+// FileMailer 将邮件以.eml文件写入本地目录，用于开发和测试环境
+type FileMailer struct {
+	cfg    *config.MailConfig
+	logger logging.Logger
+}
+
+// NewFileMailer 创建本地文件邮件发送器
+func NewFileMailer(cfg *config.MailConfig, logger logging.Logger) *FileMailer {
+	return &FileMailer{cfg: cfg, logger: logger}
+}
+
+// Send 写入邮件文件
+func (m *FileMailer) Send(ctx context.Context, message *Message) error {
+	if err := message.Validate(); err != nil {
+		return err
+	}
+	dir := m.cfg.FileDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "odoc-mail")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create mail dir: %w", err)
+	}
+	body, err := buildMIMEMessage(m.cfg, message)
+	if err != nil {
+		return err
+	}
+	fileName := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405"), idgen.GenerateUUID())
+	path := filepath.Join(dir, fileName)
+	if err := os.WriteFile(path, body, 0o600); err != nil {
+		return fmt.Errorf("write mail file: %w", err)
+	}
+	m.logger.Info("msg", "邮件已写入本地文件", "to", strings.Join(message.To, ","), "subject", message.Subject, "path", path)
+	return nil
+}
+
+// LogMailer 只把邮件输出到日志，不真实发送
+type LogMailer struct {
+	cfg    *config.MailConfig
+	logger logging.Logger
+}
+
+// NewLogMailer 创建日志邮件发送器
+func NewLogMailer(cfg *config.MailConfig, logger logging.Logger) *LogMailer {
+	return &LogMailer{cfg: cfg, logger: logger}
+}
+
+// Send 输出邮件到日志
+func (m *LogMailer) Send(ctx context.Context, message *Message) error {
+	if err := message.Validate(); err != nil {
+		return err
+	}
+	m.logger.Info("msg", "邮件未真实发送，仅输出日志",
+		"from", m.cfg.From,
+		"to", strings.Join(message.To, ","),
+		"subject", message.Subject,
+		"body", message.TextBody,
+	)
+	return nil
+}
@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+// 发送方式常量
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+// Message 邮件内容
+type Message struct {
+	To       []string
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+// Validate 校验邮件内容
+func (m *Message) Validate() error {
+	if len(m.To) == 0 {
+		return fmt.Errorf("mail recipient is empty")
+	}
+	for _, to := range m.To {
+		if strings.ContainsAny(to, "\r\n") {
+			return fmt.Errorf("invalid mail recipient: %q", to)
+		}
+	}
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return fmt.Errorf("invalid mail subject")
+	}
+	if m.TextBody == "" && m.HTMLBody == "" {
+		return fmt.Errorf("mail body is empty")
+	}
+	return nil
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	// Send 发送邮件
+	Send(ctx context.Context, message *Message) error
+}
+
+// NewMailer 根据配置创建邮件发送器，未知的发送方式回退到日志输出
+func NewMailer(cfg *config.MailConfig, logger logging.Logger) Mailer {
+	switch cfg.Driver {
+	case DriverSMTP:
+		logger.Info("msg", "使用SMTP发送邮件", "host", cfg.Host, "port", cfg.Port)
+		return NewSMTPMailer(cfg, logger)
+	case DriverFile:
+		logger.Info("msg", "邮件写入本地文件", "dir", cfg.FileDir)
+		return NewFileMailer(cfg, logger)
+	default:
+		logger.Info("msg", "邮件仅输出日志", "driver", cfg.Driver)
+		return NewLogMailer(cfg, logger)
+	}
+}
@@ -0,0 +1,92 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+func TestMessageValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *Message
+		wantErr bool
+	}{
+		{
+			name:    "正常邮件",
+			message: &Message{To: []string{"a@example.com"}, Subject: "hello", TextBody: "body"},
+		},
+		{
+			name:    "收件人为空",
+			message: &Message{Subject: "hello", TextBody: "body"},
+			wantErr: true,
+		},
+		{
+			name:    "主题包含换行",
+			message: &Message{To: []string{"a@example.com"}, Subject: "hello\r\nBcc: x@example.com", TextBody: "body"},
+			wantErr: true,
+		},
+		{
+			name:    "收件人包含换行",
+			message: &Message{To: []string{"a@example.com\r\nBcc: x@example.com"}, Subject: "hello", TextBody: "body"},
+			wantErr: true,
+		},
+		{
+			name:    "正文为空",
+			message: &Message{To: []string{"a@example.com"}, Subject: "hello"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.message.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileMailerSend(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.MailConfig{From: "no-reply@example.com", FromName: "odoc", FileDir: dir}
+	mailer := NewFileMailer(cfg, logging.NewLogger("error", "logfmt"))
+
+	message := &Message{
+		To:       []string{"a@example.com"},
+		Subject:  "验证邮箱",
+		TextBody: "text body",
+		HTMLBody: "<p>html body</p>",
+	}
+	if err := mailer.Send(context.Background(), message); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected 1 mail file, got %d (err=%v)", len(files), err)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("read mail file: %v", err)
+	}
+	raw := string(content)
+	for _, want := range []string{
+		"To: a@example.com\r\n",
+		"multipart/alternative",
+		"=?utf-8?q?",
+		base64.StdEncoding.EncodeToString([]byte("text body")),
+		base64.StdEncoding.EncodeToString([]byte("<p>html body</p>")),
+	} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("mail file missing %q", want)
+		}
+	}
+}
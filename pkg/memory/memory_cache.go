@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	gocache "github.com/patrickmn/go-cache"
@@ -18,6 +19,7 @@ type MemoryCache struct {
 	logger     logging.Logger
 	prefix     string
 	expiration time.Duration
+	takeMutex  sync.Mutex // 保证 GetAndDelete 的读取与删除不被并发的 GetAndDelete 打断
 }
 
 // NewMemoryCache 创建一个新的内存缓存
@@ -122,6 +124,34 @@ func (c *MemoryCache) setInternal(key string, value interface{}, expiration time
 	return nil
 }
 
+// SetNX 键不存在时才存入值，go-cache 的 Add 在键已存在且未过期时返回错误
+func (c *MemoryCache) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	formattedKey := c.formatKey(key)
+	if expiration == 0 {
+		expiration = c.expiration
+	}
+
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	return c.cache.Add(formattedKey, jsonData, expiration) == nil, nil
+}
+
+// GetAndDelete 原子地获取并删除值
+func (c *MemoryCache) GetAndDelete(ctx context.Context, key string, dest interface{}) (bool, error) {
+	formattedKey := c.formatKey(key)
+	c.takeMutex.Lock()
+	defer c.takeMutex.Unlock()
+
+	found, err := c.getInternal(formattedKey, dest)
+	if found || err != nil {
+		c.cache.Delete(formattedKey)
+	}
+	return found, err
+}
+
 // Delete 从缓存中删除值
 func (c *MemoryCache) Delete(ctx context.Context, key string) error {
 	formattedKey := c.formatKey(key)
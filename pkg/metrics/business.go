@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// 业务指标通过全局 MeterProvider 上报；未启用 OpenTelemetry 时为空实现，调用方无需判断
+const meterName = "github.com/yb2020/odoc"
+
+// 支付结果
+const (
+	PaymentSucceeded = "succeeded"
+	PaymentFailed    = "failed"
+	PaymentCanceled  = "canceled"
+	PaymentRefunded  = "refunded"
+	PaymentDisputed  = "disputed"
+)
+
+type businessInstruments struct {
+	uploads          metric.Int64Counter
+	uploadBytes      metric.Int64Counter
+	parseDuration    metric.Float64Histogram
+	translationChars metric.Int64Counter
+	creditConsumed   metric.Int64Counter
+	payments         metric.Int64Counter
+	paymentAmount    metric.Int64Counter
+}
+
+var (
+	instrumentsOnce sync.Once
+	instruments     businessInstruments
+)
+
+// business 首次使用时创建指标；全局 MeterProvider 在之后才设置时，otel 会把已创建的指标委托过去
+func business() *businessInstruments {
+	instrumentsOnce.Do(func() {
+		meter := otel.Meter(meterName)
+		// 创建失败时返回的仍是可用的空实现，忽略错误
+		instruments.uploads, _ = meter.Int64Counter("odoc.upload.count",
+			metric.WithDescription("文件上传次数"))
+		instruments.uploadBytes, _ = meter.Int64Counter("odoc.upload.size",
+			metric.WithDescription("上传文件大小"), metric.WithUnit("By"))
+		instruments.parseDuration, _ = meter.Float64Histogram("odoc.parse.duration",
+			metric.WithDescription("文档解析耗时"), metric.WithUnit("s"),
+			metric.WithExplicitBucketBoundaries(0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300))
+		instruments.translationChars, _ = meter.Int64Counter("odoc.translation.characters",
+			metric.WithDescription("翻译字符数"))
+		instruments.creditConsumed, _ = meter.Int64Counter("odoc.credit.consumed",
+			metric.WithDescription("积分消耗量"))
+		instruments.payments, _ = meter.Int64Counter("odoc.payment.count",
+			metric.WithDescription("支付结果次数"))
+		instruments.paymentAmount, _ = meter.Int64Counter("odoc.payment.amount",
+			metric.WithDescription("支付金额（最小货币单位）"))
+	})
+	return &instruments
+}
+
+// RecordUpload 记录一次文件上传完成，kind 为上传类型，一般取存储桶名
+func RecordUpload(ctx context.Context, kind string, success bool, size int64) {
+	m := business()
+	attrs := metric.WithAttributes(attribute.String("kind", kind), attribute.Bool("success", success))
+	m.uploads.Add(ctx, 1, attrs)
+	if success && size > 0 {
+		m.uploadBytes.Add(ctx, size, metric.WithAttributes(attribute.String("kind", kind)))
+	}
+}
+
+// RecordParse 记录一次文档解析耗时，按解析器和成功与否区分
+func RecordParse(ctx context.Context, parser string, duration time.Duration, err error) {
+	business().parseDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String("parser", parser),
+		attribute.Bool("success", err == nil),
+	))
+}
+
+// RecordTranslation 记录一次成功翻译的原文字符数，channel 为翻译渠道
+func RecordTranslation(ctx context.Context, channel string, chars int) {
+	if chars <= 0 {
+		return
+	}
+	business().translationChars.Add(ctx, int64(chars), metric.WithAttributes(attribute.String("channel", channel)))
+}
+
+// RecordCreditConsumption 记录积分消耗，bizType 为消耗积分的业务类型
+func RecordCreditConsumption(ctx context.Context, bizType string, amount int64) {
+	if amount <= 0 {
+		return
+	}
+	business().creditConsumed.Add(ctx, amount, metric.WithAttributes(attribute.String("biz_type", bizType)))
+}
+
+// RecordPayment 记录支付结果，outcome 取上方的支付结果常量；金额只在成功时累计
+func RecordPayment(ctx context.Context, provider, outcome string, amount int64, currency string) {
+	m := business()
+	m.payments.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("provider", provider),
+		attribute.String("outcome", outcome),
+	))
+	if outcome == PaymentSucceeded && amount > 0 {
+		m.paymentAmount.Add(ctx, amount, metric.WithAttributes(
+			attribute.String("provider", provider),
+			attribute.String("currency", currency),
+		))
+	}
+}
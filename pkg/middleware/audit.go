@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/audit"
+)
+
+// AuditMiddleware 审计中间件
+// 请求开始时把客户端IP、User-Agent 和请求ID 写入上下文，供业务代码记录审计日志时补全
+// 请求结束后按配置自动记录写请求，以及管理后台等需要全部记录的接口
+func AuditMiddleware(cfg config.AuditConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		ctx = audit.WithRequestInfo(ctx, audit.RequestInfo{
+			ClientIp:  c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestId: GetRequestID(ctx),
+		})
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !cfg.CaptureHTTP || !audit.Enabled() {
+			return
+		}
+		// 未匹配到路由的请求不记录
+		path := c.FullPath()
+		if path == "" || !shouldCaptureHTTP(cfg, c.Request.Method, path) {
+			return
+		}
+		// 认证中间件会替换请求上下文，这里取处理完成后的上下文以获得操作人
+		_ = audit.Record(c.Request.Context(), &audit.Entry{
+			Action:     audit.ActionHTTP,
+			EntityType: "route",
+			EntityId:   path,
+			Source:     audit.SourceHTTP,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			StatusCode: c.Writer.Status(),
+		})
+	}
+}
+
+// shouldCaptureHTTP 判断请求是否需要自动记录
+func shouldCaptureHTTP(cfg config.AuditConfig, method string, path string) bool {
+	if hasAnyPrefix(path, cfg.ExcludePaths) {
+		return false
+	}
+	if hasAnyPrefix(path, cfg.AlwaysPaths) {
+		return true
+	}
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// hasAnyPrefix 判断路径是否以任一前缀开头
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
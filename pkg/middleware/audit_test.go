@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/audit"
+)
+
+type testAuditRecorder struct {
+	entries []*audit.Entry
+}
+
+func (r *testAuditRecorder) Record(ctx context.Context, entry *audit.Entry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := &testAuditRecorder{}
+	audit.SetRecorder(recorder)
+	defer audit.SetRecorder(nil)
+
+	cfg := config.AuditConfig{
+		CaptureHTTP:  true,
+		ExcludePaths: []string{"/api/reading/heartbeat"},
+		AlwaysPaths:  []string{"/api/admin"},
+	}
+	r := gin.New()
+	r.Use(AuditMiddleware(cfg))
+	auth := newTestAuthMiddleware()
+	group := r.Group("/", auth.AuthRequired())
+	var serviceEntry *audit.Entry
+	group.POST("/api/doc/delete", func(c *gin.Context) {
+		// 业务代码记录时能从上下文中拿到请求来源信息
+		serviceEntry = &audit.Entry{Action: audit.ActionDelete, EntityType: "doc", EntityId: "d-1"}
+		_ = audit.Record(c.Request.Context(), serviceEntry)
+		c.Status(http.StatusOK)
+	})
+	group.GET("/api/doc/list", func(c *gin.Context) { c.Status(http.StatusOK) })
+	group.GET("/api/admin/audit/list", func(c *gin.Context) { c.Status(http.StatusOK) })
+	group.POST("/api/reading/heartbeat", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(method string, path string) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer u-1")
+		req.Header.Set("User-Agent", "test-agent")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	do(http.MethodPost, "/api/doc/delete")
+	do(http.MethodGet, "/api/doc/list")
+	do(http.MethodGet, "/api/admin/audit/list")
+	do(http.MethodPost, "/api/reading/heartbeat")
+	do(http.MethodPost, "/api/not-found")
+
+	if len(recorder.entries) != 3 {
+		t.Fatalf("recorded %d entries, want 3: %+v", len(recorder.entries), recorder.entries)
+	}
+	if serviceEntry.ActorId != "u-1" || serviceEntry.UserAgent != "test-agent" || serviceEntry.ClientIp == "" {
+		t.Fatalf("service entry not filled from request: %+v", serviceEntry)
+	}
+	httpEntry := recorder.entries[1]
+	if httpEntry.Source != audit.SourceHTTP || httpEntry.Method != http.MethodPost || httpEntry.EntityId != "/api/doc/delete" ||
+		httpEntry.StatusCode != http.StatusOK || httpEntry.ActorId != "u-1" {
+		t.Fatalf("http entry = %+v", httpEntry)
+	}
+	if got := recorder.entries[2].Path; got != "/api/admin/audit/list" {
+		t.Fatalf("admin entry path = %q", got)
+	}
+}
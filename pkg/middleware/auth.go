@@ -31,6 +31,15 @@ type Claims interface {
 	GetServiceName() string
 }
 
+// ScopedClaims 带作用域的令牌声明接口
+// 第三方应用令牌实现该接口，只能访问被授予的作用域覆盖的接口
+type ScopedClaims interface {
+	// GetClientId 获取第三方应用ID，第一方令牌为空
+	GetClientId() string
+	// GetScopes 获取被授予的作用域
+	GetScopes() []string
+}
+
 // AuthService 认证服务接口
 // 这个接口允许不同的认证实现（如OAuth2、JWT、基本认证等）
 type AuthService interface {
@@ -97,6 +106,17 @@ func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		// 第三方应用令牌只能访问作用域覆盖的接口
+		if !m.scopeAllowed(c, claims) {
+			msg := "第三方应用未获得访问该接口的授权" // 默认消息
+			if m.localizer != nil {
+				msg = m.localizer.Localize("auth.scope.insufficient", c)
+			}
+			response.SystemErrorNoData(c, response.Code_Forbidden, response.Status_AuthInvalidRole, msg)
+			c.Abort()
+			return
+		}
+
 		// 检查是否是管理员路径
 		// 防御性检查：确保 AdminPaths 不为 nil
 		if m.config.OAuth2.ResourceProtection.AdminPaths != nil {
@@ -152,8 +172,8 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 
 		// 验证令牌
 		claims, err := m.authService.ValidateToken(c, accessToken)
-		if err != nil {
-			// 令牌无效，但不阻止请求
+		if err != nil || !m.scopeAllowed(c, claims) {
+			// 令牌无效或作用域不足，但不阻止请求
 			c.Next()
 			return
 		}
@@ -164,6 +184,65 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 	}
 }
 
+// ResolveUserID 解析请求的用户ID但不拦截请求，供先于路由组认证中间件执行的全局中间件使用
+// 已认证的请求直接返回上下文中的用户ID，令牌缺失、无效或作用域不足时返回空字符串
+func (m *AuthMiddleware) ResolveUserID(c *gin.Context) string {
+	if userId := c.GetString(string(context.UserIDKey)); userId != "" {
+		return userId
+	}
+
+	accessToken := m.extractToken(c)
+	if accessToken == "" {
+		return ""
+	}
+	claims, err := m.authService.ValidateToken(c, accessToken)
+	if err != nil || !m.scopeAllowed(c, claims) {
+		return ""
+	}
+	return claims.GetUserID()
+}
+
+// scopeAllowed 检查第三方应用令牌的作用域是否覆盖当前请求，第一方令牌不受限制
+func (m *AuthMiddleware) scopeAllowed(c *gin.Context, claims Claims) bool {
+	scoped, ok := claims.(ScopedClaims)
+	if !ok || scoped.GetClientId() == "" {
+		return true
+	}
+
+	path := c.Request.URL.Path
+	for _, granted := range scoped.GetScopes() {
+		for _, scope := range m.config.OAuth2.AuthorizationServer.Scopes {
+			if scope.Name != granted {
+				continue
+			}
+			if len(scope.Methods) > 0 && !containsFold(scope.Methods, c.Request.Method) {
+				continue
+			}
+			for _, scopePath := range scope.Paths {
+				if scopePathMatches(path, scopePath) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// scopePathMatches 按路径段匹配作用域路径，/api/note 匹配 /api/note 与 /api/note/list，不匹配 /api/notes
+func scopePathMatches(path string, scopePath string) bool {
+	return path == scopePath || strings.HasPrefix(path, strings.TrimSuffix(scopePath, "/")+"/")
+}
+
+// containsFold 忽略大小写判断切片是否包含字符串
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *AuthMiddleware) setUserContext(c *gin.Context, claims Claims, accessToken string) {
 	// 将用户信息存储到上下文中
 	c.Set(string(context.UserIDKey), claims.GetUserID())
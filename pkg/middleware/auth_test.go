@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yb2020/odoc/config"
+)
+
+func TestScopeAllowed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newTestAuthMiddleware()
+	m.config.OAuth2.AuthorizationServer.Scopes = []config.OAuth2ScopeConfig{
+		{Name: "notes:read", Paths: []string{"/api/note/"}, Methods: []string{"GET"}},
+		{Name: "docs", Paths: []string{"/api/doc"}},
+	}
+
+	tests := []struct {
+		name   string
+		claims Claims
+		method string
+		path   string
+		want   bool
+	}{
+		{name: "first party token", claims: &testClaims{userId: "u-1"}, method: http.MethodPost, path: "/api/admin/user", want: true},
+		{name: "exact path", claims: &testClaims{clientId: "app", scopes: []string{"docs"}}, method: http.MethodPost, path: "/api/doc", want: true},
+		{name: "sub path", claims: &testClaims{clientId: "app", scopes: []string{"docs"}}, method: http.MethodPost, path: "/api/doc/list", want: true},
+		{name: "sibling prefix", claims: &testClaims{clientId: "app", scopes: []string{"docs"}}, method: http.MethodPost, path: "/api/docs/list", want: false},
+		{name: "trailing slash config exact path", claims: &testClaims{clientId: "app", scopes: []string{"notes:read"}}, method: http.MethodGet, path: "/api/note", want: false},
+		{name: "trailing slash config sub path", claims: &testClaims{clientId: "app", scopes: []string{"notes:read"}}, method: http.MethodGet, path: "/api/note/list", want: true},
+		{name: "method case insensitive", claims: &testClaims{clientId: "app", scopes: []string{"notes:read"}}, method: "get", path: "/api/note/list", want: true},
+		{name: "method not allowed", claims: &testClaims{clientId: "app", scopes: []string{"notes:read"}}, method: http.MethodPost, path: "/api/note/list", want: false},
+		{name: "scope not granted", claims: &testClaims{clientId: "app", scopes: []string{"notes:read"}}, method: http.MethodGet, path: "/api/doc/list", want: false},
+		{name: "unknown scope", claims: &testClaims{clientId: "app", scopes: []string{"admin"}}, method: http.MethodGet, path: "/api/doc/list", want: false},
+		{name: "no scopes", claims: &testClaims{clientId: "app"}, method: http.MethodGet, path: "/api/doc/list", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(tt.method, tt.path, nil)
+			if got := m.scopeAllowed(c, tt.claims); got != tt.want {
+				t.Fatalf("scopeAllowed(%s %s) = %v, want %v", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
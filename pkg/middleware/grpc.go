@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+// 业务状态码所在的 gRPC trailer，与 HTTP 响应体中的 status 一致
+const GRPCBizStatusTrailer = "x-biz-status"
+
+// gRPC 请求标签，认证拦截器写入，日志拦截器读取
+const grpcTagUserID = "user_id"
+
+// GRPCAuthService gRPC 认证服务接口
+// gRPC 请求没有 gin.Context，认证服务实现该接口后 gRPC 接口才能校验令牌
+type GRPCAuthService interface {
+	// ValidateAccessToken 验证访问令牌
+	ValidateAccessToken(ctx context.Context, token string) (Claims, error)
+}
+
+// GRPCUnaryInterceptor gRPC 一元认证拦截器，认证语义与 AuthRequired 一致
+func (m *AuthMiddleware) GRPCUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := m.authenticateGRPC(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GRPCStreamInterceptor gRPC 流式认证拦截器
+func (m *AuthMiddleware) GRPCStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := m.authenticateGRPC(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// authenticateGRPC 校验令牌并把用户信息写入上下文
+// 第三方应用令牌的作用域按 HTTP 路径授权，无法覆盖 gRPC 方法，因此一律拒绝
+func (m *AuthMiddleware) authenticateGRPC(ctx context.Context, fullMethod string) (context.Context, error) {
+	for _, method := range m.config.Server.GRPC.PublicMethods {
+		if strings.HasPrefix(fullMethod, method) {
+			return ctx, nil
+		}
+	}
+
+	accessToken := m.extractGRPCToken(ctx)
+	if accessToken == "" {
+		return nil, status.Error(codes.Unauthenticated, localizeGRPC(ctx, m.localizer, "auth.token.missing", "认证令牌缺失"))
+	}
+
+	validator, ok := m.authService.(GRPCAuthService)
+	if !ok {
+		m.logger.Error("msg", "认证服务不支持gRPC令牌校验", "method", fullMethod)
+		return nil, status.Error(codes.Unauthenticated, localizeGRPC(ctx, m.localizer, "auth.token.invalid", "无效的认证令牌"))
+	}
+	claims, err := validator.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		m.logger.Debug("msg", "gRPC令牌校验失败", "method", fullMethod, "error", err.Error())
+		return nil, status.Error(codes.Unauthenticated, localizeGRPC(ctx, m.localizer, "auth.token.invalid", "无效的认证令牌"))
+	}
+
+	if scoped, ok := claims.(ScopedClaims); ok && scoped.GetClientId() != "" {
+		return nil, status.Error(codes.PermissionDenied, localizeGRPC(ctx, m.localizer, "auth.scope.insufficient", "第三方应用未获得访问该接口的授权"))
+	}
+
+	grpc_ctxtags.Extract(ctx).Set(grpcTagUserID, claims.GetUserID())
+	uc := userContext.NewUserContext().
+		SetUserID(claims.GetUserID()).
+		SetUsername(claims.GetUsername()).
+		SetRoles(claims.GetRoles()).
+		SetDevice(claims.GetDevice()).
+		SetClaims(claims).
+		SetAccessToken(accessToken).
+		SetAuthenticated(true)
+	return uc.ToContext(ctx), nil
+}
+
+// extractGRPCToken 从 metadata 中提取令牌，请求头名称与 HTTP 一致，格式为 Bearer token
+func (m *AuthMiddleware) extractGRPCToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get(m.config.OAuth2.TokenStorage.TokenHeaderName) {
+		parts := strings.Split(value, " ")
+		if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+			return parts[1]
+		}
+	}
+	return ""
+}
+
+// GRPCLoggingUnaryInterceptor 记录 gRPC 一元请求的方法、状态码、耗时和用户
+func GRPCLoggingUnaryInterceptor(logger logging.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, tags := withGRPCTags(ctx)
+		begin := time.Now()
+		resp, err := handler(ctx, req)
+		logGRPCCall(logger, info.FullMethod, tags, begin, err)
+		return resp, err
+	}
+}
+
+// GRPCLoggingStreamInterceptor 记录 gRPC 流式请求
+func GRPCLoggingStreamInterceptor(logger logging.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, tags := withGRPCTags(stream.Context())
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		begin := time.Now()
+		err := handler(srv, wrapped)
+		logGRPCCall(logger, info.FullMethod, tags, begin, err)
+		return err
+	}
+}
+
+// withGRPCTags 确保上下文中有请求标签，内层拦截器写入的标签在请求结束后可读
+func withGRPCTags(ctx context.Context) (context.Context, grpc_ctxtags.Tags) {
+	if tags := grpc_ctxtags.Extract(ctx); tags != grpc_ctxtags.NoopTags {
+		return ctx, tags
+	}
+	tags := grpc_ctxtags.NewTags()
+	return grpc_ctxtags.SetInContext(ctx, tags), tags
+}
+
+func logGRPCCall(logger logging.Logger, method string, tags grpc_ctxtags.Tags, begin time.Time, err error) {
+	userId, _ := tags.Values()[grpcTagUserID].(string)
+	code := status.Code(err)
+	keyvals := []interface{}{
+		"msg", "gRPC请求",
+		"method", method,
+		"code", code.String(),
+		"took", time.Since(begin),
+		"userId", userId,
+	}
+	switch code {
+	case codes.OK:
+		logger.Info(keyvals...)
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		logger.Error(append(keyvals, "error", err.Error())...)
+	default:
+		logger.Warn(append(keyvals, "error", err.Error())...)
+	}
+}
+
+// GRPCErrorUnaryInterceptor 把业务错误和系统错误转换为本地化的 gRPC 状态，与 HTTP 的 ErrorHandler 一致
+// 业务错误的状态码通过 trailer 返回，已经是 gRPC 状态的错误原样返回
+func GRPCErrorUnaryInterceptor(logger logging.Logger, localizer i18n.Localizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		st, trailer := toGRPCStatus(ctx, logger, localizer, info.FullMethod, err)
+		if trailer != nil {
+			_ = grpc.SetTrailer(ctx, trailer)
+		}
+		return nil, st.Err()
+	}
+}
+
+// GRPCErrorStreamInterceptor 流式请求的错误转换
+func GRPCErrorStreamInterceptor(logger logging.Logger, localizer i18n.Localizer) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, stream)
+		if err == nil {
+			return nil
+		}
+		st, trailer := toGRPCStatus(stream.Context(), logger, localizer, info.FullMethod, err)
+		if trailer != nil {
+			stream.SetTrailer(trailer)
+		}
+		return st.Err()
+	}
+}
+
+func toGRPCStatus(ctx context.Context, logger logging.Logger, localizer i18n.Localizer, method string, err error) (*status.Status, metadata.MD) {
+	if st, ok := status.FromError(err); ok {
+		return st, nil
+	}
+
+	var bizErr *errors.BizError
+	if errors.As(err, &bizErr) {
+		msg := localizeGRPC(ctx, localizer, bizErr.MsgID, bizErr.MsgID)
+		trailer := metadata.Pairs(GRPCBizStatusTrailer, strconv.Itoa(int(bizErr.Status)))
+		return status.New(codes.FailedPrecondition, msg), trailer
+	}
+
+	var systemErr *errors.SystemError
+	if errors.As(err, &systemErr) {
+		logger.Error("msg", "gRPC请求系统错误", "method", method, "error", err.Error())
+		return status.New(codes.Internal, localizeGRPC(ctx, localizer, systemErr.MessageId, systemErr.MessageId)), nil
+	}
+
+	return status.New(codes.Internal, err.Error()), nil
+}
+
+// localizeGRPC 按 metadata 中的 accept-language 本地化消息，localizer 为空时返回默认消息
+func localizeGRPC(ctx context.Context, localizer i18n.Localizer, messageID, defaultMsg string) string {
+	if localizer == nil {
+		return defaultMsg
+	}
+	return localizer.LocalizeWithLanguage(messageID, nil, grpcLanguage(ctx))
+}
+
+// grpcLanguage 从 metadata 的 accept-language 获取首选语言，如 "zh-CN,zh;q=0.9" 取 zh-CN
+// 未指定时返回空字符串，由本地化器使用默认语言
+func grpcLanguage(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get("accept-language") {
+		for _, part := range strings.Split(value, ",") {
+			tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+			if tag != "" && tag != "*" {
+				return i18n.GlobalConverter.NormalizeToRFC5646(tag)
+			}
+		}
+	}
+	return ""
+}
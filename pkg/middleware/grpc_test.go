@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/yb2020/odoc/config"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+type testClaims struct {
+	userId   string
+	clientId string
+	scopes   []string
+}
+
+func (c *testClaims) GetUserID() string      { return c.userId }
+func (c *testClaims) GetUsername() string    { return "tester" }
+func (c *testClaims) GetRoles() []string     { return []string{"user"} }
+func (c *testClaims) GetDevice() string      { return "" }
+func (c *testClaims) GetServiceId() string   { return "" }
+func (c *testClaims) GetServiceName() string { return "" }
+func (c *testClaims) GetClientId() string    { return c.clientId }
+func (c *testClaims) GetScopes() []string    { return c.scopes }
+
+// testAuthService 令牌即用户ID，"bad" 为无效令牌，"third-party" 为第三方应用令牌
+type testAuthService struct{}
+
+func (s testAuthService) ValidateToken(ctx *gin.Context, token string) (Claims, error) {
+	return s.ValidateAccessToken(ctx, token)
+}
+
+func (testAuthService) ValidateServiceToken(ctx *gin.Context, token string) (Claims, error) {
+	return nil, stderrors.New("not used")
+}
+
+func (testAuthService) ValidateAccessToken(ctx context.Context, token string) (Claims, error) {
+	switch token {
+	case "bad":
+		return nil, errors.Biz("oauth2.error.invalid_token")
+	case "third-party":
+		return &testClaims{userId: "u-3", clientId: "client"}, nil
+	}
+	return &testClaims{userId: token}, nil
+}
+
+// testLocalizer 返回 "语言:消息ID"，便于断言使用的语言
+type testLocalizer struct{}
+
+func (testLocalizer) Localize(messageID string, c *gin.Context) string { return messageID }
+func (testLocalizer) LocalizeWithData(messageID string, data map[string]interface{}, c *gin.Context) string {
+	return messageID
+}
+func (testLocalizer) LocalizeWithLanguage(messageID string, data map[string]interface{}, lang string) string {
+	if lang == "" {
+		lang = "default"
+	}
+	return lang + ":" + messageID
+}
+func (testLocalizer) GetDefaultLanguage() string        { return "en-US" }
+func (testLocalizer) GetSupportedLanguages() []string   { return []string{"en-US", "zh-CN"} }
+func (testLocalizer) GetLanguage(c *gin.Context) string { return "en-US" }
+
+// testTransportStream 记录服务端设置的 header 和 trailer
+type testTransportStream struct {
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *testTransportStream) Method() string { return "/test.Service/Call" }
+func (s *testTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+func (s *testTransportStream) SendHeader(md metadata.MD) error { return nil }
+func (s *testTransportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+func newTestAuthMiddleware() *AuthMiddleware {
+	var cfg config.Config
+	cfg.OAuth2.TokenStorage.TokenHeaderName = "Authorization"
+	cfg.Server.GRPC.PublicMethods = []string{"/user.UserService/Register", "/grpc.reflection."}
+	return NewAuthMiddleware(cfg, logging.NewLogger("error", "logfmt"), testLocalizer{}, testAuthService{})
+}
+
+func TestGRPCAuthInterceptor(t *testing.T) {
+	interceptor := newTestAuthMiddleware().GRPCUnaryInterceptor()
+
+	call := func(method string, md metadata.MD) (string, error) {
+		ctx := context.Background()
+		if md != nil {
+			ctx = metadata.NewIncomingContext(ctx, md)
+		}
+		var userId string
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			userId, _ = userContext.GetUserID(ctx)
+			if userId != "" && !userContext.IsAuthenticated(ctx) {
+				t.Errorf("user context not marked authenticated")
+			}
+			return nil, nil
+		})
+		return userId, err
+	}
+
+	if _, err := call("/user.UserService/Register", nil); err != nil {
+		t.Errorf("public method err = %v, want nil", err)
+	}
+	if _, err := call("/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", nil); err != nil {
+		t.Errorf("reflection err = %v, want nil", err)
+	}
+
+	_, err := call("/doc.DocService/GetDocList", metadata.Pairs("accept-language", "zh-CN,zh;q=0.9"))
+	if st, _ := status.FromError(err); st.Code() != codes.Unauthenticated || st.Message() != "zh-CN:auth.token.missing" {
+		t.Errorf("missing token = %v, want localized Unauthenticated", err)
+	}
+	_, err = call("/doc.DocService/GetDocList", metadata.Pairs("authorization", "Bearer bad"))
+	if st, _ := status.FromError(err); st.Code() != codes.Unauthenticated || st.Message() != "default:auth.token.invalid" {
+		t.Errorf("invalid token = %v, want Unauthenticated", err)
+	}
+	_, err = call("/doc.DocService/GetDocList", metadata.Pairs("authorization", "Bearer third-party"))
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("third-party token = %v, want PermissionDenied", err)
+	}
+
+	userId, err := call("/doc.DocService/GetDocList", metadata.Pairs("authorization", "Bearer u-1"))
+	if err != nil || userId != "u-1" {
+		t.Errorf("valid token = (%q, %v), want u-1", userId, err)
+	}
+}
+
+func TestGRPCErrorInterceptor(t *testing.T) {
+	interceptor := GRPCErrorUnaryInterceptor(logging.NewLogger("error", "logfmt"), testLocalizer{})
+	info := &grpc.UnaryServerInfo{FullMethod: "/doc.DocService/RenameDoc"}
+
+	call := func(handlerErr error) (*testTransportStream, error) {
+		stream := &testTransportStream{}
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("accept-language", "en-US"))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+		_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, handlerErr
+		})
+		return stream, err
+	}
+
+	stream, err := call(errors.BizWithStatus(1001, "doc.user_doc.errors.rename_failed"))
+	if st, _ := status.FromError(err); st.Code() != codes.FailedPrecondition || st.Message() != "en-US:doc.user_doc.errors.rename_failed" {
+		t.Errorf("biz error = %v, want localized FailedPrecondition", err)
+	}
+	if got := stream.trailer.Get(GRPCBizStatusTrailer); len(got) != 1 || got[0] != "1001" {
+		t.Errorf("biz status trailer = %v, want 1001", got)
+	}
+
+	_, err = call(errors.System(errors.ErrorTypeDatabase, "system.database_error", stderrors.New("timeout")))
+	if st, _ := status.FromError(err); st.Code() != codes.Internal || st.Message() != "en-US:system.database_error" {
+		t.Errorf("system error = %v, want localized Internal", err)
+	}
+
+	_, err = call(status.Error(codes.InvalidArgument, "doc id is empty"))
+	if st, _ := status.FromError(err); st.Code() != codes.InvalidArgument || st.Message() != "doc id is empty" {
+		t.Errorf("status error = %v, want passthrough", err)
+	}
+
+	if _, err = call(nil); err != nil {
+		t.Errorf("nil error = %v", err)
+	}
+}
+
+func TestGRPCLoggingInterceptorSeesUserID(t *testing.T) {
+	logInterceptor := GRPCLoggingUnaryInterceptor(logging.NewLogger("error", "logfmt"))
+	auth := newTestAuthMiddleware().GRPCUnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/note.NoteService/GetWords"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer u-2"))
+
+	var tags grpc_ctxtags.Tags
+	_, err := logInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		tags = grpc_ctxtags.Extract(ctx)
+		return auth(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	})
+	if err != nil {
+		t.Fatalf("call err = %v", err)
+	}
+	if got := tags.Values()[grpcTagUserID]; got != "u-2" {
+		t.Errorf("user id tag = %v, want u-2", got)
+	}
+}
@@ -0,0 +1,515 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gocache "github.com/patrickmn/go-cache"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/internal/database"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/ratelimit"
+	"github.com/yb2020/odoc/pkg/response"
+)
+
+// 限流响应头，参考 IETF RateLimit header fields 草案，gRPC 使用同名小写 metadata
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+// 限流计数存储
+const (
+	RateLimitStoreRedis  = "redis"
+	RateLimitStoreMemory = "memory"
+)
+
+// 限流维度配置值
+const (
+	rateLimitKeyByUser   = "user"
+	rateLimitKeyByAPIKey = "api_key"
+	rateLimitKeyByIP     = "ip"
+)
+
+// MembershipTierResolver 获取用户的会员类型，限流额度按会员类型区分
+type MembershipTierResolver interface {
+	// GetMembershipTier 返回用户的会员类型，如 free、pro，没有会员信息时返回空字符串
+	GetMembershipTier(ctx context.Context, userId string) (string, error)
+}
+
+// rateLimitPolicy 解析后的限流策略，每个额度对应一个限流器，额度相同的会员类型共用
+type rateLimitPolicy struct {
+	config.RateLimitPolicyConfig
+	window   time.Duration
+	limiters map[int64]ratelimit.RateLimiter
+}
+
+// rateLimitDecision 一次限流判断的结果，用于设置响应头
+type rateLimitDecision struct {
+	policy *rateLimitPolicy
+	limit  int64
+	result *ratelimit.RateLimitResult
+}
+
+// RateLimitMiddleware 按配置对 HTTP 接口和 gRPC 方法限流
+// 策略按接口路径或 gRPC 方法匹配，按用户、API Key 或 IP 计数，额度按用户的会员类型区分
+type RateLimitMiddleware struct {
+	cfg            config.RateLimitConfig
+	policies       []*rateLimitPolicy
+	apiKeys        map[string]struct{}
+	trustedProxies []*net.IPNet
+	auth           *AuthMiddleware
+	tierResolver   MembershipTierResolver
+	tierCache      *gocache.Cache
+	localizer      i18n.Localizer
+	logger         logging.Logger
+}
+
+// NewRateLimitMiddleware 创建限流中间件
+// redis 为空或配置为 memory 时在进程内计数；auth 用于在路由组认证之前解析用户，tierResolver 为空时所有用户使用默认额度
+func NewRateLimitMiddleware(cfg config.Config, logger logging.Logger, localizer i18n.Localizer, redis database.RedisClient,
+	auth *AuthMiddleware, tierResolver MembershipTierResolver) (*RateLimitMiddleware, error) {
+	rateLimitConfig := cfg.RateLimit
+	if rateLimitConfig.KeyPrefix == "" {
+		rateLimitConfig.KeyPrefix = "ratelimit"
+	}
+	if rateLimitConfig.APIKeyHeader == "" {
+		rateLimitConfig.APIKeyHeader = "X-API-Key"
+	}
+	if rateLimitConfig.TierCacheTTL <= 0 {
+		rateLimitConfig.TierCacheTTL = 60
+	}
+	if rateLimitConfig.Store != RateLimitStoreMemory && redis == nil {
+		logger.Warn("msg", "Redis不可用，接口限流改为进程内计数")
+		rateLimitConfig.Store = RateLimitStoreMemory
+	}
+
+	limiterService := ratelimit.NewRateLimiterService(redis, logger)
+	policies := make([]*rateLimitPolicy, 0, len(rateLimitConfig.Policies))
+	for _, policyConfig := range rateLimitConfig.Policies {
+		policy, err := newRateLimitPolicy(rateLimitConfig, policyConfig, limiterService, logger)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	// 只保存API Key的摘要，与限流键一致
+	apiKeys := make(map[string]struct{}, len(rateLimitConfig.APIKeys))
+	for _, apiKey := range rateLimitConfig.APIKeys {
+		if apiKey != "" {
+			apiKeys[apiKeyDigest(apiKey)] = struct{}{}
+		}
+	}
+	trustedProxies, err := parseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	tierCacheTTL := time.Duration(rateLimitConfig.TierCacheTTL) * time.Second
+	return &RateLimitMiddleware{
+		cfg:            rateLimitConfig,
+		policies:       policies,
+		apiKeys:        apiKeys,
+		trustedProxies: trustedProxies,
+		auth:           auth,
+		tierResolver:   tierResolver,
+		tierCache:      gocache.New(tierCacheTTL, 10*time.Minute),
+		localizer:      localizer,
+		logger:         logger,
+	}, nil
+}
+
+// newRateLimitPolicy 校验策略配置，并为默认额度和每个会员类型的额度创建限流器
+func newRateLimitPolicy(cfg config.RateLimitConfig, policyConfig config.RateLimitPolicyConfig,
+	limiterService *ratelimit.RateLimiterService, logger logging.Logger) (*rateLimitPolicy, error) {
+	if policyConfig.Name == "" {
+		return nil, fmt.Errorf("限流策略缺少名称")
+	}
+	if policyConfig.KeyBy == "" {
+		policyConfig.KeyBy = rateLimitKeyByUser
+	}
+	dimension, ok := rateLimitDimension(policyConfig.KeyBy)
+	if !ok {
+		return nil, fmt.Errorf("限流策略 %s 的限流维度 %s 不支持", policyConfig.Name, policyConfig.KeyBy)
+	}
+	if policyConfig.Type == "" {
+		policyConfig.Type = string(ratelimit.CounterLimiterType)
+	}
+	timeUnit := ratelimit.TimeUnit(policyConfig.TimeUnit)
+	switch timeUnit {
+	case ratelimit.Second, ratelimit.Minute, ratelimit.Hour, ratelimit.Day:
+	case "":
+		timeUnit = ratelimit.Minute
+	default:
+		return nil, fmt.Errorf("限流策略 %s 的时间单位 %s 不支持", policyConfig.Name, policyConfig.TimeUnit)
+	}
+	if policyConfig.Cost <= 0 {
+		policyConfig.Cost = 1
+	}
+
+	// 会员类型统一按小写匹配
+	tiers := make(map[string]int64, len(policyConfig.Tiers))
+	for tier, limit := range policyConfig.Tiers {
+		tiers[strings.ToLower(tier)] = limit
+	}
+	policyConfig.Tiers = tiers
+
+	policy := &rateLimitPolicy{
+		RateLimitPolicyConfig: policyConfig,
+		window:                ratelimit.GetTimeUnitDuration(timeUnit),
+		limiters:              make(map[int64]ratelimit.RateLimiter),
+	}
+
+	// 计数器限流器的窗口即键的过期时间，其他限流器需要保留上一个窗口的数据
+	expireTime := int64(policy.window / time.Second)
+	if policyConfig.Type != string(ratelimit.CounterLimiterType) {
+		expireTime *= 2
+	}
+
+	limits := []int64{policyConfig.Limit}
+	for _, limit := range tiers {
+		limits = append(limits, limit)
+	}
+	for _, limit := range limits {
+		if limit < 0 || policy.limiters[limit] != nil {
+			continue
+		}
+		limiterConfig := ratelimit.LimiterConfig{
+			Type:       ratelimit.LimiterType(policyConfig.Type),
+			KeyPrefix:  cfg.KeyPrefix + ":" + policyConfig.Name,
+			MaxRate:    limit,
+			TimeUnit:   timeUnit,
+			Dimension:  dimension,
+			ExpireTime: expireTime,
+		}
+		if cfg.Store == RateLimitStoreMemory {
+			policy.limiters[limit] = ratelimit.NewMemoryLimiter(logger, limiterConfig)
+			continue
+		}
+		limiter, err := limiterService.CreateLimiter(limiterConfig)
+		if err != nil {
+			return nil, fmt.Errorf("限流策略 %s 创建限流器失败: %w", policyConfig.Name, err)
+		}
+		policy.limiters[limit] = limiter
+	}
+	return policy, nil
+}
+
+func rateLimitDimension(keyBy string) (ratelimit.LimiterDimension, bool) {
+	switch keyBy {
+	case rateLimitKeyByUser:
+		return ratelimit.User, true
+	case rateLimitKeyByAPIKey:
+		return ratelimit.APIKey, true
+	case rateLimitKeyByIP:
+		return ratelimit.IP, true
+	}
+	return "", false
+}
+
+// matchHTTP 返回第一条匹配接口路径和方法的策略
+func (m *RateLimitMiddleware) matchHTTP(method, path string) *rateLimitPolicy {
+	for _, policy := range m.policies {
+		if len(policy.Methods) > 0 && !containsFold(policy.Methods, method) {
+			continue
+		}
+		for _, prefix := range policy.Paths {
+			if strings.HasPrefix(path, prefix) {
+				return policy
+			}
+		}
+	}
+	return nil
+}
+
+// matchGRPC 返回第一条匹配 gRPC 方法的策略
+func (m *RateLimitMiddleware) matchGRPC(fullMethod string) *rateLimitPolicy {
+	for _, policy := range m.policies {
+		for _, prefix := range policy.GRPCMethods {
+			if strings.HasPrefix(fullMethod, prefix) {
+				return policy
+			}
+		}
+	}
+	return nil
+}
+
+// GinMiddleware HTTP 限流中间件，需注册为全局中间件
+// 全局中间件先于路由组的认证中间件执行，按用户限流时通过认证中间件解析令牌获取用户
+func (m *RateLimitMiddleware) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy := m.matchHTTP(c.Request.Method, c.Request.URL.Path)
+		if policy == nil {
+			c.Next()
+			return
+		}
+
+		var userId, apiKey string
+		switch policy.KeyBy {
+		case rateLimitKeyByUser:
+			if m.auth != nil {
+				userId = m.auth.ResolveUserID(c)
+			}
+		case rateLimitKeyByAPIKey:
+			apiKey = c.GetHeader(m.cfg.APIKeyHeader)
+		}
+
+		decision := m.allow(c.Request.Context(), policy, userId, apiKey, c.ClientIP())
+		if decision == nil {
+			c.Next()
+			return
+		}
+		for key, value := range decision.headers() {
+			c.Header(key, value)
+		}
+		if !decision.result.Allowed {
+			msg := "请求过多"
+			if m.localizer != nil {
+				msg = m.localizer.Localize("request.too_many", c)
+			}
+			response.SystemErrorNoData(c, response.Code_TooManyRequests, response.Status_TooManyRequests, msg)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// GRPCUnaryInterceptor gRPC 一元限流拦截器，需放在认证拦截器之后以获取用户
+func (m *RateLimitMiddleware) GRPCUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		decision := m.allowGRPC(ctx, info.FullMethod)
+		if decision == nil {
+			return handler(ctx, req)
+		}
+		_ = grpc.SetHeader(ctx, decision.metadata())
+		if !decision.result.Allowed {
+			return nil, m.exhausted(ctx)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GRPCStreamInterceptor gRPC 流式限流拦截器，每个流只在建立时计数一次
+func (m *RateLimitMiddleware) GRPCStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		decision := m.allowGRPC(stream.Context(), info.FullMethod)
+		if decision == nil {
+			return handler(srv, stream)
+		}
+		_ = stream.SetHeader(decision.metadata())
+		if !decision.result.Allowed {
+			return m.exhausted(stream.Context())
+		}
+		return handler(srv, stream)
+	}
+}
+
+func (m *RateLimitMiddleware) allowGRPC(ctx context.Context, fullMethod string) *rateLimitDecision {
+	policy := m.matchGRPC(fullMethod)
+	if policy == nil {
+		return nil
+	}
+
+	var userId, apiKey string
+	switch policy.KeyBy {
+	case rateLimitKeyByUser:
+		userId, _ = userContext.GetUserID(ctx)
+	case rateLimitKeyByAPIKey:
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(m.cfg.APIKeyHeader); len(values) > 0 {
+				apiKey = values[0]
+			}
+		}
+	}
+	return m.allow(ctx, policy, userId, apiKey, m.grpcClientIP(ctx))
+}
+
+func (m *RateLimitMiddleware) exhausted(ctx context.Context) error {
+	return status.Error(codes.ResourceExhausted, localizeGRPC(ctx, m.localizer, "request.too_many", "请求过多"))
+}
+
+// allow 按策略计数，策略对当前调用方不限流时返回 nil
+// 取不到用户或 API Key 时按 IP 计数，未登记的 API Key 同样按 IP 计数，避免随意更换 Key 绕过限流
+// API Key 以摘要作为限流键，避免明文写入 Redis
+func (m *RateLimitMiddleware) allow(ctx context.Context, policy *rateLimitPolicy, userId, apiKey, clientIP string) *rateLimitDecision {
+	limit := policy.Limit
+	dimension := ratelimit.IP
+	id := clientIP
+	switch {
+	case userId != "":
+		dimension, id = ratelimit.User, userId
+		if tierLimit, ok := policy.Tiers[m.membershipTier(ctx, userId)]; ok {
+			limit = tierLimit
+		}
+	case apiKey != "":
+		digest := apiKeyDigest(apiKey)
+		if _, ok := m.apiKeys[digest]; ok {
+			dimension, id = ratelimit.APIKey, digest
+		}
+	}
+	if limit < 0 {
+		return nil
+	}
+
+	// 计数器限流器把包含冒号的键当作完整的限流键，IPv6 地址中的冒号需要替换
+	key := string(dimension) + "." + strings.ReplaceAll(id, ":", "_")
+	result, err := policy.limiters[limit].Allow(ctx, key, policy.Cost)
+	if err != nil {
+		m.logger.Error("msg", "接口限流计数失败，默认放行", "policy", policy.Name, "error", err.Error())
+		return nil
+	}
+	if !result.Allowed {
+		m.logger.Warn("msg", "请求被限流", "policy", policy.Name, "dimension", dimension, "id", id, "limit", limit)
+	}
+	return &rateLimitDecision{policy: policy, limit: limit, result: result}
+}
+
+// membershipTier 获取用户的会员类型并在本地缓存，查询失败时使用默认额度
+func (m *RateLimitMiddleware) membershipTier(ctx context.Context, userId string) string {
+	if m.tierResolver == nil {
+		return ""
+	}
+	if tier, ok := m.tierCache.Get(userId); ok {
+		return tier.(string)
+	}
+	tier, err := m.tierResolver.GetMembershipTier(ctx, userId)
+	if err != nil {
+		m.logger.Warn("msg", "获取用户会员类型失败，使用默认限流额度", "userId", userId, "error", err.Error())
+		return ""
+	}
+	tier = strings.ToLower(tier)
+	m.tierCache.SetDefault(userId, tier)
+	return tier
+}
+
+// headers 生成限流响应头，RateLimit-Reset 和 Retry-After 均为秒数
+// 被拒绝时按限流器给出的等待时间计算，放行时以完整窗口作为额度恢复时间的上限
+func (d *rateLimitDecision) headers() map[string]string {
+	windowSeconds := int64(d.policy.window / time.Second)
+	reset := windowSeconds
+	if !d.result.Allowed {
+		reset = (d.result.RetryAfter + 999) / 1000
+		if reset < 1 {
+			reset = 1
+		}
+	}
+	remaining := d.result.Remaining
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	headers := map[string]string{
+		HeaderRateLimitLimit:     strconv.FormatInt(d.limit, 10),
+		HeaderRateLimitRemaining: strconv.FormatInt(remaining, 10),
+		HeaderRateLimitReset:     strconv.FormatInt(reset, 10),
+		HeaderRateLimitPolicy:    fmt.Sprintf("%d;w=%d", d.limit, windowSeconds),
+	}
+	if !d.result.Allowed {
+		headers[HeaderRetryAfter] = strconv.FormatInt(reset, 10)
+	}
+	return headers
+}
+
+func (d *rateLimitDecision) metadata() metadata.MD {
+	md := metadata.MD{}
+	for key, value := range d.headers() {
+		md.Set(key, value)
+	}
+	return md
+}
+
+func apiKeyDigest(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseTrustedProxies 解析可信代理配置，单个IP按完整掩码处理
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := net.IPv6len * 8
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, net.IPv4len*8
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func (m *RateLimitMiddleware) isTrustedProxy(ip net.IP) bool {
+	for _, network := range m.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// grpcClientIP 获取 gRPC 调用方地址
+// 只有连接来自可信代理时才采用 x-forwarded-for，从右向左跳过可信代理，取第一个不可信的地址，与 gin 的 ClientIP 一致
+func (m *RateLimitMiddleware) grpcClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	remoteIP, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		remoteIP = p.Addr.String()
+	}
+	if ip := net.ParseIP(remoteIP); ip == nil || !m.isTrustedProxy(ip) {
+		return remoteIP
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return remoteIP
+	}
+	var hops []string
+	for _, value := range md.Get("x-forwarded-for") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		ip := net.ParseIP(hop)
+		if ip == nil {
+			break
+		}
+		if i == 0 || !m.isTrustedProxy(ip) {
+			return hop
+		}
+	}
+	return remoteIP
+}
@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/yb2020/odoc/config"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+// testTierResolver 按用户ID返回会员类型
+type testTierResolver map[string]string
+
+func (r testTierResolver) GetMembershipTier(ctx context.Context, userId string) (string, error) {
+	return r[userId], nil
+}
+
+func newTestRateLimitMiddleware(t *testing.T, policies ...config.RateLimitPolicyConfig) *RateLimitMiddleware {
+	var cfg config.Config
+	cfg.RateLimit.Store = RateLimitStoreMemory
+	cfg.RateLimit.Policies = policies
+	tiers := testTierResolver{"u-free": "free", "u-pro": "PRO"}
+	m, err := NewRateLimitMiddleware(cfg, logging.NewLogger("error", "logfmt"), testLocalizer{}, nil, newTestAuthMiddleware(), tiers)
+	if err != nil {
+		t.Fatalf("NewRateLimitMiddleware err = %v", err)
+	}
+	return m
+}
+
+func TestRateLimitGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newTestRateLimitMiddleware(t, config.RateLimitPolicyConfig{
+		Name:     "ai",
+		Paths:    []string{"/api/ai/"},
+		Methods:  []string{"POST"},
+		KeyBy:    "user",
+		TimeUnit: "m",
+		Limit:    1,
+		Tiers:    map[string]int64{"free": 1, "pro": 2},
+	})
+	r := gin.New()
+	r.Use(m.GinMiddleware())
+	r.POST("/api/ai/chat", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/api/ai/history", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	call := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 专业版用户额度为2
+	for i := 0; i < 2; i++ {
+		if w := call("POST", "/api/ai/chat", "u-pro"); w.Code != http.StatusOK || w.Header().Get(HeaderRateLimitLimit) != "2" {
+			t.Fatalf("pro call %d = (%d, limit %q), want 200 with limit 2", i, w.Code, w.Header().Get(HeaderRateLimitLimit))
+		}
+	}
+	w := call("POST", "/api/ai/chat", "u-pro")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("pro over limit = %d, want 429", w.Code)
+	}
+	if w.Header().Get(HeaderRetryAfter) == "" || w.Header().Get(HeaderRateLimitRemaining) != "0" {
+		t.Errorf("429 headers = %v, want Retry-After and zero remaining", w.Header())
+	}
+
+	// 免费用户额度为1，计数与其他用户互不影响
+	if w := call("POST", "/api/ai/chat", "u-free"); w.Code != http.StatusOK || w.Header().Get(HeaderRateLimitPolicy) != "1;w=60" {
+		t.Errorf("free first call = (%d, policy %q), want 200 with 1;w=60", w.Code, w.Header().Get(HeaderRateLimitPolicy))
+	}
+	if w := call("POST", "/api/ai/chat", "u-free"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("free second call = %d, want 429", w.Code)
+	}
+
+	// 匿名请求按IP使用默认额度
+	if w := call("POST", "/api/ai/chat", ""); w.Code != http.StatusOK {
+		t.Errorf("anonymous first call = %d, want 200", w.Code)
+	}
+	if w := call("POST", "/api/ai/chat", "bad"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("invalid token call = %d, want 429 by ip", w.Code)
+	}
+
+	// 方法不匹配的接口不限流
+	if w := call("GET", "/api/ai/history", "u-free"); w.Code != http.StatusOK || w.Header().Get(HeaderRateLimitLimit) != "" {
+		t.Errorf("unmatched call = (%d, %v), want 200 without rate limit headers", w.Code, w.Header())
+	}
+}
+
+func TestRateLimitGRPCInterceptor(t *testing.T) {
+	m := newTestRateLimitMiddleware(t, config.RateLimitPolicyConfig{
+		Name:        "grpc",
+		GRPCMethods: []string{"/doc.DocService/"},
+		KeyBy:       "user",
+		TimeUnit:    "s",
+		Limit:       1,
+		Tiers:       map[string]int64{"pro": -1},
+	})
+	interceptor := m.GRPCUnaryInterceptor()
+
+	call := func(method, userId string) (*testTransportStream, error) {
+		stream := &testTransportStream{}
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+		ctx = userContext.NewUserContext().SetUserID(userId).SetAuthenticated(true).ToContext(ctx)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return stream, err
+	}
+
+	stream, err := call("/doc.DocService/GetDocList", "u-free")
+	if err != nil || len(stream.header.Get("ratelimit-remaining")) != 1 || stream.header.Get("ratelimit-remaining")[0] != "0" {
+		t.Fatalf("first call = (%v, %v), want ok with ratelimit-remaining 0", stream.header, err)
+	}
+	stream, err = call("/doc.DocService/GetDocList", "u-free")
+	if status.Code(err) != codes.ResourceExhausted || len(stream.header.Get("retry-after")) != 1 {
+		t.Errorf("second call = (%v, %v), want ResourceExhausted with retry-after", stream.header, err)
+	}
+
+	// 不限流的会员类型和未匹配的方法均不计数
+	for i := 0; i < 3; i++ {
+		if _, err := call("/doc.DocService/GetDocList", "u-pro"); err != nil {
+			t.Errorf("pro call %d err = %v, want nil", i, err)
+		}
+	}
+	if stream, err := call("/note.NoteService/GetWords", "u-free"); err != nil || stream.header != nil {
+		t.Errorf("unmatched call = (%v, %v), want no limit", stream.header, err)
+	}
+}
+
+func TestNewRateLimitMiddlewareRejectsInvalidPolicy(t *testing.T) {
+	var cfg config.Config
+	cfg.RateLimit.Store = RateLimitStoreMemory
+	cfg.RateLimit.Policies = []config.RateLimitPolicyConfig{{Name: "bad", KeyBy: "session"}}
+	if _, err := NewRateLimitMiddleware(cfg, logging.NewLogger("error", "logfmt"), nil, nil, nil, nil); err == nil {
+		t.Error("invalid key-by err = nil, want error")
+	}
+
+	cfg.RateLimit.Policies = nil
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/33"}
+	if _, err := NewRateLimitMiddleware(cfg, logging.NewLogger("error", "logfmt"), nil, nil, nil, nil); err == nil {
+		t.Error("invalid trusted proxy err = nil, want error")
+	}
+}
+
+func TestRateLimitAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.RateLimit.Store = RateLimitStoreMemory
+	cfg.RateLimit.APIKeys = []string{"key-a", "key-b"}
+	cfg.RateLimit.Policies = []config.RateLimitPolicyConfig{{Name: "open", Paths: []string{"/api/open/"}, KeyBy: "api_key", TimeUnit: "m", Limit: 1}}
+	m, err := NewRateLimitMiddleware(cfg, logging.NewLogger("error", "logfmt"), testLocalizer{}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRateLimitMiddleware err = %v", err)
+	}
+	r := gin.New()
+	r.Use(m.GinMiddleware())
+	r.GET("/api/open/search", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		apiKey string
+		want   int
+	}{
+		{name: "registered key", apiKey: "key-a", want: http.StatusOK},
+		{name: "registered key over limit", apiKey: "key-a", want: http.StatusTooManyRequests},
+		{name: "another registered key", apiKey: "key-b", want: http.StatusOK},
+		// 未登记的Key按IP计数，更换Key不能获得新的额度
+		{name: "unknown key", apiKey: "random-1", want: http.StatusOK},
+		{name: "another unknown key", apiKey: "random-2", want: http.StatusTooManyRequests},
+		{name: "no key", want: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/open/search", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if tt.apiKey != "" {
+			req.Header.Set("X-API-Key", tt.apiKey)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestRateLimitGRPCClientIP(t *testing.T) {
+	var cfg config.Config
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.1"}
+	m, err := NewRateLimitMiddleware(cfg, logging.NewLogger("error", "logfmt"), nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRateLimitMiddleware err = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		peer string
+		xff  string
+		want string
+	}{
+		{name: "direct", peer: "203.0.113.9", want: "203.0.113.9"},
+		{name: "untrusted peer forging header", peer: "203.0.113.9", xff: "1.2.3.4", want: "203.0.113.9"},
+		{name: "trusted proxy", peer: "10.0.0.2", xff: "1.2.3.4", want: "1.2.3.4"},
+		// 客户端自带的 X-Forwarded-For 位于左侧，取最右侧的不可信地址
+		{name: "forged prefix", peer: "10.0.0.2", xff: "1.2.3.4, 198.51.100.7", want: "198.51.100.7"},
+		{name: "proxy chain", peer: "192.168.1.1", xff: "198.51.100.7, 10.0.0.3", want: "198.51.100.7"},
+		{name: "trusted proxy without header", peer: "10.0.0.2", want: "10.0.0.2"},
+		{name: "invalid hop", peer: "10.0.0.2", xff: "not-an-ip", want: "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(tt.peer), Port: 5000}})
+			if tt.xff != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", tt.xff))
+			}
+			if got := m.grpcClientIP(ctx); got != tt.want {
+				t.Errorf("grpcClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -238,9 +238,11 @@ func (c *RocketMQConsumer) receiveMessages(handler func(context.Context, mqi.Mes
 
 				// 创建带超时的上下文
 				ctx, cancel := context.WithTimeout(context.Background(), time.Duration(consumeTimeout)*time.Millisecond)
+				span, ctx := rocketmq.StartConsumerSpan(ctx, message)
 
 				// 调用处理函数
 				err := handler(ctx, message)
+				rocketmq.FinishSpan(span, err)
 
 				// 无论成功失败都取消上下文
 				cancel()
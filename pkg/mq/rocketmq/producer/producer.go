@@ -75,6 +75,10 @@ func NewRocketMQProducer(config *config.Config, logger logging.Logger) (*RocketM
 	if config.RocketMQ.Topic.Event.Doc2DifyIntegrationEvent.Name != "" {
 		topics = append(topics, config.RocketMQ.Topic.Event.Doc2DifyIntegrationEvent.Name)
 	}
+	// 事件采集转发到消息队列时使用的主题
+	if config.EventTracker.Sink == "mq" && config.EventTracker.Topic != "" {
+		topics = append(topics, config.EventTracker.Topic)
+	}
 
 	// 创建生产者
 	// 注意：RocketMQ V5 客户端使用具体的选项函数，而不是 Option 类型
@@ -205,6 +209,16 @@ func (p *RocketMQProducer) Start() error {
 	return nil
 }
 
+// Check 健康检查，生产者未启动或已关闭时返回错误
+func (p *RocketMQProducer) Check(ctx context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.started {
+		return errors.New("producer not started")
+	}
+	return nil
+}
+
 // Shutdown 关闭生产者 (V5版本)
 func (p *RocketMQProducer) Shutdown() error {
 	p.mutex.Lock()
@@ -260,9 +274,11 @@ func (p *RocketMQProducer) SendSync(ctx context.Context, message mqi.Message) (s
 	}
 
 	msg := customRocketMQ.ToRocketMQMessage(message)
+	span := customRocketMQ.StartProducerSpan(ctx, msg)
 
 	// V5 版本的 Send 方法返回的是一个切片，可能包含多个结果
 	results, err := p.producer.Send(ctx, msg)
+	customRocketMQ.FinishSpan(span, err)
 	if err != nil {
 		p.logger.Error("failed to send message synchronously",
 			"topic", message.GetTopic(),
@@ -0,0 +1,56 @@
+package rocketmq
+
+import (
+	"context"
+
+	v5 "github.com/apache/rocketmq-clients/golang/v5"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	mq_interface "github.com/yb2020/odoc/pkg/mq/interface"
+)
+
+// StartProducerSpan 创建发送消息的 span，并把跟踪上下文写入消息属性，消费端据此延续同一条 trace
+func StartProducerSpan(ctx context.Context, msg *v5.Message) opentracing.Span {
+	tracer := opentracing.GlobalTracer()
+	var opts []opentracing.StartSpanOption
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+	opts = append(opts, ext.SpanKindProducer)
+	span := tracer.StartSpan("RocketMQ Send "+msg.Topic, opts...)
+	ext.Component.Set(span, "rocketmq")
+	ext.MessageBusDestination.Set(span, msg.Topic)
+
+	carrier := opentracing.TextMapCarrier{}
+	if err := tracer.Inject(span.Context(), opentracing.TextMap, carrier); err == nil {
+		for k, v := range carrier {
+			msg.AddProperty(k, v)
+		}
+	}
+	return span
+}
+
+// StartConsumerSpan 从消息属性中恢复跟踪上下文，创建消费消息的 span 并放入返回的上下文
+func StartConsumerSpan(ctx context.Context, message mq_interface.Message) (opentracing.Span, context.Context) {
+	tracer := opentracing.GlobalTracer()
+	opts := []opentracing.StartSpanOption{ext.SpanKindConsumer}
+	if props := message.GetProperties(); len(props) > 0 {
+		if spanCtx, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier(props)); err == nil {
+			opts = append(opts, opentracing.FollowsFrom(spanCtx))
+		}
+	}
+	span := tracer.StartSpan("RocketMQ Consume "+message.GetTopic(), opts...)
+	ext.Component.Set(span, "rocketmq")
+	ext.MessageBusDestination.Set(span, message.GetTopic())
+	span.SetTag("messaging.message_id", message.GetMessageId())
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+// FinishSpan 结束消息 span，失败时记录错误
+func FinishSpan(span opentracing.Span, err error) {
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.SetTag("error.message", err.Error())
+	}
+	span.Finish()
+}
@@ -73,6 +73,9 @@ type StorageInterface interface {
 	// metadata: 用户自定义元数据，会在回调时返回
 	GeneratePreSignedUpload(ctx context.Context, bucketType, objectName, contentType string, fileSize int64, metadata map[string]string) (*PreSignedUploadResponse, error)
 
+	// Ping 检查存储服务和已配置的桶是否可访问
+	Ping(ctx context.Context) error
+
 	// Close 关闭存储客户端
 	Close() error
 }
@@ -450,6 +450,18 @@ func (s *S3Storage) getBucketName(bucket string) (string, error) {
 	return "", errors.Biz("bucket not found!")
 }
 
+// Ping 依次检查已配置的桶是否可访问
+func (s *S3Storage) Ping(ctx context.Context) error {
+	for _, bucketConfig := range s.buckets {
+		if _, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+			Bucket: aws.String(bucketConfig.Name),
+		}); err != nil {
+			return fmt.Errorf("bucket %s: %w", bucketConfig.Name, err)
+		}
+	}
+	return nil
+}
+
 // Close 关闭存储客户端
 func (s *S3Storage) Close() error {
 	return nil
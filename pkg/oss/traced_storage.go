@@ -0,0 +1,76 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// tracedStorage 为对象存储的每次调用创建 span
+type tracedStorage struct {
+	StorageInterface
+	tracer opentracing.Tracer
+}
+
+// NewTracedStorage 包装存储接口，记录桶、对象名和错误；GetBucketConfig 和 Close 不访问远端，不记录
+func NewTracedStorage(storage StorageInterface, tracer opentracing.Tracer) StorageInterface {
+	return &tracedStorage{StorageInterface: storage, tracer: tracer}
+}
+
+func (s *tracedStorage) startSpan(ctx context.Context, operation, bucket, objectName string) (opentracing.Span, context.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "S3 "+operation)
+	ext.Component.Set(span, "s3")
+	ext.SpanKindRPCClient.Set(span)
+	span.SetTag("s3.bucket", bucket)
+	span.SetTag("s3.key", objectName)
+	return span, ctx
+}
+
+func finishSpan(span opentracing.Span, err error) {
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.SetTag("error.message", err.Error())
+	}
+	span.Finish()
+}
+
+func (s *tracedStorage) Upload(ctx context.Context, bucket, objectName string, reader io.Reader, objectSize int64, opts map[string]string, userMetadata map[string]string) (err error) {
+	span, ctx := s.startSpan(ctx, "Upload", bucket, objectName)
+	span.SetTag("s3.size", objectSize)
+	defer func() { finishSpan(span, err) }()
+	return s.StorageInterface.Upload(ctx, bucket, objectName, reader, objectSize, opts, userMetadata)
+}
+
+func (s *tracedStorage) Download(ctx context.Context, bucket, objectName string) (_ io.Reader, err error) {
+	span, ctx := s.startSpan(ctx, "Download", bucket, objectName)
+	defer func() { finishSpan(span, err) }()
+	return s.StorageInterface.Download(ctx, bucket, objectName)
+}
+
+func (s *tracedStorage) Delete(ctx context.Context, bucket, objectName string) (err error) {
+	span, ctx := s.startSpan(ctx, "Delete", bucket, objectName)
+	defer func() { finishSpan(span, err) }()
+	return s.StorageInterface.Delete(ctx, bucket, objectName)
+}
+
+func (s *tracedStorage) GetObjectURL(ctx context.Context, bucket, objectName string, expires time.Duration) (_ string, err error) {
+	span, ctx := s.startSpan(ctx, "GetObjectURL", bucket, objectName)
+	defer func() { finishSpan(span, err) }()
+	return s.StorageInterface.GetObjectURL(ctx, bucket, objectName, expires)
+}
+
+func (s *tracedStorage) GetPermanentURL(ctx context.Context, bucket, objectName string) (_ string, err error) {
+	span, ctx := s.startSpan(ctx, "GetPermanentURL", bucket, objectName)
+	defer func() { finishSpan(span, err) }()
+	return s.StorageInterface.GetPermanentURL(ctx, bucket, objectName)
+}
+
+func (s *tracedStorage) GeneratePreSignedUpload(ctx context.Context, bucketType, objectName, contentType string, fileSize int64, metadata map[string]string) (_ *PreSignedUploadResponse, err error) {
+	span, ctx := s.startSpan(ctx, "GeneratePreSignedUpload", bucketType, objectName)
+	span.SetTag("s3.size", fileSize)
+	defer func() { finishSpan(span, err) }()
+	return s.StorageInterface.GeneratePreSignedUpload(ctx, bucketType, objectName, contentType, fileSize, metadata)
+}
@@ -0,0 +1,198 @@
+// Package pdfrender 使用 unipdf 的纯Go渲染器将PDF页面渲染为图片，用于生成页面缩略图和图表截图
+package pdfrender
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	unipdf "github.com/unidoc/unipdf/v3/model"
+	"github.com/unidoc/unipdf/v3/render"
+)
+
+// Document 已打开的PDF文档
+type Document struct {
+	reader    *unipdf.PdfReader
+	pageCount int
+}
+
+// Open 从PDF文件内容打开文档
+func Open(data []byte) (*Document, error) {
+	reader, err := unipdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("无法创建PDF读取器: %w", err)
+	}
+	pageCount, err := reader.GetNumPages()
+	if err != nil {
+		return nil, fmt.Errorf("无法获取PDF页数: %w", err)
+	}
+	return &Document{reader: reader, pageCount: pageCount}, nil
+}
+
+// PageCount 文档页数
+func (d *Document) PageCount() int {
+	return d.pageCount
+}
+
+// RenderPage 将指定页（从1开始）渲染为指定宽度的图片，高度按页面比例计算
+func (d *Document) RenderPage(pageNum int, width int) (image.Image, error) {
+	if pageNum < 1 || pageNum > d.pageCount {
+		return nil, fmt.Errorf("页码超出范围: %d/%d", pageNum, d.pageCount)
+	}
+	if width <= 0 {
+		return nil, fmt.Errorf("渲染宽度无效: %d", width)
+	}
+	page, err := d.reader.GetPage(pageNum)
+	if err != nil {
+		return nil, fmt.Errorf("无法获取第%d页: %w", pageNum, err)
+	}
+	device := render.NewImageDevice()
+	device.OutputWidth = width
+	img, err := device.Render(page)
+	if err != nil {
+		return nil, fmt.Errorf("无法渲染第%d页: %w", pageNum, err)
+	}
+	return img, nil
+}
+
+// Scale 按宽度等比缩放图片，图片本身不大于目标宽度时原样返回
+func Scale(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if width <= 0 || bounds.Dx() <= width {
+		return img
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	scaleInto(dst, dst.Bounds(), img)
+	return dst
+}
+
+// scaleInto 使用双线性插值将图片缩放绘制到目标区域，源图片的透明像素与目标按alpha混合
+func scaleInto(dst *image.RGBA, target image.Rectangle, src image.Image) {
+	srcBounds := src.Bounds()
+	if target.Empty() || srcBounds.Empty() {
+		return
+	}
+	clipped := target.Intersect(dst.Bounds())
+	ratioX := float64(srcBounds.Dx()) / float64(target.Dx())
+	ratioY := float64(srcBounds.Dy()) / float64(target.Dy())
+	for y := clipped.Min.Y; y < clipped.Max.Y; y++ {
+		sy := (float64(y-target.Min.Y)+0.5)*ratioY - 0.5
+		for x := clipped.Min.X; x < clipped.Max.X; x++ {
+			sx := (float64(x-target.Min.X)+0.5)*ratioX - 0.5
+			r, g, b, a := bilinear(src, srcBounds, sx, sy)
+			if a == 0 {
+				continue
+			}
+			if a < 0xffff {
+				dr, dg, db, da := dst.At(x, y).RGBA()
+				inv := 0xffff - a
+				r += dr * inv / 0xffff
+				g += dg * inv / 0xffff
+				b += db * inv / 0xffff
+				a += da * inv / 0xffff
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)})
+		}
+	}
+}
+
+// bilinear 在源图片坐标 (x, y) 处双线性采样，返回预乘alpha的颜色分量
+func bilinear(src image.Image, bounds image.Rectangle, x, y float64) (uint32, uint32, uint32, uint32) {
+	clamp := func(v, lo, hi int) int {
+		if v < lo {
+			return lo
+		}
+		if v > hi {
+			return hi
+		}
+		return v
+	}
+	x0 := int(x)
+	if x < 0 {
+		x0 = -1
+	}
+	y0 := int(y)
+	if y < 0 {
+		y0 = -1
+	}
+	fx, fy := x-float64(x0), y-float64(y0)
+	maxX, maxY := bounds.Dx()-1, bounds.Dy()-1
+	var sum [4]float64
+	for _, p := range [4]struct {
+		dx, dy int
+		w      float64
+	}{
+		{0, 0, (1 - fx) * (1 - fy)},
+		{1, 0, fx * (1 - fy)},
+		{0, 1, (1 - fx) * fy},
+		{1, 1, fx * fy},
+	} {
+		px := bounds.Min.X + clamp(x0+p.dx, 0, maxX)
+		py := bounds.Min.Y + clamp(y0+p.dy, 0, maxY)
+		r, g, b, a := src.At(px, py).RGBA()
+		sum[0] += float64(r) * p.w
+		sum[1] += float64(g) * p.w
+		sum[2] += float64(b) * p.w
+		sum[3] += float64(a) * p.w
+	}
+	return uint32(sum[0] + 0.5), uint32(sum[1] + 0.5), uint32(sum[2] + 0.5), uint32(sum[3] + 0.5)
+}
+
+// Crop 按解析结果中的边界框裁剪页面图片
+// 边界框以页面左上角为原点，originWidth/originHeight 为解析时的页面尺寸，为0时使用图片尺寸
+func Crop(img image.Image, x0, y0, x1, y1, originWidth, originHeight float64) (image.Image, error) {
+	bounds := img.Bounds()
+	if originWidth <= 0 {
+		originWidth = float64(bounds.Dx())
+	}
+	if originHeight <= 0 {
+		originHeight = float64(bounds.Dy())
+	}
+	scaleX := float64(bounds.Dx()) / originWidth
+	scaleY := float64(bounds.Dy()) / originHeight
+	rect := image.Rect(
+		bounds.Min.X+int(x0*scaleX),
+		bounds.Min.Y+int(y0*scaleY),
+		bounds.Min.X+int(x1*scaleX+0.5),
+		bounds.Min.Y+int(y1*scaleY+0.5),
+	).Intersect(bounds)
+	if rect.Empty() {
+		return nil, fmt.Errorf("裁剪区域无效: (%.1f,%.1f)-(%.1f,%.1f)", x0, y0, x1, y1)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+	return dst, nil
+}
+
+// EncodeJPEG 将图片编码为JPEG，透明区域以白色填充
+func EncodeJPEG(img image.Image, quality int) ([]byte, error) {
+	if quality <= 0 || quality > 100 {
+		quality = jpeg.DefaultQuality
+	}
+	bounds := img.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(canvas, canvas.Bounds(), img, bounds.Min, draw.Over)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodePNG 将图片编码为PNG
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+package pdfrender
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func newFilledImage(width, height int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+	return img
+}
+
+func TestScale(t *testing.T) {
+	img := newFilledImage(400, 600, color.RGBA{R: 200, A: 255})
+	scaled := Scale(img, 100)
+	if scaled.Bounds().Dx() != 100 || scaled.Bounds().Dy() != 150 {
+		t.Fatalf("缩放尺寸错误: %v", scaled.Bounds())
+	}
+	if r, _, _, _ := scaled.At(50, 75).RGBA(); r>>8 != 200 {
+		t.Errorf("缩放后颜色错误: %d", r>>8)
+	}
+	if small := Scale(img, 800); small != img {
+		t.Error("目标宽度大于原图时应原样返回")
+	}
+}
+
+func TestCrop(t *testing.T) {
+	img := newFilledImage(200, 400, color.White)
+	// 左上角为原点的区域 (50,100)-(100,200)，坐标基于 100x200 的页面尺寸，图片为页面的2倍
+	draw.Draw(img, image.Rect(100, 200, 200, 400), &image.Uniform{C: color.Black}, image.Point{}, draw.Src)
+	cropped, err := Crop(img, 50, 100, 100, 200, 100, 200)
+	if err != nil {
+		t.Fatalf("裁剪失败: %v", err)
+	}
+	if cropped.Bounds().Dx() != 100 || cropped.Bounds().Dy() != 200 {
+		t.Fatalf("裁剪尺寸错误: %v", cropped.Bounds())
+	}
+	if r, _, _, _ := cropped.At(10, 10).RGBA(); r != 0 {
+		t.Errorf("裁剪区域内容错误: %d", r)
+	}
+	if _, err := Crop(img, 120, 0, 150, 10, 100, 200); err == nil {
+		t.Error("超出页面的区域应返回错误")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	data, err := EncodeJPEG(img, 80)
+	if err != nil {
+		t.Fatalf("JPEG编码失败: %v", err)
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("JPEG解码失败: %v", err)
+	}
+	// 透明像素应以白色填充
+	if r, g, b, _ := decoded.At(5, 5).RGBA(); r>>8 < 250 || g>>8 < 250 || b>>8 < 250 {
+		t.Errorf("透明区域未填充白色: %d %d %d", r>>8, g>>8, b>>8)
+	}
+	data, err = EncodePNG(img)
+	if err != nil {
+		t.Fatalf("PNG编码失败: %v", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("PNG解码失败: %v", err)
+	}
+}
+
+// minimalPDF 构造只有一页的PDF，页面尺寸为 100x200，内容流为 content
+func minimalPDF(content string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 100 200] /Contents 4 0 R /Resources << >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, 0, len(objects))
+	for i, object := range objects {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestRenderPage(t *testing.T) {
+	// 页面上半部分填充黑色的矢量矩形，没有内嵌位图
+	doc, err := Open(minimalPDF("0 0 0 rg 0 100 100 100 re f"))
+	if err != nil {
+		t.Fatalf("打开PDF失败: %v", err)
+	}
+	if doc.PageCount() != 1 {
+		t.Fatalf("页数错误: %d", doc.PageCount())
+	}
+	img, err := doc.RenderPage(1, 50)
+	if err != nil {
+		t.Fatalf("渲染失败: %v", err)
+	}
+	if img.Bounds().Dx() != 50 || img.Bounds().Dy() != 100 {
+		t.Fatalf("渲染尺寸错误: %v", img.Bounds())
+	}
+	if r, _, _, _ := img.At(25, 25).RGBA(); r>>8 > 10 {
+		t.Errorf("上半部分应为黑色: %d", r>>8)
+	}
+	if r, _, _, _ := img.At(25, 75).RGBA(); r>>8 < 245 {
+		t.Errorf("下半部分应为白色: %d", r>>8)
+	}
+	if _, err := doc.RenderPage(2, 50); err == nil {
+		t.Error("超出页数应返回错误")
+	}
+}
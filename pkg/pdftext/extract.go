@@ -0,0 +1,412 @@
+package pdftext
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	unipdf "github.com/unidoc/unipdf/v3/model"
+)
+
+// 表单XObject的最大嵌套深度，防止循环引用
+const maxFormDepth = 5
+
+// TJ 数组中大于该值（千分之一字号）的负向间距视为单词间空格
+const tjSpaceThreshold = 200
+
+// ligatures 将连字展开为普通字符，便于检索和分句
+var ligatures = strings.NewReplacer("\ufb00", "ff", "\ufb01", "fi", "\ufb02", "fl", "\ufb03", "ffi", "\ufb04", "ffl", "\ufb05", "st", "\ufb06", "st")
+
+// matrix 仿射变换矩阵 [a b c d e f]
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul 返回先应用 m 再应用 n 的变换
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func (m matrix) apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// fontInfo 已解析的字体
+type fontInfo struct {
+	font *unipdf.PdfFont
+	name string
+	bold bool
+}
+
+// textParams 随图形状态保存和恢复的文本状态参数
+type textParams struct {
+	font     *fontInfo
+	fontSize float64
+	charSp   float64
+	wordSp   float64
+	scale    float64
+	leading  float64
+	rise     float64
+}
+
+// extractor 解释内容流中的文本操作符并输出文本片段
+type extractor struct {
+	mediaBox *unipdf.PdfRectangle
+	params   textParams
+	stack    []textParams
+	tm       matrix
+	tlm      matrix
+	fonts    map[*unipdf.PdfPageResources]map[string]*fontInfo
+	spans    []Span
+	images   []Rect
+}
+
+// extractSpans 提取页面中的所有水平文本片段和位图区域
+func extractSpans(page *unipdf.PdfPage, mediaBox *unipdf.PdfRectangle) ([]Span, []Rect, error) {
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, nil, err
+	}
+	e := &extractor{
+		mediaBox: mediaBox,
+		params:   textParams{scale: 1},
+		tm:       identity,
+		tlm:      identity,
+		fonts:    make(map[*unipdf.PdfPageResources]map[string]*fontInfo),
+	}
+	if err := e.process(content, page.Resources, 0); err != nil {
+		return nil, nil, err
+	}
+	return e.spans, e.images, nil
+}
+
+func (e *extractor) process(content string, resources *unipdf.PdfPageResources, depth int) error {
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return err
+	}
+	processor := contentstream.NewContentStreamProcessor(*ops)
+	processor.SetRelaxedMode(true)
+	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, res *unipdf.PdfPageResources) error {
+			e.handle(op, ctmOf(gs), res, depth)
+			return nil
+		})
+	return processor.Process(resources)
+}
+
+// ctmOf 将图形状态中的CTM转换为本地矩阵
+func ctmOf(gs contentstream.GraphicsState) matrix {
+	e, f := gs.CTM.Transform(0, 0)
+	a, b := gs.CTM.Transform(1, 0)
+	c, d := gs.CTM.Transform(0, 1)
+	return matrix{a - e, b - f, c - e, d - f, e, f}
+}
+
+func (e *extractor) handle(op *contentstream.ContentStreamOperation, ctm matrix, res *unipdf.PdfPageResources, depth int) {
+	p := &e.params
+	switch op.Operand {
+	case "q":
+		e.stack = append(e.stack, *p)
+	case "Q":
+		if n := len(e.stack); n > 0 {
+			*p = e.stack[n-1]
+			e.stack = e.stack[:n-1]
+		}
+	case "BT":
+		e.tm = identity
+		e.tlm = identity
+	case "Tf":
+		if len(op.Params) == 2 {
+			if name, ok := core.GetName(op.Params[0]); ok {
+				p.font = e.loadFont(res, string(*name))
+			}
+			p.fontSize = floatParam(op.Params, 1)
+		}
+	case "Tc":
+		p.charSp = floatParam(op.Params, 0)
+	case "Tw":
+		p.wordSp = floatParam(op.Params, 0)
+	case "Tz":
+		p.scale = floatParam(op.Params, 0) / 100
+	case "TL":
+		p.leading = floatParam(op.Params, 0)
+	case "Ts":
+		p.rise = floatParam(op.Params, 0)
+	case "Td":
+		e.moveLine(floatParam(op.Params, 0), floatParam(op.Params, 1))
+	case "TD":
+		ty := floatParam(op.Params, 1)
+		p.leading = -ty
+		e.moveLine(floatParam(op.Params, 0), ty)
+	case "Tm":
+		if nums, err := core.GetNumbersAsFloat(op.Params); err == nil && len(nums) == 6 {
+			e.tlm = matrix{nums[0], nums[1], nums[2], nums[3], nums[4], nums[5]}
+			e.tm = e.tlm
+		}
+	case "T*":
+		e.moveLine(0, -p.leading)
+	case "Tj":
+		if len(op.Params) == 1 {
+			if data, ok := core.GetStringBytes(op.Params[0]); ok {
+				e.showText([][]byte{data}, nil, ctm)
+			}
+		}
+	case "'":
+		e.moveLine(0, -p.leading)
+		if len(op.Params) == 1 {
+			if data, ok := core.GetStringBytes(op.Params[0]); ok {
+				e.showText([][]byte{data}, nil, ctm)
+			}
+		}
+	case "\"":
+		if len(op.Params) == 3 {
+			p.wordSp = floatParam(op.Params, 0)
+			p.charSp = floatParam(op.Params, 1)
+			e.moveLine(0, -p.leading)
+			if data, ok := core.GetStringBytes(op.Params[2]); ok {
+				e.showText([][]byte{data}, nil, ctm)
+			}
+		}
+	case "TJ":
+		if len(op.Params) != 1 {
+			return
+		}
+		arr, ok := core.GetArray(op.Params[0])
+		if !ok {
+			return
+		}
+		var parts [][]byte
+		var adjusts []float64
+		for _, obj := range arr.Elements() {
+			if data, ok := core.GetStringBytes(obj); ok {
+				parts = append(parts, data)
+				adjusts = append(adjusts, 0)
+				continue
+			}
+			if v, err := core.GetNumberAsFloat(obj); err == nil {
+				if len(parts) == 0 {
+					parts = append(parts, nil)
+					adjusts = append(adjusts, 0)
+				}
+				adjusts[len(adjusts)-1] += v
+			}
+		}
+		e.showText(parts, adjusts, ctm)
+	case "Do":
+		if len(op.Params) == 1 && res != nil {
+			if name, ok := core.GetName(op.Params[0]); ok {
+				e.handleXObject(*name, ctm, res, depth)
+			}
+		}
+	}
+}
+
+func (e *extractor) moveLine(tx, ty float64) {
+	e.tlm = matrix{1, 0, 0, 1, tx, ty}.mul(e.tlm)
+	e.tm = e.tlm
+}
+
+// showText 绘制文本：parts 为依次绘制的字符串，adjusts 为每段字符串之后的 TJ 间距调整
+func (e *extractor) showText(parts [][]byte, adjusts []float64, ctm matrix) {
+	p := &e.params
+	fs := p.fontSize
+	if fs == 0 {
+		fs = 1
+	}
+	var sb strings.Builder
+	trm := e.tm.mul(ctm)
+	startX, startY := trm.apply(0, p.rise)
+	for i, data := range parts {
+		if len(data) > 0 {
+			e.decode(data, fs, &sb)
+		}
+		if adjusts != nil && adjusts[i] != 0 {
+			tx := -adjusts[i] / 1000 * fs * p.scale
+			e.tm = matrix{1, 0, 0, 1, tx, 0}.mul(e.tm)
+			if adjusts[i] < -tjSpaceThreshold && sb.Len() > 0 && !strings.HasSuffix(sb.String(), " ") {
+				sb.WriteByte(' ')
+			}
+		}
+	}
+	endX, _ := e.tm.mul(ctm).apply(0, p.rise)
+	text := ligatures.Replace(sb.String())
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	// 只保留水平书写的文本，旋转的页边标注（如arXiv编号）不参与版面分析
+	dx, dy := trm[0], trm[1]
+	if dx <= 0 || math.Abs(dy) > math.Abs(dx)*0.1 {
+		return
+	}
+	size := fs * math.Hypot(trm[2], trm[3])
+	if size <= 0 {
+		return
+	}
+	x0 := startX - e.mediaBox.Llx
+	x1 := endX - e.mediaBox.Llx
+	baseline := e.mediaBox.Ury - startY
+	span := Span{
+		Text:     text,
+		Rect:     Rect{X0: minFloat(x0, x1), Y0: baseline - size*0.8, X1: maxFloat(x0, x1), Y1: baseline + size*0.2},
+		Baseline: baseline,
+		FontSize: math.Round(size*10) / 10,
+	}
+	if p.font != nil {
+		span.FontName = p.font.name
+		span.Bold = p.font.bold
+	}
+	e.spans = append(e.spans, span)
+}
+
+// decode 解码字符串并按字形宽度推进文本矩阵
+func (e *extractor) decode(data []byte, fs float64, sb *strings.Builder) {
+	p := &e.params
+	if p.font == nil || p.font.font == nil {
+		// 缺少字体时按单字节拉丁字符处理，宽度取半个字号
+		for _, c := range data {
+			sb.WriteRune(rune(c))
+			e.advance(500, fs, c == ' ')
+		}
+		return
+	}
+	font := p.font.font
+	codes := font.BytesToCharcodes(data)
+	texts, _, _ := font.CharcodesToStrings(codes, "")
+	simple := font.IsSimple()
+	for i, code := range codes {
+		if i < len(texts) {
+			sb.WriteString(texts[i])
+		}
+		width := 500.0
+		if metrics, ok := font.GetCharMetrics(code); ok && metrics.Wx > 0 {
+			width = metrics.Wx
+		}
+		e.advance(width, fs, simple && code == 32)
+	}
+}
+
+func (e *extractor) advance(width, fs float64, space bool) {
+	p := &e.params
+	tx := width/1000*fs + p.charSp
+	if space {
+		tx += p.wordSp
+	}
+	tx *= p.scale
+	e.tm = matrix{1, 0, 0, 1, tx, 0}.mul(e.tm)
+}
+
+// handleXObject 记录位图的绘制区域，并递归提取表单中的文本
+func (e *extractor) handleXObject(name core.PdfObjectName, ctm matrix, res *unipdf.PdfPageResources, depth int) {
+	stream, kind := res.GetXObjectByName(name)
+	if stream == nil {
+		return
+	}
+	switch kind {
+	case unipdf.XObjectTypeImage:
+		rect := Rect{X0: math.Inf(1), Y0: math.Inf(1), X1: math.Inf(-1), Y1: math.Inf(-1)}
+		for _, pt := range [][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+			x, y := ctm.apply(pt[0], pt[1])
+			x -= e.mediaBox.Llx
+			y = e.mediaBox.Ury - y
+			rect.X0, rect.X1 = minFloat(rect.X0, x), maxFloat(rect.X1, x)
+			rect.Y0, rect.Y1 = minFloat(rect.Y0, y), maxFloat(rect.Y1, y)
+		}
+		if !rect.Empty() {
+			e.images = append(e.images, rect)
+		}
+	case unipdf.XObjectTypeForm:
+		if depth >= maxFormDepth {
+			return
+		}
+		form, err := unipdf.NewXObjectFormFromStream(stream)
+		if err != nil {
+			return
+		}
+		data, err := form.GetContentStream()
+		if err != nil {
+			return
+		}
+		formMatrix := identity
+		if form.Matrix != nil {
+			if arr, ok := core.GetArray(form.Matrix); ok {
+				if nums, err := core.GetNumbersAsFloat(arr.Elements()); err == nil && len(nums) == 6 {
+					formMatrix = matrix{nums[0], nums[1], nums[2], nums[3], nums[4], nums[5]}
+				}
+			}
+		}
+		formResources := form.Resources
+		if formResources == nil {
+			formResources = res
+		}
+		// 表单内容在独立的处理器中执行，通过前置cm操作继承当前的变换矩阵
+		m := formMatrix.mul(ctm)
+		prefix := fmt.Sprintf("%f %f %f %f %f %f cm\n", m[0], m[1], m[2], m[3], m[4], m[5])
+		saved := e.params
+		savedTm, savedTlm := e.tm, e.tlm
+		_ = e.process(prefix+string(data), formResources, depth+1)
+		e.params = saved
+		e.tm, e.tlm = savedTm, savedTlm
+	}
+}
+
+// loadFont 按资源名加载字体并缓存
+func (e *extractor) loadFont(res *unipdf.PdfPageResources, name string) *fontInfo {
+	if res == nil {
+		return nil
+	}
+	cache, ok := e.fonts[res]
+	if !ok {
+		cache = make(map[string]*fontInfo)
+		e.fonts[res] = cache
+	}
+	if info, ok := cache[name]; ok {
+		return info
+	}
+	info := &fontInfo{}
+	if obj, ok := res.GetFontByName(core.PdfObjectName(name)); ok {
+		if font, err := unipdf.NewPdfFontFromPdfObject(obj); err == nil {
+			info.font = font
+			info.name = font.BaseFont()
+			info.bold = isBoldFont(info.name)
+		}
+	}
+	cache[name] = info
+	return info
+}
+
+// isBoldFont 根据字体名判断是否为粗体
+func isBoldFont(name string) bool {
+	// 去掉子集前缀，如 ABCDEF+Times-Bold
+	if i := strings.Index(name, "+"); i >= 0 {
+		name = name[i+1:]
+	}
+	lower := strings.ToLower(name)
+	for _, key := range []string{"bold", "black", "heavy", "semibold", "demi", "-medi", ",b"} {
+		if strings.Contains(lower, key) {
+			return true
+		}
+	}
+	// LaTeX 的 Computer Modern 粗体，如 CMBX10
+	return strings.HasPrefix(lower, "cmbx") || strings.HasPrefix(lower, "sfbx")
+}
+
+func floatParam(params []core.PdfObject, i int) float64 {
+	if i >= len(params) {
+		return 0
+	}
+	v, err := core.GetNumberAsFloat(params[i])
+	if err != nil {
+		return 0
+	}
+	return v
+}
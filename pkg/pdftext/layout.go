@@ -0,0 +1,251 @@
+package pdftext
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 版面分析阈值，均以字号为单位
+const (
+	baselineTolerance = 0.45 // 同一行内基线允许的偏差（兼容上下标）
+	wordGap           = 0.15 // 片段间距超过该值时补空格
+	segmentGap        = 1.2  // 同一基线上间距超过该值时拆分为不同的行（栏间距、表格单元格）
+	blockGap          = 0.7  // 行间空白超过该值时开始新块
+	indentMin         = 0.6  // 首行缩进的最小宽度
+	columnMinLines    = 4    // 每栏至少包含的行数才判定为双栏
+)
+
+// layoutPage 将页面的文本片段组装为行和块，并按阅读顺序排列
+func layoutPage(p *Page) {
+	lines := buildLines(p.Spans)
+	p.TwoColumn = assignColumns(lines, p.Width)
+	p.Lines = readingOrder(lines, p.TwoColumn)
+	p.Blocks = buildBlocks(p.Lines)
+}
+
+// buildLines 按基线聚合文本片段，并在较大的水平间距处拆分
+func buildLines(spans []Span) []Line {
+	sorted := make([]Span, len(spans))
+	copy(sorted, spans)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Baseline != sorted[j].Baseline {
+			return sorted[i].Baseline < sorted[j].Baseline
+		}
+		return sorted[i].Rect.X0 < sorted[j].Rect.X0
+	})
+
+	var rows [][]Span
+	var rowBaseline, rowSize float64
+	for _, span := range sorted {
+		tolerance := baselineTolerance * math.Max(rowSize, span.FontSize)
+		if len(rows) > 0 && math.Abs(span.Baseline-rowBaseline) <= tolerance {
+			rows[len(rows)-1] = append(rows[len(rows)-1], span)
+			rowSize = math.Max(rowSize, span.FontSize)
+			continue
+		}
+		rows = append(rows, []Span{span})
+		rowBaseline, rowSize = span.Baseline, span.FontSize
+	}
+
+	var lines []Line
+	for _, row := range rows {
+		sort.SliceStable(row, func(i, j int) bool { return row[i].Rect.X0 < row[j].Rect.X0 })
+		var segment []Span
+		for _, span := range row {
+			if n := len(segment); n > 0 {
+				prev := segment[n-1]
+				size := math.Max(prev.FontSize, span.FontSize)
+				// 伪粗体等重复绘制的文本只保留一份
+				if span.Text == prev.Text && math.Abs(span.Rect.X0-prev.Rect.X0) < size*0.2 {
+					continue
+				}
+				if span.Rect.X0-prev.Rect.X1 > segmentGap*size {
+					lines = append(lines, newLine(segment))
+					segment = nil
+				}
+			}
+			segment = append(segment, span)
+		}
+		if len(segment) > 0 {
+			lines = append(lines, newLine(segment))
+		}
+	}
+	return lines
+}
+
+// newLine 由同一行内从左到右排列的片段构造行
+func newLine(spans []Span) Line {
+	line := Line{Spans: spans}
+	var sb strings.Builder
+	sizeWeight := make(map[float64]int)
+	boldChars, totalChars := 0, 0
+	mainWeight := -1
+	for i, span := range spans {
+		if i > 0 {
+			prev := spans[i-1]
+			gap := span.Rect.X0 - prev.Rect.X1
+			text := sb.String()
+			if gap > wordGap*math.Max(prev.FontSize, span.FontSize) &&
+				!strings.HasSuffix(text, " ") && !strings.HasPrefix(span.Text, " ") {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(span.Text)
+		n := len([]rune(strings.TrimSpace(span.Text)))
+		sizeWeight[span.FontSize] += n
+		totalChars += n
+		if span.Bold {
+			boldChars += n
+		}
+		if n > mainWeight {
+			mainWeight = n
+			line.Baseline = span.Baseline
+		}
+		line.Rect = line.Rect.Union(span.Rect)
+	}
+	line.Text = strings.Join(strings.Fields(sb.String()), " ")
+	best := -1
+	for size, weight := range sizeWeight {
+		if weight > best || (weight == best && size > line.FontSize) {
+			best = weight
+			line.FontSize = size
+		}
+	}
+	line.Bold = totalChars > 0 && boldChars*2 > totalChars
+	return line
+}
+
+// assignColumns 检测双栏排版并为每行标记所在的栏，返回是否为双栏
+func assignColumns(lines []Line, pageWidth float64) bool {
+	mid := pageWidth / 2
+	slack := pageWidth * 0.01
+	left, right := 0, 0
+	for _, line := range lines {
+		switch {
+		case line.Rect.X1 <= mid+slack:
+			left++
+		case line.Rect.X0 >= mid-slack:
+			right++
+		}
+	}
+	twoColumn := left >= columnMinLines && right >= columnMinLines && float64(left+right) >= float64(len(lines))*0.5
+	for i := range lines {
+		lines[i].Column = 0
+		if !twoColumn {
+			continue
+		}
+		switch {
+		case lines[i].Rect.X1 <= mid+slack:
+			lines[i].Column = 1
+		case lines[i].Rect.X0 >= mid-slack:
+			lines[i].Column = 2
+		}
+	}
+	return twoColumn
+}
+
+// readingOrder 按阅读顺序排列行：双栏页面中通栏行将页面分为若干区段，区段内先左栏后右栏
+func readingOrder(lines []Line, twoColumn bool) []Line {
+	sorted := make([]Line, len(lines))
+	copy(sorted, lines)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Baseline != sorted[j].Baseline {
+			return sorted[i].Baseline < sorted[j].Baseline
+		}
+		return sorted[i].Rect.X0 < sorted[j].Rect.X0
+	})
+	if !twoColumn {
+		return sorted
+	}
+	ordered := make([]Line, 0, len(sorted))
+	var leftLines, rightLines []Line
+	flush := func() {
+		ordered = append(ordered, leftLines...)
+		ordered = append(ordered, rightLines...)
+		leftLines, rightLines = nil, nil
+	}
+	for _, line := range sorted {
+		switch line.Column {
+		case 1:
+			leftLines = append(leftLines, line)
+		case 2:
+			rightLines = append(rightLines, line)
+		default:
+			flush()
+			ordered = append(ordered, line)
+		}
+	}
+	flush()
+	return ordered
+}
+
+// buildBlocks 将阅读顺序中相邻且版式一致的行合并为块
+func buildBlocks(lines []Line) []Block {
+	var blocks []Block
+	for _, line := range lines {
+		if n := len(blocks); n > 0 && continuesBlock(&blocks[n-1], line) {
+			block := &blocks[n-1]
+			block.Lines = append(block.Lines, line)
+			block.Rect = block.Rect.Union(line.Rect)
+			continue
+		}
+		blocks = append(blocks, Block{
+			Lines:    []Line{line},
+			Rect:     line.Rect,
+			FontSize: line.FontSize,
+			Bold:     line.Bold,
+			Column:   line.Column,
+		})
+	}
+	return blocks
+}
+
+// continuesBlock 判断行是否属于当前块
+func continuesBlock(block *Block, line Line) bool {
+	prev := block.Lines[len(block.Lines)-1]
+	size := math.Max(block.FontSize, line.FontSize)
+	if line.Column != block.Column {
+		return false
+	}
+	if math.Abs(line.FontSize-block.FontSize) > 0.8 || line.Bold != block.Bold {
+		return false
+	}
+	gap := line.Rect.Y0 - prev.Rect.Y1
+	if gap > blockGap*size || gap < -size {
+		return false
+	}
+	if line.Rect.OverlapX(block.Rect) <= 0 {
+		return false
+	}
+	// 上一行提前结束且本行首行缩进，视为新段落
+	prevShort := prev.Rect.X1 < block.Rect.X1-size
+	indented := line.Rect.X0 > block.Rect.X0+indentMin*size
+	return !(prevShort && indented)
+}
+
+func startsWithLower(s string) bool {
+	for _, r := range s {
+		return unicode.IsLower(r)
+	}
+	return false
+}
+
+func isCJKEnd(s string) bool {
+	r := []rune(s)
+	return len(r) > 0 && isCJK(r[len(r)-1])
+}
+
+func isCJKStart(s string) bool {
+	for _, r := range s {
+		return isCJK(r)
+	}
+	return false
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
@@ -0,0 +1,207 @@
+// Package pdftext 从PDF页面中提取带坐标和字体信息的文本，并按版面还原为行和块
+//
+// 文本提取直接解释页面内容流中的文本操作符，不依赖 unipdf 的授权文本提取接口；
+// 输出坐标统一为左上角原点、单位为PDF点(pt)，与解析结果中的 BBox 一致
+package pdftext
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	unipdf "github.com/unidoc/unipdf/v3/model"
+)
+
+// ErrNoText 页面中没有可提取的文本（通常是扫描版PDF）
+var ErrNoText = errors.New("pdftext: page has no extractable text")
+
+// Rect 左上角原点的矩形区域
+type Rect struct {
+	X0 float64 `json:"x0"`
+	Y0 float64 `json:"y0"`
+	X1 float64 `json:"x1"`
+	Y1 float64 `json:"y1"`
+}
+
+// Width 矩形宽度
+func (r Rect) Width() float64 {
+	return r.X1 - r.X0
+}
+
+// Height 矩形高度
+func (r Rect) Height() float64 {
+	return r.Y1 - r.Y0
+}
+
+// Union 返回同时包含两个矩形的最小矩形，空矩形不参与合并
+func (r Rect) Union(o Rect) Rect {
+	if r.Empty() {
+		return o
+	}
+	if o.Empty() {
+		return r
+	}
+	return Rect{
+		X0: minFloat(r.X0, o.X0),
+		Y0: minFloat(r.Y0, o.Y0),
+		X1: maxFloat(r.X1, o.X1),
+		Y1: maxFloat(r.Y1, o.Y1),
+	}
+}
+
+// Empty 是否为空矩形
+func (r Rect) Empty() bool {
+	return r.X1 <= r.X0 || r.Y1 <= r.Y0
+}
+
+// OverlapX 两个矩形在水平方向上的重叠宽度
+func (r Rect) OverlapX(o Rect) float64 {
+	return minFloat(r.X1, o.X1) - maxFloat(r.X0, o.X0)
+}
+
+// Span 一次文本绘制操作输出的文本片段
+type Span struct {
+	Text     string  `json:"text"`
+	Rect     Rect    `json:"rect"`
+	Baseline float64 `json:"baseline"`
+	FontName string  `json:"fontName"`
+	FontSize float64 `json:"fontSize"`
+	Bold     bool    `json:"bold"`
+}
+
+// Line 同一基线上连续的文本行
+type Line struct {
+	Text     string  `json:"text"`
+	Rect     Rect    `json:"rect"`
+	Baseline float64 `json:"baseline"`
+	FontSize float64 `json:"fontSize"` // 行内按字符数加权的主要字号
+	Bold     bool    `json:"bold"`     // 行内大部分字符为粗体
+	Column   int     `json:"column"`   // 0 通栏，1 左栏，2 右栏
+	Spans    []Span  `json:"spans"`
+}
+
+// Block 版面上相邻且字号一致的若干行，通常对应一个段落或标题
+type Block struct {
+	Lines    []Line  `json:"lines"`
+	Rect     Rect    `json:"rect"`
+	FontSize float64 `json:"fontSize"`
+	Bold     bool    `json:"bold"`
+	Column   int     `json:"column"`
+}
+
+// Text 拼接块内各行文本，处理行尾连字符断词
+func (b *Block) Text() string {
+	var sb strings.Builder
+	for i, line := range b.Lines {
+		text := strings.TrimSpace(line.Text)
+		if text == "" {
+			continue
+		}
+		if i > 0 && sb.Len() > 0 {
+			prev := sb.String()
+			if strings.HasSuffix(prev, "-") && startsWithLower(text) {
+				// 行尾连字符断开的单词直接拼接
+				sb.Reset()
+				sb.WriteString(strings.TrimSuffix(prev, "-"))
+			} else if !isCJKEnd(prev) || !isCJKStart(text) {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(text)
+	}
+	return sb.String()
+}
+
+// Page 一页的提取结果
+type Page struct {
+	Number int     `json:"number"` // 页码，从1开始
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Lines  []Line  `json:"lines"`  // 按阅读顺序排列的行
+	Blocks []Block `json:"blocks"` // 按阅读顺序排列的块
+	Images []Rect  `json:"images"` // 页面中位图的绘制区域
+	Spans  []Span  `json:"-"`
+	// TwoColumn 是否检测为双栏排版
+	TwoColumn bool `json:"twoColumn"`
+}
+
+// Document 已打开的PDF文档
+type Document struct {
+	reader    *unipdf.PdfReader
+	pageCount int
+}
+
+// Open 从PDF文件内容打开文档
+func Open(data []byte) (*Document, error) {
+	reader, err := unipdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("无法创建PDF读取器: %w", err)
+	}
+	isEncrypted, err := reader.IsEncrypted()
+	if err != nil {
+		return nil, fmt.Errorf("无法检查PDF加密状态: %w", err)
+	}
+	if isEncrypted {
+		// 大部分论文PDF只设置了权限密码，尝试使用空密码解密
+		ok, err := reader.Decrypt([]byte(""))
+		if err != nil || !ok {
+			return nil, fmt.Errorf("PDF已加密，无法解密")
+		}
+	}
+	pageCount, err := reader.GetNumPages()
+	if err != nil {
+		return nil, fmt.Errorf("无法获取PDF页数: %w", err)
+	}
+	return &Document{reader: reader, pageCount: pageCount}, nil
+}
+
+// PageCount 文档页数
+func (d *Document) PageCount() int {
+	return d.pageCount
+}
+
+// ExtractPage 提取指定页（从1开始）的文本并完成版面分析
+func (d *Document) ExtractPage(pageNum int) (*Page, error) {
+	if pageNum < 1 || pageNum > d.pageCount {
+		return nil, fmt.Errorf("页码超出范围: %d/%d", pageNum, d.pageCount)
+	}
+	page, err := d.reader.GetPage(pageNum)
+	if err != nil {
+		return nil, fmt.Errorf("无法获取第%d页: %w", pageNum, err)
+	}
+	mediaBox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, fmt.Errorf("无法获取第%d页尺寸: %w", pageNum, err)
+	}
+	result := &Page{
+		Number: pageNum,
+		Width:  mediaBox.Urx - mediaBox.Llx,
+		Height: mediaBox.Ury - mediaBox.Lly,
+	}
+	spans, images, err := extractSpans(page, mediaBox)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析第%d页内容: %w", pageNum, err)
+	}
+	result.Spans = spans
+	result.Images = images
+	if len(spans) == 0 {
+		return result, ErrNoText
+	}
+	layoutPage(result)
+	return result, nil
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
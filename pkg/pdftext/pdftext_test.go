@@ -0,0 +1,167 @@
+package pdftext
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// textOp 测试页面上的一行文本
+type textOp struct {
+	font string // F1 常规，F2 粗体
+	size float64
+	x, y float64 // PDF坐标，左下角原点
+	text string
+}
+
+// buildPDF 构造只包含标准字体文本的单页PDF
+func buildPDF(ops []textOp) []byte {
+	var content bytes.Buffer
+	for _, op := range ops {
+		fmt.Fprintf(&content, "BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET\n", op.font, op.size, op.x, op.y, op.text)
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func extractTestPage(t *testing.T, ops []textOp) *Page {
+	t.Helper()
+	doc, err := Open(buildPDF(ops))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if doc.PageCount() != 1 {
+		t.Fatalf("PageCount() = %d, want 1", doc.PageCount())
+	}
+	page, err := doc.ExtractPage(1)
+	if err != nil {
+		t.Fatalf("ExtractPage() error = %v", err)
+	}
+	return page
+}
+
+func TestExtractTwoColumnPage(t *testing.T) {
+	ops := []textOp{{font: "F2", size: 18, x: 150, y: 740, text: "A Study of Layout Analysis"}}
+	for i := 0; i < 6; i++ {
+		y := 680 - float64(i)*12
+		ops = append(ops,
+			textOp{font: "F1", size: 10, x: 72, y: y, text: fmt.Sprintf("left column line %d", i)},
+			textOp{font: "F1", size: 10, x: 320, y: y, text: fmt.Sprintf("right column line %d", i)},
+		)
+	}
+	page := extractTestPage(t, ops)
+
+	if !page.TwoColumn {
+		t.Fatalf("TwoColumn = false, want true")
+	}
+	if page.Width != 612 || page.Height != 792 {
+		t.Errorf("page size = %vx%v, want 612x792", page.Width, page.Height)
+	}
+	if len(page.Lines) != 13 {
+		t.Fatalf("len(Lines) = %d, want 13", len(page.Lines))
+	}
+	if got := page.Lines[0].Text; got != "A Study of Layout Analysis" {
+		t.Errorf("first line = %q", got)
+	}
+	// 左栏全部在右栏之前
+	for i := 0; i < 6; i++ {
+		if want := fmt.Sprintf("left column line %d", i); page.Lines[1+i].Text != want {
+			t.Errorf("Lines[%d] = %q, want %q", 1+i, page.Lines[1+i].Text, want)
+		}
+		if want := fmt.Sprintf("right column line %d", i); page.Lines[7+i].Text != want {
+			t.Errorf("Lines[%d] = %q, want %q", 7+i, page.Lines[7+i].Text, want)
+		}
+	}
+
+	if len(page.Blocks) != 3 {
+		t.Fatalf("len(Blocks) = %d, want 3", len(page.Blocks))
+	}
+	title := page.Blocks[0]
+	if !title.Bold || title.FontSize != 18 || title.Column != 0 {
+		t.Errorf("title block = bold %v size %v column %d", title.Bold, title.FontSize, title.Column)
+	}
+	// 坐标为左上角原点
+	if title.Rect.Y0 > 60 || title.Rect.X0 < 149 || title.Rect.X0 > 151 {
+		t.Errorf("title rect = %+v", title.Rect)
+	}
+	if page.Blocks[1].Column != 1 || page.Blocks[2].Column != 2 {
+		t.Errorf("block columns = %d, %d", page.Blocks[1].Column, page.Blocks[2].Column)
+	}
+	if !strings.HasPrefix(page.Blocks[2].Text(), "right column line 0 right column line 1") {
+		t.Errorf("right block text = %q", page.Blocks[2].Text())
+	}
+}
+
+func TestExtractBlocksAndSpacing(t *testing.T) {
+	ops := []textOp{
+		{font: "F2", size: 12, x: 72, y: 700, text: "1 Introduction"},
+		{font: "F1", size: 10, x: 72, y: 684, text: "Document parsing is an impor-"},
+		{font: "F1", size: 10, x: 72, y: 672, text: "tant problem."},
+		{font: "F1", size: 10, x: 82, y: 660, text: "A new paragraph starts here"},
+		{font: "F1", size: 10, x: 72, y: 648, text: "and continues."},
+		{font: "F1", size: 10, x: 72, y: 600, text: "Word"},
+		{font: "F1", size: 10, x: 100, y: 600, text: "gap"},
+	}
+	page := extractTestPage(t, ops)
+
+	if page.TwoColumn {
+		t.Fatalf("TwoColumn = true, want false")
+	}
+	var texts []string
+	for i := range page.Blocks {
+		texts = append(texts, page.Blocks[i].Text())
+	}
+	want := []string{
+		"1 Introduction",
+		"Document parsing is an important problem.",
+		"A new paragraph starts here and continues.",
+		"Word gap",
+	}
+	if strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("blocks = %q, want %q", texts, want)
+	}
+	if !page.Blocks[0].Bold || page.Blocks[1].Bold {
+		t.Errorf("bold = %v, %v", page.Blocks[0].Bold, page.Blocks[1].Bold)
+	}
+}
+
+func TestExtractPageOutOfRange(t *testing.T) {
+	doc, err := Open(buildPDF([]textOp{{font: "F1", size: 10, x: 72, y: 700, text: "x"}}))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := doc.ExtractPage(2); err == nil {
+		t.Errorf("ExtractPage(2) error = nil, want error")
+	}
+}
+
+func TestExtractEmptyPage(t *testing.T) {
+	doc, err := Open(buildPDF(nil))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := doc.ExtractPage(1); err != ErrNoText {
+		t.Errorf("ExtractPage() error = %v, want ErrNoText", err)
+	}
+}
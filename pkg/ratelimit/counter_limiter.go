@@ -2,7 +2,6 @@ package ratelimit
 
 import (
 	"context"
-	"strconv"
 	"strings"
 	"time"
 
@@ -85,19 +84,9 @@ func (l *CounterLimiter) Allow(ctx context.Context, key string, tokens int64) (*
 		}, nil
 	}
 
-	// 解析结果
-	resultArray := result.([]interface{})
-
-	// 安全地解析状态码和剩余次数
-	var statusCode, remaining int64
-
-	// 处理不同类型的返回值
-	if sc, ok := resultArray[0].(int64); ok {
-		statusCode = sc
-	} else if scStr, ok := resultArray[0].(string); ok {
-		statusCode, _ = strconv.ParseInt(scStr, 10, 64)
-	} else {
-		// 默认允许通过
+	// 解析结果，格式不符时默认允许通过
+	statusCode, remaining, ok := parseScriptResult(result)
+	if !ok {
 		return &RateLimitResult{
 			Allowed:    true,
 			Remaining:  l.config.MaxRate,
@@ -105,19 +94,11 @@ func (l *CounterLimiter) Allow(ctx context.Context, key string, tokens int64) (*
 		}, nil
 	}
 
-	if rem, ok := resultArray[1].(int64); ok {
-		remaining = rem
-	} else if remStr, ok := resultArray[1].(string); ok {
-		remaining, _ = strconv.ParseInt(remStr, 10, 64)
-	} else {
-		remaining = 0
-	}
-
 	// 计算重试时间
 	var retryAfter int64 = 0
 	if statusCode == 401 {
 		// 计算需要等待的时间（毫秒）
-		retryAfter = int64(float64(l.timeWindow) / float64(l.config.MaxRate) * 1000)
+		retryAfter = windowRetryAfter(l.timeWindow, l.config.MaxRate)
 	}
 
 	return &RateLimitResult{
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+// MemoryLimiter 进程内固定窗口限流器，用于未启用 Redis 的单机部署
+// 计数只在当前进程内有效，多实例部署需要使用 Redis 限流器
+type MemoryLimiter struct {
+	*BaseLimiter
+	config     LimiterConfig
+	timeWindow time.Duration
+
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	lastSweep time.Time
+}
+
+type memoryWindow struct {
+	count   int64
+	resetAt time.Time
+}
+
+// NewMemoryLimiter 创建进程内限流器
+func NewMemoryLimiter(logger logging.Logger, config LimiterConfig) *MemoryLimiter {
+	return &MemoryLimiter{
+		BaseLimiter: NewBaseLimiter(logger),
+		config:      config,
+		timeWindow:  GetTimeUnitDuration(config.TimeUnit),
+		windows:     make(map[string]*memoryWindow),
+		lastSweep:   time.Now(),
+	}
+}
+
+// Allow 判断请求是否允许通过
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, tokens int64) (*RateLimitResult, error) {
+	// 运行插件
+	bypass, err := l.RunPlugins(ctx, key, tokens)
+	if err != nil {
+		l.logger.Error("run plugins error", "error", err)
+	}
+
+	// 如果插件返回 true，表示请求可以绕过限流
+	if bypass {
+		return &RateLimitResult{
+			Allowed:    true,
+			Remaining:  l.config.MaxRate,
+			RetryAfter: 0,
+		}, nil
+	}
+
+	limiterKey := GetLimiterKey(l.config.KeyPrefix, l.config.Dimension, key)
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	window, ok := l.windows[limiterKey]
+	if !ok || !now.Before(window.resetAt) {
+		window = &memoryWindow{resetAt: now.Add(l.timeWindow)}
+		l.windows[limiterKey] = window
+	}
+
+	if window.count+tokens > l.config.MaxRate {
+		return &RateLimitResult{
+			Allowed:    false,
+			Remaining:  l.config.MaxRate - window.count,
+			RetryAfter: window.resetAt.Sub(now).Milliseconds(),
+		}, nil
+	}
+	window.count += tokens
+	return &RateLimitResult{
+		Allowed:    true,
+		Remaining:  l.config.MaxRate - window.count,
+		RetryAfter: 0,
+	}, nil
+}
+
+// sweep 每个窗口周期清理一次过期的计数，避免键无限增长
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.timeWindow {
+		return
+	}
+	for key, window := range l.windows {
+		if !now.Before(window.resetAt) {
+			delete(l.windows, key)
+		}
+	}
+	l.lastSweep = now
+}
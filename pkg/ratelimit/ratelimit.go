@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"plugin"
+	"strconv"
 	"sync"
 	"time"
 
@@ -48,6 +49,8 @@ const (
 	IP LimiterDimension = "ip"
 	// 接口级别限流
 	API LimiterDimension = "api"
+	// API Key级别限流
+	APIKey LimiterDimension = "api_key"
 )
 
 // 限流器配置
@@ -303,3 +306,36 @@ func GetTimeUnitDuration(unit TimeUnit) time.Duration {
 		return time.Second
 	}
 }
+
+// parseScriptResult 解析限流脚本返回的 {状态码, 剩余令牌数}
+// Redis 会把 Lua 数字转换为整数返回，兼容整数和字符串两种形式，格式不符时 ok 为 false
+func parseScriptResult(result interface{}) (statusCode int64, remaining int64, ok bool) {
+	resultArray, isArray := result.([]interface{})
+	if !isArray || len(resultArray) < 2 {
+		return 0, 0, false
+	}
+	if statusCode, ok = parseScriptInt(resultArray[0]); !ok {
+		return 0, 0, false
+	}
+	remaining, _ = parseScriptInt(resultArray[1])
+	return statusCode, remaining, true
+}
+
+func parseScriptInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// windowRetryAfter 窗口类限流器被拒绝时的重试等待时间（毫秒），按窗口内平均每个令牌的间隔估算
+func windowRetryAfter(timeWindow time.Duration, maxRate int64) int64 {
+	if maxRate <= 0 {
+		return timeWindow.Milliseconds()
+	}
+	return timeWindow.Milliseconds() / maxRate
+}
@@ -381,7 +381,7 @@ func TestSlidingWindowLimiter_Allow(t *testing.T) {
 			name:        "Allow request",
 			key:         "user1",
 			tokens:      1,
-			redisResult: int64(9), // 剩余9个令牌
+			redisResult: []interface{}{int64(200), int64(9)}, // 状态码200，剩余9个令牌
 			redisError:  nil,
 			expectedResult: &RateLimitResult{
 				Allowed:    true,
@@ -394,7 +394,7 @@ func TestSlidingWindowLimiter_Allow(t *testing.T) {
 			name:        "Deny request",
 			key:         "user2",
 			tokens:      1,
-			redisResult: int64(0), // 剩余0个令牌
+			redisResult: []interface{}{int64(401), int64(0)}, // 状态码401，剩余0个令牌
 			redisError:  nil,
 			expectedResult: &RateLimitResult{
 				Allowed:    false,
@@ -484,7 +484,7 @@ func TestTokenBucketLimiter_Allow(t *testing.T) {
 			name:        "Allow request",
 			key:         "user1",
 			tokens:      1,
-			redisResult: []interface{}{int64(1), int64(9)}, // 允许通过，剩余9个令牌
+			redisResult: []interface{}{int64(200), int64(9)}, // 状态码200，剩余9个令牌
 			redisError:  nil,
 			expectedResult: &RateLimitResult{
 				Allowed:    true,
@@ -497,7 +497,7 @@ func TestTokenBucketLimiter_Allow(t *testing.T) {
 			name:        "Deny request",
 			key:         "user2",
 			tokens:      1,
-			redisResult: []interface{}{int64(0), int64(0)}, // 不允许通过，剩余0个令牌
+			redisResult: []interface{}{int64(401), int64(0)}, // 状态码401，剩余0个令牌
 			redisError:  nil,
 			expectedResult: &RateLimitResult{
 				Allowed:    false,
@@ -587,7 +587,7 @@ func TestLeakyBucketLimiter_Allow(t *testing.T) {
 			name:        "Allow request",
 			key:         "user1",
 			tokens:      1,
-			redisResult: []interface{}{int64(1), int64(0)}, // 允许通过，等待时间0
+			redisResult: []interface{}{int64(200), int64(10)}, // 状态码200，剩余容量10
 			redisError:  nil,
 			expectedResult: &RateLimitResult{
 				Allowed:    true,
@@ -600,7 +600,7 @@ func TestLeakyBucketLimiter_Allow(t *testing.T) {
 			name:        "Deny request",
 			key:         "user2",
 			tokens:      1,
-			redisResult: []interface{}{int64(0), int64(100)}, // 不允许通过，需要等待100ms
+			redisResult: []interface{}{int64(401), int64(0)}, // 状态码401，剩余容量0
 			redisError:  nil,
 			expectedResult: &RateLimitResult{
 				Allowed:    false,
@@ -2,7 +2,6 @@ package ratelimit
 
 import (
 	"context"
-	"strconv"
 	"time"
 
 	"github.com/yb2020/odoc/internal/database"
@@ -95,7 +94,7 @@ func (l *SlidingWindowLimiter) Allow(ctx context.Context, key string, tokens int
 	`
 	
 	// 执行脚本
-	result, err := l.redis.Do(ctx, "EVAL", script, 2, limiterKey, limiterKey+":current", limiterKey+":previous", 
+	result, err := l.redis.Do(ctx, "EVAL", script, 3, limiterKey, limiterKey+":current", limiterKey+":previous", 
 		l.config.MaxRate, l.timeWindow.Milliseconds(), now, tokens, l.config.ExpireTime).Result()
 	
 	if err != nil {
@@ -108,16 +107,22 @@ func (l *SlidingWindowLimiter) Allow(ctx context.Context, key string, tokens int
 		}, nil
 	}
 	
-	// 解析结果
-	resultArray := result.([]interface{})
-	statusCode, _ := strconv.ParseInt(resultArray[0].(string), 10, 64)
-	remaining, _ := strconv.ParseInt(resultArray[1].(string), 10, 64)
+	// 解析结果，格式不符时默认放行
+	statusCode, remaining, ok := parseScriptResult(result)
+	if !ok {
+		l.logger.Error("unexpected limiter script result", "result", result)
+		return &RateLimitResult{
+			Allowed:    true,
+			Remaining:  l.config.MaxRate,
+			RetryAfter: 0,
+		}, nil
+	}
 	
 	// 计算重试时间
 	var retryAfter int64 = 0
 	if statusCode == 401 {
 		// 计算需要等待的时间（毫秒）
-		retryAfter = int64(float64(l.timeWindow) / float64(l.config.MaxRate) * 1000)
+		retryAfter = windowRetryAfter(l.timeWindow, l.config.MaxRate)
 	}
 	
 	return &RateLimitResult{
@@ -2,7 +2,6 @@ package ratelimit
 
 import (
 	"context"
-	"strconv"
 	"time"
 
 	"github.com/yb2020/odoc/internal/database"
@@ -96,10 +95,16 @@ func (l *TokenBucketLimiter) Allow(ctx context.Context, key string, tokens int64
 		}, nil
 	}
 	
-	// 解析结果
-	resultArray := result.([]interface{})
-	statusCode, _ := strconv.ParseInt(resultArray[0].(string), 10, 64)
-	remaining, _ := strconv.ParseInt(resultArray[1].(string), 10, 64)
+	// 解析结果，格式不符时默认放行
+	statusCode, remaining, ok := parseScriptResult(result)
+	if !ok {
+		l.logger.Error("unexpected limiter script result", "result", result)
+		return &RateLimitResult{
+			Allowed:    true,
+			Remaining:  l.config.MaxRate,
+			RetryAfter: 0,
+		}, nil
+	}
 	
 	// 计算重试时间
 	var retryAfter int64 = 0
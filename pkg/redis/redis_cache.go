@@ -125,6 +125,51 @@ func (c *RedisCache) SetNotBizPrefix(ctx context.Context, key string, value inte
 	return c.client.Set(ctx, key, jsonData, expiration).Err()
 }
 
+// SetNX 键不存在时才存入值
+func (c *RedisCache) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	formattedKey := c.formatKey(key)
+
+	// 如果没有指定过期时间，使用默认值
+	if expiration == 0 {
+		expiration = c.expiration
+	}
+
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	return c.client.SetNX(ctx, formattedKey, jsonData, expiration).Result()
+}
+
+// getAndDeleteScript 读取并删除键，兼容不支持 GETDEL 的 Redis 版本
+const getAndDeleteScript = `
+local value = redis.call('GET', KEYS[1])
+if value then
+	redis.call('DEL', KEYS[1])
+end
+return value
+`
+
+// GetAndDelete 原子地获取并删除值
+func (c *RedisCache) GetAndDelete(ctx context.Context, key string, dest interface{}) (bool, error) {
+	formattedKey := c.formatKey(key)
+
+	val, err := c.client.Eval(ctx, getAndDeleteScript, []string{formattedKey}).Text()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil // 键不存在或已被其他调用方取走
+		}
+		return false, err
+	}
+
+	if err := json.Unmarshal([]byte(val), dest); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 // Delete 从缓存中删除值
 func (c *RedisCache) Delete(ctx context.Context, key string) error {
 	formattedKey := c.formatKey(key)
@@ -1,10 +1,14 @@
 package registry
 
 import (
+	"context"
 	"sync"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yb2020/odoc/pkg/health"
 	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/pkg/takeout"
+	"github.com/yb2020/odoc/pkg/trash"
 	"google.golang.org/grpc"
 )
 
@@ -32,6 +36,11 @@ type ProviderRegistrar interface {
 	RegisterProviders() // 注册服务提供者
 }
 
+// HealthCheckRegistrar 可选接口：模块实现此接口后可为自身依赖的外部服务注册健康检查
+type HealthCheckRegistrar interface {
+	RegisterHealthChecks(registry *health.Registry) // 注册健康检查
+}
+
 // ModuleGroup 定义模块组接口
 type ModuleGroup interface {
 	Module                // 继承基本模块接口
@@ -119,3 +128,20 @@ func ShutdownAllModules() error {
 	}
 	return nil
 }
+
+// TakeoutExporterProvider 可选接口：模块实现此接口后，其用户数据会包含在用户数据导出压缩包中
+type TakeoutExporterProvider interface {
+	TakeoutExporters() []takeout.Exporter // 返回本模块的数据导出器
+}
+
+// UserDataDeleter 可选接口：模块实现此接口后，注销账号时会调用它删除该用户在本模块的数据
+// 各模块按初始化的逆序执行，依赖其他模块的模块先于被依赖的模块删除
+type UserDataDeleter interface {
+	Name() string                                            // 模块名称，作为注销报告中的步骤名
+	DeleteUserData(ctx context.Context, userId string) error // 删除用户数据，必须可重复执行，注销中断后会重新调用
+}
+
+// TrashHandlerProvider 可选接口：模块实现此接口后，回收站可以恢复和彻底删除本模块记录的条目
+type TrashHandlerProvider interface {
+	TrashHandlers() []trash.Handler // 返回本模块各类条目的处理器
+}
@@ -30,10 +30,11 @@ const (
 	Status_ServiceTokenNot  = 60005 // 服务令牌未找到
 
 	// HTTP 状态码
-	Code_Success       = http.StatusOK                  // HTTP成功
-	Code_InvalidParams = http.StatusBadRequest          // HTTP无效参数
-	Code_Unauthorized  = http.StatusUnauthorized        // HTTP未授权
-	Code_Forbidden     = http.StatusForbidden           // HTTP禁止访问
-	Code_NotFound      = http.StatusNotFound            // HTTP资源不存在
-	Code_InternalError = http.StatusInternalServerError // HTTP内部服务器错误
+	Code_Success         = http.StatusOK                  // HTTP成功
+	Code_InvalidParams   = http.StatusBadRequest          // HTTP无效参数
+	Code_Unauthorized    = http.StatusUnauthorized        // HTTP未授权
+	Code_Forbidden       = http.StatusForbidden           // HTTP禁止访问
+	Code_NotFound        = http.StatusNotFound            // HTTP资源不存在
+	Code_TooManyRequests = http.StatusTooManyRequests     // HTTP请求过多
+	Code_InternalError   = http.StatusInternalServerError // HTTP内部服务器错误
 )
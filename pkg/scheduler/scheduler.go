@@ -3,6 +3,7 @@ package scheduler
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redsync/redsync/v4"
@@ -20,6 +21,7 @@ type Scheduler struct {
 	cron    *cron.Cron
 	redis   *redis.Client
 	redsync *redsync.Redsync
+	running atomic.Bool
 }
 
 // NewScheduler 创建一个新的调度器
@@ -111,12 +113,14 @@ func (s *Scheduler) AddJob(spec string, job cron.Job, lockOptions *LockOptions)
 func (s *Scheduler) Start() error {
 	s.logger.Info("msg", "Starting scheduler...")
 	s.cron.Start()
+	s.running.Store(true)
 	return nil
 }
 
 // Stop 停止调度器
 func (s *Scheduler) Stop() error {
 	s.logger.Info("msg", "Stopping scheduler...")
+	s.running.Store(false)
 	// Stop会平滑地停止调度器，它会等待所有正在运行的任务完成
 	ctx := s.cron.Stop()
 	select {
@@ -129,6 +133,14 @@ func (s *Scheduler) Stop() error {
 	}
 }
 
+// Check 健康检查，调度器未启动或已停止时返回错误
+func (s *Scheduler) Check(ctx context.Context) error {
+	if !s.running.Load() {
+		return errors.New("scheduler not running")
+	}
+	return nil
+}
+
 // ScheduledJob 封装了一个待调度的任务及其所有配置。
 // 这种结构便于以声明式的方式管理和注册多个任务。
 type ScheduledJob interface {
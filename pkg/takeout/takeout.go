@@ -0,0 +1,259 @@
+package takeout
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 用户数据导出（takeout）：各业务模块实现 Exporter，把本模块的用户数据写入压缩包内以模块名命名的目录
+// 压缩包根目录的 manifest.json 记录每个文件的路径、大小、记录数，以及跳过的文件和导出失败的模块
+
+// ManifestVersion 清单格式版本，清单结构不兼容变化时递增
+const ManifestVersion = 1
+
+// ManifestFileName 清单文件名
+const ManifestFileName = "manifest.json"
+
+// Exporter 模块数据导出器
+type Exporter interface {
+	// Name 导出器名称，作为压缩包内的目录名，不要重复
+	Name() string
+	// Export 导出指定用户的数据，单个文件失败时应调用 Writer.Skip 记录后继续，返回错误表示整个模块导出失败
+	Export(ctx context.Context, userId string, w *Writer) error
+}
+
+// Manifest 压缩包清单
+type Manifest struct {
+	Version     int        `json:"version"`
+	UserId      string     `json:"userId"`
+	GeneratedAt time.Time  `json:"generatedAt"`
+	Sections    []*Section `json:"sections"`
+}
+
+// Section 单个导出器的导出结果
+type Section struct {
+	Name    string        `json:"name"`
+	Files   []File        `json:"files"`
+	Skipped []SkippedFile `json:"skipped,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// File 压缩包内的文件
+type File struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	Records int    `json:"records,omitempty"` // JSON 数组的元素个数，非结构化文件为 0
+}
+
+// SkippedFile 未能导出的文件
+type SkippedFile struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Archive 用户数据压缩包，非并发安全
+type Archive struct {
+	zw       *zip.Writer
+	manifest Manifest
+	paths    map[string]struct{}
+	closed   bool
+}
+
+// NewArchive 创建写入 w 的压缩包
+func NewArchive(w io.Writer, userId string, generatedAt time.Time) *Archive {
+	return &Archive{
+		zw: zip.NewWriter(w),
+		manifest: Manifest{
+			Version:     ManifestVersion,
+			UserId:      userId,
+			GeneratedAt: generatedAt,
+		},
+		paths: make(map[string]struct{}),
+	}
+}
+
+// Run 依次执行导出器，单个导出器失败只记录到清单，不影响其他导出器
+// 上下文被取消时立即返回
+func (a *Archive) Run(ctx context.Context, userId string, exporters []Exporter) error {
+	for _, exporter := range exporters {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		w := a.Section(exporter.Name())
+		if err := exporter.Export(ctx, userId, w); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			w.section.Error = err.Error()
+		}
+	}
+	return nil
+}
+
+// Section 获取导出器对应目录的写入器，同名目录共用一个清单分区
+func (a *Archive) Section(name string) *Writer {
+	name = SafeName(name)
+	for _, section := range a.manifest.Sections {
+		if section.Name == name {
+			return &Writer{archive: a, section: section}
+		}
+	}
+	section := &Section{Name: name, Files: []File{}}
+	a.manifest.Sections = append(a.manifest.Sections, section)
+	return &Writer{archive: a, section: section}
+}
+
+// Manifest 返回当前清单
+func (a *Archive) Manifest() *Manifest {
+	return &a.manifest
+}
+
+// Close 写入清单并结束压缩包，不会关闭底层 io.Writer
+func (a *Archive) Close() error {
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+	data, err := json.MarshalIndent(a.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	fw, err := a.zw.Create(ManifestFileName)
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return err
+	}
+	return a.zw.Close()
+}
+
+// create 在压缩包中创建文件，路径重复时在扩展名前追加序号
+func (a *Archive) create(filePath string, modified time.Time) (io.Writer, string, error) {
+	if a.closed {
+		return nil, "", fmt.Errorf("takeout archive closed")
+	}
+	filePath = a.uniquePath(filePath)
+	fw, err := a.zw.CreateHeader(&zip.FileHeader{
+		Name:     filePath,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	a.paths[filePath] = struct{}{}
+	return fw, filePath, nil
+}
+
+func (a *Archive) uniquePath(filePath string) string {
+	if _, exists := a.paths[filePath]; !exists {
+		return filePath
+	}
+	ext := path.Ext(filePath)
+	base := strings.TrimSuffix(filePath, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if _, exists := a.paths[candidate]; !exists {
+			return candidate
+		}
+	}
+}
+
+// Writer 导出器写入自己目录的写入器
+type Writer struct {
+	archive *Archive
+	section *Section
+}
+
+// WriteJSON 以 JSON 格式写入数据，数据为切片或数组时在清单中记录元素个数
+func (w *Writer) WriteJSON(name string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return w.WriteBytes(name, data, countRecords(value))
+}
+
+// WriteBytes 写入字节数据，records 为其中包含的记录数，非结构化数据传 0
+func (w *Writer) WriteBytes(name string, data []byte, records int) error {
+	fw, filePath, err := w.archive.create(w.path(name), w.archive.manifest.GeneratedAt)
+	if err != nil {
+		return err
+	}
+	n, err := fw.Write(data)
+	if err != nil {
+		return err
+	}
+	w.section.Files = append(w.section.Files, File{Path: filePath, Size: int64(n), Records: records})
+	return nil
+}
+
+// WriteFile 从 reader 流式写入文件，适用于 PDF 等大文件
+func (w *Writer) WriteFile(name string, reader io.Reader) error {
+	fw, filePath, err := w.archive.create(w.path(name), w.archive.manifest.GeneratedAt)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(fw, reader)
+	// 已创建的文件无法从压缩包中撤回，仍记入清单并在跳过列表中说明
+	w.section.Files = append(w.section.Files, File{Path: filePath, Size: n})
+	if err != nil {
+		w.Skip(name, "incomplete: "+err.Error())
+		return err
+	}
+	return nil
+}
+
+// Skip 记录未能导出的文件及原因
+func (w *Writer) Skip(name string, reason string) {
+	w.section.Skipped = append(w.section.Skipped, SkippedFile{Name: name, Reason: reason})
+}
+
+// path 计算文件在压缩包中的路径，name 可以包含子目录
+func (w *Writer) path(name string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		parts[i] = SafeName(part)
+	}
+	return w.section.Name + "/" + strings.Join(parts, "/")
+}
+
+// SafeName 将任意字符串转换为可作为压缩包内文件名的单段名称
+// 去掉路径分隔符和控制字符，避免解压时越出目标目录
+func SafeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\' || r == ':':
+			return '_'
+		case r < 0x20 || r == 0x7f:
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = strings.Trim(name, ".")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// countRecords 返回切片或数组的元素个数，其他类型返回 0
+func countRecords(value any) int {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return v.Len()
+	}
+	return 0
+}
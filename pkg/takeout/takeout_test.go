@@ -0,0 +1,167 @@
+package takeout
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testExporter struct {
+	name   string
+	export func(ctx context.Context, userId string, w *Writer) error
+}
+
+func (e *testExporter) Name() string { return e.name }
+
+func (e *testExporter) Export(ctx context.Context, userId string, w *Writer) error {
+	return e.export(ctx, userId, w)
+}
+
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader err = %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s err = %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s err = %v", f.Name, err)
+		}
+		files[f.Name] = content
+	}
+	return files
+}
+
+func TestArchiveRun(t *testing.T) {
+	var buf bytes.Buffer
+	archive := NewArchive(&buf, "u-1", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	exporters := []Exporter{
+		&testExporter{name: "doc", export: func(ctx context.Context, userId string, w *Writer) error {
+			if err := w.WriteJSON("docs.json", []map[string]string{{"id": "1"}, {"id": "2"}}); err != nil {
+				return err
+			}
+			if err := w.WriteFile("pdf/paper.pdf", strings.NewReader("%PDF-1.7")); err != nil {
+				return err
+			}
+			w.Skip("pdf/missing.pdf", "object not found")
+			return nil
+		}},
+		&testExporter{name: "note", export: func(ctx context.Context, userId string, w *Writer) error {
+			return errors.New("db down")
+		}},
+		&testExporter{name: "user", export: func(ctx context.Context, userId string, w *Writer) error {
+			return w.WriteJSON("profile.json", map[string]string{"id": userId})
+		}},
+	}
+	if err := archive.Run(context.Background(), "u-1", exporters); err != nil {
+		t.Fatalf("Run err = %v", err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Close err = %v", err)
+	}
+
+	files := readArchive(t, buf.Bytes())
+	if got := string(files["doc/pdf/paper.pdf"]); got != "%PDF-1.7" {
+		t.Fatalf("paper.pdf = %q", got)
+	}
+	if _, ok := files["user/profile.json"]; !ok {
+		t.Fatalf("user/profile.json missing, exporter after a failed one must still run")
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(files[ManifestFileName], &manifest); err != nil {
+		t.Fatalf("manifest err = %v", err)
+	}
+	if manifest.Version != ManifestVersion || manifest.UserId != "u-1" || len(manifest.Sections) != 3 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	doc := manifest.Sections[0]
+	if len(doc.Files) != 2 || doc.Files[0].Path != "doc/docs.json" || doc.Files[0].Records != 2 {
+		t.Fatalf("doc files = %+v", doc.Files)
+	}
+	if doc.Files[1].Size != int64(len("%PDF-1.7")) {
+		t.Fatalf("pdf size = %d", doc.Files[1].Size)
+	}
+	if len(doc.Skipped) != 1 || doc.Skipped[0].Name != "pdf/missing.pdf" {
+		t.Fatalf("doc skipped = %+v", doc.Skipped)
+	}
+	if manifest.Sections[1].Error != "db down" {
+		t.Fatalf("note error = %q", manifest.Sections[1].Error)
+	}
+}
+
+func TestArchiveRunCanceled(t *testing.T) {
+	var buf bytes.Buffer
+	archive := NewArchive(&buf, "u-1", time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	err := archive.Run(ctx, "u-1", []Exporter{&testExporter{name: "doc", export: func(ctx context.Context, userId string, w *Writer) error {
+		called = true
+		return nil
+	}}})
+	if !errors.Is(err, context.Canceled) || called {
+		t.Fatalf("Run err = %v, called = %v", err, called)
+	}
+}
+
+func TestWriterDuplicateAndUnsafeNames(t *testing.T) {
+	var buf bytes.Buffer
+	archive := NewArchive(&buf, "u-1", time.Now())
+	w := archive.Section("doc")
+	for _, name := range []string{"pdf/a.pdf", "pdf/a.pdf", "pdf/../../etc/passwd", "pdf/a\\b:c.pdf"} {
+		if err := w.WriteBytes(name, []byte("x"), 0); err != nil {
+			t.Fatalf("WriteBytes %s err = %v", name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Close err = %v", err)
+	}
+	files := readArchive(t, buf.Bytes())
+	for _, want := range []string{"doc/pdf/a.pdf", "doc/pdf/a-1.pdf", "doc/pdf/_/_/etc/passwd", "doc/pdf/a_b_c.pdf"} {
+		if _, ok := files[want]; !ok {
+			t.Fatalf("%s missing, got %v", want, keys(files))
+		}
+	}
+	for name := range files {
+		if strings.Contains(name, "..") {
+			t.Fatalf("unsafe path %s", name)
+		}
+	}
+}
+
+func TestSafeName(t *testing.T) {
+	cases := map[string]string{
+		"paper.pdf":    "paper.pdf",
+		"a/b":          "a_b",
+		"..":           "_",
+		"":             "_",
+		" 论文\x00.pdf ": "论文.pdf",
+	}
+	for input, want := range cases {
+		if got := SafeName(input); got != want {
+			t.Errorf("SafeName(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func keys(files map[string][]byte) []string {
+	result := make([]string, 0, len(files))
+	for name := range files {
+		result = append(result, name)
+	}
+	return result
+}
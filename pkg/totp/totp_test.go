@@ -0,0 +1,66 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录B的SHA1测试密钥
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateCodeRFCVectors(t *testing.T) {
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, c := range cases {
+		code, err := GenerateCode(rfcSecret, Counter(time.Unix(c.unix, 0)))
+		if err != nil {
+			t.Fatalf("生成验证码失败: %v", err)
+		}
+		if code != c.code {
+			t.Errorf("时间 %d 的验证码错误: 期望 %s, 实际 %s", c.unix, c.code, code)
+		}
+	}
+}
+
+func TestValidateWithSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	previous, _ := GenerateCode(secret, Counter(now)-1)
+
+	step, ok := Validate(secret, previous, now, 1)
+	if !ok || step != Counter(now)-1 {
+		t.Fatalf("允许一个时间步偏差时应校验通过, ok=%v step=%d", ok, step)
+	}
+	if _, ok := Validate(secret, previous, now, 0); ok {
+		t.Fatal("不允许偏差时上一个时间步的验证码应校验失败")
+	}
+	if _, ok := Validate(secret, "12345", now, 1); ok {
+		t.Fatal("位数不正确的验证码应校验失败")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("JBSWY3DPEHPK3PXP", "odoc", "alice@example.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/odoc:alice@example.com?") {
+		t.Fatalf("otpauth 地址格式错误: %s", uri)
+	}
+	for _, part := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=odoc", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("otpauth 地址缺少 %s: %s", part, uri)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"errors"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"gorm.io/gorm"
+)
+
+// gormSpanKey 保存在 gorm 语句实例中的 span
+const gormSpanKey = "tracing:span"
+
+// SQL 语句记录到 span 中的最大长度
+const maxStatementLength = 2048
+
+// GormPlugin 为每条 SQL 创建 span，父 span 取自 db.WithContext 传入的上下文
+type GormPlugin struct {
+	tracer opentracing.Tracer
+}
+
+// NewGormPlugin 创建 GORM 跟踪插件，通过 db.Use 注册
+func NewGormPlugin(tracer opentracing.Tracer) *GormPlugin {
+	return &GormPlugin{tracer: tracer}
+}
+
+// Name 插件名称
+func (p *GormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize 在增删改查回调前后注册 span 的创建和结束
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", p.before("CREATE")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", p.before("SELECT")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", p.before("UPDATE")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", p.before("DELETE")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", p.before("ROW")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", p.before("RAW")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", p.after),
+	)
+}
+
+func (p *GormPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+		// 没有父 span 的 SQL（如启动迁移、定时任务）不单独生成 trace
+		parent := opentracing.SpanFromContext(db.Statement.Context)
+		if parent == nil {
+			return
+		}
+		span := p.tracer.StartSpan("DB "+operation+" "+db.Statement.Table, opentracing.ChildOf(parent.Context()))
+		ext.DBType.Set(span, "sql")
+		ext.Component.Set(span, "gorm")
+		ext.SpanKindRPCClient.Set(span)
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func (p *GormPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(opentracing.Span)
+	if !ok {
+		return
+	}
+	defer span.Finish()
+
+	statement := db.Statement.SQL.String()
+	if len(statement) > maxStatementLength {
+		statement = statement[:maxStatementLength]
+	}
+	ext.DBStatement.Set(span, statement)
+	span.SetTag("db.table", db.Statement.Table)
+	span.SetTag("db.rows_affected", db.RowsAffected)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		ext.Error.Set(span, true)
+		span.SetTag("error.message", db.Error.Error())
+	}
+}
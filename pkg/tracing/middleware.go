@@ -10,7 +10,6 @@ import (
 	"github.com/go-kit/kit/endpoint"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
-	"github.com/uber/jaeger-client-go"
 	"github.com/yb2020/odoc/pkg/logging"
 	"github.com/yb2020/odoc/pkg/middleware"
 )
@@ -24,18 +23,11 @@ func NewGinTracingMiddleware(tracer opentracing.Tracer) gin.HandlerFunc {
 			opentracing.HTTPHeadersCarrier(c.Request.Header),
 		)
 
-		// 创建一个新的 span
-		var span opentracing.Span
+		// 创建一个新的 span，请求头中有跟踪上下文时继续跟踪
 		if err != nil {
-			// 请求头中没有跟踪上下文，创建一个新的
-			span = tracer.StartSpan("HTTP " + c.Request.Method + " " + c.FullPath())
-		} else {
-			// 继续跟踪
-			span = tracer.StartSpan(
-				"HTTP "+c.Request.Method+" "+c.FullPath(),
-				opentracing.ChildOf(spanCtx),
-			)
+			spanCtx = nil
 		}
+		span := tracer.StartSpan("HTTP "+c.Request.Method+" "+c.FullPath(), ext.RPCServerOption(spanCtx))
 		defer span.Finish()
 
 		// 设置 span 标签
@@ -96,11 +88,7 @@ func NewEndpointTracingMiddleware(tracer opentracing.Tracer, operationName strin
 			}
 
 			// 添加跟踪 ID 到上下文
-			traceID := ""
-			if jaegerCtx, ok := span.Context().(jaeger.SpanContext); ok {
-				traceID = jaegerCtx.TraceID().String()
-			}
-			ctx = middleware.WithTraceID(ctx, traceID)
+			ctx = middleware.WithTraceID(ctx, TraceID(span))
 
 			// 将 span 添加到上下文
 			ctx = opentracing.ContextWithSpan(ctx, span)
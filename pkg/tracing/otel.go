@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+// 指标默认上报间隔、关闭时等待导出的时间
+const (
+	defaultMetricInterval = 30 * time.Second
+	otelShutdownTimeout   = 10 * time.Second
+)
+
+// instrumentationName OpenTelemetry 中记录的埋点库名称
+const instrumentationName = "github.com/yb2020/odoc"
+
+// OTelProvider 实现了 TracerProvider 接口，通过 OTLP 将 trace 和指标上报到 collector
+//
+// 业务代码中的 opentracing span 经桥接写入 OpenTelemetry；
+// 同时注册全局 MeterProvider，pkg/metrics 中的业务指标随之上报。
+type OTelProvider struct {
+	tracer         opentracing.Tracer
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+}
+
+// NewOTelProvider 创建 OpenTelemetry 跟踪和指标提供者，并设置为全局提供者
+func NewOTelProvider(opts Options, logger logging.Logger) (*OTelProvider, error) {
+	ctx := context.Background()
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(semconv.ServiceName(opts.ServiceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	traceOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	metricOpts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		traceOpts = append(traceOpts, otlptracegrpc.WithInsecure())
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithInsecure())
+	}
+	if len(opts.Headers) > 0 {
+		traceOpts = append(traceOpts, otlptracegrpc.WithHeaders(opts.Headers))
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithHeaders(opts.Headers))
+	}
+	traceExporter, err := otlptracegrpc.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, err
+	}
+	metricExporter, err := otlpmetricgrpc.New(ctx, metricOpts...)
+	if err != nil {
+		_ = traceExporter.Shutdown(ctx)
+		return nil, err
+	}
+
+	interval := opts.MetricInterval
+	if interval <= 0 {
+		interval = defaultMetricInterval
+	}
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRate))),
+	)
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithInterval(interval))),
+	)
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	otel.SetTextMapPropagator(propagator)
+	tracer := newBridgeTracer(tracerProvider.Tracer(instrumentationName), propagator)
+	opentracing.SetGlobalTracer(tracer)
+
+	logger.Info("msg", "OpenTelemetry tracer initialized", "service", opts.ServiceName, "endpoint", opts.Endpoint,
+		"sampleRate", opts.SampleRate, "metricInterval", interval.String())
+	return &OTelProvider{
+		tracer:         tracer,
+		tracerProvider: tracerProvider,
+		meterProvider:  meterProvider,
+	}, nil
+}
+
+// GetTracer 返回桥接到 OpenTelemetry 的 opentracing 跟踪器
+func (op *OTelProvider) GetTracer() opentracing.Tracer {
+	return op.tracer
+}
+
+// Close 导出剩余的 span 和指标并关闭提供者
+func (op *OTelProvider) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), otelShutdownTimeout)
+	defer cancel()
+	return errors.Join(op.tracerProvider.Shutdown(ctx), op.meterProvider.Shutdown(ctx))
+}
@@ -0,0 +1,379 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/uber/jaeger-client-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// bridgeTracer 将 OpenTelemetry 的 Tracer 适配为 opentracing.Tracer
+//
+// 业务代码统一使用 opentracing 接口创建 span，切换到 OpenTelemetry 后无需修改；
+// 跨进程传播使用 W3C traceparent/baggage 头。
+type bridgeTracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// newBridgeTracer 创建 opentracing 到 OpenTelemetry 的桥接跟踪器
+func newBridgeTracer(tracer trace.Tracer, propagator propagation.TextMapPropagator) *bridgeTracer {
+	return &bridgeTracer{tracer: tracer, propagator: propagator}
+}
+
+// bridgeSpanContext 实现 opentracing.SpanContext，携带 OpenTelemetry 的 SpanContext 和 baggage
+type bridgeSpanContext struct {
+	spanContext trace.SpanContext
+	baggage     map[string]string
+}
+
+// ForeachBaggageItem 遍历 baggage
+func (c *bridgeSpanContext) ForeachBaggageItem(handler func(k, v string) bool) {
+	for k, v := range c.baggage {
+		if !handler(k, v) {
+			return
+		}
+	}
+}
+
+func (c *bridgeSpanContext) withBaggage(key, value string) *bridgeSpanContext {
+	items := make(map[string]string, len(c.baggage)+1)
+	for k, v := range c.baggage {
+		items[k] = v
+	}
+	items[key] = value
+	return &bridgeSpanContext{spanContext: c.spanContext, baggage: items}
+}
+
+// StartSpan 创建 span，ChildOf 引用作为父 span，其余引用记录为 link
+func (t *bridgeTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var options opentracing.StartSpanOptions
+	for _, opt := range opts {
+		opt.Apply(&options)
+	}
+
+	ctx := context.Background()
+	var parent *bridgeSpanContext
+	var links []trace.Link
+	for _, ref := range options.References {
+		sc, ok := ref.ReferencedContext.(*bridgeSpanContext)
+		if !ok || !sc.spanContext.IsValid() {
+			continue
+		}
+		if parent == nil && ref.Type == opentracing.ChildOfRef {
+			parent = sc
+			continue
+		}
+		links = append(links, trace.Link{SpanContext: sc.spanContext})
+	}
+	if parent == nil {
+		// 只有 FollowsFrom 引用时同样延续其 trace
+		for _, ref := range options.References {
+			if sc, ok := ref.ReferencedContext.(*bridgeSpanContext); ok && sc.spanContext.IsValid() {
+				parent = sc
+				break
+			}
+		}
+	}
+	if parent != nil {
+		if parent.spanContext.IsRemote() {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, parent.spanContext)
+		} else {
+			ctx = trace.ContextWithSpanContext(ctx, parent.spanContext)
+		}
+	}
+
+	startOpts := []trace.SpanStartOption{trace.WithLinks(links...)}
+	if !options.StartTime.IsZero() {
+		startOpts = append(startOpts, trace.WithTimestamp(options.StartTime))
+	}
+	if kind, ok := options.Tags[string(ext.SpanKind)]; ok {
+		startOpts = append(startOpts, trace.WithSpanKind(spanKind(kind)))
+	}
+	_, otelSpan := t.tracer.Start(ctx, operationName, startOpts...)
+
+	span := &bridgeSpan{tracer: t, span: otelSpan, context: &bridgeSpanContext{spanContext: otelSpan.SpanContext()}}
+	if parent != nil && len(parent.baggage) > 0 {
+		span.context.baggage = parent.baggage
+	}
+	for k, v := range options.Tags {
+		span.SetTag(k, v)
+	}
+	return span
+}
+
+// Inject 将 span 上下文写入 TextMap 或 HTTP 头
+func (t *bridgeTracer) Inject(sm opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	sc, ok := sm.(*bridgeSpanContext)
+	if !ok {
+		return opentracing.ErrInvalidSpanContext
+	}
+	writer, ok := carrier.(opentracing.TextMapWriter)
+	if !ok || (format != opentracing.TextMap && format != opentracing.HTTPHeaders) {
+		return opentracing.ErrUnsupportedFormat
+	}
+	ctx := trace.ContextWithSpanContext(context.Background(), sc.spanContext)
+	if len(sc.baggage) > 0 {
+		var members []baggage.Member
+		for k, v := range sc.baggage {
+			if m, err := baggage.NewMemberRaw(k, v); err == nil {
+				members = append(members, m)
+			}
+		}
+		if b, err := baggage.New(members...); err == nil {
+			ctx = baggage.ContextWithBaggage(ctx, b)
+		}
+	}
+	t.propagator.Inject(ctx, textMapWriter{writer})
+	return nil
+}
+
+// Extract 从 TextMap 或 HTTP 头中读取 span 上下文
+func (t *bridgeTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	reader, ok := carrier.(opentracing.TextMapReader)
+	if !ok || (format != opentracing.TextMap && format != opentracing.HTTPHeaders) {
+		return nil, opentracing.ErrUnsupportedFormat
+	}
+	values := make(textMapValues)
+	if err := reader.ForeachKey(func(key, val string) error {
+		values[key] = val
+		return nil
+	}); err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	ctx := t.propagator.Extract(context.Background(), values)
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	sc := &bridgeSpanContext{spanContext: spanContext}
+	for _, m := range baggage.FromContext(ctx).Members() {
+		sc = sc.withBaggage(m.Key(), m.Value())
+	}
+	return sc, nil
+}
+
+// bridgeSpan 实现 opentracing.Span
+type bridgeSpan struct {
+	tracer  *bridgeTracer
+	span    trace.Span
+	context *bridgeSpanContext
+}
+
+func (s *bridgeSpan) Finish() {
+	s.span.End()
+}
+
+func (s *bridgeSpan) FinishWithOptions(opts opentracing.FinishOptions) {
+	for _, record := range opts.LogRecords {
+		s.logFields(record.Timestamp, record.Fields...)
+	}
+	if opts.FinishTime.IsZero() {
+		s.span.End()
+		return
+	}
+	s.span.End(trace.WithTimestamp(opts.FinishTime))
+}
+
+func (s *bridgeSpan) Context() opentracing.SpanContext {
+	return s.context
+}
+
+func (s *bridgeSpan) SetOperationName(operationName string) opentracing.Span {
+	s.span.SetName(operationName)
+	return s
+}
+
+// SetTag 设置属性；error 标签转换为 span 状态
+func (s *bridgeSpan) SetTag(key string, value interface{}) opentracing.Span {
+	switch key {
+	case string(ext.Error):
+		if b, ok := value.(bool); ok && b {
+			s.span.SetStatus(codes.Error, "")
+		}
+		return s
+	case string(ext.SpanKind):
+		// span 类型只能在创建时指定，这里仅保留为属性
+	}
+	s.span.SetAttributes(toAttribute(key, value))
+	return s
+}
+
+func (s *bridgeSpan) LogFields(fields ...otlog.Field) {
+	s.logFields(time.Time{}, fields...)
+}
+
+// logFields 记录为 span 事件；event 字段作为事件名，error 字段同时记录为异常
+func (s *bridgeSpan) logFields(ts time.Time, fields ...otlog.Field) {
+	name := "log"
+	attrs := make([]attribute.KeyValue, 0, len(fields))
+	for _, f := range fields {
+		if f.Key() == "event" {
+			name = fmt.Sprint(f.Value())
+			continue
+		}
+		if err, ok := f.Value().(error); ok && f.Key() == "error" {
+			s.span.RecordError(err)
+			continue
+		}
+		attrs = append(attrs, toAttribute(f.Key(), f.Value()))
+	}
+	opts := []trace.EventOption{trace.WithAttributes(attrs...)}
+	if !ts.IsZero() {
+		opts = append(opts, trace.WithTimestamp(ts))
+	}
+	s.span.AddEvent(name, opts...)
+}
+
+func (s *bridgeSpan) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := otlog.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		s.LogFields(otlog.Error(err), otlog.String("function", "LogKV"))
+		return
+	}
+	s.LogFields(fields...)
+}
+
+func (s *bridgeSpan) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.context = s.context.withBaggage(restrictedKey, value)
+	return s
+}
+
+func (s *bridgeSpan) BaggageItem(restrictedKey string) string {
+	return s.context.baggage[restrictedKey]
+}
+
+func (s *bridgeSpan) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+// LogEvent 已废弃的 opentracing 接口
+func (s *bridgeSpan) LogEvent(event string) {
+	s.LogFields(otlog.String("event", event))
+}
+
+// LogEventWithPayload 已废弃的 opentracing 接口
+func (s *bridgeSpan) LogEventWithPayload(event string, payload interface{}) {
+	s.LogFields(otlog.String("event", event), otlog.Object("payload", payload))
+}
+
+// Log 已废弃的 opentracing 接口
+func (s *bridgeSpan) Log(data opentracing.LogData) {
+	s.logFields(data.Timestamp, otlog.String("event", data.Event), otlog.Object("payload", data.Payload))
+}
+
+// toAttribute 将 opentracing 的标签值转换为 OpenTelemetry 属性
+func toAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int8:
+		return attribute.Int(key, int(v))
+	case int16:
+		return attribute.Int(key, int(v))
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint8:
+		return attribute.Int(key, int(v))
+	case uint16:
+		return attribute.Int(key, int(v))
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case uint64:
+		return attribute.String(key, fmt.Sprint(v))
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case []string:
+		return attribute.StringSlice(key, v)
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	case error:
+		return attribute.String(key, v.Error())
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
+
+// spanKind 将 opentracing 的 span.kind 标签转换为 OpenTelemetry 的 SpanKind
+func spanKind(value interface{}) trace.SpanKind {
+	switch fmt.Sprint(value) {
+	case string(ext.SpanKindRPCClientEnum):
+		return trace.SpanKindClient
+	case string(ext.SpanKindRPCServerEnum):
+		return trace.SpanKindServer
+	case string(ext.SpanKindProducerEnum):
+		return trace.SpanKindProducer
+	case string(ext.SpanKindConsumerEnum):
+		return trace.SpanKindConsumer
+	default:
+		return trace.SpanKindInternal
+	}
+}
+
+// textMapWriter 将 opentracing.TextMapWriter 适配为 propagation.TextMapCarrier，仅用于写入
+type textMapWriter struct {
+	opentracing.TextMapWriter
+}
+
+func (w textMapWriter) Get(string) string { return "" }
+
+func (w textMapWriter) Keys() []string { return nil }
+
+// textMapValues 提取时暂存载体中的键值，键名不区分大小写
+type textMapValues map[string]string
+
+func (m textMapValues) Get(key string) string {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+func (m textMapValues) Set(key, value string) { m[key] = value }
+
+func (m textMapValues) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// TraceID 返回 span 的 trace ID，支持 Jaeger 和 OpenTelemetry 两种跟踪器，其他情况返回空
+func TraceID(span opentracing.Span) string {
+	if span == nil {
+		return ""
+	}
+	switch sc := span.Context().(type) {
+	case *bridgeSpanContext:
+		if sc.spanContext.HasTraceID() {
+			return sc.spanContext.TraceID().String()
+		}
+	case jaeger.SpanContext:
+		return sc.TraceID().String()
+	}
+	return ""
+}
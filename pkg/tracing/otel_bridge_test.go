@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestBridge 创建写入内存记录器的桥接 tracer
+func newTestBridge(t *testing.T) (*bridgeTracer, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	return newBridgeTracer(provider.Tracer("test"), propagator), recorder
+}
+
+func TestBridgeChildOf(t *testing.T) {
+	tracer, recorder := newTestBridge(t)
+
+	parent := tracer.StartSpan("parent")
+	child := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()), ext.SpanKindRPCClient)
+	child.SetTag("db.table", "t_user")
+	ext.Error.Set(child, true)
+	child.Finish()
+	parent.Finish()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended spans = %d, want 2", len(spans))
+	}
+	c, p := spans[0], spans[1]
+	if c.Parent().SpanID() != p.SpanContext().SpanID() || c.SpanContext().TraceID() != p.SpanContext().TraceID() {
+		t.Errorf("child is not linked to parent")
+	}
+	if c.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span kind = %v, want client", c.SpanKind())
+	}
+	if c.Status().Code != codes.Error {
+		t.Errorf("status = %v, want error", c.Status().Code)
+	}
+	found := false
+	for _, attr := range c.Attributes() {
+		if string(attr.Key) == "db.table" && attr.Value.AsString() == "t_user" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("db.table attribute missing: %v", c.Attributes())
+	}
+}
+
+func TestBridgeInjectExtract(t *testing.T) {
+	tracer, recorder := newTestBridge(t)
+
+	span := tracer.StartSpan("producer")
+	span.SetBaggageItem("user", "42")
+	header := http.Header{}
+	if err := tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header)); err != nil {
+		t.Fatalf("inject: %v", err)
+	}
+	if header.Get("Traceparent") == "" {
+		t.Fatalf("traceparent header not written: %v", header)
+	}
+
+	spanCtx, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	consumer := tracer.StartSpan("consumer", opentracing.FollowsFrom(spanCtx))
+	if got := consumer.BaggageItem("user"); got != "42" {
+		t.Errorf("baggage = %q, want 42", got)
+	}
+	consumer.Finish()
+	span.Finish()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].SpanContext().TraceID() != spans[1].SpanContext().TraceID() {
+		t.Fatalf("consumer span did not continue the trace")
+	}
+	if TraceID(consumer) != spans[0].SpanContext().TraceID().String() {
+		t.Errorf("TraceID = %q, want %q", TraceID(consumer), spans[0].SpanContext().TraceID())
+	}
+}
+
+func TestBridgeExtractErrors(t *testing.T) {
+	tracer, _ := newTestBridge(t)
+
+	if _, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{}); !errors.Is(err, opentracing.ErrSpanContextNotFound) {
+		t.Errorf("empty carrier err = %v, want ErrSpanContextNotFound", err)
+	}
+	if _, err := tracer.Extract(opentracing.Binary, opentracing.TextMapCarrier{}); !errors.Is(err, opentracing.ErrUnsupportedFormat) {
+		t.Errorf("binary format err = %v, want ErrUnsupportedFormat", err)
+	}
+}
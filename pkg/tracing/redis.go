@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisHook 为 Redis 命令和 pipeline 创建 span，通过 client.AddHook 注册
+type RedisHook struct {
+	tracer opentracing.Tracer
+}
+
+var _ redis.Hook = (*RedisHook)(nil)
+
+// NewRedisHook 创建 Redis 跟踪钩子
+func NewRedisHook(tracer opentracing.Tracer) *RedisHook {
+	return &RedisHook{tracer: tracer}
+}
+
+// DialHook 建立连接不记录 span
+func (h *RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook 每条命令一个 span，命令参数不记录，避免泄露缓存内容
+func (h *RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		span := h.startSpan(ctx, "Redis "+strings.ToUpper(cmd.Name()))
+		if span == nil {
+			return next(ctx, cmd)
+		}
+		defer span.Finish()
+		err := next(opentracing.ContextWithSpan(ctx, span), cmd)
+		finishRedisSpan(span, err)
+		return err
+	}
+}
+
+// ProcessPipelineHook pipeline 整体一个 span，记录命令数和命令名
+func (h *RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		span := h.startSpan(ctx, "Redis PIPELINE")
+		if span == nil {
+			return next(ctx, cmds)
+		}
+		defer span.Finish()
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, strings.ToUpper(cmd.Name()))
+		}
+		span.SetTag("db.redis.num_cmd", len(cmds))
+		ext.DBStatement.Set(span, strings.Join(names, " "))
+		err := next(opentracing.ContextWithSpan(ctx, span), cmds)
+		finishRedisSpan(span, err)
+		return err
+	}
+}
+
+// startSpan 只在已有父 span 时创建，分布式锁续期等后台命令不单独生成 trace
+func (h *RedisHook) startSpan(ctx context.Context, operationName string) opentracing.Span {
+	parent := opentracing.SpanFromContext(ctx)
+	if parent == nil {
+		return nil
+	}
+	span := h.tracer.StartSpan(operationName, opentracing.ChildOf(parent.Context()))
+	ext.DBType.Set(span, "redis")
+	ext.Component.Set(span, "go-redis")
+	ext.SpanKindRPCClient.Set(span)
+	return span
+}
+
+func finishRedisSpan(span opentracing.Span, err error) {
+	if err != nil && !errors.Is(err, redis.Nil) {
+		ext.Error.Set(span, true)
+		span.SetTag("error.message", err.Error())
+	}
+}
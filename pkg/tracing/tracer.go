@@ -3,6 +3,7 @@ package tracing
 import (
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/uber/jaeger-client-go"
@@ -13,8 +14,14 @@ import (
 	"github.com/yb2020/odoc/pkg/logging"
 )
 
+// 跟踪提供者类型
+const (
+	ProviderJaeger        = "jaeger"
+	ProviderOpenTelemetry = "opentelemetry"
+)
+
 // TracerProvider 定义了一个通用的跟踪提供者接口
-// 这个接口允许我们在将来无缝切换到 OpenTelemetry
+// 业务代码只依赖 opentracing 接口，可以在 Jaeger 和 OpenTelemetry 之间切换
 type TracerProvider interface {
 	// GetTracer 返回一个跟踪器实例
 	GetTracer() opentracing.Tracer
@@ -41,25 +48,6 @@ func (jp *JaegerProvider) Close() error {
 	return nil
 }
 
-// OTelProvider 是 OpenTelemetry 提供者的占位符
-// 这个结构体将在未来实现，当我们集成 OpenTelemetry 时
-type OTelProvider struct {
-	// 将在未来实现
-}
-
-// GetTracer 返回 OpenTelemetry 跟踪器的桥接
-// 目前返回 NoopTracer，将在未来实现
-func (op *OTelProvider) GetTracer() opentracing.Tracer {
-	// 将在未来实现 OpenTelemetry 到 OpenTracing 的桥接
-	return opentracing.NoopTracer{}
-}
-
-// Close 关闭 OpenTelemetry 提供者
-func (op *OTelProvider) Close() error {
-	// 将在未来实现
-	return nil
-}
-
 // InitTracer initializes a new Jaeger tracer
 // 这个函数保留用于向后兼容
 func InitTracer(serviceName string, jaegerURL string, sampleRate float64, logger logging.Logger) (opentracing.Tracer, io.Closer, error) {
@@ -109,18 +97,26 @@ func NewJaegerProvider(serviceName string, jaegerURL string, sampleRate float64,
 	}, nil
 }
 
+// Options 跟踪提供者配置
+type Options struct {
+	Provider       string            // jaeger 或 opentelemetry
+	ServiceName    string            // 服务名
+	Endpoint       string            // Jaeger collector 地址或 OTLP gRPC 地址(host:port)
+	SampleRate     float64           // 采样率
+	Insecure       bool              // OTLP 是否使用明文连接
+	Headers        map[string]string // OTLP 请求头，如鉴权 token
+	MetricInterval time.Duration     // 指标上报间隔
+}
+
 // NewTracerProvider 根据配置创建适当的跟踪提供者
-// 目前只支持 Jaeger，但在未来会支持 OpenTelemetry
-func NewTracerProvider(providerType string, serviceName string, endpoint string, sampleRate float64, logger logging.Logger) (TracerProvider, error) {
-	switch providerType {
-	case "jaeger":
-		return NewJaegerProvider(serviceName, endpoint, sampleRate, logger)
-	case "opentelemetry":
-		// 将在未来实现
-		logger.Warn("msg", "OpenTelemetry 尚未实现，使用 NoopTracer 代替")
-		return &OTelProvider{}, nil
+func NewTracerProvider(opts Options, logger logging.Logger) (TracerProvider, error) {
+	switch opts.Provider {
+	case ProviderJaeger, "":
+		return NewJaegerProvider(opts.ServiceName, opts.Endpoint, opts.SampleRate, logger)
+	case ProviderOpenTelemetry, "otel", "otlp":
+		return NewOTelProvider(opts, logger)
 	default:
-		logger.Warn("msg", "未知的跟踪提供者类型，使用 Jaeger", "type", providerType)
-		return NewJaegerProvider(serviceName, endpoint, sampleRate, logger)
+		logger.Warn("msg", "未知的跟踪提供者类型，使用 Jaeger", "type", opts.Provider)
+		return NewJaegerProvider(opts.ServiceName, opts.Endpoint, opts.SampleRate, logger)
 	}
 }
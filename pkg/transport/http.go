@@ -43,7 +43,7 @@ func NewHTTPHandler(
 			// 显式列出所有允许的请求头（浏览器对 * 支持不一致）
 			c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Requested-With, Access-Control-Request-Token, X-Custom-Handle-Error, Accept-Language, X-Traceid-Header, Cache-Control, Pragma")
 			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			c.Header("Access-Control-Expose-Headers", "Content-Length, Content-Type, X-Request-Id")
+			c.Header("Access-Control-Expose-Headers", "Content-Length, Content-Type, X-Request-Id, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After")
 			c.Header("Access-Control-Max-Age", "43200")
 		}
 
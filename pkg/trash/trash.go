@@ -0,0 +1,135 @@
+package trash
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+)
+
+// 回收站：业务模块在逻辑删除数据时记录一个条目，保存恢复所需的快照（如被物理删除的文件夹关系）
+// 条目的恢复和彻底删除由所属模块实现的 Handler 完成；回收站模块未初始化时 Record 为空操作
+
+// 条目类型
+const (
+	TypeDoc       = "doc"        // 文献
+	TypeFolder    = "folder"     // 文献文件夹
+	TypeMark      = "mark"       // PDF 标注
+	TypeTextMark  = "text_mark"  // 文档视图中的文本标注
+	TypeNoteShape = "note_shape" // 笔记形状
+)
+
+// 删除方式
+const (
+	ReasonUser   = "user"   // 用户直接删除
+	ReasonFolder = "folder" // 随上级文件夹一起删除
+)
+
+// Item 一个被删除的条目
+type Item struct {
+	Id        string    // 回收站条目ID，记录成功后由 Recorder 回填
+	UserId    string    // 所属用户ID，为空时从上下文中获取
+	Type      string    // 条目类型
+	ItemId    string    // 被删除数据的ID
+	Title     string    // 展示名称，如文献名、文件夹名、标注内容摘要
+	Reason    string    // 删除方式，为空时为 user
+	ParentId  string    // 随其他条目一起删除时，为该条目的回收站条目ID
+	Sort      int       // 同一次删除中的顺序，随上级一起恢复时按此顺序执行
+	Snapshot  any       // 恢复所需的关联数据，记录时序列化为 JSON，可为空
+	DeletedAt time.Time // 删除时间，为空时取当前时间
+}
+
+// Entry 回收站中的条目，交给 Handler 恢复或彻底删除
+type Entry struct {
+	Id        string
+	UserId    string
+	Type      string
+	ItemId    string
+	Title     string
+	Reason    string
+	ParentId  string
+	Snapshot  string // 记录时的快照 JSON
+	DeletedAt time.Time
+}
+
+// DecodeSnapshot 把快照解析到 v，没有快照时保持 v 不变
+func (e *Entry) DecodeSnapshot(v any) error {
+	if e.Snapshot == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(e.Snapshot), v)
+}
+
+// Handler 某类条目的恢复和彻底删除，由所属业务模块实现
+// 条目对应的数据可能已被其他途径恢复或删除，实现需要容忍这种情况
+type Handler interface {
+	// Type 处理的条目类型
+	Type() string
+	// Restore 恢复条目，返回恢复后的名称，名称冲突时会被重命名
+	Restore(ctx context.Context, entry *Entry) (string, error)
+	// Purge 彻底删除条目对应的数据，并释放不再被引用的存储
+	Purge(ctx context.Context, entry *Entry) error
+}
+
+// Recorder 回收站条目落地接口，由回收站模块实现
+type Recorder interface {
+	Record(ctx context.Context, item *Item) error
+}
+
+var (
+	recorderMu sync.RWMutex
+	recorder   Recorder
+)
+
+// SetRecorder 设置全局回收站落地实现，传 nil 表示关闭
+func SetRecorder(r Recorder) {
+	recorderMu.Lock()
+	defer recorderMu.Unlock()
+	recorder = r
+}
+
+func currentRecorder() Recorder {
+	recorderMu.RLock()
+	defer recorderMu.RUnlock()
+	return recorder
+}
+
+// Enabled 是否已设置回收站落地实现
+func Enabled() bool {
+	return currentRecorder() != nil
+}
+
+// Record 记录一个被删除的条目，所属用户和删除时间未填写时补全
+// 与审计日志不同，记录失败意味着删除后无法恢复，调用方应让删除失败；在事务上下文中调用时与删除一起提交
+func Record(ctx context.Context, item *Item) error {
+	r := currentRecorder()
+	if r == nil || item == nil {
+		return nil
+	}
+	if item.UserId == "" {
+		item.UserId, _ = userContext.GetUserID(ctx)
+	}
+	if item.Reason == "" {
+		item.Reason = ReasonUser
+	}
+	if item.DeletedAt.IsZero() {
+		item.DeletedAt = time.Now()
+	}
+	return r.Record(ctx, item)
+}
+
+// UniqueName 返回不与已有名称冲突的名称，冲突时依次尝试 "name (1)"、"name (2)"……
+func UniqueName(name string, exists func(string) bool) string {
+	if !exists(name) {
+		return name
+	}
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)", name, i)
+		if !exists(candidate) {
+			return candidate
+		}
+	}
+}
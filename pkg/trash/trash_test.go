@@ -0,0 +1,74 @@
+package trash
+
+import (
+	"context"
+	"testing"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+)
+
+type testRecorder struct {
+	items []*Item
+}
+
+func (r *testRecorder) Record(ctx context.Context, item *Item) error {
+	item.Id = "t-1"
+	r.items = append(r.items, item)
+	return nil
+}
+
+func TestRecordWithoutRecorder(t *testing.T) {
+	SetRecorder(nil)
+	if Enabled() {
+		t.Fatalf("Enabled() = true, want false")
+	}
+	item := &Item{Type: TypeDoc, ItemId: "d-1"}
+	if err := Record(context.Background(), item); err != nil {
+		t.Fatalf("Record err = %v", err)
+	}
+	if item.Id != "" {
+		t.Fatalf("item.Id = %q, want empty", item.Id)
+	}
+}
+
+func TestRecordFillsDefaults(t *testing.T) {
+	recorder := &testRecorder{}
+	SetRecorder(recorder)
+	defer SetRecorder(nil)
+
+	ctx := userContext.NewUserContext().SetUserID("u-1").ToContext(context.Background())
+	item := &Item{Type: TypeFolder, ItemId: "f-1", Title: "papers"}
+	if err := Record(ctx, item); err != nil {
+		t.Fatalf("Record err = %v", err)
+	}
+	if len(recorder.items) != 1 || item.Id != "t-1" {
+		t.Fatalf("recorded %d items, id %q", len(recorder.items), item.Id)
+	}
+	if item.UserId != "u-1" || item.Reason != ReasonUser || item.DeletedAt.IsZero() {
+		t.Fatalf("item defaults not set: %+v", item)
+	}
+}
+
+func TestDecodeSnapshot(t *testing.T) {
+	type snapshot struct {
+		ParentId string `json:"parentId"`
+	}
+	var s snapshot
+	if err := (&Entry{}).DecodeSnapshot(&s); err != nil || s.ParentId != "" {
+		t.Fatalf("empty snapshot: %+v, err %v", s, err)
+	}
+	if err := (&Entry{Snapshot: `{"parentId":"f-0"}`}).DecodeSnapshot(&s); err != nil || s.ParentId != "f-0" {
+		t.Fatalf("decoded %+v, err %v", s, err)
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	taken := map[string]bool{"notes": true, "notes (1)": true}
+	exists := func(name string) bool { return taken[name] }
+	if got := UniqueName("drafts", exists); got != "drafts" {
+		t.Fatalf("UniqueName(drafts) = %q", got)
+	}
+	if got := UniqueName("notes", exists); got != "notes (2)" {
+		t.Fatalf("UniqueName(notes) = %q, want %q", got, "notes (2)")
+	}
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// AESUtil 提供AES-GCM加密和解密功能，用于加密存储敏感字段
+type AESUtil struct {
+	key []byte
+}
+
+// NewAESUtil 创建AESUtil，密钥由任意长度的secret经SHA-256派生为256位
+func NewAESUtil(secret string) *AESUtil {
+	key := sha256.Sum256([]byte(secret))
+	return &AESUtil{key: key[:]}
+}
+
+// EncryptBase64 加密并返回Base64编码的 nonce+密文
+func (u *AESUtil) EncryptBase64(plaintext string) (string, error) {
+	gcm, err := u.newGCM()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptBase64 解密EncryptBase64的结果
+func (u *AESUtil) DecryptBase64(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := u.newGCM()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func (u *AESUtil) newGCM() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(u.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestAESEncryptionDecryption(t *testing.T) {
+	aesUtil := NewAESUtil("test-secret")
+	originalText := "JBSWY3DPEHPK3PXP"
+
+	encrypted, err := aesUtil.EncryptBase64(originalText)
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if encrypted == originalText {
+		t.Fatal("密文不应与明文相同")
+	}
+
+	decrypted, err := aesUtil.DecryptBase64(encrypted)
+	if err != nil {
+		t.Fatalf("解密失败: %v", err)
+	}
+	if decrypted != originalText {
+		t.Errorf("解密结果不匹配: 期望 %s, 实际 %s", originalText, decrypted)
+	}
+
+	// 使用不同密钥解密应失败
+	if _, err := NewAESUtil("other-secret").DecryptBase64(encrypted); err == nil {
+		t.Error("使用错误密钥解密应返回错误")
+	}
+}
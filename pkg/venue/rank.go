@@ -0,0 +1,93 @@
+package venue
+
+import (
+	"strings"
+)
+
+// 支持的评级体系
+const (
+	SystemJCR  = "jcr"  // JCR影响因子与分区（Q1-Q4）
+	SystemCAS  = "cas"  // 中科院分区（1区-4区）
+	SystemCCF  = "ccf"  // CCF推荐目录（A/B/C）
+	SystemCORE = "core" // CORE会议排名（A*/A/B/C）
+)
+
+// 无法识别的等级排在最后
+const unknownRankOrder = 100
+
+// Systems 全部支持的评级体系
+func Systems() []string {
+	return []string{SystemJCR, SystemCAS, SystemCCF, SystemCORE}
+}
+
+// IsValidSystem 是否为支持的评级体系
+func IsValidSystem(system string) bool {
+	for _, s := range Systems() {
+		if s == system {
+			return true
+		}
+	}
+	return false
+}
+
+var chineseDigits = strings.NewReplacer("一", "1", "二", "2", "三", "3", "四", "4")
+
+// NormalizeRank 将不同写法的等级统一为评级体系的标准写法
+// 如 JCR 的 "1"、"q1" -> "Q1"，中科院的 "一区"、"1" -> "1区"，CCF 的 "CCF-A"、"A类" -> "A"
+func NormalizeRank(system string, rank string) string {
+	rank = strings.TrimSpace(rank)
+	if rank == "" {
+		return ""
+	}
+	upper := strings.ToUpper(rank)
+	switch system {
+	case SystemJCR:
+		digit := strings.TrimPrefix(chineseDigits.Replace(upper), "Q")
+		digit = strings.TrimSuffix(digit, "区")
+		if len(digit) == 1 && digit >= "1" && digit <= "4" {
+			return "Q" + digit
+		}
+	case SystemCAS:
+		digit := strings.TrimSuffix(chineseDigits.Replace(upper), "区")
+		digit = strings.TrimPrefix(digit, "Q")
+		if len(digit) == 1 && digit >= "1" && digit <= "4" {
+			return digit + "区"
+		}
+	case SystemCCF:
+		letter := strings.TrimPrefix(upper, "CCF")
+		letter = strings.Trim(letter, " -_")
+		letter = strings.TrimSuffix(letter, "类")
+		if letter == "A" || letter == "B" || letter == "C" {
+			return letter
+		}
+	case SystemCORE:
+		letter := strings.TrimPrefix(upper, "CORE")
+		letter = strings.Trim(letter, " -_")
+		if letter == "A*" || letter == "A" || letter == "B" || letter == "C" {
+			return letter
+		}
+	}
+	return rank
+}
+
+// RankOrder 等级的排序值，越小表示等级越高，无法识别的等级排在最后
+func RankOrder(system string, rank string) int {
+	rank = NormalizeRank(system, rank)
+	var order []string
+	switch system {
+	case SystemJCR:
+		order = []string{"Q1", "Q2", "Q3", "Q4"}
+	case SystemCAS:
+		order = []string{"1区", "2区", "3区", "4区"}
+	case SystemCCF:
+		order = []string{"A", "B", "C"}
+	case SystemCORE:
+		order = []string{"A*", "A", "B", "C"}
+	}
+	for i, r := range order {
+		if r == rank {
+			return i + 1
+		}
+	}
+	return unknownRankOrder
+}
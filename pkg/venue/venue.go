@@ -0,0 +1,125 @@
+// Package venue 提供发表场所（期刊、会议）名称的归一化，用于将用户文献中的收录信息匹配到各评级体系的场所
+package venue
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// 括号中的会议缩写，如 (ICML 2023)、(CVPR'22)
+	parenAcronymPattern = regexp.MustCompile(`\(\s*([A-Za-z][A-Za-z0-9&\-]*?)(?:[\s\-'’]*(?:19|20)?\d{2})?\s*\)`)
+	// 年份：1999、2023，以及 '23 这种两位年份
+	yearPattern = regexp.MustCompile(`(?:^|[\s\-'’(])(?:19|20)\d{2}\b|['’]\d{2}\b`)
+	// 序数届次，如 37th、2nd
+	ordinalPattern = regexp.MustCompile(`\b\d+(?:st|nd|rd|th)\b`)
+	// 缩写末尾的年份，如 AAAI-24、CVPR22
+	acronymYearPattern = regexp.MustCompile(`[\-'’]?\d{2,4}$`)
+	// ISSN，如 0162-8828、1939-353X
+	issnPattern = regexp.MustCompile(`\b(\d{4})-?(\d{3}[\dXx])\b`)
+)
+
+// 归一化时忽略的虚词
+var stopWords = map[string]struct{}{
+	"the": {}, "of": {}, "and": {}, "on": {}, "in": {}, "for": {}, "proceedings": {}, "annual": {},
+}
+
+// Normalize 将场所名称归一化为匹配用的键：小写、去掉年份和届次、去掉括号中的缩写与标点、忽略虚词
+func Normalize(name string) string {
+	return strings.Join(Tokens(name), " ")
+}
+
+// Tokens 场所名称归一化后的词列表
+func Tokens(name string) []string {
+	name = parenAcronymPattern.ReplaceAllString(name, " ")
+	name = StripYear(name)
+	name = strings.ReplaceAll(strings.ToLower(name), "&", " and ")
+	name = ordinalPattern.ReplaceAllString(name, " ")
+	fields := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if _, ok := stopWords[field]; ok {
+			continue
+		}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+// StripYear 去掉名称中的年份，如 "NeurIPS 2023" -> "NeurIPS"、"CVPR'22" -> "CVPR"
+func StripYear(name string) string {
+	name = yearPattern.ReplaceAllString(name, " ")
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Acronym 提取会议缩写（小写、不含年份），无法识别时返回空
+// 支持括号中的缩写，如 "International Conference on Machine Learning (ICML 2023)"，
+// 以及名称本身就是缩写的情况，如 "NeurIPS 2023"、"CVPR'22"、"AAAI-24"
+func Acronym(name string) string {
+	if match := parenAcronymPattern.FindStringSubmatch(name); match != nil && isAcronym(match[1]) {
+		return strings.ToLower(match[1])
+	}
+	fields := strings.Fields(StripYear(name))
+	if len(fields) == 0 || len(fields) > 2 {
+		return ""
+	}
+	candidate := acronymYearPattern.ReplaceAllString(fields[0], "")
+	if !isAcronym(candidate) {
+		return ""
+	}
+	return strings.ToLower(candidate)
+}
+
+// isAcronym 至少两个大写字母且不超过12个字符，如 ICML、NeurIPS、SIGMOD
+func isAcronym(text string) bool {
+	if len(text) < 2 || len(text) > 12 {
+		return false
+	}
+	upper := 0
+	for _, r := range text {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '&' {
+			return false
+		}
+	}
+	return upper >= 2
+}
+
+// NormalizeISSN 统一ISSN格式为 1234-567X，不是合法格式时返回空
+func NormalizeISSN(issn string) string {
+	match := issnPattern.FindStringSubmatch(strings.TrimSpace(issn))
+	if match == nil {
+		return ""
+	}
+	return match[1] + "-" + strings.ToUpper(match[2])
+}
+
+// ExtractISSNs 提取文本中的全部ISSN
+func ExtractISSNs(text string) []string {
+	matches := issnPattern.FindAllStringSubmatch(text, -1)
+	issns := make([]string, 0, len(matches))
+	for _, match := range matches {
+		issns = append(issns, match[1]+"-"+strings.ToUpper(match[2]))
+	}
+	return issns
+}
+
+// AbbreviationMatches 判断缩写名称是否为全称的逐词缩写，如
+// "IEEE Trans. Pattern Anal. Mach. Intell." 与 "IEEE Transactions on Pattern Analysis and Machine Intelligence"
+func AbbreviationMatches(abbreviation string, fullName string) bool {
+	abbrTokens := Tokens(abbreviation)
+	fullTokens := Tokens(fullName)
+	if len(abbrTokens) < 2 || len(abbrTokens) != len(fullTokens) {
+		return false
+	}
+	for i, token := range abbrTokens {
+		if !strings.HasPrefix(fullTokens[i], token) {
+			return false
+		}
+	}
+	return true
+}
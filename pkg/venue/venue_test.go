@@ -0,0 +1,91 @@
+package venue
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name string
+		key  string
+	}{
+		{"Proceedings of the 40th International Conference on Machine Learning (ICML 2023)", "international conference machine learning"},
+		{"International Conference on Machine Learning", "international conference machine learning"},
+		{"IEEE Transactions on Pattern Analysis & Machine Intelligence", "ieee transactions pattern analysis machine intelligence"},
+		{"Nature  ", "nature"},
+		{"CVPR'22", "cvpr"},
+	}
+	for _, c := range cases {
+		if key := Normalize(c.name); key != c.key {
+			t.Errorf("归一化 %q 错误: 期望 %q, 实际 %q", c.name, c.key, key)
+		}
+	}
+}
+
+func TestAcronym(t *testing.T) {
+	cases := []struct {
+		name    string
+		acronym string
+	}{
+		{"NeurIPS 2023", "neurips"},
+		{"CVPR'22", "cvpr"},
+		{"AAAI-24", "aaai"},
+		{"Advances in Neural Information Processing Systems (NeurIPS)", "neurips"},
+		{"International Conference on Machine Learning (ICML 2023)", "icml"},
+		{"Nature", ""},
+		{"Journal of Machine Learning Research", ""},
+	}
+	for _, c := range cases {
+		if acronym := Acronym(c.name); acronym != c.acronym {
+			t.Errorf("提取 %q 的缩写错误: 期望 %q, 实际 %q", c.name, c.acronym, acronym)
+		}
+	}
+}
+
+func TestNormalizeISSN(t *testing.T) {
+	if issn := NormalizeISSN("1939353x"); issn != "1939-353X" {
+		t.Errorf("ISSN归一化错误: %q", issn)
+	}
+	if issn := NormalizeISSN("abc"); issn != "" {
+		t.Errorf("非法ISSN应返回空: %q", issn)
+	}
+	issns := ExtractISSNs("ISSN: 0162-8828, eISSN: 1939-3539")
+	if len(issns) != 2 || issns[0] != "0162-8828" || issns[1] != "1939-3539" {
+		t.Errorf("提取ISSN错误: %v", issns)
+	}
+}
+
+func TestAbbreviationMatches(t *testing.T) {
+	if !AbbreviationMatches("IEEE Trans. Pattern Anal. Mach. Intell.", "IEEE Transactions on Pattern Analysis and Machine Intelligence") {
+		t.Error("期刊缩写应匹配全称")
+	}
+	if AbbreviationMatches("IEEE Trans. Image Process.", "IEEE Transactions on Pattern Analysis and Machine Intelligence") {
+		t.Error("不同期刊的缩写不应匹配")
+	}
+}
+
+func TestRank(t *testing.T) {
+	cases := []struct {
+		system string
+		rank   string
+		norm   string
+		order  int
+	}{
+		{SystemJCR, "q1", "Q1", 1},
+		{SystemJCR, "3", "Q3", 3},
+		{SystemCAS, "一区", "1区", 1},
+		{SystemCAS, "2", "2区", 2},
+		{SystemCCF, "CCF-B", "B", 2},
+		{SystemCCF, "A类", "A", 1},
+		{SystemCORE, "A*", "A*", 1},
+		{SystemCORE, "National", "National", unknownRankOrder},
+	}
+	for _, c := range cases {
+		if norm := NormalizeRank(c.system, c.rank); norm != c.norm {
+			t.Errorf("%s 等级 %q 归一化错误: 期望 %q, 实际 %q", c.system, c.rank, c.norm, norm)
+		}
+		if order := RankOrder(c.system, c.rank); order != c.order {
+			t.Errorf("%s 等级 %q 排序值错误: 期望 %d, 实际 %d", c.system, c.rank, c.order, order)
+		}
+	}
+}
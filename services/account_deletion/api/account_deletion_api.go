@@ -0,0 +1,139 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/account_deletion"
+	"github.com/yb2020/odoc/services/account_deletion/service"
+)
+
+// AccountDeletionAPI 账号注销API处理器
+type AccountDeletionAPI struct {
+	logger                 logging.Logger
+	tracer                 opentracing.Tracer
+	localizer              i18n.Localizer
+	accountDeletionService *service.AccountDeletionService
+}
+
+// NewAccountDeletionAPI 创建账号注销API处理器
+func NewAccountDeletionAPI(logger logging.Logger, tracer opentracing.Tracer, localizer i18n.Localizer, accountDeletionService *service.AccountDeletionService) *AccountDeletionAPI {
+	return &AccountDeletionAPI{
+		logger:                 logger,
+		tracer:                 tracer,
+		localizer:              localizer,
+		accountDeletionService: accountDeletionService,
+	}
+}
+
+// @api /api/account/deletion/request
+// @method POST
+// @apiDescription 申请注销当前账号，冷静期结束后删除全部数据，冷静期内可以撤销
+func (api *AccountDeletionAPI) Request(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AccountDeletionAPI.Request")
+	defer span.Finish()
+
+	req := &pb.RequestAccountDeletionRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	deletion, err := api.accountDeletionService.Request(ctx, userId, api.localizer.GetLanguage(c))
+	if err != nil {
+		api.logger.Warn("msg", "申请注销账号失败", "userId", userId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", deletion)
+}
+
+// @api /api/account/deletion/cancel
+// @method POST
+// @apiDescription 在冷静期内撤销注销申请
+func (api *AccountDeletionAPI) Cancel(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AccountDeletionAPI.Cancel")
+	defer span.Finish()
+
+	req := &pb.CancelAccountDeletionRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	deletion, err := api.accountDeletionService.Cancel(ctx, userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", deletion)
+}
+
+// @api /api/account/deletion/get
+// @method GET
+// @apiDescription 查询当前用户最近一次注销申请，没有申请时不返回数据
+func (api *AccountDeletionAPI) Get(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AccountDeletionAPI.Get")
+	defer span.Finish()
+
+	req := &pb.GetAccountDeletionRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	deletion, err := api.accountDeletionService.Get(ctx, userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if deletion == nil {
+		response.SuccessNoData(c, "success")
+		return
+	}
+	response.Success(c, "success", deletion)
+}
+
+// @api /api/admin/account/deletion/report
+// @method GET
+// @apiDescription 管理员查询用户最近一次注销申请及各模块的删除结果
+func (api *AccountDeletionAPI) Report(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AccountDeletionAPI.Report")
+	defer span.Finish()
+
+	req := &pb.GetAccountDeletionReportRequest{}
+	if err := transport.BindProto(c, req); err != nil || req.UserId == "" {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	deletion, err := api.accountDeletionService.GetReport(ctx, req.UserId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", deletion)
+}
+
+// @api /api/admin/account/deletion/retry
+// @method POST
+// @apiDescription 管理员重试失败的注销，从未完成的模块继续删除
+func (api *AccountDeletionAPI) Retry(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AccountDeletionAPI.Retry")
+	defer span.Finish()
+
+	req := &pb.RetryAccountDeletionRequest{}
+	if err := transport.BindProto(c, req); err != nil || req.Id == "" {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	deletion, err := api.accountDeletionService.Retry(ctx, req.Id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", deletion)
+}
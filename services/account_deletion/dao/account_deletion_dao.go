@@ -0,0 +1,83 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/account_deletion/model"
+	"gorm.io/gorm"
+)
+
+// AccountDeletionDAO GORM实现的账号注销申请DAO
+type AccountDeletionDAO struct {
+	*baseDao.GormBaseDAO[model.AccountDeletion]
+	logger logging.Logger
+}
+
+// NewAccountDeletionDAO 创建一个新的账号注销申请DAO
+func NewAccountDeletionDAO(db *gorm.DB, logger logging.Logger) *AccountDeletionDAO {
+	return &AccountDeletionDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.AccountDeletion](db, logger),
+		logger:      logger,
+	}
+}
+
+// GetLatestByUserId 获取用户最近一次注销申请
+func (d *AccountDeletionDAO) GetLatestByUserId(ctx context.Context, userId string) (*model.AccountDeletion, error) {
+	var deletion model.AccountDeletion
+	result := d.GetDB(ctx).Where("user_id = ? AND is_deleted = false", userId).Order("created_at desc, id desc").First(&deletion)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取用户最近一次注销申请失败", "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &deletion, nil
+}
+
+// GetRunnable 按冷静期结束时间获取待执行的注销，包括冷静期已结束的和开始时间早于 staleBefore 的删除中申请（上次执行被中断）
+func (d *AccountDeletionDAO) GetRunnable(ctx context.Context, now time.Time, staleBefore time.Time, limit int) ([]model.AccountDeletion, error) {
+	var deletions []model.AccountDeletion
+	result := d.GetDB(ctx).Where("is_deleted = false AND ((status = ? AND execute_after <= ?) OR (status = ? AND started_at < ?))",
+		model.AccountDeletionStatusScheduled, now, model.AccountDeletionStatusRunning, staleBefore).
+		Order("execute_after asc, id asc").Limit(limit).Find(&deletions)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取待执行注销申请失败", "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return deletions, nil
+}
+
+// Claim 将申请标记为删除中并累加尝试次数，返回是否标记成功
+// 条件与 GetRunnable 一致，多个实例同时执行时只有一个能标记成功，用户同时撤销时也只有一方成功
+func (d *AccountDeletionDAO) Claim(ctx context.Context, id string, now time.Time, staleBefore time.Time) (bool, error) {
+	result := d.GetDB(ctx).Model(&model.AccountDeletion{}).
+		Where("id = ? AND ((status = ? AND execute_after <= ?) OR (status = ? AND started_at < ?))",
+			id, model.AccountDeletionStatusScheduled, now, model.AccountDeletionStatusRunning, staleBefore).
+		Updates(map[string]interface{}{
+			"status":     model.AccountDeletionStatusRunning,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"started_at": now,
+		})
+	if result.Error != nil {
+		d.logger.Error("msg", "标记注销申请失败", "id", id, "error", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateStatus 仅当申请处于 fromStatus 时更新为 toStatus，返回是否更新成功
+func (d *AccountDeletionDAO) UpdateStatus(ctx context.Context, id string, fromStatus string, toStatus string) (bool, error) {
+	result := d.GetDB(ctx).Model(&model.AccountDeletion{}).
+		Where("id = ? AND status = ?", id, fromStatus).
+		Update("status", toStatus)
+	if result.Error != nil {
+		d.logger.Error("msg", "更新注销申请状态失败", "id", id, "error", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,35 @@
+package dao
+
+import (
+	"context"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/account_deletion/model"
+	"gorm.io/gorm"
+)
+
+// AccountDeletionStepDAO GORM实现的注销步骤DAO
+type AccountDeletionStepDAO struct {
+	*baseDao.GormBaseDAO[model.AccountDeletionStep]
+	logger logging.Logger
+}
+
+// NewAccountDeletionStepDAO 创建一个新的注销步骤DAO
+func NewAccountDeletionStepDAO(db *gorm.DB, logger logging.Logger) *AccountDeletionStepDAO {
+	return &AccountDeletionStepDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.AccountDeletionStep](db, logger),
+		logger:      logger,
+	}
+}
+
+// GetByDeletionId 按执行顺序获取注销申请的全部步骤
+func (d *AccountDeletionStepDAO) GetByDeletionId(ctx context.Context, deletionId string) ([]model.AccountDeletionStep, error) {
+	var steps []model.AccountDeletionStep
+	result := d.GetDB(ctx).Where("deletion_id = ? AND is_deleted = false", deletionId).Order("sort asc").Find(&steps)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取注销步骤失败", "deletionId", deletionId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return steps, nil
+}
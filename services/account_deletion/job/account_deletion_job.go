@@ -0,0 +1,57 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/services/account_deletion/service"
+)
+
+// AccountDeletionJob 账号注销任务，删除冷静期已结束的账号数据并继续执行中断的注销
+type AccountDeletionJob struct {
+	logger                 logging.Logger
+	spec                   string                 // 任务的cron表达式，6字段标准cron表达式
+	key                    string                 // 任务的锁key，必须是唯一的unique-job-key
+	expiry                 time.Duration          // 任务的锁过期时间
+	lockOpts               *scheduler.LockOptions // 任务的锁选项
+	accountDeletionService *service.AccountDeletionService
+}
+
+func NewAccountDeletionJob(logger logging.Logger, cfg *config.Config, accountDeletionService *service.AccountDeletionService) *AccountDeletionJob {
+	spec := cfg.Scheduler.Jobs.AccountDeletionJob.Spec
+	key := cfg.Scheduler.Jobs.AccountDeletionJob.Key
+	expiry := time.Duration(cfg.Scheduler.Jobs.AccountDeletionJob.Expiry) * time.Second
+	lockOpts := &scheduler.LockOptions{
+		Key:    key,
+		Expiry: expiry,
+	}
+	return &AccountDeletionJob{logger: logger, spec: spec, key: key, expiry: expiry, lockOpts: lockOpts, accountDeletionService: accountDeletionService}
+}
+
+// Spec 获取任务的cron表达式
+func (j *AccountDeletionJob) Spec() string {
+	return j.spec
+}
+
+// LockOpts 获取任务的锁选项
+func (j *AccountDeletionJob) LockOpts() *scheduler.LockOptions {
+	return j.lockOpts
+}
+
+// NewUserContext 注销任务不以具体用户身份执行，这里直接返回原上下文
+func (j *AccountDeletionJob) NewUserContext(ctx context.Context, userId string) context.Context {
+	return ctx
+}
+
+// Run 执行任务，在执行任务前会获取锁，执行任务后会释放锁
+func (j *AccountDeletionJob) Run() {
+	completed, err := j.accountDeletionService.ProcessDue(context.Background())
+	if err != nil {
+		j.logger.Error("msg", "Account deletion job failed", "completed", completed, "error", err)
+		return
+	}
+	j.logger.Info("msg", "Account deletion job success", "completed", completed)
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// 注销申请状态
+const (
+	AccountDeletionStatusScheduled = "scheduled" // 冷静期中，可撤销
+	AccountDeletionStatusCancelled = "cancelled" // 用户已撤销
+	AccountDeletionStatusRunning   = "running"   // 删除中
+	AccountDeletionStatusCompleted = "completed" // 全部模块已删除
+	AccountDeletionStatusFailed    = "failed"    // 多次尝试后仍失败，需管理员重试
+)
+
+// AccountDeletion 账号注销申请，完成后保留作为注销凭证
+type AccountDeletion struct {
+	model.BaseModel           // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	UserId          string    `json:"userId" gorm:"column:user_id;size:36;index"`                              // 申请用户ID
+	Status          string    `json:"status" gorm:"column:status;type:varchar(20);index;comment:状态"`           // 状态
+	Language        string    `json:"language" gorm:"column:language;type:varchar(10);comment:通知邮件语言"`         // 申请时的界面语言，用于通知邮件
+	Email           string    `json:"email" gorm:"column:email;type:varchar(255);comment:通知邮箱"`                // 通知邮箱，用户删除后仍需发送完成通知，发送后清空
+	ExecuteAfter    time.Time `json:"executeAfter" gorm:"column:execute_after;index;comment:冷静期结束时间"`          // 冷静期结束时间
+	Attempts        int       `json:"attempts" gorm:"column:attempts;type:int;default:0;comment:已尝试次数"`        // 已尝试删除的次数
+	ErrorMessage    string    `json:"errorMessage" gorm:"column:error_message;type:varchar(500);comment:失败原因"` // 最近一次失败原因
+	StartedAt       time.Time `json:"startedAt" gorm:"column:started_at;comment:开始删除时间"`                       // 最近一次开始删除的时间
+	FinishedAt      time.Time `json:"finishedAt" gorm:"column:finished_at;comment:完成时间"`                       // 完成或最终失败的时间
+}
+
+// TableName 返回表名
+func (AccountDeletion) TableName() string {
+	return "t_account_deletion"
+}
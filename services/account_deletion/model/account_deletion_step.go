@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// 模块删除步骤状态
+const (
+	AccountDeletionStepStatusPending   = "pending"   // 待执行
+	AccountDeletionStepStatusSucceeded = "succeeded" // 已完成，重新执行注销时跳过
+	AccountDeletionStepStatusFailed    = "failed"    // 失败，重新执行注销时重试
+)
+
+// AccountDeletionStep 注销流程中单个模块的删除结果，构成注销报告
+type AccountDeletionStep struct {
+	model.BaseModel           // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	DeletionId      string    `json:"deletionId" gorm:"column:deletion_id;size:36;index"`                      // 注销申请ID
+	UserId          string    `json:"userId" gorm:"column:user_id;size:36;index"`                              // 用户ID
+	Module          string    `json:"module" gorm:"column:module;type:varchar(50);comment:模块名称"`               // 模块名称
+	Sort            int       `json:"sort" gorm:"column:sort;type:int;comment:执行顺序"`                           // 执行顺序
+	Status          string    `json:"status" gorm:"column:status;type:varchar(20);comment:状态"`                 // 状态
+	Attempts        int       `json:"attempts" gorm:"column:attempts;type:int;default:0;comment:已尝试次数"`        // 已尝试次数
+	ErrorMessage    string    `json:"errorMessage" gorm:"column:error_message;type:varchar(500);comment:失败原因"` // 最近一次失败原因
+	FinishedAt      time.Time `json:"finishedAt" gorm:"column:finished_at;comment:完成时间"`                       // 完成时间
+}
+
+// TableName 返回表名
+func (AccountDeletionStep) TableName() string {
+	return "t_account_deletion_step"
+}
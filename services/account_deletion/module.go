@@ -0,0 +1,134 @@
+package account_deletion
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/mail"
+	"github.com/yb2020/odoc/pkg/middleware"
+	"github.com/yb2020/odoc/pkg/registry"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/services/account_deletion/api"
+	"github.com/yb2020/odoc/services/account_deletion/dao"
+	"github.com/yb2020/odoc/services/account_deletion/job"
+	"github.com/yb2020/odoc/services/account_deletion/service"
+	userService "github.com/yb2020/odoc/services/user/service"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+)
+
+// 编译时类型检查：确保 AccountDeletionModule 实现了 registry.Module 接口
+var _ registry.Module = (*AccountDeletionModule)(nil)
+
+// Module 导出模块实例，用于自动发现和注册
+var Module = &AccountDeletionModule{}
+
+// AccountDeletionModule 账号注销模块
+type AccountDeletionModule struct {
+	db                     *gorm.DB
+	logger                 logging.Logger
+	tracer                 opentracing.Tracer
+	config                 *config.Config
+	localizer              i18n.Localizer
+	authMiddleware         *middleware.AuthMiddleware
+	userService            *userService.UserService
+	accountDeletionAPI     *api.AccountDeletionAPI
+	accountDeletionService *service.AccountDeletionService
+}
+
+// NewAccountDeletionModule 创建账号注销模块
+func NewAccountDeletionModule(db *gorm.DB,
+	config *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	localizer i18n.Localizer,
+	authMiddleware *middleware.AuthMiddleware,
+	userService *userService.UserService,
+) *AccountDeletionModule {
+	return &AccountDeletionModule{
+		db:             db,
+		logger:         logger,
+		tracer:         tracer,
+		config:         config,
+		localizer:      localizer,
+		authMiddleware: authMiddleware,
+		userService:    userService,
+	}
+}
+
+// Name 返回模块名称
+func (m *AccountDeletionModule) Name() string {
+	return "account_deletion"
+}
+
+// Initialize 初始化模块
+func (m *AccountDeletionModule) Initialize() error {
+	m.logger.Info("msg", "初始化账号注销模块")
+	accountDeletionDAO := dao.NewAccountDeletionDAO(m.db, m.logger)
+	stepDAO := dao.NewAccountDeletionStepDAO(m.db, m.logger)
+	mailer := mail.NewMailer(&m.config.Mail, m.logger)
+
+	m.accountDeletionService = service.NewAccountDeletionService(m.config, m.logger, m.tracer, m.localizer, mailer,
+		accountDeletionDAO, stepDAO, m.userService)
+	m.accountDeletionAPI = api.NewAccountDeletionAPI(m.logger, m.tracer, m.localizer, m.accountDeletionService)
+	return nil
+}
+
+// SetDeleters 设置各模块的数据删除器，需在所有模块初始化完成后调用，注销时按设置顺序执行
+func (m *AccountDeletionModule) SetDeleters(deleters []registry.UserDataDeleter) {
+	m.accountDeletionService.SetDeleters(deleters)
+	m.logger.Info("msg", "成功为账号注销模块设置删除器", "count", len(deleters))
+}
+
+// GetAccountDeletionService 获取账号注销服务
+func (m *AccountDeletionModule) GetAccountDeletionService() *service.AccountDeletionService {
+	return m.accountDeletionService
+}
+
+// Shutdown 关闭模块
+func (m *AccountDeletionModule) Shutdown() error {
+	m.logger.Info("msg", "关闭账号注销模块")
+	return nil
+}
+
+// RegisterGRPC 注册gRPC服务
+func (m *AccountDeletionModule) RegisterGRPC(server *grpc.Server) {
+	// 账号注销模块没有gRPC服务，不需要注册
+	m.logger.Debug("msg", "账号注销模块没有gRPC服务，跳过注册")
+}
+
+// RegisterJobSchedulers 注册Job定时任务
+func (m *AccountDeletionModule) RegisterJobSchedulers(scheduler *scheduler.Scheduler) {
+	if scheduler == nil {
+		m.logger.Debug("msg", "调度器未启用，账号注销模块跳过Job注册")
+		return
+	}
+	m.logger.Debug("msg", "账号注销模块注册Job定时任务")
+	deletionJob := job.NewAccountDeletionJob(m.logger, m.config, m.accountDeletionService)
+	scheduler.RegisterJobs(deletionJob)
+}
+
+// RegisterProviders 注册Provider
+func (m *AccountDeletionModule) RegisterProviders() {
+	m.logger.Debug("msg", "账号注销模块没有Provider，跳过注册")
+}
+
+// RegisterRoutes 注册路由
+func (m *AccountDeletionModule) RegisterRoutes(r *gin.Engine) {
+	deletionGroup := r.Group("/api/account/deletion")
+	deletionGroup.Use(m.authMiddleware.AuthRequired())
+	{
+		deletionGroup.POST("/request", m.accountDeletionAPI.Request)
+		deletionGroup.POST("/cancel", m.accountDeletionAPI.Cancel)
+		deletionGroup.GET("/get", m.accountDeletionAPI.Get)
+	}
+
+	adminGroup := r.Group("/api/admin/account/deletion")
+	adminGroup.Use(m.authMiddleware.AuthRequired())
+	{
+		adminGroup.GET("/report", m.accountDeletionAPI.Report)
+		adminGroup.POST("/retry", m.accountDeletionAPI.Retry)
+	}
+}
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/audit"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/mail"
+	"github.com/yb2020/odoc/pkg/registry"
+	pb "github.com/yb2020/odoc/proto/gen/go/account_deletion"
+	"github.com/yb2020/odoc/services/account_deletion/dao"
+	"github.com/yb2020/odoc/services/account_deletion/model"
+	userService "github.com/yb2020/odoc/services/user/service"
+)
+
+const accountDeletionMaxErrorLen = 500 // 失败原因最大长度，与表字段一致
+
+// AccountDeletionService 账号注销服务，负责受理和撤销申请，冷静期结束后按模块删除用户数据并记录每个模块的结果
+type AccountDeletionService struct {
+	config             *config.Config
+	cfg                *config.AccountDeletionConfig
+	logger             logging.Logger
+	tracer             opentracing.Tracer
+	localizer          i18n.Localizer
+	mailer             mail.Mailer
+	accountDeletionDAO *dao.AccountDeletionDAO
+	stepDAO            *dao.AccountDeletionStepDAO
+	userService        *userService.UserService
+	deleters           []registry.UserDataDeleter
+}
+
+// NewAccountDeletionService 创建账号注销服务
+func NewAccountDeletionService(config *config.Config, logger logging.Logger, tracer opentracing.Tracer,
+	localizer i18n.Localizer, mailer mail.Mailer,
+	accountDeletionDAO *dao.AccountDeletionDAO,
+	stepDAO *dao.AccountDeletionStepDAO,
+	userService *userService.UserService,
+) *AccountDeletionService {
+	return &AccountDeletionService{
+		config:             config,
+		cfg:                &config.AccountDeletion,
+		logger:             logger,
+		tracer:             tracer,
+		localizer:          localizer,
+		mailer:             mailer,
+		accountDeletionDAO: accountDeletionDAO,
+		stepDAO:            stepDAO,
+		userService:        userService,
+	}
+}
+
+// SetDeleters 设置参与注销的各模块删除器，注销时按设置顺序执行
+func (s *AccountDeletionService) SetDeleters(deleters []registry.UserDataDeleter) {
+	s.deleters = deleters
+}
+
+// Request 申请注销账号，冷静期结束后开始删除数据；已有未结束的申请时直接返回该申请
+func (s *AccountDeletionService) Request(ctx context.Context, userId string, lang string) (*pb.AccountDeletion, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountDeletionService.Request")
+	defer span.Finish()
+
+	if !s.cfg.Enabled {
+		return nil, errors.Biz("account_deletion.errors.disabled")
+	}
+	latest, err := s.accountDeletionDAO.GetLatestByUserId(ctx, userId)
+	if err != nil {
+		return nil, errors.Biz("account_deletion.errors.query_failed")
+	}
+	if latest != nil && isActive(latest) {
+		return toProto(latest, nil), nil
+	}
+	user, err := s.userService.GetUserByID(ctx, userId)
+	if err != nil || user == nil {
+		return nil, errors.Biz("account_deletion.errors.query_failed")
+	}
+
+	deletion := &model.AccountDeletion{
+		UserId:       userId,
+		Status:       model.AccountDeletionStatusScheduled,
+		Language:     lang,
+		Email:        user.Email,
+		ExecuteAfter: time.Now().Add(time.Duration(s.cfg.GracePeriod) * time.Second),
+	}
+	if err := s.accountDeletionDAO.Save(ctx, deletion); err != nil {
+		s.logger.Error("msg", "创建注销申请失败", "userId", userId, "error", err.Error())
+		return nil, errors.Biz("account_deletion.errors.create_failed")
+	}
+	_ = audit.Record(ctx, &audit.Entry{
+		Action:     audit.ActionCreate,
+		EntityType: "account_deletion",
+		EntityId:   deletion.Id,
+		After:      deletion,
+	})
+	s.notify(ctx, deletion, "account_deletion.mail.scheduled")
+	return toProto(deletion, nil), nil
+}
+
+// Cancel 在冷静期内撤销注销申请，开始删除后不能撤销
+func (s *AccountDeletionService) Cancel(ctx context.Context, userId string) (*pb.AccountDeletion, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountDeletionService.Cancel")
+	defer span.Finish()
+
+	deletion, err := s.accountDeletionDAO.GetLatestByUserId(ctx, userId)
+	if err != nil {
+		return nil, errors.Biz("account_deletion.errors.query_failed")
+	}
+	if deletion == nil || deletion.Status != model.AccountDeletionStatusScheduled {
+		return nil, errors.Biz("account_deletion.errors.cannot_cancel")
+	}
+	// 与注销任务的标记条件互斥，任务已开始删除时撤销失败
+	cancelled, err := s.accountDeletionDAO.UpdateStatus(ctx, deletion.Id, model.AccountDeletionStatusScheduled, model.AccountDeletionStatusCancelled)
+	if err != nil {
+		return nil, errors.Biz("account_deletion.errors.cancel_failed")
+	}
+	if !cancelled {
+		return nil, errors.Biz("account_deletion.errors.cannot_cancel")
+	}
+	before := *deletion
+	deletion.Status = model.AccountDeletionStatusCancelled
+	_ = audit.Record(ctx, &audit.Entry{
+		Action:     audit.ActionUpdate,
+		EntityType: "account_deletion",
+		EntityId:   deletion.Id,
+		Before:     &before,
+		After:      deletion,
+	})
+	s.notify(ctx, deletion, "account_deletion.mail.cancelled")
+	return toProto(deletion, nil), nil
+}
+
+// Get 查询用户最近一次注销申请，没有申请时返回 nil
+func (s *AccountDeletionService) Get(ctx context.Context, userId string) (*pb.AccountDeletion, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountDeletionService.Get")
+	defer span.Finish()
+
+	deletion, err := s.accountDeletionDAO.GetLatestByUserId(ctx, userId)
+	if err != nil {
+		return nil, errors.Biz("account_deletion.errors.query_failed")
+	}
+	if deletion == nil {
+		return nil, nil
+	}
+	return toProto(deletion, nil), nil
+}
+
+// GetReport 查询用户最近一次注销申请及各模块的删除结果，供管理员核实注销是否完成
+func (s *AccountDeletionService) GetReport(ctx context.Context, userId string) (*pb.AccountDeletion, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountDeletionService.GetReport")
+	defer span.Finish()
+
+	deletion, err := s.accountDeletionDAO.GetLatestByUserId(ctx, userId)
+	if err != nil {
+		return nil, errors.Biz("account_deletion.errors.query_failed")
+	}
+	if deletion == nil {
+		return nil, errors.Biz("account_deletion.errors.not_found")
+	}
+	steps, err := s.stepDAO.GetByDeletionId(ctx, deletion.Id)
+	if err != nil {
+		return nil, errors.Biz("account_deletion.errors.query_failed")
+	}
+	return toProto(deletion, steps), nil
+}
+
+// Retry 管理员重试失败的注销，从未完成的模块继续删除
+func (s *AccountDeletionService) Retry(ctx context.Context, id string) (*pb.AccountDeletion, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountDeletionService.Retry")
+	defer span.Finish()
+
+	deletion, err := s.accountDeletionDAO.FindExistById(ctx, id)
+	if err != nil {
+		return nil, errors.Biz("account_deletion.errors.query_failed")
+	}
+	if deletion == nil {
+		return nil, errors.Biz("account_deletion.errors.not_found")
+	}
+	if deletion.Status != model.AccountDeletionStatusFailed {
+		return nil, errors.Biz("account_deletion.errors.not_failed")
+	}
+	before := *deletion
+	deletion.Status = model.AccountDeletionStatusScheduled
+	deletion.ExecuteAfter = time.Now()
+	deletion.Attempts = 0
+	deletion.FinishedAt = time.Time{}
+	if err := s.accountDeletionDAO.Modify(ctx, deletion); err != nil {
+		return nil, errors.Biz("account_deletion.errors.retry_failed")
+	}
+	_ = audit.Record(ctx, &audit.Entry{
+		Action:     audit.ActionUpdate,
+		EntityType: "account_deletion",
+		EntityId:   deletion.Id,
+		Before:     &before,
+		After:      deletion,
+	})
+	return toProto(deletion, nil), nil
+}
+
+// ProcessDue 执行冷静期已结束和中断的注销，返回本次完成的注销数
+func (s *AccountDeletionService) ProcessDue(ctx context.Context) (int, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountDeletionService.ProcessDue")
+	defer span.Finish()
+
+	now := time.Now()
+	staleBefore := now.Add(-time.Duration(s.cfg.RunningTimeout) * time.Second)
+	deletions, err := s.accountDeletionDAO.GetRunnable(ctx, now, staleBefore, max(s.cfg.BatchSize, 1))
+	if err != nil {
+		return 0, err
+	}
+	completed := 0
+	for i := range deletions {
+		deletion := &deletions[i]
+		claimed, err := s.accountDeletionDAO.Claim(ctx, deletion.Id, now, staleBefore)
+		if err != nil {
+			return completed, err
+		}
+		if !claimed {
+			// 已被其他实例处理或用户已撤销
+			continue
+		}
+		deletion.Status = model.AccountDeletionStatusRunning
+		deletion.Attempts++
+		deletion.StartedAt = now
+		// 删除中途进程退出时不会走到失败处理，这里补上尝试次数的限制
+		if s.cfg.MaxAttempts > 0 && deletion.Attempts > s.cfg.MaxAttempts {
+			s.markFailed(ctx, deletion, errors.Biz("account_deletion.errors.interrupted"))
+			continue
+		}
+
+		if err := s.run(ctx, deletion); err != nil {
+			s.logger.Error("msg", "注销账号失败", "deletionId", deletion.Id, "userId", deletion.UserId, "attempts", deletion.Attempts, "error", err.Error())
+			s.markFailed(ctx, deletion, err)
+			continue
+		}
+		completed++
+		s.complete(ctx, deletion)
+	}
+	return completed, nil
+}
+
+// run 按顺序执行各模块的删除，已完成的模块跳过，遇到失败立即停止，下次从失败的模块继续
+func (s *AccountDeletionService) run(ctx context.Context, deletion *model.AccountDeletion) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.cfg.RunningTimeout)*time.Second)
+	defer cancel()
+
+	steps, err := s.stepDAO.GetByDeletionId(ctx, deletion.Id)
+	if err != nil {
+		return err
+	}
+	stepByModule := make(map[string]*model.AccountDeletionStep, len(steps))
+	for i := range steps {
+		stepByModule[steps[i].Module] = &steps[i]
+	}
+	for i, deleter := range s.deleters {
+		step := stepByModule[deleter.Name()]
+		if step == nil {
+			step = &model.AccountDeletionStep{
+				DeletionId: deletion.Id,
+				UserId:     deletion.UserId,
+				Module:     deleter.Name(),
+				Sort:       i,
+				Status:     model.AccountDeletionStepStatusPending,
+			}
+			if err := s.stepDAO.Save(ctx, step); err != nil {
+				return err
+			}
+		}
+		if step.Status == model.AccountDeletionStepStatusSucceeded {
+			continue
+		}
+
+		step.Attempts++
+		if err := deleter.DeleteUserData(ctx, deletion.UserId); err != nil {
+			step.Status = model.AccountDeletionStepStatusFailed
+			step.ErrorMessage = truncate(err.Error(), accountDeletionMaxErrorLen)
+			if modifyErr := s.stepDAO.Modify(context.WithoutCancel(ctx), step); modifyErr != nil {
+				s.logger.Error("msg", "更新注销步骤失败", "deletionId", deletion.Id, "module", step.Module, "error", modifyErr.Error())
+			}
+			return fmt.Errorf("%s: %w", step.Module, err)
+		}
+		step.Status = model.AccountDeletionStepStatusSucceeded
+		step.ErrorMessage = ""
+		step.FinishedAt = time.Now()
+		if err := s.stepDAO.Modify(ctx, step); err != nil {
+			return err
+		}
+		s.logger.Info("msg", "已删除用户在模块中的数据", "deletionId", deletion.Id, "userId", deletion.UserId, "module", step.Module)
+	}
+	return nil
+}
+
+// complete 标记注销完成，发送完成通知后清空保存的邮箱
+func (s *AccountDeletionService) complete(ctx context.Context, deletion *model.AccountDeletion) {
+	deletion.Status = model.AccountDeletionStatusCompleted
+	deletion.ErrorMessage = ""
+	deletion.FinishedAt = time.Now()
+	s.notify(ctx, deletion, "account_deletion.mail.completed")
+	deletion.Email = ""
+	if err := s.accountDeletionDAO.Modify(ctx, deletion); err != nil {
+		s.logger.Error("msg", "更新注销申请状态失败", "deletionId", deletion.Id, "error", err.Error())
+	}
+}
+
+// markFailed 记录失败原因，未达到最大尝试次数时等待下次执行，否则标记为失败等待管理员重试
+func (s *AccountDeletionService) markFailed(ctx context.Context, deletion *model.AccountDeletion, cause error) {
+	deletion.ErrorMessage = truncate(cause.Error(), accountDeletionMaxErrorLen)
+	deletion.Status = model.AccountDeletionStatusScheduled
+	if s.cfg.MaxAttempts > 0 && deletion.Attempts >= s.cfg.MaxAttempts {
+		deletion.Status = model.AccountDeletionStatusFailed
+		deletion.FinishedAt = time.Now()
+	}
+	if err := s.accountDeletionDAO.Modify(ctx, deletion); err != nil {
+		s.logger.Error("msg", "更新注销申请状态失败", "deletionId", deletion.Id, "error", err.Error())
+	}
+}
+
+// notify 按申请时的语言发送邮件通知，发送失败只记录日志；用户可能已被删除，使用申请时保存的邮箱
+func (s *AccountDeletionService) notify(ctx context.Context, deletion *model.AccountDeletion, templateId string) {
+	if deletion.Email == "" {
+		s.logger.Warn("msg", "注销申请没有邮箱，跳过通知", "deletionId", deletion.Id, "userId", deletion.UserId)
+		return
+	}
+	data := map[string]interface{}{
+		"Email": deletion.Email,
+		"Date":  deletion.ExecuteAfter.UTC().Format("2006-01-02 15:04 UTC"),
+		"Days":  strconv.Itoa(max(s.cfg.GracePeriod/86400, 1)),
+	}
+	lang := deletion.Language
+	if lang == "" {
+		lang = s.localizer.GetDefaultLanguage()
+	}
+	message := &mail.Message{
+		To:       []string{deletion.Email},
+		Subject:  s.localizer.LocalizeWithLanguage(templateId+".subject", data, lang),
+		TextBody: s.localizer.LocalizeWithLanguage(templateId+".body", data, lang),
+	}
+	if err := s.mailer.Send(ctx, message); err != nil {
+		s.logger.Error("msg", "发送注销通知邮件失败", "deletionId", deletion.Id, "template", templateId, "error", err.Error())
+	}
+}
+
+// isActive 申请尚未结束：冷静期中、删除中或等待管理员重试
+func isActive(deletion *model.AccountDeletion) bool {
+	switch deletion.Status {
+	case model.AccountDeletionStatusScheduled, model.AccountDeletionStatusRunning, model.AccountDeletionStatusFailed:
+		return true
+	}
+	return false
+}
+
+// toProto 转换为接口返回结构，steps 为空时不返回各模块结果
+func toProto(deletion *model.AccountDeletion, steps []model.AccountDeletionStep) *pb.AccountDeletion {
+	result := &pb.AccountDeletion{
+		Id:           deletion.Id,
+		UserId:       deletion.UserId,
+		Status:       deletion.Status,
+		CreatedAt:    toMillis(deletion.CreatedAt),
+		ExecuteAfter: toMillis(deletion.ExecuteAfter),
+		StartedAt:    toMillis(deletion.StartedAt),
+		FinishedAt:   toMillis(deletion.FinishedAt),
+		Attempts:     int32(deletion.Attempts),
+		ErrorMessage: deletion.ErrorMessage,
+	}
+	for _, step := range steps {
+		result.Steps = append(result.Steps, &pb.AccountDeletionStep{
+			Module:       step.Module,
+			Status:       step.Status,
+			Attempts:     int32(step.Attempts),
+			ErrorMessage: step.ErrorMessage,
+			FinishedAt:   toMillis(step.FinishedAt),
+		})
+	}
+	return result
+}
+
+func toMillis(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixMilli())
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	s = s[:maxLen]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
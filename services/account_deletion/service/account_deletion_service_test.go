@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/registry"
+	"github.com/yb2020/odoc/services/account_deletion/dao"
+	"github.com/yb2020/odoc/services/account_deletion/model"
+)
+
+// stubDeleter 记录调用次数，err 不为空时删除失败
+type stubDeleter struct {
+	name  string
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (d *stubDeleter) Name() string {
+	return d.name
+}
+
+func (d *stubDeleter) DeleteUserData(ctx context.Context, userId string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls++
+	return d.err
+}
+
+func (d *stubDeleter) callCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calls
+}
+
+func TestAccountDeletionService(t *testing.T) {
+	db := daotest.NewDB(t, &model.AccountDeletion{}, &model.AccountDeletionStep{})
+	logger := daotest.NewLogger()
+	deletionDAO := dao.NewAccountDeletionDAO(db, logger)
+	stepDAO := dao.NewAccountDeletionStepDAO(db, logger)
+
+	ctx := context.Background()
+	newService := func(maxAttempts int, deleters ...registry.UserDataDeleter) *AccountDeletionService {
+		cfg := &config.Config{}
+		cfg.AccountDeletion = config.AccountDeletionConfig{Enabled: true, BatchSize: 10, RunningTimeout: 60, MaxAttempts: maxAttempts}
+		s := NewAccountDeletionService(cfg, logger, opentracing.NoopTracer{}, nil, nil, deletionDAO, stepDAO, nil)
+		s.SetDeleters(deleters)
+		return s
+	}
+	// 创建一个冷静期已结束的注销申请
+	schedule := func(t *testing.T, userId string) *model.AccountDeletion {
+		t.Helper()
+		deletion := &model.AccountDeletion{
+			UserId:       userId,
+			Status:       model.AccountDeletionStatusScheduled,
+			ExecuteAfter: time.Now().Add(-time.Minute),
+		}
+		if err := deletionDAO.Save(ctx, deletion); err != nil {
+			t.Fatalf("save deletion: %v", err)
+		}
+		return deletion
+	}
+	reload := func(t *testing.T, id string) *model.AccountDeletion {
+		t.Helper()
+		deletion, err := deletionDAO.FindExistById(ctx, id)
+		if err != nil || deletion == nil {
+			t.Fatalf("find deletion = %+v, err = %v", deletion, err)
+		}
+		return deletion
+	}
+	stepsOf := func(t *testing.T, deletionId string) map[string]model.AccountDeletionStep {
+		t.Helper()
+		steps, err := stepDAO.GetByDeletionId(ctx, deletionId)
+		if err != nil {
+			t.Fatalf("find steps: %v", err)
+		}
+		result := make(map[string]model.AccountDeletionStep, len(steps))
+		for _, step := range steps {
+			result[step.Module] = step
+		}
+		return result
+	}
+	processDue := func(t *testing.T, s *AccountDeletionService) int {
+		t.Helper()
+		completed, err := s.ProcessDue(ctx)
+		if err != nil {
+			t.Fatalf("ProcessDue: %v", err)
+		}
+		return completed
+	}
+
+	t.Run("resumes from failed step", func(t *testing.T) {
+		notes := &stubDeleter{name: "note"}
+		pdf := &stubDeleter{name: "pdf", err: stderrors.New("oss unavailable")}
+		user := &stubDeleter{name: "user"}
+		s := newService(3, notes, pdf, user)
+		deletion := schedule(t, "u1")
+
+		if completed := processDue(t, s); completed != 0 {
+			t.Fatalf("completed = %d, want 0", completed)
+		}
+		got := reload(t, deletion.Id)
+		if got.Status != model.AccountDeletionStatusScheduled || got.Attempts != 1 || got.ErrorMessage != "pdf: oss unavailable" {
+			t.Fatalf("deletion = %+v, want scheduled for retry", got)
+		}
+		steps := stepsOf(t, deletion.Id)
+		if steps["note"].Status != model.AccountDeletionStepStatusSucceeded || steps["pdf"].Status != model.AccountDeletionStepStatusFailed {
+			t.Fatalf("steps = %+v", steps)
+		}
+		if _, ok := steps["user"]; ok || user.callCount() != 0 {
+			t.Fatalf("user step ran after failed step")
+		}
+
+		// 失败的模块恢复后从该模块继续，已完成的模块不再执行
+		pdf.err = nil
+		if completed := processDue(t, s); completed != 1 {
+			t.Fatalf("completed = %d, want 1", completed)
+		}
+		if notes.callCount() != 1 || pdf.callCount() != 2 || user.callCount() != 1 {
+			t.Fatalf("calls note=%d pdf=%d user=%d, want 1, 2, 1", notes.callCount(), pdf.callCount(), user.callCount())
+		}
+		got = reload(t, deletion.Id)
+		if got.Status != model.AccountDeletionStatusCompleted || got.ErrorMessage != "" || got.Attempts != 2 {
+			t.Fatalf("deletion = %+v, want completed", got)
+		}
+		steps = stepsOf(t, deletion.Id)
+		if steps["pdf"].Status != model.AccountDeletionStepStatusSucceeded || steps["pdf"].Attempts != 2 || steps["note"].Attempts != 1 {
+			t.Fatalf("steps = %+v", steps)
+		}
+	})
+
+	t.Run("marks failed after max attempts", func(t *testing.T) {
+		pdf := &stubDeleter{name: "pdf", err: stderrors.New("oss unavailable")}
+		s := newService(2, pdf)
+		deletion := schedule(t, "u2")
+
+		processDue(t, s)
+		processDue(t, s)
+		got := reload(t, deletion.Id)
+		if got.Status != model.AccountDeletionStatusFailed || got.Attempts != 2 || got.FinishedAt.IsZero() {
+			t.Fatalf("deletion = %+v, want failed after 2 attempts", got)
+		}
+
+		// 失败的申请等待管理员重试，不再自动执行
+		processDue(t, s)
+		if pdf.callCount() != 2 {
+			t.Fatalf("calls = %d, want 2", pdf.callCount())
+		}
+	})
+
+	for i := 0; i < 20; i++ {
+		t.Run(fmt.Sprintf("cancel races claim round %d", i), func(t *testing.T) {
+			deleter := &stubDeleter{name: "note"}
+			s := newService(3, deleter)
+			userId := fmt.Sprintf("race-%d", i)
+			deletion := schedule(t, userId)
+
+			var wg sync.WaitGroup
+			start := make(chan struct{})
+			var cancelErr error
+			var completed int
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				<-start
+				_, cancelErr = s.Cancel(ctx, userId)
+			}()
+			go func() {
+				defer wg.Done()
+				<-start
+				completed, _ = s.ProcessDue(ctx)
+			}()
+			close(start)
+			wg.Wait()
+
+			// 撤销和执行只有一方成功
+			got := reload(t, deletion.Id)
+			if cancelErr == nil {
+				if completed != 0 || deleter.callCount() != 0 || got.Status != model.AccountDeletionStatusCancelled {
+					t.Fatalf("cancelled but completed = %d, calls = %d, status = %s", completed, deleter.callCount(), got.Status)
+				}
+				return
+			}
+			assertBizError(t, cancelErr, "account_deletion.errors.cannot_cancel")
+			if completed != 1 || deleter.callCount() != 1 || got.Status != model.AccountDeletionStatusCompleted {
+				t.Fatalf("cancel failed but completed = %d, calls = %d, status = %s", completed, deleter.callCount(), got.Status)
+			}
+		})
+	}
+}
+
+func assertBizError(t *testing.T, err error, msgID string) {
+	t.Helper()
+	var bizErr *errors.BizError
+	if !stderrors.As(err, &bizErr) || bizErr.MsgID != msgID {
+		t.Fatalf("err = %v, want %s", err, msgID)
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	transport "github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/audit"
+	"github.com/yb2020/odoc/services/audit/service"
+)
+
+// AuditLogAPI 审计日志管理接口
+type AuditLogAPI struct {
+	auditLogService *service.AuditLogService
+	logger          logging.Logger
+	tracer          opentracing.Tracer
+}
+
+func NewAuditLogAPI(auditLogService *service.AuditLogService, logger logging.Logger, tracer opentracing.Tracer) *AuditLogAPI {
+	return &AuditLogAPI{
+		auditLogService: auditLogService,
+		logger:          logger,
+		tracer:          tracer,
+	}
+}
+
+/*
+ * @api_path: /api/admin/audit/list
+ * @method: POST
+ * @content-type: application/json
+ * @summary: 分页查询审计日志
+ */
+func (api *AuditLogAPI) List(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuditLogAPI.List")
+	defer span.Finish()
+
+	req := &pb.ListAuditLogRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析审计日志查询请求失败", "error", err)
+		c.Error(err)
+		return
+	}
+	resp, err := api.auditLogService.List(ctx, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", resp)
+}
+
+/*
+ * @api_path: /api/admin/audit/export
+ * @method: POST
+ * @content-type: application/json
+ * @summary: 导出审计日志CSV
+ */
+func (api *AuditLogAPI) Export(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuditLogAPI.Export")
+	defer span.Finish()
+
+	req := &pb.ExportAuditLogRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析审计日志导出请求失败", "error", err)
+		c.Error(err)
+		return
+	}
+	file, err := api.auditLogService.Export(ctx, req)
+	if err != nil {
+		api.logger.Error("msg", "导出审计日志失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", file.FileName))
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
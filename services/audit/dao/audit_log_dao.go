@@ -0,0 +1,116 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/audit/model"
+	"gorm.io/gorm"
+)
+
+// AuditLogFilter 审计日志查询条件，字段为零值时不限制
+type AuditLogFilter struct {
+	ActorId    string
+	Action     string
+	EntityType string
+	EntityId   string
+	Source     string
+	RequestId  string
+	ClientIp   string
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+// AuditLogDAO GORM实现的审计日志DAO
+type AuditLogDAO struct {
+	*baseDao.GormBaseDAO[model.AuditLog]
+	logger logging.Logger
+}
+
+// NewAuditLogDAO 创建一个新的审计日志DAO
+func NewAuditLogDAO(db *gorm.DB, logger logging.Logger) *AuditLogDAO {
+	return &AuditLogDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.AuditLog](db, logger),
+		logger:      logger,
+	}
+}
+
+// GetPage 按发生时间倒序分页查询审计日志
+func (d *AuditLogDAO) GetPage(ctx context.Context, filter *AuditLogFilter, page int, size int) ([]model.AuditLog, int64, error) {
+	db := d.filterQuery(ctx, filter)
+
+	var total int64
+	if err := db.Model(&model.AuditLog{}).Count(&total).Error; err != nil {
+		d.logger.Error("msg", "获取审计日志总数失败", "error", err.Error())
+		return nil, 0, err
+	}
+
+	var entities []model.AuditLog
+	result := db.Order("occurred_at desc, id desc").Offset((page - 1) * size).Limit(size).Find(&entities)
+	if result.Error != nil {
+		d.logger.Error("msg", "分页查询审计日志失败", "error", result.Error.Error())
+		return nil, 0, result.Error
+	}
+	return entities, total, nil
+}
+
+// GetList 按发生时间倒序查询审计日志，最多返回limit条
+func (d *AuditLogDAO) GetList(ctx context.Context, filter *AuditLogFilter, limit int) ([]model.AuditLog, error) {
+	var entities []model.AuditLog
+	result := d.filterQuery(ctx, filter).Order("occurred_at desc, id desc").Limit(limit).Find(&entities)
+	if result.Error != nil {
+		d.logger.Error("msg", "查询审计日志失败", "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return entities, nil
+}
+
+// DeleteBeforeOccurredAt 物理删除发生时间早于指定时间的审计日志，每次最多删除limit条，返回删除条数
+func (d *AuditLogDAO) DeleteBeforeOccurredAt(ctx context.Context, occurredAt time.Time, limit int) (int64, error) {
+	db := d.GetDB(ctx)
+	subQuery := db.Model(&model.AuditLog{}).Select("id").Where("occurred_at < ?", occurredAt).Limit(limit)
+	result := db.Where("id IN (?)", subQuery).Delete(&model.AuditLog{})
+	if result.Error != nil {
+		d.logger.Error("msg", "清理过期审计日志失败", "occurredAt", occurredAt, "error", result.Error.Error())
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// filterQuery 按查询条件构造查询
+func (d *AuditLogDAO) filterQuery(ctx context.Context, filter *AuditLogFilter) *gorm.DB {
+	db := d.GetDB(ctx).Where("is_deleted = false")
+	if filter == nil {
+		return db
+	}
+	if filter.ActorId != "" {
+		db = db.Where("actor_id = ?", filter.ActorId)
+	}
+	if filter.Action != "" {
+		db = db.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		db = db.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityId != "" {
+		db = db.Where("entity_id = ?", filter.EntityId)
+	}
+	if filter.Source != "" {
+		db = db.Where("source = ?", filter.Source)
+	}
+	if filter.RequestId != "" {
+		db = db.Where("request_id = ?", filter.RequestId)
+	}
+	if filter.ClientIp != "" {
+		db = db.Where("client_ip = ?", filter.ClientIp)
+	}
+	if !filter.StartTime.IsZero() {
+		db = db.Where("occurred_at >= ?", filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		db = db.Where("occurred_at < ?", filter.EndTime)
+	}
+	return db
+}
@@ -0,0 +1,57 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/services/audit/service"
+)
+
+// AuditLogRetentionJob 审计日志过期清理任务
+type AuditLogRetentionJob struct {
+	logger          logging.Logger
+	spec            string                 // 任务的cron表达式，6字段标准cron表达式
+	key             string                 // 任务的锁key，必须是唯一的unique-job-key
+	expiry          time.Duration          // 任务的锁过期时间
+	lockOpts        *scheduler.LockOptions // 任务的锁选项
+	auditLogService *service.AuditLogService
+}
+
+func NewAuditLogRetentionJob(logger logging.Logger, cfg *config.Config, auditLogService *service.AuditLogService) *AuditLogRetentionJob {
+	spec := cfg.Scheduler.Jobs.AuditLogRetentionJob.Spec
+	key := cfg.Scheduler.Jobs.AuditLogRetentionJob.Key
+	expiry := time.Duration(cfg.Scheduler.Jobs.AuditLogRetentionJob.Expiry) * time.Second
+	lockOpts := &scheduler.LockOptions{
+		Key:    key,
+		Expiry: expiry,
+	}
+	return &AuditLogRetentionJob{logger: logger, spec: spec, key: key, expiry: expiry, lockOpts: lockOpts, auditLogService: auditLogService}
+}
+
+// Spec 获取任务的cron表达式
+func (j *AuditLogRetentionJob) Spec() string {
+	return j.spec
+}
+
+// LockOpts 获取任务的锁选项
+func (j *AuditLogRetentionJob) LockOpts() *scheduler.LockOptions {
+	return j.lockOpts
+}
+
+// NewUserContext 清理任务不涉及用户数据，直接返回原上下文
+func (j *AuditLogRetentionJob) NewUserContext(ctx context.Context, userId string) context.Context {
+	return ctx
+}
+
+// Run 执行任务，在执行任务前会获取锁，执行任务后会释放锁
+func (j *AuditLogRetentionJob) Run() {
+	deleted, err := j.auditLogService.CleanupExpiredLogs(context.Background())
+	if err != nil {
+		j.logger.Error("msg", "Audit log retention job failed", "deleted", deleted, "error", err)
+		return
+	}
+	j.logger.Info("msg", "Audit log retention job success", "deleted", deleted)
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// AuditLog 审计日志实体，只追加不修改，过期后由清理任务物理删除
+type AuditLog struct {
+	model.BaseModel           // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	ActorId         string    `json:"actorId" gorm:"column:actor_id;size:36;index"`                            // 操作人用户ID，未登录为空
+	Action          string    `json:"action" gorm:"column:action;size:32;index"`                               // 操作类型
+	EntityType      string    `json:"entityType" gorm:"column:entity_type;size:64;index:idx_audit_log_entity"` // 目标实体类型
+	EntityId        string    `json:"entityId" gorm:"column:entity_id;size:255;index:idx_audit_log_entity"`    // 目标实体ID
+	Before          string    `json:"before" gorm:"column:before_data;type:text"`                              // 变更前的数据快照（JSON）
+	After           string    `json:"after" gorm:"column:after_data;type:text"`                                // 变更后的数据快照（JSON）
+	Changes         string    `json:"changes" gorm:"column:changes;type:text"`                                 // 字段级差异（JSON数组）
+	Source          string    `json:"source" gorm:"column:source;size:16"`                                     // 记录来源 service 或 http
+	Method          string    `json:"method" gorm:"column:method;size:16"`                                     // HTTP方法
+	Path            string    `json:"path" gorm:"column:path;size:512"`                                        // 请求路径
+	StatusCode      int       `json:"statusCode" gorm:"column:status_code"`                                    // 响应状态码
+	ClientIp        string    `json:"clientIp" gorm:"column:client_ip;size:64"`                                // 客户端IP
+	UserAgent       string    `json:"userAgent" gorm:"column:user_agent;type:text"`                            // User-Agent
+	RequestId       string    `json:"requestId" gorm:"column:request_id;size:100;index"`                       // 请求ID
+	OccurredAt      time.Time `json:"occurredAt" gorm:"column:occurred_at;index"`                              // 发生时间
+}
+
+// TableName 返回表名
+func (AuditLog) TableName() string {
+	return "t_audit_log"
+}
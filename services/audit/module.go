@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/audit"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/middleware"
+	"github.com/yb2020/odoc/pkg/registry"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/services/audit/api"
+	"github.com/yb2020/odoc/services/audit/dao"
+	"github.com/yb2020/odoc/services/audit/job"
+	"github.com/yb2020/odoc/services/audit/service"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+)
+
+// 编译时类型检查：确保 AuditModule 实现了 registry.Module 接口
+var _ registry.Module = (*AuditModule)(nil)
+
+// Module 导出模块实例，用于自动发现和注册
+var Module = &AuditModule{}
+
+// AuditModule 审计日志模块
+type AuditModule struct {
+	db              *gorm.DB
+	logger          logging.Logger
+	tracer          opentracing.Tracer
+	config          *config.Config
+	authMiddleware  *middleware.AuthMiddleware
+	auditLogAPI     *api.AuditLogAPI
+	auditLogService *service.AuditLogService
+}
+
+// NewAuditModule 创建审计日志模块
+func NewAuditModule(db *gorm.DB,
+	config *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	authMiddleware *middleware.AuthMiddleware,
+) *AuditModule {
+	return &AuditModule{
+		db:             db,
+		logger:         logger,
+		tracer:         tracer,
+		config:         config,
+		authMiddleware: authMiddleware,
+	}
+}
+
+// Name 返回模块名称
+func (m *AuditModule) Name() string {
+	return "audit"
+}
+
+// Initialize 初始化模块，开启审计日志时把审计日志服务设置为全局落地实现
+func (m *AuditModule) Initialize() error {
+	m.logger.Info("msg", "初始化审计日志模块")
+	auditConfig := &m.config.Audit
+	auditLogDAO := dao.NewAuditLogDAO(m.db, m.logger)
+
+	m.auditLogService = service.NewAuditLogService(auditConfig, m.logger, m.tracer, auditLogDAO)
+	m.auditLogAPI = api.NewAuditLogAPI(m.auditLogService, m.logger, m.tracer)
+	if auditConfig.Enabled {
+		audit.SetRecorder(m.auditLogService)
+	} else {
+		audit.SetRecorder(nil)
+		m.logger.Info("msg", "审计日志未开启，业务代码和中间件的记录均为空操作")
+	}
+	return nil
+}
+
+// GetAuditLogService 获取审计日志服务
+func (m *AuditModule) GetAuditLogService() *service.AuditLogService {
+	return m.auditLogService
+}
+
+// Shutdown 关闭模块
+func (m *AuditModule) Shutdown() error {
+	m.logger.Info("msg", "关闭审计日志模块")
+	audit.SetRecorder(nil)
+	return nil
+}
+
+// RegisterGRPC 注册gRPC服务
+func (m *AuditModule) RegisterGRPC(server *grpc.Server) {
+	// 审计日志模块没有gRPC服务，不需要注册
+	m.logger.Debug("msg", "审计日志模块没有gRPC服务，跳过注册")
+}
+
+// RegisterJobSchedulers 注册Job定时任务
+func (m *AuditModule) RegisterJobSchedulers(scheduler *scheduler.Scheduler) {
+	if scheduler == nil {
+		m.logger.Debug("msg", "调度器未启用，审计日志模块跳过Job注册")
+		return
+	}
+	m.logger.Debug("msg", "审计日志模块注册Job定时任务")
+	retentionJob := job.NewAuditLogRetentionJob(m.logger, m.config, m.auditLogService)
+	scheduler.RegisterJobs(retentionJob)
+}
+
+// RegisterProviders 注册Provider
+func (m *AuditModule) RegisterProviders() {
+	m.logger.Debug("msg", "审计日志模块没有Provider，跳过注册")
+}
+
+// RegisterRoutes 注册路由
+func (m *AuditModule) RegisterRoutes(r *gin.Engine) {
+	adminGroup := r.Group("/api/admin/audit")
+	adminGroup.Use(m.authMiddleware.AuthRequired())
+	{
+		adminGroup.POST("/list", m.auditLogAPI.List)
+		adminGroup.POST("/export", m.auditLogAPI.Export)
+	}
+}
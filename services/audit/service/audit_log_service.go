@@ -0,0 +1,308 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/audit"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	pb "github.com/yb2020/odoc/proto/gen/go/audit"
+	"github.com/yb2020/odoc/services/audit/dao"
+	"github.com/yb2020/odoc/services/audit/model"
+)
+
+const (
+	auditLogDefaultPageSize = 20
+	auditLogMaxPageSize     = 100
+	auditLogMaxUserAgentLen = 512
+)
+
+// 编译时类型检查：确保 AuditLogService 实现了 audit.Recorder 接口
+var _ audit.Recorder = (*AuditLogService)(nil)
+
+// auditLogCsvHeader 导出CSV的表头，列顺序与 auditLogCsvRow 一致
+var auditLogCsvHeader = []string{
+	"occurredAt", "actorId", "action", "entityType", "entityId", "source", "method", "path",
+	"statusCode", "clientIp", "userAgent", "requestId", "changes", "before", "after",
+}
+
+// AuditLogFile 导出的审计日志文件
+type AuditLogFile struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// AuditLogService 审计日志服务，负责写入、查询、导出和过期清理
+type AuditLogService struct {
+	cfg         *config.AuditConfig
+	auditLogDAO *dao.AuditLogDAO
+	logger      logging.Logger
+	tracer      opentracing.Tracer
+}
+
+// NewAuditLogService 创建审计日志服务
+func NewAuditLogService(cfg *config.AuditConfig, logger logging.Logger, tracer opentracing.Tracer, auditLogDAO *dao.AuditLogDAO) *AuditLogService {
+	return &AuditLogService{
+		cfg:         cfg,
+		auditLogDAO: auditLogDAO,
+		logger:      logger,
+		tracer:      tracer,
+	}
+}
+
+// Record 写入一条审计日志，实现 audit.Recorder
+// 请求结束或被取消后仍需写入，使用不会被取消的上下文
+func (s *AuditLogService) Record(ctx context.Context, entry *audit.Entry) error {
+	ctx = context.WithoutCancel(ctx)
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuditLogService.Record")
+	defer span.Finish()
+
+	auditLog := &model.AuditLog{
+		ActorId:    entry.ActorId,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityId:   entry.EntityId,
+		Before:     audit.Snapshot(entry.Before),
+		After:      audit.Snapshot(entry.After),
+		Source:     entry.Source,
+		Method:     entry.Method,
+		Path:       entry.Path,
+		StatusCode: entry.StatusCode,
+		ClientIp:   entry.ClientIp,
+		UserAgent:  truncate(entry.UserAgent, auditLogMaxUserAgentLen),
+		RequestId:  entry.RequestId,
+		OccurredAt: entry.OccurredAt,
+	}
+	if len(entry.Changes) > 0 {
+		if data, err := json.Marshal(entry.Changes); err == nil {
+			auditLog.Changes = string(data)
+		}
+	}
+	if err := s.auditLogDAO.Save(ctx, auditLog); err != nil {
+		s.logger.Error("msg", "写入审计日志失败", "action", entry.Action, "entityType", entry.EntityType,
+			"entityId", entry.EntityId, "actorId", entry.ActorId, "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// List 分页查询审计日志
+func (s *AuditLogService) List(ctx context.Context, req *pb.ListAuditLogRequest) (*pb.ListAuditLogResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuditLogService.List")
+	defer span.Finish()
+
+	page := max(int(req.CurrentPage), 1)
+	size := int(req.PageSize)
+	if size <= 0 {
+		size = auditLogDefaultPageSize
+	}
+	size = min(size, auditLogMaxPageSize)
+
+	auditLogs, total, err := s.auditLogDAO.GetPage(ctx, toDaoFilter(req.Filter), page, size)
+	if err != nil {
+		return nil, errors.Biz("audit.errors.query_failed")
+	}
+	resp := &pb.ListAuditLogResponse{Total: total}
+	for i := range auditLogs {
+		resp.Entries = append(resp.Entries, toAuditLogEntry(&auditLogs[i]))
+	}
+	return resp, nil
+}
+
+// Export 按条件导出审计日志CSV，最多导出配置的条数
+func (s *AuditLogService) Export(ctx context.Context, req *pb.ExportAuditLogRequest) (*AuditLogFile, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuditLogService.Export")
+	defer span.Finish()
+
+	loc := time.Local
+	if req.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(req.Timezone); err != nil {
+			return nil, errors.Biz("invalid timezone")
+		}
+	}
+	maxRows := s.cfg.MaxExportRows
+	if maxRows <= 0 {
+		maxRows = 50000
+	}
+	auditLogs, err := s.auditLogDAO.GetList(ctx, toDaoFilter(req.Filter), maxRows)
+	if err != nil {
+		return nil, errors.Biz("audit.errors.query_failed")
+	}
+
+	data, err := renderAuditLogCsv(auditLogs, loc)
+	if err != nil {
+		s.logger.Error("msg", "生成审计日志CSV失败", "error", err.Error())
+		return nil, err
+	}
+	fileName := "audit-log-" + time.Now().In(loc).Format("20060102150405") + ".csv"
+	return &AuditLogFile{FileName: fileName, ContentType: "text/csv; charset=utf-8", Data: data}, nil
+}
+
+// CleanupExpiredLogs 按保留天数分批物理删除过期审计日志，返回删除总数
+func (s *AuditLogService) CleanupExpiredLogs(ctx context.Context) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuditLogService.CleanupExpiredLogs")
+	defer span.Finish()
+
+	if s.cfg.RetentionDays <= 0 {
+		return 0, nil
+	}
+	deleteSize := s.cfg.RetentionDeleteSize
+	if deleteSize <= 0 {
+		deleteSize = 5000
+	}
+	cutoff := time.Now().AddDate(0, 0, -s.cfg.RetentionDays)
+
+	var total int64
+	for {
+		deleted, err := s.auditLogDAO.DeleteBeforeOccurredAt(ctx, cutoff, deleteSize)
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+		if deleted < int64(deleteSize) {
+			break
+		}
+	}
+	if total > 0 {
+		s.logger.Info("msg", "清理过期审计日志完成", "cutoff", cutoff, "deleted", total)
+	}
+	return total, nil
+}
+
+// renderAuditLogCsv 生成CSV格式的审计日志，带UTF-8 BOM以便表格软件正确识别中文
+func renderAuditLogCsv(auditLogs []model.AuditLog, loc *time.Location) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\xEF\xBB\xBF")
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(auditLogCsvHeader); err != nil {
+		return nil, err
+	}
+	for i := range auditLogs {
+		if err := writer.Write(auditLogCsvRow(&auditLogs[i], loc)); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// auditLogCsvRow 审计日志的一行，列顺序与 auditLogCsvHeader 一致
+func auditLogCsvRow(auditLog *model.AuditLog, loc *time.Location) []string {
+	statusCode := ""
+	if auditLog.StatusCode > 0 {
+		statusCode = strconv.Itoa(auditLog.StatusCode)
+	}
+	return []string{
+		auditLog.OccurredAt.In(loc).Format("2006-01-02 15:04:05"),
+		auditLog.ActorId,
+		auditLog.Action,
+		auditLog.EntityType,
+		auditLog.EntityId,
+		auditLog.Source,
+		auditLog.Method,
+		auditLog.Path,
+		statusCode,
+		auditLog.ClientIp,
+		auditLog.UserAgent,
+		auditLog.RequestId,
+		auditLog.Changes,
+		auditLog.Before,
+		auditLog.After,
+	}
+}
+
+// toAuditLogEntry 转换为接口返回的审计日志
+func toAuditLogEntry(auditLog *model.AuditLog) *pb.AuditLogEntry {
+	entry := &pb.AuditLogEntry{
+		Id:         auditLog.Id,
+		ActorId:    auditLog.ActorId,
+		Action:     auditLog.Action,
+		EntityType: auditLog.EntityType,
+		EntityId:   auditLog.EntityId,
+		Before:     auditLog.Before,
+		After:      auditLog.After,
+		Source:     auditLog.Source,
+		Method:     auditLog.Method,
+		Path:       auditLog.Path,
+		StatusCode: int32(auditLog.StatusCode),
+		ClientIp:   auditLog.ClientIp,
+		UserAgent:  auditLog.UserAgent,
+		RequestId:  auditLog.RequestId,
+		OccurredAt: uint64(auditLog.OccurredAt.UnixMilli()),
+	}
+	if auditLog.Changes != "" {
+		var changes []audit.FieldChange
+		if err := json.Unmarshal([]byte(auditLog.Changes), &changes); err == nil {
+			for _, change := range changes {
+				entry.Changes = append(entry.Changes, &pb.AuditLogChange{
+					Field:  change.Field,
+					Before: jsonString(change.Before),
+					After:  jsonString(change.After),
+				})
+			}
+		}
+	}
+	return entry
+}
+
+// toDaoFilter 转换查询条件
+func toDaoFilter(filter *pb.AuditLogFilter) *dao.AuditLogFilter {
+	if filter == nil {
+		return nil
+	}
+	return &dao.AuditLogFilter{
+		ActorId:    filter.ActorId,
+		Action:     filter.Action,
+		EntityType: filter.EntityType,
+		EntityId:   filter.EntityId,
+		Source:     filter.Source,
+		RequestId:  filter.RequestId,
+		ClientIp:   filter.ClientIp,
+		StartTime:  millisToTime(filter.StartTime),
+		EndTime:    millisToTime(filter.EndTime),
+	}
+}
+
+// jsonString 将字段值序列化为JSON，值为空时返回空串
+func jsonString(value any) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// millisToTime 毫秒时间戳转时间，0 返回零值表示不限制
+func millisToTime(millis uint64) time.Time {
+	if millis == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(millis))
+}
+
+// truncate 按字节截断字符串，不截断多字节字符
+func truncate(value string, maxLength int) string {
+	if len(value) <= maxLength {
+		return value
+	}
+	for maxLength > 0 && !utf8.RuneStart(value[maxLength]) {
+		maxLength--
+	}
+	return value[:maxLength]
+}
@@ -63,3 +63,24 @@ func (d *DocClassifyRelationDAO) GetByClassifyIdAndDocId(ctx context.Context, cl
 	}
 	return docClassifyRelations, nil
 }
+
+// GetByUserIdAndDocId 获取用户文档的分类关系
+func (d *DocClassifyRelationDAO) GetByUserIdAndDocId(ctx context.Context, userId string, docId string) ([]model.DocClassifyRelation, error) {
+	var docClassifyRelations []model.DocClassifyRelation
+	result := d.GetDB(ctx).Where("user_id = ? and doc_id = ?", userId, docId).Find(&docClassifyRelations)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取文档分类关系失败", "userId", userId, "docId", docId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return docClassifyRelations, nil
+}
+
+// RemoveByUserIdAndDocId 物理删除用户文档的全部分类关系
+func (d *DocClassifyRelationDAO) RemoveByUserIdAndDocId(ctx context.Context, userId string, docId string) error {
+	result := d.GetDB(ctx).Where("user_id = ? and doc_id = ?", userId, docId).Delete(&model.DocClassifyRelation{})
+	if result.Error != nil {
+		d.logger.Error("msg", "删除文档分类关系失败", "userId", userId, "docId", docId, "error", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
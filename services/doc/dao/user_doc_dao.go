@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"errors"
+	"time"
 
 	baseDao "github.com/yb2020/odoc/pkg/dao"
 	"github.com/yb2020/odoc/pkg/logging"
@@ -102,6 +103,42 @@ func (d *UserDocDAO) GetUserDocByPdfId(ctx context.Context, pdfId string) (*mode
 	return &doc, nil
 }
 
+// CountByPdfIdExcludeUser 统计其他用户引用该PDF的文档数，包括已逻辑删除、仍可从回收站恢复的文档
+func (d *UserDocDAO) CountByPdfIdExcludeUser(ctx context.Context, pdfId string, userId string) (int64, error) {
+	var count int64
+	result := d.GetDB(ctx).Model(&model.UserDoc{}).Where("pdf_id = ? and user_id <> ?", pdfId, userId).Count(&count)
+	if result.Error != nil {
+		d.logger.Error("msg", "统计PDF的引用文档数失败", "pdfId", pdfId, "error", result.Error.Error())
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// CountByPdfId 统计引用该PDF的文档数，包括已逻辑删除、仍可从回收站恢复的文档
+func (d *UserDocDAO) CountByPdfId(ctx context.Context, pdfId string) (int64, error) {
+	var count int64
+	result := d.GetDB(ctx).Model(&model.UserDoc{}).Where("pdf_id = ?", pdfId).Count(&count)
+	if result.Error != nil {
+		d.logger.Error("msg", "统计PDF的引用文档数失败", "pdfId", pdfId, "error", result.Error.Error())
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// GetByIdAndUserIdIncludeDeleted 获取用户的文档，包括已逻辑删除的
+func (d *UserDocDAO) GetByIdAndUserIdIncludeDeleted(ctx context.Context, id string, userId string) (*model.UserDoc, error) {
+	var doc model.UserDoc
+	result := d.GetDB(ctx).Where("id = ? AND user_id = ?", id, userId).First(&doc)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取用户文档失败", "id", id, "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &doc, nil
+}
+
 // GetAllUserDocsByUserID 获取用户所有未删除的文档列表（不分页）
 func (d *UserDocDAO) GetAllUserDocsByUserID(ctx context.Context, userID string) ([]model.UserDoc, error) {
 	var docs []model.UserDoc
@@ -156,6 +193,17 @@ func (d *UserDocDAO) GetUserDocsByUserIDAndLatestReadTimeIsNotNull(ctx context.C
 	return docs, nil
 }
 
+// GetReadFinishedUserDocsByTimeRange 查询在[start, end)时间段内标记为已读的用户文档
+func (d *UserDocDAO) GetReadFinishedUserDocsByTimeRange(ctx context.Context, start, end time.Time) ([]model.UserDoc, error) {
+	var docs []model.UserDoc
+	result := d.GetDB(ctx).Where("is_deleted = false AND read_finished_time >= ? AND read_finished_time < ?", start, end).Find(&docs)
+	if result.Error != nil {
+		d.logger.Error("msg", "根据已读时间查询用户文档列表失败", "start", start, "end", end, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return docs, nil
+}
+
 // GetUsersNotParsedByFileSHA256 根据文件SHA256查询未解析完成的用户ID列表
 func (d *UserDocDAO) GetUsersNotParsedByFileSHA256(ctx context.Context, fileSHA256 string, parseStatus int32) ([]string, error) {
 	var userIds []string
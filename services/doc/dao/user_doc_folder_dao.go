@@ -65,6 +65,50 @@ func (d *UserDocFolderDAO) GetUserDocFoldersByUserID(ctx context.Context, userID
 	return folders, nil
 }
 
+// GetByIdAndUserIdIncludeDeleted 获取用户的文件夹，包括已逻辑删除的
+func (d *UserDocFolderDAO) GetByIdAndUserIdIncludeDeleted(ctx context.Context, id string, userId string) (*model.UserDocFolder, error) {
+	var folder model.UserDocFolder
+	result := d.GetDB(ctx).Where("id = ? and user_id = ?", id, userId).First(&folder)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取用户文档文件夹失败", "id", id, "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &folder, nil
+}
+
+// GetByUserIdAndParentId 获取用户在指定文件夹下未删除的子文件夹，根目录的父文件夹ID为 0
+func (d *UserDocFolderDAO) GetByUserIdAndParentId(ctx context.Context, userId string, parentId string) ([]model.UserDocFolder, error) {
+	var folders []model.UserDocFolder
+	result := d.GetDB(ctx).Where("user_id = ? and parent_id = ? and is_deleted = false", userId, parentId).Find(&folders)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取用户文档文件夹失败", "userId", userId, "parentId", parentId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return folders, nil
+}
+
+// RestoreFolder 恢复逻辑删除的文件夹，同时更新父文件夹和名称
+func (d *UserDocFolderDAO) RestoreFolder(ctx context.Context, id string, parentId string, name string) error {
+	updates := map[string]interface{}{
+		"is_deleted": false,
+		"parent_id":  parentId,
+		"name":       name,
+		"updated_at": time.Now(),
+	}
+	if uc := userContext.GetUserContext(ctx); uc != nil && uc.UserId != "" {
+		updates["modifier_id"] = uc.UserId
+	}
+	result := d.GetDB(ctx).Model(&model.UserDocFolder{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		d.logger.Error("msg", "恢复文件夹失败", "id", id, "error", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
 // GetUserDocFoldersByParentID 根据父文件夹ID获取用户文档文件夹
 func (d *UserDocFolderDAO) GetUserDocFoldersByParentID(ctx context.Context, parentID string) ([]model.UserDocFolder, error) {
 	var folders []model.UserDocFolder
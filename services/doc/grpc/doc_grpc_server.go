@@ -0,0 +1,243 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	pb "github.com/yb2020/odoc/proto/gen/go/doc"
+	docBean "github.com/yb2020/odoc/services/doc/bean"
+	"github.com/yb2020/odoc/services/doc/service"
+	paperService "github.com/yb2020/odoc/services/paper/service"
+	pdfInterface "github.com/yb2020/odoc/services/pdf/interfaces"
+)
+
+// DocGRPCServer 文献服务的gRPC服务器，与 UserDocAPI、UserDocFolderAPI 调用相同的服务层方法
+type DocGRPCServer struct {
+	pb.UnimplementedDocServiceServer
+	userDocService       *service.UserDocService
+	userDocFolderService *service.UserDocFolderService
+	paperService         *paperService.PaperService
+	paperPdfService      pdfInterface.IPaperPdfService
+	logger               logging.Logger
+	tracer               opentracing.Tracer
+}
+
+// NewDocGRPCServer 创建文献gRPC服务器
+func NewDocGRPCServer(logger logging.Logger, tracer opentracing.Tracer, userDocService *service.UserDocService,
+	userDocFolderService *service.UserDocFolderService, paperService *paperService.PaperService) *DocGRPCServer {
+	return &DocGRPCServer{
+		userDocService:       userDocService,
+		userDocFolderService: userDocFolderService,
+		paperService:         paperService,
+		logger:               logger,
+		tracer:               tracer,
+	}
+}
+
+// SetPaperPdfService 设置pdf服务，用于解决循环依赖问题
+func (s *DocGRPCServer) SetPaperPdfService(paperPdfService pdfInterface.IPaperPdfService) error {
+	if paperPdfService == nil {
+		return errors.Biz("paperPdfService cannot be nil")
+	}
+	s.paperPdfService = paperPdfService
+	return nil
+}
+
+// RegisterServer 注册gRPC服务
+func (s *DocGRPCServer) RegisterServer(server *grpc.Server) {
+	pb.RegisterDocServiceServer(server, s)
+}
+
+// GetDocList 获取用户文档列表
+func (s *DocGRPCServer) GetDocList(ctx context.Context, req *pb.GetDocListReq) (*pb.GetDocListResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocGRPCServer.GetDocList")
+	defer span.Finish()
+
+	userId, _ := userContext.GetUserID(ctx)
+	req.UserId = &userId
+	resp, err := s.userDocService.GetDocList(ctx, req)
+	if err != nil {
+		s.logger.Error("msg", "gRPC获取用户文档列表失败", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetUserDoc 获取文献详情，只能获取自己的文献
+func (s *DocGRPCServer) GetUserDoc(ctx context.Context, req *pb.GetUserDocRequest) (*pb.DocDetailInfo, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocGRPCServer.GetUserDoc")
+	defer span.Finish()
+
+	userId, _ := userContext.GetUserID(ctx)
+	userDoc, err := s.userDocService.GetUserDocByNoteId(ctx, req.NoteId)
+	if err != nil {
+		return nil, err
+	}
+	if userId == "" || userDoc == nil || userDoc.UserId != userId {
+		return nil, status.Error(codes.PermissionDenied, "文献不存在或者不属于您本人")
+	}
+	resp, err := s.userDocService.GetUserDocDetailInfoById(ctx, req.NoteId)
+	if err != nil {
+		s.logger.Error("msg", "gRPC获取文献详情失败", "noteId", req.NoteId, "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+// FastUpload 文件秒传，根据文件SHA256复用已解析的PDF为当前用户创建文献
+func (s *DocGRPCServer) FastUpload(ctx context.Context, req *pb.HandleFileFastUploadReq) (*pb.HandleFileFastUploadResp, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocGRPCServer.FastUpload")
+	defer span.Finish()
+
+	if req.NeedUpload {
+		return nil, status.Error(codes.InvalidArgument, "file fast upload error , need upload is false")
+	}
+	if req.OssInfo == nil || req.OssInfo.FileSHA256 == "" {
+		return nil, status.Error(codes.InvalidArgument, "file fast upload error , file sha256 is empty")
+	}
+	if s.paperPdfService == nil {
+		return nil, status.Error(codes.Unavailable, "paper pdf service is not ready")
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	paperPdf, err := s.paperPdfService.GetByFileSHA256(ctx, req.OssInfo.FileSHA256)
+	if err != nil {
+		return nil, err
+	}
+	userDoc, err := s.userDocService.GetByUserIdAndPdfId(ctx, paperPdf.CreatorId, paperPdf.Id)
+	if err != nil {
+		return nil, err
+	}
+	if userDoc == nil {
+		return nil, status.Error(codes.NotFound, "file upload error , document not found")
+	}
+	paper, err := s.paperService.GetPaperById(ctx, paperPdf.PaperId)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.userDocService.HandleFileFastUpload(ctx, userId, req.OssInfo.FileName, userDoc, paperPdf, paper)
+	if err != nil {
+		s.logger.Error("msg", "gRPC文件秒传失败", "sha256", req.OssInfo.FileSHA256, "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RenameDoc 重命名用户文献
+func (s *DocGRPCServer) RenameDoc(ctx context.Context, req *pb.RenameUserDocReq) (*emptypb.Empty, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocGRPCServer.RenameDoc")
+	defer span.Finish()
+
+	if req.DocId == "" || req.DocId == "0" || req.DocName == "" {
+		return nil, status.Error(codes.InvalidArgument, "rename user doc error , doc id and doc name is empty")
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	if err := s.userDocService.RenameUserDoc(ctx, req.DocId, req.DocName, userId); err != nil {
+		s.logger.Error("msg", "gRPC重命名文献失败", "docId", req.DocId, "error", err.Error())
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// UpdateDocRemark 更新文献备注
+func (s *DocGRPCServer) UpdateDocRemark(ctx context.Context, req *pb.UpdateDocRemarkReq) (*emptypb.Empty, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocGRPCServer.UpdateDocRemark")
+	defer span.Finish()
+
+	if req.GetDocId() == "" || req.GetDocId() == "0" {
+		return nil, status.Error(codes.InvalidArgument, "doc id is empty")
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	_, err := s.userDocService.UpdateUserDocByCustomType(ctx, userId, req.GetDocId(), &docBean.UserDocUpdateRequest{
+		UpdateType: docBean.UpdateTypeRemark,
+		Remark:     req.GetRemark(),
+	})
+	if err != nil {
+		s.logger.Error("msg", "gRPC更新文献备注失败", "docId", req.GetDocId(), "error", err.Error())
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteDocs 删除用户文献
+func (s *DocGRPCServer) DeleteDocs(ctx context.Context, req *pb.DeleteDocReq) (*emptypb.Empty, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocGRPCServer.DeleteDocs")
+	defer span.Finish()
+
+	if len(req.DocIds) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "doc ids is empty")
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	if err := s.userDocService.DeleteUserDocs(ctx, req.DocIds, userId); err != nil {
+		s.logger.Error("msg", "gRPC删除文献失败", "error", err.Error())
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// CreateFolder 创建文件夹
+func (s *DocGRPCServer) CreateFolder(ctx context.Context, req *pb.CreateUserDocFolderRequest) (*pb.CreateUserDocFolderResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocGRPCServer.CreateFolder")
+	defer span.Finish()
+
+	userId, _ := userContext.GetUserID(ctx)
+	resp, err := s.userDocFolderService.CreateUserDocFolder(ctx, userId, req)
+	if err != nil {
+		s.logger.Error("msg", "gRPC创建文件夹失败", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateFolder 更新文件夹
+func (s *DocGRPCServer) UpdateFolder(ctx context.Context, req *pb.UpdateUserDocFolderReq) (*emptypb.Empty, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocGRPCServer.UpdateFolder")
+	defer span.Finish()
+
+	if req.FolderId == "" || req.FolderId == "0" {
+		return nil, status.Error(codes.InvalidArgument, "文件夹ID不能为空")
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	if err := s.userDocFolderService.UpdateUserDocFolder(ctx, req, userId); err != nil {
+		s.logger.Error("msg", "gRPC更新文件夹失败", "folderId", req.FolderId, "error", err.Error())
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteFolder 删除文件夹
+func (s *DocGRPCServer) DeleteFolder(ctx context.Context, req *pb.DeleteUserDocFolderReq) (*emptypb.Empty, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocGRPCServer.DeleteFolder")
+	defer span.Finish()
+
+	userId, _ := userContext.GetUserID(ctx)
+	if err := s.userDocFolderService.DeleteUserDocFolder(ctx, req, userId); err != nil {
+		s.logger.Error("msg", "gRPC删除文件夹失败", "error", err.Error())
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// MoveToFolder 移动文件夹或文献到另一个文件夹
+func (s *DocGRPCServer) MoveToFolder(ctx context.Context, req *pb.MoveDocOrFolderToAnotherFolderReq) (*emptypb.Empty, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocGRPCServer.MoveToFolder")
+	defer span.Finish()
+
+	if len(req.MovedFolderIds) == 0 && len(req.MovedDocItems) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "移动的文件夹或文献不能为空")
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	if err := s.userDocFolderService.MoveDocOrFolderToAnotherFolder(ctx, req, userId); err != nil {
+		s.logger.Error("msg", "gRPC移动文件夹或文献失败", "error", err.Error())
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	pb "github.com/yb2020/odoc/proto/gen/go/doc"
+	"github.com/yb2020/odoc/services/doc/dao"
+	"github.com/yb2020/odoc/services/doc/model"
+	"github.com/yb2020/odoc/services/doc/service"
+)
+
+func TestGetUserDocChecksOwner(t *testing.T) {
+	db := daotest.NewDB(t, &model.UserDoc{})
+	logger := daotest.NewLogger()
+	userDocDAO := dao.NewUserDocDAO(db, logger)
+	userDoc := &model.UserDoc{UserId: "u1", NoteId: "n1", DocName: "paper"}
+	userDoc.Id = "d1"
+	if err := userDocDAO.Save(context.Background(), userDoc); err != nil {
+		t.Fatalf("save user doc: %v", err)
+	}
+	userDocService := service.NewUserDocService(logger, opentracing.NoopTracer{}, nil, userDocDAO, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	s := NewDocGRPCServer(logger, opentracing.NoopTracer{}, userDocService, nil, nil)
+
+	tests := []struct {
+		name   string
+		userId string
+		noteId string
+	}{
+		{name: "other user", userId: "u2", noteId: "n1"},
+		{name: "anonymous", noteId: "n1"},
+		{name: "note without doc", userId: "u1", noteId: "n2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), userContext.UserIDKey, tt.userId)
+			_, err := s.GetUserDoc(ctx, &pb.GetUserDocRequest{NoteId: tt.noteId})
+			if status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("err = %v, want PermissionDenied", err)
+			}
+		})
+	}
+}
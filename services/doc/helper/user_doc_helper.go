@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/yb2020/odoc/pkg/venue"
 	docpb "github.com/yb2020/odoc/proto/gen/go/doc"
 	"github.com/yb2020/odoc/services/doc/model"
 	paperModel "github.com/yb2020/odoc/services/paper/model"
@@ -561,8 +562,117 @@ func getUserDocDisplayPublishDate(doc *model.UserDoc) string {
 	return doc.GraphPublishDate
 }
 
+// GetUserDocRanking 获取文档在指定评级体系中的等级，没有时返回nil
+func GetUserDocRanking(rankings []*docpb.UserDocVenueRanking, system string) *docpb.UserDocVenueRanking {
+	for _, ranking := range rankings {
+		if ranking.System == system {
+			return ranking
+		}
+	}
+	return nil
+}
+
+// GetUserDocEffectiveRank 获取文档在指定评级体系中的等级，JCR分区优先使用用户编辑的值
+func GetUserDocEffectiveRank(doc *model.UserDoc, rankings []*docpb.UserDocVenueRanking, system string) string {
+	if system == venue.SystemJCR && doc.UserEditedJcrPartion != "" {
+		return venue.NormalizeRank(system, doc.UserEditedJcrPartion)
+	}
+	if ranking := GetUserDocRanking(rankings, system); ranking != nil {
+		return ranking.Rank
+	}
+	return ""
+}
+
+// GetUserDocEffectiveImpactFactor 获取文档的影响因子，优先使用用户编辑的值，其次为评级库匹配到的JCR影响因子
+func GetUserDocEffectiveImpactFactor(doc *model.UserDoc, rankings []*docpb.UserDocVenueRanking) float32 {
+	if doc.UserEditedImpactOfFactor != 0 {
+		return doc.UserEditedImpactOfFactor
+	}
+	if ranking := GetUserDocRanking(rankings, venue.SystemJCR); ranking != nil {
+		return ranking.GetImpactFactor()
+	}
+	return 0
+}
+
+// FilterUserDocsByJcr 根据JCR分区和影响因子过滤用户文档
+// impactOfFactorRange 为 [最小值, 最大值]，最大值不大于0时不限制上限
+func FilterUserDocsByJcr(req *docpb.GetDocListReq, userDocs []model.UserDoc, docRankingsMap map[string][]*docpb.UserDocVenueRanking) []model.UserDoc {
+	if req == nil || len(userDocs) == 0 ||
+		(len(req.JcrPartions) == 0 && len(req.ImpactOfFactorRange) == 0 && !req.OnlyShowDocsWithImpactOfFactor) {
+		return userDocs
+	}
+
+	partitionSet := make(map[string]bool, len(req.JcrPartions))
+	for _, partition := range req.JcrPartions {
+		partitionSet[venue.NormalizeRank(venue.SystemJCR, partition)] = true
+	}
+	var minImpactFactor, maxImpactFactor float32
+	if len(req.ImpactOfFactorRange) > 0 {
+		minImpactFactor = req.ImpactOfFactorRange[0]
+	}
+	if len(req.ImpactOfFactorRange) > 1 {
+		maxImpactFactor = req.ImpactOfFactorRange[1]
+	}
+
+	filteredDocs := make([]model.UserDoc, 0)
+	for _, doc := range userDocs {
+		rankings := docRankingsMap[doc.Id]
+		if len(partitionSet) > 0 && !partitionSet[GetUserDocEffectiveRank(&doc, rankings, venue.SystemJCR)] {
+			continue
+		}
+		impactFactor := GetUserDocEffectiveImpactFactor(&doc, rankings)
+		if req.OnlyShowDocsWithImpactOfFactor && impactFactor <= 0 {
+			continue
+		}
+		if len(req.ImpactOfFactorRange) > 0 &&
+			(impactFactor < minImpactFactor || (maxImpactFactor > 0 && impactFactor > maxImpactFactor)) {
+			continue
+		}
+		filteredDocs = append(filteredDocs, doc)
+	}
+	return filteredDocs
+}
+
+// FilterUserDocsByRanking 根据评级体系的等级过滤用户文档，多个条件需同时满足
+func FilterUserDocsByRanking(req *docpb.GetDocListReq, userDocs []model.UserDoc, docRankingsMap map[string][]*docpb.UserDocVenueRanking) []model.UserDoc {
+	if req == nil || len(req.RankingFilters) == 0 || len(userDocs) == 0 {
+		return userDocs
+	}
+
+	filteredDocs := make([]model.UserDoc, 0)
+	for _, doc := range userDocs {
+		matched := true
+		for _, filter := range req.RankingFilters {
+			rank := GetUserDocEffectiveRank(&doc, docRankingsMap[doc.Id], filter.System)
+			if rank == "" {
+				matched = false
+				break
+			}
+			if len(filter.Ranks) == 0 {
+				// 未指定等级时只要求在该评级体系中有等级
+				continue
+			}
+			rankMatched := false
+			for _, r := range filter.Ranks {
+				if venue.NormalizeRank(filter.System, r) == rank {
+					rankMatched = true
+					break
+				}
+			}
+			if !rankMatched {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			filteredDocs = append(filteredDocs, doc)
+		}
+	}
+	return filteredDocs
+}
+
 // SortDocList 根据请求中的排序类型对文档列表进行排序
-func SortDocList(userDocs []model.UserDoc, req *docpb.GetDocListReq) {
+func SortDocList(userDocs []model.UserDoc, req *docpb.GetDocListReq, docRankingsMap map[string][]*docpb.UserDocVenueRanking) {
 	if len(userDocs) == 0 {
 		return
 	}
@@ -651,6 +761,32 @@ func SortDocList(userDocs []model.UserDoc, req *docpb.GetDocListReq) {
 			return userDocs[i].ImportanceScore > userDocs[j].ImportanceScore
 		})
 
+	case docpb.UserDocListSortType(7): // IMPACT_OF_FACTOR
+		// 按影响因子排序，影响因子高的文档排在前面
+		sort.Slice(userDocs, func(i, j int) bool {
+			impactFactorI := GetUserDocEffectiveImpactFactor(&userDocs[i], docRankingsMap[userDocs[i].Id])
+			impactFactorJ := GetUserDocEffectiveImpactFactor(&userDocs[j], docRankingsMap[userDocs[j].Id])
+			if impactFactorI == impactFactorJ {
+				return userDocs[i].CreatedAt.After(userDocs[j].CreatedAt)
+			}
+			return impactFactorI > impactFactorJ
+		})
+
+	case docpb.UserDocListSortType(8): // VENUE_RANKING
+		// 按指定评级体系的等级排序，等级高的文档排在前面，默认使用JCR分区
+		system := req.GetRankingSortSystem()
+		if !venue.IsValidSystem(system) {
+			system = venue.SystemJCR
+		}
+		sort.Slice(userDocs, func(i, j int) bool {
+			orderI := venue.RankOrder(system, GetUserDocEffectiveRank(&userDocs[i], docRankingsMap[userDocs[i].Id], system))
+			orderJ := venue.RankOrder(system, GetUserDocEffectiveRank(&userDocs[j], docRankingsMap[userDocs[j].Id], system))
+			if orderI == orderJ {
+				return userDocs[i].CreatedAt.After(userDocs[j].CreatedAt)
+			}
+			return orderI < orderJ
+		})
+
 	default: // docpb.UserDocListSortType(5) // CUSTOM_SORT
 		// 按自定义排序，即按 sort 字段排序
 		sort.Slice(userDocs, func(i, j int) bool {
@@ -78,6 +78,8 @@ type UserDoc struct {
 	ReadingStatus string `json:"readingStatus" gorm:"column:reading_status;type:varchar(255)"` // 阅读状态
 	Progress      int    `json:"progress" gorm:"column:progress;type:int"`                     // 进度
 	ParseStatus   int    `json:"parseStatus" gorm:"column:parse_status;type:int"`              // 解析状态
+
+	ReadFinishedTime *time.Time `json:"readFinishedTime" gorm:"column:read_finished_time;index"` // 最近一次标记为已读的时间
 	// 不持久化字段（在Java中使用transient修饰）
 	// 在Go中，我们不会添加gorm标签，这样它们就不会被持久化到数据库
 	// OriginalImpactOfFactor float32 `json:"originalImpactOfFactor" gorm:"-"` // 原始影响因子
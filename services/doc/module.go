@@ -1,6 +1,7 @@
 package doc
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -8,6 +9,8 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/pkg/cache"
 	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/pkg/takeout"
+	"github.com/yb2020/odoc/pkg/trash"
 	"google.golang.org/grpc"
 	"gorm.io/gorm"
 
@@ -19,6 +22,7 @@ import (
 	"github.com/yb2020/odoc/services/doc/api"
 	"github.com/yb2020/odoc/services/doc/dao"
 	"github.com/yb2020/odoc/services/doc/factory"
+	docgrpc "github.com/yb2020/odoc/services/doc/grpc"
 	"github.com/yb2020/odoc/services/doc/service"
 	membershipService "github.com/yb2020/odoc/services/membership/interfaces"
 	noteInterface "github.com/yb2020/odoc/services/note/interfaces"
@@ -31,6 +35,15 @@ import (
 // 编译时类型检查：确保 DocModule 实现了 registry.Module 接口
 var _ registry.Module = (*DocModule)(nil)
 
+// 编译时类型检查：确保 DocModule 实现了 registry.TakeoutExporterProvider 接口
+var _ registry.TakeoutExporterProvider = (*DocModule)(nil)
+
+// 编译时类型检查：确保 DocModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*DocModule)(nil)
+
+// 编译时类型检查：确保 DocModule 实现了 registry.TrashHandlerProvider 接口
+var _ registry.TrashHandlerProvider = (*DocModule)(nil)
+
 // Module 导出模块实例，用于自动发现和注册
 var Module = &DocModule{}
 
@@ -71,11 +84,15 @@ type DocModule struct {
 	userDocFolderAPI                 *api.UserDocFolderAPI
 	cslAPI                           *api.CslAPI
 	docClassifyAPI                   *api.DocClassifyAPI
+	grpcServer                       *docgrpc.DocGRPCServer
 	transactionManager               *baseDao.TransactionManager
 	docMetaInfoHandlerServiceFactory *factory.DocMetaInfoHandlerServiceFactory
 	userDocUploadService             *service.UserDocUploadService
 	userDocUploadLocalService        *service.UserDocUploadLocalService
 	doiMetaInfoService               *service.DoiMetaInfoService
+	takeoutExporter                  *service.DocTakeoutExporter
+	userDocTrashHandler              *service.UserDocTrashHandler
+	userDocFolderTrashHandler        *service.UserDocFolderTrashHandler
 }
 
 // NewDocModule 创建文档模块
@@ -121,8 +138,11 @@ func (m *DocModule) Shutdown() error {
 
 // RegisterGRPC 注册gRPC服务
 func (m *DocModule) RegisterGRPC(server *grpc.Server) {
-	// TODO: 实现gRPC服务注册
-	m.logger.Debug("msg", "文档模块没有gRPC服务，跳过注册")
+	if m.grpcServer == nil {
+		m.logger.Warn("msg", "gRPC服务未初始化", "module", m.Name())
+		return
+	}
+	m.grpcServer.RegisterServer(server)
 }
 
 // RegisterJobSchedulers 注册Job定时任务
@@ -197,6 +217,48 @@ func (m *DocModule) Initialize() error {
 	m.cslAPI = api.NewCslAPI(m.cslService, m.userDocService, m.logger, m.tracer)
 	m.docClassifyAPI = api.NewDocClassifyAPI(m.logger, m.tracer, m.userDocClassifyService, m.docClassifyRelationService)
 
+	// 初始化用户数据导出器
+	m.takeoutExporter = service.NewDocTakeoutExporter(m.logger, m.tracer, m.userDocDAO, m.userDocFolderDAO,
+		m.userDocFolderRelationDAO, m.userDocClassifyDAO, m.docClassifyRelationDAO, m.cslService)
+
+	// 初始化回收站处理器
+	m.userDocTrashHandler = service.NewUserDocTrashHandler(m.logger, m.tracer, m.userDocDAO, m.userDocFolderDAO,
+		m.userDocFolderRelationDAO, m.userDocClassifyDAO, m.docClassifyRelationDAO)
+	m.userDocFolderTrashHandler = service.NewUserDocFolderTrashHandler(m.logger, m.tracer, m.userDocDAO, m.userDocFolderDAO,
+		m.userDocFolderRelationDAO)
+
+	// 初始化gRPC服务
+	m.grpcServer = docgrpc.NewDocGRPCServer(m.logger, m.tracer, m.userDocService, m.userDocFolderService, m.paperService)
+
+	return nil
+}
+
+// TakeoutExporters 返回文档模块的用户数据导出器
+func (m *DocModule) TakeoutExporters() []takeout.Exporter {
+	return []takeout.Exporter{m.takeoutExporter}
+}
+
+// TrashHandlers 返回文档模块的回收站处理器
+func (m *DocModule) TrashHandlers() []trash.Handler {
+	return []trash.Handler{m.userDocTrashHandler, m.userDocFolderTrashHandler}
+}
+
+// DeleteUserData 注销账号时物理删除用户的文献、文件夹、分类、附件和引用样式设置，包括回收站中的
+func (m *DocModule) DeleteUserData(ctx context.Context, userId string) error {
+	removers := []func(context.Context, string) (int64, error){
+		m.docClassifyRelationDAO.RemoveByUserId,
+		m.userDocClassifyDAO.RemoveByUserId,
+		m.userDocFolderRelationDAO.RemoveByUserId,
+		m.userDocFolderDAO.RemoveByUserId,
+		m.userCslRelationDAO.RemoveByUserId,
+		m.userDocAttachmentDAO.RemoveByUserId,
+		m.userDocDAO.RemoveByUserId,
+	}
+	for _, remove := range removers {
+		if _, err := remove(ctx, userId); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -358,6 +420,17 @@ func (m *DocModule) SetUserDocFolderRelationToUserDocFolder() error {
 	return nil
 }
 
+// SetPdfReleaser 设置PDF文件释放器，彻底删除文献后释放不再被引用的PDF文件，用于解决循环依赖问题
+func (m *DocModule) SetPdfReleaser(pdfReleaser service.PdfReleaser) error {
+	if pdfReleaser == nil {
+		return errors.New("pdfReleaser cannot be nil")
+	}
+	if m.userDocTrashHandler != nil {
+		m.userDocTrashHandler.SetPdfReleaser(pdfReleaser)
+	}
+	return nil
+}
+
 // SetPaperPdfService 设置pdf服务，用于解决循环依赖问题
 func (m *DocModule) SetPaperPdfService(paperPdfService *pdfService.PaperPdfService) error {
 	if paperPdfService == nil {
@@ -375,6 +448,11 @@ func (m *DocModule) SetPaperPdfService(paperPdfService *pdfService.PaperPdfServi
 			return err
 		}
 	}
+	if m.grpcServer != nil {
+		if err := m.grpcServer.SetPaperPdfService(paperPdfService); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
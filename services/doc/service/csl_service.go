@@ -135,8 +135,6 @@ func (s *CslService) GetDocMetaInfo(ctx context.Context, req *pb.DocMetaInfoHand
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "CslService.GetDocMetaInfo")
 	defer span.Finish()
 
-	// 创建空的响应对象
-	metaInfoVo := &pb.DocMetaInfoSimpleVo{}
 	// 获取用户文档
 	userId, _ := userContext.GetUserID(ctx)
 	userDoc, err := s.userDocService.GetUserDocId(ctx, req.GetPaperId(), req.GetPdfId(), userId)
@@ -147,6 +145,12 @@ func (s *CslService) GetDocMetaInfo(ctx context.Context, req *pb.DocMetaInfoHand
 	if userDoc == nil {
 		return nil, errors.Biz("user doc not found")
 	}
+	return s.DocMetaInfoFromUserDoc(userDoc)
+}
+
+// DocMetaInfoFromUserDoc 根据用户文档中用户编辑过的信息构建文档元数据
+func (s *CslService) DocMetaInfoFromUserDoc(userDoc *model.UserDoc) (*pb.DocMetaInfoSimpleVo, error) {
+	metaInfoVo := &pb.DocMetaInfoSimpleVo{}
 	metaInfoVo.Title = userDoc.DocName
 	metaInfoVo.PublishTimestamp = uint64(util.GetTimestampByDate(userDoc.PublishDate))
 	metaInfoVo.PublishDateStr = &userDoc.PublishDate
@@ -157,7 +161,7 @@ func (s *CslService) GetDocMetaInfo(ctx context.Context, req *pb.DocMetaInfoHand
 		docTypeInfoListJsonDesc := constant.DocTypeInfoListJsonDesc
 		// 解析JSON字符串为DocTypeInfo列表
 		var docTypeInfos []*pb.DocTypeInfo
-		err = json.Unmarshal([]byte(docTypeInfoListJsonDesc), &docTypeInfos)
+		err := json.Unmarshal([]byte(docTypeInfoListJsonDesc), &docTypeInfos)
 		if err != nil {
 			s.logger.Error("parse doc type info list failed", "error", err.Error())
 			return nil, err
@@ -180,7 +184,7 @@ func (s *CslService) GetDocMetaInfo(ctx context.Context, req *pb.DocMetaInfoHand
 	if userDoc.AuthorDesc != "" {
 		authorList := []*pb.AuthorInfo{}
 		// 将AuthorDesc转换成AuthorList
-		err = json.Unmarshal([]byte(userDoc.AuthorDesc), &authorList)
+		err := json.Unmarshal([]byte(userDoc.AuthorDesc), &authorList)
 		if err != nil {
 			s.logger.Error("parse author list failed", "error", err.Error())
 			return nil, err
@@ -409,7 +413,17 @@ func (s *CslService) DownloadBibTex(ctx context.Context, docIds []string, w http
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("Content-Disposition", "attachment;filename=export.bib")
 
-	// 为每个文档生成BibTeX条目
+	// 将生成的BibTeX写入响应
+	_, err = w.Write([]byte(RenderBibTex(docIds, metaInfoMap)))
+	if err != nil {
+		s.logger.Error("write bibtex to response failed", "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// RenderBibTex 按文档ID顺序生成BibTeX，缺少元数据的文档跳过
+func RenderBibTex(docIds []string, metaInfoMap map[string]*pb.DocMetaInfoSimpleVo) string {
 	var buffer strings.Builder
 	separator := ""
 
@@ -427,7 +441,7 @@ func (s *CslService) DownloadBibTex(ctx context.Context, docIds []string, w http
 		containerTitle := util.GetContainerTitle(metaInfoSimpleVo)
 
 		// 生成BibTeX ID
-		bibID := fmt.Sprintf("doc%d", docId)
+		bibID := "doc" + docId
 
 		// 开始构建BibTeX条目
 		buffer.WriteString(separator)
@@ -530,13 +544,7 @@ func (s *CslService) DownloadBibTex(ctx context.Context, docIds []string, w http
 		separator = "\n"
 	}
 
-	// 将生成的BibTeX写入响应
-	_, err = w.Write([]byte(buffer.String()))
-	if err != nil {
-		s.logger.Error("write bibtex to response failed", "error", err.Error())
-		return err
-	}
-	return nil
+	return buffer.String()
 }
 
 // GetDocMetaInfoMapByDocIds 根据文档ID列表获取文档元数据映射
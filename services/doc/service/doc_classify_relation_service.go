@@ -78,3 +78,11 @@ func (s *DocClassifyRelationService) GetByClassifyIdAndDocId(ctx context.Context
 	// 调用DAO层查询文档分类关系
 	return s.docClassifyRelationDAO.GetByClassifyIdAndDocId(ctx, classifyId, docId)
 }
+
+// GetByUserIdAndDocId 查询用户文档的分类关系
+func (s *DocClassifyRelationService) GetByUserIdAndDocId(ctx context.Context, userId string, docId string) ([]model.DocClassifyRelation, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocClassifyRelationService.GetByUserIdAndDocId")
+	defer span.Finish()
+
+	return s.docClassifyRelationDAO.GetByUserIdAndDocId(ctx, userId, docId)
+}
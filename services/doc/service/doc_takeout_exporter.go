@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/takeout"
+	pb "github.com/yb2020/odoc/proto/gen/go/doc"
+	"github.com/yb2020/odoc/services/doc/dao"
+	"github.com/yb2020/odoc/services/doc/util"
+)
+
+// 编译时类型检查：确保 DocTakeoutExporter 实现了 takeout.Exporter 接口
+var _ takeout.Exporter = (*DocTakeoutExporter)(nil)
+
+// DocTakeoutExporter 导出用户的文献、文献元数据（CSL-JSON/BibTeX）、文件夹和分类
+type DocTakeoutExporter struct {
+	logger                   logging.Logger
+	tracer                   opentracing.Tracer
+	userDocDAO               *dao.UserDocDAO
+	userDocFolderDAO         *dao.UserDocFolderDAO
+	userDocFolderRelationDAO *dao.UserDocFolderRelationDAO
+	userDocClassifyDAO       *dao.UserDocClassifyDAO
+	docClassifyRelationDAO   *dao.DocClassifyRelationDAO
+	cslService               *CslService
+}
+
+// NewDocTakeoutExporter 创建文献数据导出器
+func NewDocTakeoutExporter(logger logging.Logger, tracer opentracing.Tracer,
+	userDocDAO *dao.UserDocDAO,
+	userDocFolderDAO *dao.UserDocFolderDAO,
+	userDocFolderRelationDAO *dao.UserDocFolderRelationDAO,
+	userDocClassifyDAO *dao.UserDocClassifyDAO,
+	docClassifyRelationDAO *dao.DocClassifyRelationDAO,
+	cslService *CslService,
+) *DocTakeoutExporter {
+	return &DocTakeoutExporter{
+		logger:                   logger,
+		tracer:                   tracer,
+		userDocDAO:               userDocDAO,
+		userDocFolderDAO:         userDocFolderDAO,
+		userDocFolderRelationDAO: userDocFolderRelationDAO,
+		userDocClassifyDAO:       userDocClassifyDAO,
+		docClassifyRelationDAO:   docClassifyRelationDAO,
+		cslService:               cslService,
+	}
+}
+
+// Name 导出器名称
+func (e *DocTakeoutExporter) Name() string {
+	return "doc"
+}
+
+// Export 导出用户的文献数据
+func (e *DocTakeoutExporter) Export(ctx context.Context, userId string, w *takeout.Writer) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, e.tracer, "DocTakeoutExporter.Export")
+	defer span.Finish()
+
+	userDocs, err := e.userDocDAO.GetAllUserDocsByUserID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("docs.json", userDocs); err != nil {
+		return err
+	}
+
+	// 文献元数据，单篇文献元数据解析失败时跳过
+	docIds := make([]string, 0, len(userDocs))
+	metaInfoMap := make(map[string]*pb.DocMetaInfoSimpleVo, len(userDocs))
+	cslItems := make([]*util.CSLItem, 0, len(userDocs))
+	for i := range userDocs {
+		userDoc := &userDocs[i]
+		metaInfo, err := e.cslService.DocMetaInfoFromUserDoc(userDoc)
+		if err != nil {
+			w.Skip("citations/"+userDoc.Id, err.Error())
+			continue
+		}
+		docIds = append(docIds, userDoc.Id)
+		metaInfoMap[userDoc.Id] = metaInfo
+		cslItems = append(cslItems, util.ToCSLItem("doc"+userDoc.Id, metaInfo))
+	}
+	if err := w.WriteJSON("citations/csl.json", cslItems); err != nil {
+		return err
+	}
+	if err := w.WriteBytes("citations/references.bib", []byte(RenderBibTex(docIds, metaInfoMap)), len(docIds)); err != nil {
+		return err
+	}
+
+	folders, err := e.userDocFolderDAO.GetUserDocFoldersByUserID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("folders.json", folders); err != nil {
+		return err
+	}
+	folderRelations, err := e.userDocFolderRelationDAO.GetUserDocFolderRelationsByUserID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("folder_relations.json", folderRelations); err != nil {
+		return err
+	}
+
+	classifies, err := e.userDocClassifyDAO.GetUserDocClassifiesByUserID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("classifies.json", classifies); err != nil {
+		return err
+	}
+	classifyRelations, err := e.docClassifyRelationDAO.GetDocClassifyRelationsByUserID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return w.WriteJSON("classify_relations.json", classifyRelations)
+}
@@ -177,6 +177,7 @@ func (s *UserDocFolderService) DeleteFoldersByIds(ctx context.Context, docFolder
 
 	// 使用事务执行删除操作，确保原子性
 	return s.transactionManager.ExecuteInTransaction(ctx, func(txCtx context.Context) error {
+		var userRelations []model.UserDocFolderRelation
 		if s.userDocFolderRelationService != nil {
 			// Step 1: 查询删除目录中该用户所有的 doc_id
 			relations, err := s.userDocFolderRelationService.GetUserDocFolderRelationsByFolderIDs(txCtx, folderIds)
@@ -188,6 +189,7 @@ func (s *UserDocFolderService) DeleteFoldersByIds(ctx context.Context, docFolder
 				// 保险起见，只收集当前用户的关系
 				if r.UserId == userId {
 					docIdSet[r.DocId] = struct{}{}
+					userRelations = append(userRelations, r)
 				}
 			}
 			docIds := make([]string, 0, len(docIdSet))
@@ -229,6 +231,11 @@ func (s *UserDocFolderService) DeleteFoldersByIds(ctx context.Context, docFolder
 			}
 		}
 
+		// 记录到回收站，恢复时放回文件夹中的文献
+		if err := recordUserDocFolderTrash(txCtx, userId, allFolders, userRelations); err != nil {
+			return errors.Wrap(err, "record deleted folders to trash failed")
+		}
+
 		// 2. 使用批量删除方法（逻辑删除文件夹）
 		err := s.userDocFolderDAO.BatchDeleteByIds(txCtx, folderIds)
 		if err != nil {
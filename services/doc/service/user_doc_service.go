@@ -11,11 +11,13 @@ import (
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/audit"
 	"github.com/yb2020/odoc/pkg/cache"
 	"github.com/yb2020/odoc/pkg/dao"
 	"github.com/yb2020/odoc/pkg/errors"
 	"github.com/yb2020/odoc/pkg/idgen"
 	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/venue"
 	docpb "github.com/yb2020/odoc/proto/gen/go/doc"
 	osspb "github.com/yb2020/odoc/proto/gen/go/oss"
 	docBean "github.com/yb2020/odoc/services/doc/bean"
@@ -283,6 +285,35 @@ func (s *UserDocService) GetByUserIdAndPdfId(ctx context.Context, userId, pdfId
 	return doc, nil
 }
 
+// CountOtherUsersByPdfId 统计其他用户引用该PDF的文档数，用于判断删除用户数据时能否删除PDF文件
+func (s *UserDocService) CountOtherUsersByPdfId(ctx context.Context, pdfId string, userId string) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserDocService.CountOtherUsersByPdfId")
+	defer span.Finish()
+
+	return s.userDocDAO.CountByPdfIdExcludeUser(ctx, pdfId, userId)
+}
+
+// CountByPdfId 统计引用该PDF的文档数，包括回收站中的文档，用于判断彻底删除文献后能否删除PDF文件
+func (s *UserDocService) CountByPdfId(ctx context.Context, pdfId string) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserDocService.CountByPdfId")
+	defer span.Finish()
+
+	return s.userDocDAO.CountByPdfId(ctx, pdfId)
+}
+
+// GetAllByUserId 获取用户所有未删除的文档
+func (s *UserDocService) GetAllByUserId(ctx context.Context, userId string) ([]model.UserDoc, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserDocService.GetAllByUserId")
+	defer span.Finish()
+
+	docs, err := s.userDocDAO.GetAllUserDocsByUserID(ctx, userId)
+	if err != nil {
+		s.logger.Error("msg", "get all user docs failed", "userId", userId, "error", err)
+		return nil, errors.Biz("doc.user_doc.errors.get_failed")
+	}
+	return docs, nil
+}
+
 // SaveUserDoc 直接保存用户文档对象
 func (s *UserDocService) SaveUserDoc(ctx context.Context, userDoc *model.UserDoc) error {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserDocService.SaveUserDoc")
@@ -878,7 +909,15 @@ func (s *UserDocService) GetDocList(ctx context.Context, req *docpb.GetDocListRe
 	}
 	// 过滤文件夹
 	userDocs = helper.FilterUserDocsByFolder(req, userDocs, userDocFolderRelations)
-	// 忽略影响因子和JCR分区相关的过滤逻辑
+	if len(userDocs) == 0 {
+		return response, nil
+	}
+	// 匹配收录场所在各评级体系中的等级，用于过滤、排序和展示
+	docRankingsMap := s.getUserDocVenueRankings(ctx, userDocs)
+	// 过滤JCR分区和影响因子
+	userDocs = helper.FilterUserDocsByJcr(req, userDocs, docRankingsMap)
+	// 过滤评级体系的等级
+	userDocs = helper.FilterUserDocsByRanking(req, userDocs, docRankingsMap)
 	if len(userDocs) == 0 {
 		return response, nil
 	}
@@ -890,7 +929,7 @@ func (s *UserDocService) GetDocList(ctx context.Context, req *docpb.GetDocListRe
 	userDocs = helper.FilterUserDocsBySearchContent(req, userDocs, docSearchResultMap)
 
 	// 文献列表排序
-	helper.SortDocList(userDocs, req)
+	helper.SortDocList(userDocs, req, docRankingsMap)
 
 	// 设置总数
 	response.Total = uint32(len(userDocs))
@@ -919,6 +958,16 @@ func (s *UserDocService) GetDocList(ctx context.Context, req *docpb.GetDocListRe
 	for _, doc := range userDocs {
 		// 使用辅助函数获取完整的UserDocInfo对象
 		userDocInfo := helper.GetAllInfoPbUserDocInfo(ctx, &doc, docClassifiesMap, docSearchResultMap, lastReadDoc, s.paperJcrService)
+		// 评级信息，用户未编辑JCR分区和影响因子时展示评级库中的值
+		userDocInfo.VenueRankings = docRankingsMap[doc.Id]
+		if jcrRanking := helper.GetUserDocRanking(userDocInfo.VenueRankings, venue.SystemJCR); jcrRanking != nil {
+			if doc.UserEditedJcrPartion == "" && userDocInfo.JcrVenuePartion != nil {
+				userDocInfo.JcrVenuePartion.JcrVenuePartion = &jcrRanking.Rank
+			}
+			if doc.UserEditedImpactOfFactor == 0 && jcrRanking.ImpactFactor != nil && userDocInfo.ImpactOfFactor != nil {
+				userDocInfo.ImpactOfFactor.ImpactOfFactor = jcrRanking.ImpactFactor
+			}
+		}
 		// 设置Embedding状态
 		embeddingStatus := docpb.UserDocParsedStatusEnum_EMBEDDING_FAILED
 		userDocInfo.EmbeddingStatus = embeddingStatus
@@ -932,6 +981,46 @@ func (s *UserDocService) GetDocList(ctx context.Context, req *docpb.GetDocListRe
 	return response, nil
 }
 
+// getUserDocVenueRankings 匹配文献收录场所在各评级体系中的等级，匹配失败时不影响文献列表
+func (s *UserDocService) getUserDocVenueRankings(ctx context.Context, userDocs []model.UserDoc) map[string][]*docpb.UserDocVenueRanking {
+	docRankingsMap := make(map[string][]*docpb.UserDocVenueRanking)
+	for _, doc := range userDocs {
+		venueNames := helper.GetUserDocDisplayVenueInfos(&doc)
+		if doc.Venue != "" {
+			venueNames = append([]string{doc.Venue}, venueNames...)
+		}
+		found := make(map[string]bool)
+		var rankings []*docpb.UserDocVenueRanking
+		for _, venueName := range venueNames {
+			matches, err := s.paperJcrService.MatchVenueRankings(ctx, venueName, "")
+			if err != nil {
+				s.logger.Warn("msg", "匹配文献场所评级失败", "venue", venueName, "error", err.Error())
+				return docRankingsMap
+			}
+			for _, match := range matches {
+				if found[match.System] {
+					continue
+				}
+				found[match.System] = true
+				ranking := &docpb.UserDocVenueRanking{
+					System: match.System,
+					Year:   uint32(match.Year),
+					Rank:   match.Rank,
+				}
+				if match.ImpactFactor > 0 {
+					impactFactor := match.ImpactFactor
+					ranking.ImpactFactor = &impactFactor
+				}
+				rankings = append(rankings, ranking)
+			}
+		}
+		if len(rankings) > 0 {
+			docRankingsMap[doc.Id] = rankings
+		}
+	}
+	return docRankingsMap
+}
+
 // 根据笔记id获取文献详情
 func (s *UserDocService) GetUserDocDetailInfoById(ctx context.Context, noteId string) (*docpb.DocDetailInfo, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserDocService.GetUserDocById")
@@ -1289,16 +1378,42 @@ func (s *UserDocService) DeleteUserDocs(ctx context.Context, docIds []string, us
 			if userDoc.UserId != userId {
 				return errors.Biz("doc.user_doc.errors.doc_not_belong_to_current_user")
 			}
-			// 2. 逻辑删除文献记录
+			// 已在回收站中
+			if userDoc.IsDeleted {
+				continue
+			}
+			// 2. 记录到回收站，保存文献所在的文件夹和分类
+			relations, err := s.userDocFolderRelationService.GetRelationsByUserIdAndDocIds(ctx, userId, []string{docId})
+			if err != nil {
+				return errors.Biz("doc.user_doc.errors.get_failed")
+			}
+			classifyRelations, err := s.docClassifyRelationService.GetByUserIdAndDocId(ctx, userId, docId)
+			if err != nil {
+				return errors.Biz("doc.user_doc.errors.get_failed")
+			}
+			if err := recordUserDocTrash(ctx, userDoc, relations, classifyRelations); err != nil {
+				s.logger.Error("msg", "记录回收站失败", "docId", docId, "userId", userId, "error", err.Error())
+				return errors.Biz("doc.user_doc.errors.delete_failed")
+			}
+			// 3. 逻辑删除文献记录
+			before := *userDoc
 			userDoc.IsDeleted = true
 			s.userDocDAO.ModifyExcludeNull(ctx, userDoc)
-			// 3. 物理删除文献文件夹关系
+			// 4. 物理删除文献文件夹关系
 			err = s.userDocFolderRelationService.DeleteRelationsByUserIdAndDocIds(ctx, userId, docId)
 			if err != nil {
 				return errors.Biz("delete user doc folder relation failed")
 			}
 
 			s.logger.Info("msg", "成功删除文献", "docId", docId, "userId", userId)
+			_ = audit.Record(ctx, &audit.Entry{
+				ActorId:    userId,
+				Action:     audit.ActionDelete,
+				EntityType: "doc",
+				EntityId:   docId,
+				Before:     &before,
+				After:      userDoc,
+			})
 		}
 
 		return nil
@@ -2409,6 +2524,7 @@ func (s *UserDocService) UpdateReadStatus(ctx context.Context, userId string, re
 			userDoc.ReadingStatus = docpb.DocReadingStatus_READING.String()
 			progress = 1
 		case docpb.DocReadingStatus_READ:
+			markReadFinished(userDoc)
 			userDoc.ReadingStatus = docpb.DocReadingStatus_READ.String()
 			progress = 100
 		}
@@ -2425,6 +2541,7 @@ func (s *UserDocService) UpdateReadStatus(ctx context.Context, userId string, re
 		var status docpb.DocReadingStatus
 		if progress >= 100 {
 			status = docpb.DocReadingStatus_READ
+			markReadFinished(userDoc)
 		} else {
 			status = docpb.DocReadingStatus_READING
 		}
@@ -2439,6 +2556,42 @@ func (s *UserDocService) UpdateReadStatus(ctx context.Context, userId string, re
 	return nil
 }
 
+// GetByUserIdAndIds 根据用户ID和文献ID列表获取文献
+func (s *UserDocService) GetByUserIdAndIds(ctx context.Context, userId string, ids []string) ([]model.UserDoc, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserDocService.GetByUserIdAndIds")
+	defer span.Finish()
+
+	if len(ids) == 0 {
+		return []model.UserDoc{}, nil
+	}
+	userDocs, err := s.userDocDAO.GetByUserIdAndWithIds(ctx, userId, ids)
+	if err != nil {
+		return nil, errors.Biz("doc.user_doc.errors.get_failed")
+	}
+	return userDocs, nil
+}
+
+// markReadFinished 文献从未读完变为已读时记录读完时间，重复标记已读不会刷新
+func markReadFinished(userDoc *model.UserDoc) {
+	if userDoc.ReadingStatus == docpb.DocReadingStatus_READ.String() && userDoc.ReadFinishedTime != nil {
+		return
+	}
+	now := time.Now().UTC()
+	userDoc.ReadFinishedTime = &now
+}
+
+// GetReadFinishedUserDocsByTimeRange 获取在[start, end)时间段内读完的文献，供阅读统计汇总使用
+func (s *UserDocService) GetReadFinishedUserDocsByTimeRange(ctx context.Context, start, end time.Time) ([]model.UserDoc, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserDocService.GetReadFinishedUserDocsByTimeRange")
+	defer span.Finish()
+
+	userDocs, err := s.userDocDAO.GetReadFinishedUserDocsByTimeRange(ctx, start, end)
+	if err != nil {
+		return nil, errors.Biz("doc.user_doc.errors.get_failed")
+	}
+	return userDocs, nil
+}
+
 func (s *UserDocService) GetLatestReadDocList(ctx context.Context, userId string, latestReadSize int) (*docpb.GetLatestReadDocListResp, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserDocService.GetLatestReadDocList")
 	defer span.Finish()
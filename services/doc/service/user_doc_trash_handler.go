@@ -0,0 +1,461 @@
+package service
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/trash"
+	"github.com/yb2020/odoc/services/doc/dao"
+	"github.com/yb2020/odoc/services/doc/model"
+)
+
+// rootFolderId 根目录的文件夹ID，也用作"未分类"文件夹
+const rootFolderId = "0"
+
+// trashFolderRelation 删除时被物理删除的文献文件夹关系
+type trashFolderRelation struct {
+	FolderId string `json:"folderId"`
+	DocId    string `json:"docId"`
+	Sort     int32  `json:"sort"`
+}
+
+// userDocTrashSnapshot 删除文献时保存的关联数据
+type userDocTrashSnapshot struct {
+	Relations   []trashFolderRelation `json:"relations"`   // 文献所在的文件夹
+	ClassifyIds []string              `json:"classifyIds"` // 文献的分类
+}
+
+// userDocFolderTrashSnapshot 删除文件夹时保存的关联数据
+type userDocFolderTrashSnapshot struct {
+	ParentId  string                `json:"parentId"`  // 父文件夹ID
+	Relations []trashFolderRelation `json:"relations"` // 文件夹中的文献
+}
+
+// PdfReleaser 彻底删除文献后释放不再被任何文献引用的PDF文件，由PDF模块实现
+type PdfReleaser interface {
+	ReleasePdf(ctx context.Context, pdfId string) (bool, error)
+}
+
+func toTrashFolderRelations(relations []model.UserDocFolderRelation) []trashFolderRelation {
+	result := make([]trashFolderRelation, 0, len(relations))
+	for _, r := range relations {
+		result = append(result, trashFolderRelation{FolderId: r.FolderId, DocId: r.DocId, Sort: r.Sort})
+	}
+	return result
+}
+
+// recordUserDocTrash 把删除的文献记录到回收站，保存文献所在的文件夹和分类
+func recordUserDocTrash(ctx context.Context, userDoc *model.UserDoc, relations []model.UserDocFolderRelation, classifyRelations []model.DocClassifyRelation) error {
+	snapshot := &userDocTrashSnapshot{Relations: toTrashFolderRelations(relations)}
+	for _, r := range classifyRelations {
+		snapshot.ClassifyIds = append(snapshot.ClassifyIds, r.ClassifyId)
+	}
+	return trash.Record(ctx, &trash.Item{
+		UserId:   userDoc.UserId,
+		Type:     trash.TypeDoc,
+		ItemId:   userDoc.Id,
+		Title:    userDocTitle(userDoc),
+		Snapshot: snapshot,
+	})
+}
+
+// recordUserDocFolderTrash 把删除的文件夹记录到回收站：每棵子树的根文件夹记为用户直接删除，
+// 子孙文件夹记为随根文件夹删除，按层级顺序记录，恢复根文件夹时按同样的顺序恢复整棵子树
+func recordUserDocFolderTrash(ctx context.Context, userId string, folders []model.UserDocFolder, relations []model.UserDocFolderRelation) error {
+	folderIds := make(map[string]struct{}, len(folders))
+	children := make(map[string][]*model.UserDocFolder, len(folders))
+	for i := range folders {
+		folderIds[folders[i].Id] = struct{}{}
+		children[folders[i].ParentId] = append(children[folders[i].ParentId], &folders[i])
+	}
+	relationsByFolder := make(map[string][]model.UserDocFolderRelation)
+	for _, r := range relations {
+		relationsByFolder[r.FolderId] = append(relationsByFolder[r.FolderId], r)
+	}
+	newItem := func(folder *model.UserDocFolder) *trash.Item {
+		return &trash.Item{
+			UserId: userId,
+			Type:   trash.TypeFolder,
+			ItemId: folder.Id,
+			Title:  folder.Name,
+			Snapshot: &userDocFolderTrashSnapshot{
+				ParentId:  folder.ParentId,
+				Relations: toTrashFolderRelations(relationsByFolder[folder.Id]),
+			},
+		}
+	}
+
+	for i := range folders {
+		root := &folders[i]
+		if _, ok := folderIds[root.ParentId]; ok {
+			continue
+		}
+		rootItem := newItem(root)
+		if err := trash.Record(ctx, rootItem); err != nil {
+			return err
+		}
+		// 按层级遍历子孙文件夹，保证恢复时父文件夹先于子文件夹
+		queue := children[root.Id]
+		for sort := 1; len(queue) > 0; sort++ {
+			folder := queue[0]
+			queue = append(queue[1:], children[folder.Id]...)
+			item := newItem(folder)
+			item.Reason = trash.ReasonFolder
+			item.ParentId = rootItem.Id
+			item.Sort = sort
+			if err := trash.Record(ctx, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// userDocTitle 文献的展示名称
+func userDocTitle(userDoc *model.UserDoc) string {
+	if userDoc.DocName != "" {
+		return userDoc.DocName
+	}
+	return userDoc.PaperTitle
+}
+
+// UserDocTrashHandler 回收站中文献的恢复和彻底删除
+type UserDocTrashHandler struct {
+	logger                   logging.Logger
+	tracer                   opentracing.Tracer
+	userDocDAO               *dao.UserDocDAO
+	userDocFolderDAO         *dao.UserDocFolderDAO
+	userDocFolderRelationDAO *dao.UserDocFolderRelationDAO
+	userDocClassifyDAO       *dao.UserDocClassifyDAO
+	docClassifyRelationDAO   *dao.DocClassifyRelationDAO
+	pdfReleaser              PdfReleaser
+}
+
+// NewUserDocTrashHandler 创建文献回收站处理器
+func NewUserDocTrashHandler(logger logging.Logger, tracer opentracing.Tracer,
+	userDocDAO *dao.UserDocDAO,
+	userDocFolderDAO *dao.UserDocFolderDAO,
+	userDocFolderRelationDAO *dao.UserDocFolderRelationDAO,
+	userDocClassifyDAO *dao.UserDocClassifyDAO,
+	docClassifyRelationDAO *dao.DocClassifyRelationDAO,
+) *UserDocTrashHandler {
+	return &UserDocTrashHandler{
+		logger:                   logger,
+		tracer:                   tracer,
+		userDocDAO:               userDocDAO,
+		userDocFolderDAO:         userDocFolderDAO,
+		userDocFolderRelationDAO: userDocFolderRelationDAO,
+		userDocClassifyDAO:       userDocClassifyDAO,
+		docClassifyRelationDAO:   docClassifyRelationDAO,
+	}
+}
+
+// SetPdfReleaser 设置PDF文件释放器，用于解决循环依赖问题
+func (h *UserDocTrashHandler) SetPdfReleaser(pdfReleaser PdfReleaser) {
+	h.pdfReleaser = pdfReleaser
+}
+
+// Type 处理的条目类型
+func (h *UserDocTrashHandler) Type() string {
+	return trash.TypeDoc
+}
+
+// Restore 恢复文献，放回仍然存在的原文件夹（都不存在时放入未分类）和仍然存在的分类；
+// 与已有文献重名时重命名，同一篇PDF已重新加入文献库时不能恢复
+func (h *UserDocTrashHandler) Restore(ctx context.Context, entry *trash.Entry) (string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, h.tracer, "UserDocTrashHandler.Restore")
+	defer span.Finish()
+
+	userDoc, err := h.userDocDAO.GetByIdAndUserIdIncludeDeleted(ctx, entry.ItemId, entry.UserId)
+	if err != nil {
+		return "", err
+	}
+	if userDoc == nil {
+		return "", errors.Biz("doc.user_doc.errors.doc_not_found")
+	}
+	if !userDoc.IsDeleted {
+		return userDocTitle(userDoc), nil
+	}
+	if userDoc.PdfId != "" {
+		existing, err := h.userDocDAO.GetUserDocByUserIdAndPdfId(ctx, entry.UserId, userDoc.PdfId)
+		if err != nil {
+			return "", err
+		}
+		if existing != nil {
+			return "", errors.Biz("trash.errors.doc_exists")
+		}
+	}
+	var snapshot userDocTrashSnapshot
+	if err := entry.DecodeSnapshot(&snapshot); err != nil {
+		return "", err
+	}
+
+	if err := h.userDocDAO.RestoreByIds(ctx, []string{userDoc.Id}); err != nil {
+		return "", err
+	}
+	if userDoc.DocName != "" {
+		name, err := h.uniqueDocName(ctx, entry.UserId, userDoc.DocName)
+		if err != nil {
+			return "", err
+		}
+		if name != userDoc.DocName {
+			userDoc.IsDeleted = false
+			userDoc.DocName = name
+			userDoc.UserEditedDocName = name
+			userDoc.DocNameEdited = true
+			if err := h.userDocDAO.Modify(ctx, userDoc); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := h.restoreFolderRelations(ctx, entry.UserId, userDoc.Id, snapshot.Relations); err != nil {
+		return "", err
+	}
+	if err := h.restoreClassifyRelations(ctx, entry.UserId, userDoc.Id, snapshot.ClassifyIds); err != nil {
+		return "", err
+	}
+	return userDocTitle(userDoc), nil
+}
+
+// Purge 物理删除文献及其分类和文件夹关系，PDF不再被任何文献引用时释放PDF文件
+func (h *UserDocTrashHandler) Purge(ctx context.Context, entry *trash.Entry) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, h.tracer, "UserDocTrashHandler.Purge")
+	defer span.Finish()
+
+	userDoc, err := h.userDocDAO.GetByIdAndUserIdIncludeDeleted(ctx, entry.ItemId, entry.UserId)
+	if err != nil {
+		return err
+	}
+	// 已被其他途径恢复或删除
+	if userDoc == nil || !userDoc.IsDeleted {
+		return nil
+	}
+	if err := h.docClassifyRelationDAO.RemoveByUserIdAndDocId(ctx, entry.UserId, userDoc.Id); err != nil {
+		return err
+	}
+	if err := h.userDocFolderRelationDAO.RemoveByUserIdAndDocId(ctx, entry.UserId, userDoc.Id); err != nil {
+		return err
+	}
+	if err := h.userDocDAO.RemoveById(ctx, userDoc.Id); err != nil {
+		return err
+	}
+	if userDoc.PdfId == "" || h.pdfReleaser == nil {
+		return nil
+	}
+	released, err := h.pdfReleaser.ReleasePdf(ctx, userDoc.PdfId)
+	if err != nil {
+		return err
+	}
+	h.logger.Info("msg", "已彻底删除文献", "docId", userDoc.Id, "userId", entry.UserId, "pdfReleased", released)
+	return nil
+}
+
+// uniqueDocName 返回不与用户其他未删除文献重名的名称
+func (h *UserDocTrashHandler) uniqueDocName(ctx context.Context, userId string, name string) (string, error) {
+	var queryErr error
+	result := trash.UniqueName(name, func(candidate string) bool {
+		if queryErr != nil {
+			return false
+		}
+		existing, err := h.userDocDAO.GetUserDocByUserIdAndFileName(ctx, userId, candidate)
+		if err != nil {
+			queryErr = err
+			return false
+		}
+		return existing != nil
+	})
+	return result, queryErr
+}
+
+// restoreFolderRelations 把文献放回仍然存在的原文件夹，都不存在时放入未分类
+func (h *UserDocTrashHandler) restoreFolderRelations(ctx context.Context, userId string, docId string, relations []trashFolderRelation) error {
+	restored := 0
+	for _, r := range relations {
+		if r.FolderId == rootFolderId {
+			continue
+		}
+		ok, err := restoreFolderRelation(ctx, h.userDocFolderDAO, h.userDocFolderRelationDAO, userId, r)
+		if err != nil {
+			return err
+		}
+		if ok {
+			restored++
+		}
+	}
+	if restored > 0 {
+		return nil
+	}
+	existing, err := h.userDocFolderRelationDAO.GetRelationsByUserIdAndDocIds(ctx, userId, []string{docId})
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+	sort := int32(0)
+	for _, r := range relations {
+		if r.FolderId == rootFolderId {
+			sort = r.Sort
+		}
+	}
+	return h.userDocFolderRelationDAO.Save(ctx, &model.UserDocFolderRelation{UserId: userId, FolderId: rootFolderId, DocId: docId, Sort: sort})
+}
+
+// restoreClassifyRelations 恢复文献与仍然存在的分类的关系
+func (h *UserDocTrashHandler) restoreClassifyRelations(ctx context.Context, userId string, docId string, classifyIds []string) error {
+	if len(classifyIds) == 0 {
+		return nil
+	}
+	existing, err := h.docClassifyRelationDAO.GetByUserIdAndDocId(ctx, userId, docId)
+	if err != nil {
+		return err
+	}
+	related := make(map[string]struct{}, len(existing))
+	for _, r := range existing {
+		related[r.ClassifyId] = struct{}{}
+	}
+	for _, classifyId := range classifyIds {
+		if _, ok := related[classifyId]; ok {
+			continue
+		}
+		classify, err := h.userDocClassifyDAO.FindExistById(ctx, classifyId)
+		if err != nil {
+			return err
+		}
+		if classify == nil || classify.UserId != userId {
+			continue
+		}
+		if err := h.docClassifyRelationDAO.Save(ctx, &model.DocClassifyRelation{UserId: userId, DocId: docId, ClassifyId: classifyId}); err != nil {
+			return err
+		}
+		related[classifyId] = struct{}{}
+	}
+	return nil
+}
+
+// restoreFolderRelation 文件夹仍然存在时恢复文献与文件夹的关系，并移出未分类，返回文献是否已在该文件夹中
+func restoreFolderRelation(ctx context.Context, folderDAO *dao.UserDocFolderDAO, relationDAO *dao.UserDocFolderRelationDAO, userId string, r trashFolderRelation) (bool, error) {
+	folder, err := folderDAO.GetByIdAndUserId(ctx, r.FolderId, userId)
+	if err != nil || folder == nil {
+		return false, err
+	}
+	existing, err := relationDAO.GetUserDocFolderRelationsByFolderIdAndDocId(ctx, userId, r.FolderId, r.DocId)
+	if err != nil {
+		return false, err
+	}
+	if existing == nil {
+		if err := relationDAO.Save(ctx, &model.UserDocFolderRelation{UserId: userId, FolderId: r.FolderId, DocId: r.DocId, Sort: r.Sort}); err != nil {
+			return false, err
+		}
+	}
+	// 文件夹被删除时没有其他文件夹的文献被放入了未分类，放回文件夹后从未分类中移出
+	if err := relationDAO.RemoveByUserIdAndFolderIdAndDocId(ctx, userId, rootFolderId, r.DocId); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UserDocFolderTrashHandler 回收站中文件夹的恢复和彻底删除
+type UserDocFolderTrashHandler struct {
+	logger                   logging.Logger
+	tracer                   opentracing.Tracer
+	userDocDAO               *dao.UserDocDAO
+	userDocFolderDAO         *dao.UserDocFolderDAO
+	userDocFolderRelationDAO *dao.UserDocFolderRelationDAO
+}
+
+// NewUserDocFolderTrashHandler 创建文件夹回收站处理器
+func NewUserDocFolderTrashHandler(logger logging.Logger, tracer opentracing.Tracer,
+	userDocDAO *dao.UserDocDAO,
+	userDocFolderDAO *dao.UserDocFolderDAO,
+	userDocFolderRelationDAO *dao.UserDocFolderRelationDAO,
+) *UserDocFolderTrashHandler {
+	return &UserDocFolderTrashHandler{
+		logger:                   logger,
+		tracer:                   tracer,
+		userDocDAO:               userDocDAO,
+		userDocFolderDAO:         userDocFolderDAO,
+		userDocFolderRelationDAO: userDocFolderRelationDAO,
+	}
+}
+
+// Type 处理的条目类型
+func (h *UserDocFolderTrashHandler) Type() string {
+	return trash.TypeFolder
+}
+
+// Restore 恢复文件夹到原父文件夹下，原父文件夹不存在时恢复到根目录，与同级文件夹重名时重命名；
+// 同时把文件夹中仍然存在的文献放回文件夹，回收站中的文献在恢复时会回到该文件夹
+func (h *UserDocFolderTrashHandler) Restore(ctx context.Context, entry *trash.Entry) (string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, h.tracer, "UserDocFolderTrashHandler.Restore")
+	defer span.Finish()
+
+	folder, err := h.userDocFolderDAO.GetByIdAndUserIdIncludeDeleted(ctx, entry.ItemId, entry.UserId)
+	if err != nil {
+		return "", err
+	}
+	if folder == nil {
+		return "", errors.Biz("doc.user_doc_folder.errors.not_found")
+	}
+	if !folder.IsDeleted {
+		return folder.Name, nil
+	}
+	var snapshot userDocFolderTrashSnapshot
+	if err := entry.DecodeSnapshot(&snapshot); err != nil {
+		return "", err
+	}
+
+	parentId := snapshot.ParentId
+	if parentId == "" {
+		parentId = folder.ParentId
+	}
+	if parentId != rootFolderId {
+		parent, err := h.userDocFolderDAO.GetByIdAndUserId(ctx, parentId, entry.UserId)
+		if err != nil {
+			return "", err
+		}
+		if parent == nil {
+			parentId = rootFolderId
+		}
+	}
+	siblings, err := h.userDocFolderDAO.GetByUserIdAndParentId(ctx, entry.UserId, parentId)
+	if err != nil {
+		return "", err
+	}
+	names := make(map[string]struct{}, len(siblings))
+	for _, sibling := range siblings {
+		names[sibling.Name] = struct{}{}
+	}
+	name := trash.UniqueName(folder.Name, func(candidate string) bool {
+		_, ok := names[candidate]
+		return ok
+	})
+	if err := h.userDocFolderDAO.RestoreFolder(ctx, folder.Id, parentId, name); err != nil {
+		return "", err
+	}
+
+	for _, r := range snapshot.Relations {
+		userDoc, err := h.userDocDAO.GetByIdAndUserIdIncludeDeleted(ctx, r.DocId, entry.UserId)
+		if err != nil {
+			return "", err
+		}
+		if userDoc == nil {
+			continue
+		}
+		if _, err := restoreFolderRelation(ctx, h.userDocFolderDAO, h.userDocFolderRelationDAO, entry.UserId, r); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+// Purge 物理删除文件夹，文件夹中的文献关系在删除时已经移除
+func (h *UserDocFolderTrashHandler) Purge(ctx context.Context, entry *trash.Entry) error {
+	folder, err := h.userDocFolderDAO.GetByIdAndUserIdIncludeDeleted(ctx, entry.ItemId, entry.UserId)
+	if err != nil {
+		return err
+	}
+	if folder == nil || !folder.IsDeleted {
+		return nil
+	}
+	return h.userDocFolderDAO.RemoveById(ctx, folder.Id)
+}
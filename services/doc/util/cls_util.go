@@ -382,3 +382,51 @@ func GetTimestampByDate(date string) int64 {
 	}
 	return t.Unix()
 }
+
+// CSLDate CSL-JSON中的日期
+type CSLDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+// CSLItem CSL-JSON格式的一条文献
+type CSLItem struct {
+	ID             string     `json:"id"`
+	Type           CSLType    `json:"type"`
+	Title          string     `json:"title,omitempty"`
+	Author         []*CSLName `json:"author,omitempty"`
+	Issued         *CSLDate   `json:"issued,omitempty"`
+	ContainerTitle string     `json:"container-title,omitempty"`
+	DOI            string     `json:"DOI,omitempty"`
+	URL            string     `json:"URL,omitempty"`
+	Page           string     `json:"page,omitempty"`
+	Volume         string     `json:"volume,omitempty"`
+	Issue          string     `json:"issue,omitempty"`
+}
+
+// ToCSLItem 将文档元数据转换为CSL-JSON条目
+func ToCSLItem(id string, metaInfoSimpleVo *pb.DocMetaInfoSimpleVo) *CSLItem {
+	item := &CSLItem{
+		ID:             id,
+		Type:           GetType(metaInfoSimpleVo),
+		Title:          metaInfoSimpleVo.GetTitle(),
+		ContainerTitle: GetContainerTitle(metaInfoSimpleVo),
+		DOI:            metaInfoSimpleVo.GetDoi(),
+		Page:           metaInfoSimpleVo.GetPage(),
+		Volume:         metaInfoSimpleVo.GetVolume(),
+		Issue:          metaInfoSimpleVo.GetIssue(),
+	}
+	if authors := GetAuthor(metaInfoSimpleVo); len(authors) > 0 {
+		item.Author = authors
+	}
+	if item.DOI != "" {
+		item.URL = metaInfoSimpleVo.GetUrl()
+	}
+	if year := GetYear(metaInfoSimpleVo); year > 0 {
+		dateParts := []int{year}
+		if month := GetMonth(metaInfoSimpleVo); month > 0 {
+			dateParts = append(dateParts, month)
+		}
+		item.Issued = &CSLDate{DateParts: [][]int{dateParts}}
+	}
+	return item
+}
@@ -8,67 +8,98 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/proto/gen/go/tracker"
 
+	"github.com/yb2020/odoc/config"
+	userContext "github.com/yb2020/odoc/pkg/context"
 	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/middleware"
 	"github.com/yb2020/odoc/pkg/response"
 	transport "github.com/yb2020/odoc/pkg/transport"
+	"github.com/yb2020/odoc/services/event_tracker/service"
 )
 
 const (
-	secretKey     = "rp1012162"
 	algorithmName = "HmacSHA256"
 )
 
 type EventTrackerAPI struct {
-	logger logging.Logger
-	tracer opentracing.Tracer
+	cfg                *config.EventTrackerConfig
+	eventIngestService *service.EventIngestService
+	eventQueryService  *service.EventQueryService
+	logger             logging.Logger
+	tracer             opentracing.Tracer
 }
 
-func NewEventTrackerAPI(logger logging.Logger,
+func NewEventTrackerAPI(cfg *config.EventTrackerConfig,
+	eventIngestService *service.EventIngestService,
+	eventQueryService *service.EventQueryService,
+	logger logging.Logger,
 	tracer opentracing.Tracer,
 ) *EventTrackerAPI {
 	return &EventTrackerAPI{
-		logger: logger,
-		tracer: tracer,
+		cfg:                cfg,
+		eventIngestService: eventIngestService,
+		eventQueryService:  eventQueryService,
+		logger:             logger,
+		tracer:             tracer,
 	}
 }
 
 // validateSignature 验证签名
 func (api *EventTrackerAPI) validateSignature(rawString, signature string) bool {
-	if rawString == "" || signature == "" {
+	if rawString == "" || signature == "" || api.cfg.SignSecret == "" {
 		return false
 	}
 
 	// 创建 HMAC-SHA256
-	h := hmac.New(sha256.New, []byte(secretKey))
+	h := hmac.New(sha256.New, []byte(api.cfg.SignSecret))
 	h.Write([]byte(rawString))
 
 	// 计算签名
 	computedSignature := base64.StdEncoding.EncodeToString(h.Sum(nil))
 
 	// 比较签名
-	if computedSignature != signature {
-		api.logger.Error("msg", "签名不匹配", "rawString", rawString, "signature", signature, "computedSignature", computedSignature)
+	if !hmac.Equal([]byte(computedSignature), []byte(signature)) {
+		api.logger.Error("msg", "签名不匹配", "signature", signature, "computedSignature", computedSignature)
 		return false
 	}
 
 	return true
 }
 
+// validateTimestamp 校验上报时间戳(毫秒)是否在允许的偏差内，防止重放
+func (api *EventTrackerAPI) validateTimestamp(timestamp int64) bool {
+	if api.cfg.SignMaxSkew <= 0 {
+		return true
+	}
+	skew := time.Since(time.UnixMilli(timestamp))
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew <= time.Duration(api.cfg.SignMaxSkew)*time.Second
+}
+
+/*
+ * @api_path: /report/collection_tracking0
+ * @method: POST
+ * @content-type: application/json
+ * @summary: 埋点事件上报
+ */
 func (api *EventTrackerAPI) TrackEvent(c *gin.Context) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "EventTrackerAPI.TrackEvent")
 	defer span.Finish()
-	openSwitch := true
-	if openSwitch {
+
+	// 未开启采集时直接返回成功，避免客户端重试
+	if !api.cfg.Enabled {
+		response.SuccessNoData(c, "success")
 		return
 	}
 
-	api.logger.Info("msg", "TrackEvent", "ctx", ctx)
-
 	// 使用 Proto 绑定器解析请求体
 	reqParams := &tracker.EventTrackerEncodeRequest{}
 	if err := transport.BindProto(c, reqParams); err != nil {
@@ -87,6 +118,12 @@ func (api *EventTrackerAPI) TrackEvent(c *gin.Context) {
 	// 重新设置请求体，因为 ReadAll 会消耗掉 body
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(rawBody))
 
+	if !api.validateTimestamp(int64(reqParams.Timestamp)) {
+		api.logger.Warn("msg", "签名时间戳超出允许范围", "timestamp", reqParams.Timestamp)
+		c.Error(errors.New("sign expired"))
+		return
+	}
+
 	// 拼接签名字符串，使用原始 JSON 请求体
 	rawSignStr := fmt.Sprintf("rd=%d&timestamp=%d\n%s", reqParams.Rd, reqParams.Timestamp, string(rawBody))
 
@@ -105,8 +142,66 @@ func (api *EventTrackerAPI) TrackEvent(c *gin.Context) {
 		return
 	}
 
-	// api.logger.Debug("msg", "签名验证成功", "rawSignStr", rawSignStr, "signature", reqParams.Signature, "computedSignature", reqParams.Signature)
+	// 补全服务端上下文信息后放入写缓冲区
+	userId, _ := userContext.GetUserID(ctx)
+	ingestCtx := &service.EventIngestContext{
+		UserId:   userId,
+		ClientIp: c.ClientIP(),
+	}
+	if userAgent, ok := middleware.GetUserAgentInfoFromGin(c); ok {
+		ingestCtx.UserAgent = userAgent
+	}
+	events := api.eventIngestService.BuildEvents(ctx, reqBody, ingestCtx)
+	api.eventIngestService.Enqueue(events)
 
 	// 返回成功响应
 	response.SuccessNoData(c, "success")
 }
+
+/*
+ * @api_path: /api/admin/eventTracker/countByDay
+ * @method: POST
+ * @content-type: application/json
+ * @summary: 按日期和事件统计事件数
+ */
+func (api *EventTrackerAPI) CountByDay(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "EventTrackerAPI.CountByDay")
+	defer span.Finish()
+
+	req := &tracker.EventCountByDayRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析事件统计请求失败", "error", err)
+		c.Error(err)
+		return
+	}
+	resp, err := api.eventQueryService.CountByDay(ctx, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", resp)
+}
+
+/*
+ * @api_path: /api/admin/eventTracker/funnel
+ * @method: POST
+ * @content-type: application/json
+ * @summary: 事件漏斗统计
+ */
+func (api *EventTrackerAPI) Funnel(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "EventTrackerAPI.Funnel")
+	defer span.Finish()
+
+	req := &tracker.EventFunnelRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析事件漏斗请求失败", "error", err)
+		c.Error(err)
+		return
+	}
+	resp, err := api.eventQueryService.Funnel(ctx, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", resp)
+}
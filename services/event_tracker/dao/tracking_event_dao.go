@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"context"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/event_tracker/model"
+	"gorm.io/gorm"
+)
+
+// TrackingEventDAO GORM实现的埋点事件DAO
+type TrackingEventDAO struct {
+	*baseDao.GormBaseDAO[model.TrackingEvent]
+	logger logging.Logger
+}
+
+// NewTrackingEventDAO 创建一个新的埋点事件DAO
+func NewTrackingEventDAO(db *gorm.DB, logger logging.Logger) *TrackingEventDAO {
+	return &TrackingEventDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.TrackingEvent](db, logger),
+		logger:      logger,
+	}
+}
+
+// BatchCreate 批量写入埋点事件
+func (d *TrackingEventDAO) BatchCreate(ctx context.Context, events []*model.TrackingEvent, batchSize int) error {
+	if len(events) == 0 {
+		return nil
+	}
+	result := d.GetDB(ctx).CreateInBatches(events, batchSize)
+	if result.Error != nil {
+		d.logger.Error("msg", "批量写入埋点事件失败", "count", len(events), "error", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+// CountByEventAndDay 按日期和事件编码统计[startDate, endDate]内的事件数，eventCodes为空时统计全部事件
+func (d *TrackingEventDAO) CountByEventAndDay(ctx context.Context, startDate string, endDate string, eventCodes []string) ([]model.EventDayCount, error) {
+	var counts []model.EventDayCount
+	query := d.GetDB(ctx).Model(&model.TrackingEvent{}).
+		Select("event_date, event_code, COUNT(*) AS total, COUNT(DISTINCT CASE WHEN user_id <> '' THEN user_id ELSE device_id END) AS actors").
+		Where("event_date >= ? AND event_date <= ? AND is_deleted = false", startDate, endDate)
+	if len(eventCodes) > 0 {
+		query = query.Where("event_code IN (?)", eventCodes)
+	}
+	result := query.Group("event_date, event_code").Order("event_date ASC, event_code ASC").Scan(&counts)
+	if result.Error != nil {
+		d.logger.Error("msg", "按日期统计埋点事件失败", "startDate", startDate, "endDate", endDate, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return counts, nil
+}
+
+// FindActorFirstTimes 获取[startDate, endDate]内每个行为主体首次触发某事件的时间
+func (d *TrackingEventDAO) FindActorFirstTimes(ctx context.Context, eventCode string, startDate string, endDate string) ([]model.EventActorFirstTime, error) {
+	var actors []model.EventActorFirstTime
+	result := d.GetDB(ctx).Model(&model.TrackingEvent{}).
+		Select("user_id, device_id, MIN(event_time) AS first_time").
+		Where("event_code = ? AND event_date >= ? AND event_date <= ? AND is_deleted = false", eventCode, startDate, endDate).
+		Group("user_id, device_id").Scan(&actors)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取事件首次触发时间失败", "eventCode", eventCode, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return actors, nil
+}
+
+// DeleteBeforeEventDate 物理删除event_date早于指定日期的事件，每次最多删除limit条，返回删除条数
+func (d *TrackingEventDAO) DeleteBeforeEventDate(ctx context.Context, eventDate string, limit int) (int64, error) {
+	db := d.GetDB(ctx)
+	subQuery := db.Model(&model.TrackingEvent{}).Select("id").Where("event_date < ?", eventDate).Limit(limit)
+	result := db.Where("id IN (?)", subQuery).Delete(&model.TrackingEvent{})
+	if result.Error != nil {
+		d.logger.Error("msg", "清理过期埋点事件失败", "eventDate", eventDate, "error", result.Error.Error())
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,57 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/services/event_tracker/service"
+)
+
+// TrackingEventRetentionJob 埋点事件过期清理任务
+type TrackingEventRetentionJob struct {
+	logger            logging.Logger
+	spec              string                 // 任务的cron表达式，6字段标准cron表达式
+	key               string                 // 任务的锁key，必须是唯一的unique-job-key
+	expiry            time.Duration          // 任务的锁过期时间
+	lockOpts          *scheduler.LockOptions // 任务的锁选项
+	eventQueryService *service.EventQueryService
+}
+
+func NewTrackingEventRetentionJob(logger logging.Logger, cfg *config.Config, eventQueryService *service.EventQueryService) *TrackingEventRetentionJob {
+	spec := cfg.Scheduler.Jobs.TrackingEventRetentionJob.Spec
+	key := cfg.Scheduler.Jobs.TrackingEventRetentionJob.Key
+	expiry := time.Duration(cfg.Scheduler.Jobs.TrackingEventRetentionJob.Expiry) * time.Second
+	lockOpts := &scheduler.LockOptions{
+		Key:    key,
+		Expiry: expiry,
+	}
+	return &TrackingEventRetentionJob{logger: logger, spec: spec, key: key, expiry: expiry, lockOpts: lockOpts, eventQueryService: eventQueryService}
+}
+
+// Spec 获取任务的cron表达式
+func (j *TrackingEventRetentionJob) Spec() string {
+	return j.spec
+}
+
+// LockOpts 获取任务的锁选项
+func (j *TrackingEventRetentionJob) LockOpts() *scheduler.LockOptions {
+	return j.lockOpts
+}
+
+// NewUserContext 清理任务不涉及用户数据，直接返回原上下文
+func (j *TrackingEventRetentionJob) NewUserContext(ctx context.Context, userId string) context.Context {
+	return ctx
+}
+
+// Run 执行任务，在执行任务前会获取锁，执行任务后会释放锁
+func (j *TrackingEventRetentionJob) Run() {
+	deleted, err := j.eventQueryService.CleanupExpiredEvents(context.Background())
+	if err != nil {
+		j.logger.Error("msg", "Tracking event retention job failed", "deleted", deleted, "error", err)
+		return
+	}
+	j.logger.Info("msg", "Tracking event retention job success", "deleted", deleted)
+}
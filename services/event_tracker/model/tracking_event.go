@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// TrackingEvent 埋点事件实体
+// EventDate 作为分区键：统计查询和过期清理都按 event_date 范围进行，PostgreSQL 可按该列做范围分区
+type TrackingEvent struct {
+	model.BaseModel           // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	EventDate       string    `json:"eventDate" gorm:"column:event_date;size:10;index:idx_tracking_event_date_code"`  // 事件日期(UTC) yyyy-MM-dd
+	EventCode       string    `json:"eventCode" gorm:"column:event_code;size:100;index:idx_tracking_event_date_code"` // 事件编码
+	EventTime       time.Time `json:"eventTime" gorm:"column:event_time"`                                             // 事件发生时间
+	UserId          string    `json:"userId" gorm:"column:user_id;size:36;index"`                                     // 登录用户ID，未登录为空
+	DeviceId        string    `json:"deviceId" gorm:"column:device_id;size:100;index"`                                // 设备ID
+	SessionId       string    `json:"sessionId" gorm:"column:session_id;size:100"`                                    // 前端会话ID
+	Uin             string    `json:"uin" gorm:"column:uin;size:100"`                                                 // 上报方用户标识
+	ToUin           string    `json:"toUin" gorm:"column:to_uin;size:100"`                                            // 目标用户标识
+	Url             string    `json:"url" gorm:"column:url;type:text"`                                                // 页面地址
+	PageType        string    `json:"pageType" gorm:"column:page_type;size:100"`                                      // 页面类型
+	TypeParameter   string    `json:"typeParameter" gorm:"column:type_parameter;type:text"`                           // 类型参数
+	TranContent     string    `json:"tranContent" gorm:"column:tran_content;type:text"`                               // 透传内容
+	Sources         string    `json:"sources" gorm:"column:sources;size:255"`                                         // 来源
+	Memory          string    `json:"memory" gorm:"column:memory;size:100"`                                           // 内存信息
+	Channel         string    `json:"channel" gorm:"column:channel;size:100"`                                         // 渠道
+	Referer         string    `json:"referer" gorm:"column:referer;type:text"`                                        // 来源页面
+	RefererDetail   string    `json:"refererDetail" gorm:"column:referer_detail;type:text"`                           // 来源详情
+	Platform        string    `json:"platform" gorm:"column:platform;size:50"`                                        // 平台
+	OsName          string    `json:"osName" gorm:"column:os_name;size:50"`                                           // 操作系统
+	OsVersion       string    `json:"osVersion" gorm:"column:os_version;size:50"`                                     // 操作系统版本
+	BrowserName     string    `json:"browserName" gorm:"column:browser_name;size:50"`                                 // 浏览器
+	BrowserVersion  string    `json:"browserVersion" gorm:"column:browser_version;size:50"`                           // 浏览器版本
+	BrowserLanguage string    `json:"browserLanguage" gorm:"column:browser_language;size:50"`                         // 浏览器语言
+	DeviceType      string    `json:"deviceType" gorm:"column:device_type;size:50"`                                   // 设备类型
+	ScreenSize      string    `json:"screenSize" gorm:"column:screen_size;size:50"`                                   // 屏幕尺寸
+	AppVersion      string    `json:"appVersion" gorm:"column:app_version;size:50"`                                   // 应用版本
+	Language        string    `json:"language" gorm:"column:language;size:50"`                                        // 界面语言
+	ClientIp        string    `json:"clientIp" gorm:"column:client_ip;size:64"`                                       // 客户端IP
+	UserAgent       string    `json:"userAgent" gorm:"column:user_agent;type:text"`                                   // 原始User-Agent
+}
+
+// TableName 返回表名
+func (TrackingEvent) TableName() string {
+	return "t_tracking_event"
+}
+
+// EventDayCount 按日期和事件编码聚合的事件数
+type EventDayCount struct {
+	EventDate string `json:"eventDate" gorm:"column:event_date"`
+	EventCode string `json:"eventCode" gorm:"column:event_code"`
+	Total     int64  `json:"total" gorm:"column:total"`   // 事件总数
+	Actors    int64  `json:"actors" gorm:"column:actors"` // 去重的用户数，未登录时按设备计
+}
+
+// EventActorFirstTime 某个行为主体首次触发某事件的时间
+type EventActorFirstTime struct {
+	UserId    string    `json:"userId" gorm:"column:user_id"`
+	DeviceId  string    `json:"deviceId" gorm:"column:device_id"`
+	FirstTime time.Time `json:"firstTime" gorm:"column:first_time"`
+}
+
+// ActorKey 行为主体标识，登录用户取用户ID，未登录取设备ID
+func (a EventActorFirstTime) ActorKey() string {
+	if a.UserId != "" {
+		return "u:" + a.UserId
+	}
+	return "d:" + a.DeviceId
+}
@@ -1,14 +1,22 @@
 package event_tracker
 
 import (
+	"context"
+	"errors"
+
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/health"
 	"github.com/yb2020/odoc/pkg/logging"
 	"github.com/yb2020/odoc/pkg/middleware"
+	"github.com/yb2020/odoc/pkg/mq/rocketmq/producer"
 	"github.com/yb2020/odoc/pkg/registry"
 	"github.com/yb2020/odoc/pkg/scheduler"
 	"github.com/yb2020/odoc/services/event_tracker/api"
+	"github.com/yb2020/odoc/services/event_tracker/dao"
+	"github.com/yb2020/odoc/services/event_tracker/job"
+	"github.com/yb2020/odoc/services/event_tracker/service"
 	"google.golang.org/grpc"
 	"gorm.io/gorm"
 )
@@ -16,6 +24,9 @@ import (
 // 编译时类型检查：确保 EventTrackerModule 实现了 registry.Module 接口
 var _ registry.Module = (*EventTrackerModule)(nil)
 
+// 编译时类型检查：确保 EventTrackerModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*EventTrackerModule)(nil)
+
 // Module 导出模块实例，用于自动发现和注册
 var Module = &EventTrackerModule{}
 
@@ -27,6 +38,10 @@ type EventTrackerModule struct {
 	config          *config.Config
 	authMiddleware  *middleware.AuthMiddleware
 	eventTrackerAPI *api.EventTrackerAPI
+
+	eventProducer      *producer.RocketMQProducer
+	eventIngestService *service.EventIngestService
+	eventQueryService  *service.EventQueryService
 }
 
 // NewModule 创建事件追踪模块
@@ -53,15 +68,64 @@ func (m *EventTrackerModule) Name() string {
 // Initialize 初始化模块
 func (m *EventTrackerModule) Initialize() error {
 	m.logger.Info("msg", "初始化事件追踪模块")
-	m.eventTrackerAPI = api.NewEventTrackerAPI(m.logger,
+	eventTrackerConfig := &m.config.EventTracker
+	if eventTrackerConfig.Enabled && eventTrackerConfig.SignSecret == "" {
+		return errors.New("event-tracker.sign-secret is required when event tracking is enabled")
+	}
+	trackingEventDAO := dao.NewTrackingEventDAO(m.db, m.logger)
+
+	m.eventIngestService = service.NewEventIngestService(eventTrackerConfig, m.logger, m.tracer, m.newEventSink(trackingEventDAO))
+	m.eventQueryService = service.NewEventQueryService(eventTrackerConfig, m.logger, m.tracer, trackingEventDAO)
+	m.eventIngestService.Start()
+
+	m.eventTrackerAPI = api.NewEventTrackerAPI(eventTrackerConfig,
+		m.eventIngestService,
+		m.eventQueryService,
+		m.logger,
 		m.tracer,
 	)
 	return nil
 }
 
+// newEventSink 根据配置创建事件落地端，消息队列不可用时回退到事件表
+func (m *EventTrackerModule) newEventSink(trackingEventDAO *dao.TrackingEventDAO) service.EventSink {
+	eventTrackerConfig := &m.config.EventTracker
+	if eventTrackerConfig.Sink == service.EventSinkMQ {
+		eventProducer, err := producer.NewRocketMQProducer(m.config, m.logger)
+		if err == nil {
+			err = eventProducer.Start()
+		}
+		if err == nil {
+			m.eventProducer = eventProducer
+			return service.NewMQEventSink(eventProducer, eventTrackerConfig.Topic, eventTrackerConfig.Tag)
+		}
+		m.logger.Warn("msg", "埋点事件消息队列不可用，回退到事件表", "error", err.Error())
+	}
+	return service.NewDBEventSink(trackingEventDAO, eventTrackerConfig.BatchSize)
+}
+
+// RegisterHealthChecks 埋点事件写入消息队列时注册生产者的健康检查
+func (m *EventTrackerModule) RegisterHealthChecks(registry *health.Registry) {
+	if m.eventProducer != nil {
+		registry.Register("rocketmq_producer", m.eventProducer)
+	}
+}
+
 // Shutdown 关闭模块
 func (m *EventTrackerModule) Shutdown() error {
 	m.logger.Info("msg", "关闭事件追踪模块")
+	// 先把缓冲区中的事件写完，再关闭消息队列生产者
+	if m.eventIngestService != nil {
+		m.eventIngestService.Stop()
+		if dropped := m.eventIngestService.Dropped(); dropped > 0 {
+			m.logger.Warn("msg", "埋点事件缓冲区累计丢弃事件", "dropped", dropped)
+		}
+	}
+	if m.eventProducer != nil {
+		if err := m.eventProducer.Shutdown(); err != nil {
+			m.logger.Error("msg", "关闭埋点事件消息队列生产者失败", "error", err.Error())
+		}
+	}
 	return nil
 }
 
@@ -73,13 +137,17 @@ func (m *EventTrackerModule) RegisterGRPC(server *grpc.Server) {
 
 // RegisterJobSchedulers 注册Job定时任务
 func (m *EventTrackerModule) RegisterJobSchedulers(scheduler *scheduler.Scheduler) {
-	// TODO: 实现Job定时任务注册
-	m.logger.Debug("msg", "事件追踪模块没有Job定时任务，跳过注册")
+	if scheduler == nil {
+		m.logger.Debug("msg", "调度器未启用，事件追踪模块跳过Job注册")
+		return
+	}
+	m.logger.Debug("msg", "事件追踪模块注册Job定时任务")
+	retentionJob := job.NewTrackingEventRetentionJob(m.logger, m.config, m.eventQueryService)
+	scheduler.RegisterJobs(retentionJob)
 }
 
 // RegisterProviders 注册Provider
 func (m *EventTrackerModule) RegisterProviders() {
-	m.RegisterProviders()
 	// TODO: 实现Provider注册
 	m.logger.Debug("msg", "事件追踪模块没有Provider，跳过注册")
 }
@@ -91,4 +159,16 @@ func (m *EventTrackerModule) RegisterRoutes(r *gin.Engine) {
 	).POST(
 		"/report/collection_tracking0", m.eventTrackerAPI.TrackEvent,
 	)
+
+	adminGroup := r.Group("/api/admin/eventTracker")
+	adminGroup.Use(m.authMiddleware.AuthRequired())
+	{
+		adminGroup.POST("/countByDay", m.eventTrackerAPI.CountByDay)
+		adminGroup.POST("/funnel", m.eventTrackerAPI.Funnel)
+	}
+}
+
+// DeleteUserData 注销账号时删除用户的埋点事件
+func (m *EventTrackerModule) DeleteUserData(ctx context.Context, userId string) error {
+	return m.eventQueryService.DeleteUserData(ctx, userId)
 }
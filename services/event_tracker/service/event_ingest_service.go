@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/middleware"
+	"github.com/yb2020/odoc/proto/gen/go/tracker"
+	"github.com/yb2020/odoc/services/event_tracker/model"
+)
+
+const (
+	// EventDateLayout 事件日期格式
+	EventDateLayout = "2006-01-02"
+	// maxEventCodeLength 事件编码最大长度
+	maxEventCodeLength = 100
+	// maxShortFieldLength 普通字段最大长度，超出截断
+	maxShortFieldLength = 100
+	// maxEventFutureSkew 事件时间允许超前服务器时间的范围，超出时使用服务器时间
+	maxEventFutureSkew = 5 * time.Minute
+)
+
+// EventIngestContext 上报请求的服务端上下文，用于补全事件信息
+type EventIngestContext struct {
+	UserId    string
+	ClientIp  string
+	UserAgent middleware.UserAgentInfo
+}
+
+// EventIngestService 埋点事件接收服务
+// 事件先进入内存缓冲队列，由后台协程按批量大小或刷新间隔写入落地端(write-behind)，上报接口不等待落地
+type EventIngestService struct {
+	cfg           *config.EventTrackerConfig
+	sink          EventSink
+	buffer        chan *model.TrackingEvent
+	batchSize     int
+	flushInterval time.Duration
+	dropped       atomic.Int64
+	stopCh        chan struct{}
+	doneCh        chan struct{}
+	startOnce     sync.Once
+	stopOnce      sync.Once
+	logger        logging.Logger
+	tracer        opentracing.Tracer
+}
+
+// NewEventIngestService 创建埋点事件接收服务
+func NewEventIngestService(cfg *config.EventTrackerConfig, logger logging.Logger, tracer opentracing.Tracer, sink EventSink) *EventIngestService {
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 10000
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+	flushInterval := time.Duration(cfg.FlushInterval) * time.Millisecond
+	if flushInterval <= 0 {
+		flushInterval = 2 * time.Second
+	}
+	return &EventIngestService{
+		cfg:           cfg,
+		sink:          sink,
+		buffer:        make(chan *model.TrackingEvent, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
+		logger:        logger,
+		tracer:        tracer,
+	}
+}
+
+// Start 启动后台写入协程
+func (s *EventIngestService) Start() {
+	s.startOnce.Do(func() {
+		go s.run()
+	})
+}
+
+// Stop 停止接收并把缓冲区中剩余的事件写入落地端
+func (s *EventIngestService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+		s.startOnce.Do(func() {
+			// 未启动过时直接结束，避免等待不存在的协程
+			close(s.doneCh)
+		})
+		<-s.doneCh
+	})
+}
+
+// Dropped 返回因缓冲区已满而丢弃的事件数
+func (s *EventIngestService) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Enqueue 将事件放入缓冲区，缓冲区已满时丢弃，返回接收的事件数
+func (s *EventIngestService) Enqueue(events []*model.TrackingEvent) int {
+	accepted := 0
+	for _, event := range events {
+		select {
+		case <-s.stopCh:
+			s.dropped.Add(int64(len(events) - accepted))
+			return accepted
+		default:
+		}
+		select {
+		case s.buffer <- event:
+			accepted++
+		default:
+			s.dropped.Add(1)
+		}
+	}
+	if accepted < len(events) {
+		s.logger.Warn("msg", "埋点事件缓冲区已满，丢弃事件", "dropped", len(events)-accepted, "totalDropped", s.dropped.Load())
+	}
+	return accepted
+}
+
+// BuildEvents 校验上报内容并补全用户、会话和UA信息，非法事件会被丢弃
+func (s *EventIngestService) BuildEvents(ctx context.Context, body *tracker.EventTrackerBodyRequest, ingestCtx *EventIngestContext) []*model.TrackingEvent {
+	span, _ := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "EventIngestService.BuildEvents")
+	defer span.Finish()
+
+	if body == nil || len(body.EventList) == 0 {
+		return nil
+	}
+	eventList := body.EventList
+	if s.cfg.MaxEventsPerRequest > 0 && len(eventList) > s.cfg.MaxEventsPerRequest {
+		s.logger.Warn("msg", "单次上报事件数超出限制，截断", "count", len(eventList), "max", s.cfg.MaxEventsPerRequest)
+		eventList = eventList[:s.cfg.MaxEventsPerRequest]
+	}
+
+	baseInfo := body.BaseInfo
+	if baseInfo == nil {
+		baseInfo = &tracker.EventTrackerBaseInfo{}
+	}
+	now := time.Now().UTC()
+
+	events := make([]*model.TrackingEvent, 0, len(eventList))
+	for _, item := range eventList {
+		if item == nil || item.EventInfo == nil {
+			continue
+		}
+		eventCode := item.EventInfo.EventCode
+		if eventCode == "" || len(eventCode) > maxEventCodeLength {
+			continue
+		}
+
+		eventTime := now
+		if item.EventInfo.EventTime > 0 {
+			eventTime = time.UnixMilli(int64(item.EventInfo.EventTime)).UTC()
+			if eventTime.After(now.Add(maxEventFutureSkew)) {
+				eventTime = now
+			}
+		}
+
+		event := &model.TrackingEvent{
+			EventDate:       eventTime.Format(EventDateLayout),
+			EventCode:       eventCode,
+			EventTime:       eventTime,
+			UserId:          ingestCtx.UserId,
+			DeviceId:        truncate(baseInfo.DeviceId, maxShortFieldLength),
+			SessionId:       truncate(baseInfo.SessionId, maxShortFieldLength),
+			Uin:             truncate(baseInfo.Uin, maxShortFieldLength),
+			ToUin:           truncate(item.EventInfo.Touin, maxShortFieldLength),
+			Url:             item.EventInfo.Url,
+			Channel:         truncate(baseInfo.Channel, maxShortFieldLength),
+			Referer:         baseInfo.Referer,
+			RefererDetail:   baseInfo.RefererDetail,
+			Platform:        truncate(baseInfo.Platform, 50),
+			OsName:          truncate(firstNonEmpty(baseInfo.OsName, ingestCtx.UserAgent.OsName), 50),
+			OsVersion:       truncate(ingestCtx.UserAgent.OsVersion, 50),
+			BrowserName:     truncate(firstNonEmpty(baseInfo.BrowserType, ingestCtx.UserAgent.BrowserName), 50),
+			BrowserVersion:  truncate(firstNonEmpty(baseInfo.BrowserVersion, ingestCtx.UserAgent.BrowserVersion), 50),
+			BrowserLanguage: truncate(baseInfo.BrowserLanguage, 50),
+			DeviceType:      truncate(ingestCtx.UserAgent.DeviceType, 50),
+			ScreenSize:      truncate(baseInfo.ScreenSize, 50),
+			AppVersion:      truncate(baseInfo.AppVersion, 50),
+			Language:        truncate(baseInfo.Language, 50),
+			ClientIp:        truncate(ingestCtx.ClientIp, 64),
+			UserAgent:       ingestCtx.UserAgent.RawUserAgent,
+		}
+		if item.EventData != nil {
+			event.PageType = truncate(item.EventData.PageType, maxShortFieldLength)
+			event.TypeParameter = item.EventData.TypeParameter
+			event.TranContent = item.EventData.TranContent
+			event.Sources = truncate(item.EventData.Sources, 255)
+			event.Memory = truncate(item.EventData.Memory, maxShortFieldLength)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// run 后台写入循环
+func (s *EventIngestService) run() {
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*model.TrackingEvent, 0, s.batchSize)
+	for {
+		select {
+		case event := <-s.buffer:
+			batch = append(batch, event)
+			if len(batch) >= s.batchSize {
+				s.flush(batch)
+				batch = make([]*model.TrackingEvent, 0, s.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = make([]*model.TrackingEvent, 0, s.batchSize)
+			}
+		case <-s.stopCh:
+			// 排空缓冲区后退出
+			for {
+				select {
+				case event := <-s.buffer:
+					batch = append(batch, event)
+					if len(batch) >= s.batchSize {
+						s.flush(batch)
+						batch = make([]*model.TrackingEvent, 0, s.batchSize)
+					}
+				default:
+					if len(batch) > 0 {
+						s.flush(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush 将一批事件写入落地端，失败时记录日志后丢弃，避免阻塞后续事件
+func (s *EventIngestService) flush(batch []*model.TrackingEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.sink.Write(ctx, batch); err != nil {
+		s.logger.Error("msg", "埋点事件写入失败", "count", len(batch), "error", err.Error())
+	}
+}
+
+// truncate 按字符截断字符串
+func truncate(value string, maxLength int) string {
+	if utf8.RuneCountInString(value) <= maxLength {
+		return value
+	}
+	return string([]rune(value)[:maxLength])
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
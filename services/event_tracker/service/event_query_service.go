@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/proto/gen/go/tracker"
+	"github.com/yb2020/odoc/services/event_tracker/dao"
+)
+
+// maxFunnelSteps 漏斗最多步骤数
+const maxFunnelSteps = 10
+
+// EventQueryService 埋点事件统计和清理服务
+type EventQueryService struct {
+	cfg              *config.EventTrackerConfig
+	trackingEventDAO *dao.TrackingEventDAO
+	logger           logging.Logger
+	tracer           opentracing.Tracer
+}
+
+// NewEventQueryService 创建埋点事件统计服务
+func NewEventQueryService(cfg *config.EventTrackerConfig, logger logging.Logger, tracer opentracing.Tracer, trackingEventDAO *dao.TrackingEventDAO) *EventQueryService {
+	return &EventQueryService{
+		cfg:              cfg,
+		trackingEventDAO: trackingEventDAO,
+		logger:           logger,
+		tracer:           tracer,
+	}
+}
+
+// CountByDay 按日期和事件编码统计事件数
+func (s *EventQueryService) CountByDay(ctx context.Context, req *tracker.EventCountByDayRequest) (*tracker.EventCountByDayResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "EventQueryService.CountByDay")
+	defer span.Finish()
+
+	if err := validateDateRange(req.StartDate, req.EndDate); err != nil {
+		return nil, err
+	}
+	counts, err := s.trackingEventDAO.CountByEventAndDay(ctx, req.StartDate, req.EndDate, req.EventCodes)
+	if err != nil {
+		return nil, errors.Biz("event_tracker.errors.query_failed")
+	}
+	resp := &tracker.EventCountByDayResponse{}
+	for _, count := range counts {
+		resp.Items = append(resp.Items, &tracker.EventDayCount{
+			EventDate: count.EventDate,
+			EventCode: count.EventCode,
+			Total:     count.Total,
+			Actors:    count.Actors,
+		})
+	}
+	return resp, nil
+}
+
+// Funnel 计算事件漏斗
+// 主体进入第k步的条件是：进入了第k-1步，并且首次触发第k步事件的时间不早于首次触发第k-1步的时间
+func (s *EventQueryService) Funnel(ctx context.Context, req *tracker.EventFunnelRequest) (*tracker.EventFunnelResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "EventQueryService.Funnel")
+	defer span.Finish()
+
+	if err := validateDateRange(req.StartDate, req.EndDate); err != nil {
+		return nil, err
+	}
+	if len(req.Steps) < 2 || len(req.Steps) > maxFunnelSteps {
+		return nil, errors.Biz("event_tracker.errors.invalid_funnel_steps")
+	}
+
+	resp := &tracker.EventFunnelResponse{}
+	// 上一步中仍在漏斗内的主体及其到达时间
+	var reached map[string]time.Time
+	var firstActors, prevActors int64
+	for i, eventCode := range req.Steps {
+		actors, err := s.trackingEventDAO.FindActorFirstTimes(ctx, eventCode, req.StartDate, req.EndDate)
+		if err != nil {
+			return nil, errors.Biz("event_tracker.errors.query_failed")
+		}
+
+		current := make(map[string]time.Time, len(actors))
+		for _, actor := range actors {
+			key := actor.ActorKey()
+			if i > 0 {
+				prevTime, ok := reached[key]
+				if !ok || actor.FirstTime.Before(prevTime) {
+					continue
+				}
+			}
+			current[key] = actor.FirstTime
+		}
+
+		count := int64(len(current))
+		step := &tracker.EventFunnelStep{EventCode: eventCode, Actors: count}
+		if i == 0 {
+			firstActors = count
+			if count > 0 {
+				step.ConversionRate = 1
+				step.StepConversionRate = 1
+			}
+		} else {
+			step.ConversionRate = ratio(count, firstActors)
+			step.StepConversionRate = ratio(count, prevActors)
+		}
+		resp.Steps = append(resp.Steps, step)
+		reached = current
+		prevActors = count
+	}
+	return resp, nil
+}
+
+// DeleteUserData 物理删除用户的全部埋点事件，注销账号时调用
+func (s *EventQueryService) DeleteUserData(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "EventQueryService.DeleteUserData")
+	defer span.Finish()
+
+	_, err := s.trackingEventDAO.RemoveByUserId(ctx, userId)
+	return err
+}
+
+// CleanupExpiredEvents 按保留天数分批物理删除过期事件，返回删除总数
+func (s *EventQueryService) CleanupExpiredEvents(ctx context.Context) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "EventQueryService.CleanupExpiredEvents")
+	defer span.Finish()
+
+	if s.cfg.RetentionDays <= 0 {
+		return 0, nil
+	}
+	deleteSize := s.cfg.RetentionDeleteSize
+	if deleteSize <= 0 {
+		deleteSize = 5000
+	}
+	cutoffDate := time.Now().UTC().AddDate(0, 0, -s.cfg.RetentionDays).Format(EventDateLayout)
+
+	var total int64
+	for {
+		deleted, err := s.trackingEventDAO.DeleteBeforeEventDate(ctx, cutoffDate, deleteSize)
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+		if deleted < int64(deleteSize) {
+			break
+		}
+	}
+	if total > 0 {
+		s.logger.Info("msg", "清理过期埋点事件完成", "cutoffDate", cutoffDate, "deleted", total)
+	}
+	return total, nil
+}
+
+// validateDateRange 校验日期范围
+func validateDateRange(startDate string, endDate string) error {
+	start, err := time.Parse(EventDateLayout, startDate)
+	if err != nil {
+		return errors.Biz("event_tracker.errors.invalid_date")
+	}
+	end, err := time.Parse(EventDateLayout, endDate)
+	if err != nil {
+		return errors.Biz("event_tracker.errors.invalid_date")
+	}
+	if start.After(end) {
+		return errors.Biz("event_tracker.errors.invalid_date")
+	}
+	return nil
+}
+
+// ratio 计算比例，保留四位小数
+func ratio(numerator int64, denominator int64) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return math.Round(float64(numerator)/float64(denominator)*10000) / 10000
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	mqi "github.com/yb2020/odoc/pkg/mq/interface"
+	customRocketMQ "github.com/yb2020/odoc/pkg/mq/rocketmq"
+	"github.com/yb2020/odoc/services/event_tracker/dao"
+	"github.com/yb2020/odoc/services/event_tracker/model"
+)
+
+const (
+	// EventSinkDB 事件写入事件表
+	EventSinkDB = "db"
+	// EventSinkMQ 事件转发到消息队列
+	EventSinkMQ = "mq"
+)
+
+// EventSink 埋点事件落地接口
+type EventSink interface {
+	// Write 写入一批事件
+	Write(ctx context.Context, events []*model.TrackingEvent) error
+}
+
+// DBEventSink 将事件批量写入事件表
+type DBEventSink struct {
+	trackingEventDAO *dao.TrackingEventDAO
+	batchSize        int
+}
+
+// NewDBEventSink 创建事件表落地
+func NewDBEventSink(trackingEventDAO *dao.TrackingEventDAO, batchSize int) *DBEventSink {
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+	return &DBEventSink{trackingEventDAO: trackingEventDAO, batchSize: batchSize}
+}
+
+// Write 写入一批事件
+func (s *DBEventSink) Write(ctx context.Context, events []*model.TrackingEvent) error {
+	return s.trackingEventDAO.BatchCreate(ctx, events, s.batchSize)
+}
+
+// MQEventSink 将一批事件序列化为一条消息转发到消息队列，由下游消费落地
+type MQEventSink struct {
+	producer mqi.Producer
+	topic    string
+	tag      string
+}
+
+// NewMQEventSink 创建消息队列落地
+func NewMQEventSink(producer mqi.Producer, topic string, tag string) *MQEventSink {
+	return &MQEventSink{producer: producer, topic: topic, tag: tag}
+}
+
+// Write 写入一批事件
+func (s *MQEventSink) Write(ctx context.Context, events []*model.TrackingEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	message := &customRocketMQ.Message{
+		Topic: s.topic,
+		Tags:  s.tag,
+		Body:  body,
+	}
+	_, err = s.producer.SendSync(ctx, message)
+	return err
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/membership"
+	"github.com/yb2020/odoc/services/membership/service"
+)
+
+type CreditStatementApi struct {
+	logger                 logging.Logger
+	tracer                 opentracing.Tracer
+	creditStatementService *service.CreditStatementService
+}
+
+func NewCreditStatementApi(logger logging.Logger, tracer opentracing.Tracer, creditStatementService *service.CreditStatementService) *CreditStatementApi {
+	return &CreditStatementApi{
+		logger:                 logger,
+		tracer:                 tracer,
+		creditStatementService: creditStatementService,
+	}
+}
+
+// @api /api/membership/credit/statement
+// @method GET
+// @apiDescription 分页获取积分账单明细
+func (api *CreditStatementApi) GetStatement(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "CreditStatementApi.GetStatement")
+	defer span.Finish()
+
+	req := &pb.GetCreditStatementRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	resp, err := api.creditStatementService.GetStatement(ctx, userId, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "Success", resp)
+}
+
+// @api /api/membership/credit/usage/monthly
+// @method GET
+// @apiDescription 获取最近几个月按功能汇总的积分收支
+func (api *CreditStatementApi) GetMonthlyUsage(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "CreditStatementApi.GetMonthlyUsage")
+	defer span.Finish()
+
+	req := &pb.GetCreditMonthlyUsageRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	resp, err := api.creditStatementService.GetMonthlyUsage(ctx, userId, req.Months, req.Timezone)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "Success", resp)
+}
+
+// @api /api/membership/credit/statement/download
+// @method GET
+// @apiDescription 下载积分账单，format 为 csv 或 pdf
+func (api *CreditStatementApi) DownloadStatement(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "CreditStatementApi.DownloadStatement")
+	defer span.Finish()
+
+	req := &pb.DownloadCreditStatementRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	file, err := api.creditStatementService.ExportStatement(ctx, userId, req)
+	if err != nil {
+		api.logger.Error("msg", "导出积分账单失败", "userId", userId, "format", req.Format, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", file.FileName))
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
@@ -101,7 +101,7 @@ func (api *MembershipApi) TestCallCreditFunsAi(c *gin.Context) {
 
 	sessionId1 := "0"
 	// 1.调用积分接口AI Copilot接口功能
-	err := api.membershipService.CreditFunAi(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_AI_COPILOT, "gpt-4o-mini", func(xctx context.Context, sessionId string) error {
+	err := api.membershipService.CreditFunAi(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_AI_COPILOT, "gpt-4o-mini", "", func(xctx context.Context, sessionId string) error {
 		api.logger.Info("msg", "do something", "sessionId", sessionId)
 		sessionId1 = sessionId
 		// return errors.Biz("test error")
@@ -157,7 +157,7 @@ func (api *MembershipApi) TestCallCreditFunsTranslate(c *gin.Context) {
 
 	sessionId2 := "0"
 	// 3.调用积分接口全文翻译接口功能
-	err := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_FULLTEXT, 0, func(xctx context.Context, sessionId string) error {
+	err := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_FULLTEXT, 0, "", func(xctx context.Context, sessionId string) error {
 		api.logger.Info("msg", "do something", "sessionId", sessionId)
 		sessionId2 = sessionId
 		return nil
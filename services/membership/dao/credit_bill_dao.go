@@ -1,6 +1,9 @@
 package dao
 
 import (
+	"context"
+	"time"
+
 	baseDao "github.com/yb2020/odoc/pkg/dao"
 	"github.com/yb2020/odoc/pkg/logging"
 	"github.com/yb2020/odoc/services/membership/model"
@@ -13,6 +16,13 @@ type CreditBillDAO struct {
 	logger logging.Logger
 }
 
+// CreditBillChangeSum 积分账户的流水变动合计
+type CreditBillChangeSum struct {
+	CreditId    string `gorm:"column:credit_id"`
+	Credit      int64  `gorm:"column:credit"`        // 积分变动合计（单位：0.01个）
+	AddOnCredit int64  `gorm:"column:add_on_credit"` // 附加积分变动合计（单位：0.01个）
+}
+
 // NewCreditBillDAO 创建一个新的会员积分流水DAO
 func NewCreditBillDAO(db *gorm.DB, logger logging.Logger) *CreditBillDAO {
 	return &CreditBillDAO{
@@ -20,3 +30,66 @@ func NewCreditBillDAO(db *gorm.DB, logger logging.Logger) *CreditBillDAO {
 		logger:      logger,
 	}
 }
+
+// GetPageByUserId 按时间倒序分页获取用户的积分流水，startTime/endTime 为零值时不限制
+func (d *CreditBillDAO) GetPageByUserId(ctx context.Context, userId string, startTime time.Time, endTime time.Time, page int, size int) ([]model.CreditBill, int64, error) {
+	db := d.timeRangeQuery(ctx, userId, startTime, endTime)
+
+	var total int64
+	if err := db.Model(&model.CreditBill{}).Count(&total).Error; err != nil {
+		d.logger.Error("msg", "获取用户积分流水总数失败", "userId", userId, "error", err.Error())
+		return nil, 0, err
+	}
+
+	var entities []model.CreditBill
+	result := db.Order("created_at desc, id desc").Offset((page - 1) * size).Limit(size).Find(&entities)
+	if result.Error != nil {
+		d.logger.Error("msg", "分页获取用户积分流水失败", "userId", userId, "error", result.Error.Error())
+		return nil, 0, result.Error
+	}
+	return entities, total, nil
+}
+
+// GetListByUserIdAndTimeRange 按时间倒序获取用户在时间范围内的积分流水，startTime/endTime 为零值时不限制
+func (d *CreditBillDAO) GetListByUserIdAndTimeRange(ctx context.Context, userId string, startTime time.Time, endTime time.Time) ([]model.CreditBill, error) {
+	var entities []model.CreditBill
+	result := d.timeRangeQuery(ctx, userId, startTime, endTime).Order("created_at desc, id desc").Find(&entities)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取用户积分流水失败", "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return entities, nil
+}
+
+// SumChangesByCreditIds 按积分账户汇总流水的变动合计，使用变动后减变动前的差值计算，收入为正、支出为负
+func (d *CreditBillDAO) SumChangesByCreditIds(ctx context.Context, creditIds []string) (map[string]CreditBillChangeSum, error) {
+	sums := make(map[string]CreditBillChangeSum, len(creditIds))
+	if len(creditIds) == 0 {
+		return sums, nil
+	}
+	var rows []CreditBillChangeSum
+	result := d.GetDB(ctx).Model(&model.CreditBill{}).
+		Select("credit_id, COALESCE(SUM(after_credit - before_credit), 0) AS credit, COALESCE(SUM(after_add_on_credit - before_add_on_credit), 0) AS add_on_credit").
+		Where("credit_id IN ? and is_deleted = false", creditIds).
+		Group("credit_id").
+		Scan(&rows)
+	if result.Error != nil {
+		d.logger.Error("msg", "汇总积分流水变动失败", "error", result.Error.Error())
+		return nil, result.Error
+	}
+	for _, row := range rows {
+		sums[row.CreditId] = row
+	}
+	return sums, nil
+}
+
+func (d *CreditBillDAO) timeRangeQuery(ctx context.Context, userId string, startTime time.Time, endTime time.Time) *gorm.DB {
+	db := d.GetDB(ctx).Where("user_id = ? and is_deleted = false", userId)
+	if !startTime.IsZero() {
+		db = db.Where("created_at >= ?", startTime)
+	}
+	if !endTime.IsZero() {
+		db = db.Where("created_at <= ?", endTime)
+	}
+	return db
+}
@@ -53,3 +53,14 @@ func (d *CreditDAO) GetByMembershipId(ctx context.Context, membershipId string)
 	}
 	return &membershipCredit, nil
 }
+
+// GetListAfterId 按ID升序获取指定ID之后的积分账户，用于分批遍历全部账户
+func (d *CreditDAO) GetListAfterId(ctx context.Context, lastId string, size int) ([]model.Credit, error) {
+	var entities []model.Credit
+	result := d.GetDB(ctx).Where("id > ? and is_deleted = false", lastId).Order("id asc").Limit(size).Find(&entities)
+	if result.Error != nil {
+		d.logger.Error("msg", "分批获取积分账户失败", "lastId", lastId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return entities, nil
+}
@@ -35,3 +35,17 @@ func (d *CreditPaymentRecordDAO) GetConfirmExpiredList(ctx context.Context, size
 	}
 	return entities, nil
 }
+
+// GetListByBillIds 获取扣款流水或回退流水属于指定积分流水的支付凭证
+func (d *CreditPaymentRecordDAO) GetListByBillIds(ctx context.Context, billIds []string) ([]model.CreditPaymentRecord, error) {
+	var entities []model.CreditPaymentRecord
+	if len(billIds) == 0 {
+		return entities, nil
+	}
+	result := d.GetDB(ctx).Where("is_deleted = false and (rel_credit_bid IN ? or retrieve_credit_bid IN ?)", billIds, billIds).Find(&entities)
+	if result.Error != nil {
+		d.logger.Error("msg", "根据积分流水获取支付凭证失败", "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return entities, nil
+}
@@ -0,0 +1,46 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/membership/model"
+	"gorm.io/gorm"
+)
+
+// CreditReconciliationDAO GORM实现的积分对账差异DAO
+type CreditReconciliationDAO struct {
+	*baseDao.GormBaseDAO[model.CreditReconciliation]
+	logger logging.Logger
+}
+
+// NewCreditReconciliationDAO 创建一个新的积分对账差异DAO
+func NewCreditReconciliationDAO(db *gorm.DB, logger logging.Logger) *CreditReconciliationDAO {
+	return &CreditReconciliationDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.CreditReconciliation](db, logger),
+		logger:      logger,
+	}
+}
+
+// GetByCreditIds 获取积分账户的对账差异记录，按积分账户ID索引
+func (d *CreditReconciliationDAO) GetByCreditIds(ctx context.Context, creditIds []string) (map[string]*model.CreditReconciliation, error) {
+	records := make(map[string]*model.CreditReconciliation, len(creditIds))
+	if len(creditIds) == 0 {
+		return records, nil
+	}
+	var entities []model.CreditReconciliation
+	result := d.GetDB(ctx).Where("credit_id IN ? and is_deleted = false", creditIds).Find(&entities)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return records, nil
+		}
+		d.logger.Error("msg", "获取积分对账差异记录失败", "error", result.Error.Error())
+		return nil, result.Error
+	}
+	for i := range entities {
+		records[entities[i].CreditId] = &entities[i]
+	}
+	return records, nil
+}
@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	baseDao "github.com/yb2020/odoc/pkg/dao"
@@ -41,3 +42,45 @@ func (d *OrderDAO) GetTotalNum(ctx context.Context, userId string, orderStatus i
 	}
 	return *totalNum, nil
 }
+
+// GetListByUserId 按创建时间倒序获取用户的全部订单
+func (d *OrderDAO) GetListByUserId(ctx context.Context, userId string) ([]model.Order, error) {
+	var orders []model.Order
+	result := d.GetDB(ctx).Where("user_id = ? and is_deleted = false", userId).Order("created_at desc, id desc").Find(&orders)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取用户订单列表失败", "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return orders, nil
+}
+
+// GetByPayOrderId 根据支付记录ID获取订单
+func (d *OrderDAO) GetByPayOrderId(ctx context.Context, payOrderId string) (*model.Order, error) {
+	var order model.Order
+	result := d.GetDB(ctx).Where("pay_order_id = ? and is_deleted = false", payOrderId).First(&order)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "根据支付记录ID获取订单失败", "payOrderId", payOrderId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &order, nil
+}
+
+// UpdateRefund 按累计退款金额条件更新订单的退款金额和状态，只有当前退款金额等于 fromRefundedAmount 且订单处于可退款状态时才更新
+// 返回是否更新成功，重复的退款通知不会被更新，用作退款处理的幂等保护
+func (d *OrderDAO) UpdateRefund(ctx context.Context, id string, fromRefundedAmount int64, fromStatus int32, toRefundedAmount int64, toStatus int32) (bool, error) {
+	result := d.GetDB(ctx).Model(&model.Order{}).
+		Where("id = ? and is_deleted = false and refunded_amount = ? and order_status = ?", id, fromRefundedAmount, fromStatus).
+		Updates(map[string]interface{}{
+			"refunded_amount": toRefundedAmount,
+			"order_status":    toStatus,
+			"updated_at":      time.Now().UTC(),
+		})
+	if result.Error != nil {
+		d.logger.Error("msg", "更新订单退款金额失败", "orderId", id, "error", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -10,6 +10,8 @@ type NewCreditPayOrder struct {
 	ServiceType pb.CreditServiceType `json:"serviceType"` // 服务功能类型
 	Credit      int64                `json:"credit"`      // 变动积分（单位：0.01个）
 	//AddOnCredit int64                      `json:"addOnCredit"` // 变动附加积分（单位：0.01个）
-	Content string `json:"content"` // 内容
-	Remark  string `json:"remark"`  // 备注
+	Content  string `json:"content"`  // 内容
+	Remark   string `json:"remark"`   // 备注
+	ModelKey string `json:"modelKey"` // AI辅读使用的模型key
+	PdfId    string `json:"pdfId"`    // 关联的文档PDF ID
 }
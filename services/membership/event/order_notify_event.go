@@ -0,0 +1,17 @@
+package event
+
+import "github.com/yb2020/odoc/pkg/eventbus"
+
+// 会员订单的事件类型
+const (
+	// PRO订阅订单全额退款，需要取消支付渠道的订阅，停止后续扣款
+	OrderNotifyEvent_ProRefunded eventbus.EventType = "membership.order.notify.pro_refunded"
+)
+
+// OrderNotifyEvent 会员订单通知事件
+type OrderNotifyEvent struct {
+	OrderId        string // 订单ID
+	UserId         string // 用户ID
+	SubscriptionId string // 支付渠道的订阅ID
+	Reason         string // 原因
+}
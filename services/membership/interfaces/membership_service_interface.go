@@ -39,16 +39,18 @@ type IMembershipService interface {
 	// CreditFunAi 调用积分接口AI辅读功能
 	// funType: AI辅读类型
 	// modelKey: AI模型key
+	// pdfId: 关联的文档ID，记录在积分账单中，无关联文档时为空
 	// invokeFun: 调用AI辅读接口
 	// autoConfirm: 是否自动确认
-	CreditFunAi(ctx context.Context, funType pb.CreditServiceType, modelKey string, invokeFun func(xctx context.Context, sessionId string) error, autoConfirm bool) error
+	CreditFunAi(ctx context.Context, funType pb.CreditServiceType, modelKey string, pdfId string, invokeFun func(xctx context.Context, sessionId string) error, autoConfirm bool) error
 
 	// CreditFunTranslate 调用翻译接口功能
 	// funType: 翻译类型
 	// filePageCount: 文件页数 ，只有全文翻译需要，其他类型可为0
+	// pdfId: 关联的文档ID，记录在积分账单中，无关联文档时为空
 	// invokeFun: 调用翻译接口
 	// autoConfirm: 是否自动确认
-	CreditFunTranslate(ctx context.Context, funType pb.CreditServiceType, filePageCount int32, invokeFun func(xctx context.Context, sessionId string) error, autoConfirm bool) error
+	CreditFunTranslate(ctx context.Context, funType pb.CreditServiceType, filePageCount int32, pdfId string, invokeFun func(xctx context.Context, sessionId string) error, autoConfirm bool) error
 
 	// CreditFunNote 调用笔记接口功能
 	// funType: 笔记类型
@@ -35,6 +35,9 @@ type IOrderService interface {
 	// DoOrderPayExpireHandler 订阅订单支付过期处理
 	DoOrderPayExpireHandler(ctx context.Context, orderId string) error
 
+	// DoOrderRefundHandler 订阅订单退款处理，回收积分并在全额退款时降级会员
+	DoOrderRefundHandler(ctx context.Context, orderId string, payOrderId string, refundedAmount int64, payAmount int64, reason string) error
+
 	// HandlePayNotifyEventHandler 处理支付子系统的异步通知消息机制的支付结果回调事件
 	HandlePayNotifyEventHandler(ctx context.Context, event eventbus.Event) error
 }
@@ -0,0 +1,73 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/yb2020/odoc/config"
+	userContextUtil "github.com/yb2020/odoc/context"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/services/membership/service"
+	userService "github.com/yb2020/odoc/services/user/service"
+)
+
+// creditLedgerReconcileBatchSize 每批核对的积分账户数
+const creditLedgerReconcileBatchSize = 200
+
+// CreditLedgerReconcileJob 积分账户对账任务，核对账户余额与积分流水合计，不一致的账户写入对账差异记录
+type CreditLedgerReconcileJob struct {
+	logger                 logging.Logger
+	spec                   string                 // 任务的cron表达式，6字段标准cron表达式
+	key                    string                 // 任务的锁key，必须是唯一的unique-job-key
+	expiry                 time.Duration          // 任务的锁过期时间
+	lockOpts               *scheduler.LockOptions // 任务的锁选项
+	creditReconcileService *service.CreditReconcileService
+	userService            *userService.UserService
+}
+
+func NewCreditLedgerReconcileJob(logger logging.Logger, cfg *config.Config, creditReconcileService *service.CreditReconcileService, userService *userService.UserService) *CreditLedgerReconcileJob {
+	spec := cfg.Scheduler.Jobs.CreditLedgerReconcileJob.Spec
+	key := cfg.Scheduler.Jobs.CreditLedgerReconcileJob.Key
+	expiry := time.Duration(cfg.Scheduler.Jobs.CreditLedgerReconcileJob.Expiry) * time.Second
+	lockOpts := &scheduler.LockOptions{
+		Key:    key,
+		Expiry: expiry,
+	}
+	return &CreditLedgerReconcileJob{logger: logger, spec: spec, key: key, expiry: expiry, lockOpts: lockOpts, creditReconcileService: creditReconcileService, userService: userService}
+}
+
+// Spec 获取任务的cron表达式
+func (j *CreditLedgerReconcileJob) Spec() string {
+	return j.spec
+}
+
+// LockOpts 获取任务的锁选项
+func (j *CreditLedgerReconcileJob) LockOpts() *scheduler.LockOptions {
+	return j.lockOpts
+}
+
+// NewUserContext 创建用户上下文
+func (j *CreditLedgerReconcileJob) NewUserContext(ctx context.Context, userId string) context.Context {
+	user, err := j.userService.GetUserByID(ctx, userId)
+	if err != nil {
+		j.logger.Error("msg", "Get user by id failed", "userId", userId, "error", err)
+		return ctx
+	}
+	return userContextUtil.SetUserContext(ctx, user)
+}
+
+// Run 执行任务，在执行任务前会获取锁，执行任务后会释放锁
+func (j *CreditLedgerReconcileJob) Run() {
+	ctx := context.Background()
+	checked, mismatched, err := j.creditReconcileService.ReconcileAll(ctx, creditLedgerReconcileBatchSize)
+	if err != nil {
+		j.logger.Error("msg", "Credit ledger reconcile job failed", "checked", checked, "mismatched", mismatched, "error", err)
+		return
+	}
+	if mismatched > 0 {
+		j.logger.Warn("msg", "Credit ledger reconcile job found mismatched accounts", "checked", checked, "mismatched", mismatched)
+		return
+	}
+	j.logger.Info("msg", "Credit ledger reconcile job success", "checked", checked)
+}
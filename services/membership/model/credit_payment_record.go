@@ -26,6 +26,8 @@ type CreditPaymentRecord struct {
 	ConfirmAt         *time.Time `json:"confirmAt" gorm:"column:confirm_at;comment:支付确认时间（支付成功或失败的确认时间）"`                                       //支付确认时间
 	ConfirmExpiredAt  *time.Time `json:"confirmExpiredAt" gorm:"column:confirm_expired_at;comment:支付确认过期时间"`                                    //支付确认过期时间
 	RetrieveCreditBid string     `json:"retrieveCreditBid" gorm:"column:retrieve_credit_bid;index;comment:回退积分关联的积分流水ID"`
+	ModelKey          string     `json:"modelKey" gorm:"column:model_key;varchar(64);comment:AI辅读使用的模型key"` //AI辅读使用的模型key
+	PdfId             string     `json:"pdfId" gorm:"column:pdf_id;index;comment:关联的文档PDF ID"`              //消费关联的文档，用于积分账单展示
 }
 
 // TableName 指定表名
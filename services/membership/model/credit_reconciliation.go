@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// 积分对账状态
+const (
+	CreditReconciliationStatus_Mismatch = 1 // 账户余额与流水合计不一致，待人工核查
+	CreditReconciliationStatus_Resolved = 2 // 后续对账已一致
+)
+
+// CreditReconciliation 积分账户对账差异记录，每个积分账户最多一条，由对账任务写入和更新
+type CreditReconciliation struct {
+	model.BaseModel             // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	UserId            string    `json:"userId" gorm:"column:user_id;index"`                   // 用户ID
+	MembershipId      string    `json:"membershipId" gorm:"column:membership_id;index"`       // 会员ID
+	CreditId          string    `json:"creditId" gorm:"column:credit_id;uniqueIndex"`         // 账号积分ID
+	Credit            int64     `json:"credit" gorm:"column:credit"`                          // 账户积分余额（单位：0.01个）
+	LedgerCredit      int64     `json:"ledgerCredit" gorm:"column:ledger_credit"`             // 流水合计的积分（单位：0.01个）
+	AddOnCredit       int64     `json:"addOnCredit" gorm:"column:add_on_credit"`              // 账户附加积分余额（单位：0.01个）
+	LedgerAddOnCredit int64     `json:"ledgerAddOnCredit" gorm:"column:ledger_add_on_credit"` // 流水合计的附加积分（单位：0.01个）
+	Status            int32     `json:"status" gorm:"column:status;index"`                    // 对账状态 1:不一致 2:已一致
+	CheckedAt         time.Time `json:"checkedAt" gorm:"column:checked_at"`                   // 最近一次对账时间
+}
+
+// TableName 返回表名
+func (CreditReconciliation) TableName() string {
+	return "t_membership_credit_reconciliation"
+}
@@ -12,7 +12,7 @@ type Order struct {
 	UserId               string    `json:"userId" gorm:"column:user_id;index;not null"`                              // 用户ID
 	MembershipId         string    `json:"membershipId" gorm:"column:membership_id;index;not null"`                  // 会员ID
 	PayOrderId           string    `json:"payOrderId" gorm:"column:pay_order_id;index"`                              // 支付订单ID, 与支付订单表关联
-	OrderStatus          int32     `json:"orderStatus" gorm:"column:order_status;index;not null"`                    // 订单状态（1:待支付，2:已支付，3:已完成 4:已取消）
+	OrderStatus          int32     `json:"orderStatus" gorm:"column:order_status;index;not null"`                    // 订单状态（1:待支付，2:已支付，3:处理中，4:已完成，5:已取消，6:支付失败，7:已退款，8:部分退款）
 	OrderType            int32     `json:"orderType" gorm:"column:order_type;index;not null"`                        // 订单类型（1:Free会员，2:PRO会员，3:PRO会员附加积分包）
 	SubName              string    `json:"subName" gorm:"column:sub_name;index"`                                     // 订阅名称
 	SubCredit            int64     `json:"subCredit" gorm:"column:sub_credit;index; default:0"`                      // 订阅积分（单位：0.01个）
@@ -32,6 +32,7 @@ type Order struct {
 	StripePayMode        string    `json:"stripePayMode" gorm:"column:stripe_pay_mode;index"`                        // 支付模式 payment: 一次性付款, subscription: 订阅
 	StripePriceId        string    `json:"stripePriceId" gorm:"column:stripe_price_id;index"`                        // 价格ID, subscription模式下必填
 	StripeSubscriptionId string    `json:"stripeSubscriptionId" gorm:"column:stripe_subscription_id;index"`          // Stripe订阅ID, subscription模式下通知返回
+	RefundedAmount       int64     `json:"refundedAmount" gorm:"column:refunded_amount;default:0"`                   // 累计退款金额（单位：分），与支付记录的累计退款金额一致
 }
 
 // TableName 返回表名
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/pkg/takeout"
 	"google.golang.org/grpc"
 	"gorm.io/gorm"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/yb2020/odoc/services/membership/api"
 	"github.com/yb2020/odoc/services/membership/dao"
 	"github.com/yb2020/odoc/services/membership/interfaces"
+	"github.com/yb2020/odoc/services/membership/job"
 	"github.com/yb2020/odoc/services/membership/service"
 	payevent "github.com/yb2020/odoc/services/pay/event"
 	userEvent "github.com/yb2020/odoc/services/user/event"
@@ -27,6 +29,12 @@ import (
 // 编译时类型检查：确保 MembershipModule 实现了 registry.Module 接口
 var _ registry.Module = (*MembershipModule)(nil)
 
+// 编译时类型检查：确保 MembershipModule 实现了 registry.TakeoutExporterProvider 接口
+var _ registry.TakeoutExporterProvider = (*MembershipModule)(nil)
+
+// 编译时类型检查：确保 MembershipModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*MembershipModule)(nil)
+
 // Module 导出模块实例，用于自动发现和注册
 var Module = &MembershipModule{}
 
@@ -49,8 +57,14 @@ type MembershipModule struct {
 	creditBillService     *service.CreditBillService
 	creditPaymentService  interfaces.ICreditPaymentService
 
-	membershipAPI *api.MembershipApi
-	orderAPI      *api.OrderApi
+	creditStatementService *service.CreditStatementService
+	creditReconcileService *service.CreditReconcileService
+	takeoutExporter        *service.MembershipTakeoutExporter
+	userDataDeleter        *service.MembershipUserDataDeleter
+
+	membershipAPI      *api.MembershipApi
+	orderAPI           *api.OrderApi
+	creditStatementAPI *api.CreditStatementApi
 }
 
 // NewModule 创建会员模块
@@ -91,6 +105,13 @@ func (m *MembershipModule) RegisterGRPC(server *grpc.Server) {
 
 // RegisterJobSchedulers 注册Job定时任务
 func (m *MembershipModule) RegisterJobSchedulers(scheduler *scheduler.Scheduler) {
+	if scheduler == nil {
+		m.logger.Debug("msg", "调度器未启用，会员模块跳过Job注册")
+		return
+	}
+	reconcileJob := job.NewCreditLedgerReconcileJob(m.logger, m.cfg, m.creditReconcileService, m.userService)
+	scheduler.RegisterJobs(reconcileJob)
+
 	// // TODO: 实现Job定时任务注册
 	// m.logger.Debug("msg", "会员模块注册Job定时任务")
 	// msJob := job.NewMembershipExpiredJob(m.logger, m.cfg, m.membershipService, m.userService)
@@ -131,7 +152,7 @@ func (m *MembershipModule) Initialize() error {
 
 	// 初始化会员订单服务
 	membershipSubOrderDAO := dao.NewOrderDAO(m.db, m.logger)
-	m.orderService = service.NewOrderService(m.logger, m.tracer, membershipSubOrderDAO, m.msConfigService, m.creditService, m.userMembershipService)
+	m.orderService = service.NewOrderService(m.logger, m.tracer, membershipSubOrderDAO, m.eventBus, m.msConfigService, m.creditService, m.userMembershipService)
 
 	// 初始化会员服务
 	m.membershipService = service.NewMembershipService(m.logger, m.tracer, m.msConfigService, m.userMembershipService, m.orderService, m.creditService, m.creditPaymentService)
@@ -139,6 +160,20 @@ func (m *MembershipModule) Initialize() error {
 
 	m.orderAPI = api.NewOrderApi(m.logger, m.tracer, m.orderService, m.membershipService)
 
+	// 初始化积分账单和对账服务
+	m.creditStatementService = service.NewCreditStatementService(m.logger, m.tracer, membershipCreditDAO, membershipCreditBillDAO, creditPaymentRecordDAO)
+	creditReconciliationDAO := dao.NewCreditReconciliationDAO(m.db, m.logger)
+	m.creditReconcileService = service.NewCreditReconcileService(m.logger, m.tracer, membershipCreditDAO, membershipCreditBillDAO, creditReconciliationDAO)
+	m.creditStatementAPI = api.NewCreditStatementApi(m.logger, m.tracer, m.creditStatementService)
+
+	// 初始化用户数据导出器
+	m.takeoutExporter = service.NewMembershipTakeoutExporter(m.logger, m.tracer, userMembershipDAO, membershipSubOrderDAO,
+		membershipCreditDAO, membershipCreditBillDAO)
+
+	// 初始化注销账号时的数据删除器
+	m.userDataDeleter = service.NewMembershipUserDataDeleter(m.logger, m.tracer, userMembershipDAO, membershipCreditDAO,
+		membershipCreditBillDAO, creditPaymentRecordDAO, creditReconciliationDAO)
+
 	// 订阅pay模块支付成功事件
 	m.eventBus.Subscribe(payevent.PayNotifyEvent_PaySuccess, func(ctx context.Context, event eventbus.Event) {
 		m.logger.Info("msg", "收到支付成功事件", "event", event)
@@ -172,6 +207,14 @@ func (m *MembershipModule) Initialize() error {
 		m.logger.Info("msg", "收到发票支付失败事件", "event", event)
 		m.orderService.HandlePayNotifyEventHandler(ctx, event)
 	})
+	m.eventBus.Subscribe(payevent.PayNotifyEvent_PayRefunded, func(ctx context.Context, event eventbus.Event) {
+		m.logger.Info("msg", "收到支付退款事件", "event", event)
+		m.orderService.HandlePayNotifyEventHandler(ctx, event)
+	})
+	m.eventBus.Subscribe(payevent.PayNotifyEvent_PayDisputeCreated, func(ctx context.Context, event eventbus.Event) {
+		m.logger.Info("msg", "收到支付争议事件", "event", event)
+		m.orderService.HandlePayNotifyEventHandler(ctx, event)
+	})
 
 	// 订阅用户注册事件
 	m.eventBus.Subscribe(userEvent.UserRegisterEvent, func(ctx context.Context, event eventbus.Event) {
@@ -203,6 +246,11 @@ func (m *MembershipModule) RegisterRoutes(r *gin.Engine) {
 		MembershipGroup.GET("/get-info", m.membershipAPI.GetInfo)
 		MembershipGroup.GET("/user/profile", m.membershipAPI.GetMembershipAndUserInfo)
 		MembershipGroup.GET("/get-user-credit", m.membershipAPI.GetUserCredit)
+
+		// 积分账单
+		MembershipGroup.GET("/credit/statement", m.creditStatementAPI.GetStatement)
+		MembershipGroup.GET("/credit/statement/download", m.creditStatementAPI.DownloadStatement)
+		MembershipGroup.GET("/credit/usage/monthly", m.creditStatementAPI.GetMonthlyUsage)
 	}
 
 	MembershipPublicGroup := r.Group("/api/public/membership")
@@ -220,6 +268,16 @@ func (m *MembershipModule) RegisterRoutes(r *gin.Engine) {
 	}
 }
 
+// TakeoutExporters 返回会员模块的用户数据导出器
+func (m *MembershipModule) TakeoutExporters() []takeout.Exporter {
+	return []takeout.Exporter{m.takeoutExporter}
+}
+
+// DeleteUserData 注销账号时删除用户的会员信息和积分数据，订单保留
+func (m *MembershipModule) DeleteUserData(ctx context.Context, userId string) error {
+	return m.userDataDeleter.DeleteUserData(ctx, userId)
+}
+
 // GetOrderService 获取订单服务
 func (m *MembershipModule) GetOrderService() interfaces.IOrderService {
 	return m.orderService
@@ -229,3 +287,8 @@ func (m *MembershipModule) GetOrderService() interfaces.IOrderService {
 func (m *MembershipModule) GetMembershipService() interfaces.IMembershipService {
 	return m.membershipService
 }
+
+// GetRateLimitTierResolver 获取接口限流使用的会员类型查询
+func (m *MembershipModule) GetRateLimitTierResolver() middleware.MembershipTierResolver {
+	return service.NewRateLimitTierAdapter(m.userMembershipService)
+}
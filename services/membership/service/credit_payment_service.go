@@ -50,6 +50,8 @@ func (s *CreditPaymentService) NewPaymentOrder(ctx context.Context, membershipId
 		Status:       int32(pb.CreditPaymentStatus_CREDIT_PAYMENT_STATUS_PAY_PENDING),
 		Content:      payCredit.Content,
 		Remark:       payCredit.Remark,
+		ModelKey:     payCredit.ModelKey,
+		PdfId:        payCredit.PdfId,
 	}
 	record.Id = idgen.GenerateUUID()
 
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/membership/dao"
+	"github.com/yb2020/odoc/services/membership/model"
+)
+
+// CreditReconcileService 积分对账服务，核对积分账户余额与积分流水合计是否一致
+// 积分账户创建时余额为0，之后每次变动都会写入一条流水，因此流水的变动合计应等于当前余额
+type CreditReconcileService struct {
+	logger                  logging.Logger
+	tracer                  opentracing.Tracer
+	creditDAO               *dao.CreditDAO
+	creditBillDAO           *dao.CreditBillDAO
+	creditReconciliationDAO *dao.CreditReconciliationDAO
+}
+
+func NewCreditReconcileService(logger logging.Logger, tracer opentracing.Tracer, creditDAO *dao.CreditDAO, creditBillDAO *dao.CreditBillDAO, creditReconciliationDAO *dao.CreditReconciliationDAO) *CreditReconcileService {
+	return &CreditReconcileService{
+		logger:                  logger,
+		tracer:                  tracer,
+		creditDAO:               creditDAO,
+		creditBillDAO:           creditBillDAO,
+		creditReconciliationDAO: creditReconciliationDAO,
+	}
+}
+
+// ReconcileAll 分批核对全部积分账户，返回核对的账户数和不一致的账户数
+func (s *CreditReconcileService) ReconcileAll(ctx context.Context, batchSize int) (int, int, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "CreditReconcileService.ReconcileAll")
+	defer span.Finish()
+
+	checked, mismatched := 0, 0
+	lastId := ""
+	for {
+		accounts, err := s.creditDAO.GetListAfterId(ctx, lastId, batchSize)
+		if err != nil {
+			return checked, mismatched, err
+		}
+		if len(accounts) == 0 {
+			break
+		}
+		count, err := s.reconcileBatch(ctx, accounts)
+		if err != nil {
+			return checked, mismatched, err
+		}
+		checked += len(accounts)
+		mismatched += count
+		lastId = accounts[len(accounts)-1].Id
+		if len(accounts) < batchSize {
+			break
+		}
+	}
+	return checked, mismatched, nil
+}
+
+// reconcileBatch 核对一批积分账户，不一致的账户写入对账差异记录，已恢复一致的记录标记为已一致
+func (s *CreditReconcileService) reconcileBatch(ctx context.Context, accounts []model.Credit) (int, error) {
+	creditIds := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		creditIds = append(creditIds, account.Id)
+	}
+	sums, err := s.creditBillDAO.SumChangesByCreditIds(ctx, creditIds)
+	if err != nil {
+		return 0, err
+	}
+	records, err := s.creditReconciliationDAO.GetByCreditIds(ctx, creditIds)
+	if err != nil {
+		return 0, err
+	}
+
+	mismatched := 0
+	now := time.Now()
+	for _, account := range accounts {
+		sum := sums[account.Id]
+		record := records[account.Id]
+		if sum.Credit != account.Credit || sum.AddOnCredit != account.AddOnCredit {
+			// 余额和流水不在同一事务中写入，变动过程中可能短暂不一致，重新读取后再次核对
+			latest, latestSum, err := s.reload(ctx, account.Id)
+			if err != nil {
+				return mismatched, err
+			}
+			if latest == nil {
+				continue
+			}
+			account, sum = *latest, latestSum
+		}
+
+		if sum.Credit == account.Credit && sum.AddOnCredit == account.AddOnCredit {
+			if record != nil && record.Status == model.CreditReconciliationStatus_Mismatch {
+				record.Status = model.CreditReconciliationStatus_Resolved
+				record.Credit, record.LedgerCredit = account.Credit, sum.Credit
+				record.AddOnCredit, record.LedgerAddOnCredit = account.AddOnCredit, sum.AddOnCredit
+				record.CheckedAt = now
+				if err := s.creditReconciliationDAO.Modify(ctx, record); err != nil {
+					return mismatched, err
+				}
+				s.logger.Info("msg", "积分账户对账已恢复一致", "creditId", account.Id, "userId", account.UserId)
+			}
+			continue
+		}
+
+		mismatched++
+		s.logger.Warn("msg", "积分账户余额与流水合计不一致", "creditId", account.Id, "userId", account.UserId,
+			"credit", account.Credit, "ledgerCredit", sum.Credit, "addOnCredit", account.AddOnCredit, "ledgerAddOnCredit", sum.AddOnCredit)
+		isNew := record == nil
+		if isNew {
+			record = &model.CreditReconciliation{
+				UserId:       account.UserId,
+				MembershipId: account.MembershipId,
+				CreditId:     account.Id,
+			}
+		}
+		record.Status = model.CreditReconciliationStatus_Mismatch
+		record.Credit, record.LedgerCredit = account.Credit, sum.Credit
+		record.AddOnCredit, record.LedgerAddOnCredit = account.AddOnCredit, sum.AddOnCredit
+		record.CheckedAt = now
+		if isNew {
+			err = s.creditReconciliationDAO.Save(ctx, record)
+		} else {
+			err = s.creditReconciliationDAO.Modify(ctx, record)
+		}
+		if err != nil {
+			return mismatched, err
+		}
+	}
+	return mismatched, nil
+}
+
+// reload 重新读取积分账户和流水合计，账户已删除时返回nil
+func (s *CreditReconcileService) reload(ctx context.Context, creditId string) (*model.Credit, dao.CreditBillChangeSum, error) {
+	account, err := s.creditDAO.FindExistById(ctx, creditId)
+	if err != nil || account == nil {
+		return nil, dao.CreditBillChangeSum{}, err
+	}
+	sums, err := s.creditBillDAO.SumChangesByCreditIds(ctx, []string{creditId})
+	if err != nil {
+		return nil, dao.CreditBillChangeSum{}, err
+	}
+	return account, sums[creditId], nil
+}
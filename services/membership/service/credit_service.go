@@ -9,6 +9,7 @@ import (
 	"github.com/yb2020/odoc/internal/biz"
 	"github.com/yb2020/odoc/pkg/errors"
 	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/metrics"
 	pb "github.com/yb2020/odoc/proto/gen/go/membership"
 	"github.com/yb2020/odoc/services/membership/dao"
 	"github.com/yb2020/odoc/services/membership/dto"
@@ -129,6 +130,9 @@ func (s *CreditService) InOrOutCredit(ctx context.Context, userId string, member
 		}
 		return "0", nil
 	}
+	if payCredit.Type == pb.CreditPayType_CREDIT_PAY_TYPE_REFUND_REVOKE {
+		return s.revokeCredit(ctx, userId, membershipId, payCredit) //退款回收积分和附加积分
+	}
 	if payCredit.Type == pb.CreditPayType_CREDIT_PAY_TYPE_SUB_FREE {
 		s.resetCreditZero(ctx, userId, membershipId, int32(pb.CreditPayType_CREDIT_PAY_TYPE_EXPIRED), payCredit.Content, payCredit.Remark) //积分过期
 		return s.inCredit(ctx, userId, membershipId, int32(payCredit.Type), payCredit.Credit, payCredit.Content, payCredit.Remark)         //订阅Free积分
@@ -157,6 +161,35 @@ func (s *CreditService) InOrOutCredit(ctx context.Context, userId string, member
 	return "0", errors.BizWithStatus(biz.Membership_Status_CreditBillTypeUnknown, "credit bill type unknown")
 }
 
+// revokeCredit 订单退款时回收已发放的积分和附加积分，已消费的部分无法回收，最多扣减至0
+func (s *CreditService) revokeCredit(ctx context.Context, userId string, membershipId string, payCredit dto.CreditPayIntent) (string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MembershipCreditService.revokeCredit")
+	defer span.Finish()
+
+	membershipCredit, err := s.GetByMembershipId(ctx, membershipId)
+	if err != nil {
+		return "0", err
+	}
+	if membershipCredit == nil {
+		return "0", errors.BizWithStatus(biz.Membership_Status_UserCreditAccountNotFound, "credit account not found")
+	}
+
+	billId := "0"
+	if credit := min(payCredit.Credit, membershipCredit.Credit); credit > 0 {
+		billId, err = s.outCredit(ctx, userId, membershipId, int32(payCredit.Type), credit, payCredit.Content, payCredit.Remark)
+		if err != nil {
+			return "0", err
+		}
+	}
+	if addOnCredit := min(payCredit.AddOnCredit, membershipCredit.AddOnCredit); addOnCredit > 0 {
+		billId, err = s.outAddOnCredit(ctx, userId, membershipId, int32(payCredit.Type), addOnCredit, payCredit.Content, payCredit.Remark)
+		if err != nil {
+			return "0", err
+		}
+	}
+	return billId, nil
+}
+
 // resetCreditZero 重置用户会员积分账户为0
 func (s *CreditService) resetCreditZero(ctx context.Context, userId string, membershipId string, billType int32, content string, remark string) (string, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MembershipCreditService.resetCreditZero")
@@ -528,6 +561,7 @@ func (s *CreditService) Pay(ctx context.Context, userId string, membershipId str
 	}
 
 	s.logger.Info("msg", "Pay success", "userId", userId, "paymentRecordId", paymentRecordId)
+	metrics.RecordCreditConsumption(ctx, payCredit.ServiceType.String(), record.Credit)
 
 	return nil
 }
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/signintech/gopdf"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	pb "github.com/yb2020/odoc/proto/gen/go/membership"
+	"github.com/yb2020/odoc/services/membership/dao"
+	"github.com/yb2020/odoc/services/membership/model"
+)
+
+const (
+	creditStatementDefaultPageSize  = 20
+	creditStatementMaxPageSize      = 100
+	creditMonthlyUsageDefaultMonths = 6
+	creditMonthlyUsageMaxMonths     = 12
+	creditStatementExportBatchSize  = 500
+	creditStatementExportMaxRows    = 5000 // 单个账单文件最多导出的流水条数
+)
+
+// 积分账单文件格式
+const (
+	CreditStatementFormatCsv = "csv"
+	CreditStatementFormatPdf = "pdf"
+)
+
+// CreditStatementFile 导出的积分账单文件
+type CreditStatementFile struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// CreditStatementService 积分账单服务，基于积分流水和积分支付凭证生成账单明细、按月汇总和账单文件
+type CreditStatementService struct {
+	logger                 logging.Logger
+	tracer                 opentracing.Tracer
+	creditDAO              *dao.CreditDAO
+	creditBillDAO          *dao.CreditBillDAO
+	creditPaymentRecordDAO *dao.CreditPaymentRecordDAO
+}
+
+func NewCreditStatementService(logger logging.Logger, tracer opentracing.Tracer, creditDAO *dao.CreditDAO, creditBillDAO *dao.CreditBillDAO, creditPaymentRecordDAO *dao.CreditPaymentRecordDAO) *CreditStatementService {
+	return &CreditStatementService{
+		logger:                 logger,
+		tracer:                 tracer,
+		creditDAO:              creditDAO,
+		creditBillDAO:          creditBillDAO,
+		creditPaymentRecordDAO: creditPaymentRecordDAO,
+	}
+}
+
+// GetStatement 分页获取用户的积分账单明细，每条明细带变动后的余额和消费来源
+func (s *CreditStatementService) GetStatement(ctx context.Context, userId string, req *pb.GetCreditStatementRequest) (*pb.GetCreditStatementResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "CreditStatementService.GetStatement")
+	defer span.Finish()
+
+	page := max(int(req.CurrentPage), 1)
+	size := int(req.PageSize)
+	if size <= 0 {
+		size = creditStatementDefaultPageSize
+	}
+	size = min(size, creditStatementMaxPageSize)
+
+	bills, total, err := s.creditBillDAO.GetPageByUserId(ctx, userId, millisToTime(req.StartTime), millisToTime(req.EndTime), page, size)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.buildEntries(ctx, bills)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.GetCreditStatementResponse{
+		Total:   total,
+		Entries: entries,
+	}
+	creditAccount, err := s.creditDAO.GetByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if creditAccount != nil {
+		resp.Credit = creditAccount.Credit
+		resp.AddOnCredit = creditAccount.AddOnCredit
+	}
+	return resp, nil
+}
+
+// GetMonthlyUsage 获取最近几个月按功能汇总的积分收支，月份按 timezone 划分
+func (s *CreditStatementService) GetMonthlyUsage(ctx context.Context, userId string, months uint32, timezone string) (*pb.GetCreditMonthlyUsageResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "CreditStatementService.GetMonthlyUsage")
+	defer span.Finish()
+
+	loc, err := loadStatementLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	if months == 0 {
+		months = creditMonthlyUsageDefaultMonths
+	}
+	months = min(months, creditMonthlyUsageMaxMonths)
+
+	now := time.Now().In(loc)
+	startTime := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc).AddDate(0, -int(months-1), 0)
+	bills, err := s.creditBillDAO.GetListByUserIdAndTimeRange(ctx, userId, startTime, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.buildEntries(ctx, bills)
+	if err != nil {
+		return nil, err
+	}
+
+	type usageKey struct {
+		month   string
+		feature pb.CreditFeature
+	}
+	usages := make(map[usageKey]*pb.CreditMonthlyUsage)
+	for _, entry := range entries {
+		key := usageKey{
+			month:   time.UnixMilli(int64(entry.CreatedAt)).In(loc).Format("2006-01"),
+			feature: entry.Feature,
+		}
+		usage, ok := usages[key]
+		if !ok {
+			usage = &pb.CreditMonthlyUsage{Month: key.month, Feature: key.feature}
+			usages[key] = usage
+		}
+		if change := entry.Credit + entry.AddOnCredit; change >= 0 {
+			usage.Income += change
+		} else {
+			usage.Expense -= change
+		}
+		usage.Count++
+	}
+
+	items := make([]*pb.CreditMonthlyUsage, 0, len(usages))
+	for _, usage := range usages {
+		items = append(items, usage)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Month != items[j].Month {
+			return items[i].Month > items[j].Month
+		}
+		return items[i].Feature < items[j].Feature
+	})
+	return &pb.GetCreditMonthlyUsageResponse{Items: items}, nil
+}
+
+// ExportStatement 导出时间范围内的积分账单文件，最多导出 creditStatementExportMaxRows 条流水
+func (s *CreditStatementService) ExportStatement(ctx context.Context, userId string, req *pb.DownloadCreditStatementRequest) (*CreditStatementFile, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "CreditStatementService.ExportStatement")
+	defer span.Finish()
+
+	format := strings.ToLower(req.Format)
+	if format == "" {
+		format = CreditStatementFormatCsv
+	}
+	if format != CreditStatementFormatCsv && format != CreditStatementFormatPdf {
+		return nil, errors.Biz("unsupported statement format")
+	}
+	loc, err := loadStatementLocation(req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime, endTime := millisToTime(req.StartTime), millisToTime(req.EndTime)
+	var entries []*pb.CreditStatementEntry
+	for page := 1; len(entries) < creditStatementExportMaxRows; page++ {
+		bills, total, err := s.creditBillDAO.GetPageByUserId(ctx, userId, startTime, endTime, page, creditStatementExportBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		pageEntries, err := s.buildEntries(ctx, bills)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, pageEntries...)
+		if len(bills) < creditStatementExportBatchSize || int64(page*creditStatementExportBatchSize) >= total {
+			break
+		}
+	}
+	if len(entries) > creditStatementExportMaxRows {
+		entries = entries[:creditStatementExportMaxRows]
+	}
+
+	fileName := "credit-statement-" + time.Now().In(loc).Format("20060102150405")
+	if format == CreditStatementFormatPdf {
+		data, err := s.renderPdf(userId, entries, startTime, endTime, loc)
+		if err != nil {
+			return nil, err
+		}
+		return &CreditStatementFile{FileName: fileName + ".pdf", ContentType: "application/pdf", Data: data}, nil
+	}
+	data, err := renderStatementCsv(entries, loc)
+	if err != nil {
+		return nil, err
+	}
+	return &CreditStatementFile{FileName: fileName + ".csv", ContentType: "text/csv; charset=utf-8", Data: data}, nil
+}
+
+// buildEntries 将积分流水转换为账单明细，通过支付凭证的扣款流水或回退流水关联消费的功能、模型和文档
+func (s *CreditStatementService) buildEntries(ctx context.Context, bills []model.CreditBill) ([]*pb.CreditStatementEntry, error) {
+	billIds := make([]string, 0, len(bills))
+	for _, bill := range bills {
+		billIds = append(billIds, bill.Id)
+	}
+	// 按批查询支付凭证，避免按月汇总时 IN 条件过长
+	recordByBillId := make(map[string]*model.CreditPaymentRecord, len(bills))
+	for start := 0; start < len(billIds); start += creditStatementExportBatchSize {
+		records, err := s.creditPaymentRecordDAO.GetListByBillIds(ctx, billIds[start:min(start+creditStatementExportBatchSize, len(billIds))])
+		if err != nil {
+			return nil, err
+		}
+		for i := range records {
+			if records[i].RelCreditBid != "" {
+				recordByBillId[records[i].RelCreditBid] = &records[i]
+			}
+			if records[i].RetrieveCreditBid != "" {
+				recordByBillId[records[i].RetrieveCreditBid] = &records[i]
+			}
+		}
+	}
+
+	entries := make([]*pb.CreditStatementEntry, 0, len(bills))
+	for _, bill := range bills {
+		entry := &pb.CreditStatementEntry{
+			Id:                 bill.Id,
+			PayType:            pb.CreditPayType(bill.Type),
+			CreditType:         pb.CreditType(bill.CreditType),
+			InOutType:          pb.CreditInOutType(bill.InOutType),
+			Credit:             bill.AfterCredit - bill.BeforeCredit,
+			AddOnCredit:        bill.AfterAddOnCredit - bill.BeforeAddOnCredit,
+			BalanceCredit:      bill.AfterCredit,
+			BalanceAddOnCredit: bill.AfterAddOnCredit,
+			Balance:            bill.AfterCredit + bill.AfterAddOnCredit,
+			Content:            bill.Content,
+			Remark:             bill.Remark,
+			CreatedAt:          uint64(bill.CreatedAt.UnixMilli()),
+		}
+		if record, ok := recordByBillId[bill.Id]; ok {
+			entry.ServiceType = pb.CreditServiceType(record.ServiceType)
+			entry.ModelKey = record.ModelKey
+			entry.PdfId = record.PdfId
+			entry.PaymentRecordId = record.Id
+		}
+		entry.Feature = creditFeatureOf(entry.PayType, entry.ServiceType)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// renderPdf 生成PDF格式的积分账单，字体与笔记导出共用思源黑体以支持中文内容
+func (s *CreditStatementService) renderPdf(userId string, entries []*pb.CreditStatementEntry, startTime time.Time, endTime time.Time, loc *time.Location) ([]byte, error) {
+	fontPath := findStatementFontPath()
+	if fontPath == "" {
+		s.logger.Error("msg", "找不到积分账单字体文件")
+		return nil, errors.Biz("statement font not found")
+	}
+
+	pdf := gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4Landscape})
+	if err := pdf.AddTTFFont("chinese", fontPath); err != nil {
+		return nil, errors.Wrap(err, "加载字体失败")
+	}
+	pdf.AddPage()
+	if err := pdf.SetFont("chinese", "", 16); err != nil {
+		return nil, errors.Wrap(err, "设置字体失败")
+	}
+
+	// 标题和账单信息
+	pdf.SetXY(statementPdfMargin, statementPdfMargin)
+	_ = pdf.Cell(nil, "Credit Statement / 积分账单")
+	_ = pdf.SetFont("chinese", "", 9)
+	period := "all"
+	if !startTime.IsZero() || !endTime.IsZero() {
+		period = formatStatementPeriodTime(startTime, loc) + " ~ " + formatStatementPeriodTime(endTime, loc)
+	}
+	pdf.SetXY(statementPdfMargin, statementPdfMargin+24)
+	_ = pdf.Cell(nil, fmt.Sprintf("User: %s    Period: %s    Generated: %s    Entries: %d", userId, period, time.Now().In(loc).Format("2006-01-02 15:04:05 MST"), len(entries)))
+
+	y := statementPdfMargin + 48
+	y = drawStatementPdfRow(&pdf, y, statementCsvHeader[:len(statementPdfColumnWidths)], true)
+	for _, entry := range entries {
+		if y+statementPdfRowHeight > gopdf.PageSizeA4Landscape.H-statementPdfMargin {
+			pdf.AddPage()
+			_ = pdf.SetFont("chinese", "", 9)
+			y = drawStatementPdfRow(&pdf, statementPdfMargin, statementCsvHeader[:len(statementPdfColumnWidths)], true)
+		}
+		y = drawStatementPdfRow(&pdf, y, statementRow(entry, loc)[:len(statementPdfColumnWidths)], false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Write(&buf); err != nil {
+		s.logger.Error("msg", "生成积分账单PDF失败", "error", err.Error())
+		return nil, errors.Wrap(err, "生成PDF失败")
+	}
+	return buf.Bytes(), nil
+}
+
+const (
+	statementPdfMargin    = 30.0
+	statementPdfRowHeight = 16.0
+)
+
+// PDF账单展示的列宽，对应 statementCsvHeader 的前几列
+var statementPdfColumnWidths = []float64{100, 70, 90, 60, 60, 60, 60, 60, 70, 152}
+
+// statementCsvHeader 账单文件的列
+var statementCsvHeader = []string{"Time", "Feature", "Type", "Credit", "AddOnCredit", "BalanceCredit", "BalanceAddOnCredit", "Balance", "Model", "Content", "PdfId", "PaymentRecordId", "Remark"}
+
+func drawStatementPdfRow(pdf *gopdf.GoPdf, y float64, cells []string, isHeader bool) float64 {
+	if isHeader {
+		pdf.SetFillColor(230, 230, 230)
+		pdf.RectFromUpperLeftWithStyle(statementPdfMargin, y, gopdf.PageSizeA4Landscape.W-statementPdfMargin*2, statementPdfRowHeight, "F")
+	}
+	x := statementPdfMargin
+	for i, cell := range cells {
+		width := statementPdfColumnWidths[i]
+		pdf.SetXY(x+2, y+3)
+		_ = pdf.Cell(nil, truncateStatementText(pdf, cell, width-4))
+		x += width
+	}
+	pdf.SetLineWidth(0.3)
+	pdf.Line(statementPdfMargin, y+statementPdfRowHeight, gopdf.PageSizeA4Landscape.W-statementPdfMargin, y+statementPdfRowHeight)
+	return y + statementPdfRowHeight
+}
+
+// truncateStatementText 截断超过列宽的文本
+func truncateStatementText(pdf *gopdf.GoPdf, text string, width float64) string {
+	if w, err := pdf.MeasureTextWidth(text); err != nil || w <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if w, err := pdf.MeasureTextWidth(string(runes) + "..."); err == nil && w <= width {
+			break
+		}
+	}
+	return string(runes) + "..."
+}
+
+// renderStatementCsv 生成CSV格式的积分账单，带UTF-8 BOM以便表格软件正确识别中文
+func renderStatementCsv(entries []*pb.CreditStatementEntry, loc *time.Location) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\xEF\xBB\xBF")
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(statementCsvHeader); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := writer.Write(statementRow(entry, loc)); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// statementRow 账单明细的一行，列顺序与 statementCsvHeader 一致
+func statementRow(entry *pb.CreditStatementEntry, loc *time.Location) []string {
+	return []string{
+		time.UnixMilli(int64(entry.CreatedAt)).In(loc).Format("2006-01-02 15:04:05"),
+		strings.ToLower(strings.TrimPrefix(entry.Feature.String(), "CREDIT_FEATURE_")),
+		strings.ToLower(strings.TrimPrefix(entry.PayType.String(), "CREDIT_PAY_TYPE_")),
+		formatCreditAmount(entry.Credit),
+		formatCreditAmount(entry.AddOnCredit),
+		formatCreditAmount(entry.BalanceCredit),
+		formatCreditAmount(entry.BalanceAddOnCredit),
+		formatCreditAmount(entry.Balance),
+		entry.ModelKey,
+		entry.Content,
+		entry.PdfId,
+		entry.PaymentRecordId,
+		entry.Remark,
+	}
+}
+
+// creditFeatureOf 根据流水类型和消费的功能类型确定账单的功能分类
+func creditFeatureOf(payType pb.CreditPayType, serviceType pb.CreditServiceType) pb.CreditFeature {
+	switch payType {
+	case pb.CreditPayType_CREDIT_PAY_TYPE_SUB_FREE, pb.CreditPayType_CREDIT_PAY_TYPE_SUB_PRO, pb.CreditPayType_CREDIT_PAY_TYPE_SUB_PRO_ADD_ON_CREDIT:
+		return pb.CreditFeature_CREDIT_FEATURE_SUBSCRIPTION
+	case pb.CreditPayType_CREDIT_PAY_TYPE_EXPIRED, pb.CreditPayType_CREDIT_PAY_TYPE_REFUND_REVOKE:
+		return pb.CreditFeature_CREDIT_FEATURE_SYSTEM
+	}
+
+	switch serviceType {
+	case pb.CreditServiceType_CREDIT_SERVICE_TYPE_DOCS_UPLOAD:
+		return pb.CreditFeature_CREDIT_FEATURE_DOCS
+	case pb.CreditServiceType_CREDIT_SERVICE_TYPE_NOTE_SUMMARY, pb.CreditServiceType_CREDIT_SERVICE_TYPE_NOTE_WORD,
+		pb.CreditServiceType_CREDIT_SERVICE_TYPE_NOTE_EXTRACT, pb.CreditServiceType_CREDIT_SERVICE_TYPE_NOTE_MANAGE,
+		pb.CreditServiceType_CREDIT_SERVICE_TYPE_NOTE_PDF_DOWNLOAD:
+		return pb.CreditFeature_CREDIT_FEATURE_NOTE
+	case pb.CreditServiceType_CREDIT_SERVICE_TYPE_AI_COPILOT:
+		return pb.CreditFeature_CREDIT_FEATURE_AI_COPILOT
+	case pb.CreditServiceType_CREDIT_SERVICE_TYPE_AI_PAPER_SUMMARY:
+		return pb.CreditFeature_CREDIT_FEATURE_AI_SUMMARY
+	case pb.CreditServiceType_CREDIT_SERVICE_TYPE_AI_POLISH:
+		return pb.CreditFeature_CREDIT_FEATURE_AI_POLISH
+	case pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_OCR:
+		return pb.CreditFeature_CREDIT_FEATURE_OCR
+	case pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_WORD, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_FULLTEXT,
+		pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_AI:
+		return pb.CreditFeature_CREDIT_FEATURE_TRANSLATE
+	}
+	return pb.CreditFeature_CREDIT_FEATURE_UNKNOWN
+}
+
+// formatCreditAmount 将以0.01个为单位的积分格式化为两位小数
+func formatCreditAmount(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func formatStatementPeriodTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(loc).Format("2006-01-02 15:04")
+}
+
+// loadStatementLocation 解析账单使用的时区，为空时使用服务器时区
+func loadStatementLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errors.Biz("invalid timezone")
+	}
+	return loc, nil
+}
+
+// millisToTime 毫秒时间戳转时间，0 返回零值表示不限制
+func millisToTime(millis uint64) time.Time {
+	if millis == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(millis))
+}
+
+// findStatementFontPath 查找账单PDF使用的字体文件
+func findStatementFontPath() string {
+	possiblePaths := []string{
+		filepath.Join("resources", "fonts", "SourceHanSansSC-Regular.ttf"),             // 相对于工作目录
+		filepath.Join("..", "..", "resources", "fonts", "SourceHanSansSC-Regular.ttf"), // 相对于服务目录
+	}
+	for _, path := range possiblePaths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
@@ -276,9 +276,10 @@ func (s *MembershipService) CreditFunDocsUpload(ctx context.Context, fileSize in
 // CreditFunAi 调用积分接口AI辅读功能
 // funType: AI辅读类型
 // modelKey: AI模型key
+// pdfId: 关联的文档ID，记录在积分账单中，无关联文档时为空
 // invokeFun: 调用AI辅读接口
 // autoConfirm: 是否自动确认
-func (s *MembershipService) CreditFunAi(ctx context.Context, funType pb.CreditServiceType, modelKey string, invokeFun func(xctx context.Context, sessionId string) error, autoConfirm bool) error {
+func (s *MembershipService) CreditFunAi(ctx context.Context, funType pb.CreditServiceType, modelKey string, pdfId string, invokeFun func(xctx context.Context, sessionId string) error, autoConfirm bool) error {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MembershipService.CreditFunAiCopilot")
 	defer span.Finish()
 
@@ -322,8 +323,22 @@ func (s *MembershipService) CreditFunAi(ctx context.Context, funType pb.CreditSe
 				ServiceType: funType,
 				Credit:      creditCost,
 				//AddOnCredit: 0,
+				ModelKey: modelKey,
+				PdfId:    pdfId,
 			}
 			return true, isNeedCreditPay, payCredit, nil
+		} else if funType == pb.CreditServiceType_CREDIT_SERVICE_TYPE_AI_PAPER_SUMMARY {
+			paperSummary := memberConfig.AI.PaperSummary
+			if !paperSummary.IsEnable {
+				return false, false, nil, errors.BizWithStatus(biz.Membership_Status_CreditService_Ai_PaperSummaryNotEnabled, "ai paper summary not enabled")
+			}
+			payCredit := &dto.NewCreditPayOrder{
+				CreditType:  pb.CreditType_CREDIT_TYPE_CREDIT,
+				ServiceType: funType,
+				Credit:      paperSummary.CreditCost,
+				PdfId:       pdfId,
+			}
+			return true, !paperSummary.IsFree, payCredit, nil
 		}
 		return false, false, nil, errors.BizWithStatus(biz.Membership_Status_CreditServiceTypeUnknown, "credit service type unknown")
 
@@ -334,9 +349,10 @@ func (s *MembershipService) CreditFunAi(ctx context.Context, funType pb.CreditSe
 // CreditFunTranslate 调用翻译接口功能
 // funType: 翻译类型
 // filePageCount: 文件页数
+// pdfId: 关联的文档ID，记录在积分账单中，无关联文档时为空
 // invokeFun: 调用翻译接口
 // autoConfirm: 是否自动确认
-func (s *MembershipService) CreditFunTranslate(ctx context.Context, funType pb.CreditServiceType, filePageCount int32, invokeFun func(xctx context.Context, sessionId string) error, autoConfirm bool) error {
+func (s *MembershipService) CreditFunTranslate(ctx context.Context, funType pb.CreditServiceType, filePageCount int32, pdfId string, invokeFun func(xctx context.Context, sessionId string) error, autoConfirm bool) error {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MembershipService.CreditFunTranslate")
 	defer span.Finish()
 
@@ -384,6 +400,7 @@ func (s *MembershipService) CreditFunTranslate(ctx context.Context, funType pb.C
 			ServiceType: funType,
 			Credit:      creditCost,
 			// AddOnCredit: 0,
+			PdfId: pdfId,
 		}
 
 		return true, true, payCredit, nil
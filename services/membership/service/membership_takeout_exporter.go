@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/takeout"
+	"github.com/yb2020/odoc/services/membership/dao"
+)
+
+// 编译时类型检查：确保 MembershipTakeoutExporter 实现了 takeout.Exporter 接口
+var _ takeout.Exporter = (*MembershipTakeoutExporter)(nil)
+
+// MembershipTakeoutExporter 导出用户的会员信息、订单、积分账户和积分流水
+type MembershipTakeoutExporter struct {
+	logger            logging.Logger
+	tracer            opentracing.Tracer
+	userMembershipDAO *dao.UserMembershipDAO
+	orderDAO          *dao.OrderDAO
+	creditDAO         *dao.CreditDAO
+	creditBillDAO     *dao.CreditBillDAO
+}
+
+// NewMembershipTakeoutExporter 创建会员数据导出器
+func NewMembershipTakeoutExporter(logger logging.Logger, tracer opentracing.Tracer,
+	userMembershipDAO *dao.UserMembershipDAO,
+	orderDAO *dao.OrderDAO,
+	creditDAO *dao.CreditDAO,
+	creditBillDAO *dao.CreditBillDAO,
+) *MembershipTakeoutExporter {
+	return &MembershipTakeoutExporter{
+		logger:            logger,
+		tracer:            tracer,
+		userMembershipDAO: userMembershipDAO,
+		orderDAO:          orderDAO,
+		creditDAO:         creditDAO,
+		creditBillDAO:     creditBillDAO,
+	}
+}
+
+// Name 导出器名称
+func (e *MembershipTakeoutExporter) Name() string {
+	return "membership"
+}
+
+// Export 导出用户的会员数据，没有会员或积分账户时对应文件内容为 null
+func (e *MembershipTakeoutExporter) Export(ctx context.Context, userId string, w *takeout.Writer) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, e.tracer, "MembershipTakeoutExporter.Export")
+	defer span.Finish()
+
+	membership, err := e.userMembershipDAO.GetByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("membership.json", membership); err != nil {
+		return err
+	}
+	orders, err := e.orderDAO.GetListByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("orders.json", orders); err != nil {
+		return err
+	}
+	credit, err := e.creditDAO.GetByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("credit.json", credit); err != nil {
+		return err
+	}
+	bills, err := e.creditBillDAO.GetListByUserIdAndTimeRange(ctx, userId, time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	return w.WriteJSON("credit_bills.json", bills)
+}
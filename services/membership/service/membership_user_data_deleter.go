@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/membership/dao"
+)
+
+// MembershipUserDataDeleter 注销账号时删除用户的会员信息和积分数据
+type MembershipUserDataDeleter struct {
+	logger                  logging.Logger
+	tracer                  opentracing.Tracer
+	userMembershipDAO       *dao.UserMembershipDAO
+	creditDAO               *dao.CreditDAO
+	creditBillDAO           *dao.CreditBillDAO
+	creditPaymentRecordDAO  *dao.CreditPaymentRecordDAO
+	creditReconciliationDAO *dao.CreditReconciliationDAO
+}
+
+// NewMembershipUserDataDeleter 创建会员数据删除器
+func NewMembershipUserDataDeleter(logger logging.Logger, tracer opentracing.Tracer,
+	userMembershipDAO *dao.UserMembershipDAO,
+	creditDAO *dao.CreditDAO,
+	creditBillDAO *dao.CreditBillDAO,
+	creditPaymentRecordDAO *dao.CreditPaymentRecordDAO,
+	creditReconciliationDAO *dao.CreditReconciliationDAO,
+) *MembershipUserDataDeleter {
+	return &MembershipUserDataDeleter{
+		logger:                  logger,
+		tracer:                  tracer,
+		userMembershipDAO:       userMembershipDAO,
+		creditDAO:               creditDAO,
+		creditBillDAO:           creditBillDAO,
+		creditPaymentRecordDAO:  creditPaymentRecordDAO,
+		creditReconciliationDAO: creditReconciliationDAO,
+	}
+}
+
+// DeleteUserData 物理删除用户的会员信息、积分账户、积分流水、积分支付记录和对账结果
+// 会员订单对应真实支付，作为财务凭证保留
+func (d *MembershipUserDataDeleter) DeleteUserData(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, d.tracer, "MembershipUserDataDeleter.DeleteUserData")
+	defer span.Finish()
+
+	removers := []func(context.Context, string) (int64, error){
+		d.creditReconciliationDAO.RemoveByUserId,
+		d.creditPaymentRecordDAO.RemoveByUserId,
+		d.creditBillDAO.RemoveByUserId,
+		d.creditDAO.RemoveByUserId,
+		d.userMembershipDAO.RemoveByUserId,
+	}
+	for _, remove := range removers {
+		if _, err := remove(ctx, userId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/internal/biz"
+	"github.com/yb2020/odoc/pkg/audit"
 	"github.com/yb2020/odoc/pkg/errors"
 	"github.com/yb2020/odoc/pkg/eventbus"
 	"github.com/yb2020/odoc/pkg/idgen"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/yb2020/odoc/services/membership/dao"
 	"github.com/yb2020/odoc/services/membership/dto"
+	msEvent "github.com/yb2020/odoc/services/membership/event"
 	"github.com/yb2020/odoc/services/membership/interfaces"
 	"github.com/yb2020/odoc/services/membership/model"
 	payevent "github.com/yb2020/odoc/services/pay/event"
@@ -25,18 +27,20 @@ type OrderService struct {
 	logger   logging.Logger
 	tracer   opentracing.Tracer
 	orderDAO *dao.OrderDAO
+	eventBus *eventbus.EventBus
 
 	msConfigService       *ConfigService
 	creditService         interfaces.ICreditService
 	userMembershipService interfaces.IUserMembershipService
 }
 
-func NewOrderService(logger logging.Logger, tracer opentracing.Tracer, orderDAO *dao.OrderDAO,
+func NewOrderService(logger logging.Logger, tracer opentracing.Tracer, orderDAO *dao.OrderDAO, eventBus *eventbus.EventBus,
 	membershipConfigService *ConfigService, creditService interfaces.ICreditService, userMembershipService interfaces.IUserMembershipService) *OrderService {
 	return &OrderService{
 		logger:                logger,
 		tracer:                tracer,
 		orderDAO:              orderDAO,
+		eventBus:              eventBus,
 		msConfigService:       membershipConfigService,
 		creditService:         creditService,
 		userMembershipService: userMembershipService,
@@ -404,6 +408,98 @@ func (s *OrderService) DoOrderProcessingHandler(ctx context.Context, orderId str
 	return nil
 }
 
+// DoOrderRefundHandler 订阅订单退款处理，按累计退款金额占支付金额的比例回收积分
+// refundedAmount 为支付记录的累计退款金额，先按条件更新订单退款金额再回收积分，重复或并发的通知不会重复回收；
+// 全额退款时当前生效的PRO会员降级为Free会员，并取消支付渠道的订阅
+func (s *OrderService) DoOrderRefundHandler(ctx context.Context, orderId string, payOrderId string, refundedAmount int64, payAmount int64, reason string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "OrderService.DoOrderRefundHandler")
+	defer span.Finish()
+
+	order, err := s.getOrderByIdOrPayOrderId(ctx, orderId, payOrderId)
+	if err != nil {
+		return err
+	}
+	if order == nil {
+		return errors.Biz("order not found")
+	}
+
+	if order.OrderStatus != int32(pb.OrderStatus_ORDER_STATUS_COMPLETED) && order.OrderStatus != int32(pb.OrderStatus_ORDER_STATUS_PARTIALLY_REFUNDED) {
+		s.logger.Info("msg", "order status can not refund", "orderId", order.Id, "orderStatus", order.OrderStatus)
+		return nil
+	}
+	if payAmount <= 0 || refundedAmount > payAmount {
+		refundedAmount = payAmount
+	}
+	if refundedAmount <= order.RefundedAmount {
+		s.logger.Info("msg", "order refund already handled", "orderId", order.Id, "refundedAmount", refundedAmount)
+		return nil
+	}
+	isFullRefund := refundedAmount >= payAmount
+	refundStatus := int32(pb.OrderStatus_ORDER_STATUS_PARTIALLY_REFUNDED)
+	if isFullRefund {
+		refundStatus = int32(pb.OrderStatus_ORDER_STATUS_REFUNDED)
+	}
+
+	// 1.按退款比例计算回收积分，用累计值之差计算，避免多次部分退款的舍入误差
+	revokeIntent := dto.CreditPayIntent{
+		Type:    msPb.CreditPayType_CREDIT_PAY_TYPE_REFUND_REVOKE,
+		Content: "order refund revoke credit",
+		Remark:  reason,
+	}
+	switch order.OrderType {
+	case int32(pb.OrderType_ORDER_TYPE_SUB_PRO):
+		revokeIntent.CreditType = msPb.CreditType_CREDIT_TYPE_CREDIT
+		revokeIntent.Credit = refundCredit(order.SubCredit, refundedAmount, payAmount) - refundCredit(order.SubCredit, order.RefundedAmount, payAmount)
+	case int32(pb.OrderType_ORDER_TYPE_SUB_PRO_ADD_ON_CREDIT):
+		revokeIntent.CreditType = msPb.CreditType_CREDIT_TYPE_ADD_ON_CREDIT
+		revokeIntent.AddOnCredit = refundCredit(order.SubAddOnCredit, refundedAmount, payAmount) - refundCredit(order.SubAddOnCredit, order.RefundedAmount, payAmount)
+	default:
+		s.logger.Info("msg", "ignore refund for order type", "orderId", order.Id, "orderType", order.OrderType)
+	}
+
+	// 2.先按条件更新订单退款金额和状态，抢占失败说明订单已被并发的通知更新，
+	// 重新读取后按最新的累计金额处理，已处理过的金额不会重复回收
+	applied, err := s.orderDAO.UpdateRefund(ctx, order.Id, order.RefundedAmount, order.OrderStatus, refundedAmount, refundStatus)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		s.logger.Info("msg", "order refund updated concurrently, retry", "orderId", order.Id, "refundedAmount", refundedAmount)
+		return s.DoOrderRefundHandler(ctx, orderId, payOrderId, refundedAmount, payAmount, reason)
+	}
+	before := *order
+	order.RefundedAmount = refundedAmount
+	order.OrderStatus = refundStatus
+
+	// 3.回收积分，失败时恢复订单退款金额，使下一次通知可以重新处理
+	if revokeIntent.Credit > 0 || revokeIntent.AddOnCredit > 0 {
+		if _, err := s.creditService.InOrOutCredit(ctx, order.UserId, order.MembershipId, revokeIntent); err != nil {
+			if _, restoreErr := s.orderDAO.UpdateRefund(ctx, order.Id, refundedAmount, refundStatus, before.RefundedAmount, before.OrderStatus); restoreErr != nil {
+				s.logger.Error("msg", "restore order refund failed", "orderId", order.Id, "error", restoreErr.Error())
+			}
+			return err
+		}
+	}
+
+	// 4.全额退款的PRO订单如果仍在生效，降级为Free会员；积分已回收，降级失败不再恢复订单，避免重复回收
+	if isFullRefund && order.OrderType == int32(pb.OrderType_ORDER_TYPE_SUB_PRO) {
+		if err := s.downgradeRefundedProOrder(ctx, order, reason); err != nil {
+			s.logger.Error("msg", "downgrade refunded pro order failed", "orderId", order.Id, "userId", order.UserId, "error", err.Error())
+			return err
+		}
+	}
+
+	s.logger.Info("msg", "order refunded", "orderId", order.Id, "refundedAmount", refundedAmount, "payAmount", payAmount, "revokeCredit", revokeIntent.Credit, "revokeAddOnCredit", revokeIntent.AddOnCredit)
+	_ = audit.Record(ctx, &audit.Entry{
+		Action:     audit.ActionRefund,
+		EntityType: "order",
+		EntityId:   order.Id,
+		Before:     &before,
+		After:      order,
+	})
+	return nil
+}
+
 // HandlePayNotifyEventHandler 支付子系统的异步通知消息机制的支付结果回调事件
 func (s *OrderService) HandlePayNotifyEventHandler(ctx context.Context, event eventbus.Event) error {
 	span, _ := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "OrderService.HandlePayNotifyEvent")
@@ -439,6 +535,12 @@ func (s *OrderService) HandlePayNotifyEventHandler(ctx context.Context, event ev
 		}
 
 		return s.DoOrderPaySuccessHandler(ctx, payEvent.OrderId, payEvent.PayRecordId, payEvent.SubscriptionId)
+	case payevent.PayNotifyEvent_PayRefunded:
+		return s.DoOrderRefundHandler(ctx, payEvent.OrderId, payEvent.PayRecordId, payEvent.RefundedAmount, payEvent.PayAmount, payEvent.Reason)
+	case payevent.PayNotifyEvent_PayDisputeCreated:
+		// 争议裁决前不回收权益，败诉后会收到退款事件
+		s.logger.Warn("msg", "收到支付争议事件", "orderId", payEvent.OrderId, "payRecordId", payEvent.PayRecordId, "amount", payEvent.RefundAmount, "reason", payEvent.Reason)
+		return nil
 	case payevent.PayNotifyEvent_CustomerSubscriptionsUpdated:
 		// TODO: 订阅更新处理
 		s.logger.Info("msg", "收到订阅更新事件", "event", event)
@@ -493,6 +595,62 @@ func (s *OrderService) updateOrderStatus(ctx context.Context, orderId string, or
 	return s.orderDAO.Modify(ctx, order)
 }
 
+// getOrderByIdOrPayOrderId 根据订单ID获取订单，续费订单没有业务订单ID时按支付记录ID查找
+func (s *OrderService) getOrderByIdOrPayOrderId(ctx context.Context, orderId string, payOrderId string) (*model.Order, error) {
+	if orderId != "" {
+		return s.GetById(ctx, orderId)
+	}
+	if payOrderId == "" {
+		return nil, nil
+	}
+	return s.orderDAO.GetByPayOrderId(ctx, payOrderId)
+}
+
+// downgradeRefundedProOrder 全额退款的PRO订单仍是用户当前生效的订阅时，将用户降级为Free会员并重新发放Free权益，
+// 并通知支付模块立即取消该订单的渠道订阅
+func (s *OrderService) downgradeRefundedProOrder(ctx context.Context, order *model.Order, reason string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "OrderService.downgradeRefundedProOrder")
+	defer span.Finish()
+
+	userMembership, err := s.userMembershipService.GetByUserId(ctx, order.UserId)
+	if err != nil {
+		return err
+	}
+	if userMembership == nil {
+		return errors.Biz("user membership not found")
+	}
+	now := time.Now()
+	if userMembership.Type != int32(msPb.MembershipType_MEMBERSHIP_TYPE_PRO) || !order.SubEndDate.After(now) || userMembership.EndAt.After(order.SubEndDate) {
+		s.logger.Info("msg", "refunded pro order is not active, skip downgrade", "orderId", order.Id, "userId", order.UserId)
+		return nil
+	}
+
+	// 先使PRO会员立即过期，再按新用户流程订阅Free会员，Free订阅会清零剩余积分
+	if err := s.userMembershipService.UpdateAccountType(ctx, order.UserId, int32(msPb.MembershipType_MEMBERSHIP_TYPE_FREE), "", userMembership.StartAt, now); err != nil {
+		return err
+	}
+	freeOrderId, err := s.Subscribe(ctx, order.UserId, pb.OrderType_ORDER_TYPE_SUB_FREE, 1)
+	if err != nil {
+		return err
+	}
+	if err := s.DoOrderPaySuccessHandler(ctx, freeOrderId, "0", ""); err != nil {
+		return err
+	}
+
+	if order.StripeSubscriptionId != "" {
+		s.eventBus.Publish(ctx, eventbus.Event{
+			Type: msEvent.OrderNotifyEvent_ProRefunded,
+			Data: msEvent.OrderNotifyEvent{
+				OrderId:        order.Id,
+				UserId:         order.UserId,
+				SubscriptionId: order.StripeSubscriptionId,
+				Reason:         reason,
+			},
+		}, false)
+	}
+	return nil
+}
+
 // updateOrderStripeSubscriptionId 更新订单的Stripe订阅ID
 func (s *OrderService) updateOrderStripeSubscriptionId(ctx context.Context, orderId string, stripeSubscriptionId string) error {
 	span, _ := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "OrderService.updateOrderStripeSubscriptionId")
@@ -508,3 +666,11 @@ func (s *OrderService) updateOrderStripeSubscriptionId(ctx context.Context, orde
 	order.StripeSubscriptionId = stripeSubscriptionId
 	return s.orderDAO.Modify(ctx, order)
 }
+
+// refundCredit 计算退款金额对应的积分
+func refundCredit(credit int64, refundedAmount int64, payAmount int64) int64 {
+	if payAmount <= 0 || refundedAmount >= payAmount {
+		return credit
+	}
+	return credit * refundedAmount / payAmount
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	"github.com/yb2020/odoc/pkg/eventbus"
+	msPb "github.com/yb2020/odoc/proto/gen/go/membership"
+	pb "github.com/yb2020/odoc/proto/gen/go/order"
+	"github.com/yb2020/odoc/services/membership/dao"
+	"github.com/yb2020/odoc/services/membership/dto"
+	msEvent "github.com/yb2020/odoc/services/membership/event"
+	"github.com/yb2020/odoc/services/membership/interfaces"
+	"github.com/yb2020/odoc/services/membership/model"
+	payDao "github.com/yb2020/odoc/services/pay/dao"
+	payEvent "github.com/yb2020/odoc/services/pay/event"
+	payModel "github.com/yb2020/odoc/services/pay/model"
+	"github.com/yb2020/odoc/services/pay/provider"
+	payService "github.com/yb2020/odoc/services/pay/service"
+)
+
+// memoryCreditService 记录积分变动，只实现订单服务用到的方法
+type memoryCreditService struct {
+	interfaces.ICreditService
+	mu      sync.Mutex
+	intents []dto.CreditPayIntent
+	err     error
+}
+
+func (s *memoryCreditService) InOrOutCredit(ctx context.Context, userId string, membershipId string, payCredit dto.CreditPayIntent) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return "", s.err
+	}
+	s.intents = append(s.intents, payCredit)
+	return "bill", nil
+}
+
+func (s *memoryCreditService) revoked() []dto.CreditPayIntent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var revoked []dto.CreditPayIntent
+	for _, intent := range s.intents {
+		if intent.Type == msPb.CreditPayType_CREDIT_PAY_TYPE_REFUND_REVOKE {
+			revoked = append(revoked, intent)
+		}
+	}
+	return revoked
+}
+
+// memoryUserMembershipService 内存中的用户会员，只实现订单服务用到的方法
+type memoryUserMembershipService struct {
+	interfaces.IUserMembershipService
+	mu         sync.Mutex
+	membership model.UserMembership
+}
+
+func (s *memoryUserMembershipService) GetByUserId(ctx context.Context, userId string) (*model.UserMembership, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	membership := s.membership
+	return &membership, nil
+}
+
+func (s *memoryUserMembershipService) CheckExpired(ctx context.Context, userId string) (bool, int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.membership.EndAt.After(time.Now()), s.membership.Type, nil
+}
+
+func (s *memoryUserMembershipService) UpdateAccountType(ctx context.Context, userId string, memberType int32, stripeSubscriptionId string, startDate time.Time, endDate time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.membership.Type = memberType
+	s.membership.StartAt = startDate
+	s.membership.EndAt = endDate
+	return nil
+}
+
+func (s *memoryUserMembershipService) membershipType() int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.membership.Type
+}
+
+func TestOrderRefund(t *testing.T) {
+	db := daotest.NewDB(t, &model.Order{}, &payModel.PaymentRecord{}, &payModel.PaymentRefund{})
+	logger := daotest.NewLogger()
+	tracer := opentracing.NoopTracer{}
+	ctx := context.Background()
+
+	cfg := &config.Config{}
+	cfg.Membership.Free.Name = "Free"
+	cfg.Membership.Free.Base.SubInfo = config.SubInfo{Type: uint32(pb.OrderType_ORDER_TYPE_SUB_FREE), Credit: 100, Duration: 1}
+
+	credits := &memoryCreditService{}
+	memberships := &memoryUserMembershipService{}
+	handled := make(chan eventbus.Event, 10)
+	canceled := make(chan msEvent.OrderNotifyEvent, 10)
+	bus := eventbus.NewEventBus()
+	orderDAO := dao.NewOrderDAO(db, logger)
+	orders := NewOrderService(logger, tracer, orderDAO, bus, NewConfigService(logger, tracer, cfg), credits, memberships)
+	factory := provider.NewPaymentProviderFactory(logger)
+	mock := factory.RegisterMockProvider("whsec_test")
+	paymentDAO := payDao.NewPaymentRecordDAO(db, logger)
+	payments := payService.NewPaymentService(paymentDAO, payDao.NewPaymentRefundDAO(db, logger), factory, bus, logger)
+
+	// 支付模块的退款和争议事件转发给订单服务处理
+	forward := func(ctx context.Context, event eventbus.Event) {
+		if err := orders.HandlePayNotifyEventHandler(ctx, event); err != nil {
+			t.Errorf("HandlePayNotifyEventHandler(%s): %v", event.Type, err)
+		}
+		handled <- event
+	}
+	bus.Subscribe(payEvent.PayNotifyEvent_PayRefunded, forward)
+	bus.Subscribe(payEvent.PayNotifyEvent_PayDisputeCreated, forward)
+	bus.Subscribe(msEvent.OrderNotifyEvent_ProRefunded, func(ctx context.Context, event eventbus.Event) {
+		canceled <- event.Data.(msEvent.OrderNotifyEvent)
+	})
+
+	webhook := func(t *testing.T, payload []byte, signature string) {
+		t.Helper()
+		if err := payments.HandleWebhook(ctx, payModel.PaymentChannelMock, payload, signature); err != nil {
+			t.Fatalf("HandleWebhook: %v", err)
+		}
+	}
+	// 等待支付模块异步发布的事件被订单服务处理完
+	waitEvent := func(t *testing.T, eventType eventbus.EventType) eventbus.Event {
+		t.Helper()
+		select {
+		case event := <-handled:
+			if event.Type != eventType {
+				t.Fatalf("event = %s, want %s", event.Type, eventType)
+			}
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", eventType)
+			return eventbus.Event{}
+		}
+	}
+	assertOrder := func(t *testing.T, orderId string, status pb.OrderStatus, refundedAmount int64) {
+		t.Helper()
+		order, err := orderDAO.FindExistById(ctx, orderId)
+		if err != nil || order == nil {
+			t.Fatalf("find order = %+v, err = %v", order, err)
+		}
+		if order.OrderStatus != int32(status) || order.RefundedAmount != refundedAmount {
+			t.Fatalf("order status = %d, refundedAmount = %d, want %d, %d", order.OrderStatus, order.RefundedAmount, status, refundedAmount)
+		}
+	}
+	// 创建一个已完成的PRO订阅订单及其模拟支付记录，积分和会员状态从头开始
+	placeOrder := func(t *testing.T, orderId string) (string, string) {
+		t.Helper()
+		subEnd := time.Now().AddDate(0, 1, 0)
+		credits.intents, credits.err = nil, nil
+		memberships.membership = model.UserMembership{
+			Type:    int32(msPb.MembershipType_MEMBERSHIP_TYPE_PRO),
+			StartAt: time.Now(),
+			EndAt:   subEnd,
+		}
+		memberships.membership.Id = "ms1"
+		for len(canceled) > 0 {
+			<-canceled
+		}
+
+		order := &model.Order{
+			UserId:               "u1",
+			MembershipId:         "ms1",
+			OrderStatus:          int32(pb.OrderStatus_ORDER_STATUS_COMPLETED),
+			OrderType:            int32(pb.OrderType_ORDER_TYPE_SUB_PRO),
+			SubCredit:            1000,
+			SubStartDate:         time.Now(),
+			SubEndDate:           subEnd,
+			PayAmount:            1000,
+			Currency:             "usd",
+			StripeSubscriptionId: "sub_pro",
+		}
+		order.Id = orderId
+		if err := orderDAO.Save(ctx, order); err != nil {
+			t.Fatalf("save order: %v", err)
+		}
+		payment, err := payments.CreatePayment(ctx, &payService.CreatePaymentParams{
+			UserId:          "u1",
+			OrderId:         orderId,
+			Amount:          1000,
+			Currency:        "usd",
+			Channel:         payModel.PaymentChannelMock,
+			PaymentMethodId: provider.MockPaymentMethodCard,
+		})
+		if err != nil {
+			t.Fatalf("CreatePayment: %v", err)
+		}
+		payload, signature, err := mock.CompleteCharge(payment.ProviderTxId)
+		if err != nil {
+			t.Fatalf("CompleteCharge: %v", err)
+		}
+		webhook(t, payload, signature)
+		return payment.PaymentId, payment.ProviderTxId
+	}
+
+	t.Run("lifecycle", func(t *testing.T) {
+		orderId := "order-lifecycle"
+		paymentId, chargeId := placeOrder(t, orderId)
+
+		// 部分退款按比例回收积分
+		if _, err := payments.CreateRefund(ctx, paymentId, 300, "partial"); err != nil {
+			t.Fatalf("CreateRefund: %v", err)
+		}
+		refunded := waitEvent(t, payEvent.PayNotifyEvent_PayRefunded)
+		assertOrder(t, orderId, pb.OrderStatus_ORDER_STATUS_PARTIALLY_REFUNDED, 300)
+		if revoked := credits.revoked(); len(revoked) != 1 || revoked[0].Credit != 300 {
+			t.Fatalf("revoked = %+v, want one revoke of 300", revoked)
+		}
+
+		// 渠道重复推送退款 Webhook，以及同一退款事件被并发重放，都不会再次回收
+		payload, signature, err := mock.RefundedEvent(chargeId)
+		if err != nil {
+			t.Fatalf("RefundedEvent: %v", err)
+		}
+		webhook(t, payload, signature)
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := orders.HandlePayNotifyEventHandler(ctx, refunded); err != nil {
+					t.Errorf("replay refund: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		assertOrder(t, orderId, pb.OrderStatus_ORDER_STATUS_PARTIALLY_REFUNDED, 300)
+		if revoked := credits.revoked(); len(revoked) != 1 {
+			t.Fatalf("revoked = %+v, want no second revoke", revoked)
+		}
+
+		// 争议发起和胜诉都不回收权益
+		disputeId, payload, signature, err := mock.OpenDispute(chargeId, "fraudulent")
+		if err != nil {
+			t.Fatalf("OpenDispute: %v", err)
+		}
+		webhook(t, payload, signature)
+		waitEvent(t, payEvent.PayNotifyEvent_PayDisputeCreated)
+		payload, signature, err = mock.CloseDispute(disputeId, true)
+		if err != nil {
+			t.Fatalf("CloseDispute: %v", err)
+		}
+		webhook(t, payload, signature)
+		assertOrder(t, orderId, pb.OrderStatus_ORDER_STATUS_PARTIALLY_REFUNDED, 300)
+		if revoked := credits.revoked(); len(revoked) != 1 {
+			t.Fatalf("revoked = %+v, want no revoke for dispute", revoked)
+		}
+
+		// 退还剩余金额，回收剩余积分，降级为Free会员并取消渠道订阅
+		if _, err := payments.CreateRefund(ctx, paymentId, 0, "full"); err != nil {
+			t.Fatalf("CreateRefund: %v", err)
+		}
+		waitEvent(t, payEvent.PayNotifyEvent_PayRefunded)
+		assertOrder(t, orderId, pb.OrderStatus_ORDER_STATUS_REFUNDED, 1000)
+		if revoked := credits.revoked(); len(revoked) != 2 || revoked[1].Credit != 700 {
+			t.Fatalf("revoked = %+v, want second revoke of 700", revoked)
+		}
+		if got := memberships.membershipType(); got != int32(msPb.MembershipType_MEMBERSHIP_TYPE_FREE) {
+			t.Fatalf("membership type = %d, want free", got)
+		}
+		select {
+		case canceled := <-canceled:
+			if canceled.OrderId != orderId || canceled.SubscriptionId != "sub_pro" {
+				t.Fatalf("canceled = %+v", canceled)
+			}
+		default:
+			t.Fatalf("subscription cancel event not published")
+		}
+	})
+
+	t.Run("concurrent partial refunds", func(t *testing.T) {
+		orderId := "order-partial"
+		paymentId, chargeId := placeOrder(t, orderId)
+
+		// 两笔部分退款并发提交，累计金额不会丢失，也不会被渠道通知重复计入
+		amounts := []int64{300, 400}
+		var wg sync.WaitGroup
+		for _, amount := range amounts {
+			wg.Add(1)
+			go func(amount int64) {
+				defer wg.Done()
+				if _, err := payments.CreateRefund(ctx, paymentId, amount, "partial"); err != nil {
+					t.Errorf("CreateRefund(%d): %v", amount, err)
+				}
+			}(amount)
+		}
+		wg.Wait()
+		waitEvent(t, payEvent.PayNotifyEvent_PayRefunded)
+		waitEvent(t, payEvent.PayNotifyEvent_PayRefunded)
+		payload, signature, err := mock.RefundedEvent(chargeId)
+		if err != nil {
+			t.Fatalf("RefundedEvent: %v", err)
+		}
+		webhook(t, payload, signature)
+
+		payment, err := paymentDAO.FindById(ctx, paymentId)
+		if err != nil || payment.RefundedAmount != 700 || payment.Status != payModel.PaymentStatusPartialRefund {
+			t.Fatalf("payment = %+v, err = %v, want 700 partially refunded", payment, err)
+		}
+		assertOrder(t, orderId, pb.OrderStatus_ORDER_STATUS_PARTIALLY_REFUNDED, 700)
+		var revoked int64
+		for _, intent := range credits.revoked() {
+			revoked += intent.Credit
+		}
+		if revoked != 700 {
+			t.Fatalf("revoked credit = %d, want 700", revoked)
+		}
+
+		// 超出剩余可退金额的退款被拒绝
+		if _, err := payments.CreateRefund(ctx, paymentId, 400, "too much"); err == nil {
+			t.Fatalf("CreateRefund over the remaining amount succeeded")
+		}
+	})
+
+	t.Run("dispute lost", func(t *testing.T) {
+		orderId := "order-dispute"
+		_, chargeId := placeOrder(t, orderId)
+
+		disputeId, payload, signature, err := mock.OpenDispute(chargeId, "fraudulent")
+		if err != nil {
+			t.Fatalf("OpenDispute: %v", err)
+		}
+		webhook(t, payload, signature)
+		waitEvent(t, payEvent.PayNotifyEvent_PayDisputeCreated)
+		if revoked := credits.revoked(); len(revoked) != 0 {
+			t.Fatalf("revoked = %+v, want none before dispute closed", revoked)
+		}
+
+		// 败诉按全额退款处理
+		payload, signature, err = mock.CloseDispute(disputeId, false)
+		if err != nil {
+			t.Fatalf("CloseDispute: %v", err)
+		}
+		webhook(t, payload, signature)
+		waitEvent(t, payEvent.PayNotifyEvent_PayRefunded)
+		assertOrder(t, orderId, pb.OrderStatus_ORDER_STATUS_REFUNDED, 1000)
+		if revoked := credits.revoked(); len(revoked) != 1 || revoked[0].Credit != 1000 {
+			t.Fatalf("revoked = %+v, want one revoke of 1000", revoked)
+		}
+		if got := memberships.membershipType(); got != int32(msPb.MembershipType_MEMBERSHIP_TYPE_FREE) {
+			t.Fatalf("membership type = %d, want free", got)
+		}
+	})
+
+	t.Run("revoke failure restores order", func(t *testing.T) {
+		orderId := "order-revoke"
+		placeOrder(t, orderId)
+		credits.err = stderrors.New("credit unavailable")
+
+		err := orders.DoOrderRefundHandler(ctx, orderId, "", 400, 1000, "partial")
+		if err == nil {
+			t.Fatalf("DoOrderRefundHandler err = nil, want credit error")
+		}
+		assertOrder(t, orderId, pb.OrderStatus_ORDER_STATUS_COMPLETED, 0)
+
+		// 恢复后重新通知可以正常回收
+		credits.err = nil
+		if err := orders.DoOrderRefundHandler(ctx, orderId, "", 400, 1000, "partial"); err != nil {
+			t.Fatalf("DoOrderRefundHandler: %v", err)
+		}
+		assertOrder(t, orderId, pb.OrderStatus_ORDER_STATUS_PARTIALLY_REFUNDED, 400)
+		if revoked := credits.revoked(); len(revoked) != 1 || revoked[0].Credit != 400 {
+			t.Fatalf("revoked = %+v, want one revoke of 400", revoked)
+		}
+	})
+
+	t.Run("concurrent duplicate", func(t *testing.T) {
+		orderId := "order-duplicate"
+		placeOrder(t, orderId)
+
+		// 同一退款通知并发投递，只有一个能抢占订单并回收积分
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := orders.DoOrderRefundHandler(ctx, orderId, "", 1000, 1000, "duplicate"); err != nil {
+					t.Errorf("DoOrderRefundHandler: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		assertOrder(t, orderId, pb.OrderStatus_ORDER_STATUS_REFUNDED, 1000)
+		if revoked := credits.revoked(); len(revoked) != 1 || revoked[0].Credit != 1000 {
+			t.Fatalf("revoked = %+v, want one revoke of 1000", revoked)
+		}
+		if len(canceled) != 1 {
+			t.Fatalf("cancel events = %d, want 1", len(canceled))
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/yb2020/odoc/pkg/middleware"
+	pb "github.com/yb2020/odoc/proto/gen/go/membership"
+	"github.com/yb2020/odoc/services/membership/interfaces"
+)
+
+// 确保适配器可以为接口限流提供会员类型
+var _ middleware.MembershipTierResolver = (*RateLimitTierAdapter)(nil)
+
+// RateLimitTierAdapter 适配用户会员服务到接口限流的会员类型查询
+type RateLimitTierAdapter struct {
+	userMembershipService interfaces.IUserMembershipService
+}
+
+// NewRateLimitTierAdapter 创建接口限流会员类型适配器
+func NewRateLimitTierAdapter(userMembershipService interfaces.IUserMembershipService) *RateLimitTierAdapter {
+	return &RateLimitTierAdapter{
+		userMembershipService: userMembershipService,
+	}
+}
+
+// GetMembershipTier 返回用户当前生效的会员类型，如 free、pro
+// 付费会员过期后按免费会员计算额度，没有会员账户时返回空字符串
+func (a *RateLimitTierAdapter) GetMembershipTier(ctx context.Context, userId string) (string, error) {
+	userMembership, err := a.userMembershipService.GetByUserId(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	if userMembership == nil {
+		return "", nil
+	}
+
+	membershipType := pb.MembershipType(userMembership.Type)
+	if membershipType != pb.MembershipType_MEMBERSHIP_TYPE_FREE && userMembership.EndAt.Before(time.Now()) {
+		membershipType = pb.MembershipType_MEMBERSHIP_TYPE_FREE
+	}
+	return strings.ToLower(strings.TrimPrefix(membershipType.String(), "MEMBERSHIP_TYPE_")), nil
+}
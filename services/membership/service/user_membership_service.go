@@ -7,6 +7,7 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/config"
 	"github.com/yb2020/odoc/internal/biz"
+	"github.com/yb2020/odoc/pkg/audit"
 	userContext "github.com/yb2020/odoc/pkg/context"
 	baseDao "github.com/yb2020/odoc/pkg/dao"
 	"github.com/yb2020/odoc/pkg/errors"
@@ -313,11 +314,22 @@ func (s *UserMembershipService) UpdateAccountType(ctx context.Context, userId st
 		return errors.Biz("user membership not found")
 	}
 
+	before := *userMembership
 	userMembership.Type = memberType
 	userMembership.StripeSubscriptionId = stripeSubscriptionId
 	userMembership.StartAt = startDate
 	userMembership.EndAt = endDate
-	return s.userMembershipDAO.Modify(ctx, userMembership)
+	if err := s.userMembershipDAO.Modify(ctx, userMembership); err != nil {
+		return err
+	}
+	_ = audit.Record(ctx, &audit.Entry{
+		Action:     audit.ActionUpdate,
+		EntityType: "membership",
+		EntityId:   userMembership.Id,
+		Before:     &before,
+		After:      userMembership,
+	})
+	return nil
 }
 
 // GetUserConfig 获取用户会员配置信息
@@ -1,6 +1,8 @@
 package nav
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/pkg/scheduler"
@@ -22,6 +24,9 @@ import (
 // 编译时类型检查：确保 WebsiteModule 实现了 registry.Module 接口
 var _ registry.Module = (*NavModule)(nil)
 
+// 编译时类型检查：确保 NavModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*NavModule)(nil)
+
 // Module 导出模块实例，用于自动发现和注册
 var Module = &NavModule{}
 
@@ -115,3 +120,8 @@ func (m *NavModule) RegisterRoutes(r *gin.Engine) {
 		websiteGroup.POST("/website/reorder", m.websiteAPI.ReorderWebsites)
 	}
 }
+
+// DeleteUserData 注销账号时删除用户收藏的网址
+func (m *NavModule) DeleteUserData(ctx context.Context, userId string) error {
+	return m.websiteService.DeleteUserData(ctx, userId)
+}
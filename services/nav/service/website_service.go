@@ -103,6 +103,15 @@ func (s *WebsiteService) DeleteWebsite(ctx context.Context, id string) error {
 	return s.websiteDAO.DeleteById(ctx, id)
 }
 
+// DeleteUserData 物理删除用户收藏的全部网址，注销账号时调用
+func (s *WebsiteService) DeleteUserData(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "WebsiteService.DeleteUserData")
+	defer span.Finish()
+
+	_, err := s.websiteDAO.RemoveByUserId(ctx, userId)
+	return err
+}
+
 func (s *WebsiteService) GetById(ctx context.Context, id string) (*model.Website, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "WebsiteService.GetById")
 	defer span.Finish()
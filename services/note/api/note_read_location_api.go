@@ -9,15 +9,17 @@ import (
 	"github.com/yb2020/odoc/pkg/response"
 	"github.com/yb2020/odoc/pkg/transport"
 	pb "github.com/yb2020/odoc/proto/gen/go/note"
+	"github.com/yb2020/odoc/services/note/interfaces"
 	"github.com/yb2020/odoc/services/note/model"
 	"github.com/yb2020/odoc/services/note/service"
 )
 
 // NoteReadLocationAPI 笔记阅读位置API处理器
 type NoteReadLocationAPI struct {
-	service *service.NoteReadLocationService
-	logger  logging.Logger
-	tracer  opentracing.Tracer
+	service                  *service.NoteReadLocationService
+	readingHeartbeatRecorder interfaces.IReadingHeartbeatRecorder
+	logger                   logging.Logger
+	tracer                   opentracing.Tracer
 }
 
 // NewNoteReadLocationAPI 创建笔记阅读位置API处理器
@@ -29,6 +31,11 @@ func NewNoteReadLocationAPI(service *service.NoteReadLocationService, logger log
 	}
 }
 
+// SetReadingHeartbeatRecorder 设置阅读心跳记录器，阅读位置上报同时作为阅读心跳
+func (api *NoteReadLocationAPI) SetReadingHeartbeatRecorder(recorder interfaces.IReadingHeartbeatRecorder) {
+	api.readingHeartbeatRecorder = recorder
+}
+
 // @old_api_path /noteReadLocation/getLocation
 // @api_path /api/note/noteReadLocation/getLocation
 // @api_method POST
@@ -103,5 +110,12 @@ func (api *NoteReadLocationAPI) RecordNoteReadLocation(c *gin.Context) {
 		api.service.CreateNoteReadLocation(c.Request.Context(), record)
 	}
 
+	// 阅读统计心跳失败不影响阅读位置记录
+	if api.readingHeartbeatRecorder != nil {
+		if err := api.readingHeartbeatRecorder.RecordHeartbeat(c.Request.Context(), userId, req.NoteId); err != nil {
+			api.logger.Warn("msg", "记录阅读心跳失败", "noteId", req.NoteId, "error", err.Error())
+		}
+	}
+
 	response.SuccessNoData(c, "success")
 }
@@ -57,6 +57,17 @@ func (d *NoteReadLocationDAO) GetByNoteID(ctx context.Context, noteId string) (*
 	return &location, nil
 }
 
+// GetByNoteIds 根据笔记ID列表获取笔记阅读位置列表
+func (d *NoteReadLocationDAO) GetByNoteIds(ctx context.Context, noteIds []string) ([]model.NoteReadLocation, error) {
+	var locations []model.NoteReadLocation
+	result := d.GetDB(ctx).Where("note_id IN ? AND is_deleted = false", noteIds).Find(&locations)
+	if result.Error != nil {
+		d.logger.Error("msg", "根据笔记ID列表获取笔记阅读位置失败", "noteIds", noteIds, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return locations, nil
+}
+
 // UpdateById 更新笔记阅读位置
 func (d *NoteReadLocationDAO) UpdateById(ctx context.Context, location *model.NoteReadLocation) error {
 	return d.GetDB(ctx).Save(location).Error
@@ -68,6 +68,17 @@ func (d *NoteShapeDAO) GetByNoteID(ctx context.Context, noteID string) ([]model.
 	return shapes, nil
 }
 
+// GetByNoteIds 根据笔记ID列表获取笔记形状列表
+func (d *NoteShapeDAO) GetByNoteIds(ctx context.Context, noteIds []string) ([]model.NoteShape, error) {
+	var shapes []model.NoteShape
+	result := d.GetDB(ctx).Where("note_id IN ? AND is_deleted = false", noteIds).Order("note_id, page_number").Find(&shapes)
+	if result.Error != nil {
+		d.logger.Error("msg", "根据笔记ID列表获取笔记形状列表失败", "noteIds", noteIds, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return shapes, nil
+}
+
 // GetByNoteIDAndPage 根据笔记ID和页码获取笔记形状列表
 func (d *NoteShapeDAO) GetByNoteIDAndPage(ctx context.Context, noteID string, pageNumber int) ([]model.NoteShape, error) {
 	var shapes []model.NoteShape
@@ -0,0 +1,225 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/logging"
+	pb "github.com/yb2020/odoc/proto/gen/go/note"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	"github.com/yb2020/odoc/services/note/bean"
+	noteInterface "github.com/yb2020/odoc/services/note/interfaces"
+	"github.com/yb2020/odoc/services/note/model"
+	"github.com/yb2020/odoc/services/note/service"
+)
+
+// NoteGRPCServer 论文笔记的gRPC服务器，与 PaperNoteAPI、NoteSummaryAPI、NoteWordAPI 调用相同的服务层方法
+type NoteGRPCServer struct {
+	pb.UnimplementedNoteServiceServer
+	paperNoteService   noteInterface.IPaperNoteService
+	noteSummaryService *service.NoteSummaryService
+	noteWordService    noteInterface.INoteWordService
+	userDocService     *docService.UserDocService
+	logger             logging.Logger
+	tracer             opentracing.Tracer
+}
+
+// NewNoteGRPCServer 创建论文笔记gRPC服务器
+func NewNoteGRPCServer(logger logging.Logger, tracer opentracing.Tracer, paperNoteService noteInterface.IPaperNoteService,
+	noteSummaryService *service.NoteSummaryService, noteWordService noteInterface.INoteWordService,
+	userDocService *docService.UserDocService) *NoteGRPCServer {
+	return &NoteGRPCServer{
+		paperNoteService:   paperNoteService,
+		noteSummaryService: noteSummaryService,
+		noteWordService:    noteWordService,
+		userDocService:     userDocService,
+		logger:             logger,
+		tracer:             tracer,
+	}
+}
+
+// RegisterServer 注册gRPC服务
+func (s *NoteGRPCServer) RegisterServer(server *grpc.Server) {
+	pb.RegisterNoteServiceServer(server, s)
+}
+
+// checkNoteOwner 检查笔记属于当前用户，返回当前用户ID
+func (s *NoteGRPCServer) checkNoteOwner(ctx context.Context, noteId string) (string, error) {
+	userId, _ := userContext.GetUserID(ctx)
+	paperNote, err := s.paperNoteService.GetPaperNoteById(ctx, noteId)
+	if err != nil {
+		return "", err
+	}
+	if userId == "" || paperNote == nil || paperNote.CreatorId != userId {
+		return "", status.Error(codes.PermissionDenied, "笔记不存在或者不属于您本人")
+	}
+	return userId, nil
+}
+
+// checkWordOwner 检查生词属于当前用户
+func (s *NoteGRPCServer) checkWordOwner(ctx context.Context, wordId string) error {
+	userId, _ := userContext.GetUserID(ctx)
+	noteWord, err := s.noteWordService.GetNoteWordById(ctx, wordId)
+	if err != nil {
+		return err
+	}
+	if userId == "" || noteWord == nil || noteWord.UserId != userId {
+		return status.Error(codes.PermissionDenied, "生词不存在或者不属于您本人")
+	}
+	return nil
+}
+
+// GetPaperNoteBaseInfo 获取论文笔记基础信息，只能获取自己的笔记
+func (s *NoteGRPCServer) GetPaperNoteBaseInfo(ctx context.Context, req *pb.GetPaperNoteBaseInfoByIdReq) (*pb.PaperNoteBaseInfoResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "NoteGRPCServer.GetPaperNoteBaseInfo")
+	defer span.Finish()
+
+	if _, err := s.checkNoteOwner(ctx, req.NoteId); err != nil {
+		return nil, err
+	}
+	resp, err := s.paperNoteService.GetPaperNoteBaseInfoById(ctx, req.NoteId)
+	if err != nil {
+		s.logger.Error("msg", "gRPC获取论文笔记基础信息失败", "noteId", req.NoteId, "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetSummary 获取笔记总结，只能获取自己的笔记
+func (s *NoteGRPCServer) GetSummary(ctx context.Context, req *pb.GetNoteSummaryByNoteIdRequest) (*pb.GetNoteSummaryByNoteIdResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "NoteGRPCServer.GetSummary")
+	defer span.Finish()
+
+	if _, err := s.checkNoteOwner(ctx, req.NoteId); err != nil {
+		return nil, err
+	}
+	userDoc, err := s.userDocService.GetUserDocByNoteId(ctx, req.NoteId)
+	if err != nil {
+		s.logger.Error("msg", "gRPC获取用户文档失败", "noteId", req.NoteId, "error", err.Error())
+		return nil, err
+	}
+	noteSummary, err := s.noteSummaryService.GetNoteSummaryByNoteId(ctx, req.NoteId)
+	if err != nil {
+		s.logger.Error("msg", "gRPC获取笔记总结失败", "noteId", req.NoteId, "error", err.Error())
+		return nil, err
+	}
+
+	resp := &pb.GetNoteSummaryByNoteIdResponse{}
+	if userDoc != nil {
+		resp.DocName = userDoc.DocName
+	}
+	if noteSummary != nil {
+		resp.Content = noteSummary.Content
+		resp.ModifyDate = uint64(noteSummary.UpdatedAt.UnixMilli())
+	}
+	return resp, nil
+}
+
+// SaveOrUpdateSummary 添加或更新笔记总结，只能修改自己的笔记
+func (s *NoteGRPCServer) SaveOrUpdateSummary(ctx context.Context, req *pb.SaveOrUpdateSummaryReq) (*emptypb.Empty, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "NoteGRPCServer.SaveOrUpdateSummary")
+	defer span.Finish()
+
+	if req.Content == "" {
+		return nil, status.Error(codes.InvalidArgument, "笔记摘要不能为空")
+	}
+	userId, err := s.checkNoteOwner(ctx, req.NoteId)
+	if err != nil {
+		return nil, err
+	}
+
+	noteSummary, err := s.noteSummaryService.GetNoteSummaryByNoteId(ctx, req.NoteId)
+	if err != nil {
+		return nil, err
+	}
+	if noteSummary == nil {
+		_, err = s.noteSummaryService.CreateNoteSummary(ctx, &model.NoteSummary{
+			NoteId:  req.NoteId,
+			Content: req.Content,
+			UserId:  userId,
+		})
+	} else {
+		noteSummary.Content = req.Content
+		noteSummary.UserId = userId
+		_, err = s.noteSummaryService.UpdateNoteSummary(ctx, noteSummary)
+	}
+	if err != nil {
+		s.logger.Error("msg", "gRPC保存笔记总结失败", "noteId", req.NoteId, "error", err.Error())
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// GetWords 获取笔记生词列表，只能获取自己的笔记
+func (s *NoteGRPCServer) GetWords(ctx context.Context, req *pb.GetNoteWordsByNoteIdRequest) (*pb.GetNoteWordsByNoteIdResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "NoteGRPCServer.GetWords")
+	defer span.Finish()
+
+	if _, err := s.checkNoteOwner(ctx, req.NoteId); err != nil {
+		return nil, err
+	}
+	resp, err := s.noteWordService.GetNoteWordsByNoteQuery(ctx, &bean.NoteWordQuery{
+		NoteId:      req.NoteId,
+		CurrentPage: int(req.CurrentPage),
+		PageSize:    int(req.PageSize),
+		MinLoadedId: req.MinLoadedId,
+	})
+	if err != nil {
+		s.logger.Error("msg", "gRPC获取笔记生词失败", "noteId", req.NoteId, "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SaveWord 保存笔记生词，只能添加到自己的笔记
+func (s *NoteGRPCServer) SaveWord(ctx context.Context, req *pb.SaveNoteWordRequest) (*pb.SaveNoteWordResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "NoteGRPCServer.SaveWord")
+	defer span.Finish()
+
+	if _, err := s.checkNoteOwner(ctx, req.NoteId); err != nil {
+		return nil, err
+	}
+	id, err := s.noteWordService.CreateNoteWord(ctx, req)
+	if err != nil {
+		s.logger.Error("msg", "gRPC保存笔记生词失败", "error", err.Error())
+		return nil, err
+	}
+	return &pb.SaveNoteWordResponse{Id: id}, nil
+}
+
+// UpdateWord 更新笔记生词，只能修改自己的生词
+func (s *NoteGRPCServer) UpdateWord(ctx context.Context, req *pb.UpdateNoteWordRequest) (*pb.UpdateNoteWordResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "NoteGRPCServer.UpdateWord")
+	defer span.Finish()
+
+	if err := s.checkWordOwner(ctx, req.WordId); err != nil {
+		return nil, err
+	}
+	success, err := s.noteWordService.UpdateNoteWordTargetContent(ctx, req)
+	if err != nil {
+		s.logger.Error("msg", "gRPC更新笔记生词失败", "error", err.Error())
+		return nil, err
+	}
+	return &pb.UpdateNoteWordResponse{Success: success}, nil
+}
+
+// DeleteWord 删除笔记生词，只能删除自己的生词
+func (s *NoteGRPCServer) DeleteWord(ctx context.Context, req *pb.DeleteNoteWordRequest) (*emptypb.Empty, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "NoteGRPCServer.DeleteWord")
+	defer span.Finish()
+
+	if err := s.checkWordOwner(ctx, req.WordId); err != nil {
+		return nil, err
+	}
+	if _, err := s.noteWordService.DeleteNoteWordById(ctx, req.WordId); err != nil {
+		s.logger.Error("msg", "gRPC删除笔记生词失败", "wordId", req.WordId, "error", err.Error())
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
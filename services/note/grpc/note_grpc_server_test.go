@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	pb "github.com/yb2020/odoc/proto/gen/go/note"
+	"github.com/yb2020/odoc/services/note/bean"
+	noteInterface "github.com/yb2020/odoc/services/note/interfaces"
+	"github.com/yb2020/odoc/services/note/model"
+)
+
+// fakePaperNoteService 笔记 n1 属于 u1
+type fakePaperNoteService struct {
+	noteInterface.IPaperNoteService
+}
+
+func (fakePaperNoteService) GetPaperNoteById(ctx context.Context, id string) (*model.PaperNote, error) {
+	if id != "n1" {
+		return nil, nil
+	}
+	note := &model.PaperNote{}
+	note.Id = id
+	note.CreatorId = "u1"
+	return note, nil
+}
+
+func (fakePaperNoteService) GetPaperNoteBaseInfoById(ctx context.Context, noteId string) (*pb.PaperNoteBaseInfoResponse, error) {
+	return &pb.PaperNoteBaseInfoResponse{}, nil
+}
+
+// fakeNoteWordService 生词 w1 属于 u1，记录被调用的写操作
+type fakeNoteWordService struct {
+	noteInterface.INoteWordService
+	called []string
+}
+
+func (s *fakeNoteWordService) GetNoteWordById(ctx context.Context, id string) (*model.NoteWord, error) {
+	if id != "w1" {
+		return nil, nil
+	}
+	word := &model.NoteWord{NoteId: "n1", UserId: "u1"}
+	word.Id = id
+	return word, nil
+}
+
+func (s *fakeNoteWordService) GetNoteWordsByNoteQuery(ctx context.Context, query *bean.NoteWordQuery) (*pb.GetNoteWordsByNoteIdResponse, error) {
+	s.called = append(s.called, "GetNoteWordsByNoteQuery")
+	return &pb.GetNoteWordsByNoteIdResponse{}, nil
+}
+
+func (s *fakeNoteWordService) CreateNoteWord(ctx context.Context, req *pb.SaveNoteWordRequest) (string, error) {
+	s.called = append(s.called, "CreateNoteWord")
+	return "w2", nil
+}
+
+func (s *fakeNoteWordService) UpdateNoteWordTargetContent(ctx context.Context, req *pb.UpdateNoteWordRequest) (bool, error) {
+	s.called = append(s.called, "UpdateNoteWordTargetContent")
+	return true, nil
+}
+
+func (s *fakeNoteWordService) DeleteNoteWordById(ctx context.Context, id string) (bool, error) {
+	s.called = append(s.called, "DeleteNoteWordById")
+	return true, nil
+}
+
+func TestNoteGRPCServerChecksOwner(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(s *NoteGRPCServer, ctx context.Context) error
+		// 所有者调用依赖未替换的服务时只验证拒绝
+		skipOwner bool
+	}{
+		{name: "GetPaperNoteBaseInfo", call: func(s *NoteGRPCServer, ctx context.Context) error {
+			_, err := s.GetPaperNoteBaseInfo(ctx, &pb.GetPaperNoteBaseInfoByIdReq{NoteId: "n1"})
+			return err
+		}},
+		{name: "GetSummary", skipOwner: true, call: func(s *NoteGRPCServer, ctx context.Context) error {
+			_, err := s.GetSummary(ctx, &pb.GetNoteSummaryByNoteIdRequest{NoteId: "n1"})
+			return err
+		}},
+		{name: "GetWords", call: func(s *NoteGRPCServer, ctx context.Context) error {
+			_, err := s.GetWords(ctx, &pb.GetNoteWordsByNoteIdRequest{NoteId: "n1"})
+			return err
+		}},
+		{name: "SaveWord", call: func(s *NoteGRPCServer, ctx context.Context) error {
+			_, err := s.SaveWord(ctx, &pb.SaveNoteWordRequest{NoteId: "n1"})
+			return err
+		}},
+		{name: "UpdateWord", call: func(s *NoteGRPCServer, ctx context.Context) error {
+			_, err := s.UpdateWord(ctx, &pb.UpdateNoteWordRequest{WordId: "w1"})
+			return err
+		}},
+		{name: "DeleteWord", call: func(s *NoteGRPCServer, ctx context.Context) error {
+			_, err := s.DeleteWord(ctx, &pb.DeleteNoteWordRequest{WordId: "w1"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, userId := range []string{"u2", ""} {
+				words := &fakeNoteWordService{}
+				s := NewNoteGRPCServer(daotest.NewLogger(), opentracing.NoopTracer{}, fakePaperNoteService{}, nil, words, nil)
+				ctx := context.WithValue(context.Background(), userContext.UserIDKey, userId)
+				if err := tt.call(s, ctx); status.Code(err) != codes.PermissionDenied {
+					t.Fatalf("user %q: err = %v, want PermissionDenied", userId, err)
+				}
+				if len(words.called) != 0 {
+					t.Fatalf("user %q: called %v before the owner check", userId, words.called)
+				}
+			}
+			if tt.skipOwner {
+				return
+			}
+			s := NewNoteGRPCServer(daotest.NewLogger(), opentracing.NoopTracer{}, fakePaperNoteService{}, nil, &fakeNoteWordService{}, nil)
+			ctx := context.WithValue(context.Background(), userContext.UserIDKey, "u1")
+			if err := tt.call(s, ctx); err != nil {
+				t.Fatalf("owner: err = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+)
+
+// IReadingHeartbeatRecorder 阅读心跳记录接口，由阅读统计模块实现并注入，避免笔记模块反向依赖
+type IReadingHeartbeatRecorder interface {
+	// RecordHeartbeat 记录用户在笔记上的一次阅读心跳
+	RecordHeartbeat(ctx context.Context, userId string, noteId string) error
+}
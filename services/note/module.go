@@ -1,11 +1,14 @@
 package note
 
 import (
+	"context"
 	"errors"
 
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/pkg/takeout"
+	"github.com/yb2020/odoc/pkg/trash"
 	"google.golang.org/grpc"
 	"gorm.io/gorm"
 
@@ -16,6 +19,7 @@ import (
 	userDocService "github.com/yb2020/odoc/services/doc/service"
 	"github.com/yb2020/odoc/services/note/api"
 	"github.com/yb2020/odoc/services/note/dao"
+	notegrpc "github.com/yb2020/odoc/services/note/grpc"
 	noteInterface "github.com/yb2020/odoc/services/note/interfaces"
 	"github.com/yb2020/odoc/services/note/service"
 	paperService "github.com/yb2020/odoc/services/paper/service"
@@ -26,6 +30,15 @@ import (
 // 编译时类型检查：确保 NoteModule 实现了 registry.Module 接口
 var _ registry.Module = (*NoteModule)(nil)
 
+// 编译时类型检查：确保 NoteModule 实现了 registry.TakeoutExporterProvider 接口
+var _ registry.TakeoutExporterProvider = (*NoteModule)(nil)
+
+// 编译时类型检查：确保 NoteModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*NoteModule)(nil)
+
+// 编译时类型检查：确保 NoteModule 实现了 registry.TrashHandlerProvider 接口
+var _ registry.TrashHandlerProvider = (*NoteModule)(nil)
+
 // Module 导出模块实例，用于自动发现和注册
 var Module = &NoteModule{}
 
@@ -50,6 +63,10 @@ type NoteModule struct {
 	pdfService             pdfInterface.IPaperPdfService
 	paperService           *paperService.PaperService
 	noteWordService        noteInterface.INoteWordService
+	takeoutExporter        *service.NoteTakeoutExporter
+	userDataDeleter        *service.NoteUserDataDeleter
+	noteShapeTrashHandler  *service.NoteShapeTrashHandler
+	grpcServer             *notegrpc.NoteGRPCServer
 }
 
 // NewModule 创建论文笔记模块
@@ -84,8 +101,11 @@ func (m *NoteModule) Shutdown() error {
 
 // RegisterGRPC 注册gRPC服务
 func (m *NoteModule) RegisterGRPC(server *grpc.Server) {
-	// TODO: 实现gRPC服务注册
-	m.logger.Debug("msg", "翻译模块没有gRPC服务，跳过注册")
+	if m.grpcServer == nil {
+		m.logger.Warn("msg", "gRPC服务未初始化", "module", m.Name())
+		return
+	}
+	m.grpcServer.RegisterServer(server)
 }
 
 // RegisterJobSchedulers 注册Job定时任务
@@ -145,9 +165,24 @@ func (m *NoteModule) Initialize() error {
 	// 创建NoteReadLocationAPI
 	m.noteReadLocationAPI = api.NewNoteReadLocationAPI(noteReadLocationService, m.logger, m.tracer)
 
+	// 创建用户数据导出器
+	m.takeoutExporter = service.NewNoteTakeoutExporter(m.logger, m.tracer, paperNoteDAO, noteShapeDAO, noteWordDAO,
+		noteSummaryDAO, noteReadLocationDAO)
+
+	// 创建注销账号时的数据删除器
+	m.userDataDeleter = service.NewNoteUserDataDeleter(m.logger, m.tracer, paperNoteDAO, paperNoteAccessDAO, noteShapeDAO,
+		dao.NewNoteDrawEntityDAO(m.db, m.logger), noteWordDAO, noteWordConfigDAO, noteSummaryDAO, noteReadLocationDAO,
+		dao.NewNoteLatestReadDAO(m.db, m.logger), dao.NewNoteExportHistoryDAO(m.db, m.logger))
+
+	// 创建回收站处理器
+	m.noteShapeTrashHandler = service.NewNoteShapeTrashHandler(m.logger, m.tracer, noteShapeDAO)
+
 	// 创建NoteReadLocationAPI
 	m.noteManageAPI = api.NewNoteManageAPI(m.logger, m.tracer, m.noteWordService, m.noteSummaryService, m.pdfService)
 
+	// 创建gRPC服务
+	m.grpcServer = notegrpc.NewNoteGRPCServer(m.logger, m.tracer, m.paperNoteService, m.noteSummaryService, m.noteWordService, m.userDocService)
+
 	return nil
 }
 
@@ -198,6 +233,21 @@ func (m *NoteModule) RegisterRoutes(r *gin.Engine) {
 	}
 }
 
+// TakeoutExporters 返回笔记模块的用户数据导出器
+func (m *NoteModule) TakeoutExporters() []takeout.Exporter {
+	return []takeout.Exporter{m.takeoutExporter}
+}
+
+// TrashHandlers 返回笔记模块的回收站处理器
+func (m *NoteModule) TrashHandlers() []trash.Handler {
+	return []trash.Handler{m.noteShapeTrashHandler}
+}
+
+// DeleteUserData 注销账号时删除用户的笔记数据
+func (m *NoteModule) DeleteUserData(ctx context.Context, userId string) error {
+	return m.userDataDeleter.DeleteUserData(ctx, userId)
+}
+
 // GetPaperNoteService 获取论文笔记服务
 func (m *NoteModule) GetPaperNoteService() noteInterface.IPaperNoteService {
 	return m.paperNoteService
@@ -208,6 +258,22 @@ func (m *NoteModule) GetPaperNoteAccessService() *service.PaperNoteAccessService
 	return m.paperNoteAccessService
 }
 
+// GetNoteSummaryService 获取笔记摘要服务
+func (m *NoteModule) GetNoteSummaryService() *service.NoteSummaryService {
+	return m.noteSummaryService
+}
+
+// SetReadingHeartbeatRecorder 设置阅读心跳记录器，由阅读统计模块注入
+func (m *NoteModule) SetReadingHeartbeatRecorder(recorder noteInterface.IReadingHeartbeatRecorder) error {
+	if recorder == nil {
+		return errors.New("readingHeartbeatRecorder cannot be nil")
+	}
+	if m.noteReadLocationAPI != nil {
+		m.noteReadLocationAPI.SetReadingHeartbeatRecorder(recorder)
+	}
+	return nil
+}
+
 // SetPaperPdfService 设置论文PDF服务，用于解决循环依赖问题
 func (m *NoteModule) SetPaperPdfService(pdfService pdfInterface.IPaperPdfService) error {
 	if pdfService == nil {
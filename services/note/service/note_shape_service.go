@@ -90,6 +90,22 @@ func (s *NoteShapeService) DeleteNoteShapeByIds(ctx context.Context, ids []strin
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "NoteShapeService.DeleteNoteShapeByIds")
 	defer span.Finish()
 
+	// 记录到回收站
+	shapes, err := s.noteShapeDAO.FindByIds(ctx, ids)
+	if err != nil {
+		s.logger.Error("获取笔记形状失败", "error", err)
+		return false, errors.Biz("note.note_shape.errors.delete_failed")
+	}
+	for i := range shapes {
+		if shapes[i].IsDeleted {
+			continue
+		}
+		if err := recordNoteShapeTrash(ctx, &shapes[i]); err != nil {
+			s.logger.Error("记录回收站失败", "error", err)
+			return false, errors.Biz("note.note_shape.errors.delete_failed")
+		}
+	}
+
 	// 删除笔记形状
 	if err := s.noteShapeDAO.DeleteByIds(ctx, ids); err != nil {
 		s.logger.Error("删除笔记形状失败", "error", err)
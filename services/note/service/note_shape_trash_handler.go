@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/trash"
+	"github.com/yb2020/odoc/services/note/dao"
+	"github.com/yb2020/odoc/services/note/model"
+)
+
+// recordNoteShapeTrash 把删除的笔记形状记录到回收站
+func recordNoteShapeTrash(ctx context.Context, shape *model.NoteShape) error {
+	return trash.Record(ctx, &trash.Item{
+		UserId: shape.CreatorId,
+		Type:   trash.TypeNoteShape,
+		ItemId: shape.Id,
+		Title:  shape.Type,
+	})
+}
+
+// NoteShapeTrashHandler 回收站中笔记形状的恢复和彻底删除
+type NoteShapeTrashHandler struct {
+	logger       logging.Logger
+	tracer       opentracing.Tracer
+	noteShapeDAO *dao.NoteShapeDAO
+}
+
+// NewNoteShapeTrashHandler 创建笔记形状回收站处理器
+func NewNoteShapeTrashHandler(logger logging.Logger, tracer opentracing.Tracer, noteShapeDAO *dao.NoteShapeDAO) *NoteShapeTrashHandler {
+	return &NoteShapeTrashHandler{
+		logger:       logger,
+		tracer:       tracer,
+		noteShapeDAO: noteShapeDAO,
+	}
+}
+
+// Type 处理的条目类型
+func (h *NoteShapeTrashHandler) Type() string {
+	return trash.TypeNoteShape
+}
+
+// Restore 恢复笔记形状
+func (h *NoteShapeTrashHandler) Restore(ctx context.Context, entry *trash.Entry) (string, error) {
+	if err := h.noteShapeDAO.RestoreByIds(ctx, []string{entry.ItemId}); err != nil {
+		return "", err
+	}
+	return entry.Title, nil
+}
+
+// Purge 物理删除笔记形状
+func (h *NoteShapeTrashHandler) Purge(ctx context.Context, entry *trash.Entry) error {
+	shape, err := h.noteShapeDAO.FindById(ctx, entry.ItemId)
+	if err != nil {
+		return err
+	}
+	// 已被其他途径恢复或删除
+	if shape == nil || !shape.IsDeleted {
+		return nil
+	}
+	return h.noteShapeDAO.RemoveById(ctx, shape.Id)
+}
@@ -139,6 +139,35 @@ func (s *NoteSummaryService) GetNoteSummaryByNoteId(ctx context.Context, noteId
 	return summary, nil
 }
 
+// SaveNoteSummaryContent 保存笔记摘要内容，不存在时创建
+// appendContent 为 true 时追加到已有内容之后，否则覆盖
+func (s *NoteSummaryService) SaveNoteSummaryContent(ctx context.Context, noteId string, userId string, content string, appendContent bool) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "NoteSummaryService.SaveNoteSummaryContent")
+	defer span.Finish()
+
+	noteSummary, err := s.GetNoteSummaryByNoteId(ctx, noteId)
+	if err != nil {
+		return err
+	}
+	if noteSummary == nil {
+		_, err = s.CreateNoteSummary(ctx, &model.NoteSummary{
+			NoteId:  noteId,
+			Content: content,
+			UserId:  userId,
+		})
+		return err
+	}
+
+	if appendContent && noteSummary.Content != "" {
+		noteSummary.Content = noteSummary.Content + "\n\n" + content
+	} else {
+		noteSummary.Content = content
+	}
+	noteSummary.UserId = userId
+	_, err = s.UpdateNoteSummary(ctx, noteSummary)
+	return err
+}
+
 // GetNoteSummariesByUserId 根据用户ID获取笔记摘要列表
 func (s *NoteSummaryService) GetNoteSummariesByUserId(ctx context.Context, userId string) ([]model.NoteSummary, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "NoteSummaryService.GetNoteSummariesByUserId")
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/takeout"
+	"github.com/yb2020/odoc/services/note/dao"
+	"github.com/yb2020/odoc/services/note/model"
+)
+
+// noteWordExportPageSize 导出笔记单词时每次查询的条数
+const noteWordExportPageSize = 1000
+
+// 编译时类型检查：确保 NoteTakeoutExporter 实现了 takeout.Exporter 接口
+var _ takeout.Exporter = (*NoteTakeoutExporter)(nil)
+
+// NoteTakeoutExporter 导出用户的笔记、笔记形状、生词、笔记摘要和阅读位置
+type NoteTakeoutExporter struct {
+	logger              logging.Logger
+	tracer              opentracing.Tracer
+	paperNoteDAO        *dao.PaperNoteDAO
+	noteShapeDAO        *dao.NoteShapeDAO
+	noteWordDAO         *dao.NoteWordDAO
+	noteSummaryDAO      *dao.NoteSummaryDAO
+	noteReadLocationDAO *dao.NoteReadLocationDAO
+}
+
+// NewNoteTakeoutExporter 创建笔记数据导出器
+func NewNoteTakeoutExporter(logger logging.Logger, tracer opentracing.Tracer,
+	paperNoteDAO *dao.PaperNoteDAO,
+	noteShapeDAO *dao.NoteShapeDAO,
+	noteWordDAO *dao.NoteWordDAO,
+	noteSummaryDAO *dao.NoteSummaryDAO,
+	noteReadLocationDAO *dao.NoteReadLocationDAO,
+) *NoteTakeoutExporter {
+	return &NoteTakeoutExporter{
+		logger:              logger,
+		tracer:              tracer,
+		paperNoteDAO:        paperNoteDAO,
+		noteShapeDAO:        noteShapeDAO,
+		noteWordDAO:         noteWordDAO,
+		noteSummaryDAO:      noteSummaryDAO,
+		noteReadLocationDAO: noteReadLocationDAO,
+	}
+}
+
+// Name 导出器名称
+func (e *NoteTakeoutExporter) Name() string {
+	return "note"
+}
+
+// Export 导出用户的笔记数据
+func (e *NoteTakeoutExporter) Export(ctx context.Context, userId string, w *takeout.Writer) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, e.tracer, "NoteTakeoutExporter.Export")
+	defer span.Finish()
+
+	notes, err := e.paperNoteDAO.GetAllNoteByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("notes.json", notes); err != nil {
+		return err
+	}
+	noteIds := make([]string, 0, len(notes))
+	for _, note := range notes {
+		noteIds = append(noteIds, note.Id)
+	}
+
+	shapes := []model.NoteShape{}
+	words := []model.NoteWord{}
+	locations := []model.NoteReadLocation{}
+	if len(noteIds) > 0 {
+		if shapes, err = e.noteShapeDAO.GetByNoteIds(ctx, noteIds); err != nil {
+			return err
+		}
+		for offset := 0; ; offset += noteWordExportPageSize {
+			page, err := e.noteWordDAO.GetListByNoteIds(ctx, noteIds, noteWordExportPageSize, offset)
+			if err != nil {
+				return err
+			}
+			words = append(words, page...)
+			if len(page) < noteWordExportPageSize {
+				break
+			}
+		}
+		if locations, err = e.noteReadLocationDAO.GetByNoteIds(ctx, noteIds); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteJSON("shapes.json", shapes); err != nil {
+		return err
+	}
+	if err := w.WriteJSON("words.json", words); err != nil {
+		return err
+	}
+	if err := w.WriteJSON("read_locations.json", locations); err != nil {
+		return err
+	}
+
+	summaries, err := e.noteSummaryDAO.GetByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return w.WriteJSON("summaries.json", summaries)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/note/dao"
+)
+
+// NoteUserDataDeleter 注销账号时删除用户的笔记及笔记下的全部数据
+type NoteUserDataDeleter struct {
+	logger               logging.Logger
+	tracer               opentracing.Tracer
+	paperNoteDAO         *dao.PaperNoteDAO
+	paperNoteAccessDAO   *dao.PaperNoteAccessDAO
+	noteShapeDAO         *dao.NoteShapeDAO
+	noteDrawEntityDAO    *dao.NoteDrawEntityDAO
+	noteWordDAO          *dao.NoteWordDAO
+	noteWordConfigDAO    *dao.NoteWordConfigDAO
+	noteSummaryDAO       *dao.NoteSummaryDAO
+	noteReadLocationDAO  *dao.NoteReadLocationDAO
+	noteLatestReadDAO    *dao.NoteLatestReadDAO
+	noteExportHistoryDAO *dao.NoteExportHistoryDAO
+}
+
+// NewNoteUserDataDeleter 创建笔记数据删除器
+func NewNoteUserDataDeleter(logger logging.Logger, tracer opentracing.Tracer,
+	paperNoteDAO *dao.PaperNoteDAO,
+	paperNoteAccessDAO *dao.PaperNoteAccessDAO,
+	noteShapeDAO *dao.NoteShapeDAO,
+	noteDrawEntityDAO *dao.NoteDrawEntityDAO,
+	noteWordDAO *dao.NoteWordDAO,
+	noteWordConfigDAO *dao.NoteWordConfigDAO,
+	noteSummaryDAO *dao.NoteSummaryDAO,
+	noteReadLocationDAO *dao.NoteReadLocationDAO,
+	noteLatestReadDAO *dao.NoteLatestReadDAO,
+	noteExportHistoryDAO *dao.NoteExportHistoryDAO,
+) *NoteUserDataDeleter {
+	return &NoteUserDataDeleter{
+		logger:               logger,
+		tracer:               tracer,
+		paperNoteDAO:         paperNoteDAO,
+		paperNoteAccessDAO:   paperNoteAccessDAO,
+		noteShapeDAO:         noteShapeDAO,
+		noteDrawEntityDAO:    noteDrawEntityDAO,
+		noteWordDAO:          noteWordDAO,
+		noteWordConfigDAO:    noteWordConfigDAO,
+		noteSummaryDAO:       noteSummaryDAO,
+		noteReadLocationDAO:  noteReadLocationDAO,
+		noteLatestReadDAO:    noteLatestReadDAO,
+		noteExportHistoryDAO: noteExportHistoryDAO,
+	}
+}
+
+// DeleteUserData 物理删除用户的笔记、生词、笔记摘要、笔记形状和阅读位置
+// 生词和笔记摘要按 user_id 删除，其他表没有 user_id 字段，按创建人删除，笔记本身最后删除
+func (d *NoteUserDataDeleter) DeleteUserData(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, d.tracer, "NoteUserDataDeleter.DeleteUserData")
+	defer span.Finish()
+
+	if _, err := d.noteWordDAO.RemoveByUserId(ctx, userId); err != nil {
+		return err
+	}
+	if _, err := d.noteSummaryDAO.RemoveByUserId(ctx, userId); err != nil {
+		return err
+	}
+	removers := []func(context.Context, string) (int64, error){
+		d.noteWordConfigDAO.RemoveByCreatorId,
+		d.noteShapeDAO.RemoveByCreatorId,
+		d.noteDrawEntityDAO.RemoveByCreatorId,
+		d.noteReadLocationDAO.RemoveByCreatorId,
+		d.noteLatestReadDAO.RemoveByCreatorId,
+		d.noteExportHistoryDAO.RemoveByCreatorId,
+		d.paperNoteAccessDAO.RemoveByCreatorId,
+	}
+	for _, remove := range removers {
+		if _, err := remove(ctx, userId); err != nil {
+			return err
+		}
+	}
+	notes, err := d.paperNoteDAO.RemoveByCreatorId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	d.logger.Info("msg", "已删除用户的笔记数据", "userId", userId, "notes", notes)
+	return nil
+}
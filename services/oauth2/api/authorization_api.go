@@ -0,0 +1,363 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/yb2020/odoc/config"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+	"github.com/yb2020/odoc/services/oauth2/model"
+	"github.com/yb2020/odoc/services/oauth2/service"
+)
+
+// AuthorizationAPI 第三方应用授权服务器API处理器
+// 协议端点（authorize、token、introspect、revoke、.well-known）按RFC返回原始JSON，其余接口使用统一响应格式
+type AuthorizationAPI struct {
+	authorizationService *service.AuthorizationService
+	config               *config.Config
+	localizer            i18n.Localizer
+	logger               logging.Logger
+	tracer               opentracing.Tracer
+}
+
+// NewAuthorizationAPI 创建授权服务器API处理器
+func NewAuthorizationAPI(logger logging.Logger, tracer opentracing.Tracer, localizer i18n.Localizer, config *config.Config,
+	authorizationService *service.AuthorizationService) *AuthorizationAPI {
+	return &AuthorizationAPI{
+		authorizationService: authorizationService,
+		config:               config,
+		localizer:            localizer,
+		logger:               logger,
+		tracer:               tracer,
+	}
+}
+
+// Authorize 授权端点，校验请求后跳转到授权确认页
+func (api *AuthorizationAPI) Authorize(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.Authorize")
+	defer span.Finish()
+
+	req := &model.AuthorizeRequest{
+		ResponseType:        c.Query("response_type"),
+		ClientId:            c.Query("client_id"),
+		RedirectUri:         c.Query("redirect_uri"),
+		Scope:               c.Query("scope"),
+		State:               c.Query("state"),
+		CodeChallenge:       c.Query("code_challenge"),
+		CodeChallengeMethod: c.Query("code_challenge_method"),
+	}
+	location, err := api.authorizationService.AuthorizeRedirect(ctx, req, c.Request.URL.RawQuery)
+	if err != nil {
+		api.logger.Warn("msg", "授权请求无效", "clientId", req.ClientId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+// GetConsent 获取授权确认页展示的应用与作用域
+func (api *AuthorizationAPI) GetConsent(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.GetConsent")
+	defer span.Finish()
+
+	req := &pb.GetConsentRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析授权确认请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	resp, err := api.authorizationService.GetConsent(ctx, userId, &model.AuthorizeRequest{
+		ResponseType:        req.ResponseType,
+		ClientId:            req.ClientId,
+		RedirectUri:         req.RedirectUri,
+		Scope:               req.Scope,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	})
+	if err != nil {
+		api.logger.Warn("msg", "获取授权确认信息失败", "clientId", req.ClientId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", resp)
+}
+
+// SubmitConsent 提交用户的授权决定，返回前端需要跳转的第三方回调地址
+func (api *AuthorizationAPI) SubmitConsent(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.SubmitConsent")
+	defer span.Finish()
+
+	req := &pb.SubmitConsentRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析授权确认请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	redirectUrl, err := api.authorizationService.SubmitConsent(ctx, userId, &model.AuthorizeRequest{
+		ResponseType:        req.ResponseType,
+		ClientId:            req.ClientId,
+		RedirectUri:         req.RedirectUri,
+		Scope:               req.Scope,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}, req.Approve)
+	if err != nil {
+		api.logger.Warn("msg", "提交授权确认失败", "clientId", req.ClientId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.SubmitConsentResponse{RedirectUrl: redirectUrl})
+}
+
+// Token 令牌端点（RFC 6749 3.2），请求为表单格式
+func (api *AuthorizationAPI) Token(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.Token")
+	defer span.Finish()
+
+	resp, err := api.authorizationService.Token(ctx, &model.TokenEndpointRequest{
+		GrantType:    c.PostForm("grant_type"),
+		Code:         c.PostForm("code"),
+		RedirectUri:  c.PostForm("redirect_uri"),
+		CodeVerifier: c.PostForm("code_verifier"),
+		RefreshToken: c.PostForm("refresh_token"),
+		Scope:        c.PostForm("scope"),
+		Client:       clientCredentials(c),
+	})
+	if err != nil {
+		api.writeOAuthError(c, err)
+		return
+	}
+	writeNoStore(c)
+	c.JSON(http.StatusOK, resp)
+}
+
+// Introspect 令牌内省端点（RFC 7662）
+func (api *AuthorizationAPI) Introspect(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.Introspect")
+	defer span.Finish()
+
+	resp, err := api.authorizationService.Introspect(ctx, clientCredentials(c), c.PostForm("token"), c.PostForm("token_type_hint"))
+	if err != nil {
+		api.writeOAuthError(c, err)
+		return
+	}
+	writeNoStore(c)
+	c.JSON(http.StatusOK, resp)
+}
+
+// Revoke 令牌撤销端点（RFC 7009），令牌无效时同样返回200
+func (api *AuthorizationAPI) Revoke(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.Revoke")
+	defer span.Finish()
+
+	if err := api.authorizationService.Revoke(ctx, clientCredentials(c), c.PostForm("token"), c.PostForm("token_type_hint")); err != nil {
+		api.writeOAuthError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// Metadata 授权服务器元数据（RFC 8414）
+func (api *AuthorizationAPI) Metadata(c *gin.Context) {
+	c.JSON(http.StatusOK, api.authorizationService.Metadata())
+}
+
+// JWKS 第三方令牌验签公钥（RFC 7517）
+func (api *AuthorizationAPI) JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, api.authorizationService.JWKS())
+}
+
+// ListAuthorizedApps 获取当前用户已授权的第三方应用
+func (api *AuthorizationAPI) ListAuthorizedApps(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.ListAuthorizedApps")
+	defer span.Finish()
+
+	userId, _ := userContext.GetUserID(ctx)
+	apps, err := api.authorizationService.ListAuthorizedApps(ctx, userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.ListAuthorizedAppsResponse{Apps: apps})
+}
+
+// RevokeAuthorizedApp 撤销当前用户对第三方应用的授权
+func (api *AuthorizationAPI) RevokeAuthorizedApp(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.RevokeAuthorizedApp")
+	defer span.Finish()
+
+	req := &pb.RevokeAuthorizedAppRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析撤销应用授权请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	if err := api.authorizationService.RevokeAuthorizedApp(ctx, userId, req.ClientId); err != nil {
+		api.logger.Warn("msg", "撤销应用授权失败", "userId", userId, "clientId", req.ClientId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.RevokeAuthorizedAppResponse{})
+}
+
+// AdminListClients 管理员分页获取第三方应用
+func (api *AuthorizationAPI) AdminListClients(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.AdminListClients")
+	defer span.Finish()
+
+	req := &pb.ListOAuth2ClientsRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析第三方应用列表请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Size <= 0 {
+		req.Size = 20
+	}
+
+	clients, total, err := api.authorizationService.ListClients(ctx, req.Page, req.Size)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.ListOAuth2ClientsResponse{
+		Clients: clients,
+		Total:   int32(total),
+		Page:    req.Page,
+		Size:    req.Size,
+	})
+}
+
+// AdminCreateClient 管理员注册第三方应用
+func (api *AuthorizationAPI) AdminCreateClient(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.AdminCreateClient")
+	defer span.Finish()
+
+	req := &pb.CreateOAuth2ClientRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析注册第三方应用请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	adminId, _ := userContext.GetUserID(ctx)
+	client, secret, err := api.authorizationService.CreateClient(ctx, adminId, req)
+	if err != nil {
+		api.logger.Warn("msg", "注册第三方应用失败", "adminId", adminId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.CreateOAuth2ClientResponse{Client: client.ToProto(), ClientSecret: secret})
+}
+
+// AdminUpdateClient 管理员修改第三方应用
+func (api *AuthorizationAPI) AdminUpdateClient(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.AdminUpdateClient")
+	defer span.Finish()
+
+	req := &pb.UpdateOAuth2ClientRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析修改第三方应用请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	client, err := api.authorizationService.UpdateClient(ctx, req)
+	if err != nil {
+		api.logger.Warn("msg", "修改第三方应用失败", "clientId", req.ClientId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.UpdateOAuth2ClientResponse{Client: client.ToProto()})
+}
+
+// AdminRotateClientSecret 管理员重置第三方应用密钥
+func (api *AuthorizationAPI) AdminRotateClientSecret(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.AdminRotateClientSecret")
+	defer span.Finish()
+
+	req := &pb.RotateOAuth2ClientSecretRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析重置应用密钥请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	secret, err := api.authorizationService.RotateClientSecret(ctx, req.ClientId)
+	if err != nil {
+		api.logger.Warn("msg", "重置应用密钥失败", "clientId", req.ClientId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	adminId, _ := userContext.GetUserID(ctx)
+	api.logger.Info("msg", "管理员重置第三方应用密钥", "adminId", adminId, "clientId", req.ClientId)
+	response.Success(c, "success", &pb.RotateOAuth2ClientSecretResponse{ClientSecret: secret})
+}
+
+// AdminDeleteClient 管理员删除第三方应用
+func (api *AuthorizationAPI) AdminDeleteClient(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AuthorizationAPI.AdminDeleteClient")
+	defer span.Finish()
+
+	req := &pb.DeleteOAuth2ClientRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析删除第三方应用请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	if err := api.authorizationService.DeleteClient(ctx, req.ClientId); err != nil {
+		api.logger.Warn("msg", "删除第三方应用失败", "clientId", req.ClientId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	adminId, _ := userContext.GetUserID(ctx)
+	api.logger.Info("msg", "管理员删除第三方应用", "adminId", adminId, "clientId", req.ClientId)
+	response.Success(c, "success", &pb.DeleteOAuth2ClientResponse{})
+}
+
+// writeOAuthError 按 RFC 6749 5.2 返回协议错误，客户端认证失败时附带 WWW-Authenticate
+func (api *AuthorizationAPI) writeOAuthError(c *gin.Context, err error) {
+	oauthErr, ok := err.(*model.OAuthError)
+	if !ok {
+		api.logger.Error("msg", "授权服务器内部错误", "error", err.Error())
+		oauthErr = model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+	if oauthErr.Code == model.OAuthErrInvalidClient {
+		c.Header("WWW-Authenticate", `Basic realm="oauth2"`)
+	}
+	writeNoStore(c)
+	c.JSON(oauthErr.HTTPStatus(), oauthErr)
+}
+
+// clientCredentials 读取第三方应用的认证信息，优先使用 HTTP Basic
+func clientCredentials(c *gin.Context) model.ClientCredentials {
+	if clientId, clientSecret, ok := c.Request.BasicAuth(); ok {
+		return model.ClientCredentials{ClientId: clientId, ClientSecret: clientSecret}
+	}
+	return model.ClientCredentials{ClientId: c.PostForm("client_id"), ClientSecret: c.PostForm("client_secret")}
+}
+
+// writeNoStore 令牌相关响应禁止缓存
+func writeNoStore(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+}
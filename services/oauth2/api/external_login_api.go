@@ -0,0 +1,204 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yb2020/odoc/config"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+	"github.com/yb2020/odoc/services/oauth2/helper"
+	"github.com/yb2020/odoc/services/oauth2/service"
+)
+
+const externalStateCookie = "oauth_external_state"
+
+// ExternalLoginAPI 外部身份登录（OIDC / Google / GitHub / ORCID）的路由处理器
+type ExternalLoginAPI struct {
+	logger               logging.Logger
+	externalLoginService *service.ExternalLoginService
+	oauth2Service        service.OAuth2Service
+	localizer            i18n.Localizer
+	config               *config.Config
+}
+
+// NewExternalLoginAPI 创建外部身份登录API
+func NewExternalLoginAPI(logger logging.Logger, externalLoginService *service.ExternalLoginService, oauth2Service service.OAuth2Service,
+	localizer i18n.Localizer, cfg *config.Config) *ExternalLoginAPI {
+	return &ExternalLoginAPI{
+		logger:               logger,
+		externalLoginService: externalLoginService,
+		oauth2Service:        oauth2Service,
+		localizer:            localizer,
+		config:               cfg,
+	}
+}
+
+// ListProviders 返回已启用的外部身份提供方
+func (api *ExternalLoginAPI) ListProviders(c *gin.Context) {
+	providers := api.externalLoginService.Providers()
+	resp := &pb.ListExternalProvidersResponse{
+		Providers: make([]*pb.ExternalProvider, 0, len(providers)),
+	}
+	for _, p := range providers {
+		resp.Providers = append(resp.Providers, &pb.ExternalProvider{
+			Name:        p.Name(),
+			DisplayName: p.DisplayName(),
+			Type:        p.Type(),
+		})
+	}
+	response.Success(c, "success", resp)
+}
+
+// LoginHandler 重定向到 :provider 对应的提供方进行登录
+func (api *ExternalLoginAPI) LoginHandler(c *gin.Context) {
+	api.redirectToProvider(c, c.Param("provider"), "")
+}
+
+// LinkHandler 已登录用户关联 :provider 对应的外部身份
+func (api *ExternalLoginAPI) LinkHandler(c *gin.Context) {
+	userId, ok := userContext.GetUserID(c.Request.Context())
+	if !ok || userId == "" {
+		c.Error(errors.Biz("user.error.user_not_found"))
+		return
+	}
+	api.redirectToProvider(c, c.Param("provider"), userId)
+}
+
+// CallbackHandler 处理 :provider 的回调
+func (api *ExternalLoginAPI) CallbackHandler(c *gin.Context) {
+	api.handleCallback(c, c.Param("provider"))
+}
+
+// GoogleLoginHandler 兼容旧版 /api/oauth2/google/login
+func (api *ExternalLoginAPI) GoogleLoginHandler(c *gin.Context) {
+	api.redirectToProvider(c, "google", "")
+}
+
+// GoogleCallbackHandler 兼容旧版 /api/oauth2/google/callback
+func (api *ExternalLoginAPI) GoogleCallbackHandler(c *gin.Context) {
+	api.handleCallback(c, "google")
+}
+
+// redirectToProvider 生成授权地址，并将 state 写入 HttpOnly cookie 绑定到当前浏览器
+func (api *ExternalLoginAPI) redirectToProvider(c *gin.Context, providerName, linkUserId string) {
+	isHTTPS := isHTTPSRequest(c)
+	host := c.Request.Host
+	protocol := "http"
+	if isHTTPS {
+		protocol = "https"
+	}
+
+	authURL, state, err := api.externalLoginService.BeginLogin(c.Request.Context(), providerName, protocol+"://"+host, linkUserId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.SetCookie(externalStateCookie, state, api.config.OAuth2.External.StateTTL, "/", cookieDomain(host), isHTTPS, true)
+	api.logger.Info("msg", "外部身份登录跳转", "provider", providerName, "host", host, "https", isHTTPS)
+	c.Redirect(http.StatusTemporaryRedirect, authURL)
+}
+
+// handleCallback 校验 state 后完成登录或关联，并重定向到前端
+func (api *ExternalLoginAPI) handleCallback(c *gin.Context, providerName string) {
+	cookieState, err := c.Cookie(externalStateCookie)
+	if err != nil || cookieState == "" {
+		c.Error(errors.Biz("oauth2.error.missing_state_cookie"))
+		return
+	}
+	if c.Query("state") != cookieState {
+		c.Error(errors.Biz("oauth2.error.invalid_state"))
+		return
+	}
+	isHTTPS := isHTTPSRequest(c)
+	c.SetCookie(externalStateCookie, "", -1, "/", cookieDomain(c.Request.Host), isHTTPS, true)
+
+	// 用户在提供方拒绝授权
+	if providerErr := c.Query("error"); providerErr != "" {
+		api.logger.Warn("msg", "外部身份提供方返回错误", "provider", providerName, "error", providerErr, "description", c.Query("error_description"))
+		c.Error(errors.Biz("oauth2.error.external_access_denied"))
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		c.Error(errors.Biz("oauth2.error.missing_code"))
+		return
+	}
+
+	client := helper.NewLoginClient(c, api.localizer.GetLanguage(c))
+	result, err := api.externalLoginService.CompleteLogin(c.Request.Context(), providerName, cookieState, code)
+	if err != nil {
+		api.oauth2Service.RecordExternalLoginFailure(c.Request.Context(), providerName, client, err)
+		c.Error(err)
+		return
+	}
+
+	if result.Linked {
+		c.Redirect(http.StatusFound, api.redirectURL(api.config.OAuth2.External.LinkSuccessURL))
+		return
+	}
+
+	user := result.User
+	tokenResponse, err := api.oauth2Service.CreateTokenForExternalUser(c.Request.Context(), providerName, user.Id, user.Username, user.Roles.ToStringSlice(), client)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 需要两步验证时跳转到前端验证页面，由前端调用 /api/oauth2/mfa/verify 完成登录
+	if tokenResponse.MFARequired {
+		query := url.Values{}
+		query.Set("mfaToken", tokenResponse.MFAToken)
+		query.Set("enroll", strconv.FormatBool(tokenResponse.MFAEnrollRequired))
+		verifyURL := api.redirectURL(api.config.MFA.VerifyURL)
+		separator := "?"
+		if strings.Contains(verifyURL, "?") {
+			separator = "&"
+		}
+		c.Redirect(http.StatusFound, verifyURL+separator+query.Encode())
+		return
+	}
+
+	// 登录成功，写入 Cookie，与内建登录流程保持一致
+	helper.WriteSuccessLoginCookie(c, tokenResponse.UserId, tokenResponse.AccessToken, tokenResponse.ExpiresAt, api.config.OAuth2.AppID)
+
+	loginSuccessURL := api.config.OAuth2.External.LoginSuccessURL
+	if loginSuccessURL == "" {
+		loginSuccessURL = api.config.OAuth2.GoogleOAuth2.LoginSuccessURL
+	}
+	c.Redirect(http.StatusFound, api.redirectURL(loginSuccessURL))
+}
+
+// redirectURL 未配置跳转地址时回退到根目录
+func (api *ExternalLoginAPI) redirectURL(url string) string {
+	if url == "" {
+		api.logger.Warn("msg", "未配置外部登录成功跳转地址，将重定向到根目录'/'")
+		return "/"
+	}
+	return url
+}
+
+// isHTTPSRequest 判断当前请求是否为HTTPS（含反向代理）
+func isHTTPSRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
+
+// cookieDomain 去掉端口号；www前缀的域名设置为顶级域名，支持 example.com 和 www.example.com 互通
+func cookieDomain(host string) string {
+	domain := host
+	if colonIndex := strings.Index(host, ":"); colonIndex != -1 {
+		domain = host[:colonIndex]
+	}
+	if strings.HasPrefix(domain, "www.") {
+		domain = "." + domain[4:]
+	}
+	return domain
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/yb2020/odoc/config"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+	"github.com/yb2020/odoc/services/oauth2/helper"
+	"github.com/yb2020/odoc/services/oauth2/service"
+)
+
+// MFAAPI 两步验证API处理器
+type MFAAPI struct {
+	mfaService    *service.MFAService
+	oauth2Service service.OAuth2Service
+	config        *config.Config
+	localizer     i18n.Localizer
+	logger        logging.Logger
+	tracer        opentracing.Tracer
+}
+
+// NewMFAAPI 创建两步验证API处理器
+func NewMFAAPI(logger logging.Logger, tracer opentracing.Tracer, localizer i18n.Localizer, config *config.Config,
+	mfaService *service.MFAService, oauth2Service service.OAuth2Service) *MFAAPI {
+	return &MFAAPI{
+		mfaService:    mfaService,
+		oauth2Service: oauth2Service,
+		config:        config,
+		localizer:     localizer,
+		logger:        logger,
+		tracer:        tracer,
+	}
+}
+
+// Status 获取当前用户的两步验证状态
+func (api *MFAAPI) Status(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.Status")
+	defer span.Finish()
+
+	uc := userContext.GetUserContext(ctx)
+	status, err := api.mfaService.Status(ctx, uc.UserId, uc.Roles)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.GetMFAStatusResponse{
+		Enabled:                status.Enabled,
+		Required:               status.Required,
+		RecoveryCodesRemaining: int32(status.RecoveryCodesRemaining),
+		TrustedDevices:         int32(status.TrustedDevices),
+	})
+}
+
+// BeginEnrollment 开始绑定认证器App
+func (api *MFAAPI) BeginEnrollment(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.BeginEnrollment")
+	defer span.Finish()
+
+	uc := userContext.GetUserContext(ctx)
+	secret, uri, err := api.mfaService.BeginEnrollment(ctx, uc.UserId, uc.Username)
+	if err != nil {
+		api.logger.Warn("msg", "开始绑定两步验证失败", "userId", uc.UserId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.BeginMFAEnrollmentResponse{Secret: secret, ProvisioningUri: uri})
+}
+
+// ActivateEnrollment 校验验证码并激活两步验证
+func (api *MFAAPI) ActivateEnrollment(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.ActivateEnrollment")
+	defer span.Finish()
+
+	req := &pb.ActivateMFARequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析激活两步验证请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	codes, err := api.mfaService.ActivateEnrollment(ctx, userId, req.Code)
+	if err != nil {
+		api.logger.Warn("msg", "激活两步验证失败", "userId", userId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.ActivateMFAResponse{RecoveryCodes: codes})
+}
+
+// Disable 关闭两步验证
+func (api *MFAAPI) Disable(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.Disable")
+	defer span.Finish()
+
+	req := &pb.DisableMFARequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析关闭两步验证请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	uc := userContext.GetUserContext(ctx)
+	if err := api.mfaService.Disable(ctx, uc.UserId, uc.Roles, req.Code, req.RecoveryCode); err != nil {
+		api.logger.Warn("msg", "关闭两步验证失败", "userId", uc.UserId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.DisableMFAResponse{})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (api *MFAAPI) RegenerateRecoveryCodes(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.RegenerateRecoveryCodes")
+	defer span.Finish()
+
+	req := &pb.RegenerateRecoveryCodesRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析重新生成恢复码请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	codes, err := api.mfaService.RegenerateRecoveryCodes(ctx, userId, req.Code)
+	if err != nil {
+		api.logger.Warn("msg", "重新生成恢复码失败", "userId", userId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.RegenerateRecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RevokeTrustedDevices 取消所有已记住的设备
+func (api *MFAAPI) RevokeTrustedDevices(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.RevokeTrustedDevices")
+	defer span.Finish()
+
+	userId, _ := userContext.GetUserID(ctx)
+	if err := api.mfaService.RevokeTrustedDevices(ctx, userId); err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.RevokeTrustedDevicesResponse{})
+}
+
+// Verify 登录第二步：校验验证码或恢复码后签发令牌并写入登录 cookie
+func (api *MFAAPI) Verify(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.Verify")
+	defer span.Finish()
+
+	req := &pb.VerifyMFARequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析两步验证请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	client := helper.NewLoginClient(c, api.localizer.GetLanguage(c))
+	resp, trustedToken, trustedExpiresAt, err := api.oauth2Service.VerifyMFA(ctx, req.MfaToken, req.Code, req.RecoveryCode, req.RememberDevice, client)
+	if err != nil {
+		api.logger.Warn("msg", "两步验证失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	helper.WriteSuccessLoginCookie(c, resp.UserId, resp.AccessToken, resp.ExpiresAt, api.config.OAuth2.AppID)
+	helper.WriteTrustedDeviceCookie(c, trustedToken, trustedExpiresAt, api.config.OAuth2.AppID)
+
+	response.Success(c, "signin_successful", &pb.VerifyMFAResponse{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    resp.ExpiresAt,
+		ExpiresIn:    resp.ExpiresIn,
+	})
+}
+
+// ChallengeEnroll 角色强制两步验证但尚未绑定时，在登录过程中开始绑定
+func (api *MFAAPI) ChallengeEnroll(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.ChallengeEnroll")
+	defer span.Finish()
+
+	req := &pb.ChallengeEnrollRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析登录绑定两步验证请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	secret, uri, err := api.oauth2Service.BeginChallengeEnrollment(ctx, req.MfaToken)
+	if err != nil {
+		api.logger.Warn("msg", "登录过程中开始绑定两步验证失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.ChallengeEnrollResponse{Secret: secret, ProvisioningUri: uri})
+}
+
+// ChallengeActivate 在登录过程中完成绑定并签发令牌
+func (api *MFAAPI) ChallengeActivate(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.ChallengeActivate")
+	defer span.Finish()
+
+	req := &pb.ChallengeActivateRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析登录激活两步验证请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	client := helper.NewLoginClient(c, api.localizer.GetLanguage(c))
+	resp, codes, err := api.oauth2Service.ActivateChallengeEnrollment(ctx, req.MfaToken, req.Code, client)
+	if err != nil {
+		api.logger.Warn("msg", "登录过程中激活两步验证失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	helper.WriteSuccessLoginCookie(c, resp.UserId, resp.AccessToken, resp.ExpiresAt, api.config.OAuth2.AppID)
+
+	response.Success(c, "signin_successful", &pb.ChallengeActivateResponse{
+		AccessToken:   resp.AccessToken,
+		RefreshToken:  resp.RefreshToken,
+		ExpiresAt:     resp.ExpiresAt,
+		ExpiresIn:     resp.ExpiresIn,
+		RecoveryCodes: codes,
+	})
+}
+
+// AdminListRolePolicies 管理员获取角色两步验证策略
+func (api *MFAAPI) AdminListRolePolicies(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.AdminListRolePolicies")
+	defer span.Finish()
+
+	policies, err := api.mfaService.ListRolePolicies(ctx)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.ListMFARolePoliciesResponse{Policies: policies})
+}
+
+// AdminSetRolePolicy 管理员设置角色是否强制两步验证
+func (api *MFAAPI) AdminSetRolePolicy(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.AdminSetRolePolicy")
+	defer span.Finish()
+
+	req := &pb.SetMFARolePolicyRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析角色两步验证策略请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	policy, err := api.mfaService.SetRolePolicy(ctx, req.Role, req.Required)
+	if err != nil {
+		api.logger.Warn("msg", "设置角色两步验证策略失败", "role", req.Role, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	adminId, _ := userContext.GetUserID(ctx)
+	api.logger.Info("msg", "管理员设置角色两步验证策略", "adminId", adminId, "role", req.Role, "required", req.Required)
+	response.Success(c, "success", &pb.SetMFARolePolicyResponse{Policy: policy})
+}
+
+// AdminResetUserMFA 管理员重置用户的两步验证，用户需重新绑定
+func (api *MFAAPI) AdminResetUserMFA(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "MFAAPI.AdminResetUserMFA")
+	defer span.Finish()
+
+	req := &pb.AdminResetUserMFARequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析重置两步验证请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	if err := api.mfaService.RemoveUserMFA(ctx, req.UserId); err != nil {
+		api.logger.Warn("msg", "管理员重置两步验证失败", "userId", req.UserId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	adminId, _ := userContext.GetUserID(ctx)
+	api.logger.Info("msg", "管理员重置两步验证", "adminId", adminId, "userId", req.UserId)
+	response.Success(c, "success", &pb.AdminResetUserMFAResponse{})
+}
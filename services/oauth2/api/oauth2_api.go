@@ -166,6 +166,7 @@ func (api *OAuth2API) SignInAuthCodeHandler(c *gin.Context) {
 	tokenReq := &model.TokenRequest{
 		Username: req.Username,
 		Password: req.Password,
+		Client:   helper.NewLoginClient(c, api.localizer.GetLanguage(c)),
 	}
 
 	//RSA解密
@@ -180,6 +181,16 @@ func (api *OAuth2API) SignInAuthCodeHandler(c *gin.Context) {
 		return
 	}
 
+	// 需要两步验证时不签发令牌，也不设置 cookie
+	if resp.MFARequired {
+		response.Success(c, "success", &pb.SignInAuthCodeResponse{
+			MfaRequired:       true,
+			MfaToken:          resp.MFAToken,
+			MfaEnrollRequired: resp.MFAEnrollRequired,
+		})
+		return
+	}
+
 	// 设置 cookie
 	helper.WriteSuccessLoginCookie(c, resp.UserId, resp.AccessToken, resp.ExpiresAt, api.config.OAuth2.AppID)
 
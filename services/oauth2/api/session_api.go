@@ -0,0 +1,189 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+	"github.com/yb2020/odoc/services/oauth2/dao"
+	"github.com/yb2020/odoc/services/oauth2/service"
+)
+
+// SessionAPI 登录会话与登录历史API处理器
+type SessionAPI struct {
+	sessionService *service.SessionService
+	oauth2Service  service.OAuth2Service
+	logger         logging.Logger
+	tracer         opentracing.Tracer
+}
+
+// NewSessionAPI 创建登录会话API处理器
+func NewSessionAPI(logger logging.Logger, tracer opentracing.Tracer, sessionService *service.SessionService, oauth2Service service.OAuth2Service) *SessionAPI {
+	return &SessionAPI{
+		sessionService: sessionService,
+		oauth2Service:  oauth2Service,
+		logger:         logger,
+		tracer:         tracer,
+	}
+}
+
+// ListSessions 获取当前用户的活跃会话
+func (api *SessionAPI) ListSessions(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "SessionAPI.ListSessions")
+	defer span.Finish()
+
+	userId, _ := userContext.GetUserID(ctx)
+	sessions, err := api.sessionService.ListSessions(ctx, userId, api.sessionService.CurrentSessionId(ctx))
+	if err != nil {
+		api.logger.Warn("msg", "获取会话列表失败", "userId", userId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.ListSessionsResponse{Sessions: sessions})
+}
+
+// RevokeSession 撤销当前用户的指定会话
+func (api *SessionAPI) RevokeSession(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "SessionAPI.RevokeSession")
+	defer span.Finish()
+
+	req := &pb.RevokeSessionRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析撤销会话请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	if err := api.sessionService.RevokeSession(ctx, userId, req.SessionId); err != nil {
+		api.logger.Warn("msg", "撤销会话失败", "userId", userId, "sessionId", req.SessionId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.RevokeSessionResponse{})
+}
+
+// RevokeOtherSessions 撤销当前会话以外的所有会话
+func (api *SessionAPI) RevokeOtherSessions(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "SessionAPI.RevokeOtherSessions")
+	defer span.Finish()
+
+	userId, _ := userContext.GetUserID(ctx)
+	revoked, err := api.sessionService.RevokeOtherSessions(ctx, userId, api.sessionService.CurrentSessionId(ctx))
+	if err != nil {
+		api.logger.Warn("msg", "撤销其他会话失败", "userId", userId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.RevokeOtherSessionsResponse{Revoked: int32(revoked)})
+}
+
+// ListLoginHistory 分页获取当前用户的登录历史
+func (api *SessionAPI) ListLoginHistory(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "SessionAPI.ListLoginHistory")
+	defer span.Finish()
+
+	req := &pb.ListLoginHistoryRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "无效的分页参数", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	items, total, err := api.sessionService.ListLoginHistory(ctx, &dao.LoginHistoryQuery{UserId: userId}, req.Page, req.Size)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.ListLoginHistoryResponse{
+		Items: items,
+		Total: total,
+		Page:  req.Page,
+		Size:  req.Size,
+	})
+}
+
+// AdminListSessions 管理员获取指定用户的活跃会话
+func (api *SessionAPI) AdminListSessions(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "SessionAPI.AdminListSessions")
+	defer span.Finish()
+
+	req := &pb.AdminListSessionsRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析会话查询请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	sessions, err := api.sessionService.ListSessions(ctx, req.UserId, "")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.AdminListSessionsResponse{Sessions: sessions})
+}
+
+// AdminRevokeSession 管理员撤销指定用户的会话，未指定会话时撤销该用户的全部会话
+func (api *SessionAPI) AdminRevokeSession(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "SessionAPI.AdminRevokeSession")
+	defer span.Finish()
+
+	req := &pb.AdminRevokeSessionRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析撤销会话请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	var err error
+	if req.SessionId == "" {
+		err = api.oauth2Service.RevokeUserTokens(ctx, req.UserId)
+	} else {
+		err = api.sessionService.RevokeSession(ctx, req.UserId, req.SessionId)
+	}
+	if err != nil {
+		api.logger.Warn("msg", "管理员撤销会话失败", "userId", req.UserId, "sessionId", req.SessionId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	adminId, _ := userContext.GetUserID(ctx)
+	api.logger.Info("msg", "管理员撤销会话", "adminId", adminId, "userId", req.UserId, "sessionId", req.SessionId)
+	response.Success(c, "success", &pb.AdminRevokeSessionResponse{})
+}
+
+// AdminListLoginHistory 管理员分页查询登录历史
+func (api *SessionAPI) AdminListLoginHistory(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "SessionAPI.AdminListLoginHistory")
+	defer span.Finish()
+
+	req := &pb.AdminListLoginHistoryRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "无效的分页参数", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	query := &dao.LoginHistoryQuery{
+		UserId:        req.UserId,
+		Identifier:    req.Identifier,
+		IP:            req.Ip,
+		OnlyFailed:    req.OnlyFailed,
+		OnlyNewDevice: req.OnlyNewDevice,
+	}
+	items, total, err := api.sessionService.ListLoginHistory(ctx, query, req.Page, req.Size)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.AdminListLoginHistoryResponse{
+		Items: items,
+		Total: total,
+		Page:  req.Page,
+		Size:  req.Size,
+	})
+}
@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/oauth2/model"
+	"gorm.io/gorm"
+)
+
+// LoginHistoryQuery 登录历史查询条件，空值表示不过滤
+type LoginHistoryQuery struct {
+	UserId        string
+	Identifier    string
+	IP            string
+	OnlyFailed    bool
+	OnlyNewDevice bool
+}
+
+// LoginHistoryDAO 登录历史数据访问对象
+type LoginHistoryDAO struct {
+	*baseDao.GormBaseDAO[model.LoginHistory]
+	logger logging.Logger
+}
+
+// NewLoginHistoryDAO 创建登录历史DAO
+func NewLoginHistoryDAO(db *gorm.DB, logger logging.Logger) *LoginHistoryDAO {
+	return &LoginHistoryDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.LoginHistory](db, logger),
+		logger:      logger,
+	}
+}
+
+// FindPage 按条件分页查询登录历史，按时间倒序
+func (d *LoginHistoryDAO) FindPage(ctx context.Context, query *LoginHistoryQuery, page, size int32) ([]model.LoginHistory, int64, error) {
+	db := d.GetDB(ctx).Model(&model.LoginHistory{})
+	if query.UserId != "" {
+		db = db.Where("user_id = ?", query.UserId)
+	}
+	if query.Identifier != "" {
+		db = db.Where("identifier = ?", query.Identifier)
+	}
+	if query.IP != "" {
+		db = db.Where("ip = ?", query.IP)
+	}
+	if query.OnlyFailed {
+		db = db.Where("success = ?", false)
+	}
+	if query.OnlyNewDevice {
+		db = db.Where("new_device = ?", true)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		d.logger.Error("msg", "统计登录历史失败", "userId", query.UserId, "error", err.Error())
+		return nil, 0, err
+	}
+
+	var items []model.LoginHistory
+	offset := (page - 1) * size
+	if err := db.Order("created_at DESC").Offset(int(offset)).Limit(int(size)).Find(&items).Error; err != nil {
+		d.logger.Error("msg", "分页查询登录历史失败", "userId", query.UserId, "error", err.Error())
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// CountSuccessByUserId 统计用户成功登录的次数，fingerprint 不为空时只统计该设备
+func (d *LoginHistoryDAO) CountSuccessByUserId(ctx context.Context, userId string, fingerprint string) (int64, error) {
+	var count int64
+	db := d.GetDB(ctx).Model(&model.LoginHistory{}).Where("user_id = ? AND success = ?", userId, true)
+	if fingerprint != "" {
+		db = db.Where("device_fingerprint = ?", fingerprint)
+	}
+	if err := db.Count(&count).Error; err != nil {
+		d.logger.Error("msg", "统计用户成功登录次数失败", "userId", userId, "error", err.Error())
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeleteBefore 物理删除指定时间之前的登录历史，每次最多删除limit条，返回删除条数
+func (d *LoginHistoryDAO) DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	db := d.GetDB(ctx)
+	subQuery := db.Model(&model.LoginHistory{}).Select("id").Where("created_at < ?", before).Limit(limit)
+	result := db.Where("id IN (?)", subQuery).Delete(&model.LoginHistory{})
+	if result.Error != nil {
+		d.logger.Error("msg", "清理过期登录历史失败", "before", before, "error", result.Error.Error())
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,57 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/oauth2/model"
+	"gorm.io/gorm"
+)
+
+// MFARecoveryCodeDAO 两步验证恢复码数据访问对象
+type MFARecoveryCodeDAO struct {
+	*baseDao.GormBaseDAO[model.MFARecoveryCode]
+	logger logging.Logger
+}
+
+// NewMFARecoveryCodeDAO 创建恢复码DAO
+func NewMFARecoveryCodeDAO(db *gorm.DB, logger logging.Logger) *MFARecoveryCodeDAO {
+	return &MFARecoveryCodeDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.MFARecoveryCode](db, logger),
+		logger:      logger,
+	}
+}
+
+// UseCode 将用户未使用的恢复码标记为已使用，返回是否存在该恢复码
+func (d *MFARecoveryCodeDAO) UseCode(ctx context.Context, userId string, codeHash string) (bool, error) {
+	result := d.GetDB(ctx).Model(&model.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		UpdateColumn("used_at", time.Now().UTC())
+	if result.Error != nil {
+		d.logger.Error("msg", "使用恢复码失败", "userId", userId, "error", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountUnusedByUserId 统计用户未使用的恢复码个数
+func (d *MFARecoveryCodeDAO) CountUnusedByUserId(ctx context.Context, userId string) (int64, error) {
+	var count int64
+	err := d.GetDB(ctx).Model(&model.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userId).Count(&count).Error
+	if err != nil {
+		d.logger.Error("msg", "统计恢复码失败", "userId", userId, "error", err.Error())
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeleteByUserId 物理删除用户的全部恢复码
+func (d *MFARecoveryCodeDAO) DeleteByUserId(ctx context.Context, userId string) error {
+	if err := d.GetDB(ctx).Where("user_id = ?", userId).Delete(&model.MFARecoveryCode{}).Error; err != nil {
+		d.logger.Error("msg", "删除用户恢复码失败", "userId", userId, "error", err.Error())
+		return err
+	}
+	return nil
+}
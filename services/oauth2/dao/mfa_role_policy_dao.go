@@ -0,0 +1,63 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/oauth2/model"
+	"gorm.io/gorm"
+)
+
+// MFARolePolicyDAO 角色两步验证策略数据访问对象
+type MFARolePolicyDAO struct {
+	*baseDao.GormBaseDAO[model.MFARolePolicy]
+	logger logging.Logger
+}
+
+// NewMFARolePolicyDAO 创建角色两步验证策略DAO
+func NewMFARolePolicyDAO(db *gorm.DB, logger logging.Logger) *MFARolePolicyDAO {
+	return &MFARolePolicyDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.MFARolePolicy](db, logger),
+		logger:      logger,
+	}
+}
+
+// FindByRole 获取角色的策略，不存在时返回nil
+func (d *MFARolePolicyDAO) FindByRole(ctx context.Context, role string) (*model.MFARolePolicy, error) {
+	var policy model.MFARolePolicy
+	err := d.GetDB(ctx).Where("role = ?", role).First(&policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取角色两步验证策略失败", "role", role, "error", err.Error())
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// CountRequiredByRoles 统计指定角色中强制两步验证的个数
+func (d *MFARolePolicyDAO) CountRequiredByRoles(ctx context.Context, roles []string) (int64, error) {
+	if len(roles) == 0 {
+		return 0, nil
+	}
+	var count int64
+	err := d.GetDB(ctx).Model(&model.MFARolePolicy{}).Where("role IN (?) AND required = ?", roles, true).Count(&count).Error
+	if err != nil {
+		d.logger.Error("msg", "统计角色两步验证策略失败", "roles", roles, "error", err.Error())
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindAll 获取全部策略
+func (d *MFARolePolicyDAO) FindAll(ctx context.Context) ([]model.MFARolePolicy, error) {
+	var policies []model.MFARolePolicy
+	if err := d.GetDB(ctx).Order("role ASC").Find(&policies).Error; err != nil {
+		d.logger.Error("msg", "获取角色两步验证策略失败", "error", err.Error())
+		return nil, err
+	}
+	return policies, nil
+}
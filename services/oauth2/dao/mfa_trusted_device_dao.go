@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/oauth2/model"
+	"gorm.io/gorm"
+)
+
+// MFATrustedDeviceDAO 免两步验证设备数据访问对象
+type MFATrustedDeviceDAO struct {
+	*baseDao.GormBaseDAO[model.MFATrustedDevice]
+	logger logging.Logger
+}
+
+// NewMFATrustedDeviceDAO 创建免两步验证设备DAO
+func NewMFATrustedDeviceDAO(db *gorm.DB, logger logging.Logger) *MFATrustedDeviceDAO {
+	return &MFATrustedDeviceDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.MFATrustedDevice](db, logger),
+		logger:      logger,
+	}
+}
+
+// FindValid 根据令牌哈希查找用户未过期的设备，不存在时返回nil
+func (d *MFATrustedDeviceDAO) FindValid(ctx context.Context, userId string, tokenHash string) (*model.MFATrustedDevice, error) {
+	var device model.MFATrustedDevice
+	err := d.GetDB(ctx).Where("user_id = ? AND token_hash = ? AND expires_at > ?", userId, tokenHash, time.Now().UTC()).
+		First(&device).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "查找免两步验证设备失败", "userId", userId, "error", err.Error())
+		return nil, err
+	}
+	return &device, nil
+}
+
+// TouchLastUsed 更新设备最近使用时间
+func (d *MFATrustedDeviceDAO) TouchLastUsed(ctx context.Context, id string) error {
+	err := d.GetDB(ctx).Model(&model.MFATrustedDevice{}).Where("id = ?", id).
+		UpdateColumn("last_used_at", time.Now().UTC()).Error
+	if err != nil {
+		d.logger.Error("msg", "更新免两步验证设备使用时间失败", "id", id, "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// CountValidByUserId 统计用户未过期的设备数
+func (d *MFATrustedDeviceDAO) CountValidByUserId(ctx context.Context, userId string) (int64, error) {
+	var count int64
+	err := d.GetDB(ctx).Model(&model.MFATrustedDevice{}).Where("user_id = ? AND expires_at > ?", userId, time.Now().UTC()).
+		Count(&count).Error
+	if err != nil {
+		d.logger.Error("msg", "统计免两步验证设备失败", "userId", userId, "error", err.Error())
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeleteByUserId 物理删除用户的全部设备
+func (d *MFATrustedDeviceDAO) DeleteByUserId(ctx context.Context, userId string) error {
+	if err := d.GetDB(ctx).Where("user_id = ?", userId).Delete(&model.MFATrustedDevice{}).Error; err != nil {
+		d.logger.Error("msg", "删除用户免两步验证设备失败", "userId", userId, "error", err.Error())
+		return err
+	}
+	return nil
+}
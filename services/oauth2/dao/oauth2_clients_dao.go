@@ -62,3 +62,20 @@ func (dao *OAuth2ClientsDAO) UpdateClient(ctx context.Context, client *model.OAu
 func (dao *OAuth2ClientsDAO) DeleteClient(ctx context.Context, clientID string) error {
 	return dao.db.WithContext(ctx).Where("id = ?", clientID).Delete(&model.OAuth2Clients{}).Error
 }
+
+// FindPage 分页获取客户端，最近创建的排在前面
+func (dao *OAuth2ClientsDAO) FindPage(ctx context.Context, page, size int32) ([]*model.OAuth2Clients, int64, error) {
+	var clients []*model.OAuth2Clients
+	var total int64
+	db := dao.db.WithContext(ctx).Model(&model.OAuth2Clients{})
+	if err := db.Count(&total).Error; err != nil {
+		dao.logger.Error("msg", "统计客户端数量失败", "error", err.Error())
+		return nil, 0, err
+	}
+	err := db.Order("created_at DESC").Offset(int((page - 1) * size)).Limit(int(size)).Find(&clients).Error
+	if err != nil {
+		dao.logger.Error("msg", "分页获取客户端失败", "error", err.Error())
+		return nil, 0, err
+	}
+	return clients, total, nil
+}
@@ -53,3 +53,14 @@ func (dao *OAuth2CodeDAO) MarkAuthCodeAsUsed(ctx context.Context, code string) e
 func (dao *OAuth2CodeDAO) CleanupExpiredAuthCodes(ctx context.Context) error {
 	return dao.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&model.OAuth2AuthCode{}).Error
 }
+
+// ConsumeAuthCode 将未使用的授权码标记为已使用，返回是否标记成功，授权码只能兑换一次
+func (dao *OAuth2CodeDAO) ConsumeAuthCode(ctx context.Context, code string) (bool, error) {
+	result := dao.db.WithContext(ctx).Model(&model.OAuth2AuthCode{}).Where("code = ? AND used = ?", code, false).
+		Update("used", true)
+	if result.Error != nil {
+		dao.logger.Error("msg", "使用授权码失败", "error", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
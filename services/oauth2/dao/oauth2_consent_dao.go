@@ -0,0 +1,88 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/oauth2/model"
+	"gorm.io/gorm"
+)
+
+// OAuth2ConsentDAO 第三方应用授权记录数据访问对象
+type OAuth2ConsentDAO struct {
+	*baseDao.GormBaseDAO[model.OAuth2Consent]
+	logger logging.Logger
+}
+
+// NewOAuth2ConsentDAO 创建第三方应用授权记录DAO
+func NewOAuth2ConsentDAO(db *gorm.DB, logger logging.Logger) *OAuth2ConsentDAO {
+	return &OAuth2ConsentDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.OAuth2Consent](db, logger),
+		logger:      logger,
+	}
+}
+
+// FindByUserAndClient 获取用户对应用的授权记录，不存在时返回nil
+func (d *OAuth2ConsentDAO) FindByUserAndClient(ctx context.Context, userId string, clientId string) (*model.OAuth2Consent, error) {
+	var consent model.OAuth2Consent
+	err := d.GetDB(ctx).Where("user_id = ? AND client_id = ?", userId, clientId).First(&consent).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取应用授权记录失败", "userId", userId, "clientId", clientId, "error", err.Error())
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// FindByUserId 获取用户的全部授权记录，最近授权的排在前面
+func (d *OAuth2ConsentDAO) FindByUserId(ctx context.Context, userId string) ([]model.OAuth2Consent, error) {
+	var consents []model.OAuth2Consent
+	if err := d.GetDB(ctx).Where("user_id = ?", userId).Order("updated_at DESC").Find(&consents).Error; err != nil {
+		d.logger.Error("msg", "获取用户应用授权记录失败", "userId", userId, "error", err.Error())
+		return nil, err
+	}
+	return consents, nil
+}
+
+// FindUserIdsByClientId 获取授权过该应用的用户ID
+func (d *OAuth2ConsentDAO) FindUserIdsByClientId(ctx context.Context, clientId string) ([]string, error) {
+	var userIds []string
+	err := d.GetDB(ctx).Model(&model.OAuth2Consent{}).Where("client_id = ?", clientId).Pluck("user_id", &userIds).Error
+	if err != nil {
+		d.logger.Error("msg", "获取应用授权用户失败", "clientId", clientId, "error", err.Error())
+		return nil, err
+	}
+	return userIds, nil
+}
+
+// DeleteByUserAndClient 物理删除用户对应用的授权记录
+func (d *OAuth2ConsentDAO) DeleteByUserAndClient(ctx context.Context, userId string, clientId string) error {
+	err := d.GetDB(ctx).Where("user_id = ? AND client_id = ?", userId, clientId).Delete(&model.OAuth2Consent{}).Error
+	if err != nil {
+		d.logger.Error("msg", "删除应用授权记录失败", "userId", userId, "clientId", clientId, "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// DeleteByClientId 物理删除应用的全部授权记录
+func (d *OAuth2ConsentDAO) DeleteByClientId(ctx context.Context, clientId string) error {
+	if err := d.GetDB(ctx).Where("client_id = ?", clientId).Delete(&model.OAuth2Consent{}).Error; err != nil {
+		d.logger.Error("msg", "删除应用全部授权记录失败", "clientId", clientId, "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// DeleteByUserId 物理删除用户的全部授权记录
+func (d *OAuth2ConsentDAO) DeleteByUserId(ctx context.Context, userId string) error {
+	if err := d.GetDB(ctx).Where("user_id = ?", userId).Delete(&model.OAuth2Consent{}).Error; err != nil {
+		d.logger.Error("msg", "删除用户全部应用授权记录失败", "userId", userId, "error", err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/oauth2/model"
+	"gorm.io/gorm"
+)
+
+// OAuth2SessionDAO 登录会话数据访问对象
+type OAuth2SessionDAO struct {
+	*baseDao.GormBaseDAO[model.OAuth2Session]
+	logger logging.Logger
+}
+
+// NewOAuth2SessionDAO 创建登录会话DAO
+func NewOAuth2SessionDAO(db *gorm.DB, logger logging.Logger) *OAuth2SessionDAO {
+	return &OAuth2SessionDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.OAuth2Session](db, logger),
+		logger:      logger,
+	}
+}
+
+// FindActiveByUserId 获取用户未撤销且未过期的会话，最近活跃的排在前面
+func (d *OAuth2SessionDAO) FindActiveByUserId(ctx context.Context, userId string) ([]model.OAuth2Session, error) {
+	var sessions []model.OAuth2Session
+	err := d.GetDB(ctx).Where("user_id = ? AND revoked = ? AND expires_at > ?", userId, false, time.Now().UTC()).
+		Order("last_seen_at DESC").Find(&sessions).Error
+	if err != nil {
+		d.logger.Error("msg", "获取用户活跃会话失败", "userId", userId, "error", err.Error())
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// UpdateLastSeen 更新会话最近活跃时间
+func (d *OAuth2SessionDAO) UpdateLastSeen(ctx context.Context, id string, lastSeenAt time.Time) error {
+	err := d.GetDB(ctx).Model(&model.OAuth2Session{}).Where("id = ?", id).
+		UpdateColumn("last_seen_at", lastSeenAt).Error
+	if err != nil {
+		d.logger.Error("msg", "更新会话活跃时间失败", "id", id, "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// UpdateToken 刷新令牌后更新会话当前令牌与过期时间
+func (d *OAuth2SessionDAO) UpdateToken(ctx context.Context, id string, tokenId string, expiresAt time.Time) error {
+	err := d.GetDB(ctx).Model(&model.OAuth2Session{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"token_id": tokenId, "expires_at": expiresAt, "last_seen_at": time.Now().UTC()}).Error
+	if err != nil {
+		d.logger.Error("msg", "更新会话令牌失败", "id", id, "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// RevokeByIds 将指定会话标记为已撤销
+func (d *OAuth2SessionDAO) RevokeByIds(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := d.GetDB(ctx).Model(&model.OAuth2Session{}).Where("id IN (?) AND revoked = ?", ids, false).
+		Updates(map[string]interface{}{"revoked": true, "revoked_at": time.Now().UTC()}).Error
+	if err != nil {
+		d.logger.Error("msg", "撤销会话失败", "ids", ids, "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// RevokeByUserId 将用户的全部会话标记为已撤销
+func (d *OAuth2SessionDAO) RevokeByUserId(ctx context.Context, userId string) error {
+	err := d.GetDB(ctx).Model(&model.OAuth2Session{}).Where("user_id = ? AND revoked = ?", userId, false).
+		Updates(map[string]interface{}{"revoked": true, "revoked_at": time.Now().UTC()}).Error
+	if err != nil {
+		d.logger.Error("msg", "撤销用户全部会话失败", "userId", userId, "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// DeleteEndedBefore 物理删除在指定时间之前已撤销或已过期的会话，每次最多删除limit条，返回删除条数
+func (d *OAuth2SessionDAO) DeleteEndedBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	db := d.GetDB(ctx)
+	subQuery := db.Model(&model.OAuth2Session{}).Select("id").
+		Where("expires_at < ? OR (revoked = ? AND revoked_at < ?)", before, true, before).Limit(limit)
+	result := db.Where("id IN (?)", subQuery).Delete(&model.OAuth2Session{})
+	if result.Error != nil {
+		d.logger.Error("msg", "清理过期会话失败", "before", before, "error", result.Error.Error())
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
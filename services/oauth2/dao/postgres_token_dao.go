@@ -157,6 +157,30 @@ func (dao *PostgresTokenDAO) RevokeToken(ctx context.Context, tokenID string) er
 	return nil
 }
 
+// RevokeActiveToken 条件更新撤销尚未撤销的令牌，返回是否由本次调用撤销
+func (dao *PostgresTokenDAO) RevokeActiveToken(ctx context.Context, tokenID string) (bool, error) {
+	result := dao.db.WithContext(ctx).Model(&model.OAuth2Token{}).
+		Where("token_id = ? AND revoked = ?", tokenID, false).
+		Update("revoked", true)
+
+	if result.Error != nil {
+		dao.logger.Error("msg", "撤销令牌失败", "error", result.Error.Error())
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	// 从Redis删除
+	if dao.redis != nil {
+		if err := dao.redis.Del(ctx, "token:"+tokenID).Err(); err != nil {
+			dao.logger.Warn("msg", "从缓存删除令牌失败", "error", err.Error())
+		}
+	}
+
+	return true, nil
+}
+
 // RevokeAllTokensByUserID 撤销用户的所有令牌
 func (dao *PostgresTokenDAO) RevokeAllTokensByUserID(ctx context.Context, userID string) error {
 	// 更新数据库
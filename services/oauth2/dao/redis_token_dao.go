@@ -293,6 +293,32 @@ func (dao *RedisTokenDAO) RevokeToken(ctx context.Context, tokenID string) error
 	return nil
 }
 
+// RevokeActiveToken 撤销尚未撤销的令牌，返回是否由本次调用撤销
+// 以删除刷新令牌索引作为占用标记，DEL 是原子操作，并发调用时只有一个能删除成功
+func (dao *RedisTokenDAO) RevokeActiveToken(ctx context.Context, tokenID string) (bool, error) {
+	token, err := dao.GetTokenByID(ctx, tokenID)
+	if err != nil {
+		return false, err
+	}
+	if token == nil || token.Revoked {
+		return false, nil
+	}
+
+	deleted, err := dao.redis.Del(ctx, dao.refreshTokenKey(token.RefreshToken)).Result()
+	if err != nil {
+		dao.logger.Error("msg", "删除刷新令牌索引失败", "error", err.Error())
+		return false, err
+	}
+	if deleted == 0 {
+		return false, nil
+	}
+
+	if err := dao.RevokeToken(ctx, tokenID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // RevokeTokenWithPrefix 使用自定义前缀撤销令牌（用于服务令牌）
 func (dao *RedisTokenDAO) RevokeTokenWithPrefix(ctx context.Context, tokenID string, keyPrefix string) error {
 	// 获取令牌信息
@@ -36,6 +36,9 @@ type TokenDAO interface {
 	// 撤销令牌
 	RevokeToken(ctx context.Context, tokenID string) error
 
+	// 撤销尚未撤销的令牌，返回是否由本次调用撤销；并发调用时只有一个返回 true
+	RevokeActiveToken(ctx context.Context, tokenID string) (bool, error)
+
 	// 使用自定义前缀撤销令牌（用于服务令牌）
 	RevokeTokenWithPrefix(ctx context.Context, tokenID string, keyPrefix string) error
 
@@ -0,0 +1,59 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/oauth2/model"
+	"gorm.io/gorm"
+)
+
+// UserMFADAO 用户两步验证数据访问对象
+type UserMFADAO struct {
+	*baseDao.GormBaseDAO[model.UserMFA]
+	logger logging.Logger
+}
+
+// NewUserMFADAO 创建用户两步验证DAO
+func NewUserMFADAO(db *gorm.DB, logger logging.Logger) *UserMFADAO {
+	return &UserMFADAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.UserMFA](db, logger),
+		logger:      logger,
+	}
+}
+
+// FindByUserId 获取用户的两步验证配置，不存在时返回nil
+func (d *UserMFADAO) FindByUserId(ctx context.Context, userId string) (*model.UserMFA, error) {
+	var mfa model.UserMFA
+	err := d.GetDB(ctx).Where("user_id = ?", userId).First(&mfa).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取用户两步验证配置失败", "userId", userId, "error", err.Error())
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+// UpdateLastUsedStep 仅当新的时间步大于已使用的时间步时更新，返回是否更新成功，用于防止验证码重放
+func (d *UserMFADAO) UpdateLastUsedStep(ctx context.Context, id string, step int64) (bool, error) {
+	result := d.GetDB(ctx).Model(&model.UserMFA{}).Where("id = ? AND last_used_step < ?", id, step).
+		UpdateColumn("last_used_step", step)
+	if result.Error != nil {
+		d.logger.Error("msg", "更新两步验证时间步失败", "id", id, "error", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteByUserId 物理删除用户的两步验证配置
+func (d *UserMFADAO) DeleteByUserId(ctx context.Context, userId string) error {
+	if err := d.GetDB(ctx).Where("user_id = ?", userId).Delete(&model.UserMFA{}).Error; err != nil {
+		d.logger.Error("msg", "删除用户两步验证配置失败", "userId", userId, "error", err.Error())
+		return err
+	}
+	return nil
+}
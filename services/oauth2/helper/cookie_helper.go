@@ -148,3 +148,28 @@ func IsIPv4(ip string) bool {
 
 	return true
 }
+
+// TrustedDeviceCookieName 记住此设备的 cookie 名称
+func TrustedDeviceCookieName(appId string) string {
+	if appId == "" {
+		appId = DefaultAppID
+	}
+	return fmt.Sprintf(config.GetConfig().MFA.TrustedDeviceCookie, appId)
+}
+
+// WriteTrustedDeviceCookie 设置记住此设备的 cookie，有效期与设备令牌一致
+func WriteTrustedDeviceCookie(c *gin.Context, token string, expiresAt time.Time, appId string) {
+	maxAge := int(time.Until(expiresAt).Seconds())
+	if token == "" || maxAge <= 0 {
+		return
+	}
+	trustedCookie := &http.Cookie{
+		Name:     TrustedDeviceCookieName(appId),
+		Value:    token,
+		MaxAge:   maxAge,
+		Path:     "/",
+		HttpOnly: true,
+	}
+	SetDomain(c, trustedCookie)
+	http.SetCookie(c.Writer, trustedCookie)
+}
@@ -0,0 +1,28 @@
+package helper
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/middleware"
+	"github.com/yb2020/odoc/services/oauth2/model"
+)
+
+// NewLoginClient 从请求中提取登录客户端信息：IP、UserAgentMiddleware 解析出的设备信息以及记住此设备的令牌
+func NewLoginClient(c *gin.Context, language string) *model.LoginClient {
+	client := &model.LoginClient{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Language:  language,
+	}
+	if info, ok := middleware.GetUserAgentInfoFromGin(c); ok {
+		client.OsName = info.OsName
+		client.OsVersion = info.OsVersion
+		client.BrowserName = info.BrowserName
+		client.BrowserVersion = info.BrowserVersion
+		client.DeviceType = info.DeviceType
+	}
+	if token, err := c.Cookie(TrustedDeviceCookieName(config.GetConfig().OAuth2.AppID)); err == nil {
+		client.TrustedDeviceToken = token
+	}
+	return client
+}
@@ -0,0 +1,57 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/services/oauth2/service"
+)
+
+// LoginHistoryRetentionJob 登录历史与已结束会话的过期清理任务
+type LoginHistoryRetentionJob struct {
+	logger         logging.Logger
+	spec           string                 // 任务的cron表达式，6字段标准cron表达式
+	key            string                 // 任务的锁key，必须是唯一的unique-job-key
+	expiry         time.Duration          // 任务的锁过期时间
+	lockOpts       *scheduler.LockOptions // 任务的锁选项
+	sessionService *service.SessionService
+}
+
+func NewLoginHistoryRetentionJob(logger logging.Logger, cfg *config.Config, sessionService *service.SessionService) *LoginHistoryRetentionJob {
+	spec := cfg.Scheduler.Jobs.LoginHistoryRetentionJob.Spec
+	key := cfg.Scheduler.Jobs.LoginHistoryRetentionJob.Key
+	expiry := time.Duration(cfg.Scheduler.Jobs.LoginHistoryRetentionJob.Expiry) * time.Second
+	lockOpts := &scheduler.LockOptions{
+		Key:    key,
+		Expiry: expiry,
+	}
+	return &LoginHistoryRetentionJob{logger: logger, spec: spec, key: key, expiry: expiry, lockOpts: lockOpts, sessionService: sessionService}
+}
+
+// Spec 获取任务的cron表达式
+func (j *LoginHistoryRetentionJob) Spec() string {
+	return j.spec
+}
+
+// LockOpts 获取任务的锁选项
+func (j *LoginHistoryRetentionJob) LockOpts() *scheduler.LockOptions {
+	return j.lockOpts
+}
+
+// NewUserContext 清理任务不涉及用户数据，直接返回原上下文
+func (j *LoginHistoryRetentionJob) NewUserContext(ctx context.Context, userId string) context.Context {
+	return ctx
+}
+
+// Run 执行任务，在执行任务前会获取锁，执行任务后会释放锁
+func (j *LoginHistoryRetentionJob) Run() {
+	deleted, err := j.sessionService.CleanupExpired(context.Background())
+	if err != nil {
+		j.logger.Error("msg", "Login history retention job failed", "deleted", deleted, "error", err)
+		return
+	}
+	j.logger.Info("msg", "Login history retention job success", "deleted", deleted)
+}
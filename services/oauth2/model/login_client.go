@@ -0,0 +1,78 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// 登录方式
+const (
+	LoginMethodPassword = "password"
+)
+
+// LoginClient 发起登录的客户端信息
+type LoginClient struct {
+	IP             string
+	UserAgent      string
+	OsName         string
+	OsVersion      string
+	BrowserName    string
+	BrowserVersion string
+	DeviceType     string
+	Language       string // 请求语言，用于渲染新设备登录通知
+
+	TrustedDeviceToken string // 记住此设备的令牌，用于免两步验证
+}
+
+// DeviceName 可读的设备名称，如 Chrome 120 on Windows 10
+func (c *LoginClient) DeviceName() string {
+	if c == nil {
+		return ""
+	}
+	browser := strings.TrimSpace(c.BrowserName + " " + majorVersion(c.BrowserVersion))
+	os := strings.TrimSpace(c.OsName + " " + c.OsVersion)
+	switch {
+	case browser != "" && os != "":
+		return fmt.Sprintf("%s on %s", browser, os)
+	case browser != "":
+		return browser
+	default:
+		return os
+	}
+}
+
+// Fingerprint 设备指纹，只使用操作系统、浏览器名称与设备类型，浏览器升级不会被识别为新设备
+func (c *LoginClient) Fingerprint() string {
+	if c == nil {
+		return ""
+	}
+	raw := strings.ToLower(strings.Join([]string{c.OsName, c.BrowserName, c.DeviceType}, "|"))
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// IPNetwork 返回IP所在网段，IPv4取/24，IPv6取/48，用于展示与比对而不暴露完整地址
+func (c *LoginClient) IPNetwork() string {
+	if c == nil {
+		return ""
+	}
+	ip := net.ParseIP(c.IP)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// majorVersion 只保留主版本号
+func majorVersion(version string) string {
+	if i := strings.Index(version, "."); i > 0 {
+		return version[:i]
+	}
+	return version
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"github.com/yb2020/odoc/pkg/model"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+)
+
+// LoginHistory 登录历史，成功与失败的登录尝试都会记录；只保存IP及其网段，不做地理位置解析
+type LoginHistory struct {
+	model.BaseModel
+	UserId            string `json:"userId" gorm:"column:user_id;size:36;index"`                 // 用户ID，账号不存在时为空
+	Identifier        string `json:"identifier" gorm:"column:identifier;size:255"`               // 登录时使用的账号标识（邮箱或外部提供方）
+	Method            string `json:"method" gorm:"column:method;size:32"`                        // 登录方式：password、google、github等
+	Success           bool   `json:"success" gorm:"column:success"`                              // 是否登录成功
+	FailureReason     string `json:"failureReason" gorm:"column:failure_reason;size:128"`        // 失败原因（错误码）
+	IP                string `json:"ip" gorm:"column:ip;size:64"`                                // 登录IP
+	IPNetwork         string `json:"ipNetwork" gorm:"column:ip_network;size:64"`                 // IP网段：IPv4为/24，IPv6为/48
+	UserAgent         string `json:"userAgent" gorm:"column:user_agent;size:512"`                // 原始User-Agent
+	Device            string `json:"device" gorm:"column:device;size:128"`                       // 设备名称
+	DeviceType        string `json:"deviceType" gorm:"column:device_type;size:16"`               // 设备类型
+	DeviceFingerprint string `json:"deviceFingerprint" gorm:"column:device_fingerprint;size:64"` // 设备指纹
+	NewDevice         bool   `json:"newDevice" gorm:"column:new_device"`                         // 是否为首次出现的设备
+	SessionId         string `json:"sessionId" gorm:"column:session_id;size:36"`                 // 登录成功时创建的会话ID
+}
+
+// TableName 指定表名
+func (LoginHistory) TableName() string {
+	return "t_login_history"
+}
+
+// ToProto 转换为proto
+func (h *LoginHistory) ToProto() *pb.LoginHistory {
+	return &pb.LoginHistory{
+		Id:            h.Id,
+		UserId:        h.UserId,
+		Identifier:    h.Identifier,
+		Method:        h.Method,
+		Success:       h.Success,
+		FailureReason: h.FailureReason,
+		Ip:            h.IP,
+		IpNetwork:     h.IPNetwork,
+		UserAgent:     h.UserAgent,
+		Device:        h.Device,
+		DeviceType:    h.DeviceType,
+		NewDevice:     h.NewDevice,
+		CreatedAt:     uint64(h.CreatedAt.UnixMilli()),
+	}
+}
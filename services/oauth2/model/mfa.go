@@ -0,0 +1,83 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+)
+
+// UserMFA 用户的TOTP两步验证配置，未激活时为待确认的绑定
+type UserMFA struct {
+	model.BaseModel
+	UserId       string     `json:"userId" gorm:"column:user_id;size:36;uniqueIndex:idx_unique_t_user_mfa_user_id"` // 用户ID
+	Secret       string     `json:"-" gorm:"column:secret;size:255"`                                                // 加密后的TOTP密钥
+	Enabled      bool       `json:"enabled" gorm:"column:enabled;default:false"`                                    // 是否已激活
+	EnabledAt    *time.Time `json:"enabledAt" gorm:"column:enabled_at"`                                             // 激活时间
+	LastUsedStep int64      `json:"lastUsedStep" gorm:"column:last_used_step"`                                      // 最近一次使用的时间步，防止验证码重放
+}
+
+// TableName 指定表名
+func (UserMFA) TableName() string {
+	return "t_user_mfa"
+}
+
+// MFARecoveryCode 两步验证恢复码，只保存哈希值
+type MFARecoveryCode struct {
+	model.BaseModel
+	UserId   string     `json:"userId" gorm:"column:user_id;size:36;index"` // 用户ID
+	CodeHash string     `json:"-" gorm:"column:code_hash;size:64;index"`    // 恢复码的SHA-256哈希
+	UsedAt   *time.Time `json:"usedAt" gorm:"column:used_at"`               // 使用时间，为空表示未使用
+}
+
+// TableName 指定表名
+func (MFARecoveryCode) TableName() string {
+	return "t_mfa_recovery_code"
+}
+
+// MFATrustedDevice 记住此设备后免两步验证的设备，只保存令牌哈希
+type MFATrustedDevice struct {
+	model.BaseModel
+	UserId     string    `json:"userId" gorm:"column:user_id;size:36;index"`     // 用户ID
+	TokenHash  string    `json:"-" gorm:"column:token_hash;size:64;uniqueIndex"` // 设备令牌的SHA-256哈希
+	Device     string    `json:"device" gorm:"column:device;size:128"`           // 设备名称
+	ExpiresAt  time.Time `json:"expiresAt" gorm:"column:expires_at;index"`       // 过期时间
+	LastUsedAt time.Time `json:"lastUsedAt" gorm:"column:last_used_at"`          // 最近使用时间
+}
+
+// TableName 指定表名
+func (MFATrustedDevice) TableName() string {
+	return "t_mfa_trusted_device"
+}
+
+// MFARolePolicy 按角色强制两步验证的策略
+type MFARolePolicy struct {
+	model.BaseModel
+	Role     string `json:"role" gorm:"column:role;size:32;uniqueIndex:idx_unique_t_mfa_role_policy_role"` // 角色，如 ROLE_ADMIN
+	Required bool   `json:"required" gorm:"column:required;default:false"`                                 // 是否强制两步验证
+}
+
+// TableName 指定表名
+func (MFARolePolicy) TableName() string {
+	return "t_mfa_role_policy"
+}
+
+// ToProto 转换为proto
+func (p *MFARolePolicy) ToProto() *pb.MFARolePolicy {
+	return &pb.MFARolePolicy{
+		Role:      p.Role,
+		Required:  p.Required,
+		UpdatedAt: uint64(p.UpdatedAt.UnixMilli()),
+	}
+}
+
+// MFAChallenge 第一步验证（密码或外部身份）通过后等待两步验证的登录
+type MFAChallenge struct {
+	UserId         string   `json:"userId"`
+	Username       string   `json:"username"`
+	Roles          []string `json:"roles"`
+	Device         string   `json:"device"`
+	Method         string   `json:"method"`
+	Identifier     string   `json:"identifier"`
+	EnrollRequired bool     `json:"enrollRequired"` // 角色强制两步验证但用户尚未绑定，需先完成绑定
+}
@@ -5,13 +5,19 @@ import (
 	"time"
 
 	"github.com/yb2020/odoc/pkg/model"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
 )
 
 // OAuth2Clients 表示OAuth2客户端
 type OAuth2Clients struct {
 	ID            string            `gorm:"column:id;primaryKey"`
-	Secret        string            `gorm:"column:secret"`
+	Secret        string            `gorm:"column:secret"` // 客户端密钥的SHA-256哈希，公开客户端为空
 	Name          string            `gorm:"column:name"`
+	Description   string            `gorm:"column:description;size:512"`  // 授权确认页展示的应用说明
+	HomepageURL   string            `gorm:"column:homepage_url;size:512"` // 应用主页
+	LogoURL       string            `gorm:"column:logo_url;size:512"`     // 应用图标
+	Public        bool              `gorm:"column:public;default:false"`  // 公开客户端（浏览器插件、桌面应用），无密钥，必须使用PKCE
+	CreatorId     string            `gorm:"column:creator_id;size:36"`    // 创建该客户端的管理员
 	RedirectUris  model.StringSlice `gorm:"column:redirect_uris;type:json"`
 	AllowedScopes model.StringSlice `gorm:"column:allowed_scopes;type:json"`
 	Active        bool              `gorm:"column:active;default:true"`
@@ -78,3 +84,19 @@ func (c *OAuth2Clients) SetAllowedScopesFromString(scopes string) {
 	}
 	c.AllowedScopes = strings.Split(scopes, " ")
 }
+
+// ToProto 转换为proto，不包含密钥
+func (c *OAuth2Clients) ToProto() *pb.OAuth2ClientInfo {
+	return &pb.OAuth2ClientInfo{
+		ClientId:      c.ID,
+		Name:          c.Name,
+		Description:   c.Description,
+		HomepageUrl:   c.HomepageURL,
+		LogoUrl:       c.LogoURL,
+		Public:        c.Public,
+		RedirectUris:  c.RedirectUris,
+		AllowedScopes: c.AllowedScopes,
+		Active:        c.Active,
+		CreatedAt:     uint64(c.CreatedAt.UnixMilli()),
+	}
+}
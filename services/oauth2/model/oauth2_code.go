@@ -14,6 +14,9 @@ type OAuth2AuthCode struct {
 	ExpiresAt   time.Time `gorm:"column:expires_at;index"`
 	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
 	Used        bool      `gorm:"column:used;default:false"`
+
+	CodeChallenge       string `gorm:"column:code_challenge;size:128"`      // PKCE code_challenge
+	CodeChallengeMethod string `gorm:"column:code_challenge_method;size:8"` // PKCE 方法，仅支持 S256
 }
 
 // TableName 返回表名
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// OAuth2Consent 用户对第三方应用的授权记录，再次授权相同或更小的作用域时无需重新确认
+type OAuth2Consent struct {
+	model.BaseModel
+	UserId   string `json:"userId" gorm:"column:user_id;size:36;uniqueIndex:idx_unique_t_oauth2_consent_user_client"`     // 用户ID
+	ClientId string `json:"clientId" gorm:"column:client_id;size:64;uniqueIndex:idx_unique_t_oauth2_consent_user_client"` // 第三方应用ID
+	Scope    string `json:"scope" gorm:"column:scope;size:512"`                                                           // 已授权的作用域，空格分隔
+}
+
+// TableName 指定表名
+func (OAuth2Consent) TableName() string {
+	return "t_oauth2_consent"
+}
+
+// Covers 判断已授权的作用域是否包含请求的全部作用域
+func (c *OAuth2Consent) Covers(scopes []string) bool {
+	granted := make(map[string]bool)
+	for _, scope := range strings.Fields(c.Scope) {
+		granted[scope] = true
+	}
+	for _, scope := range scopes {
+		if !granted[scope] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+)
+
+// OAuth2Session 登录会话，一次登录对应一个会话，刷新令牌时会话保持不变
+type OAuth2Session struct {
+	model.BaseModel
+	UserId            string     `json:"userId" gorm:"column:user_id;size:36;index"`                 // 用户ID
+	TokenId           string     `json:"tokenId" gorm:"column:token_id;size:36;index"`               // 当前有效的令牌ID
+	Method            string     `json:"method" gorm:"column:method;size:32"`                        // 登录方式：password、google、github等
+	Device            string     `json:"device" gorm:"column:device;size:128"`                       // 设备名称，如 Chrome 120 on Windows 10
+	DeviceType        string     `json:"deviceType" gorm:"column:device_type;size:16"`               // 设备类型：desktop、mobile
+	DeviceFingerprint string     `json:"deviceFingerprint" gorm:"column:device_fingerprint;size:64"` // 设备指纹
+	IP                string     `json:"ip" gorm:"column:ip;size:64"`                                // 登录IP
+	UserAgent         string     `json:"userAgent" gorm:"column:user_agent;size:512"`                // 原始User-Agent
+	LastSeenAt        time.Time  `json:"lastSeenAt" gorm:"column:last_seen_at"`                      // 最近活跃时间
+	ExpiresAt         time.Time  `json:"expiresAt" gorm:"column:expires_at;index"`                   // 会话过期时间（刷新令牌过期时间）
+	Revoked           bool       `json:"revoked" gorm:"column:revoked;default:false"`                // 是否已撤销
+	RevokedAt         *time.Time `json:"revokedAt" gorm:"column:revoked_at"`                         // 撤销时间
+}
+
+// TableName 指定表名
+func (OAuth2Session) TableName() string {
+	return "t_oauth2_session"
+}
+
+// ToProto 转换为proto，current 表示是否为发起请求的会话
+func (s *OAuth2Session) ToProto(current bool) *pb.Session {
+	session := &pb.Session{
+		Id:         s.Id,
+		Method:     s.Method,
+		Device:     s.Device,
+		DeviceType: s.DeviceType,
+		Ip:         s.IP,
+		UserAgent:  s.UserAgent,
+		CreatedAt:  uint64(s.CreatedAt.UnixMilli()),
+		ExpiresAt:  uint64(s.ExpiresAt.UnixMilli()),
+		Current:    current,
+	}
+	if !s.LastSeenAt.IsZero() {
+		session.LastSeenAt = uint64(s.LastSeenAt.UnixMilli())
+	}
+	return session
+}
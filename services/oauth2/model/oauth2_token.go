@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -20,6 +21,9 @@ type OAuth2Token struct {
 	Device          string            `json:"device" gorm:"column:device;type:varchar(255)"`       // 设备信息
 	ExpiresAt       time.Time         `json:"expires_at"`                                          // 过期时间
 	Revoked         bool              `json:"revoked" gorm:"column:revoked;default:false"`         // 是否已撤销
+	SessionId       string            `json:"session_id" gorm:"column:session_id;size:36;index"`   // 所属登录会话ID，刷新令牌时沿用
+	ClientId        string            `json:"client_id" gorm:"column:client_id;size:64;index"`     // 第三方应用ID，第一方登录为空
+	Scope           string            `json:"scope" gorm:"column:scope;size:512"`                  // 第三方应用被授予的作用域，空格分隔
 }
 
 // TableName 指定表名
@@ -29,9 +33,10 @@ func (OAuth2Token) TableName() string {
 
 // TokenRequest 生成令牌请求
 type TokenRequest struct {
-	Username string // 用户名
-	Password string // 密码
-	Device   string // 设备信息
+	Username string       // 用户名
+	Password string       // 密码
+	Device   string       // 设备信息
+	Client   *LoginClient // 登录客户端信息，用于记录会话与登录历史
 }
 
 // GetUsername 获取用户名
@@ -68,6 +73,10 @@ type TokenResponse struct {
 	ExpiresIn    uint64 // 过期时间（秒）
 	UserId       string // 用户ID
 	Scope        string // 作用域
+
+	MFARequired       bool   // 需要两步验证，此时不签发令牌
+	MFAToken          string // 两步验证挑战令牌
+	MFAEnrollRequired bool   // 角色强制两步验证但尚未绑定
 }
 
 // SetExpiresAt 设置过期时间
@@ -88,6 +97,8 @@ type Claims struct {
 	Roles       []string     `json:"roles"`
 	Device      string       `json:"device"`
 	ServiceInfo *ServiceInfo `json:"service_info,omitempty"` // 服务信息，仅服务令牌使用
+	ClientId    string       `json:"client_id,omitempty"`    // 第三方应用ID，仅第三方令牌使用
+	Scope       string       `json:"scope,omitempty"`        // 第三方应用被授予的作用域，空格分隔
 	jwt.StandardClaims
 }
 
@@ -121,6 +132,16 @@ func (c *Claims) GetServiceName() string {
 	return c.ServiceInfo.ServiceName
 }
 
+// GetClientId 获取第三方应用ID
+func (c *Claims) GetClientId() string {
+	return c.ClientId
+}
+
+// GetScopes 获取第三方应用被授予的作用域
+func (c *Claims) GetScopes() []string {
+	return strings.Fields(c.Scope)
+}
+
 // GetAuthCodeRequest 获取授权码请求
 type GetAuthCodeRequest struct {
 	pb.GetAuthCodeRequest
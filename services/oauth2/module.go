@@ -7,15 +7,20 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/config"
 	"github.com/yb2020/odoc/internal/database"
+	"github.com/yb2020/odoc/pkg/cache"
+	baseDao "github.com/yb2020/odoc/pkg/dao"
 	"github.com/yb2020/odoc/pkg/eventbus"
 	"github.com/yb2020/odoc/pkg/i18n"
 	"github.com/yb2020/odoc/pkg/logging"
 	"github.com/yb2020/odoc/pkg/middleware"
+	"github.com/yb2020/odoc/pkg/ratelimit"
 	"github.com/yb2020/odoc/pkg/registry"
 	"github.com/yb2020/odoc/pkg/scheduler"
 	"github.com/yb2020/odoc/pkg/utils"
 	"github.com/yb2020/odoc/services/oauth2/api"
 	"github.com/yb2020/odoc/services/oauth2/dao"
+	"github.com/yb2020/odoc/services/oauth2/job"
+	"github.com/yb2020/odoc/services/oauth2/provider"
 	"github.com/yb2020/odoc/services/oauth2/service"
 	userEvent "github.com/yb2020/odoc/services/user/event"
 	userService "github.com/yb2020/odoc/services/user/service"
@@ -26,40 +31,52 @@ import (
 // 编译时类型检查：确保 OAuth2Module 实现了 registry.Module 接口
 var _ registry.Module = (*OAuth2Module)(nil)
 
+// 编译时类型检查：确保 OAuth2Module 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*OAuth2Module)(nil)
+
 // Module 导出模块实例，用于自动发现和注册
 var Module = &OAuth2Module{}
 
 // OAuth2Module 实现OAuth2模块，实现registry.Module接口
 type OAuth2Module struct {
-	API                *api.OAuth2API
-	OAuth2Service      service.OAuth2Service
-	GoogleOAuthAPI     *api.GoogleOAuthAPI
-	GoogleOAuthService *service.GoogleOAuthService
-	db                 *gorm.DB
-	config             *config.Config
-	logger             logging.Logger
-	tracer             opentracing.Tracer
-	localizer          i18n.Localizer
-	userService        *userService.UserService
-	authMiddleware     *middleware.AuthMiddleware
-	redis              database.RedisClient
-	RSAUtil            *utils.RSAUtil
-	eventBus           *eventbus.EventBus
+	API                  *api.OAuth2API
+	OAuth2Service        service.OAuth2Service
+	ExternalLoginAPI     *api.ExternalLoginAPI
+	ExternalLoginService *service.ExternalLoginService
+	SessionAPI           *api.SessionAPI
+	SessionService       *service.SessionService
+	MFAAPI               *api.MFAAPI
+	MFAService           *service.MFAService
+	AuthorizationAPI     *api.AuthorizationAPI
+	AuthorizationService *service.AuthorizationService
+	db                   *gorm.DB
+	config               *config.Config
+	logger               logging.Logger
+	tracer               opentracing.Tracer
+	localizer            i18n.Localizer
+	userService          *userService.UserService
+	identityService      *userService.UserIdentityService
+	authMiddleware       *middleware.AuthMiddleware
+	redis                database.RedisClient
+	RSAUtil              *utils.RSAUtil
+	eventBus             *eventbus.EventBus
 }
 
 // NewOAuth2Module 创建一个新的OAuth2模块实例
 func NewOAuth2Module(db *gorm.DB, redis database.RedisClient, config *config.Config, logger logging.Logger,
-	tracer opentracing.Tracer, localizer i18n.Localizer, userService *userService.UserService, RSAUtil *utils.RSAUtil, eventBus *eventbus.EventBus) *OAuth2Module {
+	tracer opentracing.Tracer, localizer i18n.Localizer, userService *userService.UserService,
+	identityService *userService.UserIdentityService, RSAUtil *utils.RSAUtil, eventBus *eventbus.EventBus) *OAuth2Module {
 	return &OAuth2Module{
-		db:          db,
-		config:      config,
-		logger:      logger,
-		tracer:      tracer,
-		localizer:   localizer,
-		userService: userService,
-		redis:       redis,
-		RSAUtil:     RSAUtil,
-		eventBus:    eventBus,
+		db:              db,
+		config:          config,
+		logger:          logger,
+		tracer:          tracer,
+		localizer:       localizer,
+		userService:     userService,
+		identityService: identityService,
+		redis:           redis,
+		RSAUtil:         RSAUtil,
+		eventBus:        eventBus,
 	}
 }
 
@@ -83,29 +100,79 @@ func (m *OAuth2Module) Initialize() error {
 	tokenDAO := dao.NewTokenDAO(m.db, m.redis, m.logger, m.config)
 	clientDAO := dao.NewOAuth2ClientsDAO(m.db, m.logger, m.config)
 	authCodeDAO := dao.NewAuthCodeDAO(m.db, m.logger, m.config)
+	sessionDAO := dao.NewOAuth2SessionDAO(m.db, m.logger)
+	loginHistoryDAO := dao.NewLoginHistoryDAO(m.db, m.logger)
+	userMFADAO := dao.NewUserMFADAO(m.db, m.logger)
+	recoveryCodeDAO := dao.NewMFARecoveryCodeDAO(m.db, m.logger)
+	trustedDeviceDAO := dao.NewMFATrustedDeviceDAO(m.db, m.logger)
+	rolePolicyDAO := dao.NewMFARolePolicyDAO(m.db, m.logger)
+	consentDAO := dao.NewOAuth2ConsentDAO(m.db, m.logger)
+
+	// 创建登录会话服务
+	m.SessionService = service.NewSessionService(sessionDAO, loginHistoryDAO, tokenDAO, m.eventBus, &m.config.Session, m.logger, m.tracer)
+
+	// 创建两步验证服务，未配置Redis时不限制验证码校验频率
+	stateCache := cache.NewCache(m.logger, 0, "oauth2")
+	var rateLimiterService *ratelimit.RateLimiterService
+	if m.redis != nil {
+		rateLimiterService = ratelimit.NewRateLimiterService(m.redis, m.logger)
+	}
+	m.MFAService = service.NewMFAService(m.config, userMFADAO, recoveryCodeDAO, trustedDeviceDAO, rolePolicyDAO,
+		baseDao.NewTransactionManager(m.db), stateCache, rateLimiterService, m.logger, m.tracer)
 
 	// 创建OAuth2服务
 	m.OAuth2Service = service.NewOAuth2Service(tokenDAO, m.userService, m.logger,
-		m.tracer, m.config, m.localizer, clientDAO, authCodeDAO)
+		m.tracer, m.config, m.localizer, clientDAO, authCodeDAO, m.SessionService, m.MFAService)
+
+	// 创建面向第三方应用的授权服务器，未配置RSA私钥时不启用
+	authorizationService, err := service.NewAuthorizationService(m.config, clientDAO, authCodeDAO, consentDAO, tokenDAO,
+		m.userService, m.logger, m.tracer)
+	if err != nil {
+		m.logger.Warn("msg", "第三方应用授权服务器未启用", "error", err.Error())
+	} else {
+		m.AuthorizationService = authorizationService
+	}
 
 	// 订阅用户删除事件
 	m.eventBus.Subscribe(userEvent.UserDeletedEvent, func(ctx context.Context, event eventbus.Event) {
 		if userId, ok := event.Data.(string); ok {
 			m.OAuth2Service.RevokeUserTokens(ctx, userId)
+			if err := m.MFAService.RemoveUserMFA(ctx, userId); err != nil {
+				m.logger.Error("msg", "用户删除后清理两步验证失败", "userId", userId, "error", err.Error())
+			}
+			if m.AuthorizationService != nil {
+				if err := m.AuthorizationService.RemoveUserConsents(ctx, userId); err != nil {
+					m.logger.Error("msg", "用户删除后清理第三方应用授权失败", "userId", userId, "error", err.Error())
+				}
+			}
 		}
 	})
 
-	// 创建Google OAuth2服务
-	googleOAuthSvc, err := service.NewGoogleOAuthService(m.config, m.logger, *m.userService)
-	if err != nil {
-		m.logger.Error("Failed to initialize Google OAuth service", "error", err)
-		return err
-	}
-	m.GoogleOAuthService = googleOAuthSvc
+	// 订阅用户密码修改事件，撤销该用户已有的所有令牌
+	m.eventBus.Subscribe(userEvent.UserPasswordChangedEvent, func(ctx context.Context, event eventbus.Event) {
+		if userId, ok := event.Data.(string); ok {
+			if err := m.OAuth2Service.RevokeUserTokens(ctx, userId); err != nil {
+				m.logger.Error("msg", "密码修改后撤销用户令牌失败", "userId", userId, "error", err.Error())
+			}
+			// 修改密码后已记住的设备需要重新两步验证
+			if err := m.MFAService.RevokeTrustedDevices(ctx, userId); err != nil {
+				m.logger.Error("msg", "密码修改后取消记住设备失败", "userId", userId, "error", err.Error())
+			}
+		}
+	})
+
+	// 创建外部身份登录服务（OIDC / Google / GitHub / ORCID）
+	providerRegistry := provider.NewRegistry(m.config, m.logger)
+	m.ExternalLoginService = service.NewExternalLoginService(m.config, providerRegistry, stateCache, m.identityService, m.logger, m.tracer)
 
 	// 创建API层
 	m.API = api.NewOAuth2API(m.logger, m.tracer, m.localizer, m.config, m.OAuth2Service, m.RSAUtil)
-	m.GoogleOAuthAPI = api.NewGoogleOAuthAPI(m.logger, m.GoogleOAuthService, m.OAuth2Service, m.config)
+	m.ExternalLoginAPI = api.NewExternalLoginAPI(m.logger, m.ExternalLoginService, m.OAuth2Service, m.localizer, m.config)
+	m.SessionAPI = api.NewSessionAPI(m.logger, m.tracer, m.SessionService, m.OAuth2Service)
+	m.MFAAPI = api.NewMFAAPI(m.logger, m.tracer, m.localizer, m.config, m.MFAService, m.OAuth2Service)
+	if m.AuthorizationService != nil {
+		m.AuthorizationAPI = api.NewAuthorizationAPI(m.logger, m.tracer, m.localizer, m.config, m.AuthorizationService)
+	}
 
 	return nil
 }
@@ -123,8 +190,13 @@ func (m *OAuth2Module) RegisterGRPC(server *grpc.Server) {
 
 // RegisterJobSchedulers 注册Job定时任务
 func (m *OAuth2Module) RegisterJobSchedulers(scheduler *scheduler.Scheduler) {
-	// TODO: 实现Job定时任务注册
-	m.logger.Debug("msg", "OAuth2模块没有Job定时任务，跳过注册")
+	if scheduler == nil {
+		m.logger.Debug("msg", "调度器未启用，OAuth2模块跳过Job注册")
+		return
+	}
+	m.logger.Debug("msg", "OAuth2模块注册Job定时任务")
+	retentionJob := job.NewLoginHistoryRetentionJob(m.logger, m.config, m.SessionService)
+	scheduler.RegisterJobs(retentionJob)
 }
 
 // SetAuthMiddleware 设置认证中间件
@@ -132,6 +204,23 @@ func (m *OAuth2Module) SetAuthMiddleware(authMiddleware *middleware.AuthMiddlewa
 	m.authMiddleware = authMiddleware
 }
 
+// DeleteUserData 注销账号时撤销用户的全部令牌，删除会话、登录历史、两步验证和第三方应用授权
+func (m *OAuth2Module) DeleteUserData(ctx context.Context, userId string) error {
+	if err := m.OAuth2Service.RevokeUserTokens(ctx, userId); err != nil {
+		return err
+	}
+	if err := m.SessionService.RemoveUserSessions(ctx, userId); err != nil {
+		return err
+	}
+	if err := m.MFAService.RemoveUserMFA(ctx, userId); err != nil {
+		return err
+	}
+	if m.AuthorizationService == nil {
+		return nil
+	}
+	return m.AuthorizationService.RemoveUserConsents(ctx, userId)
+}
+
 // RegisterRoutes 注册路由
 func (m *OAuth2Module) RegisterRoutes(r *gin.Engine) {
 	// 注册路由
@@ -143,9 +232,18 @@ func (m *OAuth2Module) RegisterRoutes(r *gin.Engine) {
 	apiGroup.POST("/sign_in", m.API.SignInAuthCodeHandler)
 	apiGroup.POST("/validate", m.API.ValidateHandler)
 
-	// 公开路由，不需要认证 Google OAuth2认证
-	apiGroup.GET("/google/login", m.GoogleOAuthAPI.LoginHandler)
-	apiGroup.GET("/google/callback", m.GoogleOAuthAPI.CallbackHandler)
+	// 公开路由，不需要认证 外部身份登录
+	apiGroup.GET("/external/providers", m.ExternalLoginAPI.ListProviders)
+	apiGroup.GET("/external/:provider/login", m.ExternalLoginAPI.LoginHandler)
+	apiGroup.GET("/external/:provider/callback", m.ExternalLoginAPI.CallbackHandler)
+	// 兼容旧版 Google 登录地址，已在 Google 控制台登记的回调地址无需修改
+	apiGroup.GET("/google/login", m.ExternalLoginAPI.GoogleLoginHandler)
+	apiGroup.GET("/google/callback", m.ExternalLoginAPI.GoogleCallbackHandler)
+
+	// 公开路由，不需要认证 登录第二步（两步验证）
+	apiGroup.POST("/mfa/verify", m.MFAAPI.Verify)
+	apiGroup.POST("/mfa/challenge/enroll", m.MFAAPI.ChallengeEnroll)
+	apiGroup.POST("/mfa/challenge/activate", m.MFAAPI.ChallengeActivate)
 
 	// 需要认证的路由
 	authRouter := apiGroup.Group("")
@@ -153,6 +251,57 @@ func (m *OAuth2Module) RegisterRoutes(r *gin.Engine) {
 	{
 		authRouter.POST("/refresh", m.API.RefreshHandler)
 		authRouter.POST("/sign_out", m.API.SignOutHandler)
+		authRouter.GET("/external/:provider/link", m.ExternalLoginAPI.LinkHandler)
+
+		// 登录会话与登录历史
+		authRouter.GET("/sessions/list", m.SessionAPI.ListSessions)
+		authRouter.POST("/sessions/revoke", m.SessionAPI.RevokeSession)
+		authRouter.POST("/sessions/revokeOthers", m.SessionAPI.RevokeOtherSessions)
+		authRouter.GET("/loginHistory/list", m.SessionAPI.ListLoginHistory)
+
+		// 两步验证
+		authRouter.GET("/mfa/status", m.MFAAPI.Status)
+		authRouter.POST("/mfa/enroll", m.MFAAPI.BeginEnrollment)
+		authRouter.POST("/mfa/activate", m.MFAAPI.ActivateEnrollment)
+		authRouter.POST("/mfa/disable", m.MFAAPI.Disable)
+		authRouter.POST("/mfa/recoveryCodes/regenerate", m.MFAAPI.RegenerateRecoveryCodes)
+		authRouter.POST("/mfa/trustedDevices/revoke", m.MFAAPI.RevokeTrustedDevices)
+	}
+
+	// 管理员路由，供客服排查账号登录问题及管理第三方应用
+	adminGroup := r.Group("/api/admin/oauth2")
+	adminGroup.Use(m.authMiddleware.AuthRequired())
+	{
+		adminGroup.GET("/sessions/list", m.SessionAPI.AdminListSessions)
+		adminGroup.POST("/sessions/revoke", m.SessionAPI.AdminRevokeSession)
+		adminGroup.GET("/loginHistory/list", m.SessionAPI.AdminListLoginHistory)
+		adminGroup.GET("/mfa/policy/list", m.MFAAPI.AdminListRolePolicies)
+		adminGroup.POST("/mfa/policy/set", m.MFAAPI.AdminSetRolePolicy)
+		adminGroup.POST("/mfa/reset", m.MFAAPI.AdminResetUserMFA)
+	}
+
+	// 第三方应用授权服务器，未配置RSA私钥时不注册
+	if m.AuthorizationAPI != nil {
+		// 公开路由，不需要认证 协议端点
+		apiGroup.GET("/authorize", m.AuthorizationAPI.Authorize)
+		apiGroup.POST("/token", m.AuthorizationAPI.Token)
+		apiGroup.POST("/introspect", m.AuthorizationAPI.Introspect)
+		apiGroup.POST("/revoke", m.AuthorizationAPI.Revoke)
+		r.GET("/.well-known/oauth-authorization-server", m.AuthorizationAPI.Metadata)
+		r.GET("/.well-known/jwks.json", m.AuthorizationAPI.JWKS)
+
+		// 授权确认与已授权应用管理
+		authRouter.GET("/consent", m.AuthorizationAPI.GetConsent)
+		authRouter.POST("/consent/submit", m.AuthorizationAPI.SubmitConsent)
+		authRouter.GET("/authorizedApps/list", m.AuthorizationAPI.ListAuthorizedApps)
+		authRouter.POST("/authorizedApps/revoke", m.AuthorizationAPI.RevokeAuthorizedApp)
+
+		// 管理员管理第三方应用
+		adminGroup.GET("/clients/list", m.AuthorizationAPI.AdminListClients)
+		adminGroup.POST("/clients/create", m.AuthorizationAPI.AdminCreateClient)
+		adminGroup.POST("/clients/update", m.AuthorizationAPI.AdminUpdateClient)
+		adminGroup.POST("/clients/rotateSecret", m.AuthorizationAPI.AdminRotateClientSecret)
+		adminGroup.POST("/clients/delete", m.AuthorizationAPI.AdminDeleteClient)
 	}
 
 	// 服务令牌路由
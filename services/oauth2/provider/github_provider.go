@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"context"
+	"strconv"
+
+	"golang.org/x/oauth2"
+	githuboauth "golang.org/x/oauth2/github"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	usermodel "github.com/yb2020/odoc/services/user/model"
+)
+
+const githubAPIBaseURL = "https://api.github.com"
+
+// githubUser GitHub /user 接口返回的用户信息
+type githubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// githubEmail GitHub /user/emails 接口返回的邮箱
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// GitHubProvider GitHub OAuth App 适配器（GitHub不支持OIDC登录）
+type GitHubProvider struct {
+	name         string
+	displayName  string
+	redirectPath string
+	config       *oauth2.Config
+	logger       logging.Logger
+}
+
+// NewGitHubProvider 创建GitHub提供方
+func NewGitHubProvider(cfg config.ExternalProviderConfig, logger logging.Logger) *GitHubProvider {
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"read:user", "user:email"}
+	}
+	return &GitHubProvider{
+		name:         cfg.Name,
+		displayName:  cfg.DisplayName,
+		redirectPath: cfg.RedirectPath,
+		config: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Scopes:       scopes,
+			Endpoint:     githuboauth.Endpoint,
+		},
+		logger: logger,
+	}
+}
+
+// Name 提供方标识
+func (p *GitHubProvider) Name() string {
+	return p.name
+}
+
+// DisplayName 展示名称
+func (p *GitHubProvider) DisplayName() string {
+	return p.displayName
+}
+
+// Type 提供方类型
+func (p *GitHubProvider) Type() string {
+	return TypeGitHub
+}
+
+// RedirectPath 回调路径
+func (p *GitHubProvider) RedirectPath() string {
+	return p.redirectPath
+}
+
+// AuthCodeURL 生成授权地址
+func (p *GitHubProvider) AuthCodeURL(ctx context.Context, session *AuthSession) (string, error) {
+	oauthConfig := *p.config
+	oauthConfig.RedirectURL = session.RedirectURL
+	return oauthConfig.AuthCodeURL(session.State, oauth2.S256ChallengeOption(session.Verifier)), nil
+}
+
+// Exchange 换取令牌并读取GitHub用户信息，只使用已验证的邮箱
+func (p *GitHubProvider) Exchange(ctx context.Context, code string, session *AuthSession) (*usermodel.ExternalIdentity, error) {
+	oauthConfig := *p.config
+	oauthConfig.RedirectURL = session.RedirectURL
+
+	token, err := oauthConfig.Exchange(context.WithValue(ctx, oauth2.HTTPClient, httpClient), code, oauth2.VerifierOption(session.Verifier))
+	if err != nil {
+		p.logger.Error("msg", "GitHub授权码换取令牌失败", "error", err.Error())
+		return nil, errors.BizWrap("oauth2.error.external_exchange_failed", err)
+	}
+
+	var user githubUser
+	if err := getJSON(ctx, githubAPIBaseURL+"/user", token.AccessToken, &user); err != nil {
+		p.logger.Error("msg", "获取GitHub用户信息失败", "error", err.Error())
+		return nil, errors.BizWrap("oauth2.error.external_userinfo_failed", err)
+	}
+	if user.ID == 0 {
+		return nil, errors.Biz("oauth2.error.external_userinfo_failed")
+	}
+
+	var emails []githubEmail
+	if err := getJSON(ctx, githubAPIBaseURL+"/user/emails", token.AccessToken, &emails); err != nil {
+		// 未授予 user:email 时无法读取邮箱，交由上层判断是否允许登录
+		p.logger.Warn("msg", "获取GitHub用户邮箱失败", "error", err.Error())
+	}
+
+	name := user.Name
+	if name == "" {
+		name = user.Login
+	}
+	identity := &usermodel.ExternalIdentity{
+		Provider: p.name,
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     name,
+		Avatar:   user.AvatarURL,
+	}
+	if email := pickGitHubEmail(emails); email != "" {
+		identity.Email = email
+		identity.EmailVerified = true
+	}
+	return identity, nil
+}
+
+// pickGitHubEmail 优先选择已验证的主邮箱，其次任意已验证邮箱
+func pickGitHubEmail(emails []githubEmail) string {
+	verified := ""
+	for _, e := range emails {
+		if !e.Verified {
+			continue
+		}
+		if e.Primary {
+			return e.Email
+		}
+		if verified == "" {
+			verified = e.Email
+		}
+	}
+	return verified
+}
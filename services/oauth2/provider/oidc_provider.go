@@ -0,0 +1,458 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	usermodel "github.com/yb2020/odoc/services/user/model"
+)
+
+const (
+	// discoveryTTL 发现文档缓存时间
+	discoveryTTL = 24 * time.Hour
+	// jwksRefreshInterval 遇到未知kid时两次刷新JWKS的最小间隔，防止被恶意令牌放大请求
+	jwksRefreshInterval = time.Minute
+	// clockSkew 校验ID Token时间时允许的时钟偏差
+	clockSkew = 2 * time.Minute
+)
+
+// idTokenSigningMethods 允许的ID Token签名算法，不接受none与HMAC
+var idTokenSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// oidcDiscovery OIDC发现文档中使用到的字段
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// jsonWebKey JWKS中的单个公钥
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// idTokenClaims ID Token与userinfo中使用到的声明
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string      `json:"nonce"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"` // 部分IdP以字符串形式返回
+	Name              string      `json:"name"`
+	GivenName         string      `json:"given_name"`
+	FamilyName        string      `json:"family_name"`
+	PreferredUsername string      `json:"preferred_username"`
+	Picture           string      `json:"picture"`
+}
+
+// Valid 时间类声明的校验放在verifyIDToken中统一处理（带时钟偏差）
+func (c *idTokenClaims) Valid() error {
+	return nil
+}
+
+// displayName 按优先级取显示名称
+func (c *idTokenClaims) displayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	if full := strings.TrimSpace(c.GivenName + " " + c.FamilyName); full != "" {
+		return full
+	}
+	return c.PreferredUsername
+}
+
+// emailVerified 解析email_verified声明
+func (c *idTokenClaims) emailVerified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+// OIDCProvider 通用的OpenID Connect提供方，支持发现、PKCE与nonce校验
+type OIDCProvider struct {
+	name          string
+	displayName   string
+	providerType  string
+	issuer        string
+	issuerAliases []string // 部分IdP（如Google）签发的iss与发现文档不完全一致
+	clientID      string
+	clientSecret  string
+	scopes        []string
+	redirectPath  string
+	trustEmail    bool
+	logger        logging.Logger
+
+	mu              sync.RWMutex
+	discovery       *oidcDiscovery
+	discoveredAt    time.Time
+	keys            map[string]interface{}
+	keysRefreshedAt time.Time
+}
+
+// NewOIDCProvider 创建OIDC提供方，发现文档在首次使用时加载
+func NewOIDCProvider(cfg config.ExternalProviderConfig, logger logging.Logger, issuerAliases ...string) *OIDCProvider {
+	scopes := cfg.Scopes
+	if !containsString(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	providerType := cfg.Type
+	if providerType == "" {
+		providerType = TypeOIDC
+	}
+	return &OIDCProvider{
+		name:          cfg.Name,
+		displayName:   cfg.DisplayName,
+		providerType:  providerType,
+		issuer:        strings.TrimSuffix(cfg.Issuer, "/"),
+		issuerAliases: issuerAliases,
+		clientID:      cfg.ClientID,
+		clientSecret:  cfg.ClientSecret,
+		scopes:        scopes,
+		redirectPath:  cfg.RedirectPath,
+		trustEmail:    cfg.TrustEmail,
+		logger:        logger,
+	}
+}
+
+// Name 提供方标识
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+// DisplayName 展示名称
+func (p *OIDCProvider) DisplayName() string {
+	return p.displayName
+}
+
+// Type 提供方类型
+func (p *OIDCProvider) Type() string {
+	return p.providerType
+}
+
+// RedirectPath 回调路径
+func (p *OIDCProvider) RedirectPath() string {
+	return p.redirectPath
+}
+
+// AuthCodeURL 生成授权地址，携带state、nonce与PKCE challenge
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, session *AuthSession) (string, error) {
+	oauthConfig, err := p.oauthConfig(ctx, session.RedirectURL)
+	if err != nil {
+		return "", err
+	}
+	return oauthConfig.AuthCodeURL(session.State,
+		oauth2.S256ChallengeOption(session.Verifier),
+		oauth2.SetAuthURLParam("nonce", session.Nonce),
+	), nil
+}
+
+// Exchange 换取令牌，校验ID Token并返回外部身份
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, session *AuthSession) (*usermodel.ExternalIdentity, error) {
+	oauthConfig, err := p.oauthConfig(ctx, session.RedirectURL)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauthConfig.Exchange(context.WithValue(ctx, oauth2.HTTPClient, httpClient), code, oauth2.VerifierOption(session.Verifier))
+	if err != nil {
+		p.logger.Error("msg", "OIDC授权码换取令牌失败", "provider", p.name, "error", err.Error())
+		return nil, errors.BizWrap("oauth2.error.external_exchange_failed", err)
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, errors.Biz("oauth2.error.external_id_token_invalid")
+	}
+	claims, err := p.verifyIDToken(ctx, rawIDToken, session.Nonce)
+	if err != nil {
+		p.logger.Warn("msg", "OIDC ID Token校验失败", "provider", p.name, "error", err.Error())
+		return nil, errors.BizWrap("oauth2.error.external_id_token_invalid", err)
+	}
+
+	// ID Token中缺少邮箱或名称时，从userinfo端点补充
+	if claims.Email == "" || claims.displayName() == "" {
+		p.fillFromUserinfo(ctx, token, claims)
+	}
+
+	return &usermodel.ExternalIdentity{
+		Provider:      p.name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.Email != "" && (claims.emailVerified() || p.trustEmail),
+		Name:          claims.displayName(),
+		Avatar:        claims.Picture,
+	}, nil
+}
+
+// oauthConfig 根据发现文档构造oauth2配置
+func (p *OIDCProvider) oauthConfig(ctx context.Context, redirectURL string) (*oauth2.Config, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Config{
+		ClientID:     p.clientID,
+		ClientSecret: p.clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       p.scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+	}, nil
+}
+
+// getDiscovery 获取并缓存发现文档
+func (p *OIDCProvider) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.RLock()
+	if p.discovery != nil && time.Since(p.discoveredAt) < discoveryTTL {
+		discovery := p.discovery
+		p.mu.RUnlock()
+		return discovery, nil
+	}
+	p.mu.RUnlock()
+
+	var discovery oidcDiscovery
+	if err := getJSON(ctx, p.issuer+"/.well-known/openid-configuration", "", &discovery); err != nil {
+		p.logger.Error("msg", "加载OIDC发现文档失败", "provider", p.name, "issuer", p.issuer, "error", err.Error())
+		return nil, errors.BizWrap("oauth2.error.external_discovery_failed", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.issuer {
+		p.logger.Error("msg", "OIDC发现文档issuer不匹配", "provider", p.name, "expected", p.issuer, "actual", discovery.Issuer)
+		return nil, errors.Biz("oauth2.error.external_discovery_failed")
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksURI == "" {
+		return nil, errors.Biz("oauth2.error.external_discovery_failed")
+	}
+
+	p.mu.Lock()
+	p.discovery = &discovery
+	p.discoveredAt = time.Now()
+	p.mu.Unlock()
+	return &discovery, nil
+}
+
+// verifyIDToken 校验ID Token的签名、iss、aud、exp与nonce
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, rawIDToken, nonce string) (*idTokenClaims, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.getKey(ctx, kid)
+	}, jwt.WithValidMethods(idTokenSigningMethods))
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.validIssuer(claims.Issuer) {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if !claims.VerifyAudience(p.clientID, true) {
+		return nil, fmt.Errorf("audience does not contain client id")
+	}
+	now := time.Now()
+	if claims.ExpiresAt == nil || now.After(claims.ExpiresAt.Time.Add(clockSkew)) {
+		return nil, fmt.Errorf("id token expired")
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(claims.NotBefore.Time) {
+		return nil, fmt.Errorf("id token not valid yet")
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("missing subject")
+	}
+	return claims, nil
+}
+
+// validIssuer 校验iss声明
+func (p *OIDCProvider) validIssuer(issuer string) bool {
+	issuer = strings.TrimSuffix(issuer, "/")
+	return issuer == p.issuer || containsString(p.issuerAliases, issuer)
+}
+
+// getKey 根据kid获取签名公钥，未命中时刷新JWKS
+func (p *OIDCProvider) getKey(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.RLock()
+	key, ok := p.lookupKey(kid)
+	canRefresh := time.Since(p.keysRefreshedAt) >= jwksRefreshInterval
+	p.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if !canRefresh {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, discovery.JwksURI, "", &jwks); err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, err := jwk.publicKey()
+		if err != nil {
+			p.logger.Warn("msg", "跳过无法解析的JWK", "provider", p.name, "kid", jwk.Kid, "error", err.Error())
+			continue
+		}
+		keys[jwk.Kid] = publicKey
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysRefreshedAt = time.Now()
+	key, ok = p.lookupKey(kid)
+	p.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// lookupKey 查找公钥，令牌未携带kid且只有一个公钥时直接使用该公钥，调用方需持有锁
+func (p *OIDCProvider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// fillFromUserinfo 使用userinfo端点补充邮箱与名称，sub不一致时忽略
+func (p *OIDCProvider) fillFromUserinfo(ctx context.Context, token *oauth2.Token, claims *idTokenClaims) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil || discovery.UserinfoEndpoint == "" {
+		return
+	}
+	var userinfo idTokenClaims
+	if err := getJSON(ctx, discovery.UserinfoEndpoint, token.AccessToken, &userinfo); err != nil {
+		p.logger.Warn("msg", "获取OIDC userinfo失败", "provider", p.name, "error", err.Error())
+		return
+	}
+	if userinfo.Subject != claims.Subject {
+		p.logger.Warn("msg", "OIDC userinfo的sub与ID Token不一致", "provider", p.name)
+		return
+	}
+	if claims.Email == "" {
+		claims.Email = userinfo.Email
+		claims.EmailVerified = userinfo.EmailVerified
+	}
+	if claims.Name == "" {
+		claims.Name = userinfo.displayName()
+	}
+	if claims.Picture == "" {
+		claims.Picture = userinfo.Picture
+	}
+}
+
+// publicKey 将JWK转换为RSA或EC公钥
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// getJSON 发起GET请求并解析JSON响应，accessToken不为空时携带Bearer令牌
+func getJSON(ctx context.Context, url, accessToken string, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s: %s %s", url, resp.Status, string(body))
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dest)
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
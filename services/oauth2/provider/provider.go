@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	usermodel "github.com/yb2020/odoc/services/user/model"
+)
+
+// 提供方类型
+const (
+	TypeOIDC   = "oidc"
+	TypeGoogle = "google"
+	TypeGitHub = "github"
+	TypeORCID  = "orcid"
+)
+
+// AuthSession 一次外部登录的会话参数，在跳转到提供方前生成，回调时原样取回
+type AuthSession struct {
+	State       string // 防CSRF的state
+	Nonce       string // OIDC nonce，回调时与ID Token中的nonce比对
+	Verifier    string // PKCE code_verifier
+	RedirectURL string // 本次登录使用的回调地址，换取token时必须与授权时一致
+}
+
+// ExternalIdentityProvider 外部身份提供方
+type ExternalIdentityProvider interface {
+	// Name 提供方标识，对应路由中的 :provider
+	Name() string
+	// DisplayName 展示名称
+	DisplayName() string
+	// Type 提供方类型
+	Type() string
+	// RedirectPath 回调路径
+	RedirectPath() string
+	// AuthCodeURL 生成跳转到提供方的授权地址
+	AuthCodeURL(ctx context.Context, session *AuthSession) (string, error)
+	// Exchange 使用授权码换取令牌并返回外部身份信息
+	Exchange(ctx context.Context, code string, session *AuthSession) (*usermodel.ExternalIdentity, error)
+}
+
+// httpClient 访问提供方接口使用的HTTP客户端
+var httpClient = &http.Client{Timeout: 10 * time.Second}
@@ -0,0 +1,125 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+const (
+	googleIssuer       = "https://accounts.google.com"
+	orcidIssuer        = "https://orcid.org"
+	orcidSandboxIssuer = "https://sandbox.orcid.org"
+)
+
+// Registry 已启用的外部身份提供方，按配置顺序排列
+type Registry struct {
+	providers []ExternalIdentityProvider
+	byName    map[string]ExternalIdentityProvider
+}
+
+// NewRegistry 根据配置创建提供方注册表，未配置client_id的提供方会被跳过
+// 兼容旧配置：external.providers 中未声明 google 时，使用 googleOAuth2 配置生成 google 提供方
+func NewRegistry(cfg *config.Config, logger logging.Logger) *Registry {
+	r := &Registry{byName: make(map[string]ExternalIdentityProvider)}
+
+	providerConfigs := append([]config.ExternalProviderConfig{}, cfg.OAuth2.External.Providers...)
+	if legacy := legacyGoogleConfig(cfg); legacy != nil && !hasProviderName(providerConfigs, legacy.Name) {
+		providerConfigs = append(providerConfigs, *legacy)
+	}
+
+	for _, providerCfg := range providerConfigs {
+		if providerCfg.Name == "" || providerCfg.ClientID == "" || providerCfg.ClientSecret == "" {
+			logger.Warn("msg", "外部身份提供方未配置完整，跳过", "name", providerCfg.Name, "type", providerCfg.Type)
+			continue
+		}
+		if _, exists := r.byName[providerCfg.Name]; exists {
+			logger.Warn("msg", "外部身份提供方名称重复，跳过", "name", providerCfg.Name)
+			continue
+		}
+		p, err := newProvider(providerCfg, logger)
+		if err != nil {
+			logger.Warn("msg", "外部身份提供方配置无效，跳过", "name", providerCfg.Name, "error", err.Error())
+			continue
+		}
+		r.providers = append(r.providers, p)
+		r.byName[p.Name()] = p
+		logger.Info("msg", "外部身份提供方已启用", "name", p.Name(), "type", p.Type())
+	}
+	return r
+}
+
+// Get 根据名称获取提供方
+func (r *Registry) Get(name string) (ExternalIdentityProvider, bool) {
+	p, ok := r.byName[name]
+	return p, ok
+}
+
+// List 返回全部已启用的提供方
+func (r *Registry) List() []ExternalIdentityProvider {
+	return r.providers
+}
+
+// newProvider 根据类型创建提供方
+func newProvider(cfg config.ExternalProviderConfig, logger logging.Logger) (ExternalIdentityProvider, error) {
+	if cfg.RedirectPath == "" {
+		cfg.RedirectPath = fmt.Sprintf("/api/oauth2/external/%s/callback", cfg.Name)
+	}
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = cfg.Name
+	}
+
+	switch cfg.Type {
+	case TypeOIDC, "":
+		if cfg.Issuer == "" {
+			return nil, fmt.Errorf("issuer is required for oidc provider")
+		}
+		return NewOIDCProvider(cfg, logger), nil
+	case TypeGoogle:
+		if cfg.Issuer == "" {
+			cfg.Issuer = googleIssuer
+		}
+		// Google签发的ID Token中iss可能不带协议头
+		return NewOIDCProvider(cfg, logger, "accounts.google.com"), nil
+	case TypeORCID:
+		if cfg.Issuer == "" {
+			cfg.Issuer = orcidIssuer
+			if cfg.Sandbox {
+				cfg.Issuer = orcidSandboxIssuer
+			}
+		}
+		return NewOIDCProvider(cfg, logger), nil
+	case TypeGitHub:
+		return NewGitHubProvider(cfg, logger), nil
+	}
+	return nil, fmt.Errorf("unsupported provider type %q", cfg.Type)
+}
+
+// legacyGoogleConfig 将旧版 googleOAuth2 配置转换为提供方配置
+func legacyGoogleConfig(cfg *config.Config) *config.ExternalProviderConfig {
+	googleCfg := cfg.OAuth2.GoogleOAuth2
+	if googleCfg.ClientID == "" {
+		return nil
+	}
+	// 旧版scope为userinfo.email/profile，OIDC下使用等价的标准scope
+	return &config.ExternalProviderConfig{
+		Name:         TypeGoogle,
+		Type:         TypeGoogle,
+		DisplayName:  "Google",
+		ClientID:     googleCfg.ClientID,
+		ClientSecret: googleCfg.ClientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+		RedirectPath: googleCfg.RedirectPath,
+	}
+}
+
+// hasProviderName 判断配置中是否已声明指定名称的提供方
+func hasProviderName(configs []config.ExternalProviderConfig, name string) bool {
+	for _, c := range configs {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
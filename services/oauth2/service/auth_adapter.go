@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
 	"github.com/yb2020/odoc/pkg/errors"
 	"github.com/yb2020/odoc/pkg/middleware"
@@ -11,6 +13,12 @@ import (
 // 确保Claims实现了middleware.Claims接口
 var _ middleware.Claims = (*model.Claims)(nil)
 
+// 确保Claims实现了middleware.ScopedClaims接口，第三方应用令牌受作用域限制
+var _ middleware.ScopedClaims = (*model.Claims)(nil)
+
+// 确保适配器同时支持gRPC接口的令牌校验
+var _ middleware.GRPCAuthService = (*OAuth2AuthAdapter)(nil)
+
 // OAuth2AuthAdapter 适配OAuth2服务到通用认证接口
 type OAuth2AuthAdapter struct {
 	oauth2Service OAuth2Service
@@ -36,6 +44,19 @@ func (a *OAuth2AuthAdapter) ValidateToken(ctx *gin.Context, token string) (middl
 	return claims, nil
 }
 
+// ValidateAccessToken 验证令牌，供没有gin.Context的gRPC接口使用
+func (a *OAuth2AuthAdapter) ValidateAccessToken(ctx context.Context, token string) (middleware.Claims, error) {
+	request := &pb.ValidateRequest{}
+	request.AccessToken = token
+	claims, err := a.oauth2Service.ValidateToken(ctx, request)
+
+	if err != nil {
+		return nil, errors.BizWrap("oauth2.validate_token.errors.invalid_token", err)
+	}
+
+	return claims, nil
+}
+
 // RevokeToken 撤销令牌
 func (a *OAuth2AuthAdapter) RevokeToken(ctx *gin.Context, access_token string) error {
 	return a.oauth2Service.RevokeUserToken(ctx, access_token)
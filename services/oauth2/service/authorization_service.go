@@ -0,0 +1,997 @@
+package service
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/utils"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+	userpb "github.com/yb2020/odoc/proto/gen/go/user"
+	"github.com/yb2020/odoc/services/oauth2/dao"
+	"github.com/yb2020/odoc/services/oauth2/model"
+	userservice "github.com/yb2020/odoc/services/user/service"
+)
+
+// AuthorizationService 面向第三方应用的OAuth2授权服务器
+// 支持授权码+PKCE、刷新令牌、令牌内省与撤销，签发的访问令牌只能访问被授予作用域覆盖的接口
+type AuthorizationService struct {
+	cfg           *config.Config
+	clientDAO     dao.OAuth2ClientsDAO
+	authCodeDAO   dao.OAuth2CodeDAO
+	consentDAO    *dao.OAuth2ConsentDAO
+	tokenDAO      dao.TokenDAO
+	userService   *userservice.UserService
+	logger        logging.Logger
+	tracer        opentracing.Tracer
+	issuer        string
+	signingKey    *rsa.PrivateKey
+	keyId         string
+	accessExpiry  time.Duration
+	refreshExpiry time.Duration
+	codeLifetime  time.Duration
+	scopes        map[string]config.OAuth2ScopeConfig
+}
+
+// NewAuthorizationService 创建第三方应用授权服务。
+// 第三方令牌只使用RSA私钥签名，未配置或无法加载私钥时返回错误，不能与第一方令牌共用HMAC密钥
+func NewAuthorizationService(
+	cfg *config.Config,
+	clientDAO dao.OAuth2ClientsDAO,
+	authCodeDAO dao.OAuth2CodeDAO,
+	consentDAO *dao.OAuth2ConsentDAO,
+	tokenDAO dao.TokenDAO,
+	userService *userservice.UserService,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+) (*AuthorizationService, error) {
+	signingKey, err := loadTokenSigningKey(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("加载签发第三方令牌的RSA私钥失败: %w", err)
+	}
+	if signingKey == nil {
+		return nil, fmt.Errorf("未配置签发第三方令牌的RSA私钥 oauth2.rsa.privateKey")
+	}
+
+	asCfg := cfg.OAuth2.AuthorizationServer
+
+	issuer := strings.TrimRight(asCfg.Issuer, "/")
+	if issuer == "" {
+		logger.Warn("未配置授权服务器issuer，使用JWT发行者", "component", "authorization_service", "jwt_issuer", cfg.OAuth2.JWT.Issuer)
+		issuer = cfg.OAuth2.JWT.Issuer
+	}
+
+	accessExpiry := time.Duration(asCfg.AccessTokenExpiry) * time.Second
+	if accessExpiry <= 0 {
+		accessExpiry = time.Hour
+	}
+	refreshExpiry := time.Duration(asCfg.RefreshTokenExpiry) * time.Second
+	if refreshExpiry <= 0 {
+		refreshExpiry = 30 * 24 * time.Hour
+	}
+	codeLifetime := time.Duration(cfg.OAuth2.TokenStorage.AuthCode.Lifetime) * time.Second
+	if codeLifetime <= 0 {
+		codeLifetime = 10 * time.Minute
+	}
+
+	scopes := make(map[string]config.OAuth2ScopeConfig, len(asCfg.Scopes))
+	for _, scope := range asCfg.Scopes {
+		scopes[scope.Name] = scope
+	}
+
+	return &AuthorizationService{
+		cfg:           cfg,
+		clientDAO:     clientDAO,
+		authCodeDAO:   authCodeDAO,
+		consentDAO:    consentDAO,
+		tokenDAO:      tokenDAO,
+		userService:   userService,
+		logger:        logger,
+		tracer:        tracer,
+		issuer:        issuer,
+		signingKey:    signingKey,
+		keyId:         rsaKeyThumbprint(&signingKey.PublicKey),
+		accessExpiry:  accessExpiry,
+		refreshExpiry: refreshExpiry,
+		codeLifetime:  codeLifetime,
+		scopes:        scopes,
+	}, nil
+}
+
+// loadTokenSigningKey 加载签发第三方令牌的RSA私钥，未配置时返回nil
+func loadTokenSigningKey(cfg *config.Config) (*rsa.PrivateKey, error) {
+	if cfg.OAuth2.RSA.PrivateKey == "" {
+		return nil, nil
+	}
+	return utils.LoadPrivateKeyFromBase64(cfg.OAuth2.RSA.PrivateKey)
+}
+
+// rsaKeyThumbprint 按 RFC 7638 计算公钥指纹，作为JWT头部的kid
+func rsaKeyThumbprint(key *rsa.PublicKey) string {
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	sum := sha256.Sum256([]byte(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Issuer 授权服务器标识
+func (s *AuthorizationService) Issuer() string {
+	return s.issuer
+}
+
+// Metadata 授权服务器元数据（RFC 8414）
+func (s *AuthorizationService) Metadata() *model.AuthorizationServerMetadata {
+	scopes := make([]string, 0, len(s.cfg.OAuth2.AuthorizationServer.Scopes))
+	for _, scope := range s.cfg.OAuth2.AuthorizationServer.Scopes {
+		scopes = append(scopes, scope.Name)
+	}
+	authMethods := []string{"client_secret_basic", "client_secret_post", "none"}
+	metadata := &model.AuthorizationServerMetadata{
+		Issuer:                                     s.issuer,
+		AuthorizationEndpoint:                      s.issuer + "/api/oauth2/authorize",
+		TokenEndpoint:                              s.issuer + "/api/oauth2/token",
+		RevocationEndpoint:                         s.issuer + "/api/oauth2/revoke",
+		IntrospectionEndpoint:                      s.issuer + "/api/oauth2/introspect",
+		ScopesSupported:                            scopes,
+		ResponseTypesSupported:                     []string{model.ResponseTypeCode},
+		GrantTypesSupported:                        []string{model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken},
+		TokenEndpointAuthMethodsSupported:          authMethods,
+		RevocationEndpointAuthMethodsSupported:     authMethods,
+		IntrospectionEndpointAuthMethodsSupported:  []string{"client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:              []string{model.CodeChallengeMethodS256},
+		AuthorizationResponseIssParameterSupported: true,
+	}
+	metadata.JwksURI = s.issuer + "/.well-known/jwks.json"
+	return metadata
+}
+
+// JWKS 验证第三方令牌签名的公钥集合
+func (s *AuthorizationService) JWKS() *model.JSONWebKeySet {
+	keys := &model.JSONWebKeySet{Keys: []model.JSONWebKey{}}
+	publicKey := &s.signingKey.PublicKey
+	keys.Keys = append(keys.Keys, model.JSONWebKey{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: jwt.SigningMethodRS256.Alg(),
+		Kid: s.keyId,
+		N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+	})
+	return keys
+}
+
+// ScopeInfos 作用域说明，用于授权确认页
+func (s *AuthorizationService) ScopeInfos(scopes []string) []*pb.OAuth2Scope {
+	infos := make([]*pb.OAuth2Scope, 0, len(scopes))
+	for _, name := range scopes {
+		infos = append(infos, &pb.OAuth2Scope{Name: name, Description: s.scopes[name].Description})
+	}
+	return infos
+}
+
+// ValidateAuthorizeRequest 校验授权请求
+// 应用或回调地址无效时返回业务错误，不能重定向回第三方；其余错误返回 *model.OAuthError，需要带回第三方回调地址
+func (s *AuthorizationService) ValidateAuthorizeRequest(ctx context.Context, req *model.AuthorizeRequest) (*model.OAuth2Clients, []string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.ValidateAuthorizeRequest")
+	defer span.Finish()
+
+	client, err := s.clientDAO.GetClientByID(ctx, req.ClientId)
+	if err != nil {
+		s.logger.Error("获取客户端信息失败", "component", "authorization_service", "client_id", req.ClientId, "error", err)
+		return nil, nil, errors.BizWrap("oauth2.error.client_retrieval_failed", err)
+	}
+	if client == nil {
+		return nil, nil, errors.Biz("oauth2.error.client_not_found")
+	}
+	if !client.Active {
+		return nil, nil, errors.Biz("oauth2.error.client_inactive")
+	}
+	if req.RedirectUri == "" || !client.IsValidRedirectURI(req.RedirectUri) {
+		s.logger.Warn("重定向URI不合法", "component", "authorization_service", "client_id", req.ClientId, "redirect_uri", req.RedirectUri)
+		return nil, nil, errors.Biz("oauth2.error.invalid_redirect_uri")
+	}
+
+	if req.ResponseType != model.ResponseTypeCode {
+		return client, nil, model.NewOAuthError(model.OAuthErrUnsupportedResponseType, "only response_type=code is supported")
+	}
+
+	scopes, oauthErr := s.checkScopes(client, req.Scopes())
+	if oauthErr != nil {
+		return client, nil, oauthErr
+	}
+
+	// 公开客户端无法保管密钥，必须使用PKCE；只支持S256，不接受plain
+	if req.CodeChallenge == "" {
+		if client.Public {
+			return client, nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "code_challenge is required for public clients")
+		}
+	} else if req.CodeChallengeMethod != model.CodeChallengeMethodS256 {
+		return client, nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "code_challenge_method must be S256")
+	}
+
+	return client, scopes, nil
+}
+
+// checkScopes 校验请求的作用域必须已配置且在应用允许的范围内，去重后返回
+func (s *AuthorizationService) checkScopes(client *model.OAuth2Clients, requested []string) ([]string, *model.OAuthError) {
+	if len(requested) == 0 {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidScope, "scope is required")
+	}
+	seen := make(map[string]bool, len(requested))
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		if seen[scope] {
+			continue
+		}
+		if _, ok := s.scopes[scope]; !ok || !client.IsValidScope(scope) {
+			return nil, model.NewOAuthError(model.OAuthErrInvalidScope, "scope "+scope+" is not allowed")
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+// AuthorizeRedirect 处理授权端点，校验通过时跳转到授权确认页，协议错误时跳回第三方回调地址
+func (s *AuthorizationService) AuthorizeRedirect(ctx context.Context, req *model.AuthorizeRequest, rawQuery string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.AuthorizeRedirect")
+	defer span.Finish()
+
+	_, _, err := s.ValidateAuthorizeRequest(ctx, req)
+	if oauthErr, ok := err.(*model.OAuthError); ok {
+		return s.errorRedirect(req.RedirectUri, req.State, oauthErr), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	consentURL := s.cfg.OAuth2.AuthorizationServer.ConsentURL
+	if strings.Contains(consentURL, "?") {
+		return consentURL + "&" + rawQuery, nil
+	}
+	return consentURL + "?" + rawQuery, nil
+}
+
+// GetConsent 获取授权确认页需要展示的应用与作用域信息
+func (s *AuthorizationService) GetConsent(ctx context.Context, userId string, req *model.AuthorizeRequest) (*pb.GetConsentResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.GetConsent")
+	defer span.Finish()
+
+	client, scopes, err := s.ValidateAuthorizeRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	consent, err := s.consentDAO.FindByUserAndClient(ctx, userId, client.ID)
+	if err != nil {
+		return nil, errors.BizWrap("oauth2.error.consent_retrieval_failed", err)
+	}
+
+	return &pb.GetConsentResponse{
+		Client:    client.ToProto(),
+		Scopes:    s.ScopeInfos(scopes),
+		Consented: consent != nil && consent.Covers(scopes),
+	}, nil
+}
+
+// SubmitConsent 处理用户的授权决定，返回跳回第三方回调地址的URL
+func (s *AuthorizationService) SubmitConsent(ctx context.Context, userId string, req *model.AuthorizeRequest, approve bool) (string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.SubmitConsent")
+	defer span.Finish()
+
+	client, scopes, err := s.ValidateAuthorizeRequest(ctx, req)
+	if oauthErr, ok := err.(*model.OAuthError); ok {
+		return s.errorRedirect(req.RedirectUri, req.State, oauthErr), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !approve {
+		s.logger.Info("用户拒绝第三方应用授权", "component", "authorization_service", "client_id", client.ID, "user_id", userId)
+		return s.errorRedirect(req.RedirectUri, req.State, model.NewOAuthError(model.OAuthErrAccessDenied, "the user denied the request")), nil
+	}
+
+	if err := s.saveConsent(ctx, userId, client.ID, scopes); err != nil {
+		return "", err
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		return "", errors.BizWrap("oauth2.error.auth_code_save_failed", err)
+	}
+	authCode := &model.OAuth2AuthCode{
+		Code:                code,
+		ClientId:            client.ID,
+		UserId:              userId,
+		RedirectUri:         req.RedirectUri,
+		Scope:               strings.Join(scopes, " "),
+		ExpiresAt:           time.Now().Add(s.codeLifetime),
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}
+	if err := s.authCodeDAO.SaveAuthCode(ctx, authCode); err != nil {
+		s.logger.Error("保存授权码失败", "component", "authorization_service", "client_id", client.ID, "error", err)
+		return "", errors.BizWrap("oauth2.error.auth_code_save_failed", err)
+	}
+
+	s.logger.Info("用户授权第三方应用", "component", "authorization_service", "client_id", client.ID, "user_id", userId, "scope", authCode.Scope)
+
+	params := url.Values{}
+	params.Set("code", code)
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	params.Set("iss", s.issuer)
+	return appendQuery(req.RedirectUri, params), nil
+}
+
+// saveConsent 保存授权记录，已有记录时合并作用域
+func (s *AuthorizationService) saveConsent(ctx context.Context, userId string, clientId string, scopes []string) error {
+	consent, err := s.consentDAO.FindByUserAndClient(ctx, userId, clientId)
+	if err != nil {
+		return errors.BizWrap("oauth2.error.consent_storage_failed", err)
+	}
+	if consent == nil {
+		consent = &model.OAuth2Consent{UserId: userId, ClientId: clientId, Scope: strings.Join(scopes, " ")}
+		if err := s.consentDAO.Save(ctx, consent); err != nil {
+			return errors.BizWrap("oauth2.error.consent_storage_failed", err)
+		}
+		return nil
+	}
+
+	granted := strings.Fields(consent.Scope)
+	for _, scope := range scopes {
+		if !consent.Covers([]string{scope}) {
+			granted = append(granted, scope)
+		}
+	}
+	consent.Scope = strings.Join(granted, " ")
+	if err := s.consentDAO.Modify(ctx, consent); err != nil {
+		return errors.BizWrap("oauth2.error.consent_storage_failed", err)
+	}
+	return nil
+}
+
+// Token 令牌端点，支持 authorization_code 与 refresh_token 两种授权方式，错误均为 *model.OAuthError
+func (s *AuthorizationService) Token(ctx context.Context, req *model.TokenEndpointRequest) (*model.TokenEndpointResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.Token")
+	defer span.Finish()
+
+	client, oauthErr := s.authenticateClient(ctx, req.Client, true)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	switch req.GrantType {
+	case model.GrantTypeAuthorizationCode:
+		return s.exchangeAuthCode(ctx, client, req)
+	case model.GrantTypeRefreshToken:
+		return s.refreshClientToken(ctx, client, req)
+	case "":
+		return nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "grant_type is required")
+	default:
+		return nil, model.NewOAuthError(model.OAuthErrUnsupportedGrantType, "")
+	}
+}
+
+// authenticateClient 认证第三方应用，机密客户端必须提供正确的密钥，allowPublic 为false时不接受公开客户端
+func (s *AuthorizationService) authenticateClient(ctx context.Context, creds model.ClientCredentials, allowPublic bool) (*model.OAuth2Clients, *model.OAuthError) {
+	if creds.ClientId == "" {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidClient, "client authentication is required")
+	}
+	client, err := s.clientDAO.GetClientByID(ctx, creds.ClientId)
+	if err != nil {
+		s.logger.Error("获取客户端信息失败", "component", "authorization_service", "client_id", creds.ClientId, "error", err)
+		return nil, model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+	if client == nil || !client.Active {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidClient, "unknown or inactive client")
+	}
+
+	if client.Public {
+		if !allowPublic {
+			return nil, model.NewOAuthError(model.OAuthErrInvalidClient, "public clients are not allowed to use this endpoint")
+		}
+		return client, nil
+	}
+
+	expected := []byte(client.Secret)
+	actual := []byte(sha256Hex(creds.ClientSecret))
+	if creds.ClientSecret == "" || subtle.ConstantTimeCompare(expected, actual) != 1 {
+		s.logger.Warn("第三方应用认证失败", "component", "authorization_service", "client_id", client.ID)
+		return nil, model.NewOAuthError(model.OAuthErrInvalidClient, "client authentication failed")
+	}
+	return client, nil
+}
+
+// exchangeAuthCode 使用授权码换取令牌
+func (s *AuthorizationService) exchangeAuthCode(ctx context.Context, client *model.OAuth2Clients, req *model.TokenEndpointRequest) (*model.TokenEndpointResponse, error) {
+	if req.Code == "" {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "code is required")
+	}
+
+	authCode, err := s.authCodeDAO.GetAuthCode(ctx, req.Code)
+	if err != nil {
+		s.logger.Error("获取授权码失败", "component", "authorization_service", "error", err)
+		return nil, model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+	if authCode == nil || authCode.Used || time.Now().After(authCode.ExpiresAt) || authCode.ClientId != client.ID {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "authorization code is invalid or expired")
+	}
+	if authCode.RedirectUri != req.RedirectUri {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "redirect_uri does not match")
+	}
+	if oauthErr := verifyCodeVerifier(client, authCode, req.CodeVerifier); oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	// 条件更新保证授权码只能兑换一次，并发重放时只有一个请求成功
+	consumed, err := s.authCodeDAO.ConsumeAuthCode(ctx, authCode.Code)
+	if err != nil {
+		return nil, model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+	if !consumed {
+		s.logger.Warn("授权码被重复使用", "component", "authorization_service", "client_id", client.ID, "user_id", authCode.UserId)
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "authorization code has already been used")
+	}
+
+	return s.issueClientToken(ctx, client, authCode.UserId, strings.Fields(authCode.Scope))
+}
+
+// verifyCodeVerifier 校验PKCE，授权请求带了code_challenge时code_verifier必须匹配
+func verifyCodeVerifier(client *model.OAuth2Clients, authCode *model.OAuth2AuthCode, verifier string) *model.OAuthError {
+	if authCode.CodeChallenge == "" {
+		if client.Public {
+			return model.NewOAuthError(model.OAuthErrInvalidGrant, "authorization code was issued without PKCE")
+		}
+		return nil
+	}
+	if verifier == "" {
+		return model.NewOAuthError(model.OAuthErrInvalidRequest, "code_verifier is required")
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(authCode.CodeChallenge)) != 1 {
+		return model.NewOAuthError(model.OAuthErrInvalidGrant, "code_verifier does not match")
+	}
+	return nil
+}
+
+// refreshClientToken 使用刷新令牌换取新令牌，旧令牌随即失效；可以请求更小的作用域
+func (s *AuthorizationService) refreshClientToken(ctx context.Context, client *model.OAuth2Clients, req *model.TokenEndpointRequest) (*model.TokenEndpointResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "refresh_token is required")
+	}
+	if _, err := s.parseClientToken(req.RefreshToken); err != nil {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "refresh token is invalid or expired")
+	}
+
+	tokenInfo, err := s.tokenDAO.GetTokenByRefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		s.logger.Error("获取令牌信息失败", "component", "authorization_service", "error", err)
+		return nil, model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+	if tokenInfo == nil || tokenInfo.Revoked || tokenInfo.ClientId != client.ID {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "refresh token is invalid or revoked")
+	}
+
+	// 用户已撤销对应用的授权时不再续期
+	consent, err := s.consentDAO.FindByUserAndClient(ctx, tokenInfo.UserId, client.ID)
+	if err != nil {
+		return nil, model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+	granted := strings.Fields(tokenInfo.Scope)
+	if consent == nil || !consent.Covers(granted) {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "authorization has been revoked")
+	}
+
+	scopes := granted
+	if req.Scope != "" {
+		scopes = strings.Fields(req.Scope)
+		narrowed := &model.OAuth2Consent{Scope: tokenInfo.Scope}
+		if !narrowed.Covers(scopes) {
+			return nil, model.NewOAuthError(model.OAuthErrInvalidScope, "requested scope exceeds the original grant")
+		}
+	}
+
+	// 先以条件更新撤销旧令牌再签发新令牌，并发重放同一刷新令牌时只有一个请求成功
+	revoked, err := s.tokenDAO.RevokeActiveToken(ctx, tokenInfo.TokenId)
+	if err != nil {
+		s.logger.Error("撤销旧令牌失败", "component", "authorization_service", "token_id", tokenInfo.TokenId, "error", err)
+		return nil, model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+	if !revoked {
+		s.logger.Warn("刷新令牌被重复使用", "component", "authorization_service", "client_id", client.ID, "user_id", tokenInfo.UserId)
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "refresh token is invalid or revoked")
+	}
+
+	return s.issueClientToken(ctx, client, tokenInfo.UserId, scopes)
+}
+
+// issueClientToken 为第三方应用签发访问令牌与刷新令牌
+// 第三方令牌不携带用户角色，只能访问作用域覆盖的接口，也不创建登录会话
+func (s *AuthorizationService) issueClientToken(ctx context.Context, client *model.OAuth2Clients, userId string, scopes []string) (*model.TokenEndpointResponse, error) {
+	user, err := s.userService.GetUserByID(ctx, userId)
+	if err != nil || user == nil {
+		s.logger.Error("获取用户信息失败", "component", "authorization_service", "user_id", userId, "error", err)
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "resource owner not found")
+	}
+	if user.Status == userpb.UserStatus_STATUS_BANNED || user.Status == userpb.UserStatus_STATUS_DELETED {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "resource owner is disabled")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.accessExpiry)
+	scope := strings.Join(scopes, " ")
+	tokenId := uuid.New().String()
+	device := "oauth2_client:" + client.ID
+
+	accessToken, err := s.signClientToken(&model.Claims{
+		UserId:   user.Id,
+		Username: user.Username,
+		Device:   device,
+		ClientId: client.ID,
+		Scope:    scope,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    s.issuer,
+			Subject:   user.Id,
+			Audience:  client.ID,
+			ExpiresAt: expiresAt.Unix(),
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			Id:        tokenId,
+		},
+	})
+	if err != nil {
+		s.logger.Error("签发第三方访问令牌失败", "component", "authorization_service", "client_id", client.ID, "error", err)
+		return nil, model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+	refreshToken, err := s.signClientToken(&model.Claims{
+		ClientId: client.ID,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    s.issuer,
+			Subject:   user.Id,
+			Audience:  client.ID,
+			ExpiresAt: now.Add(s.refreshExpiry).Unix(),
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			Id:        uuid.New().String(),
+		},
+	})
+	if err != nil {
+		s.logger.Error("签发第三方刷新令牌失败", "component", "authorization_service", "client_id", client.ID, "error", err)
+		return nil, model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+
+	tokenInfo := &model.OAuth2Token{
+		TokenId:      tokenId,
+		UserId:       user.Id,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+		Device:       device,
+		ClientId:     client.ID,
+		Scope:        scope,
+	}
+	if err := s.tokenDAO.SaveToken(ctx, tokenInfo); err != nil {
+		s.logger.Error("保存第三方令牌失败", "component", "authorization_service", "client_id", client.ID, "error", err)
+		return nil, model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+
+	return &model.TokenEndpointResponse{
+		AccessToken:  accessToken,
+		TokenType:    model.TokenTypeBearer,
+		ExpiresIn:    uint64(s.accessExpiry.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        scope,
+	}, nil
+}
+
+// signClientToken 使用RSA私钥签名
+func (s *AuthorizationService) signClientToken(claims *model.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.keyId
+	return token.SignedString(s.signingKey)
+}
+
+// parseClientToken 解析并校验第三方令牌的签名与有效期
+func (s *AuthorizationService) parseClientToken(tokenString string) (*model.Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &model.Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.Biz("oauth2.error.invalid_token")
+		}
+		return &s.signingKey.PublicKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*model.Claims)
+	if !ok || !token.Valid || claims.ClientId == "" {
+		return nil, errors.Biz("oauth2.error.invalid_token")
+	}
+	return claims, nil
+}
+
+// findClientToken 根据令牌字符串查找令牌记录，hint只决定查找顺序
+func (s *AuthorizationService) findClientToken(ctx context.Context, token string, hint string) (*model.OAuth2Token, string, error) {
+	lookups := []string{model.TokenTypeHintAccessToken, model.TokenTypeHintRefreshToken}
+	if hint == model.TokenTypeHintRefreshToken {
+		lookups = []string{model.TokenTypeHintRefreshToken, model.TokenTypeHintAccessToken}
+	}
+	for _, tokenType := range lookups {
+		var tokenInfo *model.OAuth2Token
+		var err error
+		if tokenType == model.TokenTypeHintAccessToken {
+			tokenInfo, err = s.tokenDAO.GetTokenByAccessToken(ctx, token)
+		} else {
+			tokenInfo, err = s.tokenDAO.GetTokenByRefreshToken(ctx, token)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if tokenInfo != nil {
+			return tokenInfo, tokenType, nil
+		}
+	}
+	return nil, "", nil
+}
+
+// Introspect 令牌内省（RFC 7662），只有签发给调用方自身的令牌才会返回active=true
+func (s *AuthorizationService) Introspect(ctx context.Context, creds model.ClientCredentials, token string, hint string) (*model.IntrospectionResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.Introspect")
+	defer span.Finish()
+
+	client, oauthErr := s.authenticateClient(ctx, creds, false)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+	if token == "" {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "token is required")
+	}
+
+	inactive := &model.IntrospectionResponse{Active: false}
+	claims, err := s.parseClientToken(token)
+	if err != nil || claims.ClientId != client.ID {
+		return inactive, nil
+	}
+	tokenInfo, tokenType, err := s.findClientToken(ctx, token, hint)
+	if err != nil {
+		s.logger.Error("获取令牌信息失败", "component", "authorization_service", "error", err)
+		return nil, model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+	if tokenInfo == nil || tokenInfo.Revoked || tokenInfo.ClientId != client.ID {
+		return inactive, nil
+	}
+
+	resp := &model.IntrospectionResponse{
+		Active:   true,
+		Scope:    tokenInfo.Scope,
+		ClientId: tokenInfo.ClientId,
+		Exp:      claims.ExpiresAt,
+		Iat:      claims.IssuedAt,
+		Sub:      claims.Subject,
+		Aud:      claims.Audience,
+		Iss:      claims.Issuer,
+		Jti:      claims.Id,
+	}
+	if tokenType == model.TokenTypeHintAccessToken {
+		resp.TokenType = model.TokenTypeBearer
+		resp.Username = claims.Username
+	} else {
+		resp.TokenType = model.TokenTypeHintRefreshToken
+	}
+	return resp, nil
+}
+
+// Revoke 令牌撤销（RFC 7009），撤销访问令牌或刷新令牌都会使整组令牌失效；无效令牌同样视为成功
+func (s *AuthorizationService) Revoke(ctx context.Context, creds model.ClientCredentials, token string, hint string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.Revoke")
+	defer span.Finish()
+
+	client, oauthErr := s.authenticateClient(ctx, creds, true)
+	if oauthErr != nil {
+		return oauthErr
+	}
+	if token == "" {
+		return model.NewOAuthError(model.OAuthErrInvalidRequest, "token is required")
+	}
+
+	tokenInfo, _, err := s.findClientToken(ctx, token, hint)
+	if err != nil {
+		s.logger.Error("获取令牌信息失败", "component", "authorization_service", "error", err)
+		return model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+	if tokenInfo == nil || tokenInfo.Revoked || tokenInfo.ClientId != client.ID {
+		return nil
+	}
+	if err := s.tokenDAO.RevokeToken(ctx, tokenInfo.TokenId); err != nil {
+		s.logger.Error("撤销第三方令牌失败", "component", "authorization_service", "token_id", tokenInfo.TokenId, "error", err)
+		return model.NewOAuthError(model.OAuthErrServerError, "")
+	}
+	return nil
+}
+
+// ListAuthorizedApps 获取用户已授权的第三方应用
+func (s *AuthorizationService) ListAuthorizedApps(ctx context.Context, userId string) ([]*pb.AuthorizedApp, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.ListAuthorizedApps")
+	defer span.Finish()
+
+	consents, err := s.consentDAO.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, errors.BizWrap("oauth2.error.consent_retrieval_failed", err)
+	}
+	apps := make([]*pb.AuthorizedApp, 0, len(consents))
+	for _, consent := range consents {
+		client, err := s.clientDAO.GetClientByID(ctx, consent.ClientId)
+		if err != nil {
+			return nil, errors.BizWrap("oauth2.error.client_retrieval_failed", err)
+		}
+		if client == nil {
+			continue
+		}
+		apps = append(apps, &pb.AuthorizedApp{
+			Client:       client.ToProto(),
+			Scopes:       s.ScopeInfos(strings.Fields(consent.Scope)),
+			AuthorizedAt: uint64(consent.UpdatedAt.UnixMilli()),
+		})
+	}
+	return apps, nil
+}
+
+// RevokeAuthorizedApp 用户撤销对第三方应用的授权，同时撤销该应用持有的全部令牌
+func (s *AuthorizationService) RevokeAuthorizedApp(ctx context.Context, userId string, clientId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.RevokeAuthorizedApp")
+	defer span.Finish()
+
+	if err := s.consentDAO.DeleteByUserAndClient(ctx, userId, clientId); err != nil {
+		return errors.BizWrap("oauth2.error.consent_storage_failed", err)
+	}
+	return s.revokeClientTokens(ctx, userId, clientId)
+}
+
+// revokeClientTokens 撤销用户签发给某个第三方应用的全部令牌
+func (s *AuthorizationService) revokeClientTokens(ctx context.Context, userId string, clientId string) error {
+	tokens, err := s.tokenDAO.GetTokensByUserID(ctx, userId)
+	if err != nil {
+		s.logger.Error("获取用户令牌失败", "component", "authorization_service", "user_id", userId, "error", err)
+		return errors.BizWrap("oauth2.error.token_retrieval_failed", err)
+	}
+	for _, token := range tokens {
+		if token.ClientId != clientId || token.Revoked {
+			continue
+		}
+		if err := s.tokenDAO.RevokeToken(ctx, token.TokenId); err != nil {
+			s.logger.Error("撤销第三方令牌失败", "component", "authorization_service", "token_id", token.TokenId, "error", err)
+			return errors.BizWrap("oauth2.error.token_revocation_failed", err)
+		}
+	}
+	return nil
+}
+
+// RemoveUserConsents 删除用户的全部授权记录，用户注销时调用
+func (s *AuthorizationService) RemoveUserConsents(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.RemoveUserConsents")
+	defer span.Finish()
+
+	if err := s.consentDAO.DeleteByUserId(ctx, userId); err != nil {
+		return errors.BizWrap("oauth2.error.consent_storage_failed", err)
+	}
+	return nil
+}
+
+// ListClients 管理员分页获取第三方应用
+func (s *AuthorizationService) ListClients(ctx context.Context, page, size int32) ([]*pb.OAuth2ClientInfo, int64, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.ListClients")
+	defer span.Finish()
+
+	clients, total, err := s.clientDAO.FindPage(ctx, page, size)
+	if err != nil {
+		return nil, 0, errors.BizWrap("oauth2.error.client_retrieval_failed", err)
+	}
+	infos := make([]*pb.OAuth2ClientInfo, 0, len(clients))
+	for _, client := range clients {
+		infos = append(infos, client.ToProto())
+	}
+	return infos, total, nil
+}
+
+// CreateClient 管理员注册第三方应用，明文密钥只在创建时返回一次
+func (s *AuthorizationService) CreateClient(ctx context.Context, creatorId string, req *pb.CreateOAuth2ClientRequest) (*model.OAuth2Clients, string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.CreateClient")
+	defer span.Finish()
+
+	if err := s.checkClientSettings(req.RedirectUris, req.AllowedScopes); err != nil {
+		return nil, "", err
+	}
+
+	client := &model.OAuth2Clients{
+		ID:            strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Name:          req.Name,
+		Description:   req.Description,
+		HomepageURL:   req.HomepageUrl,
+		LogoURL:       req.LogoUrl,
+		Public:        req.Public,
+		CreatorId:     creatorId,
+		RedirectUris:  req.RedirectUris,
+		AllowedScopes: req.AllowedScopes,
+		Active:        true,
+	}
+
+	var secret string
+	if !client.Public {
+		var err error
+		if secret, err = randomToken(); err != nil {
+			return nil, "", errors.BizWrap("oauth2.error.client_storage_failed", err)
+		}
+		client.Secret = sha256Hex(secret)
+	}
+
+	if err := s.clientDAO.SaveClient(ctx, client); err != nil {
+		s.logger.Error("保存第三方应用失败", "component", "authorization_service", "error", err)
+		return nil, "", errors.BizWrap("oauth2.error.client_storage_failed", err)
+	}
+	s.logger.Info("注册第三方应用", "component", "authorization_service", "client_id", client.ID, "creator_id", creatorId)
+	return client, secret, nil
+}
+
+// UpdateClient 管理员修改第三方应用，停用应用会撤销其全部授权
+func (s *AuthorizationService) UpdateClient(ctx context.Context, req *pb.UpdateOAuth2ClientRequest) (*model.OAuth2Clients, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.UpdateClient")
+	defer span.Finish()
+
+	client, err := s.getClient(ctx, req.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkClientSettings(req.RedirectUris, req.AllowedScopes); err != nil {
+		return nil, err
+	}
+
+	deactivated := client.Active && !req.Active
+	client.Name = req.Name
+	client.Description = req.Description
+	client.HomepageURL = req.HomepageUrl
+	client.LogoURL = req.LogoUrl
+	client.RedirectUris = req.RedirectUris
+	client.AllowedScopes = req.AllowedScopes
+	client.Active = req.Active
+	if err := s.clientDAO.UpdateClient(ctx, client); err != nil {
+		s.logger.Error("更新第三方应用失败", "component", "authorization_service", "client_id", client.ID, "error", err)
+		return nil, errors.BizWrap("oauth2.error.client_storage_failed", err)
+	}
+
+	if deactivated {
+		if err := s.revokeClientGrants(ctx, client.ID); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// RotateClientSecret 管理员重置机密客户端的密钥，旧密钥立即失效
+func (s *AuthorizationService) RotateClientSecret(ctx context.Context, clientId string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.RotateClientSecret")
+	defer span.Finish()
+
+	client, err := s.getClient(ctx, clientId)
+	if err != nil {
+		return "", err
+	}
+	if client.Public {
+		return "", errors.Biz("oauth2.error.public_client_has_no_secret")
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return "", errors.BizWrap("oauth2.error.client_storage_failed", err)
+	}
+	client.Secret = sha256Hex(secret)
+	if err := s.clientDAO.UpdateClient(ctx, client); err != nil {
+		s.logger.Error("重置第三方应用密钥失败", "component", "authorization_service", "client_id", client.ID, "error", err)
+		return "", errors.BizWrap("oauth2.error.client_storage_failed", err)
+	}
+	return secret, nil
+}
+
+// DeleteClient 管理员删除第三方应用，同时撤销全部授权与令牌
+func (s *AuthorizationService) DeleteClient(ctx context.Context, clientId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AuthorizationService.DeleteClient")
+	defer span.Finish()
+
+	if _, err := s.getClient(ctx, clientId); err != nil {
+		return err
+	}
+	if err := s.revokeClientGrants(ctx, clientId); err != nil {
+		return err
+	}
+	if err := s.clientDAO.DeleteClient(ctx, clientId); err != nil {
+		s.logger.Error("删除第三方应用失败", "component", "authorization_service", "client_id", clientId, "error", err)
+		return errors.BizWrap("oauth2.error.client_storage_failed", err)
+	}
+	return nil
+}
+
+// getClient 获取第三方应用，不存在时返回业务错误
+func (s *AuthorizationService) getClient(ctx context.Context, clientId string) (*model.OAuth2Clients, error) {
+	client, err := s.clientDAO.GetClientByID(ctx, clientId)
+	if err != nil {
+		return nil, errors.BizWrap("oauth2.error.client_retrieval_failed", err)
+	}
+	if client == nil {
+		return nil, errors.Biz("oauth2.error.client_not_found")
+	}
+	return client, nil
+}
+
+// revokeClientGrants 撤销所有用户对应用的授权及应用持有的令牌
+func (s *AuthorizationService) revokeClientGrants(ctx context.Context, clientId string) error {
+	userIds, err := s.consentDAO.FindUserIdsByClientId(ctx, clientId)
+	if err != nil {
+		return errors.BizWrap("oauth2.error.consent_retrieval_failed", err)
+	}
+	for _, userId := range userIds {
+		if err := s.revokeClientTokens(ctx, userId, clientId); err != nil {
+			return err
+		}
+	}
+	if err := s.consentDAO.DeleteByClientId(ctx, clientId); err != nil {
+		return errors.BizWrap("oauth2.error.consent_storage_failed", err)
+	}
+	return nil
+}
+
+// checkClientSettings 校验回调地址为绝对URL、作用域均已配置
+func (s *AuthorizationService) checkClientSettings(redirectUris []string, allowedScopes []string) error {
+	if len(redirectUris) == 0 {
+		return errors.Biz("oauth2.error.invalid_redirect_uri")
+	}
+	for _, uri := range redirectUris {
+		parsed, err := url.Parse(uri)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Fragment != "" {
+			return errors.Biz("oauth2.error.invalid_redirect_uri")
+		}
+	}
+	for _, scope := range allowedScopes {
+		if _, ok := s.scopes[scope]; !ok {
+			return errors.Biz("oauth2.error.invalid_scope")
+		}
+	}
+	return nil
+}
+
+// errorRedirect 构造带协议错误的第三方回调地址
+func (s *AuthorizationService) errorRedirect(redirectUri string, state string, oauthErr *model.OAuthError) string {
+	params := url.Values{}
+	params.Set("error", oauthErr.Code)
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	params.Set("iss", s.issuer)
+	return appendQuery(redirectUri, params)
+}
+
+// appendQuery 在回调地址后追加查询参数，保留原有参数
+func appendQuery(rawURL string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + params.Encode()
+}
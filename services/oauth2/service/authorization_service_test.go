@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	stderrors "errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	"github.com/yb2020/odoc/pkg/memory"
+	baseModel "github.com/yb2020/odoc/pkg/model"
+	"github.com/yb2020/odoc/pkg/utils"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+	userpb "github.com/yb2020/odoc/proto/gen/go/user"
+	"github.com/yb2020/odoc/services/oauth2/dao"
+	"github.com/yb2020/odoc/services/oauth2/model"
+	userDao "github.com/yb2020/odoc/services/user/dao"
+	userModel "github.com/yb2020/odoc/services/user/model"
+	userservice "github.com/yb2020/odoc/services/user/service"
+)
+
+func assertOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+	var oauthErr *model.OAuthError
+	if !stderrors.As(err, &oauthErr) || oauthErr.Code != code {
+		t.Fatalf("err = %v, want %s", err, code)
+	}
+}
+
+func TestVerifyCodeVerifier(t *testing.T) {
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		name      string
+		public    bool
+		challenge string
+		verifier  string
+		wantCode  string
+	}{
+		{name: "S256 match", challenge: challenge, verifier: verifier},
+		{name: "S256 match public client", public: true, challenge: challenge, verifier: verifier},
+		{name: "S256 mismatch", challenge: challenge, verifier: verifier + "x", wantCode: model.OAuthErrInvalidGrant},
+		{name: "plain verifier rejected", challenge: verifier, verifier: verifier, wantCode: model.OAuthErrInvalidGrant},
+		{name: "verifier missing", challenge: challenge, wantCode: model.OAuthErrInvalidRequest},
+		{name: "confidential client without challenge", verifier: verifier},
+		{name: "public client without challenge", public: true, wantCode: model.OAuthErrInvalidGrant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &model.OAuth2Clients{ID: "app", Public: tt.public}
+			authCode := &model.OAuth2AuthCode{Code: "code", CodeChallenge: tt.challenge, CodeChallengeMethod: model.CodeChallengeMethodS256}
+			oauthErr := verifyCodeVerifier(client, authCode, tt.verifier)
+			if tt.wantCode == "" {
+				if oauthErr != nil {
+					t.Fatalf("verifyCodeVerifier = %v, want nil", oauthErr)
+				}
+				return
+			}
+			if oauthErr == nil || oauthErr.Code != tt.wantCode {
+				t.Fatalf("verifyCodeVerifier = %v, want %s", oauthErr, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCheckScopes(t *testing.T) {
+	s := &AuthorizationService{scopes: map[string]config.OAuth2ScopeConfig{
+		"notes:read":  {Name: "notes:read"},
+		"notes:write": {Name: "notes:write"},
+		"docs:read":   {Name: "docs:read"},
+	}}
+	restricted := &model.OAuth2Clients{ID: "app", AllowedScopes: baseModel.StringSlice{"notes:read", "notes:write"}}
+	unrestricted := &model.OAuth2Clients{ID: "app"}
+
+	tests := []struct {
+		name      string
+		client    *model.OAuth2Clients
+		requested []string
+		want      []string
+		wantErr   bool
+	}{
+		{name: "allowed", client: restricted, requested: []string{"notes:read"}, want: []string{"notes:read"}},
+		{name: "dedupe keeps order", client: restricted, requested: []string{"notes:write", "notes:read", "notes:write"}, want: []string{"notes:write", "notes:read"}},
+		{name: "empty", client: restricted, wantErr: true},
+		{name: "not allowed for client", client: restricted, requested: []string{"notes:read", "docs:read"}, wantErr: true},
+		{name: "unknown scope", client: unrestricted, requested: []string{"admin"}, wantErr: true},
+		{name: "unrestricted client", client: unrestricted, requested: []string{"docs:read", "notes:read"}, want: []string{"docs:read", "notes:read"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scopes, oauthErr := s.checkScopes(tt.client, tt.requested)
+			if tt.wantErr {
+				if oauthErr == nil || oauthErr.Code != model.OAuthErrInvalidScope {
+					t.Fatalf("checkScopes = %v, %v, want invalid_scope", scopes, oauthErr)
+				}
+				return
+			}
+			if oauthErr != nil {
+				t.Fatalf("checkScopes err = %v", oauthErr)
+			}
+			if len(scopes) != len(tt.want) {
+				t.Fatalf("checkScopes = %v, want %v", scopes, tt.want)
+			}
+			for i := range scopes {
+				if scopes[i] != tt.want[i] {
+					t.Fatalf("checkScopes = %v, want %v", scopes, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewAuthorizationServiceRequiresRSAKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		privateKey string
+	}{
+		{name: "not configured"},
+		{name: "invalid key", privateKey: "bm90LWEta2V5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.OAuth2.JWT.Secret = "test-secret"
+			cfg.OAuth2.RSA.PrivateKey = tt.privateKey
+			service, err := NewAuthorizationService(cfg, dao.OAuth2ClientsDAO{}, dao.OAuth2CodeDAO{}, nil, nil, nil, daotest.NewLogger(), opentracing.NoopTracer{})
+			if err == nil || service != nil {
+				t.Fatalf("NewAuthorizationService = %v, %v, want error", service, err)
+			}
+		})
+	}
+}
+
+func TestClientTokens(t *testing.T) {
+	db := daotest.NewDB(t, &userModel.User{}, &model.OAuth2Token{}, &model.OAuth2Consent{})
+	logger := daotest.NewLogger()
+	tracer := opentracing.NoopTracer{}
+	cfg := &config.Config{}
+	cfg.OAuth2.JWT.Secret = "test-secret"
+	_, privateKey, err := utils.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	cfg.OAuth2.RSA.PrivateKey = base64.StdEncoding.EncodeToString([]byte(privateKey))
+	cfg.OAuth2.TokenStorage.MaxTokensPerUser = 100
+	cfg.OAuth2.AuthorizationServer.Scopes = []config.OAuth2ScopeConfig{{Name: "notes:read"}, {Name: "notes:write"}}
+
+	ctx := context.Background()
+	userDAO := userDao.NewUserDAO(db, logger)
+	if err := userDAO.Save(ctx, &userModel.User{BaseModel: baseModel.BaseModel{Id: "u1"}, Username: "u1", Status: userpb.UserStatus_STATUS_ACTIVE}); err != nil {
+		t.Fatalf("save user: %v", err)
+	}
+	consentDAO := dao.NewOAuth2ConsentDAO(db, logger)
+	if err := consentDAO.Save(ctx, &model.OAuth2Consent{UserId: "u1", ClientId: "app", Scope: "notes:read notes:write"}); err != nil {
+		t.Fatalf("save consent: %v", err)
+	}
+	userService := userservice.NewUserService(memory.NewMemoryCache(logger, time.Minute, "test"), logger, tracer, nil, userDAO, nil,
+		baseDao.NewTransactionManager(db))
+	tokenDAO := dao.NewTokenDAO(db, nil, logger, cfg)
+	authService, err := NewAuthorizationService(cfg, dao.NewOAuth2ClientsDAO(db, logger, cfg), dao.NewAuthCodeDAO(db, logger, cfg), consentDAO, tokenDAO, userService, logger, tracer)
+	if err != nil {
+		t.Fatalf("NewAuthorizationService: %v", err)
+	}
+	client := &model.OAuth2Clients{ID: "app", Active: true}
+	refresh := func(refreshToken string, scope string) (*model.TokenEndpointResponse, error) {
+		return authService.refreshClientToken(ctx, client, &model.TokenEndpointRequest{
+			GrantType:    model.GrantTypeRefreshToken,
+			RefreshToken: refreshToken,
+			Scope:        scope,
+		})
+	}
+
+	t.Run("refresh rotates once", func(t *testing.T) {
+		issued, err := authService.issueClientToken(ctx, client, "u1", []string{"notes:read", "notes:write"})
+		if err != nil {
+			t.Fatalf("issueClientToken: %v", err)
+		}
+
+		refreshed, err := refresh(issued.RefreshToken, "notes:read")
+		if err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		if refreshed.Scope != "notes:read" || refreshed.RefreshToken == issued.RefreshToken {
+			t.Fatalf("refreshed = %+v", refreshed)
+		}
+		old, err := tokenDAO.GetTokenByAccessToken(ctx, issued.AccessToken)
+		if err != nil || old != nil {
+			t.Fatalf("old access token = %+v, err = %v, want revoked", old, err)
+		}
+
+		// 重放已使用的刷新令牌被拒绝，新令牌不受影响
+		_, err = refresh(issued.RefreshToken, "")
+		assertOAuthError(t, err, model.OAuthErrInvalidGrant)
+		current, err := tokenDAO.GetTokenByAccessToken(ctx, refreshed.AccessToken)
+		if err != nil || current == nil {
+			t.Fatalf("current token = %+v, err = %v, want active", current, err)
+		}
+
+		// 不能扩大作用域
+		_, err = refresh(refreshed.RefreshToken, "notes:write")
+		assertOAuthError(t, err, model.OAuthErrInvalidScope)
+	})
+
+	t.Run("concurrent refresh replay", func(t *testing.T) {
+		issued, err := authService.issueClientToken(ctx, client, "u1", []string{"notes:read"})
+		if err != nil {
+			t.Fatalf("issueClientToken: %v", err)
+		}
+
+		const workers = 8
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		succeeded := 0
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := refresh(issued.RefreshToken, ""); err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if succeeded != 1 {
+			t.Fatalf("succeeded = %d, want 1", succeeded)
+		}
+	})
+
+	t.Run("first party rejects client tokens", func(t *testing.T) {
+		secret := []byte("test-secret")
+		expiresAt := time.Now().Add(time.Hour)
+
+		// 修复前以第一方密钥签名的第三方令牌
+		accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &model.Claims{
+			UserId:         "u1",
+			ClientId:       "app",
+			Scope:          "notes:read",
+			StandardClaims: jwt.StandardClaims{Subject: "u1", ExpiresAt: expiresAt.Unix()},
+		}).SignedString(secret)
+		if err != nil {
+			t.Fatalf("sign access token: %v", err)
+		}
+		refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.StandardClaims{Subject: "u1", ExpiresAt: expiresAt.Unix()}).SignedString(secret)
+		if err != nil {
+			t.Fatalf("sign refresh token: %v", err)
+		}
+		if err := tokenDAO.SaveToken(ctx, &model.OAuth2Token{TokenId: "legacy", UserId: "u1", AccessToken: accessToken, RefreshToken: refreshToken, ExpiresAt: expiresAt, ClientId: "app"}); err != nil {
+			t.Fatalf("save token: %v", err)
+		}
+		s := NewOAuth2Service(tokenDAO, userService, logger, tracer, cfg, nil, dao.OAuth2ClientsDAO{}, dao.OAuth2CodeDAO{}, nil, nil)
+
+		_, err = s.ValidateToken(ctx, &pb.ValidateRequest{AccessToken: accessToken})
+		assertBizError(t, err, "oauth2.error.invalid_token")
+
+		userCtx := context.WithValue(ctx, userContext.UserIDKey, "u1")
+		_, err = s.RefreshToken(userCtx, &pb.RefreshTokenRequest{RefreshToken: refreshToken})
+		assertBizError(t, err, "oauth2.error.invalid_refresh_token")
+
+		// 授权服务器签发的RSA令牌不能在第一方刷新端点刷新
+		issued, err := authService.issueClientToken(ctx, client, "u1", []string{"notes:read"})
+		if err != nil {
+			t.Fatalf("issueClientToken: %v", err)
+		}
+		_, err = s.RefreshToken(userCtx, &pb.RefreshTokenRequest{RefreshToken: issued.RefreshToken})
+		assertBizError(t, err, "oauth2.error.invalid_refresh_token")
+	})
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+	"golang.org/x/oauth2"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/cache"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	userpb "github.com/yb2020/odoc/proto/gen/go/user"
+	"github.com/yb2020/odoc/services/oauth2/provider"
+	usermodel "github.com/yb2020/odoc/services/user/model"
+	userservice "github.com/yb2020/odoc/services/user/service"
+)
+
+const externalStateKey = "external_state:%s"
+
+// externalLoginState 跳转到提供方前保存的登录状态
+type externalLoginState struct {
+	Provider    string `json:"provider"`
+	Nonce       string `json:"nonce"`
+	Verifier    string `json:"verifier"`
+	RedirectURL string `json:"redirectUrl"`
+	LinkUserId  string `json:"linkUserId"` // 不为空表示为已登录用户关联外部身份
+}
+
+// ExternalLoginResult 外部登录回调的处理结果
+type ExternalLoginResult struct {
+	User   *usermodel.User // 登录流程中找到或创建的用户，关联流程为空
+	Linked bool            // 是否为关联外部身份流程
+}
+
+// ExternalLoginService 提供方无关的外部身份登录服务
+type ExternalLoginService struct {
+	registry        *provider.Registry
+	cache           cache.Cache
+	identityService *userservice.UserIdentityService
+	stateTTL        time.Duration
+	logger          logging.Logger
+	tracer          opentracing.Tracer
+}
+
+// NewExternalLoginService 创建外部身份登录服务
+func NewExternalLoginService(cfg *config.Config, registry *provider.Registry, cache cache.Cache,
+	identityService *userservice.UserIdentityService, logger logging.Logger, tracer opentracing.Tracer) *ExternalLoginService {
+	return &ExternalLoginService{
+		registry:        registry,
+		cache:           cache,
+		identityService: identityService,
+		stateTTL:        time.Duration(cfg.OAuth2.External.StateTTL) * time.Second,
+		logger:          logger,
+		tracer:          tracer,
+	}
+}
+
+// Providers 返回已启用的提供方
+func (s *ExternalLoginService) Providers() []provider.ExternalIdentityProvider {
+	return s.registry.List()
+}
+
+// BeginLogin 生成state、nonce与PKCE verifier并返回提供方的授权地址
+// baseURL 为当前请求的协议与域名（如 https://example.com），linkUserId 不为空时为关联流程
+func (s *ExternalLoginService) BeginLogin(ctx context.Context, providerName, baseURL, linkUserId string) (string, string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ExternalLoginService.BeginLogin")
+	span.SetTag("identity.provider", providerName)
+	defer span.Finish()
+
+	p, ok := s.registry.Get(providerName)
+	if !ok {
+		return "", "", errors.Biz("oauth2.error.external_provider_not_found")
+	}
+
+	session := &provider.AuthSession{
+		State:       uuid.New().String(),
+		Nonce:       uuid.New().String(),
+		Verifier:    oauth2.GenerateVerifier(),
+		RedirectURL: baseURL + p.RedirectPath(),
+	}
+	state := &externalLoginState{
+		Provider:    p.Name(),
+		Nonce:       session.Nonce,
+		Verifier:    session.Verifier,
+		RedirectURL: session.RedirectURL,
+		LinkUserId:  linkUserId,
+	}
+	if err := s.cache.Set(ctx, fmt.Sprintf(externalStateKey, session.State), state, s.stateTTL); err != nil {
+		s.logger.Error("msg", "保存外部登录state失败", "provider", providerName, "error", err.Error())
+		return "", "", errors.BizWrap("oauth2.error.external_login_failed", err)
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, session)
+	if err != nil {
+		return "", "", err
+	}
+	s.logger.Info("msg", "生成外部登录授权地址", "provider", providerName, "redirect_uri", session.RedirectURL, "link", linkUserId != "")
+	return authURL, session.State, nil
+}
+
+// CompleteLogin 处理提供方回调：校验state，换取外部身份，并登录或关联账号
+func (s *ExternalLoginService) CompleteLogin(ctx context.Context, providerName, stateValue, code string) (*ExternalLoginResult, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ExternalLoginService.CompleteLogin")
+	span.SetTag("identity.provider", providerName)
+	defer span.Finish()
+
+	p, ok := s.registry.Get(providerName)
+	if !ok {
+		return nil, errors.Biz("oauth2.error.external_provider_not_found")
+	}
+
+	// state 一次性使用
+	stateKey := fmt.Sprintf(externalStateKey, stateValue)
+	var state externalLoginState
+	found, err := s.cache.Get(ctx, stateKey, &state)
+	if err != nil {
+		return nil, errors.BizWrap("oauth2.error.external_login_failed", err)
+	}
+	if !found || state.Provider != p.Name() {
+		return nil, errors.Biz("oauth2.error.invalid_state")
+	}
+	if err := s.cache.Delete(ctx, stateKey); err != nil {
+		s.logger.Warn("msg", "删除外部登录state失败", "error", err.Error())
+	}
+
+	external, err := p.Exchange(ctx, code, &provider.AuthSession{
+		State:       stateValue,
+		Nonce:       state.Nonce,
+		Verifier:    state.Verifier,
+		RedirectURL: state.RedirectURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if state.LinkUserId != "" {
+		if err := s.identityService.LinkIdentity(ctx, state.LinkUserId, external); err != nil {
+			s.logger.Warn("msg", "关联外部身份失败", "provider", providerName, "userId", state.LinkUserId, "error", err.Error())
+			return nil, err
+		}
+		return &ExternalLoginResult{Linked: true}, nil
+	}
+
+	user, err := s.identityService.FindOrCreateUser(ctx, external)
+	if err != nil {
+		s.logger.Warn("msg", "处理外部身份登录失败", "provider", providerName, "error", err.Error())
+		return nil, err
+	}
+	if user.Status == userpb.UserStatus_STATUS_BANNED || user.Status == userpb.UserStatus_STATUS_DELETED {
+		return nil, errors.Biz("oauth2.error.external_account_disabled")
+	}
+	return &ExternalLoginResult{User: user}, nil
+}
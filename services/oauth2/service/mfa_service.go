@@ -0,0 +1,550 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/cache"
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/ratelimit"
+	"github.com/yb2020/odoc/pkg/totp"
+	"github.com/yb2020/odoc/pkg/utils"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+	userpb "github.com/yb2020/odoc/proto/gen/go/user"
+	"github.com/yb2020/odoc/services/oauth2/dao"
+	"github.com/yb2020/odoc/services/oauth2/model"
+)
+
+const (
+	mfaChallengeKey   = "mfa_challenge:%s"
+	mfaLimiterPrefix  = "mfa"
+	mfaValidateSkew   = 1  // 允许前后各一个时间步的时钟偏差
+	recoveryCodeBytes = 10 // 恢复码随机字节数，编码后为16个字符
+)
+
+// recoveryCodeEncoding 恢复码编码，去掉填充并使用小写便于抄写
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAStatus 用户的两步验证状态
+type MFAStatus struct {
+	Enabled                bool  // 是否已开启
+	Required               bool  // 所属角色是否强制开启
+	RecoveryCodesRemaining int64 // 剩余可用恢复码个数
+	TrustedDevices         int64 // 已记住的设备数
+}
+
+// MFAService 两步验证服务：TOTP绑定、验证码与恢复码校验、记住设备以及按角色强制开启
+type MFAService struct {
+	cfg                *config.MFAConfig
+	mfaDAO             *dao.UserMFADAO
+	recoveryCodeDAO    *dao.MFARecoveryCodeDAO
+	trustedDeviceDAO   *dao.MFATrustedDeviceDAO
+	rolePolicyDAO      *dao.MFARolePolicyDAO
+	transactionManager *baseDao.TransactionManager
+	cache              cache.Cache
+	limiter            ratelimit.RateLimiter
+	aes                *utils.AESUtil
+	logger             logging.Logger
+	tracer             opentracing.Tracer
+}
+
+// NewMFAService 创建两步验证服务，limiterService 为空时不限制验证码校验频率
+func NewMFAService(
+	cfg *config.Config,
+	mfaDAO *dao.UserMFADAO,
+	recoveryCodeDAO *dao.MFARecoveryCodeDAO,
+	trustedDeviceDAO *dao.MFATrustedDeviceDAO,
+	rolePolicyDAO *dao.MFARolePolicyDAO,
+	transactionManager *baseDao.TransactionManager,
+	cache cache.Cache,
+	limiterService *ratelimit.RateLimiterService,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+) *MFAService {
+	secretKey := cfg.MFA.SecretKey
+	if secretKey == "" {
+		logger.Warn("未配置两步验证密钥加密key，使用JWT密钥", "component", "mfa_service")
+		secretKey = cfg.OAuth2.JWT.Secret
+	}
+
+	var limiter ratelimit.RateLimiter
+	if limiterService != nil && cfg.MFA.MaxAttempts > 0 {
+		var err error
+		limiter, err = limiterService.CreateLimiter(ratelimit.LimiterConfig{
+			Type:       ratelimit.CounterLimiterType,
+			KeyPrefix:  mfaLimiterPrefix,
+			MaxRate:    cfg.MFA.MaxAttempts,
+			TimeUnit:   ratelimit.Minute,
+			Dimension:  ratelimit.User,
+			ExpireTime: 60,
+		})
+		if err != nil {
+			logger.Error("创建两步验证限流器失败", "component", "mfa_service", "error", err)
+		}
+	}
+
+	return &MFAService{
+		cfg:                &cfg.MFA,
+		mfaDAO:             mfaDAO,
+		recoveryCodeDAO:    recoveryCodeDAO,
+		trustedDeviceDAO:   trustedDeviceDAO,
+		rolePolicyDAO:      rolePolicyDAO,
+		transactionManager: transactionManager,
+		cache:              cache,
+		limiter:            limiter,
+		aes:                utils.NewAESUtil(secretKey),
+		logger:             logger,
+		tracer:             tracer,
+	}
+}
+
+// Status 获取用户的两步验证状态
+func (s *MFAService) Status(ctx context.Context, userId string, roles []string) (*MFAStatus, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.Status")
+	defer span.Finish()
+
+	enabled, required, err := s.Requirement(ctx, userId, roles)
+	if err != nil {
+		return nil, err
+	}
+	status := &MFAStatus{Enabled: enabled, Required: required}
+	if !enabled {
+		return status, nil
+	}
+	if status.RecoveryCodesRemaining, err = s.recoveryCodeDAO.CountUnusedByUserId(ctx, userId); err != nil {
+		return nil, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	if status.TrustedDevices, err = s.trustedDeviceDAO.CountValidByUserId(ctx, userId); err != nil {
+		return nil, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	return status, nil
+}
+
+// Requirement 返回用户是否已开启两步验证，以及所属角色是否强制开启
+func (s *MFAService) Requirement(ctx context.Context, userId string, roles []string) (bool, bool, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.Requirement")
+	defer span.Finish()
+
+	mfa, err := s.mfaDAO.FindByUserId(ctx, userId)
+	if err != nil {
+		return false, false, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	required, err := s.rolePolicyDAO.CountRequiredByRoles(ctx, roles)
+	if err != nil {
+		return false, false, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	return mfa != nil && mfa.Enabled, required > 0, nil
+}
+
+// BeginEnrollment 生成新的TOTP密钥并保存为待激活状态，返回密钥与 otpauth:// 地址
+func (s *MFAService) BeginEnrollment(ctx context.Context, userId string, account string) (string, string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.BeginEnrollment")
+	defer span.Finish()
+
+	mfa, err := s.mfaDAO.FindByUserId(ctx, userId)
+	if err != nil {
+		return "", "", errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	if mfa != nil && mfa.Enabled {
+		return "", "", errors.Biz("oauth2.error.mfa_already_enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	encrypted, err := s.aes.EncryptBase64(secret)
+	if err != nil {
+		return "", "", errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+
+	if mfa == nil {
+		err = s.mfaDAO.Save(ctx, &model.UserMFA{UserId: userId, Secret: encrypted})
+	} else {
+		// 重新开始绑定时覆盖之前未激活的密钥
+		mfa.Secret = encrypted
+		mfa.LastUsedStep = 0
+		err = s.mfaDAO.Modify(ctx, mfa)
+	}
+	if err != nil {
+		s.logger.Error("保存两步验证密钥失败", "component", "mfa_service", "user_id", userId, "error", err)
+		return "", "", errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	return secret, totp.ProvisioningURI(secret, s.cfg.Issuer, account), nil
+}
+
+// ActivateEnrollment 校验认证器App中的验证码并激活两步验证，返回新生成的恢复码
+func (s *MFAService) ActivateEnrollment(ctx context.Context, userId string, code string) ([]string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.ActivateEnrollment")
+	defer span.Finish()
+
+	mfa, err := s.mfaDAO.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	if mfa == nil {
+		return nil, errors.Biz("oauth2.error.mfa_not_enabled")
+	}
+	if mfa.Enabled {
+		return nil, errors.Biz("oauth2.error.mfa_already_enabled")
+	}
+	if err := s.checkAttempt(ctx, userId); err != nil {
+		return nil, err
+	}
+	if err := s.verifyTOTP(ctx, mfa, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.transactionManager.ExecuteInTransaction(ctx, func(txCtx context.Context) error {
+		now := time.Now().UTC()
+		mfa.Enabled = true
+		mfa.EnabledAt = &now
+		if err := s.mfaDAO.Modify(txCtx, mfa); err != nil {
+			return err
+		}
+		codes, err = s.replaceRecoveryCodes(txCtx, userId)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("激活两步验证失败", "component", "mfa_service", "user_id", userId, "error", err)
+		return nil, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	s.logger.Info("用户开启两步验证", "component", "mfa_service", "user_id", userId)
+	return codes, nil
+}
+
+// Disable 校验验证码或恢复码后关闭两步验证，所属角色强制开启时不允许关闭
+func (s *MFAService) Disable(ctx context.Context, userId string, roles []string, code string, recoveryCode string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.Disable")
+	defer span.Finish()
+
+	enabled, required, err := s.Requirement(ctx, userId, roles)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errors.Biz("oauth2.error.mfa_not_enabled")
+	}
+	if required {
+		return errors.Biz("oauth2.error.mfa_required_by_policy")
+	}
+	if err := s.VerifyCode(ctx, userId, code, recoveryCode); err != nil {
+		return err
+	}
+	if err := s.RemoveUserMFA(ctx, userId); err != nil {
+		return err
+	}
+	s.logger.Info("用户关闭两步验证", "component", "mfa_service", "user_id", userId)
+	return nil
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码，旧的恢复码全部失效
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userId string, code string) ([]string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.RegenerateRecoveryCodes")
+	defer span.Finish()
+
+	if err := s.VerifyCode(ctx, userId, code, ""); err != nil {
+		return nil, err
+	}
+	var codes []string
+	err := s.transactionManager.ExecuteInTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		codes, err = s.replaceRecoveryCodes(txCtx, userId)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("重新生成恢复码失败", "component", "mfa_service", "user_id", userId, "error", err)
+		return nil, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	return codes, nil
+}
+
+// VerifyCode 校验认证器App中的验证码或恢复码，二者提供其一即可；校验次数受限流控制
+func (s *MFAService) VerifyCode(ctx context.Context, userId string, code string, recoveryCode string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.VerifyCode")
+	defer span.Finish()
+
+	mfa, err := s.mfaDAO.FindByUserId(ctx, userId)
+	if err != nil {
+		return errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	if mfa == nil || !mfa.Enabled {
+		return errors.Biz("oauth2.error.mfa_not_enabled")
+	}
+	if err := s.checkAttempt(ctx, userId); err != nil {
+		return err
+	}
+
+	if code != "" {
+		return s.verifyTOTP(ctx, mfa, code)
+	}
+	normalized := normalizeRecoveryCode(recoveryCode)
+	if normalized == "" {
+		return errors.Biz("oauth2.error.mfa_invalid_code")
+	}
+	used, err := s.recoveryCodeDAO.UseCode(ctx, userId, sha256Hex(normalized))
+	if err != nil {
+		return errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	if !used {
+		return errors.Biz("oauth2.error.mfa_invalid_code")
+	}
+	s.logger.Info("用户使用恢复码完成两步验证", "component", "mfa_service", "user_id", userId)
+	return nil
+}
+
+// CreateChallenge 第一步验证通过后保存待两步验证的登录，返回挑战令牌
+func (s *MFAService) CreateChallenge(ctx context.Context, challenge *model.MFAChallenge) (string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.CreateChallenge")
+	defer span.Finish()
+
+	token, err := randomToken()
+	if err != nil {
+		return "", errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	ttl := time.Duration(s.cfg.ChallengeTTL) * time.Second
+	if err := s.cache.Set(ctx, fmt.Sprintf(mfaChallengeKey, token), challenge, ttl); err != nil {
+		s.logger.Error("保存两步验证挑战失败", "component", "mfa_service", "user_id", challenge.UserId, "error", err)
+		return "", errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	return token, nil
+}
+
+// GetChallenge 获取挑战令牌对应的登录，已过期或不存在时返回错误
+func (s *MFAService) GetChallenge(ctx context.Context, token string) (*model.MFAChallenge, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.GetChallenge")
+	defer span.Finish()
+
+	var challenge model.MFAChallenge
+	found, err := s.cache.Get(ctx, fmt.Sprintf(mfaChallengeKey, token), &challenge)
+	if err != nil {
+		return nil, errors.BizWrap("oauth2.error.mfa_challenge_expired", err)
+	}
+	if !found || challenge.UserId == "" {
+		return nil, errors.Biz("oauth2.error.mfa_challenge_expired")
+	}
+	return &challenge, nil
+}
+
+// DeleteChallenge 删除挑战令牌，挑战令牌只能成功使用一次
+func (s *MFAService) DeleteChallenge(ctx context.Context, token string) {
+	if err := s.cache.Delete(ctx, fmt.Sprintf(mfaChallengeKey, token)); err != nil {
+		s.logger.Warn("删除两步验证挑战失败", "component", "mfa_service", "error", err)
+	}
+}
+
+// IsTrustedDevice 判断设备令牌是否为用户已记住且未过期的设备
+func (s *MFAService) IsTrustedDevice(ctx context.Context, userId string, token string) bool {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.IsTrustedDevice")
+	defer span.Finish()
+
+	if token == "" {
+		return false
+	}
+	device, err := s.trustedDeviceDAO.FindValid(ctx, userId, sha256Hex(token))
+	if err != nil || device == nil {
+		return false
+	}
+	if err := s.trustedDeviceDAO.TouchLastUsed(ctx, device.Id); err != nil {
+		s.logger.Warn("更新免两步验证设备使用时间失败", "component", "mfa_service", "user_id", userId, "error", err)
+	}
+	return true
+}
+
+// TrustDevice 记住此设备，返回设备令牌与过期时间；未开启记住设备时返回空令牌
+func (s *MFAService) TrustDevice(ctx context.Context, userId string, deviceName string) (string, time.Time, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.TrustDevice")
+	defer span.Finish()
+
+	if s.cfg.RememberDeviceDays <= 0 {
+		return "", time.Time{}, nil
+	}
+	token, err := randomToken()
+	if err != nil {
+		return "", time.Time{}, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	now := time.Now().UTC()
+	device := &model.MFATrustedDevice{
+		UserId:     userId,
+		TokenHash:  sha256Hex(token),
+		Device:     truncate(deviceName, 128),
+		ExpiresAt:  now.AddDate(0, 0, s.cfg.RememberDeviceDays),
+		LastUsedAt: now,
+	}
+	if err := s.trustedDeviceDAO.Save(ctx, device); err != nil {
+		s.logger.Error("保存免两步验证设备失败", "component", "mfa_service", "user_id", userId, "error", err)
+		return "", time.Time{}, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	return token, device.ExpiresAt, nil
+}
+
+// RevokeTrustedDevices 取消用户所有已记住的设备
+func (s *MFAService) RevokeTrustedDevices(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.RevokeTrustedDevices")
+	defer span.Finish()
+
+	if err := s.trustedDeviceDAO.DeleteByUserId(ctx, userId); err != nil {
+		return errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	return nil
+}
+
+// RemoveUserMFA 删除用户的两步验证配置、恢复码与已记住的设备
+func (s *MFAService) RemoveUserMFA(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.RemoveUserMFA")
+	defer span.Finish()
+
+	err := s.transactionManager.ExecuteInTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.mfaDAO.DeleteByUserId(txCtx, userId); err != nil {
+			return err
+		}
+		if err := s.recoveryCodeDAO.DeleteByUserId(txCtx, userId); err != nil {
+			return err
+		}
+		return s.trustedDeviceDAO.DeleteByUserId(txCtx, userId)
+	})
+	if err != nil {
+		s.logger.Error("删除用户两步验证失败", "component", "mfa_service", "user_id", userId, "error", err)
+		return errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	return nil
+}
+
+// ListRolePolicies 获取全部角色的两步验证策略
+func (s *MFAService) ListRolePolicies(ctx context.Context) ([]*pb.MFARolePolicy, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.ListRolePolicies")
+	defer span.Finish()
+
+	policies, err := s.rolePolicyDAO.FindAll(ctx)
+	if err != nil {
+		return nil, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	result := make([]*pb.MFARolePolicy, 0, len(policies))
+	for i := range policies {
+		result = append(result, policies[i].ToProto())
+	}
+	return result, nil
+}
+
+// SetRolePolicy 设置角色是否强制两步验证
+func (s *MFAService) SetRolePolicy(ctx context.Context, role string, required bool) (*pb.MFARolePolicy, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "MFAService.SetRolePolicy")
+	defer span.Finish()
+
+	if _, ok := userpb.UserRole_value[role]; !ok {
+		return nil, errors.Biz("oauth2.error.mfa_invalid_role")
+	}
+	policy, err := s.rolePolicyDAO.FindByRole(ctx, role)
+	if err != nil {
+		return nil, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	if policy == nil {
+		policy = &model.MFARolePolicy{Role: role, Required: required}
+		err = s.rolePolicyDAO.Save(ctx, policy)
+	} else {
+		policy.Required = required
+		err = s.rolePolicyDAO.Modify(ctx, policy)
+	}
+	if err != nil {
+		s.logger.Error("保存角色两步验证策略失败", "component", "mfa_service", "role", role, "error", err)
+		return nil, errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	return policy.ToProto(), nil
+}
+
+// checkAttempt 按用户限制验证码校验频率，限流器异常时放行
+func (s *MFAService) checkAttempt(ctx context.Context, userId string) error {
+	if s.limiter == nil {
+		return nil
+	}
+	result, err := s.limiter.Allow(ctx, ratelimit.GetLimiterKey(mfaLimiterPrefix, ratelimit.User, userId), 1)
+	if err != nil {
+		s.logger.Warn("两步验证限流检查失败", "component", "mfa_service", "user_id", userId, "error", err)
+		return nil
+	}
+	if !result.Allowed {
+		s.logger.Warn("两步验证校验过于频繁", "component", "mfa_service", "user_id", userId)
+		return errors.Biz("oauth2.error.mfa_too_many_attempts")
+	}
+	return nil
+}
+
+// verifyTOTP 校验TOTP验证码，同一时间步的验证码只能使用一次
+func (s *MFAService) verifyTOTP(ctx context.Context, mfa *model.UserMFA, code string) error {
+	secret, err := s.aes.DecryptBase64(mfa.Secret)
+	if err != nil {
+		s.logger.Error("解密两步验证密钥失败", "component", "mfa_service", "user_id", mfa.UserId, "error", err)
+		return errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	step, ok := totp.Validate(secret, strings.TrimSpace(code), time.Now(), mfaValidateSkew)
+	if !ok {
+		return errors.Biz("oauth2.error.mfa_invalid_code")
+	}
+	updated, err := s.mfaDAO.UpdateLastUsedStep(ctx, mfa.Id, step)
+	if err != nil {
+		return errors.BizWrap("oauth2.error.mfa_storage_failed", err)
+	}
+	if !updated {
+		// 该验证码（或更新的验证码）已经使用过
+		return errors.Biz("oauth2.error.mfa_invalid_code")
+	}
+	mfa.LastUsedStep = step
+	return nil
+}
+
+// replaceRecoveryCodes 删除旧恢复码并生成新的恢复码，只保存哈希值，返回明文供用户保存
+func (s *MFAService) replaceRecoveryCodes(ctx context.Context, userId string) ([]string, error) {
+	if err := s.recoveryCodeDAO.DeleteByUserId(ctx, userId); err != nil {
+		return nil, err
+	}
+	count := s.cfg.RecoveryCodeCount
+	if count <= 0 {
+		count = 10
+	}
+	codes := make([]string, 0, count)
+	records := make([]model.MFARecoveryCode, 0, count)
+	for i := 0; i < count; i++ {
+		buf := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))
+		codes = append(codes, raw[:8]+"-"+raw[8:])
+		records = append(records, model.MFARecoveryCode{UserId: userId, CodeHash: sha256Hex(raw)})
+	}
+	if err := s.recoveryCodeDAO.SaveAll(ctx, &records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode 去掉用户输入恢复码中的空格与连字符并转为小写
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
+
+// sha256Hex 计算SHA-256哈希的十六进制表示，恢复码、记住设备令牌和第三方应用密钥只保存哈希
+func sha256Hex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken 生成32字节的随机令牌
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
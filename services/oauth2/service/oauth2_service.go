@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"crypto/rsa"
 	"fmt"
 	"strings"
 	"time"
@@ -13,10 +14,12 @@ import (
 	userContext "github.com/yb2020/odoc/pkg/context"
 	"github.com/yb2020/odoc/pkg/errors"
 	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/idgen"
 	"github.com/yb2020/odoc/pkg/logging"
 	pkgmodel "github.com/yb2020/odoc/pkg/model"
 	"github.com/yb2020/odoc/pkg/utils"
 	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+	userpb "github.com/yb2020/odoc/proto/gen/go/user"
 	"github.com/yb2020/odoc/services/oauth2/dao"
 	"github.com/yb2020/odoc/services/oauth2/model"
 	userservice "github.com/yb2020/odoc/services/user/service"
@@ -24,18 +27,21 @@ import (
 
 // OAuth2Service OAuth2服务实现
 type OAuth2Service struct {
-	tokenDAO      dao.TokenDAO
-	userService   userservice.UserService
-	logger        logging.Logger
-	tracer        opentracing.Tracer
-	localizer     i18n.Localizer
-	jwtSecret     string
-	jwtIssuer     string
-	jwtExpiry     time.Duration
-	refreshExpiry time.Duration
-	config        *config.Config
-	clientDAO     dao.OAuth2ClientsDAO
-	authCodeDAO   dao.OAuth2CodeDAO
+	tokenDAO       dao.TokenDAO
+	userService    userservice.UserService
+	logger         logging.Logger
+	tracer         opentracing.Tracer
+	localizer      i18n.Localizer
+	jwtSecret      string
+	jwtIssuer      string
+	jwtExpiry      time.Duration
+	refreshExpiry  time.Duration
+	config         *config.Config
+	clientDAO      dao.OAuth2ClientsDAO
+	authCodeDAO    dao.OAuth2CodeDAO
+	sessionService *SessionService
+	mfaService     *MFAService
+	rsaPublicKey   *rsa.PublicKey // 验证第三方应用令牌的RSA公钥，未配置时为nil
 }
 
 // NewOAuth2Service 创建OAuth2服务
@@ -48,6 +54,8 @@ func NewOAuth2Service(
 	localizer i18n.Localizer,
 	clientDAO dao.OAuth2ClientsDAO,
 	authCodeDAO dao.OAuth2CodeDAO,
+	sessionService *SessionService,
+	mfaService *MFAService,
 ) OAuth2Service {
 	// 从配置中获取JWT密钥
 	jwtSecret := config.OAuth2.JWT.Secret
@@ -79,42 +87,78 @@ func NewOAuth2Service(
 		refreshExpiry = 7 * 24 * time.Hour // 默认7天
 	}
 
+	// 第三方应用令牌由授权服务器使用RSA私钥签名
+	var rsaPublicKey *rsa.PublicKey
+	if signingKey, err := loadTokenSigningKey(config); err != nil {
+		logger.Warn("加载RSA私钥失败，将无法验证第三方应用令牌", "component", "oauth2_service", "error", err)
+	} else if signingKey != nil {
+		rsaPublicKey = &signingKey.PublicKey
+	}
+
 	return OAuth2Service{
-		tokenDAO:      tokenDAO,
-		userService:   *userService,
-		logger:        logger,
-		tracer:        tracer,
-		localizer:     localizer,
-		jwtSecret:     jwtSecret,
-		jwtIssuer:     jwtIssuer,
-		jwtExpiry:     jwtExpiry,
-		refreshExpiry: refreshExpiry,
-		config:        config,
-		clientDAO:     clientDAO,
-		authCodeDAO:   authCodeDAO,
+		tokenDAO:       tokenDAO,
+		userService:    *userService,
+		logger:         logger,
+		tracer:         tracer,
+		localizer:      localizer,
+		jwtSecret:      jwtSecret,
+		jwtIssuer:      jwtIssuer,
+		jwtExpiry:      jwtExpiry,
+		refreshExpiry:  refreshExpiry,
+		config:         config,
+		clientDAO:      clientDAO,
+		authCodeDAO:    authCodeDAO,
+		sessionService: sessionService,
+		mfaService:     mfaService,
+		rsaPublicKey:   rsaPublicKey,
 	}
 }
 
-// CreateTokenForGoogleUser creates tokens for a user authenticated via Google OAuth2.
-func (s *OAuth2Service) CreateTokenForGoogleUser(ctx context.Context, userID string, username string, roles []string) (*model.TokenResponse, error) {
-	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "OAuth2Service.CreateTokenForGoogleUser")
+// CreateTokenForExternalUser creates tokens for a user authenticated via an external identity provider.
+func (s *OAuth2Service) CreateTokenForExternalUser(ctx context.Context, provider string, userID string, username string, roles []string, client *model.LoginClient) (*model.TokenResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "OAuth2Service.CreateTokenForExternalUser")
 	defer span.Finish()
 
-	// For OAuth2 logins, the device can be identified as such.
-	device := "google_oauth2_login"
+	// For external logins, the device is identified by the provider, e.g. google_oauth2_login.
+	return s.finishLogin(ctx, &model.MFAChallenge{
+		UserId:     userID,
+		Username:   username,
+		Roles:      roles,
+		Device:     provider + "_oauth2_login",
+		Method:     provider,
+		Identifier: username,
+	}, client)
+}
+
+// RecordExternalLoginFailure 记录外部身份登录失败的历史
+func (s *OAuth2Service) RecordExternalLoginFailure(ctx context.Context, provider string, client *model.LoginClient, err error) {
+	s.sessionService.RecordLogin(ctx, &LoginAttempt{
+		Identifier:    provider,
+		Method:        provider,
+		Client:        client,
+		FailureReason: failureReason(err),
+	})
+}
 
-	tokenInfo, err := s.generateAndSaveToken(ctx, userID, username, roles, device)
+// issueSessionToken 为一次新的登录生成令牌并创建登录会话，会话创建失败不影响登录
+func (s *OAuth2Service) issueSessionToken(
+	ctx context.Context,
+	userID string,
+	username string,
+	roles []string,
+	device string,
+	method string,
+	client *model.LoginClient,
+) (*model.OAuth2Token, error) {
+	sessionId := idgen.GenerateUUID()
+	tokenInfo, err := s.generateAndSaveToken(ctx, userID, username, roles, device, sessionId)
 	if err != nil {
-		return nil, errors.BizWrap("oauth2.error.token_generation_failed", err)
+		return nil, err
 	}
-
-	return &model.TokenResponse{
-		UserId:       tokenInfo.UserId,
-		AccessToken:  tokenInfo.AccessToken,
-		RefreshToken: tokenInfo.RefreshToken,
-		ExpiresAt:    uint64(tokenInfo.ExpiresAt.Unix()),
-		ExpiresIn:    uint64(s.jwtExpiry.Seconds()),
-	}, nil
+	if err := s.sessionService.CreateSession(ctx, sessionId, tokenInfo, method, client, time.Now().Add(s.refreshExpiry)); err != nil {
+		s.logger.Error("创建登录会话失败", "component", "oauth2_service", "user_id", userID, "error", err)
+	}
+	return tokenInfo, nil
 }
 
 // generateAndSaveToken 生成并保存令牌的通用方法
@@ -124,6 +168,7 @@ func (s *OAuth2Service) generateAndSaveToken(
 	username string,
 	roles []string,
 	device string,
+	sessionId string,
 ) (*model.OAuth2Token, error) {
 	// 生成新的令牌ID
 	tokenID := uuid.New().String()
@@ -140,7 +185,7 @@ func (s *OAuth2Service) generateAndSaveToken(
 		Username: username,
 		StandardClaims: jwt.StandardClaims{
 			Issuer:    s.jwtIssuer,
-			Subject:   fmt.Sprintf("%d", userID),
+			Subject:   userID,
 			ExpiresAt: expiresAt.Unix(),
 			IssuedAt:  now.Unix(),
 			NotBefore: now.Unix(),
@@ -160,7 +205,7 @@ func (s *OAuth2Service) generateAndSaveToken(
 	refreshTokenID := uuid.New().String()
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
 		Issuer:    s.jwtIssuer,
-		Subject:   fmt.Sprintf("%d", userID),
+		Subject:   userID,
 		ExpiresAt: refreshExpiresAt.Unix(),
 		IssuedAt:  now.Unix(),
 		NotBefore: now.Unix(),
@@ -180,6 +225,7 @@ func (s *OAuth2Service) generateAndSaveToken(
 		RefreshToken: refreshTokenString,
 		ExpiresAt:    expiresAt,
 		Device:       device,
+		SessionId:    sessionId,
 	}
 
 	err = s.tokenDAO.SaveToken(ctx, tokenInfo)
@@ -202,19 +248,37 @@ func (s *OAuth2Service) GenerateToken(ctx context.Context, request *model.TokenR
 	username := request.GetUsername()
 	s.logger.Info("尝试认证用户", "component", "oauth2_service", "username", username)
 
+	attempt := &LoginAttempt{
+		Identifier: username,
+		Method:     model.LoginMethodPassword,
+		Client:     request.Client,
+	}
+
 	// 使用新添加的GetUserByEmail方法获取用户
 	user, err := s.userService.GetUserByEmail(ctx, username)
 	if err != nil {
 		s.logger.Error("用户认证失败", "component", "oauth2_service", "username", username, "error", err)
+		attempt.FailureReason = "invalid_credentials"
+		s.sessionService.RecordLogin(ctx, attempt)
 		return nil, errors.Biz("invalid_credentials")
 	}
+	attempt.UserId = user.Id
 
 	// 验证密码
 	password := utils.StrengthenPassword(request.GetPassword(), user.Id)
 	if password != user.Password {
 		s.logger.Error("用户认证失败", "component", "oauth2_service", "username", username, "error", errors.Biz("invalid_credentials"))
+		attempt.FailureReason = "invalid_credentials"
+		s.sessionService.RecordLogin(ctx, attempt)
 		return nil, errors.Biz("invalid_credentials")
 	}
+	// 开启邮箱验证时，未验证邮箱的账号不允许登录
+	if s.config.Account.RequireEmailVerification && user.Status == userpb.UserStatus_STATUS_INACTIVE {
+		s.logger.Warn("用户邮箱未验证", "component", "oauth2_service", "user_id", user.Id)
+		attempt.FailureReason = "user.account.errors.email_not_verified"
+		s.sessionService.RecordLogin(ctx, attempt)
+		return nil, errors.Biz("user.account.errors.email_not_verified")
+	}
 	s.logger.Info("用户认证成功", "component", "oauth2_service", "user_id", user.Id)
 
 	// GenerateToken 方法中
@@ -223,10 +287,53 @@ func (s *OAuth2Service) GenerateToken(ctx context.Context, request *model.TokenR
 		roles = append(roles, role.String())
 	}
 
-	tokenInfo, err := s.generateAndSaveToken(ctx, user.Id, user.Username, roles, request.Device)
+	return s.finishLogin(ctx, &model.MFAChallenge{
+		UserId:     user.Id,
+		Username:   user.Username,
+		Roles:      roles,
+		Device:     request.Device,
+		Method:     model.LoginMethodPassword,
+		Identifier: username,
+	}, request.Client)
+}
+
+// finishLogin 第一步验证通过后：需要两步验证且不是已记住的设备时返回挑战令牌，否则签发令牌
+func (s *OAuth2Service) finishLogin(ctx context.Context, challenge *model.MFAChallenge, client *model.LoginClient) (*model.TokenResponse, error) {
+	enabled, enforced, err := s.mfaService.Requirement(ctx, challenge.UserId, challenge.Roles)
+	if err != nil {
+		return nil, err
+	}
+	trusted := enabled && client != nil && s.mfaService.IsTrustedDevice(ctx, challenge.UserId, client.TrustedDeviceToken)
+	if (enabled || enforced) && !trusted {
+		challenge.EnrollRequired = !enabled
+		mfaToken, err := s.mfaService.CreateChallenge(ctx, challenge)
+		if err != nil {
+			return nil, err
+		}
+		s.logger.Info("登录需要两步验证", "component", "oauth2_service", "user_id", challenge.UserId, "enroll_required", challenge.EnrollRequired)
+		return &model.TokenResponse{
+			UserId:            challenge.UserId,
+			MFARequired:       true,
+			MFAToken:          mfaToken,
+			MFAEnrollRequired: challenge.EnrollRequired,
+		}, nil
+	}
+	return s.completeLogin(ctx, challenge, client)
+}
+
+// completeLogin 签发令牌、创建会话并记录登录历史
+func (s *OAuth2Service) completeLogin(ctx context.Context, challenge *model.MFAChallenge, client *model.LoginClient) (*model.TokenResponse, error) {
+	tokenInfo, err := s.issueSessionToken(ctx, challenge.UserId, challenge.Username, challenge.Roles, challenge.Device, challenge.Method, client)
 	if err != nil {
 		return nil, errors.BizWrap("oauth2.error.token_generation_failed", err)
 	}
+	s.sessionService.RecordLogin(ctx, &LoginAttempt{
+		UserId:     challenge.UserId,
+		Identifier: challenge.Identifier,
+		Method:     challenge.Method,
+		Client:     client,
+		SessionId:  tokenInfo.SessionId,
+	})
 
 	// 返回令牌响应
 	return &model.TokenResponse{
@@ -238,6 +345,88 @@ func (s *OAuth2Service) GenerateToken(ctx context.Context, request *model.TokenR
 	}, nil
 }
 
+// VerifyMFA 登录第二步：校验验证码或恢复码后签发令牌，rememberDevice 为true时同时返回记住设备的令牌与过期时间
+func (s *OAuth2Service) VerifyMFA(ctx context.Context, mfaToken string, code string, recoveryCode string, rememberDevice bool,
+	client *model.LoginClient) (*model.TokenResponse, string, time.Time, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "OAuth2Service.VerifyMFA")
+	defer span.Finish()
+
+	challenge, err := s.mfaService.GetChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	if challenge.EnrollRequired {
+		return nil, "", time.Time{}, errors.Biz("oauth2.error.mfa_enrollment_required")
+	}
+	if err := s.mfaService.VerifyCode(ctx, challenge.UserId, code, recoveryCode); err != nil {
+		s.sessionService.RecordLogin(ctx, &LoginAttempt{
+			UserId:        challenge.UserId,
+			Identifier:    challenge.Identifier,
+			Method:        challenge.Method,
+			Client:        client,
+			FailureReason: failureReason(err),
+		})
+		return nil, "", time.Time{}, err
+	}
+	s.mfaService.DeleteChallenge(ctx, mfaToken)
+
+	resp, err := s.completeLogin(ctx, challenge, client)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	if !rememberDevice {
+		return resp, "", time.Time{}, nil
+	}
+	trustedToken, trustedExpiresAt, err := s.mfaService.TrustDevice(ctx, challenge.UserId, client.DeviceName())
+	if err != nil {
+		// 记住设备失败不影响本次登录
+		s.logger.Warn("记住设备失败", "component", "oauth2_service", "user_id", challenge.UserId, "error", err)
+		return resp, "", time.Time{}, nil
+	}
+	return resp, trustedToken, trustedExpiresAt, nil
+}
+
+// BeginChallengeEnrollment 角色强制两步验证但尚未绑定的用户，在登录过程中开始绑定
+func (s *OAuth2Service) BeginChallengeEnrollment(ctx context.Context, mfaToken string) (string, string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "OAuth2Service.BeginChallengeEnrollment")
+	defer span.Finish()
+
+	challenge, err := s.mfaService.GetChallenge(ctx, mfaToken)
+	if err != nil {
+		return "", "", err
+	}
+	if !challenge.EnrollRequired {
+		return "", "", errors.Biz("oauth2.error.mfa_already_enabled")
+	}
+	return s.mfaService.BeginEnrollment(ctx, challenge.UserId, challenge.Identifier)
+}
+
+// ActivateChallengeEnrollment 在登录过程中完成绑定并签发令牌，同时返回恢复码
+func (s *OAuth2Service) ActivateChallengeEnrollment(ctx context.Context, mfaToken string, code string,
+	client *model.LoginClient) (*model.TokenResponse, []string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "OAuth2Service.ActivateChallengeEnrollment")
+	defer span.Finish()
+
+	challenge, err := s.mfaService.GetChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !challenge.EnrollRequired {
+		return nil, nil, errors.Biz("oauth2.error.mfa_already_enabled")
+	}
+	recoveryCodes, err := s.mfaService.ActivateEnrollment(ctx, challenge.UserId, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.mfaService.DeleteChallenge(ctx, mfaToken)
+
+	resp, err := s.completeLogin(ctx, challenge, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, recoveryCodes, nil
+}
+
 // RefreshToken 刷新令牌
 func (s *OAuth2Service) RefreshToken(ctx context.Context, request *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
 	span, _ := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "OAuth2Service.RefreshToken")
@@ -279,6 +468,11 @@ func (s *OAuth2Service) RefreshToken(ctx context.Context, request *pb.RefreshTok
 	if tokenInfo == nil || tokenInfo.Revoked {
 		return nil, errors.Biz("oauth2.error.token_revoked_or_not_found")
 	}
+	// 第三方应用的刷新令牌只能在授权服务器的令牌端点刷新，不能换取第一方令牌
+	if tokenInfo.ClientId != "" {
+		s.logger.Warn("拒绝使用第三方应用令牌刷新第一方令牌", "component", "oauth2_service", "client_id", tokenInfo.ClientId)
+		return nil, errors.Biz("oauth2.error.invalid_refresh_token")
+	}
 
 	// 验证用户ID
 	userID, ok := ctx.Value(userContext.UserIDKey).(string)
@@ -313,7 +507,18 @@ func (s *OAuth2Service) RefreshToken(ctx context.Context, request *pb.RefreshTok
 	// 实际项目中应该根据用户实际角色来判断
 	// RefreshToken 方法中
 	// 在验证刷新令牌并获取用户信息后
-	response, err := s.generateAndSaveToken(ctx, user.Id, user.Username, roles, tokenInfo.Device)
+	// 刷新令牌沿用原会话，早于会话功能签发的令牌在此时补建会话
+	var response *model.OAuth2Token
+	if tokenInfo.SessionId != "" {
+		response, err = s.generateAndSaveToken(ctx, user.Id, user.Username, roles, tokenInfo.Device, tokenInfo.SessionId)
+		if err == nil {
+			if err := s.sessionService.OnTokenRefreshed(ctx, tokenInfo.SessionId, response.TokenId, time.Now().Add(s.refreshExpiry)); err != nil {
+				s.logger.Error("更新登录会话失败", "component", "oauth2_service", "session_id", tokenInfo.SessionId, "error", err)
+			}
+		}
+	} else {
+		response, err = s.issueSessionToken(ctx, user.Id, user.Username, roles, tokenInfo.Device, "", nil)
+	}
 	if err != nil {
 		return nil, errors.BizWrap("oauth2.error.token_generation_failed", err)
 	}
@@ -342,11 +547,16 @@ func (s *OAuth2Service) ValidateToken(ctx context.Context, request *pb.ValidateR
 
 	// 解析令牌
 	token, err := jwt.ParseWithClaims(request.AccessToken, &model.Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// 验证签名方法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		// 验证签名方法，第一方令牌使用HMAC，第三方应用令牌使用RSA
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			return []byte(s.jwtSecret), nil
+		case *jwt.SigningMethodRSA:
+			if s.rsaPublicKey != nil {
+				return s.rsaPublicKey, nil
+			}
 		}
-		return []byte(s.jwtSecret), nil
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 	})
 
 	if err != nil {
@@ -364,6 +574,10 @@ func (s *OAuth2Service) ValidateToken(ctx context.Context, request *pb.ValidateR
 	if !ok {
 		return nil, errors.BizWrap("oauth2.error.invalid_token", nil)
 	}
+	// 第三方应用令牌只使用RSA签名，使用第一方密钥签名却带有应用ID的令牌无效
+	if _, isHMAC := token.Method.(*jwt.SigningMethodHMAC); isHMAC && claims.ClientId != "" {
+		return nil, errors.BizWrap("oauth2.error.invalid_token", nil)
+	}
 
 	// 根据配置决定验证方式
 	s.logger.Debug("验证令牌", "component", "oauth2_service", "token_id", claims.Id)
@@ -385,6 +599,9 @@ func (s *OAuth2Service) ValidateToken(ctx context.Context, request *pb.ValidateR
 		return nil, errors.Biz("oauth2.error.token_expired")
 	}
 
+	// 记录会话最近活跃时间
+	s.sessionService.Touch(ctx, tokenInfo.SessionId)
+
 	// 返回验证结果
 	return claims, nil
 }
@@ -411,6 +628,7 @@ func (s *OAuth2Service) RevokeUserToken(ctx context.Context, accessToken string)
 		s.logger.Error("撤销令牌失败", "component", "oauth2_service", "error", err)
 		return errors.BizWrap("oauth2.error.token_revocation_failed", err)
 	}
+	s.sessionService.MarkSessionRevoked(ctx, tokenInfo.SessionId)
 
 	return nil
 }
@@ -440,6 +658,7 @@ func (s *OAuth2Service) RevokeUserTokens(ctx context.Context, userID string) err
 		s.logger.Error("撤销用户所有令牌失败", "component", "oauth2_service", "userID", userID, "error", err)
 		return errors.BizWrap("oauth2.error.token_revocation_failed", err)
 	}
+	s.sessionService.MarkUserSessionsRevoked(ctx, userID)
 
 	return nil
 }
@@ -614,7 +833,7 @@ func (s *OAuth2Service) GenerateServiceToken(ctx context.Context, serviceId, ser
 	refreshTokenID := uuid.New().String()
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
 		Issuer:    s.jwtIssuer,
-		Subject:   fmt.Sprintf("%d", serviceId),
+		Subject:   serviceId,
 		ExpiresAt: expiresAt.Unix(),
 		IssuedAt:  now.Unix(),
 		NotBefore: now.Unix(),
@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/eventbus"
+	"github.com/yb2020/odoc/pkg/logging"
+	pb "github.com/yb2020/odoc/proto/gen/go/oauth2"
+	"github.com/yb2020/odoc/services/oauth2/dao"
+	"github.com/yb2020/odoc/services/oauth2/model"
+	userEvent "github.com/yb2020/odoc/services/user/event"
+)
+
+// LoginAttempt 一次登录尝试，用于记录登录历史
+type LoginAttempt struct {
+	UserId        string             // 用户ID，账号不存在时为空
+	Identifier    string             // 登录时使用的账号标识
+	Method        string             // 登录方式
+	Client        *model.LoginClient // 客户端信息
+	SessionId     string             // 登录成功时创建的会话ID
+	FailureReason string             // 失败原因，为空表示登录成功
+}
+
+// SessionService 登录会话与登录历史服务
+type SessionService struct {
+	sessionDAO *dao.OAuth2SessionDAO
+	historyDAO *dao.LoginHistoryDAO
+	tokenDAO   dao.TokenDAO
+	eventBus   *eventbus.EventBus
+	config     *config.SessionConfig
+	logger     logging.Logger
+	tracer     opentracing.Tracer
+	lastSeen   sync.Map // sessionId -> time.Time，控制活跃时间的写入频率
+}
+
+// NewSessionService 创建登录会话服务
+func NewSessionService(sessionDAO *dao.OAuth2SessionDAO, historyDAO *dao.LoginHistoryDAO, tokenDAO dao.TokenDAO,
+	eventBus *eventbus.EventBus, cfg *config.SessionConfig, logger logging.Logger, tracer opentracing.Tracer) *SessionService {
+	return &SessionService{
+		sessionDAO: sessionDAO,
+		historyDAO: historyDAO,
+		tokenDAO:   tokenDAO,
+		eventBus:   eventBus,
+		config:     cfg,
+		logger:     logger,
+		tracer:     tracer,
+	}
+}
+
+// CreateSession 登录成功后创建会话，sessionId 需与令牌中的 SessionId 一致
+func (s *SessionService) CreateSession(ctx context.Context, sessionId string, token *model.OAuth2Token, method string,
+	client *model.LoginClient, expiresAt time.Time) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "SessionService.CreateSession")
+	defer span.Finish()
+
+	session := &model.OAuth2Session{
+		UserId:     token.UserId,
+		TokenId:    token.TokenId,
+		Method:     method,
+		Device:     token.Device,
+		LastSeenAt: time.Now().UTC(),
+		ExpiresAt:  expiresAt.UTC(),
+	}
+	session.Id = sessionId
+	if client != nil {
+		session.Device = client.DeviceName()
+		session.DeviceType = client.DeviceType
+		session.DeviceFingerprint = client.Fingerprint()
+		session.IP = client.IP
+		session.UserAgent = truncate(client.UserAgent, 512)
+	}
+	if err := s.sessionDAO.Save(ctx, session); err != nil {
+		return errors.BizWrap("oauth2.error.session_storage_failed", err)
+	}
+	return nil
+}
+
+// OnTokenRefreshed 刷新令牌后将会话指向新令牌并延长过期时间
+func (s *SessionService) OnTokenRefreshed(ctx context.Context, sessionId string, tokenId string, expiresAt time.Time) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "SessionService.OnTokenRefreshed")
+	defer span.Finish()
+
+	if err := s.sessionDAO.UpdateToken(ctx, sessionId, tokenId, expiresAt.UTC()); err != nil {
+		return errors.BizWrap("oauth2.error.session_storage_failed", err)
+	}
+	s.lastSeen.Store(sessionId, time.Now())
+	return nil
+}
+
+// Touch 更新会话最近活跃时间，同一会话在 last-seen-interval 内只写入一次
+func (s *SessionService) Touch(ctx context.Context, sessionId string) {
+	if sessionId == "" {
+		return
+	}
+	now := time.Now()
+	interval := time.Duration(s.config.LastSeenInterval) * time.Second
+	if last, ok := s.lastSeen.Load(sessionId); ok && now.Sub(last.(time.Time)) < interval {
+		return
+	}
+	s.lastSeen.Store(sessionId, now)
+	if err := s.sessionDAO.UpdateLastSeen(ctx, sessionId, now.UTC()); err != nil {
+		s.logger.Warn("msg", "更新会话活跃时间失败", "sessionId", sessionId, "error", err.Error())
+	}
+}
+
+// RecordLogin 记录登录历史，登录成功时检测是否为新设备并发布事件；记录失败不影响登录结果
+func (s *SessionService) RecordLogin(ctx context.Context, attempt *LoginAttempt) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "SessionService.RecordLogin")
+	defer span.Finish()
+
+	success := attempt.FailureReason == ""
+	client := attempt.Client
+	if client == nil {
+		client = &model.LoginClient{}
+	}
+	history := &model.LoginHistory{
+		UserId:            attempt.UserId,
+		Identifier:        truncate(attempt.Identifier, 255),
+		Method:            attempt.Method,
+		Success:           success,
+		FailureReason:     truncate(attempt.FailureReason, 128),
+		IP:                client.IP,
+		IPNetwork:         client.IPNetwork(),
+		UserAgent:         truncate(client.UserAgent, 512),
+		Device:            client.DeviceName(),
+		DeviceType:        client.DeviceType,
+		DeviceFingerprint: client.Fingerprint(),
+		SessionId:         attempt.SessionId,
+	}
+	if success && attempt.UserId != "" && attempt.Client != nil {
+		history.NewDevice = s.isNewDevice(ctx, attempt.UserId, history.DeviceFingerprint)
+	}
+	if err := s.historyDAO.Save(ctx, history); err != nil {
+		s.logger.Error("msg", "保存登录历史失败", "userId", attempt.UserId, "method", attempt.Method, "error", err.Error())
+		return
+	}
+
+	if history.NewDevice {
+		s.logger.Info("msg", "检测到新设备登录", "userId", attempt.UserId, "device", history.Device, "ip", history.IP)
+		s.eventBus.Publish(context.WithoutCancel(ctx), eventbus.Event{
+			Type: userEvent.UserNewDeviceLoginEvent,
+			Data: userEvent.NewDeviceLoginData{
+				UserId:   attempt.UserId,
+				Method:   attempt.Method,
+				Device:   history.Device,
+				IP:       history.IP,
+				Time:     history.CreatedAt,
+				Language: client.Language,
+			},
+		}, true)
+	}
+}
+
+// isNewDevice 用户已有成功登录记录，且从未在该设备上登录成功过；首次登录不视为新设备
+func (s *SessionService) isNewDevice(ctx context.Context, userId string, fingerprint string) bool {
+	total, err := s.historyDAO.CountSuccessByUserId(ctx, userId, "")
+	if err != nil || total == 0 {
+		return false
+	}
+	matched, err := s.historyDAO.CountSuccessByUserId(ctx, userId, fingerprint)
+	if err != nil {
+		return false
+	}
+	return matched == 0
+}
+
+// CurrentSessionId 根据上下文中的访问令牌获取当前会话ID
+func (s *SessionService) CurrentSessionId(ctx context.Context) string {
+	accessToken, ok := ctx.Value(userContext.AccessTokenKey).(string)
+	if !ok || accessToken == "" {
+		return ""
+	}
+	tokenInfo, err := s.tokenDAO.GetTokenByAccessToken(ctx, accessToken)
+	if err != nil || tokenInfo == nil {
+		return ""
+	}
+	return tokenInfo.SessionId
+}
+
+// ListSessions 获取用户的活跃会话，令牌已失效的会话会被标记为已撤销
+func (s *SessionService) ListSessions(ctx context.Context, userId string, currentSessionId string) ([]*pb.Session, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "SessionService.ListSessions")
+	defer span.Finish()
+
+	sessions, err := s.activeSessions(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*pb.Session, 0, len(sessions))
+	for i := range sessions {
+		result = append(result, sessions[i].ToProto(sessions[i].Id == currentSessionId))
+	}
+	return result, nil
+}
+
+// RevokeSession 撤销用户的指定会话
+func (s *SessionService) RevokeSession(ctx context.Context, userId string, sessionId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "SessionService.RevokeSession")
+	defer span.Finish()
+
+	session, err := s.sessionDAO.FindById(ctx, sessionId)
+	if err != nil {
+		return errors.BizWrap("oauth2.error.session_retrieval_failed", err)
+	}
+	if session == nil || session.UserId != userId || session.Revoked {
+		return errors.Biz("oauth2.error.session_not_found")
+	}
+	return s.revokeSessions(ctx, []model.OAuth2Session{*session})
+}
+
+// RevokeOtherSessions 撤销当前会话以外的所有会话，返回撤销的会话数
+func (s *SessionService) RevokeOtherSessions(ctx context.Context, userId string, currentSessionId string) (int, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "SessionService.RevokeOtherSessions")
+	defer span.Finish()
+
+	if currentSessionId == "" {
+		return 0, errors.Biz("oauth2.error.session_not_found")
+	}
+	sessions, err := s.activeSessions(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+	others := make([]model.OAuth2Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session.Id != currentSessionId {
+			others = append(others, session)
+		}
+	}
+	if err := s.revokeSessions(ctx, others); err != nil {
+		return 0, err
+	}
+	s.logger.Info("msg", "撤销其他会话", "userId", userId, "revoked", len(others))
+	return len(others), nil
+}
+
+// MarkSessionRevoked 令牌被撤销（如退出登录）后同步标记会话
+func (s *SessionService) MarkSessionRevoked(ctx context.Context, sessionId string) {
+	if sessionId == "" {
+		return
+	}
+	s.lastSeen.Delete(sessionId)
+	if err := s.sessionDAO.RevokeByIds(ctx, []string{sessionId}); err != nil {
+		s.logger.Warn("msg", "标记会话撤销失败", "sessionId", sessionId, "error", err.Error())
+	}
+}
+
+// MarkUserSessionsRevoked 用户全部令牌被撤销后同步标记其全部会话
+func (s *SessionService) MarkUserSessionsRevoked(ctx context.Context, userId string) {
+	if err := s.sessionDAO.RevokeByUserId(ctx, userId); err != nil {
+		s.logger.Warn("msg", "标记用户全部会话撤销失败", "userId", userId, "error", err.Error())
+	}
+}
+
+// RemoveUserSessions 物理删除用户的全部会话和登录历史，注销账号时调用
+func (s *SessionService) RemoveUserSessions(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "SessionService.RemoveUserSessions")
+	defer span.Finish()
+
+	if _, err := s.sessionDAO.RemoveByUserId(ctx, userId); err != nil {
+		return errors.BizWrap("oauth2.error.session_storage_failed", err)
+	}
+	if _, err := s.historyDAO.RemoveByUserId(ctx, userId); err != nil {
+		return errors.BizWrap("oauth2.error.session_storage_failed", err)
+	}
+	return nil
+}
+
+// ListLoginHistory 按条件分页查询登录历史
+func (s *SessionService) ListLoginHistory(ctx context.Context, query *dao.LoginHistoryQuery, page, size int32) ([]*pb.LoginHistory, int32, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "SessionService.ListLoginHistory")
+	defer span.Finish()
+
+	items, total, err := s.historyDAO.FindPage(ctx, query, page, size)
+	if err != nil {
+		return nil, 0, errors.BizWrap("oauth2.error.login_history_retrieval_failed", err)
+	}
+	result := make([]*pb.LoginHistory, 0, len(items))
+	for i := range items {
+		result = append(result, items[i].ToProto())
+	}
+	return result, int32(total), nil
+}
+
+// CleanupExpired 分批物理删除超过保留天数的登录历史与已结束的会话，返回删除条数
+func (s *SessionService) CleanupExpired(ctx context.Context) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "SessionService.CleanupExpired")
+	defer span.Finish()
+
+	// 清理活跃时间缓存中已超过写入间隔的条目
+	interval := time.Duration(s.config.LastSeenInterval) * time.Second
+	s.lastSeen.Range(func(key, value interface{}) bool {
+		if time.Since(value.(time.Time)) >= interval {
+			s.lastSeen.Delete(key)
+		}
+		return true
+	})
+
+	if s.config.HistoryRetentionDays <= 0 {
+		return 0, nil
+	}
+	before := time.Now().UTC().AddDate(0, 0, -s.config.HistoryRetentionDays)
+	batchSize := s.config.HistoryDeleteSize
+	if batchSize <= 0 {
+		batchSize = 5000
+	}
+
+	var deleted int64
+	for {
+		n, err := s.historyDAO.DeleteBefore(ctx, before, batchSize)
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+		if n < int64(batchSize) {
+			break
+		}
+	}
+	for {
+		n, err := s.sessionDAO.DeleteEndedBefore(ctx, before, batchSize)
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+		if n < int64(batchSize) {
+			break
+		}
+	}
+	return deleted, nil
+}
+
+// activeSessions 获取用户的活跃会话，并过滤掉令牌已被撤销或淘汰的会话
+func (s *SessionService) activeSessions(ctx context.Context, userId string) ([]model.OAuth2Session, error) {
+	sessions, err := s.sessionDAO.FindActiveByUserId(ctx, userId)
+	if err != nil {
+		return nil, errors.BizWrap("oauth2.error.session_retrieval_failed", err)
+	}
+	active := make([]model.OAuth2Session, 0, len(sessions))
+	stale := make([]string, 0)
+	for _, session := range sessions {
+		tokenInfo, err := s.tokenDAO.GetTokenByID(ctx, session.TokenId)
+		if err != nil {
+			return nil, errors.BizWrap("oauth2.error.session_retrieval_failed", err)
+		}
+		if tokenInfo == nil || tokenInfo.Revoked {
+			stale = append(stale, session.Id)
+			continue
+		}
+		active = append(active, session)
+	}
+	if len(stale) > 0 {
+		if err := s.sessionDAO.RevokeByIds(ctx, stale); err != nil {
+			s.logger.Warn("msg", "标记失效会话失败", "userId", userId, "error", err.Error())
+		}
+	}
+	return active, nil
+}
+
+// revokeSessions 撤销会话当前的令牌并标记会话
+func (s *SessionService) revokeSessions(ctx context.Context, sessions []model.OAuth2Session) error {
+	ids := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		if err := s.tokenDAO.RevokeToken(ctx, session.TokenId); err != nil {
+			s.logger.Error("msg", "撤销会话令牌失败", "sessionId", session.Id, "tokenId", session.TokenId, "error", err.Error())
+			return errors.BizWrap("oauth2.error.token_revocation_failed", err)
+		}
+		s.lastSeen.Delete(session.Id)
+		ids = append(ids, session.Id)
+	}
+	if err := s.sessionDAO.RevokeByIds(ctx, ids); err != nil {
+		return errors.BizWrap("oauth2.error.session_storage_failed", err)
+	}
+	return nil
+}
+
+// failureReason 取业务错误的消息ID作为失败原因
+func failureReason(err error) string {
+	var bizErr *errors.BizError
+	if errors.As(err, &bizErr) {
+		return bizErr.MsgID
+	}
+	return "oauth2.error.external_login_failed"
+}
+
+// truncate 按字节截断字符串，避免超出列长度
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return strings.ToValidUTF8(value[:max], "")
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/eventbus"
+	"github.com/yb2020/odoc/services/oauth2/dao"
+	"github.com/yb2020/odoc/services/oauth2/model"
+)
+
+// memoryTokenDAO 只实现会话服务用到的令牌查询与撤销
+type memoryTokenDAO struct {
+	dao.TokenDAO
+	tokens map[string]*model.OAuth2Token
+}
+
+func (d *memoryTokenDAO) GetTokenByID(ctx context.Context, tokenID string) (*model.OAuth2Token, error) {
+	return d.tokens[tokenID], nil
+}
+
+func (d *memoryTokenDAO) RevokeToken(ctx context.Context, tokenID string) error {
+	if token, ok := d.tokens[tokenID]; ok {
+		token.Revoked = true
+	}
+	return nil
+}
+
+func TestSessionService(t *testing.T) {
+	db := daotest.NewDB(t, &model.OAuth2Session{}, &model.LoginHistory{})
+	logger := daotest.NewLogger()
+	sessionDAO := dao.NewOAuth2SessionDAO(db, logger)
+	historyDAO := dao.NewLoginHistoryDAO(db, logger)
+	tokens := &memoryTokenDAO{tokens: map[string]*model.OAuth2Token{}}
+	s := NewSessionService(sessionDAO, historyDAO, tokens, eventbus.NewEventBus(),
+		&config.SessionConfig{}, logger, opentracing.NoopTracer{})
+
+	ctx := context.Background()
+	createSession := func(t *testing.T, userId string, sessionId string) {
+		t.Helper()
+		token := &model.OAuth2Token{UserId: userId, TokenId: "token-" + sessionId, SessionId: sessionId}
+		tokens.tokens[token.TokenId] = token
+		if err := s.CreateSession(ctx, sessionId, token, "password", nil, time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+	}
+	addHistory := func(t *testing.T, userId string, fingerprint string, success bool) {
+		t.Helper()
+		history := &model.LoginHistory{UserId: userId, Method: "password", Success: success, DeviceFingerprint: fingerprint}
+		if err := historyDAO.Save(ctx, history); err != nil {
+			t.Fatalf("save history: %v", err)
+		}
+	}
+
+	// 其他用户的记录不影响判断
+	addHistory(t, "other", "fp-a", true)
+	deviceTests := []struct {
+		name    string
+		history []model.LoginHistory
+		want    bool
+	}{
+		{name: "first login", want: false},
+		{name: "only failed logins", history: []model.LoginHistory{{DeviceFingerprint: "fp-a"}}, want: false},
+		{name: "known device", history: []model.LoginHistory{{DeviceFingerprint: "fp-a", Success: true}}, want: false},
+		{name: "unknown device", history: []model.LoginHistory{{DeviceFingerprint: "fp-b", Success: true}}, want: true},
+		{name: "device only seen in failed login", history: []model.LoginHistory{
+			{DeviceFingerprint: "fp-b", Success: true},
+			{DeviceFingerprint: "fp-a"},
+		}, want: true},
+	}
+	for i, tt := range deviceTests {
+		t.Run(tt.name, func(t *testing.T) {
+			userId := fmt.Sprintf("device-%d", i)
+			for _, h := range tt.history {
+				addHistory(t, userId, h.DeviceFingerprint, h.Success)
+			}
+			if got := s.isNewDevice(ctx, userId, "fp-a"); got != tt.want {
+				t.Fatalf("isNewDevice = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("record login marks new device", func(t *testing.T) {
+		mac := &model.LoginClient{OsName: "Mac OS", BrowserName: "Chrome", DeviceType: "desktop"}
+		phone := &model.LoginClient{OsName: "iOS", BrowserName: "Safari", DeviceType: "mobile"}
+
+		s.RecordLogin(ctx, &LoginAttempt{UserId: "recorder", Method: "password", Client: mac})
+		s.RecordLogin(ctx, &LoginAttempt{UserId: "recorder", Method: "password", Client: phone, FailureReason: "oauth2.error.invalid_password"})
+		s.RecordLogin(ctx, &LoginAttempt{UserId: "recorder", Method: "password", Client: phone})
+		s.RecordLogin(ctx, &LoginAttempt{UserId: "recorder", Method: "password", Client: mac})
+
+		items, _, err := historyDAO.FindPage(ctx, &dao.LoginHistoryQuery{UserId: "recorder"}, 1, 10)
+		if err != nil {
+			t.Fatalf("find history: %v", err)
+		}
+		newDevices := 0
+		for _, item := range items {
+			if item.NewDevice {
+				newDevices++
+				if item.DeviceFingerprint != phone.Fingerprint() || !item.Success {
+					t.Fatalf("new device history = %+v, want successful phone login", item)
+				}
+			}
+		}
+		if len(items) != 4 || newDevices != 1 {
+			t.Fatalf("history = %d rows with %d new devices, want 4 rows with 1", len(items), newDevices)
+		}
+	})
+
+	t.Run("revoke other sessions", func(t *testing.T) {
+		createSession(t, "u1", "s1")
+		createSession(t, "u1", "s2")
+		createSession(t, "u1", "s3")
+		createSession(t, "u2", "s4")
+		// 令牌已被淘汰的会话不计入撤销数
+		tokens.tokens["token-s3"].Revoked = true
+
+		revoked, err := s.RevokeOtherSessions(ctx, "u1", "s1")
+		if err != nil {
+			t.Fatalf("RevokeOtherSessions: %v", err)
+		}
+		if revoked != 1 {
+			t.Fatalf("revoked = %d, want 1", revoked)
+		}
+		if tokens.tokens["token-s1"].Revoked || !tokens.tokens["token-s2"].Revoked || tokens.tokens["token-s4"].Revoked {
+			t.Fatalf("token revoked flags s1=%v s2=%v s4=%v", tokens.tokens["token-s1"].Revoked,
+				tokens.tokens["token-s2"].Revoked, tokens.tokens["token-s4"].Revoked)
+		}
+
+		sessions, err := s.ListSessions(ctx, "u1", "s1")
+		if err != nil {
+			t.Fatalf("ListSessions: %v", err)
+		}
+		if len(sessions) != 1 || sessions[0].Id != "s1" || !sessions[0].Current {
+			t.Fatalf("sessions = %+v, want only current s1", sessions)
+		}
+		for _, id := range []string{"s2", "s3"} {
+			session, err := sessionDAO.FindById(ctx, id)
+			if err != nil || session == nil || !session.Revoked {
+				t.Fatalf("session %s = %+v, err = %v, want revoked", id, session, err)
+			}
+		}
+		other, err := s.ListSessions(ctx, "u2", "")
+		if err != nil || len(other) != 1 {
+			t.Fatalf("other user sessions = %+v, err = %v", other, err)
+		}
+	})
+
+	t.Run("revoke other sessions requires current session", func(t *testing.T) {
+		createSession(t, "u3", "s5")
+
+		_, err := s.RevokeOtherSessions(ctx, "u3", "")
+		assertBizError(t, err, "oauth2.error.session_not_found")
+		if tokens.tokens["token-s5"].Revoked {
+			t.Fatalf("token revoked without current session")
+		}
+	})
+}
+
+func assertBizError(t *testing.T, err error, msgID string) {
+	t.Helper()
+	var bizErr *errors.BizError
+	if !stderrors.As(err, &bizErr) || bizErr.MsgID != msgID {
+		t.Fatalf("err = %v, want %s", err, msgID)
+	}
+}
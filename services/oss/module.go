@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/health"
 	"github.com/yb2020/odoc/pkg/logging"
 	"github.com/yb2020/odoc/pkg/middleware"
 	pkgoss "github.com/yb2020/odoc/pkg/oss"
@@ -133,6 +134,13 @@ func (m *OssModule) RegisterJobSchedulers(scheduler *scheduler.Scheduler) {
 	m.logger.Debug("msg", "OSS模块没有Job定时任务，跳过注册")
 }
 
+// RegisterHealthChecks 注册对象存储的健康检查
+func (m *OssModule) RegisterHealthChecks(registry *health.Registry) {
+	if m.storage != nil {
+		registry.Register("s3", health.CheckerFunc(m.storage.Ping))
+	}
+}
+
 // Shutdown 关闭模块
 func (m *OssModule) Shutdown() error {
 	m.logger.Info("msg", "关闭OSS业务模块")
@@ -8,6 +8,7 @@ import (
 
 	"github.com/yb2020/odoc/config"
 	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/metrics"
 	"github.com/yb2020/odoc/pkg/mq/rocketmq"
 	"github.com/yb2020/odoc/pkg/mq/rocketmq/producer"
 	pb "github.com/yb2020/odoc/proto/gen/go/oss"
@@ -133,8 +134,10 @@ func (s *CallbackService) processUploadEvent(ctx context.Context, bucketName, ob
 	}
 
 	if err := s.ossService.UpdateFileStatus(ctx, ossRecord.Id, ossConstant.FileStatusSuccess, event.Size); err != nil {
+		metrics.RecordUpload(ctx, bucketName, false, size)
 		return nil, fmt.Errorf("failed to update file status for record '%d': %w", ossRecord.Id, err)
 	}
+	metrics.RecordUpload(ctx, bucketName, true, size)
 
 	return event, nil
 }
@@ -61,6 +61,9 @@ type OssServiceInterface interface {
 	// DownloadObject 从存储服务下载对象
 	DownloadObject(ctx context.Context, bucketType pb.OSSBucketEnum, objectKey string) (io.ReadCloser, error)
 
+	// DeleteObject 从存储服务删除对象
+	DeleteObject(ctx context.Context, bucketType pb.OSSBucketEnum, objectKey string) error
+
 	// DownloadObjectAsBytes 下载对象并返回字节数组
 	DownloadObjectAsBytes(ctx context.Context, bucketType pb.OSSBucketEnum, objectKey string) ([]byte, error)
 
@@ -567,6 +567,19 @@ func (s *LocalOssService) DownloadObject(ctx context.Context, bucketType pb.OSSB
 	return readCloser, nil
 }
 
+// DeleteObject 从存储服务删除对象
+func (s *LocalOssService) DeleteObject(ctx context.Context, bucketType pb.OSSBucketEnum, objectKey string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "LocalOssService.DeleteObject")
+	defer span.Finish()
+
+	bucketName := constant.EnumToBucketType(s.config, bucketType)
+	if err := s.storage.Delete(ctx, bucketName, objectKey); err != nil {
+		s.logger.Error("从存储服务删除对象失败", "error", err.Error(), "bucket", bucketName, "objectKey", objectKey)
+		return errors.BizWrap("删除对象失败", err)
+	}
+	return nil
+}
+
 // DownloadObjectAsBytes 下载对象并返回字节数组  适用于文件较小的场景
 func (s *LocalOssService) DownloadObjectAsBytes(ctx context.Context, bucketType pb.OSSBucketEnum, objectKey string) ([]byte, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "LocalOssService.DownloadObjectAsBytes")
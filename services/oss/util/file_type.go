@@ -37,6 +37,7 @@ var FileTypes = struct {
 	ODT      FileType
 	ODS      FileType
 	ODP      FileType
+	EPUB     FileType
 	
 	// 音频类型
 	MP3      FileType
@@ -96,6 +97,7 @@ var FileTypes = struct {
 	ODT:      FileType{".odt", "application/vnd.oasis.opendocument.text", "OpenDocument文本文档"},
 	ODS:      FileType{".ods", "application/vnd.oasis.opendocument.spreadsheet", "OpenDocument电子表格"},
 	ODP:      FileType{".odp", "application/vnd.oasis.opendocument.presentation", "OpenDocument演示文稿"},
+	EPUB:     FileType{".epub", "application/epub+zip", "EPUB电子书"},
 	
 	// 音频类型
 	MP3:      FileType{".mp3", "audio/mpeg", "MP3音频"},
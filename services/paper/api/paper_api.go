@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
+	userContext "github.com/yb2020/odoc/pkg/context"
 	"github.com/yb2020/odoc/pkg/logging"
 	"github.com/yb2020/odoc/pkg/response"
 	"github.com/yb2020/odoc/pkg/transport"
@@ -45,7 +46,8 @@ func (api *PaperAPI) GetVersions(c *gin.Context) {
 		return
 	}
 
-	versionResponse, err := api.paperService.GetPaperVersions(c.Request.Context(), req.PaperId)
+	userId, _ := userContext.GetUserID(c.Request.Context())
+	versionResponse, err := api.paperService.GetPaperVersions(c.Request.Context(), userId, req.PaperId, req.PdfId)
 	if err != nil {
 		api.logger.Error("msg", "获取论文版本列表失败", "error", err.Error())
 		response.ErrorNoData(c, "获取论文版本列表失败")
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/paper"
+	"github.com/yb2020/odoc/services/paper/service"
+)
+
+// VenueRankingAPI 发表场所评级API处理器
+type VenueRankingAPI struct {
+	paperJcrService *service.PaperJcrService
+	logger          logging.Logger
+	tracer          opentracing.Tracer
+}
+
+// NewVenueRankingAPI 创建发表场所评级API处理器
+func NewVenueRankingAPI(paperJcrService *service.PaperJcrService, logger logging.Logger, tracer opentracing.Tracer) *VenueRankingAPI {
+	return &VenueRankingAPI{
+		paperJcrService: paperJcrService,
+		logger:          logger,
+		tracer:          tracer,
+	}
+}
+
+/*
+* @api_path: /api/admin/paper/venueRanking/import
+* @method: POST
+* @content-type: application/json
+* @summary: 导入某个评级体系某一年份的场所评级CSV，覆盖同一版本的已有数据
+ */
+func (api *VenueRankingAPI) ImportVenueRanking(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "VenueRankingAPI.ImportVenueRanking")
+	defer span.Finish()
+
+	var req pb.ImportVenueRankingRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	imported, skipped, skipErrors, err := api.paperJcrService.ImportVenueRankings(ctx, req.System, int(req.Year), req.CsvContent)
+	if err != nil {
+		api.logger.Error("msg", "导入场所评级失败", "system", req.System, "year", req.Year, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.ImportVenueRankingResponse{
+		Imported: uint32(imported),
+		Skipped:  uint32(skipped),
+		Errors:   skipErrors,
+	})
+}
+
+/*
+* @api_path: /api/admin/paper/venueRanking/versions
+* @method: GET
+* @content-type: application/json
+* @summary: 获取已导入的评级版本
+ */
+func (api *VenueRankingAPI) ListVenueRankingVersions(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "VenueRankingAPI.ListVenueRankingVersions")
+	defer span.Finish()
+
+	versions, err := api.paperJcrService.ListVenueRankingVersions(ctx)
+	if err != nil {
+		api.logger.Error("msg", "获取场所评级版本失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+	resp := &pb.ListVenueRankingVersionsResponse{}
+	for _, version := range versions {
+		resp.Versions = append(resp.Versions, &pb.VenueRankingVersion{
+			System: version.System,
+			Year:   uint32(version.Year),
+			Count:  uint32(version.Count),
+		})
+	}
+	response.Success(c, "success", resp)
+}
+
+/*
+* @api_path: /api/paper/venueRanking/match
+* @method: GET
+* @content-type: application/json
+* @summary: 查询发表场所在各评级体系中的最新等级
+ */
+func (api *VenueRankingAPI) MatchVenueRanking(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "VenueRankingAPI.MatchVenueRanking")
+	defer span.Finish()
+
+	var req pb.MatchVenueRankingRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	matches, err := api.paperJcrService.MatchVenueRankings(ctx, req.Venue, req.Issn)
+	if err != nil {
+		api.logger.Error("msg", "匹配场所评级失败", "venue", req.Venue, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	resp := &pb.MatchVenueRankingResponse{}
+	for _, match := range matches {
+		resp.Rankings = append(resp.Rankings, &pb.VenueRankingInfo{
+			System:       match.System,
+			Year:         uint32(match.Year),
+			Venue:        match.Venue,
+			Abbreviation: match.Abbreviation,
+			Rank:         match.Rank,
+			ImpactFactor: match.ImpactFactor,
+			Category:     match.Category,
+			MatchedBy:    match.MatchedBy,
+		})
+	}
+	response.Success(c, "success", resp)
+}
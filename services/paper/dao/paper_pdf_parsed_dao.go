@@ -100,3 +100,24 @@ func (d *PaperPdfParsedDAO) HasExistBySourcePdfFileSHA256AndVersion(ctx context.
 
 	return true, nil
 }
+
+// GetAllBySourcePdfFileSHA256 查询原始文件的全部解析结果，包括已逻辑删除的
+func (d *PaperPdfParsedDAO) GetAllBySourcePdfFileSHA256(ctx context.Context, sha256 string) ([]*model.PaperPdfParsed, error) {
+	var records []*model.PaperPdfParsed
+	result := d.GetDB(ctx).Where("source_pdf_sha256 = ?", sha256).Find(&records)
+	if result.Error != nil {
+		d.logger.Error("查询PaperPdfParsed记录失败", "error", result.Error.Error(), "source_pdf_sha256", sha256)
+		return nil, result.Error
+	}
+	return records, nil
+}
+
+// RemoveBySourcePdfFileSHA256 物理删除原始文件的全部解析结果
+func (d *PaperPdfParsedDAO) RemoveBySourcePdfFileSHA256(ctx context.Context, sha256 string) error {
+	result := d.GetDB(ctx).Where("source_pdf_sha256 = ?", sha256).Delete(&model.PaperPdfParsed{})
+	if result.Error != nil {
+		d.logger.Error("删除PaperPdfParsed记录失败", "error", result.Error.Error(), "source_pdf_sha256", sha256)
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package dao
+
+import (
+	"context"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/paper/model"
+	"gorm.io/gorm"
+)
+
+// VenueRankingVersion 某个评级体系某一年份的导入情况
+type VenueRankingVersion struct {
+	System string `gorm:"column:ranking_system"`
+	Year   int    `gorm:"column:ranking_year"`
+	Count  int64  `gorm:"column:count"`
+}
+
+// PaperVenueRankingDAO 提供发表场所评级数据访问功能
+type PaperVenueRankingDAO struct {
+	*baseDao.GormBaseDAO[model.PaperVenueRanking]
+	logger logging.Logger
+}
+
+// NewPaperVenueRankingDAO 创建一个新的发表场所评级DAO
+func NewPaperVenueRankingDAO(db *gorm.DB, logger logging.Logger) *PaperVenueRankingDAO {
+	return &PaperVenueRankingDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.PaperVenueRanking](db, logger),
+		logger:      logger,
+	}
+}
+
+// FindAll 获取全部评级记录，用于构建内存中的匹配索引
+func (d *PaperVenueRankingDAO) FindAll(ctx context.Context) ([]model.PaperVenueRanking, error) {
+	var rankings []model.PaperVenueRanking
+	result := d.GetDB(ctx).Where("is_deleted = ?", false).Find(&rankings)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取场所评级列表失败", "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return rankings, nil
+}
+
+// ReplaceVersion 用新数据整体替换某个评级体系某一年份的记录，重复导入同一版本不会产生重复数据
+func (d *PaperVenueRankingDAO) ReplaceVersion(ctx context.Context, system string, year int, rankings []*model.PaperVenueRanking) error {
+	return d.GetDB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("ranking_system = ? AND ranking_year = ?", system, year).Delete(&model.PaperVenueRanking{}).Error; err != nil {
+			d.logger.Error("msg", "删除旧版本场所评级失败", "system", system, "year", year, "error", err.Error())
+			return err
+		}
+		if len(rankings) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(rankings, 200).Error; err != nil {
+			d.logger.Error("msg", "批量保存场所评级失败", "system", system, "year", year, "error", err.Error())
+			return err
+		}
+		return nil
+	})
+}
+
+// CountVersions 统计各评级体系各年份的记录数
+func (d *PaperVenueRankingDAO) CountVersions(ctx context.Context) ([]VenueRankingVersion, error) {
+	var versions []VenueRankingVersion
+	result := d.GetDB(ctx).Model(&model.PaperVenueRanking{}).
+		Select("ranking_system, ranking_year, COUNT(*) AS count").
+		Where("is_deleted = ?", false).
+		Group("ranking_system, ranking_year").
+		Order("ranking_system ASC, ranking_year DESC").
+		Scan(&versions)
+	if result.Error != nil {
+		d.logger.Error("msg", "统计场所评级版本失败", "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return versions, nil
+}
@@ -0,0 +1,63 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/paper/model"
+	"gorm.io/gorm"
+)
+
+// PaperVersionDAO 提供论文版本数据访问功能
+type PaperVersionDAO struct {
+	*baseDao.GormBaseDAO[model.PaperVersion]
+	logger logging.Logger
+}
+
+// NewPaperVersionDAO 创建一个新的论文版本DAO
+func NewPaperVersionDAO(db *gorm.DB, logger logging.Logger) *PaperVersionDAO {
+	return &PaperVersionDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.PaperVersion](db, logger),
+		logger:      logger,
+	}
+}
+
+// FindByUserIdAndPaperId 获取用户登记的论文全部版本，按版本序号升序
+func (d *PaperVersionDAO) FindByUserIdAndPaperId(ctx context.Context, userId string, paperId string) ([]model.PaperVersion, error) {
+	var versions []model.PaperVersion
+	result := d.GetDB(ctx).Where("user_id = ? AND paper_id = ? AND is_deleted = ?", userId, paperId, false).Order("version_no ASC").Find(&versions)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取论文版本列表失败", "user_id", userId, "paper_id", paperId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return versions, nil
+}
+
+// FindByUserIdAndPdfId 根据PDF ID获取用户登记的版本记录，同一用户的一个PDF只会作为一篇论文的版本
+func (d *PaperVersionDAO) FindByUserIdAndPdfId(ctx context.Context, userId string, pdfId string) (*model.PaperVersion, error) {
+	var version model.PaperVersion
+	result := d.GetDB(ctx).Where("user_id = ? AND pdf_id = ? AND is_deleted = ?", userId, pdfId, false).First(&version)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取论文版本失败", "user_id", userId, "pdf_id", pdfId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &version, nil
+}
+
+// GetMaxVersionNo 获取用户登记的论文当前最大的版本序号，没有版本时返回0
+func (d *PaperVersionDAO) GetMaxVersionNo(ctx context.Context, userId string, paperId string) (int, error) {
+	var maxVersionNo int
+	result := d.GetDB(ctx).Model(&model.PaperVersion{}).
+		Where("user_id = ? AND paper_id = ?", userId, paperId).
+		Select("COALESCE(MAX(version_no), 0)").Scan(&maxVersionNo)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取论文最大版本序号失败", "user_id", userId, "paper_id", paperId, "error", result.Error.Error())
+		return 0, result.Error
+	}
+	return maxVersionNo, nil
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// PaperVenueRanking 发表场所评级，按评级体系和年份分版本导入（JCR、CCF、CORE、中科院分区）
+type PaperVenueRanking struct {
+	model.BaseModel         // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	System          string  `json:"system" gorm:"column:ranking_system;type:varchar(16);index:idx_venue_ranking_version;not null;comment:评级体系"` // 评级体系：jcr、cas、ccf、core
+	Year            int     `json:"year" gorm:"column:ranking_year;type:int;index:idx_venue_ranking_version;not null;comment:评级年份"`             // 评级年份
+	Venue           string  `json:"venue" gorm:"column:venue;type:varchar(512);not null;comment:场所全称"`                                          // 场所全称
+	VenueKey        string  `json:"venueKey" gorm:"column:venue_key;type:varchar(512);index;comment:归一化后的场所名称"`                                 // 归一化后的场所名称
+	Abbreviation    string  `json:"abbreviation" gorm:"column:abbreviation;type:varchar(128);comment:场所缩写"`                                     // 期刊缩写或会议简称
+	AbbreviationKey string  `json:"abbreviationKey" gorm:"column:abbreviation_key;type:varchar(128);index;comment:归一化后的场所缩写"`                   // 归一化后的缩写
+	Issn            string  `json:"issn" gorm:"column:issn;type:varchar(16);index;comment:ISSN"`                                                // ISSN
+	EIssn           string  `json:"eIssn" gorm:"column:e_issn;type:varchar(16);index;comment:eISSN"`                                            // eISSN
+	Rank            string  `json:"rank" gorm:"column:ranking;type:varchar(16);comment:等级"`                                                     // 等级：Q1、1区、A、A*
+	ImpactFactor    float32 `json:"impactFactor" gorm:"column:impact_factor;comment:影响因子"`                                                      // 影响因子，仅JCR
+	Category        string  `json:"category" gorm:"column:category;type:varchar(255);comment:学科分类"`                                             // 学科分类
+}
+
+// TableName 返回表名
+func (PaperVenueRanking) TableName() string {
+	return "t_paper_venue_ranking"
+}
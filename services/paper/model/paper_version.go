@@ -0,0 +1,32 @@
+package model
+
+import (
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// 论文版本来源
+const (
+	PaperVersionSourceUserUpload = "user_upload" // 用户上传
+	PaperVersionSourceArxiv      = "arxiv"       // arXiv预印本
+)
+
+// PaperVersion 论文版本记录，同一篇论文的不同PDF（如arXiv v1、v3、camera-ready）按版本号排列
+// 版本关系由用户在自己的文库中登记，按用户隔离；同一用户的一个PDF只属于一篇论文，同一论文的版本序号不重复
+type PaperVersion struct {
+	model.BaseModel        // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	UserId          string `json:"userId" gorm:"column:user_id;size:36;not null;default:'';uniqueIndex:idx_unique_t_paper_version_user_pdf;uniqueIndex:idx_unique_t_paper_version_user_paper_no;comment:用户ID"` // 用户ID
+	PaperId         string `json:"paperId" gorm:"column:paper_id;size:36;not null;uniqueIndex:idx_unique_t_paper_version_user_paper_no;comment:论文ID"`                                                          // 论文ID
+	PdfId           string `json:"pdfId" gorm:"column:pdf_id;size:36;not null;uniqueIndex:idx_unique_t_paper_version_user_pdf;comment:PDF文件ID"`                                                                // PDF文件ID
+	VersionNo       int    `json:"versionNo" gorm:"column:version_no;type:int;not null;uniqueIndex:idx_unique_t_paper_version_user_paper_no;comment:版本序号"`                                                     // 版本序号，从1开始递增
+	Label           string `json:"label" gorm:"column:label;type:varchar(64);comment:版本名称"`                                                                                                                    // 版本名称，如 arXiv v3、camera-ready
+	Source          string `json:"source" gorm:"column:source;type:varchar(20);comment:版本来源"`                                                                                                                  // 版本来源：user_upload、arxiv
+	ArxivId         string `json:"arxivId" gorm:"column:arxiv_id;type:varchar(32);comment:arXiv编号(不含版本号)"`                                                                                                     // arXiv编号，不含版本号
+	ArxivVersion    int    `json:"arxivVersion" gorm:"column:arxiv_version;type:int;comment:arXiv版本号"`                                                                                                         // arXiv版本号，未知为0
+	Doi             string `json:"doi" gorm:"column:doi;type:varchar(255);comment:DOI"`                                                                                                                        // DOI
+	PublishDate     string `json:"publishDate" gorm:"column:publish_date;type:varchar(20);comment:该版本的发布日期"`                                                                                                   // 该版本的发布日期
+}
+
+// TableName 返回表名
+func (PaperVersion) TableName() string {
+	return "t_paper_version"
+}
@@ -1,6 +1,8 @@
 package paper
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/pkg/scheduler"
@@ -20,6 +22,9 @@ import (
 // 编译时类型检查：确保 PaperModule 实现了 registry.Module 接口
 var _ registry.Module = (*PaperModule)(nil)
 
+// 编译时类型检查：确保 PaperModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*PaperModule)(nil)
+
 // Module 导出模块实例，用于自动发现和注册
 var Module = &PaperModule{}
 
@@ -40,6 +45,8 @@ type PaperModule struct {
 	paperResourcesDao       *dao.PaperResourcesDAO
 	paperJcrDao             *dao.PaperJcrDAO
 	paperPdfParsedDao       *dao.PaperPdfParsedDAO
+	paperVersionDao         *dao.PaperVersionDAO
+	paperVenueRankingDao    *dao.PaperVenueRankingDAO
 
 	userService *userService.UserService
 	// 服务实例
@@ -55,7 +62,8 @@ type PaperModule struct {
 	paperPdfParsedService       *service.PaperPdfParsedService
 
 	// API实例
-	paperAPI *api.PaperAPI
+	paperAPI        *api.PaperAPI
+	venueRankingAPI *api.VenueRankingAPI
 }
 
 // NewPaperModule 创建论文模块
@@ -116,10 +124,12 @@ func (m *PaperModule) Initialize() error {
 	m.paperResourcesDao = dao.NewPaperResourcesDAO(m.db, m.logger)
 	// 初始化paper_jcr DAO
 	m.paperJcrDao = dao.NewPaperJcrDAO(m.db, m.logger)
+	m.paperVenueRankingDao = dao.NewPaperVenueRankingDAO(m.db, m.logger)
 	m.paperPdfParsedDao = dao.NewPaperPdfParsedDAO(m.db, m.logger)
+	m.paperVersionDao = dao.NewPaperVersionDAO(m.db, m.logger)
 
 	// 初始化服务
-	m.paperService = service.NewPaperService(m.logger, m.tracer, m.paperDao)
+	m.paperService = service.NewPaperService(m.logger, m.tracer, m.paperDao, m.paperVersionDao)
 	m.paperAccessService = service.NewPaperAccessService(m.logger, m.tracer, m.paperAccessDao)
 	m.paperAnswerService = service.NewPaperAnswerService(m.logger, m.tracer, m.paperAnswerDao)
 	m.paperAttachmentService = service.NewPaperAttachmentService(m.logger, m.tracer, m.paperAttachmentDao)
@@ -128,16 +138,39 @@ func (m *PaperModule) Initialize() error {
 	m.paperQuestionService = service.NewPaperQuestionService(m.logger, m.tracer, m.paperQuestionDao)
 	m.paperResourcesService = service.NewPaperResourcesService(m.logger, m.tracer, m.paperResourcesDao)
 	// 初始化paper_jcr Service
-	m.paperJcrService = service.NewPaperJcrService(m.logger, m.tracer, m.paperJcrDao)
+	m.paperJcrService = service.NewPaperJcrService(m.logger, m.tracer, m.paperJcrDao, m.paperVenueRankingDao)
 	// 初始化paper_pdf_parsed Service
 	m.paperPdfParsedService = service.NewPaperPdfParsedService(m.logger, m.tracer, m.paperPdfParsedDao)
 
 	// 初始化API
 	m.paperAPI = api.NewPaperAPI(m.paperService, m.logger, m.tracer)
+	m.venueRankingAPI = api.NewVenueRankingAPI(m.paperJcrService, m.logger, m.tracer)
 
 	return nil
 }
 
+// DeleteUserData 注销账号时物理删除用户的论文访问记录、评论、点赞、提问、回答和登记的论文版本
+// 论文、附件和解析结果属于公共论文库，不随用户删除
+func (m *PaperModule) DeleteUserData(ctx context.Context, userId string) error {
+	// 这些表的 user_id 为历史遗留的数字ID，按创建人区分归属
+	removers := []func(context.Context, string) (int64, error){
+		m.paperCommentApprovalDao.RemoveByCreatorId,
+		m.paperCommentDao.RemoveByCreatorId,
+		m.paperAnswerDao.RemoveByCreatorId,
+		m.paperQuestionDao.RemoveByCreatorId,
+		m.paperAccessDao.RemoveByCreatorId,
+	}
+	for _, remove := range removers {
+		if _, err := remove(ctx, userId); err != nil {
+			return err
+		}
+	}
+	if _, err := m.paperVersionDao.RemoveByUserId(ctx, userId); err != nil {
+		return err
+	}
+	return nil
+}
+
 // RegisterRoutes 注册路由
 func (m *PaperModule) RegisterRoutes(r *gin.Engine) {
 	paperGroup := r.Group("/api/paper")
@@ -153,6 +186,14 @@ func (m *PaperModule) RegisterRoutes(r *gin.Engine) {
 
 		paperGroup.GET("/versions", m.paperAPI.GetVersions)
 		paperGroup.POST("/getPaperDetailInfo", m.paperAPI.GetPaperDetailInfo)
+		paperGroup.GET("/venueRanking/match", m.venueRankingAPI.MatchVenueRanking)
+	}
+
+	adminGroup := r.Group("/api/admin/paper")
+	adminGroup.Use(m.authMiddleware.AuthRequired())
+	{
+		adminGroup.POST("/venueRanking/import", m.venueRankingAPI.ImportVenueRanking)
+		adminGroup.GET("/venueRanking/versions", m.venueRankingAPI.ListVenueRankingVersions)
 	}
 }
 
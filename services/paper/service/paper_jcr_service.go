@@ -2,33 +2,131 @@ package service
 
 import (
 	"context"
+	"sync"
 
 	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/errors"
 	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/venue"
 	"github.com/yb2020/odoc/services/paper/dao"
 	"github.com/yb2020/odoc/services/paper/model"
 )
 
 // PaperJcrService 论文JCR服务
 type PaperJcrService struct {
-	paperJcrDAO *dao.PaperJcrDAO
-	logger      logging.Logger
-	tracer      opentracing.Tracer
+	paperJcrDAO          *dao.PaperJcrDAO
+	paperVenueRankingDAO *dao.PaperVenueRankingDAO
+	logger               logging.Logger
+	tracer               opentracing.Tracer
+
+	// 评级数据的内存索引，首次匹配时构建，导入新数据后失效
+	rankingIndexMu sync.RWMutex
+	rankingIndex   *venueRankingIndex
 }
 
 // NewPaperJcrService 创建一个新的论文JCR服务
-func NewPaperJcrService(logger logging.Logger, tracer opentracing.Tracer, paperJcrDAO *dao.PaperJcrDAO) *PaperJcrService {
+func NewPaperJcrService(logger logging.Logger, tracer opentracing.Tracer, paperJcrDAO *dao.PaperJcrDAO, paperVenueRankingDAO *dao.PaperVenueRankingDAO) *PaperJcrService {
 	return &PaperJcrService{
-		paperJcrDAO: paperJcrDAO,
-		logger:      logger,
-		tracer:      tracer,
+		paperJcrDAO:          paperJcrDAO,
+		paperVenueRankingDAO: paperVenueRankingDAO,
+		logger:               logger,
+		tracer:               tracer,
+	}
+}
+
+// GetPaperJcrEntityByVenue 根据venue查找PaperJcrEntity，精确查找不到时使用导入的JCR评级进行匹配
+func (s *PaperJcrService) GetPaperJcrEntityByVenue(ctx context.Context, venueName string) (*model.PaperJcrEntity, error) {
+	if venueName == "" {
+		return nil, nil
+	}
+	entity, err := s.paperJcrDAO.GetPaperJcrEntityByVenue(ctx, venueName)
+	if err != nil || entity != nil {
+		return entity, err
+	}
+
+	matches, err := s.MatchVenueRankings(ctx, venueName, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		if match.System == venue.SystemJCR {
+			entity = model.NewPaperJcrEntityWithVenue(venueName)
+			entity.ImpactOfFactor = match.ImpactFactor
+			entity.JcrPartion = match.Rank
+			entity.Source = venue.SystemJCR
+			return entity, nil
+		}
+	}
+	return nil, nil
+}
+
+// ImportVenueRankings 导入某个评级体系某一年份的CSV，同一版本重复导入时整体覆盖
+func (s *PaperJcrService) ImportVenueRankings(ctx context.Context, system string, year int, csvContent string) (imported int, skipped int, skipErrors []string, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperJcrService.ImportVenueRankings")
+	defer span.Finish()
+
+	if !venue.IsValidSystem(system) {
+		return 0, 0, nil, errors.Biz("paper.venue_ranking.errors.invalid_system")
+	}
+	rankings, skipped, skipErrors, err := parseVenueRankingCSV(system, year, csvContent)
+	if err != nil {
+		s.logger.Warn("msg", "解析场所评级CSV失败", "system", system, "year", year, "error", err.Error())
+		return 0, 0, nil, errors.Biz("paper.venue_ranking.errors.invalid_csv")
 	}
+	if err := s.paperVenueRankingDAO.ReplaceVersion(ctx, system, year, rankings); err != nil {
+		return 0, 0, nil, errors.Biz("paper.venue_ranking.errors.import_failed")
+	}
+
+	s.rankingIndexMu.Lock()
+	s.rankingIndex = nil
+	s.rankingIndexMu.Unlock()
+
+	s.logger.Info("msg", "导入场所评级成功", "system", system, "year", year, "imported", len(rankings), "skipped", skipped)
+	return len(rankings), skipped, skipErrors, nil
+}
+
+// ListVenueRankingVersions 获取已导入的评级版本
+func (s *PaperJcrService) ListVenueRankingVersions(ctx context.Context) ([]dao.VenueRankingVersion, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperJcrService.ListVenueRankingVersions")
+	defer span.Finish()
+
+	versions, err := s.paperVenueRankingDAO.CountVersions(ctx)
+	if err != nil {
+		return nil, errors.Biz("paper.venue_ranking.errors.list_failed")
+	}
+	return versions, nil
 }
 
-// GetPaperJcrEntityByVenue 根据venue查找PaperJcrEntity
-func (s *PaperJcrService) GetPaperJcrEntityByVenue(ctx context.Context, venue string) (*model.PaperJcrEntity, error) {
-	if venue == "" {
+// MatchVenueRankings 查询场所在各评级体系中最新年份的等级，按 jcr、cas、ccf、core 顺序返回
+func (s *PaperJcrService) MatchVenueRankings(ctx context.Context, venueName string, issn string) ([]*VenueRankingMatch, error) {
+	if venueName == "" && issn == "" {
 		return nil, nil
 	}
-	return s.paperJcrDAO.GetPaperJcrEntityByVenue(ctx, venue)
+	index, err := s.getRankingIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return index.match(venueName, issn), nil
+}
+
+// getRankingIndex 获取评级数据的内存索引，不存在时从数据库构建
+func (s *PaperJcrService) getRankingIndex(ctx context.Context) (*venueRankingIndex, error) {
+	s.rankingIndexMu.RLock()
+	index := s.rankingIndex
+	s.rankingIndexMu.RUnlock()
+	if index != nil {
+		return index, nil
+	}
+
+	s.rankingIndexMu.Lock()
+	defer s.rankingIndexMu.Unlock()
+	if s.rankingIndex != nil {
+		return s.rankingIndex, nil
+	}
+	rankings, err := s.paperVenueRankingDAO.FindAll(ctx)
+	if err != nil {
+		return nil, errors.Biz("paper.venue_ranking.errors.list_failed")
+	}
+	s.rankingIndex = newVenueRankingIndex(rankings)
+	return s.rankingIndex, nil
 }
@@ -67,3 +67,19 @@ func (s *PaperPdfParsedService) HasExistBySourcePdfFileSHA256AndVersion(ctx cont
 
 	return s.paperPdfParsedDAO.HasExistBySourcePdfFileSHA256AndVersion(ctx, sha256, version)
 }
+
+// GetAllBySourcePdfFileSHA256 查询原始文件的全部解析结果，包括已逻辑删除的
+func (s *PaperPdfParsedService) GetAllBySourcePdfFileSHA256(ctx context.Context, sha256 string) ([]*model.PaperPdfParsed, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperPdfParsedService.GetAllBySourcePdfFileSHA256")
+	defer span.Finish()
+
+	return s.paperPdfParsedDAO.GetAllBySourcePdfFileSHA256(ctx, sha256)
+}
+
+// RemoveBySourcePdfFileSHA256 物理删除原始文件的全部解析结果记录，解析文件由调用方删除
+func (s *PaperPdfParsedService) RemoveBySourcePdfFileSHA256(ctx context.Context, sha256 string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperPdfParsedService.RemoveBySourcePdfFileSHA256")
+	defer span.Finish()
+
+	return s.paperPdfParsedDAO.RemoveBySourcePdfFileSHA256(ctx, sha256)
+}
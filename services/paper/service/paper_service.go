@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/pkg/errors"
@@ -17,9 +18,10 @@ import (
 
 // PaperService 论文服务实现
 type PaperService struct {
-	paperDAO *dao.PaperDAO
-	logger   logging.Logger
-	tracer   opentracing.Tracer
+	paperDAO        *dao.PaperDAO
+	paperVersionDAO *dao.PaperVersionDAO
+	logger          logging.Logger
+	tracer          opentracing.Tracer
 }
 
 // NewPaperService 创建新的论文服务
@@ -27,11 +29,13 @@ func NewPaperService(
 	logger logging.Logger,
 	tracer opentracing.Tracer,
 	paperDAO *dao.PaperDAO,
+	paperVersionDAO *dao.PaperVersionDAO,
 ) *PaperService {
 	return &PaperService{
-		logger:   logger,
-		tracer:   tracer,
-		paperDAO: paperDAO,
+		logger:          logger,
+		tracer:          tracer,
+		paperDAO:        paperDAO,
+		paperVersionDAO: paperVersionDAO,
 	}
 }
 
@@ -287,8 +291,9 @@ func (s *PaperService) IsPrivatePaper(ctx context.Context, paperId string) (bool
 	return constants.PaperStatus(paper.Status) == constants.Private, nil
 }
 
-// GetPaperVersions 获取论文版本列表
-func (s *PaperService) GetPaperVersions(ctx context.Context, paperId string) (*pb.GetPaperVersionsResponse, error) {
+// GetPaperVersions 获取用户登记的论文版本列表
+// 未登记过版本的论文只有用户上传的一个版本；pdfId为当前阅读的PDF，为空时以最新版本为当前版本
+func (s *PaperService) GetPaperVersions(ctx context.Context, userId string, paperId string, pdfId string) (*pb.GetPaperVersionsResponse, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperService.GetPaperVersions")
 	defer span.Finish()
 
@@ -300,17 +305,133 @@ func (s *PaperService) GetPaperVersions(ctx context.Context, paperId string) (*p
 	if paper == nil {
 		return nil, errors.Biz("param error ! paper not found")
 	}
-	privatePaperVersionInfo := &pb.PaperVersionInfo{
-		Type:        pb.PaperVersionType_PAPER_PDF,
-		Name:        "用户上传",
-		JumpUrl:     "",
-		CurVersion:  true,
-		LastVersion: true,
-		DatePrefix:  paper.PublishDate,
+	// 新版本PDF上传时会生成自己的论文，版本关系以PDF登记的版本记录为准
+	versionPaperId := paper.Id
+	if pdfId != "" {
+		current, err := s.GetPaperVersionByPdfId(ctx, userId, pdfId)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil {
+			versionPaperId = current.PaperId
+		}
+	}
+	versions, err := s.GetPaperVersionRecords(ctx, userId, versionPaperId)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		privatePaperVersionInfo := &pb.PaperVersionInfo{
+			Type:        pb.PaperVersionType_PAPER_PDF,
+			Name:        "用户上传",
+			PdfId:       pdfId,
+			JumpUrl:     "",
+			CurVersion:  true,
+			LastVersion: true,
+			DatePrefix:  paper.PublishDate,
+		}
+		return &pb.GetPaperVersionsResponse{
+			PrivateVersions: []*pb.PaperVersionInfo{privatePaperVersionInfo},
+		}, nil
+	}
+
+	// 当前PDF不在版本列表中时，以最新版本为当前版本
+	curPdfId := versions[len(versions)-1].PdfId
+	for _, version := range versions {
+		if version.PdfId == pdfId {
+			curPdfId = pdfId
+			break
+		}
+	}
+	privateVersions := make([]*pb.PaperVersionInfo, 0, len(versions))
+	for i, version := range versions {
+		datePrefix := version.PublishDate
+		if datePrefix == "" {
+			datePrefix = paper.PublishDate
+		}
+		privateVersions = append(privateVersions, &pb.PaperVersionInfo{
+			Type:        pb.PaperVersionType_PAPER_PDF,
+			Name:        PaperVersionName(&version),
+			PdfId:       version.PdfId,
+			CurVersion:  version.PdfId == curPdfId,
+			LastVersion: i == len(versions)-1,
+			DatePrefix:  datePrefix,
+		})
+	}
+	return &pb.GetPaperVersionsResponse{
+		PrivateVersions: privateVersions,
+	}, nil
+}
+
+// GetPaperVersionRecords 获取用户为论文登记的版本记录，按版本序号升序
+func (s *PaperService) GetPaperVersionRecords(ctx context.Context, userId string, paperId string) ([]model.PaperVersion, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperService.GetPaperVersionRecords")
+	defer span.Finish()
+
+	versions, err := s.paperVersionDAO.FindByUserIdAndPaperId(ctx, userId, paperId)
+	if err != nil {
+		s.logger.Error("获取论文版本列表失败", "user_id", userId, "paper_id", paperId, "error", err)
+		return nil, errors.Biz("paper.paper_version.errors.get_failed")
 	}
+	return versions, nil
+}
+
+// GetPaperVersionByPdfId 获取用户为PDF登记的论文版本记录，未登记时返回nil
+func (s *PaperService) GetPaperVersionByPdfId(ctx context.Context, userId string, pdfId string) (*model.PaperVersion, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperService.GetPaperVersionByPdfId")
+	defer span.Finish()
+
+	version, err := s.paperVersionDAO.FindByUserIdAndPdfId(ctx, userId, pdfId)
+	if err != nil {
+		s.logger.Error("获取论文版本失败", "user_id", userId, "pdf_id", pdfId, "error", err)
+		return nil, errors.Biz("paper.paper_version.errors.get_failed")
+	}
+	return version, nil
+}
+
+// 并发登记论文版本发生唯一索引冲突时的最大尝试次数
+const paperVersionSaveAttempts = 3
+
+// AddPaperVersion 为用户的论文登记一个新版本，版本序号自动递增
+// PDF已登记过版本时直接返回已有记录，调用方需检查其所属论文；
+// 并发登记时由唯一索引拒绝重复的PDF或版本序号，重新读取后重试
+func (s *PaperService) AddPaperVersion(ctx context.Context, version *model.PaperVersion) (*model.PaperVersion, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperService.AddPaperVersion")
+	defer span.Finish()
 
-	versionResponse := &pb.GetPaperVersionsResponse{
-		PrivateVersions: []*pb.PaperVersionInfo{privatePaperVersionInfo},
+	var saveErr error
+	for attempt := 0; attempt < paperVersionSaveAttempts; attempt++ {
+		existing, err := s.paperVersionDAO.FindByUserIdAndPdfId(ctx, version.UserId, version.PdfId)
+		if err != nil {
+			s.logger.Error("获取论文版本失败", "user_id", version.UserId, "pdf_id", version.PdfId, "error", err)
+			return nil, errors.Biz("paper.paper_version.errors.get_failed")
+		}
+		if existing != nil {
+			return existing, nil
+		}
+		maxVersionNo, err := s.paperVersionDAO.GetMaxVersionNo(ctx, version.UserId, version.PaperId)
+		if err != nil {
+			s.logger.Error("获取论文最大版本序号失败", "user_id", version.UserId, "paper_id", version.PaperId, "error", err)
+			return nil, errors.Biz("paper.paper_version.errors.get_failed")
+		}
+		version.Id = ""
+		version.VersionNo = maxVersionNo + 1
+		if saveErr = s.paperVersionDAO.Save(ctx, version); saveErr == nil {
+			return version, nil
+		}
+		s.logger.Warn("保存论文版本冲突，重试", "user_id", version.UserId, "paper_id", version.PaperId, "pdf_id", version.PdfId, "attempt", attempt, "error", saveErr)
+	}
+	s.logger.Error("保存论文版本失败", "user_id", version.UserId, "paper_id", version.PaperId, "pdf_id", version.PdfId, "error", saveErr)
+	return nil, errors.Biz("paper.paper_version.errors.save_failed")
+}
+
+// PaperVersionName 版本的展示名称，未设置名称时使用arXiv版本号或版本序号
+func PaperVersionName(version *model.PaperVersion) string {
+	if version.Label != "" {
+		return version.Label
+	}
+	if version.ArxivVersion > 0 {
+		return fmt.Sprintf("arXiv v%d", version.ArxivVersion)
 	}
-	return versionResponse, nil
+	return fmt.Sprintf("版本%d", version.VersionNo)
 }
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	"github.com/yb2020/odoc/services/paper/dao"
+	"github.com/yb2020/odoc/services/paper/model"
+)
+
+func TestAddPaperVersionScopedPerUser(t *testing.T) {
+	db := daotest.NewDB(t, &model.PaperVersion{})
+	logger := daotest.NewLogger()
+	s := NewPaperService(logger, opentracing.NoopTracer{}, dao.NewPaperDAO(db, logger), dao.NewPaperVersionDAO(db, logger))
+	ctx := context.Background()
+
+	// u1 把同一个公开PDF登记为论文A的第2个版本，不影响u2把它登记到论文B
+	steps := []struct {
+		userId      string
+		paperId     string
+		pdfId       string
+		wantPaperId string
+		wantNo      int
+	}{
+		{userId: "u1", paperId: "A", pdfId: "pdf-a", wantPaperId: "A", wantNo: 1},
+		{userId: "u1", paperId: "A", pdfId: "pdf-shared", wantPaperId: "A", wantNo: 2},
+		{userId: "u2", paperId: "B", pdfId: "pdf-b", wantPaperId: "B", wantNo: 1},
+		{userId: "u2", paperId: "B", pdfId: "pdf-shared", wantPaperId: "B", wantNo: 2},
+		// 已登记的PDF返回已有记录，由调用方判断论文是否一致
+		{userId: "u1", paperId: "B", pdfId: "pdf-shared", wantPaperId: "A", wantNo: 2},
+	}
+	for _, step := range steps {
+		version, err := s.AddPaperVersion(ctx, &model.PaperVersion{UserId: step.userId, PaperId: step.paperId, PdfId: step.pdfId})
+		if err != nil {
+			t.Fatalf("AddPaperVersion(%s, %s, %s): %v", step.userId, step.paperId, step.pdfId, err)
+		}
+		if version.PaperId != step.wantPaperId || version.VersionNo != step.wantNo {
+			t.Fatalf("AddPaperVersion(%s, %s, %s) = %s v%d, want %s v%d", step.userId, step.paperId, step.pdfId,
+				version.PaperId, version.VersionNo, step.wantPaperId, step.wantNo)
+		}
+	}
+
+	for userId, wantPaperId := range map[string]string{"u1": "A", "u2": "B"} {
+		version, err := s.GetPaperVersionByPdfId(ctx, userId, "pdf-shared")
+		if err != nil || version == nil || version.PaperId != wantPaperId {
+			t.Fatalf("GetPaperVersionByPdfId(%s) = %+v, %v, want paper %s", userId, version, err, wantPaperId)
+		}
+		versions, err := s.GetPaperVersionRecords(ctx, userId, wantPaperId)
+		if err != nil || len(versions) != 2 {
+			t.Fatalf("GetPaperVersionRecords(%s) = %d records, %v, want 2", userId, len(versions), err)
+		}
+	}
+	if version, err := s.GetPaperVersionByPdfId(ctx, "u3", "pdf-shared"); err != nil || version != nil {
+		t.Fatalf("GetPaperVersionByPdfId(u3) = %+v, %v, want nil", version, err)
+	}
+}
+
+func TestAddPaperVersionConcurrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		pdfIds  []string
+		wantNos []int
+	}{
+		// 同时读到相同的最大版本序号时，唯一索引拒绝重复序号后重试
+		{name: "different pdfs", pdfIds: []string{"p1", "p2", "p3"}, wantNos: []int{1, 2, 3}},
+		// 同一PDF并发登记只保留一条记录
+		{name: "same pdf", pdfIds: []string{"p1", "p1", "p1"}, wantNos: []int{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := daotest.NewDB(t, &model.PaperVersion{})
+			logger := daotest.NewLogger()
+			versionDAO := dao.NewPaperVersionDAO(db, logger)
+			s := NewPaperService(logger, opentracing.NoopTracer{}, dao.NewPaperDAO(db, logger), versionDAO)
+			ctx := context.Background()
+
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			nos := make([]int, 0, len(tt.pdfIds))
+			for _, pdfId := range tt.pdfIds {
+				wg.Add(1)
+				go func(pdfId string) {
+					defer wg.Done()
+					version, err := s.AddPaperVersion(ctx, &model.PaperVersion{UserId: "u1", PaperId: "A", PdfId: pdfId})
+					if err != nil {
+						t.Errorf("AddPaperVersion(%s): %v", pdfId, err)
+						return
+					}
+					mu.Lock()
+					nos = append(nos, version.VersionNo)
+					mu.Unlock()
+				}(pdfId)
+			}
+			wg.Wait()
+
+			sort.Ints(nos)
+			if fmt.Sprint(nos) != fmt.Sprint(tt.wantNos) {
+				t.Fatalf("version numbers = %v, want %v", nos, tt.wantNos)
+			}
+			versions, err := versionDAO.FindByUserIdAndPaperId(ctx, "u1", "A")
+			if err != nil || len(versions) != tt.wantNos[len(tt.wantNos)-1] {
+				t.Fatalf("stored versions = %d, %v", len(versions), err)
+			}
+		})
+	}
+}
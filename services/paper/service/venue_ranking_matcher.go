@@ -0,0 +1,306 @@
+package service
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yb2020/odoc/pkg/venue"
+	"github.com/yb2020/odoc/services/paper/model"
+)
+
+// 场所评级的匹配方式
+const (
+	VenueRankingMatchedByIssn    = "issn"    // ISSN相同
+	VenueRankingMatchedByAcronym = "acronym" // 会议缩写相同
+	VenueRankingMatchedByName    = "name"    // 归一化后的名称或缩写相同
+	VenueRankingMatchedByFuzzy   = "fuzzy"   // 名称高度相似或为逐词缩写
+)
+
+// 模糊匹配时名称词集合的最低相似度
+const venueRankingFuzzyThreshold = 0.9
+
+// 导入时最多返回的错误条数
+const venueRankingMaxImportErrors = 20
+
+// VenueRankingMatch 场所在某个评级体系中匹配到的评级
+type VenueRankingMatch struct {
+	*model.PaperVenueRanking
+	MatchedBy string
+}
+
+// venueRankingIndex 评级数据的内存索引，导入新版本后整体重建
+type venueRankingIndex struct {
+	byIssn  map[string][]*model.PaperVenueRanking
+	byAbbr  map[string][]*model.PaperVenueRanking
+	byKey   map[string][]*model.PaperVenueRanking
+	byToken map[string][]*model.PaperVenueRanking
+	tokens  map[*model.PaperVenueRanking]map[string]struct{}
+	cache   sync.Map // 场所名称 -> []*VenueRankingMatch
+}
+
+func newVenueRankingIndex(rankings []model.PaperVenueRanking) *venueRankingIndex {
+	index := &venueRankingIndex{
+		byIssn:  make(map[string][]*model.PaperVenueRanking),
+		byAbbr:  make(map[string][]*model.PaperVenueRanking),
+		byKey:   make(map[string][]*model.PaperVenueRanking),
+		byToken: make(map[string][]*model.PaperVenueRanking),
+		tokens:  make(map[*model.PaperVenueRanking]map[string]struct{}),
+	}
+	for i := range rankings {
+		ranking := &rankings[i]
+		for _, issn := range []string{ranking.Issn, ranking.EIssn} {
+			if issn != "" {
+				index.byIssn[issn] = append(index.byIssn[issn], ranking)
+			}
+		}
+		if ranking.AbbreviationKey != "" {
+			index.byAbbr[ranking.AbbreviationKey] = append(index.byAbbr[ranking.AbbreviationKey], ranking)
+		}
+		if ranking.VenueKey == "" {
+			continue
+		}
+		index.byKey[ranking.VenueKey] = append(index.byKey[ranking.VenueKey], ranking)
+		tokenSet := make(map[string]struct{})
+		for _, token := range strings.Fields(ranking.VenueKey) {
+			if _, ok := tokenSet[token]; ok {
+				continue
+			}
+			tokenSet[token] = struct{}{}
+			index.byToken[token] = append(index.byToken[token], ranking)
+		}
+		index.tokens[ranking] = tokenSet
+	}
+	return index
+}
+
+// match 按 ISSN、会议缩写、归一化名称、模糊名称的顺序匹配，每个评级体系取最先匹配到的方式中年份最新的一条
+func (index *venueRankingIndex) match(venueName string, issn string) []*VenueRankingMatch {
+	cacheKey := issn + "|" + venueName
+	if cached, ok := index.cache.Load(cacheKey); ok {
+		return cached.([]*VenueRankingMatch)
+	}
+
+	found := make(map[string]*VenueRankingMatch)
+	collect := func(candidates []*model.PaperVenueRanking, matchedBy string) {
+		stage := make(map[string]*model.PaperVenueRanking)
+		for _, candidate := range candidates {
+			if _, ok := found[candidate.System]; ok {
+				continue
+			}
+			if latest, ok := stage[candidate.System]; !ok || candidate.Year > latest.Year {
+				stage[candidate.System] = candidate
+			}
+		}
+		for system, ranking := range stage {
+			found[system] = &VenueRankingMatch{PaperVenueRanking: ranking, MatchedBy: matchedBy}
+		}
+	}
+
+	issns := venue.ExtractISSNs(issn)
+	issns = append(issns, venue.ExtractISSNs(venueName)...)
+	for _, item := range issns {
+		collect(index.byIssn[item], VenueRankingMatchedByIssn)
+	}
+	if acronym := venue.Acronym(venueName); acronym != "" {
+		collect(index.byAbbr[acronym], VenueRankingMatchedByAcronym)
+	}
+	key := venue.Normalize(venueName)
+	if key != "" {
+		collect(index.byKey[key], VenueRankingMatchedByName)
+		collect(index.byAbbr[key], VenueRankingMatchedByName)
+		collect(index.fuzzyCandidates(venueName, key), VenueRankingMatchedByFuzzy)
+	}
+
+	matches := make([]*VenueRankingMatch, 0, len(found))
+	for _, system := range venue.Systems() {
+		if match, ok := found[system]; ok {
+			matches = append(matches, match)
+		}
+	}
+	index.cache.Store(cacheKey, matches)
+	return matches
+}
+
+// fuzzyCandidates 名称词集合相似度达到阈值，或名称是评级库中场所全称的逐词缩写
+func (index *venueRankingIndex) fuzzyCandidates(venueName string, key string) []*model.PaperVenueRanking {
+	queryTokens := strings.Fields(key)
+	querySet := make(map[string]struct{}, len(queryTokens))
+	for _, token := range queryTokens {
+		querySet[token] = struct{}{}
+	}
+
+	// 用最少出现的词和首词缩小候选范围，首词用于缩写名称（缩写中的其他词在索引中通常不存在）
+	var rarest []*model.PaperVenueRanking
+	for token := range querySet {
+		postings := index.byToken[token]
+		if len(postings) > 0 && (rarest == nil || len(postings) < len(rarest)) {
+			rarest = postings
+		}
+	}
+	seen := make(map[*model.PaperVenueRanking]struct{})
+	var candidates []*model.PaperVenueRanking
+	for _, postings := range [][]*model.PaperVenueRanking{rarest, index.byToken[queryTokens[0]]} {
+		for _, ranking := range postings {
+			if _, ok := seen[ranking]; ok {
+				continue
+			}
+			seen[ranking] = struct{}{}
+			if jaccard(querySet, index.tokens[ranking]) >= venueRankingFuzzyThreshold ||
+				venue.AbbreviationMatches(venueName, ranking.Venue) {
+				candidates = append(candidates, ranking)
+			}
+		}
+	}
+	return candidates
+}
+
+func jaccard(a map[string]struct{}, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	intersection := 0
+	for token := range a {
+		if _, ok := b[token]; ok {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+// 评级CSV各列可用的表头名称（小写）
+var venueRankingColumnAliases = map[string][]string{
+	"venue":        {"venue", "name", "title", "journal", "journal name", "full journal title", "journal title", "source title", "conference", "conference name", "期刊名称", "刊名", "刊物全称", "会议全称", "会议名称", "名称", "全称"},
+	"abbreviation": {"abbreviation", "abbr", "acronym", "jcr abbreviation", "iso abbreviation", "journal abbreviation", "简称", "缩写", "刊物简称", "会议简称", "刊名简称"},
+	"issn":         {"issn", "print issn", "p-issn", "pissn"},
+	"eissn":        {"eissn", "e-issn", "electronic issn", "online issn"},
+	"rank":         {"rank", "rating", "ranking", "quartile", "jif quartile", "jcr quartile", "partition", "ccf rank", "core rank", "分区", "大类分区", "等级", "类别", "级别"},
+	"impactFactor": {"impact factor", "if", "jif", "journal impact factor", "影响因子"},
+	"category":     {"category", "field", "subject", "subject area", "field of research", "for", "学科", "领域", "大类", "学科分类"},
+}
+
+// mapVenueRankingColumns 根据表头确定各字段所在的列，未出现的字段为-1
+func mapVenueRankingColumns(header []string) map[string]int {
+	columns := make(map[string]int, len(venueRankingColumnAliases))
+	for field := range venueRankingColumnAliases {
+		columns[field] = -1
+	}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		for field, aliases := range venueRankingColumnAliases {
+			if columns[field] >= 0 {
+				continue
+			}
+			for _, alias := range aliases {
+				if name == alias {
+					columns[field] = i
+					break
+				}
+			}
+		}
+		// 带年份的影响因子列，如 "2023 JIF"、"Impact Factor 2023"
+		if columns["impactFactor"] < 0 && (strings.Contains(name, "impact factor") || strings.HasSuffix(name, " jif")) {
+			columns["impactFactor"] = i
+		}
+	}
+	return columns
+}
+
+// parseVenueRankingCSV 解析评级CSV，返回可导入的记录、跳过条数与跳过原因
+func parseVenueRankingCSV(system string, year int, csvContent string) ([]*model.PaperVenueRanking, int, []string, error) {
+	reader := csv.NewReader(strings.NewReader(csvContent))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	columns := mapVenueRankingColumns(header)
+	if columns["venue"] < 0 && columns["abbreviation"] < 0 {
+		return nil, 0, nil, fmt.Errorf("CSV表头缺少场所名称列")
+	}
+	if columns["rank"] < 0 && columns["impactFactor"] < 0 {
+		return nil, 0, nil, fmt.Errorf("CSV表头缺少等级或影响因子列")
+	}
+
+	cell := func(record []string, field string) string {
+		i := columns[field]
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rankings []*model.PaperVenueRanking
+	var skipErrors []string
+	skipped := 0
+	skip := func(line int, reason string) {
+		skipped++
+		if len(skipErrors) < venueRankingMaxImportErrors {
+			skipErrors = append(skipErrors, fmt.Sprintf("第%d行: %s", line, reason))
+		}
+	}
+	seen := make(map[string]struct{})
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			skip(line, err.Error())
+			continue
+		}
+
+		name := cell(record, "venue")
+		abbreviation := cell(record, "abbreviation")
+		if name == "" {
+			name = abbreviation
+		}
+		if name == "" {
+			skip(line, "缺少场所名称")
+			continue
+		}
+		ranking := &model.PaperVenueRanking{
+			System:          system,
+			Year:            year,
+			Venue:           name,
+			VenueKey:        venue.Normalize(name),
+			Abbreviation:    abbreviation,
+			AbbreviationKey: venue.Normalize(abbreviation),
+			Issn:            venue.NormalizeISSN(cell(record, "issn")),
+			EIssn:           venue.NormalizeISSN(cell(record, "eissn")),
+			Rank:            venue.NormalizeRank(system, cell(record, "rank")),
+			Category:        cell(record, "category"),
+		}
+		if impactFactor := cell(record, "impactFactor"); impactFactor != "" {
+			value, err := strconv.ParseFloat(impactFactor, 32)
+			if err != nil {
+				// JCR中影响因子小于0.1时记为 "<0.1"，这类数据只保留分区
+				if !strings.HasPrefix(impactFactor, "<") {
+					skip(line, "影响因子格式错误: "+impactFactor)
+					continue
+				}
+			} else {
+				ranking.ImpactFactor = float32(value)
+			}
+		}
+		if ranking.Rank == "" && ranking.ImpactFactor == 0 {
+			skip(line, "缺少等级和影响因子")
+			continue
+		}
+		// 同一场所在多个学科中出现时只保留第一条（JCR、中科院分区按学科重复列出）
+		dedupKey := ranking.VenueKey + "|" + ranking.Issn
+		if _, ok := seen[dedupKey]; ok {
+			skipped++
+			continue
+		}
+		seen[dedupKey] = struct{}{}
+		rankings = append(rankings, ranking)
+	}
+	return rankings, skipped, skipErrors, nil
+}
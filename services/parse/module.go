@@ -23,17 +23,20 @@ var Module = &ParseModule{}
 
 // ParseModule PDF解析模块
 type ParseModule struct {
-	config                *config.Config
-	logger                logging.Logger
-	tracer                opentracing.Tracer
-	httpClient            http_client.HttpClient
-	parseOperateService   *service.ParseOperateService
-	grobidPdfParseService *service.GrobidPDFParseService
-	mineruPdfParseService *service.MineruPDFParseService
-	parseApi              *api.TestParseAPI
-	authMiddleware        *middleware.AuthMiddleware
-	ossService            ossService.OssServiceInterface
-	paperPdfParsedService *paperService.PaperPdfParsedService
+	config                     *config.Config
+	logger                     logging.Logger
+	tracer                     opentracing.Tracer
+	httpClient                 http_client.HttpClient
+	parseOperateService        *service.ParseOperateService
+	grobidPdfParseService      *service.GrobidPDFParseService
+	mineruPdfParseService      *service.MineruPDFParseService
+	nativePdfParseService      *service.NativePDFParseService
+	arxivSourcePdfParseService *service.ArxivSourcePDFParseService
+	pdfParseEngineService      *service.PDFParseEngineService
+	parseApi                   *api.TestParseAPI
+	authMiddleware             *middleware.AuthMiddleware
+	ossService                 ossService.OssServiceInterface
+	paperPdfParsedService      *paperService.PaperPdfParsedService
 }
 
 // NewParseModule 创建新的Parse模块实例
@@ -90,6 +93,28 @@ func (m *ParseModule) Initialize() error {
 		m.ossService,
 	)
 
+	m.nativePdfParseService = service.NewNativePDFParseService(
+		m.config,
+		m.logger,
+		m.tracer,
+	)
+	m.arxivSourcePdfParseService = service.NewArxivSourcePDFParseService(
+		m.config,
+		m.logger,
+		m.tracer,
+		m.httpClient,
+	)
+	m.pdfParseEngineService = service.NewPDFParseEngineService(
+		m.config,
+		m.logger,
+		m.tracer,
+		m.httpClient,
+		m.grobidPdfParseService,
+		m.mineruPdfParseService,
+		m.nativePdfParseService,
+		m.arxivSourcePdfParseService,
+	)
+
 	m.parseApi = api.NewTestParseApi(m.tracer, m.grobidPdfParseService, m.mineruPdfParseService)
 	return nil
 }
@@ -114,6 +139,16 @@ func (m *ParseModule) GetMineruPDFParseService() *service.MineruPDFParseService
 	return m.mineruPdfParseService
 }
 
+// GetNativePDFParseService 获取内置PDF解析服务实例
+func (m *ParseModule) GetNativePDFParseService() *service.NativePDFParseService {
+	return m.nativePdfParseService
+}
+
+// GetPDFParseEngineService 获取按规则选择解析器的PDF解析服务实例
+func (m *ParseModule) GetPDFParseEngineService() *service.PDFParseEngineService {
+	return m.pdfParseEngineService
+}
+
 // Name 返回模块名称
 func (m *ParseModule) Name() string {
 	return "parse"
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/docparser"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/http_client"
+	"github.com/yb2020/odoc/pkg/latexsrc"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/pdftext"
+	parsepb "github.com/yb2020/odoc/proto/gen/go/parsed"
+	"github.com/yb2020/odoc/services/parse/util/native"
+)
+
+// ParseEngineLatex arXiv LaTeX源码解析结果的解析器名称
+const ParseEngineLatex = "latex"
+
+// arXiv 源码解析的默认配置
+const (
+	defaultArxivSourceURL  = "https://arxiv.org/e-print/"
+	defaultArxivTimeout    = 60
+	defaultArxivMaxSizeMB  = 64
+	defaultArxivMinAligned = 0.5
+)
+
+// arxivIdRe 新格式的 arXiv 编号，如 2106.01234、2106.01234v2
+var arxivIdRe = regexp.MustCompile(`(?i)arxiv:\s*(\d{4}\.\d{4,5}(?:v\d+)?)`)
+
+// ArxivSourcePDFParseService arXiv论文的LaTeX源码解析服务
+//
+// 下载论文的 e-print 源码包，从源码中解析章节、公式、图表和引用，
+// 再与PDF文本层对齐得到坐标。公式保留LaTeX原文，引用标记与 .bbl 条目一一对应。
+type ArxivSourcePDFParseService struct {
+	config     *config.Config
+	tracer     opentracing.Tracer
+	logger     logging.Logger
+	httpClient http_client.HttpClient
+}
+
+// NewArxivSourcePDFParseService 创建新的arXiv源码解析服务实例
+func NewArxivSourcePDFParseService(
+	config *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	httpClient http_client.HttpClient,
+) *ArxivSourcePDFParseService {
+	return &ArxivSourcePDFParseService{
+		config:     config,
+		logger:     logger,
+		tracer:     tracer,
+		httpClient: httpClient,
+	}
+}
+
+// Enabled 是否启用arXiv源码解析
+func (s *ArxivSourcePDFParseService) Enabled() bool {
+	return s.config.PDF.Parse.Arxiv.Enabled
+}
+
+// ParseArxivSource 下载并解析arXiv论文的LaTeX源码，与请求中的PDF对齐
+//
+// 源码不存在、无法解析或与PDF对齐的比例过低时返回错误，由调用方回退到PDF解析引擎。
+func (s *ArxivSourcePDFParseService) ParseArxivSource(ctx context.Context, req *docparser.Request) (*docparser.Result, error) {
+	span, _ := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ArxivSourcePDFParseService.ParseArxivSource")
+	defer span.Finish()
+	span.SetTag("arxivId", req.ArxivId)
+
+	data, err := s.download(req.ArxivId)
+	if err != nil {
+		s.logger.Warn("msg", "下载arXiv源码失败", "arxivId", req.ArxivId, "error", err.Error())
+		return nil, errors.BizWrap("arxiv source download failed", err)
+	}
+	src, err := latexsrc.Extract(data)
+	if err != nil {
+		if stderrors.Is(err, latexsrc.ErrNoSource) {
+			s.logger.Info("msg", "arXiv论文没有LaTeX源码", "arxivId", req.ArxivId)
+		} else {
+			s.logger.Warn("msg", "解压arXiv源码失败", "arxivId", req.ArxivId, "error", err.Error())
+		}
+		return nil, errors.BizWrap("arxiv source extract failed", err)
+	}
+	paper, err := latexsrc.Parse(src)
+	if err != nil {
+		s.logger.Warn("msg", "解析arXiv源码失败", "arxivId", req.ArxivId, "error", err.Error())
+		return nil, errors.BizWrap("arxiv source parse failed", err)
+	}
+
+	pages, err := s.extractPages(req.Content)
+	if err != nil {
+		s.logger.Warn("msg", "读取PDF文本层失败", "arxivId", req.ArxivId, "fileSHA256", req.FileSHA256, "error", err.Error())
+		return nil, err
+	}
+	metadata, fullDocument := paper.ToDocument()
+	stats := paper.Align(metadata, pages)
+	span.SetTag("blocks", stats.Blocks)
+	span.SetTag("aligned", stats.Aligned)
+	minAligned := s.config.PDF.Parse.Arxiv.MinAligned
+	if minAligned <= 0 {
+		minAligned = defaultArxivMinAligned
+	}
+	if stats.Blocks == 0 || float64(stats.Aligned) < float64(stats.Blocks)*minAligned {
+		// 源码与PDF版本不一致或PDF文本层质量差，坐标不可信
+		s.logger.Warn("msg", "arXiv源码与PDF对齐比例过低", "arxivId", req.ArxivId, "fileSHA256", req.FileSHA256,
+			"blocks", stats.Blocks, "aligned", stats.Aligned)
+		return nil, errors.Biz("arxiv source does not match pdf")
+	}
+
+	metadata.FileSHA256 = req.FileSHA256
+	// 全文翻译使用的页面块仍从PDF版面得到
+	_, _, pageBlocks := native.HandlePages(pages)
+	s.logger.Info("msg", "arXiv源码解析完成", "arxivId", req.ArxivId, "fileSHA256", req.FileSHA256,
+		"paragraphs", len(fullDocument.Paragraphs), "formulas", len(metadata.Formulas),
+		"references", len(metadata.References), "blocks", stats.Blocks, "aligned", stats.Aligned)
+	return &docparser.Result{
+		Parser:       ParseEngineLatex,
+		Metadata:     metadata,
+		FullDocument: fullDocument,
+		PageBlocks:   pageBlocks,
+		ImageRecords: make(map[string]*parsepb.ImageRecord),
+	}, nil
+}
+
+// download 下载e-print源码包
+func (s *ArxivSourcePDFParseService) download(arxivId string) ([]byte, error) {
+	arxiv := s.config.PDF.Parse.Arxiv
+	sourceURL := arxiv.SourceURL
+	if sourceURL == "" {
+		sourceURL = defaultArxivSourceURL
+	}
+	timeout := arxiv.Timeout
+	if timeout <= 0 {
+		timeout = defaultArxivTimeout
+	}
+	maxSizeMB := arxiv.MaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultArxivMaxSizeMB
+	}
+	data, err := s.httpClient.GetWithTimeout(strings.TrimRight(sourceURL, "/")+"/"+arxivId, nil, time.Duration(timeout)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSizeMB<<20 {
+		return nil, errors.Biz("arxiv source too large")
+	}
+	return data, nil
+}
+
+// extractPages 提取PDF全部页面的文本行，单页失败时保留空页以保持页码连续
+func (s *ArxivSourcePDFParseService) extractPages(content []byte) ([]*pdftext.Page, error) {
+	doc, err := pdftext.Open(content)
+	if err != nil {
+		return nil, errors.BizWrap("arxiv source open pdf failed", err)
+	}
+	pages := make([]*pdftext.Page, 0, doc.PageCount())
+	textPages := 0
+	for pageNum := 1; pageNum <= doc.PageCount(); pageNum++ {
+		page, err := doc.ExtractPage(pageNum)
+		if page == nil {
+			page = &pdftext.Page{Number: pageNum}
+		}
+		if err == nil {
+			textPages++
+		}
+		pages = append(pages, page)
+	}
+	if textPages == 0 {
+		return nil, errors.Biz("pdf has no text layer")
+	}
+	return pages, nil
+}
+
+// arxivIdFromText 从PDF首页文本中识别arXiv编号，识别不到时返回空
+func arxivIdFromText(text string) string {
+	if m := arxivIdRe.FindStringSubmatch(text); m != nil {
+		return strings.ToLower(m[1])
+	}
+	return ""
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/docparser"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/http_client"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/metrics"
+	"github.com/yb2020/odoc/pkg/pdftext"
+	parsepb "github.com/yb2020/odoc/proto/gen/go/parsed"
+	"github.com/yb2020/odoc/services/parse/constant"
+	"github.com/yb2020/odoc/services/parse/util"
+	pdfModel "github.com/yb2020/odoc/services/pdf/model"
+)
+
+// PDF解析引擎
+const (
+	ParseEngineMineru  = "mineru"
+	ParseEngineGrobid  = "grobid"
+	ParseEngineDocling = docparser.ParserDocling
+	ParseEngineMarker  = docparser.ParserMarker
+	ParseEngineNative  = "native"
+)
+
+// 解析规则中的会员等级
+const (
+	ParseTierFree = "free"
+	ParseTierPro  = "pro"
+)
+
+// Docling、Marker 未配置超时时间时的默认超时 单位：分钟
+const defaultMarkdownParserTimeout = 10
+
+// PDFParseEngineService 通过解析器注册表选择PDF解析引擎
+//
+// 按配置的规则根据文档语言、页数和会员等级选择候选解析器，没有规则命中时使用配置的主引擎；
+// 候选解析器全部失败时回退到内置解析器。arXiv论文启用源码解析时优先解析LaTeX源码。
+type PDFParseEngineService struct {
+	config   *config.Config
+	tracer   opentracing.Tracer
+	logger   logging.Logger
+	registry *docparser.Registry
+	arxiv    *ArxivSourcePDFParseService
+}
+
+// NewPDFParseEngineService 创建新的PDF解析引擎选择服务实例，并注册全部解析器
+func NewPDFParseEngineService(
+	config *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	httpClient http_client.HttpClient,
+	grobidPdfParseService *GrobidPDFParseService,
+	mineruPdfParseService *MineruPDFParseService,
+	nativePdfParseService *NativePDFParseService,
+	arxivSourcePdfParseService *ArxivSourcePDFParseService,
+) *PDFParseEngineService {
+	s := &PDFParseEngineService{
+		config:   config,
+		logger:   logger,
+		tracer:   tracer,
+		registry: docparser.NewRegistry(),
+		arxiv:    arxivSourcePdfParseService,
+	}
+	parse := config.PDF.Parse
+	parsers := []docparser.Parser{
+		&mineruParser{config: config, service: mineruPdfParseService},
+		&grobidParser{config: config, service: grobidPdfParseService},
+		docparser.NewDoclingParser(httpClient, parse.Docling.URL, parse.Docling.ConvertURL, markdownParserTimeout(parse.Docling.Timeout)),
+		docparser.NewMarkerParser(httpClient, parse.Marker.URL, parse.Marker.ConvertURL, markdownParserTimeout(parse.Marker.Timeout)),
+		&nativeParser{service: nativePdfParseService},
+	}
+	for _, p := range parsers {
+		if err := s.registry.Register(p); err != nil {
+			logger.Error("msg", "注册PDF解析器失败", "parser", p.Name(), "error", err.Error())
+		}
+	}
+
+	rules := make([]docparser.Rule, 0, len(parse.Rules))
+	for _, r := range parse.Rules {
+		rules = append(rules, docparser.Rule{
+			Parsers:   r.Parsers,
+			Languages: r.Languages,
+			MinPages:  r.MinPages,
+			MaxPages:  r.MaxPages,
+			Tiers:     r.Tiers,
+			Require:   docparser.ParseCapabilities(r.Require),
+		})
+	}
+	s.registry.SetRules(rules, []string{s.Engine()})
+	return s
+}
+
+func markdownParserTimeout(minutes int) time.Duration {
+	if minutes <= 0 {
+		minutes = defaultMarkdownParserTimeout
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// Engine 当前配置的主解析引擎，未配置时使用Mineru
+func (s *PDFParseEngineService) Engine() string {
+	engine := strings.ToLower(strings.TrimSpace(s.config.PDF.Parse.Engine))
+	if engine == "" {
+		return ParseEngineMineru
+	}
+	return engine
+}
+
+// Registry 解析器注册表，用于注册新的解析后端
+func (s *PDFParseEngineService) Registry() *docparser.Registry {
+	return s.registry
+}
+
+// ExecuteParsePDF 使用规则选择的引擎解析PDF，返回结构与Mineru解析一致
+func (s *PDFParseEngineService) ExecuteParsePDF(ctx context.Context, pdfContent []byte, paperPdf *pdfModel.PaperPdf) (*parsepb.DocumentMetadata, *parsepb.FullDocument, []*parsepb.PageBlockData, map[string]*parsepb.ImageRecord, error) {
+	result, err := s.Parse(ctx, &docparser.Request{
+		Content:    pdfContent,
+		FileSHA256: paperPdf.FileSHA256,
+		Lang:       paperPdf.Language,
+	})
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return result.Metadata, result.FullDocument, result.PageBlocks, result.ImageRecords, nil
+}
+
+// Parse 解析文档；请求中未提供语言、页数和arXiv编号时从PDF文本层探测
+func (s *PDFParseEngineService) Parse(ctx context.Context, req *docparser.Request) (*docparser.Result, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PDFParseEngineService.Parse")
+	defer span.Finish()
+
+	if req.Lang == "" || req.PageCount <= 0 || req.ArxivId == "" {
+		lang, pageCount, arxivId := probePDF(req.Content)
+		if req.Lang == "" {
+			req.Lang = lang
+		}
+		if req.PageCount <= 0 {
+			req.PageCount = pageCount
+		}
+		if req.ArxivId == "" {
+			req.ArxivId = arxivId
+		}
+	}
+	req.Lang = ruleLanguage(req.Lang)
+	span.SetTag("lang", req.Lang)
+	span.SetTag("pageCount", req.PageCount)
+	span.SetTag("tier", req.Tier)
+	span.SetTag("arxivId", req.ArxivId)
+
+	if s.arxiv != nil && s.arxiv.Enabled() && req.ArxivId != "" {
+		// 源码解析失败时回退到PDF解析引擎，错误已在源码解析服务中记录
+		start := time.Now()
+		result, err := s.arxiv.ParseArxivSource(ctx, req)
+		metrics.RecordParse(ctx, ParseEngineLatex, time.Since(start), err)
+		if err == nil {
+			result.Score = docparser.Score(result, req.PageCount)
+			span.SetTag("parser", result.Parser)
+			span.SetTag("score", result.Score)
+			return result, nil
+		}
+	}
+
+	opts := docparser.RunOptions{
+		Compare:  s.config.PDF.Parse.Compare,
+		MinScore: s.config.PDF.Parse.MinScore,
+	}
+	if s.config.PDF.Parse.Native.Fallback {
+		opts.Fallback = []string{ParseEngineNative}
+	}
+	start := time.Now()
+	result, err := s.registry.Run(ctx, req, opts)
+	parser := s.Engine()
+	if result != nil {
+		parser = result.Parser
+	}
+	metrics.RecordParse(ctx, parser, time.Since(start), err)
+	if err != nil {
+		if stderrors.Is(err, docparser.ErrNoParser) {
+			s.logger.Error("msg", "没有可用的PDF解析引擎", "engine", s.Engine(), "fileSHA256", req.FileSHA256)
+			return nil, errors.Biz("pdf parse engine not configured")
+		}
+		s.logger.Error("msg", "PDF解析失败", "fileSHA256", req.FileSHA256, "error", err.Error())
+		return nil, err
+	}
+	span.SetTag("parser", result.Parser)
+	span.SetTag("score", result.Score)
+	s.logger.Info("msg", "PDF解析完成", "fileSHA256", req.FileSHA256, "parser", result.Parser,
+		"score", result.Score, "lang", req.Lang, "pageCount", req.PageCount, "tier", req.Tier)
+	return result, nil
+}
+
+// probePDF 从PDF首页文本层探测文档语言、页数和arXiv编号，无法读取时返回空值
+func probePDF(content []byte) (string, int, string) {
+	doc, err := pdftext.Open(content)
+	if err != nil {
+		return "", 0, ""
+	}
+	pageCount := doc.PageCount()
+	page, err := doc.ExtractPage(1)
+	if err != nil || len(page.Lines) == 0 {
+		return "", pageCount, ""
+	}
+	var sb strings.Builder
+	for _, line := range page.Lines {
+		sb.WriteString(line.Text)
+		sb.WriteByte('\n')
+	}
+	text := sb.String()
+	return util.DetectLanguage(page.Lines[0].Text, text), pageCount, arxivIdFromText(text)
+}
+
+// ruleLanguage 将语言统一为解析规则中使用的 en/zh
+func ruleLanguage(lang string) string {
+	switch {
+	case lang == "":
+		return ""
+	case strings.HasPrefix(strings.ToLower(lang), constant.LanguageZh):
+		return constant.LanguageZh
+	case strings.HasPrefix(strings.ToLower(lang), constant.LanguageEn):
+		return constant.LanguageEn
+	default:
+		return strings.ToLower(lang)
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/pdftext"
+	parsepb "github.com/yb2020/odoc/proto/gen/go/parsed"
+	"github.com/yb2020/odoc/services/parse/util/native"
+	pdfModel "github.com/yb2020/odoc/services/pdf/model"
+)
+
+// 内置解析器默认解析的最大页数
+const defaultNativeMaxPage = 100
+
+// NativePDFParseService 内置的PDF解析服务，不依赖外部服务，基于PDF文本层和版面规则解析
+type NativePDFParseService struct {
+	config *config.Config
+	tracer opentracing.Tracer
+	logger logging.Logger
+}
+
+// NewNativePDFParseService 创建新的内置PDF解析服务实例
+func NewNativePDFParseService(
+	config *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+) *NativePDFParseService {
+	return &NativePDFParseService{
+		config: config,
+		logger: logger,
+		tracer: tracer,
+	}
+}
+
+// ExecuteParsePDF 解析PDF，返回结构与Mineru解析一致；内置解析器不切分图片，图片记录为空
+func (s *NativePDFParseService) ExecuteParsePDF(ctx context.Context, pdfContent []byte, paperPdf *pdfModel.PaperPdf) (*parsepb.DocumentMetadata, *parsepb.FullDocument, []*parsepb.PageBlockData, map[string]*parsepb.ImageRecord, error) {
+	span, _ := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "NativePDFParseService.ExecuteParsePDF")
+	defer span.Finish()
+
+	doc, err := pdftext.Open(pdfContent)
+	if err != nil {
+		s.logger.Error("msg", "内置解析器打开PDF失败", "fileSHA256", paperPdf.FileSHA256, "error", err.Error())
+		return nil, nil, nil, nil, errors.BizWrap("native parse pdf failed", err)
+	}
+	maxPage := s.config.PDF.Parse.Native.MaxPage
+	if maxPage <= 0 {
+		maxPage = defaultNativeMaxPage
+	}
+	pageCount := doc.PageCount()
+	if pageCount > maxPage {
+		pageCount = maxPage
+	}
+
+	var pages []*pdftext.Page
+	textPages := 0
+	for pageNum := 1; pageNum <= pageCount; pageNum++ {
+		page, err := doc.ExtractPage(pageNum)
+		if err != nil {
+			if stderrors.Is(err, pdftext.ErrNoText) {
+				pages = append(pages, page)
+				continue
+			}
+			// 单页解析失败不影响其他页面
+			s.logger.Warn("msg", "内置解析器解析页面失败", "fileSHA256", paperPdf.FileSHA256, "pageNum", pageNum, "error", err.Error())
+			continue
+		}
+		pages = append(pages, page)
+		textPages++
+	}
+	if textPages == 0 {
+		// 扫描版PDF没有文本层，需要OCR能力的解析引擎
+		s.logger.Warn("msg", "PDF没有可提取的文本", "fileSHA256", paperPdf.FileSHA256)
+		return nil, nil, nil, nil, errors.Biz("pdf has no text layer")
+	}
+
+	paragraphs, metadata, pageBlocks := native.HandlePages(pages)
+	if len(paragraphs) == 0 {
+		return nil, nil, nil, nil, errors.Biz("native parse pdf failed")
+	}
+	metadata.FileSHA256 = paperPdf.FileSHA256
+	s.logger.Info("msg", "内置解析器解析完成", "fileSHA256", paperPdf.FileSHA256,
+		"pages", len(pages), "paragraphs", len(paragraphs), "references", len(metadata.References))
+	return metadata, &parsepb.FullDocument{Paragraphs: paragraphs}, pageBlocks, make(map[string]*parsepb.ImageRecord), nil
+}
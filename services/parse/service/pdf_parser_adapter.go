@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/docparser"
+	"github.com/yb2020/odoc/pkg/errors"
+	pdfModel "github.com/yb2020/odoc/services/pdf/model"
+)
+
+// grobidParser 将GROBID解析服务适配为解析器注册表中的解析器
+type grobidParser struct {
+	config  *config.Config
+	service *GrobidPDFParseService
+}
+
+func (p *grobidParser) Name() string {
+	return ParseEngineGrobid
+}
+
+// Capabilities GROBID 不输出公式内容和全文翻译使用的页面块
+func (p *grobidParser) Capabilities() docparser.Capability {
+	return docparser.CapHeader | docparser.CapFulltext | docparser.CapFigures
+}
+
+func (p *grobidParser) Available() bool {
+	return p.config.PDF.Parse.Grobid.URL != ""
+}
+
+func (p *grobidParser) Parse(ctx context.Context, req *docparser.Request) (*docparser.Result, error) {
+	metadata, fullDocument, err := p.service.ParsePDFFulltextWithGrobidEn(ctx, req.Content)
+	if err != nil {
+		return nil, err
+	}
+	if metadata == nil || fullDocument == nil {
+		return nil, errors.Biz("grobid parse result is empty")
+	}
+	return &docparser.Result{Metadata: metadata, FullDocument: fullDocument}, nil
+}
+
+// mineruParser 将MinerU解析服务适配为解析器注册表中的解析器
+type mineruParser struct {
+	config  *config.Config
+	service *MineruPDFParseService
+}
+
+func (p *mineruParser) Name() string {
+	return ParseEngineMineru
+}
+
+func (p *mineruParser) Capabilities() docparser.Capability {
+	return docparser.CapHeader | docparser.CapFulltext | docparser.CapFigures | docparser.CapFormulas
+}
+
+func (p *mineruParser) Available() bool {
+	return p.config.PDF.Parse.Mineru.URL != ""
+}
+
+func (p *mineruParser) Parse(ctx context.Context, req *docparser.Request) (*docparser.Result, error) {
+	metadata, fullDocument, pageBlocks, imageRecords, err := p.service.ExecuteParsePDF(ctx, req.Content, &pdfModel.PaperPdf{
+		FileSHA256: req.FileSHA256,
+		Language:   req.Lang,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &docparser.Result{
+		Metadata:     metadata,
+		FullDocument: fullDocument,
+		PageBlocks:   pageBlocks,
+		ImageRecords: imageRecords,
+	}, nil
+}
+
+// nativeParser 将内置解析服务适配为解析器注册表中的解析器，始终可用
+type nativeParser struct {
+	service *NativePDFParseService
+}
+
+func (p *nativeParser) Name() string {
+	return ParseEngineNative
+}
+
+func (p *nativeParser) Capabilities() docparser.Capability {
+	return docparser.CapHeader | docparser.CapFulltext | docparser.CapFigures
+}
+
+func (p *nativeParser) Available() bool {
+	return true
+}
+
+func (p *nativeParser) Parse(ctx context.Context, req *docparser.Request) (*docparser.Result, error) {
+	metadata, fullDocument, pageBlocks, imageRecords, err := p.service.ExecuteParsePDF(ctx, req.Content, &pdfModel.PaperPdf{
+		FileSHA256: req.FileSHA256,
+		Language:   req.Lang,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &docparser.Result{
+		Metadata:     metadata,
+		FullDocument: fullDocument,
+		PageBlocks:   pageBlocks,
+		ImageRecords: imageRecords,
+	}, nil
+}
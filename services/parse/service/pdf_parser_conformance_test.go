@@ -0,0 +1,20 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/docparser/conformance"
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+// 内置解析器直接解析夹具中的PDF，不需要回放后端响应
+func TestNativeConformance(t *testing.T) {
+	fixtures, err := conformance.LoadFixtures("testdata/native")
+	if err != nil {
+		t.Fatalf("读取夹具失败: %v", err)
+	}
+	parser := &nativeParser{service: NewNativePDFParseService(&config.Config{}, logging.NewLogger("error", "json"), opentracing.NoopTracer{})}
+	conformance.Run(t, parser, fixtures, nil)
+}
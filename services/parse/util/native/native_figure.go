@@ -0,0 +1,240 @@
+package native
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/yb2020/odoc/pkg/idgen"
+	"github.com/yb2020/odoc/pkg/pdftext"
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+	"github.com/yb2020/odoc/services/parse/constant"
+)
+
+const (
+	captionTypeFigure = constant.PdfOssTypeFigure
+	captionTypeTable  = constant.PdfOssTypeTable
+)
+
+var (
+	// 图表标题，如 "Figure 1:"、"Fig. 2."、"Table 3"、"图1"
+	captionRe     = regexp.MustCompile(`^(?i:(figure|fig\.|table|tab\.))\s*(\d+|[IVX]+)\s*([.:：|]|$)`)
+	captionBoldRe = regexp.MustCompile(`^(Figure|Fig\.|FIGURE|Table|TABLE)\s*(\d+|[IVX]+)\s+\S`)
+	captionZhRe   = regexp.MustCompile(`^(图|表)\s*(\d+)`)
+	// 正文中的图表引用，如 "Figure 3"、"Fig. 2"、"Table 1"
+	figureMarkerRe = regexp.MustCompile(`\b(Figure|Fig\.|Table|Tab\.)\s*~?(\d+)`)
+)
+
+// parseCaption 判断块是否为图表标题，返回类型和编号
+func parseCaption(b *docBlock) (captionType string, refIdx string, ok bool) {
+	if len(b.block.Lines) == 0 {
+		return "", "", false
+	}
+	if m := captionRe.FindStringSubmatch(b.text); m != nil {
+		captionType, refIdx = captionRef(m[1], m[2])
+		return captionType, refIdx, true
+	}
+	// 标题编号加粗而没有标点的写法，如 Springer 模板
+	if m := captionBoldRe.FindStringSubmatch(b.text); m != nil && len(b.block.Lines[0].Spans) > 0 && b.block.Lines[0].Spans[0].Bold {
+		captionType, refIdx = captionRef(m[1], m[2])
+		return captionType, refIdx, true
+	}
+	if m := captionZhRe.FindStringSubmatch(b.text); m != nil && len([]rune(b.text)) < 200 {
+		captionType, refIdx = captionRef(m[1], m[2])
+		return captionType, refIdx, true
+	}
+	return "", "", false
+}
+
+// captionRef 将图表标签统一为 "Figure N"/"Table N"
+func captionRef(label, number string) (string, string) {
+	switch strings.ToLower(label) {
+	case "table", "tab.", "表":
+		return captionTypeTable, "Table " + number
+	default:
+		return captionTypeFigure, "Figure " + number
+	}
+}
+
+// markFigureRegions 估算每个图表标题对应的图表区域，并将区域内的文字标记为图表内容
+func markFigureRegions(blocks []*docBlock, bodySize float64) {
+	for _, caption := range blocks {
+		if caption.kind != kindCaption {
+			continue
+		}
+		page := caption.page
+		x0, x1 := textExtent(blocks, page)
+		mid := page.Width / 2
+		switch caption.block.Column {
+		case 1:
+			x1 = math.Min(x1, mid)
+		case 2:
+			x0 = math.Max(x0, mid)
+		}
+		onPage := pageBlocksOf(blocks, page)
+		barrier := func(o *docBlock) bool {
+			if o == caption || o.kind == kindAbandon || o.kind == kindFigureBody {
+				return false
+			}
+			if math.Min(o.block.Rect.X1, x1)-math.Max(o.block.Rect.X0, x0) <= 0 {
+				return false
+			}
+			switch o.kind {
+			case kindTitle, kindHeading, kindCaption:
+				return true
+			}
+			// 双栏页面中栏内的图表不会越过通栏内容
+			if page.TwoColumn && caption.block.Column != 0 && o.block.Column == 0 {
+				return true
+			}
+			// 字号接近正文、跨越大半栏宽的多行块视为正文段落
+			return math.Abs(o.block.FontSize-bodySize) <= 0.6 &&
+				len(o.block.Lines) >= 2 &&
+				o.block.Rect.Width() > 0.6*(x1-x0)
+		}
+		top, bottom := contentRange(onPage)
+
+		var region *pdftext.Rect
+		if caption.captionType == captionTypeTable {
+			// 表格标题通常在表格上方
+			lower := bottom
+			for _, o := range onPage {
+				if o.block.Rect.Y0 >= caption.block.Rect.Y1-1 && barrier(o) {
+					lower = math.Min(lower, o.block.Rect.Y0)
+				}
+			}
+			below := pdftext.Rect{X0: x0, Y0: caption.block.Rect.Y1, X1: x1, Y1: lower}
+			if containsContent(onPage, below, caption) {
+				region = &below
+			}
+		}
+		if region == nil {
+			upper := top
+			for _, o := range onPage {
+				if o.block.Rect.Y1 <= caption.block.Rect.Y0+1 && barrier(o) {
+					upper = math.Max(upper, o.block.Rect.Y1)
+				}
+			}
+			above := pdftext.Rect{X0: x0, Y0: upper, X1: x1, Y1: caption.block.Rect.Y0}
+			region = fitImages(page, above)
+		}
+		if region == nil || region.Height() < 10 {
+			continue
+		}
+		caption.region = region
+		for _, o := range onPage {
+			if (o.kind == kindText || o.kind == kindHeading) && centerInside(o.block.Rect, *region) {
+				o.kind = kindFigureBody
+			}
+		}
+	}
+}
+
+// contentRange 页面上非页眉页脚内容的纵向范围
+func contentRange(onPage []*docBlock) (float64, float64) {
+	top, bottom := math.Inf(1), math.Inf(-1)
+	for _, o := range onPage {
+		if o.kind == kindAbandon {
+			continue
+		}
+		top = math.Min(top, o.block.Rect.Y0)
+		bottom = math.Max(bottom, o.block.Rect.Y1)
+	}
+	if math.IsInf(top, 0) {
+		return 0, 0
+	}
+	return top, bottom
+}
+
+// containsContent 区域内是否有除标题外的文字
+func containsContent(onPage []*docBlock, region pdftext.Rect, caption *docBlock) bool {
+	if region.Empty() {
+		return false
+	}
+	for _, o := range onPage {
+		if o != caption && o.kind != kindAbandon && centerInside(o.block.Rect, region) {
+			return true
+		}
+	}
+	return false
+}
+
+// fitImages 区域内有位图时收缩为位图的范围，否则按矢量图保留整个区域
+func fitImages(page *pdftext.Page, region pdftext.Rect) *pdftext.Rect {
+	if region.Empty() {
+		return nil
+	}
+	var fitted pdftext.Rect
+	for _, img := range page.Images {
+		if centerInside(img, region) {
+			fitted = fitted.Union(img)
+		}
+	}
+	if fitted.Empty() {
+		return &region
+	}
+	return &fitted
+}
+
+func centerInside(r, region pdftext.Rect) bool {
+	cx, cy := (r.X0+r.X1)/2, (r.Y0+r.Y1)/2
+	return cx >= region.X0 && cx <= region.X1 && cy >= region.Y0 && cy <= region.Y1
+}
+
+// captionParagraph 生成图表段落
+func captionParagraph(b *docBlock, sectionTitle string) *pb.Paragraph {
+	figureTable := &pb.FigureTable{
+		Id:           idgen.GenerateUUID(),
+		Type:         b.captionType,
+		RefIdx:       b.refIdx,
+		RefContent:   b.text,
+		RefBbox:      toBBox(b.block.Rect, b.page),
+		SectionTitle: sectionTitle,
+		SectionId:    sectionTitle,
+	}
+	if b.region != nil {
+		figureTable.Bbox = toBBox(*b.region, b.page)
+	}
+	paragraph := &pb.Paragraph{
+		Type:         pb.ParagraphType_IMAGE,
+		SectionTitle: sectionTitle,
+		SectionId:    sectionTitle,
+		FigureTable:  figureTable,
+	}
+	if b.captionType == captionTypeTable {
+		paragraph.Type = pb.ParagraphType_TABLE
+	}
+	return paragraph
+}
+
+// collectFiguresAndTables 从段落中收集图表
+func collectFiguresAndTables(paragraphs []*pb.Paragraph) []*pb.FigureTable {
+	var result []*pb.FigureTable
+	for _, p := range paragraphs {
+		if p.FigureTable != nil {
+			result = append(result, p.FigureTable)
+		}
+	}
+	return result
+}
+
+// buildFigureMarkers 生成正文中的图表引用标记
+func buildFigureMarkers(blocks []*docBlock) []*pb.RefMarker {
+	var markers []*pb.RefMarker
+	for _, b := range blocks {
+		if b.kind != kindText {
+			continue
+		}
+		for _, line := range b.block.Lines {
+			for _, loc := range figureMarkerRe.FindAllStringSubmatchIndex(line.Text, -1) {
+				_, refIdx := captionRef(line.Text[loc[2]:loc[3]], line.Text[loc[4]:loc[5]])
+				markers = append(markers, &pb.RefMarker{
+					RefIdx:     refIdx,
+					RefContent: line.Text[loc[4]:loc[5]],
+					Bbox:       toBBox(estimateRange(line, loc[0], loc[1]), b.page),
+				})
+			}
+		}
+	}
+	return markers
+}
@@ -0,0 +1,347 @@
+package native
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yb2020/odoc/pkg/pdftext"
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+)
+
+var (
+	// 参考文献条目编号，如 "[12]"、"12."
+	refBracketLabelRe = regexp.MustCompile(`^\[(\d{1,4})\]\s*`)
+	refNumberLabelRe  = regexp.MustCompile(`^(\d{1,4})\.\s+`)
+	yearRe            = regexp.MustCompile(`\b(19\d{2}|20\d{2})[a-z]?\b`)
+	arxivRe           = regexp.MustCompile(`(?i)(?:arxiv[:\s]*(?:preprint\s*)?(?:arxiv:)?|arxiv\.org/abs/)(\d{4}\.\d{4,5})`)
+	quotedTitleRe     = regexp.MustCompile(`[“"](.{8,}?)[,.]?[”"]`)
+	authorSplitRe     = regexp.MustCompile(`\s*(?:,\s*and\s+|,\s*&\s*|\s+and\s+|\s*&\s*|;\s*|,\s*)`)
+	initialsRe        = regexp.MustCompile(`^(?:[A-Z][a-z]?\.\s*-?)+$`)
+	// 正文中的数字引用标记，如 "[3]"、"[1, 4-6]"
+	citationRe = regexp.MustCompile(`\[(\d{1,4}(?:\s*[,–-]\s*\d{1,4})*)\]`)
+)
+
+// refLine 参考文献章节中的一行
+type refLine struct {
+	line  pdftext.Line
+	block *docBlock
+}
+
+// markReferences 将参考文献章节标题之后的正文块标记为参考文献
+func markReferences(blocks []*docBlock) {
+	inReferences := false
+	for _, b := range blocks {
+		switch b.kind {
+		case kindHeading:
+			inReferences = referencesHeadingRe.MatchString(strings.TrimSpace(b.text))
+		case kindText:
+			if inReferences {
+				b.kind = kindReference
+			}
+		}
+	}
+}
+
+// buildReferences 将参考文献章节切分为条目，返回条目列表和编号到 RefIdx 的映射
+func buildReferences(blocks []*docBlock) ([]*pb.Reference, map[string]string) {
+	var lines []refLine
+	for _, b := range blocks {
+		if b.kind != kindReference {
+			continue
+		}
+		for _, line := range b.block.Lines {
+			lines = append(lines, refLine{line: line, block: b})
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	var entries [][]refLine
+	var labelRe *regexp.Regexp
+	switch {
+	case countMatches(lines, refBracketLabelRe) >= 2:
+		labelRe = refBracketLabelRe
+	case countMatches(lines, refNumberLabelRe) >= 2:
+		labelRe = refNumberLabelRe
+	}
+	if labelRe != nil {
+		entries = splitByLabel(lines, labelRe)
+	} else {
+		entries = splitByIndent(lines)
+	}
+
+	references := make([]*pb.Reference, 0, len(entries))
+	labels := make(map[string]string)
+	for _, entry := range entries {
+		block := pdftext.Block{}
+		for _, l := range entry {
+			block.Lines = append(block.Lines, l.line)
+		}
+		text := strings.TrimSpace(block.Text())
+		if text == "" {
+			continue
+		}
+		ref := &pb.Reference{
+			RefIdx:      fmt.Sprintf("b%d", len(references)),
+			ContentText: text,
+			Bbox:        entryBBox(entry),
+		}
+		body := text
+		if labelRe != nil {
+			if m := labelRe.FindStringSubmatch(text); m != nil {
+				labels[m[1]] = ref.RefIdx
+				body = strings.TrimSpace(text[len(m[0]):])
+			}
+		}
+		parseReferenceText(ref, body)
+		references = append(references, ref)
+	}
+	return references, labels
+}
+
+func countMatches(lines []refLine, re *regexp.Regexp) int {
+	count := 0
+	for _, l := range lines {
+		if re.MatchString(l.line.Text) {
+			count++
+		}
+	}
+	return count
+}
+
+// splitByLabel 以带编号的行作为条目的开始
+func splitByLabel(lines []refLine, labelRe *regexp.Regexp) [][]refLine {
+	var entries [][]refLine
+	for _, l := range lines {
+		if labelRe.MatchString(l.line.Text) || len(entries) == 0 {
+			entries = append(entries, nil)
+		}
+		entries[len(entries)-1] = append(entries[len(entries)-1], l)
+	}
+	return entries
+}
+
+// splitByIndent 按悬挂缩进切分条目；没有缩进时每个块作为一个条目
+func splitByIndent(lines []refLine) [][]refLine {
+	type groupKey struct {
+		page   int
+		column int
+	}
+	minX := make(map[groupKey]float64)
+	for _, l := range lines {
+		k := groupKey{l.block.page.Number, l.line.Column}
+		if x, ok := minX[k]; !ok || l.line.Rect.X0 < x {
+			minX[k] = l.line.Rect.X0
+		}
+	}
+	indented := false
+	for _, l := range lines {
+		if l.line.Rect.X0 > minX[groupKey{l.block.page.Number, l.line.Column}]+0.8*l.line.FontSize {
+			indented = true
+			break
+		}
+	}
+	var entries [][]refLine
+	var prev *docBlock
+	for _, l := range lines {
+		var start bool
+		if indented {
+			start = l.line.Rect.X0 <= minX[groupKey{l.block.page.Number, l.line.Column}]+0.3*l.line.FontSize
+		} else {
+			start = l.block != prev
+		}
+		if start || len(entries) == 0 {
+			entries = append(entries, nil)
+		}
+		entries[len(entries)-1] = append(entries[len(entries)-1], l)
+		prev = l.block
+	}
+	return entries
+}
+
+// entryBBox 条目在首行所在页上的范围
+func entryBBox(entry []refLine) *pb.BBox {
+	page := entry[0].block.page
+	var rect pdftext.Rect
+	for _, l := range entry {
+		if l.block.page == page {
+			rect = rect.Union(l.line.Rect)
+		}
+	}
+	return toBBox(rect, page)
+}
+
+// parseReferenceText 从条目文本中抽取作者、标题、年份和 arXiv 编号
+func parseReferenceText(ref *pb.Reference, text string) {
+	if years := yearRe.FindAllStringSubmatch(text, -1); len(years) > 0 {
+		ref.PublishDate = years[len(years)-1][1]
+	}
+	if m := arxivRe.FindStringSubmatch(text); m != nil {
+		ref.ArxivId = m[1]
+	}
+	segments := splitReferenceSegments(text)
+	if m := quotedTitleRe.FindStringSubmatch(text); m != nil {
+		ref.Title = strings.TrimSpace(m[1])
+	} else if len(segments) >= 2 && utf8.RuneCountInString(segments[1]) >= 10 {
+		ref.Title = segments[1]
+	}
+	if len(segments) > 0 {
+		ref.Authors = parseReferenceAuthors(segments[0])
+	}
+}
+
+// splitReferenceSegments 按句点切分条目，作者姓名缩写中的句点不作为分隔
+func splitReferenceSegments(text string) []string {
+	var segments []string
+	var current []string
+	for _, token := range strings.Fields(text) {
+		current = append(current, token)
+		if !strings.HasSuffix(token, ".") {
+			continue
+		}
+		word := strings.Trim(token, ".()[],")
+		if utf8.RuneCountInString(word) < 2 || initialsRe.MatchString(token) {
+			continue
+		}
+		segments = append(segments, strings.TrimSuffix(strings.Join(current, " "), "."))
+		current = nil
+		if len(segments) == 3 {
+			break
+		}
+	}
+	if len(current) > 0 && len(segments) < 3 {
+		segments = append(segments, strings.Join(current, " "))
+	}
+	return segments
+}
+
+// parseReferenceAuthors 解析作者列表，兼容 "Smith, J." 与 "J. Smith" 两种写法
+func parseReferenceAuthors(segment string) []*pb.Author {
+	segment = strings.TrimSpace(yearRe.ReplaceAllString(segment, ""))
+	segment = strings.Trim(segment, " ,.()")
+	var names []string
+	for _, part := range authorSplitRe.Split(segment, -1) {
+		part = strings.Trim(strings.TrimSpace(part), "()")
+		if part == "" {
+			continue
+		}
+		lower := strings.ToLower(part)
+		if lower == "et al" || lower == "et al." {
+			continue
+		}
+		// "Smith, J." 被逗号拆开后，将缩写并入前一个姓氏
+		if initialsRe.MatchString(part) && len(names) > 0 && !strings.Contains(names[len(names)-1], " ") {
+			names[len(names)-1] = part + " " + names[len(names)-1]
+			continue
+		}
+		names = append(names, strings.TrimSuffix(part, " et al."))
+	}
+	var authors []*pb.Author
+	for _, name := range names {
+		fields := strings.Fields(name)
+		if len(fields) == 0 || len(fields) > 5 {
+			continue
+		}
+		author := &pb.Author{FullName: name, Surname: fields[len(fields)-1]}
+		if len(fields) > 1 {
+			author.GivenName = strings.Join(fields[:len(fields)-1], " ")
+		}
+		authors = append(authors, author)
+	}
+	return authors
+}
+
+// citation 正文中的一个引用标记
+type citation struct {
+	refIdx string
+	text   string
+	bbox   *pb.BBox
+}
+
+// findCitations 查找块中指向已知参考文献的数字引用标记
+func findCitations(b *docBlock, labels map[string]string) []citation {
+	if len(labels) == 0 {
+		return nil
+	}
+	var result []citation
+	for _, line := range b.block.Lines {
+		for _, loc := range citationRe.FindAllStringSubmatchIndex(line.Text, -1) {
+			text := line.Text[loc[0]:loc[1]]
+			bbox := toBBox(estimateRange(line, loc[0], loc[1]), b.page)
+			for _, number := range expandCitation(line.Text[loc[2]:loc[3]]) {
+				if refIdx, ok := labels[number]; ok {
+					result = append(result, citation{refIdx: refIdx, text: text, bbox: bbox})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// expandCitation 展开引用中的编号列表和范围，如 "1, 4-6" -> 1 4 5 6
+func expandCitation(s string) []string {
+	var numbers []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		bounds := strings.FieldsFunc(part, func(r rune) bool { return r == '-' || r == '–' })
+		if len(bounds) == 2 {
+			from, err1 := strconv.Atoi(strings.TrimSpace(bounds[0]))
+			to, err2 := strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err1 == nil && err2 == nil && to >= from && to-from <= 50 {
+				for n := from; n <= to; n++ {
+					numbers = append(numbers, strconv.Itoa(n))
+				}
+				continue
+			}
+		}
+		if part != "" {
+			numbers = append(numbers, part)
+		}
+	}
+	return numbers
+}
+
+// estimateRange 按字符位置估算行内一段文字的范围
+func estimateRange(line pdftext.Line, start, end int) pdftext.Rect {
+	total := utf8.RuneCountInString(line.Text)
+	if total == 0 {
+		return line.Rect
+	}
+	from := utf8.RuneCountInString(line.Text[:start])
+	to := utf8.RuneCountInString(line.Text[:end])
+	width := line.Rect.Width()
+	return pdftext.Rect{
+		X0: line.Rect.X0 + width*float64(from)/float64(total),
+		Y0: line.Rect.Y0,
+		X1: math.Min(line.Rect.X1, line.Rect.X0+width*float64(to)/float64(total)),
+		Y1: line.Rect.Y1,
+	}
+}
+
+// buildReferenceMarkers 生成正文中的参考文献引用标记
+func buildReferenceMarkers(blocks []*docBlock, labels map[string]string) []*pb.RefMarker {
+	var markers []*pb.RefMarker
+	for _, b := range blocks {
+		if b.kind != kindText && b.kind != kindCaption {
+			continue
+		}
+		for _, c := range findCitations(b, labels) {
+			markers = append(markers, &pb.RefMarker{RefIdx: c.refIdx, Bbox: c.bbox, RefContent: c.text})
+		}
+	}
+	return markers
+}
+
+// paragraphRefInfos 生成段落中的引用信息
+func paragraphRefInfos(b *docBlock, labels map[string]string) []*pb.RefInfo {
+	var refs []*pb.RefInfo
+	for _, c := range findCitations(b, labels) {
+		refs = append(refs, &pb.RefInfo{Text: c.text, Target: c.refIdx, Bbox: c.bbox})
+	}
+	return refs
+}
@@ -0,0 +1,499 @@
+// Package native 基于 pdftext 的版面分析结果，用启发式规则生成与 GROBID/MinerU 相同结构的解析结果
+package native
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/yb2020/odoc/pkg/pdftext"
+	pb "github.com/yb2020/odoc/proto/gen/go/parsed"
+	"github.com/yb2020/odoc/services/parse/util"
+	"github.com/yb2020/odoc/services/parse/util/mineru"
+)
+
+// blockKind 块的分类
+type blockKind int
+
+const (
+	kindText       blockKind = iota // 正文
+	kindTitle                       // 论文标题
+	kindHeading                     // 章节标题
+	kindAbandon                     // 页眉页脚、页码
+	kindCaption                     // 图表标题
+	kindFigureBody                  // 图表区域内的文字
+	kindReference                   // 参考文献
+)
+
+var (
+	// 带编号的章节标题，如 "1 Introduction"、"2.1. Setup"、"IV. RESULTS"、"A. Proof"
+	numberedHeadingRe = regexp.MustCompile(`^(\d+(?:\.\d+)*\.?|[IVX]+\.|[A-H]\.(?:\d+(?:\.\d+)*\.?)?)\s+\S`)
+	// 常见的无编号章节名
+	sectionNameRe = regexp.MustCompile(`(?i)^(?:\d+(?:\.\d+)*\.?\s*|[IVX]+\.\s*)?(abstract|introduction|related works?|background|preliminaries|methods?|methodology|approach|experiments?|experimental results|evaluation|results|discussion|conclusions?|conclusion and future work|limitations|references|bibliography|acknowledge?ments?|appendix|appendices|摘要|引言|前言|相关工作|方法|实验|结论|参考文献|致谢|附录)$`)
+	// 参考文献章节名
+	referencesHeadingRe = regexp.MustCompile(`(?i)^(?:\d+\.?\s*|[IVX]+\.\s*)?(references|bibliography|literature cited|参考文献)$`)
+	// 章节标题前的页码或行号，如 "29 1 Introduction"
+	leadingNumberRe = regexp.MustCompile(`^\d+\s+(\d)`)
+	// 纯页码，如 "3"、"- 3 -"、"Page 3"、"iv"
+	pageNumberRe = regexp.MustCompile(`(?i)^(?:page\s*)?[-–—]?\s*(?:\d{1,4}|[ivxlc]{1,6})\s*[-–—]?(?:\s*(?:of|/)\s*\d{1,4})?$`)
+	digitsRe     = regexp.MustCompile(`\d+`)
+)
+
+// docBlock 文档中的一个块及其分类结果
+type docBlock struct {
+	page  *pdftext.Page
+	block *pdftext.Block
+	text  string
+	kind  blockKind
+	level int
+	// 图表标题的类型（figure/table）和编号（如 Figure 1）
+	captionType string
+	refIdx      string
+	// 图表区域，仅图表标题块有效
+	region *pdftext.Rect
+}
+
+// HandlePages 将 pdftext 提取出的页面转换为段落、元数据和全文翻译用的页面块
+func HandlePages(pages []*pdftext.Page) ([]*pb.Paragraph, *pb.DocumentMetadata, []*pb.PageBlockData) {
+	var blocks []*docBlock
+	for _, page := range pages {
+		for i := range page.Blocks {
+			text := strings.TrimSpace(page.Blocks[i].Text())
+			if text == "" {
+				continue
+			}
+			blocks = append(blocks, &docBlock{page: page, block: &page.Blocks[i], text: text})
+		}
+	}
+	metadata := &pb.DocumentMetadata{}
+	for _, page := range pages {
+		metadata.Pages = append(metadata.Pages, &pb.PageInfo{
+			PageNumber: int32(page.Number),
+			Width:      page.Width,
+			Height:     page.Height,
+		})
+	}
+	if len(blocks) == 0 {
+		return nil, metadata, nil
+	}
+
+	markHeaderFooter(blocks, len(pages))
+	bodySize := bodyFontSize(blocks)
+	titleBlock := markTitle(blocks, bodySize)
+	classifyBlocks(blocks, bodySize, titleBlock)
+	markReferences(blocks)
+	markFigureRegions(blocks, bodySize)
+
+	references, labels := buildReferences(blocks)
+	paragraphs, catalogueItems := buildParagraphs(blocks, labels)
+
+	if titleBlock != nil {
+		metadata.Title = &pb.Title{
+			Bbox: toBBox(titleBlock.block.Rect, titleBlock.page),
+			Text: titleBlock.text,
+		}
+	}
+	metadata.Authors = mineru.HandleParagraphsToAuthors(frontMatterParagraphs(paragraphs, blocks))
+	metadata.Abstract, metadata.Acknowledgment = mineru.HandleAbstractAndAcknowledgments(paragraphs)
+	metadata.Catalogue = util.HandleCatalogueItems(catalogueItems)
+	// 标题没有编号时无法按规则建立层级，退化为一级目录
+	if len(metadata.Catalogue) == 0 {
+		metadata.Catalogue = flatCatalogue(catalogueItems)
+	}
+	metadata.References = references
+	metadata.ReferenceMarkers = buildReferenceMarkers(blocks, labels)
+	metadata.FiguresAndTables = collectFiguresAndTables(paragraphs)
+	metadata.FigureAndTableMarkers = buildFigureMarkers(blocks)
+
+	var title string
+	if metadata.Title != nil {
+		title = metadata.Title.Text
+	}
+	metadata.Lang = util.DetectLanguage(title, sampleContent(paragraphs))
+
+	return paragraphs, metadata, buildPageBlocks(blocks)
+}
+
+// markHeaderFooter 标记页面顶部和底部重复出现的文字以及页码
+func markHeaderFooter(blocks []*docBlock, pageCount int) {
+	const marginRatio = 0.08
+	counts := make(map[string]int)
+	inMargin := func(b *docBlock) bool {
+		h := b.page.Height
+		return len(b.block.Lines) <= 2 && (b.block.Rect.Y1 <= h*marginRatio || b.block.Rect.Y0 >= h*(1-marginRatio))
+	}
+	key := func(b *docBlock) string {
+		return strings.ToLower(digitsRe.ReplaceAllString(b.text, "#"))
+	}
+	seen := make(map[string]map[int]bool)
+	for _, b := range blocks {
+		if !inMargin(b) {
+			continue
+		}
+		k := key(b)
+		if seen[k] == nil {
+			seen[k] = make(map[int]bool)
+		}
+		if !seen[k][b.page.Number] {
+			seen[k][b.page.Number] = true
+			counts[k]++
+		}
+	}
+	repeatMin := int(math.Max(2, math.Ceil(float64(pageCount)*0.4)))
+	for _, b := range blocks {
+		if !inMargin(b) {
+			continue
+		}
+		if pageNumberRe.MatchString(b.text) || (pageCount >= 3 && counts[key(b)] >= repeatMin) {
+			b.kind = kindAbandon
+		}
+	}
+}
+
+// bodyFontSize 按字符数加权取出现最多的字号作为正文字号
+func bodyFontSize(blocks []*docBlock) float64 {
+	weights := make(map[float64]int)
+	for _, b := range blocks {
+		if b.kind == kindAbandon {
+			continue
+		}
+		for _, line := range b.block.Lines {
+			weights[math.Round(line.FontSize*2)/2] += len([]rune(line.Text))
+		}
+	}
+	var body float64
+	best := -1
+	for size, weight := range weights {
+		if weight > best || (weight == best && size < body) {
+			best, body = weight, size
+		}
+	}
+	return body
+}
+
+// markTitle 在首页上半部分找字号最大的块作为论文标题
+func markTitle(blocks []*docBlock, bodySize float64) *docBlock {
+	firstPage := blocks[0].page
+	var title *docBlock
+	for _, b := range blocks {
+		if b.page != firstPage {
+			break
+		}
+		if b.kind == kindAbandon || b.block.Rect.Y0 > firstPage.Height*0.5 {
+			continue
+		}
+		n := len([]rune(b.text))
+		if n < 4 || n > 300 || b.block.FontSize < bodySize*1.2 {
+			continue
+		}
+		if title == nil || b.block.FontSize > title.block.FontSize {
+			title = b
+		}
+	}
+	if title != nil {
+		title.kind = kindTitle
+	}
+	return title
+}
+
+// classifyBlocks 识别章节标题和图表标题
+func classifyBlocks(blocks []*docBlock, bodySize float64, titleBlock *docBlock) {
+	// 标题之后到第一个明确的章节之前是作者、单位等信息，不按字号判定为章节
+	frontMatter := titleBlock != nil
+	for _, b := range blocks {
+		if b.kind != kindText {
+			continue
+		}
+		if captionType, refIdx, ok := parseCaption(b); ok {
+			b.kind = kindCaption
+			b.captionType = captionType
+			b.refIdx = refIdx
+			continue
+		}
+		level, strong, ok := headingLevel(b, bodySize)
+		if !ok {
+			if frontMatter && isAbstractStart(b.text) {
+				frontMatter = false
+			}
+			continue
+		}
+		if frontMatter && !strong {
+			continue
+		}
+		frontMatter = false
+		b.kind = kindHeading
+		b.level = level
+	}
+}
+
+// headingLevel 判断块是否为章节标题，strong 表示有编号或为常见章节名
+func headingLevel(b *docBlock, bodySize float64) (level int, strong bool, ok bool) {
+	text := leadingNumberRe.ReplaceAllString(b.text, "$1")
+	n := len([]rune(text))
+	if n < 2 || n > 150 || len(b.block.Lines) > 3 || !startsWithLetterOrNumber(text) {
+		return 0, false, false
+	}
+	if sectionNameRe.MatchString(text) {
+		return numberingLevel(text), true, true
+	}
+	endsWithPeriod := strings.HasSuffix(text, ".") || strings.HasSuffix(text, "。")
+	numbered := numberedHeadingRe.MatchString(text) && !endsWithPeriod && hasUpperStart(text)
+	larger := b.block.FontSize >= bodySize+0.9
+	emphasised := b.block.Bold && b.block.FontSize >= bodySize-0.5
+	switch {
+	case numbered && (larger || emphasised || isUpperTitle(text)):
+		return numberingLevel(text), true, true
+	case larger && len(b.block.Lines) <= 2 && !endsWithPeriod:
+		return 1, false, true
+	case emphasised && len(b.block.Lines) == 1 && n <= 80 && !endsWithPeriod && hasUpperStart(text):
+		return 1, false, true
+	}
+	return 0, false, false
+}
+
+// numberingLevel 根据编号的层数计算标题层级
+func numberingLevel(text string) int {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return 1
+	}
+	number := strings.TrimSuffix(fields[0], ".")
+	if number == "" || !unicode.IsDigit([]rune(number)[0]) {
+		// 罗马数字为一级，字母编号（附录或IEEE的小节）为二级
+		if len(number) == 1 && number[0] >= 'A' && number[0] <= 'H' && len(fields) > 1 {
+			return 2
+		}
+		return 1
+	}
+	return len(strings.Split(number, "."))
+}
+
+// flatCatalogue 将没有编号的章节标题整理为一级目录
+func flatCatalogue(items []*pb.CatalogueItem) []*pb.CatalogueItem {
+	result := make([]*pb.CatalogueItem, 0, len(items))
+	for i, item := range items {
+		item.Order = int32(i + 1)
+		item.Level = "1"
+		item.TitleOrder = item.Title
+		item.Child = []*pb.CatalogueItem{}
+		result = append(result, item)
+	}
+	return result
+}
+
+// buildParagraphs 按阅读顺序生成段落和目录条目
+func buildParagraphs(blocks []*docBlock, labels map[string]string) ([]*pb.Paragraph, []*pb.CatalogueItem) {
+	var paragraphs []*pb.Paragraph
+	var catalogueItems []*pb.CatalogueItem
+	var latestTitle string
+	order := 1
+	for _, b := range blocks {
+		bbox := toBBox(b.block.Rect, b.page)
+		switch b.kind {
+		case kindHeading:
+			latestTitle = normalizedHeading(b)
+			catalogueItems = append(catalogueItems, &pb.CatalogueItem{
+				Title: latestTitle,
+				Bbox:  bbox,
+				Order: int32(len(catalogueItems) + 1),
+			})
+		case kindText:
+			language := util.DetectLanguageFallback(b.text)
+			paragraphs = append(paragraphs, &pb.Paragraph{
+				Type:         pb.ParagraphType_TEXT,
+				SectionTitle: latestTitle,
+				SectionId:    latestTitle,
+				References:   paragraphRefInfos(b, labels),
+				Text: &pb.Text{
+					Bbox:      bbox,
+					Text:      b.text,
+					Sentences: mineru.HandleParagraphToSentences(b.text, language, bbox),
+				},
+				Order: int32(order),
+			})
+			order++
+		case kindCaption:
+			paragraph := captionParagraph(b, latestTitle)
+			paragraph.Order = int32(order)
+			paragraphs = append(paragraphs, paragraph)
+			order++
+		}
+	}
+	return paragraphs, catalogueItems
+}
+
+// frontMatterParagraphs 返回首页第一个章节之前的段落，用于抽取作者
+func frontMatterParagraphs(paragraphs []*pb.Paragraph, blocks []*docBlock) []*pb.Paragraph {
+	if len(paragraphs) == 0 || paragraphs[0].SectionTitle != "" || paragraphs[0].Text == nil {
+		return nil
+	}
+	if paragraphs[0].Text.Bbox.PageNumber != int32(blocks[0].page.Number) {
+		return nil
+	}
+	return paragraphs[:1]
+}
+
+// buildPageBlocks 生成全文翻译使用的页面块
+func buildPageBlocks(blocks []*docBlock) []*pb.PageBlockData {
+	var result []*pb.PageBlockData
+	var current *pb.PageBlockData
+	index := 1
+	for _, b := range blocks {
+		if current == nil || current.PageIndex != int32(b.page.Number) {
+			current = &pb.PageBlockData{PageIndex: int32(b.page.Number)}
+			result = append(result, current)
+			index = 1
+		}
+		if b.kind == kindFigureBody {
+			continue
+		}
+		if b.kind == kindCaption && b.region != nil {
+			blockType := pb.PageBlockType_PAGE_BLOCK_FIGURE
+			if b.captionType == captionTypeTable {
+				blockType = pb.PageBlockType_PAGE_BLOCK_TABLE
+			}
+			current.PageBlocks = append(current.PageBlocks, &pb.PageBlock{
+				Type:  blockType,
+				Bbox:  toBBox(*b.region, b.page),
+				Index: int32(index),
+			})
+			index++
+		}
+		pageBlock := &pb.PageBlock{
+			Bbox:  toBBox(b.block.Rect, b.page),
+			Index: int32(index),
+		}
+		switch b.kind {
+		case kindTitle:
+			pageBlock.Type = pb.PageBlockType_PAGE_BLOCK_TITLE
+		case kindHeading:
+			pageBlock.Type = pb.PageBlockType_PAGE_BLOCK_TITLE
+			pageBlock.Level = int32(b.level)
+		case kindAbandon:
+			pageBlock.Type = pb.PageBlockType_PAGE_BLOCK_ABANDON
+		default:
+			// 图表标题和正文一样需要翻译
+			pageBlock.Type = pb.PageBlockType_PAGE_BLOCK_PLAIN_TEXT
+		}
+		for _, line := range b.block.Lines {
+			pageBlock.Texts = append(pageBlock.Texts, &pb.BlockText{
+				Bbox: toBBox(line.Rect, b.page),
+				Text: line.Text,
+			})
+		}
+		current.PageBlocks = append(current.PageBlocks, pageBlock)
+		index++
+	}
+	// 去掉没有内容的页面
+	filtered := result[:0]
+	for _, data := range result {
+		if len(data.PageBlocks) > 0 {
+			filtered = append(filtered, data)
+		}
+	}
+	return filtered
+}
+
+// sampleContent 取前若干段正文用于语言检测
+func sampleContent(paragraphs []*pb.Paragraph) string {
+	var sb strings.Builder
+	for _, p := range paragraphs {
+		if p.Text == nil {
+			continue
+		}
+		sb.WriteString(p.Text.Text)
+		sb.WriteByte(' ')
+		if sb.Len() > 2000 {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// toBBox 将 pdftext 的矩形转换为解析结果的 BBox
+func toBBox(rect pdftext.Rect, page *pdftext.Page) *pb.BBox {
+	return &pb.BBox{
+		X0:           rect.X0,
+		Y0:           rect.Y0,
+		X1:           rect.X1,
+		Y1:           rect.Y1,
+		OriginHeight: page.Height,
+		OriginWidth:  page.Width,
+		PageNumber:   int32(page.Number),
+	}
+}
+
+// textExtent 页面上非页眉页脚内容的水平范围
+func textExtent(blocks []*docBlock, page *pdftext.Page) (float64, float64) {
+	x0, x1 := page.Width, 0.0
+	for _, b := range blocks {
+		if b.page != page || b.kind == kindAbandon {
+			continue
+		}
+		x0 = math.Min(x0, b.block.Rect.X0)
+		x1 = math.Max(x1, b.block.Rect.X1)
+	}
+	if x1 <= x0 {
+		return 0, page.Width
+	}
+	return x0, x1
+}
+
+// pageBlocksOf 按纵坐标排列的同页块
+func pageBlocksOf(blocks []*docBlock, page *pdftext.Page) []*docBlock {
+	var result []*docBlock
+	for _, b := range blocks {
+		if b.page == page {
+			result = append(result, b)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].block.Rect.Y0 < result[j].block.Rect.Y0 })
+	return result
+}
+
+func isAbstractStart(text string) bool {
+	lower := strings.ToLower(text)
+	return strings.HasPrefix(lower, "abstract") || strings.HasPrefix(text, "摘要")
+}
+
+func startsWithLetterOrNumber(s string) bool {
+	for _, r := range s {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	return false
+}
+
+// hasUpperStart 编号之后的第一个字母为大写（或为中文）
+func hasUpperStart(s string) bool {
+	fields := strings.Fields(s)
+	if len(fields) > 1 && numberedHeadingRe.MatchString(s) {
+		s = strings.Join(fields[1:], " ")
+	}
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			return unicode.IsUpper(r) || unicode.Is(unicode.Han, r)
+		}
+	}
+	return false
+}
+
+// isUpperTitle 全大写的短标题，如 IEEE 模板的 "I. INTRODUCTION"
+func isUpperTitle(s string) bool {
+	letters := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			if unicode.IsLower(r) {
+				return false
+			}
+			letters++
+		}
+	}
+	return letters >= 3
+}
+
+// normalizedHeading 去掉行号并规范化后的章节标题
+func normalizedHeading(b *docBlock) string {
+	return util.NormalizeTitle(leadingNumberRe.ReplaceAllString(b.text, "$1"))
+}
@@ -6,9 +6,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/yb2020/odoc/pkg/logging"
 	"github.com/yb2020/odoc/pkg/response"
+	pb "github.com/yb2020/odoc/proto/gen/go/pay"
+	"github.com/yb2020/odoc/services/pay/model"
 	"github.com/yb2020/odoc/services/pay/service"
 )
 
+// MockSignatureHeader 模拟支付Webhook签名请求头
+const MockSignatureHeader = "Mock-Signature"
+
 // PaymentAPI 支付相关的API处理器
 type PaymentAPI struct {
 	paymentService *service.PaymentService
@@ -150,13 +155,13 @@ func (api *PaymentAPI) GetPaymentStatus(c *gin.Context) {
 
 // CreateRefundRequest 创建退款请求参数
 type CreateRefundRequest struct {
-	Amount int64  `json:"amount" binding:"required,gt=0"`
+	Amount int64  `json:"amount" binding:"gte=0"` // 退款金额（单位：分），为0时退还剩余全部金额
 	Reason string `json:"reason"`
 }
 
 // CreateRefund 创建退款
 // @Summary 创建退款
-// @Description 对指定支付创建退款
+// @Description 对指定支付创建全额或部分退款，退款完成后通过支付事件回收会员权益和积分
 // @Tags 支付
 // @Accept json
 // @Produce json
@@ -166,7 +171,7 @@ type CreateRefundRequest struct {
 // @Failure 400 {object} response.Response "请求参数错误"
 // @Failure 404 {object} response.Response "支付记录不存在"
 // @Failure 500 {object} response.Response "服务器内部错误"
-// @Router /payments/{payment_id}/refund [post]
+// @Router /admin/pay/payments/{payment_id}/refund [post]
 func (api *PaymentAPI) CreateRefund(c *gin.Context) {
 	paymentId := c.Param("payment_id")
 	if paymentId == "" {
@@ -182,14 +187,72 @@ func (api *PaymentAPI) CreateRefund(c *gin.Context) {
 		return
 	}
 
-	err := api.paymentService.CreateRefund(c, paymentId, req.Amount, req.Reason)
+	refund, err := api.paymentService.CreateRefund(c, paymentId, req.Amount, req.Reason)
 	if err != nil {
 		api.logger.Error("msg", "创建退款失败", "paymentId", paymentId, "error", err.Error())
 		response.Error(c, "创建退款失败: "+err.Error(), nil)
 		return
 	}
 
-	response.SuccessNoData(c, "Success")
+	response.Success(c, "Success", &pb.CreateRefundResp{Refund: toPaymentRefundInfo(refund)})
+}
+
+// GetRefunds 获取支付的退款和争议记录
+// @Summary 获取退款记录
+// @Description 获取指定支付的全部退款和争议记录
+// @Tags 支付
+// @Produce json
+// @Param payment_id path string true "支付ID"
+// @Success 200 {object} response.Response "退款记录列表"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /admin/pay/payments/{payment_id}/refunds [get]
+func (api *PaymentAPI) GetRefunds(c *gin.Context) {
+	paymentId := c.Param("payment_id")
+	if paymentId == "" {
+		api.logger.Error("msg", "支付ID格式错误", "paymentId", paymentId)
+		response.Error(c, "支付ID格式错误", nil)
+		return
+	}
+
+	refunds, err := api.paymentService.GetRefundsByPaymentId(c, paymentId)
+	if err != nil {
+		api.logger.Error("msg", "获取退款记录失败", "paymentId", paymentId, "error", err.Error())
+		response.Error(c, "获取退款记录失败: "+err.Error(), nil)
+		return
+	}
+
+	res := &pb.GetRefundsResp{Refunds: make([]*pb.PaymentRefundInfo, 0, len(refunds))}
+	for i := range refunds {
+		res.Refunds = append(res.Refunds, toPaymentRefundInfo(&refunds[i]))
+	}
+	response.Success(c, "Success", res)
+}
+
+// HandleMockWebhook 处理模拟支付渠道的Webhook回调，仅在启用模拟支付时注册
+// @Summary 处理模拟支付Webhook回调
+// @Description 接收模拟支付渠道推送的扣款、退款和争议事件
+// @Tags 支付
+// @Accept json
+// @Produce json
+// @Success 200 {string} string "Webhook处理成功"
+// @Failure 400 {string} string "请求参数错误"
+// @Router /services/pay/mock/webhook [post]
+func (api *PaymentAPI) HandleMockWebhook(c *gin.Context) {
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		api.logger.Error("msg", "读取Webhook请求体失败", "error", err.Error())
+		response.Error(c, "读取请求体失败", nil)
+		return
+	}
+
+	err = api.paymentService.HandleWebhook(c, model.PaymentChannelMock, body, c.GetHeader(MockSignatureHeader))
+	if err != nil {
+		api.logger.Error("msg", "处理模拟支付Webhook失败", "error", err.Error())
+		response.Error(c, "处理Webhook失败: "+err.Error(), nil)
+		return
+	}
+
+	response.SuccessNoData(c, "Webhook处理成功")
 }
 
 // GetUserPayments 获取用户支付记录
@@ -233,3 +296,23 @@ func (api *PaymentAPI) GetUserPayments(c *gin.Context) {
 // 		paymentGroup.GET("/user", api.GetUserPayments)
 // 	}
 // }
+
+// toPaymentRefundInfo 转换退款记录为接口返回结构
+func toPaymentRefundInfo(refund *model.PaymentRefund) *pb.PaymentRefundInfo {
+	info := &pb.PaymentRefundInfo{
+		Id:               refund.Id,
+		PaymentRecordId:  refund.PaymentRecordId,
+		OrderId:          refund.OrderId,
+		Type:             refund.Type,
+		ProviderRefundId: refund.ProviderRefundId,
+		Amount:           refund.Amount,
+		Currency:         refund.Currency,
+		Reason:           refund.Reason,
+		Status:           refund.Status,
+		CreatedAt:        uint64(refund.CreatedAt.UnixMilli()),
+	}
+	if refund.CompletedAt != nil {
+		info.CompletedAt = uint64(refund.CompletedAt.UnixMilli())
+	}
+	return info
+}
@@ -54,6 +54,20 @@ func (d *PaymentRecordDAO) GetByProviderTxId(ctx context.Context, providerTxId s
 	return &paymentRecord, nil
 }
 
+// GetByPaymentIntentId 根据支付渠道的扣款ID获取支付记录
+func (d *PaymentRecordDAO) GetByPaymentIntentId(ctx context.Context, paymentIntentId string) (*model.PaymentRecord, error) {
+	var paymentRecord model.PaymentRecord
+	result := d.GetDB(ctx).Where("payment_intent_id = ? and is_deleted = false", paymentIntentId).First(&paymentRecord)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "根据支付渠道扣款ID获取支付记录失败", "paymentIntentId", paymentIntentId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &paymentRecord, nil
+}
+
 // GetBySubscriptionId 根据订阅ID获取支付记录
 // 注意：一个订阅ID可能关联多个支付记录（初始支付和续费）。此方法返回找到的第一个记录。
 func (d *PaymentRecordDAO) GetBySubscriptionIdAndInvoiceId(ctx context.Context, subscriptionId string, invoiceId string) (*model.PaymentRecord, error) {
@@ -84,6 +98,22 @@ func (d *PaymentRecordDAO) UpdateFields(ctx context.Context, id string, fields m
 	return nil
 }
 
+// AddRefundedAmount 在一条条件更新中累计退款金额并更新支付状态，累计后超过 limit 时不更新并返回false。
+// 并发的退款各自累加，不会因读到旧的累计金额而丢失增量
+func (d *PaymentRecordDAO) AddRefundedAmount(ctx context.Context, id string, amount int64, limit int64) (bool, error) {
+	result := d.GetDB(ctx).Model(&model.PaymentRecord{}).
+		Where("id = ? AND refunded_amount + ? <= ?", id, amount, limit).
+		Updates(map[string]interface{}{
+			"refunded_amount": gorm.Expr("refunded_amount + ?", amount),
+			"status":          gorm.Expr("CASE WHEN refunded_amount + ? >= amount THEN ? ELSE ? END", amount, model.PaymentStatusRefunded, model.PaymentStatusPartialRefund),
+		})
+	if result.Error != nil {
+		d.logger.Error("msg", "累计退款金额失败", "id", id, "amount", amount, "error", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
 // GetByUserIdAndStatus 根据用户ID和支付状态获取支付记录列表
 func (d *PaymentRecordDAO) GetByUserIdAndStatus(ctx context.Context, userId string, status string) ([]model.PaymentRecord, error) {
 	var records []model.PaymentRecord
@@ -0,0 +1,95 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/pay/model"
+	"gorm.io/gorm"
+)
+
+// PaymentRefundDAO GORM实现的退款记录DAO
+type PaymentRefundDAO struct {
+	*baseDao.GormBaseDAO[model.PaymentRefund]
+	db     *gorm.DB
+	logger logging.Logger
+}
+
+// NewPaymentRefundDAO 创建一个新的退款记录DAO
+func NewPaymentRefundDAO(db *gorm.DB, logger logging.Logger) *PaymentRefundDAO {
+	return &PaymentRefundDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.PaymentRefund](db, logger),
+		db:          db,
+		logger:      logger,
+	}
+}
+
+// GetByProviderRefundId 根据支付渠道的退款ID或争议ID获取退款记录
+func (d *PaymentRefundDAO) GetByProviderRefundId(ctx context.Context, channel string, providerRefundId string) (*model.PaymentRefund, error) {
+	var paymentRefund model.PaymentRefund
+	result := d.GetDB(ctx).Where("channel = ? AND provider_refund_id = ? AND is_deleted = false", channel, providerRefundId).First(&paymentRefund)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "根据支付渠道退款ID获取退款记录失败", "channel", channel, "providerRefundId", providerRefundId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &paymentRefund, nil
+}
+
+// GetPendingByPaymentRecordId 获取支付记录下最早一条处理中的退款记录
+func (d *PaymentRefundDAO) GetPendingByPaymentRecordId(ctx context.Context, paymentRecordId string, refundType string) (*model.PaymentRefund, error) {
+	var paymentRefund model.PaymentRefund
+	result := d.GetDB(ctx).Where("payment_record_id = ? AND type = ? AND status = ? AND is_deleted = false", paymentRecordId, refundType, model.PaymentRefund_StatusPending).
+		Order("created_at ASC").First(&paymentRefund)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取处理中的退款记录失败", "paymentRecordId", paymentRecordId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &paymentRefund, nil
+}
+
+// GetByPaymentRecordId 获取支付记录下的全部退款和争议记录
+func (d *PaymentRefundDAO) GetByPaymentRecordId(ctx context.Context, paymentRecordId string) ([]model.PaymentRefund, error) {
+	var records []model.PaymentRefund
+	result := d.GetDB(ctx).Where("payment_record_id = ? AND is_deleted = false", paymentRecordId).
+		Order("created_at ASC").Find(&records)
+	if result.Error != nil {
+		d.logger.Error("msg", "根据支付记录ID获取退款记录失败", "paymentRecordId", paymentRecordId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return records, nil
+}
+
+// UpdateFields 更新退款记录的特定字段
+func (d *PaymentRefundDAO) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	if id == "" {
+		return gorm.ErrMissingWhereClause
+	}
+	result := d.GetDB(ctx).Model(&model.PaymentRefund{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		d.logger.Error("msg", "更新退款记录字段失败", "id", id, "error", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+// UpdatePendingFields 只更新仍处于处理中的退款记录，记录已被其他请求处理时返回false
+func (d *PaymentRefundDAO) UpdatePendingFields(ctx context.Context, id string, fields map[string]interface{}) (bool, error) {
+	if id == "" {
+		return false, gorm.ErrMissingWhereClause
+	}
+	result := d.GetDB(ctx).Model(&model.PaymentRefund{}).
+		Where("id = ? AND status = ?", id, model.PaymentRefund_StatusPending).Updates(fields)
+	if result.Error != nil {
+		d.logger.Error("msg", "更新处理中的退款记录失败", "id", id, "error", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
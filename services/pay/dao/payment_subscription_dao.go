@@ -39,3 +39,14 @@ func (d *PaymentSubscriptionDAO) GetByProviderSubscriptionId(ctx context.Context
 	}
 	return &paymentSubscription, nil
 }
+
+// GetActiveByUserId 获取用户生效中的订阅
+func (d *PaymentSubscriptionDAO) GetActiveByUserId(ctx context.Context, userId string) ([]model.PaymentSubscription, error) {
+	var subscriptions []model.PaymentSubscription
+	result := d.GetDB(ctx).Where("user_id = ? and status = ? and is_deleted = false", userId, model.PaymentSubscription_StatusActive).Find(&subscriptions)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取用户生效中的订阅失败", "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return subscriptions, nil
+}
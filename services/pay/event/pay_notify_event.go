@@ -16,6 +16,8 @@ const (
 	PayNotifyEvent_CustomerSubscriptionsDeleted eventbus.EventType = "pay.notify.customer.subscriptions.deleted"
 	PayNotifyEvent_InvoicePaymentSucceeded      eventbus.EventType = "pay.notify.invoice.payment_succeeded"
 	PayNotifyEvent_InvoicePaymentFailed         eventbus.EventType = "pay.notify.invoice.payment_failed"
+	PayNotifyEvent_PayRefunded                  eventbus.EventType = "pay.notify.pay_refunded"        // 退款完成或争议败诉，资金已退还用户
+	PayNotifyEvent_PayDisputeCreated            eventbus.EventType = "pay.notify.pay_dispute_created" // 用户发起争议，等待裁决
 )
 
 // 支付通知事件
@@ -28,4 +30,12 @@ type PayNotifyEvent struct {
 	UserId         string    `json:"user_id"`
 	SubStartAt     time.Time `json:"sub_start_at"`
 	SubEndAt       time.Time `json:"sub_end_at"`
+
+	// 退款与争议事件字段
+	RefundId       string `json:"refund_id"`       // 退款记录ID
+	RefundType     string `json:"refund_type"`     // 退款类型 REFUND / DISPUTE
+	RefundAmount   int64  `json:"refund_amount"`   // 本次退款金额
+	RefundedAmount int64  `json:"refunded_amount"` // 支付记录累计退款金额，订阅方据此幂等地计算需要回收的权益
+	PayAmount      int64  `json:"pay_amount"`      // 原支付金额
+	Reason         string `json:"reason"`          // 退款或争议原因
 }
@@ -26,6 +26,8 @@ type PaymentRecord struct {
 	InvoiceId              string    `json:"invoice_id" gorm:"column:invoice_id;type:varchar(255);index;comment:发票ID"`                             // 发票ID
 	PayMode                string    `json:"pay_mode" gorm:"column:pay_mode;type:varchar(50);index;comment:支付模式"`                                  // 支付模式 payment: 一次性支付模式, subscription: 订阅支付模式
 	PaidAt                 time.Time `json:"paid_at,omitempty" gorm:"column:paid_at;type:timestamptz"`                                             // 支付成功时间
+	PaymentIntentId        string    `json:"payment_intent_id" gorm:"column:payment_intent_id;type:varchar(255);index"`                            // 支付渠道的扣款ID（如Stripe PaymentIntent），退款和争议通过它关联支付记录
+	RefundedAmount         int64     `json:"refunded_amount" gorm:"column:refunded_amount;not null;default:0"`                                     // 累计已退款金额 (最小货币单位，分)，包含争议败诉被扣回的金额
 }
 
 // TableName returns the database table name for the PaymentRecord model.
@@ -36,18 +38,21 @@ func (PaymentRecord) TableName() string {
 // Constants for PaymentRecord Status
 // 这些常量定义了支付记录可能处于的各种状态。
 const (
-	PaymentStatusPending        = "PENDING"         // 待处理：支付已发起，但尚未最终完成（可能包括等待用户操作、渠道处理或银行确认等）
-	PaymentStatusRequiresAction = "REQUIRES_ACTION" // 需要操作：支付需要用户进行额外操作（如3D安全验证）
-	PaymentStatusSucceeded      = "SUCCEEDED"       // 成功：支付已成功完成
-	PaymentStatusFailed         = "FAILED"          // 失败：支付未能成功完成
-	PaymentStatusCanceled       = "CANCELED"        // 已取消：支付被用户或系统取消
-	PaymentStatusRefunded       = "REFUNDED"        // 已退款：支付已全额退款
+	PaymentStatusPending        = "PENDING"            // 待处理：支付已发起，但尚未最终完成（可能包括等待用户操作、渠道处理或银行确认等）
+	PaymentStatusRequiresAction = "REQUIRES_ACTION"    // 需要操作：支付需要用户进行额外操作（如3D安全验证）
+	PaymentStatusSucceeded      = "SUCCEEDED"          // 成功：支付已成功完成
+	PaymentStatusFailed         = "FAILED"             // 失败：支付未能成功完成
+	PaymentStatusCanceled       = "CANCELED"           // 已取消：支付被用户或系统取消
+	PaymentStatusRefunded       = "REFUNDED"           // 已退款：支付已全额退款
+	PaymentStatusPartialRefund  = "PARTIALLY_REFUNDED" // 部分退款：支付已退还部分金额，仍可继续退款
+	PaymentStatusDisputed       = "DISPUTED"           // 争议中：持卡人发起拒付，等待裁决
 )
 
 // Constants for PaymentRecord Channel
 // 这些常量定义了支付记录可能通过的支付服务提供商。
 const (
 	PaymentChannelStripe = "STRIPE" // Stripe 支付
+	PaymentChannelMock   = "MOCK"   // 进程内模拟支付，仅用于测试和本地联调
 	// PaymentChannelWechatPay      = "WECHAT_PAY"      // 微信支付
 	// PaymentChannelAlipay         = "ALIPAY"          // 支付宝
 	// 可以根据需要添加更多支付渠道，例如:
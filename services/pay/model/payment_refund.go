@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// PaymentRefund 退款与争议记录，一条支付记录可对应多次部分退款和争议
+type PaymentRefund struct {
+	model.BaseModel                 // Embedded BaseModel
+	UserId               string     `json:"user_id" gorm:"column:user_id;type:varchar(255);index;not null"`                     // 用户ID
+	PaymentRecordId      string     `json:"payment_record_id" gorm:"column:payment_record_id;type:varchar(255);index;not null"` // 关联的支付记录ID
+	OrderId              string     `json:"order_id" gorm:"column:order_id;type:varchar(255);index"`                            // 关联的业务订单ID
+	Channel              string     `json:"channel" gorm:"column:channel;type:varchar(50);index;not null"`                      // 支付服务提供商
+	Type                 string     `json:"type" gorm:"column:type;type:varchar(50);index;not null"`                            // 类型 (REFUND: 退款, DISPUTE: 争议)
+	ProviderRefundId     string     `json:"provider_refund_id" gorm:"column:provider_refund_id;type:varchar(255);index"`        // 支付渠道返回的退款ID或争议ID
+	Amount               int64      `json:"amount" gorm:"column:amount;not null"`                                               // 退款金额 (最小货币单位，分)
+	Currency             string     `json:"currency" gorm:"column:currency;type:varchar(10);not null"`                          // ISO 4217 货币代码
+	Reason               string     `json:"reason" gorm:"column:reason;type:varchar(255)"`                                      // 退款原因或争议原因
+	Status               string     `json:"status" gorm:"column:status;type:varchar(50);index;not null"`                        // 状态 (使用下方定义的常量)
+	ProviderErrorCode    string     `json:"provider_error_code,omitempty" gorm:"column:provider_error_code;type:varchar(100)"`  // 支付渠道返回的错误码
+	ProviderErrorMessage string     `json:"provider_error_message,omitempty" gorm:"column:provider_error_message;type:text"`    // 支付渠道返回的错误信息
+	CompletedAt          *time.Time `json:"completed_at,omitempty" gorm:"column:completed_at;type:timestamptz"`                 // 退款完成或争议裁决时间
+}
+
+// TableName returns the database table name for the PaymentRefund model.
+func (PaymentRefund) TableName() string {
+	return "t_pay_payment_refund"
+}
+
+// Constants for PaymentRefund Type
+const (
+	PaymentRefund_TypeRefund  = "REFUND"  // 商户主动退款
+	PaymentRefund_TypeDispute = "DISPUTE" // 持卡人发起的争议（拒付）
+)
+
+// Constants for PaymentRefund Status
+const (
+	PaymentRefund_StatusPending   = "PENDING"   // 处理中：退款已提交渠道，或争议等待裁决
+	PaymentRefund_StatusSucceeded = "SUCCEEDED" // 已完成：资金已退还用户，争议败诉同样视为完成
+	PaymentRefund_StatusFailed    = "FAILED"    // 失败：渠道拒绝退款
+	PaymentRefund_StatusCanceled  = "CANCELED"  // 已撤销：争议胜诉，资金未被扣回
+)
@@ -1,6 +1,8 @@
 package pay
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/pkg/scheduler"
@@ -13,6 +15,7 @@ import (
 	"github.com/yb2020/odoc/pkg/logging"
 	"github.com/yb2020/odoc/pkg/middleware"
 	"github.com/yb2020/odoc/pkg/registry"
+	msEvent "github.com/yb2020/odoc/services/membership/event"
 	msInterfaces "github.com/yb2020/odoc/services/membership/interfaces"
 	"github.com/yb2020/odoc/services/pay/api"
 	"github.com/yb2020/odoc/services/pay/dao"
@@ -24,6 +27,9 @@ import (
 // 编译时类型检查：确保 PayModule 实现了 registry.Module 接口
 var _ registry.Module = (*PayModule)(nil)
 
+// 编译时类型检查：确保 PayModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*PayModule)(nil)
+
 // Module 导出模块实例，用于自动发现和注册
 var Module = &PayModule{}
 
@@ -105,12 +111,17 @@ func (m *PayModule) Initialize() error {
 	m.paymentSubscriptionService = service.NewPaymentSubscriptionService(paymentSubscriptionDAO, m.logger)
 
 	paymentRecordDAO := dao.NewPaymentRecordDAO(m.db, m.logger)
+	paymentRefundDAO := dao.NewPaymentRefundDAO(m.db, m.logger)
 	paymentProviderFactory := provider.NewPaymentProviderFactory(m.logger)
 	if m.cfg.Pay.Stripe.IsEnable {
 		paymentProviderFactory.RegisterStripeProvider(m.cfg.Pay.Stripe.SecretKey, m.cfg.Pay.Stripe.WebhookSecret)
 	}
+	if m.cfg.Pay.Mock.IsEnable {
+		m.logger.Warn("msg", "模拟支付渠道已启用，请勿在生产环境使用")
+		paymentProviderFactory.RegisterMockProvider(m.cfg.Pay.Mock.WebhookSecret)
+	}
 
-	m.paymentService = service.NewPaymentService(paymentRecordDAO, paymentProviderFactory, m.logger)
+	m.paymentService = service.NewPaymentService(paymentRecordDAO, paymentRefundDAO, paymentProviderFactory, m.eventBus, m.logger)
 
 	m.paymentAPI = api.NewPaymentAPI(m.paymentService, m.logger)
 
@@ -121,12 +132,26 @@ func (m *PayModule) Initialize() error {
 		CheckoutSuccessURL: m.cfg.Pay.Stripe.CheckoutSuccessURL,
 		CheckoutCancelURL:  m.cfg.Pay.Stripe.CheckoutCancelURL,
 	}
-	m.checkoutService = service.NewStripeCheckoutService(stripeCheckoutConfig, *paymentRecordDAO, m.logger, m.eventBus, m.paymentSubscriptionService)
+	m.checkoutService = service.NewStripeCheckoutService(stripeCheckoutConfig, *paymentRecordDAO, m.logger, m.eventBus, m.paymentSubscriptionService, m.paymentService)
 	m.sripeCheckoutAPI = api.NewStripeCheckoutAPI(m.checkoutService, m.orderService, m.logger, m.tracer)
 
+	// 订阅会员模块PRO订单全额退款事件，立即取消渠道订阅
+	m.eventBus.Subscribe(msEvent.OrderNotifyEvent_ProRefunded, func(ctx context.Context, event eventbus.Event) {
+		m.logger.Info("msg", "收到PRO订单全额退款事件", "event", event)
+		orderEvent := event.Data.(msEvent.OrderNotifyEvent)
+		if err := m.checkoutService.CancelRefundedSubscription(ctx, orderEvent.SubscriptionId, "refunded"); err != nil {
+			m.logger.Error("msg", "全额退款后取消订阅失败", "orderId", orderEvent.OrderId, "subscriptionId", orderEvent.SubscriptionId, "error", err.Error())
+		}
+	})
+
 	return nil
 }
 
+// DeleteUserData 注销账号时立即取消用户的订阅，支付记录作为财务凭证保留
+func (m *PayModule) DeleteUserData(ctx context.Context, userId string) error {
+	return m.checkoutService.CancelUserSubscriptions(ctx, userId, "account_deleted")
+}
+
 // RegisterRoutes 注册路由
 func (m *PayModule) RegisterRoutes(r *gin.Engine) {
 	payGroup := r.Group("/api/pay")
@@ -135,7 +160,6 @@ func (m *PayModule) RegisterRoutes(r *gin.Engine) {
 		// payGroup.POST("/prepay", m.paymentAPI.PrePay)
 		// payGroup.POST("/stripe/webhook", m.paymentAPI.HandleStripeWebhook)
 		// payGroup.GET("/:payment_id/status", m.paymentAPI.GetPaymentStatus)
-		// payGroup.POST("/:payment_id/refund", m.paymentAPI.CreateRefund)
 		// payGroup.GET("/user", m.paymentAPI.GetUserPayments)
 
 		// stripe
@@ -145,6 +169,17 @@ func (m *PayModule) RegisterRoutes(r *gin.Engine) {
 		payGroup.POST("/stripe-checkout/cancel-subscription-immediately", m.sripeCheckoutAPI.CancelSubscriptionImmediately)
 	}
 
+	// 退款与争议管理
+	adminGroup := r.Group("/api/admin/pay")
+	adminGroup.Use(m.authMiddleware.AuthRequired())
+	{
+		adminGroup.POST("/payments/:payment_id/refund", m.paymentAPI.CreateRefund)
+		adminGroup.GET("/payments/:payment_id/refunds", m.paymentAPI.GetRefunds)
+	}
+
 	// 免验证回调 stripe checkout webhook
 	r.POST("/services/pay/stripe-checkout/webhook", m.sripeCheckoutAPI.HandleCheckoutWebhook)
+	if m.cfg.Pay.Mock.IsEnable {
+		r.POST("/services/pay/mock/webhook", m.paymentAPI.HandleMockWebhook)
+	}
 }
@@ -0,0 +1,341 @@
+package provider
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/pay/model"
+)
+
+// 模拟支付方式，CreateCharge 根据支付方式决定扣款结果
+const (
+	MockPaymentMethodCard     = "pm_mock_card"     // 创建后处于待支付状态，等待 CompleteCharge 或 FailCharge 推送结果
+	MockPaymentMethodDeclined = "pm_mock_declined" // 直接被拒绝，模拟卡片余额不足等同步失败
+)
+
+// 模拟渠道的 Webhook 事件类型，命名与 Stripe 保持一致
+const (
+	MockEventChargeSucceeded = "charge.succeeded"
+	MockEventChargeFailed    = "charge.failed"
+	MockEventChargeRefunded  = "charge.refunded"
+	MockEventDisputeCreated  = "charge.dispute.created"
+	MockEventDisputeClosed   = "charge.dispute.closed"
+)
+
+// MockWebhookPayload 模拟渠道的 Webhook 请求体
+type MockWebhookPayload struct {
+	Type           string            `json:"type"`
+	ChargeId       string            `json:"charge_id"`
+	Status         string            `json:"status"`
+	Amount         int64             `json:"amount"`
+	Currency       string            `json:"currency"`
+	RefundedAmount int64             `json:"refunded_amount,omitempty"`
+	DisputeId      string            `json:"dispute_id,omitempty"`
+	Reason         string            `json:"reason,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+// MockProvider 进程内的确定性模拟支付渠道，不访问任何外部网络
+// 扣款、退款和争议ID按创建顺序递增生成，Webhook 请求体为 JSON 并使用 HMAC-SHA256 签名，
+// 用于在测试和本地联调中跑通 下单 → Webhook → 会员开通 → 退款 的完整流程
+type MockProvider struct {
+	webhookSecret string
+	logger        logging.Logger
+
+	mu       sync.Mutex
+	seq      int64
+	charges  map[string]*mockCharge
+	disputes map[string]*mockDispute
+}
+
+type mockCharge struct {
+	id       string
+	amount   int64
+	currency string
+	status   string
+	refunded int64
+	metadata map[string]string
+}
+
+type mockDispute struct {
+	id       string
+	chargeId string
+	amount   int64
+	reason   string
+	closed   bool
+}
+
+// NewMockProvider 创建模拟支付渠道
+func NewMockProvider(webhookSecret string, logger logging.Logger) *MockProvider {
+	return &MockProvider{
+		webhookSecret: webhookSecret,
+		logger:        logger,
+		charges:       make(map[string]*mockCharge),
+		disputes:      make(map[string]*mockDispute),
+	}
+}
+
+// GetName 返回支付渠道名称
+func (p *MockProvider) GetName() string {
+	return model.PaymentChannelMock
+}
+
+// CreateCharge 创建模拟扣款
+func (p *MockProvider) CreateCharge(ctx context.Context, params *ChargeParams) (*ChargeResult, error) {
+	if params.Amount <= 0 {
+		return &ChargeResult{
+			Status:       model.PaymentStatusFailed,
+			ErrorCode:    "invalid_amount",
+			ErrorMessage: "amount must be greater than zero",
+		}, errors.Biz("无效的支付金额")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge := &mockCharge{
+		id:       p.nextId("mock_ch"),
+		amount:   params.Amount,
+		currency: params.Currency,
+		status:   model.PaymentStatusPending,
+		metadata: map[string]string{"order_id": params.OrderId, "user_id": params.UserId},
+	}
+	for key, value := range params.Metadata {
+		charge.metadata[key] = value
+	}
+	p.charges[charge.id] = charge
+
+	result := &ChargeResult{
+		ProviderTxId:      charge.id,
+		Status:            charge.status,
+		PaymentMethodType: "card",
+		Metadata:          copyMetadata(charge.metadata),
+	}
+	if params.PaymentMethodId == MockPaymentMethodDeclined {
+		charge.status = model.PaymentStatusFailed
+		result.Status = charge.status
+		result.ErrorCode = "card_declined"
+		result.ErrorMessage = "your card was declined"
+		return result, errors.Biz("模拟扣款被拒绝")
+	}
+	return result, nil
+}
+
+// HandleWebhook 校验签名并解析模拟渠道的 Webhook 请求体
+func (p *MockProvider) HandleWebhook(ctx context.Context, requestData []byte, signature string) (*WebhookEvent, error) {
+	if !hmac.Equal([]byte(p.Sign(requestData)), []byte(signature)) {
+		p.logger.Error("msg", "模拟支付webhook签名验证失败")
+		return nil, errors.Biz("invalid mock webhook signature")
+	}
+
+	var payload MockWebhookPayload
+	if err := json.Unmarshal(requestData, &payload); err != nil {
+		p.logger.Error("msg", "解析模拟支付webhook数据失败", "error", err.Error())
+		return nil, err
+	}
+
+	rawData := make(map[string]interface{})
+	if err := json.Unmarshal(requestData, &rawData); err != nil {
+		return nil, err
+	}
+
+	return &WebhookEvent{
+		EventType:      payload.Type,
+		ProviderTxId:   payload.ChargeId,
+		Status:         payload.Status,
+		Amount:         payload.Amount,
+		Currency:       payload.Currency,
+		RefundedAmount: payload.RefundedAmount,
+		DisputeId:      payload.DisputeId,
+		Reason:         payload.Reason,
+		Metadata:       copyMetadata(payload.Metadata),
+		RawData:        rawData,
+	}, nil
+}
+
+// CreateRefund 发起模拟退款，退款同步完成
+func (p *MockProvider) CreateRefund(ctx context.Context, chargeId string, amount int64, reason string) (*RefundResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[chargeId]
+	if !ok {
+		return &RefundResult{Status: "failed", ErrorCode: "resource_missing", ErrorMessage: "no such charge: " + chargeId}, errors.Biz("模拟扣款不存在")
+	}
+	if charge.status != model.PaymentStatusSucceeded && charge.status != model.PaymentStatusPartialRefund {
+		return &RefundResult{Status: "failed", ErrorCode: "charge_not_refundable", ErrorMessage: "charge status is " + charge.status}, errors.Biz("模拟扣款当前状态不可退款")
+	}
+	if amount <= 0 || charge.refunded+amount > charge.amount {
+		return &RefundResult{Status: "failed", ErrorCode: "amount_too_large", ErrorMessage: "refund amount exceeds remaining charge amount"}, errors.Biz("无效的退款金额")
+	}
+
+	charge.refunded += amount
+	charge.status = refundedStatus(charge)
+	return &RefundResult{
+		RefundId: p.nextId("mock_re"),
+		Status:   "succeeded",
+	}, nil
+}
+
+// GetChargeStatus 查询模拟扣款状态
+func (p *MockProvider) GetChargeStatus(ctx context.Context, chargeId string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[chargeId]
+	if !ok {
+		return "", fmt.Errorf("模拟扣款 '%s' 不存在", chargeId)
+	}
+	return charge.status, nil
+}
+
+// Sign 计算 Webhook 请求体的签名
+func (p *MockProvider) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(p.webhookSecret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CompleteCharge 模拟用户完成支付，返回 charge.succeeded 的请求体和签名
+func (p *MockProvider) CompleteCharge(chargeId string) ([]byte, string, error) {
+	return p.transitCharge(chargeId, MockEventChargeSucceeded, model.PaymentStatusSucceeded)
+}
+
+// FailCharge 模拟支付失败，返回 charge.failed 的请求体和签名
+func (p *MockProvider) FailCharge(chargeId string) ([]byte, string, error) {
+	return p.transitCharge(chargeId, MockEventChargeFailed, model.PaymentStatusFailed)
+}
+
+// RefundedEvent 返回扣款当前退款状态的 charge.refunded 请求体和签名，用于模拟渠道侧的退款通知
+func (p *MockProvider) RefundedEvent(chargeId string) ([]byte, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[chargeId]
+	if !ok {
+		return nil, "", fmt.Errorf("模拟扣款 '%s' 不存在", chargeId)
+	}
+	if charge.refunded == 0 {
+		return nil, "", fmt.Errorf("模拟扣款 '%s' 没有退款", chargeId)
+	}
+	return p.payload(charge, MockWebhookPayload{Type: MockEventChargeRefunded, Status: charge.status, RefundedAmount: charge.refunded})
+}
+
+// OpenDispute 模拟持卡人发起争议，返回争议ID以及 charge.dispute.created 的请求体和签名
+func (p *MockProvider) OpenDispute(chargeId string, reason string) (string, []byte, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[chargeId]
+	if !ok {
+		return "", nil, "", fmt.Errorf("模拟扣款 '%s' 不存在", chargeId)
+	}
+	if charge.status != model.PaymentStatusSucceeded && charge.status != model.PaymentStatusPartialRefund {
+		return "", nil, "", fmt.Errorf("模拟扣款 '%s' 当前状态 %s 不能发起争议", chargeId, charge.status)
+	}
+
+	dispute := &mockDispute{
+		id:       p.nextId("mock_dp"),
+		chargeId: chargeId,
+		amount:   charge.amount - charge.refunded,
+		reason:   reason,
+	}
+	p.disputes[dispute.id] = dispute
+	payload, signature, err := p.payload(charge, MockWebhookPayload{
+		Type:      MockEventDisputeCreated,
+		Status:    model.PaymentStatusDisputed,
+		Amount:    dispute.amount,
+		DisputeId: dispute.id,
+		Reason:    dispute.reason,
+	})
+	return dispute.id, payload, signature, err
+}
+
+// CloseDispute 模拟争议裁决，返回 charge.dispute.closed 的请求体和签名；败诉时争议金额计入扣款的退款金额
+func (p *MockProvider) CloseDispute(disputeId string, won bool) ([]byte, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	dispute, ok := p.disputes[disputeId]
+	if !ok {
+		return nil, "", fmt.Errorf("模拟争议 '%s' 不存在", disputeId)
+	}
+	if dispute.closed {
+		return nil, "", fmt.Errorf("模拟争议 '%s' 已裁决", disputeId)
+	}
+	dispute.closed = true
+
+	charge := p.charges[dispute.chargeId]
+	status := model.PaymentStatusSucceeded
+	if !won {
+		charge.refunded += dispute.amount
+		charge.status = refundedStatus(charge)
+		status = model.PaymentStatusRefunded
+	}
+	return p.payload(charge, MockWebhookPayload{
+		Type:      MockEventDisputeClosed,
+		Status:    status,
+		Amount:    dispute.amount,
+		DisputeId: dispute.id,
+		Reason:    dispute.reason,
+	})
+}
+
+// transitCharge 将待支付的扣款推进到终态并生成对应的 Webhook
+func (p *MockProvider) transitCharge(chargeId string, eventType string, status string) ([]byte, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[chargeId]
+	if !ok {
+		return nil, "", fmt.Errorf("模拟扣款 '%s' 不存在", chargeId)
+	}
+	if charge.status != model.PaymentStatusPending {
+		return nil, "", fmt.Errorf("模拟扣款 '%s' 当前状态 %s 不是待支付", chargeId, charge.status)
+	}
+	charge.status = status
+	return p.payload(charge, MockWebhookPayload{Type: eventType, Status: status})
+}
+
+// payload 补全扣款信息后序列化并签名
+func (p *MockProvider) payload(charge *mockCharge, payload MockWebhookPayload) ([]byte, string, error) {
+	payload.ChargeId = charge.id
+	payload.Currency = charge.currency
+	payload.Metadata = copyMetadata(charge.metadata)
+	if payload.Amount == 0 {
+		payload.Amount = charge.amount
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, p.Sign(data), nil
+}
+
+// nextId 生成按顺序递增的确定性ID，调用方需持有锁
+func (p *MockProvider) nextId(prefix string) string {
+	p.seq++
+	return fmt.Sprintf("%s_%06d", prefix, p.seq)
+}
+
+func refundedStatus(charge *mockCharge) string {
+	if charge.refunded >= charge.amount {
+		return model.PaymentStatusRefunded
+	}
+	return model.PaymentStatusPartialRefund
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	result := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		result[key] = value
+	}
+	return result
+}
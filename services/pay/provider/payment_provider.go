@@ -62,14 +62,19 @@ type ChargeResult struct {
 }
 
 // WebhookEvent Webhook事件信息
+// 退款事件的Status为REFUNDED或PARTIALLY_REFUNDED，RefundedAmount为渠道侧累计退款金额；
+// 争议事件带有DisputeId，发起时Status为DISPUTED，裁决后败诉为REFUNDED、胜诉为SUCCEEDED
 type WebhookEvent struct {
-	EventType    string                 // 事件类型（如payment_intent.succeeded）
-	ProviderTxId string                 // 支付渠道的交易ID
-	Status       string                 // 支付状态
-	Amount       int64                  // 金额
-	Currency     string                 // 货币代码
-	Metadata     map[string]string      // 元数据
-	RawData      map[string]interface{} // 原始事件数据
+	EventType      string                 // 事件类型（如payment_intent.succeeded）
+	ProviderTxId   string                 // 支付渠道的交易ID
+	Status         string                 // 支付状态
+	Amount         int64                  // 金额，争议事件为争议金额
+	Currency       string                 // 货币代码
+	RefundedAmount int64                  // 累计已退款金额（退款事件）
+	DisputeId      string                 // 争议ID（争议事件）
+	Reason         string                 // 争议原因（争议事件）
+	Metadata       map[string]string      // 元数据
+	RawData        map[string]interface{} // 原始事件数据
 }
 
 // RefundResult 退款结果
@@ -88,4 +93,6 @@ const (
 	PaymentStatusFailed         = model.PaymentStatusFailed
 	PaymentStatusCanceled       = model.PaymentStatusCanceled
 	PaymentStatusRefunded       = model.PaymentStatusRefunded
+	PaymentStatusPartialRefund  = model.PaymentStatusPartialRefund
+	PaymentStatusDisputed       = model.PaymentStatusDisputed
 )
@@ -27,6 +27,14 @@ func (f *PaymentProviderFactory) RegisterStripeProvider(apiKey, webhookSecret st
 	f.logger.Info("msg", "Stripe支付提供商已注册")
 }
 
+// RegisterMockProvider 注册进程内模拟支付提供商，返回实例以便测试驱动扣款、退款和争议
+func (f *PaymentProviderFactory) RegisterMockProvider(webhookSecret string) *MockProvider {
+	mockProvider := NewMockProvider(webhookSecret, f.logger)
+	f.providers[model.PaymentChannelMock] = mockProvider
+	f.logger.Info("msg", "模拟支付提供商已注册")
+	return mockProvider
+}
+
 // RegisterProvider 注册自定义支付提供商
 func (f *PaymentProviderFactory) RegisterProvider(provider PaymentProvider) {
 	providerName := provider.GetName()
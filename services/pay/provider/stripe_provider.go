@@ -103,6 +103,22 @@ func (p *StripeProvider) HandleWebhook(ctx context.Context, requestData []byte,
 		return nil, err
 	}
 
+	webhookEvent, err := ParseStripeEvent(event)
+	if err != nil {
+		p.logger.Error("msg", "解析Stripe事件数据失败", "eventType", event.Type, "error", err.Error())
+		return nil, err
+	}
+	if webhookEvent.Status == "" {
+		// 对于其他事件类型，只记录事件类型，不做特殊处理
+		p.logger.Info("msg", "收到未处理的Stripe事件类型", "eventType", event.Type)
+	}
+
+	return webhookEvent, nil
+}
+
+// ParseStripeEvent 将已验签的Stripe事件转换为通用的Webhook事件
+// 未识别的事件类型返回Status为空的事件，由调用方决定是否忽略
+func ParseStripeEvent(event stripe.Event) (*WebhookEvent, error) {
 	// 解析事件数据
 	webhookEvent := &WebhookEvent{
 		EventType: string(event.Type),
@@ -112,7 +128,6 @@ func (p *StripeProvider) HandleWebhook(ctx context.Context, requestData []byte,
 
 	// 将原始数据转换为map
 	if err := json.Unmarshal(event.Data.Raw, &webhookEvent.RawData); err != nil {
-		p.logger.Error("msg", "解析Stripe事件数据失败", "error", err.Error())
 		return nil, err
 	}
 
@@ -120,9 +135,7 @@ func (p *StripeProvider) HandleWebhook(ctx context.Context, requestData []byte,
 	switch event.Type {
 	case "payment_intent.succeeded", "payment_intent.payment_failed", "payment_intent.canceled":
 		var pi stripe.PaymentIntent
-		err := json.Unmarshal(event.Data.Raw, &pi)
-		if err != nil {
-			p.logger.Error("msg", "解析Stripe PaymentIntent数据失败", "error", err.Error())
+		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
 			return nil, err
 		}
 
@@ -138,15 +151,20 @@ func (p *StripeProvider) HandleWebhook(ctx context.Context, requestData []byte,
 
 	case "charge.refunded":
 		var charge stripe.Charge
-		err := json.Unmarshal(event.Data.Raw, &charge)
-		if err != nil {
-			p.logger.Error("msg", "解析Stripe Charge数据失败", "error", err.Error())
+		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
 			return nil, err
 		}
 
-		webhookEvent.ProviderTxId = charge.PaymentIntent.ID
-		webhookEvent.Status = model.PaymentStatusRefunded
+		webhookEvent.ProviderTxId = charge.ID
+		if charge.PaymentIntent != nil {
+			webhookEvent.ProviderTxId = charge.PaymentIntent.ID
+		}
+		webhookEvent.Status = model.PaymentStatusPartialRefund
+		if charge.Refunded {
+			webhookEvent.Status = model.PaymentStatusRefunded
+		}
 		webhookEvent.Amount = charge.Amount
+		webhookEvent.RefundedAmount = charge.AmountRefunded
 		webhookEvent.Currency = string(charge.Currency)
 
 		// 复制元数据
@@ -154,9 +172,27 @@ func (p *StripeProvider) HandleWebhook(ctx context.Context, requestData []byte,
 			webhookEvent.Metadata[key] = value
 		}
 
-	default:
-		// 对于其他事件类型，只记录事件类型，不做特殊处理
-		p.logger.Info("msg", "收到未处理的Stripe事件类型", "eventType", event.Type)
+	case "charge.dispute.created", "charge.dispute.closed":
+		var dispute stripe.Dispute
+		if err := json.Unmarshal(event.Data.Raw, &dispute); err != nil {
+			return nil, err
+		}
+
+		if dispute.PaymentIntent != nil {
+			webhookEvent.ProviderTxId = dispute.PaymentIntent.ID
+		} else if dispute.Charge != nil {
+			webhookEvent.ProviderTxId = dispute.Charge.ID
+		}
+		webhookEvent.DisputeId = dispute.ID
+		webhookEvent.Amount = dispute.Amount
+		webhookEvent.Currency = string(dispute.Currency)
+		webhookEvent.Reason = string(dispute.Reason)
+		webhookEvent.Status = mapStripeDisputeStatusToInternal(dispute.Status)
+
+		// 复制元数据
+		for key, value := range dispute.Metadata {
+			webhookEvent.Metadata[key] = value
+		}
 	}
 
 	return webhookEvent, nil
@@ -230,6 +266,18 @@ func mapStripeStatusToInternal(status stripe.PaymentIntentStatus) string {
 	}
 }
 
+// mapStripeDisputeStatusToInternal 将Stripe争议状态映射为支付状态：败诉视为退款，胜诉恢复为成功，其余为争议中
+func mapStripeDisputeStatusToInternal(status stripe.DisputeStatus) string {
+	switch status {
+	case stripe.DisputeStatusLost:
+		return model.PaymentStatusRefunded
+	case stripe.DisputeStatusWon, stripe.DisputeStatusWarningClosed:
+		return model.PaymentStatusSucceeded
+	default:
+		return model.PaymentStatusDisputed
+	}
+}
+
 // mapRefundReasonToStripe 将内部退款原因映射为Stripe退款原因
 func mapRefundReasonToStripe(reason string) string {
 	switch reason {
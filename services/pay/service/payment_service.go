@@ -3,31 +3,50 @@ package service
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
+	"strings"
 	"time"
 
+	"github.com/yb2020/odoc/pkg/audit"
 	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/eventbus"
+	"github.com/yb2020/odoc/pkg/idgen"
 	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/metrics"
 	"github.com/yb2020/odoc/services/pay/dao"
+	"github.com/yb2020/odoc/services/pay/event"
 	"github.com/yb2020/odoc/services/pay/model"
 	"github.com/yb2020/odoc/services/pay/provider"
 )
 
+// 累计退款金额与并发的退款冲突时的最大尝试次数
+const refundConflictRetries = 3
+
+// errRefundConflict 累计退款金额已被并发的退款更新，本次累计会超过上限
+var errRefundConflict = stderrors.New("refunded amount changed concurrently")
+
 // PaymentService 支付服务，协调支付记录DAO和支付提供商
 type PaymentService struct {
 	paymentRecordDAO       *dao.PaymentRecordDAO
+	paymentRefundDAO       *dao.PaymentRefundDAO
 	paymentProviderFactory *provider.PaymentProviderFactory
+	eventBus               *eventbus.EventBus
 	logger                 logging.Logger
 }
 
 // NewPaymentService 创建一个新的支付服务
 func NewPaymentService(
 	paymentRecordDAO *dao.PaymentRecordDAO,
+	paymentRefundDAO *dao.PaymentRefundDAO,
 	paymentProviderFactory *provider.PaymentProviderFactory,
+	eventBus *eventbus.EventBus,
 	logger logging.Logger,
 ) *PaymentService {
 	return &PaymentService{
 		paymentRecordDAO:       paymentRecordDAO,
+		paymentRefundDAO:       paymentRefundDAO,
 		paymentProviderFactory: paymentProviderFactory,
+		eventBus:               eventBus,
 		logger:                 logger,
 	}
 }
@@ -149,15 +168,39 @@ func (s *PaymentService) HandleWebhook(ctx context.Context, channel string, requ
 		return errors.Biz("解析Webhook数据失败")
 	}
 
+	return s.HandleWebhookEvent(ctx, channel, webhookEvent)
+}
+
+// HandleWebhookEvent 处理已解析的Webhook事件，包括支付结果、退款和争议
+func (s *PaymentService) HandleWebhookEvent(ctx context.Context, channel string, webhookEvent *provider.WebhookEvent) error {
+	if webhookEvent.Status == "" || webhookEvent.ProviderTxId == "" {
+		s.logger.Info("msg", "忽略无需处理的Webhook事件", "channel", channel, "eventType", webhookEvent.EventType)
+		return nil
+	}
+
 	// 查找对应的支付记录
-	paymentRecord, err := s.paymentRecordDAO.GetByProviderTxId(ctx, webhookEvent.ProviderTxId)
+	paymentRecord, err := s.getPaymentRecordByProviderTxId(ctx, webhookEvent.ProviderTxId)
 	if err != nil {
 		s.logger.Error("msg", "查询支付记录失败", "error", err.Error())
 		return errors.Biz("查询支付记录失败")
 	}
+	if paymentRecord == nil {
+		// 非本系统发起的交易，直接确认收到，避免渠道反复重试
+		s.logger.Warn("msg", "Webhook事件对应的支付记录不存在", "channel", channel, "eventType", webhookEvent.EventType, "providerTxId", webhookEvent.ProviderTxId)
+		return nil
+	}
+
+	if webhookEvent.DisputeId != "" {
+		return s.handleDisputeEvent(ctx, paymentRecord, webhookEvent)
+	}
+
+	switch webhookEvent.Status {
+	case model.PaymentStatusSucceeded:
+		if paymentRecord.Status != model.PaymentStatusPending && paymentRecord.Status != model.PaymentStatusRequiresAction {
+			s.logger.Info("msg", "支付记录已处理，忽略重复的支付成功事件", "paymentId", paymentRecord.Id, "status", paymentRecord.Status)
+			return nil
+		}
 
-	// 更新支付记录状态
-	if webhookEvent.Status == model.PaymentStatusSucceeded {
 		// 支付成功，更新支付记录
 		updateFields := map[string]interface{}{
 			"status":         model.PaymentStatusSucceeded,
@@ -170,10 +213,25 @@ func (s *PaymentService) HandleWebhook(ctx context.Context, channel string, requ
 			return errors.Biz("更新支付记录失败")
 		}
 
-		// TODO: 触发支付成功后的业务逻辑，如更新订单状态、发送通知等
-		// 这里可以通过事件总线或直接调用其他服务来完成
+		metrics.RecordPayment(ctx, channel, metrics.PaymentSucceeded, paymentRecord.Amount, paymentRecord.Currency)
+
+		// 通知业务模块发放权益
+		s.eventBus.Publish(ctx, eventbus.Event{
+			Type: event.PayNotifyEvent_PaySuccess,
+			Data: event.PayNotifyEvent{
+				OrderId:     paymentRecord.OrderId,
+				PayRecordId: paymentRecord.Id,
+				PayMode:     paymentRecord.PayMode,
+				UserId:      paymentRecord.UserId,
+			},
+		}, true)
+
+	case model.PaymentStatusFailed, model.PaymentStatusCanceled:
+		if paymentRecord.Status != model.PaymentStatusPending && paymentRecord.Status != model.PaymentStatusRequiresAction {
+			s.logger.Info("msg", "支付记录已处理，忽略重复的支付失败事件", "paymentId", paymentRecord.Id, "status", paymentRecord.Status)
+			return nil
+		}
 
-	} else if webhookEvent.Status == model.PaymentStatusFailed || webhookEvent.Status == model.PaymentStatusCanceled {
 		// 支付失败或取消，更新支付记录
 		updateFields := map[string]interface{}{
 			"status":         webhookEvent.Status,
@@ -185,60 +243,127 @@ func (s *PaymentService) HandleWebhook(ctx context.Context, channel string, requ
 			return errors.Biz("更新支付记录失败")
 		}
 
-		// TODO: 触发支付失败后的业务逻辑，如释放库存、通知用户等
+		outcome := metrics.PaymentFailed
+		if webhookEvent.Status == model.PaymentStatusCanceled {
+			outcome = metrics.PaymentCanceled
+		}
+		metrics.RecordPayment(ctx, channel, outcome, paymentRecord.Amount, paymentRecord.Currency)
+
+		s.eventBus.Publish(ctx, eventbus.Event{
+			Type: event.PayNotifyEvent_PayFailed,
+			Data: event.PayNotifyEvent{
+				OrderId:     paymentRecord.OrderId,
+				PayRecordId: paymentRecord.Id,
+				UserId:      paymentRecord.UserId,
+			},
+		}, true)
+
+	case model.PaymentStatusRefunded, model.PaymentStatusPartialRefund:
+		return s.handleRefundEvent(ctx, paymentRecord, webhookEvent)
 	}
 
 	return nil
 }
 
-// CreateRefund 创建退款
-func (s *PaymentService) CreateRefund(ctx context.Context, paymentId string, amount int64, reason string) error {
+// CreateRefund 创建退款，amount为0时退还剩余全部金额
+// 渠道同步返回成功时立即回收权益，处理中的退款等待渠道的退款Webhook完成
+func (s *PaymentService) CreateRefund(ctx context.Context, paymentId string, amount int64, reason string) (*model.PaymentRefund, error) {
 	// 查找支付记录
 	paymentRecord, err := s.paymentRecordDAO.FindById(ctx, paymentId)
 	if err != nil {
 		s.logger.Error("msg", "查询支付记录失败", "error", err.Error())
-		return errors.Biz("查询支付记录失败")
+		return nil, errors.Biz("查询支付记录失败")
+	}
+	if paymentRecord == nil {
+		return nil, errors.Biz("支付记录不存在")
 	}
 
 	// 检查支付状态
-	if paymentRecord.Status != model.PaymentStatusSucceeded {
-		return errors.Biz("只有成功的支付才能退款")
+	if paymentRecord.Status != model.PaymentStatusSucceeded && paymentRecord.Status != model.PaymentStatusPartialRefund {
+		return nil, errors.Biz("只有成功的支付才能退款")
 	}
 
-	// 检查退款金额
-	if amount <= 0 || amount > paymentRecord.Amount {
-		return errors.Biz("无效的退款金额")
+	// 检查退款金额，这里只是提前拒绝明显超额的请求，累计时会在条件更新中再次校验
+	refundable := paymentRecord.Amount - paymentRecord.RefundedAmount
+	if amount == 0 {
+		amount = refundable
+	}
+	if amount <= 0 || amount > refundable {
+		return nil, errors.Biz("无效的退款金额")
 	}
 
 	// 获取支付提供商
 	paymentProvider, err := s.paymentProviderFactory.GetProvider(paymentRecord.Channel)
 	if err != nil {
 		s.logger.Error("msg", "获取支付提供商失败", "channel", paymentRecord.Channel, "error", err.Error())
-		return errors.Biz("获取支付提供商失败")
+		return nil, errors.Biz("获取支付提供商失败")
 	}
 
-	// 调用支付提供商创建退款
-	refundResult, err := paymentProvider.CreateRefund(ctx, paymentRecord.ProviderTxId, amount, reason)
-	if err != nil {
-		s.logger.Error("msg", "创建退款失败", "error", err.Error())
-		return errors.Biz("创建退款失败")
+	// 先落库退款记录，渠道调用失败时保留失败原因
+	paymentRefund := &model.PaymentRefund{
+		UserId:          paymentRecord.UserId,
+		PaymentRecordId: paymentRecord.Id,
+		OrderId:         paymentRecord.OrderId,
+		Channel:         paymentRecord.Channel,
+		Type:            model.PaymentRefund_TypeRefund,
+		Amount:          amount,
+		Currency:        paymentRecord.Currency,
+		Reason:          reason,
+		Status:          model.PaymentRefund_StatusPending,
+	}
+	paymentRefund.Id = idgen.GenerateUUID()
+	if err := s.paymentRefundDAO.Save(ctx, paymentRefund); err != nil {
+		s.logger.Error("msg", "创建退款记录失败", "error", err.Error())
+		return nil, errors.Biz("创建退款记录失败")
 	}
 
-	// 更新支付记录状态
-	updateFields := map[string]interface{}{
-		"status": model.PaymentStatusRefunded,
+	// 调用支付提供商创建退款
+	chargeId := paymentRecord.PaymentIntentId
+	if chargeId == "" {
+		chargeId = paymentRecord.ProviderTxId
 	}
-	err = s.paymentRecordDAO.UpdateFields(ctx, paymentId, updateFields)
+	refundResult, err := paymentProvider.CreateRefund(ctx, chargeId, amount, reason)
 	if err != nil {
-		s.logger.Error("msg", "更新支付记录失败", "error", err.Error())
-		return errors.Biz("更新支付记录失败")
+		s.logger.Error("msg", "创建退款失败", "paymentId", paymentId, "error", err.Error())
+		s.failRefund(ctx, paymentRefund, refundResult)
+		return nil, errors.Biz("创建退款失败")
+	}
+
+	paymentRefund.ProviderRefundId = refundResult.RefundId
+	switch mapRefundResultStatus(refundResult.Status) {
+	case model.PaymentRefund_StatusSucceeded:
+		err := s.completeRefund(ctx, paymentRecord, paymentRefund, amount, paymentRecord.Amount)
+		if stderrors.Is(err, errRefundConflict) {
+			// 并发的退款已用完可退金额，渠道会拒绝超额退款，不应出现；记录后等待渠道通知按累计金额校正
+			s.logger.Error("msg", "退款金额超过可退金额", "paymentId", paymentId, "refundId", refundResult.RefundId, "amount", amount)
+			return nil, errors.Biz("无效的退款金额")
+		}
+		if err != nil {
+			return nil, err
+		}
+	case model.PaymentRefund_StatusFailed:
+		s.failRefund(ctx, paymentRefund, refundResult)
+		return nil, errors.Biz("创建退款失败")
+	default:
+		if err := s.paymentRefundDAO.UpdateFields(ctx, paymentRefund.Id, map[string]interface{}{"provider_refund_id": paymentRefund.ProviderRefundId}); err != nil {
+			return nil, errors.Biz("更新退款记录失败")
+		}
+		s.logger.Info("msg", "退款处理中，等待渠道通知", "paymentId", paymentId, "refundId", refundResult.RefundId)
 	}
 
-	// TODO: 创建退款记录
-	// TODO: 触发退款成功后的业务逻辑，如更新订单状态、发送通知等
+	s.logger.Info("msg", "退款已提交", "paymentId", paymentId, "refundId", refundResult.RefundId, "amount", amount, "status", paymentRefund.Status)
+	_ = audit.Record(ctx, &audit.Entry{
+		Action:     audit.ActionRefund,
+		EntityType: "payment",
+		EntityId:   paymentId,
+		After:      paymentRefund,
+	})
+	return paymentRefund, nil
+}
 
-	s.logger.Info("msg", "退款成功", "paymentId", paymentId, "refundId", refundResult.RefundId)
-	return nil
+// GetRefundsByPaymentId 获取支付记录下的退款和争议记录
+func (s *PaymentService) GetRefundsByPaymentId(ctx context.Context, paymentId string) ([]model.PaymentRefund, error) {
+	return s.paymentRefundDAO.GetByPaymentRecordId(ctx, paymentId)
 }
 
 // GetPaymentStatus 获取支付状态
@@ -340,10 +465,262 @@ func (s *PaymentService) updatePaymentRecordStatus(ctx context.Context, paymentI
 	}
 }
 
-// isTerminalStatus 判断支付状态是否是终态
+// handleRefundEvent 处理渠道的退款通知，按累计退款金额计算增量，重复通知不会重复回收权益
+func (s *PaymentService) handleRefundEvent(ctx context.Context, paymentRecord *model.PaymentRecord, webhookEvent *provider.WebhookEvent) error {
+	delta := webhookEvent.RefundedAmount - paymentRecord.RefundedAmount
+	if delta <= 0 {
+		s.logger.Info("msg", "退款已处理，忽略重复的退款事件", "paymentId", paymentRecord.Id, "refundedAmount", webhookEvent.RefundedAmount)
+		return nil
+	}
+
+	paymentRefund, err := s.paymentRefundDAO.GetPendingByPaymentRecordId(ctx, paymentRecord.Id, model.PaymentRefund_TypeRefund)
+	if err != nil {
+		return errors.Biz("查询退款记录失败")
+	}
+	created := paymentRefund == nil
+	if created {
+		// 渠道后台直接发起的退款，本地没有对应记录
+		paymentRefund = &model.PaymentRefund{
+			UserId:          paymentRecord.UserId,
+			PaymentRecordId: paymentRecord.Id,
+			OrderId:         paymentRecord.OrderId,
+			Channel:         paymentRecord.Channel,
+			Type:            model.PaymentRefund_TypeRefund,
+			Amount:          delta,
+			Currency:        paymentRecord.Currency,
+			Reason:          "refunded by provider",
+			Status:          model.PaymentRefund_StatusPending,
+		}
+		paymentRefund.Id = idgen.GenerateUUID()
+		if err := s.paymentRefundDAO.Save(ctx, paymentRefund); err != nil {
+			s.logger.Error("msg", "创建退款记录失败", "error", err.Error())
+			return errors.Biz("创建退款记录失败")
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		// 累计金额不能超过渠道通知的累计退款金额，同一笔退款的同步结果和通知只计入一次
+		err = s.completeRefund(ctx, paymentRecord, paymentRefund, delta, webhookEvent.RefundedAmount)
+		if !stderrors.Is(err, errRefundConflict) || attempt >= refundConflictRetries {
+			return err
+		}
+		// 并发的退款已更新累计金额，重新读取后按最新金额计算增量
+		paymentRecord, err = s.paymentRecordDAO.FindById(ctx, paymentRecord.Id)
+		if err != nil || paymentRecord == nil {
+			return errors.Biz("查询支付记录失败")
+		}
+		delta = webhookEvent.RefundedAmount - paymentRecord.RefundedAmount
+		if delta <= 0 {
+			s.logger.Info("msg", "退款已由并发请求计入，忽略退款事件", "paymentId", paymentRecord.Id, "refundedAmount", webhookEvent.RefundedAmount)
+			if created {
+				return s.paymentRefundDAO.UpdateFields(ctx, paymentRefund.Id, map[string]interface{}{"status": model.PaymentRefund_StatusCanceled})
+			}
+			return nil
+		}
+	}
+}
+
+// handleDisputeEvent 处理争议通知：发起时标记支付记录为争议中，败诉按退款回收权益，胜诉恢复支付状态
+func (s *PaymentService) handleDisputeEvent(ctx context.Context, paymentRecord *model.PaymentRecord, webhookEvent *provider.WebhookEvent) error {
+	dispute, err := s.paymentRefundDAO.GetByProviderRefundId(ctx, paymentRecord.Channel, webhookEvent.DisputeId)
+	if err != nil {
+		return errors.Biz("查询争议记录失败")
+	}
+	if dispute != nil && dispute.Status != model.PaymentRefund_StatusPending {
+		s.logger.Info("msg", "争议已裁决，忽略重复的争议事件", "paymentId", paymentRecord.Id, "disputeId", webhookEvent.DisputeId)
+		return nil
+	}
+	if dispute == nil {
+		dispute = &model.PaymentRefund{
+			UserId:           paymentRecord.UserId,
+			PaymentRecordId:  paymentRecord.Id,
+			OrderId:          paymentRecord.OrderId,
+			Channel:          paymentRecord.Channel,
+			Type:             model.PaymentRefund_TypeDispute,
+			ProviderRefundId: webhookEvent.DisputeId,
+			Amount:           webhookEvent.Amount,
+			Currency:         paymentRecord.Currency,
+			Reason:           webhookEvent.Reason,
+			Status:           model.PaymentRefund_StatusPending,
+		}
+		dispute.Id = idgen.GenerateUUID()
+		if err := s.paymentRefundDAO.Save(ctx, dispute); err != nil {
+			s.logger.Error("msg", "创建争议记录失败", "error", err.Error())
+			return errors.Biz("创建争议记录失败")
+		}
+	} else if webhookEvent.Status == model.PaymentStatusDisputed {
+		return nil
+	}
+
+	switch webhookEvent.Status {
+	case model.PaymentStatusDisputed:
+		if err := s.paymentRecordDAO.UpdateFields(ctx, paymentRecord.Id, map[string]interface{}{"status": model.PaymentStatusDisputed}); err != nil {
+			return errors.Biz("更新支付记录失败")
+		}
+		metrics.RecordPayment(ctx, paymentRecord.Channel, metrics.PaymentDisputed, dispute.Amount, paymentRecord.Currency)
+		s.logger.Warn("msg", "支付发生争议", "paymentId", paymentRecord.Id, "disputeId", dispute.ProviderRefundId, "amount", dispute.Amount, "reason", dispute.Reason)
+
+		s.eventBus.Publish(ctx, eventbus.Event{
+			Type: event.PayNotifyEvent_PayDisputeCreated,
+			Data: s.newRefundNotifyEvent(paymentRecord, dispute, dispute.Amount),
+		}, true)
+		return nil
+
+	case model.PaymentStatusRefunded:
+		// 争议败诉，资金已被扣回
+		for attempt := 1; ; attempt++ {
+			amount := dispute.Amount
+			if refundable := paymentRecord.Amount - paymentRecord.RefundedAmount; amount > refundable {
+				amount = refundable
+			}
+			err = s.completeRefund(ctx, paymentRecord, dispute, amount, paymentRecord.Amount)
+			if !stderrors.Is(err, errRefundConflict) || attempt >= refundConflictRetries {
+				return err
+			}
+			// 并发的退款已更新累计金额，重新读取后计算可扣回的金额
+			paymentRecord, err = s.paymentRecordDAO.FindById(ctx, paymentRecord.Id)
+			if err != nil || paymentRecord == nil {
+				return errors.Biz("查询支付记录失败")
+			}
+		}
+
+	case model.PaymentStatusSucceeded:
+		// 争议胜诉，恢复争议前的支付状态
+		now := time.Now()
+		if err := s.paymentRefundDAO.UpdateFields(ctx, dispute.Id, map[string]interface{}{
+			"status":       model.PaymentRefund_StatusCanceled,
+			"completed_at": &now,
+		}); err != nil {
+			return errors.Biz("更新争议记录失败")
+		}
+		status := model.PaymentStatusSucceeded
+		if paymentRecord.RefundedAmount > 0 {
+			status = model.PaymentStatusPartialRefund
+		}
+		if err := s.paymentRecordDAO.UpdateFields(ctx, paymentRecord.Id, map[string]interface{}{"status": status}); err != nil {
+			return errors.Biz("更新支付记录失败")
+		}
+		s.logger.Info("msg", "支付争议胜诉", "paymentId", paymentRecord.Id, "disputeId", dispute.ProviderRefundId)
+	}
+
+	return nil
+}
+
+// completeRefund 完成处理中的退款记录并累计退款金额，然后通知业务模块回收权益。
+// 累计后超过 limit 时撤销本次完成并返回 errRefundConflict，由调用方重新读取支付记录后决定是否重试
+func (s *PaymentService) completeRefund(ctx context.Context, paymentRecord *model.PaymentRecord, paymentRefund *model.PaymentRefund, amount int64, limit int64) error {
+	// 先把退款记录从处理中改为已完成，同一笔退款的同步结果和渠道通知同时到达时只有一方继续
+	now := time.Now()
+	claimed, err := s.paymentRefundDAO.UpdatePendingFields(ctx, paymentRefund.Id, map[string]interface{}{
+		"provider_refund_id": paymentRefund.ProviderRefundId,
+		"amount":             amount,
+		"status":             model.PaymentRefund_StatusSucceeded,
+		"completed_at":       &now,
+	})
+	if err != nil {
+		return errors.Biz("更新退款记录失败")
+	}
+	if !claimed {
+		s.logger.Info("msg", "退款已完成，忽略重复处理", "paymentId", paymentRecord.Id, "refundId", paymentRefund.Id)
+		return nil
+	}
+
+	applied, err := s.paymentRecordDAO.AddRefundedAmount(ctx, paymentRecord.Id, amount, limit)
+	if err != nil || !applied {
+		// 恢复为处理中，等待重试或渠道通知
+		if restoreErr := s.paymentRefundDAO.UpdateFields(ctx, paymentRefund.Id, map[string]interface{}{
+			"status":       model.PaymentRefund_StatusPending,
+			"completed_at": nil,
+		}); restoreErr != nil {
+			s.logger.Error("msg", "恢复退款记录失败", "refundId", paymentRefund.Id, "error", restoreErr.Error())
+		}
+		if err != nil {
+			return errors.Biz("更新支付记录失败")
+		}
+		return errRefundConflict
+	}
+
+	latest, err := s.paymentRecordDAO.FindById(ctx, paymentRecord.Id)
+	if err != nil || latest == nil {
+		return errors.Biz("查询支付记录失败")
+	}
+	paymentRecord.Status = latest.Status
+	paymentRecord.RefundedAmount = latest.RefundedAmount
+	paymentRefund.Amount = amount
+	paymentRefund.Status = model.PaymentRefund_StatusSucceeded
+	paymentRefund.CompletedAt = &now
+
+	metrics.RecordPayment(ctx, paymentRecord.Channel, metrics.PaymentRefunded, amount, paymentRecord.Currency)
+	s.logger.Info("msg", "退款完成", "paymentId", paymentRecord.Id, "refundId", paymentRefund.Id, "type", paymentRefund.Type, "amount", amount, "refundedAmount", paymentRecord.RefundedAmount)
+
+	s.eventBus.Publish(ctx, eventbus.Event{
+		Type: event.PayNotifyEvent_PayRefunded,
+		Data: s.newRefundNotifyEvent(paymentRecord, paymentRefund, amount),
+	}, true)
+	return nil
+}
+
+// failRefund 标记退款失败并记录渠道错误信息
+func (s *PaymentService) failRefund(ctx context.Context, paymentRefund *model.PaymentRefund, refundResult *provider.RefundResult) {
+	updateFields := map[string]interface{}{
+		"status": model.PaymentRefund_StatusFailed,
+	}
+	if refundResult != nil {
+		updateFields["provider_refund_id"] = refundResult.RefundId
+		updateFields["provider_error_code"] = refundResult.ErrorCode
+		updateFields["provider_error_message"] = refundResult.ErrorMessage
+	}
+	paymentRefund.Status = model.PaymentRefund_StatusFailed
+	if err := s.paymentRefundDAO.UpdateFields(ctx, paymentRefund.Id, updateFields); err != nil {
+		s.logger.Error("msg", "更新退款记录失败", "refundId", paymentRefund.Id, "error", err.Error())
+	}
+}
+
+// newRefundNotifyEvent 构建退款与争议的通知事件
+func (s *PaymentService) newRefundNotifyEvent(paymentRecord *model.PaymentRecord, paymentRefund *model.PaymentRefund, amount int64) event.PayNotifyEvent {
+	return event.PayNotifyEvent{
+		OrderId:        paymentRecord.OrderId,
+		PayRecordId:    paymentRecord.Id,
+		SubscriptionId: paymentRecord.ProviderSubscriptionId,
+		InvoiceId:      paymentRecord.InvoiceId,
+		PayMode:        paymentRecord.PayMode,
+		UserId:         paymentRecord.UserId,
+		RefundId:       paymentRefund.Id,
+		RefundType:     paymentRefund.Type,
+		RefundAmount:   amount,
+		RefundedAmount: paymentRecord.RefundedAmount,
+		PayAmount:      paymentRecord.Amount,
+		Reason:         paymentRefund.Reason,
+	}
+}
+
+// getPaymentRecordByProviderTxId 根据渠道交易ID查找支付记录，找不到时按扣款ID查找（如Checkout会话记录）
+func (s *PaymentService) getPaymentRecordByProviderTxId(ctx context.Context, providerTxId string) (*model.PaymentRecord, error) {
+	paymentRecord, err := s.paymentRecordDAO.GetByProviderTxId(ctx, providerTxId)
+	if err != nil || paymentRecord != nil {
+		return paymentRecord, err
+	}
+	return s.paymentRecordDAO.GetByPaymentIntentId(ctx, providerTxId)
+}
+
+// mapRefundResultStatus 将渠道返回的退款状态映射为退款记录状态
+func mapRefundResultStatus(status string) string {
+	switch strings.ToLower(status) {
+	case "succeeded":
+		return model.PaymentRefund_StatusSucceeded
+	case "failed", "canceled":
+		return model.PaymentRefund_StatusFailed
+	default:
+		return model.PaymentRefund_StatusPending
+	}
+}
+
+// isTerminalStatus 判断支付状态是否是终态，已成功的支付后续变化只通过Webhook更新
 func isTerminalStatus(status string) bool {
 	return status == model.PaymentStatusSucceeded ||
 		status == model.PaymentStatusFailed ||
 		status == model.PaymentStatusCanceled ||
-		status == model.PaymentStatusRefunded
+		status == model.PaymentStatusRefunded ||
+		status == model.PaymentStatusPartialRefund ||
+		status == model.PaymentStatusDisputed
 }
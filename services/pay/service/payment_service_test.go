@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	"github.com/yb2020/odoc/pkg/eventbus"
+	"github.com/yb2020/odoc/services/pay/dao"
+	"github.com/yb2020/odoc/services/pay/model"
+	"github.com/yb2020/odoc/services/pay/provider"
+)
+
+func TestCompleteRefundWithStaleRecord(t *testing.T) {
+	db := daotest.NewDB(t, &model.PaymentRecord{}, &model.PaymentRefund{})
+	logger := daotest.NewLogger()
+	ctx := context.Background()
+	recordDAO := dao.NewPaymentRecordDAO(db, logger)
+	refundDAO := dao.NewPaymentRefundDAO(db, logger)
+	s := NewPaymentService(recordDAO, refundDAO, provider.NewPaymentProviderFactory(logger), eventbus.NewEventBus(), logger)
+
+	record := &model.PaymentRecord{UserId: "u1", OrderId: "o1", Amount: 1000, Currency: "usd", Status: model.PaymentStatusSucceeded}
+	record.Id = "pay1"
+	if err := recordDAO.Save(ctx, record); err != nil {
+		t.Fatalf("save payment: %v", err)
+	}
+	newRefund := func(id string, amount int64) *model.PaymentRefund {
+		refund := &model.PaymentRefund{PaymentRecordId: record.Id, Type: model.PaymentRefund_TypeRefund, Amount: amount, Status: model.PaymentRefund_StatusPending}
+		refund.Id = id
+		if err := refundDAO.Save(ctx, refund); err != nil {
+			t.Fatalf("save refund: %v", err)
+		}
+		return refund
+	}
+
+	tests := []struct {
+		name         string
+		refundId     string
+		amount       int64
+		wantErr      error
+		wantRefunded int64
+		wantStatus   string
+	}{
+		// 两笔退款都基于累计金额为0时读取的支付记录完成，增量不能丢失
+		{name: "first partial refund", refundId: "re1", amount: 300, wantRefunded: 300, wantStatus: model.PaymentStatusPartialRefund},
+		{name: "second partial refund on stale record", refundId: "re2", amount: 400, wantRefunded: 700, wantStatus: model.PaymentStatusPartialRefund},
+		{name: "refund over the payment amount", refundId: "re3", amount: 400, wantErr: errRefundConflict, wantRefunded: 700, wantStatus: model.PaymentStatusPartialRefund},
+		{name: "remaining amount", refundId: "re4", amount: 300, wantRefunded: 1000, wantStatus: model.PaymentStatusRefunded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stale := *record
+			refund := newRefund(tt.refundId, tt.amount)
+			if err := s.completeRefund(ctx, &stale, refund, tt.amount, record.Amount); err != tt.wantErr {
+				t.Fatalf("completeRefund err = %v, want %v", err, tt.wantErr)
+			}
+			stored, err := recordDAO.FindById(ctx, record.Id)
+			if err != nil || stored.RefundedAmount != tt.wantRefunded || stored.Status != tt.wantStatus {
+				t.Fatalf("payment = %+v, err = %v, want %d %s", stored, err, tt.wantRefunded, tt.wantStatus)
+			}
+			// 超额时退款记录恢复为处理中
+			wantRefundStatus := model.PaymentRefund_StatusSucceeded
+			if tt.wantErr != nil {
+				wantRefundStatus = model.PaymentRefund_StatusPending
+			}
+			if stored, err := refundDAO.FindById(ctx, tt.refundId); err != nil || stored.Status != wantRefundStatus {
+				t.Fatalf("refund = %+v, err = %v, want %s", stored, err, wantRefundStatus)
+			}
+		})
+	}
+
+	// 同一退款记录再次完成（同步结果与渠道通知都到达）时不重复累计
+	again := &model.PaymentRefund{}
+	again.Id = "re1"
+	if err := s.completeRefund(ctx, record, again, 300, record.Amount); err != nil {
+		t.Fatalf("complete again: %v", err)
+	}
+	if stored, _ := recordDAO.FindById(ctx, record.Id); stored.RefundedAmount != 1000 {
+		t.Fatalf("refunded amount = %d, want 1000", stored.RefundedAmount)
+	}
+}
@@ -44,3 +44,8 @@ func (p *PaymentSubscriptionService) NewSubscription(ctx context.Context, paySub
 func (p *PaymentSubscriptionService) GetByProviderSubscriptionId(ctx context.Context, providerSubscriptionId string) (*model.PaymentSubscription, error) {
 	return p.paymentSubscriptionDAO.GetByProviderSubscriptionId(ctx, providerSubscriptionId)
 }
+
+// GetActiveByUserId 获取用户生效中的订阅
+func (p *PaymentSubscriptionService) GetActiveByUserId(ctx context.Context, userId string) ([]model.PaymentSubscription, error) {
+	return p.paymentSubscriptionDAO.GetActiveByUserId(ctx, userId)
+}
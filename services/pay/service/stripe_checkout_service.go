@@ -14,11 +14,13 @@ import (
 	"github.com/yb2020/odoc/services/pay/dao"
 	"github.com/yb2020/odoc/services/pay/event"
 	"github.com/yb2020/odoc/services/pay/model"
+	"github.com/yb2020/odoc/services/pay/provider"
 
 	"github.com/yb2020/odoc/pkg/errors"
 	"github.com/yb2020/odoc/pkg/eventbus"
 	"github.com/yb2020/odoc/pkg/idgen"
 	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/metrics"
 )
 
 // StripeCheckoutConfig 保存 StripeCheckoutService 的配置。
@@ -38,10 +40,11 @@ type StripeCheckoutService struct {
 	logger                     logging.Logger     // 日志记录
 	eventBus                   *eventbus.EventBus // 事件总线
 	paymentSubscriptionService *PaymentSubscriptionService
+	paymentService             *PaymentService // 退款与争议事件交由支付服务统一处理
 }
 
 // NewStripeCheckoutService 创建 StripeCheckoutService 的一个新实例。
-func NewStripeCheckoutService(cfg StripeCheckoutConfig, prDAO dao.PaymentRecordDAO, logger logging.Logger, eventBus *eventbus.EventBus, paymentSubscriptionService *PaymentSubscriptionService) *StripeCheckoutService {
+func NewStripeCheckoutService(cfg StripeCheckoutConfig, prDAO dao.PaymentRecordDAO, logger logging.Logger, eventBus *eventbus.EventBus, paymentSubscriptionService *PaymentSubscriptionService, paymentService *PaymentService) *StripeCheckoutService {
 	// 通常的做法是在应用程序启动时全局设置一次 Stripe API 密钥。
 	stripe.Key = cfg.SecretKey
 	// 但是，如果此服务可能使用不同的密钥或在特定上下文中运行，
@@ -52,6 +55,7 @@ func NewStripeCheckoutService(cfg StripeCheckoutConfig, prDAO dao.PaymentRecordD
 		logger:                     logger,
 		eventBus:                   eventBus,
 		paymentSubscriptionService: paymentSubscriptionService,
+		paymentService:             paymentService,
 	}
 }
 
@@ -180,6 +184,53 @@ func (s *StripeCheckoutService) CancelSubscriptionImmediately(ctx context.Contex
 	return subscription.Cancel(subID, params)
 }
 
+// CancelUserSubscriptions 注销账号时立即取消用户生效中的订阅，停止后续扣款
+// 支付记录和订阅记录作为财务凭证保留，只把订阅标记为已取消；Stripe 上已不存在的订阅视为已取消
+func (s *StripeCheckoutService) CancelUserSubscriptions(ctx context.Context, userId string, reason string) error {
+	subscriptions, err := s.paymentSubscriptionService.GetActiveByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	for i := range subscriptions {
+		if err := s.cancelSubscription(ctx, &subscriptions[i], reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelRefundedSubscription 订阅订单全额退款后立即取消对应的订阅，停止后续扣款
+// 未找到或已取消的订阅直接忽略
+func (s *StripeCheckoutService) CancelRefundedSubscription(ctx context.Context, providerSubscriptionId string, reason string) error {
+	sub, err := s.paymentSubscriptionService.GetByProviderSubscriptionId(ctx, providerSubscriptionId)
+	if err != nil {
+		return err
+	}
+	if sub == nil || sub.Status == model.PaymentSubscription_StatusCanceled {
+		s.logger.Info("msg", "退款订单的订阅不存在或已取消", "subscriptionId", providerSubscriptionId)
+		return nil
+	}
+	return s.cancelSubscription(ctx, sub, reason)
+}
+
+// cancelSubscription 立即取消渠道订阅并把订阅记录标记为已取消，Stripe 上已不存在的订阅视为已取消
+func (s *StripeCheckoutService) cancelSubscription(ctx context.Context, sub *model.PaymentSubscription, reason string) error {
+	if sub.ProviderSubscriptionId != "" && s.cfg.SecretKey != "" {
+		if _, err := s.CancelSubscriptionImmediately(ctx, sub.ProviderSubscriptionId); err != nil {
+			var stripeErr *stripe.Error
+			if !errors.As(err, &stripeErr) || stripeErr.Code != stripe.ErrorCodeResourceMissing {
+				s.logger.Error("msg", "取消订阅失败", "userId", sub.UserId, "subscriptionId", sub.ProviderSubscriptionId, "reason", reason, "error", err.Error())
+				return err
+			}
+		}
+	}
+	canceledAt := time.Now()
+	sub.Status = model.PaymentSubscription_StatusCanceled
+	sub.CancelAt = &canceledAt
+	sub.CancelReason = reason
+	return s.paymentSubscriptionService.UpdateSubscription(ctx, sub)
+}
+
 // HandleCheckoutWebhook 处理与 Checkout 相关的传入 Stripe webhook 事件。
 //
 // 该函数负责处理来自 Stripe 的多种 webhook 事件，以确保支付状态的同步和订阅生命周期的正确管理。
@@ -197,6 +248,11 @@ func (s *StripeCheckoutService) CancelSubscriptionImmediately(ctx context.Contex
 // - customer.subscription.deleted: 订阅被取消或因付款失败而终止。
 // - invoice.payment_succeeded: 订阅的周期性续订付款成功。
 // - invoice.payment_failed: 订阅的周期性续订付款失败。
+//
+// --- 退款与争议事件 ---
+// - charge.refunded: 扣款被全额或部分退款（包括在 Stripe 后台发起的退款）。
+// - charge.dispute.created: 持卡人发起争议。
+// - charge.dispute.closed: 争议裁决完成，败诉时回收权益。
 func (s *StripeCheckoutService) HandleCheckoutWebhook(ctx context.Context, payload []byte, signatureHeader string) error {
 	// 确保为此操作设置了 Stripe API 密钥（尽管 webhook.ConstructEvent 可能不直接使用它，但相关的 API 调用可能会）。
 	stripe.Key = s.cfg.SecretKey
@@ -308,6 +364,14 @@ func (s *StripeCheckoutService) HandleCheckoutWebhook(ctx context.Context, paylo
 		s.logger.Warn("msg", "Subscription renewal failed", "subscription_id", subscriptionID, "invoice_id", invoice.ID)
 		return s.processInvoicePaymentFailed(ctx, &invoice)
 
+	case "charge.refunded", "charge.dispute.created", "charge.dispute.closed":
+		webhookEvent, err := provider.ParseStripeEvent(event)
+		if err != nil {
+			s.logger.Error("msg", "Error parsing webhook JSON for refund or dispute", "event_id", event.ID, "event_type", event.Type, "error", err)
+			return errors.Biz(fmt.Sprintf("error parsing webhook JSON for %s: %v", event.Type, err))
+		}
+		return s.paymentService.HandleWebhookEvent(ctx, model.PaymentChannelStripe, webhookEvent)
+
 	default:
 		s.logger.Info("未处理的 Stripe webhook 事件类型：%s", event.Type)
 	}
@@ -389,6 +453,10 @@ func (s *StripeCheckoutService) processCheckoutSessionCompleted(ctx context.Cont
 	paymentRecord.Id = idgen.GenerateUUID()
 	paymentRecord.CreatedAt = currentTime
 	paymentRecord.UpdatedAt = currentTime
+	// 退款和争议事件只携带 PaymentIntent，需要保存以关联到此支付记录
+	if cs.PaymentIntent != nil {
+		paymentRecord.PaymentIntentId = cs.PaymentIntent.ID
+	}
 
 	// 如果 PaymentIntent ID 可用且有用，您也可以存储它。
 	// if cs.PaymentIntent != nil {
@@ -400,6 +468,7 @@ func (s *StripeCheckoutService) processCheckoutSessionCompleted(ctx context.Cont
 		// 	cs.ClientReferenceID, cs.ID, err)
 		return errors.Biz("failed to save payment record: %s" + err.Error())
 	}
+	metrics.RecordPayment(ctx, model.PaymentChannelStripe, metrics.PaymentSucceeded, paymentRecord.Amount, paymentRecord.Currency)
 
 	// 处理订阅信息
 	if paymentRecord.PayMode == model.PaymentModeSubscription {
@@ -444,6 +513,7 @@ func (s *StripeCheckoutService) processCheckoutSessionPaymentFailed(ctx context.
 		return nil // 返回 nil 以向 Stripe 确认 webhook 已收到，因为重试也无济于事。
 	}
 	s.logger.Warn("msg", "Payment failed for order", "order_id", cs.ClientReferenceID, "session_id", cs.ID)
+	metrics.RecordPayment(ctx, model.PaymentChannelStripe, metrics.PaymentFailed, cs.AmountTotal, string(cs.Currency))
 
 	// 在这里，您通常会更新内部支付/订单记录为“失败”状态。
 	// 例如，通过 ClientReferenceID (orderId) 查找支付记录并更新其状态。
@@ -611,7 +681,14 @@ func (s *StripeCheckoutService) processInvoicePaymentSucceeded(ctx context.Conte
 		s.logger.Error("msg", "Error checking existing payment record for subscription", "subscription_id", subscriptionID, "invoice_id", invoice.ID, "error", err)
 		return err
 	}
+	paymentIntentId := invoicePaymentIntentId(invoice)
 	if existingPaymentRecord != nil && existingPaymentRecord.Status == model.PaymentStatusSucceeded {
+		// 首期发票由 Checkout 会话创建支付记录，此时补充 PaymentIntent 以便后续退款
+		if existingPaymentRecord.PaymentIntentId == "" && paymentIntentId != "" {
+			if err := s.paymentRecordDAO.UpdateFields(ctx, existingPaymentRecord.Id, map[string]interface{}{"payment_intent_id": paymentIntentId}); err != nil {
+				return err
+			}
+		}
 		s.logger.Info(ctx, "Subscription %s (InvoiceID: %s) 的支付已处理。", subscriptionID, invoice.ID)
 		return nil // 已成功处理
 	}
@@ -642,6 +719,7 @@ func (s *StripeCheckoutService) processInvoicePaymentSucceeded(ctx context.Conte
 		PaidAt:                 time.Unix(invoice.StatusTransitions.PaidAt, 0),
 	}
 	renewalPaymentRecord.Id = idgen.GenerateUUID()
+	renewalPaymentRecord.PaymentIntentId = paymentIntentId
 
 	if err := s.paymentRecordDAO.Save(ctx, renewalPaymentRecord); err != nil {
 		s.logger.Error("msg", "Failed to create payment record for subscription renewal", "subscription_id", subscriptionID, "invoice_id", invoice.ID, "error", err)
@@ -649,6 +727,7 @@ func (s *StripeCheckoutService) processInvoicePaymentSucceeded(ctx context.Conte
 	}
 
 	s.logger.Info("msg", "Successfully created payment record for subscription renewal", "subscription_id", subscriptionID, "invoice_id", invoice.ID)
+	metrics.RecordPayment(ctx, model.PaymentChannelStripe, metrics.PaymentSucceeded, renewalPaymentRecord.Amount, renewalPaymentRecord.Currency)
 
 	s.eventBus.Publish(ctx, eventbus.Event{
 		Type: event.PayNotifyEvent_InvoicePaymentSucceeded,
@@ -667,9 +746,23 @@ func (s *StripeCheckoutService) processInvoicePaymentSucceeded(ctx context.Conte
 	return nil
 }
 
+// invoicePaymentIntentId 返回发票关联的 PaymentIntent ID，未展开时返回空字符串。
+func invoicePaymentIntentId(invoice *stripe.Invoice) string {
+	if invoice.Payments == nil {
+		return ""
+	}
+	for _, payment := range invoice.Payments.Data {
+		if payment.Payment != nil && payment.Payment.PaymentIntent != nil {
+			return payment.Payment.PaymentIntent.ID
+		}
+	}
+	return ""
+}
+
 // processInvoicePaymentFailed 处理接收到 invoice.payment_failed 事件时的业务逻辑。
 func (s *StripeCheckoutService) processInvoicePaymentFailed(ctx context.Context, invoice *stripe.Invoice) error {
 
 	s.logger.Info("msg", "Invoice payment failed", "invoice_id", invoice.ID)
+	metrics.RecordPayment(ctx, model.PaymentChannelStripe, metrics.PaymentFailed, invoice.AmountDue, string(invoice.Currency))
 	return nil
 }
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/docformat"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/pdf"
+	"github.com/yb2020/odoc/services/pdf/model"
+	"github.com/yb2020/odoc/services/pdf/service"
+)
+
+// DocumentAPI 非PDF文档API处理器
+type DocumentAPI struct {
+	documentImportService *service.DocumentImportService
+	docTextMarkService    *service.DocTextMarkService
+	logger                logging.Logger
+	tracer                opentracing.Tracer
+}
+
+// NewDocumentAPI 创建非PDF文档API处理器
+func NewDocumentAPI(documentImportService *service.DocumentImportService, docTextMarkService *service.DocTextMarkService, logger logging.Logger, tracer opentracing.Tracer) *DocumentAPI {
+	return &DocumentAPI{
+		documentImportService: documentImportService,
+		docTextMarkService:    docTextMarkService,
+		logger:                logger,
+		tracer:                tracer,
+	}
+}
+
+/*
+* @api_path: /api/pdf/document/upload
+* @method: POST
+* @content-type: multipart/form-data
+* @summary: 上传EPUB、DOCX、HTML或Markdown文档
+ */
+func (api *DocumentAPI) UploadDocument(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "DocumentAPI.UploadDocument")
+	defer span.Finish()
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		api.logger.Error("msg", "获取上传文件失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+	if fileHeader.Size > api.documentImportService.MaxSize() {
+		c.Error(errors.Biz("pdf.document.errors.file_too_large"))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		api.logger.Error("msg", "打开上传文件失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(io.LimitReader(file, api.documentImportService.MaxSize()+1))
+	if err != nil {
+		api.logger.Error("msg", "读取上传文件失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	imported, err := api.documentImportService.ImportDocument(ctx, userId, fileHeader.Filename, content, c.PostForm("folderId"))
+	if err != nil {
+		api.logger.Error("msg", "导入文档失败", "fileName", fileHeader.Filename, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.ImportDocumentResponse{Document: toImportedDocumentInfo(imported)})
+}
+
+/*
+* @api_path: /api/pdf/document/importUrl
+* @method: POST
+* @content-type: application/json
+* @summary: 抓取网页文章并导入
+ */
+func (api *DocumentAPI) ImportDocumentUrl(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "DocumentAPI.ImportDocumentUrl")
+	defer span.Finish()
+
+	var req pb.ImportDocumentUrlRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	imported, err := api.documentImportService.ImportURL(ctx, userId, req.Url, req.FolderId)
+	if err != nil {
+		api.logger.Error("msg", "导入网页失败", "url", req.Url, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.ImportDocumentResponse{Document: toImportedDocumentInfo(imported)})
+}
+
+/*
+* @api_path: /api/pdf/document/content
+* @method: GET
+* @content-type: application/json
+* @summary: 获取文档转换后的Markdown
+ */
+func (api *DocumentAPI) GetDocumentContent(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "DocumentAPI.GetDocumentContent")
+	defer span.Finish()
+
+	var req pb.GetDocumentContentRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	content, err := api.documentImportService.GetDocumentContent(ctx, userId, req.PdfId)
+	if err != nil {
+		api.logger.Error("msg", "获取文档内容失败", "pdfId", req.PdfId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.GetDocumentContentResponse{
+		PdfId:      content.PdfId,
+		FileFormat: content.FileFormat,
+		Title:      content.Title,
+		Markdown:   content.Markdown,
+	})
+}
+
+/*
+* @api_path: /api/pdf/document/export
+* @method: GET
+* @content-type: text/markdown
+* @summary: 导出带高亮和批注的Markdown文件
+ */
+func (api *DocumentAPI) ExportDocument(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "DocumentAPI.ExportDocument")
+	defer span.Finish()
+
+	var req pb.ExportDocumentRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	fileName, content, err := api.docTextMarkService.ExportMarkdown(ctx, userId, req.PdfId)
+	if err != nil {
+		api.logger.Error("msg", "导出文档失败", "pdfId", req.PdfId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"; filename*=UTF-8''%s", req.PdfId+".md", url.PathEscape(fileName)))
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(content))
+}
+
+/*
+* @api_path: /api/pdf/textMark/list
+* @method: GET
+* @content-type: application/json
+* @summary: 获取文档中的文本标注
+ */
+func (api *DocumentAPI) ListDocTextMarks(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "DocumentAPI.ListDocTextMarks")
+	defer span.Finish()
+
+	var req pb.ListDocTextMarksRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	items, err := api.docTextMarkService.ListMarks(ctx, userId, req.PdfId)
+	if err != nil {
+		api.logger.Error("msg", "获取文本标注失败", "pdfId", req.PdfId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	resp := &pb.ListDocTextMarksResponse{}
+	for _, item := range items {
+		info := toDocTextMarkInfo(item.Mark)
+		info.Orphaned = item.Orphaned
+		resp.Marks = append(resp.Marks, info)
+	}
+	response.Success(c, "success", resp)
+}
+
+/*
+* @api_path: /api/pdf/textMark/save
+* @method: POST
+* @content-type: application/json
+* @summary: 保存文本标注
+ */
+func (api *DocumentAPI) SaveDocTextMark(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "DocumentAPI.SaveDocTextMark")
+	defer span.Finish()
+
+	var req pb.SaveDocTextMarkRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+	if req.Anchor == nil || req.Anchor.Exact == "" {
+		response.ErrorNoData(c, "缺少标注文本")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	anchor := docformat.Anchor{
+		Exact:  req.Anchor.Exact,
+		Prefix: req.Anchor.Prefix,
+		Suffix: req.Anchor.Suffix,
+		Start:  int(req.Anchor.Start),
+		End:    int(req.Anchor.End),
+	}
+	mark, err := api.docTextMarkService.SaveMark(ctx, userId, req.PdfId, anchor, req.Color, req.Idea)
+	if err != nil {
+		api.logger.Error("msg", "保存文本标注失败", "pdfId", req.PdfId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.SaveDocTextMarkResponse{Mark: toDocTextMarkInfo(mark)})
+}
+
+/*
+* @api_path: /api/pdf/textMark/update
+* @method: POST
+* @content-type: application/json
+* @summary: 修改文本标注的颜色和批注
+ */
+func (api *DocumentAPI) UpdateDocTextMark(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "DocumentAPI.UpdateDocTextMark")
+	defer span.Finish()
+
+	var req pb.UpdateDocTextMarkRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	mark, err := api.docTextMarkService.UpdateMark(ctx, userId, req.Id, req.Color, req.Idea)
+	if err != nil {
+		api.logger.Error("msg", "修改文本标注失败", "id", req.Id, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.UpdateDocTextMarkResponse{Mark: toDocTextMarkInfo(mark)})
+}
+
+/*
+* @api_path: /api/pdf/textMark/delete
+* @method: POST
+* @content-type: application/json
+* @summary: 删除文本标注
+ */
+func (api *DocumentAPI) DeleteDocTextMark(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "DocumentAPI.DeleteDocTextMark")
+	defer span.Finish()
+
+	var req pb.DeleteDocTextMarkRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	if err := api.docTextMarkService.DeleteMark(ctx, userId, req.Id); err != nil {
+		api.logger.Error("msg", "删除文本标注失败", "id", req.Id, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.SuccessNoData(c, "success")
+}
+
+func toImportedDocumentInfo(imported *service.ImportedDocument) *pb.ImportedDocumentInfo {
+	return &pb.ImportedDocumentInfo{
+		DocId:      imported.DocId,
+		PaperId:    imported.PaperId,
+		PdfId:      imported.PdfId,
+		FileFormat: imported.FileFormat,
+		Title:      imported.Title,
+		Existed:    imported.Existed,
+	}
+}
+
+func toDocTextMarkInfo(mark *model.DocTextMark) *pb.DocTextMarkInfo {
+	return &pb.DocTextMarkInfo{
+		Id:    mark.Id,
+		PdfId: mark.PdfId,
+		Anchor: &pb.TextAnchor{
+			Exact:  mark.Exact,
+			Prefix: mark.Prefix,
+			Suffix: mark.Suffix,
+			Start:  uint32(mark.StartOffset),
+			End:    uint32(mark.EndOffset),
+		},
+		Color:     mark.Color,
+		Idea:      mark.Idea,
+		CreatedAt: uint64(mark.CreatedAt.UnixMilli()),
+	}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/pdf"
+	noteInterfaces "github.com/yb2020/odoc/services/note/interfaces"
+	paperNotesService "github.com/yb2020/odoc/services/note/service"
+	"github.com/yb2020/odoc/services/pdf/model"
+	"github.com/yb2020/odoc/services/pdf/service"
+)
+
+// PaperSummaryAPI 论文结构化总结API处理器
+type PaperSummaryAPI struct {
+	paperSummaryGenerateService *service.PaperSummaryGenerateService
+	noteSummaryService          *paperNotesService.NoteSummaryService
+	paperNoteService            noteInterfaces.IPaperNoteService
+	logger                      logging.Logger
+	tracer                      opentracing.Tracer
+}
+
+// NewPaperSummaryAPI 创建论文结构化总结API处理器
+func NewPaperSummaryAPI(
+	paperSummaryGenerateService *service.PaperSummaryGenerateService,
+	noteSummaryService *paperNotesService.NoteSummaryService,
+	paperNoteService noteInterfaces.IPaperNoteService,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+) *PaperSummaryAPI {
+	return &PaperSummaryAPI{
+		paperSummaryGenerateService: paperSummaryGenerateService,
+		noteSummaryService:          noteSummaryService,
+		paperNoteService:            paperNoteService,
+		logger:                      logger,
+		tracer:                      tracer,
+	}
+}
+
+/*
+* @api_path: /api/pdf/summary/templates
+* @method: GET
+* @content-type: application/json
+* @summary: 获取论文结构化总结模板列表
+ */
+func (api *PaperSummaryAPI) GetTemplates(c *gin.Context) {
+	resp := &pb.GetPaperSummaryTemplatesResponse{
+		DefaultTemplate: api.paperSummaryGenerateService.GetDefaultTemplateKey(),
+	}
+	for _, template := range api.paperSummaryGenerateService.GetTemplates() {
+		item := &pb.PaperSummaryTemplate{
+			Key:  template.Key,
+			Name: template.Name,
+		}
+		for _, section := range template.Sections {
+			item.Sections = append(item.Sections, &pb.PaperSummaryTemplateSection{
+				Key:   section.Key,
+				Title: section.Title,
+			})
+		}
+		resp.Templates = append(resp.Templates, item)
+	}
+	response.Success(c, "success", resp)
+}
+
+/*
+* @api_path: /api/pdf/summary/get
+* @method: POST
+* @content-type: application/json
+* @summary: 获取已生成的论文结构化总结
+ */
+func (api *PaperSummaryAPI) GetSummary(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "PaperSummaryAPI.GetSummary")
+	defer span.Finish()
+
+	var req pb.GetPaperSummaryRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	lang := api.resolveLang(c, req.Lang)
+	summary, err := api.paperSummaryGenerateService.GetSummary(ctx, userId, req.PdfId, req.TemplateKey, lang)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", buildPaperSummaryResponse(summary, req.TemplateKey, lang))
+}
+
+/*
+* @api_path: /api/pdf/summary/generate
+* @method: POST
+* @content-type: application/json
+* @summary: 生成论文结构化总结（已有缓存时直接返回，不扣积分）
+ */
+func (api *PaperSummaryAPI) GenerateSummary(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "PaperSummaryAPI.GenerateSummary")
+	defer span.Finish()
+
+	var req pb.GeneratePaperSummaryRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	lang := api.resolveLang(c, req.Lang)
+	summary, err := api.paperSummaryGenerateService.GenerateSummary(ctx, userId, req.PdfId, req.TemplateKey, lang)
+	if err != nil {
+		api.logger.Error("msg", "生成论文总结失败", "pdfId", req.PdfId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", buildPaperSummaryResponse(summary, req.TemplateKey, lang))
+}
+
+/*
+* @api_path: /api/pdf/summary/applyToNote
+* @method: POST
+* @content-type: application/json
+* @summary: 将已生成的论文总结写入笔记总结
+ */
+func (api *PaperSummaryAPI) ApplyToNote(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "PaperSummaryAPI.ApplyToNote")
+	defer span.Finish()
+
+	var req pb.ApplyPaperSummaryToNoteRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	paperNote, err := api.paperNoteService.GetPaperNoteById(ctx, req.NoteId)
+	if err != nil {
+		api.logger.Error("msg", "获取论文笔记失败", "error", err.Error())
+		response.ErrorNoData(c, "获取论文笔记失败")
+		return
+	}
+	// 检查笔记是否存在以及是否属于自己
+	if userId == "" || paperNote == nil || paperNote.CreatorId != userId {
+		response.ErrorNoData(c, "笔记不存在或者不属于您本人")
+		return
+	}
+
+	summary, err := api.paperSummaryGenerateService.GetSummary(ctx, userId, paperNote.PdfId, req.TemplateKey, api.resolveLang(c, req.Lang))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if summary == nil || summary.Summary == "" {
+		c.Error(errors.Biz("pdf.pdf_summary.errors.not_found"))
+		return
+	}
+
+	if err := api.noteSummaryService.SaveNoteSummaryContent(ctx, req.NoteId, userId, summary.Summary, req.Append); err != nil {
+		api.logger.Error("msg", "写入笔记总结失败", "noteId", req.NoteId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.SuccessNoData(c, "success")
+}
+
+// resolveLang 请求未指定语言时使用Accept-Language
+func (api *PaperSummaryAPI) resolveLang(c *gin.Context, lang string) string {
+	if lang == "" {
+		lang = c.GetHeader("Accept-Language")
+	}
+	return service.NormalizeSummaryLang(lang)
+}
+
+// buildPaperSummaryResponse 构建论文总结响应
+func buildPaperSummaryResponse(summary *model.PdfSummary, templateKey string, lang string) *pb.PaperSummaryResponse {
+	resp := &pb.PaperSummaryResponse{
+		TemplateKey: templateKey,
+		Lang:        lang,
+	}
+	if summary == nil {
+		return resp
+	}
+	resp.Exist = true
+	resp.TemplateKey = summary.Template
+	resp.Lang = summary.Lang
+	resp.Markdown = summary.Summary
+	resp.ModifyDate = uint64(summary.UpdatedAt.UnixMilli())
+	sections, _ := summary.GetSections()
+	for _, section := range sections {
+		resp.Sections = append(resp.Sections, &pb.PaperSummarySection{
+			Key:     section.Key,
+			Title:   section.Title,
+			Content: section.Content,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/pdf"
+	paperService "github.com/yb2020/odoc/services/paper/service"
+	"github.com/yb2020/odoc/services/pdf/service"
+)
+
+// PaperVersionAPI 论文版本API处理器
+type PaperVersionAPI struct {
+	paperVersionService *service.PaperVersionService
+	logger              logging.Logger
+	tracer              opentracing.Tracer
+}
+
+// NewPaperVersionAPI 创建论文版本API处理器
+func NewPaperVersionAPI(
+	paperVersionService *service.PaperVersionService,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+) *PaperVersionAPI {
+	return &PaperVersionAPI{
+		paperVersionService: paperVersionService,
+		logger:              logger,
+		tracer:              tracer,
+	}
+}
+
+/*
+* @api_path: /api/pdf/version/related
+* @method: GET
+* @content-type: application/json
+* @summary: 查找用户文库中疑似同一论文的其他版本
+ */
+func (api *PaperVersionAPI) GetRelatedVersions(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "PaperVersionAPI.GetRelatedVersions")
+	defer span.Finish()
+
+	var req pb.GetRelatedPaperVersionsRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	versions, err := api.paperVersionService.GetRelatedVersions(ctx, userId, req.PdfId)
+	if err != nil {
+		api.logger.Error("msg", "查找论文相关版本失败", "pdfId", req.PdfId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.GetRelatedPaperVersionsResponse{Versions: versions})
+}
+
+/*
+* @api_path: /api/pdf/version/attach
+* @method: POST
+* @content-type: application/json
+* @summary: 将新的PDF登记为已有论文的一个版本
+ */
+func (api *PaperVersionAPI) AttachVersion(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "PaperVersionAPI.AttachVersion")
+	defer span.Finish()
+
+	var req pb.AttachPaperVersionRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	version, err := api.paperVersionService.AttachVersion(ctx, userId, req.BasePdfId, req.PdfId, req.Label)
+	if err != nil {
+		api.logger.Error("msg", "登记论文版本失败", "basePdfId", req.BasePdfId, "pdfId", req.PdfId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.AttachPaperVersionResponse{
+		PaperId:   version.PaperId,
+		VersionNo: uint32(version.VersionNo),
+		Name:      paperService.PaperVersionName(version),
+	})
+}
+
+/*
+* @api_path: /api/pdf/version/diff
+* @method: POST
+* @content-type: application/json
+* @summary: 按章节对比两个版本的解析全文
+ */
+func (api *PaperVersionAPI) DiffVersions(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "PaperVersionAPI.DiffVersions")
+	defer span.Finish()
+
+	var req pb.DiffPaperVersionsRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	resp, err := api.paperVersionService.DiffVersions(ctx, userId, req.FromPdfId, req.ToPdfId)
+	if err != nil {
+		api.logger.Error("msg", "对比论文版本失败", "fromPdfId", req.FromPdfId, "toPdfId", req.ToPdfId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", resp)
+}
+
+/*
+* @api_path: /api/pdf/version/migrateMarks
+* @method: POST
+* @content-type: application/json
+* @summary: 将旧版本上的高亮标注按文本重新定位后迁移到新版本
+ */
+func (api *PaperVersionAPI) MigrateMarks(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "PaperVersionAPI.MigrateMarks")
+	defer span.Finish()
+
+	var req pb.MigratePaperVersionMarksRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	resp, err := api.paperVersionService.MigrateMarks(ctx, userId, req.FromPdfId, req.ToPdfId)
+	if err != nil {
+		api.logger.Error("msg", "迁移论文标注失败", "fromPdfId", req.FromPdfId, "toPdfId", req.ToPdfId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", resp)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/pdf"
+	"github.com/yb2020/odoc/services/pdf/service"
+)
+
+// PdfThumbAPI PDF缩略图API处理器
+type PdfThumbAPI struct {
+	pdfThumbRenderService *service.PdfThumbRenderService
+	logger                logging.Logger
+	tracer                opentracing.Tracer
+}
+
+// NewPdfThumbAPI 创建PDF缩略图API处理器
+func NewPdfThumbAPI(pdfThumbRenderService *service.PdfThumbRenderService, logger logging.Logger, tracer opentracing.Tracer) *PdfThumbAPI {
+	return &PdfThumbAPI{
+		pdfThumbRenderService: pdfThumbRenderService,
+		logger:                logger,
+		tracer:                tracer,
+	}
+}
+
+/*
+* @api_path: /api/pdf/thumb/list
+* @method: GET
+* @content-type: application/json
+* @summary: 获取PDF已生成的页面缩略图与图表截图
+ */
+func (api *PdfThumbAPI) ListPdfThumbs(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "PdfThumbAPI.ListPdfThumbs")
+	defer span.Finish()
+
+	var req pb.ListPdfThumbsRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	thumbs, err := api.pdfThumbRenderService.ListPdfThumbs(ctx, req.PdfId, userId)
+	if err != nil {
+		api.logger.Error("msg", "获取PDF缩略图列表失败", "pdfId", req.PdfId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	resp := &pb.ListPdfThumbsResponse{}
+	for _, thumb := range thumbs {
+		resp.Thumbs = append(resp.Thumbs, &pb.PdfThumbInfo{
+			Id:       thumb.Id,
+			Kind:     thumb.Kind,
+			PageNum:  uint32(thumb.PageNum),
+			FigureId: thumb.FigureId,
+			RefIdx:   thumb.RefIdx,
+			Width:    uint32(thumb.Width),
+			Height:   uint32(thumb.Height),
+		})
+	}
+	response.Success(c, "success", resp)
+}
+
+/*
+* @api_path: /api/pdf/thumb/page
+* @method: GET
+* @content-type: image/jpeg
+* @summary: 获取页面缩略图图片，pageNum为0时返回封面
+ */
+func (api *PdfThumbAPI) GetPageThumb(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "PdfThumbAPI.GetPageThumb")
+	defer span.Finish()
+
+	var req pb.GetPdfPageThumbRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	thumbImage, err := api.pdfThumbRenderService.GetPageThumbImage(ctx, req.PdfId, userId, int(req.PageNum))
+	if err != nil {
+		api.logger.Error("msg", "获取页面缩略图失败", "pdfId", req.PdfId, "pageNum", req.PageNum, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	api.writeImage(c, thumbImage)
+}
+
+/*
+* @api_path: /api/pdf/thumb/figure
+* @method: GET
+* @content-type: image/png
+* @summary: 获取图表截图图片，没有MinerU图片时从页面中裁剪
+ */
+func (api *PdfThumbAPI) GetFigureThumb(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "PdfThumbAPI.GetFigureThumb")
+	defer span.Finish()
+
+	var req pb.GetPdfFigureThumbRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	thumbImage, err := api.pdfThumbRenderService.GetFigureImage(ctx, req.PdfId, userId, req.FigureId)
+	if err != nil {
+		api.logger.Error("msg", "获取图表截图失败", "pdfId", req.PdfId, "figureId", req.FigureId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	api.writeImage(c, thumbImage)
+}
+
+// writeImage 输出图片并设置缓存头，对象名称包含PDF的SHA256与解析版本，内容不变时ETag不变
+func (api *PdfThumbAPI) writeImage(c *gin.Context, thumbImage *service.PdfThumbImage) {
+	etag := fmt.Sprintf("\"%x\"", sha1.Sum([]byte(thumbImage.ObjectKey)))
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", api.pdfThumbRenderService.CacheMaxAge()))
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, thumbImage.ContentType, thumbImage.Data)
+}
@@ -0,0 +1,36 @@
+package dao
+
+import (
+	"context"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/pdf/model"
+	"gorm.io/gorm"
+)
+
+// DocTextMarkDAO GORM实现的文档文本标注DAO
+type DocTextMarkDAO struct {
+	*baseDao.GormBaseDAO[model.DocTextMark]
+	logger logging.Logger
+}
+
+// NewDocTextMarkDAO 创建一个新的文档文本标注DAO
+func NewDocTextMarkDAO(db *gorm.DB, logger logging.Logger) *DocTextMarkDAO {
+	return &DocTextMarkDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.DocTextMark](db, logger),
+		logger:      logger,
+	}
+}
+
+// ListByPdfIdAndCreatorId 获取用户在文档中的全部文本标注，按起始位置排序
+func (d *DocTextMarkDAO) ListByPdfIdAndCreatorId(ctx context.Context, pdfId string, creatorId string) ([]model.DocTextMark, error) {
+	var marks []model.DocTextMark
+	result := d.GetDB(ctx).Where("pdf_id = ? AND creator_id = ? AND is_deleted = false", pdfId, creatorId).
+		Order("start_offset ASC").Find(&marks)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取文档文本标注列表失败", "pdf_id", pdfId, "creator_id", creatorId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return marks, nil
+}
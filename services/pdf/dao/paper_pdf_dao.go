@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"errors"
+	"time"
 
 	baseDao "github.com/yb2020/odoc/pkg/dao"
 	"github.com/yb2020/odoc/pkg/logging"
@@ -182,3 +183,42 @@ func (d *PaperPDFDAO) GetUserPaperPdfList(ctx context.Context, userId string) ([
 	}
 	return pdfs, nil
 }
+
+// GetAllByCreatorId 获取用户上传的全部PDF记录，包括已逻辑删除的
+func (d *PaperPDFDAO) GetAllByCreatorId(ctx context.Context, userId string) ([]model.PaperPdf, error) {
+	var pdfs []model.PaperPdf
+	result := d.GetDB(ctx).Where("creator_id = ?", userId).Find(&pdfs)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取用户上传的全部PDF记录失败", "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return pdfs, nil
+}
+
+// CountByFileSHA256ExcludeId 统计同一文件的其他PDF记录数，包括已逻辑删除的
+func (d *PaperPDFDAO) CountByFileSHA256ExcludeId(ctx context.Context, fileSHA256 string, id string) (int64, error) {
+	var count int64
+	result := d.GetDB(ctx).Model(&model.PaperPdf{}).Where("file_sha256 = ? AND id <> ?", fileSHA256, id).Count(&count)
+	if result.Error != nil {
+		d.logger.Error("msg", "统计同一文件的PDF记录数失败", "file_sha256", fileSHA256, "error", result.Error.Error())
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// ListPdfsWithoutCoverThumb 获取指定时间之后上传、尚未生成封面缩略图的PDF，按上传时间倒序；非PDF文档不生成缩略图
+func (d *PaperPDFDAO) ListPdfsWithoutCoverThumb(ctx context.Context, since time.Time, limit int) ([]model.PaperPdf, error) {
+	var pdfs []model.PaperPdf
+	result := d.GetDB(ctx).
+		Where("is_deleted = false AND created_at >= ? AND oss_object_key <> ''", since).
+		Where("(file_format IS NULL OR file_format IN ?)", []string{"", "pdf"}).
+		Where("NOT EXISTS (SELECT 1 FROM t_pdf_thumb t WHERE t.pdf_id = t_paper_pdf.id AND t.kind = ? AND t.is_deleted = false)", model.PdfThumbKindCover).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&pdfs)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取待生成缩略图的PDF失败", "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return pdfs, nil
+}
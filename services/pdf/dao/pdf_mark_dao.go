@@ -66,6 +66,30 @@ func (d *PdfMarkDAO) GetCountPdfMarksByNoteIdWithoutIsHighlight(ctx context.Cont
 	return count, nil
 }
 
+// GetCountPdfMarksByNoteIdsWithoutIsHighlight 根据笔记Ids列表获取每个笔记的PDF标记总数，没有标记的笔记不在结果中
+func (d *PdfMarkDAO) GetCountPdfMarksByNoteIdsWithoutIsHighlight(ctx context.Context, noteIds []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(noteIds))
+	if len(noteIds) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		NoteId string
+		Total  int64
+	}
+	result := d.GetDB(ctx).Model(&model.PdfMark{}).
+		Select("note_id, COUNT(*) AS total").
+		Where("note_id IN ? and is_highlight = false and is_deleted = false and type != 4", noteIds).
+		Group("note_id").Scan(&rows)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取PDF标记总数失败", "note_ids", noteIds, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	for _, row := range rows {
+		counts[row.NoteId] = row.Total
+	}
+	return counts, nil
+}
+
 // GetPdfMarksByNoteId 根据笔记ID获取PDF标记列表
 func (d *PdfMarkDAO) GetPdfMarksByNoteId(ctx context.Context, noteId string) ([]model.PdfMark, error) {
 	var marks []model.PdfMark
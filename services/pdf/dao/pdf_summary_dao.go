@@ -37,3 +37,17 @@ func (d *PdfSummaryDAO) FindExistBySourceFromAndFileSHA256AndVersionAndLang(ctx
 	}
 	return &entity, nil
 }
+
+// FindExistBySourceFromAndFileSHA256AndVersionAndLangAndTemplate 根据source_from,file_sha256,version,lang,template查找数据
+func (d *PdfSummaryDAO) FindExistBySourceFromAndFileSHA256AndVersionAndLangAndTemplate(ctx context.Context, sourceFrom, fileSHA256, version, lang, template string) (*model.PdfSummary, error) {
+	var entity model.PdfSummary
+	result := d.GetDB(ctx).Where("source_from = ? and file_sha256 = ? and version = ? and lang = ? and template = ? and is_deleted = false", sourceFrom, fileSHA256, version, lang, template).First(&entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "根据source_from,file_sha256,version,lang,template查找数据失败", "source_from", sourceFrom, "file_sha256", fileSHA256, "version", version, "lang", lang, "template", template, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &entity, nil
+}
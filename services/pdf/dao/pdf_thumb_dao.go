@@ -103,3 +103,74 @@ func (d *PdfThumbDAO) CountPdfThumbs(ctx context.Context) (string, error) {
 	}
 	return fmt.Sprintf("%d", count), nil
 }
+
+// ListByPdfId 获取PDF的全部缩略图，按类型和页码排序
+func (d *PdfThumbDAO) ListByPdfId(ctx context.Context, pdfId string) ([]model.PdfThumb, error) {
+	var thumbs []model.PdfThumb
+	result := d.GetDB(ctx).Where("pdf_id = ? AND is_deleted = false", pdfId).Order("kind ASC, page_num ASC").Find(&thumbs)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取PDF缩略图列表失败", "pdf_id", pdfId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return thumbs, nil
+}
+
+// GetByPdfIdAndKindAndPage 根据PDF ID、类型和页码获取缩略图
+func (d *PdfThumbDAO) GetByPdfIdAndKindAndPage(ctx context.Context, pdfId string, kind string, pageNum int) (*model.PdfThumb, error) {
+	var thumb model.PdfThumb
+	result := d.GetDB(ctx).Where("pdf_id = ? AND kind = ? AND page_num = ? AND is_deleted = false", pdfId, kind, pageNum).First(&thumb)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取PDF缩略图失败", "pdf_id", pdfId, "kind", kind, "page_num", pageNum, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &thumb, nil
+}
+
+// GetByPdfIdAndFigureId 根据PDF ID和解析结果中的图表ID获取图表截图
+func (d *PdfThumbDAO) GetByPdfIdAndFigureId(ctx context.Context, pdfId string, figureId string) (*model.PdfThumb, error) {
+	var thumb model.PdfThumb
+	result := d.GetDB(ctx).Where("pdf_id = ? AND figure_id = ? AND kind IN ? AND is_deleted = false", pdfId, figureId,
+		[]string{model.PdfThumbKindFigure, model.PdfThumbKindTable}).First(&thumb)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取PDF图表截图失败", "pdf_id", pdfId, "figure_id", figureId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &thumb, nil
+}
+
+// DeleteByPdfIdAndKinds 物理删除PDF指定类型的缩略图记录，用于重新生成
+func (d *PdfThumbDAO) DeleteByPdfIdAndKinds(ctx context.Context, pdfId string, kinds []string) error {
+	result := d.GetDB(ctx).Where("pdf_id = ? AND kind IN ?", pdfId, kinds).Delete(&model.PdfThumb{})
+	if result.Error != nil {
+		d.logger.Error("msg", "删除PDF缩略图失败", "pdf_id", pdfId, "kinds", kinds, "error", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+// GetAllByPdfId 获取PDF的全部缩略图，包括已逻辑删除的
+func (d *PdfThumbDAO) GetAllByPdfId(ctx context.Context, pdfId string) ([]model.PdfThumb, error) {
+	var thumbs []model.PdfThumb
+	result := d.GetDB(ctx).Where("pdf_id = ?", pdfId).Find(&thumbs)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取PDF全部缩略图失败", "pdf_id", pdfId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return thumbs, nil
+}
+
+// RemoveByPdfId 物理删除PDF的全部缩略图记录
+func (d *PdfThumbDAO) RemoveByPdfId(ctx context.Context, pdfId string) error {
+	result := d.GetDB(ctx).Where("pdf_id = ?", pdfId).Delete(&model.PdfThumb{})
+	if result.Error != nil {
+		d.logger.Error("msg", "删除PDF缩略图失败", "pdf_id", pdfId, "error", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/logging"
+	commonPb "github.com/yb2020/odoc/proto/gen/go/common"
+	notePb "github.com/yb2020/odoc/proto/gen/go/note"
+	pb "github.com/yb2020/odoc/proto/gen/go/pdf"
+	noteInterfaces "github.com/yb2020/odoc/services/note/interfaces"
+	noteProto "github.com/yb2020/odoc/services/note/proto"
+	"github.com/yb2020/odoc/services/pdf/interfaces"
+	"github.com/yb2020/odoc/services/pdf/service"
+)
+
+// PdfGRPCServer PDF标注和解析结果的gRPC服务器，与 PdfMarkAPI、PdfParseAPI 调用相同的服务层方法
+type PdfGRPCServer struct {
+	pb.UnimplementedPdfServiceServer
+	pdfMarkService   interfaces.IPdfMarkService
+	pdfParseService  *service.PdfParseService
+	paperNoteService noteInterfaces.IPaperNoteService
+	logger           logging.Logger
+	tracer           opentracing.Tracer
+}
+
+// NewPdfGRPCServer 创建PDF gRPC服务器
+func NewPdfGRPCServer(logger logging.Logger, tracer opentracing.Tracer, pdfMarkService interfaces.IPdfMarkService,
+	pdfParseService *service.PdfParseService, paperNoteService noteInterfaces.IPaperNoteService) *PdfGRPCServer {
+	return &PdfGRPCServer{
+		pdfMarkService:   pdfMarkService,
+		pdfParseService:  pdfParseService,
+		paperNoteService: paperNoteService,
+		logger:           logger,
+		tracer:           tracer,
+	}
+}
+
+// RegisterServer 注册gRPC服务
+func (s *PdfGRPCServer) RegisterServer(server *grpc.Server) {
+	pb.RegisterPdfServiceServer(server, s)
+}
+
+// checkNoteOwner 检查笔记属于当前用户
+func (s *PdfGRPCServer) checkNoteOwner(ctx context.Context, noteId string) error {
+	userId, _ := userContext.GetUserID(ctx)
+	paperNote, err := s.paperNoteService.GetPaperNoteById(ctx, noteId)
+	if err != nil {
+		return err
+	}
+	if userId == "" || paperNote == nil || paperNote.CreatorId != userId {
+		return status.Error(codes.PermissionDenied, "笔记不存在或者不属于您本人")
+	}
+	return nil
+}
+
+// checkMarkOwner 检查标注属于当前用户
+func (s *PdfGRPCServer) checkMarkOwner(ctx context.Context, markId string) error {
+	userId, _ := userContext.GetUserID(ctx)
+	mark, err := s.pdfMarkService.GetPdfMarkById(ctx, markId)
+	if err != nil {
+		return err
+	}
+	if userId == "" || mark == nil || mark.CreatorId != userId {
+		return status.Error(codes.PermissionDenied, "标注不存在或者不属于您本人")
+	}
+	return nil
+}
+
+// annotationMarkId 按服务层相同的转换取出Web标注对应的标注ID
+func annotationMarkId(annotation *notePb.WebNoteAnnotationModel) (string, error) {
+	annotationRawModel, err := (&noteProto.WebNoteProtoTransformer{}).AnnotationRawModel(annotation)
+	if err != nil {
+		return "", err
+	}
+	mark, err := (&noteProto.AnnotationModelTool{}).ToPdfMark(annotationRawModel)
+	if err != nil {
+		return "", err
+	}
+	return mark.Id, nil
+}
+
+// GetMarksByNote 获取笔记标注列表，只能获取自己的笔记
+func (s *PdfGRPCServer) GetMarksByNote(ctx context.Context, req *pb.GetNoteAnnotationListByNoteIdRequest) (*pb.GetNoteAnnotationListByNoteIdResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfGRPCServer.GetMarksByNote")
+	defer span.Finish()
+
+	if err := s.checkNoteOwner(ctx, req.NoteId); err != nil {
+		return nil, err
+	}
+	annotations, err := s.pdfMarkService.GetWebNoteAnnotationModelsByNoteId(ctx, req.NoteId)
+	if err != nil {
+		s.logger.Error("msg", "gRPC获取笔记标注列表失败", "noteId", req.NoteId, "error", err.Error())
+		return nil, err
+	}
+	return &pb.GetNoteAnnotationListByNoteIdResponse{Annotations: annotations}, nil
+}
+
+// SaveMark 保存标注
+func (s *PdfGRPCServer) SaveMark(ctx context.Context, req *notePb.WebNoteAnnotationModel) (*pb.SavePdfMarkResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfGRPCServer.SaveMark")
+	defer span.Finish()
+
+	id, err := s.pdfMarkService.SavePdfMarkByAnnotation(ctx, req)
+	if err != nil {
+		s.logger.Error("msg", "gRPC保存PDF标记失败", "error", err.Error())
+		return nil, err
+	}
+	return &pb.SavePdfMarkResponse{Uuid: id}, nil
+}
+
+// UpdateMark 更新标注，只能修改自己的标注
+func (s *PdfGRPCServer) UpdateMark(ctx context.Context, req *notePb.WebNoteAnnotationModel) (*pb.UpdatePdfMarkResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfGRPCServer.UpdateMark")
+	defer span.Finish()
+
+	markId, err := annotationMarkId(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkMarkOwner(ctx, markId); err != nil {
+		return nil, err
+	}
+	id, err := s.pdfMarkService.UpdatePdfMarkByAnnotation(ctx, req)
+	if err != nil {
+		s.logger.Error("msg", "gRPC更新PDF标记失败", "error", err.Error())
+		return nil, err
+	}
+	return &pb.UpdatePdfMarkResponse{Uuid: id}, nil
+}
+
+// DeleteMark 删除标注，只能删除自己的标注
+func (s *PdfGRPCServer) DeleteMark(ctx context.Context, req *commonPb.AnnotationPointer) (*emptypb.Empty, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfGRPCServer.DeleteMark")
+	defer span.Finish()
+
+	if req.Id == "" || req.Id == "0" {
+		return nil, status.Error(codes.InvalidArgument, "参数id不能为空")
+	}
+	if err := s.checkMarkOwner(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	if _, err := s.pdfMarkService.DeleteByAnnotationPointer(ctx, req); err != nil {
+		s.logger.Error("msg", "gRPC删除PDF标记失败", "id", req.Id, "error", err.Error())
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// GetCatalogue 获取目录解析结果
+func (s *PdfGRPCServer) GetCatalogue(ctx context.Context, req *pb.GetCatalogueRequest) (*pb.GetCatalogueResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfGRPCServer.GetCatalogue")
+	defer span.Finish()
+
+	resp, err := s.pdfParseService.GetCatalogue(ctx, req)
+	if err != nil {
+		s.logger.Error("msg", "gRPC获取目录失败", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetReference 获取参考文献解析结果
+func (s *PdfGRPCServer) GetReference(ctx context.Context, req *pb.GetReferenceRequest) (*pb.GetReferenceResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfGRPCServer.GetReference")
+	defer span.Finish()
+
+	resp, err := s.pdfParseService.GetReference(ctx, req)
+	if err != nil {
+		s.logger.Error("msg", "gRPC获取参考文献失败", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetReferenceMarkers 获取参考文献标记
+func (s *PdfGRPCServer) GetReferenceMarkers(ctx context.Context, req *pb.GetReferenceMarkersRequest) (*pb.GetReferenceMarkersResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfGRPCServer.GetReferenceMarkers")
+	defer span.Finish()
+
+	resp, err := s.pdfParseService.GetReferenceMarkers(ctx, req)
+	if err != nil {
+		s.logger.Error("msg", "gRPC获取参考文献标记失败", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetFiguresAndTables 获取图表解析结果
+func (s *PdfGRPCServer) GetFiguresAndTables(ctx context.Context, req *pb.GetFiguresAndTablesListRequest) (*pb.GetFiguresAndTablesListResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfGRPCServer.GetFiguresAndTables")
+	defer span.Finish()
+
+	resp, err := s.pdfParseService.GetFiguresAndTables(ctx, req)
+	if err != nil {
+		s.logger.Error("msg", "gRPC获取图表失败", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,110 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	commonPb "github.com/yb2020/odoc/proto/gen/go/common"
+	notePb "github.com/yb2020/odoc/proto/gen/go/note"
+	pb "github.com/yb2020/odoc/proto/gen/go/pdf"
+	noteInterfaces "github.com/yb2020/odoc/services/note/interfaces"
+	noteModel "github.com/yb2020/odoc/services/note/model"
+	"github.com/yb2020/odoc/services/pdf/interfaces"
+	"github.com/yb2020/odoc/services/pdf/model"
+)
+
+// fakePaperNoteService 笔记 n1 属于 u1
+type fakePaperNoteService struct {
+	noteInterfaces.IPaperNoteService
+}
+
+func (fakePaperNoteService) GetPaperNoteById(ctx context.Context, id string) (*noteModel.PaperNote, error) {
+	if id != "n1" {
+		return nil, nil
+	}
+	note := &noteModel.PaperNote{}
+	note.Id = id
+	note.CreatorId = "u1"
+	return note, nil
+}
+
+// fakePdfMarkService 标注 m1 属于 u1，记录被调用的操作
+type fakePdfMarkService struct {
+	interfaces.IPdfMarkService
+	called []string
+}
+
+func (s *fakePdfMarkService) GetPdfMarkById(ctx context.Context, id string) (*model.PdfMark, error) {
+	if id != "m1" {
+		return nil, nil
+	}
+	mark := &model.PdfMark{NoteId: "n1"}
+	mark.Id = id
+	mark.CreatorId = "u1"
+	return mark, nil
+}
+
+func (s *fakePdfMarkService) GetWebNoteAnnotationModelsByNoteId(ctx context.Context, noteId string) ([]*notePb.WebNoteAnnotationModel, error) {
+	s.called = append(s.called, "GetWebNoteAnnotationModelsByNoteId")
+	return nil, nil
+}
+
+func (s *fakePdfMarkService) UpdatePdfMarkByAnnotation(ctx context.Context, annotation *notePb.WebNoteAnnotationModel) (string, error) {
+	s.called = append(s.called, "UpdatePdfMarkByAnnotation")
+	return "m1", nil
+}
+
+func (s *fakePdfMarkService) DeleteByAnnotationPointer(ctx context.Context, annotationPointer *commonPb.AnnotationPointer) (bool, error) {
+	s.called = append(s.called, "DeleteByAnnotationPointer")
+	return true, nil
+}
+
+func TestPdfGRPCServerChecksOwner(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(s *PdfGRPCServer, ctx context.Context) error
+	}{
+		{name: "GetMarksByNote", call: func(s *PdfGRPCServer, ctx context.Context) error {
+			_, err := s.GetMarksByNote(ctx, &pb.GetNoteAnnotationListByNoteIdRequest{NoteId: "n1"})
+			return err
+		}},
+		{name: "UpdateMark", call: func(s *PdfGRPCServer, ctx context.Context) error {
+			_, err := s.UpdateMark(ctx, &notePb.WebNoteAnnotationModel{
+				Type:   commonPb.IDEAAnnotateType_IDEAAnnotateTypeComment,
+				Select: &notePb.WebNoteSelectAnnotation{Uuid: "m1", DocumentId: "n1"},
+			})
+			return err
+		}},
+		{name: "DeleteMark", call: func(s *PdfGRPCServer, ctx context.Context) error {
+			_, err := s.DeleteMark(ctx, &commonPb.AnnotationPointer{Id: "m1"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, userId := range []string{"u2", ""} {
+				marks := &fakePdfMarkService{}
+				s := NewPdfGRPCServer(daotest.NewLogger(), opentracing.NoopTracer{}, marks, nil, fakePaperNoteService{})
+				ctx := context.WithValue(context.Background(), userContext.UserIDKey, userId)
+				if err := tt.call(s, ctx); status.Code(err) != codes.PermissionDenied {
+					t.Fatalf("user %q: err = %v, want PermissionDenied", userId, err)
+				}
+				if len(marks.called) != 0 {
+					t.Fatalf("user %q: called %v before the owner check", userId, marks.called)
+				}
+			}
+			marks := &fakePdfMarkService{}
+			s := NewPdfGRPCServer(daotest.NewLogger(), opentracing.NoopTracer{}, marks, nil, fakePaperNoteService{})
+			ctx := context.WithValue(context.Background(), userContext.UserIDKey, "u1")
+			if err := tt.call(s, ctx); err != nil || len(marks.called) != 1 {
+				t.Fatalf("owner: err = %v, called %v", err, marks.called)
+			}
+		})
+	}
+}
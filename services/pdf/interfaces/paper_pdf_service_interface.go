@@ -51,6 +51,9 @@ type IPaperPdfService interface {
 	// GetCountPdfMarksByNoteId 根据笔记ID获取PDF标记总数
 	GetCountPdfMarksByNoteId(ctx context.Context, noteId string) (int64, error)
 
+	// GetCountPdfMarksByNoteIds 根据笔记Ids列表获取每个笔记的PDF标记总数
+	GetCountPdfMarksByNoteIds(ctx context.Context, noteIds []string) (map[string]int64, error)
+
 	// GetPdfMarksByNoteId 根据笔记ID获取PDF标记
 	GetPdfMarksByNoteId(ctx context.Context, noteId string) ([]model.PdfMark, error)
 
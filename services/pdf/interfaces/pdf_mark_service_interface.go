@@ -29,6 +29,9 @@ type IPdfMarkService interface {
 	// GetCountPdfMarksByNoteId 根据笔记ID获取PDF标记总数
 	GetCountPdfMarksByNoteId(ctx context.Context, noteId string) (int64, error)
 
+	// GetCountPdfMarksByNoteIds 根据笔记Ids列表获取每个笔记的PDF标记总数
+	GetCountPdfMarksByNoteIds(ctx context.Context, noteIds []string) (map[string]int64, error)
+
 	// GetAnnotationRawModelsByNoteId 根据笔记ID获取注释原始模型列表
 	GetAnnotationRawModelsByNoteId(ctx context.Context, noteId string) ([]*notePb.AnnotationRawModel, error)
 
@@ -0,0 +1,57 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/services/pdf/service"
+)
+
+// PdfThumbRenderJob PDF页面缩略图与图表截图渲染任务
+type PdfThumbRenderJob struct {
+	logger                logging.Logger
+	spec                  string                 // 任务的cron表达式，6字段标准cron表达式
+	key                   string                 // 任务的锁key，必须是唯一的unique-job-key
+	expiry                time.Duration          // 任务的锁过期时间
+	lockOpts              *scheduler.LockOptions // 任务的锁选项
+	pdfThumbRenderService *service.PdfThumbRenderService
+}
+
+func NewPdfThumbRenderJob(logger logging.Logger, cfg *config.Config, pdfThumbRenderService *service.PdfThumbRenderService) *PdfThumbRenderJob {
+	spec := cfg.Scheduler.Jobs.PdfThumbRenderJob.Spec
+	key := cfg.Scheduler.Jobs.PdfThumbRenderJob.Key
+	expiry := time.Duration(cfg.Scheduler.Jobs.PdfThumbRenderJob.Expiry) * time.Second
+	lockOpts := &scheduler.LockOptions{
+		Key:    key,
+		Expiry: expiry,
+	}
+	return &PdfThumbRenderJob{logger: logger, spec: spec, key: key, expiry: expiry, lockOpts: lockOpts, pdfThumbRenderService: pdfThumbRenderService}
+}
+
+// Spec 获取任务的cron表达式
+func (j *PdfThumbRenderJob) Spec() string {
+	return j.spec
+}
+
+// LockOpts 获取任务的锁选项
+func (j *PdfThumbRenderJob) LockOpts() *scheduler.LockOptions {
+	return j.lockOpts
+}
+
+// NewUserContext 渲染任务按PDF处理，不涉及用户上下文，直接返回原上下文
+func (j *PdfThumbRenderJob) NewUserContext(ctx context.Context, userId string) context.Context {
+	return ctx
+}
+
+// Run 执行任务，在执行任务前会获取锁，执行任务后会释放锁
+func (j *PdfThumbRenderJob) Run() {
+	rendered, failed, err := j.pdfThumbRenderService.RenderPendingThumbs(context.Background())
+	if err != nil {
+		j.logger.Error("msg", "Pdf thumb render job failed", "error", err)
+		return
+	}
+	j.logger.Info("msg", "Pdf thumb render job success", "rendered", rendered, "failed", failed)
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// DocTextMark 非PDF文档的文本标注实体，以文本锚点代替PDF的四边形坐标定位
+type DocTextMark struct {
+	model.BaseModel        // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	PaperId         string `json:"paperId" gorm:"column:paper_id;size:36;index"`                     // 论文ID
+	PdfId           string `json:"pdfId" gorm:"column:pdf_id;size:36;index"`                         // 文档ID，对应PaperPdf
+	Exact           string `json:"exact" gorm:"column:exact;type:text;comment:选中的文本"`                // 选中的文本
+	Prefix          string `json:"prefix" gorm:"column:prefix;type:varchar(255);comment:选中文本之前的上下文"` // 选中文本之前的上下文
+	Suffix          string `json:"suffix" gorm:"column:suffix;type:varchar(255);comment:选中文本之后的上下文"` // 选中文本之后的上下文
+	StartOffset     int    `json:"startOffset" gorm:"column:start_offset;type:int;comment:起始字符偏移"`   // 在文档Markdown中的起始字符偏移
+	EndOffset       int    `json:"endOffset" gorm:"column:end_offset;type:int;comment:结束字符偏移"`       // 在文档Markdown中的结束字符偏移
+	Color           string `json:"color" gorm:"column:color;type:varchar(32);comment:高亮颜色"`          // 高亮颜色
+	Idea            string `json:"idea" gorm:"column:idea;type:text;comment:批注内容"`                   // 批注内容
+}
+
+// TableName 返回表名
+func (DocTextMark) TableName() string {
+	return "t_doc_text_mark"
+}
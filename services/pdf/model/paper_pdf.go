@@ -16,6 +16,7 @@ type PaperPdf struct {
 	Language        string `json:"language" gorm:"column:language;type:varchar(10);comment:语言"`                    // 语言
 	OssBucketName   string `json:"ossBucketName" gorm:"column:oss_bucket_name;type:varchar(100);comment:OSS存储桶名称"` // OSS存储桶名称
 	OssObjectKey    string `json:"ossObjectKey" gorm:"column:oss_object_key;type:varchar(255);comment:OSS对象名称"`    // OSS对象名称
+	FileFormat      string `json:"fileFormat" gorm:"column:file_format;type:varchar(16);default:pdf;comment:文件格式"` // 文件格式 pdf/epub/docx/html/markdown，为空视为pdf
 }
 
 // TableName 返回表名
@@ -9,13 +9,15 @@ import (
 // PdfSummary PDF 摘要实体
 type PdfSummary struct {
 	model.BaseModel              // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
-	SourceFrom          string   `json:"from" gorm:"column:source_from;varchar(20);not null;uniqueIndex:idx_from_sha256_version_lang"` // 来源, copilot, user
-	ConversationId      string   `json:"conversationId" gorm:"column:conversation_id;varchar(100);"`                                   // 对话ID
-	FileSHA256          string   `json:"file_sha256" gorm:"column:file_sha256;varchar(64);uniqueIndex:idx_from_sha256_version_lang"`   // PDF的SHA256
-	Lang                string   `json:"lang" gorm:"column:lang;varchar(30);uniqueIndex:idx_from_sha256_version_lang"`                 // 语言
-	Summary             string   `json:"summary" gorm:"column:summary;varchar(4000);"`                                                 // 总结
-	Version             string   `json:"version" gorm:"column:version;varchar(20);uniqueIndex:idx_from_sha256_version_lang"`           // 版本
-	RelatedQuestion     string   `json:"relatedQuestion" gorm:"column:related_question;varchar(2000);"`                                // 关联问题
+	SourceFrom          string   `json:"from" gorm:"column:source_from;varchar(20);not null;uniqueIndex:idx_from_sha256_version_lang"`    // 来源, copilot, user
+	ConversationId      string   `json:"conversationId" gorm:"column:conversation_id;varchar(100);"`                                      // 对话ID
+	FileSHA256          string   `json:"file_sha256" gorm:"column:file_sha256;varchar(64);uniqueIndex:idx_from_sha256_version_lang"`      // PDF的SHA256
+	Lang                string   `json:"lang" gorm:"column:lang;varchar(30);uniqueIndex:idx_from_sha256_version_lang"`                    // 语言
+	Summary             string   `json:"summary" gorm:"column:summary;type:text;"`                                                        // 总结
+	Version             string   `json:"version" gorm:"column:version;varchar(20);uniqueIndex:idx_from_sha256_version_lang"`              // 版本
+	Template            string   `json:"template" gorm:"column:template;varchar(50);default:'';uniqueIndex:idx_from_sha256_version_lang"` // 结构化总结模板key，copilot总结为空
+	Sections            string   `json:"sections" gorm:"column:sections;type:text;"`                                                      // 结构化总结小节(JSON)
+	RelatedQuestion     string   `json:"relatedQuestion" gorm:"column:related_question;varchar(2000);"`                                   // 关联问题
 	RelatedQuestionList []string `json:"relatedQuestionList" gorm:"-"`
 }
 
@@ -46,3 +48,30 @@ func (p *PdfSummary) GetRelatedQuestions() ([]string, error) {
 	err := json.Unmarshal([]byte(p.RelatedQuestion), &questions)
 	return questions, err
 }
+
+// PdfSummarySection 结构化总结的一个小节
+type PdfSummarySection struct {
+	Key     string `json:"key"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// SetSections 设置结构化总结小节
+func (p *PdfSummary) SetSections(sections []PdfSummarySection) error {
+	data, err := json.Marshal(sections)
+	if err != nil {
+		return err
+	}
+	p.Sections = string(data)
+	return nil
+}
+
+// GetSections 获取结构化总结小节
+func (p *PdfSummary) GetSections() ([]PdfSummarySection, error) {
+	var sections []PdfSummarySection
+	if p.Sections == "" {
+		return sections, nil
+	}
+	err := json.Unmarshal([]byte(p.Sections), &sections)
+	return sections, err
+}
@@ -4,13 +4,38 @@ import (
 	"github.com/yb2020/odoc/pkg/model"
 )
 
+// 缩略图类型
+const (
+	PdfThumbKindCover  = "cover"  // 首页封面
+	PdfThumbKindPage   = "page"   // 页面缩略图
+	PdfThumbKindFigure = "figure" // 图片截图
+	PdfThumbKindTable  = "table"  // 表格截图
+)
+
+// 缩略图生成状态
+const (
+	PdfThumbStatusDone   = "done"   // 生成成功
+	PdfThumbStatusFailed = "failed" // 生成失败，不再自动重试
+)
+
 // PdfThumb PDF缩略图实体
 type PdfThumb struct {
-	model.BaseModel           // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
-	PaperId           int64   `json:"paperId" gorm:"column:paper_id;index"`  // 论文ID
-	SourceUrl         string  `json:"sourceUrl" gorm:"column:source_url"`     // 源URL
-	ThumbUrl          string  `json:"thumbUrl" gorm:"column:thumb_url"`       // 缩略图URL
-	PdfId             int64   `json:"pdfId" gorm:"column:pdf_id;index"`       // PDF ID
+	model.BaseModel        // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	PaperId         string `json:"paperId" gorm:"column:paper_id;size:36;index"`                                          // 论文ID
+	SourceUrl       string `json:"sourceUrl" gorm:"column:source_url"`                                                    // 源URL
+	ThumbUrl        string `json:"thumbUrl" gorm:"column:thumb_url"`                                                      // 缩略图URL
+	PdfId           string `json:"pdfId" gorm:"column:pdf_id;size:36;index:idx_pdf_thumb_pdf_kind_page"`                  // PDF ID
+	Kind            string `json:"kind" gorm:"column:kind;type:varchar(20);index:idx_pdf_thumb_pdf_kind_page;comment:类型"` // 类型 cover/page/figure/table
+	PageNum         int    `json:"pageNum" gorm:"column:page_num;type:int;index:idx_pdf_thumb_pdf_kind_page;comment:页码"`  // 页码，从1开始
+	FigureId        string `json:"figureId" gorm:"column:figure_id;type:varchar(64);comment:解析结果中的图表ID"`                  // 解析结果中的图表ID
+	RefIdx          string `json:"refIdx" gorm:"column:ref_idx;type:varchar(64);comment:图表编号"`                            // 图表编号，如 Figure 1
+	BucketName      string `json:"bucketName" gorm:"column:bucket_name;type:varchar(100);comment:OSS存储桶名称"`               // OSS存储桶名称
+	ObjectKey       string `json:"objectKey" gorm:"column:object_key;type:varchar(255);comment:OSS对象名称"`                  // OSS对象名称
+	ContentType     string `json:"contentType" gorm:"column:content_type;type:varchar(50);comment:图片类型"`                  // 图片类型
+	Width           int    `json:"width" gorm:"column:width;type:int;comment:图片宽度"`                                       // 图片宽度
+	Height          int    `json:"height" gorm:"column:height;type:int;comment:图片高度"`                                     // 图片高度
+	Status          string `json:"status" gorm:"column:status;type:varchar(20);comment:生成状态"`                             // 生成状态 done/failed
+	ErrorMessage    string `json:"errorMessage" gorm:"column:error_message;type:varchar(500);comment:失败原因"`               // 失败原因
 }
 
 // TableName 返回表名
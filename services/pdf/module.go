@@ -1,21 +1,31 @@
 package pdf
 
 import (
+	"context"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/cache"
+	"github.com/yb2020/odoc/pkg/http_client"
 	"github.com/yb2020/odoc/pkg/logging"
 	"github.com/yb2020/odoc/pkg/middleware"
 	"github.com/yb2020/odoc/pkg/registry"
 	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/pkg/takeout"
+	"github.com/yb2020/odoc/pkg/trash"
 	userDocService "github.com/yb2020/odoc/services/doc/service"
+	membershipInterfaces "github.com/yb2020/odoc/services/membership/interfaces"
 	noteInterfaces "github.com/yb2020/odoc/services/note/interfaces"
 	paperNotesService "github.com/yb2020/odoc/services/note/service"
 	ossService "github.com/yb2020/odoc/services/oss/service"
 	paperService "github.com/yb2020/odoc/services/paper/service"
 	"github.com/yb2020/odoc/services/pdf/api"
 	"github.com/yb2020/odoc/services/pdf/dao"
+	pdfgrpc "github.com/yb2020/odoc/services/pdf/grpc"
 	"github.com/yb2020/odoc/services/pdf/interfaces"
+	"github.com/yb2020/odoc/services/pdf/job"
 	"github.com/yb2020/odoc/services/pdf/service"
 	userService "github.com/yb2020/odoc/services/user/service"
 	"google.golang.org/grpc"
@@ -25,6 +35,15 @@ import (
 // 编译时类型检查：确保 PdfModule 实现了 registry.Module 接口
 var _ registry.Module = (*PdfModule)(nil)
 
+// 编译时类型检查：确保 PdfModule 实现了 registry.TakeoutExporterProvider 接口
+var _ registry.TakeoutExporterProvider = (*PdfModule)(nil)
+
+// 编译时类型检查：确保 PdfModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*PdfModule)(nil)
+
+// 编译时类型检查：确保 PdfModule 实现了 registry.TrashHandlerProvider 接口
+var _ registry.TrashHandlerProvider = (*PdfModule)(nil)
+
 // Module 导出模块实例，用于自动发现和注册
 var Module = &PdfModule{}
 
@@ -40,6 +59,9 @@ type PdfModule struct {
 	paperNoteService       noteInterfaces.IPaperNoteService
 	paperNoteAccessService *paperNotesService.PaperNoteAccessService
 	ossService             ossService.OssServiceInterface
+	noteSummaryService     *paperNotesService.NoteSummaryService
+	membershipService      membershipInterfaces.IMembershipService
+	httpClient             http_client.HttpClient
 	// DAO实例
 	paperPdfDAO             *dao.PaperPDFDAO
 	paperPdfSelectRecordDAO *dao.PaperPdfSelectRecordDAO
@@ -52,6 +74,7 @@ type PdfModule struct {
 	pdfReaderSettingDAO     *dao.PdfReaderSettingDAO
 	pdfThumbDAO             *dao.PdfThumbDAO
 	pdfSummaryDAO           *dao.PdfSummaryDAO
+	docTextMarkDAO          *dao.DocTextMarkDAO
 
 	// 服务实例
 	paperPdfService             *service.PaperPdfService
@@ -64,16 +87,30 @@ type PdfModule struct {
 	pdfMarkTagRelationService   *service.PdfMarkTagRelationService
 	pdfReaderSettingService     *service.PdfReaderSettingService
 	pdfThumbService             *service.PdfThumbService
+	pdfThumbRenderService       *service.PdfThumbRenderService
 	paperService                *paperService.PaperService
 	paperAccessService          *paperService.PaperAccessService
 	paperPdfParsedService       *paperService.PaperPdfParsedService
 	pdfParseService             *service.PdfParseService
 	pdfSummaryService           *service.PdfSummaryService
+	paperSummaryGenerateService *service.PaperSummaryGenerateService
+	paperVersionService         *service.PaperVersionService
+	documentImportService       *service.DocumentImportService
+	docTextMarkService          *service.DocTextMarkService
+	takeoutExporter             *service.PdfTakeoutExporter
+	userDataDeleter             *service.PdfUserDataDeleter
+	pdfMarkTrashHandler         *service.PdfMarkTrashHandler
+	docTextMarkTrashHandler     *service.DocTextMarkTrashHandler
 	// API实例
 	paperPdfAPI   *api.PaperPdfAPI
 	pdfParseAPI   *api.PdfParseAPI
 	pdfMarkAPI    *api.PdfMarkAPI
 	pdfMarkTagAPI *api.PdfMarkTagAPI
+	grpcServer    *pdfgrpc.PdfGRPCServer
+	summaryAPI    *api.PaperSummaryAPI
+	versionAPI    *api.PaperVersionAPI
+	thumbAPI      *api.PdfThumbAPI
+	documentAPI   *api.DocumentAPI
 }
 
 // NewPdfModule 创建PDF模块
@@ -87,6 +124,9 @@ func NewPdfModule(db *gorm.DB, cfg *config.Config, logger logging.Logger,
 	ossService ossService.OssServiceInterface,
 	paperAccessService *paperService.PaperAccessService,
 	paperPdfParsedService *paperService.PaperPdfParsedService,
+	noteSummaryService *paperNotesService.NoteSummaryService,
+	membershipService membershipInterfaces.IMembershipService,
+	httpClient http_client.HttpClient,
 ) *PdfModule {
 	return &PdfModule{
 		db:                     db,
@@ -102,6 +142,9 @@ func NewPdfModule(db *gorm.DB, cfg *config.Config, logger logging.Logger,
 		ossService:             ossService,
 		paperAccessService:     paperAccessService,
 		paperPdfParsedService:  paperPdfParsedService,
+		noteSummaryService:     noteSummaryService,
+		membershipService:      membershipService,
+		httpClient:             httpClient,
 	}
 }
 
@@ -118,14 +161,22 @@ func (m *PdfModule) Shutdown() error {
 
 // RegisterGRPC 注册gRPC服务
 func (m *PdfModule) RegisterGRPC(server *grpc.Server) {
-	// TODO: 实现gRPC服务注册
-	m.logger.Debug("msg", "PDF模块没有gRPC服务，跳过注册")
+	if m.grpcServer == nil {
+		m.logger.Warn("msg", "gRPC服务未初始化", "module", m.Name())
+		return
+	}
+	m.grpcServer.RegisterServer(server)
 }
 
 // RegisterJobSchedulers 注册Job定时任务
 func (m *PdfModule) RegisterJobSchedulers(scheduler *scheduler.Scheduler) {
-	// TODO: 实现Job定时任务注册
-	m.logger.Debug("msg", "PDF模块没有Job定时任务，跳过注册")
+	if scheduler == nil {
+		m.logger.Debug("msg", "调度器未启用，PDF模块跳过Job注册")
+		return
+	}
+	m.logger.Debug("msg", "PDF模块注册Job定时任务")
+	thumbRenderJob := job.NewPdfThumbRenderJob(m.logger, m.cfg, m.pdfThumbRenderService)
+	scheduler.RegisterJobs(thumbRenderJob)
 }
 
 // RegisterProviders 注册Provider
@@ -150,6 +201,7 @@ func (m *PdfModule) Initialize() error {
 	m.pdfReaderSettingDAO = dao.NewPdfReaderSettingDAO(m.db, m.logger)
 	m.pdfThumbDAO = dao.NewPdfThumbDAO(m.db, m.logger)
 	m.pdfSummaryDAO = dao.NewPdfSummaryDAO(m.db, m.logger)
+	m.docTextMarkDAO = dao.NewDocTextMarkDAO(m.db, m.logger)
 
 	// 初始化服务
 	m.paperPdfSelectRecordService = service.NewPaperPdfSelectRecordService(m.logger, m.tracer, m.paperPdfSelectRecordDAO)
@@ -175,16 +227,60 @@ func (m *PdfModule) Initialize() error {
 
 	m.pdfSummaryService = service.NewPdfSummaryService(m.logger, m.tracer, m.pdfSummaryDAO)
 
+	// PDF解析结果读取服务（本模块不投递MQ消息，producer为空）
+	cacheClient := cache.NewCache(m.logger, 30*time.Minute, m.Name())
+	m.pdfParseService = service.NewPdfParseService(m.paperPdfService, m.paperPdfParsedService, m.ossService, m.userDocService, nil, cacheClient, m.cfg, m.logger, m.tracer)
+	m.paperSummaryGenerateService = service.NewPaperSummaryGenerateService(m.cfg, m.logger, m.tracer, m.httpClient, m.paperPdfService, m.pdfParseService, m.pdfSummaryService, m.userDocService, m.membershipService)
+	m.paperVersionService = service.NewPaperVersionService(m.logger, m.tracer, m.paperService, m.pdfParseService, m.userDocService, m.paperNoteService, m.pdfMarkService)
+	m.pdfThumbRenderService = service.NewPdfThumbRenderService(m.cfg, m.logger, m.tracer, m.paperPdfDAO, m.pdfThumbDAO, m.paperPdfService, m.pdfParseService, m.ossService)
+	m.documentImportService = service.NewDocumentImportService(m.cfg, m.logger, m.tracer, m.paperPdfService, m.pdfParseService, m.paperPdfParsedService, m.userDocService, m.ossService, m.membershipService)
+	m.docTextMarkService = service.NewDocTextMarkService(m.logger, m.tracer, m.docTextMarkDAO, m.paperPdfService, m.pdfParseService)
+
 	// 初始化API
 	m.paperPdfAPI = api.NewPaperPdfAPI(m.paperPdfService, m.logger, m.tracer, m.pdfReaderSettingService)
 	m.pdfParseAPI = api.NewPdfParseAPI(m.paperPdfService, m.logger, m.tracer, m.paperService, m.userService, m.userDocService, m.paperNoteService, m.paperNoteAccessService, m.pdfReaderSettingService, m.pdfParseService)
 	m.pdfMarkAPI = api.NewPdfMarkAPI(m.pdfMarkService, m.logger, m.tracer)
 
 	m.pdfMarkTagAPI = api.NewPdfMarkTagAPI(m.pdfMarkTagService, m.logger, m.tracer)
+	m.summaryAPI = api.NewPaperSummaryAPI(m.paperSummaryGenerateService, m.noteSummaryService, m.paperNoteService, m.logger, m.tracer)
+	m.versionAPI = api.NewPaperVersionAPI(m.paperVersionService, m.logger, m.tracer)
+	m.thumbAPI = api.NewPdfThumbAPI(m.pdfThumbRenderService, m.logger, m.tracer)
+	m.documentAPI = api.NewDocumentAPI(m.documentImportService, m.docTextMarkService, m.logger, m.tracer)
+
+	// 初始化用户数据导出器
+	m.takeoutExporter = service.NewPdfTakeoutExporter(m.cfg, m.logger, m.tracer, m.paperPdfDAO, m.pdfMarkDAO,
+		m.pdfMarkTagDAO, m.pdfMarkTagRelationDAO, m.userDocService, m.paperNoteService, m.ossService)
+
+	// 初始化注销账号时的数据删除器
+	m.userDataDeleter = service.NewPdfUserDataDeleter(m.cfg, m.logger, m.tracer, m.paperPdfDAO, m.paperPdfSelectRecordDAO,
+		m.pdfAnnotationDAO, m.pdfCommentDAO, m.pdfMarkDAO, m.pdfMarkBackupDAO, m.pdfMarkTagDAO, m.pdfMarkTagRelationDAO,
+		m.pdfReaderSettingDAO, m.pdfThumbDAO, m.docTextMarkDAO, m.userDocService, m.paperPdfParsedService, m.ossService)
+
+	// 初始化回收站处理器
+	m.pdfMarkTrashHandler = service.NewPdfMarkTrashHandler(m.logger, m.tracer, m.pdfMarkDAO, m.pdfMarkTagDAO, m.pdfMarkTagRelationDAO)
+	m.docTextMarkTrashHandler = service.NewDocTextMarkTrashHandler(m.logger, m.tracer, m.docTextMarkDAO)
+
+	// 初始化gRPC服务
+	m.grpcServer = pdfgrpc.NewPdfGRPCServer(m.logger, m.tracer, m.pdfMarkService, m.pdfParseService, m.paperNoteService)
 
 	return nil
 }
 
+// TakeoutExporters 返回PDF模块的用户数据导出器
+func (m *PdfModule) TakeoutExporters() []takeout.Exporter {
+	return []takeout.Exporter{m.takeoutExporter}
+}
+
+// TrashHandlers 返回PDF模块的回收站处理器
+func (m *PdfModule) TrashHandlers() []trash.Handler {
+	return []trash.Handler{m.pdfMarkTrashHandler, m.docTextMarkTrashHandler}
+}
+
+// DeleteUserData 注销账号时删除用户的PDF标注和只有该用户引用的文件
+func (m *PdfModule) DeleteUserData(ctx context.Context, userId string) error {
+	return m.userDataDeleter.DeleteUserData(ctx, userId)
+}
+
 // RegisterRoutes 注册路由
 func (m *PdfModule) RegisterRoutes(r *gin.Engine) {
 	pdfGroup := r.Group("/api/pdf")
@@ -220,6 +316,33 @@ func (m *PdfModule) RegisterRoutes(r *gin.Engine) {
 		pdfGroup.POST("/marktag/relation/mark/save", m.pdfMarkTagAPI.AddTagToAnnotateRequest)
 		pdfGroup.POST("/marktag/relation/mark/delete", m.pdfMarkTagAPI.DeleteTagToAnnotateRequest)
 
+		// 论文结构化总结API路由
+		pdfGroup.GET("/summary/templates", m.summaryAPI.GetTemplates)
+		pdfGroup.POST("/summary/get", m.summaryAPI.GetSummary)
+		pdfGroup.POST("/summary/generate", m.summaryAPI.GenerateSummary)
+		pdfGroup.POST("/summary/applyToNote", m.summaryAPI.ApplyToNote)
+
+		// 论文版本API路由
+		pdfGroup.GET("/version/related", m.versionAPI.GetRelatedVersions)
+		pdfGroup.POST("/version/attach", m.versionAPI.AttachVersion)
+		pdfGroup.POST("/version/diff", m.versionAPI.DiffVersions)
+		pdfGroup.POST("/version/migrateMarks", m.versionAPI.MigrateMarks)
+
+		// 页面缩略图与图表截图API路由
+		pdfGroup.GET("/thumb/list", m.thumbAPI.ListPdfThumbs)
+		pdfGroup.GET("/thumb/page", m.thumbAPI.GetPageThumb)
+		pdfGroup.GET("/thumb/figure", m.thumbAPI.GetFigureThumb)
+
+		// 非PDF文档（EPUB、DOCX、网页、Markdown）API路由
+		pdfGroup.POST("/document/upload", m.documentAPI.UploadDocument)
+		pdfGroup.POST("/document/importUrl", m.documentAPI.ImportDocumentUrl)
+		pdfGroup.GET("/document/content", m.documentAPI.GetDocumentContent)
+		pdfGroup.GET("/document/export", m.documentAPI.ExportDocument)
+		pdfGroup.GET("/textMark/list", m.documentAPI.ListDocTextMarks)
+		pdfGroup.POST("/textMark/save", m.documentAPI.SaveDocTextMark)
+		pdfGroup.POST("/textMark/update", m.documentAPI.UpdateDocTextMark)
+		pdfGroup.POST("/textMark/delete", m.documentAPI.DeleteDocTextMark)
+
 	}
 }
 
@@ -273,6 +396,11 @@ func (m *PdfModule) GetPdfThumbService() *service.PdfThumbService {
 	return m.pdfThumbService
 }
 
+// GetPdfThumbRenderService 获取PDF缩略图渲染服务
+func (m *PdfModule) GetPdfThumbRenderService() *service.PdfThumbRenderService {
+	return m.pdfThumbRenderService
+}
+
 // GetPdfParseService 获取PDF解析服务
 func (m *PdfModule) GetPdfParseService() *service.PdfParseService {
 	return m.pdfParseService
@@ -282,3 +410,28 @@ func (m *PdfModule) GetPdfParseService() *service.PdfParseService {
 func (m *PdfModule) GetPdfSummaryService() *service.PdfSummaryService {
 	return m.pdfSummaryService
 }
+
+// GetPaperSummaryGenerateService 获取论文结构化总结生成服务
+func (m *PdfModule) GetPaperSummaryGenerateService() *service.PaperSummaryGenerateService {
+	return m.paperSummaryGenerateService
+}
+
+// GetPaperVersionService 获取论文版本服务
+func (m *PdfModule) GetPaperVersionService() *service.PaperVersionService {
+	return m.paperVersionService
+}
+
+// GetDocumentImportService 获取文档导入服务
+func (m *PdfModule) GetDocumentImportService() *service.DocumentImportService {
+	return m.documentImportService
+}
+
+// GetDocTextMarkService 获取文档文本标注服务
+func (m *PdfModule) GetDocTextMarkService() *service.DocTextMarkService {
+	return m.docTextMarkService
+}
+
+// GetPdfUserDataDeleter 获取PDF数据删除器，彻底删除文献时用于释放不再被引用的PDF文件
+func (m *PdfModule) GetPdfUserDataDeleter() *service.PdfUserDataDeleter {
+	return m.userDataDeleter
+}
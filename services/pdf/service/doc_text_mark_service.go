@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/docformat"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/idgen"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/pdf/dao"
+	"github.com/yb2020/odoc/services/pdf/model"
+)
+
+// 锚点上下文字段的最大长度，与表字段长度一致
+const docTextMarkContextMaxLen = 255
+
+// DocTextMarkItem 文本标注及其在当前文档中的定位结果
+type DocTextMarkItem struct {
+	Mark     *model.DocTextMark
+	Orphaned bool // 文档内容变化后无法重新定位
+}
+
+// DocTextMarkService 非PDF文档的文本标注服务，标注以文本锚点定位在文档的Markdown上
+type DocTextMarkService struct {
+	logger          logging.Logger
+	tracer          opentracing.Tracer
+	docTextMarkDAO  *dao.DocTextMarkDAO
+	paperPdfService *PaperPdfService
+	pdfParseService *PdfParseService
+}
+
+// NewDocTextMarkService 创建文档文本标注服务
+func NewDocTextMarkService(
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	docTextMarkDAO *dao.DocTextMarkDAO,
+	paperPdfService *PaperPdfService,
+	pdfParseService *PdfParseService,
+) *DocTextMarkService {
+	return &DocTextMarkService{
+		logger:          logger,
+		tracer:          tracer,
+		docTextMarkDAO:  docTextMarkDAO,
+		paperPdfService: paperPdfService,
+		pdfParseService: pdfParseService,
+	}
+}
+
+// SaveMark 保存文本标注，锚点按当前文档内容重新定位并补全上下文
+func (s *DocTextMarkService) SaveMark(ctx context.Context, userId string, pdfId string, anchor docformat.Anchor, color string, idea string) (*model.DocTextMark, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocTextMarkService.SaveMark")
+	defer span.Finish()
+
+	paperPdf, markdown, err := s.getDocumentMarkdown(ctx, userId, pdfId)
+	if err != nil {
+		return nil, err
+	}
+	start, end, ok := docformat.Resolve(markdown, anchor)
+	if !ok {
+		return nil, errors.Biz("pdf.doc_text_mark.errors.anchor_not_found")
+	}
+	resolved, err := docformat.NewAnchor(markdown, start, end)
+	if err != nil {
+		return nil, errors.Biz("pdf.doc_text_mark.errors.anchor_not_found")
+	}
+
+	mark := &model.DocTextMark{
+		PaperId:     paperPdf.PaperId,
+		PdfId:       pdfId,
+		Exact:       resolved.Exact,
+		Prefix:      truncateRunesLeft(resolved.Prefix, docTextMarkContextMaxLen),
+		Suffix:      truncateRunes(resolved.Suffix, docTextMarkContextMaxLen),
+		StartOffset: resolved.Start,
+		EndOffset:   resolved.End,
+		Color:       color,
+		Idea:        idea,
+	}
+	mark.Id = idgen.GenerateUUID()
+	mark.CreatorId = userId
+	if err := s.docTextMarkDAO.Save(ctx, mark); err != nil {
+		return nil, errors.Biz("pdf.doc_text_mark.errors.save_failed")
+	}
+	return mark, nil
+}
+
+// UpdateMark 修改标注的颜色和批注
+func (s *DocTextMarkService) UpdateMark(ctx context.Context, userId string, markId string, color string, idea string) (*model.DocTextMark, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocTextMarkService.UpdateMark")
+	defer span.Finish()
+
+	mark, err := s.getOwnedMark(ctx, userId, markId)
+	if err != nil {
+		return nil, err
+	}
+	mark.Color = color
+	mark.Idea = idea
+	if err := s.docTextMarkDAO.Modify(ctx, mark); err != nil {
+		return nil, errors.Biz("pdf.doc_text_mark.errors.update_failed")
+	}
+	return mark, nil
+}
+
+// DeleteMark 删除标注
+func (s *DocTextMarkService) DeleteMark(ctx context.Context, userId string, markId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocTextMarkService.DeleteMark")
+	defer span.Finish()
+
+	mark, err := s.getOwnedMark(ctx, userId, markId)
+	if err != nil {
+		return err
+	}
+	if err := recordDocTextMarkTrash(ctx, mark); err != nil {
+		s.logger.Error("msg", "记录回收站失败", "markId", markId, "error", err.Error())
+		return errors.Biz("pdf.doc_text_mark.errors.delete_failed")
+	}
+	if err := s.docTextMarkDAO.DeleteById(ctx, markId); err != nil {
+		return errors.Biz("pdf.doc_text_mark.errors.delete_failed")
+	}
+	return nil
+}
+
+// ListMarks 获取用户在文档中的标注，偏移按当前文档内容重新定位，无法定位的标注标记为孤立
+func (s *DocTextMarkService) ListMarks(ctx context.Context, userId string, pdfId string) ([]DocTextMarkItem, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocTextMarkService.ListMarks")
+	defer span.Finish()
+
+	_, markdown, err := s.getDocumentMarkdown(ctx, userId, pdfId)
+	if err != nil {
+		return nil, err
+	}
+	marks, err := s.docTextMarkDAO.ListByPdfIdAndCreatorId(ctx, pdfId, userId)
+	if err != nil {
+		return nil, errors.Biz("pdf.doc_text_mark.errors.list_failed")
+	}
+	items := make([]DocTextMarkItem, 0, len(marks))
+	for i := range marks {
+		mark := &marks[i]
+		start, end, ok := docformat.Resolve(markdown, markAnchor(mark))
+		if ok {
+			mark.StartOffset, mark.EndOffset = start, end
+		}
+		items = append(items, DocTextMarkItem{Mark: mark, Orphaned: !ok})
+	}
+	return items, nil
+}
+
+// ExportMarkdown 导出带高亮和批注的Markdown，返回文件名和内容
+func (s *DocTextMarkService) ExportMarkdown(ctx context.Context, userId string, pdfId string) (string, string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocTextMarkService.ExportMarkdown")
+	defer span.Finish()
+
+	_, markdown, err := s.getDocumentMarkdown(ctx, userId, pdfId)
+	if err != nil {
+		return "", "", err
+	}
+	marks, err := s.docTextMarkDAO.ListByPdfIdAndCreatorId(ctx, pdfId, userId)
+	if err != nil {
+		return "", "", errors.Biz("pdf.doc_text_mark.errors.list_failed")
+	}
+	annotations := make([]docformat.Annotation, 0, len(marks))
+	for i := range marks {
+		annotations = append(annotations, docformat.Annotation{
+			Anchor: markAnchor(&marks[i]),
+			Color:  marks[i].Color,
+			Note:   marks[i].Idea,
+		})
+	}
+	content, unresolved := docformat.ExportMarkdown(markdown, annotations)
+	if unresolved > 0 {
+		s.logger.Info("msg", "导出文档时部分标注无法定位", "pdfId", pdfId, "unresolved", unresolved)
+	}
+
+	fileName := pdfId
+	if metadata, err := s.pdfParseService.GetPdfMetadata(ctx, pdfId); err == nil && metadata != nil && metadata.Title != nil {
+		if title := sanitizeExportFileName(metadata.Title.Text); title != "" {
+			fileName = title
+		}
+	}
+	return fileName + ".md", content, nil
+}
+
+// getDocumentMarkdown 校验权限并获取文档的Markdown
+func (s *DocTextMarkService) getDocumentMarkdown(ctx context.Context, userId string, pdfId string) (*model.PaperPdf, string, error) {
+	paperPdf, err := getPermittedDocument(ctx, s.paperPdfService, pdfId, userId)
+	if err != nil {
+		return nil, "", err
+	}
+	markdown, err := s.pdfParseService.GetPdfMarkDown(ctx, pdfId)
+	if err != nil {
+		return nil, "", err
+	}
+	if markdown == nil {
+		return nil, "", errors.Biz("pdf.document.errors.content_not_found")
+	}
+	return paperPdf, *markdown, nil
+}
+
+// getOwnedMark 获取当前用户创建的标注
+func (s *DocTextMarkService) getOwnedMark(ctx context.Context, userId string, markId string) (*model.DocTextMark, error) {
+	mark, err := s.docTextMarkDAO.FindExistById(ctx, markId)
+	if err != nil {
+		return nil, errors.Biz("pdf.doc_text_mark.errors.get_failed")
+	}
+	if mark == nil {
+		return nil, errors.Biz("pdf.doc_text_mark.errors.not_found")
+	}
+	if mark.CreatorId != userId {
+		return nil, errors.Biz("pdf.doc_text_mark.errors.permission_denied")
+	}
+	return mark, nil
+}
+
+func markAnchor(mark *model.DocTextMark) docformat.Anchor {
+	return docformat.Anchor{
+		Exact:  mark.Exact,
+		Prefix: mark.Prefix,
+		Suffix: mark.Suffix,
+		Start:  mark.StartOffset,
+		End:    mark.EndOffset,
+	}
+}
+
+// truncateRunes 保留前 n 个字符
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// truncateRunesLeft 保留后 n 个字符，前缀上下文靠近选中文本的部分更有用
+func truncateRunesLeft(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[len(runes)-n:])
+}
+
+// sanitizeExportFileName 去掉文件名中的路径分隔符和控制字符
+func sanitizeExportFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = filepath.Base(name)
+	return truncateRunes(name, 100)
+}
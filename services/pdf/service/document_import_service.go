@@ -0,0 +1,439 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/docformat"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/http_client"
+	"github.com/yb2020/odoc/pkg/idgen"
+	"github.com/yb2020/odoc/pkg/logging"
+	docpb "github.com/yb2020/odoc/proto/gen/go/doc"
+	osspb "github.com/yb2020/odoc/proto/gen/go/oss"
+	docModel "github.com/yb2020/odoc/services/doc/model"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	membershipInterfaces "github.com/yb2020/odoc/services/membership/interfaces"
+	ossModel "github.com/yb2020/odoc/services/oss/model"
+	ossService "github.com/yb2020/odoc/services/oss/service"
+	paperModel "github.com/yb2020/odoc/services/paper/model"
+	paperService "github.com/yb2020/odoc/services/paper/service"
+	parseConstant "github.com/yb2020/odoc/services/parse/constant"
+	"github.com/yb2020/odoc/services/pdf/model"
+)
+
+const (
+	// 未配置时上传文档的大小上限 单位：MB
+	defaultDocumentMaxSizeMB = 50
+	// 未配置时抓取网页的超时时间 单位：秒
+	defaultDocumentFetchTimeout = 30
+)
+
+// ImportedDocument 导入后的文档
+type ImportedDocument struct {
+	DocId      string
+	PaperId    string
+	PdfId      string
+	FileFormat string
+	Title      string
+	Existed    bool // 用户已导入过相同文件，直接返回已有文档
+}
+
+// DocumentContent 文档阅读内容
+type DocumentContent struct {
+	PdfId      string
+	FileFormat string
+	Title      string
+	Markdown   string
+}
+
+// DocumentImportService 导入EPUB、DOCX、网页和Markdown文档。
+// 文档在导入时即转换为Markdown，并生成与PDF解析结果相同的元数据和段落，供阅读、检索和翻译使用；
+// 文档记录沿用PaperPdf，以FileFormat区分格式。
+type DocumentImportService struct {
+	config                *config.Config
+	logger                logging.Logger
+	tracer                opentracing.Tracer
+	fetchClient           *http.Client
+	paperPdfService       *PaperPdfService
+	pdfParseService       *PdfParseService
+	paperPdfParsedService *paperService.PaperPdfParsedService
+	userDocService        *docService.UserDocService
+	ossService            ossService.OssServiceInterface
+	membershipService     membershipInterfaces.IMembershipService
+}
+
+// NewDocumentImportService 创建文档导入服务
+func NewDocumentImportService(
+	cfg *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	paperPdfService *PaperPdfService,
+	pdfParseService *PdfParseService,
+	paperPdfParsedService *paperService.PaperPdfParsedService,
+	userDocService *docService.UserDocService,
+	ossService ossService.OssServiceInterface,
+	membershipService membershipInterfaces.IMembershipService,
+) *DocumentImportService {
+	return &DocumentImportService{
+		config:                cfg,
+		logger:                logger,
+		tracer:                tracer,
+		fetchClient:           http_client.NewPublicClient(documentFetchTimeout(cfg)),
+		paperPdfService:       paperPdfService,
+		pdfParseService:       pdfParseService,
+		paperPdfParsedService: paperPdfParsedService,
+		userDocService:        userDocService,
+		ossService:            ossService,
+		membershipService:     membershipService,
+	}
+}
+
+// MaxSize 上传文档的大小上限 单位：byte
+func (s *DocumentImportService) MaxSize() int64 {
+	maxSizeMB := s.config.PDF.Document.MaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultDocumentMaxSizeMB
+	}
+	return int64(maxSizeMB) << 20
+}
+
+// documentFetchTimeout 抓取网页的超时时间
+func documentFetchTimeout(cfg *config.Config) time.Duration {
+	timeout := cfg.PDF.Document.FetchTimeout
+	if timeout <= 0 {
+		timeout = defaultDocumentFetchTimeout
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+// ImportDocument 导入上传的文档，格式根据文件内容和扩展名识别；PDF仍走原有上传流程
+func (s *DocumentImportService) ImportDocument(ctx context.Context, userId string, fileName string, content []byte, folderId string) (*ImportedDocument, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocumentImportService.ImportDocument")
+	defer span.Finish()
+
+	format := docformat.Detect(fileName, content)
+	return s.importDocument(ctx, userId, fileName, format, content, folderId, "")
+}
+
+// ImportURL 抓取网页文章并导入
+func (s *DocumentImportService) ImportURL(ctx context.Context, userId string, rawURL string, folderId string) (*ImportedDocument, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocumentImportService.ImportURL")
+	defer span.Finish()
+
+	pageURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (pageURL.Scheme != "http" && pageURL.Scheme != "https") || pageURL.Host == "" {
+		return nil, errors.Biz("pdf.document.errors.invalid_url")
+	}
+	// 只允许访问公网地址，响应体超过上限时不再继续读取
+	content, err := http_client.FetchLimited(ctx, s.fetchClient, pageURL.String(), map[string]string{
+		"Accept": "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8",
+	}, s.MaxSize())
+	if stderrors.Is(err, http_client.ErrBodyTooLarge) {
+		return nil, errors.Biz("pdf.document.errors.file_too_large")
+	}
+	if err != nil {
+		s.logger.Warn("msg", "抓取网页失败", "url", pageURL.String(), "error", err.Error())
+		return nil, errors.Biz("pdf.document.errors.fetch_failed")
+	}
+
+	fileName := path.Base(pageURL.Path)
+	if fileName == "/" || fileName == "." {
+		fileName = pageURL.Host
+	}
+	format := docformat.Detect(fileName, content)
+	if format != docformat.FormatPDF && filepath.Ext(fileName) != format.Extension() {
+		fileName += format.Extension()
+	}
+	return s.importDocument(ctx, userId, fileName, format, content, folderId, pageURL.String())
+}
+
+// importDocument 转换文档并保存源文件、解析结果和文档记录
+func (s *DocumentImportService) importDocument(ctx context.Context, userId string, fileName string, format docformat.Format, content []byte, folderId string, baseURL string) (*ImportedDocument, error) {
+	if format == "" {
+		return nil, errors.Biz("pdf.document.errors.unsupported_format")
+	}
+	if format == docformat.FormatPDF {
+		return nil, errors.Biz("pdf.document.errors.use_pdf_upload")
+	}
+	if len(content) == 0 {
+		return nil, errors.Biz("pdf.document.errors.empty_file")
+	}
+	if int64(len(content)) > s.MaxSize() {
+		return nil, errors.Biz("pdf.document.errors.file_too_large")
+	}
+
+	hash := sha256.Sum256(content)
+	fileSHA256 := hex.EncodeToString(hash[:])
+
+	// 用户已导入过相同文件时直接返回
+	userDoc, paperPdf, _, err := s.userDocService.GetUserUploadBaseDataBySHA256AndUserId(ctx, userId, fileSHA256)
+	if err == nil && userDoc != nil && paperPdf != nil {
+		return &ImportedDocument{
+			DocId:      userDoc.Id,
+			PaperId:    paperPdf.PaperId,
+			PdfId:      paperPdf.Id,
+			FileFormat: paperPdf.FileFormat,
+			Title:      userDoc.DocName,
+			Existed:    true,
+		}, nil
+	}
+
+	var doc *docformat.Document
+	if format == docformat.FormatHTML {
+		doc, err = docformat.ConvertHTML(content, baseURL)
+	} else {
+		doc, err = docformat.Convert(format, content)
+	}
+	if err != nil {
+		s.logger.Warn("msg", "文档转换失败", "fileName", fileName, "format", string(format), "error", err.Error())
+		return nil, errors.Biz("pdf.document.errors.convert_failed")
+	}
+
+	useStorageCapacity, err := s.paperPdfService.GetPdfFileTotalSize(ctx, userId)
+	if err != nil {
+		return nil, errors.Biz("get storage capacity failed")
+	}
+
+	var imported *ImportedDocument
+	err = s.membershipService.CreditFunDocsUpload(ctx, int64(len(content)), int32(doc.EstimatePages()), useStorageCapacity, func(xctx context.Context, sessionId string) error {
+		var saveErr error
+		imported, saveErr = s.saveDocument(xctx, userId, fileName, fileSHA256, content, doc, folderId)
+		return saveErr
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// 发送文档创建事件，用于全文检索
+	if err := s.pdfParseService.needEmbedding(ctx, imported.PdfId); err != nil {
+		s.logger.Warn("msg", "发送文档创建事件失败", "pdfId", imported.PdfId, "error", err.Error())
+	}
+	return imported, nil
+}
+
+// saveDocument 上传源文件和转换结果，创建Paper、PaperPdf、UserDoc记录
+func (s *DocumentImportService) saveDocument(ctx context.Context, userId string, fileName string, fileSHA256 string, content []byte, doc *docformat.Document, folderId string) (*ImportedDocument, error) {
+	ext := doc.Format.Extension()
+	sourceKeyGen := func(uniqueId, fileName string) string {
+		return fmt.Sprintf("%s/%s/%s%s", fileSHA256, parseConstant.SourcePdfCatalog, idgen.GenerateUUID(), ext)
+	}
+	sourceRecord, err := s.ossService.UploadObjectAndSaveRecord(ctx, osspb.OSSBucketEnum_PDF, fileName, fileSHA256,
+		bytes.NewReader(content), int64(len(content)), sourceKeyGen, "", nil)
+	if err != nil {
+		s.logger.Error("msg", "上传文档源文件失败", "fileSHA256", fileSHA256, "error", err.Error())
+		return nil, errors.Biz("pdf.document.errors.upload_failed")
+	}
+
+	if err := s.saveParsedResult(ctx, userId, fileSHA256, doc); err != nil {
+		return nil, err
+	}
+
+	docName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	title := doc.Title
+	if title == "" {
+		title = docName
+	}
+	if len([]rune(title)) > 255 {
+		title = string([]rune(title)[:255])
+	}
+
+	paperId := idgen.GenerateUUID()
+	pdfId := idgen.GenerateUUID()
+	userDocId := idgen.GenerateUUID()
+	parseStatus := int(docpb.UserDocParsedStatusEnum_CONTENT_DATA_PARSED)
+
+	paper := &paperModel.Paper{
+		PaperId:     paperId,
+		OwnerId:     userId,
+		Title:       title,
+		ParseStatus: parseStatus,
+	}
+	paper.Id = paperId
+	paper.CreatorId = userId
+
+	paperPdf := &model.PaperPdf{
+		PaperId:       paperId,
+		FileSHA256:    fileSHA256,
+		Size:          int64(len(content)),
+		PageCount:     doc.EstimatePages(),
+		Language:      truncateLanguage(doc.Language),
+		OssBucketName: sourceRecord.BucketName,
+		OssObjectKey:  sourceRecord.ObjectKey,
+		FileFormat:    string(doc.Format),
+	}
+	paperPdf.Id = pdfId
+	paperPdf.CreatorId = userId
+
+	userDoc := &docModel.UserDoc{
+		UserId:      userId,
+		PaperId:     paperId,
+		PdfId:       pdfId,
+		DocName:     title,
+		PaperTitle:  title,
+		ParseStatus: parseStatus,
+	}
+	userDoc.Id = userDocId
+	userDoc.CreatorId = userId
+
+	if err := s.userDocService.SaveUploadRecords(ctx, paperPdf, paper, userDoc, folderId); err != nil {
+		s.logger.Error("msg", "保存文档记录失败", "fileSHA256", fileSHA256, "error", err.Error())
+		return nil, errors.Biz("pdf.document.errors.save_failed")
+	}
+
+	return &ImportedDocument{
+		DocId:      userDocId,
+		PaperId:    paperId,
+		PdfId:      pdfId,
+		FileFormat: paperPdf.FileFormat,
+		Title:      title,
+	}, nil
+}
+
+// saveParsedResult 上传元数据、段落和Markdown，其他用户已导入过相同文件时复用已有结果
+func (s *DocumentImportService) saveParsedResult(ctx context.Context, userId string, fileSHA256 string, doc *docformat.Document) error {
+	exist, err := s.paperPdfParsedService.HasExistBySourcePdfFileSHA256AndVersion(ctx, fileSHA256, parseConstant.ParseVersion)
+	if err != nil {
+		return errors.Biz("pdf.document.errors.save_failed")
+	}
+	if exist {
+		return nil
+	}
+
+	metadata, fullDocument := doc.Parse()
+	metadata.FileSHA256 = fileSHA256
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return errors.Biz("pdf.document.errors.convert_failed")
+	}
+	fullDocumentJSON, err := json.Marshal(fullDocument)
+	if err != nil {
+		return errors.Biz("pdf.document.errors.convert_failed")
+	}
+
+	files := []struct {
+		fileType string
+		suffix   string
+		data     []byte
+	}{
+		{parseConstant.PdfOssTypeMetadata, parseConstant.MetadataJsonSuffix, metadataJSON},
+		{parseConstant.PdfOssTypeParagraphs, parseConstant.ParagraphsJsonSuffix, fullDocumentJSON},
+		{parseConstant.PdfOssTypeMarkdown, parseConstant.MineruMdSuffix, []byte(doc.Markdown)},
+	}
+	parsedRecords := make([]*paperModel.PaperPdfParsed, 0, len(files))
+	for _, file := range files {
+		record, err := s.uploadParsedFile(ctx, fileSHA256, file.suffix, file.data)
+		if err != nil {
+			return err
+		}
+		parsed := &paperModel.PaperPdfParsed{
+			SourcePdfSHA256: fileSHA256,
+			FileSHA256:      record.FileSHA256,
+			FileType:        file.fileType,
+			FileName:        record.FileName,
+			FileSize:        record.FileSize,
+			ObjectKey:       record.ObjectKey,
+			BucketName:      record.BucketName,
+			Version:         parseConstant.ParseVersion,
+		}
+		parsed.Id = idgen.GenerateUUID()
+		parsed.CreatorId = userId
+		parsed.ModifierId = userId
+		parsed.CreatedAt = time.Now()
+		parsedRecords = append(parsedRecords, parsed)
+	}
+	if err := s.paperPdfParsedService.BatchSave(ctx, parsedRecords); err != nil {
+		s.logger.Error("msg", "保存文档解析记录失败", "fileSHA256", fileSHA256, "error", err.Error())
+		return errors.Biz("pdf.document.errors.save_failed")
+	}
+	return nil
+}
+
+// uploadParsedFile 上传一份解析结果到解析结果目录
+func (s *DocumentImportService) uploadParsedFile(ctx context.Context, fileSHA256 string, suffix string, data []byte) (*ossModel.OssRecord, error) {
+	fileName := idgen.GenerateUUID() + suffix
+	objectKeyGen := func(uniqueId, _ string) string {
+		return fmt.Sprintf("%s/%s/%s/%s", fileSHA256, parseConstant.ParsedPdfCatalog, parseConstant.ParseVersion, fileName)
+	}
+	record, err := s.ossService.UploadObjectAndSaveRecord(ctx, osspb.OSSBucketEnum_PDF, fileName,
+		fmt.Sprintf("%x", sha256.Sum256(data)), bytes.NewReader(data), int64(len(data)), objectKeyGen, "", nil)
+	if err != nil {
+		s.logger.Error("msg", "上传文档解析结果失败", "fileSHA256", fileSHA256, "fileName", fileName, "error", err.Error())
+		return nil, errors.Biz("pdf.document.errors.upload_failed")
+	}
+	return record, nil
+}
+
+// GetDocumentContent 获取文档转换后的Markdown，用于阅读器渲染和文本标注定位
+func (s *DocumentImportService) GetDocumentContent(ctx context.Context, userId string, pdfId string) (*DocumentContent, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "DocumentImportService.GetDocumentContent")
+	defer span.Finish()
+
+	paperPdf, err := getPermittedDocument(ctx, s.paperPdfService, pdfId, userId)
+	if err != nil {
+		return nil, err
+	}
+	markdown, err := s.pdfParseService.GetPdfMarkDown(ctx, pdfId)
+	if err != nil {
+		return nil, err
+	}
+	if markdown == nil {
+		return nil, errors.Biz("pdf.document.errors.content_not_found")
+	}
+	content := &DocumentContent{
+		PdfId:      pdfId,
+		FileFormat: string(docformat.Normalize(paperPdf.FileFormat)),
+		Markdown:   *markdown,
+	}
+	if metadata, err := s.pdfParseService.GetPdfMetadata(ctx, pdfId); err == nil && metadata != nil && metadata.Title != nil {
+		content.Title = metadata.Title.Text
+	}
+	return content, nil
+}
+
+// getPermittedDocument 校验用户对文档的访问权限，并确认文档不是PDF
+func getPermittedDocument(ctx context.Context, paperPdfService *PaperPdfService, pdfId string, userId string) (*model.PaperPdf, error) {
+	if pdfId == "" {
+		return nil, errors.Biz("pdf.document.errors.pdf_id_required")
+	}
+	denied, err := paperPdfService.AuthPermissionDenied(ctx, pdfId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if denied {
+		return nil, errors.Biz("pdf.document.errors.permission_denied")
+	}
+	paperPdf, err := paperPdfService.GetById(ctx, pdfId)
+	if err != nil {
+		return nil, errors.Biz("pdf.paper_pdf.errors.get_failed")
+	}
+	if paperPdf == nil {
+		return nil, errors.Biz("pdf.paper_pdf.errors.not_found")
+	}
+	if docformat.Normalize(paperPdf.FileFormat) == docformat.FormatPDF {
+		return nil, errors.Biz("pdf.document.errors.not_text_document")
+	}
+	return paperPdf, nil
+}
+
+// truncateLanguage 语言字段长度为10，截断过长的语言标签
+func truncateLanguage(language string) string {
+	language = strings.TrimSpace(language)
+	if len(language) > 10 {
+		return language[:10]
+	}
+	return language
+}
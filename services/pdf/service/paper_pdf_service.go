@@ -592,6 +592,14 @@ func (s *PaperPdfService) GetCountPdfMarksByNoteId(ctx context.Context, noteId s
 	return count, nil
 }
 
+// GetCountPdfMarksByNoteIds 根据笔记Ids列表获取每个笔记的PDF标记总数
+func (s *PaperPdfService) GetCountPdfMarksByNoteIds(ctx context.Context, noteIds []string) (map[string]int64, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperPdfService.GetCountPdfMarksByNoteIds")
+	defer span.Finish()
+
+	return s.pdfMarkService.GetCountPdfMarksByNoteIds(ctx, noteIds)
+}
+
 // GetPdfMarksByNoteId 根据笔记ID获取PDF标记
 func (s *PaperPdfService) GetPdfMarksByNoteId(ctx context.Context, noteId string) ([]model.PdfMark, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperPdfService.GetPdfMarksByNoteId")
@@ -0,0 +1,400 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	LLM "github.com/yb2020/odoc/external/LLM"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/http_client"
+	pkgi18n "github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	membershipPb "github.com/yb2020/odoc/proto/gen/go/membership"
+	parsedPb "github.com/yb2020/odoc/proto/gen/go/parsed"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	membershipInterfaces "github.com/yb2020/odoc/services/membership/interfaces"
+	"github.com/yb2020/odoc/services/pdf/model"
+)
+
+// 结构化总结中小节的分隔标记，LLM按该格式输出，便于按小节拆分
+const paperSummarySectionMarker = "### "
+
+// PaperSummaryGenerateService 论文结构化总结生成服务
+// 基于解析后的FullDocument按模板调用LLM生成总结，结果按文件SHA256+模板+语言缓存在PdfSummary中
+type PaperSummaryGenerateService struct {
+	config            *config.Config
+	logger            logging.Logger
+	tracer            opentracing.Tracer
+	llmClient         *LLM.Client
+	paperPdfService   *PaperPdfService
+	pdfParseService   *PdfParseService
+	pdfSummaryService *PdfSummaryService
+	userDocService    *docService.UserDocService
+	membershipService membershipInterfaces.IMembershipService
+}
+
+// NewPaperSummaryGenerateService 创建论文结构化总结生成服务
+func NewPaperSummaryGenerateService(
+	config *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	httpClient http_client.HttpClient,
+	paperPdfService *PaperPdfService,
+	pdfParseService *PdfParseService,
+	pdfSummaryService *PdfSummaryService,
+	userDocService *docService.UserDocService,
+	membershipService membershipInterfaces.IMembershipService,
+) *PaperSummaryGenerateService {
+	service := &PaperSummaryGenerateService{
+		config:            config,
+		logger:            logger,
+		tracer:            tracer,
+		paperPdfService:   paperPdfService,
+		pdfParseService:   pdfParseService,
+		pdfSummaryService: pdfSummaryService,
+		userDocService:    userDocService,
+		membershipService: membershipService,
+	}
+	switch config.LLM.UseChannel {
+	case "deepseek":
+		service.llmClient, _ = LLM.NewClient(LLM.ProviderDeepSeek,
+			LLM.WithAPIKey(config.LLM.Channel.Deepseek.APIKey),
+			LLM.WithBaseURL(config.LLM.Channel.Deepseek.URL),
+			LLM.WithHTTPClient(httpClient),
+			LLM.WithLogger(logger),
+			LLM.WithTimeout(200*time.Second),
+		)
+	case "gpt4o_mini":
+		service.llmClient, _ = LLM.NewClient(LLM.ProviderOpenAI,
+			LLM.WithAPIKey(config.LLM.Channel.Gpt4oMini.APIKey),
+			LLM.WithBaseURL(config.LLM.Channel.Gpt4oMini.URL),
+			LLM.WithHTTPClient(httpClient),
+			LLM.WithLogger(logger),
+			LLM.WithTimeout(200*time.Second),
+		)
+	}
+	return service
+}
+
+// GetTemplates 获取总结模板列表
+func (s *PaperSummaryGenerateService) GetTemplates() []config.SummaryTemplateConfig {
+	return s.config.Copilot.StructuredSummary.Templates
+}
+
+// GetDefaultTemplateKey 获取默认模板key
+func (s *PaperSummaryGenerateService) GetDefaultTemplateKey() string {
+	return s.config.Copilot.StructuredSummary.DefaultTemplate
+}
+
+// GetSummary 获取用户文献已生成的结构化总结，不存在时返回nil
+func (s *PaperSummaryGenerateService) GetSummary(ctx context.Context, userId string, pdfId string, templateKey string, lang string) (*model.PdfSummary, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperSummaryGenerateService.GetSummary")
+	defer span.Finish()
+
+	template, err := s.getTemplate(templateKey)
+	if err != nil {
+		return nil, err
+	}
+	fileSHA256, err := s.getFileSHA256(ctx, userId, pdfId)
+	if err != nil {
+		return nil, err
+	}
+	return s.findCached(ctx, fileSHA256, template.Key, NormalizeSummaryLang(lang))
+}
+
+// GenerateSummary 为用户文献生成结构化总结
+// 已有缓存时直接返回，不扣除积分；否则通过会员积分服务扣费后调用LLM生成并缓存
+func (s *PaperSummaryGenerateService) GenerateSummary(ctx context.Context, userId string, pdfId string, templateKey string, lang string) (*model.PdfSummary, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperSummaryGenerateService.GenerateSummary")
+	defer span.Finish()
+
+	template, err := s.getTemplate(templateKey)
+	if err != nil {
+		return nil, err
+	}
+	lang = NormalizeSummaryLang(lang)
+	fileSHA256, err := s.getFileSHA256(ctx, userId, pdfId)
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := s.findCached(ctx, fileSHA256, template.Key, lang)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil {
+		return cached, nil
+	}
+
+	var summary *model.PdfSummary
+	err = s.membershipService.CreditFunAi(ctx, membershipPb.CreditServiceType_CREDIT_SERVICE_TYPE_AI_PAPER_SUMMARY, "", pdfId, func(xctx context.Context, sessionId string) error {
+		sections, err := s.generateSections(xctx, pdfId, template, lang)
+		if err != nil {
+			return err
+		}
+		summary, err = s.saveSummary(xctx, fileSHA256, template.Key, lang, sessionId, sections)
+		return err
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// generateSections 加载论文全文并调用LLM生成各小节内容
+func (s *PaperSummaryGenerateService) generateSections(ctx context.Context, pdfId string, template *config.SummaryTemplateConfig, lang string) ([]model.PdfSummarySection, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperSummaryGenerateService.generateSections")
+	defer span.Finish()
+
+	if s.llmClient == nil {
+		return nil, errors.Biz("pdf.pdf_summary.errors.llm_not_configured")
+	}
+
+	fullDocument, err := s.pdfParseService.GetPdfFullDocument(ctx, pdfId)
+	if err != nil {
+		return nil, err
+	}
+	if fullDocument == nil || len(fullDocument.Paragraphs) == 0 {
+		return nil, errors.Biz("pdf.pdf_summary.errors.document_not_parsed")
+	}
+	// 元数据用于补充标题、摘要和图表标题，获取失败不影响总结生成
+	metadata, err := s.pdfParseService.GetPdfMetadata(ctx, pdfId)
+	if err != nil {
+		s.logger.Warn("msg", "获取PDF元数据失败，仅使用正文生成总结", "pdfId", pdfId, "error", err.Error())
+		metadata = nil
+	}
+
+	document := BuildSummaryDocumentText(metadata, fullDocument, s.config.Copilot.StructuredSummary.MaxInputChars)
+	resp, err := s.llmClient.CreateChatCompletion(ctx, LLM.ChatCompletionRequest{
+		Messages: []LLM.Message{
+			{Role: LLM.RoleSystem, Content: BuildSummarySystemPrompt(template, lang)},
+			{Role: LLM.RoleUser, Content: document},
+		},
+	})
+	if err != nil {
+		s.logger.Error("msg", "调用LLM生成论文总结失败", "pdfId", pdfId, "error", err.Error())
+		return nil, errors.Biz("pdf.pdf_summary.errors.generate_failed")
+	}
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return nil, errors.Biz("pdf.pdf_summary.errors.generate_failed")
+	}
+	return ParseSummarySections(template, resp.Choices[0].Message.Content), nil
+}
+
+// saveSummary 保存结构化总结，并发生成时以已存在的记录为准
+func (s *PaperSummaryGenerateService) saveSummary(ctx context.Context, fileSHA256 string, templateKey string, lang string, sessionId string, sections []model.PdfSummarySection) (*model.PdfSummary, error) {
+	summaryConfig := s.config.Copilot.StructuredSummary
+	summary := &model.PdfSummary{
+		SourceFrom:     summaryConfig.HandlerFrom,
+		ConversationId: sessionId,
+		FileSHA256:     fileSHA256,
+		Lang:           lang,
+		Version:        summaryConfig.HandlerVersion,
+		Template:       templateKey,
+		Summary:        RenderSummaryMarkdown(sections),
+	}
+	if err := summary.SetSections(sections); err != nil {
+		return nil, errors.BizWrap("pdf.pdf_summary.errors.create_failed", err)
+	}
+	if err := summary.SetRelatedQuestions(nil); err != nil {
+		return nil, errors.BizWrap("pdf.pdf_summary.errors.create_failed", err)
+	}
+	if _, err := s.pdfSummaryService.CreatePdfSummary(ctx, summary); err != nil {
+		existing, findErr := s.findCached(ctx, fileSHA256, templateKey, lang)
+		if findErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return summary, nil
+}
+
+// findCached 查询缓存的结构化总结
+func (s *PaperSummaryGenerateService) findCached(ctx context.Context, fileSHA256 string, templateKey string, lang string) (*model.PdfSummary, error) {
+	summaryConfig := s.config.Copilot.StructuredSummary
+	summary, err := s.pdfSummaryService.GetBySourceFromAndFileSHA256AndVersionAndLangAndTemplate(ctx,
+		summaryConfig.HandlerFrom, fileSHA256, summaryConfig.HandlerVersion, lang, templateKey)
+	if err != nil {
+		return nil, errors.Biz("pdf.pdf_summary.errors.get_failed")
+	}
+	return summary, nil
+}
+
+// getTemplate 获取模板，不存在时返回业务错误
+func (s *PaperSummaryGenerateService) getTemplate(templateKey string) (*config.SummaryTemplateConfig, error) {
+	template := s.config.Copilot.StructuredSummary.GetTemplate(templateKey)
+	if template == nil || len(template.Sections) == 0 {
+		return nil, errors.Biz("pdf.pdf_summary.errors.template_not_found")
+	}
+	return template, nil
+}
+
+// getFileSHA256 根据pdfId获取文件SHA256，用户的文献库中没有该PDF时返回业务错误
+func (s *PaperSummaryGenerateService) getFileSHA256(ctx context.Context, userId string, pdfId string) (string, error) {
+	if userId == "" {
+		return "", errors.Biz("pdf.pdf_summary.errors.doc_not_found")
+	}
+	userDoc, err := s.userDocService.GetByUserIdAndPdfId(ctx, userId, pdfId)
+	if err != nil {
+		return "", err
+	}
+	if userDoc == nil {
+		return "", errors.Biz("pdf.pdf_summary.errors.doc_not_found")
+	}
+	paperPdf, err := s.paperPdfService.GetById(ctx, pdfId)
+	if err != nil {
+		return "", errors.Biz("pdf.pdf.errors.get_failed")
+	}
+	if paperPdf == nil || paperPdf.FileSHA256 == "" {
+		return "", errors.Biz("pdf.pdf.errors.not_found")
+	}
+	return paperPdf.FileSHA256, nil
+}
+
+// NormalizeSummaryLang 规范化总结语言，仅支持中文和英文
+func NormalizeSummaryLang(lang string) string {
+	if strings.HasPrefix(strings.ToLower(lang), "zh") {
+		return pkgi18n.LanguageZhCN
+	}
+	return pkgi18n.LanguageEnUS
+}
+
+// BuildSummarySystemPrompt 根据模板构建系统提示词
+func BuildSummarySystemPrompt(template *config.SummaryTemplateConfig, lang string) string {
+	language := "English"
+	if lang == pkgi18n.LanguageZhCN {
+		language = "Simplified Chinese"
+	}
+	var b strings.Builder
+	b.WriteString("You are an assistant that writes structured summaries of academic papers. ")
+	b.WriteString("Read the paper provided by the user and answer each of the following sections based only on the paper content. ")
+	fmt.Fprintf(&b, "Write the answers in %s. ", language)
+	fmt.Fprintf(&b, "Start each section with a line of the form \"%s<key>\" using the exact key given below, followed by the content in markdown. Do not add any other sections.\n\n", paperSummarySectionMarker)
+	for _, section := range template.Sections {
+		fmt.Fprintf(&b, "- %s (%s): %s\n", section.Key, section.Title, section.Prompt)
+	}
+	return b.String()
+}
+
+// BuildSummaryDocumentText 将解析后的文档拼接为LLM输入，超出maxChars时截断
+func BuildSummaryDocumentText(metadata *parsedPb.DocumentMetadata, fullDocument *parsedPb.FullDocument, maxChars int) string {
+	var b strings.Builder
+	if metadata != nil {
+		if metadata.Title != nil && metadata.Title.Text != "" {
+			fmt.Fprintf(&b, "# %s\n\n", metadata.Title.Text)
+		}
+		if metadata.Abstract != nil && metadata.Abstract.Text != "" {
+			fmt.Fprintf(&b, "## Abstract\n%s\n\n", metadata.Abstract.Text)
+		}
+	}
+
+	currentSection := ""
+	hasFigure := false
+	for _, paragraph := range fullDocument.GetParagraphs() {
+		if paragraph.SectionTitle != "" && paragraph.SectionTitle != currentSection {
+			currentSection = paragraph.SectionTitle
+			fmt.Fprintf(&b, "## %s\n", currentSection)
+		}
+		switch paragraph.Type {
+		case parsedPb.ParagraphType_TEXT:
+			if paragraph.Text != nil && paragraph.Text.Text != "" {
+				b.WriteString(paragraph.Text.Text)
+				b.WriteString("\n\n")
+			}
+		case parsedPb.ParagraphType_IMAGE, parsedPb.ParagraphType_TABLE:
+			if paragraph.FigureTable != nil && paragraph.FigureTable.RefContent != "" {
+				fmt.Fprintf(&b, "[%s] %s\n\n", paragraph.FigureTable.Type, paragraph.FigureTable.RefContent)
+				hasFigure = true
+			}
+		}
+	}
+
+	// 正文中没有图表段落时，使用元数据中的图表标题
+	if metadata != nil && !hasFigure && len(metadata.FiguresAndTables) > 0 {
+		b.WriteString("## Figures and Tables\n")
+		for _, figure := range metadata.FiguresAndTables {
+			if figure.RefContent != "" {
+				fmt.Fprintf(&b, "- %s\n", figure.RefContent)
+			}
+		}
+	}
+
+	text := b.String()
+	if maxChars > 0 {
+		runes := []rune(text)
+		if len(runes) > maxChars {
+			text = string(runes[:maxChars])
+		}
+	}
+	return text
+}
+
+// ParseSummarySections 按模板小节拆分LLM输出，未识别到任何小节标记时整体作为第一个小节
+func ParseSummarySections(template *config.SummaryTemplateConfig, content string) []model.PdfSummarySection {
+	contents := make(map[string]*strings.Builder)
+	current := ""
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, paperSummarySectionMarker) {
+			key := strings.TrimSpace(strings.TrimPrefix(trimmed, paperSummarySectionMarker))
+			if section := matchTemplateSection(template, key); section != "" {
+				current = section
+				if _, ok := contents[current]; !ok {
+					contents[current] = &strings.Builder{}
+				}
+				continue
+			}
+		}
+		if current == "" {
+			continue
+		}
+		contents[current].WriteString(line)
+		contents[current].WriteString("\n")
+	}
+
+	if len(contents) == 0 {
+		first := template.Sections[0].Key
+		contents[first] = &strings.Builder{}
+		contents[first].WriteString(content)
+	}
+
+	sections := make([]model.PdfSummarySection, 0, len(template.Sections))
+	for _, section := range template.Sections {
+		text := ""
+		if builder, ok := contents[section.Key]; ok {
+			text = strings.TrimSpace(builder.String())
+		}
+		sections = append(sections, model.PdfSummarySection{
+			Key:     section.Key,
+			Title:   section.Title,
+			Content: text,
+		})
+	}
+	return sections
+}
+
+// matchTemplateSection 匹配小节key或标题（忽略大小写）
+func matchTemplateSection(template *config.SummaryTemplateConfig, key string) string {
+	for _, section := range template.Sections {
+		if strings.EqualFold(section.Key, key) || strings.EqualFold(section.Title, key) {
+			return section.Key
+		}
+	}
+	return ""
+}
+
+// RenderSummaryMarkdown 将结构化总结渲染为markdown
+func RenderSummaryMarkdown(sections []model.PdfSummarySection) string {
+	var b strings.Builder
+	for _, section := range sections {
+		if section.Content == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "## %s\n\n%s\n\n", section.Title, section.Content)
+	}
+	return strings.TrimSpace(b.String())
+}
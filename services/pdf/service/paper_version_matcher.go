@@ -0,0 +1,458 @@
+package service
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	parsedPb "github.com/yb2020/odoc/proto/gen/go/parsed"
+	pb "github.com/yb2020/odoc/proto/gen/go/pdf"
+	docModel "github.com/yb2020/odoc/services/doc/model"
+)
+
+// 模糊定位时标注文本的词在段落中出现的最低比例
+const anchorFuzzyContainment = 0.85
+
+// 模糊定位要求标注文本至少包含的词数，过短的文本模糊匹配容易误定位
+const anchorFuzzyMinTokens = 4
+
+var (
+	// arXiv编号：新格式 2401.01234v2，旧格式 hep-th/9901001v1
+	arxivIdPattern = regexp.MustCompile(`(?i)(\d{4}\.\d{4,5}|[a-z\-]+(?:\.[a-z]{2})?/\d{7})(?:v(\d+))?`)
+	// 以arXiv编号命名的文件，如 2401.01234v2.pdf
+	arxivFileNamePattern = regexp.MustCompile(`(?i)^(\d{4}\.\d{4,5})(?:v(\d+))?(?:\.pdf)?$`)
+	// 章节标题前的编号，如 1、2.3、IV.、A.
+	sectionNumberPattern = regexp.MustCompile(`^(?:[0-9]+(?:\.[0-9]+)*[.)]?|(?:[ivxlc]+|[a-z])[.)])\s+`)
+	// 行末断字，如 experi- ments
+	hyphenBreakPattern = regexp.MustCompile(`(\p{L})-\s+(\p{L})`)
+	whitespacePattern  = regexp.MustCompile(`\s+`)
+)
+
+// 常见连字替换，PDF抽取的文本中经常出现
+var ligatureReplacer = strings.NewReplacer("ﬁ", "fi", "ﬂ", "fl", "ﬀ", "ff", "ﬃ", "ffi", "ﬄ", "ffl", "\u00ad", "")
+
+// paperIdentity 用于判断两个PDF是否为同一论文的标识信息
+type paperIdentity struct {
+	arxivId      string
+	arxivVersion int
+	doi          string
+	titleTokens  []string
+	authorTokens map[string]struct{}
+}
+
+// merge 用other补充当前缺失的字段
+func (p *paperIdentity) merge(other *paperIdentity) {
+	if p.arxivId == "" {
+		p.arxivId, p.arxivVersion = other.arxivId, other.arxivVersion
+	}
+	if p.doi == "" {
+		p.doi = other.doi
+	}
+	if len(p.titleTokens) == 0 {
+		p.titleTokens = other.titleTokens
+	}
+	if len(p.authorTokens) == 0 {
+		p.authorTokens = other.authorTokens
+	}
+}
+
+// identityFromUserDoc 从文献信息中提取论文标识
+func identityFromUserDoc(doc *docModel.UserDoc) *paperIdentity {
+	identity := &paperIdentity{
+		titleTokens:  tokenizeText(firstNonEmpty(doc.PaperTitle, strings.TrimSuffix(doc.DocName, ".pdf"))),
+		authorTokens: authorTokens(firstNonEmpty(doc.AuthorDesc, doc.DisplayAuthors, doc.MetaAuthors)),
+	}
+	doi := normalizeDoi(firstNonEmpty(doc.UserEditedDoi, doc.MetaDoi))
+	// arXiv自动分配的DOI（10.48550/arXiv.xxxx）只作为arXiv编号使用
+	if strings.Contains(doi, "arxiv") {
+		identity.arxivId, identity.arxivVersion = parseArxivId(doi)
+	} else {
+		identity.doi = doi
+	}
+	if identity.arxivId == "" && strings.Contains(strings.ToLower(doc.MetaUrl), "arxiv.org") {
+		identity.arxivId, identity.arxivVersion = parseArxivId(doc.MetaUrl)
+	}
+	if identity.arxivId == "" {
+		if match := arxivFileNamePattern.FindStringSubmatch(strings.TrimSpace(doc.DocName)); match != nil {
+			identity.arxivId = match[1]
+			identity.arxivVersion, _ = strconv.Atoi(match[2])
+		}
+	}
+	return identity
+}
+
+// identityFromMetadata 从解析出的元数据中提取论文标识
+func identityFromMetadata(metadata *parsedPb.DocumentMetadata) *paperIdentity {
+	identity := &paperIdentity{
+		authorTokens: make(map[string]struct{}),
+	}
+	for _, doi := range metadata.Dois {
+		switch strings.ToLower(doi.Type) {
+		case "arxiv":
+			if identity.arxivId == "" {
+				identity.arxivId, identity.arxivVersion = parseArxivId(doi.Doi)
+			}
+		case "md5":
+		default:
+			if identity.doi == "" {
+				identity.doi = normalizeDoi(doi.Doi)
+			}
+		}
+	}
+	if metadata.Title != nil {
+		identity.titleTokens = tokenizeText(metadata.Title.Text)
+	}
+	for _, author := range metadata.Authors {
+		surname := author.Surname
+		if surname == "" {
+			fields := strings.Fields(author.FullName)
+			if len(fields) > 0 {
+				surname = fields[len(fields)-1]
+			}
+		}
+		for _, token := range tokenizeText(surname) {
+			identity.authorTokens[token] = struct{}{}
+		}
+	}
+	return identity
+}
+
+// matchPaperIdentity 判断两个标识是否为同一论文，返回匹配依据和得分
+func matchPaperIdentity(a *paperIdentity, b *paperIdentity) (pb.PaperVersionMatchType, float64) {
+	if a.arxivId != "" && strings.EqualFold(a.arxivId, b.arxivId) {
+		return pb.PaperVersionMatchType_PAPER_VERSION_MATCH_ARXIV, 1
+	}
+	if a.doi != "" && a.doi == b.doi {
+		return pb.PaperVersionMatchType_PAPER_VERSION_MATCH_DOI, 1
+	}
+	// 标题过短时（如 Introduction）无法区分论文
+	if len(a.titleTokens) < 3 || len(b.titleTokens) < 3 {
+		return pb.PaperVersionMatchType_PAPER_VERSION_MATCH_UNKNOWN, 0
+	}
+	titleSimilarity := jaccardSimilarity(a.titleTokens, b.titleTokens)
+	if titleSimilarity < paperVersionTitleSimilarity {
+		return pb.PaperVersionMatchType_PAPER_VERSION_MATCH_UNKNOWN, 0
+	}
+	// 任一方缺少作者信息时只按标题判断
+	if len(a.authorTokens) == 0 || len(b.authorTokens) == 0 {
+		return pb.PaperVersionMatchType_PAPER_VERSION_MATCH_TITLE, roundScore(titleSimilarity * 0.9)
+	}
+	shared := 0
+	for token := range a.authorTokens {
+		if _, ok := b.authorTokens[token]; ok {
+			shared++
+		}
+	}
+	authorOverlap := float64(shared) / math.Min(float64(len(a.authorTokens)), float64(len(b.authorTokens)))
+	if authorOverlap < paperVersionAuthorOverlap {
+		return pb.PaperVersionMatchType_PAPER_VERSION_MATCH_UNKNOWN, 0
+	}
+	return pb.PaperVersionMatchType_PAPER_VERSION_MATCH_TITLE, roundScore(titleSimilarity*0.7 + authorOverlap*0.3)
+}
+
+// parseArxivId 从文本中提取arXiv编号和版本号
+func parseArxivId(text string) (string, int) {
+	match := arxivIdPattern.FindStringSubmatch(text)
+	if match == nil {
+		return "", 0
+	}
+	version, _ := strconv.Atoi(match[2])
+	return strings.ToLower(match[1]), version
+}
+
+// normalizeDoi 统一DOI格式：小写并去掉 https://doi.org/ 等前缀
+func normalizeDoi(doi string) string {
+	doi = strings.ToLower(strings.TrimSpace(doi))
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		doi = strings.TrimPrefix(doi, prefix)
+	}
+	return strings.TrimSpace(doi)
+}
+
+// authorTokens 提取作者姓名中的词，作者信息可能是JSON或普通文本
+func authorTokens(text string) map[string]struct{} {
+	tokens := make(map[string]struct{})
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err == nil {
+		// JSON只取值，避免字段名混入
+		var values []string
+		collectJSONStrings(value, &values)
+		text = strings.Join(values, " ")
+	}
+	for _, token := range tokenizeText(text) {
+		if len([]rune(token)) > 1 {
+			tokens[token] = struct{}{}
+		}
+	}
+	return tokens
+}
+
+func collectJSONStrings(value interface{}, values *[]string) {
+	switch v := value.(type) {
+	case string:
+		*values = append(*values, v)
+	case []interface{}:
+		for _, item := range v {
+			collectJSONStrings(item, values)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			collectJSONStrings(item, values)
+		}
+	}
+}
+
+// normalizeAnchorText 归一化文本用于比较：替换连字、合并断字、小写并压缩空白
+func normalizeAnchorText(text string) string {
+	text = ligatureReplacer.Replace(text)
+	text = hyphenBreakPattern.ReplaceAllString(text, "$1$2")
+	text = whitespacePattern.ReplaceAllString(text, " ")
+	return strings.ToLower(strings.TrimSpace(text))
+}
+
+// tokenizeText 将文本拆分为小写的词，忽略标点
+func tokenizeText(text string) []string {
+	return strings.FieldsFunc(normalizeAnchorText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// jaccardSimilarity 两组词的Jaccard相似度
+func jaccardSimilarity(a []string, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	setA := make(map[string]struct{}, len(a))
+	for _, token := range a {
+		setA[token] = struct{}{}
+	}
+	setB := make(map[string]struct{}, len(b))
+	for _, token := range b {
+		setB[token] = struct{}{}
+	}
+	shared := 0
+	for token := range setA {
+		if _, ok := setB[token]; ok {
+			shared++
+		}
+	}
+	union := len(setA) + len(setB) - shared
+	if union == 0 {
+		return 0
+	}
+	return roundScore(float64(shared) / float64(union))
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+// documentSection 全文中的一个章节
+type documentSection struct {
+	key        string
+	title      string
+	paragraphs []string
+}
+
+func (s *documentSection) text() string {
+	return strings.Join(s.paragraphs, " ")
+}
+
+// sectionKey 章节标题去掉编号后归一化，用于跨版本对应章节
+func sectionKey(title string) string {
+	key := normalizeAnchorText(title)
+	key = sectionNumberPattern.ReplaceAllString(key, "")
+	return strings.Join(tokenizeText(key), " ")
+}
+
+// groupDocumentSections 按章节标题对正文段落分组，保持章节在文中的顺序
+func groupDocumentSections(document *parsedPb.FullDocument) []*documentSection {
+	sections := make([]*documentSection, 0)
+	sectionByKey := make(map[string]*documentSection)
+	for _, paragraph := range document.Paragraphs {
+		if !isTextParagraph(paragraph) {
+			continue
+		}
+		text := strings.TrimSpace(paragraph.Text.Text)
+		if text == "" {
+			continue
+		}
+		key := sectionKey(paragraph.SectionTitle)
+		section, ok := sectionByKey[key]
+		if !ok {
+			section = &documentSection{key: key, title: strings.TrimSpace(paragraph.SectionTitle)}
+			sectionByKey[key] = section
+			sections = append(sections, section)
+		}
+		section.paragraphs = append(section.paragraphs, text)
+	}
+	return sections
+}
+
+func isTextParagraph(paragraph *parsedPb.Paragraph) bool {
+	if paragraph.Text == nil {
+		return false
+	}
+	return paragraph.Type == parsedPb.ParagraphType_TEXT || paragraph.Type == parsedPb.ParagraphType_UNKOWN
+}
+
+// anchorSentence 段落中的句子及其在段落归一化文本中的位置
+type anchorSentence struct {
+	start  int
+	end    int
+	tokens []string
+	bbox   *parsedPb.BBox
+}
+
+// anchorParagraph 用于重新定位标注的段落
+type anchorParagraph struct {
+	text      string
+	tokens    map[string]struct{}
+	sentences []anchorSentence
+	bbox      *parsedPb.BBox
+}
+
+// buildTextAnchors 将全文转换为可定位的段落，句子有边界框时按句子定位，否则使用段落边界框
+func buildTextAnchors(document *parsedPb.FullDocument) []*anchorParagraph {
+	anchors := make([]*anchorParagraph, 0, len(document.Paragraphs))
+	for _, paragraph := range document.Paragraphs {
+		if !isTextParagraph(paragraph) {
+			continue
+		}
+		anchor := &anchorParagraph{bbox: paragraph.Text.Bbox, tokens: make(map[string]struct{})}
+		var builder strings.Builder
+		for _, sentence := range paragraph.Text.Sentences {
+			text := normalizeAnchorText(sentence.Text)
+			if text == "" {
+				continue
+			}
+			if builder.Len() > 0 {
+				builder.WriteString(" ")
+			}
+			start := builder.Len()
+			builder.WriteString(text)
+			anchor.sentences = append(anchor.sentences, anchorSentence{
+				start:  start,
+				end:    builder.Len(),
+				tokens: tokenizeText(text),
+				bbox:   sentence.Bbox,
+			})
+		}
+		anchor.text = builder.String()
+		if anchor.text == "" {
+			anchor.text = normalizeAnchorText(paragraph.Text.Text)
+		}
+		if anchor.text == "" {
+			continue
+		}
+		for _, token := range tokenizeText(anchor.text) {
+			anchor.tokens[token] = struct{}{}
+		}
+		anchors = append(anchors, anchor)
+	}
+	return anchors
+}
+
+// locateAnchorText 在全文中定位标注文本，返回覆盖该文本的边界框
+// 有多处匹配时选择页码离原标注最近的一处
+func locateAnchorText(anchors []*anchorParagraph, keyContent string, page int) []*parsedPb.BBox {
+	key := normalizeAnchorText(keyContent)
+	if key == "" {
+		return nil
+	}
+
+	var best []*parsedPb.BBox
+	bestDistance := math.MaxInt
+	pick := func(boxes []*parsedPb.BBox) {
+		if len(boxes) == 0 {
+			return
+		}
+		distance := int(math.Abs(float64(int(boxes[0].PageNumber) - page)))
+		if distance < bestDistance {
+			best, bestDistance = boxes, distance
+		}
+	}
+
+	// 精确匹配
+	for _, anchor := range anchors {
+		if index := strings.Index(anchor.text, key); index >= 0 {
+			pick(anchor.boxesInRange(index, index+len(key)))
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	// 模糊匹配：标注文本的词大部分出现在同一段落中
+	keyTokens := tokenizeText(key)
+	if len(keyTokens) < anchorFuzzyMinTokens {
+		return nil
+	}
+	bestContainment := 0.0
+	for _, anchor := range anchors {
+		found := 0
+		for _, token := range keyTokens {
+			if _, ok := anchor.tokens[token]; ok {
+				found++
+			}
+		}
+		containment := float64(found) / float64(len(keyTokens))
+		if containment < anchorFuzzyContainment || containment < bestContainment {
+			continue
+		}
+		boxes := anchor.boxesOverlappingTokens(keyTokens)
+		if len(boxes) == 0 {
+			continue
+		}
+		if containment > bestContainment {
+			best, bestDistance = nil, math.MaxInt
+			bestContainment = containment
+		}
+		pick(boxes)
+	}
+	return best
+}
+
+// boxesInRange 返回与归一化文本区间[start, end)重叠的句子边界框
+func (a *anchorParagraph) boxesInRange(start int, end int) []*parsedPb.BBox {
+	boxes := make([]*parsedPb.BBox, 0)
+	for _, sentence := range a.sentences {
+		if sentence.bbox != nil && sentence.start < end && sentence.end > start {
+			boxes = append(boxes, sentence.bbox)
+		}
+	}
+	if len(boxes) == 0 && a.bbox != nil {
+		boxes = append(boxes, a.bbox)
+	}
+	return boxes
+}
+
+// boxesOverlappingTokens 返回大部分词都出现在标注文本中的句子边界框
+func (a *anchorParagraph) boxesOverlappingTokens(keyTokens []string) []*parsedPb.BBox {
+	keySet := make(map[string]struct{}, len(keyTokens))
+	for _, token := range keyTokens {
+		keySet[token] = struct{}{}
+	}
+	boxes := make([]*parsedPb.BBox, 0)
+	for _, sentence := range a.sentences {
+		if sentence.bbox == nil || len(sentence.tokens) == 0 {
+			continue
+		}
+		found := 0
+		for _, token := range sentence.tokens {
+			if _, ok := keySet[token]; ok {
+				found++
+			}
+		}
+		if float64(found)/float64(len(sentence.tokens)) >= 0.5 {
+			boxes = append(boxes, sentence.bbox)
+		}
+	}
+	if len(boxes) == 0 && a.bbox != nil {
+		boxes = append(boxes, a.bbox)
+	}
+	return boxes
+}
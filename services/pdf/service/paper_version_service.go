@@ -0,0 +1,461 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/proto/gen/go/common"
+	notePb "github.com/yb2020/odoc/proto/gen/go/note"
+	parsedPb "github.com/yb2020/odoc/proto/gen/go/parsed"
+	pb "github.com/yb2020/odoc/proto/gen/go/pdf"
+	docModel "github.com/yb2020/odoc/services/doc/model"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	noteInterfaces "github.com/yb2020/odoc/services/note/interfaces"
+	paperModel "github.com/yb2020/odoc/services/paper/model"
+	paperService "github.com/yb2020/odoc/services/paper/service"
+	pdfInterfaces "github.com/yb2020/odoc/services/pdf/interfaces"
+	"github.com/yb2020/odoc/services/pdf/model"
+)
+
+// 标题相似度阈值，达到该值才认为是同一论文
+const paperVersionTitleSimilarity = 0.9
+
+// 作者重合度阈值，两边都有作者信息时需要达到该值
+const paperVersionAuthorOverlap = 0.5
+
+// PaperVersionService 论文版本服务
+// 识别用户文库中同一论文的不同PDF版本，登记版本关系，按章节对比全文，并将旧版本上的标注迁移到新版本
+type PaperVersionService struct {
+	logger           logging.Logger
+	tracer           opentracing.Tracer
+	paperService     *paperService.PaperService
+	pdfParseService  *PdfParseService
+	userDocService   *docService.UserDocService
+	paperNoteService noteInterfaces.IPaperNoteService
+	pdfMarkService   pdfInterfaces.IPdfMarkService
+}
+
+// NewPaperVersionService 创建论文版本服务
+func NewPaperVersionService(
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	paperService *paperService.PaperService,
+	pdfParseService *PdfParseService,
+	userDocService *docService.UserDocService,
+	paperNoteService noteInterfaces.IPaperNoteService,
+	pdfMarkService pdfInterfaces.IPdfMarkService,
+) *PaperVersionService {
+	return &PaperVersionService{
+		logger:           logger,
+		tracer:           tracer,
+		paperService:     paperService,
+		pdfParseService:  pdfParseService,
+		userDocService:   userDocService,
+		paperNoteService: paperNoteService,
+		pdfMarkService:   pdfMarkService,
+	}
+}
+
+// GetRelatedVersions 查找用户文库中疑似与pdfId为同一论文的其他PDF
+// 依次按arXiv编号、DOI、标题与作者匹配，结果按匹配得分降序
+func (s *PaperVersionService) GetRelatedVersions(ctx context.Context, userId string, pdfId string) ([]*pb.RelatedPaperVersion, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperVersionService.GetRelatedVersions")
+	defer span.Finish()
+
+	userDoc, err := s.getUserDoc(ctx, userId, pdfId)
+	if err != nil {
+		return nil, err
+	}
+	identity := s.getPdfIdentity(ctx, userDoc)
+	current, err := s.paperService.GetPaperVersionByPdfId(ctx, userId, pdfId)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := s.userDocService.GetAllByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	related := make([]*pb.RelatedPaperVersion, 0)
+	for i := range docs {
+		doc := &docs[i]
+		if doc.PdfId == "" || doc.PdfId == pdfId {
+			continue
+		}
+		candidate := identityFromUserDoc(doc)
+		matchType, score := matchPaperIdentity(identity, candidate)
+		if matchType == pb.PaperVersionMatchType_PAPER_VERSION_MATCH_UNKNOWN {
+			continue
+		}
+		item := &pb.RelatedPaperVersion{
+			DocId:        doc.Id,
+			PdfId:        doc.PdfId,
+			PaperId:      doc.PaperId,
+			DocName:      doc.DocName,
+			Title:        doc.PaperTitle,
+			MatchType:    matchType,
+			Score:        score,
+			ArxivId:      candidate.arxivId,
+			ArxivVersion: uint32(candidate.arxivVersion),
+		}
+		if current != nil {
+			version, err := s.paperService.GetPaperVersionByPdfId(ctx, userId, doc.PdfId)
+			if err != nil {
+				return nil, err
+			}
+			item.Attached = version != nil && version.PaperId == current.PaperId
+		}
+		related = append(related, item)
+	}
+	sort.SliceStable(related, func(i, j int) bool {
+		return related[i].Score > related[j].Score
+	})
+	return related, nil
+}
+
+// AttachVersion 将pdfId登记为basePdfId所属论文的新版本
+// basePdfId尚未登记版本时，先将其登记为该论文的第一个版本
+func (s *PaperVersionService) AttachVersion(ctx context.Context, userId string, basePdfId string, pdfId string, label string) (*paperModel.PaperVersion, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperVersionService.AttachVersion")
+	defer span.Finish()
+
+	if basePdfId == pdfId {
+		return nil, errors.Biz("pdf.paper_version.errors.same_pdf")
+	}
+	baseDoc, err := s.getUserDoc(ctx, userId, basePdfId)
+	if err != nil {
+		return nil, err
+	}
+	newDoc, err := s.getUserDoc(ctx, userId, pdfId)
+	if err != nil {
+		return nil, err
+	}
+
+	baseVersion, err := s.paperService.GetPaperVersionByPdfId(ctx, userId, basePdfId)
+	if err != nil {
+		return nil, err
+	}
+	if baseVersion == nil {
+		baseVersion, err = s.paperService.AddPaperVersion(ctx, s.buildPaperVersion(ctx, userId, baseDoc.PaperId, baseDoc, ""))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// pdfId已登记过版本（包括并发登记）时返回已有记录，需确认属于同一论文
+	version, err := s.paperService.AddPaperVersion(ctx, s.buildPaperVersion(ctx, userId, baseVersion.PaperId, newDoc, label))
+	if err != nil {
+		return nil, err
+	}
+	if version.PaperId != baseVersion.PaperId {
+		return nil, errors.Biz("pdf.paper_version.errors.attached_to_other_paper")
+	}
+	return version, nil
+}
+
+// DiffVersions 按章节对比两个版本的解析全文
+// 章节按标题（去掉编号）对应，章节内按段落文本对比新增与删除
+func (s *PaperVersionService) DiffVersions(ctx context.Context, userId string, fromPdfId string, toPdfId string) (*pb.DiffPaperVersionsResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperVersionService.DiffVersions")
+	defer span.Finish()
+
+	if _, err := s.getUserDoc(ctx, userId, fromPdfId); err != nil {
+		return nil, err
+	}
+	if _, err := s.getUserDoc(ctx, userId, toPdfId); err != nil {
+		return nil, err
+	}
+	fromDocument, err := s.getFullDocument(ctx, fromPdfId)
+	if err != nil {
+		return nil, err
+	}
+	toDocument, err := s.getFullDocument(ctx, toPdfId)
+	if err != nil {
+		return nil, err
+	}
+	return diffDocumentSections(fromDocument, toDocument), nil
+}
+
+// MigrateMarks 将用户在fromPdfId上的高亮标注迁移到toPdfId
+// 按标注的选中文本在新版本全文中重新定位，先精确匹配，再按词重合度模糊匹配；无法定位的标注在结果中返回
+func (s *PaperVersionService) MigrateMarks(ctx context.Context, userId string, fromPdfId string, toPdfId string) (*pb.MigratePaperVersionMarksResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PaperVersionService.MigrateMarks")
+	defer span.Finish()
+
+	if fromPdfId == toPdfId {
+		return nil, errors.Biz("pdf.paper_version.errors.same_pdf")
+	}
+	if _, err := s.getUserDoc(ctx, userId, fromPdfId); err != nil {
+		return nil, err
+	}
+	if _, err := s.getUserDoc(ctx, userId, toPdfId); err != nil {
+		return nil, err
+	}
+	toDocument, err := s.getFullDocument(ctx, toPdfId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.MigratePaperVersionMarksResponse{}
+	fromNote, err := s.paperNoteService.GetPaperNoteByPdfIdAndUserId(ctx, fromPdfId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if fromNote == nil {
+		return resp, nil
+	}
+	marks, err := s.pdfMarkService.GetPdfMarksByNoteId(ctx, fromNote.Id)
+	if err != nil {
+		return nil, err
+	}
+	resp.TotalCount = uint32(len(marks))
+	if len(marks) == 0 {
+		return resp, nil
+	}
+
+	// 新版本没有笔记时自动创建
+	toNote, err := s.paperNoteService.GetOwnerPaperNoteBaseInfo(ctx, userId, toPdfId)
+	if err != nil {
+		return nil, err
+	}
+	resp.NoteId = toNote.NoteId
+	existingMarks, err := s.pdfMarkService.GetPdfMarksByNoteId(ctx, toNote.NoteId)
+	if err != nil {
+		return nil, err
+	}
+	existingKeys := make(map[string]struct{}, len(existingMarks))
+	for i := range existingMarks {
+		existingKeys[migratedMarkKey(&existingMarks[i])] = struct{}{}
+	}
+
+	anchors := buildTextAnchors(toDocument)
+	for i := range marks {
+		mark := &marks[i]
+		if _, ok := existingKeys[migratedMarkKey(mark)]; ok {
+			resp.SkippedCount++
+			continue
+		}
+		if mark.Type != int(common.IDEAAnnotateType_IDEAAnnotateTypeComment) || normalizeAnchorText(mark.KeyContent) == "" {
+			resp.UnplacedMarks = append(resp.UnplacedMarks, toUnplacedPdfMark(mark, pb.PdfMarkUnplacedReason_PDF_MARK_UNPLACED_NO_TEXT))
+			continue
+		}
+		boxes := locateAnchorText(anchors, mark.KeyContent, mark.Page)
+		if len(boxes) == 0 {
+			resp.UnplacedMarks = append(resp.UnplacedMarks, toUnplacedPdfMark(mark, pb.PdfMarkUnplacedReason_PDF_MARK_UNPLACED_NOT_FOUND))
+			continue
+		}
+		newMark := buildMigratedMark(mark, boxes, toNote.PaperId, toNote.NoteId, toPdfId)
+		if _, err := s.pdfMarkService.SavePdfMarkByBean(ctx, newMark); err != nil {
+			s.logger.Warn("msg", "迁移标注失败", "markId", mark.Id, "toPdfId", toPdfId, "error", err.Error())
+			resp.UnplacedMarks = append(resp.UnplacedMarks, toUnplacedPdfMark(mark, pb.PdfMarkUnplacedReason_PDF_MARK_UNPLACED_SAVE_FAILED))
+			continue
+		}
+		existingKeys[migratedMarkKey(newMark)] = struct{}{}
+		resp.MigratedCount++
+	}
+	return resp, nil
+}
+
+// getUserDoc 获取用户文库中pdfId对应的文献，不属于该用户时返回错误
+func (s *PaperVersionService) getUserDoc(ctx context.Context, userId string, pdfId string) (*docModel.UserDoc, error) {
+	userDoc, err := s.userDocService.GetByUserIdAndPdfId(ctx, userId, pdfId)
+	if err != nil {
+		return nil, err
+	}
+	if userDoc == nil {
+		return nil, errors.Biz("pdf.paper_version.errors.doc_not_found")
+	}
+	return userDoc, nil
+}
+
+// getFullDocument 获取PDF解析后的全文，尚未解析完成时返回错误
+func (s *PaperVersionService) getFullDocument(ctx context.Context, pdfId string) (*parsedPb.FullDocument, error) {
+	document, err := s.pdfParseService.GetPdfFullDocument(ctx, pdfId)
+	if err != nil {
+		return nil, err
+	}
+	if document == nil || len(document.Paragraphs) == 0 {
+		return nil, errors.Biz("pdf.paper_version.errors.document_not_parsed")
+	}
+	return document, nil
+}
+
+// getPdfIdentity 获取PDF的论文标识，优先使用解析出的元数据，缺失的字段由文献信息补充
+func (s *PaperVersionService) getPdfIdentity(ctx context.Context, userDoc *docModel.UserDoc) *paperIdentity {
+	identity := identityFromUserDoc(userDoc)
+	metadata, err := s.pdfParseService.GetPdfMetadata(ctx, userDoc.PdfId)
+	if err != nil {
+		// 元数据只用于补充标识，获取失败时仅使用文献信息
+		s.logger.Warn("msg", "获取PDF元数据失败", "pdfId", userDoc.PdfId, "error", err.Error())
+		return identity
+	}
+	if metadata != nil {
+		identity.merge(identityFromMetadata(metadata))
+	}
+	return identity
+}
+
+// buildPaperVersion 根据文献信息构建用户的版本记录
+func (s *PaperVersionService) buildPaperVersion(ctx context.Context, userId string, paperId string, userDoc *docModel.UserDoc, label string) *paperModel.PaperVersion {
+	identity := s.getPdfIdentity(ctx, userDoc)
+	version := &paperModel.PaperVersion{
+		UserId:       userId,
+		PaperId:      paperId,
+		PdfId:        userDoc.PdfId,
+		Label:        label,
+		Source:       paperModel.PaperVersionSourceUserUpload,
+		ArxivId:      identity.arxivId,
+		ArxivVersion: identity.arxivVersion,
+		Doi:          identity.doi,
+		PublishDate:  firstNonEmpty(userDoc.DisplayPublishDate, userDoc.PublishDate, userDoc.MetaPublishDate),
+	}
+	if identity.arxivId != "" {
+		version.Source = paperModel.PaperVersionSourceArxiv
+	}
+	return version
+}
+
+// toUnplacedPdfMark 构建未能迁移的标注信息
+func toUnplacedPdfMark(mark *model.PdfMark, reason pb.PdfMarkUnplacedReason) *pb.UnplacedPdfMark {
+	return &pb.UnplacedPdfMark{
+		MarkId:     mark.Id,
+		Type:       uint32(mark.Type),
+		Page:       uint32(mark.Page),
+		KeyContent: mark.KeyContent,
+		Idea:       mark.Idea,
+		Reason:     reason,
+	}
+}
+
+// migratedMarkKey 判断新版本中是否已有相同标注的键，避免重复迁移
+func migratedMarkKey(mark *model.PdfMark) string {
+	return strings.Join([]string{
+		normalizeAnchorText(mark.KeyContent),
+		strings.TrimSpace(mark.Idea),
+	}, "\x00")
+}
+
+// buildMigratedMark 基于旧标注和新版本中的定位结果构建新标注，选区矩形使用匹配句子的边界框
+func buildMigratedMark(mark *model.PdfMark, boxes []*parsedPb.BBox, paperId string, noteId string, pdfId string) *model.PdfMark {
+	page := int(boxes[0].PageNumber)
+	rectangles := make([]*notePb.Rectangle, 0, len(boxes))
+	for _, box := range boxes {
+		rectangles = append(rectangles, &notePb.Rectangle{
+			X:          box.X0,
+			Y:          box.Y0,
+			Width:      box.X1 - box.X0,
+			Height:     box.Y1 - box.Y0,
+			PageNumber: uint32(box.PageNumber),
+		})
+	}
+
+	comment := &notePb.CommentRawModel{}
+	if mark.CommentContent != "" {
+		if err := json.Unmarshal([]byte(mark.CommentContent), comment); err != nil {
+			comment = &notePb.CommentRawModel{}
+		}
+	}
+	comment.Id = ""
+	comment.AnnotateId = ""
+	comment.NoteId = noteId
+	comment.PaperId = paperId
+	comment.PdfId = pdfId
+	comment.Page = uint32(page)
+	comment.Rectangles = rectangles
+	comment.RectStr = mark.KeyContent
+	commentContent, _ := json.Marshal(comment)
+
+	return &model.PdfMark{
+		Type:           mark.Type,
+		PaperId:        paperId,
+		NoteId:         noteId,
+		PdfId:          pdfId,
+		Idea:           mark.Idea,
+		HtmlIdea:       mark.HtmlIdea,
+		IsHighlight:    mark.IsHighlight,
+		KeyContent:     mark.KeyContent,
+		StyleId:        mark.StyleId,
+		Sort:           mark.Sort,
+		Page:           page,
+		CommentContent: string(commentContent),
+	}
+}
+
+// diffDocumentSections 按章节对比两份全文，章节顺序以新版本为准，被删除的章节排在最后
+func diffDocumentSections(from *parsedPb.FullDocument, to *parsedPb.FullDocument) *pb.DiffPaperVersionsResponse {
+	fromSections := groupDocumentSections(from)
+	toSections := groupDocumentSections(to)
+	fromByKey := make(map[string]*documentSection, len(fromSections))
+	for _, section := range fromSections {
+		fromByKey[section.key] = section
+	}
+
+	resp := &pb.DiffPaperVersionsResponse{}
+	matched := make(map[string]struct{}, len(toSections))
+	for _, toSection := range toSections {
+		fromSection, ok := fromByKey[toSection.key]
+		if !ok {
+			resp.Sections = append(resp.Sections, &pb.PaperSectionDiff{
+				Title:           toSection.title,
+				Status:          pb.PaperSectionDiffStatus_SECTION_ADDED,
+				AddedParagraphs: toSection.paragraphs,
+			})
+			resp.AddedCount++
+			continue
+		}
+		matched[toSection.key] = struct{}{}
+		diff := &pb.PaperSectionDiff{
+			Title:             toSection.title,
+			Status:            pb.PaperSectionDiffStatus_SECTION_UNCHANGED,
+			Similarity:        jaccardSimilarity(tokenizeText(fromSection.text()), tokenizeText(toSection.text())),
+			AddedParagraphs:   subtractParagraphs(toSection, fromSection),
+			RemovedParagraphs: subtractParagraphs(fromSection, toSection),
+		}
+		if len(diff.AddedParagraphs) > 0 || len(diff.RemovedParagraphs) > 0 {
+			diff.Status = pb.PaperSectionDiffStatus_SECTION_CHANGED
+			resp.ChangedCount++
+		}
+		resp.Sections = append(resp.Sections, diff)
+	}
+	for _, fromSection := range fromSections {
+		if _, ok := matched[fromSection.key]; ok {
+			continue
+		}
+		resp.Sections = append(resp.Sections, &pb.PaperSectionDiff{
+			Title:             fromSection.title,
+			Status:            pb.PaperSectionDiffStatus_SECTION_REMOVED,
+			RemovedParagraphs: fromSection.paragraphs,
+		})
+		resp.RemovedCount++
+	}
+	return resp
+}
+
+// subtractParagraphs 返回a中存在而b中不存在的段落，按归一化文本比较
+func subtractParagraphs(a *documentSection, b *documentSection) []string {
+	exists := make(map[string]struct{}, len(b.paragraphs))
+	for _, paragraph := range b.paragraphs {
+		exists[normalizeAnchorText(paragraph)] = struct{}{}
+	}
+	result := make([]string, 0)
+	for _, paragraph := range a.paragraphs {
+		if _, ok := exists[normalizeAnchorText(paragraph)]; !ok {
+			result = append(result, paragraph)
+		}
+	}
+	return result
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
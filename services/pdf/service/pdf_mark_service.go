@@ -165,6 +165,20 @@ func (s *PdfMarkService) GetCountPdfMarksByNoteId(ctx context.Context, noteId st
 	return count, nil
 }
 
+// GetCountPdfMarksByNoteIds 根据笔记Ids列表获取每个笔记的PDF标记总数
+func (s *PdfMarkService) GetCountPdfMarksByNoteIds(ctx context.Context, noteIds []string) (map[string]int64, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfMarkService.GetCountPdfMarksByNoteIds")
+	defer span.Finish()
+
+	counts, err := s.pdfMarkDAO.GetCountPdfMarksByNoteIdsWithoutIsHighlight(ctx, noteIds)
+	if err != nil {
+		s.logger.Error("获取PDF标记失败", "error", err)
+		return nil, errors.Biz("pdf.pdf_mark.errors.get_failed")
+	}
+
+	return counts, nil
+}
+
 // GetAnnotationRawModelsByNoteId 根据ID获取PDF标记
 func (s *PdfMarkService) GetAnnotationRawModelsByNoteId(ctx context.Context, noteId string) ([]*notePb.AnnotationRawModel, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfMarkService.GetAnnotationRawModelsByNoteId")
@@ -296,6 +310,23 @@ func (s *PdfMarkService) DeleteByAnnotationPointer(ctx context.Context, annotati
 	defer span.Finish()
 
 	// 新版本删除不用处理group相关的区分，已经去除group分组概念
+	// 0.记录到回收站，保存随标记一起删除的标签关系
+	mark, err := s.pdfMarkDAO.GetPdfMarkByID(ctx, annotationPointer.Id)
+	if err != nil {
+		s.logger.Error("删除PDF标记Annotation", "error", err)
+		return false, errors.Biz("pdf.pdf_mark.errors.delete_failed")
+	}
+	if mark != nil {
+		relations, err := s.pdfMarkTagRelationService.GetByMarkId(ctx, mark.Id)
+		if err != nil {
+			return false, err
+		}
+		if err := recordPdfMarkTrash(ctx, mark, relations); err != nil {
+			s.logger.Error("删除PDF标记Annotation，记录回收站失败", "error", err)
+			return false, errors.Biz("pdf.pdf_mark.errors.delete_failed")
+		}
+	}
+
 	// 1.删除PDF标记
 	if err := s.pdfMarkDAO.DeleteById(ctx, annotationPointer.Id); err != nil {
 		s.logger.Error("删除PDF标记Annotation", "error", err)
@@ -305,7 +336,7 @@ func (s *PdfMarkService) DeleteByAnnotationPointer(ctx context.Context, annotati
 	// 2.markUnusedCdnUrl(id); TODO
 
 	// 3.删除mark tag relation关系
-	_, err := s.DeleteMarkTagRelationsById(ctx, annotationPointer.Id)
+	_, err = s.DeleteMarkTagRelationsById(ctx, annotationPointer.Id)
 	if err != nil {
 		s.logger.Error("删除PDF标记Annotation", "error", err)
 		return false, errors.Biz("pdf.pdf_mark.errors.delete_failed")
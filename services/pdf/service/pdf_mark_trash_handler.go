@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/trash"
+	"github.com/yb2020/odoc/services/pdf/dao"
+	"github.com/yb2020/odoc/services/pdf/model"
+)
+
+// pdfMarkTrashSnapshot 删除PDF标注时保存的关联数据
+type pdfMarkTrashSnapshot struct {
+	TagRelationIds []string `json:"tagRelationIds"` // 随标注一起逻辑删除的标签关系
+}
+
+// recordPdfMarkTrash 把删除的PDF标注记录到回收站
+func recordPdfMarkTrash(ctx context.Context, mark *model.PdfMark, relations []model.PdfMarkTagRelation) error {
+	snapshot := &pdfMarkTrashSnapshot{}
+	for _, r := range relations {
+		snapshot.TagRelationIds = append(snapshot.TagRelationIds, r.Id)
+	}
+	title := mark.KeyContent
+	if title == "" {
+		title = mark.Idea
+	}
+	return trash.Record(ctx, &trash.Item{
+		UserId:   mark.CreatorId,
+		Type:     trash.TypeMark,
+		ItemId:   mark.Id,
+		Title:    title,
+		Snapshot: snapshot,
+	})
+}
+
+// recordDocTextMarkTrash 把删除的文档文本标注记录到回收站
+func recordDocTextMarkTrash(ctx context.Context, mark *model.DocTextMark) error {
+	return trash.Record(ctx, &trash.Item{
+		UserId: mark.CreatorId,
+		Type:   trash.TypeTextMark,
+		ItemId: mark.Id,
+		Title:  mark.Exact,
+	})
+}
+
+// PdfMarkTrashHandler 回收站中PDF标注的恢复和彻底删除
+type PdfMarkTrashHandler struct {
+	logger                logging.Logger
+	tracer                opentracing.Tracer
+	pdfMarkDAO            *dao.PdfMarkDAO
+	pdfMarkTagDAO         *dao.PdfMarkTagDAO
+	pdfMarkTagRelationDAO *dao.PdfMarkTagRelationDAO
+}
+
+// NewPdfMarkTrashHandler 创建PDF标注回收站处理器
+func NewPdfMarkTrashHandler(logger logging.Logger, tracer opentracing.Tracer,
+	pdfMarkDAO *dao.PdfMarkDAO,
+	pdfMarkTagDAO *dao.PdfMarkTagDAO,
+	pdfMarkTagRelationDAO *dao.PdfMarkTagRelationDAO,
+) *PdfMarkTrashHandler {
+	return &PdfMarkTrashHandler{
+		logger:                logger,
+		tracer:                tracer,
+		pdfMarkDAO:            pdfMarkDAO,
+		pdfMarkTagDAO:         pdfMarkTagDAO,
+		pdfMarkTagRelationDAO: pdfMarkTagRelationDAO,
+	}
+}
+
+// Type 处理的条目类型
+func (h *PdfMarkTrashHandler) Type() string {
+	return trash.TypeMark
+}
+
+// Restore 恢复PDF标注，以及标签仍然存在的标签关系
+func (h *PdfMarkTrashHandler) Restore(ctx context.Context, entry *trash.Entry) (string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, h.tracer, "PdfMarkTrashHandler.Restore")
+	defer span.Finish()
+
+	var snapshot pdfMarkTrashSnapshot
+	if err := entry.DecodeSnapshot(&snapshot); err != nil {
+		return "", err
+	}
+	if err := h.pdfMarkDAO.RestoreByIds(ctx, []string{entry.ItemId}); err != nil {
+		return "", err
+	}
+	if len(snapshot.TagRelationIds) == 0 {
+		return entry.Title, nil
+	}
+	relations, err := h.pdfMarkTagRelationDAO.FindByIds(ctx, snapshot.TagRelationIds)
+	if err != nil {
+		return "", err
+	}
+	restoreIds := make([]string, 0, len(relations))
+	for _, r := range relations {
+		if r.MarkId != entry.ItemId {
+			continue
+		}
+		tag, err := h.pdfMarkTagDAO.FindExistById(ctx, r.TagId)
+		if err != nil {
+			return "", err
+		}
+		if tag != nil {
+			restoreIds = append(restoreIds, r.Id)
+		}
+	}
+	if len(restoreIds) > 0 {
+		if err := h.pdfMarkTagRelationDAO.RestoreByIds(ctx, restoreIds); err != nil {
+			return "", err
+		}
+	}
+	return entry.Title, nil
+}
+
+// Purge 物理删除PDF标注及随其删除的标签关系
+func (h *PdfMarkTrashHandler) Purge(ctx context.Context, entry *trash.Entry) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, h.tracer, "PdfMarkTrashHandler.Purge")
+	defer span.Finish()
+
+	mark, err := h.pdfMarkDAO.FindById(ctx, entry.ItemId)
+	if err != nil {
+		return err
+	}
+	// 已被其他途径恢复或删除
+	if mark == nil || !mark.IsDeleted {
+		return nil
+	}
+	var snapshot pdfMarkTrashSnapshot
+	if err := entry.DecodeSnapshot(&snapshot); err != nil {
+		return err
+	}
+	if len(snapshot.TagRelationIds) > 0 {
+		relations, err := h.pdfMarkTagRelationDAO.FindByIds(ctx, snapshot.TagRelationIds)
+		if err != nil {
+			return err
+		}
+		for _, r := range relations {
+			if r.MarkId != mark.Id || !r.IsDeleted {
+				continue
+			}
+			if err := h.pdfMarkTagRelationDAO.RemoveById(ctx, r.Id); err != nil {
+				return err
+			}
+		}
+	}
+	return h.pdfMarkDAO.RemoveById(ctx, mark.Id)
+}
+
+// DocTextMarkTrashHandler 回收站中文档文本标注的恢复和彻底删除
+type DocTextMarkTrashHandler struct {
+	logger         logging.Logger
+	tracer         opentracing.Tracer
+	docTextMarkDAO *dao.DocTextMarkDAO
+}
+
+// NewDocTextMarkTrashHandler 创建文档文本标注回收站处理器
+func NewDocTextMarkTrashHandler(logger logging.Logger, tracer opentracing.Tracer, docTextMarkDAO *dao.DocTextMarkDAO) *DocTextMarkTrashHandler {
+	return &DocTextMarkTrashHandler{
+		logger:         logger,
+		tracer:         tracer,
+		docTextMarkDAO: docTextMarkDAO,
+	}
+}
+
+// Type 处理的条目类型
+func (h *DocTextMarkTrashHandler) Type() string {
+	return trash.TypeTextMark
+}
+
+// Restore 恢复文档文本标注，偏移在查询时按当前文档内容重新定位
+func (h *DocTextMarkTrashHandler) Restore(ctx context.Context, entry *trash.Entry) (string, error) {
+	if err := h.docTextMarkDAO.RestoreByIds(ctx, []string{entry.ItemId}); err != nil {
+		return "", err
+	}
+	return entry.Title, nil
+}
+
+// Purge 物理删除文档文本标注
+func (h *DocTextMarkTrashHandler) Purge(ctx context.Context, entry *trash.Entry) error {
+	mark, err := h.docTextMarkDAO.FindById(ctx, entry.ItemId)
+	if err != nil {
+		return err
+	}
+	if mark == nil || !mark.IsDeleted {
+		return nil
+	}
+	return h.docTextMarkDAO.RemoveById(ctx, mark.Id)
+}
@@ -459,6 +459,9 @@ func (s *PdfParseService) GetFigureAndTable(ctx context.Context, pdfId string) (
 	if err != nil {
 		return nil, err
 	}
+	if paperPdfParsed == nil || paperPdfParsed.RecordsJson == "" {
+		return nil, nil
+	}
 	var imageRecords []*parsedPb.ImageRecord
 	err = json.Unmarshal([]byte(paperPdfParsed.RecordsJson), &imageRecords)
 	if err != nil {
@@ -473,6 +476,9 @@ func (s *PdfParseService) GetPdfFullDocument(ctx context.Context, pdfId string)
 	if err != nil {
 		return nil, err
 	}
+	if paperPdfParsed == nil || paperPdfParsed.ObjectKey == "" {
+		return nil, nil
+	}
 
 	//下载oss文件并返回byte[]
 	bucketType := ossConstant.BucketTypeToEnum(s.config, paperPdfParsed.BucketName)
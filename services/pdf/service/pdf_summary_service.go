@@ -88,3 +88,11 @@ func (s *PdfSummaryService) GetBySourceFromAndFileSHA256AndVersionAndLang(ctx co
 	// 获取PDF摘要
 	return s.pdfSummaryDAO.FindExistBySourceFromAndFileSHA256AndVersionAndLang(ctx, sourceFrom, fileSHA256, version, lang)
 }
+
+// GetBySourceFromAndFileSHA256AndVersionAndLangAndTemplate 根据source_from,file_sha256,version,lang,template获取结构化PDF摘要
+func (s *PdfSummaryService) GetBySourceFromAndFileSHA256AndVersionAndLangAndTemplate(ctx context.Context, sourceFrom, fileSHA256, version, lang, template string) (*model.PdfSummary, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfSummaryService.GetBySourceFromAndFileSHA256AndVersionAndLangAndTemplate")
+	defer span.Finish()
+
+	return s.pdfSummaryDAO.FindExistBySourceFromAndFileSHA256AndVersionAndLangAndTemplate(ctx, sourceFrom, fileSHA256, version, lang, template)
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/takeout"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	noteInterfaces "github.com/yb2020/odoc/services/note/interfaces"
+	ossConstant "github.com/yb2020/odoc/services/oss/constant"
+	ossService "github.com/yb2020/odoc/services/oss/service"
+	"github.com/yb2020/odoc/services/pdf/dao"
+	"github.com/yb2020/odoc/services/pdf/model"
+)
+
+// 编译时类型检查：确保 PdfTakeoutExporter 实现了 takeout.Exporter 接口
+var _ takeout.Exporter = (*PdfTakeoutExporter)(nil)
+
+// PdfTakeoutExporter 导出用户文献的原始文件、PDF标注和标注标签
+type PdfTakeoutExporter struct {
+	config                *config.Config
+	logger                logging.Logger
+	tracer                opentracing.Tracer
+	paperPdfDAO           *dao.PaperPDFDAO
+	pdfMarkDAO            *dao.PdfMarkDAO
+	pdfMarkTagDAO         *dao.PdfMarkTagDAO
+	pdfMarkTagRelationDAO *dao.PdfMarkTagRelationDAO
+	userDocService        *docService.UserDocService
+	paperNoteService      noteInterfaces.IPaperNoteService
+	ossService            ossService.OssServiceInterface
+}
+
+// NewPdfTakeoutExporter 创建PDF数据导出器
+func NewPdfTakeoutExporter(config *config.Config, logger logging.Logger, tracer opentracing.Tracer,
+	paperPdfDAO *dao.PaperPDFDAO,
+	pdfMarkDAO *dao.PdfMarkDAO,
+	pdfMarkTagDAO *dao.PdfMarkTagDAO,
+	pdfMarkTagRelationDAO *dao.PdfMarkTagRelationDAO,
+	userDocService *docService.UserDocService,
+	paperNoteService noteInterfaces.IPaperNoteService,
+	ossService ossService.OssServiceInterface,
+) *PdfTakeoutExporter {
+	return &PdfTakeoutExporter{
+		config:                config,
+		logger:                logger,
+		tracer:                tracer,
+		paperPdfDAO:           paperPdfDAO,
+		pdfMarkDAO:            pdfMarkDAO,
+		pdfMarkTagDAO:         pdfMarkTagDAO,
+		pdfMarkTagRelationDAO: pdfMarkTagRelationDAO,
+		userDocService:        userDocService,
+		paperNoteService:      paperNoteService,
+		ossService:            ossService,
+	}
+}
+
+// Name 导出器名称
+func (e *PdfTakeoutExporter) Name() string {
+	return "pdf"
+}
+
+// Export 导出用户的原始文件和标注，单个文件下载失败时记录到清单后继续
+func (e *PdfTakeoutExporter) Export(ctx context.Context, userId string, w *takeout.Writer) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, e.tracer, "PdfTakeoutExporter.Export")
+	defer span.Finish()
+
+	userDocs, err := e.userDocService.GetAllByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	for i := range userDocs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		userDoc := &userDocs[i]
+		if userDoc.PdfId == "" || userDoc.PdfId == "0" {
+			continue
+		}
+		pdf, err := e.paperPdfDAO.GetPaperPDFByID(ctx, userDoc.PdfId)
+		if err != nil {
+			return err
+		}
+		if pdf == nil || pdf.OssObjectKey == "" {
+			w.Skip("files/"+userDoc.Id, "file not found")
+			continue
+		}
+		// 文件名带上文献ID，便于与 doc/docs.json 对应
+		ext := fileExtension(pdf)
+		docName := strings.TrimSuffix(userDoc.DocName, ext)
+		e.exportFile(ctx, w, pdf, "files/"+userDoc.Id+"_"+takeout.SafeName(docName)+ext)
+	}
+
+	notes, err := e.paperNoteService.GetAllNoteByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	noteIds := make([]string, 0, len(notes))
+	for _, note := range notes {
+		noteIds = append(noteIds, note.Id)
+	}
+	marks := []model.PdfMark{}
+	if len(noteIds) > 0 {
+		if marks, err = e.pdfMarkDAO.GetPdfMarksByNoteIds(ctx, userId, noteIds); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteJSON("marks.json", marks); err != nil {
+		return err
+	}
+
+	tags, err := e.pdfMarkTagDAO.GetTagsByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("mark_tags.json", tags); err != nil {
+		return err
+	}
+	markIds := make([]string, 0, len(marks))
+	for _, mark := range marks {
+		markIds = append(markIds, mark.Id)
+	}
+	tagRelations := []model.PdfMarkTagRelation{}
+	if len(markIds) > 0 {
+		if tagRelations, err = e.pdfMarkTagRelationDAO.GetByMarkIds(ctx, markIds); err != nil {
+			return err
+		}
+	}
+	return w.WriteJSON("mark_tag_relations.json", tagRelations)
+}
+
+// exportFile 从对象存储流式写入原始文件
+func (e *PdfTakeoutExporter) exportFile(ctx context.Context, w *takeout.Writer, pdf *model.PaperPdf, name string) {
+	reader, err := e.ossService.DownloadObject(ctx, ossConstant.BucketTypeToEnum(e.config, pdf.OssBucketName), pdf.OssObjectKey)
+	if err != nil {
+		e.logger.Warn("msg", "导出用户数据时下载原始文件失败", "pdfId", pdf.Id, "objectKey", pdf.OssObjectKey, "error", err.Error())
+		w.Skip(name, err.Error())
+		return
+	}
+	defer reader.Close()
+	if err := w.WriteFile(name, reader); err != nil {
+		e.logger.Warn("msg", "导出用户数据时写入原始文件失败", "pdfId", pdf.Id, "error", err.Error())
+	}
+}
+
+// fileExtension 根据文件格式返回扩展名，格式为空时视为pdf
+func fileExtension(pdf *model.PaperPdf) string {
+	if ext := path.Ext(pdf.OssObjectKey); ext != "" {
+		return strings.ToLower(ext)
+	}
+	if pdf.FileFormat == "" {
+		return ".pdf"
+	}
+	return "." + pdf.FileFormat
+}
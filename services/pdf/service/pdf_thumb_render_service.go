@@ -0,0 +1,575 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/pdfrender"
+	parsedPb "github.com/yb2020/odoc/proto/gen/go/parsed"
+	ossConstant "github.com/yb2020/odoc/services/oss/constant"
+	ossService "github.com/yb2020/odoc/services/oss/service"
+	parseConstant "github.com/yb2020/odoc/services/parse/constant"
+	"github.com/yb2020/odoc/services/pdf/dao"
+	"github.com/yb2020/odoc/services/pdf/model"
+)
+
+const (
+	thumbContentTypeJPEG = "image/jpeg"
+	thumbContentTypePNG  = "image/png"
+
+	// 缩略图在解析结果目录下的子目录
+	thumbCatalog = "thumbs"
+
+	// 失败原因的最大长度，与表字段长度一致
+	thumbMaxErrorMessageLen = 500
+)
+
+// 图表ID中不能直接用作对象名称的字符
+var thumbObjectNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// thumbRenderOptions 缩略图渲染参数，未配置的项使用默认值
+type thumbRenderOptions struct {
+	concurrency int
+	batchSize   int
+	lookback    time.Duration
+	coverWidth  int
+	pageWidth   int
+	maxPages    int
+	figureWidth int
+	jpegQuality int
+	cacheMaxAge int
+}
+
+func newThumbRenderOptions(cfg *config.Config) thumbRenderOptions {
+	thumbCfg := cfg.PDF.Thumb
+	options := thumbRenderOptions{
+		concurrency: thumbCfg.Concurrency,
+		batchSize:   thumbCfg.BatchSize,
+		lookback:    time.Duration(thumbCfg.LookbackHours) * time.Hour,
+		coverWidth:  thumbCfg.CoverWidth,
+		pageWidth:   thumbCfg.PageWidth,
+		maxPages:    thumbCfg.MaxPages,
+		figureWidth: thumbCfg.FigureWidth,
+		jpegQuality: thumbCfg.JpegQuality,
+		cacheMaxAge: thumbCfg.CacheMaxAge,
+	}
+	if options.concurrency <= 0 {
+		options.concurrency = 2
+	}
+	if options.batchSize <= 0 {
+		options.batchSize = 20
+	}
+	if options.lookback <= 0 {
+		options.lookback = 72 * time.Hour
+	}
+	if options.coverWidth <= 0 {
+		options.coverWidth = 480
+	}
+	if options.pageWidth <= 0 {
+		options.pageWidth = 200
+	}
+	if options.maxPages <= 0 {
+		options.maxPages = 50
+	}
+	if options.figureWidth <= 0 {
+		options.figureWidth = 1600
+	}
+	if options.jpegQuality <= 0 || options.jpegQuality > 100 {
+		options.jpegQuality = 80
+	}
+	if options.cacheMaxAge <= 0 {
+		options.cacheMaxAge = 86400
+	}
+	return options
+}
+
+// PdfThumbImage 缩略图或图表截图的图片内容
+type PdfThumbImage struct {
+	ObjectKey   string
+	ContentType string
+	Data        []byte
+}
+
+// PdfThumbRenderService 渲染PDF页面缩略图与图表截图，结果存放在PDF解析结果目录下
+type PdfThumbRenderService struct {
+	config          *config.Config
+	logger          logging.Logger
+	tracer          opentracing.Tracer
+	paperPdfDAO     *dao.PaperPDFDAO
+	pdfThumbDAO     *dao.PdfThumbDAO
+	paperPdfService *PaperPdfService
+	pdfParseService *PdfParseService
+	ossService      ossService.OssServiceInterface
+	options         thumbRenderOptions
+
+	// 正在渲染的PDF，避免任务与按需裁剪重复处理同一份PDF
+	renderingMu sync.Mutex
+	rendering   map[string]struct{}
+}
+
+// NewPdfThumbRenderService 创建PDF缩略图渲染服务
+func NewPdfThumbRenderService(
+	cfg *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	paperPdfDAO *dao.PaperPDFDAO,
+	pdfThumbDAO *dao.PdfThumbDAO,
+	paperPdfService *PaperPdfService,
+	pdfParseService *PdfParseService,
+	ossService ossService.OssServiceInterface,
+) *PdfThumbRenderService {
+	return &PdfThumbRenderService{
+		config:          cfg,
+		logger:          logger,
+		tracer:          tracer,
+		paperPdfDAO:     paperPdfDAO,
+		pdfThumbDAO:     pdfThumbDAO,
+		paperPdfService: paperPdfService,
+		pdfParseService: pdfParseService,
+		ossService:      ossService,
+		options:         newThumbRenderOptions(cfg),
+		rendering:       make(map[string]struct{}),
+	}
+}
+
+// CacheMaxAge 图片响应的浏览器缓存时间，单位：秒
+func (s *PdfThumbRenderService) CacheMaxAge() int {
+	return s.options.cacheMaxAge
+}
+
+// RenderPendingThumbs 为最近上传、尚未生成封面的PDF渲染缩略图，同时渲染的PDF数量受并发数限制
+func (s *PdfThumbRenderService) RenderPendingThumbs(ctx context.Context) (rendered int, failed int, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfThumbRenderService.RenderPendingThumbs")
+	defer span.Finish()
+
+	pdfs, err := s.paperPdfDAO.ListPdfsWithoutCoverThumb(ctx, time.Now().Add(-s.options.lookback), s.options.batchSize)
+	if err != nil {
+		return 0, 0, errors.Biz("pdf.pdf_thumb.errors.list_failed")
+	}
+	if len(pdfs) == 0 {
+		return 0, 0, nil
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, s.options.concurrency)
+	for i := range pdfs {
+		pdf := &pdfs[i]
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			renderErr := s.RenderPdfThumbs(ctx, pdf)
+			mu.Lock()
+			defer mu.Unlock()
+			if renderErr != nil {
+				failed++
+				return
+			}
+			rendered++
+		}()
+	}
+	wg.Wait()
+	return rendered, failed, nil
+}
+
+// RenderPdfThumbs 渲染PDF的封面、页面缩略图，并为没有MinerU图片的图表裁剪截图
+// 封面渲染失败时记录失败状态，任务不再重复处理该PDF
+func (s *PdfThumbRenderService) RenderPdfThumbs(ctx context.Context, pdf *model.PaperPdf) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfThumbRenderService.RenderPdfThumbs")
+	defer span.Finish()
+
+	if !s.tryStartRendering(pdf.Id) {
+		return nil
+	}
+	defer s.finishRendering(pdf.Id)
+
+	data, err := s.downloadPdf(ctx, pdf)
+	if err != nil {
+		// 下载失败可能是临时故障，不记录失败状态，下次任务重试
+		return err
+	}
+	doc, err := pdfrender.Open(data)
+	if err != nil {
+		s.logger.Warn("msg", "打开PDF失败", "pdfId", pdf.Id, "error", err.Error())
+		s.saveFailedCover(ctx, pdf, err)
+		return err
+	}
+	if err := s.pdfThumbDAO.DeleteByPdfIdAndKinds(ctx, pdf.Id, []string{model.PdfThumbKindCover, model.PdfThumbKindPage}); err != nil {
+		return errors.Biz("pdf.pdf_thumb.errors.delete_failed")
+	}
+
+	cover, err := doc.RenderPage(1, s.options.coverWidth)
+	if err != nil {
+		s.logger.Warn("msg", "渲染PDF封面失败", "pdfId", pdf.Id, "error", err.Error())
+		s.saveFailedCover(ctx, pdf, err)
+		return err
+	}
+	if err := s.saveJPEG(ctx, pdf, model.PdfThumbKindCover, 0, "cover", cover); err != nil {
+		return err
+	}
+
+	pageCount := doc.PageCount()
+	if pageCount > s.options.maxPages {
+		pageCount = s.options.maxPages
+	}
+	for pageNum := 1; pageNum <= pageCount; pageNum++ {
+		var page image.Image
+		if pageNum == 1 {
+			page = pdfrender.Scale(cover, s.options.pageWidth)
+		} else {
+			page, err = doc.RenderPage(pageNum, s.options.pageWidth)
+			if err != nil {
+				// 单页渲染失败不影响其他页面
+				s.logger.Warn("msg", "渲染PDF页面缩略图失败", "pdfId", pdf.Id, "pageNum", pageNum, "error", err.Error())
+				continue
+			}
+		}
+		if err := s.saveJPEG(ctx, pdf, model.PdfThumbKindPage, pageNum, fmt.Sprintf("page_%d", pageNum), page); err != nil {
+			return err
+		}
+	}
+
+	if err := s.renderFigureSnapshots(ctx, pdf, doc); err != nil {
+		// 图表截图可以在请求时按需生成，这里只记录日志
+		s.logger.Warn("msg", "裁剪PDF图表截图失败", "pdfId", pdf.Id, "error", err.Error())
+	}
+	s.logger.Info("msg", "生成PDF缩略图成功", "pdfId", pdf.Id, "pages", pageCount)
+	return nil
+}
+
+// ListPdfThumbs 获取PDF已生成的缩略图与图表截图
+func (s *PdfThumbRenderService) ListPdfThumbs(ctx context.Context, pdfId string, userId string) ([]model.PdfThumb, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfThumbRenderService.ListPdfThumbs")
+	defer span.Finish()
+
+	if _, err := s.getPermittedPdf(ctx, pdfId, userId); err != nil {
+		return nil, err
+	}
+	thumbs, err := s.pdfThumbDAO.ListByPdfId(ctx, pdfId)
+	if err != nil {
+		return nil, errors.Biz("pdf.pdf_thumb.errors.list_failed")
+	}
+	result := make([]model.PdfThumb, 0, len(thumbs))
+	for _, thumb := range thumbs {
+		if thumb.Status == model.PdfThumbStatusDone {
+			result = append(result, thumb)
+		}
+	}
+	return result, nil
+}
+
+// GetPageThumbImage 获取页面缩略图，pageNum为0时获取封面
+func (s *PdfThumbRenderService) GetPageThumbImage(ctx context.Context, pdfId string, userId string, pageNum int) (*PdfThumbImage, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfThumbRenderService.GetPageThumbImage")
+	defer span.Finish()
+
+	if pageNum < 0 {
+		return nil, errors.Biz("pdf.pdf_thumb.errors.invalid_page")
+	}
+	if _, err := s.getPermittedPdf(ctx, pdfId, userId); err != nil {
+		return nil, err
+	}
+	kind := model.PdfThumbKindPage
+	if pageNum == 0 {
+		kind = model.PdfThumbKindCover
+	}
+	thumb, err := s.pdfThumbDAO.GetByPdfIdAndKindAndPage(ctx, pdfId, kind, pageNum)
+	if err != nil {
+		return nil, errors.Biz("pdf.pdf_thumb.errors.get_failed")
+	}
+	if thumb == nil || thumb.Status != model.PdfThumbStatusDone {
+		return nil, errors.Biz("pdf.pdf_thumb.errors.not_found")
+	}
+	return s.downloadThumb(ctx, thumb.BucketName, thumb.ObjectKey, thumb.ContentType)
+}
+
+// GetFigureImage 获取图表截图，优先使用MinerU解析出的图片，没有时从页面中裁剪并保存
+func (s *PdfThumbRenderService) GetFigureImage(ctx context.Context, pdfId string, userId string, figureId string) (*PdfThumbImage, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "PdfThumbRenderService.GetFigureImage")
+	defer span.Finish()
+
+	pdf, err := s.getPermittedPdf(ctx, pdfId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	imageRecords, err := s.pdfParseService.GetFigureAndTable(ctx, pdfId)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range imageRecords {
+		if record.Id == figureId && record.ObjectKey != "" {
+			return s.downloadThumb(ctx, record.BucketName, record.ObjectKey, thumbContentTypeJPEG)
+		}
+	}
+
+	thumb, err := s.pdfThumbDAO.GetByPdfIdAndFigureId(ctx, pdfId, figureId)
+	if err != nil {
+		return nil, errors.Biz("pdf.pdf_thumb.errors.get_failed")
+	}
+	if thumb == nil {
+		thumb, err = s.cropFigureOnDemand(ctx, pdf, figureId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if thumb.Status != model.PdfThumbStatusDone {
+		return nil, errors.Biz("pdf.pdf_thumb.errors.not_found")
+	}
+	return s.downloadThumb(ctx, thumb.BucketName, thumb.ObjectKey, thumb.ContentType)
+}
+
+// cropFigureOnDemand 请求时图表截图尚未生成（如缩略图生成时PDF还未解析完成），从页面中裁剪
+func (s *PdfThumbRenderService) cropFigureOnDemand(ctx context.Context, pdf *model.PaperPdf, figureId string) (*model.PdfThumb, error) {
+	metadata, err := s.pdfParseService.GetPdfMetadata(ctx, pdf.Id)
+	if err != nil {
+		return nil, err
+	}
+	var figure *parsedPb.FigureTable
+	if metadata != nil {
+		for _, item := range metadata.FiguresAndTables {
+			if item.Id == figureId {
+				figure = item
+				break
+			}
+		}
+	}
+	if figure == nil || figure.Bbox == nil {
+		return nil, errors.Biz("pdf.pdf_thumb.errors.not_found")
+	}
+
+	doc, err := s.openPdf(ctx, pdf)
+	if err != nil {
+		return nil, errors.Biz("pdf.pdf_thumb.errors.render_failed")
+	}
+	pages := make(map[int]image.Image)
+	thumb, err := s.cropFigure(ctx, pdf, doc, figure, pages)
+	if err != nil {
+		s.logger.Warn("msg", "裁剪PDF图表截图失败", "pdfId", pdf.Id, "figureId", figureId, "error", err.Error())
+		return nil, errors.Biz("pdf.pdf_thumb.errors.render_failed")
+	}
+	return thumb, nil
+}
+
+// renderFigureSnapshots 为解析结果中没有MinerU图片、也没有截图的图表裁剪截图，PDF未解析时跳过
+func (s *PdfThumbRenderService) renderFigureSnapshots(ctx context.Context, pdf *model.PaperPdf, doc *pdfrender.Document) error {
+	metadata, err := s.pdfParseService.GetPdfMetadata(ctx, pdf.Id)
+	if err != nil {
+		return err
+	}
+	if metadata == nil || len(metadata.FiguresAndTables) == 0 {
+		return nil
+	}
+	imageRecords, err := s.pdfParseService.GetFigureAndTable(ctx, pdf.Id)
+	if err != nil {
+		return err
+	}
+	hasImage := make(map[string]struct{}, len(imageRecords))
+	for _, record := range imageRecords {
+		if record.ObjectKey != "" {
+			hasImage[record.Id] = struct{}{}
+		}
+	}
+
+	// 同一页的多个图表共用一次渲染结果
+	pages := make(map[int]image.Image)
+	for _, figure := range metadata.FiguresAndTables {
+		if figure.Id == "" || figure.Bbox == nil {
+			continue
+		}
+		if _, ok := hasImage[figure.Id]; ok {
+			continue
+		}
+		existing, err := s.pdfThumbDAO.GetByPdfIdAndFigureId(ctx, pdf.Id, figure.Id)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			continue
+		}
+		if _, err := s.cropFigure(ctx, pdf, doc, figure, pages); err != nil {
+			s.logger.Warn("msg", "裁剪PDF图表截图失败", "pdfId", pdf.Id, "figureId", figure.Id, "error", err.Error())
+		}
+	}
+	return nil
+}
+
+// cropFigure 按图表边界框裁剪页面并保存为PNG截图
+func (s *PdfThumbRenderService) cropFigure(ctx context.Context, pdf *model.PaperPdf, doc *pdfrender.Document, figure *parsedPb.FigureTable, pages map[int]image.Image) (*model.PdfThumb, error) {
+	bbox := figure.Bbox
+	pageNum := int(bbox.PageNumber)
+	page, ok := pages[pageNum]
+	if !ok {
+		var err error
+		page, err = doc.RenderPage(pageNum, s.options.figureWidth)
+		if err != nil {
+			return nil, err
+		}
+		pages[pageNum] = page
+	}
+	cropped, err := pdfrender.Crop(page, bbox.X0, bbox.Y0, bbox.X1, bbox.Y1, bbox.OriginWidth, bbox.OriginHeight)
+	if err != nil {
+		return nil, err
+	}
+	data, err := pdfrender.EncodePNG(cropped)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := model.PdfThumbKindFigure
+	if figure.Type == parseConstant.PdfOssTypeTable {
+		kind = model.PdfThumbKindTable
+	}
+	name := "figure_" + thumbObjectNameUnsafe.ReplaceAllString(figure.Id, "_")
+	thumb := &model.PdfThumb{
+		PaperId:  pdf.PaperId,
+		PdfId:    pdf.Id,
+		Kind:     kind,
+		PageNum:  pageNum,
+		FigureId: figure.Id,
+		RefIdx:   figure.RefIdx,
+		Width:    cropped.Bounds().Dx(),
+		Height:   cropped.Bounds().Dy(),
+	}
+	if err := s.uploadAndSave(ctx, pdf, thumb, name+".png", thumbContentTypePNG, data); err != nil {
+		return nil, err
+	}
+	return thumb, nil
+}
+
+// saveJPEG 将页面图片编码为JPEG并保存
+func (s *PdfThumbRenderService) saveJPEG(ctx context.Context, pdf *model.PaperPdf, kind string, pageNum int, name string, img image.Image) error {
+	data, err := pdfrender.EncodeJPEG(img, s.options.jpegQuality)
+	if err != nil {
+		s.logger.Error("msg", "编码缩略图失败", "pdfId", pdf.Id, "kind", kind, "pageNum", pageNum, "error", err.Error())
+		return errors.Biz("pdf.pdf_thumb.errors.render_failed")
+	}
+	thumb := &model.PdfThumb{
+		PaperId: pdf.PaperId,
+		PdfId:   pdf.Id,
+		Kind:    kind,
+		PageNum: pageNum,
+		Width:   img.Bounds().Dx(),
+		Height:  img.Bounds().Dy(),
+	}
+	return s.uploadAndSave(ctx, pdf, thumb, name+".jpg", thumbContentTypeJPEG, data)
+}
+
+// uploadAndSave 上传图片到PDF解析结果目录并保存缩略图记录
+func (s *PdfThumbRenderService) uploadAndSave(ctx context.Context, pdf *model.PaperPdf, thumb *model.PdfThumb, fileName string, contentType string, data []byte) error {
+	objectKey := fmt.Sprintf("%s/%s/%s/%s/%s", pdf.FileSHA256, parseConstant.ParsedPdfCatalog, parseConstant.ParseVersion, thumbCatalog, fileName)
+	bucketType := ossConstant.BucketTypeToEnum(s.config, pdf.OssBucketName)
+	if err := s.ossService.UploadObject(ctx, bucketType, objectKey, bytes.NewReader(data), int64(len(data)), contentType, nil); err != nil {
+		s.logger.Error("msg", "上传缩略图失败", "pdfId", pdf.Id, "objectKey", objectKey, "error", err.Error())
+		return errors.Biz("pdf.pdf_thumb.errors.upload_failed")
+	}
+	thumb.BucketName = pdf.OssBucketName
+	thumb.ObjectKey = objectKey
+	thumb.ContentType = contentType
+	thumb.Status = model.PdfThumbStatusDone
+	if err := s.pdfThumbDAO.Create(ctx, thumb); err != nil {
+		s.logger.Error("msg", "保存缩略图记录失败", "pdfId", pdf.Id, "objectKey", objectKey, "error", err.Error())
+		return errors.Biz("pdf.pdf_thumb.errors.create_failed")
+	}
+	return nil
+}
+
+// saveFailedCover 记录封面生成失败，避免后台任务反复处理无法渲染的PDF
+func (s *PdfThumbRenderService) saveFailedCover(ctx context.Context, pdf *model.PaperPdf, cause error) {
+	message := cause.Error()
+	if len(message) > thumbMaxErrorMessageLen {
+		message = message[:thumbMaxErrorMessageLen]
+	}
+	thumb := &model.PdfThumb{
+		PaperId:      pdf.PaperId,
+		PdfId:        pdf.Id,
+		Kind:         model.PdfThumbKindCover,
+		Status:       model.PdfThumbStatusFailed,
+		ErrorMessage: message,
+	}
+	if err := s.pdfThumbDAO.Create(ctx, thumb); err != nil {
+		s.logger.Error("msg", "保存缩略图失败记录失败", "pdfId", pdf.Id, "error", err.Error())
+	}
+}
+
+// downloadPdf 下载PDF文件内容
+func (s *PdfThumbRenderService) downloadPdf(ctx context.Context, pdf *model.PaperPdf) ([]byte, error) {
+	bucketType := ossConstant.BucketTypeToEnum(s.config, pdf.OssBucketName)
+	data, err := s.ossService.DownloadObjectAsBytes(ctx, bucketType, pdf.OssObjectKey)
+	if err != nil {
+		s.logger.Error("msg", "下载PDF失败", "pdfId", pdf.Id, "objectKey", pdf.OssObjectKey, "error", err.Error())
+		return nil, err
+	}
+	return data, nil
+}
+
+// openPdf 下载并打开PDF文件
+func (s *PdfThumbRenderService) openPdf(ctx context.Context, pdf *model.PaperPdf) (*pdfrender.Document, error) {
+	data, err := s.downloadPdf(ctx, pdf)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := pdfrender.Open(data)
+	if err != nil {
+		s.logger.Warn("msg", "打开PDF失败", "pdfId", pdf.Id, "error", err.Error())
+		return nil, err
+	}
+	return doc, nil
+}
+
+// downloadThumb 下载缩略图内容
+func (s *PdfThumbRenderService) downloadThumb(ctx context.Context, bucketName string, objectKey string, contentType string) (*PdfThumbImage, error) {
+	bucketType := ossConstant.BucketTypeToEnum(s.config, bucketName)
+	data, err := s.ossService.DownloadObjectAsBytes(ctx, bucketType, objectKey)
+	if err != nil {
+		s.logger.Error("msg", "下载缩略图失败", "objectKey", objectKey, "error", err.Error())
+		return nil, errors.Biz("pdf.pdf.errors.download_failed")
+	}
+	return &PdfThumbImage{ObjectKey: objectKey, ContentType: contentType, Data: data}, nil
+}
+
+// getPermittedPdf 获取用户有权限访问的PDF
+func (s *PdfThumbRenderService) getPermittedPdf(ctx context.Context, pdfId string, userId string) (*model.PaperPdf, error) {
+	denied, err := s.paperPdfService.AuthPermissionDenied(ctx, pdfId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if denied {
+		return nil, errors.Biz("pdf.pdf_thumb.errors.permission_denied")
+	}
+	pdf, err := s.paperPdfService.GetById(ctx, pdfId)
+	if err != nil {
+		return nil, err
+	}
+	if pdf == nil {
+		return nil, errors.Biz("pdf.paper_pdf.errors.not_found")
+	}
+	return pdf, nil
+}
+
+func (s *PdfThumbRenderService) tryStartRendering(pdfId string) bool {
+	s.renderingMu.Lock()
+	defer s.renderingMu.Unlock()
+	if _, ok := s.rendering[pdfId]; ok {
+		return false
+	}
+	s.rendering[pdfId] = struct{}{}
+	return true
+}
+
+func (s *PdfThumbRenderService) finishRendering(pdfId string) {
+	s.renderingMu.Lock()
+	defer s.renderingMu.Unlock()
+	delete(s.rendering, pdfId)
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	parsedPb "github.com/yb2020/odoc/proto/gen/go/parsed"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	ossConstant "github.com/yb2020/odoc/services/oss/constant"
+	ossService "github.com/yb2020/odoc/services/oss/service"
+	paperService "github.com/yb2020/odoc/services/paper/service"
+	"github.com/yb2020/odoc/services/pdf/dao"
+	"github.com/yb2020/odoc/services/pdf/model"
+)
+
+// PdfUserDataDeleter 注销账号时删除用户的PDF标注、阅读设置和上传的文件
+type PdfUserDataDeleter struct {
+	config                  *config.Config
+	logger                  logging.Logger
+	tracer                  opentracing.Tracer
+	paperPdfDAO             *dao.PaperPDFDAO
+	paperPdfSelectRecordDAO *dao.PaperPdfSelectRecordDAO
+	pdfAnnotationDAO        *dao.PdfAnnotationDAO
+	pdfCommentDAO           *dao.PdfCommentDAO
+	pdfMarkDAO              *dao.PdfMarkDAO
+	pdfMarkBackupDAO        *dao.PdfMarkBackupDAO
+	pdfMarkTagDAO           *dao.PdfMarkTagDAO
+	pdfMarkTagRelationDAO   *dao.PdfMarkTagRelationDAO
+	pdfReaderSettingDAO     *dao.PdfReaderSettingDAO
+	pdfThumbDAO             *dao.PdfThumbDAO
+	docTextMarkDAO          *dao.DocTextMarkDAO
+	userDocService          *docService.UserDocService
+	paperPdfParsedService   *paperService.PaperPdfParsedService
+	ossService              ossService.OssServiceInterface
+}
+
+// NewPdfUserDataDeleter 创建PDF数据删除器
+func NewPdfUserDataDeleter(config *config.Config, logger logging.Logger, tracer opentracing.Tracer,
+	paperPdfDAO *dao.PaperPDFDAO,
+	paperPdfSelectRecordDAO *dao.PaperPdfSelectRecordDAO,
+	pdfAnnotationDAO *dao.PdfAnnotationDAO,
+	pdfCommentDAO *dao.PdfCommentDAO,
+	pdfMarkDAO *dao.PdfMarkDAO,
+	pdfMarkBackupDAO *dao.PdfMarkBackupDAO,
+	pdfMarkTagDAO *dao.PdfMarkTagDAO,
+	pdfMarkTagRelationDAO *dao.PdfMarkTagRelationDAO,
+	pdfReaderSettingDAO *dao.PdfReaderSettingDAO,
+	pdfThumbDAO *dao.PdfThumbDAO,
+	docTextMarkDAO *dao.DocTextMarkDAO,
+	userDocService *docService.UserDocService,
+	paperPdfParsedService *paperService.PaperPdfParsedService,
+	ossService ossService.OssServiceInterface,
+) *PdfUserDataDeleter {
+	return &PdfUserDataDeleter{
+		config:                  config,
+		logger:                  logger,
+		tracer:                  tracer,
+		paperPdfDAO:             paperPdfDAO,
+		paperPdfSelectRecordDAO: paperPdfSelectRecordDAO,
+		pdfAnnotationDAO:        pdfAnnotationDAO,
+		pdfCommentDAO:           pdfCommentDAO,
+		pdfMarkDAO:              pdfMarkDAO,
+		pdfMarkBackupDAO:        pdfMarkBackupDAO,
+		pdfMarkTagDAO:           pdfMarkTagDAO,
+		pdfMarkTagRelationDAO:   pdfMarkTagRelationDAO,
+		pdfReaderSettingDAO:     pdfReaderSettingDAO,
+		pdfThumbDAO:             pdfThumbDAO,
+		docTextMarkDAO:          docTextMarkDAO,
+		userDocService:          userDocService,
+		paperPdfParsedService:   paperPdfParsedService,
+		ossService:              ossService,
+	}
+}
+
+// DeleteUserData 物理删除用户创建的标注、标签、批注、阅读设置和划词记录，以及只有该用户引用的PDF文件
+func (d *PdfUserDataDeleter) DeleteUserData(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, d.tracer, "PdfUserDataDeleter.DeleteUserData")
+	defer span.Finish()
+
+	// 这些表没有 user_id 字段，按创建人区分归属
+	removers := []func(context.Context, string) (int64, error){
+		d.pdfMarkTagRelationDAO.RemoveByCreatorId,
+		d.pdfMarkTagDAO.RemoveByCreatorId,
+		d.pdfMarkBackupDAO.RemoveByCreatorId,
+		d.pdfMarkDAO.RemoveByCreatorId,
+		d.pdfCommentDAO.RemoveByCreatorId,
+		d.pdfAnnotationDAO.RemoveByCreatorId,
+		d.docTextMarkDAO.RemoveByCreatorId,
+		d.pdfReaderSettingDAO.RemoveByCreatorId,
+		d.paperPdfSelectRecordDAO.RemoveByCreatorId,
+	}
+	for _, remove := range removers {
+		if _, err := remove(ctx, userId); err != nil {
+			return err
+		}
+	}
+
+	pdfs, err := d.paperPdfDAO.GetAllByCreatorId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	removed := 0
+	for i := range pdfs {
+		ok, err := d.removePdf(ctx, userId, &pdfs[i])
+		if err != nil {
+			return err
+		}
+		if ok {
+			removed++
+		}
+	}
+	d.logger.Info("msg", "已删除用户的PDF数据", "userId", userId, "pdfs", len(pdfs), "removedPdfs", removed)
+	return nil
+}
+
+// ReleasePdf 彻底删除文献后释放PDF，没有任何文献（包括回收站中的）引用该PDF时删除PDF记录和文件，返回是否已删除
+func (d *PdfUserDataDeleter) ReleasePdf(ctx context.Context, pdfId string) (bool, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, d.tracer, "PdfUserDataDeleter.ReleasePdf")
+	defer span.Finish()
+
+	count, err := d.userDocService.CountByPdfId(ctx, pdfId)
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	pdf, err := d.paperPdfDAO.FindExistById(ctx, pdfId)
+	if err != nil {
+		return false, err
+	}
+	if pdf == nil {
+		return false, nil
+	}
+	if err := d.releasePdf(ctx, pdf); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// removePdf 删除用户上传的PDF记录和缩略图，其他用户的文献仍引用该PDF时保留，返回是否已删除
+func (d *PdfUserDataDeleter) removePdf(ctx context.Context, userId string, pdf *model.PaperPdf) (bool, error) {
+	count, err := d.userDocService.CountOtherUsersByPdfId(ctx, pdf.Id, userId)
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err := d.releasePdf(ctx, pdf); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// releasePdf 删除PDF记录和缩略图
+// 原始文件和解析结果按 SHA256 在PDF记录间共用，没有其他PDF记录时才删除
+func (d *PdfUserDataDeleter) releasePdf(ctx context.Context, pdf *model.PaperPdf) error {
+	thumbs, err := d.pdfThumbDAO.GetAllByPdfId(ctx, pdf.Id)
+	if err != nil {
+		return err
+	}
+	for _, thumb := range thumbs {
+		if err := d.deleteObject(ctx, thumb.BucketName, thumb.ObjectKey); err != nil {
+			return err
+		}
+	}
+	if err := d.pdfThumbDAO.RemoveByPdfId(ctx, pdf.Id); err != nil {
+		return err
+	}
+
+	shared := int64(0)
+	if pdf.FileSHA256 != "" {
+		if shared, err = d.paperPdfDAO.CountByFileSHA256ExcludeId(ctx, pdf.FileSHA256, pdf.Id); err != nil {
+			return err
+		}
+	}
+	if shared == 0 {
+		if err := d.deleteObject(ctx, pdf.OssBucketName, pdf.OssObjectKey); err != nil {
+			return err
+		}
+		if err := d.removeParsed(ctx, pdf.FileSHA256); err != nil {
+			return err
+		}
+	}
+	return d.paperPdfDAO.RemoveById(ctx, pdf.Id)
+}
+
+// removeParsed 删除原始文件的解析结果文件和记录，包括图表截图
+func (d *PdfUserDataDeleter) removeParsed(ctx context.Context, fileSHA256 string) error {
+	if fileSHA256 == "" {
+		return nil
+	}
+	records, err := d.paperPdfParsedService.GetAllBySourcePdfFileSHA256(ctx, fileSHA256)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := d.deleteObject(ctx, record.BucketName, record.ObjectKey); err != nil {
+			return err
+		}
+		if record.RecordsJson == "" {
+			continue
+		}
+		var imageRecords []*parsedPb.ImageRecord
+		if err := json.Unmarshal([]byte(record.RecordsJson), &imageRecords); err != nil {
+			d.logger.Warn("msg", "解析图表记录失败，跳过删除图表文件", "parsedId", record.Id, "error", err.Error())
+			continue
+		}
+		for _, image := range imageRecords {
+			if err := d.deleteObject(ctx, image.BucketName, image.ObjectKey); err != nil {
+				return err
+			}
+		}
+	}
+	return d.paperPdfParsedService.RemoveBySourcePdfFileSHA256(ctx, fileSHA256)
+}
+
+// deleteObject 删除对象存储中的文件，对象键为空时跳过
+func (d *PdfUserDataDeleter) deleteObject(ctx context.Context, bucketName string, objectKey string) error {
+	if objectKey == "" {
+		return nil
+	}
+	return d.ossService.DeleteObject(ctx, ossConstant.BucketTypeToEnum(d.config, bucketName), objectKey)
+}
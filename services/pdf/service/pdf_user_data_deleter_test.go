@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	baseModel "github.com/yb2020/odoc/pkg/model"
+	ossPb "github.com/yb2020/odoc/proto/gen/go/oss"
+	docDao "github.com/yb2020/odoc/services/doc/dao"
+	docModel "github.com/yb2020/odoc/services/doc/model"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	ossService "github.com/yb2020/odoc/services/oss/service"
+	paperDao "github.com/yb2020/odoc/services/paper/dao"
+	paperModel "github.com/yb2020/odoc/services/paper/model"
+	paperService "github.com/yb2020/odoc/services/paper/service"
+	"github.com/yb2020/odoc/services/pdf/dao"
+	"github.com/yb2020/odoc/services/pdf/model"
+)
+
+// memoryOssService 记录被删除的对象，只实现删除器用到的方法
+type memoryOssService struct {
+	ossService.OssServiceInterface
+	mu      sync.Mutex
+	deleted []string
+}
+
+func (s *memoryOssService) DeleteObject(ctx context.Context, bucketType ossPb.OSSBucketEnum, objectKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, objectKey)
+	return nil
+}
+
+func (s *memoryOssService) deletedKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := append([]string(nil), s.deleted...)
+	sort.Strings(keys)
+	return keys
+}
+
+func assertKeys(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	sort.Strings(want)
+	if len(got) != len(want) {
+		t.Fatalf("deleted objects = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("deleted objects = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPdfUserDataDeleter(t *testing.T) {
+	db := daotest.NewDB(t, &model.PaperPdf{}, &model.PdfThumb{}, &docModel.UserDoc{}, &paperModel.PaperPdfParsed{})
+	logger := daotest.NewLogger()
+	tracer := opentracing.NoopTracer{}
+	paperPdfDAO := dao.NewPaperPDFDAO(db, logger)
+	thumbDAO := dao.NewPdfThumbDAO(db, logger)
+	userDocDAO := docDao.NewUserDocDAO(db, logger)
+	parsedDAO := paperDao.NewPaperPdfParsedDAO(db, logger)
+	oss := &memoryOssService{}
+	userDocService := docService.NewUserDocService(logger, tracer, nil, userDocDAO,
+		nil, nil, nil, nil, nil, nil, nil, nil, &config.Config{}, nil, nil)
+	deleter := &PdfUserDataDeleter{
+		config:                &config.Config{},
+		logger:                logger,
+		tracer:                tracer,
+		paperPdfDAO:           paperPdfDAO,
+		pdfThumbDAO:           thumbDAO,
+		userDocService:        userDocService,
+		paperPdfParsedService: paperService.NewPaperPdfParsedService(logger, tracer, parsedDAO),
+		ossService:            oss,
+	}
+
+	ctx := context.Background()
+	// 创建用户上传的PDF记录和一张缩略图
+	addPdf := func(t *testing.T, id string, creatorId string, sha256 string, objectKey string) *model.PaperPdf {
+		t.Helper()
+		pdf := &model.PaperPdf{BaseModel: baseModel.BaseModel{Id: id, CreatorId: creatorId}, FileSHA256: sha256, OssObjectKey: objectKey}
+		if err := paperPdfDAO.Save(ctx, pdf); err != nil {
+			t.Fatalf("save pdf: %v", err)
+		}
+		if err := thumbDAO.Save(ctx, &model.PdfThumb{PdfId: id, ObjectKey: "thumb-" + id}); err != nil {
+			t.Fatalf("save thumb: %v", err)
+		}
+		return pdf
+	}
+	addDoc := func(t *testing.T, userId string, pdfId string) {
+		t.Helper()
+		if err := userDocDAO.Save(ctx, &docModel.UserDoc{UserId: userId, PdfId: pdfId}); err != nil {
+			t.Fatalf("save doc: %v", err)
+		}
+	}
+	addParsed := func(t *testing.T, sha256 string, objectKey string) {
+		t.Helper()
+		parsed := &paperModel.PaperPdfParsed{SourcePdfSHA256: sha256, ObjectKey: objectKey,
+			RecordsJson: `[{"bucket_name":"parsed","object_key":"figure-` + sha256 + `"}]`}
+		if err := parsedDAO.Save(ctx, parsed); err != nil {
+			t.Fatalf("save parsed: %v", err)
+		}
+	}
+	pdfExists := func(t *testing.T, id string) bool {
+		t.Helper()
+		pdf, err := paperPdfDAO.FindExistById(ctx, id)
+		if err != nil {
+			t.Fatalf("find pdf: %v", err)
+		}
+		return pdf != nil
+	}
+
+	t.Run("remove pdf keeps pdf referenced by other user", func(t *testing.T) {
+		shared := addPdf(t, "pdf-shared", "u1", "sha-shared", "obj-shared")
+		own := addPdf(t, "pdf-own", "u1", "sha-own", "obj-own")
+		addDoc(t, "u1", shared.Id)
+		addDoc(t, "u2", shared.Id)
+		addDoc(t, "u1", own.Id)
+
+		removed, err := deleter.removePdf(ctx, "u1", shared)
+		if err != nil || removed {
+			t.Fatalf("removePdf(shared) = %v, %v, want kept", removed, err)
+		}
+		if !pdfExists(t, shared.Id) || len(oss.deletedKeys()) != 0 {
+			t.Fatalf("pdf referenced by another user was removed, deleted objects = %v", oss.deletedKeys())
+		}
+
+		// 只有注销用户自己引用的PDF被删除
+		removed, err = deleter.removePdf(ctx, "u1", own)
+		if err != nil || !removed {
+			t.Fatalf("removePdf(own) = %v, %v, want removed", removed, err)
+		}
+		if pdfExists(t, own.Id) {
+			t.Fatalf("own pdf not removed")
+		}
+		assertKeys(t, oss.deletedKeys(), "thumb-pdf-own", "obj-own")
+	})
+
+	t.Run("release pdf keeps object shared by sha256", func(t *testing.T) {
+		// 只检查本用例删除的对象
+		oss.deleted = nil
+		first := addPdf(t, "pdf-1", "u1", "sha-a", "obj-a")
+		second := addPdf(t, "pdf-2", "u2", "sha-a", "obj-a")
+		addParsed(t, "sha-a", "parsed-a")
+
+		// 仍有文献引用时不释放
+		addDoc(t, "u2", second.Id)
+		released, err := deleter.ReleasePdf(ctx, second.Id)
+		if err != nil || released {
+			t.Fatalf("ReleasePdf(referenced) = %v, %v, want kept", released, err)
+		}
+
+		// 另一条PDF记录共用同一文件，只删除记录和缩略图，保留原始文件和解析结果
+		released, err = deleter.ReleasePdf(ctx, first.Id)
+		if err != nil || !released {
+			t.Fatalf("ReleasePdf(first) = %v, %v, want released", released, err)
+		}
+		if pdfExists(t, first.Id) || !pdfExists(t, second.Id) {
+			t.Fatalf("pdf records first=%v second=%v, want only second", pdfExists(t, first.Id), pdfExists(t, second.Id))
+		}
+		assertKeys(t, oss.deletedKeys(), "thumb-pdf-1")
+		parsed, err := parsedDAO.GetAllBySourcePdfFileSHA256(ctx, "sha-a")
+		if err != nil || len(parsed) != 1 {
+			t.Fatalf("parsed = %v, err = %v, want kept", parsed, err)
+		}
+
+		// 最后一条记录释放时删除原始文件、解析结果和图表截图
+		if err := userDocDAO.GetDB(ctx).Where("pdf_id = ?", second.Id).Delete(&docModel.UserDoc{}).Error; err != nil {
+			t.Fatalf("delete doc: %v", err)
+		}
+		released, err = deleter.ReleasePdf(ctx, second.Id)
+		if err != nil || !released {
+			t.Fatalf("ReleasePdf(second) = %v, %v, want released", released, err)
+		}
+		assertKeys(t, oss.deletedKeys(), "thumb-pdf-1", "thumb-pdf-2", "obj-a", "parsed-a", "figure-sha-a")
+		parsed, err = parsedDAO.GetAllBySourcePdfFileSHA256(ctx, "sha-a")
+		if err != nil || len(parsed) != 0 {
+			t.Fatalf("parsed = %v, err = %v, want removed", parsed, err)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/reading"
+	"github.com/yb2020/odoc/services/reading/service"
+)
+
+// ReadingAnalyticsAPI 阅读统计API处理器
+type ReadingAnalyticsAPI struct {
+	readingSessionService *service.ReadingSessionService
+	readingStatService    *service.ReadingStatService
+	logger                logging.Logger
+	tracer                opentracing.Tracer
+}
+
+// NewReadingAnalyticsAPI 创建阅读统计API处理器
+func NewReadingAnalyticsAPI(
+	readingSessionService *service.ReadingSessionService,
+	readingStatService *service.ReadingStatService,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+) *ReadingAnalyticsAPI {
+	return &ReadingAnalyticsAPI{
+		readingSessionService: readingSessionService,
+		readingStatService:    readingStatService,
+		logger:                logger,
+		tracer:                tracer,
+	}
+}
+
+/*
+* @api_path: /api/reading/heartbeat
+* @method: POST
+* @content-type: application/json
+* @summary: 上报阅读心跳，阅读器停留期间定时调用
+ */
+func (api *ReadingAnalyticsAPI) Heartbeat(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "ReadingAnalyticsAPI.Heartbeat")
+	defer span.Finish()
+
+	var req pb.ReadingHeartbeatRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	if err := api.readingSessionService.RecordHeartbeat(ctx, userId, req.NoteId); err != nil {
+		api.logger.Error("msg", "记录阅读心跳失败", "noteId", req.NoteId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.SuccessNoData(c, "success")
+}
+
+/*
+* @api_path: /api/reading/analytics/overview
+* @method: POST
+* @content-type: application/json
+* @summary: 获取阅读概览，包含每日、每周序列和连续阅读天数
+ */
+func (api *ReadingAnalyticsAPI) GetOverview(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "ReadingAnalyticsAPI.GetOverview")
+	defer span.Finish()
+
+	var req pb.ReadingAnalyticsRangeRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	resp, err := api.readingStatService.GetReadingOverview(ctx, userId, req.StartDate, req.EndDate)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", resp)
+}
+
+/*
+* @api_path: /api/reading/analytics/papers
+* @method: POST
+* @content-type: application/json
+* @summary: 获取按文献汇总的阅读时长和标注密度
+ */
+func (api *ReadingAnalyticsAPI) GetPaperStats(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "ReadingAnalyticsAPI.GetPaperStats")
+	defer span.Finish()
+
+	var req pb.ReadingAnalyticsRangeRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	resp, err := api.readingStatService.GetPaperReadingStats(ctx, userId, req.StartDate, req.EndDate)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", resp)
+}
+
+/*
+* @api_path: /api/reading/analytics/folders
+* @method: POST
+* @content-type: application/json
+* @summary: 获取按文件夹汇总的阅读时长
+ */
+func (api *ReadingAnalyticsAPI) GetFolderStats(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "ReadingAnalyticsAPI.GetFolderStats")
+	defer span.Finish()
+
+	var req pb.ReadingAnalyticsRangeRequest
+	if err := transport.BindProto(c, &req); err != nil {
+		api.logger.Error("msg", "解析请求参数失败", "error", err.Error())
+		response.ErrorNoData(c, "解析请求参数失败")
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	resp, err := api.readingStatService.GetFolderReadingStats(ctx, userId, req.StartDate, req.EndDate)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", resp)
+}
@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"context"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/reading/model"
+	"gorm.io/gorm"
+)
+
+// ReadingDailyStatDAO GORM实现的阅读每日汇总DAO
+type ReadingDailyStatDAO struct {
+	*baseDao.GormBaseDAO[model.ReadingDailyStat]
+	logger logging.Logger
+}
+
+// NewReadingDailyStatDAO 创建一个新的阅读每日汇总DAO
+func NewReadingDailyStatDAO(db *gorm.DB, logger logging.Logger) *ReadingDailyStatDAO {
+	return &ReadingDailyStatDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.ReadingDailyStat](db, logger),
+		logger:      logger,
+	}
+}
+
+// FindByStatDate 获取某一统计日期的全部汇总记录
+func (d *ReadingDailyStatDAO) FindByStatDate(ctx context.Context, statDate string) ([]model.ReadingDailyStat, error) {
+	var stats []model.ReadingDailyStat
+	result := d.GetDB(ctx).Where("stat_date = ? AND is_deleted = false", statDate).Find(&stats)
+	if result.Error != nil {
+		d.logger.Error("msg", "根据统计日期获取阅读每日汇总失败", "statDate", statDate, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return stats, nil
+}
+
+// FindByUserIdAndStatDateRange 获取用户在[startDate, endDate]日期范围内的汇总记录
+func (d *ReadingDailyStatDAO) FindByUserIdAndStatDateRange(ctx context.Context, userId string, startDate string, endDate string) ([]model.ReadingDailyStat, error) {
+	var stats []model.ReadingDailyStat
+	result := d.GetDB(ctx).Where("user_id = ? AND stat_date >= ? AND stat_date <= ? AND is_deleted = false", userId, startDate, endDate).
+		Order("stat_date ASC").Find(&stats)
+	if result.Error != nil {
+		d.logger.Error("msg", "根据日期范围获取阅读每日汇总失败", "userId", userId, "startDate", startDate, "endDate", endDate, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return stats, nil
+}
+
+// FindReadDatesByUserId 获取用户所有有阅读时长的日期，按日期升序
+func (d *ReadingDailyStatDAO) FindReadDatesByUserId(ctx context.Context, userId string) ([]string, error) {
+	var dates []string
+	result := d.GetDB(ctx).Model(&model.ReadingDailyStat{}).
+		Where("user_id = ? AND duration_seconds > 0 AND is_deleted = false", userId).
+		Distinct("stat_date").Order("stat_date ASC").Pluck("stat_date", &dates)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取用户阅读日期失败", "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return dates, nil
+}
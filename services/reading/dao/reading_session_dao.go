@@ -0,0 +1,50 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/reading/model"
+	"gorm.io/gorm"
+)
+
+// ReadingSessionDAO GORM实现的阅读会话DAO
+type ReadingSessionDAO struct {
+	*baseDao.GormBaseDAO[model.ReadingSession]
+	logger logging.Logger
+}
+
+// NewReadingSessionDAO 创建一个新的阅读会话DAO
+func NewReadingSessionDAO(db *gorm.DB, logger logging.Logger) *ReadingSessionDAO {
+	return &ReadingSessionDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.ReadingSession](db, logger),
+		logger:      logger,
+	}
+}
+
+// FindLatestByUserIdAndNoteId 获取用户在某笔记上最近的一次阅读会话
+func (d *ReadingSessionDAO) FindLatestByUserIdAndNoteId(ctx context.Context, userId string, noteId string) (*model.ReadingSession, error) {
+	var session model.ReadingSession
+	result := d.GetDB(ctx).Where("user_id = ? AND note_id = ? AND is_deleted = false", userId, noteId).Order("last_heartbeat_at DESC").First(&session)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取最近阅读会话失败", "userId", userId, "noteId", noteId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &session, nil
+}
+
+// FindByStatDate 获取某一统计日期的全部阅读会话
+func (d *ReadingSessionDAO) FindByStatDate(ctx context.Context, statDate string) ([]model.ReadingSession, error) {
+	var sessions []model.ReadingSession
+	result := d.GetDB(ctx).Where("stat_date = ? AND is_deleted = false", statDate).Find(&sessions)
+	if result.Error != nil {
+		d.logger.Error("msg", "根据统计日期获取阅读会话失败", "statDate", statDate, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return sessions, nil
+}
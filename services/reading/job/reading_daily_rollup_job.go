@@ -0,0 +1,57 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/services/reading/service"
+)
+
+// ReadingDailyRollupJob 阅读统计每日汇总任务，周期性重算最近几天的汇总，统计接口只读取汇总结果
+type ReadingDailyRollupJob struct {
+	logger             logging.Logger
+	spec               string                 // 任务的cron表达式，6字段标准cron表达式
+	key                string                 // 任务的锁key，必须是唯一的unique-job-key
+	expiry             time.Duration          // 任务的锁过期时间
+	lockOpts           *scheduler.LockOptions // 任务的锁选项
+	readingStatService *service.ReadingStatService
+}
+
+func NewReadingDailyRollupJob(logger logging.Logger, cfg *config.Config, readingStatService *service.ReadingStatService) *ReadingDailyRollupJob {
+	spec := cfg.Scheduler.Jobs.ReadingDailyRollupJob.Spec
+	key := cfg.Scheduler.Jobs.ReadingDailyRollupJob.Key
+	expiry := time.Duration(cfg.Scheduler.Jobs.ReadingDailyRollupJob.Expiry) * time.Second
+	lockOpts := &scheduler.LockOptions{
+		Key:    key,
+		Expiry: expiry,
+	}
+	return &ReadingDailyRollupJob{logger: logger, spec: spec, key: key, expiry: expiry, lockOpts: lockOpts, readingStatService: readingStatService}
+}
+
+// Spec 获取任务的cron表达式
+func (j *ReadingDailyRollupJob) Spec() string {
+	return j.spec
+}
+
+// LockOpts 获取任务的锁选项
+func (j *ReadingDailyRollupJob) LockOpts() *scheduler.LockOptions {
+	return j.lockOpts
+}
+
+// NewUserContext 汇总任务不以具体用户身份执行，这里直接返回原上下文
+func (j *ReadingDailyRollupJob) NewUserContext(ctx context.Context, userId string) context.Context {
+	return ctx
+}
+
+// Run 执行任务，在执行任务前会获取锁，执行任务后会释放锁
+func (j *ReadingDailyRollupJob) Run() {
+	ctx := context.Background()
+	if err := j.readingStatService.RollupRecentDays(ctx); err != nil {
+		j.logger.Error("msg", "Reading daily rollup job failed", "error", err)
+		return
+	}
+	j.logger.Info("msg", "Reading daily rollup job success")
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// ReadingDailyStat 阅读每日汇总实体，按用户、日期、文献维度由定时任务汇总
+type ReadingDailyStat struct {
+	model.BaseModel        // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	UserId          string `json:"userId" gorm:"column:user_id;size:36;uniqueIndex:idx_reading_daily_stat_user_date_doc"`     // 用户ID
+	StatDate        string `json:"statDate" gorm:"column:stat_date;size:10;uniqueIndex:idx_reading_daily_stat_user_date_doc"` // 统计日期(UTC) yyyy-MM-dd
+	DocId           string `json:"docId" gorm:"column:doc_id;size:36;uniqueIndex:idx_reading_daily_stat_user_date_doc"`       // 用户文献ID
+	NoteId          string `json:"noteId" gorm:"column:note_id;size:36"`                                                      // 笔记ID
+	PdfId           string `json:"pdfId" gorm:"column:pdf_id;size:36"`                                                        // PDF文件ID
+	DurationSeconds int64  `json:"durationSeconds" gorm:"column:duration_seconds;default:0"`                                  // 当日阅读时长 单位：秒
+	SessionCount    int    `json:"sessionCount" gorm:"column:session_count;default:0"`                                        // 当日阅读会话数
+	FinishedCount   int    `json:"finishedCount" gorm:"column:finished_count;default:0"`                                      // 当日是否读完，1表示当日标记为已读
+	AnnotationCount int64  `json:"annotationCount" gorm:"column:annotation_count;default:0"`                                  // 汇总时该笔记的标注总数
+}
+
+// TableName 返回表名
+func (ReadingDailyStat) TableName() string {
+	return "t_reading_daily_stat"
+}
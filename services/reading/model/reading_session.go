@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// ReadingSession 阅读会话实体，由阅读位置心跳合并而成
+type ReadingSession struct {
+	model.BaseModel           // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	UserId          string    `json:"userId" gorm:"column:user_id;size:36;index"`               // 用户ID
+	NoteId          string    `json:"noteId" gorm:"column:note_id;size:36;index"`               // 笔记ID
+	DocId           string    `json:"docId" gorm:"column:doc_id;size:36;index"`                 // 用户文献ID
+	PdfId           string    `json:"pdfId" gorm:"column:pdf_id;size:36"`                       // PDF文件ID
+	PaperId         string    `json:"paperId" gorm:"column:paper_id;size:36"`                   // 论文ID
+	StatDate        string    `json:"statDate" gorm:"column:stat_date;size:10;index"`           // 统计日期(UTC) yyyy-MM-dd
+	StartAt         time.Time `json:"startAt" gorm:"column:start_at"`                           // 会话开始时间
+	LastHeartbeatAt time.Time `json:"lastHeartbeatAt" gorm:"column:last_heartbeat_at"`          // 最后一次心跳时间
+	DurationSeconds int64     `json:"durationSeconds" gorm:"column:duration_seconds;default:0"` // 阅读时长 单位：秒
+	HeartbeatCount  int       `json:"heartbeatCount" gorm:"column:heartbeat_count;default:0"`   // 心跳次数
+}
+
+// TableName 返回表名
+func (ReadingSession) TableName() string {
+	return "t_reading_session"
+}
@@ -0,0 +1,145 @@
+package reading
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/middleware"
+	"github.com/yb2020/odoc/pkg/registry"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	noteInterface "github.com/yb2020/odoc/services/note/interfaces"
+	pdfInterface "github.com/yb2020/odoc/services/pdf/interfaces"
+	"github.com/yb2020/odoc/services/reading/api"
+	"github.com/yb2020/odoc/services/reading/dao"
+	"github.com/yb2020/odoc/services/reading/job"
+	"github.com/yb2020/odoc/services/reading/service"
+)
+
+// 编译时类型检查：确保 ReadingModule 实现了 registry.Module 接口
+var _ registry.Module = (*ReadingModule)(nil)
+
+// 编译时类型检查：确保 ReadingModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*ReadingModule)(nil)
+
+// Module 导出模块实例，用于自动发现和注册
+var Module = &ReadingModule{}
+
+// ReadingModule 阅读统计模块
+type ReadingModule struct {
+	db                           *gorm.DB
+	logger                       logging.Logger
+	tracer                       opentracing.Tracer
+	authMiddleware               *middleware.AuthMiddleware
+	cfg                          *config.Config
+	userDocService               *docService.UserDocService
+	userDocFolderService         *docService.UserDocFolderService
+	userDocFolderRelationService *docService.UserDocFolderRelationService
+	paperNoteService             noteInterface.IPaperNoteService
+	paperPdfService              pdfInterface.IPaperPdfService
+
+	readingSessionService *service.ReadingSessionService
+	readingStatService    *service.ReadingStatService
+	readingAnalyticsAPI   *api.ReadingAnalyticsAPI
+}
+
+// NewReadingModule 创建阅读统计模块
+func NewReadingModule(db *gorm.DB, cfg *config.Config, logger logging.Logger,
+	tracer opentracing.Tracer, authMiddleware *middleware.AuthMiddleware,
+	userDocService *docService.UserDocService,
+	userDocFolderService *docService.UserDocFolderService,
+	userDocFolderRelationService *docService.UserDocFolderRelationService,
+	paperNoteService noteInterface.IPaperNoteService,
+	paperPdfService pdfInterface.IPaperPdfService,
+) *ReadingModule {
+	return &ReadingModule{
+		db:                           db,
+		logger:                       logger,
+		tracer:                       tracer,
+		cfg:                          cfg,
+		authMiddleware:               authMiddleware,
+		userDocService:               userDocService,
+		userDocFolderService:         userDocFolderService,
+		userDocFolderRelationService: userDocFolderRelationService,
+		paperNoteService:             paperNoteService,
+		paperPdfService:              paperPdfService,
+	}
+}
+
+// Name 返回模块名称
+func (m *ReadingModule) Name() string {
+	return "reading"
+}
+
+// Shutdown 停止模块
+func (m *ReadingModule) Shutdown() error {
+	m.logger.Info("msg", "关闭阅读统计模块")
+	return nil
+}
+
+// RegisterGRPC 注册gRPC服务
+func (m *ReadingModule) RegisterGRPC(server *grpc.Server) {
+	// TODO: 实现gRPC服务注册
+	m.logger.Debug("msg", "阅读统计模块没有gRPC服务，跳过注册")
+}
+
+// RegisterJobSchedulers 注册Job定时任务
+func (m *ReadingModule) RegisterJobSchedulers(scheduler *scheduler.Scheduler) {
+	if scheduler == nil {
+		m.logger.Debug("msg", "调度器未启用，阅读统计模块跳过Job注册")
+		return
+	}
+	m.logger.Debug("msg", "阅读统计模块注册Job定时任务")
+	rollupJob := job.NewReadingDailyRollupJob(m.logger, m.cfg, m.readingStatService)
+	scheduler.RegisterJobs(rollupJob)
+}
+
+// RegisterProviders 注册Provider
+func (m *ReadingModule) RegisterProviders() {
+	// TODO: 实现Provider注册
+	m.logger.Debug("msg", "阅读统计模块没有Provider，跳过注册")
+}
+
+// Initialize 初始化模块
+func (m *ReadingModule) Initialize() error {
+	m.RegisterProviders()
+	m.logger.Info("msg", "初始化阅读统计模块")
+
+	readingSessionDAO := dao.NewReadingSessionDAO(m.db, m.logger)
+	readingDailyStatDAO := dao.NewReadingDailyStatDAO(m.db, m.logger)
+
+	m.readingSessionService = service.NewReadingSessionService(m.cfg, m.logger, m.tracer, readingSessionDAO, m.paperNoteService, m.userDocService)
+	m.readingStatService = service.NewReadingStatService(m.cfg, m.logger, m.tracer, readingSessionDAO, readingDailyStatDAO,
+		m.userDocService, m.userDocFolderService, m.userDocFolderRelationService, m.paperPdfService)
+
+	m.readingAnalyticsAPI = api.NewReadingAnalyticsAPI(m.readingSessionService, m.readingStatService, m.logger, m.tracer)
+	return nil
+}
+
+// RegisterRoutes 注册路由
+func (m *ReadingModule) RegisterRoutes(r *gin.Engine) {
+	readingGroup := r.Group("/api/reading")
+	readingGroup.Use(m.authMiddleware.AuthRequired())
+	{
+		readingGroup.POST("/heartbeat", m.readingAnalyticsAPI.Heartbeat)
+		readingGroup.POST("/analytics/overview", m.readingAnalyticsAPI.GetOverview)
+		readingGroup.POST("/analytics/papers", m.readingAnalyticsAPI.GetPaperStats)
+		readingGroup.POST("/analytics/folders", m.readingAnalyticsAPI.GetFolderStats)
+	}
+}
+
+// GetReadingSessionService 获取阅读会话服务，供笔记模块上报阅读心跳
+func (m *ReadingModule) GetReadingSessionService() *service.ReadingSessionService {
+	return m.readingSessionService
+}
+
+// DeleteUserData 注销账号时删除用户的阅读统计数据
+func (m *ReadingModule) DeleteUserData(ctx context.Context, userId string) error {
+	return m.readingStatService.DeleteUserData(ctx, userId)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	noteInterfaces "github.com/yb2020/odoc/services/note/interfaces"
+	"github.com/yb2020/odoc/services/reading/dao"
+	"github.com/yb2020/odoc/services/reading/model"
+)
+
+// StatDateLayout 阅读统计日期格式
+const StatDateLayout = "2006-01-02"
+
+// 确保 ReadingSessionService 实现了笔记模块的阅读心跳接口
+var _ noteInterfaces.IReadingHeartbeatRecorder = (*ReadingSessionService)(nil)
+
+// ReadingSessionService 阅读会话服务，将阅读位置心跳合并为阅读会话
+type ReadingSessionService struct {
+	readingSessionDAO *dao.ReadingSessionDAO
+	paperNoteService  noteInterfaces.IPaperNoteService
+	userDocService    *docService.UserDocService
+	idleTimeout       time.Duration
+	logger            logging.Logger
+	tracer            opentracing.Tracer
+}
+
+// NewReadingSessionService 创建阅读会话服务
+func NewReadingSessionService(
+	cfg *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	readingSessionDAO *dao.ReadingSessionDAO,
+	paperNoteService noteInterfaces.IPaperNoteService,
+	userDocService *docService.UserDocService,
+) *ReadingSessionService {
+	idleTimeout := time.Duration(cfg.ReadingAnalytics.SessionIdleTimeout) * time.Second
+	if idleTimeout <= 0 {
+		idleTimeout = 5 * time.Minute
+	}
+	return &ReadingSessionService{
+		readingSessionDAO: readingSessionDAO,
+		paperNoteService:  paperNoteService,
+		userDocService:    userDocService,
+		idleTimeout:       idleTimeout,
+		logger:            logger,
+		tracer:            tracer,
+	}
+}
+
+// RecordHeartbeat 记录阅读心跳
+// 与上一次心跳间隔不超过空闲超时且在同一天时延长当前会话，否则开启新会话；跨天的会话拆分到新的一天
+func (s *ReadingSessionService) RecordHeartbeat(ctx context.Context, userId string, noteId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ReadingSessionService.RecordHeartbeat")
+	defer span.Finish()
+
+	if userId == "" || noteId == "" {
+		return errors.Biz("reading.session.errors.invalid_params")
+	}
+
+	now := time.Now().UTC()
+	statDate := now.Format(StatDateLayout)
+
+	latest, err := s.readingSessionDAO.FindLatestByUserIdAndNoteId(ctx, userId, noteId)
+	if err != nil {
+		return errors.Biz("reading.session.errors.get_failed")
+	}
+	if latest != nil && latest.StatDate == statDate && !now.Before(latest.LastHeartbeatAt) && now.Sub(latest.LastHeartbeatAt) <= s.idleTimeout {
+		latest.DurationSeconds += int64(now.Sub(latest.LastHeartbeatAt).Seconds())
+		latest.LastHeartbeatAt = now
+		latest.HeartbeatCount++
+		if err := s.readingSessionDAO.Modify(ctx, latest); err != nil {
+			return errors.Biz("reading.session.errors.update_failed")
+		}
+		return nil
+	}
+
+	paperNote, err := s.paperNoteService.GetPaperNoteById(ctx, noteId)
+	if err != nil {
+		return err
+	}
+	if paperNote == nil {
+		return errors.Biz("reading.session.errors.note_not_found")
+	}
+	// 只统计用户自己文献库中的文献，阅读他人分享的笔记不计入
+	userDoc, err := s.userDocService.GetByUserIdAndPdfId(ctx, userId, paperNote.PdfId)
+	if err != nil {
+		return err
+	}
+	if userDoc == nil {
+		return nil
+	}
+
+	session := &model.ReadingSession{
+		UserId:          userId,
+		NoteId:          noteId,
+		DocId:           userDoc.Id,
+		PdfId:           paperNote.PdfId,
+		PaperId:         paperNote.PaperId,
+		StatDate:        statDate,
+		StartAt:         now,
+		LastHeartbeatAt: now,
+		HeartbeatCount:  1,
+	}
+	if err := s.readingSessionDAO.Save(ctx, session); err != nil {
+		return errors.Biz("reading.session.errors.create_failed")
+	}
+	return nil
+}
@@ -0,0 +1,537 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	pb "github.com/yb2020/odoc/proto/gen/go/reading"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	pdfInterfaces "github.com/yb2020/odoc/services/pdf/interfaces"
+	"github.com/yb2020/odoc/services/reading/dao"
+	"github.com/yb2020/odoc/services/reading/model"
+)
+
+// UnclassifiedFolderId 未分类文件夹ID
+const UnclassifiedFolderId = "0"
+
+// ReadingStatService 阅读统计服务，负责每日汇总和统计查询
+type ReadingStatService struct {
+	cfg                          *config.Config
+	readingSessionDAO            *dao.ReadingSessionDAO
+	readingDailyStatDAO          *dao.ReadingDailyStatDAO
+	userDocService               *docService.UserDocService
+	userDocFolderService         *docService.UserDocFolderService
+	userDocFolderRelationService *docService.UserDocFolderRelationService
+	paperPdfService              pdfInterfaces.IPaperPdfService
+	logger                       logging.Logger
+	tracer                       opentracing.Tracer
+}
+
+// NewReadingStatService 创建阅读统计服务
+func NewReadingStatService(
+	cfg *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	readingSessionDAO *dao.ReadingSessionDAO,
+	readingDailyStatDAO *dao.ReadingDailyStatDAO,
+	userDocService *docService.UserDocService,
+	userDocFolderService *docService.UserDocFolderService,
+	userDocFolderRelationService *docService.UserDocFolderRelationService,
+	paperPdfService pdfInterfaces.IPaperPdfService,
+) *ReadingStatService {
+	return &ReadingStatService{
+		cfg:                          cfg,
+		readingSessionDAO:            readingSessionDAO,
+		readingDailyStatDAO:          readingDailyStatDAO,
+		userDocService:               userDocService,
+		userDocFolderService:         userDocFolderService,
+		userDocFolderRelationService: userDocFolderRelationService,
+		paperPdfService:              paperPdfService,
+		logger:                       logger,
+		tracer:                       tracer,
+	}
+}
+
+// rollupKey 每日汇总的维度
+type rollupKey struct {
+	userId string
+	docId  string
+}
+
+// RollupRecentDays 重算最近几天（含当天）的每日汇总，由定时任务调用
+func (s *ReadingStatService) RollupRecentDays(ctx context.Context) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ReadingStatService.RollupRecentDays")
+	defer span.Finish()
+
+	lookbackDays := s.cfg.ReadingAnalytics.RollupLookbackDays
+	if lookbackDays <= 0 {
+		lookbackDays = 2
+	}
+	today := time.Now().UTC()
+	var lastErr error
+	for i := lookbackDays - 1; i >= 0; i-- {
+		statDate := today.AddDate(0, 0, -i).Format(StatDateLayout)
+		if err := s.RollupDailyStats(ctx, statDate); err != nil {
+			s.logger.Error("msg", "阅读每日汇总失败", "statDate", statDate, "error", err.Error())
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// RollupDailyStats 根据阅读会话和读完记录重算某一天的每日汇总，可重复执行
+func (s *ReadingStatService) RollupDailyStats(ctx context.Context, statDate string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ReadingStatService.RollupDailyStats")
+	defer span.Finish()
+
+	dayStart, err := time.Parse(StatDateLayout, statDate)
+	if err != nil {
+		return errors.Biz("reading.analytics.errors.invalid_date")
+	}
+
+	aggregates := make(map[rollupKey]*model.ReadingDailyStat)
+	getOrCreate := func(userId, docId, noteId, pdfId string) *model.ReadingDailyStat {
+		key := rollupKey{userId: userId, docId: docId}
+		stat, ok := aggregates[key]
+		if !ok {
+			stat = &model.ReadingDailyStat{UserId: userId, StatDate: statDate, DocId: docId}
+			aggregates[key] = stat
+		}
+		if stat.NoteId == "" {
+			stat.NoteId = noteId
+		}
+		if stat.PdfId == "" {
+			stat.PdfId = pdfId
+		}
+		return stat
+	}
+
+	// 1. 汇总当天的阅读会话
+	sessions, err := s.readingSessionDAO.FindByStatDate(ctx, statDate)
+	if err != nil {
+		return errors.Biz("reading.session.errors.get_failed")
+	}
+	for _, session := range sessions {
+		if session.DocId == "" {
+			continue
+		}
+		stat := getOrCreate(session.UserId, session.DocId, session.NoteId, session.PdfId)
+		stat.DurationSeconds += session.DurationSeconds
+		stat.SessionCount++
+	}
+
+	// 2. 汇总当天读完的文献
+	finishedDocs, err := s.userDocService.GetReadFinishedUserDocsByTimeRange(ctx, dayStart, dayStart.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+	for _, userDoc := range finishedDocs {
+		stat := getOrCreate(userDoc.UserId, userDoc.Id, userDoc.NoteId, userDoc.PdfId)
+		stat.FinishedCount = 1
+	}
+
+	// 3. 记录标注数快照并写入汇总表
+	existingStats, err := s.readingDailyStatDAO.FindByStatDate(ctx, statDate)
+	if err != nil {
+		return errors.Biz("reading.analytics.errors.get_failed")
+	}
+	existingMap := make(map[rollupKey]*model.ReadingDailyStat, len(existingStats))
+	for i := range existingStats {
+		existingMap[rollupKey{userId: existingStats[i].UserId, docId: existingStats[i].DocId}] = &existingStats[i]
+	}
+
+	var annotationCounts map[string]int64
+	if s.paperPdfService != nil {
+		noteIds := make([]string, 0, len(aggregates))
+		for _, stat := range aggregates {
+			if stat.NoteId != "" {
+				noteIds = append(noteIds, stat.NoteId)
+			}
+		}
+		annotationCounts, err = s.paperPdfService.GetCountPdfMarksByNoteIds(ctx, noteIds)
+		if err != nil {
+			s.logger.Warn("msg", "获取笔记标注数失败", "statDate", statDate, "error", err.Error())
+		}
+	}
+
+	for key, stat := range aggregates {
+		stat.AnnotationCount = annotationCounts[stat.NoteId]
+
+		existing, ok := existingMap[key]
+		if !ok {
+			if err := s.readingDailyStatDAO.Save(ctx, stat); err != nil {
+				return errors.Biz("reading.analytics.errors.save_failed")
+			}
+			continue
+		}
+		delete(existingMap, key)
+		existing.NoteId = stat.NoteId
+		existing.PdfId = stat.PdfId
+		existing.DurationSeconds = stat.DurationSeconds
+		existing.SessionCount = stat.SessionCount
+		existing.FinishedCount = stat.FinishedCount
+		existing.AnnotationCount = stat.AnnotationCount
+		if err := s.readingDailyStatDAO.Modify(ctx, existing); err != nil {
+			return errors.Biz("reading.analytics.errors.save_failed")
+		}
+	}
+
+	// 文献读完时间被刷新到其他日期后，清除原日期的读完标记
+	for _, stale := range existingMap {
+		if stale.FinishedCount == 0 {
+			continue
+		}
+		stale.FinishedCount = 0
+		if err := s.readingDailyStatDAO.Modify(ctx, stale); err != nil {
+			return errors.Biz("reading.analytics.errors.save_failed")
+		}
+	}
+	return nil
+}
+
+// DeleteUserData 物理删除用户的阅读会话和每日统计，注销账号时调用
+func (s *ReadingStatService) DeleteUserData(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ReadingStatService.DeleteUserData")
+	defer span.Finish()
+
+	if _, err := s.readingSessionDAO.RemoveByUserId(ctx, userId); err != nil {
+		return err
+	}
+	if _, err := s.readingDailyStatDAO.RemoveByUserId(ctx, userId); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetReadingOverview 获取阅读概览：每日序列、每周序列和连续阅读天数
+func (s *ReadingStatService) GetReadingOverview(ctx context.Context, userId string, startDate string, endDate string) (*pb.GetReadingOverviewResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ReadingStatService.GetReadingOverview")
+	defer span.Finish()
+
+	start, end, err := s.resolveDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.GetReadingOverviewResponse{
+		StartDate: start.Format(StatDateLayout),
+		EndDate:   end.Format(StatDateLayout),
+	}
+
+	stats, err := s.readingDailyStatDAO.FindByUserIdAndStatDateRange(ctx, userId, resp.StartDate, resp.EndDate)
+	if err != nil {
+		return nil, errors.Biz("reading.analytics.errors.get_failed")
+	}
+
+	// 每日序列，没有数据的日期补0，便于前端直接绘图
+	dayMap := make(map[string]*pb.ReadingDailyPoint)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		point := &pb.ReadingDailyPoint{Date: day.Format(StatDateLayout)}
+		dayMap[point.Date] = point
+		resp.Days = append(resp.Days, point)
+	}
+	for _, stat := range stats {
+		point, ok := dayMap[stat.StatDate]
+		if !ok {
+			continue
+		}
+		point.DurationSeconds += stat.DurationSeconds
+		point.SessionCount += int32(stat.SessionCount)
+		point.FinishedCount += int32(stat.FinishedCount)
+		if stat.DurationSeconds > 0 {
+			point.DocCount++
+		}
+		resp.TotalDurationSeconds += stat.DurationSeconds
+		resp.TotalFinishedCount += int32(stat.FinishedCount)
+	}
+
+	// 每周序列，以周一为一周的开始
+	weekMap := make(map[string]*pb.ReadingWeeklyPoint)
+	for _, point := range resp.Days {
+		day, _ := time.Parse(StatDateLayout, point.Date)
+		weekStart := startOfWeek(day).Format(StatDateLayout)
+		week, ok := weekMap[weekStart]
+		if !ok {
+			week = &pb.ReadingWeeklyPoint{WeekStart: weekStart}
+			weekMap[weekStart] = week
+			resp.Weeks = append(resp.Weeks, week)
+		}
+		week.DurationSeconds += point.DurationSeconds
+		week.FinishedCount += point.FinishedCount
+	}
+
+	// 连续阅读天数按全部历史计算，不受查询范围限制
+	readDates, err := s.readingDailyStatDAO.FindReadDatesByUserId(ctx, userId)
+	if err != nil {
+		return nil, errors.Biz("reading.analytics.errors.get_failed")
+	}
+	resp.CurrentStreak, resp.LongestStreak = calcStreaks(readDates, time.Now().UTC())
+	return resp, nil
+}
+
+// GetPaperReadingStats 获取按文献汇总的阅读时长和标注密度，按阅读时长降序
+func (s *ReadingStatService) GetPaperReadingStats(ctx context.Context, userId string, startDate string, endDate string) (*pb.GetPaperReadingStatsResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ReadingStatService.GetPaperReadingStats")
+	defer span.Finish()
+
+	start, end, err := s.resolveDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.GetPaperReadingStatsResponse{
+		StartDate: start.Format(StatDateLayout),
+		EndDate:   end.Format(StatDateLayout),
+	}
+
+	papers, err := s.aggregateByDoc(ctx, userId, resp.StartDate, resp.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if len(papers) == 0 {
+		return resp, nil
+	}
+
+	docIds := make([]string, 0, len(papers))
+	pdfIds := make([]string, 0, len(papers))
+	for _, paper := range papers {
+		docIds = append(docIds, paper.DocId)
+		if paper.PdfId != "" {
+			pdfIds = append(pdfIds, paper.PdfId)
+		}
+	}
+
+	userDocs, err := s.userDocService.GetByUserIdAndIds(ctx, userId, docIds)
+	if err != nil {
+		return nil, err
+	}
+	docNameMap := make(map[string]string, len(userDocs))
+	for _, userDoc := range userDocs {
+		docNameMap[userDoc.Id] = userDoc.DocName
+	}
+
+	pageCountMap := make(map[string]int)
+	if s.paperPdfService != nil && len(pdfIds) > 0 {
+		pdfs, err := s.paperPdfService.GetByIds(ctx, pdfIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, pdf := range pdfs {
+			pageCountMap[pdf.Id] = pdf.PageCount
+		}
+	}
+
+	for _, paper := range papers {
+		name, ok := docNameMap[paper.DocId]
+		if !ok {
+			// 文献已被删除
+			continue
+		}
+		paper.DocName = name
+		paper.PageCount = int32(pageCountMap[paper.PdfId])
+		if paper.PageCount > 0 {
+			paper.AnnotationDensity = roundRatio(float64(paper.AnnotationCount) / float64(paper.PageCount))
+		}
+		if paper.DurationSeconds > 0 {
+			paper.AnnotationsPerHour = roundRatio(float64(paper.AnnotationCount) * 3600 / float64(paper.DurationSeconds))
+		}
+		resp.Papers = append(resp.Papers, paper)
+	}
+	sort.SliceStable(resp.Papers, func(i, j int) bool {
+		return resp.Papers[i].DurationSeconds > resp.Papers[j].DurationSeconds
+	})
+	return resp, nil
+}
+
+// GetFolderReadingStats 获取按文件夹汇总的阅读时长，一篇文献在多个文件夹时分别计入
+func (s *ReadingStatService) GetFolderReadingStats(ctx context.Context, userId string, startDate string, endDate string) (*pb.GetFolderReadingStatsResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ReadingStatService.GetFolderReadingStats")
+	defer span.Finish()
+
+	start, end, err := s.resolveDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.GetFolderReadingStatsResponse{
+		StartDate: start.Format(StatDateLayout),
+		EndDate:   end.Format(StatDateLayout),
+	}
+
+	papers, err := s.aggregateByDoc(ctx, userId, resp.StartDate, resp.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if len(papers) == 0 {
+		return resp, nil
+	}
+
+	docIds := make([]string, 0, len(papers))
+	for _, paper := range papers {
+		docIds = append(docIds, paper.DocId)
+	}
+	relations, err := s.userDocFolderRelationService.GetRelationsByUserIdAndDocIds(ctx, userId, docIds)
+	if err != nil {
+		return nil, err
+	}
+	docFolderIds := make(map[string][]string)
+	for _, relation := range relations {
+		docFolderIds[relation.DocId] = append(docFolderIds[relation.DocId], relation.FolderId)
+	}
+
+	folders, err := s.userDocFolderService.GetUserDocFolders(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	folderNameMap := make(map[string]string, len(folders))
+	for _, folder := range folders {
+		folderNameMap[folder.Id] = folder.Name
+	}
+
+	folderMap := make(map[string]*pb.FolderReadingStat)
+	for _, paper := range papers {
+		folderIds := docFolderIds[paper.DocId]
+		if len(folderIds) == 0 {
+			folderIds = []string{UnclassifiedFolderId}
+		}
+		counted := make(map[string]bool, len(folderIds))
+		for _, folderId := range folderIds {
+			if _, ok := folderNameMap[folderId]; !ok {
+				folderId = UnclassifiedFolderId
+			}
+			if counted[folderId] {
+				continue
+			}
+			counted[folderId] = true
+
+			folderStat, ok := folderMap[folderId]
+			if !ok {
+				folderStat = &pb.FolderReadingStat{FolderId: folderId, FolderName: folderNameMap[folderId]}
+				folderMap[folderId] = folderStat
+				resp.Folders = append(resp.Folders, folderStat)
+			}
+			folderStat.DurationSeconds += paper.DurationSeconds
+			folderStat.DocCount++
+			if paper.Finished {
+				folderStat.FinishedCount++
+			}
+		}
+	}
+	sort.SliceStable(resp.Folders, func(i, j int) bool {
+		return resp.Folders[i].DurationSeconds > resp.Folders[j].DurationSeconds
+	})
+	return resp, nil
+}
+
+// aggregateByDoc 将日期范围内的每日汇总按文献聚合，返回顺序与文献首次出现的日期一致
+func (s *ReadingStatService) aggregateByDoc(ctx context.Context, userId string, startDate string, endDate string) ([]*pb.PaperReadingStat, error) {
+	stats, err := s.readingDailyStatDAO.FindByUserIdAndStatDateRange(ctx, userId, startDate, endDate)
+	if err != nil {
+		return nil, errors.Biz("reading.analytics.errors.get_failed")
+	}
+
+	var papers []*pb.PaperReadingStat
+	paperMap := make(map[string]*pb.PaperReadingStat)
+	// stats按日期升序，标注数取最后一天的快照
+	for _, stat := range stats {
+		paper, ok := paperMap[stat.DocId]
+		if !ok {
+			paper = &pb.PaperReadingStat{DocId: stat.DocId}
+			paperMap[stat.DocId] = paper
+			papers = append(papers, paper)
+		}
+		if stat.NoteId != "" {
+			paper.NoteId = stat.NoteId
+		}
+		if stat.PdfId != "" {
+			paper.PdfId = stat.PdfId
+		}
+		paper.DurationSeconds += stat.DurationSeconds
+		paper.SessionCount += int32(stat.SessionCount)
+		if stat.DurationSeconds > 0 {
+			paper.ReadDays++
+			paper.LastReadDate = stat.StatDate
+		}
+		if stat.FinishedCount > 0 {
+			paper.Finished = true
+		}
+		paper.AnnotationCount = stat.AnnotationCount
+	}
+	return papers, nil
+}
+
+// resolveDateRange 解析并校验查询日期范围，为空时默认最近30天
+func (s *ReadingStatService) resolveDateRange(startDate string, endDate string) (time.Time, time.Time, error) {
+	today, _ := time.Parse(StatDateLayout, time.Now().UTC().Format(StatDateLayout))
+
+	end := today
+	if endDate != "" {
+		parsed, err := time.Parse(StatDateLayout, endDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.Biz("reading.analytics.errors.invalid_date")
+		}
+		end = parsed
+	}
+	start := end.AddDate(0, 0, -29)
+	if startDate != "" {
+		parsed, err := time.Parse(StatDateLayout, startDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.Biz("reading.analytics.errors.invalid_date")
+		}
+		start = parsed
+	}
+
+	maxRangeDays := s.cfg.ReadingAnalytics.MaxRangeDays
+	if maxRangeDays <= 0 {
+		maxRangeDays = 366
+	}
+	if start.After(end) || end.Sub(start) >= time.Duration(maxRangeDays)*24*time.Hour {
+		return time.Time{}, time.Time{}, errors.Biz("reading.analytics.errors.invalid_date_range")
+	}
+	return start, end, nil
+}
+
+// startOfWeek 返回日期所在周的周一
+func startOfWeek(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// calcStreaks 根据升序的阅读日期计算当前连续天数和最长连续天数
+// 今天还没有阅读时，截止到昨天的连续天数仍算作当前连续天数
+func calcStreaks(readDates []string, now time.Time) (int32, int32) {
+	var longest, run int32
+	var prev time.Time
+	for i, date := range readDates {
+		day, err := time.Parse(StatDateLayout, date)
+		if err != nil {
+			continue
+		}
+		if i > 0 && day.Sub(prev) == 24*time.Hour {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+		prev = day
+	}
+	if len(readDates) == 0 {
+		return 0, 0
+	}
+
+	today, _ := time.Parse(StatDateLayout, now.Format(StatDateLayout))
+	if gap := today.Sub(prev); gap != 0 && gap != 24*time.Hour {
+		return 0, longest
+	}
+	return run, longest
+}
+
+// roundRatio 保留两位小数
+func roundRatio(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	baseModel "github.com/yb2020/odoc/pkg/model"
+	docDao "github.com/yb2020/odoc/services/doc/dao"
+	docModel "github.com/yb2020/odoc/services/doc/model"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	pdfInterfaces "github.com/yb2020/odoc/services/pdf/interfaces"
+	"github.com/yb2020/odoc/services/reading/dao"
+	"github.com/yb2020/odoc/services/reading/model"
+)
+
+func TestCalcStreaks(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		dates       []string
+		wantCurrent int32
+		wantLongest int32
+	}{
+		{name: "no reading", dates: nil, wantCurrent: 0, wantLongest: 0},
+		{name: "read today only", dates: []string{"2026-03-10"}, wantCurrent: 1, wantLongest: 1},
+		{name: "streak ends today", dates: []string{"2026-03-08", "2026-03-09", "2026-03-10"}, wantCurrent: 3, wantLongest: 3},
+		{name: "streak ends yesterday", dates: []string{"2026-03-08", "2026-03-09"}, wantCurrent: 2, wantLongest: 2},
+		{name: "streak broken", dates: []string{"2026-03-01", "2026-03-02", "2026-03-03", "2026-03-07"}, wantCurrent: 0, wantLongest: 3},
+		{name: "longest in the past", dates: []string{"2026-02-01", "2026-02-02", "2026-02-03", "2026-03-09", "2026-03-10"}, wantCurrent: 2, wantLongest: 3},
+		{name: "across month end", dates: []string{"2026-02-27", "2026-02-28", "2026-03-01"}, wantCurrent: 0, wantLongest: 3},
+		{name: "invalid date skipped", dates: []string{"2026-03-09", "bad", "2026-03-10"}, wantCurrent: 2, wantLongest: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, longest := calcStreaks(tt.dates, now)
+			if current != tt.wantCurrent || longest != tt.wantLongest {
+				t.Fatalf("calcStreaks = (%d, %d), want (%d, %d)", current, longest, tt.wantCurrent, tt.wantLongest)
+			}
+		})
+	}
+}
+
+func TestStartOfWeek(t *testing.T) {
+	tests := []struct {
+		day  string
+		want string
+	}{
+		{day: "2026-03-09", want: "2026-03-09"}, // 周一
+		{day: "2026-03-12", want: "2026-03-09"},
+		{day: "2026-03-15", want: "2026-03-09"}, // 周日
+	}
+	for _, tt := range tests {
+		day, _ := time.Parse(StatDateLayout, tt.day)
+		if got := startOfWeek(day).Format(StatDateLayout); got != tt.want {
+			t.Fatalf("startOfWeek(%s) = %s, want %s", tt.day, got, tt.want)
+		}
+	}
+}
+
+// fakePaperPdfService 按笔记返回固定的标注数，记录批量查询的次数
+type fakePaperPdfService struct {
+	pdfInterfaces.IPaperPdfService
+	counts map[string]int64
+	calls  int
+}
+
+func (s *fakePaperPdfService) GetCountPdfMarksByNoteIds(ctx context.Context, noteIds []string) (map[string]int64, error) {
+	s.calls++
+	counts := make(map[string]int64, len(noteIds))
+	for _, noteId := range noteIds {
+		if count, ok := s.counts[noteId]; ok {
+			counts[noteId] = count
+		}
+	}
+	return counts, nil
+}
+
+func TestRollupDailyStats(t *testing.T) {
+	db := daotest.NewDB(t, &model.ReadingSession{}, &model.ReadingDailyStat{}, &docModel.UserDoc{})
+	logger := daotest.NewLogger()
+	tracer := opentracing.NoopTracer{}
+	sessionDAO := dao.NewReadingSessionDAO(db, logger)
+	statDAO := dao.NewReadingDailyStatDAO(db, logger)
+	userDocDAO := docDao.NewUserDocDAO(db, logger)
+	userDocService := docService.NewUserDocService(logger, tracer, nil, userDocDAO,
+		nil, nil, nil, nil, nil, nil, nil, nil, &config.Config{}, nil, nil)
+	marks := &fakePaperPdfService{counts: map[string]int64{"note-d1": 3}}
+	statService := NewReadingStatService(&config.Config{}, logger, tracer, sessionDAO, statDAO, userDocService, nil, nil, marks)
+
+	ctx := context.Background()
+	addSession := func(userId string, docId string, statDate string, seconds int64) {
+		session := &model.ReadingSession{UserId: userId, DocId: docId, NoteId: "note-" + docId, StatDate: statDate, DurationSeconds: seconds}
+		if err := sessionDAO.Save(ctx, session); err != nil {
+			t.Fatalf("save session: %v", err)
+		}
+	}
+	statsOf := func(statDate string) map[string]model.ReadingDailyStat {
+		stats, err := statDAO.FindByStatDate(ctx, statDate)
+		if err != nil {
+			t.Fatalf("find stats: %v", err)
+		}
+		result := make(map[string]model.ReadingDailyStat, len(stats))
+		for _, stat := range stats {
+			result[stat.UserId+"/"+stat.DocId] = stat
+		}
+		return result
+	}
+	statDate := "2026-03-10"
+	finishedAt := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+
+	addSession("u1", "d1", statDate, 600)
+	addSession("u1", "d1", statDate, 300)
+	addSession("u1", "d2", statDate, 120)
+	addSession("u2", "d3", statDate, 60)
+	addSession("u1", "", statDate, 999)        // 没有关联文献的会话不汇总
+	addSession("u1", "d1", "2026-03-09", 1000) // 其他日期
+	userDoc := &docModel.UserDoc{BaseModel: baseModel.BaseModel{Id: "d2"}, UserId: "u1", ReadFinishedTime: &finishedAt}
+	if err := userDocDAO.Save(ctx, userDoc); err != nil {
+		t.Fatalf("save user doc: %v", err)
+	}
+
+	if err := statService.RollupDailyStats(ctx, statDate); err != nil {
+		t.Fatalf("RollupDailyStats: %v", err)
+	}
+	stats := statsOf(statDate)
+	if len(stats) != 3 {
+		t.Fatalf("stats = %+v, want 3 rows", stats)
+	}
+	// 所有笔记的标注数通过一次批量查询获取
+	if marks.calls != 1 {
+		t.Fatalf("annotation count queries = %d, want 1", marks.calls)
+	}
+	if s := stats["u1/d1"]; s.DurationSeconds != 900 || s.SessionCount != 2 || s.FinishedCount != 0 || s.NoteId != "note-d1" || s.AnnotationCount != 3 {
+		t.Fatalf("u1/d1 = %+v", s)
+	}
+	if s := stats["u1/d2"]; s.DurationSeconds != 120 || s.SessionCount != 1 || s.FinishedCount != 1 || s.AnnotationCount != 0 {
+		t.Fatalf("u1/d2 = %+v", s)
+	}
+	if s := stats["u2/d3"]; s.DurationSeconds != 60 || s.SessionCount != 1 {
+		t.Fatalf("u2/d3 = %+v", s)
+	}
+
+	// 重复执行结果不变，不产生重复行
+	if err := statService.RollupDailyStats(ctx, statDate); err != nil {
+		t.Fatalf("RollupDailyStats again: %v", err)
+	}
+	again := statsOf(statDate)
+	if len(again) != 3 || again["u1/d1"].DurationSeconds != 900 || again["u1/d1"].Id != stats["u1/d1"].Id {
+		t.Fatalf("stats after rerun = %+v", again)
+	}
+
+	// 读完时间被刷新到其他日期后，原日期的读完标记被清除
+	movedAt := time.Date(2026, 3, 11, 8, 0, 0, 0, time.UTC)
+	userDoc.ReadFinishedTime = &movedAt
+	if err := userDocDAO.Modify(ctx, userDoc); err != nil {
+		t.Fatalf("modify user doc: %v", err)
+	}
+	if err := statService.RollupDailyStats(ctx, statDate); err != nil {
+		t.Fatalf("RollupDailyStats after move: %v", err)
+	}
+	if s := statsOf(statDate)["u1/d2"]; s.FinishedCount != 0 || s.DurationSeconds != 120 {
+		t.Fatalf("u1/d2 after move = %+v", s)
+	}
+
+	// 日期格式不合法时报错
+	if err := statService.RollupDailyStats(ctx, "2026/03/10"); err == nil {
+		t.Fatalf("RollupDailyStats invalid date err = nil")
+	}
+}
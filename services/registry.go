@@ -8,6 +8,7 @@ import (
 	"github.com/yb2020/odoc/pkg/dao"
 	"github.com/yb2020/odoc/pkg/distlock"
 	"github.com/yb2020/odoc/pkg/eventbus"
+	"github.com/yb2020/odoc/pkg/health"
 	"github.com/yb2020/odoc/pkg/http_client"
 	"github.com/yb2020/odoc/pkg/i18n"
 	"github.com/yb2020/odoc/pkg/logging"
@@ -15,7 +16,11 @@ import (
 	"github.com/yb2020/odoc/pkg/oss"
 	"github.com/yb2020/odoc/pkg/registry"
 	"github.com/yb2020/odoc/pkg/scheduler"
+	pkgTakeout "github.com/yb2020/odoc/pkg/takeout"
+	pkgTrash "github.com/yb2020/odoc/pkg/trash"
 	"github.com/yb2020/odoc/pkg/utils"
+	"github.com/yb2020/odoc/services/account_deletion"
+	"github.com/yb2020/odoc/services/audit"
 	"github.com/yb2020/odoc/services/doc"
 	"github.com/yb2020/odoc/services/event_tracker"
 	"github.com/yb2020/odoc/services/membership"
@@ -28,7 +33,10 @@ import (
 	"github.com/yb2020/odoc/services/parse"
 	"github.com/yb2020/odoc/services/pay"
 	"github.com/yb2020/odoc/services/pdf"
+	"github.com/yb2020/odoc/services/reading"
+	"github.com/yb2020/odoc/services/takeout"
 	"github.com/yb2020/odoc/services/translate"
+	"github.com/yb2020/odoc/services/trash"
 	"github.com/yb2020/odoc/services/user"
 	"gorm.io/gorm"
 )
@@ -37,6 +45,10 @@ var (
 	// 全局变量，用于存储已初始化的模块
 	initializedModules     []registry.Module
 	initializedGRPCModules []registry.GRPCModule
+	// 认证中间件，HTTP 路由和 gRPC 拦截器共用
+	authMiddlewareInstance *middleware.AuthMiddleware
+	// 接口限流中间件，未启用限流时为 nil
+	rateLimitMiddlewareInstance *middleware.RateLimitMiddleware
 )
 
 // InitializeModules 初始化所有模块
@@ -45,6 +57,8 @@ func InitializeModules(db *gorm.DB, redis database.RedisClient, localizer i18n.L
 	// 清空已初始化的模块列表
 	initializedModules = nil
 	initializedGRPCModules = nil
+	authMiddlewareInstance = nil
+	rateLimitMiddlewareInstance = nil
 
 	// 创建事件总线
 	eventBus := eventbus.NewEventBus()
@@ -71,7 +85,8 @@ func InitializeModules(db *gorm.DB, redis database.RedisClient, localizer i18n.L
 	rsaUtil := utils.NewRSAUtil(config.OAuth2.RSA.PublicKey, config.OAuth2.RSA.PrivateKey)
 
 	// 初始化OAuth2模块（依赖用户模块）
-	oauth2Module := oauth2.NewOAuth2Module(db, redis, config, logger, tracer, localizer, userModule.GetUserService(), rsaUtil, eventBus)
+	oauth2Module := oauth2.NewOAuth2Module(db, redis, config, logger, tracer, localizer, userModule.GetUserService(),
+		userModule.GetIdentityService(), rsaUtil, eventBus)
 	if err := oauth2Module.Initialize(); err != nil {
 		return err
 	}
@@ -82,16 +97,25 @@ func InitializeModules(db *gorm.DB, redis database.RedisClient, localizer i18n.L
 
 	// 创建认证中间件
 	authMiddleware := middleware.NewAuthMiddleware(*config, logger, localizer, authAdapter)
+	authMiddlewareInstance = authMiddleware
 
 	// 更新已初始化模块的认证中间件
 	userModule.SetAuthMiddleware(authMiddleware)
 	oauth2Module.SetAuthMiddleware(authMiddleware)
 
+	// 初始化审计日志模块，需在其他业务模块之前完成，业务代码通过 pkg/audit 记录审计日志
+	auditModule := audit.NewAuditModule(db, config, logger, tracer, authMiddleware)
+	if err := auditModule.Initialize(); err != nil {
+		return err
+	}
+	initializedModules = append(initializedModules, auditModule)
+
 	// 初始化OSS存储
 	storage, err := oss.NewStorageInterface(config)
 	if err != nil {
 		return err
 	}
+	storage = oss.NewTracedStorage(storage, tracer)
 
 	// 初始化OSS模块
 	ossModule := ossService.NewOssModule(db, config, logger, tracer, storage)
@@ -109,6 +133,16 @@ func InitializeModules(db *gorm.DB, redis database.RedisClient, localizer i18n.L
 	}
 	initializedModules = append(initializedModules, membershipModule)
 
+	// 初始化接口限流中间件，额度按会员类型区分
+	if config.RateLimit.Enabled {
+		rateLimitMiddleware, err := middleware.NewRateLimitMiddleware(*config, logger, localizer, redis, authMiddleware, membershipModule.GetRateLimitTierResolver())
+		if err != nil {
+			logger.Error("msg", "初始化接口限流中间件失败", "error", err.Error())
+			return err
+		}
+		rateLimitMiddlewareInstance = rateLimitMiddleware
+	}
+
 	// 初始化论文模块
 	paperModule := paper.NewPaperModule(db, config, logger, tracer, authMiddleware, userModule.GetUserService())
 	if err := paperModule.Initialize(); err != nil {
@@ -140,6 +174,8 @@ func InitializeModules(db *gorm.DB, redis database.RedisClient, localizer i18n.L
 		docModule.GetUserDocService(), noteModule.GetPaperNoteService(),
 		noteModule.GetPaperNoteAccessService(), ossModule.GetOssService(),
 		paperModule.GetPaperAccessService(), paperModule.GetPaperPdfParsedService(),
+		noteModule.GetNoteSummaryService(), membershipModule.GetMembershipService(),
+		httpClient,
 	)
 	if err := pdfModule.Initialize(); err != nil {
 		return err
@@ -190,6 +226,63 @@ func InitializeModules(db *gorm.DB, redis database.RedisClient, localizer i18n.L
 	}
 	initializedModules = append(initializedModules, eventTrackerModule)
 
+	// 初始化阅读统计模块
+	readingModule := reading.NewReadingModule(db, config, logger, tracer, authMiddleware,
+		docModule.GetUserDocService(),
+		docModule.GetUserDocFolderService(),
+		docModule.GetUserDocFolderRelationService(),
+		noteModule.GetPaperNoteService(),
+		pdfModule.GetPaperPdfService(),
+	)
+	if err := readingModule.Initialize(); err != nil {
+		return err
+	}
+	initializedModules = append(initializedModules, readingModule)
+
+	// 初始化用户数据导出模块，导出器在所有业务模块初始化完成后收集
+	takeoutModule := takeout.NewTakeoutModule(db, config, logger, tracer, localizer, authMiddleware,
+		ossModule.GetOssService(), userModule.GetUserService())
+	if err := takeoutModule.Initialize(); err != nil {
+		return err
+	}
+	var takeoutExporters []pkgTakeout.Exporter
+	for _, module := range initializedModules {
+		if provider, ok := module.(registry.TakeoutExporterProvider); ok {
+			takeoutExporters = append(takeoutExporters, provider.TakeoutExporters()...)
+		}
+	}
+	takeoutModule.SetExporters(takeoutExporters)
+	initializedModules = append(initializedModules, takeoutModule)
+
+	// 初始化回收站模块，处理器在所有业务模块初始化完成后收集
+	trashModule := trash.NewTrashModule(db, config, logger, tracer, authMiddleware, transactionManager)
+	if err := trashModule.Initialize(); err != nil {
+		return err
+	}
+	var trashHandlers []pkgTrash.Handler
+	for _, module := range initializedModules {
+		if provider, ok := module.(registry.TrashHandlerProvider); ok {
+			trashHandlers = append(trashHandlers, provider.TrashHandlers()...)
+		}
+	}
+	trashModule.SetHandlers(trashHandlers)
+	initializedModules = append(initializedModules, trashModule)
+
+	// 初始化账号注销模块，删除器按模块初始化的逆序执行，依赖方的数据先于被依赖方删除，用户账号最后删除
+	accountDeletionModule := account_deletion.NewAccountDeletionModule(db, config, logger, tracer, localizer, authMiddleware,
+		userModule.GetUserService())
+	if err := accountDeletionModule.Initialize(); err != nil {
+		return err
+	}
+	var userDataDeleters []registry.UserDataDeleter
+	for i := len(initializedModules) - 1; i >= 0; i-- {
+		if deleter, ok := initializedModules[i].(registry.UserDataDeleter); ok {
+			userDataDeleters = append(userDataDeleters, deleter)
+		}
+	}
+	accountDeletionModule.SetDeleters(userDataDeleters)
+	initializedModules = append(initializedModules, accountDeletionModule)
+
 	//============================ 依赖注入 ============================
 	// 解决循环依赖：为docModule注入noteModule的服务
 	if err := docModule.SetNoteService(noteModule.GetPaperNoteService()); err != nil {
@@ -205,12 +298,21 @@ func InitializeModules(db *gorm.DB, redis database.RedisClient, localizer i18n.L
 		logger.Error("msg", "设置文献文件夹和文献文件夹依赖注入失败", "error", err.Error())
 		return err
 	}
+	if err := docModule.SetPdfReleaser(pdfModule.GetPdfUserDataDeleter()); err != nil {
+		logger.Error("msg", "设置PDF文件释放器失败", "error", err.Error())
+		return err
+	}
 
 	if err := noteModule.SetPaperPdfService(pdfModule.GetPaperPdfService()); err != nil {
 		logger.Error("msg", "设置笔记服务失败", "error", err.Error())
 		return err
 	}
 	logger.Info("msg", "成功为Note模块设置论文PDF服务")
+	if err := noteModule.SetReadingHeartbeatRecorder(readingModule.GetReadingSessionService()); err != nil {
+		logger.Error("msg", "设置阅读心跳记录器失败", "error", err.Error())
+		return err
+	}
+	logger.Info("msg", "成功为Note模块设置阅读心跳记录器")
 	if err := ossModule.SetPaperPdfParsedService(paperModule.GetPaperPdfParsedService()); err != nil {
 		logger.Error("msg", "设置笔记服务失败", "error", err.Error())
 		return err
@@ -266,6 +368,16 @@ func GetAllGRPCModules() []registry.GRPCModule {
 	return modules
 }
 
+// GetAuthMiddleware 返回认证中间件，模块尚未初始化时返回 nil
+func GetAuthMiddleware() *middleware.AuthMiddleware {
+	return authMiddlewareInstance
+}
+
+// GetRateLimitMiddleware 返回接口限流中间件，未启用限流或模块尚未初始化时返回 nil
+func GetRateLimitMiddleware() *middleware.RateLimitMiddleware {
+	return rateLimitMiddlewareInstance
+}
+
 // RegisterAllModuleRoutes 注册所有模块的路由
 func RegisterAllModuleRoutes(r *gin.Engine) {
 	for _, module := range GetAllModules() {
@@ -279,3 +391,12 @@ func RegisterAllModuleJobSchedulers(scheduler *scheduler.Scheduler) {
 		module.RegisterJobSchedulers(scheduler)
 	}
 }
+
+// RegisterAllModuleHealthChecks 注册所有模块的健康检查
+func RegisterAllModuleHealthChecks(healthRegistry *health.Registry) {
+	for _, module := range GetAllModules() {
+		if registrar, ok := module.(registry.HealthCheckRegistrar); ok {
+			registrar.RegisterHealthChecks(healthRegistry)
+		}
+	}
+}
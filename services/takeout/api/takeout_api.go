@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/takeout"
+	"github.com/yb2020/odoc/services/takeout/service"
+)
+
+// TakeoutAPI 用户数据导出API处理器
+type TakeoutAPI struct {
+	logger         logging.Logger
+	tracer         opentracing.Tracer
+	localizer      i18n.Localizer
+	takeoutService *service.TakeoutService
+}
+
+// NewTakeoutAPI 创建用户数据导出API处理器
+func NewTakeoutAPI(logger logging.Logger, tracer opentracing.Tracer, localizer i18n.Localizer, takeoutService *service.TakeoutService) *TakeoutAPI {
+	return &TakeoutAPI{
+		logger:         logger,
+		tracer:         tracer,
+		localizer:      localizer,
+		takeoutService: takeoutService,
+	}
+}
+
+// @api /api/takeout/request
+// @method POST
+// @apiDescription 申请导出当前用户的全部数据，导出完成后发送邮件通知
+func (api *TakeoutAPI) Request(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "TakeoutAPI.Request")
+	defer span.Finish()
+
+	req := &pb.CreateTakeoutRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	task, err := api.takeoutService.Request(ctx, userId, api.localizer.GetLanguage(c))
+	if err != nil {
+		api.logger.Warn("msg", "申请导出用户数据失败", "userId", userId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", task)
+}
+
+// @api /api/takeout/get
+// @method GET
+// @apiDescription 查询导出申请，不传 id 时返回最近一次申请，可下载时返回临时下载地址
+func (api *TakeoutAPI) Get(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "TakeoutAPI.Get")
+	defer span.Finish()
+
+	req := &pb.GetTakeoutRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	task, err := api.takeoutService.Get(ctx, userId, req.Id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if task == nil {
+		response.SuccessNoData(c, "success")
+		return
+	}
+	response.Success(c, "success", task)
+}
+
+// @api /api/takeout/download
+// @method GET
+// @apiDescription 下载导出的压缩包，仅在导出完成且未过期时可下载
+func (api *TakeoutAPI) Download(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "TakeoutAPI.Download")
+	defer span.Finish()
+
+	req := &pb.DownloadTakeoutRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	file, err := api.takeoutService.Download(ctx, userId, req.Id)
+	if err != nil {
+		api.logger.Warn("msg", "下载导出压缩包失败", "userId", userId, "id", req.Id, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	defer file.Reader.Close()
+
+	c.DataFromReader(http.StatusOK, file.Size, "application/zip", file.Reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=\"%s\"", file.FileName),
+	})
+}
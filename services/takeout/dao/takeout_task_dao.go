@@ -0,0 +1,108 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/takeout/model"
+	"gorm.io/gorm"
+)
+
+// TakeoutTaskDAO GORM实现的用户数据导出申请DAO
+type TakeoutTaskDAO struct {
+	*baseDao.GormBaseDAO[model.TakeoutTask]
+	logger logging.Logger
+}
+
+// NewTakeoutTaskDAO 创建一个新的用户数据导出申请DAO
+func NewTakeoutTaskDAO(db *gorm.DB, logger logging.Logger) *TakeoutTaskDAO {
+	return &TakeoutTaskDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.TakeoutTask](db, logger),
+		logger:      logger,
+	}
+}
+
+// GetLatestByUserId 获取用户最近一次导出申请
+func (d *TakeoutTaskDAO) GetLatestByUserId(ctx context.Context, userId string) (*model.TakeoutTask, error) {
+	var task model.TakeoutTask
+	result := d.GetDB(ctx).Where("user_id = ? AND is_deleted = false", userId).Order("created_at desc, id desc").First(&task)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取用户最近一次导出申请失败", "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &task, nil
+}
+
+// GetListByUserId 获取用户的全部导出申请，包括已逻辑删除的
+func (d *TakeoutTaskDAO) GetListByUserId(ctx context.Context, userId string) ([]model.TakeoutTask, error) {
+	var tasks []model.TakeoutTask
+	result := d.GetDB(ctx).Where("user_id = ?", userId).Find(&tasks)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取用户导出申请列表失败", "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return tasks, nil
+}
+
+// GetByIdAndUserId 获取用户的导出申请
+func (d *TakeoutTaskDAO) GetByIdAndUserId(ctx context.Context, id string, userId string) (*model.TakeoutTask, error) {
+	var task model.TakeoutTask
+	result := d.GetDB(ctx).Where("id = ? AND user_id = ? AND is_deleted = false", id, userId).First(&task)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "获取导出申请失败", "id", id, "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return &task, nil
+}
+
+// GetRunnable 按申请时间获取待导出的申请，包括排队中的和开始时间早于 staleBefore 的导出中申请（上次执行被中断）
+func (d *TakeoutTaskDAO) GetRunnable(ctx context.Context, staleBefore time.Time, limit int) ([]model.TakeoutTask, error) {
+	var tasks []model.TakeoutTask
+	result := d.GetDB(ctx).Where("is_deleted = false AND (status = ? OR (status = ? AND started_at < ?))",
+		model.TakeoutStatusPending, model.TakeoutStatusRunning, staleBefore).
+		Order("created_at asc, id asc").Limit(limit).Find(&tasks)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取待导出申请失败", "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return tasks, nil
+}
+
+// Claim 将申请标记为导出中并累加尝试次数，返回是否标记成功
+// 条件与 GetRunnable 一致，多个实例同时执行时只有一个能标记成功
+func (d *TakeoutTaskDAO) Claim(ctx context.Context, id string, staleBefore time.Time, startedAt time.Time) (bool, error) {
+	result := d.GetDB(ctx).Model(&model.TakeoutTask{}).
+		Where("id = ? AND (status = ? OR (status = ? AND started_at < ?))",
+			id, model.TakeoutStatusPending, model.TakeoutStatusRunning, staleBefore).
+		Updates(map[string]interface{}{
+			"status":     model.TakeoutStatusRunning,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"started_at": startedAt,
+		})
+	if result.Error != nil {
+		d.logger.Error("msg", "标记导出申请失败", "id", id, "error", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetExpired 获取下载有效期已过但仍为已完成状态的申请
+func (d *TakeoutTaskDAO) GetExpired(ctx context.Context, now time.Time, limit int) ([]model.TakeoutTask, error) {
+	var tasks []model.TakeoutTask
+	result := d.GetDB(ctx).Where("status = ? AND expires_at < ? AND is_deleted = false", model.TakeoutStatusSucceeded, now).
+		Order("expires_at asc").Limit(limit).Find(&tasks)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取已过期导出申请失败", "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return tasks, nil
+}
@@ -0,0 +1,62 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/services/takeout/service"
+)
+
+// TakeoutExportJob 用户数据导出任务，生成排队中的导出压缩包并处理过期的压缩包
+type TakeoutExportJob struct {
+	logger         logging.Logger
+	spec           string                 // 任务的cron表达式，6字段标准cron表达式
+	key            string                 // 任务的锁key，必须是唯一的unique-job-key
+	expiry         time.Duration          // 任务的锁过期时间
+	lockOpts       *scheduler.LockOptions // 任务的锁选项
+	takeoutService *service.TakeoutService
+}
+
+func NewTakeoutExportJob(logger logging.Logger, cfg *config.Config, takeoutService *service.TakeoutService) *TakeoutExportJob {
+	spec := cfg.Scheduler.Jobs.TakeoutExportJob.Spec
+	key := cfg.Scheduler.Jobs.TakeoutExportJob.Key
+	expiry := time.Duration(cfg.Scheduler.Jobs.TakeoutExportJob.Expiry) * time.Second
+	lockOpts := &scheduler.LockOptions{
+		Key:    key,
+		Expiry: expiry,
+	}
+	return &TakeoutExportJob{logger: logger, spec: spec, key: key, expiry: expiry, lockOpts: lockOpts, takeoutService: takeoutService}
+}
+
+// Spec 获取任务的cron表达式
+func (j *TakeoutExportJob) Spec() string {
+	return j.spec
+}
+
+// LockOpts 获取任务的锁选项
+func (j *TakeoutExportJob) LockOpts() *scheduler.LockOptions {
+	return j.lockOpts
+}
+
+// NewUserContext 每个申请的用户上下文由导出服务设置，这里直接返回原上下文
+func (j *TakeoutExportJob) NewUserContext(ctx context.Context, userId string) context.Context {
+	return ctx
+}
+
+// Run 执行任务，在执行任务前会获取锁，执行任务后会释放锁
+func (j *TakeoutExportJob) Run() {
+	ctx := context.Background()
+	expired, err := j.takeoutService.ExpireTasks(ctx)
+	if err != nil {
+		j.logger.Error("msg", "Takeout expire failed", "expired", expired, "error", err)
+	}
+	succeeded, err := j.takeoutService.ProcessPending(ctx)
+	if err != nil {
+		j.logger.Error("msg", "Takeout export job failed", "succeeded", succeeded, "error", err)
+		return
+	}
+	j.logger.Info("msg", "Takeout export job success", "succeeded", succeeded, "expired", expired)
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// 导出申请状态
+const (
+	TakeoutStatusPending   = "pending"   // 排队中
+	TakeoutStatusRunning   = "running"   // 导出中
+	TakeoutStatusSucceeded = "succeeded" // 已完成，可在有效期内下载
+	TakeoutStatusFailed    = "failed"    // 多次尝试后仍失败
+	TakeoutStatusExpired   = "expired"   // 已超过下载有效期
+)
+
+// TakeoutTask 用户数据导出申请
+type TakeoutTask struct {
+	model.BaseModel           // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	UserId          string    `json:"userId" gorm:"column:user_id;size:36;index"`                              // 申请用户ID
+	Status          string    `json:"status" gorm:"column:status;type:varchar(20);index;comment:状态"`           // 状态
+	Language        string    `json:"language" gorm:"column:language;type:varchar(10);comment:通知邮件语言"`         // 申请时的界面语言，用于通知邮件
+	Attempts        int       `json:"attempts" gorm:"column:attempts;type:int;default:0;comment:已尝试次数"`        // 已尝试导出的次数
+	BucketName      string    `json:"bucketName" gorm:"column:bucket_name;type:varchar(100);comment:OSS存储桶名称"` // OSS存储桶名称
+	ObjectKey       string    `json:"objectKey" gorm:"column:object_key;type:varchar(255);comment:OSS对象名称"`    // OSS对象名称
+	FileSize        int64     `json:"fileSize" gorm:"column:file_size;type:bigint;comment:压缩包大小"`              // 压缩包大小（字节）
+	FileSHA256      string    `json:"fileSHA256" gorm:"column:file_sha256;type:varchar(64);comment:压缩包SHA256"` // 压缩包SHA256
+	ErrorMessage    string    `json:"errorMessage" gorm:"column:error_message;type:varchar(500);comment:失败原因"` // 最近一次失败原因
+	StartedAt       time.Time `json:"startedAt" gorm:"column:started_at;comment:开始导出时间"`                       // 最近一次开始导出的时间
+	FinishedAt      time.Time `json:"finishedAt" gorm:"column:finished_at;comment:完成时间"`                       // 完成或最终失败的时间
+	ExpiresAt       time.Time `json:"expiresAt" gorm:"column:expires_at;index;comment:下载截止时间"`                 // 下载截止时间
+}
+
+// TableName 返回表名
+func (TakeoutTask) TableName() string {
+	return "t_takeout_task"
+}
@@ -0,0 +1,141 @@
+package takeout
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/mail"
+	"github.com/yb2020/odoc/pkg/middleware"
+	"github.com/yb2020/odoc/pkg/registry"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	pkgTakeout "github.com/yb2020/odoc/pkg/takeout"
+	ossService "github.com/yb2020/odoc/services/oss/service"
+	"github.com/yb2020/odoc/services/takeout/api"
+	"github.com/yb2020/odoc/services/takeout/dao"
+	"github.com/yb2020/odoc/services/takeout/job"
+	"github.com/yb2020/odoc/services/takeout/service"
+	userService "github.com/yb2020/odoc/services/user/service"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+)
+
+// 编译时类型检查：确保 TakeoutModule 实现了 registry.Module 接口
+var _ registry.Module = (*TakeoutModule)(nil)
+
+// 编译时类型检查：确保 TakeoutModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*TakeoutModule)(nil)
+
+// Module 导出模块实例，用于自动发现和注册
+var Module = &TakeoutModule{}
+
+// TakeoutModule 用户数据导出模块
+type TakeoutModule struct {
+	db             *gorm.DB
+	logger         logging.Logger
+	tracer         opentracing.Tracer
+	config         *config.Config
+	localizer      i18n.Localizer
+	authMiddleware *middleware.AuthMiddleware
+	ossService     ossService.OssServiceInterface
+	userService    *userService.UserService
+	takeoutAPI     *api.TakeoutAPI
+	takeoutService *service.TakeoutService
+}
+
+// NewTakeoutModule 创建用户数据导出模块
+func NewTakeoutModule(db *gorm.DB,
+	config *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	localizer i18n.Localizer,
+	authMiddleware *middleware.AuthMiddleware,
+	ossService ossService.OssServiceInterface,
+	userService *userService.UserService,
+) *TakeoutModule {
+	return &TakeoutModule{
+		db:             db,
+		logger:         logger,
+		tracer:         tracer,
+		config:         config,
+		localizer:      localizer,
+		authMiddleware: authMiddleware,
+		ossService:     ossService,
+		userService:    userService,
+	}
+}
+
+// Name 返回模块名称
+func (m *TakeoutModule) Name() string {
+	return "takeout"
+}
+
+// Initialize 初始化模块
+func (m *TakeoutModule) Initialize() error {
+	m.logger.Info("msg", "初始化用户数据导出模块")
+	takeoutTaskDAO := dao.NewTakeoutTaskDAO(m.db, m.logger)
+	mailer := mail.NewMailer(&m.config.Mail, m.logger)
+
+	m.takeoutService = service.NewTakeoutService(m.config, m.logger, m.tracer, m.localizer, mailer,
+		takeoutTaskDAO, m.ossService, m.userService)
+	m.takeoutAPI = api.NewTakeoutAPI(m.logger, m.tracer, m.localizer, m.takeoutService)
+	return nil
+}
+
+// SetExporters 设置各模块的数据导出器，需在所有模块初始化完成后调用
+func (m *TakeoutModule) SetExporters(exporters []pkgTakeout.Exporter) {
+	m.takeoutService.SetExporters(exporters)
+	m.logger.Info("msg", "成功为用户数据导出模块设置导出器", "count", len(exporters))
+}
+
+// GetTakeoutService 获取用户数据导出服务
+func (m *TakeoutModule) GetTakeoutService() *service.TakeoutService {
+	return m.takeoutService
+}
+
+// DeleteUserData 注销账号时删除用户的导出压缩包和导出申请
+func (m *TakeoutModule) DeleteUserData(ctx context.Context, userId string) error {
+	return m.takeoutService.DeleteUserData(ctx, userId)
+}
+
+// Shutdown 关闭模块
+func (m *TakeoutModule) Shutdown() error {
+	m.logger.Info("msg", "关闭用户数据导出模块")
+	return nil
+}
+
+// RegisterGRPC 注册gRPC服务
+func (m *TakeoutModule) RegisterGRPC(server *grpc.Server) {
+	// 用户数据导出模块没有gRPC服务，不需要注册
+	m.logger.Debug("msg", "用户数据导出模块没有gRPC服务，跳过注册")
+}
+
+// RegisterJobSchedulers 注册Job定时任务
+func (m *TakeoutModule) RegisterJobSchedulers(scheduler *scheduler.Scheduler) {
+	if scheduler == nil {
+		m.logger.Debug("msg", "调度器未启用，用户数据导出模块跳过Job注册")
+		return
+	}
+	m.logger.Debug("msg", "用户数据导出模块注册Job定时任务")
+	exportJob := job.NewTakeoutExportJob(m.logger, m.config, m.takeoutService)
+	scheduler.RegisterJobs(exportJob)
+}
+
+// RegisterProviders 注册Provider
+func (m *TakeoutModule) RegisterProviders() {
+	m.logger.Debug("msg", "用户数据导出模块没有Provider，跳过注册")
+}
+
+// RegisterRoutes 注册路由
+func (m *TakeoutModule) RegisterRoutes(r *gin.Engine) {
+	takeoutGroup := r.Group("/api/takeout")
+	takeoutGroup.Use(m.authMiddleware.AuthRequired())
+	{
+		takeoutGroup.POST("/request", m.takeoutAPI.Request)
+		takeoutGroup.GET("/get", m.takeoutAPI.Get)
+		takeoutGroup.GET("/download", m.takeoutAPI.Download)
+	}
+}
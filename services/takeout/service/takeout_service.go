@@ -0,0 +1,436 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	userContextUtil "github.com/yb2020/odoc/context"
+	"github.com/yb2020/odoc/pkg/audit"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/mail"
+	"github.com/yb2020/odoc/pkg/takeout"
+	ossPb "github.com/yb2020/odoc/proto/gen/go/oss"
+	pb "github.com/yb2020/odoc/proto/gen/go/takeout"
+	ossConstant "github.com/yb2020/odoc/services/oss/constant"
+	ossService "github.com/yb2020/odoc/services/oss/service"
+	"github.com/yb2020/odoc/services/takeout/dao"
+	"github.com/yb2020/odoc/services/takeout/model"
+	userService "github.com/yb2020/odoc/services/user/service"
+)
+
+const (
+	takeoutExpireBatchSize = 100 // 过期处理每次最多处理的申请数
+	takeoutMaxErrorLen     = 500 // 失败原因最大长度，与表字段一致
+)
+
+// TakeoutFile 下载的导出压缩包
+type TakeoutFile struct {
+	FileName string
+	Size     int64
+	Reader   io.ReadCloser
+}
+
+// TakeoutService 用户数据导出服务，负责受理申请、生成压缩包、通知用户和过期处理
+type TakeoutService struct {
+	config         *config.Config
+	cfg            *config.TakeoutConfig
+	logger         logging.Logger
+	tracer         opentracing.Tracer
+	localizer      i18n.Localizer
+	mailer         mail.Mailer
+	takeoutTaskDAO *dao.TakeoutTaskDAO
+	ossService     ossService.OssServiceInterface
+	userService    *userService.UserService
+	exporters      []takeout.Exporter
+}
+
+// NewTakeoutService 创建用户数据导出服务
+func NewTakeoutService(config *config.Config, logger logging.Logger, tracer opentracing.Tracer,
+	localizer i18n.Localizer, mailer mail.Mailer,
+	takeoutTaskDAO *dao.TakeoutTaskDAO,
+	ossService ossService.OssServiceInterface,
+	userService *userService.UserService,
+) *TakeoutService {
+	return &TakeoutService{
+		config:         config,
+		cfg:            &config.Takeout,
+		logger:         logger,
+		tracer:         tracer,
+		localizer:      localizer,
+		mailer:         mailer,
+		takeoutTaskDAO: takeoutTaskDAO,
+		ossService:     ossService,
+		userService:    userService,
+	}
+}
+
+// SetExporters 设置参与导出的各模块导出器，压缩包内按设置顺序排列
+func (s *TakeoutService) SetExporters(exporters []takeout.Exporter) {
+	s.exporters = exporters
+}
+
+// Request 申请导出用户的全部数据，已有排队中或导出中的申请时直接返回该申请
+func (s *TakeoutService) Request(ctx context.Context, userId string, lang string) (*pb.TakeoutTask, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TakeoutService.Request")
+	defer span.Finish()
+
+	if !s.cfg.Enabled {
+		return nil, errors.Biz("takeout.errors.disabled")
+	}
+	latest, err := s.takeoutTaskDAO.GetLatestByUserId(ctx, userId)
+	if err != nil {
+		return nil, errors.Biz("takeout.errors.query_failed")
+	}
+	if latest != nil {
+		if latest.Status == model.TakeoutStatusPending || latest.Status == model.TakeoutStatusRunning {
+			return s.toProto(ctx, latest), nil
+		}
+		// 失败的申请不计入间隔，允许用户立即重新申请
+		interval := time.Duration(s.cfg.RequestInterval) * time.Second
+		if latest.Status != model.TakeoutStatusFailed && time.Since(latest.CreatedAt) < interval {
+			return nil, errors.Biz("takeout.errors.too_frequent")
+		}
+	}
+
+	task := &model.TakeoutTask{
+		UserId:   userId,
+		Status:   model.TakeoutStatusPending,
+		Language: lang,
+	}
+	if err := s.takeoutTaskDAO.Save(ctx, task); err != nil {
+		s.logger.Error("msg", "创建导出申请失败", "userId", userId, "error", err.Error())
+		return nil, errors.Biz("takeout.errors.create_failed")
+	}
+	_ = audit.Record(ctx, &audit.Entry{
+		Action:     audit.ActionCreate,
+		EntityType: "takeout",
+		EntityId:   task.Id,
+		After:      task,
+	})
+	return s.toProto(ctx, task), nil
+}
+
+// Get 查询用户的导出申请，id 为空时返回最近一次申请，没有申请时返回 nil
+func (s *TakeoutService) Get(ctx context.Context, userId string, id string) (*pb.TakeoutTask, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TakeoutService.Get")
+	defer span.Finish()
+
+	task, err := s.getTask(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		if id != "" {
+			return nil, errors.Biz("takeout.errors.not_found")
+		}
+		return nil, nil
+	}
+	return s.toProto(ctx, task), nil
+}
+
+// Download 获取导出压缩包，仅在导出完成且未过期时可下载，调用方负责关闭 Reader
+func (s *TakeoutService) Download(ctx context.Context, userId string, id string) (*TakeoutFile, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TakeoutService.Download")
+	defer span.Finish()
+
+	task, err := s.getTask(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, errors.Biz("takeout.errors.not_found")
+	}
+	if !isDownloadable(task, time.Now()) {
+		if task.Status == model.TakeoutStatusSucceeded || task.Status == model.TakeoutStatusExpired {
+			return nil, errors.Biz("takeout.errors.expired")
+		}
+		return nil, errors.Biz("takeout.errors.not_ready")
+	}
+	reader, err := s.ossService.DownloadObject(ctx, ossConstant.BucketTypeToEnum(s.config, task.BucketName), task.ObjectKey)
+	if err != nil {
+		return nil, err
+	}
+	return &TakeoutFile{
+		FileName: archiveFileName(task),
+		Size:     task.FileSize,
+		Reader:   reader,
+	}, nil
+}
+
+// ProcessPending 处理排队中和中断的导出申请，返回本次导出成功的申请数
+func (s *TakeoutService) ProcessPending(ctx context.Context) (int, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TakeoutService.ProcessPending")
+	defer span.Finish()
+
+	if !s.cfg.Enabled {
+		return 0, nil
+	}
+	staleBefore := time.Now().Add(-time.Duration(s.cfg.RunningTimeout) * time.Second)
+	tasks, err := s.takeoutTaskDAO.GetRunnable(ctx, staleBefore, max(s.cfg.BatchSize, 1))
+	if err != nil {
+		return 0, err
+	}
+	succeeded := 0
+	for i := range tasks {
+		task := &tasks[i]
+		startedAt := time.Now()
+		claimed, err := s.takeoutTaskDAO.Claim(ctx, task.Id, staleBefore, startedAt)
+		if err != nil {
+			return succeeded, err
+		}
+		if !claimed {
+			// 已被其他实例处理
+			continue
+		}
+		task.Status = model.TakeoutStatusRunning
+		task.Attempts++
+		task.StartedAt = startedAt
+		// 导出中途进程退出时不会走到失败处理，这里补上尝试次数的限制
+		if s.cfg.MaxAttempts > 0 && task.Attempts > s.cfg.MaxAttempts {
+			s.markFailed(ctx, task, errors.Biz("takeout.errors.interrupted"))
+			continue
+		}
+
+		if err := s.export(ctx, task); err != nil {
+			s.logger.Error("msg", "导出用户数据失败", "taskId", task.Id, "userId", task.UserId, "attempts", task.Attempts, "error", err.Error())
+			s.markFailed(ctx, task, err)
+			continue
+		}
+		succeeded++
+		s.notify(ctx, task, "takeout.mail.ready")
+	}
+	return succeeded, nil
+}
+
+// ExpireTasks 将超过下载有效期的申请标记为已过期并删除压缩包，返回处理的申请数
+func (s *TakeoutService) ExpireTasks(ctx context.Context) (int, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TakeoutService.ExpireTasks")
+	defer span.Finish()
+
+	tasks, err := s.takeoutTaskDAO.GetExpired(ctx, time.Now(), takeoutExpireBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for i := range tasks {
+		task := &tasks[i]
+		// 删除失败时只记录日志，临时存储桶的生命周期规则会兜底清理
+		if err := s.ossService.DeleteObject(ctx, ossConstant.BucketTypeToEnum(s.config, task.BucketName), task.ObjectKey); err != nil {
+			s.logger.Warn("msg", "删除过期的导出压缩包失败", "taskId", task.Id, "objectKey", task.ObjectKey, "error", err.Error())
+		}
+		task.Status = model.TakeoutStatusExpired
+		if err := s.takeoutTaskDAO.Modify(ctx, task); err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// DeleteUserData 删除用户的全部导出压缩包和导出申请，注销账号时调用
+func (s *TakeoutService) DeleteUserData(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TakeoutService.DeleteUserData")
+	defer span.Finish()
+
+	tasks, err := s.takeoutTaskDAO.GetListByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		task := &tasks[i]
+		if task.ObjectKey == "" || task.Status == model.TakeoutStatusExpired {
+			continue
+		}
+		if err := s.ossService.DeleteObject(ctx, ossConstant.BucketTypeToEnum(s.config, task.BucketName), task.ObjectKey); err != nil {
+			return err
+		}
+	}
+	_, err = s.takeoutTaskDAO.RemoveByUserId(ctx, userId)
+	return err
+}
+
+// export 生成压缩包并上传到临时存储桶，成功后更新申请状态
+func (s *TakeoutService) export(ctx context.Context, task *model.TakeoutTask) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.cfg.RunningTimeout)*time.Second)
+	defer cancel()
+
+	// 部分服务从上下文读取当前用户，导出前设置为申请用户
+	user, err := s.userService.GetUserByID(ctx, task.UserId)
+	if err != nil {
+		return err
+	}
+	ctx = userContextUtil.SetUserContext(ctx, user)
+
+	file, err := os.CreateTemp("", "takeout-*.zip")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	hash := sha256.New()
+	archive := takeout.NewArchive(io.MultiWriter(file, hash), task.UserId, task.StartedAt)
+	if err := archive.Run(ctx, task.UserId, s.exporters); err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	objectKey := "takeout/" + task.UserId + "/" + task.Id + ".zip"
+	metadata := map[string]string{"user-id": task.UserId, "takeout-id": task.Id}
+	if err := s.ossService.UploadObject(ctx, ossPb.OSSBucketEnum_TEMP, objectKey, file, size, "application/zip", metadata); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	task.Status = model.TakeoutStatusSucceeded
+	task.BucketName = ossConstant.EnumToBucketType(s.config, ossPb.OSSBucketEnum_TEMP)
+	task.ObjectKey = objectKey
+	task.FileSize = size
+	task.FileSHA256 = hex.EncodeToString(hash.Sum(nil))
+	task.ErrorMessage = ""
+	task.FinishedAt = now
+	task.ExpiresAt = now.Add(time.Duration(s.cfg.LinkExpiry) * time.Second)
+	return s.takeoutTaskDAO.Modify(context.WithoutCancel(ctx), task)
+}
+
+// markFailed 记录失败原因，未达到最大尝试次数时重新排队，否则标记为失败并通知用户
+func (s *TakeoutService) markFailed(ctx context.Context, task *model.TakeoutTask, cause error) {
+	task.ErrorMessage = truncate(cause.Error(), takeoutMaxErrorLen)
+	task.Status = model.TakeoutStatusPending
+	if task.Attempts >= s.cfg.MaxAttempts {
+		task.Status = model.TakeoutStatusFailed
+		task.FinishedAt = time.Now()
+	}
+	if err := s.takeoutTaskDAO.Modify(ctx, task); err != nil {
+		s.logger.Error("msg", "更新导出申请状态失败", "taskId", task.Id, "error", err.Error())
+		return
+	}
+	if task.Status == model.TakeoutStatusFailed {
+		s.notify(ctx, task, "takeout.mail.failed")
+	}
+}
+
+// notify 按申请时的语言发送邮件通知，发送失败只记录日志
+func (s *TakeoutService) notify(ctx context.Context, task *model.TakeoutTask, templateId string) {
+	user, err := s.userService.GetUserByID(ctx, task.UserId)
+	if err != nil || user == nil || user.Email == "" {
+		s.logger.Warn("msg", "导出申请用户没有邮箱，跳过通知", "taskId", task.Id, "userId", task.UserId)
+		return
+	}
+	data := map[string]interface{}{
+		"Email": user.Email,
+	}
+	if task.Status == model.TakeoutStatusSucceeded {
+		link, err := s.downloadURL(ctx, task)
+		if err != nil {
+			s.logger.Error("msg", "生成导出压缩包下载地址失败", "taskId", task.Id, "error", err.Error())
+			return
+		}
+		data["Link"] = link
+		data["Hours"] = strconv.Itoa(max(s.cfg.LinkExpiry/3600, 1))
+	}
+	lang := task.Language
+	if lang == "" {
+		lang = s.localizer.GetDefaultLanguage()
+	}
+	message := &mail.Message{
+		To:       []string{user.Email},
+		Subject:  s.localizer.LocalizeWithLanguage(templateId+".subject", data, lang),
+		TextBody: s.localizer.LocalizeWithLanguage(templateId+".body", data, lang),
+	}
+	if err := s.mailer.Send(ctx, message); err != nil {
+		s.logger.Error("msg", "发送导出通知邮件失败", "taskId", task.Id, "template", templateId, "error", err.Error())
+	}
+}
+
+// downloadURL 生成压缩包的临时下载地址，有效期不超过申请的下载截止时间
+func (s *TakeoutService) downloadURL(ctx context.Context, task *model.TakeoutTask) (string, error) {
+	expiresIn := int(time.Until(task.ExpiresAt) / time.Second)
+	return s.ossService.GetFileTemporaryURL(ctx, ossConstant.BucketTypeToEnum(s.config, task.BucketName), task.ObjectKey, expiresIn)
+}
+
+// getTask 按ID获取用户的导出申请，id 为空时获取最近一次申请
+func (s *TakeoutService) getTask(ctx context.Context, userId string, id string) (*model.TakeoutTask, error) {
+	var task *model.TakeoutTask
+	var err error
+	if id == "" {
+		task, err = s.takeoutTaskDAO.GetLatestByUserId(ctx, userId)
+	} else {
+		task, err = s.takeoutTaskDAO.GetByIdAndUserId(ctx, id, userId)
+	}
+	if err != nil {
+		return nil, errors.Biz("takeout.errors.query_failed")
+	}
+	return task, nil
+}
+
+// toProto 转换为接口返回结构，可下载时附带临时下载地址
+func (s *TakeoutService) toProto(ctx context.Context, task *model.TakeoutTask) *pb.TakeoutTask {
+	result := &pb.TakeoutTask{
+		Id:           task.Id,
+		Status:       task.Status,
+		FileSize:     task.FileSize,
+		ErrorMessage: task.ErrorMessage,
+		CreatedAt:    toMillis(task.CreatedAt),
+		FinishedAt:   toMillis(task.FinishedAt),
+		ExpiresAt:    toMillis(task.ExpiresAt),
+	}
+	if isDownloadable(task, time.Now()) {
+		link, err := s.downloadURL(ctx, task)
+		if err != nil {
+			s.logger.Warn("msg", "生成导出压缩包下载地址失败", "taskId", task.Id, "error", err.Error())
+		} else {
+			result.DownloadUrl = link
+		}
+	}
+	return result
+}
+
+// isDownloadable 导出完成且未超过下载截止时间
+func isDownloadable(task *model.TakeoutTask, now time.Time) bool {
+	return task.Status == model.TakeoutStatusSucceeded && now.Before(task.ExpiresAt)
+}
+
+// archiveFileName 下载时的文件名
+func archiveFileName(task *model.TakeoutTask) string {
+	return "odoc-takeout-" + task.FinishedAt.Format("20060102") + ".zip"
+}
+
+func toMillis(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixMilli())
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	s = s[:maxLen]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
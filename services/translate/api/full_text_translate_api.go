@@ -93,7 +93,7 @@ func (api *FullTextTranslateAPI) Translate(c *gin.Context) {
 
 	var finalResp *translate.FullTextTranslateResponse
 	// 使用CreditFunTranslate包装全文翻译逻辑 //TODO filePageSize设置为真实的页数   这里不需要自动提交，拿到sessionId后，进行二次确认
-	err := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_FULLTEXT, 0, func(xctx context.Context, sessionId string) error {
+	err := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_FULLTEXT, 0, req.PdfId, func(xctx context.Context, sessionId string) error {
 		// 调用服务
 		resp, err := api.fullTextService.Translate(xctx, req, sessionId)
 		if err != nil {
@@ -129,7 +129,7 @@ func (api *OCRTextTranslateAPI) OCRTranslate(c *gin.Context) {
 
 	var finalResp *translate.TranslateResponse
 	// 使用CreditFunTranslate包装OCR翻译逻辑
-	err := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_OCR, 0, func(xctx context.Context, sessionId string) error {
+	err := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_OCR, 0, request.GetPdfId(), func(xctx context.Context, sessionId string) error {
 		// 调用服务
 		resp, err := api.ocrTranslateService.OCRTranslate(xctx, request)
 		if err != nil {
@@ -146,7 +146,7 @@ func (api *TextTranslateAPI) Translate(c *gin.Context) {
 	translateResp := &translate.TranslateResponse{}
 	if matches := wordPattern.FindStringSubmatch(content); len(matches) > 1 {
 		// 使用CreditFunTranslate包装单词翻译逻辑
-		err := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_WORD, 0, func(xctx context.Context, sessionId string) error {
+		err := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_WORD, 0, req.GetPdfId(), func(xctx context.Context, sessionId string) error {
 			// 如果是单词，使用单词发音服务
 			wordContent := strings.ToLower(matches[1])
 			if req.PdfId == nil {
@@ -192,7 +192,7 @@ func (api *TextTranslateAPI) Translate(c *gin.Context) {
 	}
 
 	// 使用CreditFunTranslate包装翻译逻辑
-	err := api.membershipService.CreditFunTranslate(ctx, creditServiceType, 0, func(xctx context.Context, sessionId string) error {
+	err := api.membershipService.CreditFunTranslate(ctx, creditServiceType, 0, req.GetPdfId(), func(xctx context.Context, sessionId string) error {
 		// 移除特殊字符（如果没有使用术语库）
 		if req.UseGlossary == nil || !*req.UseGlossary {
 			content = removeSpecialWords(content, api.config.Translate.Text.Special.Words.NeedReplace.List)
@@ -516,7 +516,7 @@ func (api *TextTranslateAPI) AiTranslate(c *gin.Context) {
 
 	if isWordMode {
 		// 使用CreditFunTranslate包装单词翻译逻辑
-		err := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_WORD, 0, func(xctx context.Context, sessionId string) error {
+		err := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_WORD, 0, req.GetPdfId(), func(xctx context.Context, sessionId string) error {
 			// 处理单词发音
 			wordContent := req.Text
 			translateResp, err := api.wordPronunciationService.GetTranslateResp(xctx, service.TranslateSourceYoudao, wordContent, *req.PdfId)
@@ -547,7 +547,7 @@ func (api *TextTranslateAPI) AiTranslate(c *gin.Context) {
 	}
 
 	// 使用CreditFunTranslate包装AI翻译逻辑
-	creditErr := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_AI, 0, func(xctx context.Context, sessionId string) error {
+	creditErr := api.membershipService.CreditFunTranslate(ctx, pb.CreditServiceType_CREDIT_SERVICE_TYPE_TRANSLATE_AI, 0, req.GetPdfId(), func(xctx context.Context, sessionId string) error {
 		// 创建通道用于接收翻译结果
 		errorChan := make(chan error)
 		completeChan := make(chan struct{})
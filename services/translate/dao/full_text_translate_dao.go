@@ -139,3 +139,29 @@ func (dao *FullTextTranslateDAO) FindByUserIdAndIntervalDays(ctx context.Context
 	}
 	return histories, nil
 }
+
+// FindAllByUserId 查询用户的全部翻译历史记录，包括已逻辑删除的
+func (dao *FullTextTranslateDAO) FindAllByUserId(ctx context.Context, userId string) ([]model.FullTextTranslate, error) {
+	var histories []model.FullTextTranslate
+	err := dao.GetDB(ctx).
+		Where("user_id = ?", userId).
+		Find(&histories).Error
+	if err != nil {
+		dao.logger.Error("查询用户全部翻译历史记录失败", "error", err.Error(), "userId", userId)
+		return nil, err
+	}
+	return histories, nil
+}
+
+// CountOtherUsersByTargetObjectKey 统计其他用户引用同一译文文件的翻译记录数，包括已逻辑删除的
+func (dao *FullTextTranslateDAO) CountOtherUsersByTargetObjectKey(ctx context.Context, targetObjectKey string, userId string) (int64, error) {
+	var count int64
+	err := dao.GetDB(ctx).Model(&model.FullTextTranslate{}).
+		Where("target_object_key = ? AND user_id <> ?", targetObjectKey, userId).
+		Count(&count).Error
+	if err != nil {
+		dao.logger.Error("统计译文文件引用数失败", "error", err.Error(), "targetObjectKey", targetObjectKey)
+		return 0, err
+	}
+	return count, nil
+}
@@ -1,6 +1,7 @@
 package translate
 
 import (
+	"context"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +18,7 @@ import (
 	"github.com/yb2020/odoc/pkg/ratelimit"
 	"github.com/yb2020/odoc/pkg/registry"
 	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/pkg/takeout"
 	docService "github.com/yb2020/odoc/services/doc/service"
 	membershipService "github.com/yb2020/odoc/services/membership/interfaces"
 	noteInterface "github.com/yb2020/odoc/services/note/interfaces"
@@ -33,6 +35,12 @@ import (
 // 编译时类型检查：确保 TranslateModule 实现了 registry.Module 接口
 var _ registry.Module = (*TranslateModule)(nil)
 
+// 编译时类型检查：确保 TranslateModule 实现了 registry.TakeoutExporterProvider 接口
+var _ registry.TakeoutExporterProvider = (*TranslateModule)(nil)
+
+// 编译时类型检查：确保 TranslateModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*TranslateModule)(nil)
+
 // Module 导出模块实例，用于自动发现和注册
 var Module = &TranslateModule{}
 
@@ -55,6 +63,8 @@ type TranslateModule struct {
 	ossService               ossService.OssServiceInterface
 	lockTemplate             *distlock.LockTemplate
 	membershipService        membershipService.IMembershipService
+	takeoutExporter          *service.TranslateTakeoutExporter
+	userDataDeleter          *service.TranslateUserDataDeleter
 }
 
 // NewModule 创建翻译模块
@@ -106,6 +116,7 @@ func (m *TranslateModule) Initialize() error {
 	glossaryDAO := dao.NewGlossaryDAO(m.db, m.logger)
 	glossaryService := service.NewGlossaryService(m.config, m.logger, m.tracer, glossaryDAO)
 	m.glossaryAPI = api.NewGlossaryAPI(glossaryService, m.logger, m.tracer)
+	m.takeoutExporter = service.NewTranslateTakeoutExporter(m.logger, m.tracer, &glossaryDAO)
 
 	// external ocr api
 	imageOCRApiService := ocr.NewImageOCRApiService(m.logger, m.config.Translate.OCR.ExtractTextURL, m.config, m.httpClient)
@@ -139,9 +150,23 @@ func (m *TranslateModule) Initialize() error {
 	ocrTranslateService := service.NewOCRTranslateService(m.config, m.logger, m.tracer, ocrTranslateDAO, textTranslateService, glossaryService, rateLimiterService, imageOCRApiService)
 	m.ocrTextTranslateAPI = api.NewOCRTextTranslateAPI(m.config, m.logger, m.tracer, ocrTranslateService, rateLimiterService, m.membershipService)
 
+	// 注销账号时的数据删除器
+	m.userDataDeleter = service.NewTranslateUserDataDeleter(m.config, m.logger, m.tracer, &glossaryDAO, &textTranslateDAO,
+		&ocrTranslateDAO, &fullTextTranslateDAO, m.ossService)
+
 	return nil
 }
 
+// TakeoutExporters 返回翻译模块的用户数据导出器
+func (m *TranslateModule) TakeoutExporters() []takeout.Exporter {
+	return []takeout.Exporter{m.takeoutExporter}
+}
+
+// DeleteUserData 注销账号时删除用户的术语表和翻译记录
+func (m *TranslateModule) DeleteUserData(ctx context.Context, userId string) error {
+	return m.userDataDeleter.DeleteUserData(ctx, userId)
+}
+
 // Shutdown 关闭模块
 func (m *TranslateModule) Shutdown() error {
 	m.logger.Info("msg", "关闭翻译模块")
@@ -10,6 +10,7 @@ import (
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/audit"
 	"github.com/yb2020/odoc/pkg/errors"
 	"github.com/yb2020/odoc/pkg/logging"
 	"github.com/yb2020/odoc/proto/gen/go/translate"
@@ -173,6 +174,7 @@ func (s *GlossaryService) UpdateGlossary(ctx context.Context, userId string, req
 	}
 
 	// 更新术语条目
+	before := *glossary
 	glossary.OriginalText = originalText
 	glossary.TranslationText = req.TranslationText
 	glossary.MatchCase = req.MatchCase
@@ -186,6 +188,14 @@ func (s *GlossaryService) UpdateGlossary(ctx context.Context, userId string, req
 		return errors.BizWrap("translate.glossary.errors.entry_update_failed", err)
 	}
 
+	_ = audit.Record(ctx, &audit.Entry{
+		ActorId:    userId,
+		Action:     audit.ActionUpdate,
+		EntityType: "glossary",
+		EntityId:   glossary.Id,
+		Before:     &before,
+		After:      glossary,
+	})
 	return nil
 }
 
@@ -217,6 +227,13 @@ func (s *GlossaryService) DeleteGlossary(ctx context.Context, userId string, id
 		return errors.BizWrap("translate.glossary.errors.entry_delete_failed", err)
 	}
 
+	_ = audit.Record(ctx, &audit.Entry{
+		ActorId:    userId,
+		Action:     audit.ActionDelete,
+		EntityType: "glossary",
+		EntityId:   id,
+		Before:     glossary,
+	})
 	return nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/config"
@@ -19,6 +20,7 @@ import (
 	pkgi18n "github.com/yb2020/odoc/pkg/i18n"
 	"github.com/yb2020/odoc/pkg/idgen"
 	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/metrics"
 	baseModel "github.com/yb2020/odoc/pkg/model"
 	"github.com/yb2020/odoc/pkg/utils"
 	"github.com/yb2020/odoc/proto/gen/go/translate"
@@ -324,16 +326,22 @@ func (s *TextTranslateService) callTranslateAPI(ctx context.Context, channel tra
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TextTranslateService.callTranslateAPI")
 	defer span.Finish()
 
+	var result string
+	var err error
 	switch channel {
 	case translate.TranslateChannel_GOOGLE:
 		googleFreeClient := externalTranslateApi.NewGoogleFreeTranslateClient(*s.config, s.httpClient, s.logger)
-		return googleFreeClient.Translate(sourceContent, sourceLanguage, targetLanguage)
+		result, err = googleFreeClient.Translate(sourceContent, sourceLanguage, targetLanguage)
 	case translate.TranslateChannel_YOUDAO:
 		youdaoClient := externalTranslateApi.NewYoudaoTranslateClient(*s.config, s.httpClient, s.logger)
-		return youdaoClient.Translate(sourceContent, sourceLanguage, targetLanguage)
+		result, err = youdaoClient.Translate(sourceContent, sourceLanguage, targetLanguage)
+	default:
+		return "", errors.Biz("translate failed")
 	}
-
-	return "", errors.Biz("translate failed")
+	if err == nil {
+		metrics.RecordTranslation(ctx, channel.String(), utf8.RuneCountInString(sourceContent))
+	}
+	return result, err
 }
 
 // parseTranslateResult 解析翻译结果
@@ -428,6 +436,7 @@ func (s *TextTranslateService) StreamTranslateWithLLM(ctx context.Context, text
 	if err != nil {
 		return "", fmt.Errorf("调用流式API失败: %w", err)
 	}
+	metrics.RecordTranslation(ctx, translate.TranslateChannel_AI.String(), utf8.RuneCountInString(text))
 
 	return fullContent.String(), nil
 }
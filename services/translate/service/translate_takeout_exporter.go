@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/takeout"
+	"github.com/yb2020/odoc/services/translate/dao"
+)
+
+// 编译时类型检查：确保 TranslateTakeoutExporter 实现了 takeout.Exporter 接口
+var _ takeout.Exporter = (*TranslateTakeoutExporter)(nil)
+
+// TranslateTakeoutExporter 导出用户的翻译术语表
+type TranslateTakeoutExporter struct {
+	logger      logging.Logger
+	tracer      opentracing.Tracer
+	glossaryDAO *dao.GlossaryDAO
+}
+
+// NewTranslateTakeoutExporter 创建翻译数据导出器
+func NewTranslateTakeoutExporter(logger logging.Logger, tracer opentracing.Tracer, glossaryDAO *dao.GlossaryDAO) *TranslateTakeoutExporter {
+	return &TranslateTakeoutExporter{
+		logger:      logger,
+		tracer:      tracer,
+		glossaryDAO: glossaryDAO,
+	}
+}
+
+// Name 导出器名称
+func (e *TranslateTakeoutExporter) Name() string {
+	return "translate"
+}
+
+// Export 导出用户的术语表
+func (e *TranslateTakeoutExporter) Export(ctx context.Context, userId string, w *takeout.Writer) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, e.tracer, "TranslateTakeoutExporter.Export")
+	defer span.Finish()
+
+	glossaries, err := e.glossaryDAO.GetGlossariesByUserID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return w.WriteJSON("glossaries.json", glossaries)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	ossConstant "github.com/yb2020/odoc/services/oss/constant"
+	ossService "github.com/yb2020/odoc/services/oss/service"
+	"github.com/yb2020/odoc/services/translate/dao"
+)
+
+// TranslateUserDataDeleter 注销账号时删除用户的术语表和翻译记录
+type TranslateUserDataDeleter struct {
+	config               *config.Config
+	logger               logging.Logger
+	tracer               opentracing.Tracer
+	glossaryDAO          *dao.GlossaryDAO
+	textTranslateDAO     *dao.TextTranslateDAO
+	ocrTranslateDAO      *dao.OCRTranslateDAO
+	fullTextTranslateDAO *dao.FullTextTranslateDAO
+	ossService           ossService.OssServiceInterface
+}
+
+// NewTranslateUserDataDeleter 创建翻译数据删除器
+func NewTranslateUserDataDeleter(config *config.Config, logger logging.Logger, tracer opentracing.Tracer,
+	glossaryDAO *dao.GlossaryDAO,
+	textTranslateDAO *dao.TextTranslateDAO,
+	ocrTranslateDAO *dao.OCRTranslateDAO,
+	fullTextTranslateDAO *dao.FullTextTranslateDAO,
+	ossService ossService.OssServiceInterface,
+) *TranslateUserDataDeleter {
+	return &TranslateUserDataDeleter{
+		config:               config,
+		logger:               logger,
+		tracer:               tracer,
+		glossaryDAO:          glossaryDAO,
+		textTranslateDAO:     textTranslateDAO,
+		ocrTranslateDAO:      ocrTranslateDAO,
+		fullTextTranslateDAO: fullTextTranslateDAO,
+		ossService:           ossService,
+	}
+}
+
+// DeleteUserData 物理删除用户的术语表、划词翻译、OCR翻译和全文翻译记录
+// 全文翻译的译文文件按原文 SHA256 在用户间复用，只有没有其他用户引用时才删除文件
+func (d *TranslateUserDataDeleter) DeleteUserData(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, d.tracer, "TranslateUserDataDeleter.DeleteUserData")
+	defer span.Finish()
+
+	histories, err := d.fullTextTranslateDAO.FindAllByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	deletedKeys := make(map[string]bool)
+	for _, history := range histories {
+		if history.TargetObjectKey == "" || deletedKeys[history.TargetObjectKey] {
+			continue
+		}
+		count, err := d.fullTextTranslateDAO.CountOtherUsersByTargetObjectKey(ctx, history.TargetObjectKey, userId)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		bucket := ossConstant.BucketTypeToEnum(d.config, history.TargetBucketName)
+		if err := d.ossService.DeleteObject(ctx, bucket, history.TargetObjectKey); err != nil {
+			return err
+		}
+		deletedKeys[history.TargetObjectKey] = true
+	}
+
+	if _, err := d.fullTextTranslateDAO.RemoveByUserId(ctx, userId); err != nil {
+		return err
+	}
+	if _, err := d.ocrTranslateDAO.RemoveByUserId(ctx, userId); err != nil {
+		return err
+	}
+	if _, err := d.textTranslateDAO.RemoveByUserId(ctx, userId); err != nil {
+		return err
+	}
+	if _, err := d.glossaryDAO.RemoveByUserId(ctx, userId); err != nil {
+		return err
+	}
+	d.logger.Info("msg", "已删除用户的翻译数据", "userId", userId, "fullTextTranslates", len(histories), "deletedFiles", len(deletedKeys))
+	return nil
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/trash"
+	"github.com/yb2020/odoc/services/trash/service"
+)
+
+// TrashAPI 回收站API处理器
+type TrashAPI struct {
+	logger       logging.Logger
+	tracer       opentracing.Tracer
+	trashService *service.TrashService
+}
+
+// NewTrashAPI 创建回收站API处理器
+func NewTrashAPI(logger logging.Logger, tracer opentracing.Tracer, trashService *service.TrashService) *TrashAPI {
+	return &TrashAPI{
+		logger:       logger,
+		tracer:       tracer,
+		trashService: trashService,
+	}
+}
+
+// @api /api/trash/list
+// @method POST
+// @apiDescription 分页查询当前用户回收站中的条目，按删除时间倒序
+func (api *TrashAPI) List(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "TrashAPI.List")
+	defer span.Finish()
+
+	req := &pb.ListTrashRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	resp, err := api.trashService.List(ctx, userId, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", resp)
+}
+
+// @api /api/trash/restore
+// @method POST
+// @apiDescription 恢复回收站中的条目，随该条目一起删除的条目同时恢复，名称冲突时自动重命名
+func (api *TrashAPI) Restore(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "TrashAPI.Restore")
+	defer span.Finish()
+
+	req := &pb.RestoreTrashRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	items, err := api.trashService.Restore(ctx, userId, req.Ids)
+	if err != nil {
+		api.logger.Warn("msg", "恢复回收站条目失败", "userId", userId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.Success(c, "success", &pb.RestoreTrashResponse{Items: items})
+}
+
+// @api /api/trash/purge
+// @method POST
+// @apiDescription 彻底删除回收站中的条目，随该条目一起删除的条目同时彻底删除，不能恢复
+func (api *TrashAPI) Purge(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "TrashAPI.Purge")
+	defer span.Finish()
+
+	req := &pb.PurgeTrashRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	if err := api.trashService.Purge(ctx, userId, req.Ids); err != nil {
+		api.logger.Warn("msg", "彻底删除回收站条目失败", "userId", userId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.SuccessNoData(c, "success")
+}
+
+// @api /api/trash/empty
+// @method POST
+// @apiDescription 清空当前用户的回收站，不能恢复
+func (api *TrashAPI) Empty(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "TrashAPI.Empty")
+	defer span.Finish()
+
+	req := &pb.EmptyTrashRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		response.ErrorNoData(c, "bad request params")
+		return
+	}
+	userId, _ := userContext.GetUserID(ctx)
+	if _, err := api.trashService.Empty(ctx, userId); err != nil {
+		api.logger.Warn("msg", "清空回收站失败", "userId", userId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	response.SuccessNoData(c, "success")
+}
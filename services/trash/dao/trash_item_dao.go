@@ -0,0 +1,88 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/trash/model"
+	"gorm.io/gorm"
+)
+
+// TrashItemDAO GORM实现的回收站条目DAO
+type TrashItemDAO struct {
+	*baseDao.GormBaseDAO[model.TrashItem]
+	logger logging.Logger
+}
+
+// NewTrashItemDAO 创建一个新的回收站条目DAO
+func NewTrashItemDAO(db *gorm.DB, logger logging.Logger) *TrashItemDAO {
+	return &TrashItemDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.TrashItem](db, logger),
+		logger:      logger,
+	}
+}
+
+// ListByUserId 按删除时间倒序分页获取用户直接删除的回收站条目，itemType 为空时不限制类型
+func (d *TrashItemDAO) ListByUserId(ctx context.Context, userId string, itemType string, offset int, limit int) ([]model.TrashItem, int64, error) {
+	query := d.GetDB(ctx).Model(&model.TrashItem{}).Where("user_id = ? and parent_id = ''", userId)
+	if itemType != "" {
+		query = query.Where("item_type = ?", itemType)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		d.logger.Error("msg", "统计回收站条目失败", "userId", userId, "error", err.Error())
+		return nil, 0, err
+	}
+	var items []model.TrashItem
+	if err := query.Order("deleted_at desc, sort asc, id desc").Offset(offset).Limit(limit).Find(&items).Error; err != nil {
+		d.logger.Error("msg", "获取回收站条目失败", "userId", userId, "error", err.Error())
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// GetByUserIdAndIds 获取用户指定的回收站条目
+func (d *TrashItemDAO) GetByUserIdAndIds(ctx context.Context, userId string, ids []string) ([]model.TrashItem, error) {
+	var items []model.TrashItem
+	result := d.GetDB(ctx).Where("user_id = ? AND id IN ?", userId, ids).Order("deleted_at asc, sort asc").Find(&items)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取回收站条目失败", "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return items, nil
+}
+
+// GetByParentId 按删除顺序获取随指定条目一起删除的条目
+func (d *TrashItemDAO) GetByParentId(ctx context.Context, parentId string) ([]model.TrashItem, error) {
+	var items []model.TrashItem
+	result := d.GetDB(ctx).Where("parent_id = ?", parentId).Order("sort asc, id asc").Find(&items)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取随之删除的回收站条目失败", "parentId", parentId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return items, nil
+}
+
+// GetRootsByUserId 获取用户直接删除的条目，随其他条目一起删除的条目由上级条目带出
+func (d *TrashItemDAO) GetRootsByUserId(ctx context.Context, userId string, limit int) ([]model.TrashItem, error) {
+	var items []model.TrashItem
+	result := d.GetDB(ctx).Where("user_id = ? AND parent_id = ''", userId).Order("deleted_at asc, id asc").Limit(limit).Find(&items)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取用户回收站条目失败", "userId", userId, "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return items, nil
+}
+
+// GetExpiredRoots 获取删除时间早于 before 的直接删除的条目
+func (d *TrashItemDAO) GetExpiredRoots(ctx context.Context, before time.Time, limit int) ([]model.TrashItem, error) {
+	var items []model.TrashItem
+	result := d.GetDB(ctx).Where("parent_id = '' AND deleted_at < ?", before).Order("deleted_at asc, id asc").Limit(limit).Find(&items)
+	if result.Error != nil {
+		d.logger.Error("msg", "获取过期回收站条目失败", "error", result.Error.Error())
+		return nil, result.Error
+	}
+	return items, nil
+}
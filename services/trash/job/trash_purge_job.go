@@ -0,0 +1,57 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/services/trash/service"
+)
+
+// TrashPurgeJob 回收站清理任务，彻底删除超过保留天数的条目并释放存储
+type TrashPurgeJob struct {
+	logger       logging.Logger
+	spec         string                 // 任务的cron表达式，6字段标准cron表达式
+	key          string                 // 任务的锁key，必须是唯一的unique-job-key
+	expiry       time.Duration          // 任务的锁过期时间
+	lockOpts     *scheduler.LockOptions // 任务的锁选项
+	trashService *service.TrashService
+}
+
+func NewTrashPurgeJob(logger logging.Logger, cfg *config.Config, trashService *service.TrashService) *TrashPurgeJob {
+	spec := cfg.Scheduler.Jobs.TrashPurgeJob.Spec
+	key := cfg.Scheduler.Jobs.TrashPurgeJob.Key
+	expiry := time.Duration(cfg.Scheduler.Jobs.TrashPurgeJob.Expiry) * time.Second
+	lockOpts := &scheduler.LockOptions{
+		Key:    key,
+		Expiry: expiry,
+	}
+	return &TrashPurgeJob{logger: logger, spec: spec, key: key, expiry: expiry, lockOpts: lockOpts, trashService: trashService}
+}
+
+// Spec 获取任务的cron表达式
+func (j *TrashPurgeJob) Spec() string {
+	return j.spec
+}
+
+// LockOpts 获取任务的锁选项
+func (j *TrashPurgeJob) LockOpts() *scheduler.LockOptions {
+	return j.lockOpts
+}
+
+// NewUserContext 清理任务不以具体用户身份执行，这里直接返回原上下文
+func (j *TrashPurgeJob) NewUserContext(ctx context.Context, userId string) context.Context {
+	return ctx
+}
+
+// Run 执行任务，在执行任务前会获取锁，执行任务后会释放锁
+func (j *TrashPurgeJob) Run() {
+	purged, err := j.trashService.PurgeExpired(context.Background())
+	if err != nil {
+		j.logger.Error("msg", "Trash purge job failed", "purged", purged, "error", err)
+		return
+	}
+	j.logger.Info("msg", "Trash purge job success", "purged", purged)
+}
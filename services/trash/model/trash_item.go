@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+)
+
+// TrashItem 回收站条目，条目被恢复或彻底删除后物理删除
+type TrashItem struct {
+	model.BaseModel           // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	UserId          string    `json:"userId" gorm:"column:user_id;size:36;index"`                           // 所属用户ID
+	ItemType        string    `json:"itemType" gorm:"column:item_type;type:varchar(20);index;comment:条目类型"` // 条目类型
+	ItemId          string    `json:"itemId" gorm:"column:item_id;size:36;index;comment:被删除数据的ID"`          // 被删除数据的ID
+	Title           string    `json:"title" gorm:"column:title;type:varchar(255);comment:展示名称"`             // 展示名称
+	Reason          string    `json:"reason" gorm:"column:reason;type:varchar(20);comment:删除方式"`            // 删除方式
+	ParentId        string    `json:"parentId" gorm:"column:parent_id;size:36;index;comment:随之删除的上级条目ID"`   // 随其他条目一起删除时，为该条目的ID
+	Sort            int       `json:"sort" gorm:"column:sort;type:int;default:0;comment:同一次删除中的顺序"`         // 同一次删除中的顺序
+	Snapshot        string    `json:"snapshot" gorm:"column:snapshot;type:text;comment:恢复所需的关联数据"`          // 恢复所需的关联数据（JSON）
+	DeletedAt       time.Time `json:"deletedAt" gorm:"column:deleted_at;index;comment:删除时间"`                // 删除时间
+}
+
+// TableName 返回表名
+func (TrashItem) TableName() string {
+	return "t_trash_item"
+}
@@ -0,0 +1,138 @@
+package trash
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/middleware"
+	"github.com/yb2020/odoc/pkg/registry"
+	"github.com/yb2020/odoc/pkg/scheduler"
+	pkgTrash "github.com/yb2020/odoc/pkg/trash"
+	"github.com/yb2020/odoc/services/trash/api"
+	"github.com/yb2020/odoc/services/trash/dao"
+	"github.com/yb2020/odoc/services/trash/job"
+	"github.com/yb2020/odoc/services/trash/service"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+)
+
+// 编译时类型检查：确保 TrashModule 实现了 registry.Module 接口
+var _ registry.Module = (*TrashModule)(nil)
+
+// 编译时类型检查：确保 TrashModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*TrashModule)(nil)
+
+// Module 导出模块实例，用于自动发现和注册
+var Module = &TrashModule{}
+
+// TrashModule 回收站模块
+type TrashModule struct {
+	db                 *gorm.DB
+	logger             logging.Logger
+	tracer             opentracing.Tracer
+	config             *config.Config
+	authMiddleware     *middleware.AuthMiddleware
+	transactionManager *baseDao.TransactionManager
+	trashAPI           *api.TrashAPI
+	trashService       *service.TrashService
+}
+
+// NewTrashModule 创建回收站模块
+func NewTrashModule(db *gorm.DB,
+	config *config.Config,
+	logger logging.Logger,
+	tracer opentracing.Tracer,
+	authMiddleware *middleware.AuthMiddleware,
+	transactionManager *baseDao.TransactionManager,
+) *TrashModule {
+	return &TrashModule{
+		db:                 db,
+		logger:             logger,
+		tracer:             tracer,
+		config:             config,
+		authMiddleware:     authMiddleware,
+		transactionManager: transactionManager,
+	}
+}
+
+// Name 返回模块名称
+func (m *TrashModule) Name() string {
+	return "trash"
+}
+
+// Initialize 初始化模块，开启回收站时把回收站服务设置为全局落地实现
+func (m *TrashModule) Initialize() error {
+	m.logger.Info("msg", "初始化回收站模块")
+	trashItemDAO := dao.NewTrashItemDAO(m.db, m.logger)
+
+	m.trashService = service.NewTrashService(&m.config.Trash, m.logger, m.tracer, m.transactionManager, trashItemDAO)
+	m.trashAPI = api.NewTrashAPI(m.logger, m.tracer, m.trashService)
+	if m.config.Trash.Enabled {
+		pkgTrash.SetRecorder(m.trashService)
+	} else {
+		pkgTrash.SetRecorder(nil)
+		m.logger.Info("msg", "回收站未开启，删除的数据不再记录")
+	}
+	return nil
+}
+
+// SetHandlers 设置各模块的条目处理器，需在所有模块初始化完成后调用
+func (m *TrashModule) SetHandlers(handlers []pkgTrash.Handler) {
+	m.trashService.SetHandlers(handlers)
+	m.logger.Info("msg", "成功为回收站模块设置条目处理器", "count", len(handlers))
+}
+
+// GetTrashService 获取回收站服务
+func (m *TrashModule) GetTrashService() *service.TrashService {
+	return m.trashService
+}
+
+// DeleteUserData 注销账号时删除用户的回收站条目
+func (m *TrashModule) DeleteUserData(ctx context.Context, userId string) error {
+	return m.trashService.DeleteUserData(ctx, userId)
+}
+
+// Shutdown 关闭模块
+func (m *TrashModule) Shutdown() error {
+	m.logger.Info("msg", "关闭回收站模块")
+	pkgTrash.SetRecorder(nil)
+	return nil
+}
+
+// RegisterGRPC 注册gRPC服务
+func (m *TrashModule) RegisterGRPC(server *grpc.Server) {
+	// 回收站模块没有gRPC服务，不需要注册
+	m.logger.Debug("msg", "回收站模块没有gRPC服务，跳过注册")
+}
+
+// RegisterJobSchedulers 注册Job定时任务
+func (m *TrashModule) RegisterJobSchedulers(scheduler *scheduler.Scheduler) {
+	if scheduler == nil {
+		m.logger.Debug("msg", "调度器未启用，回收站模块跳过Job注册")
+		return
+	}
+	m.logger.Debug("msg", "回收站模块注册Job定时任务")
+	purgeJob := job.NewTrashPurgeJob(m.logger, m.config, m.trashService)
+	scheduler.RegisterJobs(purgeJob)
+}
+
+// RegisterProviders 注册Provider
+func (m *TrashModule) RegisterProviders() {
+	m.logger.Debug("msg", "回收站模块没有Provider，跳过注册")
+}
+
+// RegisterRoutes 注册路由
+func (m *TrashModule) RegisterRoutes(r *gin.Engine) {
+	trashGroup := r.Group("/api/trash")
+	trashGroup.Use(m.authMiddleware.AuthRequired())
+	{
+		trashGroup.POST("/list", m.trashAPI.List)
+		trashGroup.POST("/restore", m.trashAPI.Restore)
+		trashGroup.POST("/purge", m.trashAPI.Purge)
+		trashGroup.POST("/empty", m.trashAPI.Empty)
+	}
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/audit"
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/trash"
+	pb "github.com/yb2020/odoc/proto/gen/go/trash"
+	"github.com/yb2020/odoc/services/trash/dao"
+	"github.com/yb2020/odoc/services/trash/model"
+)
+
+const (
+	trashDefaultPageSize = 20
+	trashMaxPageSize     = 100
+	trashMaxIds          = 100 // 单次恢复或彻底删除的最大条目数
+	trashTitleMaxLen     = 255 // 名称最大长度，与表字段一致
+)
+
+// 编译时类型检查：确保 TrashService 实现了 trash.Recorder 接口
+var _ trash.Recorder = (*TrashService)(nil)
+
+// TrashService 回收站服务，记录各模块删除的条目，并调用所属模块的处理器恢复或彻底删除
+type TrashService struct {
+	cfg                *config.TrashConfig
+	logger             logging.Logger
+	tracer             opentracing.Tracer
+	transactionManager *baseDao.TransactionManager
+	trashItemDAO       *dao.TrashItemDAO
+	handlers           map[string]trash.Handler
+}
+
+// NewTrashService 创建回收站服务
+func NewTrashService(cfg *config.TrashConfig, logger logging.Logger, tracer opentracing.Tracer,
+	transactionManager *baseDao.TransactionManager,
+	trashItemDAO *dao.TrashItemDAO,
+) *TrashService {
+	return &TrashService{
+		cfg:                cfg,
+		logger:             logger,
+		tracer:             tracer,
+		transactionManager: transactionManager,
+		trashItemDAO:       trashItemDAO,
+		handlers:           make(map[string]trash.Handler),
+	}
+}
+
+// SetHandlers 设置各类条目的处理器
+func (s *TrashService) SetHandlers(handlers []trash.Handler) {
+	for _, handler := range handlers {
+		s.handlers[handler.Type()] = handler
+	}
+}
+
+// Record 保存一个被删除的条目，上下文中有事务时随删除一起提交
+func (s *TrashService) Record(ctx context.Context, item *trash.Item) error {
+	snapshot := ""
+	if item.Snapshot != nil {
+		data, err := json.Marshal(item.Snapshot)
+		if err != nil {
+			return err
+		}
+		snapshot = string(data)
+	}
+	trashItem := &model.TrashItem{
+		UserId:    item.UserId,
+		ItemType:  item.Type,
+		ItemId:    item.ItemId,
+		Title:     truncate(item.Title, trashTitleMaxLen),
+		Reason:    item.Reason,
+		ParentId:  item.ParentId,
+		Sort:      item.Sort,
+		Snapshot:  snapshot,
+		DeletedAt: item.DeletedAt,
+	}
+	if err := s.trashItemDAO.Save(ctx, trashItem); err != nil {
+		s.logger.Error("msg", "记录回收站条目失败", "type", item.Type, "itemId", item.ItemId, "error", err.Error())
+		return err
+	}
+	item.Id = trashItem.Id
+	return nil
+}
+
+// List 分页查询用户回收站中的条目
+func (s *TrashService) List(ctx context.Context, userId string, req *pb.ListTrashRequest) (*pb.ListTrashResponse, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TrashService.List")
+	defer span.Finish()
+
+	page := max(int(req.CurrentPage), 1)
+	size := int(req.PageSize)
+	if size <= 0 {
+		size = trashDefaultPageSize
+	}
+	size = min(size, trashMaxPageSize)
+
+	items, total, err := s.trashItemDAO.ListByUserId(ctx, userId, req.Type, (page-1)*size, size)
+	if err != nil {
+		return nil, errors.Biz("trash.errors.query_failed")
+	}
+	resp := &pb.ListTrashResponse{Total: total, Items: make([]*pb.TrashItem, 0, len(items))}
+	for i := range items {
+		resp.Items = append(resp.Items, s.toProto(&items[i]))
+	}
+	return resp, nil
+}
+
+// Restore 恢复用户选择的条目，随之删除的条目一起恢复；每个条目在一个事务中恢复
+func (s *TrashService) Restore(ctx context.Context, userId string, ids []string) ([]*pb.RestoredTrashItem, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TrashService.Restore")
+	defer span.Finish()
+
+	items, err := s.getUserItems(ctx, userId, ids)
+	if err != nil {
+		return nil, err
+	}
+	restored := make([]*pb.RestoredTrashItem, 0, len(items))
+	for i := range items {
+		var result []*pb.RestoredTrashItem
+		err := s.transactionManager.ExecuteInTransaction(ctx, func(txCtx context.Context) error {
+			var err error
+			result, err = s.restoreTree(txCtx, &items[i])
+			return err
+		})
+		if err != nil {
+			s.logger.Error("msg", "恢复回收站条目失败", "trashId", items[i].Id, "type", items[i].ItemType, "error", err.Error())
+			if _, ok := err.(*errors.BizError); ok {
+				return nil, err
+			}
+			return nil, errors.Biz("trash.errors.restore_failed")
+		}
+		restored = append(restored, result...)
+	}
+	return restored, nil
+}
+
+// Purge 彻底删除用户选择的条目，随之删除的条目一起彻底删除
+func (s *TrashService) Purge(ctx context.Context, userId string, ids []string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TrashService.Purge")
+	defer span.Finish()
+
+	items, err := s.getUserItems(ctx, userId, ids)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if err := s.purgeTree(ctx, &items[i]); err != nil {
+			s.logger.Error("msg", "彻底删除回收站条目失败", "trashId", items[i].Id, "type", items[i].ItemType, "error", err.Error())
+			return errors.Biz("trash.errors.purge_failed")
+		}
+	}
+	return nil
+}
+
+// Empty 清空用户的回收站，返回彻底删除的条目数（不含随之删除的条目）
+func (s *TrashService) Empty(ctx context.Context, userId string) (int, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TrashService.Empty")
+	defer span.Finish()
+
+	purged := 0
+	for {
+		items, err := s.trashItemDAO.GetRootsByUserId(ctx, userId, max(s.cfg.PurgeBatchSize, 1))
+		if err != nil {
+			return purged, errors.Biz("trash.errors.query_failed")
+		}
+		if len(items) == 0 {
+			return purged, nil
+		}
+		for i := range items {
+			if err := s.purgeTree(ctx, &items[i]); err != nil {
+				s.logger.Error("msg", "清空回收站失败", "userId", userId, "trashId", items[i].Id, "error", err.Error())
+				return purged, errors.Biz("trash.errors.purge_failed")
+			}
+			purged++
+		}
+	}
+}
+
+// PurgeExpired 彻底删除超过保留天数的条目并释放存储，返回本次彻底删除的条目数
+// 单个条目失败时记录日志后继续，下次执行时重试
+func (s *TrashService) PurgeExpired(ctx context.Context) (int, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "TrashService.PurgeExpired")
+	defer span.Finish()
+
+	if s.cfg.RetentionDays <= 0 {
+		return 0, nil
+	}
+	before := time.Now().AddDate(0, 0, -s.cfg.RetentionDays)
+	items, err := s.trashItemDAO.GetExpiredRoots(ctx, before, max(s.cfg.PurgeBatchSize, 1))
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for i := range items {
+		if err := s.purgeTree(ctx, &items[i]); err != nil {
+			s.logger.Error("msg", "彻底删除过期回收站条目失败", "trashId", items[i].Id, "type", items[i].ItemType, "error", err.Error())
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// DeleteUserData 注销账号时删除用户的回收站条目，条目对应的数据由各模块删除
+func (s *TrashService) DeleteUserData(ctx context.Context, userId string) error {
+	_, err := s.trashItemDAO.RemoveByUserId(ctx, userId)
+	return err
+}
+
+// getUserItems 获取用户选择的条目，条目不存在或不属于该用户时返回错误
+func (s *TrashService) getUserItems(ctx context.Context, userId string, ids []string) ([]model.TrashItem, error) {
+	if len(ids) == 0 || len(ids) > trashMaxIds {
+		return nil, errors.Biz("trash.errors.invalid_ids")
+	}
+	items, err := s.trashItemDAO.GetByUserIdAndIds(ctx, userId, ids)
+	if err != nil {
+		return nil, errors.Biz("trash.errors.query_failed")
+	}
+	if len(items) == 0 {
+		return nil, errors.Biz("trash.errors.not_found")
+	}
+	return items, nil
+}
+
+// restoreTree 恢复条目及随之删除的条目，上级先于下级恢复
+func (s *TrashService) restoreTree(ctx context.Context, item *model.TrashItem) ([]*pb.RestoredTrashItem, error) {
+	restored := make([]*pb.RestoredTrashItem, 0, 1)
+	title, err := s.restoreItem(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	restored = append(restored, &pb.RestoredTrashItem{Id: item.Id, Type: item.ItemType, ItemId: item.ItemId, Title: title})
+
+	children, err := s.trashItemDAO.GetByParentId(ctx, item.Id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range children {
+		title, err := s.restoreItem(ctx, &children[i])
+		if err != nil {
+			return nil, err
+		}
+		restored = append(restored, &pb.RestoredTrashItem{Id: children[i].Id, Type: children[i].ItemType, ItemId: children[i].ItemId, Title: title})
+	}
+	return restored, nil
+}
+
+func (s *TrashService) restoreItem(ctx context.Context, item *model.TrashItem) (string, error) {
+	handler, ok := s.handlers[item.ItemType]
+	if !ok {
+		return "", errors.Biz("trash.errors.unsupported_type")
+	}
+	title, err := handler.Restore(ctx, toEntry(item))
+	if err != nil {
+		return "", err
+	}
+	if err := s.trashItemDAO.RemoveById(ctx, item.Id); err != nil {
+		return "", err
+	}
+	_ = audit.Record(ctx, &audit.Entry{
+		Action:     audit.ActionUpdate,
+		EntityType: item.ItemType,
+		EntityId:   item.ItemId,
+		After:      map[string]string{"restoredFrom": "trash", "title": title},
+	})
+	return title, nil
+}
+
+// purgeTree 彻底删除条目及随之删除的条目，下级先于上级删除；
+// 不在事务中执行，对象存储无法回滚，每个条目删除数据后再删除回收站记录，失败时可以重试
+func (s *TrashService) purgeTree(ctx context.Context, item *model.TrashItem) error {
+	children, err := s.trashItemDAO.GetByParentId(ctx, item.Id)
+	if err != nil {
+		return err
+	}
+	for i := len(children) - 1; i >= 0; i-- {
+		if err := s.purgeItem(ctx, &children[i]); err != nil {
+			return err
+		}
+	}
+	return s.purgeItem(ctx, item)
+}
+
+func (s *TrashService) purgeItem(ctx context.Context, item *model.TrashItem) error {
+	handler, ok := s.handlers[item.ItemType]
+	if !ok {
+		return errors.Biz("trash.errors.unsupported_type")
+	}
+	if err := handler.Purge(ctx, toEntry(item)); err != nil {
+		return err
+	}
+	if err := s.trashItemDAO.RemoveById(ctx, item.Id); err != nil {
+		return err
+	}
+	_ = audit.Record(ctx, &audit.Entry{
+		ActorId:    item.UserId,
+		Action:     audit.ActionDelete,
+		EntityType: item.ItemType,
+		EntityId:   item.ItemId,
+		Before:     map[string]string{"title": item.Title, "deletedFrom": "trash"},
+	})
+	return nil
+}
+
+func (s *TrashService) toProto(item *model.TrashItem) *pb.TrashItem {
+	result := &pb.TrashItem{
+		Id:        item.Id,
+		Type:      item.ItemType,
+		ItemId:    item.ItemId,
+		Title:     item.Title,
+		Reason:    item.Reason,
+		ParentId:  item.ParentId,
+		DeletedAt: toMillis(item.DeletedAt),
+	}
+	if s.cfg.RetentionDays > 0 {
+		result.ExpiresAt = toMillis(item.DeletedAt.AddDate(0, 0, s.cfg.RetentionDays))
+	}
+	return result
+}
+
+func toEntry(item *model.TrashItem) *trash.Entry {
+	return &trash.Entry{
+		Id:        item.Id,
+		UserId:    item.UserId,
+		Type:      item.ItemType,
+		ItemId:    item.ItemId,
+		Title:     item.Title,
+		Reason:    item.Reason,
+		ParentId:  item.ParentId,
+		Snapshot:  item.Snapshot,
+		DeletedAt: item.DeletedAt,
+	}
+}
+
+func toMillis(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixMilli())
+}
+
+// truncate 按字符截断，避免截断多字节字符
+func truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen])
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/config"
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	"github.com/yb2020/odoc/pkg/trash"
+	docDao "github.com/yb2020/odoc/services/doc/dao"
+	docModel "github.com/yb2020/odoc/services/doc/model"
+	docService "github.com/yb2020/odoc/services/doc/service"
+	"github.com/yb2020/odoc/services/trash/dao"
+	"github.com/yb2020/odoc/services/trash/model"
+)
+
+// fakePdfReleaser 记录被释放的PDF
+type fakePdfReleaser struct {
+	released []string
+}
+
+func (r *fakePdfReleaser) ReleasePdf(ctx context.Context, pdfId string) (bool, error) {
+	r.released = append(r.released, pdfId)
+	return true, nil
+}
+
+func TestTrashRestoreAndPurgeFolders(t *testing.T) {
+	db := daotest.NewDB(t, &model.TrashItem{}, &docModel.UserDoc{}, &docModel.UserDocFolder{}, &docModel.UserDocFolderRelation{},
+		&docModel.UserDocClassify{}, &docModel.DocClassifyRelation{})
+	logger := daotest.NewLogger()
+	tracer := opentracing.NoopTracer{}
+	transactionManager := baseDao.NewTransactionManager(db)
+	trashItemDAO := dao.NewTrashItemDAO(db, logger)
+	userDocDAO := docDao.NewUserDocDAO(db, logger)
+	folderDAO := docDao.NewUserDocFolderDAO(db, logger)
+	relationDAO := docDao.NewUserDocFolderRelationDAO(db, logger)
+
+	folderService := docService.NewUserDocFolderService(logger, tracer, folderDAO, transactionManager)
+	if err := folderService.SetUserDocFolderRelationService(docService.NewUserDocFolderRelationService(logger, tracer, relationDAO, folderDAO)); err != nil {
+		t.Fatalf("set relation service: %v", err)
+	}
+	docHandler := docService.NewUserDocTrashHandler(logger, tracer, userDocDAO, folderDAO, relationDAO,
+		docDao.NewUserDocClassifyDAO(db, logger), docDao.NewDocClassifyRelationDAO(db, logger))
+	releaser := &fakePdfReleaser{}
+	docHandler.SetPdfReleaser(releaser)
+	s := NewTrashService(&config.TrashConfig{Enabled: true, PurgeBatchSize: 10}, logger, tracer, transactionManager, trashItemDAO)
+	s.SetHandlers([]trash.Handler{docHandler, docService.NewUserDocFolderTrashHandler(logger, tracer, userDocDAO, folderDAO, relationDAO)})
+	trash.SetRecorder(s)
+	t.Cleanup(func() { trash.SetRecorder(nil) })
+
+	ctx := context.Background()
+	saveFolder := func(userId, id, parentId, name string) {
+		folder := &docModel.UserDocFolder{UserId: userId, ParentId: parentId, Name: name}
+		folder.Id = id
+		if err := folderDAO.Save(ctx, folder); err != nil {
+			t.Fatalf("save folder %s: %v", id, err)
+		}
+	}
+	saveDoc := func(userId, id, pdfId, folderId string) {
+		userDoc := &docModel.UserDoc{UserId: userId, PdfId: pdfId, DocName: id}
+		userDoc.Id = id
+		if err := userDocDAO.Save(ctx, userDoc); err != nil {
+			t.Fatalf("save doc %s: %v", id, err)
+		}
+		if err := relationDAO.Save(ctx, &docModel.UserDocFolderRelation{UserId: userId, FolderId: folderId, DocId: id}); err != nil {
+			t.Fatalf("save relation %s: %v", id, err)
+		}
+	}
+	rootItem := func(userId, itemId string) *model.TrashItem {
+		items, err := trashItemDAO.GetRootsByUserId(ctx, userId, 10)
+		if err != nil {
+			t.Fatalf("get trash roots: %v", err)
+		}
+		for i := range items {
+			if items[i].ItemId == itemId {
+				return &items[i]
+			}
+		}
+		t.Fatalf("%s not in trash of %s: %+v", itemId, userId, items)
+		return nil
+	}
+	folderOf := func(userId, id string) *docModel.UserDocFolder {
+		folder, err := folderDAO.GetByIdAndUserIdIncludeDeleted(ctx, id, userId)
+		if err != nil || folder == nil {
+			t.Fatalf("get folder %s = %+v, %v", id, folder, err)
+		}
+		return folder
+	}
+
+	t.Run("nested folder", func(t *testing.T) {
+		// a > b > c，文献 d1 只在 c 中
+		saveFolder("u1", "a", "0", "Papers")
+		saveFolder("u1", "b", "a", "NLP")
+		saveFolder("u1", "c", "b", "Parsing")
+		saveDoc("u1", "d1", "", "c")
+		if err := folderService.DeleteFoldersByIds(ctx, []string{"a"}, "u1"); err != nil {
+			t.Fatalf("DeleteFoldersByIds: %v", err)
+		}
+		root := rootItem("u1", "a")
+		children, err := trashItemDAO.GetByParentId(ctx, root.Id)
+		if err != nil || len(children) != 2 || children[0].ItemId != "b" || children[1].ItemId != "c" {
+			t.Fatalf("subtree items = %+v, %v, want b then c", children, err)
+		}
+
+		// 删除后根目录下新建了同名文件夹，恢复时重命名
+		saveFolder("u1", "a2", "0", "Papers")
+		restored, err := s.Restore(ctx, "u1", []string{root.Id})
+		if err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		want := []struct{ itemId, title string }{{"a", "Papers (1)"}, {"b", "NLP"}, {"c", "Parsing"}}
+		if len(restored) != len(want) {
+			t.Fatalf("restored = %+v, want %d items", restored, len(want))
+		}
+		for i, w := range want {
+			if restored[i].ItemId != w.itemId || restored[i].Title != w.title {
+				t.Fatalf("restored[%d] = %s %q, want %s %q", i, restored[i].ItemId, restored[i].Title, w.itemId, w.title)
+			}
+		}
+		for id, parentId := range map[string]string{"a": "0", "b": "a", "c": "b"} {
+			if folder := folderOf("u1", id); folder.IsDeleted || folder.ParentId != parentId {
+				t.Fatalf("folder %s = %+v, want restored under %s", id, folder, parentId)
+			}
+		}
+		// 文献回到 c，并移出删除文件夹时放入的未分类
+		relations, err := relationDAO.GetRelationsByUserIdAndDocIds(ctx, "u1", []string{"d1"})
+		if err != nil || len(relations) != 1 || relations[0].FolderId != "c" {
+			t.Fatalf("d1 relations = %+v, %v, want only folder c", relations, err)
+		}
+		if items, _ := trashItemDAO.GetRootsByUserId(ctx, "u1", 10); len(items) != 0 {
+			t.Fatalf("trash after restore = %+v, want empty", items)
+		}
+	})
+
+	t.Run("parent purged", func(t *testing.T) {
+		// 先删除子文件夹 y，再删除并彻底删除父文件夹 x，恢复 y 时放到根目录
+		saveFolder("u2", "x", "0", "Old")
+		saveFolder("u2", "y", "x", "Kept")
+		if err := folderService.DeleteFoldersByIds(ctx, []string{"y"}, "u2"); err != nil {
+			t.Fatalf("delete y: %v", err)
+		}
+		if err := folderService.DeleteFoldersByIds(ctx, []string{"x"}, "u2"); err != nil {
+			t.Fatalf("delete x: %v", err)
+		}
+		if err := s.Purge(ctx, "u2", []string{rootItem("u2", "x").Id}); err != nil {
+			t.Fatalf("Purge x: %v", err)
+		}
+		if folder, err := folderDAO.GetByIdAndUserIdIncludeDeleted(ctx, "x", "u2"); err != nil || folder != nil {
+			t.Fatalf("purged folder x = %+v, %v, want removed", folder, err)
+		}
+		if _, err := s.Restore(ctx, "u2", []string{rootItem("u2", "y").Id}); err != nil {
+			t.Fatalf("Restore y: %v", err)
+		}
+		if folder := folderOf("u2", "y"); folder.IsDeleted || folder.ParentId != "0" {
+			t.Fatalf("folder y = %+v, want restored to the root", folder)
+		}
+	})
+
+	t.Run("purge releases storage", func(t *testing.T) {
+		saveFolder("u3", "f", "0", "Inbox")
+		saveDoc("u3", "d3", "pdf-3", "f")
+		if err := userDocDAO.DeleteById(ctx, "d3"); err != nil {
+			t.Fatalf("delete doc: %v", err)
+		}
+		if err := trash.Record(ctx, &trash.Item{UserId: "u3", Type: trash.TypeDoc, ItemId: "d3", Title: "d3"}); err != nil {
+			t.Fatalf("record doc: %v", err)
+		}
+		if err := folderService.DeleteFoldersByIds(ctx, []string{"f"}, "u3"); err != nil {
+			t.Fatalf("delete folder: %v", err)
+		}
+
+		purged, err := s.Empty(ctx, "u3")
+		if err != nil || purged != 2 {
+			t.Fatalf("Empty = %d, %v, want 2", purged, err)
+		}
+		if len(releaser.released) != 1 || releaser.released[0] != "pdf-3" {
+			t.Fatalf("released = %v, want [pdf-3]", releaser.released)
+		}
+		if userDoc, err := userDocDAO.GetByIdAndUserIdIncludeDeleted(ctx, "d3", "u3"); err != nil || userDoc != nil {
+			t.Fatalf("purged doc = %+v, %v, want removed", userDoc, err)
+		}
+		if relations, _ := relationDAO.GetRelationsByUserIdAndDocIds(ctx, "u3", []string{"d3"}); len(relations) != 0 {
+			t.Fatalf("purged doc relations = %+v, want none", relations)
+		}
+		if folder, err := folderDAO.GetByIdAndUserIdIncludeDeleted(ctx, "f", "u3"); err != nil || folder != nil {
+			t.Fatalf("purged folder = %+v, %v, want removed", folder, err)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/user"
+	"github.com/yb2020/odoc/services/user/service"
+)
+
+// AccountAPI 邮箱账号生命周期API处理器
+type AccountAPI struct {
+	accountService *service.AccountService
+	logger         logging.Logger
+	tracer         opentracing.Tracer
+	localizer      i18n.Localizer
+}
+
+// NewAccountAPI 创建邮箱账号生命周期API处理器
+func NewAccountAPI(logger logging.Logger, tracer opentracing.Tracer,
+	localizer i18n.Localizer,
+	accountService *service.AccountService,
+) *AccountAPI {
+	return &AccountAPI{
+		accountService: accountService,
+		logger:         logger,
+		tracer:         tracer,
+		localizer:      localizer,
+	}
+}
+
+// SendVerificationEmail 发送邮箱验证邮件
+func (api *AccountAPI) SendVerificationEmail(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AccountAPI.SendVerificationEmail")
+	defer span.Finish()
+
+	req := &pb.SendVerificationEmailRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析发送验证邮件请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	if err := api.accountService.SendVerificationEmail(ctx, req.Email, api.localizer.GetLanguage(c)); err != nil {
+		api.logger.Warn("msg", "发送验证邮件失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, "success", &pb.SendVerificationEmailResponse{})
+}
+
+// VerifyEmail 验证邮箱
+func (api *AccountAPI) VerifyEmail(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AccountAPI.VerifyEmail")
+	defer span.Finish()
+
+	req := &pb.VerifyEmailRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析验证邮箱请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	user, err := api.accountService.VerifyEmail(ctx, req.Token)
+	if err != nil {
+		api.logger.Warn("msg", "验证邮箱失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, "success", &pb.VerifyEmailResponse{
+		User: user.ToProto(),
+	})
+}
+
+// ForgotPassword 忘记密码
+func (api *AccountAPI) ForgotPassword(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AccountAPI.ForgotPassword")
+	defer span.Finish()
+
+	req := &pb.ForgotPasswordRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析忘记密码请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	if err := api.accountService.ForgotPassword(ctx, req.Email, api.localizer.GetLanguage(c)); err != nil {
+		api.logger.Warn("msg", "发送重置密码邮件失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, "success", &pb.ForgotPasswordResponse{})
+}
+
+// ResetPassword 重置密码
+func (api *AccountAPI) ResetPassword(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AccountAPI.ResetPassword")
+	defer span.Finish()
+
+	req := &pb.ResetPasswordRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析重置密码请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	if err := api.accountService.ResetPassword(ctx, req.Token, req.NewPassword, api.localizer.GetLanguage(c)); err != nil {
+		api.logger.Warn("msg", "重置密码失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, "success", &pb.ResetPasswordResponse{})
+}
+
+// ChangePassword 修改密码
+func (api *AccountAPI) ChangePassword(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "AccountAPI.ChangePassword")
+	defer span.Finish()
+
+	req := &pb.ChangePasswordRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析修改密码请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	if err := api.accountService.ChangePassword(ctx, req.OldPassword, req.NewPassword, api.localizer.GetLanguage(c)); err != nil {
+		api.logger.Warn("msg", "修改密码失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, "success", &pb.ChangePasswordResponse{})
+}
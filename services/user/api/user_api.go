@@ -19,22 +19,25 @@ import (
 
 // UserAPI 用户API处理器
 type UserAPI struct {
-	userService *service.UserService
-	logger      logging.Logger
-	tracer      opentracing.Tracer
-	localizer   i18n.Localizer
+	userService    *service.UserService
+	accountService *service.AccountService
+	logger         logging.Logger
+	tracer         opentracing.Tracer
+	localizer      i18n.Localizer
 }
 
 // NewUserAPI 创建用户API处理器
 func NewUserAPI(logger logging.Logger, tracer opentracing.Tracer,
 	localizer i18n.Localizer,
 	userService *service.UserService,
+	accountService *service.AccountService,
 ) *UserAPI {
 	return &UserAPI{
-		userService: userService,
-		logger:      logger,
-		tracer:      tracer,
-		localizer:   localizer,
+		userService:    userService,
+		accountService: accountService,
+		logger:         logger,
+		tracer:         tracer,
+		localizer:      localizer,
 	}
 }
 
@@ -105,6 +108,11 @@ func (api *UserAPI) Register(c *gin.Context) {
 		return
 	}
 
+	// 注册成功后发送邮箱验证邮件，发送失败时用户可以重新请求发送
+	if err := api.accountService.SendVerificationEmail(ctx, req.Email, api.localizer.GetLanguage(c)); err != nil {
+		api.logger.Warn("msg", "注册后发送验证邮件失败", "email", req.Email, "error", err.Error())
+	}
+
 	response.Success(c, "success", resp)
 }
 
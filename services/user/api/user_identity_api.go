@@ -0,0 +1,74 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/response"
+	"github.com/yb2020/odoc/pkg/transport"
+	pb "github.com/yb2020/odoc/proto/gen/go/user"
+	"github.com/yb2020/odoc/services/user/service"
+)
+
+// UserIdentityAPI 用户外部身份API处理器
+type UserIdentityAPI struct {
+	identityService *service.UserIdentityService
+	logger          logging.Logger
+	tracer          opentracing.Tracer
+}
+
+// NewUserIdentityAPI 创建用户外部身份API处理器
+func NewUserIdentityAPI(logger logging.Logger, tracer opentracing.Tracer, identityService *service.UserIdentityService) *UserIdentityAPI {
+	return &UserIdentityAPI{
+		identityService: identityService,
+		logger:          logger,
+		tracer:          tracer,
+	}
+}
+
+// ListIdentities 获取当前用户已关联的外部身份
+func (api *UserIdentityAPI) ListIdentities(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "UserIdentityAPI.ListIdentities")
+	defer span.Finish()
+
+	userId, _ := userContext.GetUserID(ctx)
+	identities, hasPassword, err := api.identityService.ListIdentities(ctx, userId)
+	if err != nil {
+		api.logger.Warn("msg", "获取外部身份列表失败", "userId", userId, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	resp := &pb.ListUserIdentitiesResponse{
+		Identities:  make([]*pb.UserIdentity, 0, len(identities)),
+		HasPassword: hasPassword,
+	}
+	for i := range identities {
+		resp.Identities = append(resp.Identities, identities[i].ToProto())
+	}
+	response.Success(c, "success", resp)
+}
+
+// UnlinkIdentity 解除关联外部身份
+func (api *UserIdentityAPI) UnlinkIdentity(c *gin.Context) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(c.Request.Context(), api.tracer, "UserIdentityAPI.UnlinkIdentity")
+	defer span.Finish()
+
+	req := &pb.UnlinkUserIdentityRequest{}
+	if err := transport.BindProto(c, req); err != nil {
+		api.logger.Warn("msg", "解析解除关联外部身份请求失败", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	userId, _ := userContext.GetUserID(ctx)
+	if err := api.identityService.UnlinkIdentity(ctx, userId, req.Provider); err != nil {
+		api.logger.Warn("msg", "解除关联外部身份失败", "userId", userId, "provider", req.Provider, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, "success", &pb.UnlinkUserIdentityResponse{})
+}
@@ -8,6 +8,7 @@ import (
 
 	baseDao "github.com/yb2020/odoc/pkg/dao"
 	"github.com/yb2020/odoc/pkg/logging"
+	pb "github.com/yb2020/odoc/proto/gen/go/user"
 	"github.com/yb2020/odoc/services/user/model"
 )
 
@@ -39,16 +40,42 @@ func (d *UserDAO) GetUserByEmail(ctx context.Context, email string) (*model.User
 	return &user, nil
 }
 
-// FindByGoogleID 根据 Google Open ID 查找用户
-func (d *UserDAO) FindByGoogleID(ctx context.Context, googleID string) (*model.User, error) {
-	var user model.User
-	err := d.GetDB(ctx).Where("google_open_id = ?", googleID).First(&user).Error
+// ActivateAndClearPassword 激活未激活的账号并清空密码，账号已不是未激活状态时返回false
+func (d *UserDAO) ActivateAndClearPassword(ctx context.Context, id string) (bool, error) {
+	result := d.GetDB(ctx).Model(&model.User{}).
+		Where("id = ? AND status = ? AND is_deleted = ?", id, pb.UserStatus_STATUS_INACTIVE, false).
+		Updates(map[string]interface{}{"status": pb.UserStatus_STATUS_ACTIVE, "password": ""})
+	if result.Error != nil {
+		d.logger.Error("msg", "激活账号失败", "id", id, "error", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// LegacyGoogleBinding 旧版 t_user.google_open_id 中保存的 Google 绑定关系
+type LegacyGoogleBinding struct {
+	UserId       string
+	Email        string
+	Nickname     string
+	Avatar       string
+	GoogleOpenId string
+}
+
+// FindLegacyGoogleBindings 查询旧版 google_open_id 列中仍有值的用户，列不存在时返回空
+func (d *UserDAO) FindLegacyGoogleBindings(ctx context.Context) ([]LegacyGoogleBinding, error) {
+	db := d.GetDB(ctx)
+	if !db.Migrator().HasColumn(&model.User{}, "google_open_id") {
+		return nil, nil
+	}
+
+	var bindings []LegacyGoogleBinding
+	err := db.Model(&model.User{}).
+		Select("id AS user_id, email, nickname, avatar, google_open_id").
+		Where("google_open_id IS NOT NULL AND google_open_id <> '' AND is_deleted = ?", false).
+		Scan(&bindings).Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// 返回 nil, nil 表示未找到，由 service 层决定是创建还是返回业务错误
-			return nil, nil
-		}
+		d.logger.Error("msg", "查询旧版Google绑定关系失败", "error", err.Error())
 		return nil, err
 	}
-	return &user, nil
+	return bindings, nil
 }
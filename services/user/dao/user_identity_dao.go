@@ -0,0 +1,86 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/services/user/model"
+)
+
+// UserIdentityDAO 用户外部身份数据访问对象
+type UserIdentityDAO struct {
+	*baseDao.GormBaseDAO[model.UserIdentity]
+	logger logging.Logger
+}
+
+// NewUserIdentityDAO 创建用户外部身份DAO
+func NewUserIdentityDAO(db *gorm.DB, logger logging.Logger) *UserIdentityDAO {
+	return &UserIdentityDAO{
+		GormBaseDAO: baseDao.NewGormBaseDAO[model.UserIdentity](db, logger),
+		logger:      logger,
+	}
+}
+
+// FindByProviderAndSubject 根据提供方和提供方用户标识查找外部身份
+func (d *UserIdentityDAO) FindByProviderAndSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	err := d.GetDB(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "根据提供方查找外部身份失败", "provider", provider, "error", err.Error())
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// FindByUserIdAndProvider 查找用户在指定提供方下关联的外部身份
+func (d *UserIdentityDAO) FindByUserIdAndProvider(ctx context.Context, userId, provider string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	err := d.GetDB(ctx).Where("user_id = ? AND provider = ?", userId, provider).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error("msg", "查找用户外部身份失败", "userId", userId, "provider", provider, "error", err.Error())
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// FindByUserId 获取用户关联的全部外部身份
+func (d *UserIdentityDAO) FindByUserId(ctx context.Context, userId string) ([]model.UserIdentity, error) {
+	var identities []model.UserIdentity
+	err := d.GetDB(ctx).Where("user_id = ?", userId).Order("created_at ASC").Find(&identities).Error
+	if err != nil {
+		d.logger.Error("msg", "获取用户外部身份列表失败", "userId", userId, "error", err.Error())
+		return nil, err
+	}
+	return identities, nil
+}
+
+// CountByUserId 统计用户关联的外部身份数量
+func (d *UserIdentityDAO) CountByUserId(ctx context.Context, userId string) (int64, error) {
+	var count int64
+	err := d.GetDB(ctx).Model(&model.UserIdentity{}).Where("user_id = ?", userId).Count(&count).Error
+	if err != nil {
+		d.logger.Error("msg", "统计用户外部身份数量失败", "userId", userId, "error", err.Error())
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeleteByUserId 物理删除用户关联的全部外部身份
+func (d *UserIdentityDAO) DeleteByUserId(ctx context.Context, userId string) error {
+	err := d.GetDB(ctx).Where("user_id = ?", userId).Delete(&model.UserIdentity{}).Error
+	if err != nil {
+		d.logger.Error("msg", "删除用户外部身份失败", "userId", userId, "error", err.Error())
+		return err
+	}
+	return nil
+}
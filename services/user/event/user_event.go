@@ -1,11 +1,29 @@
 package event
 
-import "github.com/yb2020/odoc/pkg/eventbus"
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/eventbus"
+)
 
 // 用户模块下的事件类型
 const (
 	UserRegisterEvent eventbus.EventType = "user.register"
 	UserDeletedEvent  eventbus.EventType = "user.deleted"
+	// UserPasswordChangedEvent 用户密码被修改或重置，Data为用户ID
+	UserPasswordChangedEvent eventbus.EventType = "user.password_changed"
+	// UserNewDeviceLoginEvent 用户在首次出现的设备上登录成功，Data为NewDeviceLoginData
+	UserNewDeviceLoginEvent eventbus.EventType = "user.new_device_login"
 
 	// 其他事件类型...
 )
+
+// NewDeviceLoginData 新设备登录事件数据
+type NewDeviceLoginData struct {
+	UserId   string    // 用户ID
+	Method   string    // 登录方式
+	Device   string    // 设备名称
+	IP       string    // 登录IP
+	Time     time.Time // 登录时间
+	Language string    // 登录请求的语言，用于渲染通知邮件
+}
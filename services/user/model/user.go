@@ -36,7 +36,6 @@ type User struct {
 	Nickname            string        `json:"nickname" gorm:"type:varchar(200)"`
 	Avatar              string        `json:"avatar" gorm:"type:varchar(200)"`
 	Roles               UserRoleSlice `json:"roles" gorm:"type:json"`
-	GoogleOpenId        string        `json:"google_open_id" gorm:"type:varchar(200);uniqueIndex:idx_unique_t_user_google_open_id"`
 	Status              pb.UserStatus `json:"status" gorm:"type:int;default:1"` // 默认为激活状态
 	AccessToken         string        `json:"access_token" gorm:"-"`
 	AccessTokenExpires  uint64        `json:"access_token_expires" gorm:"-"`
@@ -97,7 +96,6 @@ func (u *User) ToProto() *pb.User {
 		RefreshToken:        u.RefreshToken,
 		RefreshTokenExpires: u.RefreshTokenExpires,
 		LastLogin:           u.LastLogin,
-		GoogleOpenId:        u.GoogleOpenId,
 		Status:              u.Status,
 	}
 
@@ -124,7 +122,6 @@ func (u *User) FromProto(pbUser *pb.User) {
 	u.RefreshToken = pbUser.RefreshToken
 	u.RefreshTokenExpires = pbUser.RefreshTokenExpires
 	u.LastLogin = pbUser.LastLogin
-	u.GoogleOpenId = pbUser.GoogleOpenId
 	if pbUser.Status != pb.UserStatus_STATUS_UNKNOWN {
 		u.Status = pbUser.Status
 	}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yb2020/odoc/pkg/model"
+	pb "github.com/yb2020/odoc/proto/gen/go/user"
+)
+
+// UserIdentity 用户关联的外部身份（Google、GitHub、ORCID、机构OIDC等）
+// 同一提供方下的 Subject 全局唯一，一个用户可以关联多个提供方
+type UserIdentity struct {
+	model.BaseModel           // 嵌入基础模型，继承ID、CreatedAt、UpdatedAt字段和钩子方法
+	UserId          string    `json:"userId" gorm:"column:user_id;size:36;index"`                                                      // 用户ID
+	Provider        string    `json:"provider" gorm:"column:provider;size:64;uniqueIndex:idx_unique_t_user_identity_provider_subject"` // 提供方标识
+	Subject         string    `json:"subject" gorm:"column:subject;size:255;uniqueIndex:idx_unique_t_user_identity_provider_subject"`  // 提供方内的用户唯一标识（OIDC sub、GitHub id、ORCID iD）
+	Email           string    `json:"email" gorm:"column:email;size:255"`                                                              // 提供方返回的邮箱
+	EmailVerified   bool      `json:"emailVerified" gorm:"column:email_verified;default:false"`                                        // 提供方是否声明邮箱已验证
+	DisplayName     string    `json:"displayName" gorm:"column:display_name;size:200"`                                                 // 提供方返回的显示名称
+	Avatar          string    `json:"avatar" gorm:"column:avatar;size:500"`                                                            // 提供方返回的头像
+	LastLoginAt     time.Time `json:"lastLoginAt" gorm:"column:last_login_at"`                                                         // 最后一次通过该身份登录的时间
+}
+
+// TableName 返回表名
+func (UserIdentity) TableName() string {
+	return "t_user_identity"
+}
+
+// ToProto 将外部身份转换为protobuf消息
+func (i *UserIdentity) ToProto() *pb.UserIdentity {
+	if i == nil {
+		return nil
+	}
+	pbIdentity := &pb.UserIdentity{
+		Id:          i.Id,
+		Provider:    i.Provider,
+		Email:       i.Email,
+		DisplayName: i.DisplayName,
+		Avatar:      i.Avatar,
+		LinkedAt:    uint64(i.CreatedAt.UnixMilli()),
+	}
+	if !i.LastLoginAt.IsZero() {
+		pbIdentity.LastLoginAt = uint64(i.LastLoginAt.UnixMilli())
+	}
+	return pbIdentity
+}
+
+// ExternalIdentity 外部身份提供方认证成功后返回的用户信息
+type ExternalIdentity struct {
+	Provider      string // 提供方标识
+	Subject       string // 提供方内的用户唯一标识
+	Email         string // 邮箱
+	EmailVerified bool   // 邮箱是否已验证（已结合提供方的 trust_email 配置）
+	Name          string // 显示名称
+	Avatar        string // 头像
+}
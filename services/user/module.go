@@ -1,12 +1,15 @@
 package user
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/yb2020/odoc/pkg/cache"
 	"github.com/yb2020/odoc/pkg/scheduler"
+	"github.com/yb2020/odoc/pkg/takeout"
 	"google.golang.org/grpc"
 	"gorm.io/gorm"
 
@@ -15,25 +18,39 @@ import (
 	"github.com/yb2020/odoc/pkg/eventbus"
 	"github.com/yb2020/odoc/pkg/i18n"
 	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/mail"
 	"github.com/yb2020/odoc/pkg/middleware"
 	"github.com/yb2020/odoc/pkg/registry"
 	"github.com/yb2020/odoc/services/user/api"
 	"github.com/yb2020/odoc/services/user/dao"
+	"github.com/yb2020/odoc/services/user/event"
 	usergrpc "github.com/yb2020/odoc/services/user/grpc"
+	"github.com/yb2020/odoc/services/user/model"
 	"github.com/yb2020/odoc/services/user/service"
 )
 
 // 编译时类型检查：确保 UserModule 实现了 registry.Module 接口
 var _ registry.Module = (*UserModule)(nil)
 
+// 编译时类型检查：确保 UserModule 实现了 registry.TakeoutExporterProvider 接口
+var _ registry.TakeoutExporterProvider = (*UserModule)(nil)
+
+// 编译时类型检查：确保 UserModule 实现了 registry.UserDataDeleter 接口
+var _ registry.UserDataDeleter = (*UserModule)(nil)
+
 // Module 导出模块实例，用于自动发现和注册
 var Module = &UserModule{}
 
 // UserModule 实现用户服务模块
 type UserModule struct {
 	API                *api.UserAPI
+	AccountAPI         *api.AccountAPI
+	IdentityAPI        *api.UserIdentityAPI
 	GRPCService        *usergrpc.UserGRPCServer
 	UserService        *service.UserService
+	AccountService     *service.AccountService
+	IdentityService    *service.UserIdentityService
+	TakeoutExporter    *service.UserTakeoutExporter
 	authMiddleware     *middleware.AuthMiddleware
 	db                 *gorm.DB
 	config             *config.Config
@@ -75,13 +92,65 @@ func (m *UserModule) Initialize() error {
 	cacheClient := cache.NewCache(m.logger, 30*time.Minute, m.Name())
 	m.UserService = service.NewUserService(cacheClient, m.logger, m.tracer, m.localizer, userDAO, m.eventBus, m.transactionManager)
 
-	m.API = api.NewUserAPI(m.logger, m.tracer, m.localizer, m.UserService)
+	if m.config.Account.TokenSecret == "" {
+		return errors.New("account.token-secret is required")
+	}
+	accountCache := cache.NewCache(m.logger, 0, "account")
+	accountTokenService := service.NewAccountTokenService(accountCache, m.config.Account.TokenSecret, m.logger, m.tracer)
+	mailer := mail.NewMailer(&m.config.Mail, m.logger)
+	m.AccountService = service.NewAccountService(&m.config.Account, m.logger, m.tracer, m.localizer, accountCache,
+		m.eventBus, mailer, m.UserService, accountTokenService)
+
+	identityDAO := dao.NewUserIdentityDAO(m.db, m.logger)
+	m.IdentityService = service.NewUserIdentityService(identityDAO, userDAO, m.UserService, m.eventBus, m.logger, m.tracer, m.transactionManager)
+	m.migrateLegacyGoogleBindings()
+	m.TakeoutExporter = service.NewUserTakeoutExporter(m.logger, m.tracer, userDAO, identityDAO)
+
+	// 用户删除后清理其关联的外部身份，避免外部账号无法再次注册
+	m.eventBus.Subscribe(event.UserDeletedEvent, func(ctx context.Context, e eventbus.Event) {
+		if userId, ok := e.Data.(string); ok {
+			if err := m.IdentityService.RemoveUserIdentities(ctx, userId); err != nil {
+				m.logger.Error("msg", "清理已删除用户的外部身份失败", "userId", userId, "error", err.Error())
+			}
+		}
+	})
+
+	// 新设备登录时发送提醒邮件
+	if m.config.Session.NotifyNewDevice {
+		m.eventBus.Subscribe(event.UserNewDeviceLoginEvent, func(ctx context.Context, e eventbus.Event) {
+			if data, ok := e.Data.(event.NewDeviceLoginData); ok {
+				if err := m.AccountService.SendNewDeviceLoginNotice(ctx, &data); err != nil {
+					m.logger.Warn("msg", "发送新设备登录提醒失败", "userId", data.UserId, "error", err.Error())
+				}
+			}
+		})
+	}
+
+	m.API = api.NewUserAPI(m.logger, m.tracer, m.localizer, m.UserService, m.AccountService)
+	m.AccountAPI = api.NewAccountAPI(m.logger, m.tracer, m.localizer, m.AccountService)
+	m.IdentityAPI = api.NewUserIdentityAPI(m.logger, m.tracer, m.IdentityService)
 
 	m.GRPCService = usergrpc.NewUserGRPCServer(m.logger, m.tracer, m.UserService)
 
 	return nil
 }
 
+// migrateLegacyGoogleBindings 将旧版 google_open_id 迁移到外部身份表，失败不影响启动，下次启动会继续迁移
+func (m *UserModule) migrateLegacyGoogleBindings() {
+	if !m.db.Migrator().HasTable(&model.UserIdentity{}) {
+		m.logger.Warn("msg", "外部身份表不存在，跳过旧版Google绑定迁移，请先执行数据库结构同步")
+		return
+	}
+	migrated, err := m.IdentityService.MigrateLegacyGoogleBindings(context.Background())
+	if err != nil {
+		m.logger.Error("msg", "迁移旧版Google绑定失败", "migrated", migrated, "error", err.Error())
+		return
+	}
+	if migrated > 0 {
+		m.logger.Info("msg", "迁移旧版Google绑定完成", "migrated", migrated)
+	}
+}
+
 // Shutdown 关闭模块
 func (m *UserModule) Shutdown() error {
 	// 目前没有需要清理的资源
@@ -99,6 +168,10 @@ func (m *UserModule) RegisterRoutes(r *gin.Engine) {
 	{
 		userPublicGroup.GET("/email/exists", m.API.CheckEmailExists)
 		userPublicGroup.POST("/register", m.API.Register)
+		userPublicGroup.POST("/email/sendVerification", m.AccountAPI.SendVerificationEmail)
+		userPublicGroup.POST("/email/verify", m.AccountAPI.VerifyEmail)
+		userPublicGroup.POST("/password/forgot", m.AccountAPI.ForgotPassword)
+		userPublicGroup.POST("/password/reset", m.AccountAPI.ResetPassword)
 	}
 
 	userGroup := r.Group("/api/user")
@@ -106,6 +179,9 @@ func (m *UserModule) RegisterRoutes(r *gin.Engine) {
 	{
 		userGroup.GET("/profile", m.API.GetProfile)
 		userGroup.POST("/profile/update", m.API.UpdateProfile)
+		userGroup.POST("/password/change", m.AccountAPI.ChangePassword)
+		userGroup.GET("/identities/list", m.IdentityAPI.ListIdentities)
+		userGroup.POST("/identities/unlink", m.IdentityAPI.UnlinkIdentity)
 	}
 
 	adminGroup := r.Group("/api/admin/user")
@@ -146,6 +222,29 @@ func (m *UserModule) SetAuthMiddleware(authMiddleware *middleware.AuthMiddleware
 	m.authMiddleware = authMiddleware
 }
 
+// TakeoutExporters 返回用户模块的用户数据导出器
+func (m *UserModule) TakeoutExporters() []takeout.Exporter {
+	return []takeout.Exporter{m.TakeoutExporter}
+}
+
+// DeleteUserData 注销账号时删除用户的外部身份和用户本身，作为注销流程的最后一步
+func (m *UserModule) DeleteUserData(ctx context.Context, userId string) error {
+	if err := m.IdentityService.RemoveUserIdentities(ctx, userId); err != nil {
+		return err
+	}
+	return m.UserService.RemoveUser(ctx, userId)
+}
+
+// GetAccountService 返回邮箱账号生命周期服务实例
+func (m *UserModule) GetAccountService() *service.AccountService {
+	return m.AccountService
+}
+
+// GetIdentityService 返回用户外部身份服务实例
+func (m *UserModule) GetIdentityService() *service.UserIdentityService {
+	return m.IdentityService
+}
+
 // GetUserService 返回用户服务实例
 func (m *UserModule) GetUserService() *service.UserService {
 	return m.UserService
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/internal/biz"
+	"github.com/yb2020/odoc/pkg/cache"
+	userContext "github.com/yb2020/odoc/pkg/context"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/eventbus"
+	"github.com/yb2020/odoc/pkg/i18n"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/mail"
+	pb "github.com/yb2020/odoc/proto/gen/go/user"
+	"github.com/yb2020/odoc/services/user/event"
+	"github.com/yb2020/odoc/services/user/model"
+)
+
+var accountMailThrottleKey = "account_mail:%s:%s"
+
+// AccountService 邮箱账号生命周期服务：邮箱验证、找回密码、修改密码
+type AccountService struct {
+	cfg                 *config.AccountConfig
+	userService         *UserService
+	accountTokenService *AccountTokenService
+	mailer              mail.Mailer
+	cache               cache.Cache
+	localizer           i18n.Localizer
+	eventBus            *eventbus.EventBus
+	logger              logging.Logger
+	tracer              opentracing.Tracer
+}
+
+// NewAccountService 创建邮箱账号生命周期服务
+func NewAccountService(cfg *config.AccountConfig, logger logging.Logger, tracer opentracing.Tracer,
+	localizer i18n.Localizer, cache cache.Cache, eventBus *eventbus.EventBus, mailer mail.Mailer,
+	userService *UserService, accountTokenService *AccountTokenService) *AccountService {
+	return &AccountService{
+		cfg:                 cfg,
+		userService:         userService,
+		accountTokenService: accountTokenService,
+		mailer:              mailer,
+		cache:               cache,
+		localizer:           localizer,
+		eventBus:            eventBus,
+		logger:              logger,
+		tracer:              tracer,
+	}
+}
+
+// SendVerificationEmail 发送邮箱验证邮件，邮箱不存在或已验证时静默返回，避免泄露账号是否存在
+func (s *AccountService) SendVerificationEmail(ctx context.Context, email string, lang string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountService.SendVerificationEmail")
+	defer span.Finish()
+
+	user, err := s.findUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Status != pb.UserStatus_STATUS_INACTIVE {
+		s.logger.Info("msg", "邮箱不存在或无需验证，跳过发送验证邮件", "email", email)
+		return nil
+	}
+	if !s.acquireSendSlot(ctx, AccountTokenVerifyEmail, user.Email) {
+		return errors.Biz("user.account.errors.send_too_frequent")
+	}
+
+	ttl := time.Duration(s.cfg.VerifyTokenTTL) * time.Second
+	token, err := s.accountTokenService.Issue(ctx, AccountTokenVerifyEmail, user.Id, user.Email, ttl)
+	if err != nil {
+		s.logger.Error("msg", "签发邮箱验证令牌失败", "userId", user.Id, "error", err.Error())
+		return errors.Biz("user.account.errors.send_mail_failed")
+	}
+	data := map[string]interface{}{
+		"Email": user.Email,
+		"Link":  buildTokenLink(s.cfg.VerifyURL, token),
+		"Hours": int(ttl.Hours()),
+	}
+	return s.sendMail(ctx, user.Email, "user.mail.verify_email", data, lang)
+}
+
+// VerifyEmail 使用令牌验证邮箱并激活账号
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountService.VerifyEmail")
+	defer span.Finish()
+
+	claims, err := s.accountTokenService.Consume(ctx, AccountTokenVerifyEmail, token)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userService.GetUserByID(ctx, claims.UserId)
+	if err != nil {
+		return nil, errors.BizWrap(err.Error(), err)
+	}
+	if user == nil {
+		return nil, errors.Biz("user.error.user_not_found")
+	}
+	// 发出验证邮件后修改过邮箱，旧令牌不再有效
+	if !strings.EqualFold(user.Email, claims.Email) {
+		return nil, errors.Biz("user.account.errors.invalid_token")
+	}
+	switch user.Status {
+	case pb.UserStatus_STATUS_ACTIVE:
+		return user, nil
+	case pb.UserStatus_STATUS_INACTIVE:
+	default:
+		return nil, errors.BizWithStatus(biz.User_StatusNotActive, "user not active")
+	}
+
+	user.Status = pb.UserStatus_STATUS_ACTIVE
+	// 因为使用了，ModifyExcludeNull，设置为空，即可不更新密码字段
+	user.Password = ""
+	updatedUser, err := s.userService.UpdateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("msg", "邮箱验证成功", "userId", user.Id)
+	return updatedUser, nil
+}
+
+// ForgotPassword 发送重置密码邮件，邮箱不存在时静默返回，避免泄露账号是否存在
+func (s *AccountService) ForgotPassword(ctx context.Context, email string, lang string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountService.ForgotPassword")
+	defer span.Finish()
+
+	user, err := s.findUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil || !canResetPassword(user.Status) {
+		s.logger.Info("msg", "邮箱不存在或账号不可用，跳过发送重置密码邮件", "email", email)
+		return nil
+	}
+	if !s.acquireSendSlot(ctx, AccountTokenResetPassword, user.Email) {
+		return errors.Biz("user.account.errors.send_too_frequent")
+	}
+
+	ttl := time.Duration(s.cfg.ResetTokenTTL) * time.Second
+	token, err := s.accountTokenService.Issue(ctx, AccountTokenResetPassword, user.Id, user.Email, ttl)
+	if err != nil {
+		s.logger.Error("msg", "签发重置密码令牌失败", "userId", user.Id, "error", err.Error())
+		return errors.Biz("user.account.errors.send_mail_failed")
+	}
+	data := map[string]interface{}{
+		"Email":   user.Email,
+		"Link":    buildTokenLink(s.cfg.ResetURL, token),
+		"Minutes": int(ttl.Minutes()),
+	}
+	return s.sendMail(ctx, user.Email, "user.mail.reset_password", data, lang)
+}
+
+// ResetPassword 使用令牌重置密码，并撤销该用户已有的所有登录令牌
+// 能收到重置邮件说明邮箱可用，未验证的账号同时完成邮箱验证
+func (s *AccountService) ResetPassword(ctx context.Context, token string, newPassword string, lang string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountService.ResetPassword")
+	defer span.Finish()
+
+	if err := s.validatePassword(newPassword); err != nil {
+		return err
+	}
+	claims, err := s.accountTokenService.Consume(ctx, AccountTokenResetPassword, token)
+	if err != nil {
+		return err
+	}
+	user, err := s.userService.GetUserByID(ctx, claims.UserId)
+	if err != nil {
+		return errors.BizWrap(err.Error(), err)
+	}
+	if user == nil {
+		return errors.Biz("user.error.user_not_found")
+	}
+	if !strings.EqualFold(user.Email, claims.Email) || !canResetPassword(user.Status) {
+		return errors.Biz("user.account.errors.invalid_token")
+	}
+
+	if user.Status == pb.UserStatus_STATUS_INACTIVE {
+		user.Status = pb.UserStatus_STATUS_ACTIVE
+	}
+	return s.updatePassword(ctx, user, newPassword, lang)
+}
+
+// ChangePassword 登录用户修改密码，并撤销该用户已有的所有登录令牌
+func (s *AccountService) ChangePassword(ctx context.Context, oldPassword string, newPassword string, lang string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountService.ChangePassword")
+	defer span.Finish()
+
+	userId, ok := userContext.GetUserID(ctx)
+	if !ok {
+		return errors.Biz("user.error.user_not_found")
+	}
+	if err := s.validatePassword(newPassword); err != nil {
+		return err
+	}
+	user, err := s.userService.GetUserByID(ctx, userId)
+	if err != nil {
+		return errors.BizWrap(err.Error(), err)
+	}
+	if user == nil {
+		return errors.Biz("user.error.user_not_found")
+	}
+	// 第三方登录创建的账号没有密码，需要通过找回密码设置
+	if user.Password == "" {
+		return errors.Biz("user.account.errors.password_not_set")
+	}
+	if HashPassword(oldPassword, user.Id) != user.Password {
+		return errors.Biz("user.account.errors.old_password_incorrect")
+	}
+	if oldPassword == newPassword {
+		return errors.Biz("user.account.errors.password_unchanged")
+	}
+	return s.updatePassword(ctx, user, newPassword, lang)
+}
+
+// updatePassword 保存新密码，撤销所有登录令牌并发送通知邮件
+func (s *AccountService) updatePassword(ctx context.Context, user *model.User, newPassword string, lang string) error {
+	user.Password = HashPassword(newPassword, user.Id)
+	if _, err := s.userService.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	s.logger.Info("msg", "用户密码已更新", "userId", user.Id)
+
+	// 同步发布，确保返回前旧的登录令牌已经撤销
+	s.eventBus.Publish(ctx, eventbus.Event{Type: event.UserPasswordChangedEvent, Data: user.Id}, false)
+
+	data := map[string]interface{}{
+		"Email": user.Email,
+		"Time":  time.Now().UTC().Format("2006-01-02 15:04:05 UTC"),
+	}
+	if err := s.sendMail(ctx, user.Email, "user.mail.password_changed", data, lang); err != nil {
+		// 通知邮件失败不影响修改结果
+		s.logger.Warn("msg", "发送密码修改通知邮件失败", "userId", user.Id, "error", err.Error())
+	}
+	return nil
+}
+
+// SendNewDeviceLoginNotice 账号在新设备上登录成功后发送提醒邮件
+func (s *AccountService) SendNewDeviceLoginNotice(ctx context.Context, data *event.NewDeviceLoginData) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountService.SendNewDeviceLoginNotice")
+	defer span.Finish()
+
+	user, err := s.userService.GetUserByID(ctx, data.UserId)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Email == "" {
+		return nil
+	}
+	device := data.Device
+	if device == "" {
+		device = "-"
+	}
+	mailData := map[string]interface{}{
+		"Email":  user.Email,
+		"Device": device,
+		"IP":     data.IP,
+		"Method": data.Method,
+		"Time":   data.Time.UTC().Format("2006-01-02 15:04:05 UTC"),
+	}
+	return s.sendMail(ctx, user.Email, "user.mail.new_device_login", mailData, data.Language)
+}
+
+// findUserByEmail 根据邮箱查找用户，不存在时返回nil
+func (s *AccountService) findUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	user, err := s.userService.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err.Error() == "user.error.user_email_not_found" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// acquireSendSlot 同一邮箱同一用途在发送间隔内只允许发送一次
+func (s *AccountService) acquireSendSlot(ctx context.Context, purpose AccountTokenPurpose, email string) bool {
+	if s.cfg.SendInterval <= 0 {
+		return true
+	}
+	key := fmt.Sprintf(accountMailThrottleKey, purpose, strings.ToLower(email))
+	// 并发请求只有一个能占到发送间隔
+	acquired, err := s.cache.SetNX(ctx, key, time.Now().Unix(), time.Duration(s.cfg.SendInterval)*time.Second)
+	if err != nil {
+		// 缓存不可用时不阻断邮件发送
+		s.logger.Warn("msg", "记录邮件发送时间失败", "email", email, "error", err.Error())
+		return true
+	}
+	return acquired
+}
+
+// validatePassword 校验新密码强度
+func (s *AccountService) validatePassword(password string) error {
+	minLength := s.cfg.PasswordMinLength
+	if minLength <= 0 {
+		minLength = 6
+	}
+	if utf8.RuneCountInString(password) < minLength {
+		return errors.Biz("user.account.errors.password_too_short")
+	}
+	return nil
+}
+
+// sendMail 按语言渲染邮件模板并发送，模板为 <templateId>.subject 和 <templateId>.body
+func (s *AccountService) sendMail(ctx context.Context, to string, templateId string, data map[string]interface{}, lang string) error {
+	message := &mail.Message{
+		To:       []string{to},
+		Subject:  s.localizer.LocalizeWithLanguage(templateId+".subject", data, lang),
+		TextBody: s.localizer.LocalizeWithLanguage(templateId+".body", data, lang),
+	}
+	if err := s.mailer.Send(ctx, message); err != nil {
+		s.logger.Error("msg", "发送邮件失败", "to", to, "template", templateId, "error", err.Error())
+		return errors.Biz("user.account.errors.send_mail_failed")
+	}
+	return nil
+}
+
+// canResetPassword 被封禁或已删除的账号不允许找回密码
+func canResetPassword(status pb.UserStatus) bool {
+	return status == pb.UserStatus_STATUS_ACTIVE || status == pb.UserStatus_STATUS_INACTIVE
+}
+
+// buildTokenLink 在链接上拼接token参数
+func buildTokenLink(baseURL string, token string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || baseURL == "" {
+		return token
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
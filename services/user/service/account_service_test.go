@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yb2020/odoc/config"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	"github.com/yb2020/odoc/pkg/memory"
+)
+
+func TestAcquireSendSlotConcurrent(t *testing.T) {
+	logger := daotest.NewLogger()
+	s := &AccountService{
+		cfg:    &config.AccountConfig{SendInterval: 60},
+		cache:  memory.NewMemoryCache(logger, time.Minute, "test"),
+		logger: logger,
+	}
+
+	// 同一邮箱同时发起的请求只有一个能发送邮件
+	const workers = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	acquired := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.acquireSendSlot(context.Background(), AccountTokenResetPassword, "U1@example.com") {
+				mu.Lock()
+				acquired++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if acquired != 1 {
+		t.Fatalf("acquired = %d, want 1", acquired)
+	}
+
+	// 发送间隔按邮箱和用途区分，邮箱不区分大小写
+	if s.acquireSendSlot(context.Background(), AccountTokenResetPassword, "u1@example.com") {
+		t.Fatalf("same email in other case acquired a slot")
+	}
+	if !s.acquireSendSlot(context.Background(), AccountTokenVerifyEmail, "u1@example.com") {
+		t.Fatalf("other purpose did not acquire a slot")
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/yb2020/odoc/pkg/cache"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/logging"
+)
+
+// AccountTokenPurpose 一次性令牌用途
+type AccountTokenPurpose string
+
+const (
+	// AccountTokenVerifyEmail 邮箱验证
+	AccountTokenVerifyEmail AccountTokenPurpose = "verify_email"
+	// AccountTokenResetPassword 重置密码
+	AccountTokenResetPassword AccountTokenPurpose = "reset_password"
+)
+
+const (
+	accountTokenKey     = "account_token:%s:%s"
+	accountTokenUserKey = "account_token:%s:user:%s"
+)
+
+// accountTokenRecord 令牌在缓存中保存的内容
+type accountTokenRecord struct {
+	UserId string `json:"userId"`
+	Email  string `json:"email"`
+}
+
+// AccountTokenClaims 令牌校验通过后得到的信息
+type AccountTokenClaims struct {
+	UserId string
+	Email  string
+}
+
+// AccountTokenService 一次性令牌服务
+// 令牌格式为 base64url(用途|用户ID|随机串|过期时间).base64url(HMAC-SHA256签名)，
+// 随机串作为键保存在缓存中，使用后删除；同一用户同一用途只保留最新签发的令牌
+type AccountTokenService struct {
+	cache  cache.Cache
+	secret []byte
+	logger logging.Logger
+	tracer opentracing.Tracer
+}
+
+// NewAccountTokenService 创建一次性令牌服务，secret 不能为空
+func NewAccountTokenService(cache cache.Cache, secret string, logger logging.Logger, tracer opentracing.Tracer) *AccountTokenService {
+	return &AccountTokenService{
+		cache:  cache,
+		secret: []byte(secret),
+		logger: logger,
+		tracer: tracer,
+	}
+}
+
+// Issue 签发令牌，并使该用户同一用途的旧令牌失效
+func (s *AccountTokenService) Issue(ctx context.Context, purpose AccountTokenPurpose, userId string, email string, ttl time.Duration) (string, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountTokenService.Issue")
+	defer span.Finish()
+
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return "", err
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+	expiresAt := time.Now().Add(ttl).Unix()
+
+	userKey := fmt.Sprintf(accountTokenUserKey, purpose, userId)
+	var previousNonce string
+	if found, err := s.cache.Get(ctx, userKey, &previousNonce); err == nil && found && previousNonce != "" {
+		if err := s.cache.Delete(ctx, fmt.Sprintf(accountTokenKey, purpose, previousNonce)); err != nil {
+			s.logger.Warn("msg", "删除旧令牌失败", "purpose", purpose, "userId", userId, "error", err.Error())
+		}
+	}
+
+	record := &accountTokenRecord{UserId: userId, Email: email}
+	if err := s.cache.Set(ctx, fmt.Sprintf(accountTokenKey, purpose, nonce), record, ttl); err != nil {
+		return "", err
+	}
+	if err := s.cache.Set(ctx, userKey, nonce, ttl); err != nil {
+		return "", err
+	}
+
+	payload := strings.Join([]string{string(purpose), userId, nonce, strconv.FormatInt(expiresAt, 10)}, "|")
+	encodedPayload := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encodedPayload + "." + s.sign(encodedPayload), nil
+}
+
+// Consume 校验并消费令牌，令牌只能使用一次
+func (s *AccountTokenService) Consume(ctx context.Context, purpose AccountTokenPurpose, token string) (*AccountTokenClaims, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AccountTokenService.Consume")
+	defer span.Finish()
+
+	encodedPayload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(encodedPayload))) {
+		return nil, errors.Biz("user.account.errors.invalid_token")
+	}
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, errors.Biz("user.account.errors.invalid_token")
+	}
+	parts := strings.Split(string(payloadBytes), "|")
+	if len(parts) != 4 || parts[0] != string(purpose) {
+		return nil, errors.Biz("user.account.errors.invalid_token")
+	}
+	userId, nonce := parts[1], parts[2]
+	expiresAt, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return nil, errors.Biz("user.account.errors.invalid_token")
+	}
+	if time.Now().Unix() > expiresAt {
+		return nil, errors.Biz("user.account.errors.token_expired")
+	}
+
+	// 读取与删除在同一次原子操作中完成，并发使用同一令牌时只有一个请求能取到
+	var record accountTokenRecord
+	found, err := s.cache.GetAndDelete(ctx, fmt.Sprintf(accountTokenKey, purpose, nonce), &record)
+	if err != nil {
+		s.logger.Error("msg", "读取令牌失败", "purpose", purpose, "error", err.Error())
+		return nil, errors.Biz("user.account.errors.invalid_token")
+	}
+	if !found || record.UserId != userId {
+		// 已使用、已被新令牌替换或已过期
+		return nil, errors.Biz("user.account.errors.token_used")
+	}
+
+	if err := s.cache.Delete(ctx, fmt.Sprintf(accountTokenUserKey, purpose, userId)); err != nil {
+		s.logger.Warn("msg", "删除用户令牌索引失败", "purpose", purpose, "userId", userId, "error", err.Error())
+	}
+	return &AccountTokenClaims{UserId: record.UserId, Email: record.Email}, nil
+}
+
+// sign 计算签名
+func (s *AccountTokenService) sign(encodedPayload string) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(encodedPayload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	"github.com/yb2020/odoc/pkg/memory"
+)
+
+func newAccountTokenService() *AccountTokenService {
+	logger := daotest.NewLogger()
+	return NewAccountTokenService(memory.NewMemoryCache(logger, time.Minute, "test"), "test-secret", logger, opentracing.NoopTracer{})
+}
+
+func TestAccountTokenConsumeOnce(t *testing.T) {
+	s := newAccountTokenService()
+	ctx := context.Background()
+	token, err := s.Issue(ctx, AccountTokenResetPassword, "u1", "u1@example.com", time.Minute)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	_, err = s.Consume(ctx, AccountTokenVerifyEmail, token)
+	assertBizError(t, err, "user.account.errors.invalid_token")
+
+	claims, err := s.Consume(ctx, AccountTokenResetPassword, token)
+	if err != nil || claims.UserId != "u1" || claims.Email != "u1@example.com" {
+		t.Fatalf("Consume = %+v, %v", claims, err)
+	}
+	_, err = s.Consume(ctx, AccountTokenResetPassword, token)
+	assertBizError(t, err, "user.account.errors.token_used")
+}
+
+func TestAccountTokenReissueInvalidatesPrevious(t *testing.T) {
+	s := newAccountTokenService()
+	ctx := context.Background()
+	first, _ := s.Issue(ctx, AccountTokenVerifyEmail, "u1", "u1@example.com", time.Minute)
+	second, _ := s.Issue(ctx, AccountTokenVerifyEmail, "u1", "u1@example.com", time.Minute)
+
+	_, err := s.Consume(ctx, AccountTokenVerifyEmail, first)
+	assertBizError(t, err, "user.account.errors.token_used")
+	if _, err := s.Consume(ctx, AccountTokenVerifyEmail, second); err != nil {
+		t.Fatalf("Consume latest token: %v", err)
+	}
+}
+
+func TestAccountTokenConcurrentConsume(t *testing.T) {
+	s := newAccountTokenService()
+	token, _ := s.Issue(context.Background(), AccountTokenResetPassword, "u1", "u1@example.com", time.Minute)
+
+	const workers = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Consume(context.Background(), AccountTokenResetPassword, token); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Fatalf("succeeded = %d, want 1", succeeded)
+	}
+}
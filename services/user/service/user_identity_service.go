@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/eventbus"
+	idgen "github.com/yb2020/odoc/pkg/idgen"
+	"github.com/yb2020/odoc/pkg/logging"
+	baseModel "github.com/yb2020/odoc/pkg/model"
+	"github.com/yb2020/odoc/pkg/utils"
+	pb "github.com/yb2020/odoc/proto/gen/go/user"
+	"github.com/yb2020/odoc/services/user/dao"
+	"github.com/yb2020/odoc/services/user/event"
+	"github.com/yb2020/odoc/services/user/model"
+)
+
+// LegacyGoogleProvider 旧版 google_open_id 迁移后对应的提供方标识
+const LegacyGoogleProvider = "google"
+
+// UserIdentityService 用户外部身份服务，负责外部登录时的用户查找、创建以及账号关联
+type UserIdentityService struct {
+	identityDAO        *dao.UserIdentityDAO
+	userDAO            *dao.UserDAO
+	userService        *UserService
+	eventBus           *eventbus.EventBus
+	logger             logging.Logger
+	tracer             opentracing.Tracer
+	transactionManager *baseDao.TransactionManager
+}
+
+// NewUserIdentityService 创建用户外部身份服务
+func NewUserIdentityService(identityDAO *dao.UserIdentityDAO, userDAO *dao.UserDAO, userService *UserService,
+	eventBus *eventbus.EventBus, logger logging.Logger, tracer opentracing.Tracer, transactionManager *baseDao.TransactionManager) *UserIdentityService {
+	return &UserIdentityService{
+		identityDAO:        identityDAO,
+		userDAO:            userDAO,
+		userService:        userService,
+		eventBus:           eventBus,
+		logger:             logger,
+		tracer:             tracer,
+		transactionManager: transactionManager,
+	}
+}
+
+// FindOrCreateUser 外部登录回调时调用：
+// 1. 已关联的外部身份直接返回对应用户；
+// 2. 邮箱已验证且存在同邮箱用户时自动关联，未激活的账号同时被激活并清空注册时设置的密码；
+// 3. 否则使用外部身份信息创建新用户。
+func (s *UserIdentityService) FindOrCreateUser(ctx context.Context, external *model.ExternalIdentity) (*model.User, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserIdentityService.FindOrCreateUser")
+	span.SetTag("identity.provider", external.Provider)
+	defer span.Finish()
+
+	if external.Provider == "" || external.Subject == "" {
+		return nil, errors.Biz("user.identity.errors.invalid_identity")
+	}
+
+	var user *model.User
+	activated := false
+	txErr := s.transactionManager.ExecuteInTransaction(ctx, func(txCtx context.Context) error {
+		identity, err := s.identityDAO.FindByProviderAndSubject(txCtx, external.Provider, external.Subject)
+		if err != nil {
+			return err
+		}
+
+		if identity != nil {
+			found, err := s.userDAO.FindExistById(txCtx, identity.UserId)
+			if err != nil {
+				return err
+			}
+			if found != nil {
+				s.logger.Info("msg", "通过外部身份找到现有用户", "provider", external.Provider, "userId", found.Id)
+				user = found
+				return s.touchIdentity(txCtx, identity, external)
+			}
+			// 关联的用户已被删除，清理残留的外部身份后按新用户处理
+			s.logger.Warn("msg", "外部身份关联的用户不存在，清理残留记录", "provider", external.Provider, "userId", identity.UserId)
+			if err := s.identityDAO.RemoveById(txCtx, identity.Id); err != nil {
+				return err
+			}
+		}
+
+		email := strings.TrimSpace(external.Email)
+		if email == "" {
+			return errors.Biz("user.identity.errors.email_required")
+		}
+		if !external.EmailVerified {
+			return errors.Biz("user.identity.errors.email_not_verified")
+		}
+
+		found, err := s.userDAO.GetUserByEmail(txCtx, email)
+		if err != nil {
+			return err
+		}
+		if found != nil {
+			s.logger.Info("msg", "通过邮箱找到现有用户，自动关联外部身份", "provider", external.Provider, "userId", found.Id)
+			// 外部提供方已验证该邮箱，未激活的账号视为完成邮箱验证。
+			// 未激活账号的密码由注册者设置，注册者未必是邮箱的所有者，激活时清空密码，避免抢注的密码继续有效
+			if found.Status == pb.UserStatus_STATUS_INACTIVE {
+				if activated, err = s.userService.ActivateWithoutPassword(txCtx, found.Id); err != nil {
+					return err
+				}
+				found.Status = pb.UserStatus_STATUS_ACTIVE
+				found.Password = ""
+			}
+			user = found
+			return s.createIdentity(txCtx, found.Id, external)
+		}
+
+		newUser := &model.User{
+			BaseModel: baseModel.BaseModel{
+				Id: idgen.GenerateUUID(),
+			},
+			Email:    utils.ToValidUTF8(email, ""),
+			Username: utils.ToValidUTF8(email, ""), // 使用 Email 作为默认用户名
+			Nickname: utils.ToValidUTF8(external.Name, ""),
+			Avatar:   utils.ToValidUTF8(external.Avatar, ""),
+			Status:   pb.UserStatus_STATUS_ACTIVE,
+			Roles:    model.UserRoleSlice{pb.UserRole_ROLE_USER},
+			// 注意：密码字段为空，外部登录的用户可以通过找回密码设置密码
+		}
+		if err := s.userDAO.Save(txCtx, newUser); err != nil {
+			s.logger.Error("msg", "通过外部身份创建新用户失败", "provider", external.Provider, "error", err.Error())
+			return err
+		}
+		s.logger.Info("msg", "通过外部身份创建新用户成功", "provider", external.Provider, "userId", newUser.Id)
+		user = newUser
+		return s.createIdentity(txCtx, newUser.Id, external)
+	})
+	if txErr != nil {
+		return nil, txErr
+	}
+	if activated {
+		// 同步发布，撤销使用原密码签发的登录令牌
+		s.logger.Info("msg", "外部登录激活账号，已清空原密码", "provider", external.Provider, "userId", user.Id)
+		s.eventBus.Publish(ctx, eventbus.Event{Type: event.UserPasswordChangedEvent, Data: user.Id}, false)
+	}
+
+	return user, nil
+}
+
+// LinkIdentity 将外部身份关联到已登录的用户
+func (s *UserIdentityService) LinkIdentity(ctx context.Context, userId string, external *model.ExternalIdentity) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserIdentityService.LinkIdentity")
+	span.SetTag("identity.provider", external.Provider)
+	defer span.Finish()
+
+	if external.Provider == "" || external.Subject == "" {
+		return errors.Biz("user.identity.errors.invalid_identity")
+	}
+
+	return s.transactionManager.ExecuteInTransaction(ctx, func(txCtx context.Context) error {
+		identity, err := s.identityDAO.FindByProviderAndSubject(txCtx, external.Provider, external.Subject)
+		if err != nil {
+			return err
+		}
+		if identity != nil {
+			if identity.UserId != userId {
+				return errors.Biz("user.identity.errors.linked_to_other_user")
+			}
+			return s.touchIdentity(txCtx, identity, external)
+		}
+
+		existing, err := s.identityDAO.FindByUserIdAndProvider(txCtx, userId, external.Provider)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return errors.Biz("user.identity.errors.provider_already_linked")
+		}
+
+		s.logger.Info("msg", "关联外部身份", "provider", external.Provider, "userId", userId)
+		return s.createIdentity(txCtx, userId, external)
+	})
+}
+
+// UnlinkIdentity 解除外部身份关联，未设置密码时必须保留至少一个外部身份
+func (s *UserIdentityService) UnlinkIdentity(ctx context.Context, userId, provider string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserIdentityService.UnlinkIdentity")
+	span.SetTag("identity.provider", provider)
+	defer span.Finish()
+
+	return s.transactionManager.ExecuteInTransaction(ctx, func(txCtx context.Context) error {
+		identity, err := s.identityDAO.FindByUserIdAndProvider(txCtx, userId, provider)
+		if err != nil {
+			return err
+		}
+		if identity == nil {
+			return errors.Biz("user.identity.errors.not_linked")
+		}
+
+		hasPassword, err := s.hasPassword(txCtx, userId)
+		if err != nil {
+			return err
+		}
+		if !hasPassword {
+			count, err := s.identityDAO.CountByUserId(txCtx, userId)
+			if err != nil {
+				return err
+			}
+			if count <= 1 {
+				return errors.Biz("user.identity.errors.last_login_method")
+			}
+		}
+
+		s.logger.Info("msg", "解除外部身份关联", "provider", provider, "userId", userId)
+		return s.identityDAO.RemoveById(txCtx, identity.Id)
+	})
+}
+
+// ListIdentities 获取用户已关联的外部身份，并返回用户是否已设置密码
+func (s *UserIdentityService) ListIdentities(ctx context.Context, userId string) ([]model.UserIdentity, bool, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserIdentityService.ListIdentities")
+	defer span.Finish()
+
+	identities, err := s.identityDAO.FindByUserId(ctx, userId)
+	if err != nil {
+		return nil, false, err
+	}
+	hasPassword, err := s.hasPassword(ctx, userId)
+	if err != nil {
+		return nil, false, err
+	}
+	return identities, hasPassword, nil
+}
+
+// RemoveUserIdentities 删除用户关联的全部外部身份，用于用户删除后的清理
+func (s *UserIdentityService) RemoveUserIdentities(ctx context.Context, userId string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserIdentityService.RemoveUserIdentities")
+	defer span.Finish()
+
+	return s.identityDAO.DeleteByUserId(ctx, userId)
+}
+
+// MigrateLegacyGoogleBindings 将旧版 t_user.google_open_id 中的绑定关系迁移到外部身份表
+// 已迁移的记录会被跳过，可重复执行；返回本次新迁移的数量
+func (s *UserIdentityService) MigrateLegacyGoogleBindings(ctx context.Context) (int, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserIdentityService.MigrateLegacyGoogleBindings")
+	defer span.Finish()
+
+	bindings, err := s.userDAO.FindLegacyGoogleBindings(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, binding := range bindings {
+		identity, err := s.identityDAO.FindByProviderAndSubject(ctx, LegacyGoogleProvider, binding.GoogleOpenId)
+		if err != nil {
+			return migrated, err
+		}
+		if identity != nil {
+			continue
+		}
+		// 旧版流程只接受 Google 已验证的邮箱
+		err = s.createIdentity(ctx, binding.UserId, &model.ExternalIdentity{
+			Provider:      LegacyGoogleProvider,
+			Subject:       binding.GoogleOpenId,
+			Email:         binding.Email,
+			EmailVerified: true,
+			Name:          binding.Nickname,
+			Avatar:        binding.Avatar,
+		})
+		if err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+// createIdentity 新建外部身份记录
+func (s *UserIdentityService) createIdentity(ctx context.Context, userId string, external *model.ExternalIdentity) error {
+	identity := &model.UserIdentity{
+		UserId:        userId,
+		Provider:      external.Provider,
+		Subject:       external.Subject,
+		Email:         utils.ToValidUTF8(external.Email, ""),
+		EmailVerified: external.EmailVerified,
+		DisplayName:   utils.ToValidUTF8(external.Name, ""),
+		Avatar:        utils.ToValidUTF8(external.Avatar, ""),
+		LastLoginAt:   time.Now(),
+	}
+	if err := s.identityDAO.Save(ctx, identity); err != nil {
+		s.logger.Error("msg", "保存外部身份失败", "provider", external.Provider, "userId", userId, "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// touchIdentity 使用提供方返回的最新资料刷新外部身份，并记录登录时间
+func (s *UserIdentityService) touchIdentity(ctx context.Context, identity *model.UserIdentity, external *model.ExternalIdentity) error {
+	identity.Email = utils.ToValidUTF8(external.Email, "")
+	identity.EmailVerified = external.EmailVerified
+	identity.DisplayName = utils.ToValidUTF8(external.Name, "")
+	identity.Avatar = utils.ToValidUTF8(external.Avatar, "")
+	identity.LastLoginAt = time.Now()
+	return s.identityDAO.Modify(ctx, identity)
+}
+
+// hasPassword 用户是否设置了登录密码，需直接查库（缓存中的用户不包含密码）
+func (s *UserIdentityService) hasPassword(ctx context.Context, userId string) (bool, error) {
+	user, err := s.userDAO.FindExistById(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	if user == nil {
+		return false, errors.Biz("user.error.user_not_found")
+	}
+	return user.Password != "", nil
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	baseDao "github.com/yb2020/odoc/pkg/dao"
+	"github.com/yb2020/odoc/pkg/dao/daotest"
+	"github.com/yb2020/odoc/pkg/errors"
+	"github.com/yb2020/odoc/pkg/eventbus"
+	"github.com/yb2020/odoc/pkg/memory"
+	baseModel "github.com/yb2020/odoc/pkg/model"
+	pb "github.com/yb2020/odoc/proto/gen/go/user"
+	"github.com/yb2020/odoc/services/user/dao"
+	"github.com/yb2020/odoc/services/user/event"
+	"github.com/yb2020/odoc/services/user/model"
+)
+
+func assertBizError(t *testing.T, err error, msgID string) {
+	t.Helper()
+	var bizErr *errors.BizError
+	if !stderrors.As(err, &bizErr) || bizErr.MsgID != msgID {
+		t.Fatalf("err = %v, want %s", err, msgID)
+	}
+}
+
+func TestUserIdentityService(t *testing.T) {
+	db := daotest.NewDB(t, &model.User{}, &model.UserIdentity{})
+	logger := daotest.NewLogger()
+	tracer := opentracing.NoopTracer{}
+	transactionManager := baseDao.NewTransactionManager(db)
+	userDAO := dao.NewUserDAO(db, logger)
+	identityDAO := dao.NewUserIdentityDAO(db, logger)
+	eventBus := eventbus.NewEventBus()
+	userService := NewUserService(memory.NewMemoryCache(logger, time.Minute, "test"), logger, tracer, nil, userDAO, eventBus, transactionManager)
+	s := NewUserIdentityService(identityDAO, userDAO, userService, eventBus, logger, tracer, transactionManager)
+
+	ctx := context.Background()
+	createUser := func(t *testing.T, email string, password string, status pb.UserStatus) *model.User {
+		t.Helper()
+		user := &model.User{
+			BaseModel: baseModel.BaseModel{Id: "user-" + email},
+			Username:  email,
+			Email:     email,
+			Password:  password,
+			Status:    status,
+		}
+		if err := userDAO.Save(ctx, user); err != nil {
+			t.Fatalf("save user: %v", err)
+		}
+		return user
+	}
+	link := func(t *testing.T, userId string, provider string, subject string) {
+		t.Helper()
+		err := s.LinkIdentity(ctx, userId, &model.ExternalIdentity{
+			Provider: provider,
+			Subject:  subject,
+			Email:    subject + "@example.com",
+		})
+		if err != nil {
+			t.Fatalf("link %s: %v", provider, err)
+		}
+	}
+	countIdentities := func(t *testing.T, userId string) int64 {
+		t.Helper()
+		count, err := identityDAO.CountByUserId(ctx, userId)
+		if err != nil {
+			t.Fatalf("count identities: %v", err)
+		}
+		return count
+	}
+
+	t.Run("links verified email", func(t *testing.T) {
+		existing := createUser(t, "alice@example.com", "", pb.UserStatus_STATUS_INACTIVE)
+
+		user, err := s.FindOrCreateUser(ctx, &model.ExternalIdentity{
+			Provider:      "github",
+			Subject:       "gh-1",
+			Email:         " alice@example.com ",
+			EmailVerified: true,
+		})
+		if err != nil {
+			t.Fatalf("FindOrCreateUser: %v", err)
+		}
+		if user.Id != existing.Id {
+			t.Fatalf("user id = %s, want existing user %s", user.Id, existing.Id)
+		}
+		identity, err := identityDAO.FindByProviderAndSubject(ctx, "github", "gh-1")
+		if err != nil || identity == nil || identity.UserId != existing.Id {
+			t.Fatalf("identity = %+v, err = %v, want linked to %s", identity, err, existing.Id)
+		}
+		// 提供方已验证邮箱，未激活的账号被激活
+		stored, err := userDAO.FindExistById(ctx, existing.Id)
+		if err != nil || stored.Status != pb.UserStatus_STATUS_ACTIVE {
+			t.Fatalf("stored user = %+v, err = %v, want active", stored, err)
+		}
+
+		// 再次登录通过外部身份找到同一用户，不重复关联
+		again, err := s.FindOrCreateUser(ctx, &model.ExternalIdentity{
+			Provider:      "github",
+			Subject:       "gh-1",
+			Email:         "alice@example.com",
+			EmailVerified: true,
+		})
+		if err != nil || again.Id != existing.Id {
+			t.Fatalf("second login user = %+v, err = %v", again, err)
+		}
+		if got := countIdentities(t, existing.Id); got != 1 {
+			t.Fatalf("identities = %d, want 1", got)
+		}
+	})
+
+	passwordTests := []struct {
+		name         string
+		email        string
+		status       pb.UserStatus
+		wantPassword bool
+		wantRevoked  bool
+	}{
+		// 他人抢先用受害者邮箱注册的未激活账号，外部登录激活后注册时设置的密码失效
+		{name: "inactive account registered by someone else", email: "grace@example.com", status: pb.UserStatus_STATUS_INACTIVE, wantRevoked: true},
+		{name: "active account keeps its password", email: "heidi@example.com", status: pb.UserStatus_STATUS_ACTIVE, wantPassword: true},
+	}
+	for _, tt := range passwordTests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := createUser(t, tt.email, HashPassword("chosen-by-registrant", "user-"+tt.email), tt.status)
+			revoked := 0
+			eventBus.Subscribe(event.UserPasswordChangedEvent, func(ctx context.Context, e eventbus.Event) {
+				if e.Data.(string) == existing.Id {
+					revoked++
+				}
+			})
+
+			user, err := s.FindOrCreateUser(ctx, &model.ExternalIdentity{
+				Provider:      "google",
+				Subject:       "g-" + tt.email,
+				Email:         tt.email,
+				EmailVerified: true,
+			})
+			if err != nil || user.Id != existing.Id {
+				t.Fatalf("FindOrCreateUser = %+v, %v, want existing user", user, err)
+			}
+			stored, err := userDAO.FindExistById(ctx, existing.Id)
+			if err != nil || stored.Status != pb.UserStatus_STATUS_ACTIVE {
+				t.Fatalf("stored user = %+v, err = %v, want active", stored, err)
+			}
+			if hasPassword := stored.Password != ""; hasPassword != tt.wantPassword {
+				t.Fatalf("has password = %v, want %v", hasPassword, tt.wantPassword)
+			}
+			if (revoked == 1) != tt.wantRevoked || revoked > 1 {
+				t.Fatalf("revoked tokens %d times, want revoked = %v", revoked, tt.wantRevoked)
+			}
+		})
+	}
+
+	t.Run("rejects unverified email", func(t *testing.T) {
+		existing := createUser(t, "bob@example.com", "hash", pb.UserStatus_STATUS_ACTIVE)
+
+		_, err := s.FindOrCreateUser(ctx, &model.ExternalIdentity{
+			Provider: "oidc",
+			Subject:  "attacker",
+			Email:    "bob@example.com",
+		})
+		assertBizError(t, err, "user.identity.errors.email_not_verified")
+		if got := countIdentities(t, existing.Id); got != 0 {
+			t.Fatalf("identities = %d, want 0", got)
+		}
+		identity, err := identityDAO.FindByProviderAndSubject(ctx, "oidc", "attacker")
+		if err != nil || identity != nil {
+			t.Fatalf("identity = %+v, err = %v, want none", identity, err)
+		}
+	})
+
+	t.Run("requires email", func(t *testing.T) {
+		_, err := s.FindOrCreateUser(ctx, &model.ExternalIdentity{
+			Provider:      "orcid",
+			Subject:       "0000-0001",
+			EmailVerified: true,
+		})
+		assertBizError(t, err, "user.identity.errors.email_required")
+	})
+
+	t.Run("creates new user", func(t *testing.T) {
+		user, err := s.FindOrCreateUser(ctx, &model.ExternalIdentity{
+			Provider:      "google",
+			Subject:       "g-1",
+			Email:         "carol@example.com",
+			EmailVerified: true,
+			Name:          "Carol",
+		})
+		if err != nil {
+			t.Fatalf("FindOrCreateUser: %v", err)
+		}
+		if user.Email != "carol@example.com" || user.Nickname != "Carol" || user.Password != "" {
+			t.Fatalf("new user = %+v", user)
+		}
+		if got := countIdentities(t, user.Id); got != 1 {
+			t.Fatalf("identities = %d, want 1", got)
+		}
+	})
+
+	t.Run("rejects identity of other user", func(t *testing.T) {
+		owner := createUser(t, "dave@example.com", "hash", pb.UserStatus_STATUS_ACTIVE)
+		other := createUser(t, "erin@example.com", "hash", pb.UserStatus_STATUS_ACTIVE)
+		link(t, owner.Id, "github", "gh-dave")
+
+		err := s.LinkIdentity(ctx, other.Id, &model.ExternalIdentity{Provider: "github", Subject: "gh-dave"})
+		assertBizError(t, err, "user.identity.errors.linked_to_other_user")
+	})
+
+	unlinkTests := []struct {
+		name      string
+		password  string
+		providers []string
+		unlink    string
+		wantErr   string
+		wantLeft  int64
+	}{
+		{name: "last identity without password", providers: []string{"github"}, unlink: "github", wantErr: "user.identity.errors.last_login_method", wantLeft: 1},
+		{name: "another identity remains", providers: []string{"github", "google"}, unlink: "github", wantLeft: 1},
+		{name: "password remains", password: "hash", providers: []string{"github"}, unlink: "github", wantLeft: 0},
+		{name: "provider not linked", password: "hash", providers: []string{"github"}, unlink: "orcid", wantErr: "user.identity.errors.not_linked", wantLeft: 1},
+	}
+	for i, tt := range unlinkTests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := fmt.Sprintf("frank%d", i)
+			user := createUser(t, name+"@example.com", tt.password, pb.UserStatus_STATUS_ACTIVE)
+			for _, provider := range tt.providers {
+				link(t, user.Id, provider, provider+"-"+name)
+			}
+
+			err := s.UnlinkIdentity(ctx, user.Id, tt.unlink)
+			if tt.wantErr != "" {
+				assertBizError(t, err, tt.wantErr)
+			} else if err != nil {
+				t.Fatalf("UnlinkIdentity: %v", err)
+			}
+			if got := countIdentities(t, user.Id); got != tt.wantLeft {
+				t.Fatalf("identities = %d, want %d", got, tt.wantLeft)
+			}
+		})
+	}
+}
@@ -71,93 +71,6 @@ func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*model.
 	return user, nil
 }
 
-// FindOrCreateByGoogleID handles user lookup, creation, and binding using Google ID.
-// It's designed to be called within the Google OAuth callback flow.
-func (s *UserService) FindOrCreateByGoogleID(ctx context.Context, googleUserInfo *model.GoogleUserInfo) (*model.User, error) {
-	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserService.FindOrCreateByGoogleID")
-	defer span.Finish()
-
-	var user *model.User
-	var err error
-
-	// 步骤 1: 开启事务
-	txErr := s.transactionManager.ExecuteInTransaction(ctx, func(txCtx context.Context) error {
-		// 步骤 2: 用 GoogleOpenId 查��用户
-		s.logger.Info("msg", "通过GoogleID查找用户", "googleId", googleUserInfo.ID)
-		foundUser, findErr := s.userDAO.FindByGoogleID(txCtx, googleUserInfo.ID)
-		if findErr != nil {
-			s.logger.Error("msg", "通过GoogleID查找用户失败", "error", findErr)
-			return findErr // 返回错误以回滚事务
-		}
-
-		// 步骤 3: 如果找到，直接赋值并返回
-		if foundUser != nil {
-			s.logger.Info("msg", "通过GoogleID找到现有用户", "userId", foundUser.Id)
-			user = foundUser
-			return nil // 事务成功
-		}
-
-		// 步骤 4: 如果没找到，再用 Email 查找用户
-		s.logger.Info("msg", "通过GoogleID未找到用户，尝试通过Email查找", "email", googleUserInfo.Email)
-		foundUser, findErr = s.userDAO.GetUserByEmail(txCtx, googleUserInfo.Email)
-		if findErr != nil {
-			s.logger.Error("msg", "通过Email查找用户失败", "error", findErr)
-			return findErr
-		}
-
-		// 步骤 5: 如果用 Email 找到了
-		if foundUser != nil {
-			s.logger.Info("msg", "通过Email找到现有用户，准备绑定GoogleID", "userId", foundUser.Id)
-			// 更新该用户的 GoogleOpenId 字段，完成绑定
-			foundUser.GoogleOpenId = utils.ToValidUTF8(googleUserInfo.ID, "")
-			if updateErr := s.userDAO.ModifyExcludeNull(txCtx, foundUser); updateErr != nil {
-				s.logger.Error("msg", "绑定GoogleID失败", "error", updateErr)
-				return updateErr
-			}
-			s.logger.Info("msg", "绑定GoogleID成功", "userId", foundUser.Id)
-			user = foundUser
-			return nil // 事务成功
-		}
-
-		// 步骤 6: 如果 email 也找不到，则创建新用户
-		s.logger.Info("msg", "用户不存在，创建新用户", "email", googleUserInfo.Email)
-		newUser := &model.User{
-			BaseModel: baseModel.BaseModel{
-				Id: idgen.GenerateUUID(),
-			},
-			Email:        utils.ToValidUTF8(googleUserInfo.Email, ""),
-			Username:     utils.ToValidUTF8(googleUserInfo.Email, ""), // 使用 Email 作为默认用户名
-			Nickname:     utils.ToValidUTF8(googleUserInfo.Name, ""),
-			Avatar:       utils.ToValidUTF8(googleUserInfo.Picture, ""),
-			GoogleOpenId: utils.ToValidUTF8(googleUserInfo.ID, ""),
-			Status:       pb.UserStatus_STATUS_ACTIVE,                // 直接设为激活状态
-			Roles:        model.UserRoleSlice{pb.UserRole_ROLE_USER}, // 赋予默认角色
-			// 注意：密码字段为空，因为这是第三方登录，不需要密码
-		}
-
-		if createErr := s.userDAO.Save(txCtx, newUser); createErr != nil {
-			s.logger.Error("msg", "创建新用户失败", "error", createErr)
-			return createErr
-		}
-
-		s.logger.Info("msg", "创建新用户成功", "userId", newUser.Id)
-		user = newUser
-		return nil // 事务成功
-	})
-
-	if txErr != nil {
-		// 处理事务本身或事务内部返回的错误
-		if txErr.Error() == "user.error.user_not_found_for_creation" {
-			// This case should now be handled by the creation logic above.
-			// If we still get this, it's an unexpected state.
-			return nil, errors.Biz("user.error.user_creation_failed_unexpectedly")
-		}
-		return nil, txErr
-	}
-
-	return user, err
-}
-
 // UpdateUser 更新用户
 func (s *UserService) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserService.UpdateUser")
@@ -190,6 +103,22 @@ func (s *UserService) UpdateUser(ctx context.Context, user *model.User) (*model.
 	return user, nil
 }
 
+// ActivateWithoutPassword 激活未激活的账号并清空注册时设置的密码，返回是否发生了激活
+func (s *UserService) ActivateWithoutPassword(ctx context.Context, id string) (bool, error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserService.ActivateWithoutPassword")
+	span.SetTag("user.id", id)
+	defer span.Finish()
+
+	activated, err := s.userDAO.ActivateAndClearPassword(ctx, id)
+	if err != nil {
+		return false, errors.BizWrap(err.Error(), err)
+	}
+	if err := s.cache.Delete(ctx, fmt.Sprintf(cacheKey, id)); err != nil {
+		s.logger.Error("msg", "清除用户缓存失败", "error", err)
+	}
+	return activated, nil
+}
+
 // GetProfile 获取用户个人资料
 func (s *UserService) GetProfile(ctx context.Context, req *pb.GetProfileRequest) (*pb.GetProfileResponse, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserService.GetProfile")
@@ -246,7 +175,7 @@ func (s *UserService) Register(ctx context.Context, req *pb.RegisterRequest) (*p
 			Id: id,
 		},
 		Email:    req.Email,
-		Password: utils.StrengthenPassword(req.Password, fmt.Sprintf("%d", id)),
+		Password: HashPassword(req.Password, id),
 		Roles:    []pb.UserRole{pb.UserRole_ROLE_USER},
 		Status:   pb.UserStatus_STATUS_INACTIVE,
 	}
@@ -455,6 +384,24 @@ func (s *UserService) DeleteUserById(ctx context.Context, id string) error {
 	return nil
 }
 
+// RemoveUser 注销账号时物理删除用户，其他模块的数据由账号注销流程先行删除
+func (s *UserService) RemoveUser(ctx context.Context, id string) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserService.RemoveUser")
+	defer span.Finish()
+
+	// 所有的用户都下线
+	s.eventBus.Publish(ctx, eventbus.Event{Type: event.UserDeletedEvent, Data: id}, true)
+
+	s.logger.Info("msg", "物理删除用户", "id", id)
+	if err := s.userDAO.RemoveById(ctx, id); err != nil {
+		return err
+	}
+	if err := s.cache.Delete(ctx, fmt.Sprintf(cacheKey, id)); err != nil {
+		s.logger.Error("msg", "清除用户缓存失败", "error", err)
+	}
+	return nil
+}
+
 // DeleteUserByIds 批量删除用户
 func (s *UserService) DeleteUserByIds(ctx context.Context, ids []string) error {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserService.DeleteUserByIds")
@@ -508,6 +455,11 @@ func (s *UserService) GetUserByID(ctx context.Context, id string) (*model.User,
 	return user2, nil
 }
 
+// HashPassword 计算密码摘要，与登录时的校验方式保持一致
+func HashPassword(password string, userId string) string {
+	return utils.StrengthenPassword(password, userId)
+}
+
 // 检查用户状态是否为激活
 func (s *UserService) CheckUserStatusActive(ctx context.Context, id string) (bool, error) {
 	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UserService.CheckUserStatusActive")
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/yb2020/odoc/pkg/logging"
+	"github.com/yb2020/odoc/pkg/takeout"
+	pb "github.com/yb2020/odoc/proto/gen/go/user"
+	"github.com/yb2020/odoc/services/user/dao"
+	"github.com/yb2020/odoc/services/user/model"
+)
+
+// 编译时类型检查：确保 UserTakeoutExporter 实现了 takeout.Exporter 接口
+var _ takeout.Exporter = (*UserTakeoutExporter)(nil)
+
+// userProfileExport 导出的用户资料，不包含密码等凭据
+type userProfileExport struct {
+	Id        string              `json:"id"`
+	Username  string              `json:"username"`
+	Email     string              `json:"email"`
+	Nickname  string              `json:"nickname"`
+	Avatar    string              `json:"avatar"`
+	Roles     model.UserRoleSlice `json:"roles"`
+	Status    pb.UserStatus       `json:"status"`
+	CreatedAt time.Time           `json:"createdAt"`
+	UpdatedAt time.Time           `json:"updatedAt"`
+}
+
+// UserTakeoutExporter 导出用户资料和关联的外部身份
+type UserTakeoutExporter struct {
+	logger      logging.Logger
+	tracer      opentracing.Tracer
+	userDAO     *dao.UserDAO
+	identityDAO *dao.UserIdentityDAO
+}
+
+// NewUserTakeoutExporter 创建用户数据导出器
+func NewUserTakeoutExporter(logger logging.Logger, tracer opentracing.Tracer, userDAO *dao.UserDAO, identityDAO *dao.UserIdentityDAO) *UserTakeoutExporter {
+	return &UserTakeoutExporter{
+		logger:      logger,
+		tracer:      tracer,
+		userDAO:     userDAO,
+		identityDAO: identityDAO,
+	}
+}
+
+// Name 导出器名称
+func (e *UserTakeoutExporter) Name() string {
+	return "user"
+}
+
+// Export 导出用户资料和外部身份
+func (e *UserTakeoutExporter) Export(ctx context.Context, userId string, w *takeout.Writer) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, e.tracer, "UserTakeoutExporter.Export")
+	defer span.Finish()
+
+	user, err := e.userDAO.FindExistById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		profile := &userProfileExport{
+			Id:        user.Id,
+			Username:  user.Username,
+			Email:     user.Email,
+			Nickname:  user.Nickname,
+			Avatar:    user.Avatar,
+			Roles:     user.Roles,
+			Status:    user.Status,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		}
+		if err := w.WriteJSON("profile.json", profile); err != nil {
+			return err
+		}
+	}
+	identities, err := e.identityDAO.FindByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return w.WriteJSON("identities.json", identities)
+}
@@ -22,7 +22,10 @@ import (
 	"gorm.io/gorm/schema"
 
 	// Import model packages
+	accountdeletionmodel "github.com/yb2020/odoc/services/account_deletion/model"
+	auditmodel "github.com/yb2020/odoc/services/audit/model"
 	docmodel "github.com/yb2020/odoc/services/doc/model"
+	eventtrackermodel "github.com/yb2020/odoc/services/event_tracker/model"
 	membershipmodel "github.com/yb2020/odoc/services/membership/model"
 	"github.com/yb2020/odoc/services/nav/model"
 	notemodel "github.com/yb2020/odoc/services/note/model"
@@ -31,7 +34,10 @@ import (
 	papermodel "github.com/yb2020/odoc/services/paper/model"
 	paymodel "github.com/yb2020/odoc/services/pay/model"
 	pdfmodel "github.com/yb2020/odoc/services/pdf/model"
+	readingmodel "github.com/yb2020/odoc/services/reading/model"
+	takeoutmodel "github.com/yb2020/odoc/services/takeout/model"
 	translatemodel "github.com/yb2020/odoc/services/translate/model"
+	trashmodel "github.com/yb2020/odoc/services/trash/model"
 	usermodel "github.com/yb2020/odoc/services/user/model"
 )
 
@@ -464,7 +470,7 @@ func updateTable(db *gorm.DB, modelType reflect.Type) error {
 		}
 
 		// 如果数据库中的列不在模型中，询问是否删除
-		if !found && !isSystemColumn(dbColName) {
+		if !found && !isSystemColumn(dbColName) && !isRetainedColumn(modelTable.Name, dbColName) {
 			if askForConfirmation(fmt.Sprintf("是否删除未使用的列 %s.%s?", modelTable.Name, dbColName)) {
 				dropColumnSQL := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", modelTable.Name, dbColName)
 				err := db.Exec(dropColumnSQL).Error
@@ -570,6 +576,21 @@ func isSystemColumn(columnName string) bool {
 	return false
 }
 
+// retainedColumns 模型中已移除但仍需保留数据的旧列，服务启动时会从中迁移数据，确认迁移完成后再手动删除
+var retainedColumns = map[string][]string{
+	"t_user": {"google_open_id"}, // 已迁移至 t_user_identity
+}
+
+// 检查是否是需要保留的旧列
+func isRetainedColumn(tableName, columnName string) bool {
+	for _, name := range retainedColumns[tableName] {
+		if name == columnName {
+			return true
+		}
+	}
+	return false
+}
+
 // 将驼峰式命名转换为蛇形命名
 func toSnakeCase(s string) string {
 	var result strings.Builder
@@ -764,6 +785,12 @@ func scanAllModels() []ModelInfo {
 		Package:   "user",
 	})
 
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(usermodel.UserIdentity{}),
+		TableName: usermodel.UserIdentity{}.TableName(),
+		Package:   "user",
+	})
+
 	// 添加 OAuth2 模型
 	models = append(models, ModelInfo{
 		Type:      reflect.TypeOf(oauth2model.OAuth2Clients{}),
@@ -777,12 +804,54 @@ func scanAllModels() []ModelInfo {
 		Package:   "oauth2",
 	})
 
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(oauth2model.OAuth2Consent{}),
+		TableName: oauth2model.OAuth2Consent{}.TableName(),
+		Package:   "oauth2",
+	})
+
 	models = append(models, ModelInfo{
 		Type:      reflect.TypeOf(oauth2model.OAuth2Token{}),
 		TableName: oauth2model.OAuth2Token{}.TableName(),
 		Package:   "oauth2",
 	})
 
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(oauth2model.OAuth2Session{}),
+		TableName: oauth2model.OAuth2Session{}.TableName(),
+		Package:   "oauth2",
+	})
+
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(oauth2model.LoginHistory{}),
+		TableName: oauth2model.LoginHistory{}.TableName(),
+		Package:   "oauth2",
+	})
+
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(oauth2model.UserMFA{}),
+		TableName: oauth2model.UserMFA{}.TableName(),
+		Package:   "oauth2",
+	})
+
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(oauth2model.MFARecoveryCode{}),
+		TableName: oauth2model.MFARecoveryCode{}.TableName(),
+		Package:   "oauth2",
+	})
+
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(oauth2model.MFATrustedDevice{}),
+		TableName: oauth2model.MFATrustedDevice{}.TableName(),
+		Package:   "oauth2",
+	})
+
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(oauth2model.MFARolePolicy{}),
+		TableName: oauth2model.MFARolePolicy{}.TableName(),
+		Package:   "oauth2",
+	})
+
 	// 添加 Translate 模型
 	models = append(models, ModelInfo{
 		Type:      reflect.TypeOf(translatemodel.Glossary{}),
@@ -924,6 +993,11 @@ func scanAllModels() []ModelInfo {
 		TableName: pdfmodel.PdfThumb{}.TableName(),
 		Package:   "pdf",
 	})
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(pdfmodel.DocTextMark{}),
+		TableName: pdfmodel.DocTextMark{}.TableName(),
+		Package:   "pdf",
+	})
 	models = append(models, ModelInfo{
 		Type:      reflect.TypeOf(pdfmodel.PdfSummary{}),
 		TableName: pdfmodel.PdfSummary{}.TableName(),
@@ -990,6 +1064,18 @@ func scanAllModels() []ModelInfo {
 		Package:   "paper",
 	})
 
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(papermodel.PaperVersion{}),
+		TableName: papermodel.PaperVersion{}.TableName(),
+		Package:   "paper",
+	})
+
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(papermodel.PaperVenueRanking{}),
+		TableName: papermodel.PaperVenueRanking{}.TableName(),
+		Package:   "paper",
+	})
+
 	// ----- Paper 模块---//
 
 	// ----- Doc 模块---//
@@ -1066,6 +1152,11 @@ func scanAllModels() []ModelInfo {
 		TableName: membershipmodel.CreditPaymentRecord{}.TableName(),
 		Package:   "membership",
 	})
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(membershipmodel.CreditReconciliation{}),
+		TableName: membershipmodel.CreditReconciliation{}.TableName(),
+		Package:   "membership",
+	})
 	// ----- Membership 模块---//
 
 	// ----- Pay 模块---//
@@ -1079,6 +1170,11 @@ func scanAllModels() []ModelInfo {
 		TableName: paymodel.PaymentSubscription{}.TableName(),
 		Package:   "pay",
 	})
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(paymodel.PaymentRefund{}),
+		TableName: paymodel.PaymentRefund{}.TableName(),
+		Package:   "pay",
+	})
 	// ----- Pay 模块---//
 	// ----- Nav 模块---//
 	models = append(models, ModelInfo{
@@ -1095,6 +1191,64 @@ func scanAllModels() []ModelInfo {
 		Package:   "oss",
 	})
 
+	// ----- Reading 模块---//
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(readingmodel.ReadingSession{}),
+		TableName: readingmodel.ReadingSession{}.TableName(),
+		Package:   "reading",
+	})
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(readingmodel.ReadingDailyStat{}),
+		TableName: readingmodel.ReadingDailyStat{}.TableName(),
+		Package:   "reading",
+	})
+	// ----- Reading 模块---//
+
+	// ----- EventTracker 模块---//
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(eventtrackermodel.TrackingEvent{}),
+		TableName: eventtrackermodel.TrackingEvent{}.TableName(),
+		Package:   "event_tracker",
+	})
+	// ----- EventTracker 模块---//
+
+	// ----- Audit 模块---//
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(auditmodel.AuditLog{}),
+		TableName: auditmodel.AuditLog{}.TableName(),
+		Package:   "audit",
+	})
+	// ----- Audit 模块---//
+
+	// ----- Takeout 模块---//
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(takeoutmodel.TakeoutTask{}),
+		TableName: takeoutmodel.TakeoutTask{}.TableName(),
+		Package:   "takeout",
+	})
+	// ----- Takeout 模块---//
+
+	// ----- AccountDeletion 模块---//
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(accountdeletionmodel.AccountDeletion{}),
+		TableName: accountdeletionmodel.AccountDeletion{}.TableName(),
+		Package:   "account_deletion",
+	})
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(accountdeletionmodel.AccountDeletionStep{}),
+		TableName: accountdeletionmodel.AccountDeletionStep{}.TableName(),
+		Package:   "account_deletion",
+	})
+	// ----- AccountDeletion 模块---//
+
+	// ----- Trash 模块---//
+	models = append(models, ModelInfo{
+		Type:      reflect.TypeOf(trashmodel.TrashItem{}),
+		TableName: trashmodel.TrashItem{}.TableName(),
+		Package:   "trash",
+	})
+	// ----- Trash 模块---//
+
 	return models
 }
 